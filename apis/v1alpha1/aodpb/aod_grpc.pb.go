@@ -0,0 +1,263 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: aod/v1alpha1/aod.proto
+
+package aodpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	AccessOnDemand_HandleIAM_FullMethodName  = "/aod.v1alpha1.AccessOnDemand/HandleIAM"
+	AccessOnDemand_CleanupIAM_FullMethodName = "/aod.v1alpha1.AccessOnDemand/CleanupIAM"
+	AccessOnDemand_Validate_FullMethodName   = "/aod.v1alpha1.AccessOnDemand/Validate"
+	AccessOnDemand_ListGrants_FullMethodName = "/aod.v1alpha1.AccessOnDemand/ListGrants"
+)
+
+// AccessOnDemandClient is the client API for AccessOnDemand service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// AccessOnDemand handles AOD IAM requests, the same as the CLI and the HTTP
+// server.
+type AccessOnDemandClient interface {
+	// HandleIAM adds the IAM bindings in the request with an expiry condition.
+	HandleIAM(ctx context.Context, in *HandleIAMRequest, opts ...grpc.CallOption) (*HandleIAMResponse, error)
+	// CleanupIAM removes the IAM bindings in the request.
+	CleanupIAM(ctx context.Context, in *CleanupIAMRequest, opts ...grpc.CallOption) (*CleanupIAMResponse, error)
+	// Validate validates the IAM request without handling it.
+	Validate(ctx context.Context, in *ValidateRequest, opts ...grpc.CallOption) (*ValidateResponse, error)
+	// ListGrants lists the active AOD IAM bindings on the resources.
+	ListGrants(ctx context.Context, in *ListGrantsRequest, opts ...grpc.CallOption) (*ListGrantsResponse, error)
+}
+
+type accessOnDemandClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAccessOnDemandClient(cc grpc.ClientConnInterface) AccessOnDemandClient {
+	return &accessOnDemandClient{cc}
+}
+
+func (c *accessOnDemandClient) HandleIAM(ctx context.Context, in *HandleIAMRequest, opts ...grpc.CallOption) (*HandleIAMResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HandleIAMResponse)
+	err := c.cc.Invoke(ctx, AccessOnDemand_HandleIAM_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accessOnDemandClient) CleanupIAM(ctx context.Context, in *CleanupIAMRequest, opts ...grpc.CallOption) (*CleanupIAMResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CleanupIAMResponse)
+	err := c.cc.Invoke(ctx, AccessOnDemand_CleanupIAM_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accessOnDemandClient) Validate(ctx context.Context, in *ValidateRequest, opts ...grpc.CallOption) (*ValidateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ValidateResponse)
+	err := c.cc.Invoke(ctx, AccessOnDemand_Validate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accessOnDemandClient) ListGrants(ctx context.Context, in *ListGrantsRequest, opts ...grpc.CallOption) (*ListGrantsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListGrantsResponse)
+	err := c.cc.Invoke(ctx, AccessOnDemand_ListGrants_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AccessOnDemandServer is the server API for AccessOnDemand service.
+// All implementations must embed UnimplementedAccessOnDemandServer
+// for forward compatibility.
+//
+// AccessOnDemand handles AOD IAM requests, the same as the CLI and the HTTP
+// server.
+type AccessOnDemandServer interface {
+	// HandleIAM adds the IAM bindings in the request with an expiry condition.
+	HandleIAM(context.Context, *HandleIAMRequest) (*HandleIAMResponse, error)
+	// CleanupIAM removes the IAM bindings in the request.
+	CleanupIAM(context.Context, *CleanupIAMRequest) (*CleanupIAMResponse, error)
+	// Validate validates the IAM request without handling it.
+	Validate(context.Context, *ValidateRequest) (*ValidateResponse, error)
+	// ListGrants lists the active AOD IAM bindings on the resources.
+	ListGrants(context.Context, *ListGrantsRequest) (*ListGrantsResponse, error)
+	mustEmbedUnimplementedAccessOnDemandServer()
+}
+
+// UnimplementedAccessOnDemandServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAccessOnDemandServer struct{}
+
+func (UnimplementedAccessOnDemandServer) HandleIAM(context.Context, *HandleIAMRequest) (*HandleIAMResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HandleIAM not implemented")
+}
+func (UnimplementedAccessOnDemandServer) CleanupIAM(context.Context, *CleanupIAMRequest) (*CleanupIAMResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CleanupIAM not implemented")
+}
+func (UnimplementedAccessOnDemandServer) Validate(context.Context, *ValidateRequest) (*ValidateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Validate not implemented")
+}
+func (UnimplementedAccessOnDemandServer) ListGrants(context.Context, *ListGrantsRequest) (*ListGrantsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListGrants not implemented")
+}
+func (UnimplementedAccessOnDemandServer) mustEmbedUnimplementedAccessOnDemandServer() {}
+func (UnimplementedAccessOnDemandServer) testEmbeddedByValue()                        {}
+
+// UnsafeAccessOnDemandServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AccessOnDemandServer will
+// result in compilation errors.
+type UnsafeAccessOnDemandServer interface {
+	mustEmbedUnimplementedAccessOnDemandServer()
+}
+
+func RegisterAccessOnDemandServer(s grpc.ServiceRegistrar, srv AccessOnDemandServer) {
+	// If the following call pancis, it indicates UnimplementedAccessOnDemandServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&AccessOnDemand_ServiceDesc, srv)
+}
+
+func _AccessOnDemand_HandleIAM_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HandleIAMRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessOnDemandServer).HandleIAM(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AccessOnDemand_HandleIAM_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessOnDemandServer).HandleIAM(ctx, req.(*HandleIAMRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AccessOnDemand_CleanupIAM_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CleanupIAMRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessOnDemandServer).CleanupIAM(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AccessOnDemand_CleanupIAM_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessOnDemandServer).CleanupIAM(ctx, req.(*CleanupIAMRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AccessOnDemand_Validate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessOnDemandServer).Validate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AccessOnDemand_Validate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessOnDemandServer).Validate(ctx, req.(*ValidateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AccessOnDemand_ListGrants_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListGrantsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessOnDemandServer).ListGrants(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AccessOnDemand_ListGrants_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessOnDemandServer).ListGrants(ctx, req.(*ListGrantsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AccessOnDemand_ServiceDesc is the grpc.ServiceDesc for AccessOnDemand service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AccessOnDemand_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "aod.v1alpha1.AccessOnDemand",
+	HandlerType: (*AccessOnDemandServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "HandleIAM",
+			Handler:    _AccessOnDemand_HandleIAM_Handler,
+		},
+		{
+			MethodName: "CleanupIAM",
+			Handler:    _AccessOnDemand_CleanupIAM_Handler,
+		},
+		{
+			MethodName: "Validate",
+			Handler:    _AccessOnDemand_Validate_Handler,
+		},
+		{
+			MethodName: "ListGrants",
+			Handler:    _AccessOnDemand_ListGrants_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "aod/v1alpha1/aod.proto",
+}
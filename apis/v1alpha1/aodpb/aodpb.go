@@ -0,0 +1,19 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package aodpb contains the generated protobuf and gRPC types of the AOD API,
+// see protos/aod/v1alpha1/aod.proto.
+package aodpb
+
+//go:generate protoc -I ../../../protos --go_out=../../.. --go_opt=module=github.com/abcxyz/access-on-demand --go-grpc_out=../../.. --go-grpc_opt=module=github.com/abcxyz/access-on-demand aod/v1alpha1/aod.proto
@@ -0,0 +1,25 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+// CombinedRequest represents an IAM request and a tool request in one file,
+// the tool commands are run with the IAM bindings of the IAM request.
+type CombinedRequest struct {
+	// IAM request granting the access needed by the tool commands.
+	IAM *IAMRequest `yaml:"iam,omitempty"`
+
+	// Tool request run with the access granted by the IAM request.
+	Tool *ToolRequest `yaml:"tool,omitempty"`
+}
@@ -0,0 +1,141 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// PolicyConflict is a conflict between two policies of the same resource in a
+// request, which specify contradictory intent for the resource. The policies of
+// the same resource are handled separately, so a conflict would otherwise be
+// resolved by whichever policy is handled last.
+type PolicyConflict struct {
+	// Resource is the resource of the conflicting policies.
+	Resource string
+
+	// First and Second are the indices of the conflicting policies, First is
+	// less than Second.
+	First  int
+	Second int
+
+	// Role and Member are the role and member of the contradictory bindings, if
+	// the conflict is between bindings.
+	Role   string
+	Member string
+
+	// Reason describes the contradictory intent.
+	Reason string
+}
+
+// Error implements error.
+func (c *PolicyConflict) Error() string {
+	return fmt.Sprintf("resource %q conflicts with policies[%d]: %s", c.Resource, c.First, c.Reason)
+}
+
+// PolicyConflicts returns the conflicts between the policies of the same
+// resource, in the order of the policies. The policies of a resource conflict
+// if:
+//
+//   - They depend on different resources, so the resource is both handled
+//     before and after the other resources. Each policy is only compared with
+//     the first policy of its resource.
+//   - They bind the same role to the same member with different start offsets,
+//     so one binding would replace the other. Each policy is compared with all
+//     prior policies of its resource.
+func PolicyConflicts(ps []*ResourcePolicy) []*PolicyConflict {
+	prior := make(map[string][]int, len(ps))
+	var conflicts []*PolicyConflict
+	for i, p := range ps {
+		js := prior[p.Resource]
+		prior[p.Resource] = append(js, i)
+		if len(js) == 0 {
+			continue
+		}
+		if a, b := dependencySet(ps[js[0]]), dependencySet(p); !slices.Equal(a, b) {
+			conflicts = append(conflicts, &PolicyConflict{
+				Resource: p.Resource,
+				First:    js[0],
+				Second:   i,
+				Reason:   fmt.Sprintf("dependsOn %q differs from %q", b, a),
+			})
+		}
+		for _, j := range js {
+			conflicts = append(conflicts, bindingConflicts(ps, j, i)...)
+		}
+	}
+	return conflicts
+}
+
+// memberRole is a role bound to a single member.
+type memberRole struct {
+	role   string
+	member string
+}
+
+// bindingConflicts returns the conflicts between the bindings of policies
+// first and second that bind the same role to the same member with different
+// start offsets.
+func bindingConflicts(ps []*ResourcePolicy, first, second int) []*PolicyConflict {
+	offsets := make(map[memberRole]time.Duration)
+	for _, b := range ps[first].Bindings {
+		for _, r := range b.Roles() {
+			for _, m := range b.Members {
+				if _, ok := offsets[memberRole{r, m}]; !ok {
+					offsets[memberRole{r, m}] = b.StartOffset
+				}
+			}
+		}
+	}
+
+	var conflicts []*PolicyConflict
+	for _, b := range ps[second].Bindings {
+		for _, r := range b.Roles() {
+			for _, m := range b.Members {
+				k := memberRole{r, m}
+				o, ok := offsets[k]
+				if !ok || o == b.StartOffset {
+					continue
+				}
+				// Only report the first contradictory binding of the role and member.
+				delete(offsets, k)
+				conflicts = append(conflicts, &PolicyConflict{
+					Resource: ps[second].Resource,
+					First:    first,
+					Second:   second,
+					Role:     r,
+					Member:   m,
+					Reason:   fmt.Sprintf("role %s of %s starts at offset %s instead of %s", r, m, b.StartOffset, o),
+				})
+			}
+		}
+	}
+	return conflicts
+}
+
+// dependencySet returns the sorted unique resources the policy depends on,
+// excluding the resource of the policy itself.
+func dependencySet(p *ResourcePolicy) []string {
+	deps := make([]string, 0, len(p.DependsOn))
+	for _, d := range p.DependsOn {
+		if d != p.Resource {
+			deps = append(deps, d)
+		}
+	}
+	slices.Sort(deps)
+	return slices.Compact(deps)
+}
@@ -0,0 +1,109 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPolicyConflicts(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		policies []*ResourcePolicy
+		want     []*PolicyConflict
+	}{
+		{
+			name: "no_duplicate_resources",
+			policies: []*ResourcePolicy{
+				{Resource: "folders/bar", Bindings: []*Binding{{Members: []string{"user:a@example.com"}, Role: "roles/viewer"}}},
+				{Resource: "projects/baz", Bindings: []*Binding{{Members: []string{"user:a@example.com"}, Role: "roles/viewer", StartOffset: 30 * time.Minute}}},
+			},
+		},
+		{
+			name: "duplicate_resources_same_intent",
+			policies: []*ResourcePolicy{
+				{Resource: "projects/baz", Bindings: []*Binding{{Members: []string{"user:a@example.com"}, Role: "roles/viewer"}}},
+				{Resource: "projects/baz", Bindings: []*Binding{
+					{Members: []string{"user:a@example.com"}, Role: "roles/viewer"},
+					{Members: []string{"user:a@example.com"}, Role: "roles/run.developer", StartOffset: 30 * time.Minute},
+				}},
+			},
+		},
+		{
+			name: "different_depends_on",
+			policies: []*ResourcePolicy{
+				{Resource: "folders/bar"},
+				{Resource: "projects/baz", DependsOn: []string{"folders/bar"}},
+				{Resource: "projects/baz", DependsOn: []string{"projects/baz"}},
+			},
+			want: []*PolicyConflict{{
+				Resource: "projects/baz",
+				First:    1,
+				Second:   2,
+				Reason:   `dependsOn [] differs from ["folders/bar"]`,
+			}},
+		},
+		{
+			name: "different_start_offsets",
+			policies: []*ResourcePolicy{
+				{Resource: "projects/baz", Bindings: []*Binding{{Members: []string{"user:a@example.com"}, Role: "roles/viewer"}}},
+				{Resource: "folders/bar", Bindings: []*Binding{{Members: []string{"user:a@example.com"}, Role: "roles/viewer"}}},
+				{Resource: "projects/baz", Bindings: []*Binding{
+					{Members: []string{"user:a@example.com", "user:b@example.com"}, Role: "roles/viewer", StartOffset: 30 * time.Minute},
+					{Members: []string{"user:a@example.com"}, Role: "roles/viewer", StartOffset: time.Hour},
+				}},
+			},
+			want: []*PolicyConflict{{
+				Resource: "projects/baz",
+				First:    0,
+				Second:   2,
+				Role:     "roles/viewer",
+				Member:   "user:a@example.com",
+				Reason:   "role roles/viewer of user:a@example.com starts at offset 30m0s instead of 0s",
+			}},
+		},
+		{
+			name: "different_start_offsets_of_role_bundle",
+			policies: []*ResourcePolicy{
+				{Resource: "projects/baz", Bindings: []*Binding{{Members: []string{"user:a@example.com"}, Role: "roles/bigquery.dataViewer"}}},
+				{Resource: "projects/baz", Bindings: []*Binding{{Members: []string{"user:a@example.com"}, RoleBundle: "bq-read", StartOffset: time.Hour}}},
+			},
+			want: []*PolicyConflict{{
+				Resource: "projects/baz",
+				First:    0,
+				Second:   1,
+				Role:     "roles/bigquery.dataViewer",
+				Member:   "user:a@example.com",
+				Reason:   "role roles/bigquery.dataViewer of user:a@example.com starts at offset 1h0m0s instead of 0s",
+			}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := PolicyConflicts(tc.policies)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Process(%+v) got conflicts diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
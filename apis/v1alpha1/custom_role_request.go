@@ -0,0 +1,31 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+// CustomRoleRequest requests a temporary custom role of the permissions, for
+// when no predefined role is narrow enough. The role is created in the
+// resource, bound to the members for the duration of the request, and deleted
+// once it expires.
+type CustomRoleRequest struct {
+	// Resource to create the custom role in and bind it on, an organization or
+	// a project, since custom roles cannot be created in folders.
+	Resource string `yaml:"resource,omitempty"`
+
+	// Permissions of the custom role, e.g. "storage.objects.get".
+	Permissions []string `yaml:"permissions,omitempty"`
+
+	// Members to bind the custom role to, e.g. "user:alice@example.com".
+	Members []string `yaml:"members,omitempty"`
+}
@@ -0,0 +1,73 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"fmt"
+	"slices"
+)
+
+// PolicyLevels groups the indices of the policies into levels by their
+// dependencies, so that the policies in a level only depend on the policies in
+// the previous levels. The indices in a level are in the order of the
+// policies. Dependencies on resources not in the policies, or on the resource
+// of the policy itself, are ignored. It returns an error if the dependencies
+// have a cycle.
+func PolicyLevels(ps []*ResourcePolicy) ([][]int, error) {
+	byResource := make(map[string][]int, len(ps))
+	for i, p := range ps {
+		byResource[p.Resource] = append(byResource[p.Resource], i)
+	}
+
+	// The indices of the policies each policy depends on.
+	deps := make([][]int, len(ps))
+	for i, p := range ps {
+		for _, d := range p.DependsOn {
+			if d == p.Resource {
+				continue
+			}
+			deps[i] = append(deps[i], byResource[d]...)
+		}
+	}
+
+	var levels [][]int
+	done := make([]bool, len(ps))
+	for remaining := len(ps); remaining > 0; {
+		var level []int
+		for i := range ps {
+			if done[i] {
+				continue
+			}
+			if !slices.ContainsFunc(deps[i], func(j int) bool { return !done[j] }) {
+				level = append(level, i)
+			}
+		}
+		if len(level) == 0 {
+			var cycle []string
+			for i, p := range ps {
+				if !done[i] {
+					cycle = append(cycle, p.Resource)
+				}
+			}
+			return nil, fmt.Errorf("dependency cycle between resources %q", cycle)
+		}
+		for _, i := range level {
+			done[i] = true
+		}
+		remaining -= len(level)
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestPolicyLevels(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		policies   []*ResourcePolicy
+		wantLevels [][]int
+		wantErr    string
+	}{
+		{
+			name: "no_dependencies",
+			policies: []*ResourcePolicy{
+				{Resource: "folders/foo"},
+				{Resource: "projects/bar"},
+			},
+			wantLevels: [][]int{{0, 1}},
+		},
+		{
+			name: "dependencies",
+			policies: []*ResourcePolicy{
+				{Resource: "projects/bar", DependsOn: []string{"folders/foo"}},
+				{Resource: "projects/baz", DependsOn: []string{"projects/bar", "folders/foo"}},
+				{Resource: "folders/foo"},
+				{Resource: "projects/qux"},
+			},
+			wantLevels: [][]int{{2, 3}, {0}, {1}},
+		},
+		{
+			name: "duplicate_resources",
+			policies: []*ResourcePolicy{
+				{Resource: "projects/bar", DependsOn: []string{"folders/foo"}},
+				{Resource: "folders/foo"},
+				{Resource: "folders/foo"},
+			},
+			wantLevels: [][]int{{1, 2}, {0}},
+		},
+		{
+			name: "ignored_dependencies",
+			policies: []*ResourcePolicy{
+				{Resource: "projects/bar", DependsOn: []string{"projects/bar", "folders/unknown"}},
+			},
+			wantLevels: [][]int{{0}},
+		},
+		{
+			name: "cycle",
+			policies: []*ResourcePolicy{
+				{Resource: "folders/foo"},
+				{Resource: "projects/bar", DependsOn: []string{"projects/baz"}},
+				{Resource: "projects/baz", DependsOn: []string{"projects/bar"}},
+			},
+			wantErr: `dependency cycle between resources ["projects/bar" "projects/baz"]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			gotLevels, err := PolicyLevels(tc.policies)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error: %s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantLevels, gotLevels); diff != "" {
+				t.Errorf("Process(%+v) got levels diff (-want, +got): %s", tc.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"fmt"
+)
+
+// FieldError is a validation error of a field in a request. The Path is the
+// YAML path of the field, e.g. "policies[2].bindings[0].members[1]", which can
+// be used to locate the field in the request file.
+type FieldError struct {
+	// Path is the YAML path of the field.
+	Path string
+
+	// Line and Column are the position of the field in the request file, they
+	// are zero if the position is unknown.
+	Line   int
+	Column int
+
+	// Err is the underlying validation error.
+	Err error
+}
+
+// Error implements error.
+func (e *FieldError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s at line %d, column %d: %v", e.Path, e.Line, e.Column, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying validation error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// fieldErrorf returns a FieldError at the given path with a formatted error.
+func fieldErrorf(path, format string, a ...any) error {
+	return &FieldError{Path: path, Err: fmt.Errorf(format, a...)}
+}
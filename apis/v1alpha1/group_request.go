@@ -0,0 +1,28 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+// GroupRequest requests temporary memberships of a Google Group, for
+// organizations granting access via groups instead of direct IAM bindings. The
+// members are added to the group with an expiry, and Cloud Identity removes
+// them once it passes.
+type GroupRequest struct {
+	// Group to add the members to, by its email, e.g. "prod-admins@example.com".
+	Group string `yaml:"group,omitempty"`
+
+	// Members to add to the group, users or service accounts, e.g.
+	// "user:alice@example.com".
+	Members []string `yaml:"members,omitempty"`
+}
@@ -0,0 +1,85 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import "time"
+
+// IAMPolicyDiff contains the IAM binding changes to be made to the IAM policy
+// of a resource.
+type IAMPolicyDiff struct {
+	// Resource is the name of the GCP resource of the IAM policy, such as
+	// "projects/foo" or "buckets/foo", see resource.Parse for the formats.
+	Resource string `json:"resource" yaml:"resource"`
+
+	// Added contains the bindings to be added to the IAM policy.
+	Added []*BindingChange `json:"added,omitempty" yaml:"added,omitempty"`
+
+	// Removed contains the bindings to be removed from the IAM policy, such as
+	// expired AOD bindings.
+	Removed []*BindingChange `json:"removed,omitempty" yaml:"removed,omitempty"`
+}
+
+// BindingChange is an IAM binding of a role to a single member, with its
+// optional condition.
+type BindingChange struct {
+	// Role of the binding.
+	Role string `json:"role" yaml:"role"`
+
+	// Member of the binding, for example "user:alice@example.com".
+	Member string `json:"member" yaml:"member"`
+
+	// ConditionTitle is the title of the binding condition, if any.
+	ConditionTitle string `json:"conditionTitle,omitempty" yaml:"conditionTitle,omitempty"`
+
+	// ConditionExpression is the expression of the binding condition, if any.
+	ConditionExpression string `json:"conditionExpression,omitempty" yaml:"conditionExpression,omitempty"`
+}
+
+// Statuses of requested bindings in the live IAM policies.
+const (
+	// BindingStatusPresent is the status of a requested binding that has an
+	// active AOD binding with the expected expiry, or with any expiry if the
+	// expiry is not checked.
+	BindingStatusPresent = "PRESENT"
+
+	// BindingStatusMissing is the status of a requested binding that has no
+	// active AOD binding.
+	BindingStatusMissing = "MISSING"
+
+	// BindingStatusDifferentExpiry is the status of a requested binding that has
+	// active AOD bindings, none of which has the expected expiry.
+	BindingStatusDifferentExpiry = "DIFFERENT_EXPIRY"
+)
+
+// BindingStatus is the status of a requested IAM binding of a role to a single
+// member in the live IAM policy of a resource.
+type BindingStatus struct {
+	// Resource is the name of the GCP resource the binding is requested on, such
+	// as "projects/foo".
+	Resource string `json:"resource" yaml:"resource"`
+
+	// Role of the binding.
+	Role string `json:"role" yaml:"role"`
+
+	// Member of the binding, for example "user:alice@example.com".
+	Member string `json:"member" yaml:"member"`
+
+	// Status is one of "PRESENT", "MISSING" and "DIFFERENT_EXPIRY".
+	Status string `json:"status" yaml:"status"`
+
+	// Expiry is the latest expiry of the active AOD bindings of the role and
+	// member, if any.
+	Expiry *time.Time `json:"expiry,omitempty" yaml:"expiry,omitempty"`
+}
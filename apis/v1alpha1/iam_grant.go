@@ -0,0 +1,34 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import "time"
+
+// ActiveGrant is an active IAM binding of a role to a single member added by
+// AOD, which has not expired yet.
+type ActiveGrant struct {
+	// Resource is the name of the GCP resource the binding is on, in one of the
+	// formats accepted by resource.Parse, such as "projects/foo".
+	Resource string `json:"resource" yaml:"resource"`
+
+	// Role of the binding.
+	Role string `json:"role" yaml:"role"`
+
+	// Member of the binding, for example "user:alice@example.com".
+	Member string `json:"member" yaml:"member"`
+
+	// Expiry is the expiration time of the binding.
+	Expiry time.Time `json:"expiry" yaml:"expiry"`
+}
@@ -14,21 +14,62 @@
 
 package v1alpha1
 
+import "time"
+
 // IAMRequest represents a request to update IAM policies.
 type IAMRequest struct {
 	// List of ResourcePolicy, each specifies the IAM principals/members to role
 	// bindings to be added for a GCP resource IAM policy.
 	ResourcePolicies []*ResourcePolicy `yaml:"policies,omitempty"`
+
+	// List of DenyPolicy, each specifies the temporary IAM deny rules to be
+	// created for a GCP resource, which block access instead of granting it.
+	DenyPolicies []*DenyPolicy `yaml:"denyPolicies,omitempty"`
+
+	// Recurring restricts the bindings to recurring time windows within the
+	// duration of the request, such as business hours, for standing but
+	// bounded access.
+	Recurring *Recurring `yaml:"recurring,omitempty"`
+
+	// Entitlements are references to the named entitlements of the entitlement
+	// catalog of the AOD deployment, which are expanded to the policies of
+	// their resources and roles before the request is validated.
+	Entitlements []*EntitlementRef `yaml:"entitlements,omitempty"`
+}
+
+// EntitlementRef requests a named entitlement of the entitlement catalog for
+// the members.
+type EntitlementRef struct {
+	// Entitlement is the name of the entitlement in the catalog, e.g.
+	// "bq-oncall".
+	Entitlement string `yaml:"entitlement,omitempty"`
+
+	// Members is a list of IAM principals to be granted the roles of the
+	// entitlement, e.g. ["user:alice@example.com"].
+	Members []string `yaml:"members,omitempty"`
+
+	// Duration is the requested duration of the entitlement, e.g. "2h", which
+	// must not exceed the max duration of the entitlement. The request must be
+	// handled with a duration no longer than it.
+	Duration time.Duration `yaml:"duration,omitempty"`
 }
 
 // ResourcePolicy specifies the IAM principals/members to role bindings to be
 // added for a GCP resource IAM policy.
 type ResourcePolicy struct {
-	// Resource represents one of GCP organization, folder, and project.
+	// Resource is the name of the GCP resource whose IAM policy is updated, such
+	// as "organizations/123", "folders/456", "projects/foo", "buckets/foo" or a
+	// full resource name like "//pubsub.googleapis.com/projects/foo/topics/bar".
+	// See resource.Parse for all accepted formats.
 	Resource string `yaml:"resource,omitempty"`
 
 	// Bindings contains a list of IAM principals/members to role bindings.
 	Bindings []*Binding `yaml:"bindings,omitempty"`
+
+	// DependsOn is a list of the resources of other policies in the request to
+	// be handled before this policy, e.g. a folder to be granted before its
+	// projects. The policy is skipped if any of them fails.
+	DependsOn []string `yaml:"dependsOn,omitempty"`
 }
 
 // Binding associates IAM principals/members with a role.
@@ -41,4 +82,41 @@ type Binding struct {
 	// Editor (roles/editor), and Viewer (roles/viewer) are not allowed since
 	// conditional role bindings do not work with basic roles.
 	Role string `yaml:"role,omitempty"`
+
+	// RoleBundle is the name of a curated read-only set of roles to be assigned
+	// to Members instead of Role, e.g. "bq-read". See role_bundles.yaml for the
+	// available role bundles.
+	RoleBundle string `yaml:"roleBundle,omitempty"`
+
+	// StartOffset delays the start of the binding by the offset from the
+	// start time of the request, e.g. "30m", so that multi-phase operations
+	// can be encoded in one request. The binding expires with the request.
+	StartOffset time.Duration `yaml:"startOffset,omitempty"`
+}
+
+// DenyPolicy specifies the temporary IAM deny rules to be created for a GCP
+// resource. The rules are created as an IAM deny policy attached to the
+// resource, which is deleted once the request expires.
+type DenyPolicy struct {
+	// Resource is the organization, folder or project the deny policy is
+	// attached to, such as "organizations/123", "folders/456" or "projects/foo".
+	Resource string `yaml:"resource,omitempty"`
+
+	// Rules contains a list of IAM deny rules.
+	Rules []*DenyRule `yaml:"rules,omitempty"`
+}
+
+// DenyRule denies IAM principals/members the permissions.
+type DenyRule struct {
+	// DeniedMembers is a list of IAM principals to be denied the permissions,
+	// in the same format as Binding.Members, e.g. ["group:oncall@example.com"].
+	DeniedMembers []string `yaml:"deniedMembers,omitempty"`
+
+	// ExceptionMembers is a list of IAM principals not to be denied, e.g. the
+	// members of a denied group who still need access.
+	ExceptionMembers []string `yaml:"exceptionMembers,omitempty"`
+
+	// DeniedPermissions is a list of permissions to be denied, in the format
+	// of the IAM deny API, e.g. "cloudresourcemanager.googleapis.com/projects.delete".
+	DeniedPermissions []string `yaml:"deniedPermissions,omitempty"`
 }
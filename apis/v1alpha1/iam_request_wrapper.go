@@ -29,4 +29,18 @@ type IAMRequestWrapper struct {
 	// Start time of the IAM permission lifecycle, StartTime + Duration is when
 	// the permission will expire.
 	StartTime time.Time
+
+	// Optional requester of the IAM request, e.g. "user:alice@example.com".
+	Requester string `yaml:"requester,omitempty"`
+
+	// Optional approvers of the IAM request.
+	Approvers []string `yaml:"approvers,omitempty"`
+
+	// Optional source of the IAM request, e.g. the URL of the pull request or
+	// the ticket the request is from.
+	Source string `yaml:"source,omitempty"`
+
+	// Optional SHA256 hash of the IAM request file in hex, which is computed
+	// from the file and not serialized.
+	RequestHash string `yaml:"-"`
 }
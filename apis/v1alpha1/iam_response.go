@@ -21,6 +21,19 @@ type IAMResponse struct {
 	// IAM policy of the resource.
 	Policy *iampb.Policy
 
-	// Resource represents one of GCP organization, folder, and project.
+	// Resource is the name of the GCP resource of the IAM policy, in one of the
+	// formats accepted by resource.Parse, such as "projects/foo".
 	Resource string
+
+	// Warnings are the errors that did not stop the IAM policy update, such as
+	// failures to check the expiry of existing AOD bindings.
+	Warnings []string `yaml:"warnings,omitempty"`
+
+	// Skipped is the reason the IAM policy was not updated, such as the resource
+	// is pending deletion.
+	Skipped string `yaml:"skipped,omitempty"`
+
+	// Grants are the names of the PAM grants requested for the resource, when
+	// the request is handled with PAM instead of editing the IAM policy.
+	Grants []string `yaml:"grants,omitempty"`
 }
@@ -0,0 +1,26 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+// PluginRequest represents a request of a custom kind, such as access to an
+// internal database or VPN, which is validated, handled and cleaned up by the
+// plugin of the kind.
+type PluginRequest struct {
+	// Kind of the request, the name of the plugin handling it, e.g. "vpn".
+	Kind string `yaml:"kind,omitempty"`
+
+	// Spec of the request, which is passed as is to the plugin.
+	Spec map[string]any `yaml:"spec,omitempty"`
+}
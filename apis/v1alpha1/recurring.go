@@ -0,0 +1,150 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Recurring specifies the recurring time windows the bindings of a request
+// grant access in, e.g. Monday to Friday from 09:00 to 17:00.
+type Recurring struct {
+	// Days of the week, each a day such as "Mon" or an inclusive range of days
+	// such as "Mon-Fri". Default is every day.
+	Days []string `yaml:"days,omitempty"`
+
+	// Hours of the day in the format of "HH:MM-HH:MM", e.g. "09:00-17:00", the
+	// end is exclusive and can be "24:00". Default is all day.
+	Hours string `yaml:"hours,omitempty"`
+
+	// TimeZone of the days and hours, e.g. "America/Los_Angeles". Default is
+	// "UTC".
+	TimeZone string `yaml:"timeZone,omitempty"`
+
+	// Until is the last day of the access in the format of "YYYY-MM-DD", the
+	// bindings expire at the end of the day if it is before the expiry of the
+	// request.
+	Until string `yaml:"until,omitempty"`
+}
+
+// weekdays are the abbreviations of the days of the week, indexed by
+// time.Weekday.
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Weekdays returns the sorted days of the week of the recurring windows, or
+// nil if the windows are on every day.
+func (r *Recurring) Weekdays() ([]time.Weekday, error) {
+	var days []time.Weekday
+	for _, d := range r.Days {
+		first, last, isRange := strings.Cut(d, "-")
+		from, err := parseWeekday(first)
+		if err != nil {
+			return nil, err
+		}
+		to := from
+		if isRange {
+			if to, err = parseWeekday(last); err != nil {
+				return nil, err
+			}
+		}
+		// Ranges may wrap around the week, e.g. "Fri-Mon".
+		for day := from; ; day = (day + 1) % 7 {
+			days = append(days, day)
+			if day == to {
+				break
+			}
+		}
+	}
+	slices.Sort(days)
+	return slices.Compact(days), nil
+}
+
+// parseWeekday parses the day of the week, such as "Mon" or "Monday".
+func parseWeekday(s string) (time.Weekday, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	for i, d := range weekdays {
+		if v == d || v == strings.ToLower(time.Weekday(i).String()) {
+			return time.Weekday(i), nil
+		}
+	}
+	return 0, fmt.Errorf(`day %q is not a day of the week (expected e.g. "Mon" or "Mon-Fri")`, s)
+}
+
+// HourRange returns the start and the exclusive end of the recurring windows
+// as offsets from midnight, which are 0 and 24h if the windows are all day.
+func (r *Recurring) HourRange() (start, end time.Duration, err error) {
+	if r.Hours == "" {
+		return 0, 24 * time.Hour, nil
+	}
+	first, last, ok := strings.Cut(r.Hours, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf(`hours %q is not a valid format (expected "HH:MM-HH:MM")`, r.Hours)
+	}
+	if start, err = parseTimeOfDay(first); err != nil {
+		return 0, 0, fmt.Errorf("hours %q is not valid: %w", r.Hours, err)
+	}
+	if end, err = parseTimeOfDay(last); err != nil {
+		return 0, 0, fmt.Errorf("hours %q is not valid: %w", r.Hours, err)
+	}
+	if start >= end {
+		return 0, 0, fmt.Errorf("hours %q must start before they end", r.Hours)
+	}
+	return start, end, nil
+}
+
+// parseTimeOfDay parses the time of the day in the format of "HH:MM" as an
+// offset from midnight, up to "24:00".
+func parseTimeOfDay(s string) (time.Duration, error) {
+	var h, m int
+	if n, err := fmt.Sscanf(s, "%02d:%02d", &h, &m); err != nil || n != 2 || len(s) != len("15:04") {
+		return 0, fmt.Errorf(`time %q is not in the format of "HH:MM"`, s)
+	}
+	if h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m > 0) {
+		return 0, fmt.Errorf("time %q is not a time of the day", s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// Location returns the time zone of the recurring windows.
+func (r *Recurring) Location() (*time.Location, error) {
+	if r.TimeZone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(r.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("time zone %q is not valid: %w", r.TimeZone, err)
+	}
+	return loc, nil
+}
+
+// UntilTime returns the end of the last day of the access in the time zone,
+// or the zero time if the access is not bounded by a day.
+func (r *Recurring) UntilTime() (time.Time, error) {
+	if r.Until == "" {
+		return time.Time{}, nil
+	}
+	loc, err := r.Location()
+	if err != nil {
+		return time.Time{}, err
+	}
+	day, err := time.ParseInLocation(time.DateOnly, r.Until, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf(`until %q is not a valid date (expected "YYYY-MM-DD")`, r.Until)
+	}
+	return day.AddDate(0, 0, 1), nil
+}
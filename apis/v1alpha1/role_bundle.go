@@ -0,0 +1,76 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	_ "embed"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed role_bundles.yaml
+var roleBundlesYAML []byte
+
+// roleBundles parses the embedded role bundles once.
+var roleBundles = sync.OnceValue(func() map[string]*RoleBundle {
+	var bundles map[string]*RoleBundle
+	if err := yaml.Unmarshal(roleBundlesYAML, &bundles); err != nil {
+		panic(fmt.Sprintf("failed to parse embedded role bundles: %v", err))
+	}
+	return bundles
+})
+
+// RoleBundle is a curated read-only set of roles, which can be selected with
+// "roleBundle" in a Binding instead of a single role.
+type RoleBundle struct {
+	// Description of the role bundle.
+	Description string `yaml:"description"`
+
+	// ResourceTypes are the resource types the role bundle can be granted on,
+	// e.g. "projects".
+	ResourceTypes []string `yaml:"resourceTypes"`
+
+	// Roles in the role bundle.
+	Roles []string `yaml:"roles"`
+}
+
+// LookupRoleBundle returns the role bundle of the given name.
+func LookupRoleBundle(name string) (*RoleBundle, bool) {
+	b, ok := roleBundles()[name]
+	return b, ok
+}
+
+// RoleBundleNames returns the sorted names of all role bundles.
+func RoleBundleNames() []string {
+	return slices.Sorted(maps.Keys(roleBundles()))
+}
+
+// Roles returns the roles of the binding, which are the roles of the role
+// bundle if it is set, or the role otherwise. It returns nil if the role
+// bundle is unknown.
+func (b *Binding) Roles() []string {
+	if b.RoleBundle == "" {
+		return []string{b.Role}
+	}
+	rb, ok := LookupRoleBundle(b.RoleBundle)
+	if !ok {
+		return nil
+	}
+	return rb.Roles
+}
@@ -0,0 +1,87 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRoleBundles(t *testing.T) {
+	t.Parallel()
+
+	names := RoleBundleNames()
+	if len(names) == 0 {
+		t.Fatal("got no role bundles, want at least one")
+	}
+	for _, name := range names {
+		rb, _ := LookupRoleBundle(name)
+		if rb.Description == "" {
+			t.Errorf("role bundle %q has no description", name)
+		}
+		if len(rb.ResourceTypes) == 0 {
+			t.Errorf("role bundle %q has no resource types", name)
+		}
+		if len(rb.Roles) == 0 {
+			t.Errorf("role bundle %q has no roles", name)
+		}
+		for _, r := range rb.Roles {
+			if !strings.HasPrefix(r, "roles/") {
+				t.Errorf("role bundle %q has invalid role %q", name, r)
+			}
+			switch r {
+			case "roles/owner", "roles/editor", "roles/viewer":
+				t.Errorf("role bundle %q has basic role %q", name, r)
+			}
+		}
+	}
+}
+
+func TestBindingRoles(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		binding *Binding
+		want    []string
+	}{
+		{
+			name:    "role",
+			binding: &Binding{Role: "roles/bigquery.dataViewer"},
+			want:    []string{"roles/bigquery.dataViewer"},
+		},
+		{
+			name:    "role_bundle",
+			binding: &Binding{RoleBundle: "bq-read"},
+			want:    []string{"roles/bigquery.dataViewer", "roles/bigquery.jobUser", "roles/bigquery.metadataViewer"},
+		},
+		{
+			name:    "unknown_role_bundle",
+			binding: &Binding{RoleBundle: "foo"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tc.want, tc.binding.Roles()); diff != "" {
+				t.Errorf("Roles() got diff (-want, +got): %v", diff)
+			}
+		})
+	}
+}
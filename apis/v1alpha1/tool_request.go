@@ -21,4 +21,19 @@ type ToolRequest struct {
 
 	// List of commands without tool name.
 	Do []string `yaml:"do,omitempty"`
+
+	// CLI is the legacy name of Tool, kept to read the request files of the
+	// old schema. It is mapped to Tool during validation.
+	//
+	// Deprecated: Use Tool instead.
+	CLI string `yaml:"cli,omitempty"`
+}
+
+// Warnings returns the warnings of the request, such as the deprecated fields
+// set in it.
+func (r *ToolRequest) Warnings() []string {
+	if r.CLI == "" {
+		return nil
+	}
+	return []string{`"cli" is deprecated, use "tool" instead`}
 }
@@ -0,0 +1,214 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+)
+
+// Default options of validating requests.
+var (
+	defaultMemberTypes = []string{"user"}
+	defaultTools       = []string{defaultTool}
+)
+
+// memberTypes are the member types of IAM bindings that can be allowed, the
+// ones of members with emails.
+var memberTypes = []string{"user", "group", "serviceAccount"}
+
+// ValidateOptions are the options of validating requests, for policies of the
+// AOD deployment beyond the format of the requests. The zero value and nil
+// validate with the defaults.
+type ValidateOptions struct {
+	// AllowedMemberTypes are the types of the members of IAM bindings, any of
+	// "user", "group" and "serviceAccount". Default is "user".
+	AllowedMemberTypes []string
+
+	// AllowedTools are the tools of tool requests. Default is "gcloud".
+	AllowedTools []string
+
+	// AllowedRoles and DeniedRoles are the patterns of the roles of IAM
+	// bindings, in the syntax of path.Match, e.g. "roles/storage.*". A role is
+	// allowed if it matches any of the allowed roles, or none is set, and does
+	// not match any of the denied roles. The roles of role bundles are checked
+	// too.
+	AllowedRoles []string
+	DeniedRoles  []string
+
+	// AllowedResources are the patterns of the resources of IAM requests, in
+	// the syntax of path.Match, e.g. "projects/dev-*" or "folders/123". All
+	// resources are allowed if empty. Resources are matched by name only,
+	// allowing a folder does not allow the folders and projects in it.
+	AllowedResources []string
+
+	// RolePolicies are the policies of the roles of IAM bindings on the
+	// resources they apply to, checked in addition to AllowedRoles and
+	// DeniedRoles.
+	RolePolicies []*RolePolicy
+
+	// MaxPolicies is the max number of resource policies of IAM requests.
+	// Default is 0, no limit.
+	MaxPolicies int
+
+	// MaxBindings is the max number of bindings of a resource policy. Default
+	// is 0, no limit.
+	MaxBindings int
+
+	// MaxMembers is the max number of members of a binding. Default is 0, no
+	// limit.
+	MaxMembers int
+
+	// MaxCommands is the max number of do commands of tool requests. Default is
+	// 0, no limit.
+	MaxCommands int
+
+	// CustomCheck is called with the IAM requests that are otherwise valid,
+	// for custom checks such as the rules of a policy file.
+	CustomCheck func(r *IAMRequest) error
+}
+
+// RolePolicy is a policy of the roles that can be requested on the resources
+// it applies to.
+type RolePolicy struct {
+	// Resources are the patterns of the resources the policy applies to, in
+	// the syntax of path.Match, e.g. "projects/prod-*". It applies to all
+	// resources if empty.
+	Resources []string `yaml:"resources,omitempty"`
+
+	// Allow are the patterns of the roles that can be requested, all roles can
+	// be requested if empty. See ValidateOptions.AllowedRoles.
+	Allow []string `yaml:"allow,omitempty"`
+
+	// Deny are the patterns of the roles that cannot be requested, which take
+	// precedence over Allow.
+	Deny []string `yaml:"deny,omitempty"`
+}
+
+// Check returns an error if the options are not valid.
+func (o *ValidateOptions) Check() (retErr error) {
+	for _, t := range o.AllowedMemberTypes {
+		if !slices.Contains(memberTypes, t) {
+			retErr = errors.Join(retErr, fmt.Errorf("allowed member type %q isn't one of %q", t, memberTypes))
+		}
+	}
+	for _, p := range slices.Concat(o.AllowedRoles, o.DeniedRoles) {
+		if _, err := path.Match(p, ""); err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("role pattern %q is not valid: %w", p, err))
+		}
+	}
+	for _, p := range o.AllowedResources {
+		if _, err := path.Match(p, ""); err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("resource pattern %q is not valid: %w", p, err))
+		}
+	}
+	for i, rp := range o.RolePolicies {
+		for _, p := range slices.Concat(rp.Resources, rp.Allow, rp.Deny) {
+			if _, err := path.Match(p, ""); err != nil {
+				retErr = errors.Join(retErr, fmt.Errorf("role policy %d pattern %q is not valid: %w", i, p, err))
+			}
+		}
+	}
+	for _, c := range []struct {
+		name string
+		n    int
+	}{
+		{"max policies", o.MaxPolicies},
+		{"max bindings", o.MaxBindings},
+		{"max members", o.MaxMembers},
+		{"max commands", o.MaxCommands},
+	} {
+		if c.n < 0 {
+			retErr = errors.Join(retErr, fmt.Errorf("%s must not be negative, got %d", c.name, c.n))
+		}
+	}
+	return retErr
+}
+
+// memberTypes returns the allowed member types.
+func (o *ValidateOptions) memberTypes() []string {
+	if len(o.AllowedMemberTypes) == 0 {
+		return defaultMemberTypes
+	}
+	return o.AllowedMemberTypes
+}
+
+// tools returns the allowed tools.
+func (o *ValidateOptions) tools() []string {
+	if len(o.AllowedTools) == 0 {
+		return defaultTools
+	}
+	return o.AllowedTools
+}
+
+// checkResource returns an error if the resource is not allowed.
+func (o *ValidateOptions) checkResource(resource string) error {
+	if len(o.AllowedResources) > 0 && matchPattern(o.AllowedResources, resource) == "" {
+		return fmt.Errorf("resource %q is not allowed (must match one of %q)", resource, o.AllowedResources)
+	}
+	return nil
+}
+
+// checkRole returns an error if the role is not allowed on the resource.
+func (o *ValidateOptions) checkRole(resource, role string) error {
+	if err := checkRolePatterns(role, o.AllowedRoles, o.DeniedRoles); err != nil {
+		return err
+	}
+	for _, rp := range o.RolePolicies {
+		if len(rp.Resources) > 0 && matchPattern(rp.Resources, resource) == "" {
+			continue
+		}
+		if err := checkRolePatterns(role, rp.Allow, rp.Deny); err != nil {
+			if len(rp.Resources) > 0 {
+				return fmt.Errorf("%w on %s", err, resource)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// checkRolePatterns returns an error if the role matches any of the denied
+// patterns, or does not match any of the allowed patterns if there is any.
+func checkRolePatterns(role string, allowed, denied []string) error {
+	if p := matchPattern(denied, role); p != "" {
+		return fmt.Errorf("role %q is denied (matches %q)", role, p)
+	}
+	if len(allowed) > 0 && matchPattern(allowed, role) == "" {
+		return fmt.Errorf("role %q is not allowed (must match one of %q)", role, allowed)
+	}
+	return nil
+}
+
+// matchPattern returns the first of the patterns matching the name, or empty
+// if none matches. The patterns are checked by Check.
+func matchPattern(patterns []string, name string) string {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return p
+		}
+	}
+	return ""
+}
+
+// orDefault returns the options, or the zero options if it is nil.
+func (o *ValidateOptions) orDefault() *ValidateOptions {
+	if o == nil {
+		return &ValidateOptions{}
+	}
+	return o
+}
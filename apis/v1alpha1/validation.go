@@ -19,7 +19,11 @@ import (
 	"errors"
 	"fmt"
 	"net/mail"
+	"regexp"
+	"slices"
 	"strings"
+
+	"github.com/abcxyz/access-on-demand/pkg/resource"
 )
 
 var (
@@ -31,74 +35,452 @@ var (
 		'<': {},
 		';': {},
 	}
+
+	// pluginKindRegexp matches the kinds of plugin requests, which are part of
+	// the plugin executable names.
+	pluginKindRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]{0,62}$`)
+
+	// permissionRegexp matches IAM permissions in the format of
+	// "<service>.<resource>.<verb>", e.g. "storage.objects.get".
+	permissionRegexp = regexp.MustCompile(`^[a-z][a-zA-Z0-9]*(\.[a-zA-Z0-9]+){2,}$`)
+
+	// denyPermissionRegexp matches IAM permissions in the format of the IAM
+	// deny API, "<service FQDN>/<resource>.<verb>", e.g.
+	// "iam.googleapis.com/roles.list".
+	denyPermissionRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]*(\.[a-z0-9-]+)*\.googleapis\.com/[a-zA-Z0-9]+(\.[a-zA-Z0-9]+)+$`)
+
+	// predefinedRoleRegexp matches predefined and basic roles, e.g.
+	// "roles/viewer".
+	predefinedRoleRegexp = regexp.MustCompile(`^roles/[a-zA-Z0-9_.]+$`)
+
+	// customRoleRegexp matches custom roles of projects and organizations, e.g.
+	// "projects/foo/roles/myRole", capturing the type of the parent.
+	customRoleRegexp = regexp.MustCompile(`^(projects|organizations)/[^/]+/roles/[a-zA-Z0-9_.]{3,64}$`)
 )
 
-// ValidateIAMRequest checks if the IAMRequest is valid.
-func ValidateIAMRequest(r *IAMRequest) (retErr error) {
-	if len(r.ResourcePolicies) == 0 {
+// MaxCustomRolePermissions is the max number of permissions of a custom role.
+const MaxCustomRolePermissions = 3000
+
+// ValidateIAMRequest checks if the IAMRequest is valid, and satisfies the
+// options if they are not nil.
+func ValidateIAMRequest(r *IAMRequest, opts *ValidateOptions) (retErr error) {
+	opts = opts.orDefault()
+	if err := opts.Check(); err != nil {
+		return fmt.Errorf("invalid validate options: %w", err)
+	}
+
+	// Entitlements are expanded to policies with the entitlement catalog
+	// before the request is validated.
+	if len(r.Entitlements) > 0 {
+		retErr = fieldErrorf("entitlements", "entitlements must be expanded with an entitlement catalog")
+		return
+	}
+	if len(r.ResourcePolicies) == 0 && len(r.DenyPolicies) == 0 {
 		retErr = fmt.Errorf("policies not found")
 		return
 	}
-	for _, s := range r.ResourcePolicies {
-		// Check if resource type is valid.
-		resourceType := strings.Split(s.Resource, "/")[0]
-		switch resourceType {
-		case "organizations", "folders", "projects":
-			// Ok.
-		default:
-			retErr = errors.Join(retErr, fmt.Errorf("resource %q isn't one of [organizations, folders, projects]", s.Resource))
+	if opts.MaxPolicies > 0 && len(r.ResourcePolicies) > opts.MaxPolicies {
+		retErr = errors.Join(retErr, fieldErrorf("policies",
+			"request can have at most %d policies, got %d", opts.MaxPolicies, len(r.ResourcePolicies)))
+	}
+	for i, s := range r.ResourcePolicies {
+		if opts.MaxBindings > 0 && len(s.Bindings) > opts.MaxBindings {
+			retErr = errors.Join(retErr, fieldErrorf(fmt.Sprintf("policies[%d].bindings", i),
+				"policy can have at most %d bindings, got %d", opts.MaxBindings, len(s.Bindings)))
+		}
+
+		// Check if resource name is valid.
+		resourceType := resource.TypeOf(s.Resource)
+		if _, err := resource.Parse(s.Resource); err != nil {
+			retErr = errors.Join(retErr, &FieldError{
+				Path: fmt.Sprintf("policies[%d].resource", i),
+				Err:  err,
+			})
+		} else if err := opts.checkResource(s.Resource); err != nil {
+			retErr = errors.Join(retErr, &FieldError{
+				Path: fmt.Sprintf("policies[%d].resource", i),
+				Err:  err,
+			})
+		}
+
+		// Check if the dependencies are other resources in the request.
+		for j, d := range s.DependsOn {
+			path := fmt.Sprintf("policies[%d].dependsOn[%d]", i, j)
+			switch {
+			case d == s.Resource:
+				retErr = errors.Join(retErr, fieldErrorf(path, "resource %q cannot depend on itself", d))
+			case !slices.ContainsFunc(r.ResourcePolicies, func(p *ResourcePolicy) bool { return p.Resource == d }):
+				retErr = errors.Join(retErr, fieldErrorf(path, "resource %q is not in the request", d))
+			}
 		}
 
 		// Check if IAM member is valid.
-		for _, b := range s.Bindings {
-			for _, m := range b.Members {
-				parts := strings.SplitN(m, ":", 2)
-				if len(parts) < 2 {
-					retErr = errors.Join(retErr, fmt.Errorf(`member %q is not a valid format (expected "user:<email>")`, m))
-					continue
-				}
+		for j, b := range s.Bindings {
+			if err := validateRoleBundle(b, resourceType); err != nil {
+				retErr = errors.Join(retErr, &FieldError{
+					Path: fmt.Sprintf("policies[%d].bindings[%d].roleBundle", i, j),
+					Err:  err,
+				})
+			}
 
-				// Check if prefix is "user".
-				if got, want := parts[0], "user"; got != want {
-					retErr = errors.Join(retErr, fmt.Errorf(`member %q is not of "user" type (got %q)`, m, got))
+			// Both set is reported as a role bundle error.
+			if b.RoleBundle == "" || b.Role == "" {
+				for _, role := range b.Roles() {
+					if role == "" {
+						continue
+					}
+					if err := validateRole(role, resourceType); err != nil {
+						retErr = errors.Join(retErr, &FieldError{Path: bindingRolePath(i, j, b), Err: err})
+						continue
+					}
+					if err := opts.checkRole(s.Resource, role); err != nil {
+						retErr = errors.Join(retErr, &FieldError{Path: bindingRolePath(i, j, b), Err: err})
+					}
 				}
+			}
 
-				// Check if the email is a valid email.
-				email := parts[1]
-				if _, err := mail.ParseAddress(email); err != nil {
-					retErr = errors.Join(retErr, fmt.Errorf("member %q does not appear to be a valid email address (got %q)", m, email))
-				}
+			if b.StartOffset < 0 {
+				retErr = errors.Join(retErr, fieldErrorf(fmt.Sprintf("policies[%d].bindings[%d].startOffset", i, j),
+					"start offset must not be negative, got %s", b.StartOffset))
+			}
+
+			if opts.MaxMembers > 0 && len(b.Members) > opts.MaxMembers {
+				retErr = errors.Join(retErr, fieldErrorf(fmt.Sprintf("policies[%d].bindings[%d].members", i, j),
+					"binding can have at most %d members, got %d", opts.MaxMembers, len(b.Members)))
+			}
+			for k, m := range b.Members {
+				path := fmt.Sprintf("policies[%d].bindings[%d].members[%d]", i, j, k)
+				retErr = errors.Join(retErr, validateMember(path, m, opts.memberTypes()))
 			}
 		}
 	}
+	// Check if the policies of the same resource conflict.
+	for _, c := range PolicyConflicts(r.ResourcePolicies) {
+		retErr = errors.Join(retErr, &FieldError{
+			Path: fmt.Sprintf("policies[%d]", c.Second),
+			Err:  c,
+		})
+	}
+	if _, err := PolicyLevels(r.ResourcePolicies); err != nil {
+		retErr = errors.Join(retErr, err)
+	}
+	retErr = errors.Join(retErr, validateDenyPolicies(r.DenyPolicies, opts))
+	if r.Recurring != nil {
+		if len(r.ResourcePolicies) == 0 {
+			retErr = errors.Join(retErr, fieldErrorf("recurring", "recurring windows only apply to policies, but policies not found"))
+		}
+		retErr = errors.Join(retErr, validateRecurring(r.Recurring))
+	}
+	if retErr == nil && opts.CustomCheck != nil {
+		retErr = opts.CustomCheck(r)
+	}
 	return
 }
 
-// ValidateToolRequest checks if the ToolRequest is valid.
-func ValidateToolRequest(r *ToolRequest) (retErr error) {
+// validateDenyPolicies checks if the deny policies are of valid and allowed
+// resources, and their rules deny valid members valid permissions.
+func validateDenyPolicies(ps []*DenyPolicy, opts *ValidateOptions) (retErr error) {
+	for i, p := range ps {
+		if rn, err := resource.Parse(p.Resource); err != nil {
+			retErr = errors.Join(retErr, &FieldError{Path: fmt.Sprintf("denyPolicies[%d].resource", i), Err: err})
+		} else if !resource.IsType(string(rn.Type)) {
+			retErr = errors.Join(retErr, fieldErrorf(fmt.Sprintf("denyPolicies[%d].resource", i),
+				"resource %q is not an organization, a folder or a project, deny policies can only be attached to them", p.Resource))
+		} else if err := opts.checkResource(p.Resource); err != nil {
+			retErr = errors.Join(retErr, &FieldError{Path: fmt.Sprintf("denyPolicies[%d].resource", i), Err: err})
+		}
+
+		if len(p.Rules) == 0 {
+			retErr = errors.Join(retErr, fieldErrorf(fmt.Sprintf("denyPolicies[%d].rules", i), "rules not found"))
+		}
+		for j, rule := range p.Rules {
+			path := fmt.Sprintf("denyPolicies[%d].rules[%d]", i, j)
+			if len(rule.DeniedMembers) == 0 {
+				retErr = errors.Join(retErr, fieldErrorf(path+".deniedMembers", "denied members not found"))
+			}
+			for k, m := range rule.DeniedMembers {
+				retErr = errors.Join(retErr, validateMember(fmt.Sprintf("%s.deniedMembers[%d]", path, k), m, opts.memberTypes()))
+			}
+			for k, m := range rule.ExceptionMembers {
+				retErr = errors.Join(retErr, validateMember(fmt.Sprintf("%s.exceptionMembers[%d]", path, k), m, opts.memberTypes()))
+			}
+			if len(rule.DeniedPermissions) == 0 {
+				retErr = errors.Join(retErr, fieldErrorf(path+".deniedPermissions", "denied permissions not found"))
+			}
+			for k, perm := range rule.DeniedPermissions {
+				if !denyPermissionRegexp.MatchString(perm) {
+					retErr = errors.Join(retErr, fieldErrorf(fmt.Sprintf("%s.deniedPermissions[%d]", path, k),
+						`permission %q is not a valid format (expected "<service>.googleapis.com/<resource>.<verb>")`, perm))
+				}
+			}
+		}
+	}
+	return retErr
+}
+
+// validateRecurring checks if the recurring windows are valid and not empty.
+func validateRecurring(r *Recurring) (retErr error) {
+	if len(r.Days) == 0 && r.Hours == "" {
+		retErr = errors.Join(retErr, fieldErrorf("recurring", "days or hours not found"))
+	}
+	for i, d := range r.Days {
+		if _, err := (&Recurring{Days: []string{d}}).Weekdays(); err != nil {
+			retErr = errors.Join(retErr, &FieldError{Path: fmt.Sprintf("recurring.days[%d]", i), Err: err})
+		}
+	}
+	if _, _, err := r.HourRange(); err != nil {
+		retErr = errors.Join(retErr, &FieldError{Path: "recurring.hours", Err: err})
+	}
+	if _, err := r.Location(); err != nil {
+		retErr = errors.Join(retErr, &FieldError{Path: "recurring.timeZone", Err: err})
+	} else if _, err := r.UntilTime(); err != nil {
+		retErr = errors.Join(retErr, &FieldError{Path: "recurring.until", Err: err})
+	}
+	return retErr
+}
+
+// bindingRolePath returns the path of the role or the role bundle of the
+// binding.
+func bindingRolePath(i, j int, b *Binding) string {
+	if b.RoleBundle != "" {
+		return fmt.Sprintf("policies[%d].bindings[%d].roleBundle", i, j)
+	}
+	return fmt.Sprintf("policies[%d].bindings[%d].role", i, j)
+}
+
+// validateMember checks if the member at the path is of one of the types with
+// a valid email.
+func validateMember(path, m string, types []string) (retErr error) {
+	parts := strings.SplitN(m, ":", 2)
+	if len(parts) < 2 {
+		return fieldErrorf(path, `member %q is not a valid format (expected "%s:<email>")`, m, types[0])
+	}
+
+	// Check if prefix is one of the types.
+	if got := parts[0]; !slices.Contains(types, got) {
+		if len(types) == 1 {
+			retErr = errors.Join(retErr, fieldErrorf(path, `member %q is not of %q type (got %q)`, m, types[0], got))
+		} else {
+			retErr = errors.Join(retErr, fieldErrorf(path, `member %q is not of any of %q types (got %q)`, m, types, got))
+		}
+	}
+
+	// Check if the email is a valid email.
+	email := parts[1]
+	if _, err := mail.ParseAddress(email); err != nil {
+		retErr = errors.Join(retErr, fieldErrorf(path, "member %q does not appear to be a valid email address (got %q)", m, email))
+	}
+	return retErr
+}
+
+// validateRole checks if the role is a predefined role, or a custom role of a
+// project or an organization. Custom roles of projects can only be granted on
+// projects.
+func validateRole(role string, resourceType resource.Type) error {
+	if predefinedRoleRegexp.MatchString(role) {
+		return nil
+	}
+	m := customRoleRegexp.FindStringSubmatch(role)
+	if m == nil {
+		return fmt.Errorf(`role %q is not a valid format (expected "roles/<id>", "projects/<project>/roles/<id>" or "organizations/<organization>/roles/<id>")`, role)
+	}
+	if resource.Type(m[1]) == resource.TypeProject && (resourceType == resource.TypeFolder || resourceType == resource.TypeOrganization) {
+		return fmt.Errorf("project custom role %q cannot be granted on %q, must be granted on projects", role, resourceType)
+	}
+	return nil
+}
+
+// validateRoleBundle checks if the role bundle of the binding, if any, is known
+// and can be granted on the resource type.
+func validateRoleBundle(b *Binding, resourceType resource.Type) error {
+	if b.RoleBundle == "" {
+		return nil
+	}
+	if b.Role != "" {
+		return fmt.Errorf("role %q and role bundle %q cannot both be set", b.Role, b.RoleBundle)
+	}
+	rb, ok := LookupRoleBundle(b.RoleBundle)
+	if !ok {
+		return fmt.Errorf("role bundle %q isn't one of %q", b.RoleBundle, RoleBundleNames())
+	}
+	if !slices.Contains(rb.ResourceTypes, string(resourceType)) {
+		return fmt.Errorf("role bundle %q cannot be granted on %q, must be one of %q", b.RoleBundle, resourceType, rb.ResourceTypes)
+	}
+	return nil
+}
+
+// ValidateToolRequest checks if the ToolRequest is valid, and satisfies the
+// options if they are not nil.
+func ValidateToolRequest(r *ToolRequest, opts *ValidateOptions) (retErr error) {
+	opts = opts.orDefault()
+	if err := opts.Check(); err != nil {
+		return fmt.Errorf("invalid validate options: %w", err)
+	}
+
+	// Map the deprecated cli to tool.
+	if r.CLI != "" {
+		if r.Tool != "" && r.Tool != r.CLI {
+			retErr = errors.Join(retErr, fieldErrorf("cli", "cli %q conflicts with tool %q, only set tool", r.CLI, r.Tool))
+		} else {
+			r.Tool = r.CLI
+		}
+	}
+
 	// Set default tool.
 	if r.Tool == "" {
 		r.Tool = defaultTool
 	}
 	// TODO (#49): support other tools.
-	if r.Tool != defaultTool {
-		retErr = errors.Join(retErr, fmt.Errorf("tool %q is not supported", r.Tool))
+	if !slices.Contains(opts.tools(), r.Tool) {
+		retErr = errors.Join(retErr, fieldErrorf("tool", "tool %q is not supported", r.Tool))
 	}
 
 	// Check if it does not have any do commands.
 	if len(r.Do) == 0 {
 		retErr = errors.Join(retErr, fmt.Errorf("do commands not found"))
 	} else {
+		if opts.MaxCommands > 0 && len(r.Do) > opts.MaxCommands {
+			retErr = errors.Join(retErr, fieldErrorf("do",
+				"request can have at most %d do commands, got %d", opts.MaxCommands, len(r.Do)))
+		}
 		// Check if the do commands are valid.
-		for _, c := range r.Do {
+		for i, c := range r.Do {
 			if err := checkCommand(c); err != nil {
-				retErr = errors.Join(retErr, fmt.Errorf("do command %q is not valid: %w", c, err))
+				retErr = errors.Join(retErr, &FieldError{
+					Path: fmt.Sprintf("do[%d]", i),
+					Err:  fmt.Errorf("do command %q is not valid: %w", c, err),
+				})
 			}
 		}
 	}
 	return retErr
 }
 
+// ValidateCombinedRequest checks if the CombinedRequest is valid, including its
+// IAM request and tool request, and satisfies the options if they are not nil.
+func ValidateCombinedRequest(r *CombinedRequest, opts *ValidateOptions) (retErr error) {
+	if err := opts.orDefault().Check(); err != nil {
+		return fmt.Errorf("invalid validate options: %w", err)
+	}
+	if r.IAM == nil {
+		retErr = errors.Join(retErr, fmt.Errorf("iam request not found"))
+	} else {
+		retErr = errors.Join(retErr, sectionError("iam", ValidateIAMRequest(r.IAM, opts)))
+	}
+	if r.Tool == nil {
+		retErr = errors.Join(retErr, fmt.Errorf("tool request not found"))
+	} else {
+		retErr = errors.Join(retErr, sectionError("tool", ValidateToolRequest(r.Tool, opts)))
+	}
+	return retErr
+}
+
+// ValidatePluginRequest checks if the PluginRequest is valid, the spec is
+// validated by the plugin of the kind.
+func ValidatePluginRequest(r *PluginRequest) (retErr error) {
+	if r.Kind == "" {
+		retErr = errors.Join(retErr, fmt.Errorf("kind not found"))
+	} else if !pluginKindRegexp.MatchString(r.Kind) {
+		retErr = errors.Join(retErr, fieldErrorf("kind",
+			"kind %q is not valid, must be lowercase letters, digits and hyphens starting with a letter", r.Kind))
+	}
+	if len(r.Spec) == 0 {
+		retErr = errors.Join(retErr, fmt.Errorf("spec not found"))
+	}
+	return retErr
+}
+
+// ValidateCustomRoleRequest checks if the CustomRoleRequest is valid.
+func ValidateCustomRoleRequest(r *CustomRoleRequest) (retErr error) {
+	if r.Resource == "" {
+		retErr = errors.Join(retErr, fmt.Errorf("resource not found"))
+	} else if rn, err := resource.Parse(r.Resource); err != nil {
+		retErr = errors.Join(retErr, &FieldError{Path: "resource", Err: err})
+	} else if rn.Type == resource.TypeFolder {
+		retErr = errors.Join(retErr, fieldErrorf("resource",
+			"resource %q is a folder, custom roles can only be created in organizations and projects", r.Resource))
+	} else if !resource.IsType(string(rn.Type)) {
+		retErr = errors.Join(retErr, fieldErrorf("resource",
+			"resource %q is not an organization or a project, custom roles can only be created in organizations and projects", r.Resource))
+	}
+
+	switch {
+	case len(r.Permissions) == 0:
+		retErr = errors.Join(retErr, fmt.Errorf("permissions not found"))
+	case len(r.Permissions) > MaxCustomRolePermissions:
+		retErr = errors.Join(retErr, fieldErrorf("permissions",
+			"custom role can have at most %d permissions, got %d", MaxCustomRolePermissions, len(r.Permissions)))
+	}
+	seen := make(map[string]struct{}, len(r.Permissions))
+	for i, p := range r.Permissions {
+		path := fmt.Sprintf("permissions[%d]", i)
+		if !permissionRegexp.MatchString(p) {
+			retErr = errors.Join(retErr, fieldErrorf(path,
+				`permission %q is not valid (expected "<service>.<resource>.<verb>")`, p))
+		}
+		if _, ok := seen[p]; ok {
+			retErr = errors.Join(retErr, fieldErrorf(path, "permission %q is duplicated", p))
+		}
+		seen[p] = struct{}{}
+	}
+
+	if len(r.Members) == 0 {
+		retErr = errors.Join(retErr, fmt.Errorf("members not found"))
+	}
+	for i, m := range r.Members {
+		retErr = errors.Join(retErr, validateMember(fmt.Sprintf("members[%d]", i), m, defaultMemberTypes))
+	}
+	return retErr
+}
+
+// groupMemberTypes are the types of the members of GroupRequests, the ones
+// whose group memberships can expire.
+var groupMemberTypes = []string{"user", "serviceAccount"}
+
+// ValidateGroupRequest checks if the GroupRequest is valid.
+func ValidateGroupRequest(r *GroupRequest) (retErr error) {
+	if r.Group == "" {
+		retErr = errors.Join(retErr, fmt.Errorf("group not found"))
+	} else if _, err := mail.ParseAddress(r.Group); err != nil || strings.ContainsAny(r.Group, "<> ") {
+		retErr = errors.Join(retErr, fieldErrorf("group", "group %q is not a valid email address", r.Group))
+	}
+
+	if len(r.Members) == 0 {
+		retErr = errors.Join(retErr, fmt.Errorf("members not found"))
+	}
+	seen := make(map[string]struct{}, len(r.Members))
+	for i, m := range r.Members {
+		path := fmt.Sprintf("members[%d]", i)
+		retErr = errors.Join(retErr, validateMember(path, m, groupMemberTypes))
+		if _, ok := seen[m]; ok {
+			retErr = errors.Join(retErr, fieldErrorf(path, "member %q is duplicated", m))
+		}
+		seen[m] = struct{}{}
+	}
+	return retErr
+}
+
+// sectionError returns the validation error of a section of a request with the
+// paths of the FieldErrors prefixed by the section, and the other errors
+// wrapped in FieldErrors of the section.
+func sectionError(section string, err error) error {
+	switch e := err.(type) { //nolint:errorlint // Walking the error tree.
+	case nil:
+		return nil
+	case *FieldError:
+		e.Path = section + "." + e.Path
+		return e
+	case interface{ Unwrap() []error }:
+		var retErr error
+		for _, ee := range e.Unwrap() {
+			retErr = errors.Join(retErr, sectionError(section, ee))
+		}
+		return retErr
+	default:
+		return &FieldError{Path: section, Err: err}
+	}
+}
+
 func checkCommand(c string) (retErr error) {
 	scanner := bufio.NewScanner(strings.NewReader(c))
 	for row := 1; scanner.Scan(); row++ {
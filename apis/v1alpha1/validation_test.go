@@ -15,7 +15,9 @@
 package v1alpha1
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/abcxyz/pkg/testutil"
 )
@@ -76,6 +78,13 @@ func TestValidateIAMRequest(t *testing.T) {
 			},
 			wantErr: `policies not found`,
 		},
+		{
+			name: "unexpanded_entitlements",
+			request: &IAMRequest{
+				Entitlements: []*EntitlementRef{{Entitlement: "bq-oncall", Members: []string{"user:alice@example.com"}}},
+			},
+			wantErr: `entitlements: entitlements must be expanded with an entitlement catalog`,
+		},
 		{
 			name: "invalid_email",
 			request: &IAMRequest{
@@ -93,7 +102,7 @@ func TestValidateIAMRequest(t *testing.T) {
 					},
 				},
 			},
-			wantErr: `member "user:example.com" does not appear to be a valid email address (got "example.com")`,
+			wantErr: `policies[0].bindings[0].members[0]: member "user:example.com" does not appear to be a valid email address (got "example.com")`,
 		},
 		{
 			name: "invalid_member_invalid_type",
@@ -113,7 +122,7 @@ func TestValidateIAMRequest(t *testing.T) {
 					},
 				},
 			},
-			wantErr: `member "group:test-group@example.com" is not of "user" type`,
+			wantErr: `policies[0].bindings[0].members[0]: member "group:test-group@example.com" is not of "user" type`,
 		},
 		{
 			name: "invalid_member_missing_email",
@@ -190,7 +199,211 @@ func TestValidateIAMRequest(t *testing.T) {
 					},
 				},
 			},
-			wantErr: `resource "foo" isn't one of [organizations, folders, projects]`,
+			wantErr: `policies[0].resource: resource "foo" isn't one of [organizations, folders, projects, buckets, secrets, serviceAccounts, bigquery/datasets, kms/keyrings, //<service>]`,
+		},
+		{
+			name: "recurring",
+			request: &IAMRequest{
+				ResourcePolicies: []*ResourcePolicy{
+					{Resource: "projects/foo", Bindings: []*Binding{{Members: []string{"user:alice@example.com"}, Role: "roles/viewer"}}},
+				},
+				Recurring: &Recurring{
+					Days:     []string{"Mon-Fri", "sun"},
+					Hours:    "09:00-17:30",
+					TimeZone: "America/Los_Angeles",
+					Until:    "2009-12-31",
+				},
+			},
+		},
+		{
+			name: "invalid_recurring",
+			request: &IAMRequest{
+				ResourcePolicies: []*ResourcePolicy{
+					{Resource: "projects/foo", Bindings: []*Binding{{Members: []string{"user:alice@example.com"}, Role: "roles/viewer"}}},
+				},
+				Recurring: &Recurring{
+					Days:     []string{"Mon-Someday"},
+					Hours:    "17:00-09:00",
+					TimeZone: "Mars/Olympus_Mons",
+				},
+			},
+			wantErr: `recurring.days[0]: day "Someday" is not a day of the week (expected e.g. "Mon" or "Mon-Fri")
+recurring.hours: hours "17:00-09:00" must start before they end
+recurring.timeZone: time zone "Mars/Olympus_Mons" is not valid`,
+		},
+		{
+			name: "invalid_recurring_until",
+			request: &IAMRequest{
+				ResourcePolicies: []*ResourcePolicy{
+					{Resource: "projects/foo", Bindings: []*Binding{{Members: []string{"user:alice@example.com"}, Role: "roles/viewer"}}},
+				},
+				Recurring: &Recurring{Hours: "9-17", Until: "next week"},
+			},
+			wantErr: `recurring.hours: hours "9-17" is not valid: time "9" is not in the format of "HH:MM"
+recurring.until: until "next week" is not a valid date (expected "YYYY-MM-DD")`,
+		},
+		{
+			name: "role_bundle",
+			request: &IAMRequest{
+				ResourcePolicies: []*ResourcePolicy{
+					{
+						Resource: "projects/foo",
+						Bindings: []*Binding{
+							{
+								Members: []string{
+									"user:test-user@example.com",
+								},
+								RoleBundle: "bq-read",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "unknown_role_bundle",
+			request: &IAMRequest{
+				ResourcePolicies: []*ResourcePolicy{
+					{
+						Resource: "projects/foo",
+						Bindings: []*Binding{
+							{
+								Members: []string{
+									"user:test-user@example.com",
+								},
+								RoleBundle: "foo",
+							},
+						},
+					},
+				},
+			},
+			wantErr: `policies[0].bindings[0].roleBundle: role bundle "foo" isn't one of ["bq-read" "gcs-read" "project-viewer-plus-logs"]`,
+		},
+		{
+			name: "role_and_role_bundle",
+			request: &IAMRequest{
+				ResourcePolicies: []*ResourcePolicy{
+					{
+						Resource: "projects/foo",
+						Bindings: []*Binding{
+							{
+								Members: []string{
+									"user:test-user@example.com",
+								},
+								Role:       "roles/bigquery.dataViewer",
+								RoleBundle: "bq-read",
+							},
+						},
+					},
+				},
+			},
+			wantErr: `policies[0].bindings[0].roleBundle: role "roles/bigquery.dataViewer" and role bundle "bq-read" cannot both be set`,
+		},
+		{
+			name: "success_depends_on",
+			request: &IAMRequest{
+				ResourcePolicies: []*ResourcePolicy{
+					{
+						Resource: "folders/bar",
+						Bindings: []*Binding{{Members: []string{"user:test-user@example.com"}, Role: "roles/browser"}},
+					},
+					{
+						Resource:  "projects/baz",
+						Bindings:  []*Binding{{Members: []string{"user:test-user@example.com"}, Role: "roles/run.developer"}},
+						DependsOn: []string{"folders/bar"},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid_depends_on",
+			request: &IAMRequest{
+				ResourcePolicies: []*ResourcePolicy{
+					{
+						Resource:  "folders/bar",
+						Bindings:  []*Binding{{Members: []string{"user:test-user@example.com"}, Role: "roles/browser"}},
+						DependsOn: []string{"folders/bar", "projects/qux"},
+					},
+				},
+			},
+			wantErr: `policies[0].dependsOn[0]: resource "folders/bar" cannot depend on itself` + "\n" +
+				`policies[0].dependsOn[1]: resource "projects/qux" is not in the request`,
+		},
+		{
+			name: "depends_on_cycle",
+			request: &IAMRequest{
+				ResourcePolicies: []*ResourcePolicy{
+					{
+						Resource:  "folders/bar",
+						Bindings:  []*Binding{{Members: []string{"user:test-user@example.com"}, Role: "roles/browser"}},
+						DependsOn: []string{"projects/baz"},
+					},
+					{
+						Resource:  "projects/baz",
+						Bindings:  []*Binding{{Members: []string{"user:test-user@example.com"}, Role: "roles/run.developer"}},
+						DependsOn: []string{"folders/bar"},
+					},
+				},
+			},
+			wantErr: `dependency cycle between resources ["folders/bar" "projects/baz"]`,
+		},
+		{
+			name: "duplicate_resources",
+			request: &IAMRequest{
+				ResourcePolicies: []*ResourcePolicy{
+					{
+						Resource: "folders/bar",
+						Bindings: []*Binding{{Members: []string{"user:test-user@example.com"}, Role: "roles/browser"}},
+					},
+					{
+						Resource:  "projects/baz",
+						Bindings:  []*Binding{{Members: []string{"user:test-user@example.com"}, Role: "roles/run.developer"}},
+						DependsOn: []string{"folders/bar"},
+					},
+					{
+						Resource:  "projects/baz",
+						Bindings:  []*Binding{{Members: []string{"user:test-user@example.com"}, Role: "roles/run.viewer"}},
+						DependsOn: []string{"folders/bar", "folders/bar"},
+					},
+				},
+			},
+		},
+		{
+			name: "conflicting_duplicate_resources",
+			request: &IAMRequest{
+				ResourcePolicies: []*ResourcePolicy{
+					{
+						Resource: "folders/bar",
+						Bindings: []*Binding{{Members: []string{"user:test-user@example.com"}, Role: "roles/browser"}},
+					},
+					{
+						Resource:  "projects/baz",
+						Bindings:  []*Binding{{Members: []string{"user:test-user@example.com"}, Role: "roles/run.developer"}},
+						DependsOn: []string{"folders/bar"},
+					},
+					{
+						Resource: "projects/baz",
+						Bindings: []*Binding{{Members: []string{"user:test-user@example.com"}, Role: "roles/run.viewer"}},
+					},
+				},
+			},
+			wantErr: `policies[2]: resource "projects/baz" conflicts with policies[1]: dependsOn [] differs from ["folders/bar"]`,
+		},
+		{
+			name: "contradictory_bindings_of_duplicate_resources",
+			request: &IAMRequest{
+				ResourcePolicies: []*ResourcePolicy{
+					{
+						Resource: "projects/baz",
+						Bindings: []*Binding{{Members: []string{"user:test-user@example.com"}, Role: "roles/run.viewer"}},
+					},
+					{
+						Resource: "projects/baz",
+						Bindings: []*Binding{{Members: []string{"user:test-user@example.com"}, Role: "roles/run.viewer", StartOffset: 30 * time.Minute}},
+					},
+				},
+			},
+			wantErr: `policies[1]: resource "projects/baz" conflicts with policies[0]: role roles/run.viewer of user:test-user@example.com starts at offset 30m0s instead of 0s`,
 		},
 	}
 
@@ -198,7 +411,7 @@ func TestValidateIAMRequest(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			gotErr := ValidateIAMRequest(tc.request)
+			gotErr := ValidateIAMRequest(tc.request, nil)
 			if diff := testutil.DiffErrString(gotErr, tc.wantErr); diff != "" {
 				t.Errorf("Process %s got unexpected error: %s", tc.name, diff)
 			}
@@ -249,7 +462,27 @@ func TestValidateToolRequest(t *testing.T) {
 					"run jobs execute my-job",
 				},
 			},
-			wantErr: `tool "aws" is not supported`,
+			wantErr: `tool: tool "aws" is not supported`,
+		},
+		{
+			name: "success_with_deprecated_cli",
+			request: &ToolRequest{
+				CLI: "gcloud",
+				Do: []string{
+					"run jobs execute my-job",
+				},
+			},
+		},
+		{
+			name: "deprecated_cli_conflicts_with_tool",
+			request: &ToolRequest{
+				Tool: "gcloud",
+				CLI:  "aws",
+				Do: []string{
+					"run jobs execute my-job",
+				},
+			},
+			wantErr: `cli: cli "aws" conflicts with tool "gcloud", only set tool`,
 		},
 		{
 			name: "invalid_do_command",
@@ -259,7 +492,7 @@ func TestValidateToolRequest(t *testing.T) {
 jobs execute my-job && rmdir dir`,
 				},
 			},
-			wantErr: `disallowed command character '&' at 2:20
+			wantErr: `do[0]: do command "run\njobs execute my-job && rmdir dir" is not valid: disallowed command character '&' at 2:20
 disallowed command character '&' at 2:21`,
 		},
 	}
@@ -268,7 +501,519 @@ disallowed command character '&' at 2:21`,
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			gotErr := ValidateToolRequest(tc.request)
+			gotErr := ValidateToolRequest(tc.request, nil)
+			if diff := testutil.DiffErrString(gotErr, tc.wantErr); diff != "" {
+				t.Errorf("Process %s got unexpected error: %s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestValidatePluginRequest(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		request *PluginRequest
+		wantErr string
+	}{
+		{
+			name: "success",
+			request: &PluginRequest{
+				Kind: "internal-db",
+				Spec: map[string]any{"database": "orders"},
+			},
+		},
+		{
+			name:    "missing_kind_and_spec",
+			request: &PluginRequest{},
+			wantErr: "kind not found\nspec not found",
+		},
+		{
+			name: "invalid_kind",
+			request: &PluginRequest{
+				Kind: "../vpn",
+				Spec: map[string]any{"network": "corp"},
+			},
+			wantErr: `kind: kind "../vpn" is not valid`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			gotErr := ValidatePluginRequest(tc.request)
+			if diff := testutil.DiffErrString(gotErr, tc.wantErr); diff != "" {
+				t.Errorf("Process %s got unexpected error: %s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestValidateCustomRoleRequest(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		request *CustomRoleRequest
+		wantErr string
+	}{
+		{
+			name: "success",
+			request: &CustomRoleRequest{
+				Resource:    "projects/foo",
+				Permissions: []string{"storage.objects.get", "storage.objects.list"},
+				Members:     []string{"user:foo@example.com"},
+			},
+		},
+		{
+			name: "organization",
+			request: &CustomRoleRequest{
+				Resource:    "organizations/123",
+				Permissions: []string{"resourcemanager.projects.get"},
+				Members:     []string{"user:foo@example.com"},
+			},
+		},
+		{
+			name: "folder",
+			request: &CustomRoleRequest{
+				Resource:    "folders/123",
+				Permissions: []string{"resourcemanager.folders.get"},
+				Members:     []string{"user:foo@example.com"},
+			},
+			wantErr: "custom roles can only be created in organizations and projects",
+		},
+		{
+			name: "bucket",
+			request: &CustomRoleRequest{
+				Resource:    "buckets/foo",
+				Permissions: []string{"storage.objects.get"},
+				Members:     []string{"user:foo@example.com"},
+			},
+			wantErr: `resource: resource "buckets/foo" is not an organization or a project`,
+		},
+		{
+			name: "invalid_resource",
+			request: &CustomRoleRequest{
+				Resource:    "topics/foo",
+				Permissions: []string{"storage.objects.get"},
+				Members:     []string{"user:foo@example.com"},
+			},
+			wantErr: `resource: resource "topics/foo" isn't one of`,
+		},
+		{
+			name: "invalid_permission",
+			request: &CustomRoleRequest{
+				Resource:    "projects/foo",
+				Permissions: []string{"roles/viewer"},
+				Members:     []string{"user:foo@example.com"},
+			},
+			wantErr: `permissions[0]: permission "roles/viewer" is not valid`,
+		},
+		{
+			name: "duplicate_permission",
+			request: &CustomRoleRequest{
+				Resource:    "projects/foo",
+				Permissions: []string{"storage.objects.get", "storage.objects.get"},
+				Members:     []string{"user:foo@example.com"},
+			},
+			wantErr: `permissions[1]: permission "storage.objects.get" is duplicated`,
+		},
+		{
+			name: "invalid_member",
+			request: &CustomRoleRequest{
+				Resource:    "projects/foo",
+				Permissions: []string{"storage.objects.get"},
+				Members:     []string{"group:foo@example.com"},
+			},
+			wantErr: `members[0]: member "group:foo@example.com" is not of "user" type`,
+		},
+		{
+			name:    "empty",
+			request: &CustomRoleRequest{},
+			wantErr: "resource not found\npermissions not found\nmembers not found",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			gotErr := ValidateCustomRoleRequest(tc.request)
+			if diff := testutil.DiffErrString(gotErr, tc.wantErr); diff != "" {
+				t.Errorf("Process %s got unexpected error: %s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestValidateGroupRequest(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		request *GroupRequest
+		wantErr string
+	}{
+		{
+			name: "success",
+			request: &GroupRequest{
+				Group:   "prod-admins@example.com",
+				Members: []string{"user:foo@example.com", "serviceAccount:bar@baz.iam.gserviceaccount.com"},
+			},
+		},
+		{
+			name:    "missing_group",
+			request: &GroupRequest{Members: []string{"user:foo@example.com"}},
+			wantErr: "group not found",
+		},
+		{
+			name: "invalid_group",
+			request: &GroupRequest{
+				Group:   "groups/prod-admins",
+				Members: []string{"user:foo@example.com"},
+			},
+			wantErr: `group: group "groups/prod-admins" is not a valid email address`,
+		},
+		{
+			name:    "missing_members",
+			request: &GroupRequest{Group: "prod-admins@example.com"},
+			wantErr: "members not found",
+		},
+		{
+			name: "group_member",
+			request: &GroupRequest{
+				Group:   "prod-admins@example.com",
+				Members: []string{"group:oncall@example.com"},
+			},
+			wantErr: `members[0]: member "group:oncall@example.com" is not of any of ["user" "serviceAccount"] types (got "group")`,
+		},
+		{
+			name: "duplicated_member",
+			request: &GroupRequest{
+				Group:   "prod-admins@example.com",
+				Members: []string{"user:foo@example.com", "user:foo@example.com"},
+			},
+			wantErr: `members[1]: member "user:foo@example.com" is duplicated`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			gotErr := ValidateGroupRequest(tc.request)
+			if diff := testutil.DiffErrString(gotErr, tc.wantErr); diff != "" {
+				t.Errorf("Process %s got unexpected error: %s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestValidateCombinedRequest(t *testing.T) {
+	t.Parallel()
+
+	validIAM := func() *IAMRequest {
+		return &IAMRequest{
+			ResourcePolicies: []*ResourcePolicy{{
+				Resource: "projects/foo",
+				Bindings: []*Binding{{Members: []string{"user:foo@example.com"}, Role: "roles/run.developer"}},
+			}},
+		}
+	}
+
+	cases := []struct {
+		name     string
+		request  *CombinedRequest
+		wantTool string
+		wantErr  string
+	}{
+		{
+			name: "success",
+			request: &CombinedRequest{
+				IAM:  validIAM(),
+				Tool: &ToolRequest{Do: []string{"run jobs execute my-job"}},
+			},
+			wantTool: "gcloud",
+		},
+		{
+			name:    "missing_sections",
+			request: &CombinedRequest{},
+			wantErr: "iam request not found\ntool request not found",
+		},
+		{
+			name: "invalid_sections",
+			request: &CombinedRequest{
+				IAM: &IAMRequest{
+					ResourcePolicies: []*ResourcePolicy{{
+						Resource: "foo/bar",
+						Bindings: []*Binding{{Members: []string{"group:foo@example.com"}, Role: "roles/run.developer"}},
+					}},
+				},
+				Tool: &ToolRequest{Tool: "aws"},
+			},
+			wantTool: "aws",
+			wantErr: `iam.policies[0].resource: resource "foo/bar" isn't one of [organizations, folders, projects, buckets, secrets, serviceAccounts, bigquery/datasets, kms/keyrings, //<service>]` + "\n" +
+				`iam.policies[0].bindings[0].members[0]: member "group:foo@example.com" is not of "user" type (got "group")` + "\n" +
+				`tool.tool: tool "aws" is not supported` + "\n" +
+				`tool: do commands not found`,
+		},
+		{
+			name: "missing_policies",
+			request: &CombinedRequest{
+				IAM:  &IAMRequest{},
+				Tool: &ToolRequest{Do: []string{"run jobs execute my-job"}},
+			},
+			wantTool: "gcloud",
+			wantErr:  "iam: policies not found",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			gotErr := ValidateCombinedRequest(tc.request, nil)
+			if diff := testutil.DiffErrString(gotErr, tc.wantErr); diff != "" {
+				t.Errorf("Process %s got unexpected error: %s", tc.name, diff)
+			}
+			if tc.request.Tool != nil && tc.request.Tool.Tool != tc.wantTool {
+				t.Errorf("Process %s got tool %q, want %q", tc.name, tc.request.Tool.Tool, tc.wantTool)
+			}
+		})
+	}
+}
+
+func TestValidateOptions(t *testing.T) {
+	t.Parallel()
+
+	iamRequest := func(role string, members ...string) *IAMRequest {
+		return &IAMRequest{
+			ResourcePolicies: []*ResourcePolicy{{
+				Resource: "projects/foo",
+				Bindings: []*Binding{{Members: members, Role: role}},
+			}},
+		}
+	}
+
+	cases := []struct {
+		name        string
+		opts        *ValidateOptions
+		iamRequest  *IAMRequest
+		toolRequest *ToolRequest
+		wantErr     string
+	}{
+		{
+			name:       "allowed_member_types",
+			opts:       &ValidateOptions{AllowedMemberTypes: []string{"user", "group"}},
+			iamRequest: iamRequest("roles/viewer", "user:alice@example.com", "group:eng@example.com"),
+		},
+		{
+			name:       "member_type_not_allowed",
+			opts:       &ValidateOptions{AllowedMemberTypes: []string{"user", "group"}},
+			iamRequest: iamRequest("roles/viewer", "serviceAccount:sa@foo.iam.gserviceaccount.com"),
+			wantErr:    `policies[0].bindings[0].members[0]: member "serviceAccount:sa@foo.iam.gserviceaccount.com" is not of any of ["user" "group"] types (got "serviceAccount")`,
+		},
+		{
+			name:       "allowed_resource",
+			opts:       &ValidateOptions{AllowedResources: []string{"folders/123", "projects/f*"}},
+			iamRequest: iamRequest("roles/viewer", "user:alice@example.com"),
+		},
+		{
+			name:       "resource_not_allowed",
+			opts:       &ValidateOptions{AllowedResources: []string{"folders/123", "projects/dev-*"}},
+			iamRequest: iamRequest("roles/viewer", "user:alice@example.com"),
+			wantErr:    `policies[0].resource: resource "projects/foo" is not allowed (must match one of ["folders/123" "projects/dev-*"])`,
+		},
+		{
+			name:       "invalid_resource_pattern",
+			opts:       &ValidateOptions{AllowedResources: []string{"projects/["}},
+			iamRequest: iamRequest("roles/viewer", "user:alice@example.com"),
+			wantErr:    `invalid validate options: resource pattern "projects/[" is not valid`,
+		},
+		{
+			name: "custom_check",
+			opts: &ValidateOptions{CustomCheck: func(r *IAMRequest) error {
+				return fmt.Errorf("custom check of %d policies failed", len(r.ResourcePolicies))
+			}},
+			iamRequest: iamRequest("roles/viewer", "user:alice@example.com"),
+			wantErr:    "custom check of 1 policies failed",
+		},
+		{
+			name:       "allowed_role",
+			opts:       &ValidateOptions{AllowedRoles: []string{"roles/storage.*"}},
+			iamRequest: iamRequest("roles/storage.objectViewer", "user:alice@example.com"),
+		},
+		{
+			name:       "role_not_allowed",
+			opts:       &ValidateOptions{AllowedRoles: []string{"roles/storage.*"}},
+			iamRequest: iamRequest("roles/owner", "user:alice@example.com"),
+			wantErr:    `policies[0].bindings[0].role: role "roles/owner" is not allowed (must match one of ["roles/storage.*"])`,
+		},
+		{
+			name:       "role_denied",
+			opts:       &ValidateOptions{AllowedRoles: []string{"roles/*"}, DeniedRoles: []string{"roles/owner"}},
+			iamRequest: iamRequest("roles/owner", "user:alice@example.com"),
+			wantErr:    `policies[0].bindings[0].role: role "roles/owner" is denied (matches "roles/owner")`,
+		},
+		{
+			name: "role_policy_denied_on_resource",
+			opts: &ValidateOptions{RolePolicies: []*RolePolicy{
+				{Resources: []string{"projects/prod-*"}, Deny: []string{"roles/owner"}},
+			}},
+			iamRequest: &IAMRequest{
+				ResourcePolicies: []*ResourcePolicy{
+					{Resource: "projects/dev-foo", Bindings: []*Binding{{Members: []string{"user:alice@example.com"}, Role: "roles/owner"}}},
+					{Resource: "projects/prod-foo", Bindings: []*Binding{{Members: []string{"user:alice@example.com"}, Role: "roles/owner"}}},
+				},
+			},
+			wantErr: `policies[1].bindings[0].role: role "roles/owner" is denied (matches "roles/owner") on projects/prod-foo`,
+		},
+		{
+			name: "role_policies_all_resources",
+			opts: &ValidateOptions{RolePolicies: []*RolePolicy{
+				{Allow: []string{"roles/storage.*"}},
+				{Resources: []string{"projects/prod-*"}, Deny: []string{"roles/storage.admin"}},
+			}},
+			iamRequest: iamRequest("roles/viewer", "user:alice@example.com"),
+			wantErr:    `policies[0].bindings[0].role: role "roles/viewer" is not allowed (must match one of ["roles/storage.*"])`,
+		},
+		{
+			name:       "max_members",
+			opts:       &ValidateOptions{MaxMembers: 1},
+			iamRequest: iamRequest("roles/viewer", "user:alice@example.com", "user:bob@example.com"),
+			wantErr:    "policies[0].bindings[0].members: binding can have at most 1 members, got 2",
+		},
+		{
+			name: "custom_roles",
+			iamRequest: &IAMRequest{
+				ResourcePolicies: []*ResourcePolicy{
+					{Resource: "projects/foo", Bindings: []*Binding{
+						{Members: []string{"user:alice@example.com"}, Role: "projects/foo/roles/myRole"},
+						{Members: []string{"user:alice@example.com"}, Role: "organizations/123/roles/my_role.v2"},
+					}},
+					{Resource: "folders/456", Bindings: []*Binding{
+						{Members: []string{"user:alice@example.com"}, Role: "organizations/123/roles/myRole"},
+					}},
+				},
+			},
+		},
+		{
+			name: "invalid_role_format",
+			iamRequest: &IAMRequest{
+				ResourcePolicies: []*ResourcePolicy{
+					{Resource: "projects/foo", Bindings: []*Binding{
+						{Members: []string{"user:alice@example.com"}, Role: "viewer"},
+						{Members: []string{"user:alice@example.com"}, Role: "folders/456/roles/myRole"},
+						{Members: []string{"user:alice@example.com"}, Role: "projects/foo/roles/a"},
+					}},
+				},
+			},
+			wantErr: `policies[0].bindings[0].role: role "viewer" is not a valid format (expected "roles/<id>", "projects/<project>/roles/<id>" or "organizations/<organization>/roles/<id>")
+policies[0].bindings[1].role: role "folders/456/roles/myRole" is not a valid format (expected "roles/<id>", "projects/<project>/roles/<id>" or "organizations/<organization>/roles/<id>")
+policies[0].bindings[2].role: role "projects/foo/roles/a" is not a valid format (expected "roles/<id>", "projects/<project>/roles/<id>" or "organizations/<organization>/roles/<id>")`,
+		},
+		{
+			name: "project_custom_role_on_folder",
+			iamRequest: &IAMRequest{
+				ResourcePolicies: []*ResourcePolicy{
+					{Resource: "folders/456", Bindings: []*Binding{{Members: []string{"user:alice@example.com"}, Role: "projects/foo/roles/myRole"}}},
+				},
+			},
+			wantErr: `policies[0].bindings[0].role: project custom role "projects/foo/roles/myRole" cannot be granted on "folders", must be granted on projects`,
+		},
+		{
+			name: "deny_policies_only",
+			opts: &ValidateOptions{AllowedMemberTypes: []string{"user", "group"}},
+			iamRequest: &IAMRequest{
+				DenyPolicies: []*DenyPolicy{{
+					Resource: "projects/foo",
+					Rules: []*DenyRule{{
+						DeniedMembers:     []string{"group:contractors@example.com"},
+						ExceptionMembers:  []string{"user:alice@example.com"},
+						DeniedPermissions: []string{"storage.googleapis.com/buckets.delete", "compute.googleapis.com/instances.setIamPolicy"},
+					}},
+				}},
+			},
+		},
+		{
+			name: "invalid_deny_policies",
+			iamRequest: &IAMRequest{
+				DenyPolicies: []*DenyPolicy{
+					{Resource: "projects/foo"},
+					{
+						Resource: "projects/foo",
+						Rules: []*DenyRule{
+							{DeniedMembers: []string{"alice@example.com"}, DeniedPermissions: []string{"storage.buckets.delete"}},
+							{},
+						},
+					},
+				},
+			},
+			wantErr: `denyPolicies[0].rules: rules not found
+denyPolicies[1].rules[0].deniedMembers[0]: member "alice@example.com" is not a valid format (expected "user:<email>")
+denyPolicies[1].rules[0].deniedPermissions[0]: permission "storage.buckets.delete" is not a valid format (expected "<service>.googleapis.com/<resource>.<verb>")
+denyPolicies[1].rules[1].deniedMembers: denied members not found
+denyPolicies[1].rules[1].deniedPermissions: denied permissions not found`,
+		},
+		{
+			name: "negative_start_offset",
+			iamRequest: &IAMRequest{
+				ResourcePolicies: []*ResourcePolicy{
+					{Resource: "projects/foo", Bindings: []*Binding{{Members: []string{"user:alice@example.com"}, Role: "roles/viewer", StartOffset: -30 * time.Minute}}},
+				},
+			},
+			wantErr: "policies[0].bindings[0].startOffset: start offset must not be negative, got -30m0s",
+		},
+		{
+			name: "max_policies",
+			opts: &ValidateOptions{MaxPolicies: 1},
+			iamRequest: &IAMRequest{
+				ResourcePolicies: []*ResourcePolicy{
+					{Resource: "projects/foo", Bindings: []*Binding{{Members: []string{"user:alice@example.com"}, Role: "roles/viewer"}}},
+					{Resource: "projects/bar", Bindings: []*Binding{{Members: []string{"user:alice@example.com"}, Role: "roles/viewer"}}},
+				},
+			},
+			wantErr: "policies: request can have at most 1 policies, got 2",
+		},
+		{
+			name:       "invalid_options",
+			opts:       &ValidateOptions{AllowedMemberTypes: []string{"domain"}, DeniedRoles: []string{"roles/["}, MaxBindings: -1},
+			iamRequest: iamRequest("roles/viewer", "user:alice@example.com"),
+			wantErr: `invalid validate options: allowed member type "domain" isn't one of ["user" "group" "serviceAccount"]
+role pattern "roles/[" is not valid: syntax error in pattern
+max bindings must not be negative, got -1`,
+		},
+		{
+			name:        "allowed_tool",
+			opts:        &ValidateOptions{AllowedTools: []string{"gcloud", "bq"}},
+			toolRequest: &ToolRequest{Tool: "bq", Do: []string{"ls"}},
+		},
+		{
+			name:        "tool_not_allowed",
+			opts:        &ValidateOptions{AllowedTools: []string{"bq"}},
+			toolRequest: &ToolRequest{Do: []string{"projects list"}},
+			wantErr:     `tool: tool "gcloud" is not supported`,
+		},
+		{
+			name:        "max_commands",
+			opts:        &ValidateOptions{MaxCommands: 1},
+			toolRequest: &ToolRequest{Do: []string{"projects list", "projects describe foo"}},
+			wantErr:     "do: request can have at most 1 do commands, got 2",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var gotErr error
+			if tc.iamRequest != nil {
+				gotErr = ValidateIAMRequest(tc.iamRequest, tc.opts)
+			} else {
+				gotErr = ValidateToolRequest(tc.toolRequest, tc.opts)
+			}
 			if diff := testutil.DiffErrString(gotErr, tc.wantErr); diff != "" {
 				t.Errorf("Process %s got unexpected error: %s", tc.name, diff)
 			}
@@ -0,0 +1,33 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import createFetchClient, { type ClientOptions } from 'openapi-fetch';
+
+import type { components, paths } from './schema';
+
+export type { components, paths };
+
+export type IAMRequest = components['schemas']['IAMRequest'];
+export type ResourcePolicy = components['schemas']['ResourcePolicy'];
+export type Binding = components['schemas']['Binding'];
+export type Operation = components['schemas']['Operation'];
+
+/**
+ * Creates a client of the AOD server at options.baseUrl, e.g.
+ * "https://aod.example.com". The paths, parameters and bodies are typed by the
+ * OpenAPI document of the server.
+ */
+export function createClient(options: ClientOptions) {
+  return createFetchClient<paths>(options);
+}
@@ -0,0 +1,195 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package admission consults external policy engines on whether IAM requests
+// are admitted before they are handled.
+package admission
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+)
+
+// maxResponseSize is the max size of OPA responses, 1MiB.
+const maxResponseSize = 1 << 20
+
+// ErrDenied is returned when a request is denied by the policy decision.
+var ErrDenied = errors.New("request denied by admission policy")
+
+// OPAChecker checks IAM requests with the decision of an Open Policy Agent
+// (OPA) policy, queried with the OPA data API.
+type OPAChecker struct {
+	client *http.Client
+	url    string
+	token  string
+}
+
+// NewOPAChecker creates a new OPAChecker querying the decision at the OPA data
+// API URL, such as "http://localhost:8181/v1/data/aod/allow". The token is
+// sent as the bearer token if it is not empty.
+func NewOPAChecker(client *http.Client, url, token string) (*OPAChecker, error) {
+	if url == "" {
+		return nil, fmt.Errorf("opa url is required")
+	}
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("opa url %q must be an http or https URL", url)
+	}
+	return &OPAChecker{
+		client: client,
+		url:    url,
+		token:  token,
+	}, nil
+}
+
+// Check posts the normalized IAM request as the input of the decision, and
+// returns an error wrapping ErrDenied if the decision is deny. The decision is
+// either a bool, or an object with the bool "allow" and the optional list of
+// string "reasons" explaining a deny. An undefined decision is a deny.
+func (c *OPAChecker) Check(ctx context.Context, w *v1alpha1.IAMRequestWrapper) error {
+	b, err := json.Marshal(map[string]any{"input": Input(w)})
+	if err != nil {
+		return fmt.Errorf("failed to marshal opa input: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("failed to create opa request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query opa: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("failed to read opa response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected opa response status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var out struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return fmt.Errorf("failed to unmarshal opa response: %w", err)
+	}
+	return decide(out.Result)
+}
+
+// decide returns nil if the decision result allows the request.
+func decide(result json.RawMessage) error {
+	if len(result) == 0 || string(result) == "null" {
+		return fmt.Errorf("%w: decision is undefined", ErrDenied)
+	}
+
+	var allow bool
+	if err := json.Unmarshal(result, &allow); err == nil {
+		if !allow {
+			return ErrDenied
+		}
+		return nil
+	}
+
+	var decision struct {
+		Allow   *bool    `json:"allow"`
+		Reasons []string `json:"reasons"`
+	}
+	if err := json.Unmarshal(result, &decision); err != nil {
+		return fmt.Errorf("decision must be a bool or an object with allow, got %s", result)
+	}
+	if decision.Allow == nil {
+		return fmt.Errorf("%w: decision has no allow", ErrDenied)
+	}
+	if *decision.Allow {
+		return nil
+	}
+	if len(decision.Reasons) > 0 {
+		return fmt.Errorf("%w: %s", ErrDenied, strings.Join(decision.Reasons, "; "))
+	}
+	return ErrDenied
+}
+
+// Input returns the normalized IAM request, the input of the decision:
+//
+//   - policies: the policies of the request in the schema of the request file.
+//   - bindings: the bindings flattened to one per role, with "resource",
+//     "role" and "members". The roles of role bundles are expanded.
+//   - duration: the duration in seconds, 0 if it is not known.
+//   - startTime: the start time in RFC 3339 format, omitted if it is not
+//     known.
+//   - requester, approvers and source: the provenance of the request, omitted
+//     if they are not known.
+func Input(w *v1alpha1.IAMRequestWrapper) map[string]any {
+	policies := make([]any, 0)
+	bindings := make([]any, 0)
+	if w.IAMRequest != nil {
+		for _, p := range w.ResourcePolicies {
+			pb := make([]any, 0, len(p.Bindings))
+			for _, b := range p.Bindings {
+				pb = append(pb, map[string]any{
+					"members":    b.Members,
+					"role":       b.Role,
+					"roleBundle": b.RoleBundle,
+				})
+				for _, role := range b.Roles() {
+					bindings = append(bindings, map[string]any{
+						"resource": p.Resource,
+						"role":     role,
+						"members":  b.Members,
+					})
+				}
+			}
+			policies = append(policies, map[string]any{
+				"resource":  p.Resource,
+				"bindings":  pb,
+				"dependsOn": p.DependsOn,
+			})
+		}
+	}
+
+	in := map[string]any{
+		"policies": policies,
+		"bindings": bindings,
+		"duration": int64(w.Duration / time.Second),
+	}
+	if !w.StartTime.IsZero() {
+		in["startTime"] = w.StartTime.UTC().Format(time.RFC3339)
+	}
+	if w.Requester != "" {
+		in["requester"] = w.Requester
+	}
+	if len(w.Approvers) > 0 {
+		in["approvers"] = w.Approvers
+	}
+	if w.Source != "" {
+		in["source"] = w.Source
+	}
+	return in
+}
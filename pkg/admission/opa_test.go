@@ -0,0 +1,190 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestOPAChecker_Check(t *testing.T) {
+	t.Parallel()
+
+	reqWrapper := &v1alpha1.IAMRequestWrapper{
+		IAMRequest: &v1alpha1.IAMRequest{
+			ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+				Resource: "projects/foo",
+				Bindings: []*v1alpha1.Binding{{
+					Members: []string{"user:alice@example.com"},
+					Role:    "roles/iam.roleViewer",
+				}},
+			}},
+		},
+		Duration:  2 * time.Hour,
+		StartTime: time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC),
+		Requester: "user:alice@example.com",
+	}
+	wantInput := map[string]any{
+		"input": map[string]any{
+			"policies": []any{map[string]any{
+				"resource": "projects/foo",
+				"bindings": []any{map[string]any{
+					"members":    []any{"user:alice@example.com"},
+					"role":       "roles/iam.roleViewer",
+					"roleBundle": "",
+				}},
+				"dependsOn": nil,
+			}},
+			"bindings": []any{map[string]any{
+				"resource": "projects/foo",
+				"role":     "roles/iam.roleViewer",
+				"members":  []any{"user:alice@example.com"},
+			}},
+			"duration":  float64(7200),
+			"startTime": "2009-11-10T23:00:00Z",
+			"requester": "user:alice@example.com",
+		},
+	}
+
+	cases := []struct {
+		name    string
+		status  int
+		resp    string
+		wantErr string
+	}{
+		{
+			name:   "allowed_bool",
+			status: http.StatusOK,
+			resp:   `{"result": true}`,
+		},
+		{
+			name:   "allowed_object",
+			status: http.StatusOK,
+			resp:   `{"result": {"allow": true}}`,
+		},
+		{
+			name:    "denied_bool",
+			status:  http.StatusOK,
+			resp:    `{"result": false}`,
+			wantErr: "request denied by admission policy",
+		},
+		{
+			name:    "denied_with_reasons",
+			status:  http.StatusOK,
+			resp:    `{"result": {"allow": false, "reasons": ["iam roles need approval", "too long"]}}`,
+			wantErr: "request denied by admission policy: iam roles need approval; too long",
+		},
+		{
+			name:    "undefined",
+			status:  http.StatusOK,
+			resp:    `{}`,
+			wantErr: "request denied by admission policy: decision is undefined",
+		},
+		{
+			name:    "no_allow",
+			status:  http.StatusOK,
+			resp:    `{"result": {"reasons": ["foo"]}}`,
+			wantErr: "request denied by admission policy: decision has no allow",
+		},
+		{
+			name:    "invalid_decision",
+			status:  http.StatusOK,
+			resp:    `{"result": "yes"}`,
+			wantErr: `decision must be a bool or an object with allow, got "yes"`,
+		},
+		{
+			name:    "server_error",
+			status:  http.StatusInternalServerError,
+			resp:    `{"code": "internal_error"}`,
+			wantErr: "unexpected opa response status 500",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got, want := r.Header.Get("Authorization"), "Bearer token"; got != want {
+					t.Errorf("authorization got %q, want %q", got, want)
+				}
+				var got map[string]any
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				if diff := cmp.Diff(wantInput, got); diff != "" {
+					t.Errorf("input got diff (-want, +got): %v", diff)
+				}
+				w.WriteHeader(tc.status)
+				fmt.Fprint(w, tc.resp)
+			}))
+			t.Cleanup(srv.Close)
+
+			c, err := NewOPAChecker(srv.Client(), srv.URL+"/v1/data/aod/allow", "token")
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = c.Check(context.Background(), reqWrapper)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected err: %s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestNewOPAChecker(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		url     string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			url:  "http://localhost:8181/v1/data/aod/allow",
+		},
+		{
+			name:    "missing_url",
+			wantErr: "opa url is required",
+		},
+		{
+			name:    "invalid_scheme",
+			url:     "localhost:8181",
+			wantErr: `opa url "localhost:8181" must be an http or https URL`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewOPAChecker(http.DefaultClient, tc.url, "")
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected err: %s", tc.name, diff)
+			}
+		})
+	}
+}
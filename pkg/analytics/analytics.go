@@ -0,0 +1,100 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package analytics reports anonymous usage of the AOD CLI to an endpoint
+// configured by the user, which helps the maintainers prioritize features. It
+// is only used when the user opts in.
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxResponseSize is the max size of the responses read, 64KiB.
+const maxResponseSize = 64 << 10
+
+// Event is an anonymous usage event of a CLI command. It must not contain any
+// data of the user, such as the flags, paths, resources, members or error
+// messages of the command.
+type Event struct {
+	// Command is the command run, e.g. "iam handle".
+	Command string `json:"command"`
+
+	// Version is the version of the CLI.
+	Version string `json:"version"`
+
+	// OS and Arch are the platform of the CLI, e.g. "linux" and "amd64".
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+
+	// LatencyMillis is the time the command took in milliseconds.
+	LatencyMillis int64 `json:"latencyMillis"`
+
+	// ErrorCategory is the category of the failure of the command, e.g.
+	// "validation", or empty if it succeeded.
+	ErrorCategory string `json:"errorCategory,omitempty"`
+}
+
+// Reporter reports usage events to an HTTP endpoint.
+type Reporter struct {
+	client   *http.Client
+	endpoint string
+}
+
+// NewReporter creates a new Reporter posting the events as JSON to the
+// endpoint URL, such as "https://analytics.example.com/aod".
+func NewReporter(client *http.Client, endpoint string) (*Reporter, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("analytics endpoint is required")
+	}
+	if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+		return nil, fmt.Errorf("analytics endpoint %q must be an http or https URL", endpoint)
+	}
+	return &Reporter{
+		client:   client,
+		endpoint: endpoint,
+	}, nil
+}
+
+// Report posts the event to the endpoint, and returns an error if the response
+// status is not 2xx.
+func (r *Reporter) Report(ctx context.Context, e *Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal analytics event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("failed to create analytics request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to report analytics event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+		return fmt.Errorf("unexpected analytics response status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
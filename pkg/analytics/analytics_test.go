@@ -0,0 +1,124 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestReporter_Report(t *testing.T) {
+	t.Parallel()
+
+	event := &Event{
+		Command:       "iam handle",
+		Version:       "1.0.0",
+		OS:            "linux",
+		Arch:          "amd64",
+		LatencyMillis: 1500,
+		ErrorCategory: "validation",
+	}
+
+	cases := []struct {
+		name     string
+		status   int
+		wantBody map[string]any
+		wantErr  string
+	}{
+		{
+			name:   "success",
+			status: http.StatusNoContent,
+			wantBody: map[string]any{
+				"command":       "iam handle",
+				"version":       "1.0.0",
+				"os":            "linux",
+				"arch":          "amd64",
+				"latencyMillis": float64(1500),
+				"errorCategory": "validation",
+			},
+		},
+		{
+			name:    "server_error",
+			status:  http.StatusInternalServerError,
+			wantErr: "unexpected analytics response status 500: injected error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var gotBody map[string]any
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&gotBody); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				w.WriteHeader(tc.status)
+				if tc.status >= 300 {
+					fmt.Fprint(w, "injected error")
+				}
+			}))
+			t.Cleanup(srv.Close)
+
+			r, err := NewReporter(srv.Client(), srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = r.Report(context.Background(), event)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error: %s", tc.name, diff)
+			}
+			if tc.wantBody != nil {
+				if diff := cmp.Diff(tc.wantBody, gotBody); diff != "" {
+					t.Errorf("Process(%+v) got request body diff (-want, +got):\n%s", tc.name, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestNewReporter(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		endpoint string
+		wantErr  string
+	}{
+		{name: "https", endpoint: "https://analytics.example.com/aod"},
+		{name: "missing", wantErr: "analytics endpoint is required"},
+		{name: "not_http", endpoint: "ftp://example.com", wantErr: `analytics endpoint "ftp://example.com" must be an http or https URL`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewReporter(http.DefaultClient, tc.endpoint)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error: %s", tc.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package approval verifies IAM requests are approved by other users than
+// their authors before they are handled.
+package approval
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var (
+	// prRegex matches a pull request in the format of "owner/repo/number".
+	prRegex = regexp.MustCompile(`^([^/\s]+)/([^/\s]+)/([0-9]+)$`)
+
+	// prURLRegex matches the URL of a GitHub pull request, such as
+	// "https://github.com/owner/repo/pull/1".
+	prURLRegex = regexp.MustCompile(`^https://[^/\s]+/([^/\s]+)/([^/\s]+)/pull/([0-9]+)/?$`)
+)
+
+// PullRequest identifies a GitHub pull request.
+type PullRequest struct {
+	Owner  string
+	Repo   string
+	Number int
+}
+
+// String returns the pull request in the format of "owner/repo/number".
+func (p *PullRequest) String() string {
+	return fmt.Sprintf("%s/%s/%d", p.Owner, p.Repo, p.Number)
+}
+
+// ParsePullRequest parses a pull request in the format of "owner/repo/number",
+// or its URL such as "https://github.com/owner/repo/pull/1".
+func ParsePullRequest(s string) (*PullRequest, error) {
+	m := prRegex.FindStringSubmatch(s)
+	if m == nil {
+		m = prURLRegex.FindStringSubmatch(s)
+	}
+	if m == nil {
+		return nil, fmt.Errorf("pull request %q isn't in the format of %q or a pull request URL", s, "owner/repo/number")
+	}
+	n, err := strconv.Atoi(m[3])
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("pull request %q has an invalid number", s)
+	}
+	return &PullRequest{Owner: m[1], Repo: m[2], Number: n}, nil
+}
+
+// Result is the result of a successful approval verification.
+type Result struct {
+	// Author of the pull request.
+	Author string
+
+	// Approvers are the distinct users other than the author who approved the
+	// latest commit of the pull request, sorted.
+	Approvers []string
+}
@@ -0,0 +1,84 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package approval
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestParsePullRequest(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		in      string
+		want    *PullRequest
+		wantErr string
+	}{
+		{
+			name: "owner_repo_number",
+			in:   "foo/bar/12",
+			want: &PullRequest{Owner: "foo", Repo: "bar", Number: 12},
+		},
+		{
+			name: "url",
+			in:   "https://github.com/foo/bar/pull/12",
+			want: &PullRequest{Owner: "foo", Repo: "bar", Number: 12},
+		},
+		{
+			name: "url_trailing_slash",
+			in:   "https://github.example.com/foo/bar/pull/12/",
+			want: &PullRequest{Owner: "foo", Repo: "bar", Number: 12},
+		},
+		{
+			name:    "missing_number",
+			in:      "foo/bar",
+			wantErr: `pull request "foo/bar" isn't in the format of "owner/repo/number"`,
+		},
+		{
+			name:    "issue_url",
+			in:      "https://github.com/foo/bar/issues/12",
+			wantErr: "isn't in the format",
+		},
+		{
+			name:    "zero_number",
+			in:      "foo/bar/0",
+			wantErr: `pull request "foo/bar/0" has an invalid number`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParsePullRequest(tc.in)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Process(%+v) got pull request diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if got != nil {
+				if got, want := got.String(), "foo/bar/12"; got != want {
+					t.Errorf("Process(%+v) got string %q, want %q", tc.name, got, want)
+				}
+			}
+		})
+	}
+}
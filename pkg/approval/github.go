@@ -0,0 +1,193 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+)
+
+// DefaultGitHubAPIURL is the URL of the GitHub REST API.
+const DefaultGitHubAPIURL = "https://api.github.com"
+
+// perPage is the page size of the list requests, which is the max of the
+// GitHub REST API.
+const perPage = 100
+
+// Review states of pull request reviews.
+const (
+	reviewStateApproved  = "APPROVED"
+	reviewStateCommented = "COMMENTED"
+)
+
+// GitHubVerifier verifies the approvals of request files in GitHub pull
+// requests.
+type GitHubVerifier struct {
+	client *http.Client
+	apiURL string
+	token  string
+}
+
+// NewGitHubVerifier creates a new GitHubVerifier with the token, which needs
+// permission to read pull requests. The apiURL is the URL of the GitHub REST
+// API, such as DefaultGitHubAPIURL.
+func NewGitHubVerifier(client *http.Client, apiURL, token string) (*GitHubVerifier, error) {
+	if token == "" {
+		return nil, fmt.Errorf("github token is required")
+	}
+	return &GitHubVerifier{
+		client: client,
+		apiURL: strings.TrimSuffix(apiURL, "/"),
+		token:  token,
+	}, nil
+}
+
+// githubPullRequest is a pull request in the GitHub REST API.
+type githubPullRequest struct {
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Head struct {
+		SHA string `json:"sha"`
+	} `json:"head"`
+}
+
+// githubFile is a file changed in a pull request in the GitHub REST API.
+type githubFile struct {
+	Filename string `json:"filename"`
+}
+
+// githubReview is a pull request review in the GitHub REST API.
+type githubReview struct {
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	State    string `json:"state"`
+	CommitID string `json:"commit_id"`
+}
+
+// Verify verifies the file is changed in the pull request, and the latest
+// commit of the pull request is approved by at least the required number of
+// distinct users other than its author. The file is a path in the repository,
+// or a local path of the file in a checkout of the repository.
+//
+// Only the latest review of each user counts, so approvals that are dismissed
+// or followed by requested changes do not count, and approvals of earlier
+// commits do not count since the file might have changed after them.
+func (v *GitHubVerifier) Verify(ctx context.Context, pr *PullRequest, file string, required int) (*Result, error) {
+	prPath := fmt.Sprintf("/repos/%s/%s/pulls/%d", pr.Owner, pr.Repo, pr.Number)
+
+	var p githubPullRequest
+	if err := v.get(ctx, prPath, &p); err != nil {
+		return nil, fmt.Errorf("failed to get pull request %s: %w", pr, err)
+	}
+
+	files, err := list[githubFile](ctx, v, prPath+"/files")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files of pull request %s: %w", pr, err)
+	}
+	if !slices.ContainsFunc(files, func(f *githubFile) bool { return matchFile(file, f.Filename) }) {
+		return nil, fmt.Errorf("file %q is not changed in pull request %s", file, pr)
+	}
+
+	reviews, err := list[githubReview](ctx, v, prPath+"/reviews")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reviews of pull request %s: %w", pr, err)
+	}
+
+	// Reviews are listed in chronological order, keep the latest review of each
+	// user. Comments do not change the review state of a user.
+	latest := make(map[string]*githubReview)
+	for _, r := range reviews {
+		if r.State == reviewStateCommented {
+			continue
+		}
+		latest[strings.ToLower(r.User.Login)] = r
+	}
+
+	author := strings.ToLower(p.User.Login)
+	approvers := make([]string, 0, len(latest))
+	for login, r := range latest {
+		if login == author || r.State != reviewStateApproved || r.CommitID != p.Head.SHA {
+			continue
+		}
+		approvers = append(approvers, r.User.Login)
+	}
+	slices.Sort(approvers)
+
+	if len(approvers) < required {
+		return nil, fmt.Errorf("pull request %s is approved by %d users other than the author %q at commit %s, %d required",
+			pr, len(approvers), p.User.Login, p.Head.SHA, required)
+	}
+	return &Result{Author: p.User.Login, Approvers: approvers}, nil
+}
+
+// matchFile reports whether the file is the repository file, either as the
+// path in the repository or as a local path ending with it.
+func matchFile(file, repoFile string) bool {
+	file = path.Clean(strings.ReplaceAll(file, "\\", "/"))
+	return file == repoFile || strings.HasSuffix(file, "/"+repoFile)
+}
+
+// list gets all pages of the list from the GitHub REST API.
+func list[T any](ctx context.Context, v *GitHubVerifier, p string) ([]*T, error) {
+	var all []*T
+	for page := 1; ; page++ {
+		var items []*T
+		if err := v.get(ctx, fmt.Sprintf("%s?per_page=%d&page=%d", p, perPage, page), &items); err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+		if len(items) < perPage {
+			return all, nil
+		}
+	}
+}
+
+// get sends a GET request to the GitHub REST API, and decodes the JSON response
+// to out.
+func (v *GitHubVerifier) get(ctx context.Context, p string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.apiURL+p, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+v.token)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Limit the response body to 4MiB.
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
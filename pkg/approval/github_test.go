@@ -0,0 +1,222 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestGitHubVerifier_Verify(t *testing.T) {
+	t.Parallel()
+
+	pr := &PullRequest{Owner: "foo", Repo: "bar", Number: 1}
+	review := func(login, state, commit string) *githubReview {
+		r := &githubReview{State: state, CommitID: commit}
+		r.User.Login = login
+		return r
+	}
+	manyFiles := make([]*githubFile, 0, 101)
+	for i := range 100 {
+		manyFiles = append(manyFiles, &githubFile{Filename: fmt.Sprintf("other/%d.yaml", i)})
+	}
+	manyFiles = append(manyFiles, &githubFile{Filename: "requests/iam.yaml"})
+
+	cases := []struct {
+		name     string
+		file     string
+		required int
+		prStatus int
+		files    []*githubFile
+		reviews  []*githubReview
+		want     *Result
+		wantErr  string
+	}{
+		{
+			name:     "approved",
+			file:     "requests/iam.yaml",
+			required: 2,
+			files:    []*githubFile{{Filename: "requests/iam.yaml"}},
+			reviews: []*githubReview{
+				review("bob", reviewStateApproved, "head"),
+				review("carol", reviewStateApproved, "head"),
+			},
+			want: &Result{Author: "alice", Approvers: []string{"bob", "carol"}},
+		},
+		{
+			name:     "local_path",
+			file:     "/home/runner/work/bar/bar/requests/iam.yaml",
+			required: 1,
+			files:    []*githubFile{{Filename: "requests/iam.yaml"}},
+			reviews:  []*githubReview{review("bob", reviewStateApproved, "head")},
+			want:     &Result{Author: "alice", Approvers: []string{"bob"}},
+		},
+		{
+			name:     "paginated_files",
+			file:     "requests/iam.yaml",
+			required: 1,
+			files:    manyFiles,
+			reviews:  []*githubReview{review("bob", reviewStateApproved, "head")},
+			want:     &Result{Author: "alice", Approvers: []string{"bob"}},
+		},
+		{
+			name:     "self_approval",
+			file:     "requests/iam.yaml",
+			required: 1,
+			files:    []*githubFile{{Filename: "requests/iam.yaml"}},
+			reviews:  []*githubReview{review("Alice", reviewStateApproved, "head")},
+			wantErr:  `pull request foo/bar/1 is approved by 0 users other than the author "alice" at commit head, 1 required`,
+		},
+		{
+			name:     "duplicate_approvals",
+			file:     "requests/iam.yaml",
+			required: 2,
+			files:    []*githubFile{{Filename: "requests/iam.yaml"}},
+			reviews: []*githubReview{
+				review("bob", reviewStateApproved, "head"),
+				review("bob", reviewStateApproved, "head"),
+			},
+			wantErr: "is approved by 1 users other than the author",
+		},
+		{
+			name:     "stale_approval",
+			file:     "requests/iam.yaml",
+			required: 1,
+			files:    []*githubFile{{Filename: "requests/iam.yaml"}},
+			reviews:  []*githubReview{review("bob", reviewStateApproved, "old")},
+			wantErr:  "is approved by 0 users other than the author",
+		},
+		{
+			name:     "changes_requested_after_approval",
+			file:     "requests/iam.yaml",
+			required: 1,
+			files:    []*githubFile{{Filename: "requests/iam.yaml"}},
+			reviews: []*githubReview{
+				review("bob", reviewStateApproved, "head"),
+				review("bob", "CHANGES_REQUESTED", "head"),
+			},
+			wantErr: "is approved by 0 users other than the author",
+		},
+		{
+			name:     "comment_after_approval",
+			file:     "requests/iam.yaml",
+			required: 1,
+			files:    []*githubFile{{Filename: "requests/iam.yaml"}},
+			reviews: []*githubReview{
+				review("bob", reviewStateApproved, "head"),
+				review("bob", reviewStateCommented, "head"),
+			},
+			want: &Result{Author: "alice", Approvers: []string{"bob"}},
+		},
+		{
+			name:     "file_not_changed",
+			file:     "requests/other.yaml",
+			required: 1,
+			files:    []*githubFile{{Filename: "requests/iam.yaml"}},
+			wantErr:  `file "requests/other.yaml" is not changed in pull request foo/bar/1`,
+		},
+		{
+			name:     "pull_request_not_found",
+			file:     "requests/iam.yaml",
+			required: 1,
+			prStatus: http.StatusNotFound,
+			wantErr:  "failed to get pull request foo/bar/1: unexpected response status 404",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got, want := r.Header.Get("Authorization"), "Bearer test-token"; got != want {
+					t.Errorf("authorization header got %q, want %q", got, want)
+				}
+				var resp any
+				switch r.URL.Path {
+				case "/repos/foo/bar/pulls/1":
+					if tc.prStatus != 0 {
+						w.WriteHeader(tc.prStatus)
+						fmt.Fprint(w, `{"message": "Not Found"}`)
+						return
+					}
+					resp = map[string]any{
+						"user": map[string]any{"login": "alice"},
+						"head": map[string]any{"sha": "head"},
+					}
+				case "/repos/foo/bar/pulls/1/files":
+					resp = page(t, r, tc.files)
+				case "/repos/foo/bar/pulls/1/reviews":
+					resp = page(t, r, tc.reviews)
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL)
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				if err := json.NewEncoder(w).Encode(resp); err != nil {
+					t.Errorf("failed to encode response: %v", err)
+				}
+			}))
+			t.Cleanup(srv.Close)
+
+			v, err := NewGitHubVerifier(srv.Client(), srv.URL+"/", "test-token")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := v.Verify(context.Background(), pr, tc.file, tc.required)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Process(%+v) got result diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestNewGitHubVerifier(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewGitHubVerifier(http.DefaultClient, DefaultGitHubAPIURL, ""); err == nil {
+		t.Errorf("NewGitHubVerifier() got no error, want error for empty token")
+	}
+}
+
+// page returns the page of the items in the request.
+func page[T any](tb testing.TB, r *http.Request, items []*T) []*T {
+	tb.Helper()
+
+	if got, want := r.URL.Query().Get("per_page"), strconv.Itoa(perPage); got != want {
+		tb.Errorf("per_page got %q, want %q", got, want)
+	}
+	p, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		tb.Errorf("invalid page: %v", err)
+		return nil
+	}
+	start := min((p-1)*perPage, len(items))
+	end := min(start+perPage, len(items))
+	return items[start:end]
+}
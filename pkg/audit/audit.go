@@ -0,0 +1,202 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit contains the AOD audit events and the sinks to write them to.
+package audit
+
+import (
+	"context"
+	"time"
+)
+
+// Types of audit events.
+const (
+	// EventTypeGrant is the type of events when IAM bindings are granted.
+	EventTypeGrant = "GRANT"
+
+	// EventTypeCleanup is the type of events when requested and expired AOD IAM
+	// bindings are removed.
+	EventTypeCleanup = "CLEANUP"
+
+	// EventTypeRenew is the type of events when the expiry of active AOD IAM
+	// bindings is extended.
+	EventTypeRenew = "RENEW"
+
+	// EventTypeRevoke is the type of events when a member is removed from all
+	// AOD IAM bindings.
+	EventTypeRevoke = "REVOKE"
+
+	// EventTypeRewrite is the type of events when a member is replaced with
+	// another member in all AOD IAM bindings, the bindings have the old and the
+	// new members.
+	EventTypeRewrite = "REWRITE"
+
+	// EventTypeRetitle is the type of events when the condition title of AOD
+	// IAM bindings is replaced, the condition title is the new title.
+	EventTypeRetitle = "RETITLE"
+
+	// EventTypeValidationDenied is the type of events when a request fails
+	// validation.
+	EventTypeValidationDenied = "VALIDATION_DENIED"
+
+	// EventTypeRollback is the type of events when a resource updated by a
+	// failed grant is restored to its IAM policy before the grant.
+	EventTypeRollback = "ROLLBACK"
+
+	// EventTypeUsage is the type of events with the activity of a member in
+	// Cloud Audit Logs during an expired grant.
+	EventTypeUsage = "USAGE"
+
+	// EventTypeCreateRole is the type of events when a temporary custom role is
+	// created for a custom role request.
+	EventTypeCreateRole = "CREATE_ROLE"
+
+	// EventTypeDeleteRole is the type of events when a temporary custom role is
+	// deleted, because it expired or failed to be bound.
+	EventTypeDeleteRole = "DELETE_ROLE"
+
+	// EventTypeCreateDenyPolicy is the type of events when a temporary IAM deny
+	// policy is created.
+	EventTypeCreateDenyPolicy = "CREATE_DENY_POLICY"
+
+	// EventTypeDeleteDenyPolicy is the type of events when a temporary IAM deny
+	// policy is deleted, because it expired or was cleaned up.
+	EventTypeDeleteDenyPolicy = "DELETE_DENY_POLICY"
+
+	// EventTypeAddGroupMember is the type of events when a member is added to a
+	// Google Group with an expiry, or its membership is extended.
+	EventTypeAddGroupMember = "ADD_GROUP_MEMBER"
+
+	// EventTypeRemoveGroupMember is the type of events when a temporary member
+	// is removed from a Google Group before its membership expires.
+	EventTypeRemoveGroupMember = "REMOVE_GROUP_MEMBER"
+)
+
+// Outcomes of audit events.
+const (
+	OutcomeSuccess = "SUCCESS"
+	OutcomeFailure = "FAILURE"
+)
+
+// Event is an AOD audit event. Its JSON encoding is the stable schema
+// documented in docs/audit.md, new fields may be added but existing fields
+// will not be renamed or removed.
+type Event struct {
+	// Type of the event, one of "GRANT", "CLEANUP", "RENEW", "REVOKE",
+	// "REWRITE", "RETITLE", "ROLLBACK", "VALIDATION_DENIED", "USAGE",
+	// "CREATE_ROLE", "DELETE_ROLE", "CREATE_DENY_POLICY", "DELETE_DENY_POLICY",
+	// "ADD_GROUP_MEMBER" and "REMOVE_GROUP_MEMBER".
+	Type string `json:"type"`
+
+	// Time when the event happened.
+	Time time.Time `json:"time"`
+
+	// Resource is the GCP resource of the IAM policy, if any, or the group of
+	// the membership in the format of "groups/<email>".
+	Resource string `json:"resource,omitempty"`
+
+	// Bindings are the requested IAM bindings.
+	Bindings []*Binding `json:"bindings,omitempty"`
+
+	// Expiry is the expiration time of granted IAM bindings, only set for
+	// "GRANT", "RENEW", "ROLLBACK", "USAGE" and "ADD_GROUP_MEMBER" events.
+	Expiry *time.Time `json:"expiry,omitempty"`
+
+	// ConditionTitle is the title of the AOD IAM bindings condition.
+	ConditionTitle string `json:"conditionTitle,omitempty"`
+
+	// Requester of the request, if provided.
+	Requester string `json:"requester,omitempty"`
+
+	// Approvers of the request, if provided.
+	Approvers []string `json:"approvers,omitempty"`
+
+	// Source of the request, if provided.
+	Source string `json:"source,omitempty"`
+
+	// RequestHash is the SHA256 hash of the request file, if provided.
+	RequestHash string `json:"requestHash,omitempty"`
+
+	// Caller is the identity that made the IAM policy change, if known.
+	Caller string `json:"caller,omitempty"`
+
+	// CorrelationID identifies the AOD run that made the IAM policy change, and
+	// is attached to the IAM calls as gRPC metadata, if provided.
+	CorrelationID string `json:"correlationId,omitempty"`
+
+	// Outcome of the event, one of "SUCCESS" and "FAILURE".
+	Outcome string `json:"outcome"`
+
+	// Error message when the outcome is "FAILURE".
+	Error string `json:"error,omitempty"`
+
+	// Usage is the activity of the member of the bindings during the grant,
+	// only set for "USAGE" events.
+	Usage *Usage `json:"usage,omitempty"`
+
+	// Permissions of the created custom role or the denied permissions of the
+	// created deny policy, only set for "CREATE_ROLE" and "CREATE_DENY_POLICY"
+	// events.
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// Usage is the activity of a member on a resource during a grant, found in
+// Cloud Audit Logs.
+type Usage struct {
+	// Member whose activity it is.
+	Member string `json:"member" yaml:"member"`
+
+	// Start and End of the grant.
+	Start time.Time `json:"start" yaml:"start"`
+	End   time.Time `json:"end" yaml:"end"`
+
+	// Calls is the number of API calls of the member.
+	Calls int `json:"calls" yaml:"calls"`
+
+	// Methods are the called API methods, by the most calls.
+	Methods []*MethodUsage `json:"methods,omitempty" yaml:"methods,omitempty"`
+
+	// Permissions are the sorted permissions granted to the calls.
+	Permissions []string `json:"permissions,omitempty" yaml:"permissions,omitempty"`
+
+	// Truncated is whether there were more calls than were read from the logs,
+	// so the usage is incomplete.
+	Truncated bool `json:"truncated,omitempty" yaml:"truncated,omitempty"`
+}
+
+// MethodUsage is the number of calls of an API method.
+type MethodUsage struct {
+	// Service of the method, e.g. "bigquery.googleapis.com".
+	Service string `json:"service" yaml:"service"`
+
+	// Method name, e.g. "google.cloud.bigquery.v2.JobService.InsertJob".
+	Method string `json:"method" yaml:"method"`
+
+	// Calls is the number of calls of the method.
+	Calls int `json:"calls" yaml:"calls"`
+}
+
+// Binding associates IAM members with a role.
+type Binding struct {
+	// Role of the binding.
+	Role string `json:"role"`
+
+	// Members of the binding.
+	Members []string `json:"members"`
+}
+
+// Sink writes audit events.
+type Sink interface {
+	Write(ctx context.Context, e *Event) error
+}
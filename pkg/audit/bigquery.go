@@ -0,0 +1,199 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	bigquery "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+// bigQueryTableRegex matches a BigQuery table in the format of
+// "project.dataset.table".
+var bigQueryTableRegex = regexp.MustCompile(`^([^.]+)\.([^.]+)\.([^.]+)$`)
+
+// BigQuerySink writes audit events to a BigQuery table, one row per role of
+// the event bindings. The table schema is returned by BigQuerySchema.
+type BigQuerySink struct {
+	service   *bigquery.Service
+	projectID string
+	datasetID string
+	tableID   string
+}
+
+// NewBigQuerySink creates a new BigQuerySink writing to the table, in the
+// format of "project.dataset.table".
+func NewBigQuerySink(ctx context.Context, table string, opts ...option.ClientOption) (*BigQuerySink, error) {
+	if err := ValidateBigQueryTable(table); err != nil {
+		return nil, err
+	}
+	svc, err := bigquery.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bigquery service: %w", err)
+	}
+	m := bigQueryTableRegex.FindStringSubmatch(table)
+	return &BigQuerySink{
+		service:   svc,
+		projectID: m[1],
+		datasetID: m[2],
+		tableID:   m[3],
+	}, nil
+}
+
+// ValidateBigQueryTable checks the table is in the format of
+// "project.dataset.table".
+func ValidateBigQueryTable(table string) error {
+	if !bigQueryTableRegex.MatchString(table) {
+		return fmt.Errorf("table %q isn't in the format of %q", table, "project.dataset.table")
+	}
+	return nil
+}
+
+// Write inserts the rows of the event to the table.
+func (s *BigQuerySink) Write(ctx context.Context, e *Event) error {
+	req := &bigquery.TableDataInsertAllRequest{Rows: bigQueryRows(e)}
+	resp, err := s.service.Tabledata.InsertAll(s.projectID, s.datasetID, s.tableID, req).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("failed to insert rows: %w", err)
+	}
+
+	var retErr error
+	for _, ie := range resp.InsertErrors {
+		for _, ep := range ie.Errors {
+			retErr = errors.Join(retErr, fmt.Errorf("failed to insert row %d: %s", ie.Index, ep.Message))
+		}
+	}
+	return retErr
+}
+
+// bigQueryRows converts the event to table rows, one row per role of the
+// bindings, or a single row without role and members if there is no binding.
+func bigQueryRows(e *Event) []*bigquery.TableDataInsertAllRequestRows {
+	base := map[string]bigquery.JsonValue{
+		"time":    e.Time.UTC().Format(time.RFC3339Nano),
+		"type":    e.Type,
+		"outcome": e.Outcome,
+	}
+	optional := map[string]string{
+		"resource":        e.Resource,
+		"condition_title": e.ConditionTitle,
+		"requester":       e.Requester,
+		"source":          e.Source,
+		"request_hash":    e.RequestHash,
+		"error":           e.Error,
+	}
+	for k, v := range optional {
+		if v != "" {
+			base[k] = v
+		}
+	}
+	if e.Expiry != nil {
+		base["expiry"] = e.Expiry.UTC().Format(time.RFC3339Nano)
+	}
+	if len(e.Approvers) > 0 {
+		base["approvers"] = e.Approvers
+	}
+	if e.Usage != nil {
+		// JSON columns are inserted as JSON strings.
+		if b, err := json.Marshal(e.Usage); err == nil {
+			base["usage"] = string(b)
+		}
+	}
+
+	if len(e.Bindings) == 0 {
+		return []*bigquery.TableDataInsertAllRequestRows{{Json: base}}
+	}
+
+	rows := make([]*bigquery.TableDataInsertAllRequestRows, 0, len(e.Bindings))
+	for _, b := range e.Bindings {
+		row := make(map[string]bigquery.JsonValue, len(base)+2)
+		for k, v := range base {
+			row[k] = v
+		}
+		row["role"] = b.Role
+		row["members"] = b.Members
+		rows = append(rows, &bigquery.TableDataInsertAllRequestRows{Json: row})
+	}
+	return rows
+}
+
+// BigQuerySchema returns the schema of the BigQuery table of AOD audit events.
+// Like the JSON schema of the events, new columns may be added but existing
+// columns will not be renamed or removed.
+func BigQuerySchema() *bigquery.TableSchema {
+	return &bigquery.TableSchema{
+		Fields: []*bigquery.TableFieldSchema{
+			{Name: "time", Type: "TIMESTAMP", Mode: "REQUIRED", Description: "When the event happened."},
+			{Name: "type", Type: "STRING", Mode: "REQUIRED", Description: "The type of the event."},
+			{Name: "resource", Type: "STRING", Description: "The GCP resource of the IAM policy."},
+			{Name: "role", Type: "STRING", Description: "The role of the requested binding."},
+			{Name: "members", Type: "STRING", Mode: "REPEATED", Description: "The members of the requested binding."},
+			{Name: "expiry", Type: "TIMESTAMP", Description: "When the granted binding expires."},
+			{Name: "condition_title", Type: "STRING", Description: "The title of the AOD IAM bindings condition."},
+			{Name: "requester", Type: "STRING", Description: "The requester of the request."},
+			{Name: "approvers", Type: "STRING", Mode: "REPEATED", Description: "The approvers of the request."},
+			{Name: "source", Type: "STRING", Description: "The source of the request."},
+			{Name: "request_hash", Type: "STRING", Description: "The SHA256 hash of the request file."},
+			{Name: "outcome", Type: "STRING", Mode: "REQUIRED", Description: "The outcome of the event."},
+			{Name: "error", Type: "STRING", Description: "The error message of a failed event."},
+			{Name: "usage", Type: "JSON", Description: "The activity of the member during the grant of a usage event."},
+		},
+	}
+}
+
+// CreateBigQueryTable creates the table, in the format of
+// "project.dataset.table", with the schema returned by BigQuerySchema and
+// daily partitioning on the event time. It is skipped if the table already
+// exists, the dataset must exist.
+func CreateBigQueryTable(ctx context.Context, table string, opts ...option.ClientOption) error {
+	if err := ValidateBigQueryTable(table); err != nil {
+		return err
+	}
+	svc, err := bigquery.NewService(ctx, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create bigquery service: %w", err)
+	}
+
+	m := bigQueryTableRegex.FindStringSubmatch(table)
+	t := &bigquery.Table{
+		TableReference: &bigquery.TableReference{
+			ProjectId: m[1],
+			DatasetId: m[2],
+			TableId:   m[3],
+		},
+		Description: "AOD audit events.",
+		Schema:      BigQuerySchema(),
+		TimePartitioning: &bigquery.TimePartitioning{
+			Type:  "DAY",
+			Field: "time",
+		},
+	}
+	if _, err := svc.Tables.Insert(m[1], m[2], t).Context(ctx).Do(); err != nil {
+		var gErr *googleapi.Error
+		if errors.As(err, &gErr) && gErr.Code == http.StatusConflict {
+			return nil
+		}
+		return fmt.Errorf("failed to create table %q: %w", table, err)
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestBigQuerySink_Write(t *testing.T) {
+	t.Parallel()
+
+	expiry := time.Date(2009, 11, 11, 1, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name     string
+		event    *Event
+		status   int
+		respBody string
+		wantRows []map[string]any
+		wantErr  string
+	}{
+		{
+			name: "row_per_role",
+			event: &Event{
+				Type:     EventTypeGrant,
+				Time:     time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC),
+				Resource: "projects/baz",
+				Bindings: []*Binding{
+					{Role: "roles/viewer", Members: []string{"user:test-user@example.com"}},
+					{Role: "roles/editor", Members: []string{"user:test-user@example.com"}},
+				},
+				Expiry:         &expiry,
+				ConditionTitle: "abcxyz-aod-expiry",
+				Requester:      "user:requester@example.com",
+				Approvers:      []string{"user:approver@example.com"},
+				Outcome:        OutcomeSuccess,
+			},
+			status:   http.StatusOK,
+			respBody: `{}`,
+			wantRows: []map[string]any{
+				{
+					"time":            "2009-11-10T23:00:00Z",
+					"type":            "GRANT",
+					"resource":        "projects/baz",
+					"role":            "roles/viewer",
+					"members":         []any{"user:test-user@example.com"},
+					"expiry":          "2009-11-11T01:00:00Z",
+					"condition_title": "abcxyz-aod-expiry",
+					"requester":       "user:requester@example.com",
+					"approvers":       []any{"user:approver@example.com"},
+					"outcome":         "SUCCESS",
+				},
+				{
+					"time":            "2009-11-10T23:00:00Z",
+					"type":            "GRANT",
+					"resource":        "projects/baz",
+					"role":            "roles/editor",
+					"members":         []any{"user:test-user@example.com"},
+					"expiry":          "2009-11-11T01:00:00Z",
+					"condition_title": "abcxyz-aod-expiry",
+					"requester":       "user:requester@example.com",
+					"approvers":       []any{"user:approver@example.com"},
+					"outcome":         "SUCCESS",
+				},
+			},
+		},
+		{
+			name: "no_bindings",
+			event: &Event{
+				Type:    EventTypeValidationDenied,
+				Time:    time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC),
+				Outcome: OutcomeFailure,
+				Error:   "policies not found",
+			},
+			status:   http.StatusOK,
+			respBody: `{}`,
+			wantRows: []map[string]any{
+				{
+					"time":    "2009-11-10T23:00:00Z",
+					"type":    "VALIDATION_DENIED",
+					"outcome": "FAILURE",
+					"error":   "policies not found",
+				},
+			},
+		},
+		{
+			name: "insert_errors",
+			event: &Event{
+				Type:    EventTypeCleanup,
+				Time:    time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC),
+				Outcome: OutcomeSuccess,
+			},
+			status:   http.StatusOK,
+			respBody: `{"insertErrors":[{"index":0,"errors":[{"message":"no such field: foo"}]}]}`,
+			wantErr:  "failed to insert row 0: no such field: foo",
+		},
+		{
+			name: "insert_failure",
+			event: &Event{
+				Type:    EventTypeCleanup,
+				Outcome: OutcomeSuccess,
+			},
+			status:   http.StatusForbidden,
+			respBody: `{}`,
+			wantErr:  "failed to insert rows",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			var mu sync.Mutex
+			var gotPath string
+			var got struct {
+				Rows []struct {
+					JSON map[string]any `json:"json"`
+				} `json:"rows"`
+			}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				gotPath = r.URL.Path
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.respBody)) //nolint:errcheck // Best effort.
+			}))
+			t.Cleanup(srv.Close)
+
+			s, err := NewBigQuerySink(ctx, "test-project.aod.audit",
+				option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotErr := s.Write(ctx, tc.event)
+			if diff := testutil.DiffErrString(gotErr, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error: %s", tc.name, diff)
+			}
+			if tc.wantErr != "" {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if got, want := gotPath, "/projects/test-project/datasets/aod/tables/audit/insertAll"; got != want {
+				t.Errorf("path got %q, want %q", got, want)
+			}
+			gotRows := make([]map[string]any, 0, len(got.Rows))
+			for _, r := range got.Rows {
+				gotRows = append(gotRows, r.JSON)
+			}
+			if diff := cmp.Diff(tc.wantRows, gotRows); diff != "" {
+				t.Errorf("rows (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCreateBigQueryTable(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		table   string
+		status  int
+		wantErr string
+	}{
+		{
+			name:   "created",
+			table:  "test-project.aod.audit",
+			status: http.StatusOK,
+		},
+		{
+			name:   "already_exists",
+			table:  "test-project.aod.audit",
+			status: http.StatusConflict,
+		},
+		{
+			name:    "create_failure",
+			table:   "test-project.aod.audit",
+			status:  http.StatusForbidden,
+			wantErr: `failed to create table "test-project.aod.audit"`,
+		},
+		{
+			name:    "invalid_table",
+			table:   "aod.audit",
+			wantErr: `table "aod.audit" isn't in the format of "project.dataset.table"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			var mu sync.Mutex
+			var gotPath string
+			var got struct {
+				TableReference struct {
+					TableID string `json:"tableId"`
+				} `json:"tableReference"`
+				Schema struct {
+					Fields []map[string]any `json:"fields"`
+				} `json:"schema"`
+				TimePartitioning map[string]any `json:"timePartitioning"`
+			}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				gotPath = r.URL.Path
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				w.WriteHeader(tc.status)
+				w.Write([]byte(`{}`)) //nolint:errcheck // Best effort.
+			}))
+			t.Cleanup(srv.Close)
+
+			gotErr := CreateBigQueryTable(ctx, tc.table,
+				option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+			if diff := testutil.DiffErrString(gotErr, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error: %s", tc.name, diff)
+			}
+			if tc.wantErr != "" {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if got, want := gotPath, "/projects/test-project/datasets/aod/tables"; got != want {
+				t.Errorf("path got %q, want %q", got, want)
+			}
+			if got, want := got.TableReference.TableID, "audit"; got != want {
+				t.Errorf("table ID got %q, want %q", got, want)
+			}
+			if got, want := len(got.Schema.Fields), len(BigQuerySchema().Fields); got != want {
+				t.Errorf("got %d schema fields, want %d", got, want)
+			}
+			if diff := cmp.Diff(map[string]any{"type": "DAY", "field": "time"}, got.TimePartitioning); diff != "" {
+				t.Errorf("time partitioning (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+
+	"cloud.google.com/go/compute/metadata"
+	"golang.org/x/oauth2/google"
+)
+
+// impersonationURLRegex matches the service account in the impersonation URL
+// of external account credentials, such as GitHub Actions workload identity
+// federation.
+var impersonationURLRegex = regexp.MustCompile(`/serviceAccounts/([^/:]+):generateAccessToken$`)
+
+// DetectCaller returns the identity of the application default credentials,
+// in the format of an IAM member such as "serviceAccount:<email>". It is best
+// effort, an empty string is returned if the identity cannot be detected, such
+// as for user credentials.
+func DetectCaller(ctx context.Context) string {
+	creds, err := google.FindDefaultCredentials(ctx)
+	if err != nil {
+		return ""
+	}
+	if len(creds.JSON) > 0 {
+		return callerFromCredentialsJSON(creds.JSON)
+	}
+	if metadata.OnGCE() {
+		email, err := metadata.EmailWithContext(ctx, "default")
+		if err != nil {
+			return ""
+		}
+		return "serviceAccount:" + email
+	}
+	return ""
+}
+
+// callerFromCredentialsJSON returns the service account of the credentials
+// JSON file, or an empty string if there is none.
+func callerFromCredentialsJSON(data []byte) string {
+	var f struct {
+		ClientEmail      string `json:"client_email"`
+		ImpersonationURL string `json:"service_account_impersonation_url"`
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return ""
+	}
+	if f.ClientEmail != "" {
+		return "serviceAccount:" + f.ClientEmail
+	}
+	if m := impersonationURLRegex.FindStringSubmatch(f.ImpersonationURL); m != nil {
+		return "serviceAccount:" + m[1]
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"testing"
+)
+
+func TestCallerFromCredentialsJSON(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			name: "service_account_key",
+			data: `{"type": "service_account", "client_email": "aod@test-project.iam.gserviceaccount.com"}`,
+			want: "serviceAccount:aod@test-project.iam.gserviceaccount.com",
+		},
+		{
+			name: "external_account_impersonation",
+			data: `{
+				"type": "external_account",
+				"service_account_impersonation_url": "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/aod@test-project.iam.gserviceaccount.com:generateAccessToken"
+			}`,
+			want: "serviceAccount:aod@test-project.iam.gserviceaccount.com",
+		},
+		{
+			name: "authorized_user",
+			data: `{"type": "authorized_user", "client_id": "foo"}`,
+		},
+		{
+			name: "invalid_json",
+			data: `bananas`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := callerFromCredentialsJSON([]byte(tc.data)); got != tc.want {
+				t.Errorf("callerFromCredentialsJSON got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
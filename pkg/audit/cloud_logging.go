@@ -0,0 +1,150 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	logging "google.golang.org/api/logging/v2"
+	"google.golang.org/api/option"
+)
+
+// DefaultLogID is the default Cloud Logging log ID of AOD audit events.
+const DefaultLogID = "aod-audit"
+
+// CloudLoggingSink writes audit events to Cloud Logging as structured log
+// entries, the event is the jsonPayload of the log entry.
+type CloudLoggingSink struct {
+	service *logging.Service
+	logName string
+}
+
+// NewCloudLoggingSink creates a new CloudLoggingSink writing to the
+// "aod-audit" log of the given project.
+func NewCloudLoggingSink(ctx context.Context, projectID string, opts ...option.ClientOption) (*CloudLoggingSink, error) {
+	svc, err := logging.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create logging service: %w", err)
+	}
+	return &CloudLoggingSink{
+		service: svc,
+		logName: LogName(projectID),
+	}, nil
+}
+
+// LogName returns the full log name of AOD audit events in the project.
+func LogName(projectID string) string {
+	return fmt.Sprintf("projects/%s/logs/%s", projectID, DefaultLogID)
+}
+
+// Write writes the event to Cloud Logging.
+func (s *CloudLoggingSink) Write(ctx context.Context, e *Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	severity := "NOTICE"
+	if e.Outcome == OutcomeFailure {
+		severity = "WARNING"
+	}
+
+	req := &logging.WriteLogEntriesRequest{
+		LogName:  s.logName,
+		Resource: &logging.MonitoredResource{Type: "global"},
+		Entries: []*logging.LogEntry{
+			{
+				JsonPayload: googleapi.RawMessage(payload),
+				Severity:    severity,
+				Timestamp:   e.Time.UTC().Format(time.RFC3339Nano),
+			},
+		},
+	}
+	if _, err := s.service.Entries.Write(req).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to write log entries: %w", err)
+	}
+	return nil
+}
+
+// LogMetrics returns the recommended log-based metrics over the AOD audit
+// events in the project. See docs/audit.md for the alerting recipes built on
+// them.
+func LogMetrics(projectID string) []*logging.LogMetric {
+	base := fmt.Sprintf("logName=%q", LogName(projectID))
+	resourceLabel := &logging.MetricDescriptor{
+		MetricKind: "DELTA",
+		ValueType:  "INT64",
+		Labels: []*logging.LabelDescriptor{
+			{Key: "resource", ValueType: "STRING", Description: "The GCP resource of the IAM policy."},
+		},
+	}
+	resourceExtractor := map[string]string{"resource": "EXTRACT(jsonPayload.resource)"}
+
+	return []*logging.LogMetric{
+		{
+			Name:             "aod_grants",
+			Description:      "Number of AOD IAM grants.",
+			Filter:           fmt.Sprintf(`%s AND jsonPayload.type="%s" AND jsonPayload.outcome="%s"`, base, EventTypeGrant, OutcomeSuccess),
+			MetricDescriptor: resourceLabel,
+			LabelExtractors:  resourceExtractor,
+		},
+		{
+			Name:             "aod_cleanups",
+			Description:      "Number of AOD IAM cleanups.",
+			Filter:           fmt.Sprintf(`%s AND jsonPayload.type="%s" AND jsonPayload.outcome="%s"`, base, EventTypeCleanup, OutcomeSuccess),
+			MetricDescriptor: resourceLabel,
+			LabelExtractors:  resourceExtractor,
+		},
+		{
+			Name:             "aod_failures",
+			Description:      "Number of failed AOD IAM grants and cleanups.",
+			Filter:           fmt.Sprintf(`%s AND jsonPayload.outcome="%s"`, base, OutcomeFailure),
+			MetricDescriptor: resourceLabel,
+			LabelExtractors:  resourceExtractor,
+		},
+		{
+			Name:        "aod_validation_denied",
+			Description: "Number of AOD requests denied by validation.",
+			Filter:      fmt.Sprintf(`%s AND jsonPayload.type="%s"`, base, EventTypeValidationDenied),
+		},
+	}
+}
+
+// CreateLogMetrics creates the log-based metrics returned by LogMetrics in the
+// project, metrics that already exist are skipped.
+func CreateLogMetrics(ctx context.Context, projectID string, opts ...option.ClientOption) (retErr error) {
+	svc, err := logging.NewService(ctx, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create logging service: %w", err)
+	}
+
+	parent := "projects/" + projectID
+	for _, m := range LogMetrics(projectID) {
+		if _, err := svc.Projects.Metrics.Create(parent, m).Context(ctx).Do(); err != nil {
+			var gErr *googleapi.Error
+			if errors.As(err, &gErr) && gErr.Code == http.StatusConflict {
+				continue
+			}
+			retErr = errors.Join(retErr, fmt.Errorf("failed to create log metric %q: %w", m.Name, err))
+		}
+	}
+	return retErr
+}
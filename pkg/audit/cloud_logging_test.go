@@ -0,0 +1,170 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	logging "google.golang.org/api/logging/v2"
+	"google.golang.org/api/option"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestCloudLoggingSink_Write(t *testing.T) {
+	t.Parallel()
+
+	expiry := time.Date(2009, 11, 11, 1, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name         string
+		event        *Event
+		status       int
+		wantSeverity string
+		wantPayload  map[string]any
+		wantErr      string
+	}{
+		{
+			name: "success",
+			event: &Event{
+				Type:           EventTypeGrant,
+				Time:           time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC),
+				Resource:       "projects/baz",
+				Bindings:       []*Binding{{Role: "roles/viewer", Members: []string{"user:test-user@example.com"}}},
+				Expiry:         &expiry,
+				ConditionTitle: "abcxyz-aod-expiry",
+				Outcome:        OutcomeSuccess,
+			},
+			status:       http.StatusOK,
+			wantSeverity: "NOTICE",
+			wantPayload: map[string]any{
+				"type":     "GRANT",
+				"time":     "2009-11-10T23:00:00Z",
+				"resource": "projects/baz",
+				"bindings": []any{
+					map[string]any{"role": "roles/viewer", "members": []any{"user:test-user@example.com"}},
+				},
+				"expiry":         "2009-11-11T01:00:00Z",
+				"conditionTitle": "abcxyz-aod-expiry",
+				"outcome":        "SUCCESS",
+			},
+		},
+		{
+			name: "failure_outcome",
+			event: &Event{
+				Type:    EventTypeValidationDenied,
+				Time:    time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC),
+				Outcome: OutcomeFailure,
+				Error:   "policies not found",
+			},
+			status:       http.StatusOK,
+			wantSeverity: "WARNING",
+			wantPayload: map[string]any{
+				"type":    "VALIDATION_DENIED",
+				"time":    "2009-11-10T23:00:00Z",
+				"outcome": "FAILURE",
+				"error":   "policies not found",
+			},
+		},
+		{
+			name: "write_failure",
+			event: &Event{
+				Type:    EventTypeCleanup,
+				Outcome: OutcomeSuccess,
+			},
+			status:  http.StatusForbidden,
+			wantErr: "failed to write log entries",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			var mu sync.Mutex
+			var got logging.WriteLogEntriesRequest
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				w.WriteHeader(tc.status)
+				w.Write([]byte(`{}`)) //nolint:errcheck // Best effort.
+			}))
+			t.Cleanup(srv.Close)
+
+			s, err := NewCloudLoggingSink(ctx, "test-project",
+				option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotErr := s.Write(ctx, tc.event)
+			if diff := testutil.DiffErrString(gotErr, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error: %s", tc.name, diff)
+			}
+			if tc.wantErr != "" {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if got, want := got.LogName, "projects/test-project/logs/aod-audit"; got != want {
+				t.Errorf("log name got %q, want %q", got, want)
+			}
+			if len(got.Entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(got.Entries))
+			}
+			if got, want := got.Entries[0].Severity, tc.wantSeverity; got != want {
+				t.Errorf("severity got %q, want %q", got, want)
+			}
+			var gotPayload map[string]any
+			if err := json.Unmarshal(got.Entries[0].JsonPayload, &gotPayload); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantPayload, gotPayload); diff != "" {
+				t.Errorf("Process(%+v) got payload diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestLogMetrics(t *testing.T) {
+	t.Parallel()
+
+	want := map[string]string{
+		"aod_grants":            `logName="projects/p/logs/aod-audit" AND jsonPayload.type="GRANT" AND jsonPayload.outcome="SUCCESS"`,
+		"aod_cleanups":          `logName="projects/p/logs/aod-audit" AND jsonPayload.type="CLEANUP" AND jsonPayload.outcome="SUCCESS"`,
+		"aod_failures":          `logName="projects/p/logs/aod-audit" AND jsonPayload.outcome="FAILURE"`,
+		"aod_validation_denied": `logName="projects/p/logs/aod-audit" AND jsonPayload.type="VALIDATION_DENIED"`,
+	}
+
+	got := make(map[string]string)
+	for _, m := range LogMetrics("p") {
+		got[m.Name] = m.Filter
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("LogMetrics got diff (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+
+	"github.com/abcxyz/pkg/logging"
+)
+
+// LogMessage is the message of the structured log entries of audit events.
+const LogMessage = "AOD audit event"
+
+// LogKey is the key of the audit event in the structured log entries.
+const LogKey = "auditEvent"
+
+// LogSink writes audit events as structured log entries with the logger in
+// the context. With the JSON log format, the event is in the "auditEvent"
+// field of the log entry, which Cloud Logging parses as part of the
+// jsonPayload when the logs are collected from Cloud Run, GKE and Compute
+// Engine.
+type LogSink struct{}
+
+// NewLogSink creates a new LogSink.
+func NewLogSink() *LogSink {
+	return &LogSink{}
+}
+
+// Write writes the event as a structured log entry, with severity "WARNING"
+// for "FAILURE" events and "NOTICE" for others.
+func (s *LogSink) Write(ctx context.Context, e *Event) error {
+	level := logging.LevelNotice
+	if e.Outcome == OutcomeFailure {
+		level = logging.LevelWarning
+	}
+	logging.FromContext(ctx).Log(ctx, level, LogMessage, LogKey, e)
+	return nil
+}
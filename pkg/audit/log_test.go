@@ -0,0 +1,112 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/logging"
+)
+
+func TestLogSink_Write(t *testing.T) {
+	t.Parallel()
+
+	expiry := time.Date(2009, 11, 11, 1, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name         string
+		event        *Event
+		wantSeverity string
+		wantEvent    map[string]any
+	}{
+		{
+			name: "success",
+			event: &Event{
+				Type:           EventTypeGrant,
+				Time:           time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC),
+				Resource:       "projects/baz",
+				Bindings:       []*Binding{{Role: "roles/viewer", Members: []string{"user:test-user@example.com"}}},
+				Expiry:         &expiry,
+				ConditionTitle: "abcxyz-aod-expiry",
+				Outcome:        OutcomeSuccess,
+				Caller:         "serviceAccount:aod@test-project.iam.gserviceaccount.com",
+			},
+			wantSeverity: "NOTICE",
+			wantEvent: map[string]any{
+				"type":     "GRANT",
+				"time":     "2009-11-10T23:00:00Z",
+				"resource": "projects/baz",
+				"bindings": []any{
+					map[string]any{"role": "roles/viewer", "members": []any{"user:test-user@example.com"}},
+				},
+				"expiry":         "2009-11-11T01:00:00Z",
+				"conditionTitle": "abcxyz-aod-expiry",
+				"outcome":        "SUCCESS",
+				"caller":         "serviceAccount:aod@test-project.iam.gserviceaccount.com",
+			},
+		},
+		{
+			name: "failure_outcome",
+			event: &Event{
+				Type:     EventTypeCleanup,
+				Time:     time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC),
+				Resource: "projects/baz",
+				Outcome:  OutcomeFailure,
+				Error:    "injected error",
+			},
+			wantSeverity: "WARNING",
+			wantEvent: map[string]any{
+				"type":     "CLEANUP",
+				"time":     "2009-11-10T23:00:00Z",
+				"resource": "projects/baz",
+				"outcome":  "FAILURE",
+				"error":    "injected error",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			ctx := logging.WithLogger(context.Background(),
+				logging.New(&buf, logging.LevelInfo, logging.FormatJSON, false))
+
+			if err := NewLogSink().Write(ctx, tc.event); err != nil {
+				t.Fatal(err)
+			}
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to unmarshal log entry %q: %v", buf.String(), err)
+			}
+			if got, want := got["severity"], tc.wantSeverity; got != want {
+				t.Errorf("severity got %q, want %q", got, want)
+			}
+			if got, want := got["message"], LogMessage; got != want {
+				t.Errorf("message got %q, want %q", got, want)
+			}
+			if diff := cmp.Diff(tc.wantEvent, got[LogKey]); diff != "" {
+				t.Errorf("Process(%+v) got event diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"google.golang.org/api/option"
+	pubsub "google.golang.org/api/pubsub/v1"
+)
+
+// topicRegex matches the full name of a Pub/Sub topic.
+var topicRegex = regexp.MustCompile(`^projects/[^/]+/topics/[^/]+$`)
+
+// PubSubPublisher publishes audit events to a Pub/Sub topic as JSON messages,
+// with the event type and resource as message attributes for filtering.
+type PubSubPublisher struct {
+	service *pubsub.Service
+	topic   string
+}
+
+// NewPubSubPublisher creates a new PubSubPublisher publishing to the topic, in
+// the format of "projects/{project}/topics/{topic}".
+func NewPubSubPublisher(ctx context.Context, topic string, opts ...option.ClientOption) (*PubSubPublisher, error) {
+	if err := ValidateTopic(topic); err != nil {
+		return nil, err
+	}
+	svc, err := pubsub.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pubsub service: %w", err)
+	}
+	return &PubSubPublisher{
+		service: svc,
+		topic:   topic,
+	}, nil
+}
+
+// ValidateTopic checks the topic is the full name of a Pub/Sub topic.
+func ValidateTopic(topic string) error {
+	if !topicRegex.MatchString(topic) {
+		return fmt.Errorf("topic %q isn't in the format of %q", topic, "projects/{project}/topics/{topic}")
+	}
+	return nil
+}
+
+// Publish publishes the event to the topic.
+func (p *PubSubPublisher) Publish(ctx context.Context, e *Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	attrs := map[string]string{"type": e.Type}
+	if e.Resource != "" {
+		attrs["resource"] = e.Resource
+	}
+
+	req := &pubsub.PublishRequest{
+		Messages: []*pubsub.PubsubMessage{
+			{
+				Data:       base64.StdEncoding.EncodeToString(data),
+				Attributes: attrs,
+			},
+		},
+	}
+	if _, err := p.service.Projects.Topics.Publish(p.topic, req).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+	return nil
+}
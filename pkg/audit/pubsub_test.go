@@ -0,0 +1,171 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	pubsub "google.golang.org/api/pubsub/v1"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestPubSubPublisher_Publish(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		event     *Event
+		status    int
+		wantAttrs map[string]string
+		wantData  map[string]any
+		wantErr   string
+	}{
+		{
+			name: "success",
+			event: &Event{
+				Type:     EventTypeCleanup,
+				Time:     time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC),
+				Resource: "projects/baz",
+				Bindings: []*Binding{{Role: "roles/viewer", Members: []string{"user:test-user@example.com"}}},
+				Outcome:  OutcomeSuccess,
+			},
+			status:    http.StatusOK,
+			wantAttrs: map[string]string{"type": "CLEANUP", "resource": "projects/baz"},
+			wantData: map[string]any{
+				"type":     "CLEANUP",
+				"time":     "2009-11-10T23:00:00Z",
+				"resource": "projects/baz",
+				"bindings": []any{
+					map[string]any{"role": "roles/viewer", "members": []any{"user:test-user@example.com"}},
+				},
+				"outcome": "SUCCESS",
+			},
+		},
+		{
+			name: "publish_failure",
+			event: &Event{
+				Type:    EventTypeGrant,
+				Outcome: OutcomeSuccess,
+			},
+			status:  http.StatusForbidden,
+			wantErr: "failed to publish message",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			var mu sync.Mutex
+			var gotPath string
+			var got pubsub.PublishRequest
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				gotPath = r.URL.Path
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				w.WriteHeader(tc.status)
+				w.Write([]byte(`{"messageIds":["1"]}`)) //nolint:errcheck // Best effort.
+			}))
+			t.Cleanup(srv.Close)
+
+			p, err := NewPubSubPublisher(ctx, "projects/test-project/topics/aod-events",
+				option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotErr := p.Publish(ctx, tc.event)
+			if diff := testutil.DiffErrString(gotErr, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error: %s", tc.name, diff)
+			}
+			if tc.wantErr != "" {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if got, want := gotPath, "/v1/projects/test-project/topics/aod-events:publish"; got != want {
+				t.Errorf("path got %q, want %q", got, want)
+			}
+			if len(got.Messages) != 1 {
+				t.Fatalf("got %d messages, want 1", len(got.Messages))
+			}
+			if diff := cmp.Diff(tc.wantAttrs, got.Messages[0].Attributes); diff != "" {
+				t.Errorf("attributes (-want, +got):\n%s", diff)
+			}
+			data, err := base64.StdEncoding.DecodeString(got.Messages[0].Data)
+			if err != nil {
+				t.Fatalf("failed to decode data: %v", err)
+			}
+			var gotData map[string]any
+			if err := json.Unmarshal(data, &gotData); err != nil {
+				t.Fatalf("failed to unmarshal data: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantData, gotData); diff != "" {
+				t.Errorf("data (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateTopic(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		topic   string
+		wantErr string
+	}{
+		{
+			name:  "valid",
+			topic: "projects/test-project/topics/aod-events",
+		},
+		{
+			name:    "missing_project",
+			topic:   "topics/aod-events",
+			wantErr: `topic "topics/aod-events" isn't in the format of "projects/{project}/topics/{topic}"`,
+		},
+		{
+			name:    "trailing_segment",
+			topic:   "projects/test-project/topics/aod-events/foo",
+			wantErr: "isn't in the format of",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := testutil.DiffErrString(ValidateTopic(tc.topic), tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error: %s", tc.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bigqueryiam gets and sets the IAM policies of BigQuery datasets and
+// tables in the format of the IAM API, so that they are handled like the IAM
+// policies of projects. The IAM policy of a dataset is its access entries.
+package bigqueryiam
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/googleapis/gax-go/v2"
+	bigquery "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/option"
+	"google.golang.org/genproto/googleapis/type/expr"
+
+	"github.com/abcxyz/access-on-demand/pkg/iamdelta"
+	"github.com/abcxyz/access-on-demand/pkg/resource"
+)
+
+// accessPolicyVersion is the version of the access entries of datasets which
+// supports IAM conditions.
+const accessPolicyVersion = 3
+
+// Client gets and sets the IAM policies of BigQuery datasets and tables, named
+// "bigquery/datasets/<project>/<dataset>" and
+// "bigquery/datasets/<project>/<dataset>/tables/<table>".
+type Client struct {
+	service *bigquery.Service
+}
+
+// NewClient creates a new Client.
+func NewClient(ctx context.Context, opts ...option.ClientOption) (*Client, error) {
+	svc, err := bigquery.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bigquery service: %w", err)
+	}
+	return &Client{service: svc}, nil
+}
+
+// GetIamPolicy gets the IAM policy of the BigQuery dataset or table.
+func (c *Client) GetIamPolicy(ctx context.Context, req *iampb.GetIamPolicyRequest, _ ...gax.CallOption) (*iampb.Policy, error) {
+	project, dataset, table, err := parse(req.GetResource())
+	if err != nil {
+		return nil, err
+	}
+
+	if table != "" {
+		p, err := c.service.Tables.GetIamPolicy(tablePath(project, dataset, table), &bigquery.GetIamPolicyRequest{
+			Options: &bigquery.GetPolicyOptions{
+				RequestedPolicyVersion: int64(req.GetOptions().GetRequestedPolicyVersion()),
+			},
+		}).Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get IAM policy of table %q: %w", req.GetResource(), err)
+		}
+		return fromPolicy(p)
+	}
+
+	ds, err := c.service.Datasets.Get(project, dataset).AccessPolicyVersion(accessPolicyVersion).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dataset %q: %w", req.GetResource(), err)
+	}
+	return fromDataset(ds), nil
+}
+
+// SetIamPolicy sets the IAM policy of the BigQuery dataset or table. The
+// access entries of datasets which are not of members, such as authorized
+// views, are kept.
+func (c *Client) SetIamPolicy(ctx context.Context, req *iampb.SetIamPolicyRequest, _ ...gax.CallOption) (*iampb.Policy, error) {
+	project, dataset, table, err := parse(req.GetResource())
+	if err != nil {
+		return nil, err
+	}
+
+	if table != "" {
+		p, err := c.service.Tables.SetIamPolicy(tablePath(project, dataset, table), &bigquery.SetIamPolicyRequest{
+			Policy: toPolicy(req.GetPolicy()),
+		}).Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("failed to set IAM policy of table %q: %w", req.GetResource(), err)
+		}
+		return fromPolicy(p)
+	}
+
+	// Read the current access entries to keep the ones which are not of
+	// members, the etag of the policy guards against concurrent changes.
+	current, err := c.service.Datasets.Get(project, dataset).AccessPolicyVersion(accessPolicyVersion).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dataset %q: %w", req.GetResource(), err)
+	}
+	var access []*bigquery.DatasetAccess
+	for _, a := range current.Access {
+		if accessMember(a) == "" {
+			access = append(access, a)
+		}
+	}
+	for _, b := range req.GetPolicy().GetBindings() {
+		for _, m := range b.GetMembers() {
+			access = append(access, toAccess(b.GetRole(), m, b.GetCondition()))
+		}
+	}
+
+	call := c.service.Datasets.Patch(project, dataset, &bigquery.Dataset{
+		Access:          access,
+		ForceSendFields: []string{"Access"},
+	}).AccessPolicyVersion(accessPolicyVersion).Context(ctx)
+	if etag := req.GetPolicy().GetEtag(); len(etag) > 0 {
+		call.Header().Set("If-Match", string(etag))
+	}
+	ds, err := call.Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update dataset %q: %w", req.GetResource(), err)
+	}
+	return fromDataset(ds), nil
+}
+
+// PatchIamPolicy applies the changes to the bindings to the latest IAM policy
+// of the BigQuery dataset or table.
+func (c *Client) PatchIamPolicy(ctx context.Context, resource string, d *iamdelta.Delta) (*iampb.Policy, error) {
+	return iamdelta.Patch(ctx, c, resource, d) //nolint:wrapcheck // Want passthrough
+}
+
+// parse returns the project, dataset and table of the name of the BigQuery
+// dataset or table, the table is empty for datasets.
+func parse(name string) (project, dataset, table string, err error) {
+	rn, err := resource.Parse(name)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to parse resource: %w", err)
+	}
+	if rn.Type != resource.TypeBigQueryDataset && rn.Type != resource.TypeBigQueryTable {
+		return "", "", "", fmt.Errorf("resource %q is not a BigQuery dataset or table", name)
+	}
+	project, dataset, table = rn.BigQuery()
+	return project, dataset, table, nil
+}
+
+// tablePath returns the path of the table in the BigQuery API.
+func tablePath(project, dataset, table string) string {
+	return fmt.Sprintf("projects/%s/datasets/%s/tables/%s", project, dataset, table)
+}
+
+// fromPolicy converts the IAM policy of a table to an IAM policy of the IAM
+// API.
+func fromPolicy(p *bigquery.Policy) (*iampb.Policy, error) {
+	etag, err := base64.StdEncoding.DecodeString(p.Etag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode etag %q: %w", p.Etag, err)
+	}
+	policy := &iampb.Policy{Version: int32(p.Version), Etag: etag}
+	for _, b := range p.Bindings {
+		policy.Bindings = append(policy.Bindings, &iampb.Binding{
+			Role:      b.Role,
+			Members:   b.Members,
+			Condition: fromExpr(b.Condition),
+		})
+	}
+	return policy, nil
+}
+
+// toPolicy converts the IAM policy of the IAM API to an IAM policy of a table.
+func toPolicy(p *iampb.Policy) *bigquery.Policy {
+	policy := &bigquery.Policy{
+		Version: int64(p.GetVersion()),
+		Etag:    base64.StdEncoding.EncodeToString(p.GetEtag()),
+	}
+	for _, b := range p.GetBindings() {
+		policy.Bindings = append(policy.Bindings, &bigquery.Binding{
+			Role:      b.GetRole(),
+			Members:   b.GetMembers(),
+			Condition: toExpr(b.GetCondition()),
+		})
+	}
+	return policy
+}
+
+// fromDataset converts the access entries of members of the dataset to an IAM
+// policy of the IAM API, with a binding of each role and condition.
+func fromDataset(ds *bigquery.Dataset) *iampb.Policy {
+	policy := &iampb.Policy{Version: accessPolicyVersion, Etag: []byte(ds.Etag)}
+	bindings := make(map[string]*iampb.Binding)
+	for _, a := range ds.Access {
+		m := accessMember(a)
+		if m == "" {
+			continue
+		}
+		key := a.Role
+		if a.Condition != nil {
+			key = strings.Join([]string{a.Role, a.Condition.Title, a.Condition.Description, a.Condition.Expression}, "\x00")
+		}
+		b, ok := bindings[key]
+		if !ok {
+			b = &iampb.Binding{Role: a.Role, Condition: fromExpr(a.Condition)}
+			bindings[key] = b
+			policy.Bindings = append(policy.Bindings, b)
+		}
+		b.Members = append(b.Members, m)
+	}
+	return policy
+}
+
+// accessMember returns the IAM member of the access entry, or an empty string
+// if the access entry is not of a member, such as an authorized view.
+func accessMember(a *bigquery.DatasetAccess) string {
+	switch {
+	case a.UserByEmail != "" && strings.HasSuffix(a.UserByEmail, ".gserviceaccount.com"):
+		return "serviceAccount:" + a.UserByEmail
+	case a.UserByEmail != "":
+		return "user:" + a.UserByEmail
+	case a.GroupByEmail != "":
+		return "group:" + a.GroupByEmail
+	case a.Domain != "":
+		return "domain:" + a.Domain
+	case a.SpecialGroup != "":
+		return "specialGroup:" + a.SpecialGroup
+	default:
+		return a.IamMember
+	}
+}
+
+// toAccess returns the access entry of the IAM member with the role and
+// condition.
+func toAccess(role, member string, cond *expr.Expr) *bigquery.DatasetAccess {
+	a := &bigquery.DatasetAccess{Role: role, Condition: toExpr(cond)}
+	typ, id, _ := strings.Cut(member, ":")
+	switch typ {
+	case "user", "serviceAccount":
+		a.UserByEmail = id
+	case "group":
+		a.GroupByEmail = id
+	case "domain":
+		a.Domain = id
+	case "specialGroup":
+		a.SpecialGroup = id
+	default:
+		a.IamMember = member
+	}
+	return a
+}
+
+// fromExpr converts the condition of the BigQuery API to a condition of the IAM
+// API.
+func fromExpr(e *bigquery.Expr) *expr.Expr {
+	if e == nil {
+		return nil
+	}
+	return &expr.Expr{
+		Title:       e.Title,
+		Description: e.Description,
+		Expression:  e.Expression,
+		Location:    e.Location,
+	}
+}
+
+// toExpr converts the condition of the IAM API to a condition of the BigQuery
+// API.
+func toExpr(e *expr.Expr) *bigquery.Expr {
+	if e == nil {
+		return nil
+	}
+	return &bigquery.Expr{
+		Title:       e.GetTitle(),
+		Description: e.GetDescription(),
+		Expression:  e.GetExpression(),
+		Location:    e.GetLocation(),
+	}
+}
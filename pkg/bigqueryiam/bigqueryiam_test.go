@@ -0,0 +1,238 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigqueryiam
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	bigquery "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/option"
+	"google.golang.org/genproto/googleapis/type/expr"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+// fakeBigQuery is a fake BigQuery API of a dataset and a table.
+type fakeBigQuery struct {
+	mu      sync.Mutex
+	dataset *bigquery.Dataset
+	policy  *bigquery.Policy
+	calls   []string
+}
+
+func (f *fakeBigQuery) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, r.Method+" "+r.URL.Path)
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case strings.HasSuffix(r.URL.Path, ":getIamPolicy"):
+		json.NewEncoder(w).Encode(f.policy) //nolint:errcheck // Test server.
+	case strings.HasSuffix(r.URL.Path, ":setIamPolicy"):
+		var req bigquery.SetIamPolicyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Policy.Etag != f.policy.Etag {
+			http.Error(w, `{"error": {"code": 409, "message": "etag mismatch"}}`, http.StatusConflict)
+			return
+		}
+		f.policy = req.Policy
+		json.NewEncoder(w).Encode(f.policy) //nolint:errcheck // Test server.
+	case r.URL.Query().Get("accessPolicyVersion") != "3":
+		http.Error(w, `{"error": {"code": 400, "message": "missing access policy version"}}`, http.StatusBadRequest)
+	case r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(f.dataset) //nolint:errcheck // Test server.
+	case r.Method == http.MethodPatch:
+		if etag := r.Header.Get("If-Match"); etag != f.dataset.Etag {
+			http.Error(w, `{"error": {"code": 412, "message": "precondition failed"}}`, http.StatusPreconditionFailed)
+			return
+		}
+		var ds bigquery.Dataset
+		if err := json.NewDecoder(r.Body).Decode(&ds); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.dataset.Access = ds.Access
+		f.dataset.Etag += "+"
+		json.NewEncoder(w).Encode(f.dataset) //nolint:errcheck // Test server.
+	}
+}
+
+func TestClientDataset(t *testing.T) {
+	t.Parallel()
+
+	view := &bigquery.DatasetAccess{View: &bigquery.TableReference{ProjectId: "foo", DatasetId: "other", TableId: "view"}}
+	fake := &fakeBigQuery{
+		dataset: &bigquery.Dataset{
+			Etag: "etag1",
+			Access: []*bigquery.DatasetAccess{
+				{Role: "OWNER", SpecialGroup: "projectOwners"},
+				{Role: "roles/bigquery.dataViewer", UserByEmail: "alice@example.com"},
+				{Role: "roles/bigquery.dataViewer", UserByEmail: "sa@foo.iam.gserviceaccount.com"},
+				view,
+			},
+		},
+	}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	c, err := NewClient(ctx, option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	got, err := c.GetIamPolicy(ctx, &iampb.GetIamPolicyRequest{Resource: "bigquery/datasets/foo/bar"})
+	if err != nil {
+		t.Fatalf("GetIamPolicy got unexpected error: %v", err)
+	}
+	want := &iampb.Policy{
+		Version: 3,
+		Etag:    []byte("etag1"),
+		Bindings: []*iampb.Binding{
+			{Role: "OWNER", Members: []string{"specialGroup:projectOwners"}},
+			{Role: "roles/bigquery.dataViewer", Members: []string{"user:alice@example.com", "serviceAccount:sa@foo.iam.gserviceaccount.com"}},
+		},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("GetIamPolicy got policy diff (-want, +got):\n%s", diff)
+	}
+
+	cond := &expr.Expr{Title: "expiry", Expression: `request.time < timestamp("2009-11-10T23:00:00Z")`}
+	got.Bindings = append(got.Bindings, &iampb.Binding{
+		Role:      "roles/bigquery.dataEditor",
+		Members:   []string{"group:analysts@example.com"},
+		Condition: cond,
+	})
+	got, err = c.SetIamPolicy(ctx, &iampb.SetIamPolicyRequest{Resource: "bigquery/datasets/foo/bar", Policy: got})
+	if err != nil {
+		t.Fatalf("SetIamPolicy got unexpected error: %v", err)
+	}
+	want.Etag = []byte("etag1+")
+	want.Bindings = append(want.Bindings, &iampb.Binding{
+		Role:      "roles/bigquery.dataEditor",
+		Members:   []string{"group:analysts@example.com"},
+		Condition: cond,
+	})
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("SetIamPolicy got policy diff (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(view, fake.dataset.Access[0]); diff != "" {
+		t.Errorf("SetIamPolicy got authorized view diff (-want, +got):\n%s", diff)
+	}
+
+	// The etag of the policy is stale.
+	got.Etag = []byte("etag1")
+	_, err = c.SetIamPolicy(ctx, &iampb.SetIamPolicyRequest{Resource: "bigquery/datasets/foo/bar", Policy: got})
+	if diff := testutil.DiffErrString(err, `failed to update dataset "bigquery/datasets/foo/bar"`); diff != "" {
+		t.Errorf("SetIamPolicy got unexpected error: %s", diff)
+	}
+
+	_, err = c.GetIamPolicy(ctx, &iampb.GetIamPolicyRequest{Resource: "projects/foo"})
+	if diff := testutil.DiffErrString(err, `resource "projects/foo" is not a BigQuery dataset or table`); diff != "" {
+		t.Errorf("GetIamPolicy got unexpected error: %s", diff)
+	}
+
+	wantCalls := []string{
+		"GET /projects/foo/datasets/bar",
+		"GET /projects/foo/datasets/bar",
+		"PATCH /projects/foo/datasets/bar",
+		"GET /projects/foo/datasets/bar",
+		"PATCH /projects/foo/datasets/bar",
+	}
+	if diff := cmp.Diff(wantCalls, fake.calls); diff != "" {
+		t.Errorf("got calls diff (-want, +got):\n%s", diff)
+	}
+}
+
+func TestClientTable(t *testing.T) {
+	t.Parallel()
+
+	etag := base64.StdEncoding.EncodeToString([]byte("etag1"))
+	fake := &fakeBigQuery{
+		policy: &bigquery.Policy{
+			Version: 1,
+			Etag:    etag,
+			Bindings: []*bigquery.Binding{
+				{Role: "roles/bigquery.dataViewer", Members: []string{"user:alice@example.com"}},
+			},
+		},
+	}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	c, err := NewClient(ctx, option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	got, err := c.GetIamPolicy(ctx, &iampb.GetIamPolicyRequest{
+		Resource: "bigquery/datasets/foo/bar/tables/baz",
+		Options:  &iampb.GetPolicyOptions{RequestedPolicyVersion: 3},
+	})
+	if err != nil {
+		t.Fatalf("GetIamPolicy got unexpected error: %v", err)
+	}
+	want := &iampb.Policy{
+		Version: 1,
+		Etag:    []byte("etag1"),
+		Bindings: []*iampb.Binding{
+			{Role: "roles/bigquery.dataViewer", Members: []string{"user:alice@example.com"}},
+		},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("GetIamPolicy got policy diff (-want, +got):\n%s", diff)
+	}
+
+	got.Version = 3
+	got.Bindings[0].Condition = &expr.Expr{Title: "expiry", Expression: `request.time < timestamp("2009-11-10T23:00:00Z")`}
+	if _, err := c.SetIamPolicy(ctx, &iampb.SetIamPolicyRequest{Resource: "bigquery/datasets/foo/bar/tables/baz", Policy: got}); err != nil {
+		t.Fatalf("SetIamPolicy got unexpected error: %v", err)
+	}
+	wantPolicy := &bigquery.Policy{
+		Version: 3,
+		Etag:    etag,
+		Bindings: []*bigquery.Binding{{
+			Role:      "roles/bigquery.dataViewer",
+			Members:   []string{"user:alice@example.com"},
+			Condition: &bigquery.Expr{Title: "expiry", Expression: `request.time < timestamp("2009-11-10T23:00:00Z")`},
+		}},
+	}
+	if diff := cmp.Diff(wantPolicy, fake.policy); diff != "" {
+		t.Errorf("SetIamPolicy got table policy diff (-want, +got):\n%s", diff)
+	}
+
+	wantCalls := []string{
+		"POST /projects/foo/datasets/bar/tables/baz:getIamPolicy",
+		"POST /projects/foo/datasets/bar/tables/baz:setIamPolicy",
+	}
+	if diff := cmp.Diff(wantCalls, fake.calls); diff != "" {
+		t.Errorf("got calls diff (-want, +got):\n%s", diff)
+	}
+}
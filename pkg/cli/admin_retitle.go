@@ -0,0 +1,311 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/time/rate"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/inventory"
+	"github.com/abcxyz/access-on-demand/pkg/messages"
+	"github.com/abcxyz/access-on-demand/pkg/resource"
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+)
+
+var _ cli.Command = (*AdminRetitleCommand)(nil)
+
+// maxConditionTitleLength is the max length of IAM condition titles.
+const maxConditionTitleLength = 100
+
+// iamRetitleHandler interface that replaces the condition title of IAM
+// bindings.
+type iamRetitleHandler interface {
+	RetitleConditions(ctx context.Context, from, to string, resources []string, limiter *rate.Limiter) ([]*v1alpha1.IAMResponse, error)
+}
+
+// AdminRetitleCommand replaces the condition title of existing AOD IAM bindings
+// in an organization or folder and all its descendant folders and projects.
+type AdminRetitleCommand struct {
+	cli.BaseCommand
+
+	flagFrom string
+
+	flagTo string
+
+	flagRoots []string
+
+	flagRateLimit float64
+
+	flagUseInventory bool
+
+	flagVerbose bool
+
+	iamHandlerFlags iamHandlerFlags
+
+	// testHandler is used for testing only.
+	testHandler iamRetitleHandler
+
+	// testLister is used for testing only.
+	testLister descendantsLister
+}
+
+func (c *AdminRetitleCommand) Desc() string {
+	return `Replace the condition title of AOD IAM bindings in the given resources and all their descendants`
+}
+
+func (c *AdminRetitleCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Replace the condition title of the AOD IAM bindings in the organization and all
+its descendant folders and projects:
+
+      {{ COMMAND }} -from "old-title" -to "new-title" -root "organizations/123"
+
+Update at most 2 resources per second, and report the progress of each resource
+to stderr:
+
+      {{ COMMAND }} -from "old-title" -to "new-title" -root "folders/456" -rate-limit 2 -progress json
+
+Find the descendants with AOD IAM bindings of the old title with Cloud Asset
+Inventory, which is faster for large organizations:
+
+      {{ COMMAND }} -from "old-title" -to "new-title" -root "organizations/123" -use-inventory
+
+The IAM policies of resources without bindings of the old title are not
+updated. Bindings of the new title with the same role and condition are merged.
+`
+}
+
+func (c *AdminRetitleCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "from",
+		Target:  &c.flagFrom,
+		Example: "old-title",
+		Usage:   `The condition title to replace.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "to",
+		Target:  &c.flagTo,
+		Example: "new-title",
+		Usage:   `The condition title to replace with.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "root",
+		Target:  &c.flagRoots,
+		Example: "organizations/123",
+		Usage: `The organization, folder or project to replace the condition ` +
+			`title in, can be repeated. The descendant folders and projects ` +
+			`of organizations and folders are also updated.`,
+	})
+
+	f.Float64Var(&cli.Float64Var{
+		Name:    "rate-limit",
+		Target:  &c.flagRateLimit,
+		Default: 10,
+		Usage: `The max number of resources to read and update per second, ` +
+			`0 for no limit.`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "use-inventory",
+		Target:  &c.flagUseInventory,
+		Default: false,
+		Usage: `Use Cloud Asset Inventory to find the descendants with IAM ` +
+			`bindings of the condition title to replace with a single query, ` +
+			`instead of listing all folders and projects.`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "verbose",
+		Target:  &c.flagVerbose,
+		Default: false,
+		Usage:   `Turn on verbose mode to print updated IAM policies. Note that it may contain sensitive information`,
+	})
+
+	c.iamHandlerFlags.register(f)
+
+	return set
+}
+
+func (c *AdminRetitleCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagFrom == "" || c.flagTo == "" {
+		return fmt.Errorf("from and to are required")
+	}
+
+	if c.flagFrom == c.flagTo {
+		return fmt.Errorf("from and to must be different, got %q", c.flagFrom)
+	}
+
+	if len(c.flagTo) > maxConditionTitleLength {
+		return fmt.Errorf("to must be at most %d characters, got %d", maxConditionTitleLength, len(c.flagTo))
+	}
+
+	if len(c.flagRoots) == 0 {
+		return fmt.Errorf("root is required")
+	}
+
+	if c.flagRateLimit < 0 {
+		return fmt.Errorf("rate-limit must not be negative, got %g", c.flagRateLimit)
+	}
+
+	if err := c.iamHandlerFlags.validate(); err != nil {
+		return err
+	}
+
+	return c.retitle(ctx)
+}
+
+func (c *AdminRetitleCommand) retitle(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	// Validate the roots as an IAMRequest.
+	req := &v1alpha1.IAMRequest{}
+	for _, r := range c.flagRoots {
+		req.ResourcePolicies = append(req.ResourcePolicies, &v1alpha1.ResourcePolicy{Resource: r})
+	}
+	if err := v1alpha1.ValidateIAMRequest(req, nil); err != nil {
+		return withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate roots: %w", err))
+	}
+	for _, r := range c.flagRoots {
+		if !resource.HasType(r, resource.Types...) {
+			return withExitCode(ExitCodeValidation, fmt.Errorf("root %q must be an organization, folder or project", r))
+		}
+	}
+
+	var l descendantsLister
+	if c.testLister != nil {
+		// Use testLister if it is for testing.
+		l = c.testLister
+	} else if c.flagUseInventory {
+		searcher, err := inventory.NewSearcher(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to create inventory searcher: %w", err)
+		}
+		l = &inventoryLister{
+			searcher:       searcher,
+			conditionTitle: c.flagFrom,
+		}
+	} else {
+		walker, closer, newWalkerErr := newWalker(ctx)
+		if newWalkerErr != nil {
+			return newWalkerErr
+		}
+		l = walker
+		defer func() {
+			if err := closer.Close(); err != nil {
+				logger.ErrorContext(ctx, "failed to close", "error", err)
+			}
+		}()
+	}
+
+	var resources []string
+	for _, r := range c.flagRoots {
+		if resource.HasType(r, resource.TypeProject) {
+			resources = append(resources, r)
+			continue
+		}
+		ds, err := l.Descendants(ctx, r)
+		if err != nil {
+			return fmt.Errorf("failed to list descendants of %q: %w", r, err)
+		}
+		resources = append(resources, ds...)
+	}
+	resources = dedupe(resources)
+
+	var h iamRetitleHandler
+	if c.testHandler != nil {
+		// Use testHandler if it is for testing.
+		h = c.testHandler
+	} else {
+		iamHandler, closer, newHandlerErr := newIAMHandler(ctx, &c.iamHandlerFlags, &c.BaseCommand)
+		if newHandlerErr != nil {
+			return newHandlerErr
+		}
+		h = iamHandler
+		defer func() {
+			if err := closer.Close(); err != nil {
+				logger.ErrorContext(ctx, "failed to close", "error", err)
+			}
+		}()
+	}
+
+	limit := rate.Inf
+	if c.flagRateLimit > 0 {
+		limit = rate.Limit(c.flagRateLimit)
+	}
+	logger.InfoContext(ctx, "retitling AOD bindings",
+		"from", c.flagFrom,
+		"to", c.flagTo,
+		"resources", len(resources),
+		"rate_limit", c.flagRateLimit)
+	resp, err := h.RetitleConditions(ctx, c.flagFrom, c.flagTo, resources, rate.NewLimiter(limit, 1))
+	if err != nil {
+		return withExitCode(apiExitCode(err, len(resources)),
+			fmt.Errorf("failed to retitle AOD bindings: %w", err))
+	}
+
+	retitled := make([]string, 0, len(resp))
+	skipped := make(map[string]string)
+	for _, r := range resp {
+		if r.Skipped != "" {
+			skipped[r.Resource] = r.Skipped
+			continue
+		}
+		retitled = append(retitled, r.Resource)
+	}
+	printMessageHeader(ctx, &c.BaseCommand, messages.HeaderRetitled)
+	out := map[string]any{
+		"from":     c.flagFrom,
+		"to":       c.flagTo,
+		"scanned":  len(resources),
+		"retitled": retitled,
+	}
+	if len(skipped) > 0 {
+		out["skipped"] = skipped
+	}
+	if err := encodeYaml(c.Stdout(), out); err != nil {
+		return fmt.Errorf("failed to output retitled resources: %w", err)
+	}
+
+	if c.flagVerbose {
+		printMessageHeader(ctx, &c.BaseCommand, messages.HeaderUpdatedPolicies)
+		if err := encodeYaml(c.Stdout(), resp); err != nil {
+			return fmt.Errorf("failed to output IAM policies: %w", err)
+		}
+	}
+
+	return nil
+}
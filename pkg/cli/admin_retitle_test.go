@@ -0,0 +1,222 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/time/rate"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestAdminRetitleCommand(t *testing.T) {
+	t.Parallel()
+
+	descendants := map[string][]string{
+		"organizations/1": {"organizations/1", "projects/foo", "folders/2", "projects/bar"},
+		"folders/2":       {"folders/2", "projects/bar"},
+	}
+
+	cases := []struct {
+		name         string
+		args         []string
+		handler      *fakeIAMRetitleHandler
+		lister       *fakeDescendantsLister
+		expResources []string
+		expLimit     rate.Limit
+		expOut       string
+		expErr       string
+		expExitCode  int
+	}{
+		{
+			name: "success",
+			args: []string{"-from", "old-title", "-to", "new-title", "-root", "organizations/1"},
+			handler: &fakeIAMRetitleHandler{
+				resp: []*v1alpha1.IAMResponse{
+					{Resource: "projects/foo"},
+					{Resource: "projects/bar", Skipped: "resource projects/bar is not active (state: DELETE_REQUESTED)"},
+				},
+			},
+			lister:       &fakeDescendantsLister{descendants: descendants},
+			expResources: []string{"organizations/1", "projects/foo", "folders/2", "projects/bar"},
+			expLimit:     10,
+			expOut: `
+------Successfully Retitled AOD Bindings------
+from: old-title
+retitled:
+  - projects/foo
+scanned: 4
+skipped:
+  projects/bar: 'resource projects/bar is not active (state: DELETE_REQUESTED)'
+to: new-title`,
+		},
+		{
+			name:         "success_overlapping_roots",
+			args:         []string{"-from", "old-title", "-to", "new-title", "-root", "folders/2", "-root", "projects/baz", "-root", "organizations/1", "-rate-limit", "0.5"},
+			handler:      &fakeIAMRetitleHandler{},
+			lister:       &fakeDescendantsLister{descendants: descendants},
+			expResources: []string{"folders/2", "projects/bar", "projects/baz", "organizations/1", "projects/foo"},
+			expLimit:     0.5,
+			expOut: `
+------Successfully Retitled AOD Bindings------
+from: old-title
+retitled: []
+scanned: 5
+to: new-title`,
+		},
+		{
+			name:         "no_rate_limit",
+			args:         []string{"-from", "old-title", "-to", "new-title", "-root", "projects/foo", "-rate-limit", "0"},
+			handler:      &fakeIAMRetitleHandler{},
+			lister:       &fakeDescendantsLister{},
+			expResources: []string{"projects/foo"},
+			expLimit:     rate.Inf,
+			expOut: `
+------Successfully Retitled AOD Bindings------
+from: old-title
+retitled: []
+scanned: 1
+to: new-title`,
+		},
+		{
+			name: "handler_failure",
+			args: []string{"-from", "old-title", "-to", "new-title", "-root", "folders/2"},
+			handler: &fakeIAMRetitleHandler{
+				injectErr: fmt.Errorf("injected error"),
+			},
+			lister:       &fakeDescendantsLister{descendants: descendants},
+			expResources: []string{"folders/2", "projects/bar"},
+			expLimit:     10,
+			expErr:       "failed to retitle AOD bindings: injected error",
+			expExitCode:  ExitCodePartialFailure,
+		},
+		{
+			name:        "lister_failure",
+			args:        []string{"-from", "old-title", "-to", "new-title", "-root", "folders/2"},
+			handler:     &fakeIAMRetitleHandler{},
+			lister:      &fakeDescendantsLister{injectErr: fmt.Errorf("injected error")},
+			expErr:      `failed to list descendants of "folders/2": injected error`,
+			expExitCode: ExitCodeFailure,
+		},
+		{
+			name:        "root_not_in_hierarchy",
+			args:        []string{"-from", "old-title", "-to", "new-title", "-root", "buckets/foo"},
+			handler:     &fakeIAMRetitleHandler{},
+			lister:      &fakeDescendantsLister{},
+			expErr:      `root "buckets/foo" must be an organization, folder or project`,
+			expExitCode: ExitCodeValidation,
+		},
+		{
+			name:        "missing_root",
+			args:        []string{"-from", "old-title", "-to", "new-title"},
+			handler:     &fakeIAMRetitleHandler{},
+			lister:      &fakeDescendantsLister{},
+			expErr:      "root is required",
+			expExitCode: ExitCodeFailure,
+		},
+		{
+			name:        "missing_to",
+			args:        []string{"-from", "old-title", "-root", "folders/2"},
+			handler:     &fakeIAMRetitleHandler{},
+			lister:      &fakeDescendantsLister{},
+			expErr:      "from and to are required",
+			expExitCode: ExitCodeFailure,
+		},
+		{
+			name:        "same_titles",
+			args:        []string{"-from", "old-title", "-to", "old-title", "-root", "folders/2"},
+			handler:     &fakeIAMRetitleHandler{},
+			lister:      &fakeDescendantsLister{},
+			expErr:      `from and to must be different, got "old-title"`,
+			expExitCode: ExitCodeFailure,
+		},
+		{
+			name:        "to_too_long",
+			args:        []string{"-from", "old-title", "-to", strings.Repeat("a", 101), "-root", "folders/2"},
+			handler:     &fakeIAMRetitleHandler{},
+			lister:      &fakeDescendantsLister{},
+			expErr:      "to must be at most 100 characters, got 101",
+			expExitCode: ExitCodeFailure,
+		},
+		{
+			name:        "negative_rate_limit",
+			args:        []string{"-from", "old-title", "-to", "new-title", "-root", "folders/2", "-rate-limit", "-1"},
+			handler:     &fakeIAMRetitleHandler{},
+			lister:      &fakeDescendantsLister{},
+			expErr:      "rate-limit must not be negative, got -1",
+			expExitCode: ExitCodeFailure,
+		},
+		{
+			name:        "unexpected_args",
+			args:        []string{"foo"},
+			handler:     &fakeIAMRetitleHandler{},
+			lister:      &fakeDescendantsLister{},
+			expErr:      `unexpected arguments: ["foo"]`,
+			expExitCode: ExitCodeFailure,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var cmd AdminRetitleCommand
+			cmd.testHandler = tc.handler
+			cmd.testLister = tc.lister
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if err != nil {
+				if got, want := ExitCode(err), tc.expExitCode; got != want {
+					t.Errorf("Process(%+v) got exit code %d, want %d", tc.name, got, want)
+				}
+			}
+			if diff := cmp.Diff(tc.expResources, tc.handler.gotResources); diff != "" {
+				t.Errorf("Process(%+v) got resources diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if got, want := tc.handler.gotLimit, tc.expLimit; got != want {
+				t.Errorf("Process(%+v) got rate limit %v, want %v", tc.name, got, want)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+type fakeIAMRetitleHandler struct {
+	injectErr    error
+	gotResources []string
+	gotLimit     rate.Limit
+	resp         []*v1alpha1.IAMResponse
+}
+
+func (h *fakeIAMRetitleHandler) RetitleConditions(ctx context.Context, from, to string, resources []string, limiter *rate.Limiter) ([]*v1alpha1.IAMResponse, error) {
+	h.gotResources = append(h.gotResources, resources...)
+	h.gotLimit = limiter.Limit()
+	return h.resp, h.injectErr
+}
@@ -0,0 +1,118 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/abcxyz/access-on-demand/internal/version"
+	"github.com/abcxyz/access-on-demand/pkg/analytics"
+	"github.com/abcxyz/pkg/cli"
+)
+
+// doNotTrackEnv is the environment variable which disables the usage
+// analytics regardless of the CLI config, see https://consoledonottrack.com.
+const doNotTrackEnv = "DO_NOT_TRACK"
+
+// analyticsTimeout is the deadline of reporting the usage analytics, so that
+// an unavailable endpoint does not hold up the CLI.
+const analyticsTimeout = 2 * time.Second
+
+// reportAnalytics reports the anonymous usage of the command run with args to
+// the analytics endpoint, if the user opted in with the CLI config and
+// DO_NOT_TRACK is not set. Only the name of the command, the version and
+// platform of the CLI, the latency and the category of the error are reported,
+// never the flags, arguments or error messages.
+func reportAnalytics(ctx context.Context, client *http.Client, lookupEnv cli.LookupEnvFunc, args []string, latency time.Duration, runErr error) error {
+	if v, ok := lookupEnv(doNotTrackEnv); ok && v != "" && v != "0" {
+		return nil
+	}
+	c, err := loadCLIConfig(ctx, lookupEnv)
+	if err != nil {
+		return fmt.Errorf("failed to load CLI config: %w", err)
+	}
+	if !c.Analytics || c.AnalyticsEndpoint == "" {
+		return nil
+	}
+
+	r, err := analytics.NewReporter(client, c.AnalyticsEndpoint)
+	if err != nil {
+		return fmt.Errorf("failed to create analytics reporter: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, analyticsTimeout)
+	defer cancel()
+
+	if err := r.Report(ctx, &analytics.Event{
+		Command:       commandName(args),
+		Version:       version.Version,
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		LatencyMillis: latency.Milliseconds(),
+		ErrorCategory: errorCategory(runErr),
+	}); err != nil {
+		return fmt.Errorf("failed to report analytics: %w", err)
+	}
+	return nil
+}
+
+// commandName returns the name of the command run with args, e.g.
+// "iam handle". Only the names of the known commands are returned, so that no
+// argument of the user is reported.
+func commandName(args []string) string {
+	var names []string
+	cmd := RootCmd()
+	for _, arg := range args {
+		root, ok := cmd.(*cli.RootCommand)
+		if !ok {
+			break
+		}
+		factory, ok := root.Commands[arg]
+		if !ok {
+			break
+		}
+		names = append(names, arg)
+		cmd = factory()
+	}
+	return strings.Join(names, " ")
+}
+
+// errorCategory returns the category of the error by its exit code, or an
+// empty string if err is nil.
+func errorCategory(err error) string {
+	switch ExitCode(err) {
+	case 0:
+		return ""
+	case ExitCodeInvalidRequest:
+		return "invalid_request"
+	case ExitCodeValidation:
+		return "validation"
+	case ExitCodeAPIFailure:
+		return "api_failure"
+	case ExitCodePartialFailure:
+		return "partial_failure"
+	case ExitCodeTimeout:
+		return "timeout"
+	case ExitCodeMaintenance:
+		return "maintenance"
+	default:
+		return "failure"
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/internal/version"
+	"github.com/abcxyz/access-on-demand/pkg/analytics"
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestReportAnalytics(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		env       map[string]string
+		args      []string
+		runErr    error
+		wantEvent *analytics.Event
+		wantErr   string
+	}{
+		{
+			name: "success",
+			env:  map[string]string{"AOD_ANALYTICS": "true"},
+			args: []string{"iam", "handle", "-path", "/secret/path.yaml"},
+			wantEvent: &analytics.Event{
+				Command:       "iam handle",
+				Version:       version.Version,
+				OS:            runtime.GOOS,
+				Arch:          runtime.GOARCH,
+				LatencyMillis: 1500,
+			},
+		},
+		{
+			name:   "error_category",
+			env:    map[string]string{"AOD_ANALYTICS": "true"},
+			args:   []string{"iam", "validate", "user@example.com"},
+			runErr: withExitCode(ExitCodeValidation, fmt.Errorf("invalid member user@example.com")),
+			wantEvent: &analytics.Event{
+				Command:       "iam validate",
+				Version:       version.Version,
+				OS:            runtime.GOOS,
+				Arch:          runtime.GOARCH,
+				LatencyMillis: 1500,
+				ErrorCategory: "validation",
+			},
+		},
+		{
+			name: "unknown_command",
+			env:  map[string]string{"AOD_ANALYTICS": "true"},
+			args: []string{"iam", "my-secret-command"},
+			wantEvent: &analytics.Event{
+				Command:       "iam",
+				Version:       version.Version,
+				OS:            runtime.GOOS,
+				Arch:          runtime.GOARCH,
+				LatencyMillis: 1500,
+			},
+		},
+		{
+			name: "disabled_by_default",
+			args: []string{"iam", "handle"},
+		},
+		{
+			name: "do_not_track",
+			env:  map[string]string{"AOD_ANALYTICS": "true", doNotTrackEnv: "1"},
+			args: []string{"iam", "handle"},
+		},
+		{
+			name:    "invalid_endpoint",
+			env:     map[string]string{"AOD_ANALYTICS": "true", "AOD_ANALYTICS_ENDPOINT": "ftp://example.com"},
+			args:    []string{"iam", "handle"},
+			wantErr: `analytics endpoint "ftp://example.com" must be an http or https URL`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var gotEvent *analytics.Event
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotEvent = &analytics.Event{}
+				if err := json.NewDecoder(r.Body).Decode(gotEvent); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			t.Cleanup(srv.Close)
+
+			env := map[string]string{
+				"HOME":                   t.TempDir(),
+				"AOD_ANALYTICS_ENDPOINT": srv.URL,
+			}
+			for k, v := range tc.env {
+				env[k] = v
+			}
+
+			err := reportAnalytics(context.Background(), srv.Client(), cli.MapLookuper(env), tc.args, 1500*time.Millisecond, tc.runErr)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantEvent, gotEvent); diff != "" {
+				t.Errorf("Process(%+v) got event diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestErrorCategory(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "nil", err: nil, want: ""},
+		{name: "invalid_request", err: withExitCode(ExitCodeInvalidRequest, fmt.Errorf("injected")), want: "invalid_request"},
+		{name: "api_failure", err: withExitCode(ExitCodeAPIFailure, fmt.Errorf("injected")), want: "api_failure"},
+		{name: "partial_failure", err: withExitCode(ExitCodePartialFailure, fmt.Errorf("injected")), want: "partial_failure"},
+		{name: "timeout", err: withExitCode(ExitCodeTimeout, fmt.Errorf("injected")), want: "timeout"},
+		{name: "maintenance", err: withExitCode(ExitCodeMaintenance, fmt.Errorf("injected")), want: "maintenance"},
+		{name: "other", err: fmt.Errorf("injected"), want: "failure"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := errorCategory(tc.err); got != tc.want {
+				t.Errorf("errorCategory(%v) got %q, want %q", tc.err, got, tc.want)
+			}
+		})
+	}
+}
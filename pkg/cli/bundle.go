@@ -0,0 +1,23 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+// bundlePathUsage is the usage of the "-path" flag of the commands accepting
+// request bundles.
+const bundlePathUsage = `The path of IAM request file, in YAML format. It ` +
+	`can also be a bundle of requests, which is a multi-document YAML file, a ` +
+	`gzip compressed YAML file (".gz"), or a tarball of YAML files (".tar", ` +
+	`".tar.gz", ".tgz") with an optional "SHA256SUMS" file to verify the ` +
+	`requests against. Use "-" to read a YAML file from stdin.`
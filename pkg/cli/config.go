@@ -0,0 +1,221 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sethvargo/go-envconfig"
+	"gopkg.in/yaml.v3"
+
+	"github.com/abcxyz/access-on-demand/pkg/requestutil"
+	"github.com/abcxyz/pkg/cli"
+)
+
+// configFileEnv is the environment variable of the path of the CLI config
+// file, which defaults to "aod/config.yaml" in the user config directory.
+const configFileEnv = "AOD_CONFIG_FILE"
+
+// cliConfig is the config of the defaults of the flags shared by the commands,
+// read from the CLI config file and overridden by the AOD_* environment
+// variables. Flags set on the command line take precedence over both.
+type cliConfig struct {
+	// ConditionTitle is the default of "-custom-condition-title".
+	ConditionTitle string `yaml:"condition_title" env:"AOD_CONDITION_TITLE"`
+
+	// Duration is the default of "-duration".
+	Duration time.Duration `yaml:"duration" env:"AOD_DURATION"`
+
+	// MaxRetries is the default of "-max-retries".
+	MaxRetries *uint `yaml:"max_retries" env:"AOD_MAX_RETRIES"`
+
+	// RetryInitialDelay is the default of "-retry-initial-delay".
+	RetryInitialDelay time.Duration `yaml:"retry_initial_delay" env:"AOD_RETRY_INITIAL_DELAY"`
+
+	// APICallBudget is the default of "-api-call-budget".
+	APICallBudget int64 `yaml:"api_call_budget" env:"AOD_API_CALL_BUDGET"`
+
+	// Format is the default of "-format".
+	Format string `yaml:"format" env:"AOD_FORMAT"`
+
+	// Policy is the default of "-policy".
+	Policy string `yaml:"policy" env:"AOD_POLICY"`
+
+	// IdentityMap is the default of "-identity-map".
+	IdentityMap string `yaml:"identity_map" env:"AOD_IDENTITY_MAP"`
+
+	// Maintenance is the default of "-maintenance".
+	Maintenance string `yaml:"maintenance" env:"AOD_MAINTENANCE"`
+
+	// ResourceManagerFallbackEndpoints is the default of
+	// "-resource-manager-fallback-endpoint".
+	ResourceManagerFallbackEndpoints []string `yaml:"resource_manager_fallback_endpoints" env:"AOD_RESOURCE_MANAGER_FALLBACK_ENDPOINTS"`
+
+	// Timeout is the default of the global "-timeout" flag.
+	Timeout time.Duration `yaml:"timeout" env:"AOD_TIMEOUT"`
+
+	// Analytics opts in to reporting anonymous usage analytics to
+	// AnalyticsEndpoint, see [reportAnalytics].
+	Analytics bool `yaml:"analytics" env:"AOD_ANALYTICS"`
+
+	// AnalyticsEndpoint is the URL the usage analytics are reported to.
+	AnalyticsEndpoint string `yaml:"analytics_endpoint" env:"AOD_ANALYTICS_ENDPOINT"`
+}
+
+// flagDefaults returns the values of the config by the names of the flags they
+// are the defaults of. Unset values are omitted.
+func (c *cliConfig) flagDefaults() map[string]string {
+	m := make(map[string]string)
+	if c.ConditionTitle != "" {
+		m["custom-condition-title"] = c.ConditionTitle
+	}
+	if c.Duration != 0 {
+		m["duration"] = c.Duration.String()
+	}
+	if c.MaxRetries != nil {
+		m["max-retries"] = strconv.FormatUint(uint64(*c.MaxRetries), 10)
+	}
+	if c.RetryInitialDelay != 0 {
+		m["retry-initial-delay"] = c.RetryInitialDelay.String()
+	}
+	if c.APICallBudget != 0 {
+		m["api-call-budget"] = strconv.FormatInt(c.APICallBudget, 10)
+	}
+	if c.Format != "" {
+		m["format"] = c.Format
+	}
+	if c.Policy != "" {
+		m["policy"] = c.Policy
+	}
+	if c.IdentityMap != "" {
+		m["identity-map"] = c.IdentityMap
+	}
+	if c.Maintenance != "" {
+		m["maintenance"] = c.Maintenance
+	}
+	if len(c.ResourceManagerFallbackEndpoints) > 0 {
+		m["resource-manager-fallback-endpoint"] = strings.Join(c.ResourceManagerFallbackEndpoints, ",")
+	}
+	return m
+}
+
+// configFilePath returns the path of the CLI config file, and whether it is set
+// explicitly with AOD_CONFIG_FILE. The default path is "aod/config.yaml" in
+// XDG_CONFIG_HOME, or in "~/.config" if it is not set.
+func configFilePath(lookupEnv cli.LookupEnvFunc) (string, bool) {
+	if p, ok := lookupEnv(configFileEnv); ok && p != "" {
+		return p, true
+	}
+	dir, ok := lookupEnv("XDG_CONFIG_HOME")
+	if !ok || dir == "" {
+		home, ok := lookupEnv("HOME")
+		if !ok || home == "" {
+			return "", false
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "aod", "config.yaml"), false
+}
+
+// loadCLIConfig reads the CLI config from the config file, if it exists, and
+// the environment variables looked up with lookupEnv. A missing config file is
+// only an error if it is set explicitly.
+func loadCLIConfig(ctx context.Context, lookupEnv cli.LookupEnvFunc) (*cliConfig, error) {
+	var c cliConfig
+
+	if path, explicit := configFilePath(lookupEnv); path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist) && !explicit:
+		case err != nil:
+			return nil, fmt.Errorf("failed to read config file %q: %w", path, err)
+		default:
+			dec := yaml.NewDecoder(bytes.NewReader(data))
+			dec.KnownFields(true)
+			if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("failed to parse config file %q: %w", path, requestutil.ExplainUnknownFields(err, &c))
+			}
+		}
+	}
+
+	if err := envconfig.ProcessWith(ctx, &envconfig.Config{
+		Target:           &c,
+		Lookuper:         envLookuper(lookupEnv),
+		DefaultOverwrite: true,
+		DefaultNoInit:    true,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to process environment: %w", err)
+	}
+	return &c, nil
+}
+
+// envLookuper adapts the environment lookup of a command to envconfig.
+type envLookuper cli.LookupEnvFunc
+
+func (l envLookuper) Lookup(key string) (string, bool) {
+	return l(key)
+}
+
+// newFlagSet returns a flag set of the command, where the flags not set on the
+// command line default to the CLI config, see [cliConfig].
+func newFlagSet(cmd *cli.BaseCommand) *cli.FlagSet {
+	set := cmd.NewFlagSet()
+	set.AfterParse(func(existingErr error) error {
+		if existingErr != nil {
+			return nil //nolint:nilerr // The parse error is already returned.
+		}
+		return applyCLIConfig(context.Background(), set, cmd.LookupEnv)
+	})
+	return set
+}
+
+// applyCLIConfig sets the flags of the set that are not set on the command
+// line to the CLI config.
+func applyCLIConfig(ctx context.Context, set *cli.FlagSet, lookupEnv cli.LookupEnvFunc) error {
+	c, err := loadCLIConfig(ctx, lookupEnv)
+	if err != nil {
+		return fmt.Errorf("failed to load CLI config: %w", err)
+	}
+
+	explicit := make(map[string]bool)
+	set.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	defaults := c.flagDefaults()
+	for _, name := range slices.Sorted(maps.Keys(defaults)) {
+		f := set.Lookup(name)
+		if f == nil || explicit[name] {
+			continue
+		}
+		if err := f.Value.Set(defaults[name]); err != nil {
+			return fmt.Errorf("invalid CLI config of %q: %w", name, err)
+		}
+	}
+	return nil
+}
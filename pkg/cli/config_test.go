@@ -0,0 +1,244 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestLoadCLIConfig(t *testing.T) {
+	t.Parallel()
+
+	uintPtr := func(u uint) *uint { return &u }
+
+	cases := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		want    *cliConfig
+		wantErr string
+	}{
+		{
+			name: "file",
+			file: `
+condition_title: my-aod-expiry
+duration: 2h
+max_retries: 0
+retry_initial_delay: 1s
+format: json
+`,
+			want: &cliConfig{
+				ConditionTitle:    "my-aod-expiry",
+				Duration:          2 * time.Hour,
+				MaxRetries:        uintPtr(0),
+				RetryInitialDelay: time.Second,
+				Format:            "json",
+			},
+		},
+		{
+			name: "env_overrides_file",
+			file: `
+condition_title: my-aod-expiry
+duration: 2h
+`,
+			env: map[string]string{
+				"AOD_DURATION":    "30m",
+				"AOD_MAX_RETRIES": "3",
+			},
+			want: &cliConfig{
+				ConditionTitle: "my-aod-expiry",
+				Duration:       30 * time.Minute,
+				MaxRetries:     uintPtr(3),
+			},
+		},
+		{
+			name: "env_only",
+			env: map[string]string{
+				"AOD_FORMAT": "yaml",
+			},
+			want: &cliConfig{Format: "yaml"},
+		},
+		{
+			name: "maintenance",
+			env: map[string]string{
+				"AOD_MAINTENANCE": "IAM freeze",
+			},
+			want: &cliConfig{Maintenance: "IAM freeze"},
+		},
+		{
+			name: "identity_map",
+			file: `identity_map: /etc/aod/identities.yaml`,
+			want: &cliConfig{IdentityMap: "/etc/aod/identities.yaml"},
+		},
+		{
+			name: "resource_manager_fallback_endpoints",
+			env: map[string]string{
+				"AOD_RESOURCE_MANAGER_FALLBACK_ENDPOINTS": "us-east1-cloudresourcemanager.googleapis.com:443,us-west1-cloudresourcemanager.googleapis.com:443",
+			},
+			want: &cliConfig{ResourceManagerFallbackEndpoints: []string{
+				"us-east1-cloudresourcemanager.googleapis.com:443",
+				"us-west1-cloudresourcemanager.googleapis.com:443",
+			}},
+		},
+		{
+			name:    "unknown_field",
+			file:    `bananas: 1`,
+			wantErr: `line 1: unknown field "bananas" in cliConfig, must be one of ["condition_title"`,
+		},
+		{
+			name:    "invalid_env",
+			env:     map[string]string{"AOD_DURATION": "bananas"},
+			wantErr: "failed to process environment",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			home := t.TempDir()
+			env := map[string]string{"HOME": home}
+			for k, v := range tc.env {
+				env[k] = v
+			}
+			if tc.file != "" {
+				dir := filepath.Join(home, ".config", "aod")
+				if err := os.MkdirAll(dir, 0o700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(tc.file), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := loadCLIConfig(context.Background(), cli.MapLookuper(env))
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Process(%+v) got config diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestLoadCLIConfig_ExplicitFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "aod.yaml")
+	env := cli.MapLookuper(map[string]string{configFileEnv: path})
+
+	if _, err := loadCLIConfig(context.Background(), env); err == nil {
+		t.Errorf("got no error for missing explicit config file, want error")
+	}
+
+	if err := os.WriteFile(path, []byte("duration: 4h"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadCLIConfig(context.Background(), env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&cliConfig{Duration: 4 * time.Hour}, got); diff != "" {
+		t.Errorf("got config diff (-want, +got):\n%s", diff)
+	}
+}
+
+func TestNewFlagSet(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name         string
+		args         []string
+		env          map[string]string
+		wantDuration time.Duration
+		wantFormat   string
+		wantErr      string
+	}{
+		{
+			name:         "no_config",
+			wantDuration: time.Hour,
+			wantFormat:   formatText,
+		},
+		{
+			name:         "env_config",
+			env:          map[string]string{"AOD_DURATION": "2h", "AOD_FORMAT": "json"},
+			wantDuration: 2 * time.Hour,
+			wantFormat:   formatJSON,
+		},
+		{
+			name:         "flag_overrides_config",
+			args:         []string{"-duration", "3h"},
+			env:          map[string]string{"AOD_DURATION": "2h"},
+			wantDuration: 3 * time.Hour,
+			wantFormat:   formatText,
+		},
+		{
+			name:         "unknown_flags_ignored",
+			env:          map[string]string{"AOD_CONDITION_TITLE": "my-aod-expiry"},
+			wantDuration: time.Hour,
+			wantFormat:   formatText,
+		},
+		{
+			name:    "invalid_config",
+			env:     map[string]string{"AOD_DURATION": "bananas"},
+			wantErr: "failed to load CLI config",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			env := map[string]string{"HOME": t.TempDir()}
+			for k, v := range tc.env {
+				env[k] = v
+			}
+
+			var cmd cli.BaseCommand
+			cmd.SetLookupEnv(cli.MapLookuper(env))
+
+			var gotDuration time.Duration
+			var gotFormat string
+			set := newFlagSet(&cmd)
+			f := set.NewSection("COMMAND OPTIONS")
+			f.DurationVar(&cli.DurationVar{Name: "duration", Target: &gotDuration, Default: time.Hour})
+			f.StringVar(&cli.StringVar{Name: "format", Target: &gotFormat, Default: formatText})
+
+			err := set.Parse(tc.args)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if tc.wantErr != "" {
+				return
+			}
+			if gotDuration != tc.wantDuration {
+				t.Errorf("Process(%+v) got duration %s, want %s", tc.name, gotDuration, tc.wantDuration)
+			}
+			if gotFormat != tc.wantFormat {
+				t.Errorf("Process(%+v) got format %q, want %q", tc.name, gotFormat, tc.wantFormat)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/posener/complete/v2"
+	"github.com/posener/complete/v2/predict"
+
+	"github.com/abcxyz/access-on-demand/pkg/examples"
+	"github.com/abcxyz/pkg/cli"
+)
+
+var (
+	_ cli.Command      = (*ExamplesCommand)(nil)
+	_ cli.ArgPredictor = (*ExamplesCommand)(nil)
+)
+
+// ExamplesCommand prints the bundled example request files, or writes them to
+// a directory.
+type ExamplesCommand struct {
+	cli.BaseCommand
+
+	flagWrite string
+
+	flagForce bool
+}
+
+func (c *ExamplesCommand) Desc() string {
+	return `List, print or write the bundled example request files`
+}
+
+func (c *ExamplesCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options] [name]
+
+List the bundled example request files:
+
+      {{ COMMAND }}
+
+Print the example request file of the given name:
+
+      {{ COMMAND }} combined
+
+Write the example request file of the given name to a directory:
+
+      {{ COMMAND }} -write "requests" combined
+
+Write all the example request files to a directory:
+
+      {{ COMMAND }} -write "requests"
+`
+}
+
+func (c *ExamplesCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "write",
+		Target:  &c.flagWrite,
+		Example: "requests",
+		Predict: predict.Dirs("*"),
+		Usage: `The directory to write the example request files to, as ` +
+			`"<name>.yaml". All the examples are written if no name is given.`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "force",
+		Target:  &c.flagForce,
+		Default: false,
+		Usage:   `Overwrite the example request files if they exist.`,
+	})
+
+	return set
+}
+
+func (c *ExamplesCommand) PredictArgs() complete.Predictor {
+	return predict.Set(examples.Names())
+}
+
+func (c *ExamplesCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 1 {
+		return fmt.Errorf("expected at most one example name, got %q", args)
+	}
+
+	selected := examples.All()
+	if len(args) == 1 {
+		e, ok := examples.Lookup(args[0])
+		if !ok {
+			return fmt.Errorf("example %q not found, must be one of %q", args[0], examples.Names())
+		}
+		selected = []*examples.Example{e}
+	}
+
+	if c.flagWrite != "" {
+		return c.write(selected)
+	}
+	if len(args) == 0 {
+		return c.list()
+	}
+
+	b, err := selected[0].Content()
+	if err != nil {
+		return err
+	}
+	if _, err := c.Stdout().Write(b); err != nil {
+		return fmt.Errorf("failed to output example: %w", err)
+	}
+	return nil
+}
+
+// list prints the names and descriptions of all the examples.
+func (c *ExamplesCommand) list() error {
+	tw := tabwriter.NewWriter(c.Stdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tDESCRIPTION")
+	for _, e := range examples.All() {
+		fmt.Fprintf(tw, "%s\t%s\n", e.Name, e.Description)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("failed to flush table: %w", err)
+	}
+	return nil
+}
+
+// write writes the files of the examples to the directory of the write flag,
+// which is created if it does not exist. Existing files are only overwritten
+// with the force flag.
+func (c *ExamplesCommand) write(selected []*examples.Example) error {
+	if err := os.MkdirAll(c.flagWrite, 0o755); err != nil {
+		return fmt.Errorf("failed to create directory %q: %w", c.flagWrite, err)
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !c.flagForce {
+		flags |= os.O_EXCL
+	}
+	for _, e := range selected {
+		b, err := e.Content()
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(c.flagWrite, e.FileName())
+		f, err := os.OpenFile(path, flags, 0o644)
+		if err != nil {
+			if errors.Is(err, fs.ErrExist) {
+				return fmt.Errorf("file %q already exists, set force to overwrite it", path)
+			}
+			return fmt.Errorf("failed to open file %q: %w", path, err)
+		}
+		if _, err := f.Write(b); err != nil {
+			return errors.Join(fmt.Errorf("failed to write file %q: %w", path, err), f.Close())
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to close file %q: %w", path, err)
+		}
+		c.Outf("Successfully wrote example %s to %s", e.Name, path)
+	}
+	return nil
+}
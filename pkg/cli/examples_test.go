@@ -0,0 +1,154 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/pkg/examples"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestExamplesCommand(t *testing.T) {
+	t.Parallel()
+
+	combined, ok := examples.Lookup("combined")
+	if !ok {
+		t.Fatal("combined example not found")
+	}
+	combinedContent, err := combined.Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		args     []string
+		existing string
+		expFiles []string
+		expOut   string
+		expErr   string
+	}{
+		{
+			name: "list",
+			expOut: `
+NAME            DESCRIPTION
+iam             IAM request granting a role to members on a project
+tool            Tool request running gcloud commands
+combined        Combined request granting IAM bindings and running the tool commands needing them
+multi-resource  IAM request granting roles on an organization, a folder and projects`,
+		},
+		{
+			name:   "print",
+			args:   []string{"combined"},
+			expOut: string(combinedContent),
+		},
+		{
+			name:     "write",
+			args:     []string{"-write", "{{dir}}", "combined"},
+			expFiles: []string{"combined.yaml"},
+			expOut:   "Successfully wrote example combined to {{dir}}/combined.yaml",
+		},
+		{
+			name:     "write_all",
+			args:     []string{"-write", "{{dir}}"},
+			expFiles: []string{"combined.yaml", "iam.yaml", "multi-resource.yaml", "tool.yaml"},
+			expOut: `
+Successfully wrote example iam to {{dir}}/iam.yaml
+Successfully wrote example tool to {{dir}}/tool.yaml
+Successfully wrote example combined to {{dir}}/combined.yaml
+Successfully wrote example multi-resource to {{dir}}/multi-resource.yaml`,
+		},
+		{
+			name:     "write_force",
+			args:     []string{"-write", "{{dir}}", "-force", "combined"},
+			existing: "combined.yaml",
+			expFiles: []string{"combined.yaml"},
+			expOut:   "Successfully wrote example combined to {{dir}}/combined.yaml",
+		},
+		{
+			name:     "file_exists",
+			args:     []string{"-write", "{{dir}}", "combined"},
+			existing: "combined.yaml",
+			expErr:   "already exists, set force to overwrite it",
+		},
+		{
+			name:   "unknown_example",
+			args:   []string{"bananas"},
+			expErr: `example "bananas" not found`,
+		},
+		{
+			name:   "too_many_args",
+			args:   []string{"iam", "tool"},
+			expErr: `expected at most one example name, got ["iam" "tool"]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			dir := filepath.Join(t.TempDir(), "requests")
+			if tc.existing != "" {
+				if err := os.MkdirAll(dir, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, tc.existing), []byte("existing"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			args := make([]string, 0, len(tc.args))
+			for _, a := range tc.args {
+				args = append(args, strings.ReplaceAll(a, "{{dir}}", dir))
+			}
+
+			var cmd ExamplesCommand
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			expOut := strings.ReplaceAll(tc.expOut, "{{dir}}", dir)
+			if diff := cmp.Diff(strings.TrimSpace(expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+
+			for _, name := range tc.expFiles {
+				e, _ := examples.Lookup(strings.TrimSuffix(name, ".yaml"))
+				want, err := e.Content()
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatalf("Process(%+v) failed to read written file: %v", tc.name, err)
+				}
+				if diff := cmp.Diff(string(want), string(got)); diff != "" {
+					t.Errorf("Process(%+v) got file %s diff (-want, +got):\n%s", tc.name, name, diff)
+				}
+			}
+		})
+	}
+}
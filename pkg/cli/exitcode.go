@@ -0,0 +1,127 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+
+	"github.com/abcxyz/access-on-demand/pkg/handler"
+)
+
+// Exit codes of the CLI by the class of the failure, so that CI can branch on
+// the class instead of parsing the error message.
+const (
+	// ExitCodeFailure is the exit code of any other failure, such as invalid
+	// flags.
+	ExitCodeFailure = 1
+
+	// ExitCodeInvalidRequest is the exit code when a request file cannot be
+	// read or parsed.
+	ExitCodeInvalidRequest = 2
+
+	// ExitCodeValidation is the exit code when a request violates a validation
+	// policy, such as an invalid member, an unknown role bundle or missing
+	// approvals.
+	ExitCodeValidation = 3
+
+	// ExitCodeAPIFailure is the exit code when the IAM API calls failed for all
+	// the resources.
+	ExitCodeAPIFailure = 4
+
+	// ExitCodePartialFailure is the exit code when the IAM API calls failed for
+	// some of the resources or requests, and succeeded for the others.
+	ExitCodePartialFailure = 5
+
+	// ExitCodeTimeout is the exit code when the command did not finish before
+	// the deadline of the global "-timeout" flag.
+	ExitCodeTimeout = 6
+
+	// ExitCodeMaintenance is the exit code when a grant is refused because AOD
+	// is in maintenance mode.
+	ExitCodeMaintenance = 7
+)
+
+// exitError is an error with the exit code of its class.
+type exitError struct {
+	code int
+	err  error
+}
+
+// Error implements error.
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode returns the error with the exit code, or nil if err is nil.
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// ExitCode returns the exit code of the error returned by [Run], which is the
+// code of the outermost error with an exit code, or [ExitCodeFailure] if there
+// is none. It returns 0 if err is nil.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var e *exitError
+	if errors.As(err, &e) {
+		return e.code
+	}
+	return ExitCodeFailure
+}
+
+// apiExitCode returns the exit code of the error of handling n resources, or n
+// requests. The errors of the failed resources or requests are joined, so it
+// is a partial failure if there are fewer errors than n. A request rolled back
+// is a partial failure only if some resources failed to be rolled back, and a
+// request failing the pre-flight checks is never a partial failure. A grant
+// refused in maintenance mode has its own exit code.
+func apiExitCode(err error, n int) int {
+	var mErr *handler.MaintenanceError
+	if errors.As(err, &mErr) {
+		return ExitCodeMaintenance
+	}
+
+	var pfErr *handler.PreflightError
+	if errors.As(err, &pfErr) {
+		return ExitCodeAPIFailure
+	}
+
+	var rbErr *handler.RollbackError
+	if errors.As(err, &rbErr) {
+		if len(rbErr.Failed) > 0 {
+			return ExitCodePartialFailure
+		}
+		return ExitCodeAPIFailure
+	}
+
+	failed := 1
+	if joined, ok := err.(interface{ Unwrap() []error }); ok { //nolint:errorlint // Counting the joined errors.
+		failed = len(joined.Unwrap())
+	}
+	if failed < n {
+		return ExitCodePartialFailure
+	}
+	return ExitCodeAPIFailure
+}
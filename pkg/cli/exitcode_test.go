@@ -0,0 +1,203 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/abcxyz/access-on-demand/pkg/handler"
+	"github.com/abcxyz/pkg/logging"
+)
+
+func TestExitCode(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{
+			name: "nil",
+			want: 0,
+		},
+		{
+			name: "other_failure",
+			err:  fmt.Errorf("injected error"),
+			want: ExitCodeFailure,
+		},
+		{
+			name: "wrapped",
+			err:  fmt.Errorf("outer: %w", withExitCode(ExitCodeValidation, fmt.Errorf("injected error"))),
+			want: ExitCodeValidation,
+		},
+		{
+			name: "outermost",
+			err:  withExitCode(ExitCodePartialFailure, withExitCode(ExitCodeAPIFailure, fmt.Errorf("injected error"))),
+			want: ExitCodePartialFailure,
+		},
+		{
+			name: "joined",
+			err:  errors.Join(fmt.Errorf("injected error"), withExitCode(ExitCodeInvalidRequest, fmt.Errorf("injected error"))),
+			want: ExitCodeInvalidRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := ExitCode(tc.err); got != tc.want {
+				t.Errorf("ExitCode(%v) got %d, want %d", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIAMHandleCommand_ExitCode(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.yaml":         "policies:\n- resource: projects/a\n  bindings:\n  - members: [user:alice@example.com]\n    role: roles/viewer\n",
+		"b.yaml":         "policies:\n- resource: projects/b\n  bindings:\n  - members: [user:bob@example.com]\n    role: roles/viewer\n",
+		"ab.yaml":        "policies:\n- resource: projects/a\n  bindings:\n  - members: [user:alice@example.com]\n    role: roles/viewer\n- resource: projects/b\n  bindings:\n  - members: [user:bob@example.com]\n    role: roles/viewer\n",
+		"malformed.yaml": "policies: [",
+		"invalid.yaml":   "policies:\n- resource: projects/c\n  bindings:\n  - members: [group:eng@example.com]\n    role: roles/viewer\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name    string
+		paths   []string
+		handler *fakeIAMHandler
+		want    int
+	}{
+		{
+			name:    "success",
+			paths:   []string{"a.yaml"},
+			handler: &fakeIAMHandler{},
+			want:    0,
+		},
+		{
+			name:    "invalid_request",
+			paths:   []string{"malformed.yaml"},
+			handler: &fakeIAMHandler{},
+			want:    ExitCodeInvalidRequest,
+		},
+		{
+			name:    "validation",
+			paths:   []string{"invalid.yaml"},
+			handler: &fakeIAMHandler{},
+			want:    ExitCodeValidation,
+		},
+		{
+			name:    "api_failure",
+			paths:   []string{"a.yaml"},
+			handler: &fakeIAMHandler{injectErr: fmt.Errorf("injected error")},
+			want:    ExitCodeAPIFailure,
+		},
+		{
+			name:    "partial_resources",
+			paths:   []string{"ab.yaml"},
+			handler: &fakeIAMHandler{injectErr: errors.Join(fmt.Errorf("injected error"))},
+			want:    ExitCodePartialFailure,
+		},
+		{
+			name:    "partial_requests",
+			paths:   []string{"a.yaml", "b.yaml"},
+			handler: &fakeIAMHandler{injectErrs: map[string]error{"projects/b": fmt.Errorf("injected error")}},
+			want:    ExitCodePartialFailure,
+		},
+		{
+			name:    "all_requests_failed",
+			paths:   []string{"a.yaml", "b.yaml"},
+			handler: &fakeIAMHandler{injectErr: fmt.Errorf("injected error")},
+			want:    ExitCodeAPIFailure,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			args := []string{"-duration", "2h"}
+			for _, p := range tc.paths {
+				args = append(args, "-path", filepath.Join(dir, p))
+			}
+
+			var cmd IAMHandleCommand
+			cmd.testHandler = tc.handler
+			cmd.Pipe()
+
+			err := cmd.Run(ctx, args)
+			if got := ExitCode(err); got != tc.want {
+				t.Errorf("Process(%+v) got exit code %d, want %d, error: %v", tc.name, got, tc.want, err)
+			}
+		})
+	}
+}
+
+func TestAPIExitCode(t *testing.T) {
+	t.Parallel()
+
+	errs := errors.Join(fmt.Errorf("injected error a"), fmt.Errorf("injected error b"))
+	if got, want := apiExitCode(errs, 3), ExitCodePartialFailure; got != want {
+		t.Errorf("apiExitCode(%q, 3) got %d, want %d", strings.ReplaceAll(errs.Error(), "\n", "; "), got, want)
+	}
+	if got, want := apiExitCode(errs, 2), ExitCodeAPIFailure; got != want {
+		t.Errorf("apiExitCode(%q, 2) got %d, want %d", strings.ReplaceAll(errs.Error(), "\n", "; "), got, want)
+	}
+
+	rolledBack := fmt.Errorf("failed to handle IAM request: %w", &handler.RollbackError{
+		Err:        fmt.Errorf("injected error"),
+		RolledBack: []string{"projects/a"},
+	})
+	if got, want := apiExitCode(rolledBack, 2), ExitCodeAPIFailure; got != want {
+		t.Errorf("apiExitCode(%q, 2) got %d, want %d", rolledBack, got, want)
+	}
+	rollbackFailed := &handler.RollbackError{
+		Err:         fmt.Errorf("injected error"),
+		Failed:      []string{"projects/a"},
+		RollbackErr: fmt.Errorf("injected rollback error"),
+	}
+	if got, want := apiExitCode(rollbackFailed, 2), ExitCodePartialFailure; got != want {
+		t.Errorf("apiExitCode(%q, 2) got %d, want %d", rollbackFailed, got, want)
+	}
+	preflightFailed := &handler.PreflightError{
+		Err:       errors.Join(fmt.Errorf("injected error")),
+		Resources: []string{"projects/a"},
+	}
+	if got, want := apiExitCode(preflightFailed, 2), ExitCodeAPIFailure; got != want {
+		t.Errorf("apiExitCode(%q, 2) got %d, want %d", preflightFailed, got, want)
+	}
+
+	maintenance := fmt.Errorf("failed to handle: %w", &handler.MaintenanceError{Reason: "incident"})
+	if got, want := apiExitCode(maintenance, 2), ExitCodeMaintenance; got != want {
+		t.Errorf("apiExitCode(%q, 2) got %d, want %d", maintenance, got, want)
+	}
+}
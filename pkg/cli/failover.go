@@ -0,0 +1,132 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	resourcemanager "cloud.google.com/go/resourcemanager/apiv3"
+	"google.golang.org/api/option"
+
+	"github.com/abcxyz/access-on-demand/pkg/failover"
+	"github.com/abcxyz/access-on-demand/pkg/handler"
+	"github.com/abcxyz/pkg/multicloser"
+)
+
+// resourceManagerEndpoint is the default endpoint of the resource manager API.
+const resourceManagerEndpoint = "cloudresourcemanager.googleapis.com:443"
+
+// resourceManagerFailover are the IAM clients of organizations, folders and
+// projects failing over to the fallback endpoints of the resource manager API.
+type resourceManagerFailover struct {
+	organizations *failover.Client
+	folders       *failover.Client
+	projects      *failover.Client
+}
+
+// newResourceManagerFailover creates the failover clients with the default
+// clients as the primary endpoint, followed by clients of the fallback
+// endpoints.
+func newResourceManagerFailover(
+	ctx context.Context,
+	fallbacks []string,
+	organizationsClient *resourcemanager.OrganizationsClient,
+	foldersClient *resourcemanager.FoldersClient,
+	projectsClient *resourcemanager.ProjectsClient,
+) (*resourceManagerFailover, *multicloser.Closer, error) {
+	var closer *multicloser.Closer
+
+	orgEndpoints := []*failover.Endpoint{{Name: resourceManagerEndpoint, Client: organizationsClient}}
+	folderEndpoints := []*failover.Endpoint{{Name: resourceManagerEndpoint, Client: foldersClient}}
+	projectEndpoints := []*failover.Endpoint{{Name: resourceManagerEndpoint, Client: projectsClient}}
+	for _, ep := range fallbacks {
+		oc, err := resourcemanager.NewOrganizationsClient(ctx, option.WithEndpoint(ep))
+		if err != nil {
+			return nil, closer, fmt.Errorf("failed to create organizations client of endpoint %q: %w", ep, err)
+		}
+		closer = multicloser.Append(closer, oc.Close)
+		orgEndpoints = append(orgEndpoints, &failover.Endpoint{Name: ep, Client: oc})
+
+		fc, err := resourcemanager.NewFoldersClient(ctx, option.WithEndpoint(ep))
+		if err != nil {
+			return nil, closer, fmt.Errorf("failed to create folders client of endpoint %q: %w", ep, err)
+		}
+		closer = multicloser.Append(closer, fc.Close)
+		folderEndpoints = append(folderEndpoints, &failover.Endpoint{Name: ep, Client: fc})
+
+		pc, err := resourcemanager.NewProjectsClient(ctx, option.WithEndpoint(ep))
+		if err != nil {
+			return nil, closer, fmt.Errorf("failed to create projects client of endpoint %q: %w", ep, err)
+		}
+		closer = multicloser.Append(closer, pc.Close)
+		projectEndpoints = append(projectEndpoints, &failover.Endpoint{Name: ep, Client: pc})
+	}
+
+	var f resourceManagerFailover
+	var err error
+	if f.organizations, err = failover.New(orgEndpoints); err != nil {
+		return nil, closer, fmt.Errorf("failed to create organizations failover client: %w", err)
+	}
+	if f.folders, err = failover.New(folderEndpoints); err != nil {
+		return nil, closer, fmt.Errorf("failed to create folders failover client: %w", err)
+	}
+	if f.projects, err = failover.New(projectEndpoints); err != nil {
+		return nil, closer, fmt.Errorf("failed to create projects failover client: %w", err)
+	}
+	return &f, closer, nil
+}
+
+// clients returns the IAM clients of organizations, folders and projects.
+func (f *resourceManagerFailover) clients() (handler.IAMClient, handler.IAMClient, handler.IAMClient) {
+	return f.organizations, f.folders, f.projects
+}
+
+// printDegraded prints a warning to w if the resource manager API was
+// degraded, which is when IAM calls were served by fallback endpoints or the
+// circuit of an endpoint is open. In GitHub Actions it is printed as a workflow
+// command to be shown as an annotation.
+func (f *resourceManagerFailover) printDegraded(w io.Writer, githubActions bool) {
+	failovers := make(map[string]int)
+	open := make(map[string]struct{})
+	for _, c := range []*failover.Client{f.organizations, f.folders, f.projects} {
+		for ep, n := range c.Failovers() {
+			failovers[ep] += n
+		}
+		for _, ep := range c.OpenCircuits() {
+			open[ep] = struct{}{}
+		}
+	}
+	if len(failovers) == 0 && len(open) == 0 {
+		return
+	}
+
+	details := make([]string, 0, 2)
+	if len(failovers) > 0 {
+		served := make([]string, 0, len(failovers))
+		for _, ep := range slices.Sorted(maps.Keys(failovers)) {
+			served = append(served, fmt.Sprintf("%d by %q", failovers[ep], ep))
+		}
+		details = append(details, "IAM calls were served by fallback endpoints: "+strings.Join(served, ", "))
+	}
+	if len(open) > 0 {
+		details = append(details, fmt.Sprintf("circuits are open for endpoints %q", slices.Sorted(maps.Keys(open))))
+	}
+	printWarning(w, "degraded mode, the resource manager API failed over: "+strings.Join(details, "; "), githubActions)
+}
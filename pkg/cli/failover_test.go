@@ -0,0 +1,114 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/abcxyz/access-on-demand/pkg/failover"
+	"github.com/abcxyz/access-on-demand/pkg/handler"
+)
+
+// unavailableIAMClient is an IAM client of an unavailable endpoint.
+type unavailableIAMClient struct{}
+
+func (c *unavailableIAMClient) GetIamPolicy(context.Context, *iampb.GetIamPolicyRequest, ...gax.CallOption) (*iampb.Policy, error) {
+	return nil, status.Error(codes.Unavailable, "regional outage")
+}
+
+func (c *unavailableIAMClient) SetIamPolicy(context.Context, *iampb.SetIamPolicyRequest, ...gax.CallOption) (*iampb.Policy, error) {
+	return nil, status.Error(codes.Unavailable, "regional outage")
+}
+
+func TestResourceManagerFailoverPrintDegraded(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		primary       handler.IAMClient
+		calls         int
+		githubActions bool
+		expOut        string
+	}{
+		{
+			name:    "healthy",
+			primary: &fakeIAMClient{},
+			calls:   2,
+		},
+		{
+			name:    "failed_over",
+			primary: &unavailableIAMClient{},
+			calls:   1,
+			expOut: `WARNING: degraded mode, the resource manager API failed over: IAM calls were served by ` +
+				`fallback endpoints: 3 by "us-east1-cloudresourcemanager.googleapis.com:443"`,
+		},
+		{
+			name:          "circuit_open_github_actions",
+			primary:       &unavailableIAMClient{},
+			calls:         failover.DefaultThreshold,
+			githubActions: true,
+			expOut: `::warning::degraded mode, the resource manager API failed over: IAM calls were served by ` +
+				`fallback endpoints: 9 by "us-east1-cloudresourcemanager.googleapis.com:443"; circuits are ` +
+				`open for endpoints ["cloudresourcemanager.googleapis.com:443"]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			newClient := func() *failover.Client {
+				c, err := failover.New([]*failover.Endpoint{
+					{Name: resourceManagerEndpoint, Client: tc.primary},
+					{Name: "us-east1-cloudresourcemanager.googleapis.com:443", Client: &fakeIAMClient{}},
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return c
+			}
+			f := &resourceManagerFailover{
+				organizations: newClient(),
+				folders:       newClient(),
+				projects:      newClient(),
+			}
+
+			ctx := context.Background()
+			orgs, folders, projects := f.clients()
+			for i := 0; i < tc.calls; i++ {
+				for _, c := range []handler.IAMClient{orgs, folders, projects} {
+					if _, err := c.GetIamPolicy(ctx, &iampb.GetIamPolicyRequest{}); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			var out bytes.Buffer
+			f.printDegraded(&out, tc.githubActions)
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(out.String())); diff != "" {
+				t.Errorf("printDegraded(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// Statuses of the requests in the GitHub Actions outputs.
+const (
+	actionStatusHandled   = "handled"
+	actionStatusCleanedUp = "cleaned_up"
+	actionStatusFailed    = "failed"
+)
+
+// actionResult is the result of a request in the "results" output of the
+// GitHub Action, a stable JSON schema for the steps after it.
+type actionResult struct {
+	Path      string     `json:"path"`
+	Status    string     `json:"status"`
+	Resources []string   `json:"resources"`
+	StartTime *time.Time `json:"startTime,omitempty"`
+	Expiry    *time.Time `json:"expiry,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// newActionResult returns the result of the IAM request at the path, with the
+// status, or the failed status if err is not nil.
+func newActionResult(path string, req *v1alpha1.IAMRequest, status string, err error) *actionResult {
+	r := &actionResult{Path: path, Status: status, Resources: make([]string, 0, len(req.ResourcePolicies))}
+	for _, p := range req.ResourcePolicies {
+		r.Resources = append(r.Resources, p.Resource)
+	}
+	if err != nil {
+		r.Status = actionStatusFailed
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// actionOutputs returns the GitHub Actions outputs of the results: "results",
+// the JSON array of the results, and "expiry", the earliest expiry of the
+// handled requests in RFC 3339 format, or empty if there is none.
+func actionOutputs(results []*actionResult) (map[string]string, error) {
+	b, err := json.Marshal(results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal results: %w", err)
+	}
+	var expiry time.Time
+	for _, r := range results {
+		if r.Expiry != nil && (expiry.IsZero() || r.Expiry.Before(expiry)) {
+			expiry = *r.Expiry
+		}
+	}
+	outputs := map[string]string{"results": string(b), "expiry": ""}
+	if !expiry.IsZero() {
+		outputs["expiry"] = expiry.UTC().Format(time.RFC3339)
+	}
+	return outputs, nil
+}
+
+// writeActionOutputs writes the outputs of the results to the file at
+// GITHUB_OUTPUT read with getenv, if it is set. Failures are logged.
+func writeActionOutputs(ctx context.Context, getenv func(string) string, results []*actionResult) {
+	path := getenv("GITHUB_OUTPUT")
+	if path == "" {
+		return
+	}
+	logger := logging.FromContext(ctx)
+
+	outputs, err := actionOutputs(results)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to render GitHub Actions outputs", "error", err)
+		return
+	}
+	delim, err := outputDelimiter()
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to render GitHub Actions outputs", "error", err)
+		return
+	}
+
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		// Use the multiline syntax in case the value has line breaks.
+		fmt.Fprintf(&b, "%s<<%s\n%s\n%s\n", name, delim, outputs[name], delim)
+	}
+	if err := appendFile(path, b.String()); err != nil {
+		logger.ErrorContext(ctx, "failed to write GitHub Actions outputs", "error", err)
+	}
+}
+
+// writeStepSummary appends the markdown of the comment to the job summary at
+// GITHUB_STEP_SUMMARY read with getenv, if it is set. Failures are logged.
+func writeStepSummary(ctx context.Context, getenv func(string) string, c *prComment) {
+	path := getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		return
+	}
+	logger := logging.FromContext(ctx)
+
+	body, err := c.body(messageCatalog(ctx, getenv))
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to render GitHub Actions step summary", "error", err)
+		return
+	}
+	if err := appendFile(path, body+"\n"); err != nil {
+		logger.ErrorContext(ctx, "failed to write GitHub Actions step summary", "error", err)
+	}
+}
+
+// outputDelimiter returns a random delimiter of multiline output values.
+func outputDelimiter() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate delimiter: %w", err)
+	}
+	return "aod_" + hex.EncodeToString(b), nil
+}
+
+// appendFile appends s to the file at the path.
+func appendFile(path, s string) (retErr error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open %q: %w", path, err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil && retErr == nil {
+			retErr = fmt.Errorf("failed to close %q: %w", path, err)
+		}
+	}()
+	if _, err := f.WriteString(s); err != nil {
+		return fmt.Errorf("failed to write %q: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/logging"
+)
+
+func TestActionOutputs(t *testing.T) {
+	t.Parallel()
+
+	req := &v1alpha1.IAMRequest{
+		ResourcePolicies: []*v1alpha1.ResourcePolicy{
+			{Resource: "projects/foo"},
+			{Resource: "folders/bar"},
+		},
+	}
+	st := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	expiry1, expiry2 := st.Add(2*time.Hour), st.Add(time.Hour)
+
+	handled1 := newActionResult("a.yaml", req, actionStatusHandled, nil)
+	handled1.StartTime, handled1.Expiry = &st, &expiry1
+	handled2 := newActionResult("b.yaml", req, actionStatusHandled, nil)
+	handled2.StartTime, handled2.Expiry = &st, &expiry2
+
+	cases := []struct {
+		name    string
+		results []*actionResult
+		want    map[string]string
+	}{
+		{
+			name:    "handled",
+			results: []*actionResult{handled1, handled2},
+			want: map[string]string{
+				"results": `[{"path":"a.yaml","status":"handled","resources":["projects/foo","folders/bar"],"startTime":"2009-11-10T23:00:00Z","expiry":"2009-11-11T01:00:00Z"},` +
+					`{"path":"b.yaml","status":"handled","resources":["projects/foo","folders/bar"],"startTime":"2009-11-10T23:00:00Z","expiry":"2009-11-11T00:00:00Z"}]`,
+				"expiry": "2009-11-11T00:00:00Z",
+			},
+		},
+		{
+			name:    "failed",
+			results: []*actionResult{newActionResult("a.yaml", req, actionStatusCleanedUp, fmt.Errorf("injected error"))},
+			want: map[string]string{
+				"results": `[{"path":"a.yaml","status":"failed","resources":["projects/foo","folders/bar"],"error":"injected error"}]`,
+				"expiry":  "",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := actionOutputs(tc.results)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Process(%+v) got outputs diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestWriteActionOutputs(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+	path := filepath.Join(t.TempDir(), "output")
+	if err := os.WriteFile(path, []byte("existing=1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	getenv := func(k string) string {
+		return map[string]string{"GITHUB_OUTPUT": path}[k]
+	}
+	req := &v1alpha1.IAMRequest{ResourcePolicies: []*v1alpha1.ResourcePolicy{{Resource: "projects/foo"}}}
+
+	writeActionOutputs(ctx, getenv, []*actionResult{newActionResult("a.yaml", req, actionStatusCleanedUp, nil)})
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := regexp.MustCompile(`aod_[0-9a-f]{32}`).ReplaceAllString(string(b), "DELIM")
+	want := `existing=1
+expiry<<DELIM
+
+DELIM
+results<<DELIM
+[{"path":"a.yaml","status":"cleaned_up","resources":["projects/foo"]}]
+DELIM
+`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("got outputs file diff (-want, +got):\n%s", diff)
+	}
+}
+
+func TestWriteStepSummary(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+	path := filepath.Join(t.TempDir(), "summary")
+	getenv := func(k string) string {
+		return map[string]string{"GITHUB_STEP_SUMMARY": path}[k]
+	}
+
+	writeStepSummary(ctx, getenv, &prComment{Action: "handle", Done: "handled", Err: fmt.Errorf("injected error")})
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "**AOD failed to handle the IAM request.**\n\n```\ninjected error\n```"
+	if diff := cmp.Diff(want, strings.TrimSpace(string(b))); diff != "" {
+		t.Errorf("got summary diff (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,403 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/posener/complete/v2/predict"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/group"
+	"github.com/abcxyz/access-on-demand/pkg/handler"
+	"github.com/abcxyz/access-on-demand/pkg/messages"
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+)
+
+var (
+	_ cli.Command = (*GroupValidateCommand)(nil)
+	_ cli.Command = (*GroupHandleCommand)(nil)
+	_ cli.Command = (*GroupCleanupCommand)(nil)
+)
+
+// groupPathUsage is the usage of the path flag of the group commands.
+const groupPathUsage = `The path of group request file, in YAML format. ` +
+	`Use "-" to read it from stdin.`
+
+// groupHandler interface that handles group requests.
+type groupHandler interface {
+	Do(context.Context, *v1alpha1.GroupRequest, *v1alpha1.IAMRequestWrapper) (*handler.GroupResponse, error)
+	Cleanup(context.Context, *v1alpha1.GroupRequest) (*handler.GroupResponse, error)
+}
+
+// readGroupRequest reads and validates the group request at the path.
+func readGroupRequest(rr *requestReader, path string) (*v1alpha1.GroupRequest, error) {
+	var req v1alpha1.GroupRequest
+	loc, err := rr.requestWithLocator(path, &req)
+	if err != nil {
+		return nil, withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &req, err))
+	}
+
+	if err := v1alpha1.ValidateGroupRequest(&req); err != nil {
+		err = loc.Annotate(err)
+		return nil, withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", &req, err))
+	}
+	return &req, nil
+}
+
+// newGroupHandler returns a group handler sharing the audit sinks and the
+// other settings of the IAM handler of the flags.
+func newGroupHandler(ctx context.Context, flags *iamHandlerFlags, cmd *cli.BaseCommand) (*handler.GroupHandler, func(), error) {
+	logger := logging.FromContext(ctx)
+
+	m, err := group.NewClient(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create group client: %w", err)
+	}
+	h, closer, err := newIAMHandler(ctx, flags, cmd)
+	done := func() {
+		if err := closer.Close(); err != nil {
+			logger.ErrorContext(ctx, "failed to close", "error", err)
+		}
+	}
+	if err != nil {
+		done()
+		return nil, nil, err
+	}
+	gh, err := handler.NewGroupHandler(h, m)
+	if err != nil {
+		done()
+		return nil, nil, fmt.Errorf("failed to create group handler: %w", err)
+	}
+	return gh, done, nil
+}
+
+// printGroupResponse prints the warnings of the group response to stderr, and
+// the response with the header to stdout.
+func printGroupResponse(ctx context.Context, cmd *cli.BaseCommand, header messages.ID, resp *handler.GroupResponse) error {
+	githubActions := cmd.GetEnv("GITHUB_ACTIONS") == "true"
+	for _, msg := range resp.Warnings {
+		printWarning(cmd.Stderr(), msg, githubActions)
+	}
+	printMessageHeader(ctx, cmd, header)
+	out := map[string]any{
+		"group":   resp.Group,
+		"members": resp.Members,
+	}
+	if resp.Expiry != nil {
+		out["expiry"] = resp.Expiry.UTC().Format(time.RFC3339)
+	}
+	if err := encodeYaml(cmd.Stdout(), out); err != nil {
+		return fmt.Errorf("failed to output group response: %w", err)
+	}
+	return nil
+}
+
+// GroupValidateCommand validates group requests.
+type GroupValidateCommand struct {
+	cli.BaseCommand
+
+	flagPath string
+}
+
+func (c *GroupValidateCommand) Desc() string {
+	return `Validate the group request YAML file at the given path`
+}
+
+func (c *GroupValidateCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Validate the group request YAML file at the given path:
+
+      {{ COMMAND }} -path "/path/to/file.yaml"
+`
+}
+
+func (c *GroupValidateCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "path",
+		Target:  &c.flagPath,
+		Example: "/path/to/file.yaml",
+		Predict: predict.Files("*"),
+		Usage:   groupPathUsage,
+	})
+
+	return set
+}
+
+func (c *GroupValidateCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagPath == "" {
+		return fmt.Errorf("path is required")
+	}
+
+	if _, err := readGroupRequest(newRequestReader(c.Stdin()), c.flagPath); err != nil {
+		return err
+	}
+	c.Outf("Successfully validated group request")
+
+	return nil
+}
+
+// GroupHandleCommand adds members to Google Groups with an expiry.
+type GroupHandleCommand struct {
+	cli.BaseCommand
+
+	flagPath string
+
+	flagDuration time.Duration
+
+	flagStartTime time.Time
+
+	iamHandlerFlags iamHandlerFlags
+
+	provenanceFlags provenanceFlags
+
+	// testHandler is used for testing only.
+	testHandler groupHandler
+}
+
+func (c *GroupHandleCommand) Desc() string {
+	return `Add the members in the group request YAML file to its Google Group with an expiry`
+}
+
+func (c *GroupHandleCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Add the members in the group request YAML file to its Google Group for 2 hours:
+
+      {{ COMMAND }} -path "/path/to/file.yaml" -duration "2h"
+
+Cloud Identity removes the members once their memberships expire. Temporary
+memberships are extended but never shortened, and permanent members are left
+unchanged.
+`
+}
+
+func (c *GroupHandleCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "path",
+		Target:  &c.flagPath,
+		Example: "/path/to/file.yaml",
+		Predict: predict.Files("*"),
+		Usage:   groupPathUsage,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "duration",
+		Target:  &c.flagDuration,
+		Example: "2h",
+		Usage:   `The group membership lifecycle, as a duration.`,
+	})
+
+	f.TimeVar(time.RFC3339, &cli.TimeVar{
+		Name:    "start-time",
+		Target:  &c.flagStartTime,
+		Example: "2009-11-10T23:00:00Z",
+		Usage: `The start time of the group membership lifecycle in RFC3339 ` +
+			`format. Default is current UTC time.`,
+	})
+
+	c.iamHandlerFlags.register(f)
+	c.provenanceFlags.register(f)
+
+	return set
+}
+
+func (c *GroupHandleCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagPath == "" {
+		return fmt.Errorf("path is required")
+	}
+
+	if err := c.iamHandlerFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.provenanceFlags.validate(); err != nil {
+		return err
+	}
+
+	if c.flagDuration <= 0 {
+		return fmt.Errorf("a positive duration is required")
+	}
+
+	// Default start time to the current time.
+	now := c.iamHandlerFlags.now()
+	if c.flagStartTime.IsZero() {
+		c.flagStartTime = now
+	}
+
+	if c.flagStartTime.Add(c.flagDuration).Before(now) {
+		return fmt.Errorf("expiry (start time: %q + duration: %q) already passed", c.flagStartTime, c.flagDuration)
+	}
+
+	return c.handleGroup(ctx)
+}
+
+func (c *GroupHandleCommand) handleGroup(ctx context.Context) error {
+	rr := newRequestReader(c.Stdin())
+	req, err := readGroupRequest(rr, c.flagPath)
+	if err != nil {
+		return err
+	}
+
+	h := c.testHandler
+	if h == nil {
+		groupHandler, done, err := newGroupHandler(ctx, &c.iamHandlerFlags, &c.BaseCommand)
+		if err != nil {
+			return err
+		}
+		defer done()
+		h = groupHandler
+	}
+
+	reqWrapper := &v1alpha1.IAMRequestWrapper{
+		Duration:  c.flagDuration,
+		StartTime: c.flagStartTime,
+	}
+	if err := c.provenanceFlags.apply(ctx, reqWrapper); err != nil {
+		return err
+	}
+	if reqWrapper.RequestHash, err = rr.hash(c.flagPath); err != nil {
+		return fmt.Errorf("failed to hash %T: %w", req, err)
+	}
+
+	resp, err := h.Do(ctx, req, reqWrapper)
+	if err != nil {
+		return withExitCode(apiExitCode(err, len(req.Members)), fmt.Errorf("failed to handle %T: %w", req, err))
+	}
+	return printGroupResponse(ctx, &c.BaseCommand, messages.HeaderGroupHandled, resp)
+}
+
+// GroupCleanupCommand removes temporary members from Google Groups before
+// their memberships expire.
+type GroupCleanupCommand struct {
+	cli.BaseCommand
+
+	flagPath string
+
+	iamHandlerFlags iamHandlerFlags
+
+	// testHandler is used for testing only.
+	testHandler groupHandler
+}
+
+func (c *GroupCleanupCommand) Desc() string {
+	return `Remove the temporary members in the group request YAML file from its Google Group`
+}
+
+func (c *GroupCleanupCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Remove the members in the group request YAML file from its Google Group before
+their memberships expire:
+
+      {{ COMMAND }} -path "/path/to/file.yaml"
+
+Only temporary memberships are removed, permanent members are left unchanged.
+`
+}
+
+func (c *GroupCleanupCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "path",
+		Target:  &c.flagPath,
+		Example: "/path/to/file.yaml",
+		Predict: predict.Files("*"),
+		Usage:   groupPathUsage,
+	})
+
+	c.iamHandlerFlags.register(f)
+
+	return set
+}
+
+func (c *GroupCleanupCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagPath == "" {
+		return fmt.Errorf("path is required")
+	}
+
+	if err := c.iamHandlerFlags.validate(); err != nil {
+		return err
+	}
+
+	req, err := readGroupRequest(newRequestReader(c.Stdin()), c.flagPath)
+	if err != nil {
+		return err
+	}
+
+	h := c.testHandler
+	if h == nil {
+		groupHandler, done, err := newGroupHandler(ctx, &c.iamHandlerFlags, &c.BaseCommand)
+		if err != nil {
+			return err
+		}
+		defer done()
+		h = groupHandler
+	}
+
+	resp, err := h.Cleanup(ctx, req)
+	if err != nil {
+		return withExitCode(apiExitCode(err, len(req.Members)), fmt.Errorf("failed to clean up %T: %w", req, err))
+	}
+	return printGroupResponse(ctx, &c.BaseCommand, messages.HeaderGroupCleanedUp, resp)
+}
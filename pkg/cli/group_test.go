@@ -0,0 +1,284 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/handler"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+const validGroupFile = `
+group: admins@example.com
+members:
+  - user:alice@example.com
+  - user:bob@example.com
+`
+
+func TestGroupValidateCommand(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		file   string
+		args   []string
+		expOut string
+		expErr string
+	}{
+		{
+			name:   "success",
+			file:   validGroupFile,
+			args:   []string{"-path", "{{path}}"},
+			expOut: "Successfully validated group request",
+		},
+		{
+			name:   "invalid_member",
+			file:   "group: admins@example.com\nmembers: [group:ops@example.com]\n",
+			args:   []string{"-path", "{{path}}"},
+			expErr: "group:ops@example.com",
+		},
+		{
+			name:   "invalid_yaml",
+			file:   `bananas`,
+			args:   []string{"-path", "{{path}}"},
+			expErr: "failed to read *v1alpha1.GroupRequest",
+		},
+		{
+			name:   "missing_path",
+			expErr: "path is required",
+		},
+		{
+			name:   "unexpected_args",
+			args:   []string{"foo"},
+			expErr: `unexpected arguments: ["foo"]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			args := writeRoleFile(t, tc.file, tc.args)
+
+			var cmd GroupValidateCommand
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestGroupHandleCommand(t *testing.T) {
+	t.Parallel()
+
+	startTime := time.Now().UTC().Truncate(time.Second)
+	expiry := startTime.Add(2 * time.Hour).Format(time.RFC3339)
+
+	cases := []struct {
+		name     string
+		file     string
+		args     []string
+		handler  *fakeGroupHandler
+		expCalls int
+		expOut   string
+		expErr   string
+	}{
+		{
+			name:     "success",
+			file:     validGroupFile,
+			args:     []string{"-path", "{{path}}", "-duration", "2h", "-start-time", startTime.Format(time.RFC3339)},
+			handler:  &fakeGroupHandler{},
+			expCalls: 1,
+			expOut: fmt.Sprintf(`
+------Successfully Handled Group Request------
+expiry: "%s"
+group: admins@example.com
+members:
+  - user:alice@example.com
+  - user:bob@example.com`, expiry),
+		},
+		{
+			name:     "handler_failure",
+			file:     validGroupFile,
+			args:     []string{"-path", "{{path}}", "-duration", "2h"},
+			handler:  &fakeGroupHandler{injectErr: fmt.Errorf("injected error")},
+			expCalls: 1,
+			expErr:   "failed to handle *v1alpha1.GroupRequest: injected error",
+		},
+		{
+			name:    "invalid_request",
+			file:    "group: admins\nmembers: [user:alice@example.com]\n",
+			args:    []string{"-path", "{{path}}", "-duration", "2h"},
+			handler: &fakeGroupHandler{},
+			expErr:  `group "admins" is not a valid email address`,
+		},
+		{
+			name:    "missing_duration",
+			file:    validGroupFile,
+			args:    []string{"-path", "{{path}}"},
+			handler: &fakeGroupHandler{},
+			expErr:  "a positive duration is required",
+		},
+		{
+			name:    "expired",
+			file:    validGroupFile,
+			args:    []string{"-path", "{{path}}", "-duration", "2h", "-start-time", "2009-11-10T23:00:00Z"},
+			handler: &fakeGroupHandler{},
+			expErr:  "already passed",
+		},
+		{
+			name:    "missing_path",
+			handler: &fakeGroupHandler{},
+			expErr:  "path is required",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			args := writeRoleFile(t, tc.file, tc.args)
+
+			var cmd GroupHandleCommand
+			cmd.testHandler = tc.handler
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if got := tc.handler.doCalls; got != tc.expCalls {
+				t.Errorf("Process(%+v) got %d handler calls, want %d", tc.name, got, tc.expCalls)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestGroupCleanupCommand(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		file      string
+		args      []string
+		handler   *fakeGroupHandler
+		expCalls  int
+		expOut    string
+		expStderr string
+		expErr    string
+	}{
+		{
+			name:     "success",
+			file:     validGroupFile,
+			args:     []string{"-path", "{{path}}"},
+			handler:  &fakeGroupHandler{warnings: []string{"user:bob@example.com is not a member of group admins@example.com"}},
+			expCalls: 1,
+			expOut: `
+------Successfully Cleaned Up Group Request------
+group: admins@example.com
+members:
+  - user:alice@example.com
+  - user:bob@example.com`,
+			expStderr: "WARNING: user:bob@example.com is not a member of group admins@example.com",
+		},
+		{
+			name:     "handler_failure",
+			file:     validGroupFile,
+			args:     []string{"-path", "{{path}}"},
+			handler:  &fakeGroupHandler{injectErr: fmt.Errorf("injected error")},
+			expCalls: 1,
+			expErr:   "failed to clean up *v1alpha1.GroupRequest: injected error",
+		},
+		{
+			name:    "missing_path",
+			handler: &fakeGroupHandler{},
+			expErr:  "path is required",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			args := writeRoleFile(t, tc.file, tc.args)
+
+			var cmd GroupCleanupCommand
+			cmd.testHandler = tc.handler
+			_, stdout, stderr := cmd.Pipe()
+
+			err := cmd.Run(ctx, args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if got := tc.handler.cleanupCalls; got != tc.expCalls {
+				t.Errorf("Process(%+v) got %d handler calls, want %d", tc.name, got, tc.expCalls)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expStderr), strings.TrimSpace(stderr.String())); diff != "" {
+				t.Errorf("Process(%+v) got stderr diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+type fakeGroupHandler struct {
+	injectErr    error
+	warnings     []string
+	doCalls      int
+	cleanupCalls int
+}
+
+func (h *fakeGroupHandler) Do(ctx context.Context, r *v1alpha1.GroupRequest, w *v1alpha1.IAMRequestWrapper) (*handler.GroupResponse, error) {
+	h.doCalls++
+	if h.injectErr != nil {
+		return nil, h.injectErr
+	}
+	expiry := w.StartTime.Add(w.Duration)
+	return &handler.GroupResponse{Group: r.Group, Members: r.Members, Expiry: &expiry, Warnings: h.warnings}, nil
+}
+
+func (h *fakeGroupHandler) Cleanup(ctx context.Context, r *v1alpha1.GroupRequest) (*handler.GroupResponse, error) {
+	h.cleanupCalls++
+	if h.injectErr != nil {
+		return nil, h.injectErr
+	}
+	return &handler.GroupResponse{Group: r.Group, Members: r.Members, Warnings: h.warnings}, nil
+}
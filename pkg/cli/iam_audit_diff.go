@@ -0,0 +1,289 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/posener/complete/v2/predict"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/registry"
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+)
+
+var _ cli.Command = (*IAMAuditDiffCommand)(nil)
+
+// IAMAuditDiffCommand shows the AOD grants gained, lost or extended between
+// two points in time according to the grant registry.
+type IAMAuditDiffCommand struct {
+	cli.BaseCommand
+
+	flagRegistryProject string
+
+	flagRegistryDatabase string
+
+	flagResources []string
+
+	flagMember string
+
+	flagFrom time.Time
+
+	flagTo time.Time
+
+	flagPeriod time.Duration
+
+	flagFormat string
+
+	// testStore is used for testing only.
+	testStore historyStore
+}
+
+func (c *IAMAuditDiffCommand) Desc() string {
+	return `Show the AOD grants gained, lost or extended between two points in time`
+}
+
+func (c *IAMAuditDiffCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Show who gained, lost or extended which AOD grants on a resource between two
+points in time, according to the grant registry:
+
+      {{ COMMAND }} -registry-project "my-project" -resource "projects/foo" -from "2009-11-03T00:00:00Z" -to "2009-11-10T00:00:00Z"
+
+Show the changes of all AOD grants over the last week in JSON format, e.g. for
+a weekly report:
+
+      {{ COMMAND }} -registry-project "my-project" -period "168h" -format "json"
+`
+}
+
+func (c *IAMAuditDiffCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "registry-project",
+		Target:  &c.flagRegistryProject,
+		Example: "my-project",
+		Usage:   "The project of the Firestore database of the grant registry.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "registry-database",
+		Target:  &c.flagRegistryDatabase,
+		Default: "(default)",
+		Example: "aod",
+		Usage:   "The Firestore database of the grant registry.",
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "resource",
+		Target:  &c.flagResources,
+		Example: "projects/foo",
+		Usage: `The resource to show the changes of, can be repeated. Default ` +
+			`is all resources.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "member",
+		Target:  &c.flagMember,
+		Example: "user:alice@example.com",
+		Usage:   `The member to show the changes of. Default is all members.`,
+	})
+
+	f.TimeVar(time.RFC3339, &cli.TimeVar{
+		Name:    "from",
+		Target:  &c.flagFrom,
+		Example: "2009-11-03T00:00:00Z",
+		Usage: `The earlier point in time in RFC3339 format, required unless ` +
+			`period is set.`,
+	})
+
+	f.TimeVar(time.RFC3339, &cli.TimeVar{
+		Name:    "to",
+		Target:  &c.flagTo,
+		Example: "2009-11-10T00:00:00Z",
+		Usage:   `The later point in time in RFC3339 format. Default is now.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "period",
+		Target:  &c.flagPeriod,
+		Example: "168h",
+		Usage:   `The period before the later point in time to show the changes of, instead of from.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "format",
+		Target:  &c.flagFormat,
+		Example: "json",
+		Default: formatTable,
+		Predict: predict.Set{formatTable, formatJSON, formatYAML},
+		Usage:   `The output format, one of "table", "json" and "yaml".`,
+	})
+
+	return set
+}
+
+func (c *IAMAuditDiffCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagRegistryProject == "" {
+		return fmt.Errorf("registry-project is required")
+	}
+
+	if c.flagTo.IsZero() {
+		c.flagTo = time.Now().UTC()
+	}
+
+	switch {
+	case c.flagFrom.IsZero() && c.flagPeriod == 0:
+		return fmt.Errorf("one of from and period is required")
+	case !c.flagFrom.IsZero() && c.flagPeriod != 0:
+		return fmt.Errorf("from and period are mutually exclusive")
+	case c.flagPeriod < 0:
+		return fmt.Errorf("period must be positive, got %s", c.flagPeriod)
+	case c.flagPeriod > 0:
+		c.flagFrom = c.flagTo.Add(-c.flagPeriod)
+	}
+
+	if !c.flagFrom.Before(c.flagTo) {
+		return fmt.Errorf("from %s must be before to %s",
+			c.flagFrom.Format(time.RFC3339), c.flagTo.Format(time.RFC3339))
+	}
+
+	if err := checkFormat(c.flagFormat, formatTable, formatJSON, formatYAML); err != nil {
+		return err
+	}
+
+	return c.diff(ctx)
+}
+
+func (c *IAMAuditDiffCommand) diff(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	var s historyStore
+	if c.testStore != nil {
+		// Use testStore if it is for testing.
+		s = c.testStore
+	} else {
+		store, err := registry.NewFirestoreStore(ctx, c.flagRegistryProject, c.flagRegistryDatabase)
+		if err != nil {
+			return fmt.Errorf("failed to create registry store: %w", err)
+		}
+		s = store
+		defer func() {
+			if err := store.Close(); err != nil {
+				logger.ErrorContext(ctx, "failed to close", "error", err)
+			}
+		}()
+	}
+
+	queries := []*registry.Query{{Member: c.flagMember}}
+	if len(c.flagResources) > 0 {
+		queries = queries[:0]
+		for _, r := range dedupe(c.flagResources) {
+			queries = append(queries, &registry.Query{Resource: r, Member: c.flagMember})
+		}
+	}
+
+	var records []*registry.Record
+	for _, q := range queries {
+		rs, err := s.Query(ctx, q)
+		if err != nil {
+			return fmt.Errorf("failed to query grant registry: %w", err)
+		}
+		records = append(records, rs...)
+	}
+
+	d := registry.DiffGrants(records, c.flagFrom, c.flagTo)
+	if c.flagMember != "" {
+		// Records of the member may include other members of the same grants.
+		d.Gained = grantsOf(d.Gained, c.flagMember)
+		d.Lost = grantsOf(d.Lost, c.flagMember)
+		d.Extended = grantsOf(d.Extended, c.flagMember)
+	}
+
+	switch c.flagFormat {
+	case formatJSON:
+		if err := encodeJSON(c.Stdout(), d); err != nil {
+			return fmt.Errorf("failed to output diff: %w", err)
+		}
+	case formatYAML:
+		if err := encodeYaml(c.Stdout(), d); err != nil {
+			return fmt.Errorf("failed to output diff: %w", err)
+		}
+	default:
+		if err := printGrantDiffTable(c.Stdout(), d); err != nil {
+			return fmt.Errorf("failed to output diff: %w", err)
+		}
+	}
+	return nil
+}
+
+// grantsOf returns the grants of the member.
+func grantsOf(grants []*v1alpha1.ActiveGrant, member string) []*v1alpha1.ActiveGrant {
+	var result []*v1alpha1.ActiveGrant
+	for _, g := range grants {
+		if g.Member == member {
+			result = append(result, g)
+		}
+	}
+	return result
+}
+
+// printGrantDiffTable prints the changes of the diff to w as a table.
+func printGrantDiffTable(w io.Writer, d *registry.GrantDiff) error {
+	if d.Empty() {
+		fmt.Fprintln(w, "No changes")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHANGE\tRESOURCE\tROLE\tMEMBER\tEXPIRY")
+	for _, c := range []struct {
+		change string
+		grants []*v1alpha1.ActiveGrant
+	}{
+		{change: "gained", grants: d.Gained},
+		{change: "lost", grants: d.Lost},
+		{change: "extended", grants: d.Extended},
+	} {
+		for _, g := range c.grants {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+				c.change, g.Resource, g.Role, g.Member, g.Expiry.Format(time.RFC3339))
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("failed to flush table: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/pkg/registry"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestIAMAuditDiffCommand(t *testing.T) {
+	t.Parallel()
+
+	from := time.Date(2009, 11, 3, 0, 0, 0, 0, time.UTC)
+	to := from.Add(7 * 24 * time.Hour)
+	ptr := func(t time.Time) *time.Time { return &t }
+	records := []*registry.Record{
+		{
+			Type:     "GRANT",
+			Time:     from.Add(-time.Hour),
+			Resource: "projects/foo",
+			Role:     "roles/viewer",
+			Members:  []string{"user:alice@example.com"},
+			Expiry:   ptr(from.Add(time.Hour)),
+		},
+		{
+			Type:     "GRANT",
+			Time:     from.Add(24 * time.Hour),
+			Resource: "projects/foo",
+			Role:     "roles/editor",
+			Members:  []string{"user:alice@example.com", "user:bob@example.com"},
+			Expiry:   ptr(to.Add(time.Hour)),
+		},
+	}
+
+	cases := []struct {
+		name     string
+		args     []string
+		store    *fakeHistoryStore
+		expQuery *registry.Query
+		expOut   string
+		expErr   string
+	}{
+		{
+			name: "success_table",
+			args: []string{
+				"-registry-project", "my-project", "-resource", "projects/foo",
+				"-from", "2009-11-03T00:00:00Z", "-to", "2009-11-10T00:00:00Z",
+			},
+			store:    &fakeHistoryStore{records: records},
+			expQuery: &registry.Query{Resource: "projects/foo"},
+			expOut: `
+CHANGE  RESOURCE      ROLE          MEMBER                  EXPIRY
+gained  projects/foo  roles/editor  user:alice@example.com  2009-11-10T01:00:00Z
+gained  projects/foo  roles/editor  user:bob@example.com    2009-11-10T01:00:00Z
+lost    projects/foo  roles/viewer  user:alice@example.com  2009-11-03T01:00:00Z`,
+		},
+		{
+			name: "success_member_json",
+			args: []string{
+				"-registry-project", "my-project", "-member", "user:bob@example.com",
+				"-period", "168h", "-to", "2009-11-10T00:00:00Z", "-format", "json",
+			},
+			store:    &fakeHistoryStore{records: records[1:]},
+			expQuery: &registry.Query{Member: "user:bob@example.com"},
+			expOut: `
+{
+  "from": "2009-11-03T00:00:00Z",
+  "to": "2009-11-10T00:00:00Z",
+  "gained": [
+    {
+      "resource": "projects/foo",
+      "role": "roles/editor",
+      "member": "user:bob@example.com",
+      "expiry": "2009-11-10T01:00:00Z"
+    }
+  ]
+}`,
+		},
+		{
+			name: "no_changes",
+			args: []string{
+				"-registry-project", "my-project",
+				"-from", "2009-11-04T00:00:00Z", "-to", "2009-11-05T00:00:00Z",
+			},
+			store:    &fakeHistoryStore{records: records},
+			expQuery: &registry.Query{},
+			expOut:   "No changes",
+		},
+		{
+			name: "query_failure",
+			args: []string{
+				"-registry-project", "my-project", "-period", "24h",
+			},
+			store:    &fakeHistoryStore{injectErr: fmt.Errorf("injected error")},
+			expQuery: &registry.Query{},
+			expErr:   "failed to query grant registry: injected error",
+		},
+		{
+			name:   "missing_registry_project",
+			args:   []string{"-period", "24h"},
+			store:  &fakeHistoryStore{},
+			expErr: "registry-project is required",
+		},
+		{
+			name:   "missing_from_and_period",
+			args:   []string{"-registry-project", "my-project"},
+			store:  &fakeHistoryStore{},
+			expErr: "one of from and period is required",
+		},
+		{
+			name:   "from_and_period",
+			args:   []string{"-registry-project", "my-project", "-from", "2009-11-03T00:00:00Z", "-period", "24h"},
+			store:  &fakeHistoryStore{},
+			expErr: "from and period are mutually exclusive",
+		},
+		{
+			name:   "negative_period",
+			args:   []string{"-registry-project", "my-project", "-period", "-24h"},
+			store:  &fakeHistoryStore{},
+			expErr: "period must be positive, got -24h0m0s",
+		},
+		{
+			name: "from_after_to",
+			args: []string{
+				"-registry-project", "my-project",
+				"-from", "2009-11-10T00:00:00Z", "-to", "2009-11-03T00:00:00Z",
+			},
+			store:  &fakeHistoryStore{},
+			expErr: "from 2009-11-10T00:00:00Z must be before to 2009-11-03T00:00:00Z",
+		},
+		{
+			name:   "invalid_format",
+			args:   []string{"-registry-project", "my-project", "-period", "24h", "-format", "text"},
+			store:  &fakeHistoryStore{},
+			expErr: `invalid format "text"`,
+		},
+		{
+			name:   "unexpected_args",
+			args:   []string{"foo"},
+			store:  &fakeHistoryStore{},
+			expErr: `unexpected arguments: ["foo"]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var cmd IAMAuditDiffCommand
+			cmd.testStore = tc.store
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expQuery, tc.store.gotQuery); diff != "" {
+				t.Errorf("Process(%+v) got query diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
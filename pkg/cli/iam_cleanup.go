@@ -16,12 +16,18 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/posener/complete/v2/predict"
 
 	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/denypolicy"
+	"github.com/abcxyz/access-on-demand/pkg/handler"
+	"github.com/abcxyz/access-on-demand/pkg/messages"
 	"github.com/abcxyz/access-on-demand/pkg/requestutil"
+	"github.com/abcxyz/access-on-demand/pkg/server"
 	"github.com/abcxyz/pkg/cli"
 	"github.com/abcxyz/pkg/logging"
 )
@@ -38,13 +44,21 @@ type iamCleanupHandler interface {
 type IAMCleanupCommand struct {
 	cli.BaseCommand
 
-	flagPath string
+	flagPaths []string
 
 	flagVerbose bool
 
-	// Optional custom condition title as AOD bindings identifier, required for
-	// integration test.
-	flagCustomConditionTitle string
+	iamHandlerFlags iamHandlerFlags
+
+	prCommentFlags prCommentFlags
+
+	detachFlags detachFlags
+
+	identityFlags identityFlags
+
+	entitlementFlags entitlementFlags
+
+	receiptFlags receiptFlags
 
 	// testHandler is used for testing only.
 	testHandler iamCleanupHandler
@@ -63,24 +77,33 @@ Cleanup of the IAM request YAML file in the given path:
 
       {{ COMMAND }} -path "/path/to/file.yaml"
 
+Cleanup of all IAM request YAML files in the directory:
+
+      {{ COMMAND }} -path "/path/to/requests"
+
 Cleanup of the IAM request YAML file and output applied IAM changes:
 
       {{ COMMAND }} -path "/path/to/file.yaml" -verbose
+
+Cleanup of exactly the bindings granted by iam handle in its grant receipts:
+
+      {{ COMMAND }} -receipt "/path/to/receipt.yaml"
 `
 }
 
 func (c *IAMCleanupCommand) Flags() *cli.FlagSet {
-	set := c.NewFlagSet()
+	set := newFlagSet(&c.BaseCommand)
 
 	// Command options
 	f := set.NewSection("COMMAND OPTIONS")
 
-	f.StringVar(&cli.StringVar{
+	f.StringSliceVar(&cli.StringSliceVar{
 		Name:    "path",
-		Target:  &c.flagPath,
+		Target:  &c.flagPaths,
 		Example: "/path/to/file.yaml",
 		Predict: predict.Files("*"),
-		Usage:   "The path of IAM request file, in YAML format.",
+		Usage: `The path of IAM request file, in YAML format. Use "-" to ` +
+			`read it from stdin.` + multiPathUsage,
 	})
 
 	f.BoolVar(&cli.BoolVar{
@@ -90,13 +113,19 @@ func (c *IAMCleanupCommand) Flags() *cli.FlagSet {
 		Usage:   "Turn on verbose mode to print updated IAM policies. Note that it may contain sensitive information.",
 	})
 
-	f.StringVar(&cli.StringVar{
-		Name:    "custom-condition-title",
-		Target:  &c.flagCustomConditionTitle,
-		Hidden:  true,
-		Example: "foo-aod-expiry",
-		Usage:   "The custom title for the aod expiry condition.",
-	})
+	c.iamHandlerFlags.register(f)
+
+	c.prCommentFlags.register(f)
+
+	c.detachFlags.register(f)
+
+	c.identityFlags.register(f)
+	c.entitlementFlags.register(f)
+
+	c.receiptFlags.register(f, `The path of the signed grant receipts written `+
+		`by iam handle, in YAML format, to clean up exactly the bindings `+
+		`they granted instead of the requests at path. The receipts are `+
+		`verified with receipt-key before any of them is cleaned up.`)
 
 	return set
 }
@@ -111,24 +140,51 @@ func (c *IAMCleanupCommand) Run(ctx context.Context, args []string) error {
 		return fmt.Errorf("unexpected arguments: %q", args)
 	}
 
-	if c.flagPath == "" {
+	if len(c.flagPaths) == 0 && c.receiptFlags.flagReceipt == "" {
 		return fmt.Errorf("path is required")
 	}
 
+	if len(c.flagPaths) > 0 && c.receiptFlags.flagReceipt != "" {
+		return fmt.Errorf("path and receipt cannot both be set")
+	}
+
+	if err := c.receiptFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.iamHandlerFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.prCommentFlags.validate(c.GetEnv); err != nil {
+		return err
+	}
+
+	if err := c.detachFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.identityFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.entitlementFlags.validate(); err != nil {
+		return err
+	}
+
 	return c.cleanupIAM(ctx)
 }
 
 func (c *IAMCleanupCommand) cleanupIAM(ctx context.Context) error {
 	logger := logging.FromContext(ctx)
 
-	// Read request from file path.
-	var req v1alpha1.IAMRequest
-	if err := requestutil.ReadRequestFromPath(c.flagPath, &req); err != nil {
-		return fmt.Errorf("failed to read %T: %w", &req, err)
+	paths, reqs, retErr := c.requests(ctx)
+	if retErr != nil {
+		return retErr
 	}
 
-	if err := v1alpha1.ValidateIAMRequest(&req); err != nil {
-		return fmt.Errorf("failed to validate %T: %w", &req, err)
+	if c.detachFlags.flagDetach {
+		return c.detach(ctx, paths, reqs)
 	}
 
 	var h iamCleanupHandler
@@ -136,7 +192,20 @@ func (c *IAMCleanupCommand) cleanupIAM(ctx context.Context) error {
 		// Use testHandler if it is for testing.
 		h = c.testHandler
 	} else {
-		iamHandler, closer, newHandlerErr := newIAMHandler(ctx, c.flagCustomConditionTitle)
+		var opts []handler.Option
+		if slices.ContainsFunc(reqs, func(r *v1alpha1.IAMRequest) bool { return len(r.DenyPolicies) > 0 }) {
+			m, err := denypolicy.NewClient(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to create deny policies client: %w", err)
+			}
+			defer func() {
+				if err := m.Close(); err != nil {
+					logger.ErrorContext(ctx, "failed to close", "error", err)
+				}
+			}()
+			opts = append(opts, handler.WithDenyPolicyManager(m))
+		}
+		iamHandler, closer, newHandlerErr := newIAMHandler(ctx, &c.iamHandlerFlags, &c.BaseCommand, opts...)
 		if newHandlerErr != nil {
 			return newHandlerErr
 		}
@@ -148,21 +217,136 @@ func (c *IAMCleanupCommand) cleanupIAM(ctx context.Context) error {
 		}()
 	}
 
-	resp, err := h.Cleanup(ctx, &req)
+	var failed int
+	results := make([]*actionResult, 0, len(reqs))
+	for i, req := range reqs {
+		err := c.cleanup(ctx, h, req)
+		if err != nil {
+			failed++
+			retErr = errors.Join(retErr, withPath(paths, paths[i], err))
+		}
+		results = append(results, newActionResult(paths[i], req, actionStatusCleanedUp, err))
+	}
+	writeActionOutputs(ctx, c.GetEnv, results)
+	if failed > 0 && failed < len(reqs) {
+		return withExitCode(ExitCodePartialFailure, retErr)
+	}
+	return retErr
+}
+
+// requests returns the IAM requests to clean up, either read from the request
+// files at the paths, or the requests in the verified receipts. The requests
+// of the receipts are labeled by their grant IDs instead of paths.
+func (c *IAMCleanupCommand) requests(ctx context.Context) ([]string, []*v1alpha1.IAMRequest, error) {
+	if c.receiptFlags.flagReceipt != "" {
+		receipts, err := c.receiptFlags.read()
+		if err != nil {
+			return nil, nil, withExitCode(ExitCodeValidation, err)
+		}
+		labels := make([]string, 0, len(receipts))
+		reqs := make([]*v1alpha1.IAMRequest, 0, len(receipts))
+		for _, rc := range receipts {
+			labels = append(labels, "grant "+rc.GrantID)
+			reqs = append(reqs, rc.Request)
+		}
+		return labels, reqs, nil
+	}
+
+	paths, err := expandRequestPaths(c.flagPaths)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Validate all requests before cleaning up any of them.
+	rr := newRequestReader(c.Stdin())
+	reqs := make([]*v1alpha1.IAMRequest, 0, len(paths))
+	var retErr error
+	for _, p := range paths {
+		req, err := c.read(ctx, rr, p)
+		if err != nil {
+			retErr = errors.Join(retErr, withPath(paths, p, err))
+			continue
+		}
+		reqs = append(reqs, req)
+	}
+	if retErr != nil {
+		return nil, nil, retErr
+	}
+	return paths, reqs, nil
+}
+
+// detach enqueues the cleanups of the IAM requests as detached operations on
+// the AOD server.
+func (c *IAMCleanupCommand) detach(ctx context.Context, paths []string, reqs []*v1alpha1.IAMRequest) error {
+	client, err := c.detachFlags.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	var ops []*server.Operation
+	var retErr error
+	for i, req := range reqs {
+		op, err := client.DetachCleanup(ctx, req)
+		if err != nil {
+			retErr = errors.Join(retErr, withPath(paths, paths[i], fmt.Errorf("failed to detach IAM request: %w", err)))
+			continue
+		}
+		ops = append(ops, op)
+	}
+	return errors.Join(retErr, printOperations(ctx, &c.BaseCommand, ops))
+}
+
+// read reads and validates the IAM request file at the path.
+func (c *IAMCleanupCommand) read(ctx context.Context, rr *requestReader, path string) (*v1alpha1.IAMRequest, error) {
+	// Read request from file path.
+	docs, err := rr.iamBundle(path)
+	if err != nil {
+		return nil, withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &v1alpha1.IAMRequest{}, err))
+	}
+	if err := injectFailure(c.iamHandlerFlags.flagInjectFailure, failureStageParse); err != nil {
+		return nil, withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &v1alpha1.IAMRequest{}, err))
+	}
+
+	if err := requestutil.ExpandIAMBundle(docs, c.entitlementFlags.catalog, 0); err != nil {
+		auditValidationDenied(ctx, c.iamHandlerFlags.flagAuditLogProject, err)
+		return nil, withExitCode(ExitCodeValidation, fmt.Errorf("failed to expand entitlements of %T: %w", &v1alpha1.IAMRequest{}, err))
+	}
+	if err := requestutil.ResolveIAMBundle(docs, c.identityFlags.identities); err != nil {
+		auditValidationDenied(ctx, c.iamHandlerFlags.flagAuditLogProject, err)
+		return nil, withExitCode(ExitCodeValidation, fmt.Errorf("failed to resolve members of %T: %w", &v1alpha1.IAMRequest{}, err))
+	}
+	req, err := requestutil.ValidateIAMBundle(docs, nil)
+	if err != nil {
+		auditValidationDenied(ctx, c.iamHandlerFlags.flagAuditLogProject, err)
+		return nil, withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", req, err))
+	}
+	return req, nil
+}
+
+// cleanup cleans up the IAM request and outputs the result.
+func (c *IAMCleanupCommand) cleanup(ctx context.Context, h iamCleanupHandler, req *v1alpha1.IAMRequest) error {
+	resp, err := h.Cleanup(ctx, req)
+	comment := &prComment{Action: "clean up", Done: "cleaned up", Summary: req, Err: err}
+	if c.flagVerbose {
+		comment.Responses = redactResponses(resp, c.iamHandlerFlags.conditionTitle(), requestMembers(req))
+	}
+	c.prCommentFlags.post(ctx, c.GetEnv, comment)
+	writeStepSummary(ctx, c.GetEnv, comment)
 	// The error here might only be errrors of parsing the condition expiration
 	// expression of some IAM bindings, it does not necessarily mean that it
 	// failed to cleanup the requested bindings.
 	if err != nil {
-		return fmt.Errorf("failed to clean up IAM policy: %w", err)
+		return withExitCode(apiExitCode(err, len(req.ResourcePolicies)),
+			fmt.Errorf("failed to clean up IAM policy: %w", err))
 	}
 
-	printHeader(c.Stdout(), "Successfully Removed Requested Bindings")
-	if err := encodeYaml(c.Stdout(), &req); err != nil {
+	printMessageHeader(ctx, &c.BaseCommand, messages.HeaderCleanedUp)
+	if err := encodeYaml(c.Stdout(), req); err != nil {
 		return fmt.Errorf("failed to output applied request: %w", err)
 	}
 
 	if c.flagVerbose {
-		printHeader(c.Stdout(), "Cleaned Up IAM Policies")
+		printMessageHeader(ctx, &c.BaseCommand, messages.HeaderCleanedUpPolicies)
 		if err := encodeYaml(c.Stdout(), resp); err != nil {
 			return fmt.Errorf("failed to output IAM policies: %w", err)
 		}
@@ -274,3 +274,101 @@ func (h *fakeIAMCleanupHandler) Cleanup(ctx context.Context, req *v1alpha1.IAMRe
 	h.gotReq = req
 	return h.resp, h.injectErr
 }
+
+func TestIAMCleanupCommand_Receipt(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "iam.yaml")
+	receiptPath := filepath.Join(dir, "receipt.yaml")
+	content := "policies:\n- resource: projects/a\n  bindings:\n  - members: [user:alice@example.com]\n    role: roles/viewer\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	wantReq := &v1alpha1.IAMRequest{
+		ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+			Resource: "projects/a",
+			Bindings: []*v1alpha1.Binding{{Members: []string{"user:alice@example.com"}, Role: "roles/viewer"}},
+		}},
+	}
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	// Handle the request to write its receipt, then change the request file
+	// as if it was edited after the handle.
+	handleCmd := IAMHandleCommand{testHandler: &fakeIAMHandler{}}
+	_, _, _ = handleCmd.Pipe()
+	if err := handleCmd.Run(ctx, []string{"-path", path, "-duration", "2h", "-receipt", receiptPath, "-receipt-key", "s3cr3t"}); err != nil {
+		t.Fatalf("failed to handle IAM request: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(strings.ReplaceAll(content, "alice", "bob")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	receiptContent, err := os.ReadFile(receiptPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tamperedPath := filepath.Join(dir, "tampered.yaml")
+	if err := os.WriteFile(tamperedPath, []byte(strings.ReplaceAll(string(receiptContent), "alice", "bob")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		args    []string
+		expReq  *v1alpha1.IAMRequest
+		expCode int
+		expErr  string
+	}{
+		{
+			name:   "success",
+			args:   []string{"-receipt", receiptPath, "-receipt-key", "s3cr3t"},
+			expReq: wantReq,
+		},
+		{
+			name:    "tampered",
+			args:    []string{"-receipt", tamperedPath, "-receipt-key", "s3cr3t"},
+			expCode: ExitCodeValidation,
+			expErr:  "failed to verify receipt file: invalid signature of receipt of grant",
+		},
+		{
+			name:    "wrong_key",
+			args:    []string{"-receipt", receiptPath, "-receipt-key", "bananas"},
+			expCode: ExitCodeValidation,
+			expErr:  "failed to verify receipt file: invalid signature of receipt of grant",
+		},
+		{
+			name:    "missing_key",
+			args:    []string{"-receipt", receiptPath},
+			expCode: ExitCodeFailure,
+			expErr:  "receipt-key is required with receipt",
+		},
+		{
+			name:    "path_and_receipt",
+			args:    []string{"-path", path, "-receipt", receiptPath, "-receipt-key", "s3cr3t"},
+			expCode: ExitCodeFailure,
+			expErr:  "path and receipt cannot both be set",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := &fakeIAMCleanupHandler{}
+			cmd := IAMCleanupCommand{testHandler: h}
+			_, _, _ = cmd.Pipe()
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if got := ExitCode(err); got != tc.expCode {
+				t.Errorf("Process(%+v) got exit code %d, want %d", tc.name, got, tc.expCode)
+			}
+			if diff := cmp.Diff(tc.expReq, h.gotReq); diff != "" {
+				t.Errorf("Process(%+v) got request diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/posener/complete/v2/predict"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	colorGreen = "\x1b[32m"
+	colorRed   = "\x1b[31m"
+	colorReset = "\x1b[0m"
+)
+
+var _ cli.Command = (*IAMDiffCommand)(nil)
+
+// iamDiffHandler interface that diffs the IAMRequestWrapper against the
+// current IAM policies.
+type iamDiffHandler interface {
+	Diff(context.Context, *v1alpha1.IAMRequestWrapper) ([]*v1alpha1.IAMPolicyDiff, error)
+}
+
+// IAMDiffCommand shows the IAM policy changes an IAM request would make.
+type IAMDiffCommand struct {
+	cli.BaseCommand
+
+	flagPath string
+
+	flagDuration time.Duration
+
+	flagStartTime time.Time
+
+	flagFormat string
+
+	flagColor bool
+
+	iamHandlerFlags iamHandlerFlags
+
+	// testHandler is used for testing only.
+	testHandler iamDiffHandler
+}
+
+func (c *IAMDiffCommand) Desc() string {
+	return `Show the IAM policy changes of the IAM request YAML file in the given path`
+}
+
+func (c *IAMDiffCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Show the IAM bindings to be added and removed by the IAM request YAML file:
+
+      {{ COMMAND }} -path "/path/to/file.yaml" -duration "2h"
+
+Show the IAM policy changes in JSON format:
+
+      {{ COMMAND }} -path "/path/to/file.yaml" -duration "2h" -format "json"
+`
+}
+
+func (c *IAMDiffCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "path",
+		Target:  &c.flagPath,
+		Example: "/path/to/file.yaml",
+		Predict: predict.Files("*"),
+		Usage:   `The path of IAM request file, in YAML format. Use "-" to read it from stdin.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "duration",
+		Target:  &c.flagDuration,
+		Example: "2h",
+		Usage:   `The IAM permission lifecycle, as a duration.`,
+	})
+
+	f.TimeVar(time.RFC3339, &cli.TimeVar{
+		Name:    "start-time",
+		Target:  &c.flagStartTime,
+		Example: "2009-11-10T23:00:00Z",
+		Usage: `The start time of the IAM permission lifecycle in RFC3339 format. ` +
+			`Default is current UTC time.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "format",
+		Target:  &c.flagFormat,
+		Example: "json",
+		Default: formatText,
+		Predict: predict.Set{formatText, formatJSON},
+		Usage:   `The output format, one of "text" and "json".`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "color",
+		Target:  &c.flagColor,
+		Default: false,
+		Usage:   `Colorize the "text" output.`,
+	})
+
+	c.iamHandlerFlags.register(f)
+
+	return set
+}
+
+func (c *IAMDiffCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagPath == "" {
+		return fmt.Errorf("path is required")
+	}
+
+	if c.flagDuration <= 0 {
+		return fmt.Errorf("a positive duration is required")
+	}
+
+	if err := checkFormat(c.flagFormat, formatText, formatJSON); err != nil {
+		return err
+	}
+
+	if err := c.iamHandlerFlags.validate(); err != nil {
+		return err
+	}
+
+	// Default start time to the current time.
+	if c.flagStartTime.IsZero() {
+		c.flagStartTime = c.iamHandlerFlags.now()
+	}
+
+	return c.diffIAM(ctx)
+}
+
+func (c *IAMDiffCommand) diffIAM(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	// Read request from file path.
+	var req v1alpha1.IAMRequest
+	loc, err := newRequestReader(c.Stdin()).requestWithLocator(c.flagPath, &req)
+	if err != nil {
+		return withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &req, err))
+	}
+	if err := injectFailure(c.iamHandlerFlags.flagInjectFailure, failureStageParse); err != nil {
+		return withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &req, err))
+	}
+
+	if err := v1alpha1.ValidateIAMRequest(&req, nil); err != nil {
+		err = loc.Annotate(err)
+		return withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", &req, err))
+	}
+
+	var h iamDiffHandler
+	if c.testHandler != nil {
+		// Use testHandler if it is for testing.
+		h = c.testHandler
+	} else {
+		iamHandler, closer, newHandlerErr := newIAMHandler(ctx, &c.iamHandlerFlags, &c.BaseCommand)
+		if newHandlerErr != nil {
+			return newHandlerErr
+		}
+		h = iamHandler
+		defer func() {
+			if err := closer.Close(); err != nil {
+				logger.ErrorContext(ctx, "failed to close", "error", err)
+			}
+		}()
+	}
+
+	diffs, err := h.Diff(ctx, &v1alpha1.IAMRequestWrapper{
+		IAMRequest: &req,
+		Duration:   c.flagDuration,
+		StartTime:  c.flagStartTime,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to diff IAM request: %w", err)
+	}
+
+	if c.flagFormat == formatJSON {
+		if err := encodeJSON(c.Stdout(), diffs); err != nil {
+			return fmt.Errorf("failed to output IAM policy diffs: %w", err)
+		}
+		return nil
+	}
+
+	for _, d := range diffs {
+		printHeader(c.Stdout(), d.Resource)
+		if len(d.Added) == 0 && len(d.Removed) == 0 {
+			fmt.Fprintln(c.Stdout(), "No changes")
+			continue
+		}
+		for _, b := range d.Added {
+			c.printBindingChange(c.Stdout(), "+", colorGreen, b)
+		}
+		for _, b := range d.Removed {
+			c.printBindingChange(c.Stdout(), "-", colorRed, b)
+		}
+	}
+	return nil
+}
+
+// printBindingChange prints the binding change in a single line prefixed with
+// the symbol.
+func (c *IAMDiffCommand) printBindingChange(w io.Writer, symbol, color string, b *v1alpha1.BindingChange) {
+	line := fmt.Sprintf("%s %s %s", symbol, b.Role, b.Member)
+	if b.ConditionTitle != "" || b.ConditionExpression != "" {
+		line = fmt.Sprintf("%s (%s: %s)", line, b.ConditionTitle, b.ConditionExpression)
+	}
+	if c.flagColor {
+		line = color + line + colorReset
+	}
+	fmt.Fprintln(w, line)
+}
@@ -0,0 +1,219 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/posener/complete/v2/predict"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+)
+
+var _ cli.Command = (*IAMDiffLiveCommand)(nil)
+
+// iamDiffLiveHandler interface that diffs the IAMRequest against the live IAM
+// policies.
+type iamDiffLiveHandler interface {
+	DiffLive(ctx context.Context, r *v1alpha1.IAMRequest, expiry time.Time) ([]*v1alpha1.BindingStatus, error)
+}
+
+// IAMDiffLiveCommand reports which requested IAM bindings are present in the
+// live IAM policies.
+type IAMDiffLiveCommand struct {
+	cli.BaseCommand
+
+	flagPath string
+
+	flagDuration time.Duration
+
+	flagStartTime time.Time
+
+	flagFormat string
+
+	iamHandlerFlags iamHandlerFlags
+
+	// testHandler is used for testing only.
+	testHandler iamDiffLiveHandler
+}
+
+func (c *IAMDiffLiveCommand) Desc() string {
+	return `Report which IAM bindings in the IAM request YAML file in the given path are present in the live IAM policies`
+}
+
+func (c *IAMDiffLiveCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Report which requested IAM bindings currently have active AOD IAM bindings, with
+any expiry:
+
+      {{ COMMAND }} -path "/path/to/file.yaml"
+
+Also report the active AOD IAM bindings whose expiry is different from the
+expiry of the request handled at the start time with the duration:
+
+      {{ COMMAND }} -path "/path/to/file.yaml" -start-time "2009-11-10T23:00:00Z" -duration "2h"
+
+Each requested binding of a role to a member is reported as one of "PRESENT",
+"MISSING" and "DIFFERENT_EXPIRY".
+`
+}
+
+func (c *IAMDiffLiveCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "path",
+		Target:  &c.flagPath,
+		Example: "/path/to/file.yaml",
+		Predict: predict.Files("*"),
+		Usage:   `The path of IAM request file, in YAML format. Use "-" to read it from stdin.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "duration",
+		Target:  &c.flagDuration,
+		Example: "2h",
+		Usage: `The IAM permission lifecycle of the handled request, as a ` +
+			`duration. The expiry is not checked if it is not set.`,
+	})
+
+	f.TimeVar(time.RFC3339, &cli.TimeVar{
+		Name:    "start-time",
+		Target:  &c.flagStartTime,
+		Example: "2009-11-10T23:00:00Z",
+		Usage: `The start time of the IAM permission lifecycle of the handled ` +
+			`request in RFC3339 format, required if duration is set.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "format",
+		Target:  &c.flagFormat,
+		Example: "json",
+		Default: formatText,
+		Predict: predict.Set{formatText, formatJSON},
+		Usage:   `The output format, one of "text" and "json".`,
+	})
+
+	c.iamHandlerFlags.register(f)
+
+	return set
+}
+
+func (c *IAMDiffLiveCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagPath == "" {
+		return fmt.Errorf("path is required")
+	}
+
+	if c.flagDuration < 0 {
+		return fmt.Errorf("a positive duration is required")
+	}
+
+	if (c.flagDuration == 0) != c.flagStartTime.IsZero() {
+		return fmt.Errorf("start-time and duration must be set together")
+	}
+
+	if err := checkFormat(c.flagFormat, formatText, formatJSON); err != nil {
+		return err
+	}
+
+	if err := c.iamHandlerFlags.validate(); err != nil {
+		return err
+	}
+
+	return c.diffLive(ctx)
+}
+
+func (c *IAMDiffLiveCommand) diffLive(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	// Read request from file path.
+	var req v1alpha1.IAMRequest
+	loc, err := newRequestReader(c.Stdin()).requestWithLocator(c.flagPath, &req)
+	if err != nil {
+		return withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &req, err))
+	}
+
+	if err := v1alpha1.ValidateIAMRequest(&req, nil); err != nil {
+		err = loc.Annotate(err)
+		return withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", &req, err))
+	}
+
+	var h iamDiffLiveHandler
+	if c.testHandler != nil {
+		// Use testHandler if it is for testing.
+		h = c.testHandler
+	} else {
+		iamHandler, closer, newHandlerErr := newIAMHandler(ctx, &c.iamHandlerFlags, &c.BaseCommand)
+		if newHandlerErr != nil {
+			return newHandlerErr
+		}
+		h = iamHandler
+		defer func() {
+			if err := closer.Close(); err != nil {
+				logger.ErrorContext(ctx, "failed to close", "error", err)
+			}
+		}()
+	}
+
+	// A zero expiry is not checked.
+	var expiry time.Time
+	if c.flagDuration > 0 {
+		expiry = c.flagStartTime.Add(c.flagDuration)
+	}
+
+	statuses, err := h.DiffLive(ctx, &req, expiry)
+	if err != nil {
+		return fmt.Errorf("failed to diff IAM request against live IAM policies: %w", err)
+	}
+
+	if c.flagFormat == formatJSON {
+		if err := encodeJSON(c.Stdout(), statuses); err != nil {
+			return fmt.Errorf("failed to output binding statuses: %w", err)
+		}
+		return nil
+	}
+
+	var resource string
+	for _, s := range statuses {
+		if s.Resource != resource {
+			resource = s.Resource
+			printHeader(c.Stdout(), resource)
+		}
+		line := fmt.Sprintf("%s %s %s", s.Status, s.Role, s.Member)
+		if s.Expiry != nil {
+			line = fmt.Sprintf("%s (expiry: %s)", line, s.Expiry.Format(time.RFC3339))
+		}
+		fmt.Fprintln(c.Stdout(), line)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestIAMDiffLiveCommand(t *testing.T) {
+	t.Parallel()
+
+	// Set up IAM request file.
+	requestFileContentByName := map[string]string{
+		"valid.yaml": `
+policies:
+- resource: projects/baz
+  bindings:
+    - members:
+      - user:alice@example.com
+      - user:bob@example.com
+      role: roles/bigquery.dataViewer
+`,
+		"invalid.yaml": `bananas`,
+	}
+	dir := t.TempDir()
+	for name, content := range requestFileContentByName {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	validRequest := &v1alpha1.IAMRequest{
+		ResourcePolicies: []*v1alpha1.ResourcePolicy{
+			{
+				Resource: "projects/baz",
+				Bindings: []*v1alpha1.Binding{
+					{
+						Members: []string{"user:alice@example.com", "user:bob@example.com"},
+						Role:    "roles/bigquery.dataViewer",
+					},
+				},
+			},
+		},
+	}
+
+	st := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	expiry := st.Add(2 * time.Hour)
+	statuses := []*v1alpha1.BindingStatus{
+		{
+			Resource: "projects/baz",
+			Role:     "roles/bigquery.dataViewer",
+			Member:   "user:alice@example.com",
+			Status:   v1alpha1.BindingStatusPresent,
+			Expiry:   &expiry,
+		},
+		{
+			Resource: "projects/baz",
+			Role:     "roles/bigquery.dataViewer",
+			Member:   "user:bob@example.com",
+			Status:   v1alpha1.BindingStatusMissing,
+		},
+	}
+
+	cases := []struct {
+		name      string
+		args      []string
+		handler   *fakeIAMDiffLiveHandler
+		expReq    *v1alpha1.IAMRequest
+		expExpiry time.Time
+		expOut    string
+		expErr    string
+	}{
+		{
+			name:    "success_text",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml")},
+			handler: &fakeIAMDiffLiveHandler{statuses: statuses},
+			expReq:  validRequest,
+			expOut: `
+------projects/baz------
+PRESENT roles/bigquery.dataViewer user:alice@example.com (expiry: 2009-11-11T01:00:00Z)
+MISSING roles/bigquery.dataViewer user:bob@example.com`,
+		},
+		{
+			name: "success_json_with_expiry",
+			args: []string{
+				"-path", filepath.Join(dir, "valid.yaml"),
+				"-start-time", "2009-11-10T23:00:00Z",
+				"-duration", "2h",
+				"-format", "json",
+			},
+			handler:   &fakeIAMDiffLiveHandler{statuses: statuses[1:]},
+			expReq:    validRequest,
+			expExpiry: expiry,
+			expOut: `
+[
+  {
+    "resource": "projects/baz",
+    "role": "roles/bigquery.dataViewer",
+    "member": "user:bob@example.com",
+    "status": "MISSING"
+  }
+]`,
+		},
+		{
+			name: "handler_failure",
+			args: []string{"-path", filepath.Join(dir, "valid.yaml")},
+			handler: &fakeIAMDiffLiveHandler{
+				injectErr: fmt.Errorf("injected error"),
+			},
+			expReq: validRequest,
+			expErr: "failed to diff IAM request against live IAM policies: injected error",
+		},
+		{
+			name:    "invalid_yaml",
+			args:    []string{"-path", filepath.Join(dir, "invalid.yaml")},
+			handler: &fakeIAMDiffLiveHandler{},
+			expErr:  "failed to read *v1alpha1.IAMRequest",
+		},
+		{
+			name:    "duration_without_start_time",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h"},
+			handler: &fakeIAMDiffLiveHandler{},
+			expErr:  "start-time and duration must be set together",
+		},
+		{
+			name:    "negative_duration",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "-2h"},
+			handler: &fakeIAMDiffLiveHandler{},
+			expErr:  "a positive duration is required",
+		},
+		{
+			name:    "invalid_format",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-format", "yaml"},
+			handler: &fakeIAMDiffLiveHandler{},
+			expErr:  `invalid format "yaml"`,
+		},
+		{
+			name:    "missing_path",
+			args:    []string{},
+			handler: &fakeIAMDiffLiveHandler{},
+			expErr:  "path is required",
+		},
+		{
+			name:    "unexpected_args",
+			args:    []string{"foo"},
+			handler: &fakeIAMDiffLiveHandler{},
+			expErr:  `unexpected arguments: ["foo"]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var cmd IAMDiffLiveCommand
+			cmd.testHandler = tc.handler
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expReq, tc.handler.gotReq); diff != "" {
+				t.Errorf("Process(%+v) got request diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if got, want := tc.handler.gotExpiry, tc.expExpiry; !got.Equal(want) {
+				t.Errorf("Process(%+v) got expiry %s, want %s", tc.name, got, want)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+type fakeIAMDiffLiveHandler struct {
+	injectErr error
+	gotReq    *v1alpha1.IAMRequest
+	gotExpiry time.Time
+	statuses  []*v1alpha1.BindingStatus
+}
+
+func (h *fakeIAMDiffLiveHandler) DiffLive(ctx context.Context, r *v1alpha1.IAMRequest, expiry time.Time) ([]*v1alpha1.BindingStatus, error) {
+	h.gotReq = r
+	h.gotExpiry = expiry
+	return h.statuses, h.injectErr
+}
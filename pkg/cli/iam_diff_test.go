@@ -0,0 +1,241 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestIAMDiffCommand(t *testing.T) {
+	t.Parallel()
+
+	// Set up IAM request file.
+	requestFileContentByName := map[string]string{
+		"valid.yaml": `
+policies:
+- resource: projects/baz
+  bindings:
+    - members:
+      - user:test-project-user@example.com
+      role: roles/bigquery.dataViewer
+`,
+		"invalid.yaml": `bananas`,
+	}
+	dir := t.TempDir()
+	for name, content := range requestFileContentByName {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	validRequest := &v1alpha1.IAMRequest{
+		ResourcePolicies: []*v1alpha1.ResourcePolicy{
+			{
+				Resource: "projects/baz",
+				Bindings: []*v1alpha1.Binding{
+					{
+						Members: []string{"user:test-project-user@example.com"},
+						Role:    "roles/bigquery.dataViewer",
+					},
+				},
+			},
+		},
+	}
+
+	st := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	diffs := []*v1alpha1.IAMPolicyDiff{
+		{
+			Resource: "projects/baz",
+			Added: []*v1alpha1.BindingChange{
+				{
+					Role:                "roles/bigquery.dataViewer",
+					Member:              "user:test-project-user@example.com",
+					ConditionTitle:      "abcxyz-aod-expiry",
+					ConditionExpression: "request.time < timestamp('2009-11-11T01:00:00Z')",
+				},
+			},
+			Removed: []*v1alpha1.BindingChange{
+				{
+					Role:   "roles/viewer",
+					Member: "user:test-project-user@example.com",
+				},
+			},
+		},
+		{
+			Resource: "projects/qux",
+		},
+	}
+
+	cases := []struct {
+		name    string
+		args    []string
+		handler *fakeIAMDiffHandler
+		expReq  *v1alpha1.IAMRequestWrapper
+		expOut  string
+		expErr  string
+	}{
+		{
+			name:    "success_text",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-start-time", st.Format(time.RFC3339)},
+			handler: &fakeIAMDiffHandler{resp: diffs},
+			expOut: `
+------projects/baz------
++ roles/bigquery.dataViewer user:test-project-user@example.com (abcxyz-aod-expiry: request.time < timestamp('2009-11-11T01:00:00Z'))
+- roles/viewer user:test-project-user@example.com
+------projects/qux------
+No changes`,
+			expReq: &v1alpha1.IAMRequestWrapper{
+				IAMRequest: validRequest,
+				Duration:   2 * time.Hour,
+				StartTime:  st,
+			},
+		},
+		{
+			name:    "success_text_color",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-start-time", st.Format(time.RFC3339), "-color"},
+			handler: &fakeIAMDiffHandler{resp: diffs[:1]},
+			expOut: "------projects/baz------\n" +
+				"\x1b[32m+ roles/bigquery.dataViewer user:test-project-user@example.com (abcxyz-aod-expiry: request.time < timestamp('2009-11-11T01:00:00Z'))\x1b[0m\n" +
+				"\x1b[31m- roles/viewer user:test-project-user@example.com\x1b[0m",
+			expReq: &v1alpha1.IAMRequestWrapper{
+				IAMRequest: validRequest,
+				Duration:   2 * time.Hour,
+				StartTime:  st,
+			},
+		},
+		{
+			name:    "success_json",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-start-time", st.Format(time.RFC3339), "-format", "json"},
+			handler: &fakeIAMDiffHandler{resp: diffs[:1]},
+			expOut: `
+[
+  {
+    "resource": "projects/baz",
+    "added": [
+      {
+        "role": "roles/bigquery.dataViewer",
+        "member": "user:test-project-user@example.com",
+        "conditionTitle": "abcxyz-aod-expiry",
+        "conditionExpression": "request.time < timestamp('2009-11-11T01:00:00Z')"
+      }
+    ],
+    "removed": [
+      {
+        "role": "roles/viewer",
+        "member": "user:test-project-user@example.com"
+      }
+    ]
+  }
+]`,
+			expReq: &v1alpha1.IAMRequestWrapper{
+				IAMRequest: validRequest,
+				Duration:   2 * time.Hour,
+				StartTime:  st,
+			},
+		},
+		{
+			name:    "unexpected_args",
+			args:    []string{"foo"},
+			handler: &fakeIAMDiffHandler{},
+			expErr:  `unexpected arguments: ["foo"]`,
+		},
+		{
+			name:    "missing_path",
+			args:    []string{},
+			handler: &fakeIAMDiffHandler{},
+			expErr:  `path is required`,
+		},
+		{
+			name:    "invalid_duration",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml")},
+			handler: &fakeIAMDiffHandler{},
+			expErr:  "a positive duration is required",
+		},
+		{
+			name:    "invalid_format",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-format", "xml"},
+			handler: &fakeIAMDiffHandler{},
+			expErr:  `invalid format "xml"`,
+		},
+		{
+			name:    "invalid_yaml",
+			args:    []string{"-path", filepath.Join(dir, "invalid.yaml"), "-duration", "2h"},
+			handler: &fakeIAMDiffHandler{},
+			expErr:  "failed to read *v1alpha1.IAMRequest",
+		},
+		{
+			name: "handler_failure",
+			args: []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-start-time", st.Format(time.RFC3339)},
+			handler: &fakeIAMDiffHandler{
+				injectErr: fmt.Errorf("injected error"),
+			},
+			expErr: "injected error",
+			expReq: &v1alpha1.IAMRequestWrapper{
+				IAMRequest: validRequest,
+				Duration:   2 * time.Hour,
+				StartTime:  st,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var cmd IAMDiffCommand
+			cmd.testHandler = tc.handler
+			_, stdout, _ := cmd.Pipe()
+
+			args := append([]string{}, tc.args...)
+
+			err := cmd.Run(ctx, args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expReq, tc.handler.gotReq); diff != "" {
+				t.Errorf("Process(%+v) got request diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+type fakeIAMDiffHandler struct {
+	injectErr error
+	gotReq    *v1alpha1.IAMRequestWrapper
+	resp      []*v1alpha1.IAMPolicyDiff
+}
+
+func (h *fakeIAMDiffHandler) Diff(ctx context.Context, req *v1alpha1.IAMRequestWrapper) ([]*v1alpha1.IAMPolicyDiff, error) {
+	h.gotReq = req
+	return h.resp, h.injectErr
+}
@@ -16,29 +16,47 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/posener/complete/v2/predict"
 
 	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/denypolicy"
+	"github.com/abcxyz/access-on-demand/pkg/handler"
+	"github.com/abcxyz/access-on-demand/pkg/messages"
+	"github.com/abcxyz/access-on-demand/pkg/pam"
 	"github.com/abcxyz/access-on-demand/pkg/requestutil"
+	"github.com/abcxyz/access-on-demand/pkg/server"
 	"github.com/abcxyz/pkg/cli"
 	"github.com/abcxyz/pkg/logging"
 )
 
 var _ cli.Command = (*IAMHandleCommand)(nil)
 
+// Backends of IAMHandleCommand to grant the requested bindings with.
+const (
+	// backendIAM adds the bindings to the IAM policies of the resources.
+	backendIAM = "iam"
+
+	// backendPAM requests grants of the existing PAM entitlements of the
+	// resources granting the roles.
+	backendPAM = "pam"
+)
+
 // iamHandler interface that handles the IAMRequestWrapper.
 type iamHandler interface {
 	Do(context.Context, *v1alpha1.IAMRequestWrapper) ([]*v1alpha1.IAMResponse, error)
+	WaitForPropagation(ctx context.Context, r *v1alpha1.IAMRequest, expiry time.Time, timeout time.Duration) error
 }
 
 // IAMHandleCommand handles IAM requests.
 type IAMHandleCommand struct {
 	cli.BaseCommand
 
-	flagPath string
+	flagPaths []string
 
 	flagDuration time.Duration
 
@@ -46,9 +64,37 @@ type IAMHandleCommand struct {
 
 	flagVerbose bool
 
-	// Optional custom condition title for IAM bindings expiration, required for
-	// integration test.
-	flagCustomConditionTitle string
+	flagNoImplicitCleanup bool
+
+	flagRollbackOnFailure bool
+
+	flagPreflight bool
+
+	flagWaitForPropagation time.Duration
+
+	flagBackend string
+
+	iamHandlerFlags iamHandlerFlags
+
+	prCommentFlags prCommentFlags
+
+	provenanceFlags provenanceFlags
+
+	memberCheckFlags memberCheckFlags
+
+	policyFlags policyFlags
+
+	identityFlags identityFlags
+
+	entitlementFlags entitlementFlags
+
+	approvalFlags approvalFlags
+
+	admissionFlags admissionFlags
+
+	detachFlags detachFlags
+
+	receiptFlags receiptFlags
 
 	// testHandler is used for testing only.
 	testHandler iamHandler
@@ -66,24 +112,46 @@ Handle the IAM request YAML file in the given path:
 
       {{ COMMAND }} -path "/path/to/file.yaml" -duration "2h" -start-time "2009-11-10T23:00:00Z"
 
+Handle all IAM request YAML files matching the glob:
+
+      {{ COMMAND }} -path "requests/*.yaml" -duration "2h"
+
 Handle the IAM request YAML file and output applied IAM changes:
 
       {{ COMMAND }} -path "/path/to/file.yaml" -duration "2h" -start-time "2009-11-10T23:00:00Z" -verbose
+
+Handle the IAM request YAML file all-or-nothing, restoring the resources
+already updated if any resource fails:
+
+      {{ COMMAND }} -path "/path/to/file.yaml" -duration "2h" -rollback-on-failure
+
+Check all the resources are accessible before modifying any of them:
+
+      {{ COMMAND }} -path "/path/to/file.yaml" -duration "2h" -preflight
+
+Wait up to 2 minutes for the granted bindings to be visible in the IAM policies:
+
+      {{ COMMAND }} -path "/path/to/file.yaml" -duration "2h" -wait-for-propagation "2m"
+
+Request grants of the Privileged Access Manager entitlements granting the roles
+instead of editing the IAM policies:
+
+      {{ COMMAND }} -path "/path/to/file.yaml" -duration "2h" -backend "pam"
 `
 }
 
 func (c *IAMHandleCommand) Flags() *cli.FlagSet {
-	set := c.NewFlagSet()
+	set := newFlagSet(&c.BaseCommand)
 
 	// Command options
 	f := set.NewSection("COMMAND OPTIONS")
 
-	f.StringVar(&cli.StringVar{
+	f.StringSliceVar(&cli.StringSliceVar{
 		Name:    "path",
-		Target:  &c.flagPath,
+		Target:  &c.flagPaths,
 		Example: "/path/to/file.yaml",
 		Predict: predict.Files("*"),
-		Usage:   `The path of IAM request file, in YAML format.`,
+		Usage:   bundlePathUsage + multiPathUsage,
 	})
 
 	f.DurationVar(&cli.DurationVar{
@@ -97,7 +165,6 @@ func (c *IAMHandleCommand) Flags() *cli.FlagSet {
 		Name:    "start-time",
 		Target:  &c.flagStartTime,
 		Example: "2009-11-10T23:00:00Z",
-		Default: time.Now().UTC(),
 		Usage: `The start time of the IAM permission lifecycle in RFC3339 format. ` +
 			`Default is current UTC time.`,
 	})
@@ -109,14 +176,74 @@ func (c *IAMHandleCommand) Flags() *cli.FlagSet {
 		Usage:   `Turn on verbose mode to print updated IAM policies. Note that it may contain sensitive information`,
 	})
 
+	f.BoolVar(&cli.BoolVar{
+		Name:    "no-implicit-cleanup",
+		Target:  &c.flagNoImplicitCleanup,
+		Default: false,
+		Usage: `Do not remove expired AOD IAM bindings of the resources when ` +
+			`adding the requested bindings.`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "rollback-on-failure",
+		Target:  &c.flagRollbackOnFailure,
+		Default: false,
+		Usage: `Restore the resources already updated to their prior IAM ` +
+			`policies if the request fails for any resource, so that the ` +
+			`request is applied all-or-nothing.`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "preflight",
+		Target:  &c.flagPreflight,
+		Default: false,
+		Usage: `Check that the IAM policies of all the resources can be read ` +
+			`and set before modifying any of them, and fail without ` +
+			`modifying any if some resources are inaccessible.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "wait-for-propagation",
+		Target:  &c.flagWaitForPropagation,
+		Example: "2m",
+		Usage: `After handling the request, poll the IAM policies of the ` +
+			`resources until the granted bindings are visible, and fail ` +
+			`if they are not visible within the duration. Default is not ` +
+			`to wait.`,
+	})
+
 	f.StringVar(&cli.StringVar{
-		Name:    "custom-condition-title",
-		Target:  &c.flagCustomConditionTitle,
-		Hidden:  true,
-		Example: "foo-aod-expiry",
-		Usage:   `The custom title for the aod expiry condition.`,
+		Name:    "backend",
+		Target:  &c.flagBackend,
+		Example: backendPAM,
+		Default: backendIAM,
+		Predict: predict.Set{backendIAM, backendPAM},
+		Usage: `How to grant the requested bindings, one of "iam" to add ` +
+			`them to the IAM policies, or "pam" to request grants of the ` +
+			`existing Privileged Access Manager entitlements of the ` +
+			`resources granting the roles to the caller.`,
 	})
 
+	c.iamHandlerFlags.register(f)
+
+	c.prCommentFlags.register(f)
+	c.provenanceFlags.register(f)
+
+	c.memberCheckFlags.register(f)
+	c.policyFlags.register(f)
+	c.identityFlags.register(f)
+	c.entitlementFlags.register(f)
+
+	c.approvalFlags.register(f)
+	c.admissionFlags.register(f)
+
+	c.detachFlags.register(f)
+
+	c.receiptFlags.register(f, `The path to write the signed grant receipts of `+
+		`the handled requests to, in YAML format, with their grant IDs, `+
+		`bindings and expiries. Present it to iam cleanup to clean up `+
+		`exactly the granted bindings.`)
+
 	return set
 }
 
@@ -130,40 +257,171 @@ func (c *IAMHandleCommand) Run(ctx context.Context, args []string) error {
 		return fmt.Errorf("unexpected arguments: %q", args)
 	}
 
-	if c.flagPath == "" {
+	if len(c.flagPaths) == 0 {
 		return fmt.Errorf("path is required")
 	}
 
+	if err := c.iamHandlerFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.policyFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.identityFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.entitlementFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.prCommentFlags.validate(c.GetEnv); err != nil {
+		return err
+	}
+
+	if err := c.grantChecks().validate(c.flagPaths); err != nil {
+		return err
+	}
+
+	if err := c.detachFlags.validate(); err != nil {
+		return err
+	}
+
+	if c.detachFlags.flagDetach && c.flagRollbackOnFailure {
+		return fmt.Errorf("rollback-on-failure is not supported with detach")
+	}
+
+	if c.flagWaitForPropagation < 0 {
+		return fmt.Errorf("wait-for-propagation must not be negative, got %s", c.flagWaitForPropagation)
+	}
+
+	if c.detachFlags.flagDetach && c.flagWaitForPropagation > 0 {
+		return fmt.Errorf("wait-for-propagation is not supported with detach")
+	}
+
+	if err := c.receiptFlags.validate(); err != nil {
+		return err
+	}
+
+	if c.detachFlags.flagDetach && c.receiptFlags.flagReceipt != "" {
+		return fmt.Errorf("receipt is not supported with detach")
+	}
+
+	if err := c.validateBackend(); err != nil {
+		return err
+	}
+
 	if c.flagDuration <= 0 {
 		return fmt.Errorf("a positive duration is required")
 	}
 
-	if c.flagStartTime.Add(c.flagDuration).Before(time.Now()) {
+	// Default start time to the current time.
+	now := c.iamHandlerFlags.now()
+	if c.flagStartTime.IsZero() {
+		c.flagStartTime = now
+	}
+
+	if c.flagStartTime.Add(c.flagDuration).Before(now) {
 		return fmt.Errorf("expiry (start time: %q + duration: %q) already passed", c.flagStartTime, c.flagDuration)
 	}
 
 	return c.handleIAM(ctx)
 }
 
+// validateBackend returns an error if the backend is unknown, or the flags are
+// not supported by the backend.
+func (c *IAMHandleCommand) validateBackend() error {
+	switch c.flagBackend {
+	case backendIAM:
+		return nil
+	case backendPAM:
+	default:
+		return fmt.Errorf("backend must be one of %q, got %q", []string{backendIAM, backendPAM}, c.flagBackend)
+	}
+
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{name: "detach", set: c.detachFlags.flagDetach},
+		{name: "no-implicit-cleanup", set: c.flagNoImplicitCleanup},
+		{name: "preflight", set: c.flagPreflight},
+		{name: "receipt", set: c.receiptFlags.flagReceipt != ""},
+		{name: "rollback-on-failure", set: c.flagRollbackOnFailure},
+		{name: "wait-for-propagation", set: c.flagWaitForPropagation > 0},
+	} {
+		if f.set {
+			return fmt.Errorf("%s is not supported with backend %q", f.name, backendPAM)
+		}
+	}
+	return nil
+}
+
 func (c *IAMHandleCommand) handleIAM(ctx context.Context) error {
 	logger := logging.FromContext(ctx)
 
-	// Read request from file path.
-	var req v1alpha1.IAMRequest
-	if err := requestutil.ReadRequestFromPath(c.flagPath, &req); err != nil {
-		return fmt.Errorf("failed to read %T: %w", &req, err)
+	paths, err := expandRequestPaths(c.flagPaths)
+	if err != nil {
+		return err
 	}
 
-	if err := v1alpha1.ValidateIAMRequest(&req); err != nil {
-		return fmt.Errorf("failed to validate %T: %w", &req, err)
+	// Validate all requests before handling any of them.
+	rr := newRequestReader(c.Stdin())
+	reqs := make([]*v1alpha1.IAMRequestWrapper, 0, len(paths))
+	var retErr error
+	for _, p := range paths {
+		reqWrapper, err := c.prepare(ctx, rr, p)
+		if err != nil {
+			retErr = errors.Join(retErr, withPath(paths, p, err))
+			continue
+		}
+		reqs = append(reqs, reqWrapper)
+	}
+	if retErr != nil {
+		return retErr
+	}
+
+	if c.detachFlags.flagDetach {
+		return c.detach(ctx, paths, reqs)
 	}
 
 	var h iamHandler
 	if c.testHandler != nil {
 		// Use testHandler if it is for testing.
 		h = c.testHandler
+	} else if c.flagBackend == backendPAM {
+		pamHandler, done, err := newPAMHandler(ctx, &c.iamHandlerFlags, &c.BaseCommand)
+		if err != nil {
+			return err
+		}
+		defer done()
+		h = pamHandler
 	} else {
-		iamHandler, closer, newHandlerErr := newIAMHandler(ctx, c.flagCustomConditionTitle)
+		var opts []handler.Option
+		if c.flagNoImplicitCleanup {
+			opts = append(opts, handler.WithSkipImplicitCleanup())
+		}
+		if c.flagRollbackOnFailure {
+			opts = append(opts, handler.WithRollbackOnFailure())
+		}
+		if c.flagPreflight {
+			opts = append(opts, handler.WithPreflight())
+		}
+		if slices.ContainsFunc(reqs, func(r *v1alpha1.IAMRequestWrapper) bool { return len(r.DenyPolicies) > 0 }) {
+			m, err := denypolicy.NewClient(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to create deny policies client: %w", err)
+			}
+			defer func() {
+				if err := m.Close(); err != nil {
+					logger.ErrorContext(ctx, "failed to close", "error", err)
+				}
+			}()
+			opts = append(opts, handler.WithDenyPolicyManager(m))
+		}
+		iamHandler, closer, newHandlerErr := newIAMHandler(ctx, &c.iamHandlerFlags, &c.BaseCommand, opts...)
 		if newHandlerErr != nil {
 			return newHandlerErr
 		}
@@ -175,28 +433,231 @@ func (c *IAMHandleCommand) handleIAM(ctx context.Context) error {
 		}()
 	}
 
+	var failed int
+	results := make([]*actionResult, 0, len(reqs))
+	handled := make([]*v1alpha1.IAMRequestWrapper, 0, len(reqs))
+	for i, reqWrapper := range reqs {
+		err := c.handle(ctx, h, reqWrapper)
+		res := newActionResult(paths[i], reqWrapper.IAMRequest, actionStatusHandled, err)
+		if err != nil {
+			failed++
+			retErr = errors.Join(retErr, withPath(paths, paths[i], err))
+		} else {
+			startTime, expiry := reqWrapper.StartTime, reqWrapper.StartTime.Add(reqWrapper.Duration)
+			res.StartTime, res.Expiry = &startTime, &expiry
+			handled = append(handled, reqWrapper)
+		}
+		results = append(results, res)
+	}
+	writeActionOutputs(ctx, c.GetEnv, results)
+	// Only the handled requests have receipts, so that the cleanup does not
+	// target the bindings of the failed ones.
+	if c.receiptFlags.flagReceipt != "" && len(handled) > 0 {
+		if err := c.receiptFlags.write(handled); err != nil {
+			retErr = errors.Join(retErr, err)
+		}
+	}
+	if failed > 0 && failed < len(reqs) {
+		return withExitCode(ExitCodePartialFailure, retErr)
+	}
+	return retErr
+}
+
+// detach enqueues the IAM requests as detached operations on the AOD server.
+func (c *IAMHandleCommand) detach(ctx context.Context, paths []string, reqs []*v1alpha1.IAMRequestWrapper) error {
+	client, err := c.detachFlags.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	var ops []*server.Operation
+	var retErr error
+	for i, reqWrapper := range reqs {
+		op, err := client.DetachHandle(ctx, reqWrapper)
+		if err != nil {
+			retErr = errors.Join(retErr, withPath(paths, paths[i], fmt.Errorf("failed to detach IAM request: %w", err)))
+			continue
+		}
+		ops = append(ops, op)
+	}
+	return errors.Join(retErr, printOperations(ctx, &c.BaseCommand, ops))
+}
+
+// prepare reads and validates the IAM request file at the path, and wraps it
+// with the duration, provenance and approvers of the request.
+func (c *IAMHandleCommand) prepare(ctx context.Context, rr *requestReader, path string) (*v1alpha1.IAMRequestWrapper, error) {
+	// Read request from file path.
+	docs, err := rr.iamBundle(path)
+	if err != nil {
+		return nil, withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &v1alpha1.IAMRequest{}, err))
+	}
+	if err := injectFailure(c.iamHandlerFlags.flagInjectFailure, failureStageParse); err != nil {
+		return nil, withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &v1alpha1.IAMRequest{}, err))
+	}
+
+	if err := requestutil.ExpandIAMBundle(docs, c.entitlementFlags.catalog, c.flagDuration); err != nil {
+		auditValidationDenied(ctx, c.iamHandlerFlags.flagAuditLogProject, err)
+		return nil, withExitCode(ExitCodeValidation, fmt.Errorf("failed to expand entitlements of %T: %w", &v1alpha1.IAMRequest{}, err))
+	}
+	if err := requestutil.ResolveIAMBundle(docs, c.identityFlags.identities); err != nil {
+		auditValidationDenied(ctx, c.iamHandlerFlags.flagAuditLogProject, err)
+		return nil, withExitCode(ExitCodeValidation, fmt.Errorf("failed to resolve members of %T: %w", &v1alpha1.IAMRequest{}, err))
+	}
+	req, err := requestutil.ValidateIAMBundle(docs, c.policyFlags.options(c.flagDuration))
+	if err != nil {
+		auditValidationDenied(ctx, c.iamHandlerFlags.flagAuditLogProject, err)
+		return nil, withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", req, err))
+	}
+	return c.grantChecks().wrap(ctx, rr, path, req, c.flagDuration, c.flagStartTime)
+}
+
+// grantChecks returns the checks of the IAM requests before they are granted.
+func (c *IAMHandleCommand) grantChecks() *grantChecks {
+	return &grantChecks{
+		memberCheckFlags: &c.memberCheckFlags,
+		approvalFlags:    &c.approvalFlags,
+		provenanceFlags:  &c.provenanceFlags,
+		admissionFlags:   &c.admissionFlags,
+		getenv:           c.GetEnv,
+		auditLogProject:  c.iamHandlerFlags.flagAuditLogProject,
+	}
+}
+
+// grantChecks are the checks of validated IAM requests before they are
+// granted, shared by the commands that grant IAM requests.
+type grantChecks struct {
+	memberCheckFlags *memberCheckFlags
+	approvalFlags    *approvalFlags
+	provenanceFlags  *provenanceFlags
+	admissionFlags   *admissionFlags
+
+	getenv          func(string) string
+	auditLogProject string
+}
+
+// validate validates the flags of the checks for the request paths.
+func (g *grantChecks) validate(paths []string) error {
+	if err := g.approvalFlags.validate(); err != nil {
+		return err
+	}
+	if slices.Contains(paths, stdinPath) && g.approvalFlags.flagRequireApprovals > 0 {
+		return fmt.Errorf("require-approvals requires path to be a file in the pull request, not stdin")
+	}
+	return g.provenanceFlags.validate()
+}
+
+// wrap checks the members and approvals of the validated IAM request read from
+// the path, wraps it with the duration, start time, provenance and approvers of
+// the request, and checks the wrapped request is admitted.
+func (g *grantChecks) wrap(ctx context.Context, rr *requestReader, path string, req *v1alpha1.IAMRequest, duration time.Duration, startTime time.Time) (*v1alpha1.IAMRequestWrapper, error) {
+	if err := g.memberCheckFlags.check(ctx, req, g.auditLogProject); err != nil {
+		return nil, withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", req, err))
+	}
+	approvers, err := g.approvalFlags.verify(ctx, path, g.getenv, g.auditLogProject)
+	if err != nil {
+		return nil, withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", req, err))
+	}
+
 	// Wrap IAMRequest to include Duration.
 	reqWrapper := &v1alpha1.IAMRequestWrapper{
-		IAMRequest: &req,
-		Duration:   c.flagDuration,
-		StartTime:  c.flagStartTime,
+		IAMRequest: req,
+		Duration:   duration,
+		StartTime:  startTime,
+	}
+	if err := g.provenanceFlags.apply(ctx, reqWrapper); err != nil {
+		return nil, err
+	}
+	// Record the verified approvers unless the approvers are provided.
+	if len(reqWrapper.Approvers) == 0 {
+		reqWrapper.Approvers = approvers
+	}
+	if err := g.admissionFlags.check(ctx, reqWrapper, g.getenv, g.auditLogProject); err != nil {
+		return nil, withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", req, err))
+	}
+	if reqWrapper.RequestHash, err = rr.hash(path); err != nil {
+		return nil, fmt.Errorf("failed to hash %T: %w", req, err)
 	}
+	return reqWrapper, nil
+}
 
+// handle handles the IAM request and outputs the result.
+func (c *IAMHandleCommand) handle(ctx context.Context, h iamHandler, reqWrapper *v1alpha1.IAMRequestWrapper) error {
 	resp, err := h.Do(ctx, reqWrapper)
+	comment := &prComment{Action: "handle", Done: "handled", Summary: reqWrapper, Err: err}
+	if c.flagVerbose {
+		comment.Responses = redactResponses(resp, c.iamHandlerFlags.conditionTitle(), requestMembers(reqWrapper.IAMRequest))
+	}
+	c.prCommentFlags.post(ctx, c.GetEnv, comment)
+	writeStepSummary(ctx, c.GetEnv, comment)
+	printWarnings(c.Stderr(), resp, c.GetEnv("GITHUB_ACTIONS") == "true")
 	if err != nil {
-		return fmt.Errorf("failed to handle IAM request: %w", err)
+		printPartiallyApplied(c.Stderr(), resp, c.flagRollbackOnFailure, c.GetEnv("GITHUB_ACTIONS") == "true")
+		return withExitCode(apiExitCode(err, len(reqWrapper.ResourcePolicies)),
+			fmt.Errorf("failed to handle IAM request: %w", err))
 	}
-	printHeader(c.Stdout(), "Successfully Handled IAM Request")
+	printMessageHeader(ctx, &c.BaseCommand, messages.HeaderHandled)
 	if err := encodeYaml(c.Stdout(), reqWrapper); err != nil {
 		return fmt.Errorf("failed to output applied request: %w", err)
 	}
 
+	if c.flagBackend == backendPAM {
+		printMessageHeader(ctx, &c.BaseCommand, messages.HeaderPAMGrants)
+		if err := encodeYaml(c.Stdout(), pamGrants(resp)); err != nil {
+			return fmt.Errorf("failed to output PAM grants: %w", err)
+		}
+	}
+
 	if c.flagVerbose {
-		printHeader(c.Stdout(), "Updated IAM Policies")
+		printMessageHeader(ctx, &c.BaseCommand, messages.HeaderUpdatedPolicies)
 		if err := encodeYaml(c.Stdout(), resp); err != nil {
 			return fmt.Errorf("failed to output IAM policies: %w", err)
 		}
 	}
 
+	if c.flagWaitForPropagation > 0 {
+		expiry := reqWrapper.StartTime.Add(reqWrapper.Duration)
+		if err := h.WaitForPropagation(ctx, reqWrapper.IAMRequest, expiry, c.flagWaitForPropagation); err != nil {
+			return withExitCode(ExitCodeAPIFailure, fmt.Errorf("failed to confirm IAM request propagation: %w", err))
+		}
+	}
+
 	return nil
 }
+
+// newPAMHandler returns a PAM handler sharing the audit sinks and the other
+// settings of the IAM handler of the flags.
+func newPAMHandler(ctx context.Context, flags *iamHandlerFlags, cmd *cli.BaseCommand) (*handler.PAMHandler, func(), error) {
+	logger := logging.FromContext(ctx)
+
+	m, err := pam.NewClient(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create PAM client: %w", err)
+	}
+	h, closer, err := newIAMHandler(ctx, flags, cmd)
+	done := func() {
+		if err := closer.Close(); err != nil {
+			logger.ErrorContext(ctx, "failed to close", "error", err)
+		}
+	}
+	if err != nil {
+		done()
+		return nil, nil, err
+	}
+	ph, err := handler.NewPAMHandler(h, m)
+	if err != nil {
+		done()
+		return nil, nil, fmt.Errorf("failed to create PAM handler: %w", err)
+	}
+	return ph, done, nil
+}
+
+// pamGrants returns the PAM grants of the responses by their resources.
+func pamGrants(resps []*v1alpha1.IAMResponse) map[string][]string {
+	result := make(map[string][]string, len(resps))
+	for _, r := range resps {
+		if r != nil && len(r.Grants) > 0 {
+			result[r.Resource] = r.Grants
+		}
+	}
+	return result
+}
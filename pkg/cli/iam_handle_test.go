@@ -16,6 +16,8 @@ package cli
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -24,8 +26,10 @@ import (
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/idtoken"
 
 	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/approval"
 	"github.com/abcxyz/pkg/logging"
 	"github.com/abcxyz/pkg/testutil"
 )
@@ -68,13 +72,66 @@ policies:
     role: roles/cloudkms.cryptoOperator
 `,
 		"invalid.yaml": `bananas`,
+		"policy.yaml": `
+roles:
+- resources: ["projects/*"]
+  deny: ["roles/bigquery.*"]
+`,
+		"rule-policy.yaml": `
+rules:
+- name: short-grants
+  expression: request.duration <= duration('4h')
+`,
+		"bundle.yaml": `
+policies:
+- resource: organizations/foo
+  bindings:
+  - members:
+    - user:test-org-userA@example.com
+    - user:test-org-userB@example.com
+    role: roles/cloudkms.cryptoOperator
+  - members:
+    - user:test-org-userA@example.com
+    - user:test-org-userB@example.com
+    role: roles/accessapproval.approver
+---
+policies:
+- resource: folders/bar
+  bindings:
+    - members:
+      - user:test-folder-user@example.com
+      role: roles/cloudkms.cryptoOperator
+- resource: projects/baz
+  bindings:
+    - members:
+      - user:test-project-user@example.com
+      role: roles/bigquery.dataViewer
+`,
+		"invalid-bundle.yaml": `
+policies:
+- resource: projects/baz
+  bindings:
+    - members:
+      - user:test-project-user@example.com
+      role: roles/bigquery.dataViewer
+---
+policies:
+- resource: organizations/foo
+  bindings:
+  - members:
+    - group:test-org-group@example.com
+    role: roles/cloudkms.cryptoOperator
+`,
 	}
 	dir := t.TempDir()
+	hashes := make(map[string]string, len(requestFileContentByName))
 	for name, content := range requestFileContentByName {
 		path := filepath.Join(dir, name)
 		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
 			t.Fatal(err)
 		}
+		sum := sha256.Sum256([]byte(content))
+		hashes[name] = hex.EncodeToString(sum[:])
 	}
 
 	// The request expected from the valid request yaml file.
@@ -127,12 +184,16 @@ policies:
 	st := time.Now().UTC().Round(time.Second)
 
 	cases := []struct {
-		name    string
-		args    []string
-		handler *fakeIAMHandler
-		expReq  *v1alpha1.IAMRequestWrapper
-		expOut  string
-		expErr  string
+		name     string
+		args     []string
+		handler  *fakeIAMHandler
+		checker  *fakeMemberChecker
+		verifier *fakeApprovalVerifier
+		admitter *fakeAdmissionChecker
+		tokens   *fakeTokenValidator
+		expReq   *v1alpha1.IAMRequestWrapper
+		expOut   string
+		expErr   string
 	}{
 		{
 			name:    "success",
@@ -165,11 +226,284 @@ iamrequest:
 duration: 2h0m0s
 starttime: %s`, st.Format(time.RFC3339)),
 			expReq: &v1alpha1.IAMRequestWrapper{
-				IAMRequest: validRequest,
-				Duration:   2 * time.Hour,
-				StartTime:  st,
+				IAMRequest:  validRequest,
+				Duration:    2 * time.Hour,
+				StartTime:   st,
+				RequestHash: hashes["valid.yaml"],
+			},
+		},
+		{
+			name: "success_pam",
+			args: []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-start-time", st.Format(time.RFC3339), "-backend", "pam"},
+			handler: &fakeIAMHandler{resp: []*v1alpha1.IAMResponse{
+				{Resource: "organizations/foo", Grants: []string{"organizations/foo/locations/global/entitlements/kms/grants/1"}},
+				{Resource: "folders/bar", Grants: []string{"folders/bar/locations/global/entitlements/kms/grants/2"}},
+				{Resource: "projects/baz", Grants: []string{"projects/baz/locations/global/entitlements/bq/grants/3"}},
+			}},
+			expOut: fmt.Sprintf(`
+------Successfully Handled IAM Request------
+iamrequest:
+  policies:
+    - resource: organizations/foo
+      bindings:
+        - members:
+            - user:test-org-userA@example.com
+            - user:test-org-userB@example.com
+          role: roles/cloudkms.cryptoOperator
+        - members:
+            - user:test-org-userA@example.com
+            - user:test-org-userB@example.com
+          role: roles/accessapproval.approver
+    - resource: folders/bar
+      bindings:
+        - members:
+            - user:test-folder-user@example.com
+          role: roles/cloudkms.cryptoOperator
+    - resource: projects/baz
+      bindings:
+        - members:
+            - user:test-project-user@example.com
+          role: roles/bigquery.dataViewer
+duration: 2h0m0s
+starttime: %s
+------Requested PAM Grants------
+folders/bar:
+  - folders/bar/locations/global/entitlements/kms/grants/2
+organizations/foo:
+  - organizations/foo/locations/global/entitlements/kms/grants/1
+projects/baz:
+  - projects/baz/locations/global/entitlements/bq/grants/3`, st.Format(time.RFC3339)),
+			expReq: &v1alpha1.IAMRequestWrapper{
+				IAMRequest:  validRequest,
+				Duration:    2 * time.Hour,
+				StartTime:   st,
+				RequestHash: hashes["valid.yaml"],
+			},
+		},
+		{
+			name: "success_with_provenance",
+			args: []string{
+				"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-start-time", st.Format(time.RFC3339),
+				"-requester", "user:requester@example.com",
+				"-approver", "user:approverA@example.com", "-approver", "user:approverB@example.com",
+				"-source", "https://github.com/foo/bar/pull/1",
+			},
+			handler: &fakeIAMHandler{},
+			expOut: fmt.Sprintf(`
+------Successfully Handled IAM Request------
+iamrequest:
+  policies:
+    - resource: organizations/foo
+      bindings:
+        - members:
+            - user:test-org-userA@example.com
+            - user:test-org-userB@example.com
+          role: roles/cloudkms.cryptoOperator
+        - members:
+            - user:test-org-userA@example.com
+            - user:test-org-userB@example.com
+          role: roles/accessapproval.approver
+    - resource: folders/bar
+      bindings:
+        - members:
+            - user:test-folder-user@example.com
+          role: roles/cloudkms.cryptoOperator
+    - resource: projects/baz
+      bindings:
+        - members:
+            - user:test-project-user@example.com
+          role: roles/bigquery.dataViewer
+duration: 2h0m0s
+starttime: %s
+requester: user:requester@example.com
+approvers:
+  - user:approverA@example.com
+  - user:approverB@example.com
+source: https://github.com/foo/bar/pull/1`, st.Format(time.RFC3339)),
+			expReq: &v1alpha1.IAMRequestWrapper{
+				IAMRequest:  validRequest,
+				Duration:    2 * time.Hour,
+				StartTime:   st,
+				RequestHash: hashes["valid.yaml"],
+				Requester:   "user:requester@example.com",
+				Approvers:   []string{"user:approverA@example.com", "user:approverB@example.com"},
+				Source:      "https://github.com/foo/bar/pull/1",
+			},
+		},
+		{
+			name: "success_with_id_token",
+			args: []string{
+				"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-start-time", st.Format(time.RFC3339),
+				"-id-token", "test-token", "-id-token-audience", "aod",
+			},
+			handler: &fakeIAMHandler{},
+			tokens: &fakeTokenValidator{claims: map[string]any{
+				"email":          "requester@example.com",
+				"email_verified": true,
+			}},
+			expOut: fmt.Sprintf(`
+------Successfully Handled IAM Request------
+iamrequest:
+  policies:
+    - resource: organizations/foo
+      bindings:
+        - members:
+            - user:test-org-userA@example.com
+            - user:test-org-userB@example.com
+          role: roles/cloudkms.cryptoOperator
+        - members:
+            - user:test-org-userA@example.com
+            - user:test-org-userB@example.com
+          role: roles/accessapproval.approver
+    - resource: folders/bar
+      bindings:
+        - members:
+            - user:test-folder-user@example.com
+          role: roles/cloudkms.cryptoOperator
+    - resource: projects/baz
+      bindings:
+        - members:
+            - user:test-project-user@example.com
+          role: roles/bigquery.dataViewer
+duration: 2h0m0s
+starttime: %s
+requester: user:requester@example.com`, st.Format(time.RFC3339)),
+			expReq: &v1alpha1.IAMRequestWrapper{
+				IAMRequest:  validRequest,
+				Duration:    2 * time.Hour,
+				StartTime:   st,
+				RequestHash: hashes["valid.yaml"],
+				Requester:   "user:requester@example.com",
+			},
+		},
+		{
+			name: "id_token_unverified_email",
+			args: []string{
+				"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-start-time", st.Format(time.RFC3339),
+				"-id-token", "test-token",
+			},
+			handler: &fakeIAMHandler{},
+			tokens:  &fakeTokenValidator{claims: map[string]any{"email": "requester@example.com"}},
+			expErr:  `failed to get requester from id-token: email "requester@example.com" in ID token is not verified`,
+		},
+		{
+			name: "id_token_missing_email",
+			args: []string{
+				"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-start-time", st.Format(time.RFC3339),
+				"-id-token", "test-token",
+			},
+			handler: &fakeIAMHandler{},
+			tokens:  &fakeTokenValidator{claims: map[string]any{}},
+			expErr:  "ID token has no email claim",
+		},
+		{
+			name: "id_token_invalid",
+			args: []string{
+				"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-start-time", st.Format(time.RFC3339),
+				"-id-token", "test-token", "-id-token-audience", "other",
+			},
+			handler: &fakeIAMHandler{},
+			tokens:  &fakeTokenValidator{claims: map[string]any{"email": "requester@example.com", "email_verified": true}},
+			expErr:  `failed to validate ID token: audience "other" does not match`,
+		},
+		{
+			name: "requester_and_id_token",
+			args: []string{
+				"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h",
+				"-requester", "user:requester@example.com", "-id-token", "test-token",
+			},
+			handler: &fakeIAMHandler{},
+			expErr:  "requester and id-token cannot both be set",
+		},
+		{
+			name: "success_with_approvals",
+			args: []string{
+				"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-start-time", st.Format(time.RFC3339),
+				"-require-approvals", "2", "-github-pr", "foo/bar/1",
+			},
+			handler:  &fakeIAMHandler{},
+			verifier: &fakeApprovalVerifier{result: &approval.Result{Author: "alice", Approvers: []string{"bob", "carol"}}},
+			expOut: fmt.Sprintf(`
+------Successfully Handled IAM Request------
+iamrequest:
+  policies:
+    - resource: organizations/foo
+      bindings:
+        - members:
+            - user:test-org-userA@example.com
+            - user:test-org-userB@example.com
+          role: roles/cloudkms.cryptoOperator
+        - members:
+            - user:test-org-userA@example.com
+            - user:test-org-userB@example.com
+          role: roles/accessapproval.approver
+    - resource: folders/bar
+      bindings:
+        - members:
+            - user:test-folder-user@example.com
+          role: roles/cloudkms.cryptoOperator
+    - resource: projects/baz
+      bindings:
+        - members:
+            - user:test-project-user@example.com
+          role: roles/bigquery.dataViewer
+duration: 2h0m0s
+starttime: %s
+approvers:
+  - github:bob
+  - github:carol`, st.Format(time.RFC3339)),
+			expReq: &v1alpha1.IAMRequestWrapper{
+				IAMRequest:  validRequest,
+				Duration:    2 * time.Hour,
+				StartTime:   st,
+				RequestHash: hashes["valid.yaml"],
+				Approvers:   []string{"github:bob", "github:carol"},
+			},
+		},
+		{
+			name:    "success_bundle",
+			args:    []string{"-path", filepath.Join(dir, "bundle.yaml"), "-duration", "2h", "-start-time", st.Format(time.RFC3339)},
+			handler: &fakeIAMHandler{},
+			expOut: fmt.Sprintf(`
+------Successfully Handled IAM Request------
+iamrequest:
+  policies:
+    - resource: organizations/foo
+      bindings:
+        - members:
+            - user:test-org-userA@example.com
+            - user:test-org-userB@example.com
+          role: roles/cloudkms.cryptoOperator
+        - members:
+            - user:test-org-userA@example.com
+            - user:test-org-userB@example.com
+          role: roles/accessapproval.approver
+    - resource: folders/bar
+      bindings:
+        - members:
+            - user:test-folder-user@example.com
+          role: roles/cloudkms.cryptoOperator
+    - resource: projects/baz
+      bindings:
+        - members:
+            - user:test-project-user@example.com
+          role: roles/bigquery.dataViewer
+duration: 2h0m0s
+starttime: %s`, st.Format(time.RFC3339)),
+			expReq: &v1alpha1.IAMRequestWrapper{
+				IAMRequest:  validRequest,
+				Duration:    2 * time.Hour,
+				StartTime:   st,
+				RequestHash: hashes["bundle.yaml"],
 			},
 		},
+		{
+			name:    "invalid_bundle",
+			args:    []string{"-path", filepath.Join(dir, "invalid-bundle.yaml"), "-duration", "2h"},
+			handler: &fakeIAMHandler{},
+			expErr: fmt.Sprintf(`%s#1: policies[0].bindings[0].members[0] at line 13, column 7: member "group:test-org-group@example.com" is not of "user" type`,
+				filepath.Join(dir, "invalid-bundle.yaml")),
+		},
 		{
 			name: "success_verbose",
 			args: []string{
@@ -216,9 +550,51 @@ starttime: %s
   resource: test
 `, st.Format(time.RFC3339)),
 			expReq: &v1alpha1.IAMRequestWrapper{
-				IAMRequest: validRequest,
-				Duration:   2 * time.Hour,
-				StartTime:  st,
+				IAMRequest:  validRequest,
+				Duration:    2 * time.Hour,
+				StartTime:   st,
+				RequestHash: hashes["valid.yaml"],
+			},
+		},
+		{
+			name: "success_pinned_now",
+			args: []string{
+				"-path", filepath.Join(dir, "valid.yaml"),
+				"-duration", "2h",
+				"-now", "2009-11-10T23:00:00Z",
+			},
+			handler: &fakeIAMHandler{},
+			expOut: `
+------Successfully Handled IAM Request------
+iamrequest:
+  policies:
+    - resource: organizations/foo
+      bindings:
+        - members:
+            - user:test-org-userA@example.com
+            - user:test-org-userB@example.com
+          role: roles/cloudkms.cryptoOperator
+        - members:
+            - user:test-org-userA@example.com
+            - user:test-org-userB@example.com
+          role: roles/accessapproval.approver
+    - resource: folders/bar
+      bindings:
+        - members:
+            - user:test-folder-user@example.com
+          role: roles/cloudkms.cryptoOperator
+    - resource: projects/baz
+      bindings:
+        - members:
+            - user:test-project-user@example.com
+          role: roles/bigquery.dataViewer
+duration: 2h0m0s
+starttime: 2009-11-10T23:00:00Z`,
+			expReq: &v1alpha1.IAMRequestWrapper{
+				IAMRequest:  validRequest,
+				Duration:    2 * time.Hour,
+				StartTime:   time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC),
+				RequestHash: hashes["valid.yaml"],
 			},
 		},
 		{
@@ -239,6 +615,42 @@ starttime: %s
 			handler: &fakeIAMHandler{},
 			expErr:  `duration is required`,
 		},
+		{
+			name:    "rollback_with_detach",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-detach", "-server-url", "http://localhost:8080", "-rollback-on-failure"},
+			handler: &fakeIAMHandler{},
+			expErr:  "rollback-on-failure is not supported with detach",
+		},
+		{
+			name:    "wait_for_propagation_with_detach",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-detach", "-server-url", "http://localhost:8080", "-wait-for-propagation", "1m"},
+			handler: &fakeIAMHandler{},
+			expErr:  "wait-for-propagation is not supported with detach",
+		},
+		{
+			name:    "invalid_backend",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-backend", "bananas"},
+			handler: &fakeIAMHandler{},
+			expErr:  `backend must be one of ["iam" "pam"], got "bananas"`,
+		},
+		{
+			name:    "pam_with_rollback",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-backend", "pam", "-rollback-on-failure"},
+			handler: &fakeIAMHandler{},
+			expErr:  `rollback-on-failure is not supported with backend "pam"`,
+		},
+		{
+			name:    "receipt_without_key",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-receipt", filepath.Join(dir, "receipt.yaml")},
+			handler: &fakeIAMHandler{},
+			expErr:  "receipt-key is required with receipt",
+		},
+		{
+			name:    "pam_with_receipt",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-backend", "pam", "-receipt", filepath.Join(dir, "receipt.yaml"), "-receipt-key", "s3cr3t"},
+			handler: &fakeIAMHandler{},
+			expErr:  `receipt is not supported with backend "pam"`,
+		},
 		{
 			name:    "invalid_duration",
 			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "-2h"},
@@ -271,9 +683,10 @@ starttime: %s
 			},
 			expErr: "injected error",
 			expReq: &v1alpha1.IAMRequestWrapper{
-				IAMRequest: validRequest,
-				Duration:   1 * time.Hour,
-				StartTime:  st,
+				IAMRequest:  validRequest,
+				Duration:    1 * time.Hour,
+				StartTime:   st,
+				RequestHash: hashes["valid.yaml"],
 			},
 		},
 		{
@@ -282,6 +695,156 @@ starttime: %s
 			handler: &fakeIAMHandler{},
 			expErr:  "failed to validate *v1alpha1.IAMRequest",
 		},
+		{
+			name:    "inject_parse_failure",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-inject-failure", "parse"},
+			handler: &fakeIAMHandler{},
+			expErr:  `injected failure at stage "parse"`,
+		},
+		{
+			name:    "invalid_inject_failure_stage",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-inject-failure", "tool-exec"},
+			handler: &fakeIAMHandler{},
+			expErr:  `invalid inject-failure stage "tool-exec"`,
+		},
+		{
+			name:    "invalid_concurrency",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-concurrency", "0"},
+			handler: &fakeIAMHandler{},
+			expErr:  "concurrency must be at least 1, got 0",
+		},
+		{
+			name:    "github_comment_without_pr",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-github-comment"},
+			handler: &fakeIAMHandler{},
+			expErr:  "pr is required with github-comment",
+		},
+		{
+			name:    "invalid_allowed_resource_pattern",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-allowed-resource-pattern", "projects/("},
+			handler: &fakeIAMHandler{},
+			expErr:  `invalid allowed resource pattern "projects/("`,
+		},
+		{
+			name:    "policy_denied",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-policy", filepath.Join(dir, "policy.yaml")},
+			handler: &fakeIAMHandler{},
+			expErr:  `role "roles/bigquery.dataViewer" is denied (matches "roles/bigquery.*") on projects/baz`,
+		},
+		{
+			name:    "policy_rule_denied",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "8h", "-policy", filepath.Join(dir, "rule-policy.yaml")},
+			handler: &fakeIAMHandler{},
+			expErr:  `request violates rule "short-grants"`,
+		},
+		{
+			name:    "invalid_identity_map",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-identity-map", filepath.Join(dir, "policy.yaml")},
+			handler: &fakeIAMHandler{},
+			expErr:  "invalid identity map: invalid identity map file",
+		},
+		{
+			name:    "invalid_protected_resource",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-protected-resource", "projects"},
+			handler: &fakeIAMHandler{},
+			expErr:  "invalid protected resource",
+		},
+		{
+			name:    "invalid_resource_manager_fallback_endpoint",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-resource-manager-fallback-endpoint", "us-east1-cloudresourcemanager.googleapis.com"},
+			handler: &fakeIAMHandler{},
+			expErr:  `invalid resource manager fallback endpoint "us-east1-cloudresourcemanager.googleapis.com"`,
+		},
+		{
+			name:    "invalid_progress",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-progress", "text"},
+			handler: &fakeIAMHandler{},
+			expErr:  `invalid progress: invalid format "text", must be one of ["json"]`,
+		},
+		{
+			name:    "invalid_audit_bigquery_table",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-audit-bigquery-table", "aod.audit"},
+			handler: &fakeIAMHandler{},
+			expErr:  `invalid audit BigQuery table: table "aod.audit" isn't in the format of "project.dataset.table"`,
+		},
+		{
+			name:    "invalid_event_topic",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-event-topic", "aod-events"},
+			handler: &fakeIAMHandler{},
+			expErr:  `invalid event topic: topic "aod-events" isn't in the format of "projects/{project}/topics/{topic}"`,
+		},
+		{
+			name:    "invalid_otel_exporter",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-otel-exporter", "zipkin"},
+			handler: &fakeIAMHandler{},
+			expErr:  `invalid otel exporter "zipkin", must be one of ["otlp" "stderr"]`,
+		},
+		{
+			name:    "invalid_ticket_github_repo",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-ticket-github-repo", "foo"},
+			handler: &fakeIAMHandler{},
+			expErr:  `invalid ticket GitHub repo: repo "foo" isn't in the format of "owner/repo"`,
+		},
+		{
+			name: "multiple_ticket_creators",
+			args: []string{
+				"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-ticket-github-repo", "foo/bar",
+				"-ticket-jira-url", "https://example.atlassian.net", "-ticket-jira-project", "OPS",
+			},
+			handler: &fakeIAMHandler{},
+			expErr:  "only one of ticket-github-repo and ticket-jira-url can be set",
+		},
+		{
+			name:    "ticket_jira_missing_project",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-ticket-jira-url", "https://example.atlassian.net"},
+			handler: &fakeIAMHandler{},
+			expErr:  "ticket-jira-url and ticket-jira-project must be set together",
+		},
+		{
+			name:    "invalid_org_config",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-org-config", filepath.Join(dir, "invalid.yaml")},
+			handler: &fakeIAMHandler{},
+			expErr:  "invalid org config: failed to read *cli.orgConfigs",
+		},
+		{
+			name:     "approvals_failure",
+			args:     []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-require-approvals", "1", "-github-pr", "foo/bar/1"},
+			handler:  &fakeIAMHandler{},
+			verifier: &fakeApprovalVerifier{injectErr: fmt.Errorf(`pull request foo/bar/1 is approved by 0 users other than the author "alice"`)},
+			expErr:   `failed to verify approvals: pull request foo/bar/1 is approved by 0 users other than the author "alice"`,
+		},
+		{
+			name:    "approvals_missing_pr",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-require-approvals", "1"},
+			handler: &fakeIAMHandler{},
+			expErr:  "github-pr is required with require-approvals",
+		},
+		{
+			name:    "approvals_invalid_pr",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-require-approvals", "1", "-github-pr", "foo/bar"},
+			handler: &fakeIAMHandler{},
+			expErr:  `invalid github-pr: pull request "foo/bar" isn't in the format`,
+		},
+		{
+			name:    "approvals_negative",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-require-approvals", "-1"},
+			handler: &fakeIAMHandler{},
+			expErr:  "require-approvals must not be negative, got -1",
+		},
+		{
+			name:    "check_members_failure",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-check-member-domain", "example.com"},
+			handler: &fakeIAMHandler{},
+			checker: &fakeMemberChecker{injectErr: fmt.Errorf(`member "user:test-org-userA@example.com": user does not exist in the directory`)},
+			expErr:  `failed to check members: member "user:test-org-userA@example.com": user does not exist in the directory`,
+		},
+		{
+			name:     "admission_denied",
+			args:     []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-requester", "user:alice@example.com", "-opa-url", "http://localhost:8181/v1/data/aod/allow"},
+			handler:  &fakeIAMHandler{},
+			admitter: &fakeAdmissionChecker{injectErr: fmt.Errorf("request denied by admission policy: iam roles need approval")},
+			expErr:   "failed to check admission: request denied by admission policy: iam roles need approval",
+		},
 	}
 
 	for _, tc := range cases {
@@ -292,6 +855,18 @@ starttime: %s
 
 			var cmd IAMHandleCommand
 			cmd.testHandler = tc.handler
+			if tc.checker != nil {
+				cmd.memberCheckFlags.testChecker = tc.checker
+			}
+			if tc.verifier != nil {
+				cmd.approvalFlags.testVerifier = tc.verifier
+			}
+			if tc.admitter != nil {
+				cmd.admissionFlags.testChecker = tc.admitter
+			}
+			if tc.tokens != nil {
+				cmd.provenanceFlags.testTokenValidator = tc.tokens
+			}
 			_, stdout, _ := cmd.Pipe()
 
 			args := append([]string{}, tc.args...)
@@ -310,13 +885,303 @@ starttime: %s
 	}
 }
 
+type fakeApprovalVerifier struct {
+	result    *approval.Result
+	injectErr error
+}
+
+func (v *fakeApprovalVerifier) Verify(ctx context.Context, pr *approval.PullRequest, file string, required int) (*approval.Result, error) {
+	return v.result, v.injectErr
+}
+
+type fakeTokenValidator struct {
+	claims map[string]any
+}
+
+func (v *fakeTokenValidator) Validate(ctx context.Context, idToken, audience string) (*idtoken.Payload, error) {
+	if audience != "" && audience != "aod" {
+		return nil, fmt.Errorf("audience %q does not match", audience)
+	}
+	return &idtoken.Payload{Audience: "aod", Claims: v.claims}, nil
+}
+
 type fakeIAMHandler struct {
 	injectErr error
-	gotReq    *v1alpha1.IAMRequestWrapper
-	resp      []*v1alpha1.IAMResponse
+	// injectErrs injects errors by the resource of the first policy.
+	injectErrs map[string]error
+	gotReq     *v1alpha1.IAMRequestWrapper
+	gotReqs    []*v1alpha1.IAMRequestWrapper
+	resp       []*v1alpha1.IAMResponse
+
+	propagationErr    error
+	gotPropagationReq *v1alpha1.IAMRequest
 }
 
 func (h *fakeIAMHandler) Do(ctx context.Context, req *v1alpha1.IAMRequestWrapper) ([]*v1alpha1.IAMResponse, error) {
 	h.gotReq = req
+	h.gotReqs = append(h.gotReqs, req)
+	if err, ok := h.injectErrs[req.ResourcePolicies[0].Resource]; ok {
+		return nil, err
+	}
 	return h.resp, h.injectErr
 }
+
+func (h *fakeIAMHandler) WaitForPropagation(ctx context.Context, r *v1alpha1.IAMRequest, expiry time.Time, timeout time.Duration) error {
+	h.gotPropagationReq = r
+	return h.propagationErr
+}
+
+func TestIAMHandleCommand_WaitForPropagation(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "iam.yaml")
+	content := "policies:\n- resource: projects/a\n  bindings:\n  - members: [user:alice@example.com]\n    role: roles/viewer\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	wantReq := &v1alpha1.IAMRequest{
+		ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+			Resource: "projects/a",
+			Bindings: []*v1alpha1.Binding{{Members: []string{"user:alice@example.com"}, Role: "roles/viewer"}},
+		}},
+	}
+
+	cases := []struct {
+		name    string
+		args    []string
+		handler *fakeIAMHandler
+		expReq  *v1alpha1.IAMRequest
+		expCode int
+		expErr  string
+	}{
+		{
+			name:    "visible",
+			args:    []string{"-path", path, "-duration", "2h", "-wait-for-propagation", "1m"},
+			handler: &fakeIAMHandler{},
+			expReq:  wantReq,
+		},
+		{
+			name:    "not_visible",
+			args:    []string{"-path", path, "-duration", "2h", "-wait-for-propagation", "1m"},
+			handler: &fakeIAMHandler{propagationErr: fmt.Errorf("injected error")},
+			expReq:  wantReq,
+			expCode: ExitCodeAPIFailure,
+			expErr:  "failed to confirm IAM request propagation: injected error",
+		},
+		{
+			name:    "no_wait",
+			args:    []string{"-path", path, "-duration", "2h"},
+			handler: &fakeIAMHandler{propagationErr: fmt.Errorf("injected error")},
+		},
+		{
+			name:    "negative_wait",
+			args:    []string{"-path", path, "-duration", "2h", "-wait-for-propagation", "-1m"},
+			handler: &fakeIAMHandler{},
+			expCode: ExitCodeFailure,
+			expErr:  "wait-for-propagation must not be negative",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var cmd IAMHandleCommand
+			cmd.testHandler = tc.handler
+			_, _, _ = cmd.Pipe()
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if got := ExitCode(err); got != tc.expCode {
+				t.Errorf("Process(%+v) got exit code %d, want %d", tc.name, got, tc.expCode)
+			}
+			if diff := cmp.Diff(tc.expReq, tc.handler.gotPropagationReq); diff != "" {
+				t.Errorf("Process(%+v) got propagation request diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestIAMHandleCommand_MultiplePaths(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"team-a/iam.yaml":  "policies:\n- resource: projects/a\n  bindings:\n  - members: [user:alice@example.com]\n    role: roles/viewer\n",
+		"team-b/iam.yaml":  "policies:\n- resource: projects/b\n  bindings:\n  - members: [user:bob@example.com]\n    role: roles/viewer\n",
+		"team-b/notes.txt": "not a request",
+		"invalid.yaml":     "policies:\n- resource: projects/c\n  bindings:\n  - members: [group:eng@example.com]\n    role: roles/viewer\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name         string
+		args         []string
+		handler      *fakeIAMHandler
+		expResources []string
+		expErr       string
+	}{
+		{
+			name:         "repeated_paths",
+			args:         []string{"-path", "{{dir}}/team-a/iam.yaml", "-path", "{{dir}}/team-b/iam.yaml"},
+			handler:      &fakeIAMHandler{},
+			expResources: []string{"projects/a", "projects/b"},
+		},
+		{
+			name:         "glob",
+			args:         []string{"-path", "{{dir}}/team-*/*.yaml"},
+			handler:      &fakeIAMHandler{},
+			expResources: []string{"projects/a", "projects/b"},
+		},
+		{
+			name:         "directory",
+			args:         []string{"-path", "{{dir}}/team-b", "-path", "{{dir}}/team-b/iam.yaml"},
+			handler:      &fakeIAMHandler{},
+			expResources: []string{"projects/b"},
+		},
+		{
+			name:    "invalid_request",
+			args:    []string{"-path", "{{dir}}"},
+			handler: &fakeIAMHandler{},
+			expErr:  `{{dir}}/invalid.yaml: failed to validate *v1alpha1.IAMRequest`,
+		},
+		{
+			name:         "handler_failure",
+			args:         []string{"-path", "{{dir}}/team-*"},
+			handler:      &fakeIAMHandler{injectErrs: map[string]error{"projects/a": fmt.Errorf("injected error")}},
+			expResources: []string{"projects/a", "projects/b"},
+			expErr:       `{{dir}}/team-a/iam.yaml: failed to handle IAM request: injected error`,
+		},
+		{
+			name:    "no_matches",
+			args:    []string{"-path", "{{dir}}/team-c/*.yaml"},
+			handler: &fakeIAMHandler{},
+			expErr:  `no files match "{{dir}}/team-c/*.yaml"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var cmd IAMHandleCommand
+			cmd.testHandler = tc.handler
+			_, _, _ = cmd.Pipe()
+
+			args := []string{"-duration", "2h"}
+			for _, a := range tc.args {
+				args = append(args, strings.ReplaceAll(a, "{{dir}}", dir))
+			}
+
+			err := cmd.Run(ctx, args)
+			if diff := testutil.DiffErrString(err, strings.ReplaceAll(tc.expErr, "{{dir}}", dir)); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			var gotResources []string
+			for _, r := range tc.handler.gotReqs {
+				gotResources = append(gotResources, r.ResourcePolicies[0].Resource)
+			}
+			if diff := cmp.Diff(tc.expResources, gotResources); diff != "" {
+				t.Errorf("Process(%+v) got resources diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestIAMHandleCommand_Entitlements(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "iam.yaml")
+	catalogPath := filepath.Join(dir, "entitlements.yaml")
+	files := map[string]string{
+		path:        "entitlements:\n- entitlement: bq-oncall\n  members: [user:alice@example.com]\n  duration: 2h\n",
+		catalogPath: "bq-oncall:\n  resources: [projects/a]\n  roles: [roles/bigquery.dataViewer, roles/bigquery.jobUser]\n  maxDuration: 4h\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name    string
+		args    []string
+		expReq  *v1alpha1.IAMRequest
+		expCode int
+		expErr  string
+	}{
+		{
+			name: "expanded",
+			args: []string{"-path", path, "-duration", "2h", "-entitlement-catalog", catalogPath},
+			expReq: &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+					Resource: "projects/a",
+					Bindings: []*v1alpha1.Binding{
+						{Members: []string{"user:alice@example.com"}, Role: "roles/bigquery.dataViewer"},
+						{Members: []string{"user:alice@example.com"}, Role: "roles/bigquery.jobUser"},
+					},
+				}},
+			},
+		},
+		{
+			name:    "longer_than_requested",
+			args:    []string{"-path", path, "-duration", "3h", "-entitlement-catalog", catalogPath},
+			expCode: ExitCodeValidation,
+			expErr:  `failed to expand entitlements of *v1alpha1.IAMRequest: entitlements[0].duration at line 4, column 13: request is handled with duration 3h0m0s, longer than the requested duration 2h0m0s of entitlement "bq-oncall"`,
+		},
+		{
+			name:    "no_catalog",
+			args:    []string{"-path", path, "-duration", "2h"},
+			expCode: ExitCodeValidation,
+			expErr:  "entitlements must be expanded with an entitlement catalog",
+		},
+		{
+			name:    "invalid_catalog",
+			args:    []string{"-path", path, "-duration", "2h", "-entitlement-catalog", path},
+			expCode: ExitCodeFailure,
+			expErr:  "invalid entitlement catalog",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			h := &fakeIAMHandler{}
+			cmd := IAMHandleCommand{testHandler: h}
+			_, _, _ = cmd.Pipe()
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if got := ExitCode(err); got != tc.expCode {
+				t.Errorf("Process(%+v) got exit code %d, want %d", tc.name, got, tc.expCode)
+			}
+			var gotReq *v1alpha1.IAMRequest
+			if h.gotReq != nil {
+				gotReq = h.gotReq.IAMRequest
+			}
+			if diff := cmp.Diff(tc.expReq, gotReq); diff != "" {
+				t.Errorf("Process(%+v) got request diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/posener/complete/v2/predict"
+
+	"github.com/abcxyz/access-on-demand/pkg/registry"
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+)
+
+var _ cli.Command = (*IAMHistoryCommand)(nil)
+
+// historyStore interface that queries the grant registry.
+type historyStore interface {
+	Query(ctx context.Context, q *registry.Query) ([]*registry.Record, error)
+}
+
+// IAMHistoryCommand shows the grants and cleanups recorded in the grant
+// registry.
+type IAMHistoryCommand struct {
+	cli.BaseCommand
+
+	flagRegistryProject string
+
+	flagRegistryDatabase string
+
+	flagResource string
+
+	flagMember string
+
+	flagLimit int
+
+	flagFormat string
+
+	// testStore is used for testing only.
+	testStore historyStore
+}
+
+func (c *IAMHistoryCommand) Desc() string {
+	return `Show the AOD grants and cleanups recorded in the grant registry`
+}
+
+func (c *IAMHistoryCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Show the most recent AOD grants and cleanups of a resource recorded in the
+grant registry:
+
+      {{ COMMAND }} -registry-project "my-project" -resource "projects/foo"
+
+Show the most recent AOD grants and cleanups of a member in JSON format:
+
+      {{ COMMAND }} -registry-project "my-project" -member "user:alice@example.com" -format "json"
+`
+}
+
+func (c *IAMHistoryCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "registry-project",
+		Target:  &c.flagRegistryProject,
+		Example: "my-project",
+		Usage:   "The project of the Firestore database of the grant registry.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "registry-database",
+		Target:  &c.flagRegistryDatabase,
+		Default: "(default)",
+		Example: "aod",
+		Usage:   "The Firestore database of the grant registry.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "resource",
+		Target:  &c.flagResource,
+		Example: "projects/foo",
+		Usage:   `The organization, folder or project to show the history of.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "member",
+		Target:  &c.flagMember,
+		Example: "user:alice@example.com",
+		Usage:   `The member to show the history of.`,
+	})
+
+	f.IntVar(&cli.IntVar{
+		Name:    "limit",
+		Target:  &c.flagLimit,
+		Default: 100,
+		Example: "20",
+		Usage:   `The max number of the most recent records to show.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "format",
+		Target:  &c.flagFormat,
+		Example: "json",
+		Default: formatTable,
+		Predict: predict.Set{formatTable, formatJSON, formatYAML},
+		Usage:   `The output format, one of "table", "json" and "yaml".`,
+	})
+
+	return set
+}
+
+func (c *IAMHistoryCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagRegistryProject == "" {
+		return fmt.Errorf("registry-project is required")
+	}
+
+	if c.flagResource == "" && c.flagMember == "" {
+		return fmt.Errorf("at least one of resource and member is required")
+	}
+
+	if c.flagLimit <= 0 {
+		return fmt.Errorf("limit must be positive, got %d", c.flagLimit)
+	}
+
+	if err := checkFormat(c.flagFormat, formatTable, formatJSON, formatYAML); err != nil {
+		return err
+	}
+
+	return c.history(ctx)
+}
+
+func (c *IAMHistoryCommand) history(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	var s historyStore
+	if c.testStore != nil {
+		// Use testStore if it is for testing.
+		s = c.testStore
+	} else {
+		store, err := registry.NewFirestoreStore(ctx, c.flagRegistryProject, c.flagRegistryDatabase)
+		if err != nil {
+			return fmt.Errorf("failed to create registry store: %w", err)
+		}
+		s = store
+		defer func() {
+			if err := store.Close(); err != nil {
+				logger.ErrorContext(ctx, "failed to close", "error", err)
+			}
+		}()
+	}
+
+	records, err := s.Query(ctx, &registry.Query{
+		Resource: c.flagResource,
+		Member:   c.flagMember,
+		Limit:    c.flagLimit,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query grant registry: %w", err)
+	}
+
+	switch c.flagFormat {
+	case formatJSON:
+		if err := encodeJSON(c.Stdout(), records); err != nil {
+			return fmt.Errorf("failed to output records: %w", err)
+		}
+	case formatYAML:
+		if err := encodeYaml(c.Stdout(), records); err != nil {
+			return fmt.Errorf("failed to output records: %w", err)
+		}
+	default:
+		if err := printRecordsTable(c.Stdout(), records); err != nil {
+			return fmt.Errorf("failed to output records: %w", err)
+		}
+	}
+	return nil
+}
+
+// printRecordsTable prints the records to w as a table.
+func printRecordsTable(w io.Writer, records []*registry.Record) error {
+	if len(records) == 0 {
+		fmt.Fprintln(w, "No records")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tTYPE\tRESOURCE\tROLE\tMEMBERS\tEXPIRY\tREQUESTER")
+	for _, r := range records {
+		expiry := "-"
+		if r.Expiry != nil {
+			expiry = r.Expiry.Format(time.RFC3339)
+		}
+		requester := "-"
+		if r.Requester != "" {
+			requester = r.Requester
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.Time.Format(time.RFC3339), r.Type, r.Resource, r.Role,
+			strings.Join(r.Members, ","), expiry, requester)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("failed to flush table: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/pkg/registry"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestIAMHistoryCommand(t *testing.T) {
+	t.Parallel()
+
+	grantTime := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	cleanupTime := grantTime.Add(3 * time.Hour)
+	expiry := grantTime.Add(2 * time.Hour)
+	records := []*registry.Record{
+		{
+			Type:     "CLEANUP",
+			Time:     cleanupTime,
+			Resource: "projects/foo",
+			Role:     "roles/viewer",
+			Members:  []string{"user:alice@example.com"},
+		},
+		{
+			Type:        "GRANT",
+			Time:        grantTime,
+			Resource:    "projects/foo",
+			Role:        "roles/viewer",
+			Members:     []string{"user:alice@example.com", "user:bob@example.com"},
+			Expiry:      &expiry,
+			Requester:   "alice@example.com",
+			RequestHash: "abc123",
+		},
+	}
+
+	cases := []struct {
+		name     string
+		args     []string
+		store    *fakeHistoryStore
+		expQuery *registry.Query
+		expOut   string
+		expErr   string
+	}{
+		{
+			name:     "success_table",
+			args:     []string{"-registry-project", "my-project", "-resource", "projects/foo"},
+			store:    &fakeHistoryStore{records: records},
+			expQuery: &registry.Query{Resource: "projects/foo", Limit: 100},
+			expOut: `
+TIME                  TYPE     RESOURCE      ROLE          MEMBERS                                      EXPIRY                REQUESTER
+2009-11-11T02:00:00Z  CLEANUP  projects/foo  roles/viewer  user:alice@example.com                       -                     -
+2009-11-10T23:00:00Z  GRANT    projects/foo  roles/viewer  user:alice@example.com,user:bob@example.com  2009-11-11T01:00:00Z  alice@example.com`,
+		},
+		{
+			name: "success_json",
+			args: []string{
+				"-registry-project", "my-project", "-member", "user:bob@example.com",
+				"-limit", "1", "-format", "json",
+			},
+			store:    &fakeHistoryStore{records: records[1:]},
+			expQuery: &registry.Query{Member: "user:bob@example.com", Limit: 1},
+			expOut: `
+[
+  {
+    "type": "GRANT",
+    "time": "2009-11-10T23:00:00Z",
+    "resource": "projects/foo",
+    "role": "roles/viewer",
+    "members": [
+      "user:alice@example.com",
+      "user:bob@example.com"
+    ],
+    "expiry": "2009-11-11T01:00:00Z",
+    "requester": "alice@example.com",
+    "requestHash": "abc123"
+  }
+]`,
+		},
+		{
+			name: "success_yaml",
+			args: []string{
+				"-registry-project", "my-project", "-resource", "projects/foo",
+				"-member", "user:alice@example.com", "-format", "yaml",
+			},
+			store:    &fakeHistoryStore{records: records[:1]},
+			expQuery: &registry.Query{Resource: "projects/foo", Member: "user:alice@example.com", Limit: 100},
+			expOut: `
+- type: CLEANUP
+  time: 2009-11-11T02:00:00Z
+  resource: projects/foo
+  role: roles/viewer
+  members:
+    - user:alice@example.com`,
+		},
+		{
+			name:     "no_records",
+			args:     []string{"-registry-project", "my-project", "-resource", "projects/foo"},
+			store:    &fakeHistoryStore{},
+			expQuery: &registry.Query{Resource: "projects/foo", Limit: 100},
+			expOut:   "No records",
+		},
+		{
+			name:     "query_failure",
+			args:     []string{"-registry-project", "my-project", "-resource", "projects/foo"},
+			store:    &fakeHistoryStore{injectErr: fmt.Errorf("injected error")},
+			expQuery: &registry.Query{Resource: "projects/foo", Limit: 100},
+			expErr:   "failed to query grant registry: injected error",
+		},
+		{
+			name:   "missing_registry_project",
+			args:   []string{"-resource", "projects/foo"},
+			store:  &fakeHistoryStore{},
+			expErr: "registry-project is required",
+		},
+		{
+			name:   "missing_resource_and_member",
+			args:   []string{"-registry-project", "my-project"},
+			store:  &fakeHistoryStore{},
+			expErr: "at least one of resource and member is required",
+		},
+		{
+			name:   "invalid_limit",
+			args:   []string{"-registry-project", "my-project", "-resource", "projects/foo", "-limit", "0"},
+			store:  &fakeHistoryStore{},
+			expErr: "limit must be positive, got 0",
+		},
+		{
+			name:   "invalid_format",
+			args:   []string{"-registry-project", "my-project", "-resource", "projects/foo", "-format", "text"},
+			store:  &fakeHistoryStore{},
+			expErr: `invalid format "text"`,
+		},
+		{
+			name:   "unexpected_args",
+			args:   []string{"foo"},
+			store:  &fakeHistoryStore{},
+			expErr: `unexpected arguments: ["foo"]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var cmd IAMHistoryCommand
+			cmd.testStore = tc.store
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expQuery, tc.store.gotQuery); diff != "" {
+				t.Errorf("Process(%+v) got query diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+type fakeHistoryStore struct {
+	injectErr error
+	gotQuery  *registry.Query
+	records   []*registry.Record
+}
+
+func (s *fakeHistoryStore) Query(ctx context.Context, q *registry.Query) ([]*registry.Record, error) {
+	s.gotQuery = q
+	return s.records, s.injectErr
+}
@@ -0,0 +1,236 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/posener/complete/v2/predict"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+)
+
+var _ cli.Command = (*IAMListCommand)(nil)
+
+// iamListHandler interface that lists the active AOD grants.
+type iamListHandler interface {
+	List(ctx context.Context, resources []string) ([]*v1alpha1.ActiveGrant, error)
+}
+
+// listedGrant is an active AOD grant with its remaining time to expiry.
+type listedGrant struct {
+	v1alpha1.ActiveGrant `yaml:",inline"`
+
+	// Remaining is the remaining time to expiry, rounded to seconds.
+	Remaining string `json:"remaining" yaml:"remaining"`
+}
+
+// IAMListCommand lists the active AOD IAM bindings of resources.
+type IAMListCommand struct {
+	cli.BaseCommand
+
+	flagResources []string
+
+	flagPath string
+
+	flagFormat string
+
+	iamHandlerFlags iamHandlerFlags
+
+	// testHandler is used for testing only.
+	testHandler iamListHandler
+}
+
+func (c *IAMListCommand) Desc() string {
+	return `List the active AOD IAM bindings of the given resources`
+}
+
+func (c *IAMListCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+List the active AOD IAM bindings of the resources, with the remaining time to
+expiry:
+
+      {{ COMMAND }} -resource "projects/foo" -resource "folders/bar"
+
+List the active AOD IAM bindings of the resources in the IAM request YAML file
+in JSON format:
+
+      {{ COMMAND }} -path "/path/to/file.yaml" -format "json"
+`
+}
+
+func (c *IAMListCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "resource",
+		Target:  &c.flagResources,
+		Example: "projects/foo",
+		Usage:   `The organization, folder or project to list, can be repeated.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "path",
+		Target:  &c.flagPath,
+		Example: "/path/to/file.yaml",
+		Predict: predict.Files("*"),
+		Usage: `The path of IAM request file, in YAML format, the resources ` +
+			`of which to list Use "-" to read it from stdin.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "format",
+		Target:  &c.flagFormat,
+		Example: "json",
+		Default: formatTable,
+		Predict: predict.Set{formatTable, formatJSON, formatYAML},
+		Usage:   `The output format, one of "table", "json" and "yaml".`,
+	})
+
+	c.iamHandlerFlags.register(f)
+
+	return set
+}
+
+func (c *IAMListCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if len(c.flagResources) == 0 && c.flagPath == "" {
+		return fmt.Errorf("at least one of resource and path is required")
+	}
+
+	if err := checkFormat(c.flagFormat, formatTable, formatJSON, formatYAML); err != nil {
+		return err
+	}
+
+	if err := c.iamHandlerFlags.validate(); err != nil {
+		return err
+	}
+
+	return c.list(ctx)
+}
+
+func (c *IAMListCommand) list(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	resources := slices.Clone(c.flagResources)
+	if c.flagPath != "" {
+		docs, err := newRequestReader(c.Stdin()).iamBundle(c.flagPath)
+		if err != nil {
+			return withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &v1alpha1.IAMRequest{}, err))
+		}
+		for _, d := range docs {
+			for _, p := range d.Request.ResourcePolicies {
+				resources = append(resources, p.Resource)
+			}
+		}
+	}
+	slices.Sort(resources)
+	resources = slices.Compact(resources)
+
+	// Validate the resources as an IAMRequest.
+	req := &v1alpha1.IAMRequest{}
+	for _, r := range resources {
+		req.ResourcePolicies = append(req.ResourcePolicies, &v1alpha1.ResourcePolicy{Resource: r})
+	}
+	if err := v1alpha1.ValidateIAMRequest(req, nil); err != nil {
+		return withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate resources: %w", err))
+	}
+
+	var h iamListHandler
+	if c.testHandler != nil {
+		// Use testHandler if it is for testing.
+		h = c.testHandler
+	} else {
+		iamHandler, closer, newHandlerErr := newIAMHandler(ctx, &c.iamHandlerFlags, &c.BaseCommand)
+		if newHandlerErr != nil {
+			return newHandlerErr
+		}
+		h = iamHandler
+		defer func() {
+			if err := closer.Close(); err != nil {
+				logger.ErrorContext(ctx, "failed to close", "error", err)
+			}
+		}()
+	}
+
+	grants, err := h.List(ctx, resources)
+	if err != nil {
+		return withExitCode(ExitCodeAPIFailure, fmt.Errorf("failed to list active AOD grants: %w", err))
+	}
+
+	now := c.iamHandlerFlags.now()
+	listed := make([]*listedGrant, 0, len(grants))
+	for _, g := range grants {
+		listed = append(listed, &listedGrant{
+			ActiveGrant: *g,
+			Remaining:   g.Expiry.Sub(now).Round(time.Second).String(),
+		})
+	}
+
+	switch c.flagFormat {
+	case formatJSON:
+		if err := encodeJSON(c.Stdout(), listed); err != nil {
+			return fmt.Errorf("failed to output active AOD grants: %w", err)
+		}
+	case formatYAML:
+		if err := encodeYaml(c.Stdout(), listed); err != nil {
+			return fmt.Errorf("failed to output active AOD grants: %w", err)
+		}
+	default:
+		if err := printGrantsTable(c.Stdout(), listed); err != nil {
+			return fmt.Errorf("failed to output active AOD grants: %w", err)
+		}
+	}
+	return nil
+}
+
+// printGrantsTable prints the grants to w as a table.
+func printGrantsTable(w io.Writer, grants []*listedGrant) error {
+	if len(grants) == 0 {
+		fmt.Fprintln(w, "No active AOD grants")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RESOURCE\tROLE\tMEMBER\tEXPIRY\tREMAINING")
+	for _, g := range grants {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			g.Resource, g.Role, g.Member, g.Expiry.Format(time.RFC3339), g.Remaining)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("failed to flush table: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestIAMListCommand(t *testing.T) {
+	t.Parallel()
+
+	// Set up IAM request file.
+	requestFileContentByName := map[string]string{
+		"valid.yaml": `
+policies:
+- resource: folders/bar
+  bindings:
+    - members:
+      - user:test-folder-user@example.com
+      role: roles/cloudkms.cryptoOperator
+`,
+		"invalid.yaml": `bananas`,
+	}
+	dir := t.TempDir()
+	for name, content := range requestFileContentByName {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := "2009-11-10T23:00:00Z"
+	expiry := time.Date(2009, 11, 11, 0, 30, 0, 0, time.UTC)
+	grants := []*v1alpha1.ActiveGrant{
+		{Resource: "folders/bar", Role: "roles/cloudkms.cryptoOperator", Member: "user:alice@example.com", Expiry: expiry},
+		{Resource: "projects/baz", Role: "roles/viewer", Member: "user:bob@example.com", Expiry: expiry},
+	}
+
+	cases := []struct {
+		name         string
+		args         []string
+		handler      *fakeIAMListHandler
+		expResources []string
+		expOut       string
+		expErr       string
+	}{
+		{
+			name:         "success_table",
+			args:         []string{"-resource", "projects/baz", "-path", filepath.Join(dir, "valid.yaml"), "-now", now},
+			handler:      &fakeIAMListHandler{grants: grants},
+			expResources: []string{"folders/bar", "projects/baz"},
+			expOut: `
+RESOURCE      ROLE                           MEMBER                  EXPIRY                REMAINING
+folders/bar   roles/cloudkms.cryptoOperator  user:alice@example.com  2009-11-11T00:30:00Z  1h30m0s
+projects/baz  roles/viewer                   user:bob@example.com    2009-11-11T00:30:00Z  1h30m0s`,
+		},
+		{
+			name:         "success_json",
+			args:         []string{"-resource", "projects/baz", "-format", "json", "-now", now},
+			handler:      &fakeIAMListHandler{grants: grants[1:]},
+			expResources: []string{"projects/baz"},
+			expOut: `
+[
+  {
+    "resource": "projects/baz",
+    "role": "roles/viewer",
+    "member": "user:bob@example.com",
+    "expiry": "2009-11-11T00:30:00Z",
+    "remaining": "1h30m0s"
+  }
+]`,
+		},
+		{
+			name:         "success_yaml",
+			args:         []string{"-resource", "projects/baz", "-format", "yaml", "-now", now},
+			handler:      &fakeIAMListHandler{grants: grants[1:]},
+			expResources: []string{"projects/baz"},
+			expOut: `
+- resource: projects/baz
+  role: roles/viewer
+  member: user:bob@example.com
+  expiry: 2009-11-11T00:30:00Z
+  remaining: 1h30m0s`,
+		},
+		{
+			name:         "no_grants",
+			args:         []string{"-resource", "projects/baz"},
+			handler:      &fakeIAMListHandler{},
+			expResources: []string{"projects/baz"},
+			expOut:       "No active AOD grants",
+		},
+		{
+			name: "handler_failure",
+			args: []string{"-resource", "projects/baz"},
+			handler: &fakeIAMListHandler{
+				injectErr: fmt.Errorf("injected error"),
+			},
+			expResources: []string{"projects/baz"},
+			expErr:       "failed to list active AOD grants: injected error",
+		},
+		{
+			name:    "invalid_resource",
+			args:    []string{"-resource", "foo/bar"},
+			handler: &fakeIAMListHandler{},
+			expErr:  `resource "foo/bar" isn't one of [organizations, folders, projects, buckets, secrets, serviceAccounts, bigquery/datasets, kms/keyrings, //<service>]`,
+		},
+		{
+			name:    "invalid_yaml",
+			args:    []string{"-path", filepath.Join(dir, "invalid.yaml")},
+			handler: &fakeIAMListHandler{},
+			expErr:  "failed to read *v1alpha1.IAMRequest",
+		},
+		{
+			name:    "invalid_format",
+			args:    []string{"-resource", "projects/baz", "-format", "text"},
+			handler: &fakeIAMListHandler{},
+			expErr:  `invalid format "text"`,
+		},
+		{
+			name:    "missing_resources",
+			args:    []string{},
+			handler: &fakeIAMListHandler{},
+			expErr:  "at least one of resource and path is required",
+		},
+		{
+			name:    "unexpected_args",
+			args:    []string{"foo"},
+			handler: &fakeIAMListHandler{},
+			expErr:  `unexpected arguments: ["foo"]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var cmd IAMListCommand
+			cmd.testHandler = tc.handler
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expResources, tc.handler.gotResources); diff != "" {
+				t.Errorf("Process(%+v) got resources diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+type fakeIAMListHandler struct {
+	injectErr    error
+	gotResources []string
+	grants       []*v1alpha1.ActiveGrant
+}
+
+func (h *fakeIAMListHandler) List(ctx context.Context, resources []string) ([]*v1alpha1.ActiveGrant, error) {
+	h.gotResources = resources
+	return h.grants, h.injectErr
+}
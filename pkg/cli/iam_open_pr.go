@@ -0,0 +1,309 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/posener/complete/v2/predict"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/github"
+	"github.com/abcxyz/access-on-demand/pkg/messages"
+	"github.com/abcxyz/access-on-demand/pkg/requestutil"
+	"github.com/abcxyz/pkg/cli"
+)
+
+var _ cli.Command = (*IAMOpenPRCommand)(nil)
+
+// prOpener interface that opens pull requests.
+type prOpener interface {
+	Open(ctx context.Context, pr *github.PullRequest) (*github.Result, error)
+}
+
+// IAMOpenPRCommand opens a GitHub pull request adding an IAM request file.
+type IAMOpenPRCommand struct {
+	cli.BaseCommand
+
+	flagPath string
+
+	flagRepo string
+
+	flagRepoPath string
+
+	flagBase string
+
+	flagBranch string
+
+	flagTitle string
+
+	flagBody string
+
+	flagLabels []string
+
+	flagReviewers []string
+
+	flagDuration time.Duration
+
+	// testOpener is used for testing only.
+	testOpener prOpener
+}
+
+func (c *IAMOpenPRCommand) Desc() string {
+	return `Open a GitHub pull request adding an IAM request YAML file`
+}
+
+func (c *IAMOpenPRCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Open a pull request adding the IAM request file to the repository:
+
+      {{ COMMAND }} -path "iam.yaml" -repo "my-org/aod-requests" -repo-path "requests/iam.yaml"
+
+Open a pull request with labels and reviewers:
+
+      {{ COMMAND }} -path "iam.yaml" -repo "my-org/aod-requests" -label "aod" -reviewer "my-org/admins"
+`
+}
+
+func (c *IAMOpenPRCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "path",
+		Target:  &c.flagPath,
+		Example: "/path/to/file.yaml",
+		Predict: predict.Files("*"),
+		Usage:   `The path of IAM request file, in YAML format. Use "-" to read it from stdin.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "repo",
+		Target:  &c.flagRepo,
+		Example: "my-org/aod-requests",
+		Usage: `The GitHub repository, in the format of "owner/repo", to open ` +
+			`the pull request in. The token is read from GITHUB_TOKEN.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "repo-path",
+		Target:  &c.flagRepoPath,
+		Example: "requests/iam.yaml",
+		Usage: `The path in the repository to commit the request file to, ` +
+			`default is the base name of path.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "base",
+		Target:  &c.flagBase,
+		Example: "main",
+		Usage: `The branch to merge the pull request into, default is the ` +
+			`default branch of the repository.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "branch",
+		Target:  &c.flagBranch,
+		Example: "aod-request-alice",
+		Usage: `The branch to create for the pull request, default is ` +
+			`"aod-request-" followed by a hash of the request file.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "title",
+		Target:  &c.flagTitle,
+		Default: github.DefaultTitleTemplate,
+		Usage: `The template of the pull request title, in the Go ` +
+			`text/template format with the fields Path, Resources, Members, ` +
+			`Roles and Duration.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "body",
+		Target:  &c.flagBody,
+		Default: github.DefaultBodyTemplate,
+		Usage: `The template of the pull request body, in the same format ` +
+			`as title.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "label",
+		Target:  &c.flagLabels,
+		Example: "aod",
+		Usage:   `The label to add to the pull request, can be repeated.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "reviewer",
+		Target:  &c.flagReviewers,
+		Example: "my-org/admins",
+		Usage: `The user, or team in the format of "org/team", to request ` +
+			`review from, can be repeated.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "duration",
+		Target:  &c.flagDuration,
+		Example: "2h",
+		Usage:   `The duration to handle the request with, for the templates.`,
+	})
+
+	return set
+}
+
+func (c *IAMOpenPRCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagPath == "" {
+		return fmt.Errorf("path is required")
+	}
+
+	if c.flagPath == stdinPath && c.flagRepoPath == "" {
+		return fmt.Errorf("repo-path is required when path is stdin")
+	}
+
+	if c.flagRepo == "" {
+		return fmt.Errorf("repo is required")
+	}
+	if err := github.ValidateRepo(c.flagRepo); err != nil {
+		return fmt.Errorf("invalid repo: %w", err)
+	}
+
+	if c.flagDuration < 0 {
+		return fmt.Errorf("duration must not be negative")
+	}
+
+	return c.open(ctx)
+}
+
+func (c *IAMOpenPRCommand) open(ctx context.Context) error {
+	data, err := newRequestReader(c.Stdin()).readFile(c.flagPath)
+	if err != nil {
+		return err
+	}
+	name := c.flagPath
+	if name == stdinPath {
+		name = stdinName
+	}
+	docs, err := requestutil.ReadBundle[v1alpha1.IAMRequest](name, data)
+	if err != nil {
+		return withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &v1alpha1.IAMRequest{}, err))
+	}
+	req, err := requestutil.ValidateIAMBundle(docs, nil)
+	if err != nil {
+		return withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", req, err))
+	}
+
+	repoPath := c.flagRepoPath
+	if repoPath == "" {
+		repoPath = filepath.Base(c.flagPath)
+	}
+	branch := c.flagBranch
+	if branch == "" {
+		sum := sha256.Sum256(data)
+		branch = "aod-request-" + hex.EncodeToString(sum[:])[:12]
+	}
+
+	td := prTemplateData(repoPath, req, c.flagDuration)
+	title, err := github.Render(c.flagTitle, td)
+	if err != nil {
+		return fmt.Errorf("failed to render title: %w", err)
+	}
+	body, err := github.Render(c.flagBody, td)
+	if err != nil {
+		return fmt.Errorf("failed to render body: %w", err)
+	}
+
+	opener := c.testOpener
+	if opener == nil {
+		apiURL := c.GetEnv("GITHUB_API_URL")
+		if apiURL == "" {
+			apiURL = github.DefaultAPIURL
+		}
+		o, err := github.NewPROpener(&http.Client{Timeout: 30 * time.Second}, apiURL, c.flagRepo, c.GetEnv("GITHUB_TOKEN"))
+		if err != nil {
+			return fmt.Errorf("failed to create pull request opener: %w", err)
+		}
+		opener = o
+	}
+
+	result, err := opener.Open(ctx, &github.PullRequest{
+		Branch:        branch,
+		Base:          c.flagBase,
+		Path:          repoPath,
+		Content:       data,
+		CommitMessage: title,
+		Title:         title,
+		Body:          body,
+		Labels:        c.flagLabels,
+		Reviewers:     c.flagReviewers,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open pull request: %w", err)
+	}
+
+	printMessageHeader(ctx, &c.BaseCommand, messages.HeaderOpenedPR)
+	if err := encodeYaml(c.Stdout(), map[string]any{
+		"branch": branch,
+		"number": result.Number,
+		"path":   repoPath,
+		"url":    result.URL,
+	}); err != nil {
+		return fmt.Errorf("failed to output pull request: %w", err)
+	}
+	return nil
+}
+
+// prTemplateData returns the template data of the IAM request committed to the
+// path in the repository.
+func prTemplateData(path string, req *v1alpha1.IAMRequest, duration time.Duration) *github.TemplateData {
+	td := &github.TemplateData{Path: path}
+	for _, p := range req.ResourcePolicies {
+		td.Resources = append(td.Resources, p.Resource)
+		for _, b := range p.Bindings {
+			td.Members = append(td.Members, b.Members...)
+			if b.Role != "" {
+				td.Roles = append(td.Roles, b.Role)
+			}
+			if b.RoleBundle != "" {
+				td.Roles = append(td.Roles, b.RoleBundle)
+			}
+		}
+	}
+	td.Resources = dedupe(td.Resources)
+	td.Members = dedupe(td.Members)
+	td.Roles = dedupe(td.Roles)
+	if duration > 0 {
+		td.Duration = duration.String()
+	}
+	return td
+}
@@ -0,0 +1,227 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/pkg/github"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestIAMOpenPRCommand(t *testing.T) {
+	t.Parallel()
+
+	validFile := `
+policies:
+  - resource: projects/foo
+    bindings:
+      - members:
+          - user:alice@example.com
+        role: roles/run.developer
+      - members:
+          - user:alice@example.com
+          - user:bob@example.com
+        roleBundle: gcs-read
+`
+
+	cases := []struct {
+		name   string
+		file   string
+		args   []string
+		opener *fakePROpener
+		expPR  *github.PullRequest
+		expOut string
+		expErr string
+	}{
+		{
+			name:   "success",
+			file:   validFile,
+			args:   []string{"-path", "{{path}}", "-repo", "foo/bar", "-duration", "2h"},
+			opener: &fakePROpener{},
+			expPR: &github.PullRequest{
+				Branch:        "aod-request-a3dbac0207f3",
+				Path:          "iam.yaml",
+				Content:       []byte(validFile),
+				CommitMessage: "Request access to projects/foo",
+				Title:         "Request access to projects/foo",
+				Body: "Request access for user:alice@example.com, user:bob@example.com.\n\n" +
+					"- Resources: projects/foo\n" +
+					"- Roles: roles/run.developer, gcs-read\n" +
+					"- Duration: 2h0m0s\n" +
+					"- Request file: `iam.yaml`\n",
+			},
+			expOut: `
+------Successfully Opened Pull Request------
+branch: aod-request-a3dbac0207f3
+number: 7
+path: iam.yaml
+url: https://github.com/foo/bar/pull/7`,
+		},
+		{
+			name: "success_options",
+			file: validFile,
+			args: []string{
+				"-path", "{{path}}", "-repo", "foo/bar", "-repo-path", "requests/alice.yaml",
+				"-base", "release", "-branch", "alice", "-title", "AOD: {{ .Path }}", "-body", "{{ len .Members }} members",
+				"-label", "aod", "-label", "iam", "-reviewer", "carol", "-reviewer", "foo/admins",
+			},
+			opener: &fakePROpener{},
+			expPR: &github.PullRequest{
+				Branch:        "alice",
+				Base:          "release",
+				Path:          "requests/alice.yaml",
+				Content:       []byte(validFile),
+				CommitMessage: "AOD: requests/alice.yaml",
+				Title:         "AOD: requests/alice.yaml",
+				Body:          "2 members",
+				Labels:        []string{"aod", "iam"},
+				Reviewers:     []string{"carol", "foo/admins"},
+			},
+			expOut: `
+------Successfully Opened Pull Request------
+branch: alice
+number: 7
+path: requests/alice.yaml
+url: https://github.com/foo/bar/pull/7`,
+		},
+		{
+			name: "opener_failure",
+			file: validFile,
+			args: []string{"-path", "{{path}}", "-repo", "foo/bar", "-branch", "alice"},
+			opener: &fakePROpener{
+				injectErr: fmt.Errorf("injected error"),
+			},
+			expPR: &github.PullRequest{
+				Branch:        "alice",
+				Path:          "iam.yaml",
+				Content:       []byte(validFile),
+				CommitMessage: "Request access to projects/foo",
+				Title:         "Request access to projects/foo",
+				Body: "Request access for user:alice@example.com, user:bob@example.com.\n\n" +
+					"- Resources: projects/foo\n" +
+					"- Roles: roles/run.developer, gcs-read\n" +
+					"- Request file: `iam.yaml`\n",
+			},
+			expErr: "failed to open pull request: injected error",
+		},
+		{
+			name: "invalid_request",
+			file: `
+policies:
+  - resource: projects/foo
+    bindings:
+      - members:
+          - group:eng@example.com
+        role: roles/run.developer
+`,
+			args:   []string{"-path", "{{path}}", "-repo", "foo/bar"},
+			opener: &fakePROpener{},
+			expErr: `member "group:eng@example.com" is not of "user" type`,
+		},
+		{
+			name:   "invalid_title",
+			file:   validFile,
+			args:   []string{"-path", "{{path}}", "-repo", "foo/bar", "-title", "{{ .Bananas }}"},
+			opener: &fakePROpener{},
+			expErr: "failed to render title",
+		},
+		{
+			name:   "missing_file",
+			args:   []string{"-path", "{{path}}", "-repo", "foo/bar"},
+			opener: &fakePROpener{},
+			expErr: "failed to read file",
+		},
+		{
+			name:   "missing_path",
+			args:   []string{"-repo", "foo/bar"},
+			opener: &fakePROpener{},
+			expErr: "path is required",
+		},
+		{
+			name:   "missing_repo",
+			args:   []string{"-path", "{{path}}"},
+			opener: &fakePROpener{},
+			expErr: "repo is required",
+		},
+		{
+			name:   "invalid_repo",
+			args:   []string{"-path", "{{path}}", "-repo", "foo"},
+			opener: &fakePROpener{},
+			expErr: `invalid repo: repo "foo" isn't in the format of "owner/repo"`,
+		},
+		{
+			name:   "unexpected_args",
+			args:   []string{"foo"},
+			opener: &fakePROpener{},
+			expErr: `unexpected arguments: ["foo"]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			path := filepath.Join(t.TempDir(), "iam.yaml")
+			if tc.file != "" {
+				if err := os.WriteFile(path, []byte(tc.file), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			args := make([]string, 0, len(tc.args))
+			for _, a := range tc.args {
+				args = append(args, strings.ReplaceAll(a, "{{path}}", path))
+			}
+
+			var cmd IAMOpenPRCommand
+			cmd.testOpener = tc.opener
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expPR, tc.opener.gotPR); diff != "" {
+				t.Errorf("Process(%+v) got pull request diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+type fakePROpener struct {
+	injectErr error
+	gotPR     *github.PullRequest
+}
+
+func (o *fakePROpener) Open(ctx context.Context, pr *github.PullRequest) (*github.Result, error) {
+	o.gotPR = pr
+	if o.injectErr != nil {
+		return nil, o.injectErr
+	}
+	return &github.Result{Number: 7, URL: "https://github.com/foo/bar/pull/7"}, nil
+}
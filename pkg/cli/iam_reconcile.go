@@ -0,0 +1,333 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/messages"
+	"github.com/abcxyz/access-on-demand/pkg/registry"
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+)
+
+var _ cli.Command = (*IAMReconcileCommand)(nil)
+
+// reconcileSource is the source of the IAM requests re-applying missing
+// grants, recorded in their audit events.
+const reconcileSource = "aod-reconcile"
+
+// iamReconcileHandler interface that lists, re-applies and sweeps AOD grants.
+type iamReconcileHandler interface {
+	Do(ctx context.Context, r *v1alpha1.IAMRequestWrapper) ([]*v1alpha1.IAMResponse, error)
+	List(ctx context.Context, resources []string) ([]*v1alpha1.ActiveGrant, error)
+	Sweep(ctx context.Context, resources []string) ([]*v1alpha1.IAMResponse, error)
+}
+
+// reconcileResult is the output of a reconciliation.
+type reconcileResult struct {
+	registry.Drift `yaml:",inline"`
+
+	// Reapplied is the number of missing grants re-applied.
+	Reapplied int `yaml:"reapplied"`
+
+	// Swept are the resources expired AOD bindings were removed from.
+	Swept []string `yaml:"swept"`
+}
+
+// IAMReconcileCommand compares the grants recorded in the grant registry with
+// the live IAM policies and converges them.
+type IAMReconcileCommand struct {
+	cli.BaseCommand
+
+	flagResources []string
+
+	flagInterval time.Duration
+
+	flagDryRun bool
+
+	iamHandlerFlags iamHandlerFlags
+
+	// testHandler is used for testing only.
+	testHandler iamReconcileHandler
+
+	// testStore is used for testing only.
+	testStore historyStore
+}
+
+func (c *IAMReconcileCommand) Desc() string {
+	return `Re-apply the unexpired AOD grants in the grant registry missing from the IAM policies and remove expired AOD bindings`
+}
+
+func (c *IAMReconcileCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Re-apply the unexpired grants recorded in the grant registry which are missing
+from the IAM policies of the resources, and remove the expired AOD IAM bindings
+from them:
+
+      {{ COMMAND }} -registry-project "my-project" -resource "projects/foo"
+
+Show the drift of the IAM policies from the grant registry without updating
+them:
+
+      {{ COMMAND }} -registry-project "my-project" -resource "projects/foo" -dry-run
+
+Reconcile the resources every 10 minutes until the command is stopped:
+
+      {{ COMMAND }} -registry-project "my-project" -resource "projects/foo" -resource "folders/456" -interval "10m"
+
+Active AOD IAM bindings which are not recorded in the grant registry are
+reported as untracked and kept.
+`
+}
+
+func (c *IAMReconcileCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "resource",
+		Target:  &c.flagResources,
+		Example: "projects/foo",
+		Usage:   `The organization, folder or project to reconcile, can be repeated.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "interval",
+		Target:  &c.flagInterval,
+		Example: "10m",
+		Usage: `The interval to reconcile the resources at until the command ` +
+			`is stopped. Failures of a reconciliation are logged and retried ` +
+			`at the next interval. Default is to reconcile once.`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "dry-run",
+		Target:  &c.flagDryRun,
+		Default: false,
+		Usage:   `Only show the drift of the IAM policies without updating them.`,
+	})
+
+	c.iamHandlerFlags.register(f)
+
+	return set
+}
+
+func (c *IAMReconcileCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if len(c.flagResources) == 0 {
+		return fmt.Errorf("resource is required")
+	}
+
+	if c.iamHandlerFlags.flagRegistryProject == "" {
+		return fmt.Errorf("registry-project is required")
+	}
+
+	if c.flagInterval < 0 {
+		return fmt.Errorf("interval must not be negative, got %s", c.flagInterval)
+	}
+
+	if err := c.iamHandlerFlags.validate(); err != nil {
+		return err
+	}
+
+	return c.run(ctx)
+}
+
+func (c *IAMReconcileCommand) run(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	// Validate the resources as an IAMRequest.
+	req := &v1alpha1.IAMRequest{}
+	for _, r := range c.flagResources {
+		req.ResourcePolicies = append(req.ResourcePolicies, &v1alpha1.ResourcePolicy{Resource: r})
+	}
+	if err := v1alpha1.ValidateIAMRequest(req, nil); err != nil {
+		return withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate resources: %w", err))
+	}
+	resources := dedupe(c.flagResources)
+
+	var s historyStore
+	if c.testStore != nil {
+		// Use testStore if it is for testing.
+		s = c.testStore
+	} else {
+		store, err := registry.NewFirestoreStore(ctx, c.iamHandlerFlags.flagRegistryProject, c.iamHandlerFlags.flagRegistryDatabase)
+		if err != nil {
+			return fmt.Errorf("failed to create registry store: %w", err)
+		}
+		s = store
+		defer func() {
+			if err := store.Close(); err != nil {
+				logger.ErrorContext(ctx, "failed to close", "error", err)
+			}
+		}()
+	}
+
+	var h iamReconcileHandler
+	if c.testHandler != nil {
+		// Use testHandler if it is for testing.
+		h = c.testHandler
+	} else {
+		iamHandler, closer, newHandlerErr := newIAMHandler(ctx, &c.iamHandlerFlags, &c.BaseCommand)
+		if newHandlerErr != nil {
+			return newHandlerErr
+		}
+		h = iamHandler
+		defer func() {
+			if err := closer.Close(); err != nil {
+				logger.ErrorContext(ctx, "failed to close", "error", err)
+			}
+		}()
+	}
+
+	if c.flagInterval == 0 {
+		return c.reconcile(ctx, s, h, resources)
+	}
+
+	ticker := time.NewTicker(c.flagInterval)
+	defer ticker.Stop()
+	for {
+		if err := c.reconcile(ctx, s, h, resources); err != nil {
+			logger.ErrorContext(ctx, "failed to reconcile", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// reconcile reconciles the resources once and outputs the result.
+func (c *IAMReconcileCommand) reconcile(ctx context.Context, s historyStore, h iamReconcileHandler, resources []string) error {
+	now := c.iamHandlerFlags.now()
+
+	var records []*registry.Record
+	for _, r := range resources {
+		rs, err := s.Query(ctx, &registry.Query{Resource: r})
+		if err != nil {
+			return fmt.Errorf("failed to query grant registry of %s: %w", r, err)
+		}
+		records = append(records, rs...)
+	}
+	live, err := h.List(ctx, resources)
+	if err != nil {
+		return withExitCode(ExitCodeAPIFailure, fmt.Errorf("failed to list active AOD grants: %w", err))
+	}
+
+	result := &reconcileResult{Drift: *registry.Compare(registry.ActiveGrants(records, now), live)}
+	if !c.flagDryRun {
+		var retErr error
+		for _, reqWrapper := range reapplyRequests(result.Missing, now) {
+			if _, err := h.Do(ctx, reqWrapper); err != nil {
+				retErr = errors.Join(retErr, fmt.Errorf("failed to re-apply missing grants: %w", err))
+				continue
+			}
+			for _, p := range reqWrapper.ResourcePolicies {
+				for _, b := range p.Bindings {
+					result.Reapplied += len(b.Members)
+				}
+			}
+		}
+
+		resp, err := h.Sweep(ctx, resources)
+		if err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("failed to sweep expired AOD bindings: %w", err))
+		}
+		for _, r := range resp {
+			if r.Skipped == "" {
+				result.Swept = append(result.Swept, r.Resource)
+			}
+		}
+		if retErr != nil {
+			return withExitCode(ExitCodeAPIFailure, retErr)
+		}
+	}
+
+	printMessageHeader(ctx, &c.BaseCommand, messages.HeaderReconciled)
+	if err := encodeYaml(c.Stdout(), result); err != nil {
+		return fmt.Errorf("failed to output reconciliation: %w", err)
+	}
+	return nil
+}
+
+// reapplyRequests returns the IAM requests re-applying the grants, one per
+// expiry, starting at now.
+func reapplyRequests(grants []*v1alpha1.ActiveGrant, now time.Time) []*v1alpha1.IAMRequestWrapper {
+	var expiries []time.Time
+	byExpiry := make(map[time.Time]map[string]map[string][]string)
+	for _, g := range grants {
+		exp := g.Expiry.UTC()
+		if _, ok := byExpiry[exp]; !ok {
+			expiries = append(expiries, exp)
+			byExpiry[exp] = make(map[string]map[string][]string)
+		}
+		if _, ok := byExpiry[exp][g.Resource]; !ok {
+			byExpiry[exp][g.Resource] = make(map[string][]string)
+		}
+		byExpiry[exp][g.Resource][g.Role] = append(byExpiry[exp][g.Resource][g.Role], g.Member)
+	}
+	slices.SortFunc(expiries, func(a, b time.Time) int { return a.Compare(b) })
+
+	result := make([]*v1alpha1.IAMRequestWrapper, 0, len(expiries))
+	for _, exp := range expiries {
+		req := &v1alpha1.IAMRequest{}
+		for _, resource := range sortedKeys(byExpiry[exp]) {
+			p := &v1alpha1.ResourcePolicy{Resource: resource}
+			for _, role := range sortedKeys(byExpiry[exp][resource]) {
+				p.Bindings = append(p.Bindings, &v1alpha1.Binding{
+					Members: byExpiry[exp][resource][role],
+					Role:    role,
+				})
+			}
+			req.ResourcePolicies = append(req.ResourcePolicies, p)
+		}
+		result = append(result, &v1alpha1.IAMRequestWrapper{
+			IAMRequest: req,
+			Duration:   exp.Sub(now),
+			StartTime:  now,
+			Source:     reconcileSource,
+		})
+	}
+	return result
+}
+
+// sortedKeys returns the sorted keys of the map.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
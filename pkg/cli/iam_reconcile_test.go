@@ -0,0 +1,198 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/registry"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestIAMReconcileCommand(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	expiry := now.Add(2 * time.Hour)
+	records := []*registry.Record{
+		{
+			Type:     "GRANT",
+			Time:     now.Add(-time.Hour),
+			Resource: "projects/foo",
+			Role:     "roles/viewer",
+			Members:  []string{"user:alice@example.com", "user:bob@example.com"},
+			Expiry:   &expiry,
+		},
+	}
+	live := []*v1alpha1.ActiveGrant{
+		{Resource: "projects/foo", Role: "roles/viewer", Member: "user:alice@example.com", Expiry: expiry},
+		{Resource: "projects/foo", Role: "roles/editor", Member: "user:carol@example.com", Expiry: expiry},
+	}
+	baseArgs := []string{"-registry-project", "my-project", "-resource", "projects/foo", "-now", now.Format(time.RFC3339)}
+
+	cases := []struct {
+		name    string
+		args    []string
+		store   *fakeHistoryStore
+		handler *fakeIAMReconcileHandler
+		expReqs []*v1alpha1.IAMRequestWrapper
+		expOut  string
+		expErr  string
+	}{
+		{
+			name:  "success",
+			args:  baseArgs,
+			store: &fakeHistoryStore{records: records},
+			handler: &fakeIAMReconcileHandler{
+				grants:    live,
+				sweepResp: []*v1alpha1.IAMResponse{{Resource: "projects/foo"}},
+			},
+			expReqs: []*v1alpha1.IAMRequestWrapper{{
+				IAMRequest: &v1alpha1.IAMRequest{
+					ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+						Resource: "projects/foo",
+						Bindings: []*v1alpha1.Binding{{
+							Members: []string{"user:bob@example.com"},
+							Role:    "roles/viewer",
+						}},
+					}},
+				},
+				Duration:  2 * time.Hour,
+				StartTime: now,
+				Source:    "aod-reconcile",
+			}},
+			expOut: `
+------Successfully Reconciled AOD Bindings------
+missing:
+  - resource: projects/foo
+    role: roles/viewer
+    member: user:bob@example.com
+    expiry: 2009-11-11T01:00:00Z
+untracked:
+  - resource: projects/foo
+    role: roles/editor
+    member: user:carol@example.com
+    expiry: 2009-11-11T01:00:00Z
+reapplied: 1
+swept:
+  - projects/foo`,
+		},
+		{
+			name:    "dry_run",
+			args:    append([]string{"-dry-run"}, baseArgs...),
+			store:   &fakeHistoryStore{records: records},
+			handler: &fakeIAMReconcileHandler{grants: live[:1]},
+			expOut: `
+------Successfully Reconciled AOD Bindings------
+missing:
+  - resource: projects/foo
+    role: roles/viewer
+    member: user:bob@example.com
+    expiry: 2009-11-11T01:00:00Z
+reapplied: 0
+swept: []`,
+		},
+		{
+			name:    "reapply_failure",
+			args:    baseArgs,
+			store:   &fakeHistoryStore{records: records},
+			handler: &fakeIAMReconcileHandler{doErr: fmt.Errorf("injected error")},
+			expErr:  "failed to re-apply missing grants: injected error",
+		},
+		{
+			name:    "query_failure",
+			args:    baseArgs,
+			store:   &fakeHistoryStore{injectErr: fmt.Errorf("injected error")},
+			handler: &fakeIAMReconcileHandler{},
+			expErr:  "failed to query grant registry of projects/foo: injected error",
+		},
+		{
+			name:   "missing_registry_project",
+			args:   []string{"-resource", "projects/foo"},
+			expErr: "registry-project is required",
+		},
+		{
+			name:   "missing_resource",
+			args:   []string{"-registry-project", "my-project"},
+			expErr: "resource is required",
+		},
+		{
+			name:   "negative_interval",
+			args:   append([]string{"-interval", "-1m"}, baseArgs...),
+			expErr: "interval must not be negative, got -1m0s",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var cmd IAMReconcileCommand
+			if tc.store != nil {
+				cmd.testStore = tc.store
+			}
+			if tc.handler != nil {
+				cmd.testHandler = tc.handler
+			}
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if tc.handler != nil {
+				if diff := cmp.Diff(tc.expReqs, tc.handler.gotReqs); diff != "" {
+					t.Errorf("Process(%+v) got requests diff (-want, +got):\n%s", tc.name, diff)
+				}
+			}
+		})
+	}
+}
+
+type fakeIAMReconcileHandler struct {
+	grants    []*v1alpha1.ActiveGrant
+	sweepResp []*v1alpha1.IAMResponse
+	doErr     error
+	gotReqs   []*v1alpha1.IAMRequestWrapper
+}
+
+func (h *fakeIAMReconcileHandler) Do(ctx context.Context, req *v1alpha1.IAMRequestWrapper) ([]*v1alpha1.IAMResponse, error) {
+	if h.doErr != nil {
+		return nil, h.doErr
+	}
+	h.gotReqs = append(h.gotReqs, req)
+	return nil, nil
+}
+
+func (h *fakeIAMReconcileHandler) List(ctx context.Context, resources []string) ([]*v1alpha1.ActiveGrant, error) {
+	return h.grants, nil
+}
+
+func (h *fakeIAMReconcileHandler) Sweep(ctx context.Context, resources []string) ([]*v1alpha1.IAMResponse, error) {
+	return h.sweepResp, nil
+}
@@ -0,0 +1,249 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/posener/complete/v2/predict"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/handler"
+	"github.com/abcxyz/access-on-demand/pkg/messages"
+	"github.com/abcxyz/access-on-demand/pkg/requestutil"
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+)
+
+var _ cli.Command = (*IAMRenewCommand)(nil)
+
+// iamRenewHandler interface that renews the IAMRequestWrapper.
+type iamRenewHandler interface {
+	Renew(context.Context, *v1alpha1.IAMRequestWrapper) ([]*v1alpha1.IAMResponse, error)
+}
+
+// IAMRenewCommand extends the expiry of existing AOD IAM bindings.
+type IAMRenewCommand struct {
+	cli.BaseCommand
+
+	flagPath string
+
+	flagDuration time.Duration
+
+	flagVerbose bool
+
+	flagPreflight bool
+
+	iamHandlerFlags iamHandlerFlags
+
+	provenanceFlags provenanceFlags
+
+	policyFlags policyFlags
+
+	identityFlags identityFlags
+
+	entitlementFlags entitlementFlags
+
+	// testHandler is used for testing only.
+	testHandler iamRenewHandler
+}
+
+func (c *IAMRenewCommand) Desc() string {
+	return `Extend the expiry of existing AOD IAM bindings in the IAM request YAML file in the given path`
+}
+
+func (c *IAMRenewCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Extend the expiry of the active AOD IAM bindings of the members and roles in the
+IAM request YAML file to 2 hours from now:
+
+      {{ COMMAND }} -path "/path/to/file.yaml" -duration "2h"
+
+Members without active AOD IAM bindings are not granted and are reported as
+errors.
+`
+}
+
+func (c *IAMRenewCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "path",
+		Target:  &c.flagPath,
+		Example: "/path/to/file.yaml",
+		Predict: predict.Files("*"),
+		Usage:   bundlePathUsage,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "duration",
+		Target:  &c.flagDuration,
+		Example: "2h",
+		Usage:   `The IAM permission lifecycle from now, as a duration.`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "verbose",
+		Target:  &c.flagVerbose,
+		Default: false,
+		Usage:   `Turn on verbose mode to print updated IAM policies. Note that it may contain sensitive information`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "preflight",
+		Target:  &c.flagPreflight,
+		Default: false,
+		Usage: `Check that the IAM policies of all the resources can be read ` +
+			`and set before modifying any of them, and fail without ` +
+			`modifying any if some resources are inaccessible.`,
+	})
+
+	c.iamHandlerFlags.register(f)
+	c.provenanceFlags.register(f)
+	c.policyFlags.register(f)
+	c.identityFlags.register(f)
+	c.entitlementFlags.register(f)
+
+	return set
+}
+
+func (c *IAMRenewCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagPath == "" {
+		return fmt.Errorf("path is required")
+	}
+
+	if err := c.iamHandlerFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.policyFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.identityFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.entitlementFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.provenanceFlags.validate(); err != nil {
+		return err
+	}
+
+	if c.flagDuration <= 0 {
+		return fmt.Errorf("a positive duration is required")
+	}
+
+	return c.renewIAM(ctx)
+}
+
+func (c *IAMRenewCommand) renewIAM(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	// Read request from file path.
+	rr := newRequestReader(c.Stdin())
+	docs, err := rr.iamBundle(c.flagPath)
+	if err != nil {
+		return withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &v1alpha1.IAMRequest{}, err))
+	}
+	if err := injectFailure(c.iamHandlerFlags.flagInjectFailure, failureStageParse); err != nil {
+		return withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &v1alpha1.IAMRequest{}, err))
+	}
+
+	if err := requestutil.ExpandIAMBundle(docs, c.entitlementFlags.catalog, c.flagDuration); err != nil {
+		auditValidationDenied(ctx, c.iamHandlerFlags.flagAuditLogProject, err)
+		return withExitCode(ExitCodeValidation, fmt.Errorf("failed to expand entitlements of %T: %w", &v1alpha1.IAMRequest{}, err))
+	}
+	if err := requestutil.ResolveIAMBundle(docs, c.identityFlags.identities); err != nil {
+		auditValidationDenied(ctx, c.iamHandlerFlags.flagAuditLogProject, err)
+		return withExitCode(ExitCodeValidation, fmt.Errorf("failed to resolve members of %T: %w", &v1alpha1.IAMRequest{}, err))
+	}
+	req, err := requestutil.ValidateIAMBundle(docs, c.policyFlags.options(c.flagDuration))
+	if err != nil {
+		auditValidationDenied(ctx, c.iamHandlerFlags.flagAuditLogProject, err)
+		return withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", req, err))
+	}
+
+	var h iamRenewHandler
+	if c.testHandler != nil {
+		// Use testHandler if it is for testing.
+		h = c.testHandler
+	} else {
+		var opts []handler.Option
+		if c.flagPreflight {
+			opts = append(opts, handler.WithPreflight())
+		}
+		iamHandler, closer, newHandlerErr := newIAMHandler(ctx, &c.iamHandlerFlags, &c.BaseCommand, opts...)
+		if newHandlerErr != nil {
+			return newHandlerErr
+		}
+		h = iamHandler
+		defer func() {
+			if err := closer.Close(); err != nil {
+				logger.ErrorContext(ctx, "failed to close", "error", err)
+			}
+		}()
+	}
+
+	// Wrap IAMRequest to renew from now.
+	reqWrapper := &v1alpha1.IAMRequestWrapper{
+		IAMRequest: req,
+		Duration:   c.flagDuration,
+		StartTime:  c.iamHandlerFlags.now(),
+	}
+	if err := c.provenanceFlags.apply(ctx, reqWrapper); err != nil {
+		return err
+	}
+	if reqWrapper.RequestHash, err = rr.hash(c.flagPath); err != nil {
+		return fmt.Errorf("failed to hash %T: %w", req, err)
+	}
+
+	resp, err := h.Renew(ctx, reqWrapper)
+	if err != nil {
+		return withExitCode(apiExitCode(err, len(reqWrapper.ResourcePolicies)),
+			fmt.Errorf("failed to renew IAM request: %w", err))
+	}
+	printMessageHeader(ctx, &c.BaseCommand, messages.HeaderRenewed)
+	if err := encodeYaml(c.Stdout(), reqWrapper); err != nil {
+		return fmt.Errorf("failed to output renewed request: %w", err)
+	}
+
+	if c.flagVerbose {
+		printMessageHeader(ctx, &c.BaseCommand, messages.HeaderUpdatedPolicies)
+		if err := encodeYaml(c.Stdout(), resp); err != nil {
+			return fmt.Errorf("failed to output IAM policies: %w", err)
+		}
+	}
+
+	return nil
+}
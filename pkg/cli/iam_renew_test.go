@@ -0,0 +1,193 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestIAMRenewCommand(t *testing.T) {
+	t.Parallel()
+
+	// Set up IAM request file.
+	requestFileContentByName := map[string]string{
+		"valid.yaml": `
+policies:
+- resource: projects/baz
+  bindings:
+    - members:
+      - user:test-project-user@example.com
+      role: roles/bigquery.dataViewer
+`,
+		"invalid-request.yaml": `
+policies:
+- resource: projects/baz
+  bindings:
+    - members:
+      - group:test-project-group@example.com
+      role: roles/bigquery.dataViewer
+`,
+		"invalid.yaml": `bananas`,
+	}
+	dir := t.TempDir()
+	hashes := make(map[string]string, len(requestFileContentByName))
+	for name, content := range requestFileContentByName {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256([]byte(content))
+		hashes[name] = hex.EncodeToString(sum[:])
+	}
+
+	validRequest := &v1alpha1.IAMRequest{
+		ResourcePolicies: []*v1alpha1.ResourcePolicy{
+			{
+				Resource: "projects/baz",
+				Bindings: []*v1alpha1.Binding{
+					{
+						Members: []string{"user:test-project-user@example.com"},
+						Role:    "roles/bigquery.dataViewer",
+					},
+				},
+			},
+		},
+	}
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name    string
+		args    []string
+		handler *fakeIAMRenewHandler
+		expReq  *v1alpha1.IAMRequestWrapper
+		expOut  string
+		expErr  string
+	}{
+		{
+			name:    "success",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-now", now.Format(time.RFC3339)},
+			handler: &fakeIAMRenewHandler{},
+			expOut: fmt.Sprintf(`
+------Successfully Renewed IAM Request------
+iamrequest:
+  policies:
+    - resource: projects/baz
+      bindings:
+        - members:
+            - user:test-project-user@example.com
+          role: roles/bigquery.dataViewer
+duration: 2h0m0s
+starttime: %s`, now.Format(time.RFC3339)),
+			expReq: &v1alpha1.IAMRequestWrapper{
+				IAMRequest:  validRequest,
+				Duration:    2 * time.Hour,
+				StartTime:   now,
+				RequestHash: hashes["valid.yaml"],
+			},
+		},
+		{
+			name: "handler_failure",
+			args: []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-now", now.Format(time.RFC3339)},
+			handler: &fakeIAMRenewHandler{
+				injectErr: fmt.Errorf(`active AOD binding not found for member "user:test-project-user@example.com"`),
+			},
+			expReq: &v1alpha1.IAMRequestWrapper{
+				IAMRequest:  validRequest,
+				Duration:    2 * time.Hour,
+				StartTime:   now,
+				RequestHash: hashes["valid.yaml"],
+			},
+			expErr: `failed to renew IAM request: active AOD binding not found for member "user:test-project-user@example.com"`,
+		},
+		{
+			name:    "invalid_request",
+			args:    []string{"-path", filepath.Join(dir, "invalid-request.yaml"), "-duration", "2h"},
+			handler: &fakeIAMRenewHandler{},
+			expErr:  "failed to validate *v1alpha1.IAMRequest",
+		},
+		{
+			name:    "invalid_yaml",
+			args:    []string{"-path", filepath.Join(dir, "invalid.yaml"), "-duration", "2h"},
+			handler: &fakeIAMRenewHandler{},
+			expErr:  "failed to read *v1alpha1.IAMRequest",
+		},
+		{
+			name:    "missing_duration",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml")},
+			handler: &fakeIAMRenewHandler{},
+			expErr:  "a positive duration is required",
+		},
+		{
+			name:    "missing_path",
+			args:    []string{"-duration", "2h"},
+			handler: &fakeIAMRenewHandler{},
+			expErr:  "path is required",
+		},
+		{
+			name:    "unexpected_args",
+			args:    []string{"foo"},
+			handler: &fakeIAMRenewHandler{},
+			expErr:  `unexpected arguments: ["foo"]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var cmd IAMRenewCommand
+			cmd.testHandler = tc.handler
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expReq, tc.handler.gotReq); diff != "" {
+				t.Errorf("Process(%+v) got request diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+type fakeIAMRenewHandler struct {
+	injectErr error
+	gotReq    *v1alpha1.IAMRequestWrapper
+	resp      []*v1alpha1.IAMResponse
+}
+
+func (h *fakeIAMRenewHandler) Renew(ctx context.Context, req *v1alpha1.IAMRequestWrapper) ([]*v1alpha1.IAMResponse, error) {
+	h.gotReq = req
+	return h.resp, h.injectErr
+}
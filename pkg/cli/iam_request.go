@@ -0,0 +1,254 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/posener/complete/v2/predict"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/resource"
+	"github.com/abcxyz/pkg/cli"
+)
+
+var _ cli.Command = (*IAMRequestCommand)(nil)
+
+// IAMRequestCommand generates IAM request files from flags.
+type IAMRequestCommand struct {
+	cli.BaseCommand
+
+	flagOrganizations []string
+
+	flagFolders []string
+
+	flagProjects []string
+
+	flagMembers []string
+
+	flagRoles []string
+
+	flagRoleBundle string
+
+	flagDuration time.Duration
+
+	flagOut string
+
+	flagForce bool
+}
+
+func (c *IAMRequestCommand) Desc() string {
+	return `Generate an IAM request YAML file from flags`
+}
+
+func (c *IAMRequestCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Generate an IAM request YAML file granting the roles to the members on the
+resources:
+
+      {{ COMMAND }} -project "foo" -role "roles/run.developer" -member "user:alice@example.com" -duration "2h" -out "iam.yaml"
+
+Print the IAM request to stdout instead:
+
+      {{ COMMAND }} -folder "123" -role-bundle "project-viewer-plus-logs" -member "user:alice@example.com"
+`
+}
+
+func (c *IAMRequestCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "organization",
+		Target:  &c.flagOrganizations,
+		Example: "123456",
+		Usage:   `The ID of the organization to grant the roles on, can be repeated.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "folder",
+		Target:  &c.flagFolders,
+		Example: "123456",
+		Usage:   `The ID of the folder to grant the roles on, can be repeated.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "project",
+		Target:  &c.flagProjects,
+		Example: "my-project",
+		Usage:   `The ID of the project to grant the roles on, can be repeated.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "member",
+		Target:  &c.flagMembers,
+		Example: "user:alice@example.com",
+		Usage:   `The member to grant the roles to, can be repeated.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "role",
+		Target:  &c.flagRoles,
+		Example: "roles/run.developer",
+		Usage:   `The role to grant, can be repeated.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "role-bundle",
+		Target:  &c.flagRoleBundle,
+		Example: "bq-read",
+		Predict: predict.Set(v1alpha1.RoleBundleNames()),
+		Usage:   `The role bundle to grant, in addition to the roles.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "duration",
+		Target:  &c.flagDuration,
+		Example: "2h",
+		Usage: `The duration to handle the request with, which is recorded in ` +
+			`a comment of the request file.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "out",
+		Target:  &c.flagOut,
+		Example: "/path/to/iam.yaml",
+		Predict: predict.Files("*"),
+		Usage:   `The path to write the request file to, default is stdout.`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "force",
+		Target:  &c.flagForce,
+		Default: false,
+		Usage:   `Overwrite the request file if it exists.`,
+	})
+
+	return set
+}
+
+func (c *IAMRequestCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if len(c.flagOrganizations)+len(c.flagFolders)+len(c.flagProjects) == 0 {
+		return fmt.Errorf("at least one of organization, folder and project is required")
+	}
+
+	if len(c.flagMembers) == 0 {
+		return fmt.Errorf("member is required")
+	}
+
+	if len(c.flagRoles) == 0 && c.flagRoleBundle == "" {
+		return fmt.Errorf("at least one of role and role-bundle is required")
+	}
+
+	if c.flagDuration < 0 {
+		return fmt.Errorf("duration must not be negative")
+	}
+
+	return c.generate()
+}
+
+func (c *IAMRequestCommand) generate() error {
+	req := c.request()
+	if err := v1alpha1.ValidateIAMRequest(req, nil); err != nil {
+		return withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", req, err))
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintln(&b, `# Generated by "aod iam request".`)
+	if c.flagDuration > 0 {
+		path := c.flagOut
+		if path == "" {
+			path = "iam.yaml"
+		}
+		fmt.Fprintf(&b, "# Handle with: aod iam handle -path %q -duration %s\n", path, c.flagDuration)
+	}
+	if err := encodeYaml(&b, req); err != nil {
+		return fmt.Errorf("failed to output request: %w", err)
+	}
+
+	if c.flagOut == "" {
+		if _, err := c.Stdout().Write(b.Bytes()); err != nil {
+			return fmt.Errorf("failed to output request: %w", err)
+		}
+		return nil
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !c.flagForce {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(c.flagOut, flags, 0o644)
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("file %q already exists, set force to overwrite it", c.flagOut)
+		}
+		return fmt.Errorf("failed to open file %q: %w", c.flagOut, err)
+	}
+	if _, err := f.Write(b.Bytes()); err != nil {
+		return errors.Join(fmt.Errorf("failed to write file %q: %w", c.flagOut, err), f.Close())
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close file %q: %w", c.flagOut, err)
+	}
+	c.Outf("Successfully wrote IAM request to %s", c.flagOut)
+	return nil
+}
+
+// request returns the IAM request of the flags, with a binding of each role to
+// the members on each resource.
+func (c *IAMRequestCommand) request() *v1alpha1.IAMRequest {
+	var resources []string
+	for _, o := range c.flagOrganizations {
+		resources = append(resources, resource.New(resource.TypeOrganization, o).String())
+	}
+	for _, f := range c.flagFolders {
+		resources = append(resources, resource.New(resource.TypeFolder, f).String())
+	}
+	for _, p := range c.flagProjects {
+		resources = append(resources, resource.New(resource.TypeProject, p).String())
+	}
+
+	req := &v1alpha1.IAMRequest{}
+	for _, r := range resources {
+		p := &v1alpha1.ResourcePolicy{Resource: r}
+		for _, role := range c.flagRoles {
+			p.Bindings = append(p.Bindings, &v1alpha1.Binding{Members: c.flagMembers, Role: role})
+		}
+		if c.flagRoleBundle != "" {
+			p.Bindings = append(p.Bindings, &v1alpha1.Binding{Members: c.flagMembers, RoleBundle: c.flagRoleBundle})
+		}
+		req.ResourcePolicies = append(req.ResourcePolicies, p)
+	}
+	return req
+}
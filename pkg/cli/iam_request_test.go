@@ -0,0 +1,200 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestIAMRequestCommand(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		args     []string
+		existing string
+		expFile  string
+		expOut   string
+		expErr   string
+	}{
+		{
+			name: "success_stdout",
+			args: []string{
+				"-project", "foo", "-folder", "123",
+				"-role", "roles/run.developer", "-role", "roles/logging.viewer",
+				"-member", "user:alice@example.com", "-member", "user:bob@example.com",
+			},
+			expOut: `
+# Generated by "aod iam request".
+policies:
+  - resource: folders/123
+    bindings:
+      - members:
+          - user:alice@example.com
+          - user:bob@example.com
+        role: roles/run.developer
+      - members:
+          - user:alice@example.com
+          - user:bob@example.com
+        role: roles/logging.viewer
+  - resource: projects/foo
+    bindings:
+      - members:
+          - user:alice@example.com
+          - user:bob@example.com
+        role: roles/run.developer
+      - members:
+          - user:alice@example.com
+          - user:bob@example.com
+        role: roles/logging.viewer`,
+		},
+		{
+			name: "success_file",
+			args: []string{
+				"-project", "foo", "-role", "roles/run.developer", "-role-bundle", "gcs-read",
+				"-member", "user:alice@example.com", "-duration", "2h", "-out", "{{path}}",
+			},
+			expFile: `# Generated by "aod iam request".
+# Handle with: aod iam handle -path "{{path}}" -duration 2h0m0s
+policies:
+  - resource: projects/foo
+    bindings:
+      - members:
+          - user:alice@example.com
+        role: roles/run.developer
+      - members:
+          - user:alice@example.com
+        roleBundle: gcs-read
+`,
+			expOut: `Successfully wrote IAM request to {{path}}`,
+		},
+		{
+			name: "success_overwrite",
+			args: []string{
+				"-organization", "456", "-role", "roles/browser",
+				"-member", "user:alice@example.com", "-out", "{{path}}", "-force",
+			},
+			existing: "bananas",
+			expFile: `# Generated by "aod iam request".
+policies:
+  - resource: organizations/456
+    bindings:
+      - members:
+          - user:alice@example.com
+        role: roles/browser
+`,
+			expOut: `Successfully wrote IAM request to {{path}}`,
+		},
+		{
+			name: "file_exists",
+			args: []string{
+				"-organization", "456", "-role", "roles/browser",
+				"-member", "user:alice@example.com", "-out", "{{path}}",
+			},
+			existing: "bananas",
+			expFile:  "bananas",
+			expErr:   "already exists, set force to overwrite it",
+		},
+		{
+			name: "invalid_member",
+			args: []string{
+				"-project", "foo", "-role", "roles/browser", "-member", "group:eng@example.com",
+			},
+			expErr: `policies[0].bindings[0].members[0]: member "group:eng@example.com" is not of "user" type`,
+		},
+		{
+			name: "invalid_role_bundle",
+			args: []string{
+				"-project", "foo", "-role-bundle", "bananas", "-member", "user:alice@example.com",
+			},
+			expErr: `role bundle "bananas" isn't one of`,
+		},
+		{
+			name:   "missing_resource",
+			args:   []string{"-role", "roles/browser", "-member", "user:alice@example.com"},
+			expErr: "at least one of organization, folder and project is required",
+		},
+		{
+			name:   "missing_member",
+			args:   []string{"-project", "foo", "-role", "roles/browser"},
+			expErr: "member is required",
+		},
+		{
+			name:   "missing_role",
+			args:   []string{"-project", "foo", "-member", "user:alice@example.com"},
+			expErr: "at least one of role and role-bundle is required",
+		},
+		{
+			name:   "negative_duration",
+			args:   []string{"-project", "foo", "-role", "roles/browser", "-member", "user:alice@example.com", "-duration", "-1h"},
+			expErr: "duration must not be negative",
+		},
+		{
+			name:   "unexpected_args",
+			args:   []string{"foo"},
+			expErr: `unexpected arguments: ["foo"]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			path := filepath.Join(t.TempDir(), "iam.yaml")
+			if tc.existing != "" {
+				if err := os.WriteFile(path, []byte(tc.existing), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			args := make([]string, 0, len(tc.args))
+			for _, a := range tc.args {
+				args = append(args, strings.ReplaceAll(a, "{{path}}", path))
+			}
+
+			var cmd IAMRequestCommand
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			expOut := strings.ReplaceAll(tc.expOut, "{{path}}", path)
+			if diff := cmp.Diff(strings.TrimSpace(expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if tc.expFile != "" {
+				got, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				expFile := strings.ReplaceAll(tc.expFile, "{{path}}", path)
+				if diff := cmp.Diff(expFile, string(got)); diff != "" {
+					t.Errorf("Process(%+v) got file diff (-want, +got):\n%s", tc.name, diff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/posener/complete/v2/predict"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/messages"
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+)
+
+var _ cli.Command = (*IAMRevokeUserCommand)(nil)
+
+// iamRevokeMemberHandler interface that revokes a member from AOD bindings.
+type iamRevokeMemberHandler interface {
+	RevokeMember(ctx context.Context, member string, resources []string) ([]*v1alpha1.IAMResponse, error)
+}
+
+// IAMRevokeUserCommand removes a member from all AOD IAM bindings.
+type IAMRevokeUserCommand struct {
+	cli.BaseCommand
+
+	flagMember string
+
+	flagResources []string
+
+	flagPath string
+
+	flagVerbose bool
+
+	iamHandlerFlags iamHandlerFlags
+
+	// testHandler is used for testing only.
+	testHandler iamRevokeMemberHandler
+}
+
+func (c *IAMRevokeUserCommand) Desc() string {
+	return `Remove a member from all AOD IAM bindings of the given resources`
+}
+
+func (c *IAMRevokeUserCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Remove the member from all AOD IAM bindings of the given resources, across all
+roles:
+
+      {{ COMMAND }} -member "user:alice@example.com" -resource "projects/foo" -resource "folders/bar"
+
+Remove the member from all AOD IAM bindings of the resources in the IAM request
+YAML file:
+
+      {{ COMMAND }} -member "user:alice@example.com" -path "/path/to/file.yaml"
+`
+}
+
+func (c *IAMRevokeUserCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "member",
+		Target:  &c.flagMember,
+		Example: "user:alice@example.com",
+		Usage:   `The member to remove from all AOD IAM bindings.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "resource",
+		Target:  &c.flagResources,
+		Example: "projects/foo",
+		Usage: `The organization, folder or project to remove the member ` +
+			`from, can be repeated.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "path",
+		Target:  &c.flagPath,
+		Example: "/path/to/file.yaml",
+		Predict: predict.Files("*"),
+		Usage: `The path of IAM request file, in YAML format, the resources ` +
+			`of which to remove the member from Use "-" to read it from stdin.`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "verbose",
+		Target:  &c.flagVerbose,
+		Default: false,
+		Usage:   `Turn on verbose mode to print updated IAM policies. Note that it may contain sensitive information`,
+	})
+
+	c.iamHandlerFlags.register(f)
+
+	return set
+}
+
+func (c *IAMRevokeUserCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagMember == "" {
+		return fmt.Errorf("member is required")
+	}
+
+	if len(c.flagResources) == 0 && c.flagPath == "" {
+		return fmt.Errorf("at least one of resource and path is required")
+	}
+
+	if err := c.iamHandlerFlags.validate(); err != nil {
+		return err
+	}
+
+	return c.revokeUser(ctx)
+}
+
+func (c *IAMRevokeUserCommand) revokeUser(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	resources := slices.Clone(c.flagResources)
+	if c.flagPath != "" {
+		docs, err := newRequestReader(c.Stdin()).iamBundle(c.flagPath)
+		if err != nil {
+			return withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &v1alpha1.IAMRequest{}, err))
+		}
+		for _, d := range docs {
+			for _, p := range d.Request.ResourcePolicies {
+				resources = append(resources, p.Resource)
+			}
+		}
+	}
+	slices.Sort(resources)
+	resources = slices.Compact(resources)
+
+	// Validate the member and resources as an IAMRequest.
+	req := &v1alpha1.IAMRequest{}
+	for _, r := range resources {
+		req.ResourcePolicies = append(req.ResourcePolicies, &v1alpha1.ResourcePolicy{
+			Resource: r,
+			Bindings: []*v1alpha1.Binding{{Members: []string{c.flagMember}}},
+		})
+	}
+	if err := v1alpha1.ValidateIAMRequest(req, nil); err != nil {
+		auditValidationDenied(ctx, c.iamHandlerFlags.flagAuditLogProject, err)
+		return withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", req, err))
+	}
+
+	var h iamRevokeMemberHandler
+	if c.testHandler != nil {
+		// Use testHandler if it is for testing.
+		h = c.testHandler
+	} else {
+		iamHandler, closer, newHandlerErr := newIAMHandler(ctx, &c.iamHandlerFlags, &c.BaseCommand)
+		if newHandlerErr != nil {
+			return newHandlerErr
+		}
+		h = iamHandler
+		defer func() {
+			if err := closer.Close(); err != nil {
+				logger.ErrorContext(ctx, "failed to close", "error", err)
+			}
+		}()
+	}
+
+	resp, err := h.RevokeMember(ctx, c.flagMember, resources)
+	if err != nil {
+		return withExitCode(apiExitCode(err, len(resources)),
+			fmt.Errorf("failed to revoke member %q: %w", c.flagMember, err))
+	}
+
+	printMessageHeader(ctx, &c.BaseCommand, messages.HeaderRevokedUser)
+	if err := encodeYaml(c.Stdout(), map[string]any{
+		"member":    c.flagMember,
+		"resources": resources,
+	}); err != nil {
+		return fmt.Errorf("failed to output revoked member: %w", err)
+	}
+
+	if c.flagVerbose {
+		printMessageHeader(ctx, &c.BaseCommand, messages.HeaderUpdatedPolicies)
+		if err := encodeYaml(c.Stdout(), resp); err != nil {
+			return fmt.Errorf("failed to output IAM policies: %w", err)
+		}
+	}
+
+	return nil
+}
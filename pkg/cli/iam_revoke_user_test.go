@@ -0,0 +1,185 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestIAMRevokeUserCommand(t *testing.T) {
+	t.Parallel()
+
+	// Set up IAM request file.
+	requestFileContentByName := map[string]string{
+		"valid.yaml": `
+policies:
+- resource: folders/bar
+  bindings:
+    - members:
+      - user:test-folder-user@example.com
+      role: roles/cloudkms.cryptoOperator
+- resource: projects/baz
+  bindings:
+    - members:
+      - user:test-project-user@example.com
+      role: roles/bigquery.dataViewer
+`,
+		"invalid.yaml": `bananas`,
+	}
+	dir := t.TempDir()
+	for name, content := range requestFileContentByName {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name         string
+		args         []string
+		handler      *fakeIAMRevokeMemberHandler
+		expMember    string
+		expResources []string
+		expOut       string
+		expErr       string
+	}{
+		{
+			name:         "success_with_resources",
+			args:         []string{"-member", "user:alice@example.com", "-resource", "projects/foo", "-resource", "folders/bar"},
+			handler:      &fakeIAMRevokeMemberHandler{},
+			expMember:    "user:alice@example.com",
+			expResources: []string{"folders/bar", "projects/foo"},
+			expOut: `
+------Successfully Revoked Member From AOD Bindings------
+member: user:alice@example.com
+resources:
+  - folders/bar
+  - projects/foo`,
+		},
+		{
+			name: "success_with_path_and_resources",
+			args: []string{
+				"-member", "user:alice@example.com",
+				"-path", filepath.Join(dir, "valid.yaml"),
+				"-resource", "projects/baz",
+			},
+			handler:      &fakeIAMRevokeMemberHandler{},
+			expMember:    "user:alice@example.com",
+			expResources: []string{"folders/bar", "projects/baz"},
+			expOut: `
+------Successfully Revoked Member From AOD Bindings------
+member: user:alice@example.com
+resources:
+  - folders/bar
+  - projects/baz`,
+		},
+		{
+			name: "handler_failure",
+			args: []string{"-member", "user:alice@example.com", "-resource", "projects/foo"},
+			handler: &fakeIAMRevokeMemberHandler{
+				injectErr: fmt.Errorf("injected error"),
+			},
+			expMember:    "user:alice@example.com",
+			expResources: []string{"projects/foo"},
+			expErr:       `failed to revoke member "user:alice@example.com": injected error`,
+		},
+		{
+			name:    "invalid_member",
+			args:    []string{"-member", "group:foo@example.com", "-resource", "projects/foo"},
+			handler: &fakeIAMRevokeMemberHandler{},
+			expErr:  `member "group:foo@example.com" is not of "user" type`,
+		},
+		{
+			name:    "invalid_resource",
+			args:    []string{"-member", "user:alice@example.com", "-resource", "foo/bar"},
+			handler: &fakeIAMRevokeMemberHandler{},
+			expErr:  `resource "foo/bar" isn't one of [organizations, folders, projects, buckets, secrets, serviceAccounts, bigquery/datasets, kms/keyrings, //<service>]`,
+		},
+		{
+			name:    "invalid_yaml",
+			args:    []string{"-member", "user:alice@example.com", "-path", filepath.Join(dir, "invalid.yaml")},
+			handler: &fakeIAMRevokeMemberHandler{},
+			expErr:  "failed to read *v1alpha1.IAMRequest",
+		},
+		{
+			name:    "missing_member",
+			args:    []string{"-resource", "projects/foo"},
+			handler: &fakeIAMRevokeMemberHandler{},
+			expErr:  "member is required",
+		},
+		{
+			name:    "missing_resources",
+			args:    []string{"-member", "user:alice@example.com"},
+			handler: &fakeIAMRevokeMemberHandler{},
+			expErr:  "at least one of resource and path is required",
+		},
+		{
+			name:    "unexpected_args",
+			args:    []string{"foo"},
+			handler: &fakeIAMRevokeMemberHandler{},
+			expErr:  `unexpected arguments: ["foo"]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var cmd IAMRevokeUserCommand
+			cmd.testHandler = tc.handler
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if got, want := tc.handler.gotMember, tc.expMember; got != want {
+				t.Errorf("Process(%+v) got member %q, want %q", tc.name, got, want)
+			}
+			if diff := cmp.Diff(tc.expResources, tc.handler.gotResources); diff != "" {
+				t.Errorf("Process(%+v) got resources diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+type fakeIAMRevokeMemberHandler struct {
+	injectErr    error
+	gotMember    string
+	gotResources []string
+	resp         []*v1alpha1.IAMResponse
+}
+
+func (h *fakeIAMRevokeMemberHandler) RevokeMember(ctx context.Context, member string, resources []string) ([]*v1alpha1.IAMResponse, error) {
+	h.gotMember = member
+	h.gotResources = resources
+	return h.resp, h.injectErr
+}
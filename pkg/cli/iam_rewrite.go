@@ -0,0 +1,285 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/posener/complete/v2/predict"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/messages"
+	"github.com/abcxyz/access-on-demand/pkg/requestutil"
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+)
+
+var _ cli.Command = (*IAMRewriteCommand)(nil)
+
+// iamRewriteMemberHandler interface that rewrites a member in AOD bindings.
+type iamRewriteMemberHandler interface {
+	RewriteMember(ctx context.Context, from, to string, resources []string) ([]*v1alpha1.IAMResponse, error)
+}
+
+// rewrittenFile is a request file with the number of rewritten members.
+type rewrittenFile struct {
+	Path         string `yaml:"path"`
+	Replacements int    `yaml:"replacements"`
+}
+
+// IAMRewriteCommand replaces a member with another member in IAM request files
+// and in active AOD IAM bindings.
+type IAMRewriteCommand struct {
+	cli.BaseCommand
+
+	flagFrom string
+
+	flagTo string
+
+	flagPaths []string
+
+	flagResources []string
+
+	flagLive bool
+
+	iamHandlerFlags iamHandlerFlags
+
+	// testHandler is used for testing only.
+	testHandler iamRewriteMemberHandler
+}
+
+func (c *IAMRewriteCommand) Desc() string {
+	return `Replace a member with another member in IAM request files and AOD IAM bindings`
+}
+
+func (c *IAMRewriteCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Replace the member in the IAM request YAML files, in place:
+
+      {{ COMMAND }} -from "user:old@example.com" -to "user:new@example.com" -path "/path/to/file.yaml"
+
+Also replace the member in the AOD IAM bindings of the resources in the IAM
+request YAML files:
+
+      {{ COMMAND }} -from "user:old@example.com" -to "user:new@example.com" -path "/path/to/file.yaml" -live
+
+Replace the member in the AOD IAM bindings of the given resources:
+
+      {{ COMMAND }} -from "user:old@example.com" -to "user:new@example.com" -resource "projects/foo"
+`
+}
+
+func (c *IAMRewriteCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "from",
+		Target:  &c.flagFrom,
+		Example: "user:old@example.com",
+		Usage:   `The member to replace.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "to",
+		Target:  &c.flagTo,
+		Example: "user:new@example.com",
+		Usage:   `The member to replace with.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "path",
+		Target:  &c.flagPaths,
+		Example: "/path/to/file.yaml",
+		Predict: predict.Files("*"),
+		Usage: `The path of IAM request file, in YAML format, to replace ` +
+			`the member in, can be repeated.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "resource",
+		Target:  &c.flagResources,
+		Example: "projects/foo",
+		Usage: `The organization, folder or project to replace the member ` +
+			`in the AOD IAM bindings of, can be repeated.`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "live",
+		Target:  &c.flagLive,
+		Default: false,
+		Usage: `Also replace the member in the AOD IAM bindings of the ` +
+			`resources in the IAM request files.`,
+	})
+
+	c.iamHandlerFlags.register(f)
+
+	return set
+}
+
+func (c *IAMRewriteCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagFrom == "" || c.flagTo == "" {
+		return fmt.Errorf("from and to are required")
+	}
+
+	if c.flagFrom == c.flagTo {
+		return fmt.Errorf("from and to must be different, got %q", c.flagFrom)
+	}
+
+	if len(c.flagResources) == 0 && len(c.flagPaths) == 0 {
+		return fmt.Errorf("at least one of resource and path is required")
+	}
+
+	if slices.Contains(c.flagPaths, stdinPath) {
+		return fmt.Errorf("path can't be stdin, the files are rewritten in place")
+	}
+
+	if c.flagLive && len(c.flagPaths) == 0 {
+		return fmt.Errorf("live requires path")
+	}
+
+	if err := c.iamHandlerFlags.validate(); err != nil {
+		return err
+	}
+
+	return c.rewrite(ctx)
+}
+
+func (c *IAMRewriteCommand) rewrite(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	var fileResources []string
+	for _, p := range c.flagPaths {
+		docs, err := requestutil.ReadBundleFromPath[v1alpha1.IAMRequest](p)
+		if err != nil {
+			return withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &v1alpha1.IAMRequest{}, err))
+		}
+		for _, d := range docs {
+			for _, rp := range d.Request.ResourcePolicies {
+				fileResources = append(fileResources, rp.Resource)
+			}
+		}
+	}
+
+	resources := slices.Clone(c.flagResources)
+	if c.flagLive {
+		resources = append(resources, fileResources...)
+	}
+	slices.Sort(resources)
+	resources = slices.Compact(resources)
+
+	// Validate the members and resources as an IAMRequest.
+	req := &v1alpha1.IAMRequest{}
+	for _, r := range slices.Concat(resources, fileResources) {
+		req.ResourcePolicies = append(req.ResourcePolicies, &v1alpha1.ResourcePolicy{
+			Resource: r,
+			Bindings: []*v1alpha1.Binding{{Members: []string{c.flagFrom, c.flagTo}}},
+		})
+	}
+	if err := v1alpha1.ValidateIAMRequest(req, nil); err != nil {
+		auditValidationDenied(ctx, c.iamHandlerFlags.flagAuditLogProject, err)
+		return withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", req, err))
+	}
+
+	files := make([]*rewrittenFile, 0, len(c.flagPaths))
+	for _, p := range c.flagPaths {
+		n, err := rewriteFile(p, c.flagFrom, c.flagTo)
+		if err != nil {
+			return err
+		}
+		files = append(files, &rewrittenFile{Path: p, Replacements: n})
+	}
+
+	if len(resources) > 0 {
+		var h iamRewriteMemberHandler
+		if c.testHandler != nil {
+			// Use testHandler if it is for testing.
+			h = c.testHandler
+		} else {
+			iamHandler, closer, newHandlerErr := newIAMHandler(ctx, &c.iamHandlerFlags, &c.BaseCommand)
+			if newHandlerErr != nil {
+				return newHandlerErr
+			}
+			h = iamHandler
+			defer func() {
+				if err := closer.Close(); err != nil {
+					logger.ErrorContext(ctx, "failed to close", "error", err)
+				}
+			}()
+		}
+
+		if _, err := h.RewriteMember(ctx, c.flagFrom, c.flagTo, resources); err != nil {
+			return withExitCode(apiExitCode(err, len(resources)),
+				fmt.Errorf("failed to rewrite member %q: %w", c.flagFrom, err))
+		}
+	}
+
+	printMessageHeader(ctx, &c.BaseCommand, messages.HeaderRewritten)
+	out := map[string]any{
+		"from": c.flagFrom,
+		"to":   c.flagTo,
+	}
+	if len(files) > 0 {
+		out["files"] = files
+	}
+	if len(resources) > 0 {
+		out["resources"] = resources
+	}
+	if err := encodeYaml(c.Stdout(), out); err != nil {
+		return fmt.Errorf("failed to output rewritten member: %w", err)
+	}
+	return nil
+}
+
+// rewriteFile replaces the member from with the member to in the IAM request
+// file in place, and returns the number of replacements. The file is not
+// written if there is no replacement.
+func rewriteFile(path, from, to string) (int, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat file %q: %w", path, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read file %q: %w", path, err)
+	}
+	rewritten, n, err := requestutil.RewriteMembers(data, from, to)
+	if err != nil {
+		return 0, fmt.Errorf("failed to rewrite file %q: %w", path, err)
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	if err := os.WriteFile(path, rewritten, fi.Mode().Perm()); err != nil {
+		return 0, fmt.Errorf("failed to write file %q: %w", path, err)
+	}
+	return n, nil
+}
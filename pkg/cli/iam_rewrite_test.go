@@ -0,0 +1,240 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestIAMRewriteCommand(t *testing.T) {
+	t.Parallel()
+
+	validFile := `
+policies:
+- resource: folders/bar
+  bindings:
+    - members:
+      - user:old@example.com
+      - user:bob@example.com
+      role: roles/cloudkms.cryptoOperator
+`
+	rewrittenFile := `
+policies:
+- resource: folders/bar
+  bindings:
+    - members:
+      - user:new@example.com
+      - user:bob@example.com
+      role: roles/cloudkms.cryptoOperator
+`
+	otherFile := `
+policies:
+- resource: folders/bar
+  bindings:
+    - members:
+      - user:bob@example.com
+      role: roles/cloudkms.cryptoOperator
+`
+
+	cases := []struct {
+		name         string
+		file         string
+		args         []string
+		handler      *fakeIAMRewriteMemberHandler
+		expFile      string
+		expResources []string
+		expOut       string
+		expErr       string
+	}{
+		{
+			name:    "success_file",
+			file:    validFile,
+			args:    []string{"-from", "user:old@example.com", "-to", "user:new@example.com", "-path", "{{path}}"},
+			handler: &fakeIAMRewriteMemberHandler{},
+			expFile: rewrittenFile,
+			expOut: `
+------Successfully Rewrote Member------
+files:
+  - path: {{path}}
+    replacements: 1
+from: user:old@example.com
+to: user:new@example.com`,
+		},
+		{
+			name:         "success_file_live",
+			file:         validFile,
+			args:         []string{"-from", "user:old@example.com", "-to", "user:new@example.com", "-path", "{{path}}", "-live", "-resource", "projects/baz"},
+			handler:      &fakeIAMRewriteMemberHandler{},
+			expFile:      rewrittenFile,
+			expResources: []string{"folders/bar", "projects/baz"},
+			expOut: `
+------Successfully Rewrote Member------
+files:
+  - path: {{path}}
+    replacements: 1
+from: user:old@example.com
+resources:
+  - folders/bar
+  - projects/baz
+to: user:new@example.com`,
+		},
+		{
+			name:         "success_resources",
+			args:         []string{"-from", "user:old@example.com", "-to", "user:new@example.com", "-resource", "projects/baz"},
+			handler:      &fakeIAMRewriteMemberHandler{},
+			expResources: []string{"projects/baz"},
+			expOut: `
+------Successfully Rewrote Member------
+from: user:old@example.com
+resources:
+  - projects/baz
+to: user:new@example.com`,
+		},
+		{
+			name:    "no_replacements",
+			file:    otherFile,
+			args:    []string{"-from", "user:old@example.com", "-to", "user:new@example.com", "-path", "{{path}}"},
+			handler: &fakeIAMRewriteMemberHandler{},
+			expFile: otherFile,
+			expOut: `
+------Successfully Rewrote Member------
+files:
+  - path: {{path}}
+    replacements: 0
+from: user:old@example.com
+to: user:new@example.com`,
+		},
+		{
+			name:         "handler_failure",
+			file:         validFile,
+			args:         []string{"-from", "user:old@example.com", "-to", "user:new@example.com", "-path", "{{path}}", "-live"},
+			handler:      &fakeIAMRewriteMemberHandler{injectErr: fmt.Errorf("injected error")},
+			expFile:      rewrittenFile,
+			expResources: []string{"folders/bar"},
+			expErr:       `failed to rewrite member "user:old@example.com": injected error`,
+		},
+		{
+			name:    "invalid_member",
+			file:    validFile,
+			args:    []string{"-from", "user:old@example.com", "-to", "group:new@example.com", "-path", "{{path}}"},
+			handler: &fakeIAMRewriteMemberHandler{},
+			expFile: validFile,
+			expErr:  `member "group:new@example.com" is not of "user" type`,
+		},
+		{
+			name:    "invalid_yaml",
+			file:    `bananas`,
+			args:    []string{"-from", "user:old@example.com", "-to", "user:new@example.com", "-path", "{{path}}"},
+			handler: &fakeIAMRewriteMemberHandler{},
+			expFile: `bananas`,
+			expErr:  "failed to read *v1alpha1.IAMRequest",
+		},
+		{
+			name:    "same_members",
+			args:    []string{"-from", "user:old@example.com", "-to", "user:old@example.com", "-resource", "projects/baz"},
+			handler: &fakeIAMRewriteMemberHandler{},
+			expErr:  `from and to must be different, got "user:old@example.com"`,
+		},
+		{
+			name:    "missing_to",
+			args:    []string{"-from", "user:old@example.com", "-resource", "projects/baz"},
+			handler: &fakeIAMRewriteMemberHandler{},
+			expErr:  "from and to are required",
+		},
+		{
+			name:    "missing_resources",
+			args:    []string{"-from", "user:old@example.com", "-to", "user:new@example.com"},
+			handler: &fakeIAMRewriteMemberHandler{},
+			expErr:  "at least one of resource and path is required",
+		},
+		{
+			name:    "live_without_path",
+			args:    []string{"-from", "user:old@example.com", "-to", "user:new@example.com", "-resource", "projects/baz", "-live"},
+			handler: &fakeIAMRewriteMemberHandler{},
+			expErr:  "live requires path",
+		},
+		{
+			name:    "unexpected_args",
+			args:    []string{"foo"},
+			handler: &fakeIAMRewriteMemberHandler{},
+			expErr:  `unexpected arguments: ["foo"]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			path := filepath.Join(t.TempDir(), "iam.yaml")
+			if tc.file != "" {
+				if err := os.WriteFile(path, []byte(tc.file), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			args := make([]string, 0, len(tc.args))
+			for _, a := range tc.args {
+				args = append(args, strings.ReplaceAll(a, "{{path}}", path))
+			}
+
+			var cmd IAMRewriteCommand
+			cmd.testHandler = tc.handler
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expResources, tc.handler.gotResources); diff != "" {
+				t.Errorf("Process(%+v) got resources diff (-want, +got):\n%s", tc.name, diff)
+			}
+			expOut := strings.ReplaceAll(tc.expOut, "{{path}}", path)
+			if diff := cmp.Diff(strings.TrimSpace(expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if tc.file != "" {
+				got, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(tc.expFile, string(got)); diff != "" {
+					t.Errorf("Process(%+v) got file diff (-want, +got):\n%s", tc.name, diff)
+				}
+			}
+		})
+	}
+}
+
+type fakeIAMRewriteMemberHandler struct {
+	injectErr    error
+	gotResources []string
+}
+
+func (h *fakeIAMRewriteMemberHandler) RewriteMember(ctx context.Context, from, to string, resources []string) ([]*v1alpha1.IAMResponse, error) {
+	h.gotResources = resources
+	return nil, h.injectErr
+}
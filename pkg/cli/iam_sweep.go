@@ -0,0 +1,450 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	resourcemanager "cloud.google.com/go/resourcemanager/apiv3"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/cursor"
+	"github.com/abcxyz/access-on-demand/pkg/hierarchy"
+	"github.com/abcxyz/access-on-demand/pkg/inventory"
+	"github.com/abcxyz/access-on-demand/pkg/messages"
+	"github.com/abcxyz/access-on-demand/pkg/resource"
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/multicloser"
+)
+
+var _ cli.Command = (*IAMSweepCommand)(nil)
+
+// iamSweepHandler interface that sweeps expired AOD bindings.
+type iamSweepHandler interface {
+	Sweep(ctx context.Context, resources []string) ([]*v1alpha1.IAMResponse, error)
+}
+
+// descendantsLister interface that lists an organization or folder and all its
+// descendant folders and projects.
+type descendantsLister interface {
+	Descendants(ctx context.Context, root string) ([]string, error)
+}
+
+// IAMSweepCommand removes expired AOD IAM bindings from an organization or
+// folder and all its descendant folders and projects.
+type IAMSweepCommand struct {
+	cli.BaseCommand
+
+	flagResources []string
+
+	flagUseInventory bool
+
+	flagVerbose bool
+
+	flagResumeCursor string
+
+	flagCursorProject string
+
+	flagCursorDatabase string
+
+	flagCursorBatchSize int
+
+	iamHandlerFlags iamHandlerFlags
+
+	// testHandler is used for testing only.
+	testHandler iamSweepHandler
+
+	// testLister is used for testing only.
+	testLister descendantsLister
+
+	// testCursorStore is used for testing only.
+	testCursorStore cursor.Store
+}
+
+func (c *IAMSweepCommand) Desc() string {
+	return `Remove expired AOD IAM bindings from the given resources and all their descendants`
+}
+
+func (c *IAMSweepCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Remove expired AOD IAM bindings from the organization and all its descendant
+folders and projects:
+
+      {{ COMMAND }} -resource "organizations/123"
+
+Remove expired AOD IAM bindings from the folder and all its descendant folders
+and projects, and from the project:
+
+      {{ COMMAND }} -resource "folders/456" -resource "projects/foo"
+
+Find the descendants with AOD IAM bindings with Cloud Asset Inventory, which is
+faster for large organizations:
+
+      {{ COMMAND }} -resource "organizations/123" -use-inventory
+
+Save the progress to a cursor in Firestore after every batch of resources, and
+continue where the previous run left off, such as when a Cloud Run job hits its
+max runtime:
+
+      {{ COMMAND }} -resource "organizations/123" -resume-cursor "nightly" -cursor-project "my-project"
+
+The IAM policies of resources without expired AOD IAM bindings are not updated.
+`
+}
+
+func (c *IAMSweepCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "resource",
+		Target:  &c.flagResources,
+		Example: "organizations/123",
+		Usage: `The organization, folder or project to sweep, can be repeated. ` +
+			`The descendant folders and projects of organizations and folders ` +
+			`are also swept.`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "use-inventory",
+		Target:  &c.flagUseInventory,
+		Default: false,
+		Usage: `Use Cloud Asset Inventory to find the descendants with AOD IAM ` +
+			`bindings with a single query, instead of listing all folders and ` +
+			`projects. Bindings added in the last few minutes may be missed.`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "verbose",
+		Target:  &c.flagVerbose,
+		Default: false,
+		Usage:   `Turn on verbose mode to print updated IAM policies. Note that it may contain sensitive information`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "resume-cursor",
+		Target:  &c.flagResumeCursor,
+		Example: "nightly",
+		Usage: `The ID of the cursor to save the swept resources to after ` +
+			`every batch, and to skip the resources already swept by previous ` +
+			`runs with the same cursor. The cursor is deleted when all the ` +
+			`resources are swept.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "cursor-project",
+		Target:  &c.flagCursorProject,
+		Example: "my-project",
+		Usage: `The project of the Firestore database to save the cursor to, ` +
+			`in collection "aod-cursors", required with resume-cursor.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "cursor-database",
+		Target:  &c.flagCursorDatabase,
+		Default: "(default)",
+		Example: "aod",
+		Usage:   `The Firestore database to save the cursor to.`,
+	})
+
+	f.IntVar(&cli.IntVar{
+		Name:    "cursor-batch-size",
+		Target:  &c.flagCursorBatchSize,
+		Default: 100,
+		Usage:   `The number of resources to sweep between cursor saves.`,
+	})
+
+	c.iamHandlerFlags.register(f)
+
+	return set
+}
+
+func (c *IAMSweepCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if len(c.flagResources) == 0 {
+		return fmt.Errorf("resource is required")
+	}
+
+	if c.flagResumeCursor != "" && c.flagCursorProject == "" {
+		return fmt.Errorf("cursor-project is required with resume-cursor")
+	}
+
+	if c.flagCursorBatchSize <= 0 {
+		return fmt.Errorf("cursor-batch-size must be positive, got %d", c.flagCursorBatchSize)
+	}
+
+	if err := c.iamHandlerFlags.validate(); err != nil {
+		return err
+	}
+
+	return c.sweep(ctx)
+}
+
+func (c *IAMSweepCommand) sweep(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	// Validate the resources as an IAMRequest.
+	req := &v1alpha1.IAMRequest{}
+	for _, r := range c.flagResources {
+		req.ResourcePolicies = append(req.ResourcePolicies, &v1alpha1.ResourcePolicy{Resource: r})
+	}
+	if err := v1alpha1.ValidateIAMRequest(req, nil); err != nil {
+		return withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate resources: %w", err))
+	}
+
+	var l descendantsLister
+	if c.testLister != nil {
+		// Use testLister if it is for testing.
+		l = c.testLister
+	} else if c.flagUseInventory {
+		searcher, err := inventory.NewSearcher(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to create inventory searcher: %w", err)
+		}
+		l = &inventoryLister{
+			searcher:       searcher,
+			conditionTitle: c.iamHandlerFlags.conditionTitle(),
+		}
+	} else {
+		walker, closer, newWalkerErr := newWalker(ctx)
+		if newWalkerErr != nil {
+			return newWalkerErr
+		}
+		l = walker
+		defer func() {
+			if err := closer.Close(); err != nil {
+				logger.ErrorContext(ctx, "failed to close", "error", err)
+			}
+		}()
+	}
+
+	var resources []string
+	for _, r := range c.flagResources {
+		if resource.HasType(r, resource.TypeProject) {
+			resources = append(resources, r)
+			continue
+		}
+		ds, err := l.Descendants(ctx, r)
+		if err != nil {
+			return fmt.Errorf("failed to list descendants of %q: %w", r, err)
+		}
+		resources = append(resources, ds...)
+	}
+	resources = dedupe(resources)
+
+	var h iamSweepHandler
+	if c.testHandler != nil {
+		// Use testHandler if it is for testing.
+		h = c.testHandler
+	} else {
+		iamHandler, closer, newHandlerErr := newIAMHandler(ctx, &c.iamHandlerFlags, &c.BaseCommand)
+		if newHandlerErr != nil {
+			return newHandlerErr
+		}
+		h = iamHandler
+		defer func() {
+			if err := closer.Close(); err != nil {
+				logger.ErrorContext(ctx, "failed to close", "error", err)
+			}
+		}()
+	}
+
+	var resp []*v1alpha1.IAMResponse
+	var resumed int
+	if c.flagResumeCursor == "" {
+		r, err := h.Sweep(ctx, resources)
+		if err != nil {
+			return withExitCode(apiExitCode(err, len(resources)),
+				fmt.Errorf("failed to sweep expired AOD bindings: %w", err))
+		}
+		resp = r
+	} else {
+		r, n, err := c.sweepWithCursor(ctx, h, resources)
+		if err != nil {
+			return err
+		}
+		resp, resumed = r, n
+	}
+
+	swept := make([]string, 0, len(resp))
+	skipped := make(map[string]string)
+	for _, r := range resp {
+		if r.Skipped != "" {
+			skipped[r.Resource] = r.Skipped
+			continue
+		}
+		swept = append(swept, r.Resource)
+	}
+	printMessageHeader(ctx, &c.BaseCommand, messages.HeaderSwept)
+	out := map[string]any{
+		"scanned": len(resources) - resumed,
+		"swept":   swept,
+	}
+	if resumed > 0 {
+		out["resumed"] = resumed
+	}
+	if len(skipped) > 0 {
+		out["skipped"] = skipped
+	}
+	if err := encodeYaml(c.Stdout(), out); err != nil {
+		return fmt.Errorf("failed to output swept resources: %w", err)
+	}
+
+	if c.flagVerbose {
+		printMessageHeader(ctx, &c.BaseCommand, messages.HeaderUpdatedPolicies)
+		if err := encodeYaml(c.Stdout(), resp); err != nil {
+			return fmt.Errorf("failed to output IAM policies: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// sweepWithCursor sweeps the resources not swept yet in the cursor, in batches,
+// and saves the cursor after every batch. The cursor is deleted when all the
+// resources are swept. It returns the responses and the number of resources
+// skipped since they were swept by previous runs.
+func (c *IAMSweepCommand) sweepWithCursor(ctx context.Context, h iamSweepHandler, resources []string) ([]*v1alpha1.IAMResponse, int, error) {
+	logger := logging.FromContext(ctx)
+
+	store := c.testCursorStore
+	if store == nil {
+		s, err := cursor.NewFirestoreStore(ctx, c.flagCursorProject, c.flagCursorDatabase)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to create cursor store: %w", err)
+		}
+		store = s
+		defer func() {
+			if err := s.Close(); err != nil {
+				logger.ErrorContext(ctx, "failed to close", "error", err)
+			}
+		}()
+	}
+
+	cur, err := store.Get(ctx, c.flagResumeCursor)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get cursor %q: %w", c.flagResumeCursor, err)
+	}
+	if cur == nil {
+		cur = &cursor.Cursor{ID: c.flagResumeCursor}
+	}
+
+	processed := make(map[string]struct{}, len(cur.Processed))
+	for _, r := range cur.Processed {
+		processed[r] = struct{}{}
+	}
+	remaining := make([]string, 0, len(resources))
+	for _, r := range resources {
+		if _, ok := processed[r]; !ok {
+			remaining = append(remaining, r)
+		}
+	}
+	resumed := len(resources) - len(remaining)
+	if resumed > 0 {
+		logger.InfoContext(ctx, "resuming sweep from cursor",
+			"cursor", c.flagResumeCursor,
+			"swept", resumed,
+			"remaining", len(remaining))
+	}
+
+	var resp []*v1alpha1.IAMResponse
+	for batch := range slices.Chunk(remaining, c.flagCursorBatchSize) {
+		r, err := h.Sweep(ctx, batch)
+		if err != nil {
+			// The previous batches are swept, so a failed batch is a partial
+			// failure unless it is the only one.
+			return nil, resumed, withExitCode(apiExitCode(err, len(remaining)),
+				fmt.Errorf("failed to sweep expired AOD bindings: %w", err))
+		}
+		resp = append(resp, r...)
+
+		cur.Processed = append(cur.Processed, batch...)
+		cur.UpdateTime = time.Now().UTC()
+		if err := store.Put(ctx, cur); err != nil {
+			return nil, resumed, fmt.Errorf("failed to save cursor %q: %w", c.flagResumeCursor, err)
+		}
+	}
+
+	if err := store.Delete(ctx, c.flagResumeCursor); err != nil {
+		return nil, resumed, fmt.Errorf("failed to delete cursor %q: %w", c.flagResumeCursor, err)
+	}
+	return resp, resumed, nil
+}
+
+// inventoryLister lists the organization or folder and its descendants that
+// have AOD IAM bindings with Cloud Asset Inventory.
+type inventoryLister struct {
+	searcher       *inventory.Searcher
+	conditionTitle string
+}
+
+// Descendants returns the root and the descendants that have AOD IAM bindings.
+func (l *inventoryLister) Descendants(ctx context.Context, root string) ([]string, error) {
+	bs, err := l.searcher.Search(ctx, root, l.conditionTitle)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Want passthrough
+	}
+	return inventory.Resources(bs), nil
+}
+
+// newWalker creates a hierarchy walker with new resource manager clients.
+func newWalker(ctx context.Context) (*hierarchy.Walker, *multicloser.Closer, error) {
+	var closer *multicloser.Closer
+
+	foldersClient, err := resourcemanager.NewFoldersClient(ctx)
+	if err != nil {
+		return nil, closer, fmt.Errorf("failed to create folders client: %w", err)
+	}
+	closer = multicloser.Append(closer, foldersClient.Close)
+
+	projectsClient, err := resourcemanager.NewProjectsClient(ctx)
+	if err != nil {
+		return nil, closer, fmt.Errorf("failed to create projects client: %w", err)
+	}
+	closer = multicloser.Append(closer, projectsClient.Close)
+
+	return hierarchy.NewWalker(foldersClient, projectsClient), closer, nil
+}
+
+// dedupe removes the duplicates from the resources, keeping the first
+// occurrences in order.
+func dedupe(resources []string) []string {
+	seen := make(map[string]struct{}, len(resources))
+	result := make([]string, 0, len(resources))
+	for _, r := range resources {
+		if _, ok := seen[r]; ok {
+			continue
+		}
+		seen[r] = struct{}{}
+		result = append(result, r)
+	}
+	return result
+}
@@ -0,0 +1,344 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/cursor"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestIAMSweepCommand(t *testing.T) {
+	t.Parallel()
+
+	descendants := map[string][]string{
+		"organizations/1": {"organizations/1", "projects/foo", "folders/2", "projects/bar"},
+		"folders/2":       {"folders/2", "projects/bar"},
+	}
+
+	cases := []struct {
+		name         string
+		args         []string
+		handler      *fakeIAMSweepHandler
+		lister       *fakeDescendantsLister
+		expResources []string
+		expOut       string
+		expErr       string
+	}{
+		{
+			name: "success",
+			args: []string{"-resource", "organizations/1"},
+			handler: &fakeIAMSweepHandler{
+				resp: []*v1alpha1.IAMResponse{{Resource: "projects/foo"}},
+			},
+			lister:       &fakeDescendantsLister{descendants: descendants},
+			expResources: []string{"organizations/1", "projects/foo", "folders/2", "projects/bar"},
+			expOut: `
+------Successfully Swept Expired AOD Bindings------
+scanned: 4
+swept:
+  - projects/foo`,
+		},
+		{
+			name: "success_skipped",
+			args: []string{"-resource", "organizations/1"},
+			handler: &fakeIAMSweepHandler{
+				resp: []*v1alpha1.IAMResponse{
+					{Resource: "projects/foo"},
+					{Resource: "projects/bar", Skipped: "resource projects/bar is not active (state: DELETE_REQUESTED)"},
+				},
+			},
+			lister:       &fakeDescendantsLister{descendants: descendants},
+			expResources: []string{"organizations/1", "projects/foo", "folders/2", "projects/bar"},
+			expOut: `
+------Successfully Swept Expired AOD Bindings------
+scanned: 4
+skipped:
+  projects/bar: 'resource projects/bar is not active (state: DELETE_REQUESTED)'
+swept:
+  - projects/foo`,
+		},
+		{
+			name:         "success_overlapping_resources",
+			args:         []string{"-resource", "folders/2", "-resource", "projects/baz", "-resource", "organizations/1"},
+			handler:      &fakeIAMSweepHandler{},
+			lister:       &fakeDescendantsLister{descendants: descendants},
+			expResources: []string{"folders/2", "projects/bar", "projects/baz", "organizations/1", "projects/foo"},
+			expOut: `
+------Successfully Swept Expired AOD Bindings------
+scanned: 5
+swept: []`,
+		},
+		{
+			name: "handler_failure",
+			args: []string{"-resource", "folders/2"},
+			handler: &fakeIAMSweepHandler{
+				injectErr: fmt.Errorf("injected error"),
+			},
+			lister:       &fakeDescendantsLister{descendants: descendants},
+			expResources: []string{"folders/2", "projects/bar"},
+			expErr:       "failed to sweep expired AOD bindings: injected error",
+		},
+		{
+			name:    "lister_failure",
+			args:    []string{"-resource", "folders/2"},
+			handler: &fakeIAMSweepHandler{},
+			lister:  &fakeDescendantsLister{injectErr: fmt.Errorf("injected error")},
+			expErr:  `failed to list descendants of "folders/2": injected error`,
+		},
+		{
+			name:    "invalid_resource",
+			args:    []string{"-resource", "foo/bar"},
+			handler: &fakeIAMSweepHandler{},
+			lister:  &fakeDescendantsLister{},
+			expErr:  `resource "foo/bar" isn't one of [organizations, folders, projects, buckets, secrets, serviceAccounts, bigquery/datasets, kms/keyrings, //<service>]`,
+		},
+		{
+			name:    "missing_resource",
+			args:    []string{},
+			handler: &fakeIAMSweepHandler{},
+			lister:  &fakeDescendantsLister{},
+			expErr:  "resource is required",
+		},
+		{
+			name:    "unexpected_args",
+			args:    []string{"foo"},
+			handler: &fakeIAMSweepHandler{},
+			lister:  &fakeDescendantsLister{},
+			expErr:  `unexpected arguments: ["foo"]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var cmd IAMSweepCommand
+			cmd.testHandler = tc.handler
+			cmd.testLister = tc.lister
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expResources, tc.handler.gotResources); diff != "" {
+				t.Errorf("Process(%+v) got resources diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestIAMSweepCommand_Cursor(t *testing.T) {
+	t.Parallel()
+
+	descendants := map[string][]string{
+		"organizations/1": {"organizations/1", "projects/foo", "folders/2", "projects/bar", "projects/baz"},
+	}
+	cursorArgs := []string{
+		"-resource", "organizations/1", "-resume-cursor", "nightly",
+		"-cursor-project", "my-project", "-cursor-batch-size", "2",
+	}
+
+	cases := []struct {
+		name       string
+		args       []string
+		handler    *fakeIAMSweepHandler
+		store      *fakeCursorStore
+		expBatches [][]string
+		expPuts    [][]string
+		expDeleted bool
+		expOut     string
+		expErr     string
+	}{
+		{
+			name:    "new_cursor",
+			args:    cursorArgs,
+			handler: &fakeIAMSweepHandler{},
+			store:   &fakeCursorStore{},
+			expBatches: [][]string{
+				{"organizations/1", "projects/foo"},
+				{"folders/2", "projects/bar"},
+				{"projects/baz"},
+			},
+			expPuts: [][]string{
+				{"organizations/1", "projects/foo"},
+				{"organizations/1", "projects/foo", "folders/2", "projects/bar"},
+				{"organizations/1", "projects/foo", "folders/2", "projects/bar", "projects/baz"},
+			},
+			expDeleted: true,
+			expOut: `
+------Successfully Swept Expired AOD Bindings------
+scanned: 5
+swept: []`,
+		},
+		{
+			name:    "resume_cursor",
+			args:    cursorArgs,
+			handler: &fakeIAMSweepHandler{},
+			store: &fakeCursorStore{
+				cursor: &cursor.Cursor{ID: "nightly", Processed: []string{"organizations/1", "projects/foo", "projects/old"}},
+			},
+			expBatches: [][]string{
+				{"folders/2", "projects/bar"},
+				{"projects/baz"},
+			},
+			expPuts: [][]string{
+				{"organizations/1", "projects/foo", "projects/old", "folders/2", "projects/bar"},
+				{"organizations/1", "projects/foo", "projects/old", "folders/2", "projects/bar", "projects/baz"},
+			},
+			expDeleted: true,
+			expOut: `
+------Successfully Swept Expired AOD Bindings------
+resumed: 2
+scanned: 3
+swept: []`,
+		},
+		{
+			name: "handler_failure",
+			args: cursorArgs,
+			handler: &fakeIAMSweepHandler{
+				injectErr: fmt.Errorf("injected error"),
+			},
+			store:      &fakeCursorStore{},
+			expBatches: [][]string{{"organizations/1", "projects/foo"}},
+			expErr:     "failed to sweep expired AOD bindings: injected error",
+		},
+		{
+			name:       "put_failure",
+			args:       cursorArgs,
+			handler:    &fakeIAMSweepHandler{},
+			store:      &fakeCursorStore{injectPutErr: fmt.Errorf("injected error")},
+			expBatches: [][]string{{"organizations/1", "projects/foo"}},
+			expErr:     `failed to save cursor "nightly": injected error`,
+		},
+		{
+			name:    "get_failure",
+			args:    cursorArgs,
+			handler: &fakeIAMSweepHandler{},
+			store:   &fakeCursorStore{injectGetErr: fmt.Errorf("injected error")},
+			expErr:  `failed to get cursor "nightly": injected error`,
+		},
+		{
+			name:    "missing_cursor_project",
+			args:    []string{"-resource", "organizations/1", "-resume-cursor", "nightly"},
+			handler: &fakeIAMSweepHandler{},
+			store:   &fakeCursorStore{},
+			expErr:  "cursor-project is required with resume-cursor",
+		},
+		{
+			name:    "invalid_batch_size",
+			args:    []string{"-resource", "organizations/1", "-cursor-batch-size", "0"},
+			handler: &fakeIAMSweepHandler{},
+			store:   &fakeCursorStore{},
+			expErr:  "cursor-batch-size must be positive, got 0",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var cmd IAMSweepCommand
+			cmd.testHandler = tc.handler
+			cmd.testLister = &fakeDescendantsLister{descendants: descendants}
+			cmd.testCursorStore = tc.store
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expBatches, tc.handler.gotBatches); diff != "" {
+				t.Errorf("Process(%+v) got batches diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expPuts, tc.store.gotPuts); diff != "" {
+				t.Errorf("Process(%+v) got cursor puts diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if got, want := tc.store.deleted, tc.expDeleted; got != want {
+				t.Errorf("Process(%+v) got cursor deleted %t, want %t", tc.name, got, want)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+type fakeIAMSweepHandler struct {
+	injectErr    error
+	gotResources []string
+	gotBatches   [][]string
+	resp         []*v1alpha1.IAMResponse
+}
+
+func (h *fakeIAMSweepHandler) Sweep(ctx context.Context, resources []string) ([]*v1alpha1.IAMResponse, error) {
+	h.gotResources = append(h.gotResources, resources...)
+	h.gotBatches = append(h.gotBatches, resources)
+	return h.resp, h.injectErr
+}
+
+type fakeDescendantsLister struct {
+	injectErr   error
+	descendants map[string][]string
+}
+
+func (l *fakeDescendantsLister) Descendants(ctx context.Context, root string) ([]string, error) {
+	if l.injectErr != nil {
+		return nil, l.injectErr
+	}
+	return l.descendants[root], nil
+}
+
+type fakeCursorStore struct {
+	injectGetErr error
+	injectPutErr error
+	cursor       *cursor.Cursor
+	gotPuts      [][]string
+	deleted      bool
+}
+
+func (s *fakeCursorStore) Get(ctx context.Context, id string) (*cursor.Cursor, error) {
+	return s.cursor, s.injectGetErr
+}
+
+func (s *fakeCursorStore) Put(ctx context.Context, c *cursor.Cursor) error {
+	if s.injectPutErr != nil {
+		return s.injectPutErr
+	}
+	s.gotPuts = append(s.gotPuts, slices.Clone(c.Processed))
+	return nil
+}
+
+func (s *fakeCursorStore) Delete(ctx context.Context, id string) error {
+	s.deleted = true
+	return nil
+}
@@ -0,0 +1,205 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/posener/complete/v2/predict"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/handler"
+	"github.com/abcxyz/access-on-demand/pkg/messages"
+	"github.com/abcxyz/access-on-demand/pkg/requestutil"
+	"github.com/abcxyz/access-on-demand/pkg/usage"
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+)
+
+var _ cli.Command = (*IAMUsageCommand)(nil)
+
+// iamUsageHandler interface that verifies the usage of an expired grant.
+type iamUsageHandler interface {
+	VerifyUsage(context.Context, *v1alpha1.IAMRequestWrapper) ([]*handler.ResourceUsage, error)
+}
+
+// IAMUsageCommand summarizes the activity of the members of an expired grant
+// in Cloud Audit Logs.
+type IAMUsageCommand struct {
+	cli.BaseCommand
+
+	flagPath string
+
+	flagStartTime time.Time
+
+	flagDuration time.Duration
+
+	flagMaxEntries int
+
+	iamHandlerFlags iamHandlerFlags
+
+	// testHandler is used for testing only.
+	testHandler iamUsageHandler
+}
+
+func (c *IAMUsageCommand) Desc() string {
+	return `Summarize the activity of the members of an expired grant of the IAM request YAML file in the given path`
+}
+
+func (c *IAMUsageCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Summarize the activity in Cloud Audit Logs of the members of the IAM request
+YAML file granted at the start time for 2 hours, and write it to the audit log
+project as "USAGE" audit events:
+
+      {{ COMMAND }} -path "/path/to/file.yaml" -start-time "2009-11-10T23:00:00Z" -duration "2h" -audit-log-project "my-project"
+
+The grant must have expired. Only "user" and "serviceAccount" members are
+summarized, and only the logs of the resources themselves are queried, e.g. the
+activity in the projects of a folder is not included for the folder.
+`
+}
+
+func (c *IAMUsageCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "path",
+		Target:  &c.flagPath,
+		Example: "/path/to/file.yaml",
+		Predict: predict.Files("*"),
+		Usage:   bundlePathUsage,
+	})
+
+	f.TimeVar(time.RFC3339, &cli.TimeVar{
+		Name:    "start-time",
+		Target:  &c.flagStartTime,
+		Example: "2009-11-10T23:00:00Z",
+		Usage:   `The start time of the grant in RFC3339 format.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "duration",
+		Target:  &c.flagDuration,
+		Example: "2h",
+		Usage:   `The duration of the grant.`,
+	})
+
+	f.IntVar(&cli.IntVar{
+		Name:    "max-entries",
+		Target:  &c.flagMaxEntries,
+		Default: usage.DefaultMaxEntries,
+		Usage: `The max number of Cloud Audit Logs entries to read per member ` +
+			`and resource, the usage is marked truncated if there are more.`,
+	})
+
+	c.iamHandlerFlags.register(f)
+
+	return set
+}
+
+func (c *IAMUsageCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagPath == "" {
+		return fmt.Errorf("path is required")
+	}
+
+	if c.flagStartTime.IsZero() {
+		return fmt.Errorf("start-time is required")
+	}
+
+	if c.flagDuration <= 0 {
+		return fmt.Errorf("a positive duration is required")
+	}
+
+	if c.flagMaxEntries < 1 {
+		return fmt.Errorf("max-entries must be at least 1, got %d", c.flagMaxEntries)
+	}
+
+	if err := c.iamHandlerFlags.validate(); err != nil {
+		return err
+	}
+
+	return c.verifyUsage(ctx)
+}
+
+func (c *IAMUsageCommand) verifyUsage(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	// Read request from file path.
+	rr := newRequestReader(c.Stdin())
+	docs, err := rr.iamBundle(c.flagPath)
+	if err != nil {
+		return withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &v1alpha1.IAMRequest{}, err))
+	}
+
+	req, err := requestutil.ValidateIAMBundle(docs, nil)
+	if err != nil {
+		return withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", req, err))
+	}
+
+	var h iamUsageHandler
+	if c.testHandler != nil {
+		// Use testHandler if it is for testing.
+		h = c.testHandler
+	} else {
+		q, err := usage.NewLoggingQuerier(ctx, c.flagMaxEntries)
+		if err != nil {
+			return fmt.Errorf("failed to create usage querier: %w", err)
+		}
+		iamHandler, closer, newHandlerErr := newIAMHandler(ctx, &c.iamHandlerFlags, &c.BaseCommand,
+			handler.WithUsageQuerier(q))
+		if newHandlerErr != nil {
+			return newHandlerErr
+		}
+		h = iamHandler
+		defer func() {
+			if err := closer.Close(); err != nil {
+				logger.ErrorContext(ctx, "failed to close", "error", err)
+			}
+		}()
+	}
+
+	reqWrapper := &v1alpha1.IAMRequestWrapper{
+		IAMRequest: req,
+		Duration:   c.flagDuration,
+		StartTime:  c.flagStartTime,
+	}
+	usages, err := h.VerifyUsage(ctx, reqWrapper)
+	if err != nil {
+		return withExitCode(ExitCodeAPIFailure, fmt.Errorf("failed to verify usage: %w", err))
+	}
+
+	printMessageHeader(ctx, &c.BaseCommand, messages.HeaderUsage)
+	if err := encodeYaml(c.Stdout(), usages); err != nil {
+		return fmt.Errorf("failed to output usage: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+	"github.com/abcxyz/access-on-demand/pkg/handler"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestIAMUsageCommand(t *testing.T) {
+	t.Parallel()
+
+	// Set up IAM request file.
+	requestFileContentByName := map[string]string{
+		"valid.yaml": `
+policies:
+- resource: projects/baz
+  bindings:
+    - members:
+      - user:test-project-user@example.com
+      role: roles/bigquery.dataViewer
+`,
+		"invalid.yaml": `bananas`,
+	}
+	dir := t.TempDir()
+	for name, content := range requestFileContentByName {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	usages := []*handler.ResourceUsage{{
+		Resource: "projects/baz",
+		Usages: []*audit.Usage{{
+			Member: "user:test-project-user@example.com",
+			Start:  start,
+			End:    start.Add(2 * time.Hour),
+			Calls:  2,
+			Methods: []*audit.MethodUsage{
+				{Service: "bigquery.googleapis.com", Method: "jobservice.insert", Calls: 2},
+			},
+			Permissions: []string{"bigquery.jobs.create"},
+		}},
+	}}
+	validReq := &v1alpha1.IAMRequestWrapper{
+		IAMRequest: &v1alpha1.IAMRequest{
+			ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+				Resource: "projects/baz",
+				Bindings: []*v1alpha1.Binding{{
+					Members: []string{"user:test-project-user@example.com"},
+					Role:    "roles/bigquery.dataViewer",
+				}},
+			}},
+		},
+		StartTime: start,
+		Duration:  2 * time.Hour,
+	}
+
+	cases := []struct {
+		name    string
+		args    []string
+		handler *fakeIAMUsageHandler
+		expReq  *v1alpha1.IAMRequestWrapper
+		expOut  string
+		expErr  string
+	}{
+		{
+			name:    "success",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-start-time", "2009-11-10T23:00:00Z", "-duration", "2h"},
+			handler: &fakeIAMUsageHandler{resp: usages},
+			expReq:  validReq,
+			expOut: `
+------Usage of Expired Grant------
+- resource: projects/baz
+  usages:
+    - member: user:test-project-user@example.com
+      start: 2009-11-10T23:00:00Z
+      end: 2009-11-11T01:00:00Z
+      calls: 2
+      methods:
+        - service: bigquery.googleapis.com
+          method: jobservice.insert
+          calls: 2
+      permissions:
+        - bigquery.jobs.create`,
+		},
+		{
+			name:    "handler_failure",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-start-time", "2009-11-10T23:00:00Z", "-duration", "2h"},
+			handler: &fakeIAMUsageHandler{injectErr: fmt.Errorf("injected error")},
+			expReq:  validReq,
+			expErr:  "failed to verify usage: injected error",
+		},
+		{
+			name:    "invalid_yaml",
+			args:    []string{"-path", filepath.Join(dir, "invalid.yaml"), "-start-time", "2009-11-10T23:00:00Z", "-duration", "2h"},
+			handler: &fakeIAMUsageHandler{},
+			expErr:  "failed to read *v1alpha1.IAMRequest",
+		},
+		{
+			name:    "missing_path",
+			args:    []string{"-start-time", "2009-11-10T23:00:00Z", "-duration", "2h"},
+			handler: &fakeIAMUsageHandler{},
+			expErr:  "path is required",
+		},
+		{
+			name:    "missing_start_time",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h"},
+			handler: &fakeIAMUsageHandler{},
+			expErr:  "start-time is required",
+		},
+		{
+			name:    "missing_duration",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-start-time", "2009-11-10T23:00:00Z"},
+			handler: &fakeIAMUsageHandler{},
+			expErr:  "a positive duration is required",
+		},
+		{
+			name:    "invalid_max_entries",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml"), "-start-time", "2009-11-10T23:00:00Z", "-duration", "2h", "-max-entries", "0"},
+			handler: &fakeIAMUsageHandler{},
+			expErr:  "max-entries must be at least 1, got 0",
+		},
+		{
+			name:    "unexpected_args",
+			args:    []string{"foo"},
+			handler: &fakeIAMUsageHandler{},
+			expErr:  `unexpected arguments: ["foo"]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var cmd IAMUsageCommand
+			cmd.testHandler = tc.handler
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expReq, tc.handler.gotReq); diff != "" {
+				t.Errorf("Process(%+v) got request diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+type fakeIAMUsageHandler struct {
+	injectErr error
+	gotReq    *v1alpha1.IAMRequestWrapper
+	resp      []*handler.ResourceUsage
+}
+
+func (h *fakeIAMUsageHandler) VerifyUsage(ctx context.Context, req *v1alpha1.IAMRequestWrapper) ([]*handler.ResourceUsage, error) {
+	h.gotReq = req
+	return h.resp, h.injectErr
+}
@@ -16,11 +16,13 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/posener/complete/v2/predict"
 
 	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/messages"
 	"github.com/abcxyz/access-on-demand/pkg/requestutil"
 	"github.com/abcxyz/pkg/cli"
 )
@@ -31,11 +33,25 @@ var _ cli.Command = (*IAMValidateCommand)(nil)
 type IAMValidateCommand struct {
 	cli.BaseCommand
 
-	flagPath string
+	flagPaths []string
+
+	flagAuditLogProject string
+
+	memberCheckFlags memberCheckFlags
+
+	roleCheckFlags roleCheckFlags
+
+	liveCheckFlags liveCheckFlags
+
+	policyFlags policyFlags
+
+	identityFlags identityFlags
+
+	entitlementFlags entitlementFlags
 }
 
 func (c *IAMValidateCommand) Desc() string {
-	return `Validate the IAM request YAML file at the given path`
+	return `Validate the IAM request YAML files at the given paths`
 }
 
 func (c *IAMValidateCommand) Help() string {
@@ -45,23 +61,53 @@ Usage: {{ COMMAND }} [options]
 Validate the IAM request YAML file at the given path:
 
       {{ COMMAND }} -path "/path/to/file.yaml"
+
+Validate all IAM request YAML files in the directory:
+
+      {{ COMMAND }} -path "/path/to/requests"
+
+Validate the IAM request and check its roles exist and are not deprecated:
+
+      {{ COMMAND }} -path "/path/to/file.yaml" -check-roles
+
+Validate the IAM request and report whether its resources exist and their IAM
+policies can be set with the current credentials:
+
+      {{ COMMAND }} -path "/path/to/file.yaml" -live
 `
 }
 
 func (c *IAMValidateCommand) Flags() *cli.FlagSet {
-	set := c.NewFlagSet()
+	set := newFlagSet(&c.BaseCommand)
 
 	// Command options
 	f := set.NewSection("COMMAND OPTIONS")
 
-	f.StringVar(&cli.StringVar{
+	f.StringSliceVar(&cli.StringSliceVar{
 		Name:    "path",
-		Target:  &c.flagPath,
+		Target:  &c.flagPaths,
 		Example: "/path/to/file.yaml",
 		Predict: predict.Files("*"),
-		Usage:   `The path of IAM request file, in YAML format.`,
+		Usage: `The path of IAM request file, in YAML format. Use "-" to ` +
+			`read it from stdin.` + multiPathUsage,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "audit-log-project",
+		Target:  &c.flagAuditLogProject,
+		Example: "my-project",
+		Usage: `The project to write validation denied audit events to, in ` +
+			`Cloud Logging log "aod-audit". Audit events are not written if it ` +
+			`is not set.`,
 	})
 
+	c.memberCheckFlags.register(f)
+	c.roleCheckFlags.register(f)
+	c.liveCheckFlags.register(f)
+	c.policyFlags.register(f)
+	c.identityFlags.register(f)
+	c.entitlementFlags.register(f)
+
 	return set
 }
 
@@ -75,24 +121,115 @@ func (c *IAMValidateCommand) Run(ctx context.Context, args []string) error {
 		return fmt.Errorf("unexpected arguments: %q", args)
 	}
 
-	if c.flagPath == "" {
+	if len(c.flagPaths) == 0 {
 		return fmt.Errorf("path is required")
 	}
 
+	if err := c.policyFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.identityFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.entitlementFlags.validate(); err != nil {
+		return err
+	}
+
 	return c.validate(ctx)
 }
 
 func (c *IAMValidateCommand) validate(ctx context.Context) error {
-	// Read request from YAML file.
-	var req v1alpha1.IAMRequest
-	if err := requestutil.ReadRequestFromPath(c.flagPath, &req); err != nil {
-		return fmt.Errorf("failed to read %T: %w", &req, err)
+	paths, err := expandRequestPaths(c.flagPaths)
+	if err != nil {
+		return err
+	}
+
+	rr := newRequestReader(c.Stdin())
+	var retErr error
+	var resources []string
+	for _, p := range paths {
+		req, err := c.validateFile(ctx, rr, p)
+		if err != nil {
+			retErr = errors.Join(retErr, withPath(paths, p, err))
+			continue
+		}
+		for _, rp := range req.ResourcePolicies {
+			resources = append(resources, rp.Resource)
+		}
+	}
+	if retErr != nil {
+		return retErr
+	}
+
+	if c.liveCheckFlags.flagLive {
+		if err := c.checkLive(ctx, dedupe(resources)); err != nil {
+			return err
+		}
 	}
 
-	if err := v1alpha1.ValidateIAMRequest(&req); err != nil {
-		return fmt.Errorf("failed to validate %T: %w", &req, err)
+	if len(paths) > 1 {
+		c.Outf("Successfully validated %d IAM requests", len(paths))
+	} else {
+		c.Outf("Successfully validated IAM request")
+	}
+	return nil
+}
+
+// validateFile validates the IAM requests of the bundle at the path, as they
+// are merged when the bundle is handled, and returns the merged request if it
+// is valid.
+func (c *IAMValidateCommand) validateFile(ctx context.Context, rr *requestReader, path string) (*v1alpha1.IAMRequest, error) {
+	// Read requests from file path.
+	docs, err := rr.iamBundle(path)
+	if err != nil {
+		return nil, withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &v1alpha1.IAMRequest{}, err))
 	}
-	c.Outf("Successfully validated IAM request")
 
+	if err := requestutil.ExpandIAMBundle(docs, c.entitlementFlags.catalog, 0); err != nil {
+		auditValidationDenied(ctx, c.flagAuditLogProject, err)
+		return nil, withExitCode(ExitCodeValidation, fmt.Errorf("failed to expand entitlements of %T: %w", &v1alpha1.IAMRequest{}, err))
+	}
+	if err := requestutil.ResolveIAMBundle(docs, c.identityFlags.identities); err != nil {
+		auditValidationDenied(ctx, c.flagAuditLogProject, err)
+		return nil, withExitCode(ExitCodeValidation, fmt.Errorf("failed to resolve members of %T: %w", &v1alpha1.IAMRequest{}, err))
+	}
+	req, err := requestutil.ValidateIAMBundle(docs, c.policyFlags.options(0))
+	if err != nil {
+		auditValidationDenied(ctx, c.flagAuditLogProject, err)
+		return nil, withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", req, err))
+	}
+	if err := c.memberCheckFlags.check(ctx, req, c.flagAuditLogProject); err != nil {
+		return nil, withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", req, err))
+	}
+	if err := c.roleCheckFlags.check(ctx, req, c.flagAuditLogProject); err != nil {
+		return nil, withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", req, err))
+	}
+	return req, nil
+}
+
+// checkLive outputs the readiness of the resources, and fails if any of them
+// is not ready.
+func (c *IAMValidateCommand) checkLive(ctx context.Context, resources []string) error {
+	rs, err := c.liveCheckFlags.check(ctx, resources)
+	if err != nil {
+		return withExitCode(ExitCodeAPIFailure, err)
+	}
+
+	printMessageHeader(ctx, &c.BaseCommand, messages.HeaderResourceReadiness)
+	if err := encodeYaml(c.Stdout(), rs); err != nil {
+		return fmt.Errorf("failed to output resource readiness: %w", err)
+	}
+
+	var notReady []string
+	for _, r := range rs {
+		if !r.Ready {
+			notReady = append(notReady, r.Resource)
+		}
+	}
+	if len(notReady) > 0 {
+		return withExitCode(ExitCodeValidation, fmt.Errorf("resources are not ready: %q", notReady))
+	}
 	return nil
 }
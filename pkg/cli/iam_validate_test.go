@@ -16,6 +16,7 @@ package cli
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -49,9 +50,48 @@ policies:
     - group:test-org-group@example.com
     - user:test-org-userB@example.com
     role: roles/cloudkms.cryptoOperator
+`,
+		"invalid-bundle.yaml": `
+policies:
+- resource: organizations/foo
+  bindings:
+  - members:
+    - user:test-org-userA@example.com
+    role: roles/cloudkms.cryptoOperator
+---
+policies:
+- resource: not-a-resource
+  bindings:
+  - members:
+    - bogus
+    role: roles/owner
 `,
 		"invalid-yaml.yaml": `bananas`,
 		"empty-file.yaml":   ``,
+		"deny-policy.yaml": `
+roles:
+- resources: ["organizations/*"]
+  deny: ["roles/cloudkms.*"]
+`,
+		"allow-policy.yaml": `
+roles:
+- allow: ["roles/cloudkms.cryptoOperator"]
+`,
+		"invalid-policy.yaml": `roles: bananas`,
+		"aliased-request.yaml": `
+policies:
+- resource: organizations/foo
+  bindings:
+  - members:
+    - github:octocat
+    - github:hubot
+    role: roles/cloudkms.cryptoOperator
+`,
+		"identities.yaml": `
+github:
+  octocat: user:test-org-userA@example.com
+`,
+		"invalid-identities.yaml": `user: {}`,
 	}
 	dir := t.TempDir()
 	for name, content := range requestFileContentByName {
@@ -64,7 +104,11 @@ policies:
 	cases := []struct {
 		name     string
 		args     []string
+		stdin    string
 		fileData []byte
+		checker  *fakeMemberChecker
+		roles    *fakeRoleChecker
+		live     *fakeResourceChecker
 		expOut   string
 		expErr   string
 	}{
@@ -73,6 +117,113 @@ policies:
 			args:   []string{"-path", filepath.Join(dir, "valid-request.yaml")},
 			expOut: "Successfully validated IAM request",
 		},
+		{
+			name:    "check_members_success",
+			args:    []string{"-path", filepath.Join(dir, "valid-request.yaml"), "-check-member-domain", "example.com"},
+			checker: &fakeMemberChecker{},
+			expOut:  "Successfully validated IAM request",
+		},
+		{
+			name:    "check_members_failure",
+			args:    []string{"-path", filepath.Join(dir, "valid-request.yaml"), "-check-member-domain", "example.com"},
+			checker: &fakeMemberChecker{injectErr: fmt.Errorf(`member "user:test-org-userB@example.com": user is suspended`)},
+			expErr:  `failed to check members: member "user:test-org-userB@example.com": user is suspended`,
+		},
+		{
+			name:   "check_roles_success",
+			args:   []string{"-path", filepath.Join(dir, "valid-request.yaml"), "-check-roles"},
+			roles:  &fakeRoleChecker{},
+			expOut: "Successfully validated IAM request",
+		},
+		{
+			name:   "check_roles_failure",
+			args:   []string{"-path", filepath.Join(dir, "valid-request.yaml"), "-check-roles"},
+			roles:  &fakeRoleChecker{injectErr: fmt.Errorf(`role "roles/cloudkms.cryptoOperator": role does not exist`)},
+			expErr: `failed to check roles: role "roles/cloudkms.cryptoOperator": role does not exist`,
+		},
+		{
+			name:  "live_ready",
+			args:  []string{"-path", filepath.Join(dir, "valid-request.yaml"), "-path", "-", "-live"},
+			stdin: requestFileContentByName["valid-request.yaml"],
+			live:  &fakeResourceChecker{},
+			expOut: `
+------Resource Readiness------
+- resource: organizations/foo
+  ready: true
+Successfully validated 2 IAM requests`,
+		},
+		{
+			name: "live_not_ready",
+			args: []string{"-path", filepath.Join(dir, "valid-request.yaml"), "-live"},
+			live: &fakeResourceChecker{notReady: map[string]string{
+				"organizations/foo": `resource organizations/foo is not accessible: missing permission "resourcemanager.organizations.setIamPolicy"`,
+			}},
+			expOut: `
+------Resource Readiness------
+- resource: organizations/foo
+  ready: false
+  error: 'resource organizations/foo is not accessible: missing permission "resourcemanager.organizations.setIamPolicy"'`,
+			expErr: `resources are not ready: ["organizations/foo"]`,
+		},
+		{
+			name:   "policy_allowed",
+			args:   []string{"-path", filepath.Join(dir, "valid-request.yaml"), "-policy", filepath.Join(dir, "allow-policy.yaml")},
+			expOut: "Successfully validated IAM request",
+		},
+		{
+			name:   "policy_denied",
+			args:   []string{"-path", filepath.Join(dir, "valid-request.yaml"), "-policy", filepath.Join(dir, "deny-policy.yaml")},
+			expErr: `policies[0].bindings[0].role at line 8, column 11: role "roles/cloudkms.cryptoOperator" is denied (matches "roles/cloudkms.*") on organizations/foo`,
+		},
+		{
+			name:   "invalid_policy",
+			args:   []string{"-path", filepath.Join(dir, "valid-request.yaml"), "-policy", filepath.Join(dir, "invalid-policy.yaml")},
+			expErr: "invalid policy: invalid policy file",
+		},
+		{
+			name:   "identity_map",
+			args:   []string{"-path", "-", "-identity-map", filepath.Join(dir, "identities.yaml")},
+			stdin:  strings.ReplaceAll(requestFileContentByName["valid-request.yaml"], "user:test-org-userA@example.com", "github:OctoCat"),
+			expOut: "Successfully validated IAM request",
+		},
+		{
+			name:   "identity_map_unknown_alias",
+			args:   []string{"-path", filepath.Join(dir, "aliased-request.yaml"), "-identity-map", filepath.Join(dir, "identities.yaml")},
+			expErr: `policies[0].bindings[0].members[1] at line 7, column 7: unknown github identity "hubot"`,
+		},
+		{
+			name:   "alias_without_identity_map",
+			args:   []string{"-path", filepath.Join(dir, "aliased-request.yaml")},
+			expErr: `policies[0].bindings[0].members[0] at line 6, column 7: member "github:octocat" is not of "user" type`,
+		},
+		{
+			name:   "invalid_identity_map",
+			args:   []string{"-path", filepath.Join(dir, "valid-request.yaml"), "-identity-map", filepath.Join(dir, "invalid-identities.yaml")},
+			expErr: `invalid identity map: invalid identity map file`,
+		},
+		{
+			name:   "stdin",
+			args:   []string{"-path", "-"},
+			stdin:  requestFileContentByName["valid-request.yaml"],
+			expOut: "Successfully validated IAM request",
+		},
+		{
+			name:   "stdin_invalid_request",
+			args:   []string{"-path", "-"},
+			stdin:  requestFileContentByName["invalid-request.yaml"],
+			expErr: `policies[0].bindings[0].members[0] at line 6, column 7: member "group:test-org-group@example.com" is not of "user" type`,
+		},
+		{
+			name:   "multiple_paths",
+			args:   []string{"-path", filepath.Join(dir, "valid-request.yaml"), "-path", "-"},
+			stdin:  requestFileContentByName["valid-request.yaml"],
+			expOut: "Successfully validated 2 IAM requests",
+		},
+		{
+			name:   "multiple_paths_invalid_request",
+			args:   []string{"-path", filepath.Join(dir, "valid-request.yaml"), "-path", filepath.Join(dir, "invalid-request.yaml")},
+			expErr: filepath.Join(dir, "invalid-request.yaml") + `: failed to validate *v1alpha1.IAMRequest`,
+		},
 		{
 			name:   "empty_file",
 			args:   []string{"-path", filepath.Join(dir, "empty-file.yaml")},
@@ -86,7 +237,18 @@ policies:
 		{
 			name:   "invalid_request",
 			args:   []string{"-path", filepath.Join(dir, "invalid-request.yaml")},
-			expErr: "failed to validate *v1alpha1.IAMRequest",
+			expErr: `policies[0].bindings[0].members[0] at line 6, column 7: member "group:test-org-group@example.com" is not of "user" type`,
+		},
+		{
+			name:   "bundle",
+			args:   []string{"-path", "-"},
+			stdin:  requestFileContentByName["valid-request.yaml"] + "---\n" + requestFileContentByName["valid-request.yaml"],
+			expOut: "Successfully validated IAM request",
+		},
+		{
+			name:   "bundle_invalid_document",
+			args:   []string{"-path", filepath.Join(dir, "invalid-bundle.yaml")},
+			expErr: `invalid-bundle.yaml#1: policies[0].resource at line 10, column 13: resource "not-a-resource" isn't one of`,
 		},
 		{
 			name:   "unexpected_args",
@@ -107,7 +269,17 @@ policies:
 			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
 
 			var cmd IAMValidateCommand
-			_, stdout, _ := cmd.Pipe()
+			if tc.checker != nil {
+				cmd.memberCheckFlags.testChecker = tc.checker
+			}
+			if tc.roles != nil {
+				cmd.roleCheckFlags.testChecker = tc.roles
+			}
+			if tc.live != nil {
+				cmd.liveCheckFlags.testChecker = tc.live
+			}
+			stdin, stdout, _ := cmd.Pipe()
+			stdin.WriteString(tc.stdin)
 
 			args := append([]string{}, tc.args...)
 
@@ -118,6 +290,24 @@ policies:
 			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
 				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
 			}
+			if tc.checker != nil {
+				wantMembers := []string{"user:test-org-userA@example.com", "user:test-org-userB@example.com"}
+				if diff := cmp.Diff(wantMembers, tc.checker.gotMembers); diff != "" {
+					t.Errorf("Process(%+v) got checked members diff (-want, +got):\n%s", tc.name, diff)
+				}
+			}
+			if tc.roles != nil {
+				wantRoles := []string{"roles/cloudkms.cryptoOperator"}
+				if diff := cmp.Diff(wantRoles, tc.roles.gotRoles); diff != "" {
+					t.Errorf("Process(%+v) got checked roles diff (-want, +got):\n%s", tc.name, diff)
+				}
+			}
+			if tc.live != nil {
+				wantResources := []string{"organizations/foo"}
+				if diff := cmp.Diff(wantResources, tc.live.gotResources); diff != "" {
+					t.Errorf("Process(%+v) got checked resources diff (-want, +got):\n%s", tc.name, diff)
+				}
+			}
 		})
 	}
 }
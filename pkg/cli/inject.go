@@ -0,0 +1,92 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/googleapis/gax-go/v2"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/handler"
+)
+
+// Stages where a failure can be injected with the hidden "-inject-failure"
+// flag. They are used to exercise downstream workflow handling of AOD failures
+// without breaking real resources.
+const (
+	failureStageParse     = "parse"
+	failureStageGetPolicy = "get-policy"
+	failureStageSetPolicy = "set-policy"
+	failureStageToolExec  = "tool-exec"
+)
+
+var (
+	// iamFailureStages are the stages supported by IAM commands.
+	iamFailureStages = []string{failureStageParse, failureStageGetPolicy, failureStageSetPolicy}
+
+	// toolFailureStages are the stages supported by tool commands.
+	toolFailureStages = []string{failureStageParse, failureStageToolExec}
+
+	// errInjectedFailure is the error returned at the injected stage.
+	errInjectedFailure = errors.New("injected failure")
+)
+
+// checkFailureStage checks if the stage is empty or one of the allowed stages.
+func checkFailureStage(stage string, allowed []string) error {
+	if stage == "" || slices.Contains(allowed, stage) {
+		return nil
+	}
+	return fmt.Errorf("invalid inject-failure stage %q, must be one of %q", stage, allowed)
+}
+
+// injectFailure returns an injected error if the stage is the injected stage.
+func injectFailure(injected, stage string) error {
+	if injected != stage {
+		return nil
+	}
+	return fmt.Errorf("%w at stage %q", errInjectedFailure, stage)
+}
+
+// failingIAMClient wraps an IAMClient and fails the injected stage.
+type failingIAMClient struct {
+	handler.IAMClient
+	stage string
+}
+
+func (c *failingIAMClient) GetIamPolicy(ctx context.Context, req *iampb.GetIamPolicyRequest, opts ...gax.CallOption) (*iampb.Policy, error) {
+	if err := injectFailure(c.stage, failureStageGetPolicy); err != nil {
+		return nil, err
+	}
+	return c.IAMClient.GetIamPolicy(ctx, req, opts...) //nolint:wrapcheck // Want passthrough
+}
+
+func (c *failingIAMClient) SetIamPolicy(ctx context.Context, req *iampb.SetIamPolicyRequest, opts ...gax.CallOption) (*iampb.Policy, error) {
+	if err := injectFailure(c.stage, failureStageSetPolicy); err != nil {
+		return nil, err
+	}
+	return c.IAMClient.SetIamPolicy(ctx, req, opts...) //nolint:wrapcheck // Want passthrough
+}
+
+// failingToolHandler fails the tool execution without running any commands.
+type failingToolHandler struct{}
+
+func (h *failingToolHandler) Do(context.Context, *v1alpha1.ToolRequest) error {
+	return injectFailure(failureStageToolExec, failureStageToolExec)
+}
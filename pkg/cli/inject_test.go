@@ -0,0 +1,79 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/googleapis/gax-go/v2"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestFailingIAMClient(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		stage      string
+		wantGetErr string
+		wantSetErr string
+	}{
+		{
+			name:       "get_policy",
+			stage:      failureStageGetPolicy,
+			wantGetErr: `injected failure at stage "get-policy"`,
+		},
+		{
+			name:       "set_policy",
+			stage:      failureStageSetPolicy,
+			wantSetErr: `injected failure at stage "set-policy"`,
+		},
+		{
+			name:  "other_stage",
+			stage: failureStageParse,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			c := &failingIAMClient{IAMClient: &fakeIAMClient{}, stage: tc.stage}
+
+			_, gotErr := c.GetIamPolicy(ctx, &iampb.GetIamPolicyRequest{})
+			if diff := testutil.DiffErrString(gotErr, tc.wantGetErr); diff != "" {
+				t.Errorf("GetIamPolicy got unexpected error: %s", diff)
+			}
+			_, gotErr = c.SetIamPolicy(ctx, &iampb.SetIamPolicyRequest{})
+			if diff := testutil.DiffErrString(gotErr, tc.wantSetErr); diff != "" {
+				t.Errorf("SetIamPolicy got unexpected error: %s", diff)
+			}
+		})
+	}
+}
+
+type fakeIAMClient struct{}
+
+func (c *fakeIAMClient) GetIamPolicy(context.Context, *iampb.GetIamPolicyRequest, ...gax.CallOption) (*iampb.Policy, error) {
+	return &iampb.Policy{}, nil
+}
+
+func (c *fakeIAMClient) SetIamPolicy(_ context.Context, r *iampb.SetIamPolicyRequest, _ ...gax.CallOption) (*iampb.Policy, error) {
+	return r.GetPolicy(), nil
+}
@@ -0,0 +1,289 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/api/idtoken"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/messages"
+	"github.com/abcxyz/access-on-demand/pkg/server"
+	"github.com/abcxyz/pkg/cli"
+)
+
+var (
+	_ cli.Command = (*OpStatusCommand)(nil)
+	_ cli.Command = (*OpWaitCommand)(nil)
+)
+
+// operationClient enqueues IAM requests as detached operations on an AOD
+// server and gets the operations.
+type operationClient interface {
+	DetachHandle(ctx context.Context, req *v1alpha1.IAMRequestWrapper) (*server.Operation, error)
+	DetachCleanup(ctx context.Context, req *v1alpha1.IAMRequest) (*server.Operation, error)
+	GetOperation(ctx context.Context, id string) (*server.Operation, error)
+}
+
+// serverFlags are the flags of the AOD server to call.
+type serverFlags struct {
+	flagServerURL string
+
+	// testClient is used for testing only.
+	testClient operationClient
+}
+
+// register registers the server flags to the given flag section.
+func (s *serverFlags) register(f *cli.FlagSection) {
+	f.StringVar(&cli.StringVar{
+		Name:    "server-url",
+		Target:  &s.flagServerURL,
+		EnvVar:  "AOD_SERVER_URL",
+		Example: "https://aod.example.com",
+		Usage: `The URL of the AOD server, see "aod server". The server is ` +
+			`called with a Google-signed ID token with the URL as the audience.`,
+	})
+}
+
+// client creates the client of the AOD server.
+func (s *serverFlags) client(ctx context.Context) (operationClient, error) {
+	if s.testClient != nil {
+		return s.testClient, nil
+	}
+	if s.flagServerURL == "" {
+		return nil, fmt.Errorf("server-url is required")
+	}
+	hc, err := idtoken.NewClient(ctx, s.flagServerURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create authenticated http client: %w", err)
+	}
+	c, err := server.NewClient(hc, s.flagServerURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create server client: %w", err)
+	}
+	return c, nil
+}
+
+// detachFlags are the flags to enqueue requests as detached operations on an
+// AOD server instead of handling them in the CLI.
+type detachFlags struct {
+	serverFlags
+
+	flagDetach bool
+}
+
+// register registers the detach flags to the given flag section.
+func (d *detachFlags) register(f *cli.FlagSection) {
+	f.BoolVar(&cli.BoolVar{
+		Name:    "detach",
+		Target:  &d.flagDetach,
+		Default: false,
+		Usage: `Enqueue the requests as detached operations on the AOD ` +
+			`server of server-url and return the operation IDs immediately, ` +
+			`instead of handling them in the CLI. See "aod op status" and ` +
+			`"aod op wait".`,
+	})
+
+	d.serverFlags.register(f)
+}
+
+// validate validates the detach flags.
+func (d *detachFlags) validate() error {
+	if d.flagDetach && d.flagServerURL == "" && d.testClient == nil {
+		return fmt.Errorf("server-url is required with detach")
+	}
+	return nil
+}
+
+// printOperations prints the detached operations to the stdout of the command.
+func printOperations(ctx context.Context, cmd *cli.BaseCommand, ops []*server.Operation) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	printMessageHeader(ctx, cmd, messages.HeaderDetached)
+	if err := encodeYaml(cmd.Stdout(), ops); err != nil {
+		return fmt.Errorf("failed to output operations: %w", err)
+	}
+	return nil
+}
+
+// OpStatusCommand gets the status of a detached operation.
+type OpStatusCommand struct {
+	cli.BaseCommand
+
+	serverFlags serverFlags
+}
+
+func (c *OpStatusCommand) Desc() string {
+	return `Get the status of a detached operation on the AOD server`
+}
+
+func (c *OpStatusCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options] <id>
+
+Get the status of the detached operation with the ID:
+
+      {{ COMMAND }} -server-url "https://aod.example.com" "0123456789abcdef"
+`
+}
+
+func (c *OpStatusCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	c.serverFlags.register(f)
+
+	return set
+}
+
+func (c *OpStatusCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) != 1 {
+		return fmt.Errorf("expected exactly one operation ID, got %q", args)
+	}
+
+	client, err := c.serverFlags.client(ctx)
+	if err != nil {
+		return err
+	}
+	op, err := client.GetOperation(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("failed to get operation %q: %w", args[0], err)
+	}
+	return printOperation(ctx, &c.BaseCommand, op)
+}
+
+// OpWaitCommand waits for a detached operation to be done.
+type OpWaitCommand struct {
+	cli.BaseCommand
+
+	flagPollInterval time.Duration
+
+	flagTimeout time.Duration
+
+	serverFlags serverFlags
+}
+
+func (c *OpWaitCommand) Desc() string {
+	return `Wait for a detached operation on the AOD server to be done`
+}
+
+func (c *OpWaitCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options] <id>
+
+Wait for the detached operation with the ID to be done, it fails if the
+operation failed:
+
+      {{ COMMAND }} -server-url "https://aod.example.com" -timeout "30m" "0123456789abcdef"
+`
+}
+
+func (c *OpWaitCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "poll-interval",
+		Target:  &c.flagPollInterval,
+		Default: 5 * time.Second,
+		Example: "10s",
+		Usage:   `The interval to poll the status of the operation.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "timeout",
+		Target:  &c.flagTimeout,
+		Default: 30 * time.Minute,
+		Example: "1h",
+		Usage:   `The max duration to wait for the operation, 0 to wait indefinitely.`,
+	})
+
+	c.serverFlags.register(f)
+
+	return set
+}
+
+func (c *OpWaitCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) != 1 {
+		return fmt.Errorf("expected exactly one operation ID, got %q", args)
+	}
+
+	if c.flagPollInterval <= 0 {
+		return fmt.Errorf("poll-interval must be positive")
+	}
+	if c.flagTimeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+
+	client, err := c.serverFlags.client(ctx)
+	if err != nil {
+		return err
+	}
+	if c.flagTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.flagTimeout)
+		defer cancel()
+	}
+
+	id := args[0]
+	for {
+		op, err := client.GetOperation(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to get operation %q: %w", id, err)
+		}
+		if op.Done {
+			if err := printOperation(ctx, &c.BaseCommand, op); err != nil {
+				return err
+			}
+			if op.Error != "" {
+				return fmt.Errorf("operation %q failed: %s", id, op.Error)
+			}
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to wait for operation %q: %w", id, ctx.Err())
+		case <-time.After(c.flagPollInterval):
+		}
+	}
+}
+
+// printOperation prints the operation to the stdout of the command.
+func printOperation(ctx context.Context, cmd *cli.BaseCommand, op *server.Operation) error {
+	printMessageHeader(ctx, cmd, messages.HeaderOperation)
+	if err := encodeYaml(cmd.Stdout(), op); err != nil {
+		return fmt.Errorf("failed to output operation: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/server"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestOpWaitCommand(t *testing.T) {
+	t.Parallel()
+
+	createTime := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	pending := &server.Operation{ID: "op1", Type: server.OperationTypeHandle, CreateTime: createTime, UpdateTime: createTime}
+	done := &server.Operation{ID: "op1", Type: server.OperationTypeHandle, Done: true, CreateTime: createTime, UpdateTime: createTime.Add(time.Minute)}
+	failed := &server.Operation{ID: "op1", Type: server.OperationTypeHandle, Done: true, Error: "injected error", CreateTime: createTime, UpdateTime: createTime.Add(time.Minute)}
+
+	cases := []struct {
+		name    string
+		args    []string
+		client  *fakeOperationClient
+		expGets int
+		expOut  string
+		expErr  string
+	}{
+		{
+			name:    "success",
+			args:    []string{"-poll-interval", "1ms", "op1"},
+			client:  &fakeOperationClient{ops: []*server.Operation{pending, pending, done}},
+			expGets: 3,
+			expOut: `
+------Operation------
+id: op1
+type: handle
+done: true
+createTime: 2009-11-10T23:00:00Z
+updateTime: 2009-11-10T23:01:00Z`,
+		},
+		{
+			name:    "operation_failure",
+			args:    []string{"-poll-interval", "1ms", "op1"},
+			client:  &fakeOperationClient{ops: []*server.Operation{failed}},
+			expGets: 1,
+			expOut: `
+------Operation------
+id: op1
+type: handle
+done: true
+error: injected error
+createTime: 2009-11-10T23:00:00Z
+updateTime: 2009-11-10T23:01:00Z`,
+			expErr: `operation "op1" failed: injected error`,
+		},
+		{
+			name:    "timeout",
+			args:    []string{"-poll-interval", "1ms", "-timeout", "20ms", "op1"},
+			client:  &fakeOperationClient{ops: []*server.Operation{pending}},
+			expErr:  `failed to wait for operation "op1": context deadline exceeded`,
+			expGets: -1,
+		},
+		{
+			name:    "get_failure",
+			args:    []string{"op1"},
+			client:  &fakeOperationClient{injectErr: fmt.Errorf("injected error")},
+			expErr:  `failed to get operation "op1": injected error`,
+			expGets: 1,
+		},
+		{
+			name:   "missing_id",
+			args:   []string{},
+			client: &fakeOperationClient{},
+			expErr: `expected exactly one operation ID, got []`,
+		},
+		{
+			name:   "invalid_poll_interval",
+			args:   []string{"-poll-interval", "0s", "op1"},
+			client: &fakeOperationClient{},
+			expErr: `poll-interval must be positive`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var cmd OpWaitCommand
+			cmd.serverFlags.testClient = tc.client
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+			// The number of polls is not deterministic with a timeout.
+			if tc.expGets >= 0 && tc.client.gets != tc.expGets {
+				t.Errorf("Process(%+v) got %d polls, want %d", tc.name, tc.client.gets, tc.expGets)
+			}
+		})
+	}
+}
+
+func TestOpStatusCommand(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	createTime := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	var cmd OpStatusCommand
+	cmd.serverFlags.testClient = &fakeOperationClient{ops: []*server.Operation{
+		{ID: "op1", Type: server.OperationTypeCleanup, CreateTime: createTime, UpdateTime: createTime},
+	}}
+	_, stdout, _ := cmd.Pipe()
+
+	if err := cmd.Run(ctx, []string{"op1"}); err != nil {
+		t.Fatal(err)
+	}
+	want := `
+------Operation------
+id: op1
+type: cleanup
+done: false
+createTime: 2009-11-10T23:00:00Z
+updateTime: 2009-11-10T23:00:00Z`
+	if diff := cmp.Diff(strings.TrimSpace(want), strings.TrimSpace(stdout.String())); diff != "" {
+		t.Errorf("got output diff (-want, +got):\n%s", diff)
+	}
+}
+
+func TestIAMHandleCommand_Detach(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	path := filepath.Join(t.TempDir(), "iam.yaml")
+	content := "policies:\n- resource: projects/a\n  bindings:\n  - members: [user:alice@example.com]\n    role: roles/viewer\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	createTime := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	client := &fakeOperationClient{ops: []*server.Operation{
+		{ID: "op1", Type: server.OperationTypeHandle, CreateTime: createTime, UpdateTime: createTime},
+	}}
+	h := &fakeIAMHandler{}
+	var cmd IAMHandleCommand
+	cmd.testHandler = h
+	cmd.detachFlags.testClient = client
+	_, stdout, _ := cmd.Pipe()
+
+	if err := cmd.Run(ctx, []string{"-path", path, "-duration", "2h", "-detach"}); err != nil {
+		t.Fatal(err)
+	}
+	want := `
+------Successfully Detached IAM Requests------
+- id: op1
+  type: handle
+  done: false
+  createTime: 2009-11-10T23:00:00Z
+  updateTime: 2009-11-10T23:00:00Z`
+	if diff := cmp.Diff(strings.TrimSpace(want), strings.TrimSpace(stdout.String())); diff != "" {
+		t.Errorf("got output diff (-want, +got):\n%s", diff)
+	}
+	if got, want := len(client.gotHandles), 1; got != want {
+		t.Fatalf("got %d detached requests, want %d", got, want)
+	}
+	if got, want := client.gotHandles[0].Duration, 2*time.Hour; got != want {
+		t.Errorf("got detached duration %s, want %s", got, want)
+	}
+	if h.gotReq != nil {
+		t.Errorf("got request handled in the CLI %+v, want none", h.gotReq)
+	}
+}
+
+type fakeOperationClient struct {
+	// ops are returned in order by the calls, the last one is repeated.
+	ops       []*server.Operation
+	injectErr error
+
+	gets       int
+	gotHandles []*v1alpha1.IAMRequestWrapper
+	gotCleanup []*v1alpha1.IAMRequest
+}
+
+func (c *fakeOperationClient) next() (*server.Operation, error) {
+	if c.injectErr != nil {
+		return nil, c.injectErr
+	}
+	op := c.ops[0]
+	if len(c.ops) > 1 {
+		c.ops = c.ops[1:]
+	}
+	return op, nil
+}
+
+func (c *fakeOperationClient) DetachHandle(ctx context.Context, req *v1alpha1.IAMRequestWrapper) (*server.Operation, error) {
+	c.gotHandles = append(c.gotHandles, req)
+	return c.next()
+}
+
+func (c *fakeOperationClient) DetachCleanup(ctx context.Context, req *v1alpha1.IAMRequest) (*server.Operation, error) {
+	c.gotCleanup = append(c.gotCleanup, req)
+	return c.next()
+}
+
+func (c *fakeOperationClient) GetOperation(ctx context.Context, id string) (*server.Operation, error) {
+	c.gets++
+	return c.next()
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	resourcemanager "cloud.google.com/go/resourcemanager/apiv3"
+	"google.golang.org/api/option"
+
+	"github.com/abcxyz/access-on-demand/pkg/handler"
+	"github.com/abcxyz/access-on-demand/pkg/hierarchy"
+	"github.com/abcxyz/access-on-demand/pkg/requestutil"
+	"github.com/abcxyz/access-on-demand/pkg/resource"
+	"github.com/abcxyz/pkg/multicloser"
+)
+
+// orgConfigs is the configuration of the clients of each organization, for
+// operating AOD across organizations with separate credentials or endpoints.
+type orgConfigs struct {
+	Organizations []*orgConfig `yaml:"organizations,omitempty"`
+}
+
+// orgConfig is the configuration of the clients of an organization, which are
+// used for the organization and its descendant folders and projects.
+type orgConfig struct {
+	// Organization is the name of the organization, e.g. "organizations/123".
+	Organization string `yaml:"organization,omitempty"`
+
+	// Optional path of the credentials JSON file, default is the application
+	// default credentials.
+	CredentialsFile string `yaml:"credentialsFile,omitempty"`
+
+	// Optional endpoint of Cloud Resource Manager, default is the global
+	// endpoint.
+	Endpoint string `yaml:"endpoint,omitempty"`
+}
+
+// readOrgConfigs reads and validates the organization configs at the path.
+func readOrgConfigs(path string) (*orgConfigs, error) {
+	var c orgConfigs
+	if err := requestutil.ReadRequestFromPath(path, &c); err != nil {
+		return nil, fmt.Errorf("failed to read %T: %w", &c, err)
+	}
+	if len(c.Organizations) == 0 {
+		return nil, fmt.Errorf("no organizations in %q", path)
+	}
+	seen := make(map[string]struct{}, len(c.Organizations))
+	for _, o := range c.Organizations {
+		if !resource.HasType(o.Organization, resource.TypeOrganization) {
+			return nil, fmt.Errorf("invalid organization %q, must be organizations/<id>", o.Organization)
+		}
+		if _, ok := seen[o.Organization]; ok {
+			return nil, fmt.Errorf("duplicate organization %q", o.Organization)
+		}
+		seen[o.Organization] = struct{}{}
+	}
+	return &c, nil
+}
+
+// clientOptions returns the options to create the clients of the organization.
+func (o *orgConfig) clientOptions() []option.ClientOption {
+	var opts []option.ClientOption
+	if o.CredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(o.CredentialsFile))
+	}
+	if o.Endpoint != "" {
+		opts = append(opts, option.WithEndpoint(o.Endpoint))
+	}
+	return opts
+}
+
+// newOrgHandlerOptions creates the clients of each organization, and returns
+// the IAMHandler options to route the resources of each organization to its
+// clients. The organizations and states of folders and projects are found with
+// the organizations' clients, and then the default clients. Every IAM client is
+// wrapped with wrap.
+func newOrgHandlerOptions(
+	ctx context.Context,
+	c *orgConfigs,
+	defaultWalker *hierarchy.Walker,
+	wrap func(handler.IAMClient) handler.IAMClient,
+) ([]handler.Option, *multicloser.Closer, error) {
+	var closer *multicloser.Closer
+	var opts []handler.Option
+	walkers := make([]*hierarchy.Walker, 0, len(c.Organizations)+1)
+	for _, o := range c.Organizations {
+		organizationsClient, err := resourcemanager.NewOrganizationsClient(ctx, o.clientOptions()...)
+		if err != nil {
+			return nil, closer, fmt.Errorf("failed to create organizations client of %q: %w", o.Organization, err)
+		}
+		closer = multicloser.Append(closer, organizationsClient.Close)
+
+		foldersClient, err := resourcemanager.NewFoldersClient(ctx, o.clientOptions()...)
+		if err != nil {
+			return nil, closer, fmt.Errorf("failed to create folders client of %q: %w", o.Organization, err)
+		}
+		closer = multicloser.Append(closer, foldersClient.Close)
+
+		projectsClient, err := resourcemanager.NewProjectsClient(ctx, o.clientOptions()...)
+		if err != nil {
+			return nil, closer, fmt.Errorf("failed to create projects client of %q: %w", o.Organization, err)
+		}
+		closer = multicloser.Append(closer, projectsClient.Close)
+
+		opts = append(opts, handler.WithOrganizationClients(
+			o.Organization,
+			wrap(organizationsClient),
+			wrap(foldersClient),
+			wrap(projectsClient),
+		))
+		walkers = append(walkers, hierarchy.NewWalker(foldersClient, projectsClient))
+	}
+	walkers = append(walkers, defaultWalker)
+	resolver := hierarchy.NewResolver(walkers...)
+	opts = append(opts,
+		handler.WithOrganizationResolver(resolver),
+		handler.WithStateChecker(resolver),
+	)
+	return opts, closer, nil
+}
@@ -0,0 +1,144 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestReadOrgConfigs(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		file    string
+		want    *orgConfigs
+		wantErr string
+	}{
+		{
+			name: "success",
+			file: `
+organizations:
+- organization: organizations/123
+  credentialsFile: /path/to/customer-a.json
+- organization: organizations/456
+  endpoint: https://cloudresourcemanager.example.com
+`,
+			want: &orgConfigs{
+				Organizations: []*orgConfig{
+					{
+						Organization:    "organizations/123",
+						CredentialsFile: "/path/to/customer-a.json",
+					},
+					{
+						Organization: "organizations/456",
+						Endpoint:     "https://cloudresourcemanager.example.com",
+					},
+				},
+			},
+		},
+		{
+			name:    "no_organizations",
+			file:    `organizations: []`,
+			wantErr: "no organizations in",
+		},
+		{
+			name: "invalid_organization",
+			file: `
+organizations:
+- organization: folders/123
+`,
+			wantErr: `invalid organization "folders/123", must be organizations/<id>`,
+		},
+		{
+			name: "duplicate_organization",
+			file: `
+organizations:
+- organization: organizations/123
+- organization: organizations/123
+  credentialsFile: /path/to/customer-a.json
+`,
+			wantErr: `duplicate organization "organizations/123"`,
+		},
+		{
+			name: "unknown_field",
+			file: `
+organizations:
+- organization: organizations/123
+  credentials: /path/to/customer-a.json
+`,
+			wantErr: "failed to read *cli.orgConfigs",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "orgs.yaml")
+			if err := os.WriteFile(path, []byte(tc.file), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := readOrgConfigs(path)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Process(%+v) got configs diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestOrgConfigClientOptions(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		config  *orgConfig
+		wantLen int
+	}{
+		{
+			name:   "default",
+			config: &orgConfig{Organization: "organizations/123"},
+		},
+		{
+			name: "credentials_and_endpoint",
+			config: &orgConfig{
+				Organization:    "organizations/123",
+				CredentialsFile: "/path/to/customer-a.json",
+				Endpoint:        "https://cloudresourcemanager.example.com",
+			},
+			wantLen: 2,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got, want := len(tc.config.clientOptions()), tc.wantLen; got != want {
+				t.Errorf("Process(%+v) got %d client options, want %d", tc.name, got, want)
+			}
+		})
+	}
+}
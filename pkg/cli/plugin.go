@@ -0,0 +1,388 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/posener/complete/v2/predict"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/messages"
+	"github.com/abcxyz/access-on-demand/pkg/plugin"
+	"github.com/abcxyz/pkg/cli"
+)
+
+var (
+	_ cli.Command = (*PluginValidateCommand)(nil)
+	_ cli.Command = (*PluginHandleCommand)(nil)
+	_ cli.Command = (*PluginCleanupCommand)(nil)
+)
+
+// pluginPathUsage is the usage of the path flag of the plugin commands.
+const pluginPathUsage = `The path of plugin request file, in YAML format, ` +
+	`with the kind of the request under "kind" and the request of the ` +
+	`plugin under "spec". Use "-" to read it from stdin.`
+
+// pluginRunner interface that runs the operations of plugin requests.
+type pluginRunner interface {
+	Run(context.Context, *plugin.Request) (*plugin.Response, error)
+}
+
+// pluginFlags are the flags to find the plugins of request kinds.
+type pluginFlags struct {
+	flagPluginDirs []string
+
+	// testRunner is used for testing only.
+	testRunner pluginRunner
+}
+
+// register registers the plugin flags to the given flag section.
+func (p *pluginFlags) register(f *cli.FlagSection) {
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "plugin-dir",
+		Target:  &p.flagPluginDirs,
+		Example: "/path/to/plugins",
+		Predict: predict.Dirs("*"),
+		Usage: `The directory to find the "aod-plugin-<kind>" plugin ` +
+			`executables in before PATH, can be repeated.`,
+	})
+}
+
+// runner returns the runner of the plugins writing their stderr to stderr.
+func (p *pluginFlags) runner(stderr io.Writer) pluginRunner {
+	if p.testRunner != nil {
+		return p.testRunner
+	}
+	return plugin.NewRunner(plugin.WithDirs(p.flagPluginDirs...), plugin.WithStderr(stderr))
+}
+
+// readPluginRequest reads the plugin request at the path, and validates it and
+// then validates its spec with the plugin.
+func readPluginRequest(ctx context.Context, rr *requestReader, path string, r pluginRunner) (*v1alpha1.PluginRequest, error) {
+	var req v1alpha1.PluginRequest
+	loc, err := rr.requestWithLocator(path, &req)
+	if err != nil {
+		return nil, withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &req, err))
+	}
+
+	if err := v1alpha1.ValidatePluginRequest(&req); err != nil {
+		err = loc.Annotate(err)
+		return nil, withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", &req, err))
+	}
+
+	if _, err := r.Run(ctx, &plugin.Request{
+		Operation: plugin.OperationValidate,
+		Kind:      req.Kind,
+		Spec:      req.Spec,
+	}); err != nil {
+		return nil, withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", &req, err))
+	}
+	return &req, nil
+}
+
+// PluginValidateCommand validates plugin requests.
+type PluginValidateCommand struct {
+	cli.BaseCommand
+
+	flagPath string
+
+	pluginFlags pluginFlags
+}
+
+func (c *PluginValidateCommand) Desc() string {
+	return `Validate the plugin request YAML file at the given path with the plugin of its kind`
+}
+
+func (c *PluginValidateCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Validate the plugin request YAML file at the given path with the plugin of its
+kind, the "aod-plugin-<kind>" executable:
+
+      {{ COMMAND }} -path "/path/to/file.yaml"
+`
+}
+
+func (c *PluginValidateCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "path",
+		Target:  &c.flagPath,
+		Example: "/path/to/file.yaml",
+		Predict: predict.Files("*"),
+		Usage:   pluginPathUsage,
+	})
+
+	c.pluginFlags.register(f)
+
+	return set
+}
+
+func (c *PluginValidateCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagPath == "" {
+		return fmt.Errorf("path is required")
+	}
+
+	r := c.pluginFlags.runner(c.Stderr())
+	if _, err := readPluginRequest(ctx, newRequestReader(c.Stdin()), c.flagPath, r); err != nil {
+		return err
+	}
+	c.Outf("Successfully validated plugin request")
+
+	return nil
+}
+
+// PluginHandleCommand handles plugin requests.
+type PluginHandleCommand struct {
+	cli.BaseCommand
+
+	flagPath string
+
+	flagDuration time.Duration
+
+	flagStartTime time.Time
+
+	flagRequester string
+
+	pluginFlags pluginFlags
+
+	prCommentFlags prCommentFlags
+}
+
+func (c *PluginHandleCommand) Desc() string {
+	return `Handle the plugin request YAML file at the given path with the plugin of its kind`
+}
+
+func (c *PluginHandleCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Validate and then handle the plugin request YAML file at the given path with
+the plugin of its kind, the "aod-plugin-<kind>" executable, granting the access
+for 2 hours:
+
+      {{ COMMAND }} -path "/path/to/file.yaml" -duration "2h"
+`
+}
+
+func (c *PluginHandleCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "path",
+		Target:  &c.flagPath,
+		Example: "/path/to/file.yaml",
+		Predict: predict.Files("*"),
+		Usage:   pluginPathUsage,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "duration",
+		Target:  &c.flagDuration,
+		Example: "2h",
+		Usage:   `The access lifecycle, as a duration.`,
+	})
+
+	f.TimeVar(time.RFC3339, &cli.TimeVar{
+		Name:    "start-time",
+		Target:  &c.flagStartTime,
+		Example: "2009-11-10T23:00:00Z",
+		Usage: `The start time of the access lifecycle in RFC3339 format. ` +
+			`Default is current UTC time.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "requester",
+		Target:  &c.flagRequester,
+		Example: "user:alice@example.com",
+		Usage:   `The requester of the request, which is passed to the plugin.`,
+	})
+
+	c.pluginFlags.register(f)
+	c.prCommentFlags.register(f)
+
+	return set
+}
+
+func (c *PluginHandleCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagPath == "" {
+		return fmt.Errorf("path is required")
+	}
+
+	if err := c.prCommentFlags.validate(c.GetEnv); err != nil {
+		return err
+	}
+
+	if c.flagDuration <= 0 {
+		return fmt.Errorf("a positive duration is required")
+	}
+
+	// Default start time to the current time.
+	now := time.Now().UTC()
+	if c.flagStartTime.IsZero() {
+		c.flagStartTime = now
+	}
+
+	if c.flagStartTime.Add(c.flagDuration).Before(now) {
+		return fmt.Errorf("expiry (start time: %q + duration: %q) already passed", c.flagStartTime, c.flagDuration)
+	}
+
+	r := c.pluginFlags.runner(c.Stderr())
+	req, err := readPluginRequest(ctx, newRequestReader(c.Stdin()), c.flagPath, r)
+	if err != nil {
+		return err
+	}
+
+	expiry := c.flagStartTime.Add(c.flagDuration)
+	resp, err := r.Run(ctx, &plugin.Request{
+		Operation: plugin.OperationHandle,
+		Kind:      req.Kind,
+		Spec:      req.Spec,
+		StartTime: &c.flagStartTime,
+		Expiry:    &expiry,
+		Requester: c.flagRequester,
+	})
+	c.prCommentFlags.post(ctx, c.GetEnv, &prComment{Action: "handle", Done: "handled", Summary: req, Err: err})
+	if err != nil {
+		return withExitCode(ExitCodeAPIFailure, fmt.Errorf("failed to handle %T: %w", req, err))
+	}
+	return printPluginResponse(ctx, &c.BaseCommand, messages.HeaderPluginHandled, resp)
+}
+
+// PluginCleanupCommand cleans up plugin requests.
+type PluginCleanupCommand struct {
+	cli.BaseCommand
+
+	flagPath string
+
+	pluginFlags pluginFlags
+
+	prCommentFlags prCommentFlags
+}
+
+func (c *PluginCleanupCommand) Desc() string {
+	return `Clean up the plugin request YAML file at the given path with the plugin of its kind`
+}
+
+func (c *PluginCleanupCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Remove the access of the plugin request YAML file at the given path with the
+plugin of its kind, the "aod-plugin-<kind>" executable:
+
+      {{ COMMAND }} -path "/path/to/file.yaml"
+`
+}
+
+func (c *PluginCleanupCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "path",
+		Target:  &c.flagPath,
+		Example: "/path/to/file.yaml",
+		Predict: predict.Files("*"),
+		Usage:   pluginPathUsage,
+	})
+
+	c.pluginFlags.register(f)
+	c.prCommentFlags.register(f)
+
+	return set
+}
+
+func (c *PluginCleanupCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagPath == "" {
+		return fmt.Errorf("path is required")
+	}
+
+	if err := c.prCommentFlags.validate(c.GetEnv); err != nil {
+		return err
+	}
+
+	r := c.pluginFlags.runner(c.Stderr())
+	req, err := readPluginRequest(ctx, newRequestReader(c.Stdin()), c.flagPath, r)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.Run(ctx, &plugin.Request{
+		Operation: plugin.OperationCleanup,
+		Kind:      req.Kind,
+		Spec:      req.Spec,
+	})
+	c.prCommentFlags.post(ctx, c.GetEnv, &prComment{Action: "clean up", Done: "cleaned up", Summary: req, Err: err})
+	if err != nil {
+		return withExitCode(ExitCodeAPIFailure, fmt.Errorf("failed to clean up %T: %w", req, err))
+	}
+	return printPluginResponse(ctx, &c.BaseCommand, messages.HeaderPluginCleanedUp, resp)
+}
+
+// printPluginResponse prints the warnings of the plugin response to stderr,
+// and the response with the header to stdout.
+func printPluginResponse(ctx context.Context, cmd *cli.BaseCommand, header messages.ID, resp *plugin.Response) error {
+	githubActions := cmd.GetEnv("GITHUB_ACTIONS") == "true"
+	for _, msg := range resp.Warnings {
+		printWarning(cmd.Stderr(), msg, githubActions)
+	}
+	printMessageHeader(ctx, cmd, header)
+	if err := encodeYaml(cmd.Stdout(), resp); err != nil {
+		return fmt.Errorf("failed to output plugin response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,354 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/abcxyz/access-on-demand/pkg/plugin"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+const validPluginFile = `
+kind: vpn
+spec:
+  network: corp
+`
+
+func TestPluginValidateCommand(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		file   string
+		args   []string
+		runner *fakePluginRunner
+		expOps []string
+		expOut string
+		expErr string
+	}{
+		{
+			name:   "success",
+			file:   validPluginFile,
+			args:   []string{"-path", "{{path}}"},
+			runner: &fakePluginRunner{},
+			expOps: []string{plugin.OperationValidate},
+			expOut: "Successfully validated plugin request",
+		},
+		{
+			name: "plugin_validation_failure",
+			file: validPluginFile,
+			args: []string{"-path", "{{path}}"},
+			runner: &fakePluginRunner{injectErrs: map[string]error{
+				plugin.OperationValidate: fmt.Errorf("network not found"),
+			}},
+			expOps: []string{plugin.OperationValidate},
+			expErr: "failed to validate *v1alpha1.PluginRequest: network not found",
+		},
+		{
+			name:   "invalid_kind",
+			file:   "kind: VPN\nspec:\n  network: corp\n",
+			args:   []string{"-path", "{{path}}"},
+			runner: &fakePluginRunner{},
+			expErr: `kind "VPN" is not valid`,
+		},
+		{
+			name:   "invalid_yaml",
+			file:   `bananas`,
+			args:   []string{"-path", "{{path}}"},
+			runner: &fakePluginRunner{},
+			expErr: "failed to read *v1alpha1.PluginRequest",
+		},
+		{
+			name:   "missing_path",
+			runner: &fakePluginRunner{},
+			expErr: "path is required",
+		},
+		{
+			name:   "unexpected_args",
+			args:   []string{"foo"},
+			runner: &fakePluginRunner{},
+			expErr: `unexpected arguments: ["foo"]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var cmd PluginValidateCommand
+			cmd.pluginFlags.testRunner = tc.runner
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, pluginArgs(t, tc.file, tc.args))
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expOps, tc.runner.gotOps()); diff != "" {
+				t.Errorf("Process(%+v) got operations diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expOut, strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestPluginHandleCommand(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		file      string
+		args      []string
+		runner    *fakePluginRunner
+		expReqs   []*plugin.Request
+		expOut    string
+		expStderr string
+		expErr    string
+	}{
+		{
+			name: "success",
+			file: validPluginFile,
+			args: []string{"-path", "{{path}}", "-duration", "2h", "-requester", "user:alice@example.com"},
+			runner: &fakePluginRunner{resp: &plugin.Response{
+				Message:  "granted",
+				Warnings: []string{"network is shared"},
+				Output:   map[string]any{"profile": "corp-alice"},
+			}},
+			expReqs: []*plugin.Request{
+				{
+					Operation: plugin.OperationValidate,
+					Kind:      "vpn",
+					Spec:      map[string]any{"network": "corp"},
+				},
+				{
+					Operation: plugin.OperationHandle,
+					Kind:      "vpn",
+					Spec:      map[string]any{"network": "corp"},
+					Requester: "user:alice@example.com",
+				},
+			},
+			expOut: `
+------Successfully Handled Plugin Request------
+message: granted
+warnings:
+  - network is shared
+output:
+  profile: corp-alice`,
+			expStderr: "WARNING: network is shared",
+		},
+		{
+			name: "handle_failure",
+			file: validPluginFile,
+			args: []string{"-path", "{{path}}", "-duration", "2h"},
+			runner: &fakePluginRunner{injectErrs: map[string]error{
+				plugin.OperationHandle: fmt.Errorf("injected error"),
+			}},
+			expReqs: []*plugin.Request{
+				{Operation: plugin.OperationValidate, Kind: "vpn", Spec: map[string]any{"network": "corp"}},
+				{Operation: plugin.OperationHandle, Kind: "vpn", Spec: map[string]any{"network": "corp"}},
+			},
+			expErr: "failed to handle *v1alpha1.PluginRequest: injected error",
+		},
+		{
+			name: "validate_failure",
+			file: validPluginFile,
+			args: []string{"-path", "{{path}}", "-duration", "2h"},
+			runner: &fakePluginRunner{injectErrs: map[string]error{
+				plugin.OperationValidate: fmt.Errorf("network not found"),
+			}},
+			expReqs: []*plugin.Request{
+				{Operation: plugin.OperationValidate, Kind: "vpn", Spec: map[string]any{"network": "corp"}},
+			},
+			expErr: "failed to validate *v1alpha1.PluginRequest: network not found",
+		},
+		{
+			name:   "missing_duration",
+			file:   validPluginFile,
+			args:   []string{"-path", "{{path}}"},
+			runner: &fakePluginRunner{},
+			expErr: "a positive duration is required",
+		},
+		{
+			name:   "expiry_passed",
+			file:   validPluginFile,
+			args:   []string{"-path", "{{path}}", "-duration", "2h", "-start-time", "2009-11-10T23:00:00Z"},
+			runner: &fakePluginRunner{},
+			expErr: "already passed",
+		},
+		{
+			name:   "missing_path",
+			args:   []string{"-duration", "2h"},
+			runner: &fakePluginRunner{},
+			expErr: "path is required",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var cmd PluginHandleCommand
+			cmd.pluginFlags.testRunner = tc.runner
+			_, stdout, stderr := cmd.Pipe()
+
+			start := time.Now().UTC()
+			err := cmd.Run(ctx, pluginArgs(t, tc.file, tc.args))
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			for _, r := range tc.runner.gotReqs {
+				if r.Operation != plugin.OperationHandle {
+					continue
+				}
+				if r.StartTime == nil || r.StartTime.Before(start) || r.Expiry == nil || !r.Expiry.Equal(r.StartTime.Add(2*time.Hour)) {
+					t.Errorf("Process(%+v) got start time %v and expiry %v, want 2h lifecycle from now", tc.name, r.StartTime, r.Expiry)
+				}
+			}
+			if diff := cmp.Diff(tc.expReqs, tc.runner.gotReqs, cmpopts.IgnoreFields(plugin.Request{}, "StartTime", "Expiry")); diff != "" {
+				t.Errorf("Process(%+v) got requests diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expStderr, strings.TrimSpace(stderr.String())); diff != "" {
+				t.Errorf("Process(%+v) got stderr diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestPluginCleanupCommand(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		file   string
+		args   []string
+		runner *fakePluginRunner
+		expOps []string
+		expOut string
+		expErr string
+	}{
+		{
+			name:   "success",
+			file:   validPluginFile,
+			args:   []string{"-path", "{{path}}"},
+			runner: &fakePluginRunner{resp: &plugin.Response{Message: "revoked"}},
+			expOps: []string{plugin.OperationValidate, plugin.OperationCleanup},
+			expOut: `
+------Successfully Cleaned Up Plugin Request------
+message: revoked`,
+		},
+		{
+			name: "cleanup_failure",
+			file: validPluginFile,
+			args: []string{"-path", "{{path}}"},
+			runner: &fakePluginRunner{injectErrs: map[string]error{
+				plugin.OperationCleanup: fmt.Errorf("injected error"),
+			}},
+			expOps: []string{plugin.OperationValidate, plugin.OperationCleanup},
+			expErr: "failed to clean up *v1alpha1.PluginRequest: injected error",
+		},
+		{
+			name:   "missing_spec",
+			file:   "kind: vpn\n",
+			args:   []string{"-path", "{{path}}"},
+			runner: &fakePluginRunner{},
+			expErr: "spec not found",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var cmd PluginCleanupCommand
+			cmd.pluginFlags.testRunner = tc.runner
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, pluginArgs(t, tc.file, tc.args))
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expOps, tc.runner.gotOps()); diff != "" {
+				t.Errorf("Process(%+v) got operations diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+// pluginArgs writes the file to a temp dir if it is not empty, and returns the
+// args with "{{path}}" replaced by its path.
+func pluginArgs(tb testing.TB, file string, args []string) []string {
+	tb.Helper()
+
+	path := filepath.Join(tb.TempDir(), "plugin.yaml")
+	if file != "" {
+		if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	result := make([]string, 0, len(args))
+	for _, a := range args {
+		result = append(result, strings.ReplaceAll(a, "{{path}}", path))
+	}
+	return result
+}
+
+type fakePluginRunner struct {
+	injectErrs map[string]error
+	resp       *plugin.Response
+	gotReqs    []*plugin.Request
+}
+
+func (r *fakePluginRunner) Run(ctx context.Context, req *plugin.Request) (*plugin.Response, error) {
+	r.gotReqs = append(r.gotReqs, req)
+	if err := r.injectErrs[req.Operation]; err != nil {
+		return nil, err
+	}
+	if r.resp == nil {
+		return &plugin.Response{}, nil
+	}
+	return r.resp, nil
+}
+
+func (r *fakePluginRunner) gotOps() []string {
+	var ops []string
+	for _, req := range r.gotReqs {
+		ops = append(ops, req.Operation)
+	}
+	return ops
+}
@@ -0,0 +1,232 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/posener/complete/v2/predict"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/messages"
+	"github.com/abcxyz/access-on-demand/pkg/policy"
+	"github.com/abcxyz/access-on-demand/pkg/requestutil"
+	"github.com/abcxyz/pkg/cli"
+)
+
+var _ cli.Command = (*PolicyTestCommand)(nil)
+
+// policyFlags are the flags of the AOD policy file that requests are validated
+// with.
+type policyFlags struct {
+	flagPolicy string
+
+	// The policy read from flagPolicy by validate.
+	policy *policy.Policy
+}
+
+// register registers the policy flags to the given flag section.
+func (p *policyFlags) register(f *cli.FlagSection) {
+	f.StringVar(&cli.StringVar{
+		Name:    "policy",
+		Target:  &p.flagPolicy,
+		Example: "/path/to/aod-policy.yaml",
+		Predict: predict.Files("*"),
+		Usage: "The path of the AOD policy file, in YAML format, with the " +
+			"roles that may or may not be requested. Requests are not " +
+			"checked against any policy if it is not set.",
+	})
+}
+
+// validate reads the policy file if it is set.
+func (p *policyFlags) validate() error {
+	if p.flagPolicy == "" {
+		return nil
+	}
+	pol, err := policy.Read(p.flagPolicy)
+	if err != nil {
+		return fmt.Errorf("invalid policy: %w", err)
+	}
+	p.policy = pol
+	return nil
+}
+
+// options returns the options to validate requests handled with the duration,
+// or 0 if it is not known, or nil if there is no policy.
+func (p *policyFlags) options(duration time.Duration) *v1alpha1.ValidateOptions {
+	if p.policy == nil {
+		return nil
+	}
+	return p.policy.ValidateOptions(duration)
+}
+
+// policyTestResult is the result of testing the policy against a request.
+type policyTestResult struct {
+	Path    string               `yaml:"path"`
+	Allowed bool                 `yaml:"allowed"`
+	Error   string               `yaml:"error,omitempty"`
+	Rules   []*policy.RuleResult `yaml:"rules,omitempty"`
+}
+
+// PolicyTestCommand tests a policy file against sample IAM requests.
+type PolicyTestCommand struct {
+	cli.BaseCommand
+
+	flagPaths []string
+
+	flagDuration time.Duration
+
+	flagExpectDenied bool
+
+	policyFlags policyFlags
+}
+
+func (c *PolicyTestCommand) Desc() string {
+	return `Test the AOD policy file against sample IAM requests`
+}
+
+func (c *PolicyTestCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Test the policy file against the IAM request YAML files, which must all be
+allowed:
+
+      {{ COMMAND }} -policy "/path/to/aod-policy.yaml" -path "/path/to/file.yaml" -duration "2h"
+
+Test that the IAM requests are all denied by the policy file:
+
+      {{ COMMAND }} -policy "/path/to/aod-policy.yaml" -path "/path/to/file.yaml" -expect-denied
+`
+}
+
+func (c *PolicyTestCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	c.policyFlags.register(f)
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "path",
+		Target:  &c.flagPaths,
+		Example: "/path/to/file.yaml",
+		Predict: predict.Files("*"),
+		Usage:   `The path of sample IAM request file, in YAML format, can be repeated.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "duration",
+		Target:  &c.flagDuration,
+		Example: "2h",
+		Usage: `The duration the requests are handled with, for the rules ` +
+			`using request.duration. Default is 0, as when requests are only validated.`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "expect-denied",
+		Target:  &c.flagExpectDenied,
+		Default: false,
+		Usage:   `Expect all the requests to be denied instead of allowed.`,
+	})
+
+	return set
+}
+
+func (c *PolicyTestCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.policyFlags.flagPolicy == "" {
+		return fmt.Errorf("policy is required")
+	}
+
+	if len(c.flagPaths) == 0 {
+		return fmt.Errorf("path is required")
+	}
+
+	if c.flagDuration < 0 {
+		return fmt.Errorf("duration must not be negative, got %q", c.flagDuration)
+	}
+
+	if err := c.policyFlags.validate(); err != nil {
+		return err
+	}
+
+	return c.test(ctx)
+}
+
+func (c *PolicyTestCommand) test(ctx context.Context) error {
+	rr := newRequestReader(c.Stdin())
+
+	// Validate without the rules, which are evaluated separately to report
+	// the result of each of them.
+	opts := c.policyFlags.options(c.flagDuration)
+	opts.CustomCheck = nil
+
+	results := make([]*policyTestResult, 0, len(c.flagPaths))
+	var unexpected int
+	for _, p := range c.flagPaths {
+		// The rules are evaluated on the merged requests of the bundle, as they
+		// are handled.
+		docs, err := rr.iamBundle(p)
+		if err != nil {
+			return withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &v1alpha1.IAMRequest{}, err))
+		}
+
+		res := &policyTestResult{Path: p, Allowed: true}
+		if req, err := requestutil.ValidateIAMBundle(docs, opts); err != nil {
+			res.Allowed = false
+			res.Error = err.Error()
+		} else {
+			rules, err := c.policyFlags.policy.EvalRules(req, c.flagDuration)
+			if err != nil {
+				return withExitCode(ExitCodeValidation, fmt.Errorf("failed to test %q: %w", p, err))
+			}
+			res.Rules = rules
+			for _, r := range rules {
+				res.Allowed = res.Allowed && r.Passed
+			}
+		}
+		if res.Allowed == c.flagExpectDenied {
+			unexpected++
+		}
+		results = append(results, res)
+	}
+
+	printMessageHeader(ctx, &c.BaseCommand, messages.HeaderPolicyTested)
+	if err := encodeYaml(c.Stdout(), results); err != nil {
+		return fmt.Errorf("failed to output policy test results: %w", err)
+	}
+
+	if unexpected > 0 {
+		want := "allowed"
+		if c.flagExpectDenied {
+			want = "denied"
+		}
+		return withExitCode(ExitCodeValidation,
+			fmt.Errorf("%d of %d requests are not %s by the policy", unexpected, len(results), want))
+	}
+	return nil
+}
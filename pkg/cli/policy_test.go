@@ -0,0 +1,196 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestPolicyTestCommand(t *testing.T) {
+	t.Parallel()
+
+	fileContentByName := map[string]string{
+		"policy.yaml": `
+roles:
+  - deny:
+      - roles/owner
+rules:
+  - name: short-iam-grants
+    expression: request.duration <= duration('4h') || !bindings.exists(b, b.role.startsWith('roles/iam'))
+    message: IAM roles can be requested for at most 4h.
+`,
+		"iam.yaml": `
+policies:
+  - resource: projects/foo
+    bindings:
+      - members:
+          - user:alice@example.com
+        role: roles/iam.roleViewer
+`,
+		"owner.yaml": `
+policies:
+  - resource: projects/foo
+    bindings:
+      - members:
+          - user:alice@example.com
+        role: roles/owner
+`,
+		"bundle.yaml": `
+policies:
+  - resource: projects/foo
+    bindings:
+      - members:
+          - user:alice@example.com
+        role: roles/iam.roleViewer
+---
+policies:
+  - resource: projects/bar
+    bindings:
+      - members:
+          - user:alice@example.com
+        role: roles/owner
+`,
+		"invalid-policy.yaml": `
+rules:
+  - name: foo
+    expression: size(bindings)
+`,
+	}
+	dir := t.TempDir()
+	for name, content := range fileContentByName {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name   string
+		args   []string
+		expOut string
+		expErr string
+	}{
+		{
+			name: "allowed",
+			args: []string{"-policy", "{{dir}}/policy.yaml", "-path", "{{dir}}/iam.yaml", "-duration", "2h"},
+			expOut: `
+------Policy Test Results------
+- path: {{dir}}/iam.yaml
+  allowed: true
+  rules:
+    - name: short-iam-grants
+      passed: true`,
+		},
+		{
+			name: "denied_by_rule",
+			args: []string{"-policy", "{{dir}}/policy.yaml", "-path", "{{dir}}/iam.yaml", "-duration", "8h"},
+			expOut: `
+------Policy Test Results------
+- path: {{dir}}/iam.yaml
+  allowed: false
+  rules:
+    - name: short-iam-grants
+      passed: false
+      message: IAM roles can be requested for at most 4h.`,
+			expErr: "1 of 1 requests are not allowed by the policy",
+		},
+		{
+			name: "expect_denied",
+			args: []string{"-policy", "{{dir}}/policy.yaml", "-path", "{{dir}}/owner.yaml", "-expect-denied"},
+			expOut: `
+------Policy Test Results------
+- path: {{dir}}/owner.yaml
+  allowed: false
+  error: 'policies[0].bindings[0].role at line 7, column 15: role "roles/owner" is denied (matches "roles/owner")'`,
+		},
+		{
+			name: "expect_denied_but_allowed",
+			args: []string{"-policy", "{{dir}}/policy.yaml", "-path", "{{dir}}/iam.yaml", "-path", "{{dir}}/owner.yaml", "-expect-denied"},
+			expOut: `
+------Policy Test Results------
+- path: {{dir}}/iam.yaml
+  allowed: true
+  rules:
+    - name: short-iam-grants
+      passed: true
+- path: {{dir}}/owner.yaml
+  allowed: false
+  error: 'policies[0].bindings[0].role at line 7, column 15: role "roles/owner" is denied (matches "roles/owner")'`,
+			expErr: "1 of 2 requests are not denied by the policy",
+		},
+		{
+			name: "bundle_denied",
+			args: []string{"-policy", "{{dir}}/policy.yaml", "-path", "{{dir}}/bundle.yaml", "-expect-denied"},
+			expOut: `
+------Policy Test Results------
+- path: {{dir}}/bundle.yaml
+  allowed: false
+  error: '{{dir}}/bundle.yaml#1: policies[0].bindings[0].role at line 14, column 15: role "roles/owner" is denied (matches "roles/owner")'`,
+		},
+		{
+			name:   "invalid_policy",
+			args:   []string{"-policy", "{{dir}}/invalid-policy.yaml", "-path", "{{dir}}/iam.yaml"},
+			expErr: `rule "foo" expression must evaluate to bool`,
+		},
+		{
+			name:   "missing_policy",
+			args:   []string{"-path", "{{dir}}/iam.yaml"},
+			expErr: "policy is required",
+		},
+		{
+			name:   "missing_path",
+			args:   []string{"-policy", "{{dir}}/policy.yaml"},
+			expErr: "path is required",
+		},
+		{
+			name:   "unexpected_args",
+			args:   []string{"foo"},
+			expErr: `unexpected arguments: ["foo"]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			args := make([]string, 0, len(tc.args))
+			for _, a := range tc.args {
+				args = append(args, strings.ReplaceAll(a, "{{dir}}", dir))
+			}
+
+			var cmd PolicyTestCommand
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			expOut := strings.ReplaceAll(tc.expOut, "{{dir}}", dir)
+			if diff := cmp.Diff(strings.TrimSpace(expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/approval"
+	"github.com/abcxyz/access-on-demand/pkg/github"
+	"github.com/abcxyz/access-on-demand/pkg/messages"
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// prNumberRegex matches a bare pull request number.
+var prNumberRegex = regexp.MustCompile(`^[0-9]+$`)
+
+// prCommenter comments on pull requests.
+type prCommenter interface {
+	Comment(ctx context.Context, number int, body string) (string, error)
+}
+
+// prCommentFlags are the flags to post the results of handling requests as
+// comments on their pull requests.
+type prCommentFlags struct {
+	flagGitHubComment bool
+
+	flagPR string
+
+	// Pull request parsed from flagPR by validate.
+	pr *approval.PullRequest
+
+	// testCommenter is used for testing only.
+	testCommenter prCommenter
+}
+
+// register registers the PR comment flags to the given flag section.
+func (p *prCommentFlags) register(f *cli.FlagSection) {
+	f.BoolVar(&cli.BoolVar{
+		Name:    "github-comment",
+		Target:  &p.flagGitHubComment,
+		Default: false,
+		Usage: "Whether to post the result as a comment on the pull request " +
+			"of pr. The token is read from GITHUB_TOKEN. Failures to post " +
+			"the comment are logged and do not fail the command.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "pr",
+		Target:  &p.flagPR,
+		Example: "123",
+		Usage: `The GitHub pull request to comment on, a number in the ` +
+			`repository of GITHUB_REPOSITORY, in the format of ` +
+			`"owner/repo/number" or a pull request URL, required with ` +
+			`github-comment.`,
+	})
+}
+
+// validate validates the PR comment flags, a bare pull request number is in
+// the repository of GITHUB_REPOSITORY read with getenv.
+func (p *prCommentFlags) validate(getenv func(string) string) error {
+	if !p.flagGitHubComment {
+		return nil
+	}
+	if p.flagPR == "" {
+		return fmt.Errorf("pr is required with github-comment")
+	}
+	s := p.flagPR
+	if prNumberRegex.MatchString(s) {
+		repo := getenv("GITHUB_REPOSITORY")
+		if repo == "" {
+			return fmt.Errorf("GITHUB_REPOSITORY is required when pr is a number")
+		}
+		s = repo + "/" + s
+	}
+	pr, err := approval.ParsePullRequest(s)
+	if err != nil {
+		return fmt.Errorf("invalid pr: %w", err)
+	}
+	p.pr = pr
+	return nil
+}
+
+// prComment is the result of handling a request to comment on its pull
+// request.
+type prComment struct {
+	// Action done to the request, e.g. "handle".
+	Action string
+
+	// Done is the past tense of the action, e.g. "handled".
+	Done string
+
+	// Summary of the request, encoded as YAML in the comment.
+	Summary any
+
+	// Err is the error of handling the request, if any.
+	Err error
+
+	// Responses are the redacted updated IAM policies, included in the comment
+	// if set.
+	Responses []*redactedResponse
+}
+
+// body returns the markdown body of the comment, with the headline from the
+// message catalog.
+func (c *prComment) body(msgs *messages.Catalog) (string, error) {
+	var b strings.Builder
+	if c.Err != nil {
+		fmt.Fprintf(&b, "%s\n\n", msgs.Render(messages.PRCommentFailure, c))
+		fmt.Fprintf(&b, "```\n%s\n```\n", c.Err)
+	} else {
+		fmt.Fprintf(&b, "%s\n", msgs.Render(messages.PRCommentSuccess, c))
+	}
+
+	if c.Summary != nil {
+		var y bytes.Buffer
+		if err := encodeYaml(&y, c.Summary); err != nil {
+			return "", fmt.Errorf("failed to encode summary: %w", err)
+		}
+		fmt.Fprintf(&b, "\n```yaml\n%s```\n", y.String())
+	}
+
+	if len(c.Responses) > 0 {
+		var y bytes.Buffer
+		if err := encodeYaml(&y, c.Responses); err != nil {
+			return "", fmt.Errorf("failed to encode IAM policies: %w", err)
+		}
+		fmt.Fprintf(&b, "\n<details>\n<summary>AOD bindings in the updated IAM policies (redacted)</summary>\n\n```yaml\n%s```\n\n</details>\n", y.String())
+	}
+	return b.String(), nil
+}
+
+// post posts the comment on the pull request if github-comment is set, with
+// the token read from GITHUB_TOKEN with getenv. Failures are logged.
+func (p *prCommentFlags) post(ctx context.Context, getenv func(string) string, c *prComment) {
+	if !p.flagGitHubComment {
+		return
+	}
+	logger := logging.FromContext(ctx)
+
+	commenter := p.testCommenter
+	if commenter == nil {
+		apiURL := getenv("GITHUB_API_URL")
+		if apiURL == "" {
+			apiURL = github.DefaultAPIURL
+		}
+		repo := p.pr.Owner + "/" + p.pr.Repo
+		gc, err := github.NewCommenter(&http.Client{Timeout: 30 * time.Second}, apiURL, repo, getenv("GITHUB_TOKEN"))
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to create pull request commenter", "error", err)
+			return
+		}
+		commenter = gc
+	}
+
+	body, err := c.body(messageCatalog(ctx, getenv))
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to render pull request comment", "error", err)
+		return
+	}
+	url, err := commenter.Comment(ctx, p.pr.Number, body)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to comment on pull request",
+			"pull_request", p.pr.String(),
+			"error", err)
+		return
+	}
+	logger.InfoContext(ctx, "commented on pull request",
+		"pull_request", p.pr.String(),
+		"url", url)
+}
+
+// redactedResponse is an IAM response with only the AOD bindings, and with
+// the members other than the requested members redacted.
+type redactedResponse struct {
+	Resource string             `yaml:"resource"`
+	Bindings []*redactedBinding `yaml:"bindings,omitempty"`
+	Warnings []string           `yaml:"warnings,omitempty"`
+	Skipped  string             `yaml:"skipped,omitempty"`
+}
+
+// redactedBinding is an AOD binding with redacted members.
+type redactedBinding struct {
+	Role       string   `yaml:"role"`
+	Members    []string `yaml:"members"`
+	Expression string   `yaml:"expression"`
+}
+
+// redactResponses returns the responses with only the AOD bindings, with the
+// condition title, in the policies. Members other than the given members are
+// replaced by their count.
+func redactResponses(resps []*v1alpha1.IAMResponse, conditionTitle string, members []string) []*redactedResponse {
+	result := make([]*redactedResponse, 0, len(resps))
+	for _, r := range resps {
+		if r == nil {
+			continue
+		}
+		rr := &redactedResponse{Resource: r.Resource, Warnings: r.Warnings, Skipped: r.Skipped}
+		for _, b := range r.Policy.GetBindings() {
+			if b.GetCondition().GetTitle() != conditionTitle {
+				continue
+			}
+			var kept []string
+			var redacted int
+			for _, m := range b.GetMembers() {
+				if slices.Contains(members, m) {
+					kept = append(kept, m)
+				} else {
+					redacted++
+				}
+			}
+			if redacted > 0 {
+				kept = append(kept, strconv.Itoa(redacted)+" other member(s) redacted")
+			}
+			rr.Bindings = append(rr.Bindings, &redactedBinding{
+				Role:       b.GetRole(),
+				Members:    kept,
+				Expression: b.GetCondition().GetExpression(),
+			})
+		}
+		result = append(result, rr)
+	}
+	return result
+}
+
+// requestMembers returns the distinct members in the IAM request.
+func requestMembers(req *v1alpha1.IAMRequest) []string {
+	var members []string
+	for _, p := range req.ResourcePolicies {
+		for _, b := range p.Bindings {
+			members = append(members, b.Members...)
+		}
+	}
+	return dedupe(members)
+}
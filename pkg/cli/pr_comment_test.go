@@ -0,0 +1,268 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genproto/googleapis/type/expr"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/approval"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestPRCommentFlags(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		flags   *prCommentFlags
+		env     map[string]string
+		wantPR  *approval.PullRequest
+		wantErr string
+	}{
+		{
+			name:  "disabled",
+			flags: &prCommentFlags{flagPR: "bananas"},
+		},
+		{
+			name:   "number",
+			flags:  &prCommentFlags{flagGitHubComment: true, flagPR: "12"},
+			env:    map[string]string{"GITHUB_REPOSITORY": "foo/bar"},
+			wantPR: &approval.PullRequest{Owner: "foo", Repo: "bar", Number: 12},
+		},
+		{
+			name:   "url",
+			flags:  &prCommentFlags{flagGitHubComment: true, flagPR: "https://github.com/foo/bar/pull/12"},
+			wantPR: &approval.PullRequest{Owner: "foo", Repo: "bar", Number: 12},
+		},
+		{
+			name:    "number_without_repository",
+			flags:   &prCommentFlags{flagGitHubComment: true, flagPR: "12"},
+			wantErr: "GITHUB_REPOSITORY is required when pr is a number",
+		},
+		{
+			name:    "missing_pr",
+			flags:   &prCommentFlags{flagGitHubComment: true},
+			wantErr: "pr is required with github-comment",
+		},
+		{
+			name:    "invalid_pr",
+			flags:   &prCommentFlags{flagGitHubComment: true, flagPR: "foo/bar"},
+			wantErr: `invalid pr: pull request "foo/bar" isn't in the format`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.flags.validate(func(k string) string { return tc.env[k] })
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantPR, tc.flags.pr); diff != "" {
+				t.Errorf("Process(%+v) got pull request diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestPRCommentFlagsPost(t *testing.T) {
+	t.Parallel()
+
+	summary := &v1alpha1.IAMRequest{
+		ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+			Resource: "projects/foo",
+			Bindings: []*v1alpha1.Binding{{Members: []string{"user:alice@example.com"}, Role: "roles/viewer"}},
+		}},
+	}
+
+	cases := []struct {
+		name      string
+		enabled   bool
+		comment   *prComment
+		messages  string
+		commenter *fakePRCommenter
+		wantBody  string
+	}{
+		{
+			name:      "success",
+			enabled:   true,
+			comment:   &prComment{Action: "handle", Done: "handled", Summary: summary},
+			commenter: &fakePRCommenter{},
+			wantBody: "**AOD successfully handled the IAM request.**\n\n" +
+				"```yaml\n" +
+				"policies:\n" +
+				"  - resource: projects/foo\n" +
+				"    bindings:\n" +
+				"      - members:\n" +
+				"          - user:alice@example.com\n" +
+				"        role: roles/viewer\n" +
+				"```\n",
+		},
+		{
+			name:    "failure_with_responses",
+			enabled: true,
+			comment: &prComment{
+				Action: "clean up",
+				Done:   "cleaned up",
+				Err:    fmt.Errorf("injected error"),
+				Responses: []*redactedResponse{{
+					Resource: "projects/foo",
+					Bindings: []*redactedBinding{{
+						Role:       "roles/viewer",
+						Members:    []string{"user:alice@example.com", "1 other member(s) redacted"},
+						Expression: "request.time < timestamp('2009-11-10T23:00:00Z')",
+					}},
+				}},
+			},
+			commenter: &fakePRCommenter{},
+			wantBody: "**AOD failed to clean up the IAM request.**\n\n" +
+				"```\ninjected error\n```\n\n" +
+				"<details>\n<summary>AOD bindings in the updated IAM policies (redacted)</summary>\n\n" +
+				"```yaml\n" +
+				"- resource: projects/foo\n" +
+				"  bindings:\n" +
+				"    - role: roles/viewer\n" +
+				"      members:\n" +
+				"        - user:alice@example.com\n" +
+				"        - 1 other member(s) redacted\n" +
+				"      expression: request.time < timestamp('2009-11-10T23:00:00Z')\n" +
+				"```\n\n</details>\n",
+		},
+		{
+			name:      "commenter_failure",
+			enabled:   true,
+			comment:   &prComment{Action: "handle", Done: "handled"},
+			commenter: &fakePRCommenter{injectErr: fmt.Errorf("injected error")},
+			wantBody:  "**AOD successfully handled the IAM request.**\n",
+		},
+		{
+			name:    "custom_messages",
+			enabled: true,
+			comment: &prComment{Action: "handle", Done: "handled", Err: fmt.Errorf("injected error")},
+			messages: `
+messages:
+  pr_comment_failure: "**AOD failed to {{ .Action }} the IAM request, see https://example.com/aod-help.**"
+`,
+			commenter: &fakePRCommenter{},
+			wantBody: "**AOD failed to handle the IAM request, see https://example.com/aod-help.**\n\n" +
+				"```\ninjected error\n```\n",
+		},
+		{
+			name:      "disabled",
+			comment:   &prComment{Action: "handle", Done: "handled"},
+			commenter: &fakePRCommenter{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			flags := &prCommentFlags{
+				flagGitHubComment: tc.enabled,
+				pr:                &approval.PullRequest{Owner: "foo", Repo: "bar", Number: 12},
+				testCommenter:     tc.commenter,
+			}
+			env := map[string]string{}
+			if tc.messages != "" {
+				path := filepath.Join(t.TempDir(), "messages.yaml")
+				if err := os.WriteFile(path, []byte(tc.messages), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				env[messagesFileEnv] = path
+			}
+			flags.post(ctx, func(k string) string { return env[k] }, tc.comment)
+
+			if diff := cmp.Diff(tc.wantBody, tc.commenter.gotBody); diff != "" {
+				t.Errorf("Process(%+v) got body diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if tc.enabled && tc.commenter.gotNumber != 12 {
+				t.Errorf("Process(%+v) got pull request number %d, want 12", tc.name, tc.commenter.gotNumber)
+			}
+		})
+	}
+}
+
+func TestRedactResponses(t *testing.T) {
+	t.Parallel()
+
+	cond := &expr.Expr{Title: "aod-expiry", Expression: "request.time < timestamp('2009-11-10T23:00:00Z')"}
+	resps := []*v1alpha1.IAMResponse{
+		{
+			Resource: "projects/foo",
+			Policy: &iampb.Policy{
+				Version: 3,
+				Etag:    []byte("etag"),
+				Bindings: []*iampb.Binding{
+					{Role: "roles/owner", Members: []string{"user:admin@example.com"}},
+					{Role: "roles/viewer", Members: []string{"user:alice@example.com", "user:bob@example.com", "user:carol@example.com"}, Condition: cond},
+					{Role: "roles/browser", Members: []string{"user:bob@example.com"}, Condition: cond},
+				},
+			},
+			Warnings: []string{"bananas"},
+		},
+		{
+			Resource: "projects/bar",
+			Skipped:  "resource projects/bar is not active (state: DELETE_REQUESTED)",
+		},
+	}
+
+	want := []*redactedResponse{
+		{
+			Resource: "projects/foo",
+			Bindings: []*redactedBinding{
+				{Role: "roles/viewer", Members: []string{"user:alice@example.com", "2 other member(s) redacted"}, Expression: cond.GetExpression()},
+				{Role: "roles/browser", Members: []string{"1 other member(s) redacted"}, Expression: cond.GetExpression()},
+			},
+			Warnings: []string{"bananas"},
+		},
+		{
+			Resource: "projects/bar",
+			Skipped:  "resource projects/bar is not active (state: DELETE_REQUESTED)",
+		},
+	}
+
+	got := redactResponses(resps, "aod-expiry", []string{"user:alice@example.com"})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("redactResponses got diff (-want, +got):\n%s", diff)
+	}
+}
+
+type fakePRCommenter struct {
+	injectErr error
+	gotNumber int
+	gotBody   string
+}
+
+func (c *fakePRCommenter) Comment(ctx context.Context, number int, body string) (string, error) {
+	c.gotNumber, c.gotBody = number, body
+	if c.injectErr != nil {
+		return "", c.injectErr
+	}
+	return "https://github.com/foo/bar/pull/12#issuecomment-1", nil
+}
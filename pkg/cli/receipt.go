@@ -0,0 +1,120 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/posener/complete/v2/predict"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/receipt"
+	"github.com/abcxyz/pkg/cli"
+)
+
+// receiptFlags are the flags of the signed grant receipts, which iam handle
+// writes for the handled requests, and iam cleanup reads to clean up exactly
+// the granted bindings.
+type receiptFlags struct {
+	flagReceipt string
+
+	flagReceiptKey string
+}
+
+// register registers the receipt flags to the given flag section, with the
+// usage of the receipt file of the command.
+func (r *receiptFlags) register(f *cli.FlagSection, usage string) {
+	f.StringVar(&cli.StringVar{
+		Name:    "receipt",
+		Target:  &r.flagReceipt,
+		Example: "/path/to/receipt.yaml",
+		Predict: predict.Files("*"),
+		Usage:   usage,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "receipt-key",
+		Target:  &r.flagReceiptKey,
+		EnvVar:  "AOD_RECEIPT_KEY",
+		Example: "s3cr3t",
+		Usage: "The key to sign and verify grant receipts with HMAC-SHA256, " +
+			"required with receipt.",
+	})
+}
+
+// validate validates the receipt flags.
+func (r *receiptFlags) validate() error {
+	if r.flagReceipt != "" && r.flagReceiptKey == "" {
+		return fmt.Errorf("receipt-key is required with receipt")
+	}
+	return nil
+}
+
+// write signs the receipts of the handled requests and writes them to the
+// receipt file, replacing it if it exists.
+func (r *receiptFlags) write(reqs []*v1alpha1.IAMRequestWrapper) error {
+	receipts := make([]*receipt.Receipt, 0, len(reqs))
+	for _, w := range reqs {
+		rc := &receipt.Receipt{
+			GrantID:   w.RequestHash,
+			Request:   w.IAMRequest,
+			StartTime: w.StartTime,
+			Expiry:    w.StartTime.Add(w.Duration),
+		}
+		if err := receipt.Sign(rc, []byte(r.flagReceiptKey)); err != nil {
+			return fmt.Errorf("failed to sign receipt: %w", err)
+		}
+		receipts = append(receipts, rc)
+	}
+
+	f, err := os.Create(r.flagReceipt)
+	if err != nil {
+		return fmt.Errorf("failed to create receipt file: %w", err)
+	}
+	if err := receipt.Write(f, receipts); err != nil {
+		return errors.Join(fmt.Errorf("failed to write receipt file: %w", err), f.Close())
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close receipt file: %w", err)
+	}
+	return nil
+}
+
+// read reads the receipts of the receipt file, and verifies all of them are
+// signed with the receipt key.
+func (r *receiptFlags) read() ([]*receipt.Receipt, error) {
+	f, err := os.Open(r.flagReceipt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open receipt file: %w", err)
+	}
+	defer f.Close()
+
+	receipts, err := receipt.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read receipt file: %w", err)
+	}
+	var retErr error
+	for _, rc := range receipts {
+		if err := receipt.Verify(rc, []byte(r.flagReceiptKey)); err != nil {
+			retErr = errors.Join(retErr, err)
+		}
+	}
+	if retErr != nil {
+		return nil, fmt.Errorf("failed to verify receipt file: %w", retErr)
+	}
+	return receipts, nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/posener/complete/v2/predict"
+
+	"github.com/abcxyz/access-on-demand/pkg/messages"
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+)
+
+var _ cli.Command = (*RequestCleanupCommand)(nil)
+
+// RequestCleanupCommand handles the cleanup of combined requests, which removes
+// the bindings of the IAM request and expired AOD bindings from IAM policy.
+type RequestCleanupCommand struct {
+	cli.BaseCommand
+
+	flagPath string
+
+	flagVerbose bool
+
+	iamHandlerFlags iamHandlerFlags
+
+	identityFlags identityFlags
+
+	entitlementFlags entitlementFlags
+
+	// testHandler is used for testing only.
+	testHandler iamCleanupHandler
+}
+
+func (c *RequestCleanupCommand) Desc() string {
+	return "Clean up the IAM bindings requested in the given combined request " +
+		"YAML file along with other expired AOD IAM bindings"
+}
+
+func (c *RequestCleanupCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Cleanup of the combined request YAML file in the given path:
+
+      {{ COMMAND }} -path "/path/to/file.yaml"
+
+Cleanup of the combined request YAML file and output applied IAM changes:
+
+      {{ COMMAND }} -path "/path/to/file.yaml" -verbose
+`
+}
+
+func (c *RequestCleanupCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "path",
+		Target:  &c.flagPath,
+		Example: "/path/to/file.yaml",
+		Predict: predict.Files("*"),
+		Usage:   combinedPathUsage,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "verbose",
+		Target:  &c.flagVerbose,
+		Default: false,
+		Usage:   "Turn on verbose mode to print updated IAM policies. Note that it may contain sensitive information.",
+	})
+
+	c.iamHandlerFlags.register(f)
+	c.identityFlags.register(f)
+	c.entitlementFlags.register(f)
+
+	return set
+}
+
+func (c *RequestCleanupCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagPath == "" {
+		return fmt.Errorf("path is required")
+	}
+
+	if err := c.iamHandlerFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.identityFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.entitlementFlags.validate(); err != nil {
+		return err
+	}
+
+	return c.cleanup(ctx)
+}
+
+func (c *RequestCleanupCommand) cleanup(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	req, err := readCombinedRequest(newRequestReader(c.Stdin()), c.flagPath,
+		c.entitlementFlags.catalog, c.identityFlags.identities, 0, nil)
+	if err != nil {
+		auditValidationDenied(ctx, c.iamHandlerFlags.flagAuditLogProject, err)
+		return err
+	}
+
+	var h iamCleanupHandler
+	if c.testHandler != nil {
+		// Use testHandler if it is for testing.
+		h = c.testHandler
+	} else {
+		iamHandler, closer, newHandlerErr := newIAMHandler(ctx, &c.iamHandlerFlags, &c.BaseCommand)
+		if newHandlerErr != nil {
+			return newHandlerErr
+		}
+		h = iamHandler
+		defer func() {
+			if err := closer.Close(); err != nil {
+				logger.ErrorContext(ctx, "failed to close", "error", err)
+			}
+		}()
+	}
+
+	resp, err := h.Cleanup(ctx, req.IAM)
+	if err != nil {
+		return withExitCode(apiExitCode(err, len(req.IAM.ResourcePolicies)),
+			fmt.Errorf("failed to clean up IAM policy: %w", err))
+	}
+
+	printMessageHeader(ctx, &c.BaseCommand, messages.HeaderCleanedUp)
+	if err := encodeYaml(c.Stdout(), req.IAM); err != nil {
+		return fmt.Errorf("failed to output applied request: %w", err)
+	}
+
+	if c.flagVerbose {
+		printMessageHeader(ctx, &c.BaseCommand, messages.HeaderCleanedUpPolicies)
+		if err := encodeYaml(c.Stdout(), resp); err != nil {
+			return fmt.Errorf("failed to output IAM policies: %w", err)
+		}
+	}
+
+	return nil
+}
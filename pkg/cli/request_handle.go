@@ -0,0 +1,302 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/posener/complete/v2/predict"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/handler"
+	"github.com/abcxyz/access-on-demand/pkg/messages"
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+)
+
+var _ cli.Command = (*RequestHandleCommand)(nil)
+
+// RequestHandleCommand handles combined requests, it grants the IAM bindings of
+// the IAM request and then runs the commands of the tool request.
+type RequestHandleCommand struct {
+	cli.BaseCommand
+
+	flagPath string
+
+	flagDuration time.Duration
+
+	flagStartTime time.Time
+
+	flagVerbose bool
+
+	iamHandlerFlags iamHandlerFlags
+
+	provenanceFlags provenanceFlags
+
+	memberCheckFlags memberCheckFlags
+
+	policyFlags policyFlags
+
+	identityFlags identityFlags
+
+	entitlementFlags entitlementFlags
+
+	approvalFlags approvalFlags
+
+	admissionFlags admissionFlags
+
+	receiptFlags receiptFlags
+
+	// testIAMHandler and testToolHandler are used for testing only.
+	testIAMHandler  iamHandler
+	testToolHandler toolHandler
+}
+
+func (c *RequestHandleCommand) Desc() string {
+	return `Handle the IAM request and run the tool commands in the combined ` +
+		`request YAML file at the given path`
+}
+
+func (c *RequestHandleCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Grant the IAM bindings and then run the tool commands in the combined request
+YAML file at the given path:
+
+      {{ COMMAND }} -path "/path/to/file.yaml" -duration "2h"
+
+Handle the combined request and output applied IAM changes and commands output:
+
+      {{ COMMAND }} -path "/path/to/file.yaml" -duration "2h" -verbose
+
+Handle the combined request after verifying it is approved in the pull request:
+
+      {{ COMMAND }} -path "/path/to/file.yaml" -duration "2h" -require-approvals 1 -github-pr "my-org/my-repo/123"
+`
+}
+
+func (c *RequestHandleCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "path",
+		Target:  &c.flagPath,
+		Example: "/path/to/file.yaml",
+		Predict: predict.Files("*"),
+		Usage:   combinedPathUsage,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "duration",
+		Target:  &c.flagDuration,
+		Example: "2h",
+		Usage:   `The IAM permission lifecycle, as a duration.`,
+	})
+
+	f.TimeVar(time.RFC3339, &cli.TimeVar{
+		Name:    "start-time",
+		Target:  &c.flagStartTime,
+		Example: "2009-11-10T23:00:00Z",
+		Usage: `The start time of the IAM permission lifecycle in RFC3339 format. ` +
+			`Default is current UTC time.`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "verbose",
+		Target:  &c.flagVerbose,
+		Default: false,
+		Usage: `Turn on verbose mode to print updated IAM policies and commands ` +
+			`output. Note that it may contain sensitive information`,
+	})
+
+	c.iamHandlerFlags.register(f)
+
+	c.provenanceFlags.register(f)
+
+	c.memberCheckFlags.register(f)
+	c.policyFlags.register(f)
+	c.identityFlags.register(f)
+	c.entitlementFlags.register(f)
+
+	c.approvalFlags.register(f)
+	c.admissionFlags.register(f)
+
+	c.receiptFlags.register(f, `The path to write the signed grant receipt of `+
+		`the IAM request to, in YAML format, with its grant ID, bindings `+
+		`and expiry. Present it to iam cleanup to clean up exactly the `+
+		`granted bindings.`)
+
+	return set
+}
+
+func (c *RequestHandleCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagPath == "" {
+		return fmt.Errorf("path is required")
+	}
+
+	if err := c.iamHandlerFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.policyFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.identityFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.entitlementFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.grantChecks().validate([]string{c.flagPath}); err != nil {
+		return err
+	}
+
+	if err := c.receiptFlags.validate(); err != nil {
+		return err
+	}
+
+	if c.flagDuration <= 0 {
+		return fmt.Errorf("a positive duration is required")
+	}
+
+	// Default start time to the current time.
+	now := c.iamHandlerFlags.now()
+	if c.flagStartTime.IsZero() {
+		c.flagStartTime = now
+	}
+
+	if c.flagStartTime.Add(c.flagDuration).Before(now) {
+		return fmt.Errorf("expiry (start time: %q + duration: %q) already passed", c.flagStartTime, c.flagDuration)
+	}
+
+	return c.handle(ctx)
+}
+
+func (c *RequestHandleCommand) handle(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	rr := newRequestReader(c.Stdin())
+	req, err := readCombinedRequest(rr, c.flagPath, c.entitlementFlags.catalog, c.identityFlags.identities,
+		c.flagDuration, c.policyFlags.options(c.flagDuration))
+	if err != nil {
+		auditValidationDenied(ctx, c.iamHandlerFlags.flagAuditLogProject, err)
+		return err
+	}
+	printToolWarnings(c.Stderr(), req.Tool, c.GetEnv("GITHUB_ACTIONS") == "true")
+
+	var ih iamHandler
+	if c.testIAMHandler != nil {
+		// Use testIAMHandler if it is for testing.
+		ih = c.testIAMHandler
+	} else {
+		iamHandler, closer, newHandlerErr := newIAMHandler(ctx, &c.iamHandlerFlags, &c.BaseCommand)
+		if newHandlerErr != nil {
+			return newHandlerErr
+		}
+		ih = iamHandler
+		defer func() {
+			if err := closer.Close(); err != nil {
+				logger.ErrorContext(ctx, "failed to close", "error", err)
+			}
+		}()
+	}
+
+	reqWrapper, err := c.grantChecks().wrap(ctx, rr, c.flagPath, req.IAM, c.flagDuration, c.flagStartTime)
+	if err != nil {
+		return err
+	}
+
+	resp, err := ih.Do(ctx, reqWrapper)
+	printWarnings(c.Stderr(), resp, c.GetEnv("GITHUB_ACTIONS") == "true")
+	if err != nil {
+		return withExitCode(apiExitCode(err, len(reqWrapper.ResourcePolicies)),
+			fmt.Errorf("failed to handle IAM request: %w", err))
+	}
+	printMessageHeader(ctx, &c.BaseCommand, messages.HeaderHandled)
+	if err := encodeYaml(c.Stdout(), reqWrapper); err != nil {
+		return fmt.Errorf("failed to output applied request: %w", err)
+	}
+	if c.flagVerbose {
+		printMessageHeader(ctx, &c.BaseCommand, messages.HeaderUpdatedPolicies)
+		if err := encodeYaml(c.Stdout(), resp); err != nil {
+			return fmt.Errorf("failed to output IAM policies: %w", err)
+		}
+	}
+	if c.receiptFlags.flagReceipt != "" {
+		if err := c.receiptFlags.write([]*v1alpha1.IAMRequestWrapper{reqWrapper}); err != nil {
+			return err
+		}
+	}
+
+	var th toolHandler
+	if c.testToolHandler != nil {
+		// Use testToolHandler if it is for testing.
+		th = c.testToolHandler
+	} else {
+		opts := []handler.ToolHandlerOption{handler.WithStderr(c.Stderr())}
+		if c.flagVerbose {
+			printMessageHeader(ctx, &c.BaseCommand, messages.HeaderToolOutput)
+			opts = append(opts, handler.WithStdout(c.Stdout()))
+		}
+		th = handler.NewToolHandler(ctx, opts...)
+	}
+
+	// The IAM bindings are kept until they expire or are cleaned up if the
+	// commands fail, so that the commands can be retried.
+	if err := th.Do(ctx, req.Tool); err != nil {
+		return fmt.Errorf(`failed to run "do" commands: %w`, err)
+	}
+
+	printMessageHeader(ctx, &c.BaseCommand, messages.HeaderToolCompleted)
+	cmds := make([]string, 0, len(req.Tool.Do))
+	for _, sub := range req.Tool.Do {
+		cmds = append(cmds, fmt.Sprintf("%s %s", req.Tool.Tool, sub))
+	}
+	if err := encodeYaml(c.Stdout(), cmds); err != nil {
+		return fmt.Errorf("failed to output executed commands: %w", err)
+	}
+
+	return nil
+}
+
+// grantChecks returns the checks of the IAM request before it is granted.
+func (c *RequestHandleCommand) grantChecks() *grantChecks {
+	return &grantChecks{
+		memberCheckFlags: &c.memberCheckFlags,
+		approvalFlags:    &c.approvalFlags,
+		provenanceFlags:  &c.provenanceFlags,
+		admissionFlags:   &c.admissionFlags,
+		getenv:           c.GetEnv,
+		auditLogProject:  c.iamHandlerFlags.flagAuditLogProject,
+	}
+}
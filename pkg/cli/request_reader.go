@@ -0,0 +1,233 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/requestutil"
+)
+
+const (
+	// stdinPath is the path of a request file to read it from stdin.
+	stdinPath = "-"
+
+	// stdinName identifies the request read from stdin in errors.
+	stdinName = "<stdin>"
+
+	// maxStdinSize is the max size of a request read from stdin, the same as the
+	// max size of a request file.
+	maxStdinSize = 64 * 1_000
+)
+
+// multiPathUsage is appended to the usage of the "-path" flag of the commands
+// accepting multiple request files.
+const multiPathUsage = ` It can be repeated, and can be a directory of ` +
+	`YAML files or a glob such as "requests/*.yaml", each request is ` +
+	`processed separately.`
+
+// requestFileExts are the extensions of the request files in directories.
+var requestFileExts = []string{".yaml", ".yml"}
+
+// expandRequestPaths expands the paths of request files, each of which is one
+// of:
+//
+//   - A file, or "-" for stdin, which is kept as is.
+//   - A directory, which is expanded to the YAML files in it and its
+//     subdirectories, in lexical order.
+//   - A glob such as "requests/*.yaml", which is expanded to the matching files
+//     and the YAML files in the matching directories.
+//
+// The expanded paths are deduplicated. It returns an error if a directory or a
+// glob has no request files.
+func expandRequestPaths(paths []string) ([]string, error) {
+	var result []string
+	var retErr error
+	for _, p := range paths {
+		matches := []string{p}
+		if p != stdinPath && isGlob(p) {
+			m, err := filepath.Glob(p)
+			if err != nil {
+				retErr = errors.Join(retErr, fmt.Errorf("invalid glob %q: %w", p, err))
+				continue
+			}
+			if len(m) == 0 {
+				retErr = errors.Join(retErr, fmt.Errorf("no files match %q", p))
+				continue
+			}
+			matches = m
+		}
+
+		for _, m := range matches {
+			files, err := requestFilesInDir(m)
+			if err != nil {
+				retErr = errors.Join(retErr, err)
+				continue
+			}
+			result = append(result, files...)
+		}
+	}
+	if retErr != nil {
+		return nil, retErr
+	}
+
+	// Deduplicate the paths in order, e.g. a file matched by multiple globs.
+	seen := make(map[string]struct{}, len(result))
+	return slices.DeleteFunc(result, func(p string) bool {
+		if _, ok := seen[p]; ok {
+			return true
+		}
+		seen[p] = struct{}{}
+		return false
+	}), nil
+}
+
+// withPath identifies the file of the error with the path, if there are
+// multiple paths.
+func withPath(paths []string, path string, err error) error {
+	if len(paths) > 1 {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return err
+}
+
+// requestFilesInDir returns the YAML files in the directory at the path and its
+// subdirectories, or the path itself if it is not a directory.
+func requestFilesInDir(path string) ([]string, error) {
+	if path == stdinPath {
+		return []string{path}, nil
+	}
+	fi, err := os.Stat(path)
+	if err != nil || !fi.IsDir() {
+		// Missing files are kept as is, the error of reading them is reported
+		// by the command.
+		return []string{path}, nil //nolint:nilerr // Want passthrough
+	}
+
+	var files []string
+	if err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && slices.Contains(requestFileExts, filepath.Ext(p)) {
+			files = append(files, p)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to read directory %q: %w", path, err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no request files in directory %q", path)
+	}
+	return files, nil
+}
+
+// isGlob returns whether the path has any glob meta characters.
+func isGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// requestReader reads request files at paths, or from stdin if the path is
+// "-". Stdin is read once and kept in memory, so that the request can be read
+// multiple times by a command, e.g. to decode and then hash it.
+type requestReader struct {
+	stdin io.Reader
+
+	stdinOnce sync.Once
+	stdinData []byte
+	stdinErr  error
+}
+
+// newRequestReader creates a requestReader which reads "-" from stdin.
+func newRequestReader(stdin io.Reader) *requestReader {
+	return &requestReader{stdin: stdin}
+}
+
+// readStdin reads stdin once.
+func (r *requestReader) readStdin() ([]byte, error) {
+	r.stdinOnce.Do(func() {
+		data, err := io.ReadAll(io.LimitReader(r.stdin, maxStdinSize+1))
+		if err != nil {
+			r.stdinErr = fmt.Errorf("failed to read %s: %w", stdinName, err)
+			return
+		}
+		if len(data) > maxStdinSize {
+			r.stdinErr = fmt.Errorf("content of %s exceeds size limit of %d bytes", stdinName, maxStdinSize)
+			return
+		}
+		r.stdinData = data
+	})
+	return r.stdinData, r.stdinErr
+}
+
+// readFile returns the content of the file at the path.
+func (r *requestReader) readFile(path string) ([]byte, error) {
+	if path == stdinPath {
+		return r.readStdin()
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %q: %w", path, err)
+	}
+	return data, nil
+}
+
+// iamBundle reads the IAM requests in the bundle at the path, see
+// [requestutil.ReadBundleFromPath]. Stdin is read as a YAML file, which may
+// contain multiple YAML documents.
+func (r *requestReader) iamBundle(path string) ([]*requestutil.Document[v1alpha1.IAMRequest], error) {
+	if path != stdinPath {
+		return requestutil.ReadBundleFromPath[v1alpha1.IAMRequest](path) //nolint:wrapcheck // Want passthrough
+	}
+	data, err := r.readStdin()
+	if err != nil {
+		return nil, err
+	}
+	return requestutil.ReadBundle[v1alpha1.IAMRequest](stdinName, data) //nolint:wrapcheck // Want passthrough
+}
+
+// requestWithLocator reads the request file at the path to req, see
+// [requestutil.ReadRequestWithLocator].
+func (r *requestReader) requestWithLocator(path string, req any) (*requestutil.Locator, error) {
+	if path != stdinPath {
+		return requestutil.ReadRequestWithLocator(path, req) //nolint:wrapcheck // Want passthrough
+	}
+	data, err := r.readStdin()
+	if err != nil {
+		return nil, err
+	}
+	return requestutil.DecodeRequestWithLocator(data, req) //nolint:wrapcheck // Want passthrough
+}
+
+// hash returns the SHA256 hash of the request file at the path in hex.
+func (r *requestReader) hash(path string) (string, error) {
+	if path != stdinPath {
+		return requestutil.HashFile(path) //nolint:wrapcheck // Want passthrough
+	}
+	data, err := r.readStdin()
+	if err != nil {
+		return "", err
+	}
+	return requestutil.Hash(data), nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/pkg/requestutil"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestRequestReader(t *testing.T) {
+	t.Parallel()
+
+	content := `
+policies:
+- resource: projects/foo
+  bindings:
+  - members:
+    - user:alice@example.com
+    role: roles/viewer
+`
+	path := filepath.Join(t.TempDir(), "iam.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	wantHash, err := requestutil.HashFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		path    string
+		stdin   string
+		wantErr string
+	}{
+		{
+			name: "file",
+			path: path,
+		},
+		{
+			name:  "stdin",
+			path:  "-",
+			stdin: content,
+		},
+		{
+			name:    "stdin_too_large",
+			path:    "-",
+			stdin:   strings.Repeat("a", maxStdinSize+1),
+			wantErr: "content of <stdin> exceeds size limit of 64000 bytes",
+		},
+		{
+			name:    "missing_file",
+			path:    filepath.Join(t.TempDir(), "missing.yaml"),
+			wantErr: "failed to read file",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			rr := newRequestReader(strings.NewReader(tc.stdin))
+
+			// Read the request multiple times as the commands do.
+			docs, err := rr.iamBundle(tc.path)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected err: %s", tc.name, diff)
+			}
+			if err != nil {
+				return
+			}
+			if got, want := len(docs), 1; got != want {
+				t.Fatalf("Process(%+v) got %d documents, want %d", tc.name, got, want)
+			}
+			if diff := cmp.Diff([]string{"user:alice@example.com"}, docs[0].Request.ResourcePolicies[0].Bindings[0].Members); diff != "" {
+				t.Errorf("Process(%+v) got members diff (-want, +got):\n%s", tc.name, diff)
+			}
+
+			gotHash, err := rr.hash(tc.path)
+			if err != nil {
+				t.Fatalf("Process(%+v) failed to hash: %v", tc.name, err)
+			}
+			if gotHash != wantHash {
+				t.Errorf("Process(%+v) got hash %q, want %q", tc.name, gotHash, wantHash)
+			}
+		})
+	}
+}
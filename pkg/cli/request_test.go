@@ -0,0 +1,577 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/approval"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+// combinedRequestFiles are the combined request files used by the tests of
+// the request commands.
+var combinedRequestFiles = map[string]string{
+	"valid.yaml": `
+iam:
+  policies:
+  - resource: projects/foo
+    bindings:
+    - members:
+      - user:test-project-user@example.com
+      role: roles/run.developer
+tool:
+  do:
+  - run jobs execute my-job
+`,
+	"invalid-request.yaml": `
+iam:
+  policies:
+  - resource: projects/foo
+    bindings:
+    - members:
+      - group:test-project-group@example.com
+      role: roles/run.developer
+tool:
+  tool: aws
+  do:
+  - run jobs execute my-job
+`,
+	"missing-tool.yaml": `
+iam:
+  policies:
+  - resource: projects/foo
+    bindings:
+    - members:
+      - user:test-project-user@example.com
+      role: roles/run.developer
+`,
+	"aliased.yaml": `
+iam:
+  policies:
+  - resource: projects/foo
+    bindings:
+    - members:
+      - github:octocat
+      role: roles/run.developer
+tool:
+  do:
+  - run jobs execute my-job
+`,
+	"entitlement.yaml": `
+iam:
+  entitlements:
+  - entitlement: run-dev
+    members: [user:test-project-user@example.com]
+    duration: 2h
+tool:
+  do:
+  - run jobs execute my-job
+`,
+	"identities.yaml": `
+github:
+  octocat: user:test-project-user@example.com
+`,
+	"entitlements.yaml": `
+run-dev:
+  resources: [projects/foo]
+  roles: [roles/run.developer]
+  maxDuration: 4h
+`,
+	"invalid.yaml": `bananas`,
+}
+
+// writeCombinedRequestFiles writes the combined request files to a temporary
+// directory, and returns the directory and the hashes of the files by name.
+func writeCombinedRequestFiles(tb testing.TB) (string, map[string]string) {
+	tb.Helper()
+
+	dir := tb.TempDir()
+	hashes := make(map[string]string, len(combinedRequestFiles))
+	for name, content := range combinedRequestFiles {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			tb.Fatal(err)
+		}
+		sum := sha256.Sum256([]byte(content))
+		hashes[name] = hex.EncodeToString(sum[:])
+	}
+	return dir, hashes
+}
+
+// validCombinedIAMRequest is the IAM request in the valid combined request
+// file.
+func validCombinedIAMRequest() *v1alpha1.IAMRequest {
+	return &v1alpha1.IAMRequest{
+		ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+			Resource: "projects/foo",
+			Bindings: []*v1alpha1.Binding{{
+				Members: []string{"user:test-project-user@example.com"},
+				Role:    "roles/run.developer",
+			}},
+		}},
+	}
+}
+
+func TestRequestValidateCommand(t *testing.T) {
+	t.Parallel()
+
+	dir, _ := writeCombinedRequestFiles(t)
+
+	cases := []struct {
+		name   string
+		args   []string
+		expOut string
+		expErr string
+	}{
+		{
+			name:   "success",
+			args:   []string{"-path", filepath.Join(dir, "valid.yaml")},
+			expOut: `Successfully validated combined request`,
+		},
+		{
+			name: "invalid_request",
+			args: []string{"-path", filepath.Join(dir, "invalid-request.yaml")},
+			expErr: `iam.policies[0].bindings[0].members[0] at line 7, column 9: member "group:test-project-group@example.com" is not of "user" type (got "group")` + "\n" +
+				`tool.tool at line 10, column 9: tool "aws" is not supported`,
+		},
+		{
+			name:   "missing_tool",
+			args:   []string{"-path", filepath.Join(dir, "missing-tool.yaml")},
+			expErr: "tool request not found",
+		},
+		{
+			name:   "identity_alias",
+			args:   []string{"-path", filepath.Join(dir, "aliased.yaml"), "-identity-map", filepath.Join(dir, "identities.yaml")},
+			expOut: `Successfully validated combined request`,
+		},
+		{
+			name:   "unknown_identity_alias",
+			args:   []string{"-path", filepath.Join(dir, "aliased.yaml")},
+			expErr: `iam.policies[0].bindings[0].members[0] at line 7, column 9: member "github:octocat" is not of "user" type`,
+		},
+		{
+			name:   "entitlement",
+			args:   []string{"-path", filepath.Join(dir, "entitlement.yaml"), "-entitlement-catalog", filepath.Join(dir, "entitlements.yaml")},
+			expOut: `Successfully validated combined request`,
+		},
+		{
+			name:   "entitlement_without_catalog",
+			args:   []string{"-path", filepath.Join(dir, "entitlement.yaml")},
+			expErr: "iam.entitlements at line 4, column 3: entitlements must be expanded with an entitlement catalog",
+		},
+		{
+			name:   "invalid_yaml",
+			args:   []string{"-path", filepath.Join(dir, "invalid.yaml")},
+			expErr: "failed to read *v1alpha1.CombinedRequest",
+		},
+		{
+			name:   "missing_path",
+			args:   []string{},
+			expErr: "path is required",
+		},
+		{
+			name:   "unexpected_args",
+			args:   []string{"foo"},
+			expErr: `unexpected arguments: ["foo"]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var cmd RequestValidateCommand
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestRequestHandleCommand(t *testing.T) {
+	t.Parallel()
+
+	dir, hashes := writeCombinedRequestFiles(t)
+	st := time.Now().UTC().Round(time.Second)
+	handledOut := fmt.Sprintf(`
+------Successfully Handled IAM Request------
+iamrequest:
+  policies:
+    - resource: projects/foo
+      bindings:
+        - members:
+            - user:test-project-user@example.com
+          role: roles/run.developer
+duration: 2h0m0s
+starttime: %s
+------Successfully Completed Commands------
+- gcloud run jobs execute my-job`, st.Format(time.RFC3339))
+
+	cases := []struct {
+		name        string
+		args        []string
+		iamHandler  *fakeIAMHandler
+		toolHandler *fakeToolHandler
+		checker     *fakeMemberChecker
+		verifier    *fakeApprovalVerifier
+		expIAMReq   *v1alpha1.IAMRequestWrapper
+		expToolReq  *v1alpha1.ToolRequest
+		expOut      string
+		expErr      string
+	}{
+		{
+			name:        "success",
+			args:        []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-start-time", st.Format(time.RFC3339)},
+			iamHandler:  &fakeIAMHandler{},
+			toolHandler: &fakeToolHandler{},
+			expIAMReq: &v1alpha1.IAMRequestWrapper{
+				IAMRequest:  validCombinedIAMRequest(),
+				Duration:    2 * time.Hour,
+				StartTime:   st,
+				RequestHash: hashes["valid.yaml"],
+			},
+			expToolReq: &v1alpha1.ToolRequest{Tool: "gcloud", Do: []string{"run jobs execute my-job"}},
+			expOut: fmt.Sprintf(`
+------Successfully Handled IAM Request------
+iamrequest:
+  policies:
+    - resource: projects/foo
+      bindings:
+        - members:
+            - user:test-project-user@example.com
+          role: roles/run.developer
+duration: 2h0m0s
+starttime: %s
+------Successfully Completed Commands------
+- gcloud run jobs execute my-job`, st.Format(time.RFC3339)),
+		},
+		{
+			name:        "iam_handler_failure",
+			args:        []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-start-time", st.Format(time.RFC3339)},
+			iamHandler:  &fakeIAMHandler{injectErr: fmt.Errorf("injected error")},
+			toolHandler: &fakeToolHandler{},
+			expIAMReq: &v1alpha1.IAMRequestWrapper{
+				IAMRequest:  validCombinedIAMRequest(),
+				Duration:    2 * time.Hour,
+				StartTime:   st,
+				RequestHash: hashes["valid.yaml"],
+			},
+			expErr: "failed to handle IAM request: injected error",
+		},
+		{
+			name:        "tool_handler_failure",
+			args:        []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-start-time", st.Format(time.RFC3339)},
+			iamHandler:  &fakeIAMHandler{},
+			toolHandler: &fakeToolHandler{injectErr: fmt.Errorf("injected error")},
+			expIAMReq: &v1alpha1.IAMRequestWrapper{
+				IAMRequest:  validCombinedIAMRequest(),
+				Duration:    2 * time.Hour,
+				StartTime:   st,
+				RequestHash: hashes["valid.yaml"],
+			},
+			expToolReq: &v1alpha1.ToolRequest{Tool: "gcloud", Do: []string{"run jobs execute my-job"}},
+			expOut: fmt.Sprintf(`
+------Successfully Handled IAM Request------
+iamrequest:
+  policies:
+    - resource: projects/foo
+      bindings:
+        - members:
+            - user:test-project-user@example.com
+          role: roles/run.developer
+duration: 2h0m0s
+starttime: %s`, st.Format(time.RFC3339)),
+			expErr: `failed to run "do" commands: injected error`,
+		},
+		{
+			name:        "identity_alias",
+			args:        []string{"-path", filepath.Join(dir, "aliased.yaml"), "-duration", "2h", "-start-time", st.Format(time.RFC3339), "-identity-map", filepath.Join(dir, "identities.yaml")},
+			iamHandler:  &fakeIAMHandler{},
+			toolHandler: &fakeToolHandler{},
+			expIAMReq: &v1alpha1.IAMRequestWrapper{
+				IAMRequest:  validCombinedIAMRequest(),
+				Duration:    2 * time.Hour,
+				StartTime:   st,
+				RequestHash: hashes["aliased.yaml"],
+			},
+			expToolReq: &v1alpha1.ToolRequest{Tool: "gcloud", Do: []string{"run jobs execute my-job"}},
+			expOut:     handledOut,
+		},
+		{
+			name:        "entitlement",
+			args:        []string{"-path", filepath.Join(dir, "entitlement.yaml"), "-duration", "2h", "-start-time", st.Format(time.RFC3339), "-entitlement-catalog", filepath.Join(dir, "entitlements.yaml")},
+			iamHandler:  &fakeIAMHandler{},
+			toolHandler: &fakeToolHandler{},
+			expIAMReq: &v1alpha1.IAMRequestWrapper{
+				IAMRequest:  validCombinedIAMRequest(),
+				Duration:    2 * time.Hour,
+				StartTime:   st,
+				RequestHash: hashes["entitlement.yaml"],
+			},
+			expToolReq: &v1alpha1.ToolRequest{Tool: "gcloud", Do: []string{"run jobs execute my-job"}},
+			expOut:     handledOut,
+		},
+		{
+			name:        "entitlement_longer_than_requested",
+			args:        []string{"-path", filepath.Join(dir, "entitlement.yaml"), "-duration", "3h", "-entitlement-catalog", filepath.Join(dir, "entitlements.yaml")},
+			iamHandler:  &fakeIAMHandler{},
+			toolHandler: &fakeToolHandler{},
+			expErr:      `failed to expand entitlements of *v1alpha1.CombinedRequest: entitlements[0].duration at line 6, column 15: request is handled with duration 3h0m0s, longer than the requested duration 2h0m0s of entitlement "run-dev"`,
+		},
+		{
+			name:        "approved",
+			args:        []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-start-time", st.Format(time.RFC3339), "-require-approvals", "1", "-github-pr", "foo/bar/1"},
+			iamHandler:  &fakeIAMHandler{},
+			toolHandler: &fakeToolHandler{},
+			verifier:    &fakeApprovalVerifier{result: &approval.Result{Author: "alice", Approvers: []string{"bob"}}},
+			expIAMReq: &v1alpha1.IAMRequestWrapper{
+				IAMRequest:  validCombinedIAMRequest(),
+				Duration:    2 * time.Hour,
+				StartTime:   st,
+				Approvers:   []string{"github:bob"},
+				RequestHash: hashes["valid.yaml"],
+			},
+			expToolReq: &v1alpha1.ToolRequest{Tool: "gcloud", Do: []string{"run jobs execute my-job"}},
+			expOut: fmt.Sprintf(`
+------Successfully Handled IAM Request------
+iamrequest:
+  policies:
+    - resource: projects/foo
+      bindings:
+        - members:
+            - user:test-project-user@example.com
+          role: roles/run.developer
+duration: 2h0m0s
+starttime: %s
+approvers:
+  - github:bob
+------Successfully Completed Commands------
+- gcloud run jobs execute my-job`, st.Format(time.RFC3339)),
+		},
+		{
+			name:        "not_approved",
+			args:        []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-require-approvals", "1", "-github-pr", "foo/bar/1"},
+			iamHandler:  &fakeIAMHandler{},
+			toolHandler: &fakeToolHandler{},
+			verifier:    &fakeApprovalVerifier{injectErr: fmt.Errorf(`pull request foo/bar/1 is approved by 0 users other than the author "alice"`)},
+			expErr:      `failed to validate *v1alpha1.IAMRequest: failed to verify approvals: pull request foo/bar/1 is approved by 0 users other than the author "alice"`,
+		},
+		{
+			name:        "approvals_from_stdin",
+			args:        []string{"-path", "-", "-duration", "2h", "-require-approvals", "1", "-github-pr", "foo/bar/1"},
+			iamHandler:  &fakeIAMHandler{},
+			toolHandler: &fakeToolHandler{},
+			expErr:      "require-approvals requires path to be a file in the pull request, not stdin",
+		},
+		{
+			name:        "member_check_failure",
+			args:        []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-check-member-domain", "example.com"},
+			iamHandler:  &fakeIAMHandler{},
+			toolHandler: &fakeToolHandler{},
+			checker:     &fakeMemberChecker{injectErr: fmt.Errorf(`member "user:test-project-user@example.com": user does not exist in the directory`)},
+			expErr:      `failed to validate *v1alpha1.IAMRequest: failed to check members: member "user:test-project-user@example.com": user does not exist in the directory`,
+		},
+		{
+			name:        "receipt_without_key",
+			args:        []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-receipt", filepath.Join(dir, "receipt.yaml")},
+			iamHandler:  &fakeIAMHandler{},
+			toolHandler: &fakeToolHandler{},
+			expErr:      "receipt-key is required with receipt",
+		},
+		{
+			name:        "invalid_request",
+			args:        []string{"-path", filepath.Join(dir, "invalid-request.yaml"), "-duration", "2h"},
+			iamHandler:  &fakeIAMHandler{},
+			toolHandler: &fakeToolHandler{},
+			expErr:      "failed to validate *v1alpha1.CombinedRequest",
+		},
+		{
+			name:        "missing_duration",
+			args:        []string{"-path", filepath.Join(dir, "valid.yaml")},
+			iamHandler:  &fakeIAMHandler{},
+			toolHandler: &fakeToolHandler{},
+			expErr:      "a positive duration is required",
+		},
+		{
+			name:        "expired",
+			args:        []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-start-time", "2009-11-10T23:00:00Z"},
+			iamHandler:  &fakeIAMHandler{},
+			toolHandler: &fakeToolHandler{},
+			expErr:      "already passed",
+		},
+		{
+			name:        "missing_path",
+			args:        []string{"-duration", "2h"},
+			iamHandler:  &fakeIAMHandler{},
+			toolHandler: &fakeToolHandler{},
+			expErr:      "path is required",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var cmd RequestHandleCommand
+			cmd.testIAMHandler = tc.iamHandler
+			cmd.testToolHandler = tc.toolHandler
+			if tc.checker != nil {
+				cmd.memberCheckFlags.testChecker = tc.checker
+			}
+			if tc.verifier != nil {
+				cmd.approvalFlags.testVerifier = tc.verifier
+			}
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expIAMReq, tc.iamHandler.gotReq); diff != "" {
+				t.Errorf("Process(%+v) got IAM request diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expToolReq, tc.toolHandler.gotReq); diff != "" {
+				t.Errorf("Process(%+v) got tool request diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestRequestHandleCommand_Receipt(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	dir, hashes := writeCombinedRequestFiles(t)
+	receiptPath := filepath.Join(dir, "receipt.yaml")
+
+	cmd := RequestHandleCommand{testIAMHandler: &fakeIAMHandler{}, testToolHandler: &fakeToolHandler{}}
+	_, _, _ = cmd.Pipe()
+	if err := cmd.Run(ctx, []string{"-path", filepath.Join(dir, "valid.yaml"), "-duration", "2h", "-receipt", receiptPath, "-receipt-key", "s3cr3t"}); err != nil {
+		t.Fatalf("failed to handle request: %v", err)
+	}
+
+	flags := &receiptFlags{flagReceipt: receiptPath, flagReceiptKey: "s3cr3t"}
+	receipts, err := flags.read()
+	if err != nil {
+		t.Fatalf("failed to read receipt file: %v", err)
+	}
+	if got, want := len(receipts), 1; got != want {
+		t.Fatalf("got %d receipts, want %d", got, want)
+	}
+	if got, want := receipts[0].GrantID, hashes["valid.yaml"]; got != want {
+		t.Errorf("got grant ID %q, want %q", got, want)
+	}
+	if diff := cmp.Diff(validCombinedIAMRequest(), receipts[0].Request); diff != "" {
+		t.Errorf("got receipt request diff (-want, +got):\n%s", diff)
+	}
+}
+
+func TestRequestCleanupCommand(t *testing.T) {
+	t.Parallel()
+
+	dir, _ := writeCombinedRequestFiles(t)
+
+	cases := []struct {
+		name    string
+		args    []string
+		handler *fakeIAMCleanupHandler
+		expReq  *v1alpha1.IAMRequest
+		expOut  string
+		expErr  string
+	}{
+		{
+			name:    "success",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml")},
+			handler: &fakeIAMCleanupHandler{},
+			expReq:  validCombinedIAMRequest(),
+			expOut: `
+------Successfully Removed Requested Bindings------
+policies:
+  - resource: projects/foo
+    bindings:
+      - members:
+          - user:test-project-user@example.com
+        role: roles/run.developer`,
+		},
+		{
+			name:    "handler_failure",
+			args:    []string{"-path", filepath.Join(dir, "valid.yaml")},
+			handler: &fakeIAMCleanupHandler{injectErr: fmt.Errorf("injected error")},
+			expReq:  validCombinedIAMRequest(),
+			expErr:  "failed to clean up IAM policy: injected error",
+		},
+		{
+			name:    "invalid_request",
+			args:    []string{"-path", filepath.Join(dir, "invalid-request.yaml")},
+			handler: &fakeIAMCleanupHandler{},
+			expErr:  "failed to validate *v1alpha1.CombinedRequest",
+		},
+		{
+			name:    "missing_path",
+			args:    []string{},
+			handler: &fakeIAMCleanupHandler{},
+			expErr:  "path is required",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var cmd RequestCleanupCommand
+			cmd.testHandler = tc.handler
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expReq, tc.handler.gotReq); diff != "" {
+				t.Errorf("Process(%+v) got request diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
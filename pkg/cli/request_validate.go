@@ -0,0 +1,151 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/posener/complete/v2/predict"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/entitlement"
+	"github.com/abcxyz/access-on-demand/pkg/identity"
+	"github.com/abcxyz/access-on-demand/pkg/requestutil"
+	"github.com/abcxyz/pkg/cli"
+)
+
+var _ cli.Command = (*RequestValidateCommand)(nil)
+
+// combinedPathUsage is the usage of the path flag of the combined request
+// commands.
+const combinedPathUsage = `The path of combined request file, in YAML format, ` +
+	`with the IAM request under "iam" and the tool request under "tool". ` +
+	`Use "-" to read it from stdin.`
+
+// RequestValidateCommand validates combined requests.
+type RequestValidateCommand struct {
+	cli.BaseCommand
+
+	flagPath string
+
+	policyFlags policyFlags
+
+	identityFlags identityFlags
+
+	entitlementFlags entitlementFlags
+}
+
+func (c *RequestValidateCommand) Desc() string {
+	return `Validate the combined request YAML file at the given path`
+}
+
+func (c *RequestValidateCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Validate the combined request YAML file at the given path:
+
+      {{ COMMAND }} -path "/path/to/file.yaml"
+`
+}
+
+func (c *RequestValidateCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "path",
+		Target:  &c.flagPath,
+		Example: "/path/to/file.yaml",
+		Predict: predict.Files("*"),
+		Usage:   combinedPathUsage,
+	})
+
+	c.policyFlags.register(f)
+	c.identityFlags.register(f)
+	c.entitlementFlags.register(f)
+
+	return set
+}
+
+func (c *RequestValidateCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagPath == "" {
+		return fmt.Errorf("path is required")
+	}
+
+	if err := c.policyFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.identityFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.entitlementFlags.validate(); err != nil {
+		return err
+	}
+
+	req, err := readCombinedRequest(newRequestReader(c.Stdin()), c.flagPath,
+		c.entitlementFlags.catalog, c.identityFlags.identities, 0, c.policyFlags.options(0))
+	if err != nil {
+		return err
+	}
+	printToolWarnings(c.Stderr(), req.Tool, c.GetEnv("GITHUB_ACTIONS") == "true")
+	c.Outf("Successfully validated combined request")
+
+	return nil
+}
+
+// readCombinedRequest reads and validates the combined request at the path
+// with the options, which may be nil. The entitlement references in its IAM
+// request are expanded with the catalog for the duration, or 0 if it is not
+// known, and the member aliases are resolved with the identity map, the same as
+// IAM request files. The catalog and the identity map may be nil.
+func readCombinedRequest(rr *requestReader, path string, catalog entitlement.Catalog, identities identity.Map, duration time.Duration, opts *v1alpha1.ValidateOptions) (*v1alpha1.CombinedRequest, error) {
+	var req v1alpha1.CombinedRequest
+	loc, err := rr.requestWithLocator(path, &req)
+	if err != nil {
+		return nil, withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &req, err))
+	}
+
+	if req.IAM != nil {
+		docs := []*requestutil.Document[v1alpha1.IAMRequest]{{Name: path, Request: req.IAM, Locator: loc.Section("iam")}}
+		if err := requestutil.ExpandIAMBundle(docs, catalog, duration); err != nil {
+			return nil, withExitCode(ExitCodeValidation, fmt.Errorf("failed to expand entitlements of %T: %w", &req, err))
+		}
+		if err := requestutil.ResolveIAMBundle(docs, identities); err != nil {
+			return nil, withExitCode(ExitCodeValidation, fmt.Errorf("failed to resolve members of %T: %w", &req, err))
+		}
+	}
+
+	if err := v1alpha1.ValidateCombinedRequest(&req, opts); err != nil {
+		err = loc.Annotate(err)
+		return nil, withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", &req, err))
+	}
+	return &req, nil
+}
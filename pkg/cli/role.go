@@ -0,0 +1,401 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/posener/complete/v2/predict"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/customrole"
+	"github.com/abcxyz/access-on-demand/pkg/handler"
+	"github.com/abcxyz/access-on-demand/pkg/messages"
+	"github.com/abcxyz/access-on-demand/pkg/resource"
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+)
+
+var (
+	_ cli.Command = (*RoleValidateCommand)(nil)
+	_ cli.Command = (*RoleHandleCommand)(nil)
+	_ cli.Command = (*RoleCleanupCommand)(nil)
+)
+
+// rolePathUsage is the usage of the path flag of the role commands.
+const rolePathUsage = `The path of custom role request file, in YAML format. ` +
+	`Use "-" to read it from stdin.`
+
+// roleHandler interface that handles custom role requests.
+type roleHandler interface {
+	DoCustomRole(context.Context, *v1alpha1.CustomRoleRequest, *v1alpha1.IAMRequestWrapper) (*handler.CustomRoleResponse, error)
+	CleanupCustomRoles(ctx context.Context, resources []string) ([]string, error)
+}
+
+// readCustomRoleRequest reads and validates the custom role request at the
+// path.
+func readCustomRoleRequest(rr *requestReader, path string) (*v1alpha1.CustomRoleRequest, error) {
+	var req v1alpha1.CustomRoleRequest
+	loc, err := rr.requestWithLocator(path, &req)
+	if err != nil {
+		return nil, withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &req, err))
+	}
+
+	if err := v1alpha1.ValidateCustomRoleRequest(&req); err != nil {
+		err = loc.Annotate(err)
+		return nil, withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", &req, err))
+	}
+	return &req, nil
+}
+
+// newRoleHandler returns an IAM handler with a manager of custom roles.
+func newRoleHandler(ctx context.Context, flags *iamHandlerFlags, cmd *cli.BaseCommand) (*handler.IAMHandler, func(), error) {
+	logger := logging.FromContext(ctx)
+
+	m, err := customrole.NewClient(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create custom roles client: %w", err)
+	}
+	h, closer, err := newIAMHandler(ctx, flags, cmd, handler.WithCustomRoleManager(m))
+	done := func() {
+		if err := closer.Close(); err != nil {
+			logger.ErrorContext(ctx, "failed to close", "error", err)
+		}
+	}
+	if err != nil {
+		done()
+		return nil, nil, err
+	}
+	return h, done, nil
+}
+
+// RoleValidateCommand validates custom role requests.
+type RoleValidateCommand struct {
+	cli.BaseCommand
+
+	flagPath string
+}
+
+func (c *RoleValidateCommand) Desc() string {
+	return `Validate the custom role request YAML file at the given path`
+}
+
+func (c *RoleValidateCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Validate the custom role request YAML file at the given path:
+
+      {{ COMMAND }} -path "/path/to/file.yaml"
+`
+}
+
+func (c *RoleValidateCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "path",
+		Target:  &c.flagPath,
+		Example: "/path/to/file.yaml",
+		Predict: predict.Files("*"),
+		Usage:   rolePathUsage,
+	})
+
+	return set
+}
+
+func (c *RoleValidateCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagPath == "" {
+		return fmt.Errorf("path is required")
+	}
+
+	if _, err := readCustomRoleRequest(newRequestReader(c.Stdin()), c.flagPath); err != nil {
+		return err
+	}
+	c.Outf("Successfully validated custom role request")
+
+	return nil
+}
+
+// RoleHandleCommand creates temporary custom roles and binds them.
+type RoleHandleCommand struct {
+	cli.BaseCommand
+
+	flagPath string
+
+	flagDuration time.Duration
+
+	flagStartTime time.Time
+
+	iamHandlerFlags iamHandlerFlags
+
+	provenanceFlags provenanceFlags
+
+	// testHandler is used for testing only.
+	testHandler roleHandler
+}
+
+func (c *RoleHandleCommand) Desc() string {
+	return `Create a temporary custom role of the permissions in the custom role request YAML file and bind it`
+}
+
+func (c *RoleHandleCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Create a custom role of the permissions in the custom role request YAML file in
+its resource, and bind it to its members for 2 hours:
+
+      {{ COMMAND }} -path "/path/to/file.yaml" -duration "2h"
+
+The role is deleted if it fails to be bound. Expired roles are deleted by the
+"role cleanup" command.
+`
+}
+
+func (c *RoleHandleCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "path",
+		Target:  &c.flagPath,
+		Example: "/path/to/file.yaml",
+		Predict: predict.Files("*"),
+		Usage:   rolePathUsage,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "duration",
+		Target:  &c.flagDuration,
+		Example: "2h",
+		Usage:   `The custom role lifecycle, as a duration.`,
+	})
+
+	f.TimeVar(time.RFC3339, &cli.TimeVar{
+		Name:    "start-time",
+		Target:  &c.flagStartTime,
+		Example: "2009-11-10T23:00:00Z",
+		Usage: `The start time of the custom role lifecycle in RFC3339 ` +
+			`format. Default is current UTC time.`,
+	})
+
+	c.iamHandlerFlags.register(f)
+	c.provenanceFlags.register(f)
+
+	return set
+}
+
+func (c *RoleHandleCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagPath == "" {
+		return fmt.Errorf("path is required")
+	}
+
+	if err := c.iamHandlerFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.provenanceFlags.validate(); err != nil {
+		return err
+	}
+
+	if c.flagDuration <= 0 {
+		return fmt.Errorf("a positive duration is required")
+	}
+
+	// Default start time to the current time.
+	now := c.iamHandlerFlags.now()
+	if c.flagStartTime.IsZero() {
+		c.flagStartTime = now
+	}
+
+	if c.flagStartTime.Add(c.flagDuration).Before(now) {
+		return fmt.Errorf("expiry (start time: %q + duration: %q) already passed", c.flagStartTime, c.flagDuration)
+	}
+
+	return c.handleRole(ctx)
+}
+
+func (c *RoleHandleCommand) handleRole(ctx context.Context) error {
+	rr := newRequestReader(c.Stdin())
+	req, err := readCustomRoleRequest(rr, c.flagPath)
+	if err != nil {
+		return err
+	}
+
+	h := c.testHandler
+	if h == nil {
+		roleHandler, done, err := newRoleHandler(ctx, &c.iamHandlerFlags, &c.BaseCommand)
+		if err != nil {
+			return err
+		}
+		defer done()
+		h = roleHandler
+	}
+
+	reqWrapper := &v1alpha1.IAMRequestWrapper{
+		Duration:  c.flagDuration,
+		StartTime: c.flagStartTime,
+	}
+	if err := c.provenanceFlags.apply(ctx, reqWrapper); err != nil {
+		return err
+	}
+	if reqWrapper.RequestHash, err = rr.hash(c.flagPath); err != nil {
+		return fmt.Errorf("failed to hash %T: %w", req, err)
+	}
+
+	resp, err := h.DoCustomRole(ctx, req, reqWrapper)
+	if err != nil {
+		return withExitCode(apiExitCode(err, 1), fmt.Errorf("failed to handle %T: %w", req, err))
+	}
+
+	printMessageHeader(ctx, &c.BaseCommand, messages.HeaderRoleHandled)
+	if err := encodeYaml(c.Stdout(), map[string]any{
+		"role":     resp.Role,
+		"members":  req.Members,
+		"duration": c.flagDuration.String(),
+		"expiry":   c.flagStartTime.Add(c.flagDuration).UTC().Format(time.RFC3339),
+	}); err != nil {
+		return fmt.Errorf("failed to output custom role: %w", err)
+	}
+	return nil
+}
+
+// RoleCleanupCommand deletes expired custom roles created by AOD.
+type RoleCleanupCommand struct {
+	cli.BaseCommand
+
+	flagResources []string
+
+	iamHandlerFlags iamHandlerFlags
+
+	// testHandler is used for testing only.
+	testHandler roleHandler
+}
+
+func (c *RoleCleanupCommand) Desc() string {
+	return `Delete the expired custom roles created by AOD in the given organizations and projects`
+}
+
+func (c *RoleCleanupCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Delete the expired custom roles created by AOD in the project:
+
+      {{ COMMAND }} -resource "projects/foo"
+
+The expired AOD IAM bindings of the roles are removed by the "iam cleanup" and
+"iam sweep" commands.
+`
+}
+
+func (c *RoleCleanupCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "resource",
+		Target:  &c.flagResources,
+		Example: "projects/foo",
+		Usage: `The organization or project to delete the expired custom ` +
+			`roles of, can be repeated.`,
+	})
+
+	c.iamHandlerFlags.register(f)
+
+	return set
+}
+
+func (c *RoleCleanupCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if len(c.flagResources) == 0 {
+		return fmt.Errorf("at least one resource is required")
+	}
+
+	if err := c.iamHandlerFlags.validate(); err != nil {
+		return err
+	}
+
+	for _, r := range c.flagResources {
+		rn, err := resource.Parse(r)
+		if err != nil {
+			return withExitCode(ExitCodeValidation, fmt.Errorf("invalid resource: %w", err))
+		}
+		if rn.Type == resource.TypeFolder {
+			return withExitCode(ExitCodeValidation, fmt.Errorf("resource %q is a folder, custom roles can only be in organizations and projects", r))
+		}
+		if !resource.IsType(string(rn.Type)) {
+			return withExitCode(ExitCodeValidation, fmt.Errorf("resource %q is not an organization or a project, custom roles can only be in organizations and projects", r))
+		}
+	}
+
+	h := c.testHandler
+	if h == nil {
+		roleHandler, done, err := newRoleHandler(ctx, &c.iamHandlerFlags, &c.BaseCommand)
+		if err != nil {
+			return err
+		}
+		defer done()
+		h = roleHandler
+	}
+
+	deleted, err := h.CleanupCustomRoles(ctx, c.flagResources)
+	if err != nil {
+		return withExitCode(apiExitCode(err, len(c.flagResources)),
+			fmt.Errorf("failed to clean up custom roles: %w", err))
+	}
+
+	printMessageHeader(ctx, &c.BaseCommand, messages.HeaderRolesCleanedUp)
+	if err := encodeYaml(c.Stdout(), map[string]any{"deleted": deleted}); err != nil {
+		return fmt.Errorf("failed to output deleted custom roles: %w", err)
+	}
+	return nil
+}
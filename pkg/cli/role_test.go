@@ -0,0 +1,309 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/customrole"
+	"github.com/abcxyz/access-on-demand/pkg/handler"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+const validRoleFile = `
+resource: projects/foo
+permissions:
+  - storage.objects.get
+  - storage.objects.list
+members:
+  - user:alice@example.com
+`
+
+func TestRoleValidateCommand(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		file   string
+		args   []string
+		expOut string
+		expErr string
+	}{
+		{
+			name:   "success",
+			file:   validRoleFile,
+			args:   []string{"-path", "{{path}}"},
+			expOut: "Successfully validated custom role request",
+		},
+		{
+			name:   "folder_resource",
+			file:   "resource: folders/bar\npermissions: [storage.objects.get]\nmembers: [user:alice@example.com]\n",
+			args:   []string{"-path", "{{path}}"},
+			expErr: `resource "folders/bar" is a folder`,
+		},
+		{
+			name:   "invalid_permission",
+			file:   "resource: projects/foo\npermissions: [storage]\nmembers: [user:alice@example.com]\n",
+			args:   []string{"-path", "{{path}}"},
+			expErr: `permission "storage" is not valid`,
+		},
+		{
+			name:   "invalid_yaml",
+			file:   `bananas`,
+			args:   []string{"-path", "{{path}}"},
+			expErr: "failed to read *v1alpha1.CustomRoleRequest",
+		},
+		{
+			name:   "missing_path",
+			expErr: "path is required",
+		},
+		{
+			name:   "unexpected_args",
+			args:   []string{"foo"},
+			expErr: `unexpected arguments: ["foo"]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			args := writeRoleFile(t, tc.file, tc.args)
+
+			var cmd RoleValidateCommand
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestRoleHandleCommand(t *testing.T) {
+	t.Parallel()
+
+	startTime := time.Now().UTC().Truncate(time.Second)
+	expiry := startTime.Add(2 * time.Hour).Format(time.RFC3339)
+
+	cases := []struct {
+		name     string
+		file     string
+		args     []string
+		handler  *fakeRoleHandler
+		expCalls int
+		expOut   string
+		expErr   string
+	}{
+		{
+			name:     "success",
+			file:     validRoleFile,
+			args:     []string{"-path", "{{path}}", "-duration", "2h", "-start-time", startTime.Format(time.RFC3339)},
+			handler:  &fakeRoleHandler{},
+			expCalls: 1,
+			expOut: fmt.Sprintf(`
+------Successfully Handled Custom Role Request------
+duration: 2h0m0s
+expiry: "%s"
+members:
+  - user:alice@example.com
+role:
+  name: projects/foo/roles/aod_1_abcd
+  permissions:
+    - storage.objects.get
+    - storage.objects.list`, expiry),
+		},
+		{
+			name:     "handler_failure",
+			file:     validRoleFile,
+			args:     []string{"-path", "{{path}}", "-duration", "2h"},
+			handler:  &fakeRoleHandler{injectErr: fmt.Errorf("injected error")},
+			expCalls: 1,
+			expErr:   "failed to handle *v1alpha1.CustomRoleRequest: injected error",
+		},
+		{
+			name:    "invalid_request",
+			file:    "resource: projects/foo\npermissions: [storage]\nmembers: [user:alice@example.com]\n",
+			args:    []string{"-path", "{{path}}", "-duration", "2h"},
+			handler: &fakeRoleHandler{},
+			expErr:  `permission "storage" is not valid`,
+		},
+		{
+			name:    "missing_duration",
+			file:    validRoleFile,
+			args:    []string{"-path", "{{path}}"},
+			handler: &fakeRoleHandler{},
+			expErr:  "a positive duration is required",
+		},
+		{
+			name:    "expired",
+			file:    validRoleFile,
+			args:    []string{"-path", "{{path}}", "-duration", "2h", "-start-time", "2009-11-10T23:00:00Z"},
+			handler: &fakeRoleHandler{},
+			expErr:  "already passed",
+		},
+		{
+			name:    "missing_path",
+			handler: &fakeRoleHandler{},
+			expErr:  "path is required",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			args := writeRoleFile(t, tc.file, tc.args)
+
+			var cmd RoleHandleCommand
+			cmd.testHandler = tc.handler
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if got := tc.handler.doCalls; got != tc.expCalls {
+				t.Errorf("Process(%+v) got %d handler calls, want %d", tc.name, got, tc.expCalls)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestRoleCleanupCommand(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name         string
+		args         []string
+		handler      *fakeRoleHandler
+		expResources []string
+		expOut       string
+		expErr       string
+	}{
+		{
+			name:         "success",
+			args:         []string{"-resource", "projects/foo", "-resource", "organizations/123"},
+			handler:      &fakeRoleHandler{deleted: []string{"projects/foo/roles/aod_1_abcd"}},
+			expResources: []string{"projects/foo", "organizations/123"},
+			expOut: `
+------Successfully Deleted Expired Custom Roles------
+deleted:
+  - projects/foo/roles/aod_1_abcd`,
+		},
+		{
+			name:         "handler_failure",
+			args:         []string{"-resource", "projects/foo"},
+			handler:      &fakeRoleHandler{injectErr: fmt.Errorf("injected error")},
+			expResources: []string{"projects/foo"},
+			expErr:       "failed to clean up custom roles: injected error",
+		},
+		{
+			name:    "folder_resource",
+			args:    []string{"-resource", "folders/bar"},
+			handler: &fakeRoleHandler{},
+			expErr:  `resource "folders/bar" is a folder`,
+		},
+		{
+			name:    "missing_resource",
+			handler: &fakeRoleHandler{},
+			expErr:  "at least one resource is required",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var cmd RoleCleanupCommand
+			cmd.testHandler = tc.handler
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expResources, tc.handler.gotResources); diff != "" {
+				t.Errorf("Process(%+v) got resources diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+// writeRoleFile writes the file to a temporary path if it is not empty, and
+// returns the args with "{{path}}" replaced with the path.
+func writeRoleFile(tb testing.TB, file string, args []string) []string {
+	tb.Helper()
+
+	path := filepath.Join(tb.TempDir(), "role.yaml")
+	if file != "" {
+		if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	result := make([]string, 0, len(args))
+	for _, a := range args {
+		result = append(result, strings.ReplaceAll(a, "{{path}}", path))
+	}
+	return result
+}
+
+type fakeRoleHandler struct {
+	injectErr    error
+	deleted      []string
+	doCalls      int
+	gotResources []string
+}
+
+func (h *fakeRoleHandler) DoCustomRole(ctx context.Context, r *v1alpha1.CustomRoleRequest, w *v1alpha1.IAMRequestWrapper) (*handler.CustomRoleResponse, error) {
+	h.doCalls++
+	if h.injectErr != nil {
+		return nil, h.injectErr
+	}
+	return &handler.CustomRoleResponse{
+		Role: &customrole.Role{Name: r.Resource + "/roles/aod_1_abcd", Permissions: r.Permissions},
+	}, nil
+}
+
+func (h *fakeRoleHandler) CleanupCustomRoles(ctx context.Context, resources []string) ([]string, error) {
+	h.gotResources = resources
+	return h.deleted, h.injectErr
+}
@@ -17,9 +17,13 @@ package cli
 
 import (
 	"context"
+	"net/http"
+	"os"
+	"time"
 
 	"github.com/abcxyz/access-on-demand/internal/version"
 	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
 )
 
 // RootCmd defines the starting command structure.
@@ -39,12 +43,161 @@ var RootCmd = func() cli.Command {
 						"cleanup": func() cli.Command {
 							return &IAMCleanupCommand{}
 						},
+						"audit-diff": func() cli.Command {
+							return &IAMAuditDiffCommand{}
+						},
 						"validate": func() cli.Command {
 							return &IAMValidateCommand{}
 						},
+						"diff": func() cli.Command {
+							return &IAMDiffCommand{}
+						},
+						"diff-against-live": func() cli.Command {
+							return &IAMDiffLiveCommand{}
+						},
+						"history": func() cli.Command {
+							return &IAMHistoryCommand{}
+						},
+						"list": func() cli.Command {
+							return &IAMListCommand{}
+						},
+						"reconcile": func() cli.Command {
+							return &IAMReconcileCommand{}
+						},
+						"renew": func() cli.Command {
+							return &IAMRenewCommand{}
+						},
+						"request": func() cli.Command {
+							return &IAMRequestCommand{}
+						},
+						"open-pr": func() cli.Command {
+							return &IAMOpenPRCommand{}
+						},
+						"revoke-user": func() cli.Command {
+							return &IAMRevokeUserCommand{}
+						},
+						"rewrite": func() cli.Command {
+							return &IAMRewriteCommand{}
+						},
+						"sweep": func() cli.Command {
+							return &IAMSweepCommand{}
+						},
+						"usage": func() cli.Command {
+							return &IAMUsageCommand{}
+						},
+					},
+				}
+			},
+			"request": func() cli.Command {
+				return &cli.RootCommand{
+					Name:        "request",
+					Description: "Perform operations on combined IAM and tool requests",
+					Commands: map[string]cli.CommandFactory{
+						"handle": func() cli.Command {
+							return &RequestHandleCommand{}
+						},
+						"cleanup": func() cli.Command {
+							return &RequestCleanupCommand{}
+						},
+						"validate": func() cli.Command {
+							return &RequestValidateCommand{}
+						},
 					},
 				}
 			},
+			"plugin": func() cli.Command {
+				return &cli.RootCommand{
+					Name:        "plugin",
+					Description: "Perform operations on requests of custom kinds handled by plugins",
+					Commands: map[string]cli.CommandFactory{
+						"handle": func() cli.Command {
+							return &PluginHandleCommand{}
+						},
+						"cleanup": func() cli.Command {
+							return &PluginCleanupCommand{}
+						},
+						"validate": func() cli.Command {
+							return &PluginValidateCommand{}
+						},
+					},
+				}
+			},
+			"role": func() cli.Command {
+				return &cli.RootCommand{
+					Name:        "role",
+					Description: "Perform operations on temporary custom roles",
+					Commands: map[string]cli.CommandFactory{
+						"handle": func() cli.Command {
+							return &RoleHandleCommand{}
+						},
+						"cleanup": func() cli.Command {
+							return &RoleCleanupCommand{}
+						},
+						"validate": func() cli.Command {
+							return &RoleValidateCommand{}
+						},
+					},
+				}
+			},
+			"admin": func() cli.Command {
+				return &cli.RootCommand{
+					Name:        "admin",
+					Description: "Perform administrative operations on existing AOD bindings",
+					Commands: map[string]cli.CommandFactory{
+						"retitle": func() cli.Command {
+							return &AdminRetitleCommand{}
+						},
+					},
+				}
+			},
+			"examples": func() cli.Command {
+				return &ExamplesCommand{}
+			},
+			"group": func() cli.Command {
+				return &cli.RootCommand{
+					Name:        "group",
+					Description: "Perform operations on temporary Google Group memberships",
+					Commands: map[string]cli.CommandFactory{
+						"handle": func() cli.Command {
+							return &GroupHandleCommand{}
+						},
+						"cleanup": func() cli.Command {
+							return &GroupCleanupCommand{}
+						},
+						"validate": func() cli.Command {
+							return &GroupValidateCommand{}
+						},
+					},
+				}
+			},
+			"policy": func() cli.Command {
+				return &cli.RootCommand{
+					Name:        "policy",
+					Description: "Perform operations on AOD policy files",
+					Commands: map[string]cli.CommandFactory{
+						"test": func() cli.Command {
+							return &PolicyTestCommand{}
+						},
+					},
+				}
+			},
+			"op": func() cli.Command {
+				return &cli.RootCommand{
+					Name:        "op",
+					Description: "Perform operations on detached operations of the AOD server",
+					Commands: map[string]cli.CommandFactory{
+						"status": func() cli.Command {
+							return &OpStatusCommand{}
+						},
+						"wait": func() cli.Command {
+							return &OpWaitCommand{}
+						},
+					},
+				}
+			},
+			"server": func() cli.Command {
+				return &ServerCommand{}
+			},
 			"tool": func() cli.Command {
 				return &cli.RootCommand{
 					Name:        "tool",
@@ -56,6 +209,9 @@ var RootCmd = func() cli.Command {
 						"validate": func() cli.Command {
 							return &ToolValidateCommand{}
 						},
+						"explain": func() cli.Command {
+							return &ToolExplainCommand{}
+						},
 					},
 				}
 			},
@@ -65,5 +221,20 @@ var RootCmd = func() cli.Command {
 
 // Run executes the CLI.
 func Run(ctx context.Context, args []string) error {
-	return RootCmd().Run(ctx, args) //nolint:wrapcheck // Want passthrough
+	timeout, args, err := parseGlobalFlags(ctx, args, os.LookupEnv)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err = runWithTimeout(ctx, timeout, func(ctx context.Context) error {
+		return RootCmd().Run(ctx, args) //nolint:wrapcheck // Want passthrough
+	})
+	if reportErr := reportAnalytics(ctx, http.DefaultClient, os.LookupEnv, args, time.Since(start), err); reportErr != nil {
+		logging.FromContext(ctx).DebugContext(ctx, "failed to report usage analytics", "error", reportErr)
+	}
+	if err != nil {
+		return withErrorHelp(ctx, err, os.Getenv)
+	}
+	return nil
 }
@@ -25,8 +25,17 @@ func TestRootCommand_Help(t *testing.T) {
 	exp := `
 Usage: aod COMMAND
 
-  iam     Perform operations to modify IAM policies on demand
-  tool    Perform operations to run CLI tools on demand
+  admin       Perform administrative operations on existing AOD bindings
+  examples    List, print or write the bundled example request files
+  group       Perform operations on temporary Google Group memberships
+  iam         Perform operations to modify IAM policies on demand
+  op          Perform operations on detached operations of the AOD server
+  plugin      Perform operations on requests of custom kinds handled by plugins
+  policy      Perform operations on AOD policy files
+  request     Perform operations on combined IAM and tool requests
+  role        Perform operations on temporary custom roles
+  server      Serve IAM requests over HTTP and gRPC
+  tool        Perform operations to run CLI tools on demand
 `
 
 	cmd := RootCmd()
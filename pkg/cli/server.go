@@ -0,0 +1,271 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/registry"
+	"github.com/abcxyz/access-on-demand/pkg/server"
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/serving"
+)
+
+var _ cli.Command = (*ServerCommand)(nil)
+
+// ServerCommand serves IAM requests over HTTP, and optionally over gRPC.
+type ServerCommand struct {
+	cli.BaseCommand
+
+	flagPort string
+
+	flagGRPCPort string
+
+	flagRevokeWebhookToken string
+
+	iamHandlerFlags iamHandlerFlags
+
+	memberCheckFlags memberCheckFlags
+
+	policyFlags policyFlags
+
+	identityFlags identityFlags
+
+	admissionFlags admissionFlags
+
+	// testHandler is used for testing only.
+	testHandler server.IAMHandler
+}
+
+func (c *ServerCommand) Desc() string {
+	return `Serve IAM requests over HTTP and gRPC`
+}
+
+func (c *ServerCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Serve IAM requests over HTTP on port 8080, or the port in the PORT environment
+variable, e.g. as a Cloud Run service:
+
+      {{ COMMAND }}
+
+Serve IAM requests over HTTP on the given port:
+
+      {{ COMMAND }} -port 9090
+
+The endpoints accept the same IAM request payloads as the CLI, in YAML or JSON
+format:
+
+      POST /v1/iam:handle?duration=2h
+      POST /v1/iam:cleanup
+      POST /v1/iam:validate
+
+Also serve the aod.v1alpha1.AccessOnDemand gRPC service on the given port:
+
+      {{ COMMAND }} -grpc-port 9091
+
+Also serve the revoke webhook, which revokes the grants of a member or a grant
+ID before they expire, to callers with the bearer token, looking up the grants
+in the grant registry:
+
+      AOD_REVOKE_WEBHOOK_TOKEN="s3cr3t" {{ COMMAND }} -registry-project "my-project"
+
+      POST /v1/webhooks/revoke
+`
+}
+
+func (c *ServerCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "port",
+		Target:  &c.flagPort,
+		EnvVar:  "PORT",
+		Default: "8080",
+		Example: "8080",
+		Usage:   `The port to serve on.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "grpc-port",
+		Target:  &c.flagGRPCPort,
+		EnvVar:  "GRPC_PORT",
+		Example: "9091",
+		Usage: `The port to serve the gRPC service on, the gRPC service is ` +
+			`not served if unset.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "revoke-webhook-token",
+		Target:  &c.flagRevokeWebhookToken,
+		EnvVar:  "AOD_REVOKE_WEBHOOK_TOKEN",
+		Example: "s3cr3t",
+		Usage: `The bearer token callers of the revoke webhook must ` +
+			`authenticate with, the webhook is not served if unset. With ` +
+			`registry-project, the grants to revoke are looked up in the ` +
+			`grant registry.`,
+	})
+
+	c.iamHandlerFlags.register(f)
+
+	c.memberCheckFlags.register(f)
+	c.policyFlags.register(f)
+	c.identityFlags.register(f)
+	c.admissionFlags.register(f)
+
+	return set
+}
+
+func (c *ServerCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagPort == "" {
+		return fmt.Errorf("port is required")
+	}
+
+	if err := c.iamHandlerFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.policyFlags.validate(); err != nil {
+		return err
+	}
+
+	if err := c.identityFlags.validate(); err != nil {
+		return err
+	}
+
+	// The budget is of the lifetime of the handler, which is shared by all
+	// requests of the server.
+	if c.iamHandlerFlags.flagAPICallBudget > 0 {
+		return fmt.Errorf("api-call-budget is not supported by the server")
+	}
+
+	return c.serve(ctx)
+}
+
+func (c *ServerCommand) serve(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	var h server.IAMHandler
+	if c.testHandler != nil {
+		// Use testHandler if it is for testing.
+		h = c.testHandler
+	} else {
+		iamHandler, closer, newHandlerErr := newIAMHandler(ctx, &c.iamHandlerFlags, &c.BaseCommand)
+		if newHandlerErr != nil {
+			return newHandlerErr
+		}
+		h = iamHandler
+		defer func() {
+			if err := closer.Close(); err != nil {
+				logger.ErrorContext(ctx, "failed to close", "error", err)
+			}
+		}()
+	}
+
+	opts := []server.Option{
+		server.WithNowFunc(c.iamHandlerFlags.now),
+		server.WithIdentityMap(c.identityFlags.identities),
+		server.WithValidateFunc(func(ctx context.Context, w *v1alpha1.IAMRequestWrapper) error {
+			return c.memberCheckFlags.check(ctx, w.IAMRequest, c.iamHandlerFlags.flagAuditLogProject)
+		}),
+		server.WithValidateFunc(func(ctx context.Context, w *v1alpha1.IAMRequestWrapper) error {
+			return c.admissionFlags.check(ctx, w, c.GetEnv, c.iamHandlerFlags.flagAuditLogProject)
+		}),
+	}
+	if c.flagRevokeWebhookToken != "" {
+		opts = append(opts, server.WithRevokeWebhook(c.flagRevokeWebhookToken))
+		if project := c.iamHandlerFlags.flagRegistryProject; project != "" {
+			store, err := registry.NewFirestoreStore(ctx, project, c.iamHandlerFlags.flagRegistryDatabase)
+			if err != nil {
+				return fmt.Errorf("failed to create registry store: %w", err)
+			}
+			defer func() {
+				if err := store.Close(); err != nil {
+					logger.ErrorContext(ctx, "failed to close registry store", "error", err)
+				}
+			}()
+			opts = append(opts, server.WithGrantRegistry(store))
+		}
+	}
+	if c.policyFlags.policy != nil {
+		// The rules are evaluated with the duration of each request, which is
+		// zero for requests that are cleaned up or only validated.
+		opts = append(opts, server.WithValidateFunc(func(_ context.Context, w *v1alpha1.IAMRequestWrapper) error {
+			return v1alpha1.ValidateIAMRequest(w.IAMRequest, c.policyFlags.options(w.Duration)) //nolint:wrapcheck // Want passthrough
+		}))
+	}
+	s, err := server.New(h, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create server: %w", err)
+	}
+	// Wait for the detached operations in progress before closing the handler.
+	defer s.Wait()
+
+	srv, err := serving.New(c.flagPort)
+	if err != nil {
+		return fmt.Errorf("failed to create serving infrastructure: %w", err)
+	}
+	if c.flagGRPCPort == "" {
+		if err := srv.StartHTTPHandler(ctx, s.Routes(ctx)); err != nil {
+			return fmt.Errorf("failed to serve: %w", err)
+		}
+		return nil
+	}
+
+	grpcSrv, err := serving.New(c.flagGRPCPort)
+	if err != nil {
+		return fmt.Errorf("failed to create grpc serving infrastructure: %w", err)
+	}
+	gs := grpc.NewServer()
+	s.RegisterGRPC(gs)
+
+	// Stop both servers if either of them fails.
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.Go(func() error {
+		if err := srv.StartHTTPHandler(egCtx, s.Routes(egCtx)); err != nil {
+			return fmt.Errorf("failed to serve: %w", err)
+		}
+		return nil
+	})
+	eg.Go(func() error {
+		if err := grpcSrv.StartGRPC(egCtx, gs); err != nil {
+			return fmt.Errorf("failed to serve grpc: %w", err)
+		}
+		return nil
+	})
+	if err := eg.Wait(); err != nil {
+		return err //nolint:wrapcheck // Want passthrough
+	}
+	return nil
+}
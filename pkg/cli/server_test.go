@@ -0,0 +1,115 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"testing"
+
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestServerCommand(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		handler *fakeServerHandler
+		expErr  string
+	}{
+		{
+			name:    "success",
+			args:    []string{"-port", "0"},
+			handler: &fakeServerHandler{},
+		},
+		{
+			name:    "port_from_env",
+			env:     map[string]string{"PORT": "0"},
+			handler: &fakeServerHandler{},
+		},
+		{
+			name:    "success_grpc",
+			args:    []string{"-port", "0", "-grpc-port", "0"},
+			handler: &fakeServerHandler{},
+		},
+		{
+			name:    "revoke_webhook",
+			args:    []string{"-port", "0"},
+			env:     map[string]string{"AOD_REVOKE_WEBHOOK_TOKEN": "s3cr3t"},
+			handler: &fakeServerHandler{},
+		},
+		{
+			name:    "invalid_grpc_port",
+			args:    []string{"-port", "0", "-grpc-port", "bananas"},
+			handler: &fakeServerHandler{},
+			expErr:  "failed to create grpc serving infrastructure",
+		},
+		{
+			name:    "invalid_port",
+			args:    []string{"-port", "bananas"},
+			handler: &fakeServerHandler{},
+			expErr:  "failed to create serving infrastructure",
+		},
+		{
+			name:    "empty_port",
+			args:    []string{"-port", ""},
+			handler: &fakeServerHandler{},
+			expErr:  "port is required",
+		},
+		{
+			name:    "api_call_budget",
+			args:    []string{"-port", "0", "-api-call-budget", "100"},
+			handler: &fakeServerHandler{},
+			expErr:  "api-call-budget is not supported by the server",
+		},
+		{
+			name:    "unexpected_args",
+			args:    []string{"foo"},
+			handler: &fakeServerHandler{},
+			expErr:  `unexpected arguments: ["foo"]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+			// Cancel the context so that the server stops right after it starts.
+			ctx, cancel := context.WithCancel(ctx)
+			cancel()
+
+			var cmd ServerCommand
+			cmd.testHandler = tc.handler
+			cmd.SetLookupEnv(cli.MapLookuper(tc.env))
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+type fakeServerHandler struct {
+	fakeIAMHandler
+	fakeIAMCleanupHandler
+	fakeIAMListHandler
+	fakeIAMRevokeMemberHandler
+}
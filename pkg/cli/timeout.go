@@ -0,0 +1,88 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abcxyz/pkg/cli"
+)
+
+// timeoutFlag is the name of the global flag of the deadline of a command,
+// which must precede the command, e.g. "aod -timeout 10m iam handle".
+const timeoutFlag = "timeout"
+
+// errTimeout is the cause of the context of a command which timed out.
+var errTimeout = errors.New("command timed out")
+
+// parseGlobalFlags parses the global flags preceding the command in args, and
+// returns the timeout and the rest of args. The timeout defaults to the CLI
+// config if the flag is not set, and zero means no timeout.
+func parseGlobalFlags(ctx context.Context, args []string, lookupEnv cli.LookupEnvFunc) (time.Duration, []string, error) {
+	var value string
+	var explicit bool
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		name, v, hasValue := strings.Cut(strings.TrimLeft(args[0], "-"), "=")
+		if name != timeoutFlag {
+			break
+		}
+		if !hasValue {
+			if len(args) < 2 {
+				return 0, nil, fmt.Errorf("flag needs an argument: -%s", timeoutFlag)
+			}
+			v, args = args[1], args[1:]
+		}
+		value, explicit, args = v, true, args[1:]
+	}
+
+	if !explicit {
+		c, err := loadCLIConfig(ctx, lookupEnv)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to load CLI config: %w", err)
+		}
+		return c.Timeout, args, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid value %q for flag -%s: %w", value, timeoutFlag, err)
+	}
+	if d < 0 {
+		return 0, nil, fmt.Errorf("%s must not be negative, got %s", timeoutFlag, d)
+	}
+	return d, args, nil
+}
+
+// runWithTimeout runs the function with a context that is canceled after the
+// timeout, unless the timeout is zero. The error of a run that timed out has
+// [ExitCodeTimeout], regardless of how the function handled the cancellation.
+func runWithTimeout(ctx context.Context, timeout time.Duration, run func(context.Context) error) error {
+	if timeout == 0 {
+		return run(ctx)
+	}
+
+	ctx, cancel := context.WithTimeoutCause(ctx, timeout, fmt.Errorf("%w after %s", errTimeout, timeout))
+	defer cancel()
+
+	err := run(ctx)
+	if cause := context.Cause(ctx); err != nil && errors.Is(cause, errTimeout) {
+		return withExitCode(ExitCodeTimeout, fmt.Errorf("%w: %w", cause, err))
+	}
+	return err
+}
@@ -0,0 +1,170 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestParseGlobalFlags(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name        string
+		args        []string
+		env         map[string]string
+		wantTimeout time.Duration
+		wantArgs    []string
+		wantErr     string
+	}{
+		{
+			name:     "no_flags",
+			args:     []string{"iam", "handle", "-timeout", "1m"},
+			wantArgs: []string{"iam", "handle", "-timeout", "1m"},
+		},
+		{
+			name:        "timeout",
+			args:        []string{"-timeout", "10m", "iam", "handle"},
+			wantTimeout: 10 * time.Minute,
+			wantArgs:    []string{"iam", "handle"},
+		},
+		{
+			name:        "timeout_with_equals",
+			args:        []string{"--timeout=30s", "iam", "handle"},
+			wantTimeout: 30 * time.Second,
+			wantArgs:    []string{"iam", "handle"},
+		},
+		{
+			name:        "timeout_from_env",
+			args:        []string{"iam", "handle"},
+			env:         map[string]string{"AOD_TIMEOUT": "5m"},
+			wantTimeout: 5 * time.Minute,
+			wantArgs:    []string{"iam", "handle"},
+		},
+		{
+			name:        "flag_overrides_env",
+			args:        []string{"-timeout", "0", "iam", "handle"},
+			env:         map[string]string{"AOD_TIMEOUT": "5m"},
+			wantTimeout: 0,
+			wantArgs:    []string{"iam", "handle"},
+		},
+		{
+			name:     "other_flag",
+			args:     []string{"-version"},
+			wantArgs: []string{"-version"},
+		},
+		{
+			name:    "missing_value",
+			args:    []string{"-timeout"},
+			wantErr: "flag needs an argument: -timeout",
+		},
+		{
+			name:    "invalid_value",
+			args:    []string{"-timeout", "bananas", "iam"},
+			wantErr: `invalid value "bananas" for flag -timeout`,
+		},
+		{
+			name:    "negative_value",
+			args:    []string{"-timeout", "-1m", "iam"},
+			wantErr: "timeout must not be negative, got -1m0s",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			env := map[string]string{configFileEnv: ""}
+			for k, v := range tc.env {
+				env[k] = v
+			}
+			gotTimeout, gotArgs, err := parseGlobalFlags(context.Background(), tc.args, cli.MapLookuper(env))
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if gotTimeout != tc.wantTimeout {
+				t.Errorf("Process(%+v) got timeout %s, want %s", tc.name, gotTimeout, tc.wantTimeout)
+			}
+			if diff := cmp.Diff(tc.wantArgs, gotArgs); diff != "" {
+				t.Errorf("Process(%+v) got args diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestRunWithTimeout(t *testing.T) {
+	t.Parallel()
+
+	blocking := func(ctx context.Context) error {
+		<-ctx.Done()
+		return fmt.Errorf("failed to wait: %w", ctx.Err())
+	}
+
+	cases := []struct {
+		name     string
+		timeout  time.Duration
+		run      func(context.Context) error
+		wantCode int
+		wantErr  string
+	}{
+		{
+			name:    "success",
+			timeout: time.Minute,
+			run:     func(context.Context) error { return nil },
+		},
+		{
+			name:     "timed_out",
+			timeout:  10 * time.Millisecond,
+			run:      blocking,
+			wantCode: ExitCodeTimeout,
+			wantErr:  "command timed out after 10ms: failed to wait: context deadline exceeded",
+		},
+		{
+			name:    "no_timeout",
+			timeout: 0,
+			run: func(ctx context.Context) error {
+				if _, ok := ctx.Deadline(); ok {
+					return fmt.Errorf("unexpected deadline")
+				}
+				return withExitCode(ExitCodeValidation, errors.New("injected error"))
+			},
+			wantCode: ExitCodeValidation,
+			wantErr:  "injected error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := runWithTimeout(context.Background(), tc.timeout, tc.run)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if got := ExitCode(err); got != tc.wantCode {
+				t.Errorf("Process(%+v) got exit code %d, want %d", tc.name, got, tc.wantCode)
+			}
+		})
+	}
+}
@@ -22,8 +22,9 @@ import (
 
 	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
 	"github.com/abcxyz/access-on-demand/pkg/handler"
-	"github.com/abcxyz/access-on-demand/pkg/requestutil"
+	"github.com/abcxyz/access-on-demand/pkg/messages"
 	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
 )
 
 var _ cli.Command = (*ToolDoCommand)(nil)
@@ -41,6 +42,12 @@ type ToolDoCommand struct {
 
 	flagVerbose bool
 
+	// Optional stage to inject a failure at, for testing downstream workflows
+	// only.
+	flagInjectFailure string
+
+	telemetryFlags telemetryFlags
+
 	// testHandler is used for testing only.
 	testHandler toolHandler
 }
@@ -69,7 +76,7 @@ Execute commands in tool request YAML file and output commands executed:
 }
 
 func (c *ToolDoCommand) Flags() *cli.FlagSet {
-	set := c.NewFlagSet()
+	set := newFlagSet(&c.BaseCommand)
 
 	// Command options
 	f := set.NewSection("COMMAND OPTIONS")
@@ -79,7 +86,7 @@ func (c *ToolDoCommand) Flags() *cli.FlagSet {
 		Target:  &c.flagPath,
 		Example: "/path/to/file.yaml",
 		Predict: predict.Files("*"),
-		Usage:   `The path of tool request file, in YAML format.`,
+		Usage:   `The path of tool request file, in YAML format. Use "-" to read it from stdin.`,
 	})
 
 	f.BoolVar(&cli.BoolVar{
@@ -89,6 +96,17 @@ func (c *ToolDoCommand) Flags() *cli.FlagSet {
 		Usage:   `Turn on verbose mode to print commands output. Note that outputs may contain sensitive information`,
 	})
 
+	f.StringVar(&cli.StringVar{
+		Name:    "inject-failure",
+		Target:  &c.flagInjectFailure,
+		Hidden:  true,
+		Example: "tool-exec",
+		Usage: fmt.Sprintf(`The stage to inject a failure at, for testing `+
+			`workflows only, one of %q.`, toolFailureStages),
+	})
+
+	c.telemetryFlags.register(f)
+
 	return set
 }
 
@@ -106,26 +124,56 @@ func (c *ToolDoCommand) Run(ctx context.Context, args []string) error {
 		return fmt.Errorf("path is required")
 	}
 
+	if err := checkFailureStage(c.flagInjectFailure, toolFailureStages); err != nil {
+		return err
+	}
+
+	if err := c.telemetryFlags.validate(); err != nil {
+		return err
+	}
+
 	// Read request from file path.
 	var req v1alpha1.ToolRequest
-	if err := requestutil.ReadRequestFromPath(c.flagPath, &req); err != nil {
-		return fmt.Errorf("failed to read %T: %w", &req, err)
+	loc, err := newRequestReader(c.Stdin()).requestWithLocator(c.flagPath, &req)
+	if err != nil {
+		return withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &req, err))
+	}
+	if err := injectFailure(c.flagInjectFailure, failureStageParse); err != nil {
+		return withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &req, err))
 	}
 
-	if err := v1alpha1.ValidateToolRequest(&req); err != nil {
-		return fmt.Errorf("failed to validate %T: %w", &req, err)
+	if err := v1alpha1.ValidateToolRequest(&req, nil); err != nil {
+		err = loc.Annotate(err)
+		return withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", &req, err))
 	}
+	printToolWarnings(c.Stderr(), &req, c.GetEnv("GITHUB_ACTIONS") == "true")
 
 	var h toolHandler
-	// Use testhandler if it is for testing.
-	if c.testHandler != nil {
+	if c.flagInjectFailure == failureStageToolExec {
+		// Fail the execution if it is injected.
+		h = &failingToolHandler{}
+	} else if c.testHandler != nil {
+		// Use testhandler if it is for testing.
 		h = c.testHandler
 	} else {
 		opts := []handler.ToolHandlerOption{handler.WithStderr(c.Stderr())}
 		if c.flagVerbose {
-			printHeader(c.Stdout(), "Tool Commands Output")
+			printMessageHeader(ctx, &c.BaseCommand, messages.HeaderToolOutput)
 			opts = append(opts, handler.WithStdout(c.Stdout()))
 		}
+		tp, err := c.telemetryFlags.providers(ctx, c.Stderr())
+		if err != nil {
+			return err
+		}
+		if tp != nil {
+			shutdown := shutdownFunc(tp)
+			defer func() {
+				if err := shutdown(); err != nil {
+					logging.FromContext(ctx).ErrorContext(ctx, "failed to shut down telemetry", "error", err)
+				}
+			}()
+			opts = append(opts, handler.WithToolTelemetry(tp.TracerProvider, tp.MeterProvider))
+		}
 		h = handler.NewToolHandler(ctx, opts...)
 	}
 
@@ -133,15 +181,15 @@ func (c *ToolDoCommand) Run(ctx context.Context, args []string) error {
 		return fmt.Errorf(`failed to run "do" commands: %w`, err)
 	}
 
-	if err := c.output(req.Do, req.Tool); err != nil {
+	if err := c.output(ctx, req.Do, req.Tool); err != nil {
 		return fmt.Errorf("failed to print outputs: %w", err)
 	}
 
 	return nil
 }
 
-func (c *ToolDoCommand) output(subcmds []string, tool string) error {
-	printHeader(c.Stdout(), "Successfully Completed Commands")
+func (c *ToolDoCommand) output(ctx context.Context, subcmds []string, tool string) error {
+	printMessageHeader(ctx, &c.BaseCommand, messages.HeaderToolCompleted)
 	cmds := make([]string, 0, len(subcmds))
 	for _, sub := range subcmds {
 		cmds = append(cmds, fmt.Sprintf("%s %s", tool, sub))
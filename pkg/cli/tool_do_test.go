@@ -112,6 +112,24 @@ do:
 			testHandler: &fakeToolHandler{},
 			expErr:      "failed to validate *v1alpha1.ToolRequest",
 		},
+		{
+			name:        "inject_tool_exec_failure",
+			args:        []string{"-path", filepath.Join(dir, "valid.yaml"), "-inject-failure", "tool-exec"},
+			testHandler: &fakeToolHandler{},
+			expErr:      `injected failure at stage "tool-exec"`,
+		},
+		{
+			name:        "invalid_inject_failure_stage",
+			args:        []string{"-path", filepath.Join(dir, "valid.yaml"), "-inject-failure", "get-policy"},
+			testHandler: &fakeToolHandler{},
+			expErr:      `invalid inject-failure stage "get-policy"`,
+		},
+		{
+			name:        "invalid_otel_exporter",
+			args:        []string{"-path", filepath.Join(dir, "valid.yaml"), "-otel-exporter", "zipkin"},
+			testHandler: &fakeToolHandler{},
+			expErr:      `invalid otel exporter "zipkin", must be one of ["otlp" "stderr"]`,
+		},
 	}
 
 	for _, tc := range cases {
@@ -0,0 +1,153 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/posener/complete/v2/predict"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/messages"
+	"github.com/abcxyz/access-on-demand/pkg/permissions"
+	"github.com/abcxyz/access-on-demand/pkg/requestutil"
+	"github.com/abcxyz/pkg/cli"
+)
+
+var _ cli.Command = (*ToolExplainCommand)(nil)
+
+// ToolExplainCommand explains whether an IAM request grants the permissions
+// needed by the commands of a tool request.
+type ToolExplainCommand struct {
+	cli.BaseCommand
+
+	flagPath string
+
+	flagIAMPath string
+}
+
+func (c *ToolExplainCommand) Desc() string {
+	return `Explain whether an IAM request grants the permissions needed by a tool request`
+}
+
+func (c *ToolExplainCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+Explain whether the IAM request YAML file grants the IAM permissions needed by
+the commands in the tool request YAML file:
+
+      {{ COMMAND }} -path "/path/to/tool.yaml" -iam-path "/path/to/iam.yaml"
+
+The permissions needed by the commands and granted by the roles are looked up in
+a bundled dataset of common commands and predefined roles. It fails if any
+permission is not granted by the roles, all of which are in the dataset.
+Permissions are reported UNKNOWN if the command or some roles are not in the
+dataset.
+`
+}
+
+func (c *ToolExplainCommand) Flags() *cli.FlagSet {
+	set := newFlagSet(&c.BaseCommand)
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "path",
+		Target:  &c.flagPath,
+		Example: "/path/to/tool.yaml",
+		Predict: predict.Files("*"),
+		Usage:   `The path of tool request file, in YAML format. Use "-" to read it from stdin.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "iam-path",
+		Target:  &c.flagIAMPath,
+		Example: "/path/to/iam.yaml",
+		Predict: predict.Files("*"),
+		Usage:   `The path of IAM request file, in YAML format. Use "-" to read it from stdin.`,
+	})
+
+	return set
+}
+
+func (c *ToolExplainCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagPath == "" {
+		return fmt.Errorf("path is required")
+	}
+
+	if c.flagIAMPath == "" {
+		return fmt.Errorf("iam-path is required")
+	}
+
+	if c.flagPath == stdinPath && c.flagIAMPath == stdinPath {
+		return fmt.Errorf("only one of path and iam-path can be read from stdin")
+	}
+
+	return c.explain(ctx)
+}
+
+func (c *ToolExplainCommand) explain(ctx context.Context) error {
+	rr := newRequestReader(c.Stdin())
+	var toolReq v1alpha1.ToolRequest
+	loc, err := rr.requestWithLocator(c.flagPath, &toolReq)
+	if err != nil {
+		return withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &toolReq, err))
+	}
+	if err := v1alpha1.ValidateToolRequest(&toolReq, nil); err != nil {
+		err = loc.Annotate(err)
+		return withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", &toolReq, err))
+	}
+	printToolWarnings(c.Stderr(), &toolReq, c.GetEnv("GITHUB_ACTIONS") == "true")
+
+	docs, err := rr.iamBundle(c.flagIAMPath)
+	if err != nil {
+		return withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &v1alpha1.IAMRequest{}, err))
+	}
+	iamReq, err := requestutil.ValidateIAMBundle(docs, nil)
+	if err != nil {
+		return withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", iamReq, err))
+	}
+
+	e, err := permissions.Explain(&toolReq, iamReq)
+	if err != nil {
+		return fmt.Errorf("failed to explain tool request: %w", err)
+	}
+
+	printMessageHeader(ctx, &c.BaseCommand, messages.HeaderToolPermissions)
+	if err := encodeYaml(c.Stdout(), e); err != nil {
+		return fmt.Errorf("failed to output explanation: %w", err)
+	}
+
+	if missing := e.Missing(); len(missing) > 0 {
+		cmds := make([]string, 0, len(missing))
+		for _, m := range missing {
+			cmds = append(cmds, m.Command)
+		}
+		return fmt.Errorf("IAM request does not grant the permissions needed by commands %q", cmds)
+	}
+	return nil
+}
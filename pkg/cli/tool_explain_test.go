@@ -0,0 +1,167 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestToolExplainCommand(t *testing.T) {
+	t.Parallel()
+
+	requestFileContentByName := map[string]string{
+		"tool.yaml": `
+tool: 'gcloud'
+do:
+  - 'storage ls gs://foo'
+  - 'storage rm gs://foo/bar'
+`,
+		"iam-granted.yaml": `
+policies:
+  - resource: projects/foo
+    bindings:
+      - members:
+          - user:foo@example.com
+        role: roles/storage.objectAdmin
+`,
+		"iam-missing.yaml": `
+policies:
+  - resource: projects/foo
+    bindings:
+      - members:
+          - user:foo@example.com
+        role: roles/storage.objectViewer
+`,
+		"invalid-tool.yaml": `
+tool: 'tool_not_exist'
+do:
+  - 'do'
+`,
+		"invalid.yaml": `bananas`,
+	}
+	dir := t.TempDir()
+	for name, content := range requestFileContentByName {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name   string
+		args   []string
+		expOut string
+		expErr string
+	}{
+		{
+			name: "granted",
+			args: []string{"-path", filepath.Join(dir, "tool.yaml"), "-iam-path", filepath.Join(dir, "iam-granted.yaml")},
+			expOut: `
+------Tool Request Permissions------
+commands:
+  - command: storage ls gs://foo
+    matched: storage ls
+    status: GRANTED
+    permissions:
+      - permission: storage.objects.list
+        status: GRANTED
+        grantedBy:
+          - roles/storage.objectAdmin
+  - command: storage rm gs://foo/bar
+    matched: storage rm
+    status: GRANTED
+    permissions:
+      - permission: storage.objects.delete
+        status: GRANTED
+        grantedBy:
+          - roles/storage.objectAdmin`,
+		},
+		{
+			name: "missing",
+			args: []string{"-path", filepath.Join(dir, "tool.yaml"), "-iam-path", filepath.Join(dir, "iam-missing.yaml")},
+			expOut: `
+------Tool Request Permissions------
+commands:
+  - command: storage ls gs://foo
+    matched: storage ls
+    status: GRANTED
+    permissions:
+      - permission: storage.objects.list
+        status: GRANTED
+        grantedBy:
+          - roles/storage.objectViewer
+  - command: storage rm gs://foo/bar
+    matched: storage rm
+    status: MISSING
+    permissions:
+      - permission: storage.objects.delete
+        status: MISSING`,
+			expErr: `IAM request does not grant the permissions needed by commands ["storage rm gs://foo/bar"]`,
+		},
+		{
+			name:   "invalid_tool_request",
+			args:   []string{"-path", filepath.Join(dir, "invalid-tool.yaml"), "-iam-path", filepath.Join(dir, "iam-granted.yaml")},
+			expErr: "failed to validate *v1alpha1.ToolRequest",
+		},
+		{
+			name:   "invalid_iam_request",
+			args:   []string{"-path", filepath.Join(dir, "tool.yaml"), "-iam-path", filepath.Join(dir, "invalid.yaml")},
+			expErr: "failed to read *v1alpha1.IAMRequest",
+		},
+		{
+			name:   "missing_path",
+			args:   []string{"-iam-path", filepath.Join(dir, "iam-granted.yaml")},
+			expErr: "path is required",
+		},
+		{
+			name:   "missing_iam_path",
+			args:   []string{"-path", filepath.Join(dir, "tool.yaml")},
+			expErr: "iam-path is required",
+		},
+		{
+			name:   "unexpected_args",
+			args:   []string{"foo"},
+			expErr: `unexpected arguments: ["foo"]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var cmd ToolExplainCommand
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
@@ -21,7 +21,6 @@ import (
 	"github.com/posener/complete/v2/predict"
 
 	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
-	"github.com/abcxyz/access-on-demand/pkg/requestutil"
 	"github.com/abcxyz/pkg/cli"
 )
 
@@ -49,7 +48,7 @@ Validate the tool request YAML file at the given path:
 }
 
 func (c *ToolValidateCommand) Flags() *cli.FlagSet {
-	set := c.NewFlagSet()
+	set := newFlagSet(&c.BaseCommand)
 
 	// Command options
 	f := set.NewSection("COMMAND OPTIONS")
@@ -59,7 +58,7 @@ func (c *ToolValidateCommand) Flags() *cli.FlagSet {
 		Target:  &c.flagPath,
 		Example: "/path/to/file.yaml",
 		Predict: predict.Files("*"),
-		Usage:   `The path of tool request file, in YAML format.`,
+		Usage:   `The path of tool request file, in YAML format. Use "-" to read it from stdin.`,
 	})
 
 	return set
@@ -85,13 +84,16 @@ func (c *ToolValidateCommand) Run(ctx context.Context, args []string) error {
 func (c *ToolValidateCommand) validate(ctx context.Context) error {
 	// Read request from file path.
 	var req v1alpha1.ToolRequest
-	if err := requestutil.ReadRequestFromPath(c.flagPath, &req); err != nil {
-		return fmt.Errorf("failed to read %T: %w", &req, err)
+	loc, err := newRequestReader(c.Stdin()).requestWithLocator(c.flagPath, &req)
+	if err != nil {
+		return withExitCode(ExitCodeInvalidRequest, fmt.Errorf("failed to read %T: %w", &req, err))
 	}
 
-	if err := v1alpha1.ValidateToolRequest(&req); err != nil {
-		return fmt.Errorf("failed to validate %T: %w", &req, err)
+	if err := v1alpha1.ValidateToolRequest(&req, nil); err != nil {
+		err = loc.Annotate(err)
+		return withExitCode(ExitCodeValidation, fmt.Errorf("failed to validate %T: %w", &req, err))
 	}
+	printToolWarnings(c.Stderr(), &req, c.GetEnv("GITHUB_ACTIONS") == "true")
 	c.Outf("Successfully validated tool request")
 
 	return nil
@@ -40,6 +40,11 @@ do:
 `,
 		"invalid-request.yaml": `
 tool: 'tool_not_exist'
+do:
+  - 'do'
+`,
+		"deprecated-cli.yaml": `
+cli: 'gcloud'
 do:
   - 'do'
 `,
@@ -55,16 +60,23 @@ do:
 	}
 
 	cases := []struct {
-		name   string
-		args   []string
-		expOut string
-		expErr string
+		name      string
+		args      []string
+		expOut    string
+		expStderr string
+		expErr    string
 	}{
 		{
 			name:   "success",
 			args:   []string{"-path", filepath.Join(dir, "valid.yaml")},
 			expOut: `Successfully validated tool request`,
 		},
+		{
+			name:      "deprecated_cli",
+			args:      []string{"-path", filepath.Join(dir, "deprecated-cli.yaml")},
+			expOut:    `Successfully validated tool request`,
+			expStderr: `WARNING: tool request: "cli" is deprecated, use "tool" instead`,
+		},
 		{
 			name:   "empty_file",
 			args:   []string{"-path", filepath.Join(dir, "empty-file.yaml")},
@@ -99,7 +111,7 @@ do:
 			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
 
 			var cmd ToolValidateCommand
-			_, stdout, _ := cmd.Pipe()
+			_, stdout, stderr := cmd.Pipe()
 
 			args := append([]string{}, tc.args...)
 
@@ -110,6 +122,9 @@ do:
 			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
 				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
 			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expStderr), strings.TrimSpace(stderr.String())); diff != "" {
+				t.Errorf("Process(%+v) got stderr diff (-want, +got):\n%s", tc.name, diff)
+			}
 		})
 	}
 }
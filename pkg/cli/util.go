@@ -16,16 +16,77 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	resourcemanager "cloud.google.com/go/resourcemanager/apiv3"
+	"github.com/posener/complete/v2/predict"
+	"github.com/sethvargo/go-retry"
+	"google.golang.org/api/idtoken"
 	"gopkg.in/yaml.v3"
 
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/internal/version"
+	"github.com/abcxyz/access-on-demand/pkg/admission"
+	"github.com/abcxyz/access-on-demand/pkg/approval"
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+	"github.com/abcxyz/access-on-demand/pkg/bigqueryiam"
+	"github.com/abcxyz/access-on-demand/pkg/directory"
+	"github.com/abcxyz/access-on-demand/pkg/entitlement"
+	"github.com/abcxyz/access-on-demand/pkg/genericiam"
 	"github.com/abcxyz/access-on-demand/pkg/handler"
+	"github.com/abcxyz/access-on-demand/pkg/hierarchy"
+	"github.com/abcxyz/access-on-demand/pkg/iamrole"
+	"github.com/abcxyz/access-on-demand/pkg/identity"
+	"github.com/abcxyz/access-on-demand/pkg/kmsiam"
+	"github.com/abcxyz/access-on-demand/pkg/messages"
+	"github.com/abcxyz/access-on-demand/pkg/progress"
+	"github.com/abcxyz/access-on-demand/pkg/registry"
+	"github.com/abcxyz/access-on-demand/pkg/resource"
+	"github.com/abcxyz/access-on-demand/pkg/secretmanageriam"
+	"github.com/abcxyz/access-on-demand/pkg/serviceaccountiam"
+	"github.com/abcxyz/access-on-demand/pkg/storageiam"
+	"github.com/abcxyz/access-on-demand/pkg/telemetry"
+	"github.com/abcxyz/access-on-demand/pkg/ticket"
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
 	"github.com/abcxyz/pkg/multicloser"
 )
 
+// Output formats supported by the commands.
+const (
+	formatText  = "text"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+	formatTable = "table"
+)
+
+// checkFormat checks if the format is one of the allowed formats.
+func checkFormat(format string, allowed ...string) error {
+	if !slices.Contains(allowed, format) {
+		return fmt.Errorf("invalid format %q, must be one of %q", format, allowed)
+	}
+	return nil
+}
+
+// encodeJSON writes indented JSON encoding of v to w.
+func encodeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode to json: %w", err)
+	}
+	return nil
+}
+
 // encodeYaml writes YAML encoding of v to w.
 func encodeYaml(w io.Writer, v any) error {
 	enc := yaml.NewEncoder(w)
@@ -46,7 +107,1240 @@ func printHeader(w io.Writer, header string) {
 	fmt.Fprintf(w, "------%s------\n", header)
 }
 
-func newIAMHandler(ctx context.Context, customConditionTitle string) (*handler.IAMHandler, *multicloser.Closer, error) {
+// messagesFileEnv is the environment variable of the YAML file overriding the
+// user-facing messages, see [messages.Load].
+const messagesFileEnv = "AOD_MESSAGES_FILE"
+
+// messageCatalog returns the catalog of the user-facing messages, with the
+// overrides in the file of AOD_MESSAGES_FILE if set. An invalid file is logged
+// and the default messages are used, so that it never fails a command.
+func messageCatalog(ctx context.Context, getenv func(string) string) *messages.Catalog {
+	path := getenv(messagesFileEnv)
+	if path == "" {
+		return messages.Default()
+	}
+	c, err := messages.Load(path)
+	if err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "failed to load messages, using the default messages",
+			"path", path,
+			"error", err)
+		return messages.Default()
+	}
+	return c
+}
+
+// withErrorHelp appends the error help message to the error, if it is set in
+// the message catalog.
+func withErrorHelp(ctx context.Context, err error, getenv func(string) string) error {
+	help := messageCatalog(ctx, getenv).Render(messages.ErrorHelp, map[string]string{"Error": err.Error()})
+	if help == "" {
+		return err
+	}
+	return fmt.Errorf("%w\n\n%s", err, help)
+}
+
+// printMessageHeader prints the message as the header to the stdout of the
+// command.
+func printMessageHeader(ctx context.Context, cmd *cli.BaseCommand, id messages.ID) {
+	printHeader(cmd.Stdout(), messageCatalog(ctx, cmd.GetEnv).Render(id, nil))
+}
+
+// printWarnings prints the warnings of the IAM responses to w. In GitHub
+// Actions they are printed as workflow commands to be shown as annotations.
+func printWarnings(w io.Writer, resps []*v1alpha1.IAMResponse, githubActions bool) {
+	for _, r := range resps {
+		for _, msg := range r.Warnings {
+			printWarning(w, fmt.Sprintf("%s: %s", r.Resource, msg), githubActions)
+		}
+	}
+}
+
+// printToolWarnings prints the warnings of the tool request to w, such as the
+// deprecated fields set in it. In GitHub Actions they are printed as workflow
+// commands to be shown as annotations.
+func printToolWarnings(w io.Writer, r *v1alpha1.ToolRequest, githubActions bool) {
+	for _, msg := range r.Warnings() {
+		printWarning(w, "tool request: "+msg, githubActions)
+	}
+}
+
+// printWarning prints the warning message to w. In GitHub Actions it is
+// printed as a workflow command to be shown as an annotation.
+func printWarning(w io.Writer, msg string, githubActions bool) {
+	if githubActions {
+		fmt.Fprintf(w, "::warning::%s\n", escapeWorkflowCommand(msg))
+	} else {
+		fmt.Fprintf(w, "WARNING: %s\n", msg)
+	}
+}
+
+// printPartiallyApplied prints a warning to w with the resources of a failed
+// request which were updated, which are the resources of the IAM responses,
+// and how to recover. In GitHub Actions it is printed as a workflow command to
+// be shown as an annotation.
+func printPartiallyApplied(w io.Writer, resps []*v1alpha1.IAMResponse, rollback, githubActions bool) {
+	if len(resps) == 0 {
+		return
+	}
+	resources := make([]string, 0, len(resps))
+	for _, r := range resps {
+		resources = append(resources, r.Resource)
+	}
+	msg := fmt.Sprintf("the IAM request was partially applied to resources %q, "+
+		"rerun the request to retry the failed resources, or run \"aod iam cleanup\" "+
+		"with the request to remove the applied bindings", resources)
+	if !rollback {
+		msg += ", use -rollback-on-failure to apply requests all-or-nothing"
+	}
+	printWarning(w, msg, githubActions)
+}
+
+// escapeWorkflowCommand escapes the message of a GitHub Actions workflow
+// command.
+func escapeWorkflowCommand(msg string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(msg)
+}
+
+// provenanceFlags are the flags of the provenance of an IAM request, which are
+// serialized in the output and audit events.
+type provenanceFlags struct {
+	flagRequester string
+
+	flagIDToken string
+
+	flagIDTokenAudience string
+
+	flagApprovers []string
+
+	flagSource string
+
+	// testTokenValidator is used for testing only.
+	testTokenValidator idTokenValidator
+}
+
+// idTokenValidator validates OIDC ID tokens.
+type idTokenValidator interface {
+	Validate(ctx context.Context, idToken, audience string) (*idtoken.Payload, error)
+}
+
+// register registers the provenance flags to the given flag section.
+func (p *provenanceFlags) register(f *cli.FlagSection) {
+	f.StringVar(&cli.StringVar{
+		Name:    "requester",
+		Target:  &p.flagRequester,
+		Example: "user:alice@example.com",
+		Usage:   "The requester of the IAM request.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "id-token",
+		Target:  &p.flagIDToken,
+		EnvVar:  "AOD_ID_TOKEN",
+		Example: "eyJhbGciOi...",
+		Usage: "The Google-signed OIDC ID token of the requester, to derive " +
+			"the requester from the verified email in the token instead of " +
+			"setting requester.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "id-token-audience",
+		Target:  &p.flagIDTokenAudience,
+		Example: "https://aod.example.com",
+		Usage:   "The expected audience of the ID token, not checked if empty.",
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "approver",
+		Target:  &p.flagApprovers,
+		Example: "user:bob@example.com",
+		Usage:   "The approver of the IAM request, can be repeated.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "source",
+		Target:  &p.flagSource,
+		Example: "https://github.com/org/repo/pull/1",
+		Usage:   "The source of the IAM request, such as a pull request URL.",
+	})
+}
+
+// validate validates the provenance flags.
+func (p *provenanceFlags) validate() error {
+	if p.flagRequester != "" && p.flagIDToken != "" {
+		return fmt.Errorf("requester and id-token cannot both be set")
+	}
+	return nil
+}
+
+// apply sets the provenance of the IAM request wrapper, the requester is
+// derived from the ID token if it is set.
+func (p *provenanceFlags) apply(ctx context.Context, w *v1alpha1.IAMRequestWrapper) error {
+	w.Requester = p.flagRequester
+	w.Approvers = p.flagApprovers
+	w.Source = p.flagSource
+	if p.flagIDToken == "" {
+		return nil
+	}
+
+	requester, err := p.tokenRequester(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get requester from id-token: %w", err)
+	}
+	w.Requester = requester
+	return nil
+}
+
+// tokenRequester returns the requester of the verified email in the ID token.
+func (p *provenanceFlags) tokenRequester(ctx context.Context) (string, error) {
+	v := p.testTokenValidator
+	if v == nil {
+		iv, err := idtoken.NewValidator(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to create ID token validator: %w", err)
+		}
+		v = iv
+	}
+
+	payload, err := v.Validate(ctx, p.flagIDToken, p.flagIDTokenAudience)
+	if err != nil {
+		return "", fmt.Errorf("failed to validate ID token: %w", err)
+	}
+	email, _ := payload.Claims["email"].(string)
+	if email == "" {
+		return "", fmt.Errorf("ID token has no email claim")
+	}
+	if verified, _ := payload.Claims["email_verified"].(bool); !verified {
+		return "", fmt.Errorf("email %q in ID token is not verified", email)
+	}
+	if strings.HasSuffix(email, ".gserviceaccount.com") {
+		return "serviceAccount:" + email, nil
+	}
+	return "user:" + email, nil
+}
+
+// memberChecker checks the members of IAM requests exist.
+type memberChecker interface {
+	CheckMembers(ctx context.Context, members []string) error
+}
+
+// memberCheckFlags are the flags to check the user members of IAM requests
+// exist in the Workspace or Cloud Identity directory.
+type memberCheckFlags struct {
+	flagCheckMemberDomains []string
+
+	// testChecker is used for testing only.
+	testChecker memberChecker
+}
+
+// register registers the member check flags to the given flag section.
+func (m *memberCheckFlags) register(f *cli.FlagSection) {
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "check-member-domain",
+		Target:  &m.flagCheckMemberDomains,
+		Example: "example.com",
+		Usage: "The Workspace or Cloud Identity domain to check the user " +
+			"members in exist and are not suspended, can be repeated. User " +
+			"members in other domains are not checked. Members are not " +
+			"checked if it is not set.",
+	})
+}
+
+// check checks the user members of the request exist in the directory if any
+// domain is set. Check failures are written as validation denied audit events
+// to the audit log project if it is set.
+func (m *memberCheckFlags) check(ctx context.Context, req *v1alpha1.IAMRequest, auditLogProject string) error {
+	if len(m.flagCheckMemberDomains) == 0 {
+		return nil
+	}
+
+	checker := m.testChecker
+	if checker == nil {
+		c, err := directory.NewChecker(ctx, m.flagCheckMemberDomains)
+		if err != nil {
+			return fmt.Errorf("failed to create directory checker: %w", err)
+		}
+		checker = c
+	}
+
+	var members []string
+	for _, p := range req.ResourcePolicies {
+		for _, b := range p.Bindings {
+			members = append(members, b.Members...)
+		}
+	}
+	if err := checker.CheckMembers(ctx, members); err != nil {
+		auditValidationDenied(ctx, auditLogProject, err)
+		return fmt.Errorf("failed to check members: %w", err)
+	}
+	return nil
+}
+
+// identityFlags are the flags to resolve the member aliases in IAM requests,
+// such as "github:octocat", with an identity map.
+type identityFlags struct {
+	flagIdentityMap string
+
+	// The identity map read from flagIdentityMap by validate.
+	identities identity.Map
+}
+
+// register registers the identity flags to the given flag section.
+func (i *identityFlags) register(f *cli.FlagSection) {
+	f.StringVar(&cli.StringVar{
+		Name:    "identity-map",
+		Target:  &i.flagIdentityMap,
+		Example: "/path/to/identities.yaml",
+		Predict: predict.Files("*"),
+		Usage: "The path of the identity map file, in YAML format, with the " +
+			"IAM members of member aliases such as \"github:octocat\". " +
+			"Member aliases are not resolved if it is not set.",
+	})
+}
+
+// validate reads the identity map file if it is set.
+func (i *identityFlags) validate() error {
+	if i.flagIdentityMap == "" {
+		return nil
+	}
+	m, err := identity.Read(i.flagIdentityMap)
+	if err != nil {
+		return fmt.Errorf("invalid identity map: %w", err)
+	}
+	i.identities = m
+	return nil
+}
+
+// entitlementFlags are the flags to expand the references to named
+// entitlements in IAM requests, such as "bq-oncall", with an entitlement
+// catalog.
+type entitlementFlags struct {
+	flagEntitlementCatalog string
+
+	// The entitlement catalog read from flagEntitlementCatalog by validate.
+	catalog entitlement.Catalog
+}
+
+// register registers the entitlement flags to the given flag section.
+func (e *entitlementFlags) register(f *cli.FlagSection) {
+	f.StringVar(&cli.StringVar{
+		Name:    "entitlement-catalog",
+		Target:  &e.flagEntitlementCatalog,
+		Example: "/path/to/entitlements.yaml",
+		Predict: predict.Files("*"),
+		Usage: "The path of the entitlement catalog file, in YAML format, " +
+			"with the roles, resources and max durations of the named " +
+			"entitlements that requests can reference. Requests with " +
+			"entitlements fail validation if it is not set.",
+	})
+}
+
+// validate reads the entitlement catalog file if it is set.
+func (e *entitlementFlags) validate() error {
+	if e.flagEntitlementCatalog == "" {
+		return nil
+	}
+	c, err := entitlement.Read(e.flagEntitlementCatalog)
+	if err != nil {
+		return fmt.Errorf("invalid entitlement catalog: %w", err)
+	}
+	e.catalog = c
+	return nil
+}
+
+// roleChecker checks the roles of IAM requests exist.
+type roleChecker interface {
+	CheckRoles(ctx context.Context, roles []string) error
+}
+
+// roleCheckFlags are the flags to check the roles of IAM requests exist with
+// the IAM Roles API.
+type roleCheckFlags struct {
+	flagCheckRoles bool
+
+	// testChecker is used for testing only.
+	testChecker roleChecker
+}
+
+// register registers the role check flags to the given flag section.
+func (r *roleCheckFlags) register(f *cli.FlagSection) {
+	f.BoolVar(&cli.BoolVar{
+		Name:    "check-roles",
+		Target:  &r.flagCheckRoles,
+		Default: false,
+		Usage: "Whether to check the roles, including custom roles, exist " +
+			"and are not deprecated with the IAM Roles API.",
+	})
+}
+
+// check checks the roles of the request exist if enabled. Check failures are
+// written as validation denied audit events to the audit log project if it is
+// set.
+func (r *roleCheckFlags) check(ctx context.Context, req *v1alpha1.IAMRequest, auditLogProject string) error {
+	if !r.flagCheckRoles {
+		return nil
+	}
+
+	checker := r.testChecker
+	if checker == nil {
+		c, err := iamrole.NewChecker(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to create role checker: %w", err)
+		}
+		checker = c
+	}
+
+	var roles []string
+	for _, p := range req.ResourcePolicies {
+		for _, b := range p.Bindings {
+			roles = append(roles, b.Roles()...)
+		}
+	}
+	if err := checker.CheckRoles(ctx, roles); err != nil {
+		auditValidationDenied(ctx, auditLogProject, err)
+		return fmt.Errorf("failed to check roles: %w", err)
+	}
+	return nil
+}
+
+// resourceChecker checks the resources of IAM requests are ready to be
+// handled.
+type resourceChecker interface {
+	CheckResources(ctx context.Context, resources []string) ([]*handler.ResourceReadiness, error)
+}
+
+// liveCheckFlags are the flags to check the resources of IAM requests exist
+// and their IAM policies can be set with the current credentials.
+type liveCheckFlags struct {
+	flagLive bool
+
+	// testChecker is used for testing only.
+	testChecker resourceChecker
+}
+
+// register registers the live check flags to the given flag section.
+func (l *liveCheckFlags) register(f *cli.FlagSection) {
+	f.BoolVar(&cli.BoolVar{
+		Name:    "live",
+		Target:  &l.flagLive,
+		Default: false,
+		Usage: "Whether to check each resource exists and the current " +
+			"credentials can get and set its IAM policy, and report the " +
+			"readiness of each resource.",
+	})
+}
+
+// check returns the readiness of the resources.
+func (l *liveCheckFlags) check(ctx context.Context, resources []string) ([]*handler.ResourceReadiness, error) {
+	checker := l.testChecker
+	if checker == nil {
+		h, closer, err := newResourceChecker(ctx)
+		defer func() {
+			if err := closer.Close(); err != nil {
+				logging.FromContext(ctx).ErrorContext(ctx, "failed to close", "error", err)
+			}
+		}()
+		if err != nil {
+			return nil, err
+		}
+		checker = h
+	}
+
+	rs, err := checker.CheckResources(ctx, resources)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check resources: %w", err)
+	}
+	return rs, nil
+}
+
+// newResourceChecker creates an IAMHandler with new resource manager clients
+// to check resources with.
+func newResourceChecker(ctx context.Context) (*handler.IAMHandler, *multicloser.Closer, error) {
+	var closer *multicloser.Closer
+
+	organizationsClient, err := resourcemanager.NewOrganizationsClient(ctx)
+	if err != nil {
+		return nil, closer, fmt.Errorf("failed to create organizations client: %w", err)
+	}
+	closer = multicloser.Append(closer, organizationsClient.Close)
+
+	foldersClient, err := resourcemanager.NewFoldersClient(ctx)
+	if err != nil {
+		return nil, closer, fmt.Errorf("failed to create folders client: %w", err)
+	}
+	closer = multicloser.Append(closer, foldersClient.Close)
+
+	projectsClient, err := resourcemanager.NewProjectsClient(ctx)
+	if err != nil {
+		return nil, closer, fmt.Errorf("failed to create projects client: %w", err)
+	}
+	closer = multicloser.Append(closer, projectsClient.Close)
+
+	bigQueryClient, err := bigqueryiam.NewClient(ctx)
+	if err != nil {
+		return nil, closer, fmt.Errorf("failed to create BigQuery client: %w", err)
+	}
+
+	storageClient, err := storageiam.NewClient(ctx)
+	if err != nil {
+		return nil, closer, fmt.Errorf("failed to create storage client: %w", err)
+	}
+
+	secretManagerClient, err := secretmanageriam.NewClient(ctx)
+	if err != nil {
+		return nil, closer, fmt.Errorf("failed to create secret manager client: %w", err)
+	}
+
+	kmsClient, err := kmsiam.NewClient(ctx)
+	if err != nil {
+		return nil, closer, fmt.Errorf("failed to create cloud kms client: %w", err)
+	}
+
+	serviceAccountClient, err := serviceaccountiam.NewClient(ctx)
+	if err != nil {
+		return nil, closer, fmt.Errorf("failed to create service account client: %w", err)
+	}
+
+	iamPolicyClient := genericiam.NewClient(nil)
+	closer = multicloser.Append(closer, iamPolicyClient.Close)
+
+	h, err := handler.NewIAMHandler(ctx, organizationsClient, foldersClient, projectsClient,
+		handler.WithBigQueryClient(bigQueryClient),
+		handler.WithStorageClient(storageClient),
+		handler.WithSecretManagerClient(secretManagerClient),
+		handler.WithKMSClient(kmsClient),
+		handler.WithServiceAccountClient(serviceAccountClient),
+		handler.WithIAMPolicyClient(iamPolicyClient))
+	if err != nil {
+		return nil, closer, fmt.Errorf("failed to create IAM handler: %w", err)
+	}
+	return h, closer, nil
+}
+
+// approvalVerifier verifies request files are approved in pull requests.
+type approvalVerifier interface {
+	Verify(ctx context.Context, pr *approval.PullRequest, file string, required int) (*approval.Result, error)
+}
+
+// approvalFlags are the flags to verify IAM requests are approved by other
+// users than their authors before they are handled.
+type approvalFlags struct {
+	flagRequireApprovals int
+
+	flagGitHubPR string
+
+	// Pull request parsed from flagGitHubPR by validate.
+	pr *approval.PullRequest
+
+	// testVerifier is used for testing only.
+	testVerifier approvalVerifier
+}
+
+// register registers the approval flags to the given flag section.
+func (a *approvalFlags) register(f *cli.FlagSection) {
+	f.IntVar(&cli.IntVar{
+		Name:    "require-approvals",
+		Target:  &a.flagRequireApprovals,
+		Default: 0,
+		Example: "2",
+		Usage: "The number of distinct users other than the author who must " +
+			"have approved the latest commit of the pull request changing the " +
+			"request file. The token is read from GITHUB_TOKEN. Approvals are " +
+			"not verified if it is 0.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "github-pr",
+		Target:  &a.flagGitHubPR,
+		Example: "my-org/my-repo/123",
+		Usage: `The GitHub pull request of the request file, in the format ` +
+			`of "owner/repo/number" or a pull request URL, required with ` +
+			`require-approvals.`,
+	})
+}
+
+// validate validates the approval flags.
+func (a *approvalFlags) validate() error {
+	if a.flagRequireApprovals < 0 {
+		return fmt.Errorf("require-approvals must not be negative, got %d", a.flagRequireApprovals)
+	}
+	if a.flagRequireApprovals == 0 {
+		return nil
+	}
+	if a.flagGitHubPR == "" {
+		return fmt.Errorf("github-pr is required with require-approvals")
+	}
+	pr, err := approval.ParsePullRequest(a.flagGitHubPR)
+	if err != nil {
+		return fmt.Errorf("invalid github-pr: %w", err)
+	}
+	a.pr = pr
+	return nil
+}
+
+// verify verifies the request file is approved in the pull request if
+// approvals are required, and returns the approvers. Verification failures are
+// written as validation denied audit events to the audit log project if it is
+// set.
+func (a *approvalFlags) verify(ctx context.Context, path string, getenv func(string) string, auditLogProject string) ([]string, error) {
+	if a.flagRequireApprovals == 0 {
+		return nil, nil
+	}
+
+	verifier := a.testVerifier
+	if verifier == nil {
+		apiURL := getenv("GITHUB_API_URL")
+		if apiURL == "" {
+			apiURL = approval.DefaultGitHubAPIURL
+		}
+		v, err := approval.NewGitHubVerifier(&http.Client{Timeout: 30 * time.Second}, apiURL, getenv("GITHUB_TOKEN"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create approval verifier: %w", err)
+		}
+		verifier = v
+	}
+
+	result, err := verifier.Verify(ctx, a.pr, path, a.flagRequireApprovals)
+	if err != nil {
+		auditValidationDenied(ctx, auditLogProject, err)
+		return nil, fmt.Errorf("failed to verify approvals: %w", err)
+	}
+	approvers := make([]string, 0, len(result.Approvers))
+	for _, login := range result.Approvers {
+		approvers = append(approvers, "github:"+login)
+	}
+	return approvers, nil
+}
+
+// admissionChecker checks IAM requests are admitted by an external policy.
+type admissionChecker interface {
+	Check(ctx context.Context, w *v1alpha1.IAMRequestWrapper) error
+}
+
+// admissionFlags are the flags to check IAM requests are admitted by an Open
+// Policy Agent (OPA) policy before they are handled.
+type admissionFlags struct {
+	flagOPAURL string
+
+	// testChecker is used for testing only.
+	testChecker admissionChecker
+}
+
+// register registers the admission flags to the given flag section.
+func (a *admissionFlags) register(f *cli.FlagSection) {
+	f.StringVar(&cli.StringVar{
+		Name:    "opa-url",
+		Target:  &a.flagOPAURL,
+		Example: "http://localhost:8181/v1/data/aod/allow",
+		Usage: "The OPA data API URL of the decision whether IAM requests " +
+			"are admitted. The normalized request is posted as the input, " +
+			"and requests are refused if the decision is not allow. The " +
+			"bearer token is read from OPA_TOKEN. Requests are not checked " +
+			"if it is not set.",
+	})
+}
+
+// check checks the IAM request is admitted by the OPA policy if the URL is
+// set. Denials are written as validation denied audit events to the audit log
+// project if it is set.
+func (a *admissionFlags) check(ctx context.Context, w *v1alpha1.IAMRequestWrapper, getenv func(string) string, auditLogProject string) error {
+	if a.flagOPAURL == "" {
+		return nil
+	}
+
+	checker := a.testChecker
+	if checker == nil {
+		c, err := admission.NewOPAChecker(&http.Client{Timeout: 30 * time.Second}, a.flagOPAURL, getenv("OPA_TOKEN"))
+		if err != nil {
+			return fmt.Errorf("failed to create admission checker: %w", err)
+		}
+		checker = c
+	}
+
+	if err := checker.Check(ctx, w); err != nil {
+		auditValidationDenied(ctx, auditLogProject, err)
+		return fmt.Errorf("failed to check admission: %w", err)
+	}
+	return nil
+}
+
+// Exporters of the telemetry flags.
+const (
+	telemetryExporterOTLP   = "otlp"
+	telemetryExporterStderr = "stderr"
+)
+
+// telemetryFlags are the flags to export OpenTelemetry traces and metrics of
+// the handlers.
+type telemetryFlags struct {
+	flagExporter string
+
+	flagEndpoint string
+
+	flagInsecure bool
+}
+
+// register registers the telemetry flags to the given flag section.
+func (t *telemetryFlags) register(f *cli.FlagSection) {
+	f.StringVar(&cli.StringVar{
+		Name:    "otel-exporter",
+		Target:  &t.flagExporter,
+		Example: "otlp",
+		Predict: predict.Set{telemetryExporterOTLP, telemetryExporterStderr},
+		Usage: `The exporter of OpenTelemetry traces and metrics, one of "otlp" ` +
+			`to export to an OpenTelemetry collector with OTLP over gRPC, and ` +
+			`"stderr" to write them to stderr for debugging. Telemetry is not ` +
+			`exported if it is not set.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "otel-endpoint",
+		Target:  &t.flagEndpoint,
+		Example: "localhost:4317",
+		Usage: `The "host:port" of the OpenTelemetry collector for the "otlp" ` +
+			`exporter, default is from the OTEL_EXPORTER_OTLP_ENDPOINT ` +
+			`environment variable, or "localhost:4317".`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "otel-insecure",
+		Target:  &t.flagInsecure,
+		Default: false,
+		Usage:   `Whether to disable TLS to the OpenTelemetry collector.`,
+	})
+}
+
+// validate checks if the telemetry flags are valid.
+func (t *telemetryFlags) validate() error {
+	if t.flagExporter == "" {
+		return nil
+	}
+	if allowed := []string{telemetryExporterOTLP, telemetryExporterStderr}; !slices.Contains(allowed, t.flagExporter) {
+		return fmt.Errorf("invalid otel exporter %q, must be one of %q", t.flagExporter, allowed)
+	}
+	return nil
+}
+
+// providers returns the OpenTelemetry providers of the flags, or nil if the
+// exporter is not set. The providers must be shut down to flush the telemetry.
+func (t *telemetryFlags) providers(ctx context.Context, stderr io.Writer) (*telemetry.Providers, error) {
+	cfg := &telemetry.Config{
+		Endpoint:       t.flagEndpoint,
+		Insecure:       t.flagInsecure,
+		ServiceName:    version.Name,
+		ServiceVersion: version.Version,
+	}
+	switch t.flagExporter {
+	case "":
+		return nil, nil
+	case telemetryExporterStderr:
+		cfg.Exporter = telemetry.ExporterWriter
+		cfg.Writer = stderr
+	default:
+		cfg.Exporter = telemetry.ExporterOTLP
+	}
+	p, err := telemetry.NewProviders(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create telemetry providers: %w", err)
+	}
+	return p, nil
+}
+
+// shutdownFunc returns a function shutting down the providers with a timeout,
+// to be appended to a closer.
+func shutdownFunc(p *telemetry.Providers) func() error {
+	return func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return p.Shutdown(ctx)
+	}
+}
+
+// iamHandlerFlags are the flags shared by the commands that create an
+// IAMHandler.
+type iamHandlerFlags struct {
+	// Optional custom condition title as AOD bindings identifier, required for
+	// integration test.
+	flagCustomConditionTitle string
+
+	// Optional stage to inject a failure at, for testing downstream workflows
+	// only.
+	flagInjectFailure string
+
+	// Optional override of the current time, for deterministic outputs in tests
+	// only.
+	flagNow time.Time
+
+	// Optional project to write audit events to Cloud Logging.
+	flagAuditLogProject string
+
+	// Optional BigQuery table to write audit events to.
+	flagAuditBigQueryTable string
+
+	// Optional flag to write audit events as structured log entries.
+	flagAuditLogEntries bool
+
+	// Optional max number of resources to handle concurrently.
+	flagConcurrency int
+
+	// Optional max number of retries of the IAM calls, and the initial delay of
+	// the fibonacci backoff between them.
+	flagMaxRetries        uint
+	flagRetryInitialDelay time.Duration
+
+	// Optional max number of IAM API calls of the run, including retries.
+	flagAPICallBudget int64
+
+	// Optional format of progress events written to stderr.
+	flagProgress string
+
+	// Optional project of the Firestore database to record grants and cleanups
+	// to.
+	flagRegistryProject string
+
+	// Optional Firestore database to record grants and cleanups to.
+	flagRegistryDatabase string
+
+	// Optional Pub/Sub topic to publish events of added or removed bindings to.
+	flagEventTopic string
+
+	// Optional GitHub repository to open issues in when permission is denied.
+	flagTicketGitHubRepo string
+
+	// Optional JIRA site to open issues in when permission is denied.
+	flagTicketJiraURL string
+
+	// Optional JIRA project to open issues in when permission is denied.
+	flagTicketJiraProject string
+
+	// Optional path of the config of the clients of each organization.
+	flagOrgConfig string
+
+	// Organization configs read from flagOrgConfig by validate.
+	orgConfigs *orgConfigs
+
+	// Optional correlation ID of the run, attached to the IAM calls and audit
+	// events.
+	flagCorrelationID string
+
+	// Optional prefixes and patterns of the only resources whose IAM policies
+	// can be modified.
+	flagAllowedResourcePrefixes []string
+	flagAllowedResourcePatterns []string
+
+	// Optional resources hosting the AOD deployment, and whether granting roles
+	// on them is allowed.
+	flagProtectedResources      []string
+	flagAllowProtectedResources bool
+
+	// Optional flag to guard against stale reads of IAM policies.
+	flagConsistentReads bool
+
+	// Optional endpoints of the IAMPolicy API by service, for resources named
+	// by their full resource names.
+	flagIAMEndpoints map[string]string
+
+	// Optional fallback endpoints of the resource manager API, which IAM calls
+	// fail over to if the default endpoint is unavailable.
+	flagResourceManagerFallbackEndpoints []string
+
+	// Optional reason of the maintenance mode, where no access is granted.
+	flagMaintenance string
+
+	// Optional exporter of OpenTelemetry traces and metrics.
+	telemetryFlags telemetryFlags
+}
+
+// register registers the IAMHandler flags to the given flag section.
+func (i *iamHandlerFlags) register(f *cli.FlagSection) {
+	f.StringVar(&cli.StringVar{
+		Name:    "custom-condition-title",
+		Target:  &i.flagCustomConditionTitle,
+		Hidden:  true,
+		Example: "foo-aod-expiry",
+		Usage:   "The custom title for the aod expiry condition.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "inject-failure",
+		Target:  &i.flagInjectFailure,
+		Hidden:  true,
+		Example: "set-policy",
+		Usage: fmt.Sprintf("The stage to inject a failure at, for testing "+
+			"workflows only, one of %q.", iamFailureStages),
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "audit-log-project",
+		Target:  &i.flagAuditLogProject,
+		Example: "my-project",
+		Usage: "The project to write audit events to, in Cloud Logging log " +
+			`"aod-audit". Audit events are not written if it is not set.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "audit-bigquery-table",
+		Target:  &i.flagAuditBigQueryTable,
+		Example: "my-project.aod.audit",
+		Usage: "The BigQuery table to write audit events to, in the format " +
+			`of "project.dataset.table". Audit events are not written to ` +
+			"BigQuery if it is not set.",
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "audit-log-entries",
+		Target:  &i.flagAuditLogEntries,
+		Default: false,
+		Usage: "Whether to write audit events as structured JSON log " +
+			"entries with the other logs, for log-based metrics and alerts " +
+			"when the logs are collected to Cloud Logging, such as on Cloud " +
+			"Run.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "registry-project",
+		Target:  &i.flagRegistryProject,
+		Example: "my-project",
+		Usage: "The project of the Firestore database to record grants and " +
+			`cleanups to, in collection "aod-grants". Grants and cleanups are ` +
+			"not recorded if it is not set.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "registry-database",
+		Target:  &i.flagRegistryDatabase,
+		Default: "(default)",
+		Example: "aod",
+		Usage:   "The Firestore database to record grants and cleanups to.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "event-topic",
+		Target:  &i.flagEventTopic,
+		Example: "projects/my-project/topics/aod-events",
+		Usage: "The Pub/Sub topic to publish an event to whenever IAM " +
+			"bindings are added or removed. Events are not published if it " +
+			"is not set.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "ticket-github-repo",
+		Target:  &i.flagTicketGitHubRepo,
+		Example: "my-org/aod-onboarding",
+		Usage: "The GitHub repository, in the format of \"owner/repo\", to " +
+			"open an issue in describing the missing permissions when AOD is " +
+			"denied permission to the IAM policy of a resource. The token is " +
+			"read from GITHUB_TOKEN. Issues are not opened if it is not set.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "ticket-jira-url",
+		Target:  &i.flagTicketJiraURL,
+		Example: "https://example.atlassian.net",
+		Usage: "The JIRA site to open an issue in describing the missing " +
+			"permissions when AOD is denied permission to the IAM policy of " +
+			"a resource. The credentials are read from JIRA_USER and " +
+			"JIRA_API_TOKEN. Issues are not opened if it is not set.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "ticket-jira-project",
+		Target:  &i.flagTicketJiraProject,
+		Example: "OPS",
+		Usage:   "The key of the JIRA project to open issues in.",
+	})
+
+	f.IntVar(&cli.IntVar{
+		Name:    "concurrency",
+		Target:  &i.flagConcurrency,
+		Default: 1,
+		Example: "5",
+		Usage:   "The max number of resources to handle concurrently.",
+	})
+
+	f.UintVar(&cli.UintVar{
+		Name:    "max-retries",
+		Target:  &i.flagMaxRetries,
+		Default: 5,
+		Example: "3",
+		Usage:   "The max number of retries of a failed IAM call.",
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "retry-initial-delay",
+		Target:  &i.flagRetryInitialDelay,
+		Default: 500 * time.Millisecond,
+		Example: "1s",
+		Usage: "The delay before the first retry of a failed IAM call, " +
+			"which grows with a fibonacci backoff.",
+	})
+
+	f.Int64Var(&cli.Int64Var{
+		Name:    "api-call-budget",
+		Target:  &i.flagAPICallBudget,
+		Example: "1000",
+		Usage: "The max number of IAM API calls of the run, including " +
+			"retries, to protect shared quotas from pathological requests. " +
+			"The run fails once it is exceeded. There is no budget if it is " +
+			"not set.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "progress",
+		Target:  &i.flagProgress,
+		Example: "json",
+		Predict: predict.Set{formatJSON},
+		Usage: "The format of progress events of handling each resource, " +
+			`written to stderr. Only "json" is supported, which writes each ` +
+			"event as a JSON line. Progress events are not written if it is " +
+			"not set.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "org-config",
+		Target:  &i.flagOrgConfig,
+		Example: "/path/to/orgs.yaml",
+		Predict: predict.Files("*"),
+		Usage: "The path of the YAML config of the credentials and endpoints " +
+			"of each organization. The IAM policies of an organization and " +
+			"its descendant folders and projects are updated with the " +
+			"organization's credentials and endpoint. Other resources are " +
+			"updated with the application default credentials.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "correlation-id",
+		Target:  &i.flagCorrelationID,
+		Example: "my-workflow-run-123",
+		Usage: "The ID to correlate the IAM calls in the GCP audit logs and " +
+			`the AOD audit events with this run, attached to the IAM calls ` +
+			`as the "x-aod-correlation-id" gRPC metadata. A random ID is ` +
+			"generated if it is not set.",
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "allowed-resource-prefix",
+		Target:  &i.flagAllowedResourcePrefixes,
+		Example: "projects/dev-",
+		Usage: "The prefix of the resources whose IAM policies this AOD " +
+			"deployment may modify, can be repeated. Modifying other " +
+			"resources fails regardless of the request. All resources are " +
+			"allowed if neither this nor allowed-resource-pattern is set.",
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "allowed-resource-pattern",
+		Target:  &i.flagAllowedResourcePatterns,
+		Example: "projects/team-a-.*",
+		Usage: "The regular expression fully matching the resources whose " +
+			"IAM policies this AOD deployment may modify, can be repeated, " +
+			"see allowed-resource-prefix.",
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "protected-resource",
+		Target:  &i.flagProtectedResources,
+		Example: "projects/aod-infra",
+		Usage: "The resource hosting this AOD deployment, such as the " +
+			"project of its service account, can be repeated. Granting " +
+			"roles on it fails unless allow-protected-resources is set.",
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "allow-protected-resources",
+		Target:  &i.flagAllowProtectedResources,
+		Default: false,
+		Usage: "Allow granting roles on the protected resources, such as " +
+			"for requests with an extra approval. The grants are reported " +
+			"as warnings.",
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "consistent-reads",
+		Target:  &i.flagConsistentReads,
+		Default: false,
+		Usage: "Whether to guard against stale reads of IAM policies, which " +
+			"are eventually consistent with the updates. Reads returning a " +
+			"policy a set was rejected for are retried instead of setting " +
+			"the policy again, and updated policies are read back until the " +
+			"updates are visible, for resources with frequent IAM updates.",
+	})
+
+	f.StringMapVar(&cli.StringMapVar{
+		Name:    "iam-endpoint",
+		Target:  &i.flagIAMEndpoints,
+		Example: "pubsub.googleapis.com=us-east1-pubsub.googleapis.com:443",
+		Usage: "The endpoint of the IAMPolicy API of a service, in the format " +
+			`of "service=endpoint", for resources named by their full ` +
+			`resource names, such as "//pubsub.googleapis.com/projects/` +
+			`foo/topics/bar". The endpoint of a service is "<service>:443" ` +
+			"if it is not set. It can be repeated for multiple services.",
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "resource-manager-fallback-endpoint",
+		Target:  &i.flagResourceManagerFallbackEndpoints,
+		Example: "us-east1-cloudresourcemanager.googleapis.com:443",
+		Usage: "A fallback endpoint of the resource manager API, which the " +
+			"IAM calls of organizations, folders and projects fail over to " +
+			"if the default endpoint is unavailable, such as during a " +
+			"regional API disruption. It can be repeated, the endpoints are " +
+			"tried in order. Endpoints failing repeatedly are skipped for a " +
+			"cooldown, and a warning of the degraded mode is printed.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "maintenance",
+		Target:  &i.flagMaintenance,
+		Example: "IAM freeze for incident INC-123",
+		Usage: "The reason AOD is in maintenance mode, such as during a " +
+			"security incident or an IAM freeze. If set, requests granting " +
+			"or renewing access are refused with the reason, while cleanups " +
+			"and revocations keep working. It is usually set for a whole " +
+			"deployment with AOD_MAINTENANCE.",
+	})
+
+	i.telemetryFlags.register(f)
+
+	f.TimeVar(time.RFC3339, &cli.TimeVar{
+		Name:    "now",
+		Target:  &i.flagNow,
+		Hidden:  true,
+		Example: "2009-11-10T23:00:00Z",
+		Usage: "The override of the current time in RFC3339 format, for " +
+			"deterministic outputs in tests only.",
+	})
+}
+
+// now returns the current UTC time, or the overridden time if "-now" is set.
+func (i *iamHandlerFlags) now() time.Time {
+	if !i.flagNow.IsZero() {
+		return i.flagNow
+	}
+	return time.Now().UTC()
+}
+
+// conditionTitle returns the condition title of AOD bindings, which is the
+// custom condition title if "-custom-condition-title" is set.
+func (i *iamHandlerFlags) conditionTitle() string {
+	if i.flagCustomConditionTitle != "" {
+		return i.flagCustomConditionTitle
+	}
+	return handler.DefaultConditionTitle
+}
+
+// wrapIAMClient wraps the IAM client to fail the stage of "-inject-failure" if
+// it is set.
+func (i *iamHandlerFlags) wrapIAMClient(c handler.IAMClient) handler.IAMClient {
+	if i.flagInjectFailure == "" {
+		return c
+	}
+	return &failingIAMClient{IAMClient: c, stage: i.flagInjectFailure}
+}
+
+// auditEnabled returns whether audit events are written or published anywhere.
+func (i *iamHandlerFlags) auditEnabled() bool {
+	return i.flagAuditLogProject != "" || i.flagAuditBigQueryTable != "" ||
+		i.flagAuditLogEntries || i.flagRegistryProject != "" || i.flagEventTopic != ""
+}
+
+// validate checks if the IAMHandler flags are valid.
+func (i *iamHandlerFlags) validate() error {
+	if i.flagConcurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1, got %d", i.flagConcurrency)
+	}
+	if i.flagAPICallBudget < 0 {
+		return fmt.Errorf("api-call-budget must not be negative, got %d", i.flagAPICallBudget)
+	}
+	if i.flagRetryInitialDelay <= 0 {
+		return fmt.Errorf("retry-initial-delay must be positive, got %s", i.flagRetryInitialDelay)
+	}
+	if i.flagProgress != "" {
+		if err := checkFormat(i.flagProgress, formatJSON); err != nil {
+			return fmt.Errorf("invalid progress: %w", err)
+		}
+	}
+	if i.flagAuditBigQueryTable != "" {
+		if err := audit.ValidateBigQueryTable(i.flagAuditBigQueryTable); err != nil {
+			return fmt.Errorf("invalid audit BigQuery table: %w", err)
+		}
+	}
+	if i.flagEventTopic != "" {
+		if err := audit.ValidateTopic(i.flagEventTopic); err != nil {
+			return fmt.Errorf("invalid event topic: %w", err)
+		}
+	}
+	if err := i.telemetryFlags.validate(); err != nil {
+		return err
+	}
+	if i.flagTicketGitHubRepo != "" {
+		if err := ticket.ValidateGitHubRepo(i.flagTicketGitHubRepo); err != nil {
+			return fmt.Errorf("invalid ticket GitHub repo: %w", err)
+		}
+	}
+	if i.flagTicketGitHubRepo != "" && i.flagTicketJiraURL != "" {
+		return fmt.Errorf("only one of ticket-github-repo and ticket-jira-url can be set")
+	}
+	if (i.flagTicketJiraURL == "") != (i.flagTicketJiraProject == "") {
+		return fmt.Errorf("ticket-jira-url and ticket-jira-project must be set together")
+	}
+	for _, pattern := range i.flagAllowedResourcePatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid allowed resource pattern %q: %w", pattern, err)
+		}
+	}
+	for _, r := range i.flagProtectedResources {
+		if _, err := resource.Parse(r); err != nil {
+			return fmt.Errorf("invalid protected resource: %w", err)
+		}
+	}
+	for _, ep := range i.flagResourceManagerFallbackEndpoints {
+		if _, _, err := net.SplitHostPort(ep); err != nil {
+			return fmt.Errorf("invalid resource manager fallback endpoint %q, must be in the format of \"<host>:<port>\"", ep)
+		}
+	}
+	if i.flagOrgConfig != "" {
+		c, err := readOrgConfigs(i.flagOrgConfig)
+		if err != nil {
+			return fmt.Errorf("invalid org config: %w", err)
+		}
+		i.orgConfigs = c
+	}
+	return checkFailureStage(i.flagInjectFailure, iamFailureStages)
+}
+
+// ticketEnabled returns whether tickets are opened when permission is denied.
+func (i *iamHandlerFlags) ticketEnabled() bool {
+	return i.flagTicketGitHubRepo != "" || i.flagTicketJiraURL != ""
+}
+
+// ticketCreator returns the ticket creator of the flags, with the credentials
+// read from the environment with getenv.
+func (i *iamHandlerFlags) ticketCreator(getenv func(string) string) (handler.TicketCreator, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	if i.flagTicketGitHubRepo != "" {
+		apiURL := getenv("GITHUB_API_URL")
+		if apiURL == "" {
+			apiURL = ticket.DefaultGitHubAPIURL
+		}
+		c, err := ticket.NewGitHubCreator(client, apiURL, i.flagTicketGitHubRepo, getenv("GITHUB_TOKEN"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GitHub ticket creator: %w", err)
+		}
+		return c, nil
+	}
+	c, err := ticket.NewJiraCreator(client, i.flagTicketJiraURL, i.flagTicketJiraProject,
+		getenv("JIRA_USER"), getenv("JIRA_API_TOKEN"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JIRA ticket creator: %w", err)
+	}
+	return c, nil
+}
+
+// newIAMHandler creates an IAMHandler with the flags and the extra options,
+// progress events are written to the stderr of the command if enabled, and the
+// credentials of the ticket creator are read from the environment of the
+// command.
+func newIAMHandler(ctx context.Context, flags *iamHandlerFlags, cmd *cli.BaseCommand, extraOpts ...handler.Option) (*handler.IAMHandler, *multicloser.Closer, error) {
 	var closer *multicloser.Closer
 
 	// Create resource manager clients.
@@ -68,17 +1362,173 @@ func newIAMHandler(ctx context.Context, customConditionTitle string) (*handler.I
 	}
 	closer = multicloser.Append(closer, projectsClient.Close)
 
-	var opts []handler.Option
-	if customConditionTitle != "" {
-		opts = append(opts, handler.WithCustomConditionTitle(customConditionTitle))
+	var organizationsIAMClient, foldersIAMClient, projectsIAMClient handler.IAMClient = organizationsClient, foldersClient, projectsClient
+	if len(flags.flagResourceManagerFallbackEndpoints) > 0 {
+		f, fCloser, err := newResourceManagerFailover(ctx, flags.flagResourceManagerFallbackEndpoints,
+			organizationsClient, foldersClient, projectsClient)
+		closer = multicloser.Append(closer, fCloser.Close)
+		if err != nil {
+			return nil, closer, err
+		}
+		organizationsIAMClient, foldersIAMClient, projectsIAMClient = f.clients()
+
+		// Closed after the output of the command, so the warning of the degraded
+		// mode is the last thing printed.
+		closer = multicloser.Append(closer, func() {
+			f.printDegraded(cmd.Stderr(), cmd.GetEnv("GITHUB_ACTIONS") == "true")
+		})
+	}
+
+	bigQueryClient, err := bigqueryiam.NewClient(ctx)
+	if err != nil {
+		return nil, closer, fmt.Errorf("failed to create BigQuery client: %w", err)
+	}
+
+	storageClient, err := storageiam.NewClient(ctx)
+	if err != nil {
+		return nil, closer, fmt.Errorf("failed to create storage client: %w", err)
 	}
 
+	secretManagerClient, err := secretmanageriam.NewClient(ctx)
+	if err != nil {
+		return nil, closer, fmt.Errorf("failed to create secret manager client: %w", err)
+	}
+
+	kmsClient, err := kmsiam.NewClient(ctx)
+	if err != nil {
+		return nil, closer, fmt.Errorf("failed to create cloud kms client: %w", err)
+	}
+
+	serviceAccountClient, err := serviceaccountiam.NewClient(ctx)
+	if err != nil {
+		return nil, closer, fmt.Errorf("failed to create service account client: %w", err)
+	}
+
+	iamPolicyClient := genericiam.NewClient(flags.flagIAMEndpoints)
+	closer = multicloser.Append(closer, iamPolicyClient.Close)
+
+	correlationID := flags.flagCorrelationID
+	if correlationID == "" {
+		if correlationID, err = handler.NewCorrelationID(); err != nil {
+			return nil, closer, err
+		}
+	}
+
+	opts := []handler.Option{
+		handler.WithConcurrency(flags.flagConcurrency),
+		handler.WithCorrelationID(correlationID),
+		handler.WithRetry(retry.WithMaxRetries(uint64(flags.flagMaxRetries), retry.NewFibonacci(flags.flagRetryInitialDelay))),
+		handler.WithBigQueryClient(flags.wrapIAMClient(bigQueryClient)),
+		handler.WithStorageClient(flags.wrapIAMClient(storageClient)),
+		handler.WithSecretManagerClient(flags.wrapIAMClient(secretManagerClient)),
+		handler.WithKMSClient(flags.wrapIAMClient(kmsClient)),
+		handler.WithServiceAccountClient(flags.wrapIAMClient(serviceAccountClient)),
+		handler.WithIAMPolicyClient(flags.wrapIAMClient(iamPolicyClient)),
+	}
+	if flags.flagCustomConditionTitle != "" {
+		opts = append(opts, handler.WithCustomConditionTitle(flags.flagCustomConditionTitle))
+	}
+	if flags.flagAPICallBudget > 0 {
+		opts = append(opts, handler.WithAPICallBudget(flags.flagAPICallBudget))
+	}
+	if len(flags.flagAllowedResourcePrefixes) > 0 {
+		opts = append(opts, handler.WithAllowedResourcePrefixes(flags.flagAllowedResourcePrefixes...))
+	}
+	if len(flags.flagAllowedResourcePatterns) > 0 {
+		opts = append(opts, handler.WithAllowedResourcePatterns(flags.flagAllowedResourcePatterns...))
+	}
+	if len(flags.flagProtectedResources) > 0 {
+		opts = append(opts, handler.WithProtectedResources(flags.flagProtectedResources...))
+	}
+	if flags.flagAllowProtectedResources {
+		opts = append(opts, handler.WithAllowProtectedResources())
+	}
+	if flags.flagConsistentReads {
+		opts = append(opts, handler.WithConsistentReads())
+	}
+	if flags.flagMaintenance != "" {
+		opts = append(opts, handler.WithMaintenance(flags.flagMaintenance))
+	}
+	if !flags.flagNow.IsZero() {
+		opts = append(opts, handler.WithNowFunc(flags.now))
+	}
+	if flags.flagProgress == formatJSON {
+		opts = append(opts, handler.WithProgressReporter(progress.NewJSONReporter(cmd.Stderr())))
+	}
+	if flags.flagRegistryProject != "" {
+		store, err := registry.NewFirestoreStore(ctx, flags.flagRegistryProject, flags.flagRegistryDatabase)
+		if err != nil {
+			return nil, closer, fmt.Errorf("failed to create registry store: %w", err)
+		}
+		closer = multicloser.Append(closer, store.Close)
+		opts = append(opts, handler.WithAuditSink(registry.NewSink(store)))
+	}
+	if flags.flagAuditLogProject != "" {
+		sink, err := audit.NewCloudLoggingSink(ctx, flags.flagAuditLogProject)
+		if err != nil {
+			return nil, closer, fmt.Errorf("failed to create audit sink: %w", err)
+		}
+		opts = append(opts, handler.WithAuditSink(sink))
+	}
+	if flags.flagAuditBigQueryTable != "" {
+		sink, err := audit.NewBigQuerySink(ctx, flags.flagAuditBigQueryTable)
+		if err != nil {
+			return nil, closer, fmt.Errorf("failed to create BigQuery audit sink: %w", err)
+		}
+		opts = append(opts, handler.WithAuditSink(sink))
+	}
+	if flags.flagAuditLogEntries {
+		opts = append(opts, handler.WithAuditSink(audit.NewLogSink()))
+	}
+	if flags.flagEventTopic != "" {
+		pub, err := audit.NewPubSubPublisher(ctx, flags.flagEventTopic)
+		if err != nil {
+			return nil, closer, fmt.Errorf("failed to create event publisher: %w", err)
+		}
+		opts = append(opts, handler.WithEventPublisher(pub))
+	}
+
+	if flags.auditEnabled() || flags.ticketEnabled() {
+		opts = append(opts, handler.WithCaller(audit.DetectCaller(ctx)))
+	}
+	if flags.ticketEnabled() {
+		c, err := flags.ticketCreator(cmd.GetEnv)
+		if err != nil {
+			return nil, closer, err
+		}
+		opts = append(opts, handler.WithTicketCreator(c))
+	}
+
+	tp, err := flags.telemetryFlags.providers(ctx, cmd.Stderr())
+	if err != nil {
+		return nil, closer, err
+	}
+	if tp != nil {
+		closer = multicloser.Append(closer, shutdownFunc(tp))
+		opts = append(opts, handler.WithTelemetry(tp.TracerProvider, tp.MeterProvider))
+	}
+
+	// Check the states of resources failing to get or set IAM policies, to skip
+	// resources that are pending deletion instead of retrying them.
+	defaultWalker := hierarchy.NewWalker(foldersClient, projectsClient)
+	opts = append(opts, handler.WithStateChecker(defaultWalker))
+	if flags.orgConfigs != nil {
+		orgOpts, orgCloser, err := newOrgHandlerOptions(ctx, flags.orgConfigs, defaultWalker, flags.wrapIAMClient)
+		closer = multicloser.Append(closer, orgCloser.Close)
+		if err != nil {
+			return nil, closer, err
+		}
+		opts = append(opts, orgOpts...)
+	}
+
+	opts = append(opts, extraOpts...)
+
 	// Create IAMHandler with the clients.
 	h, err := handler.NewIAMHandler(
 		ctx,
-		organizationsClient,
-		foldersClient,
-		projectsClient,
+		flags.wrapIAMClient(organizationsIAMClient),
+		flags.wrapIAMClient(foldersIAMClient),
+		flags.wrapIAMClient(projectsIAMClient),
 		opts...,
 	)
 	if err != nil {
@@ -86,3 +1536,28 @@ func newIAMHandler(ctx context.Context, customConditionTitle string) (*handler.I
 	}
 	return h, closer, nil
 }
+
+// auditValidationDenied writes a validation denied audit event to the Cloud
+// Logging of the project if the project is set. Failures are logged and
+// ignored.
+func auditValidationDenied(ctx context.Context, projectID string, validateErr error) {
+	if projectID == "" {
+		return
+	}
+
+	logger := logging.FromContext(ctx)
+	sink, err := audit.NewCloudLoggingSink(ctx, projectID)
+	if err != nil {
+		logger.WarnContext(ctx, "failed to create audit sink", "error", err)
+		return
+	}
+
+	if err := sink.Write(ctx, &audit.Event{
+		Type:    audit.EventTypeValidationDenied,
+		Time:    time.Now().UTC(),
+		Outcome: audit.OutcomeFailure,
+		Error:   validateErr.Error(),
+	}); err != nil {
+		logger.WarnContext(ctx, "failed to write audit event", "error", err)
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/handler"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestPrintWarnings(t *testing.T) {
+	t.Parallel()
+
+	resps := []*v1alpha1.IAMResponse{
+		{
+			Resource: "organizations/foo",
+			Warnings: []string{
+				"failed to check expiry: 100% invalid",
+				"failed to check expiry: line1\nline2",
+			},
+		},
+		{
+			Resource: "folders/bar",
+		},
+		{
+			Resource: "projects/baz",
+			Warnings: []string{"failed to check expiry: bananas"},
+		},
+	}
+
+	cases := []struct {
+		name          string
+		resps         []*v1alpha1.IAMResponse
+		githubActions bool
+		expOut        string
+	}{
+		{
+			name:  "plain",
+			resps: resps,
+			expOut: `
+WARNING: organizations/foo: failed to check expiry: 100% invalid
+WARNING: organizations/foo: failed to check expiry: line1
+line2
+WARNING: projects/baz: failed to check expiry: bananas`,
+		},
+		{
+			name:          "github_actions",
+			resps:         resps,
+			githubActions: true,
+			expOut: `
+::warning::organizations/foo: failed to check expiry: 100%25 invalid
+::warning::organizations/foo: failed to check expiry: line1%0Aline2
+::warning::projects/baz: failed to check expiry: bananas`,
+		},
+		{
+			name:  "no_warnings",
+			resps: resps[1:2],
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var out bytes.Buffer
+			printWarnings(&out, tc.resps, tc.githubActions)
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(out.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestPrintPartiallyApplied(t *testing.T) {
+	t.Parallel()
+
+	resps := []*v1alpha1.IAMResponse{{Resource: "folders/bar"}, {Resource: "projects/baz"}}
+
+	cases := []struct {
+		name          string
+		resps         []*v1alpha1.IAMResponse
+		rollback      bool
+		githubActions bool
+		expOut        string
+	}{
+		{
+			name:  "plain",
+			resps: resps,
+			expOut: `WARNING: the IAM request was partially applied to resources ["folders/bar" "projects/baz"], ` +
+				`rerun the request to retry the failed resources, or run "aod iam cleanup" with the request ` +
+				`to remove the applied bindings, use -rollback-on-failure to apply requests all-or-nothing`,
+		},
+		{
+			name:          "github_actions_rollback",
+			resps:         resps[1:],
+			rollback:      true,
+			githubActions: true,
+			expOut: `::warning::the IAM request was partially applied to resources ["projects/baz"], ` +
+				`rerun the request to retry the failed resources, or run "aod iam cleanup" with the request ` +
+				`to remove the applied bindings`,
+		},
+		{
+			name: "not_applied",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var out bytes.Buffer
+			printPartiallyApplied(&out, tc.resps, tc.rollback, tc.githubActions)
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(out.String())); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestIAMHandlerFlagsTicketCreator(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		flags   *iamHandlerFlags
+		env     map[string]string
+		wantErr string
+	}{
+		{
+			name:  "github",
+			flags: &iamHandlerFlags{flagTicketGitHubRepo: "foo/bar"},
+			env:   map[string]string{"GITHUB_TOKEN": "test-token"},
+		},
+		{
+			name:    "github_missing_token",
+			flags:   &iamHandlerFlags{flagTicketGitHubRepo: "foo/bar"},
+			wantErr: "failed to create GitHub ticket creator: github token is required",
+		},
+		{
+			name:  "jira",
+			flags: &iamHandlerFlags{flagTicketJiraURL: "https://example.atlassian.net", flagTicketJiraProject: "OPS"},
+			env:   map[string]string{"JIRA_USER": "bot@example.com", "JIRA_API_TOKEN": "test-token"},
+		},
+		{
+			name:    "jira_missing_credentials",
+			flags:   &iamHandlerFlags{flagTicketJiraURL: "https://example.atlassian.net", flagTicketJiraProject: "OPS"},
+			wantErr: "failed to create JIRA ticket creator: jira user and token are required",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := tc.flags.ticketCreator(func(k string) string { return tc.env[k] })
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error: %s", tc.name, diff)
+			}
+		})
+	}
+}
+
+type fakeMemberChecker struct {
+	injectErr  error
+	gotMembers []string
+}
+
+func (c *fakeMemberChecker) CheckMembers(ctx context.Context, members []string) error {
+	c.gotMembers = members
+	return c.injectErr
+}
+
+type fakeRoleChecker struct {
+	injectErr error
+	gotRoles  []string
+}
+
+func (c *fakeRoleChecker) CheckRoles(ctx context.Context, roles []string) error {
+	c.gotRoles = roles
+	return c.injectErr
+}
+
+type fakeResourceChecker struct {
+	notReady     map[string]string
+	gotResources []string
+}
+
+func (c *fakeResourceChecker) CheckResources(ctx context.Context, resources []string) ([]*handler.ResourceReadiness, error) {
+	c.gotResources = resources
+	result := make([]*handler.ResourceReadiness, 0, len(resources))
+	for _, r := range resources {
+		msg, ok := c.notReady[r]
+		result = append(result, &handler.ResourceReadiness{Resource: r, Ready: !ok, Error: msg})
+	}
+	return result, nil
+}
+
+type fakeAdmissionChecker struct {
+	injectErr error
+	gotReq    *v1alpha1.IAMRequestWrapper
+}
+
+func (c *fakeAdmissionChecker) Check(ctx context.Context, w *v1alpha1.IAMRequestWrapper) error {
+	c.gotReq = w
+	return c.injectErr
+}
+
+func TestWithErrorHelp(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		messages string
+		wantErr  string
+	}{
+		{
+			name:    "no_messages_file",
+			wantErr: "injected error",
+		},
+		{
+			name: "error_help",
+			messages: `
+messages:
+  error_help: "Need help with {{ printf \"%q\" .Error }}? See https://example.com/aod-help."
+`,
+			wantErr: "injected error\n\nNeed help with \"injected error\"? See https://example.com/aod-help.",
+		},
+		{
+			name: "invalid_messages_file",
+			messages: `
+messages:
+  unknown: "foo"
+`,
+			wantErr: "injected error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			env := map[string]string{}
+			if tc.messages != "" {
+				path := filepath.Join(t.TempDir(), "messages.yaml")
+				if err := os.WriteFile(path, []byte(tc.messages), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				env[messagesFileEnv] = path
+			}
+
+			injected := fmt.Errorf("injected error")
+			err := withErrorHelp(ctx, injected, func(k string) string { return env[k] })
+			if got := err.Error(); got != tc.wantErr {
+				t.Errorf("Process(%+v) got error %q, want %q", tc.name, got, tc.wantErr)
+			}
+			if !errors.Is(err, injected) {
+				t.Errorf("Process(%+v) got error %v, want it to wrap %v", tc.name, err, injected)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cursor persists the progress of long running operations over many
+// resources, so that they can resume where they left off.
+package cursor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultCollection is the default Firestore collection of the cursors.
+const DefaultCollection = "aod-cursors"
+
+// Cursor is the progress of an operation.
+type Cursor struct {
+	// ID of the cursor, which is the ID of its document.
+	ID string `firestore:"-"`
+
+	// Processed are the resources that have been processed.
+	Processed []string `firestore:"processed"`
+
+	// UpdateTime is when the cursor was last saved.
+	UpdateTime time.Time `firestore:"updateTime"`
+}
+
+// Store stores the cursors.
+type Store interface {
+	// Get returns the cursor with the ID, or nil if it does not exist.
+	Get(ctx context.Context, id string) (*Cursor, error)
+
+	// Put creates or replaces the cursor.
+	Put(ctx context.Context, c *Cursor) error
+
+	// Delete deletes the cursor with the ID, it is not an error if the cursor
+	// does not exist.
+	Delete(ctx context.Context, id string) error
+}
+
+// FirestoreStore stores the cursors as documents in a Firestore collection.
+type FirestoreStore struct {
+	client     *firestore.Client
+	collection string
+}
+
+// NewFirestoreStore creates a new FirestoreStore storing to the "aod-cursors"
+// collection of the Firestore database in the project.
+func NewFirestoreStore(ctx context.Context, projectID, databaseID string, opts ...option.ClientOption) (*FirestoreStore, error) {
+	client, err := firestore.NewClientWithDatabase(ctx, projectID, databaseID, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create firestore client: %w", err)
+	}
+	return &FirestoreStore{
+		client:     client,
+		collection: DefaultCollection,
+	}, nil
+}
+
+// Close closes the Firestore client.
+func (s *FirestoreStore) Close() error {
+	if err := s.client.Close(); err != nil {
+		return fmt.Errorf("failed to close firestore client: %w", err)
+	}
+	return nil
+}
+
+// Get returns the cursor with the ID, or nil if it does not exist.
+func (s *FirestoreStore) Get(ctx context.Context, id string) (*Cursor, error) {
+	d, err := s.client.Collection(s.collection).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document %q: %w", id, err)
+	}
+	var c Cursor
+	if err := d.DataTo(&c); err != nil {
+		return nil, fmt.Errorf("failed to decode document %q: %w", id, err)
+	}
+	c.ID = id
+	return &c, nil
+}
+
+// Put creates or replaces the cursor document.
+func (s *FirestoreStore) Put(ctx context.Context, c *Cursor) error {
+	if _, err := s.client.Collection(s.collection).Doc(c.ID).Set(ctx, c); err != nil {
+		return fmt.Errorf("failed to set document %q: %w", c.ID, err)
+	}
+	return nil
+}
+
+// Delete deletes the cursor document.
+func (s *FirestoreStore) Delete(ctx context.Context, id string) error {
+	if _, err := s.client.Collection(s.collection).Doc(id).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete document %q: %w", id, err)
+	}
+	return nil
+}
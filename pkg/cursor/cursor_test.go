@@ -0,0 +1,152 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cursor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestFirestoreStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	docName := "projects/test-project/databases/(default)/documents/aod-cursors/nightly"
+	cursor := &Cursor{
+		ID:         "nightly",
+		Processed:  []string{"folders/2", "projects/bar"},
+		UpdateTime: now,
+	}
+	cursorFields := map[string]*firestorepb.Value{
+		"processed":  stringArray("folders/2", "projects/bar"),
+		"updateTime": {ValueType: &firestorepb.Value_TimestampValue{TimestampValue: timestamppb.New(now)}},
+	}
+
+	server := &fakeFirestoreServer{
+		docs: map[string]*firestorepb.Document{
+			docName: {
+				Name:       docName,
+				Fields:     cursorFields,
+				CreateTime: timestamppb.New(now),
+				UpdateTime: timestamppb.New(now),
+			},
+		},
+	}
+	_, conn := testutil.FakeGRPCServer(t, func(s *grpc.Server) {
+		firestorepb.RegisterFirestoreServer(s, server)
+	})
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	store, err := NewFirestoreStore(ctx, "test-project", "(default)", option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	got, err := store.Get(ctx, "nightly")
+	if err != nil {
+		t.Fatalf("Get got unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(cursor, got); diff != "" {
+		t.Errorf("Get got cursor diff (-want, +got):\n%s", diff)
+	}
+
+	got, err = store.Get(ctx, "missing")
+	if err != nil {
+		t.Fatalf("Get got unexpected error: %v", err)
+	}
+	if got != nil {
+		t.Errorf("Get got cursor %+v, want nil", got)
+	}
+
+	if err := store.Put(ctx, cursor); err != nil {
+		t.Fatalf("Put got unexpected error: %v", err)
+	}
+	if err := store.Delete(ctx, "nightly"); err != nil {
+		t.Fatalf("Delete got unexpected error: %v", err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if got, want := len(server.commits), 2; got != want {
+		t.Fatalf("got %d commits, want %d", got, want)
+	}
+	put := server.commits[0].GetWrites()[0]
+	if got, want := put.GetUpdate().GetName(), docName; got != want {
+		t.Errorf("Put got document name %q, want %q", got, want)
+	}
+	if diff := cmp.Diff(cursorFields, put.GetUpdate().GetFields(), protocmp.Transform()); diff != "" {
+		t.Errorf("Put got document fields diff (-want, +got):\n%s", diff)
+	}
+	if got, want := server.commits[1].GetWrites()[0].GetDelete(), docName; got != want {
+		t.Errorf("Delete got document name %q, want %q", got, want)
+	}
+}
+
+func stringArray(ss ...string) *firestorepb.Value {
+	vs := make([]*firestorepb.Value, 0, len(ss))
+	for _, s := range ss {
+		vs = append(vs, &firestorepb.Value{ValueType: &firestorepb.Value_StringValue{StringValue: s}})
+	}
+	return &firestorepb.Value{ValueType: &firestorepb.Value_ArrayValue{ArrayValue: &firestorepb.ArrayValue{Values: vs}}}
+}
+
+type fakeFirestoreServer struct {
+	firestorepb.UnimplementedFirestoreServer
+
+	mu      sync.Mutex
+	docs    map[string]*firestorepb.Document
+	commits []*firestorepb.CommitRequest
+}
+
+func (s *fakeFirestoreServer) BatchGetDocuments(r *firestorepb.BatchGetDocumentsRequest, stream firestorepb.Firestore_BatchGetDocumentsServer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range r.GetDocuments() {
+		resp := &firestorepb.BatchGetDocumentsResponse{ReadTime: timestamppb.Now()}
+		if d, ok := s.docs[name]; ok {
+			resp.Result = &firestorepb.BatchGetDocumentsResponse_Found{Found: d}
+		} else {
+			resp.Result = &firestorepb.BatchGetDocumentsResponse_Missing{Missing: name}
+		}
+		if err := stream.Send(resp); err != nil {
+			return err //nolint:wrapcheck // Want passthrough
+		}
+	}
+	return nil
+}
+
+func (s *fakeFirestoreServer) Commit(_ context.Context, r *firestorepb.CommitRequest) (*firestorepb.CommitResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commits = append(s.commits, r)
+	resp := &firestorepb.CommitResponse{CommitTime: timestamppb.Now()}
+	for range r.GetWrites() {
+		resp.WriteResults = append(resp.WriteResults, &firestorepb.WriteResult{UpdateTime: timestamppb.Now()})
+	}
+	return resp, nil
+}
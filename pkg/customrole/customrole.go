@@ -0,0 +1,187 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package customrole manages the temporary custom roles AOD creates for
+// requests of permissions no predefined role is narrow enough for. The expiry
+// of a role is encoded in its ID, so that expired roles can be found and
+// deleted without any other state.
+package customrole
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	iam "google.golang.org/api/iam/v1"
+	"google.golang.org/api/option"
+
+	"github.com/abcxyz/access-on-demand/pkg/resource"
+)
+
+// IDPrefix is the prefix of the IDs of the custom roles created by AOD.
+const IDPrefix = "aod_"
+
+// Role is a custom role of an organization or a project.
+type Role struct {
+	// Name of the role, e.g. "projects/foo/roles/aod_1257894000_0123abcd".
+	Name string `yaml:"name,omitempty"`
+
+	// Title of the role.
+	Title string `yaml:"title,omitempty"`
+
+	// Description of the role.
+	Description string `yaml:"description,omitempty"`
+
+	// Permissions of the role.
+	Permissions []string `yaml:"permissions,omitempty"`
+}
+
+// RoleID returns the ID of a custom role expiring at expiry, in the format of
+// "aod_<expiry unix seconds>_<hash of seed>". The seed distinguishes roles of
+// the same expiry, since the IDs of deleted roles cannot be reused.
+func RoleID(expiry time.Time, seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	return fmt.Sprintf("%s%d_%s", IDPrefix, expiry.Unix(), hex.EncodeToString(sum[:4]))
+}
+
+// Expiry returns the expiry of the custom role of the name or ID, and whether
+// it is a custom role created by AOD.
+func Expiry(name string) (time.Time, bool) {
+	rest, ok := strings.CutPrefix(path.Base(name), IDPrefix)
+	if !ok {
+		return time.Time{}, false
+	}
+	secs, _, ok := strings.Cut(rest, "_")
+	if !ok {
+		return time.Time{}, false
+	}
+	n, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(n, 0).UTC(), true
+}
+
+// Client creates, lists and deletes custom roles with the IAM API.
+type Client struct {
+	service *iam.Service
+}
+
+// NewClient creates a new Client.
+func NewClient(ctx context.Context, opts ...option.ClientOption) (*Client, error) {
+	svc, err := iam.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create iam service: %w", err)
+	}
+	return &Client{service: svc}, nil
+}
+
+// CreateRole creates the custom role with the ID in the parent, an
+// organization or a project.
+func (c *Client) CreateRole(ctx context.Context, parent, roleID string, r *Role) (*Role, error) {
+	req := &iam.CreateRoleRequest{
+		RoleId: roleID,
+		Role: &iam.Role{
+			Title:               r.Title,
+			Description:         r.Description,
+			IncludedPermissions: r.Permissions,
+			Stage:               "GA",
+		},
+	}
+
+	typ, err := parentType(parent)
+	if err != nil {
+		return nil, err
+	}
+	var created *iam.Role
+	if typ == resource.TypeOrganization {
+		created, err = c.service.Organizations.Roles.Create(parent, req).Context(ctx).Do()
+	} else {
+		created, err = c.service.Projects.Roles.Create(parent, req).Context(ctx).Do()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create custom role %q in %q: %w", roleID, parent, err)
+	}
+	return toRole(created), nil
+}
+
+// ListRoles lists the custom roles of the parent, an organization or a
+// project, excluding the deleted roles.
+func (c *Client) ListRoles(ctx context.Context, parent string) ([]*Role, error) {
+	var roles []*Role
+	collect := func(resp *iam.ListRolesResponse) error {
+		for _, r := range resp.Roles {
+			roles = append(roles, toRole(r))
+		}
+		return nil
+	}
+
+	typ, err := parentType(parent)
+	if err != nil {
+		return nil, err
+	}
+	if typ == resource.TypeOrganization {
+		err = c.service.Organizations.Roles.List(parent).Pages(ctx, collect)
+	} else {
+		err = c.service.Projects.Roles.List(parent).Pages(ctx, collect)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list custom roles of %q: %w", parent, err)
+	}
+	return roles, nil
+}
+
+// DeleteRole deletes the custom role of the name. The role can be undeleted
+// within 7 days, and its ID cannot be reused for 37 days.
+func (c *Client) DeleteRole(ctx context.Context, name string) error {
+	typ, err := parentType(name)
+	if err != nil {
+		return err
+	}
+	if typ == resource.TypeOrganization {
+		_, err = c.service.Organizations.Roles.Delete(name).Context(ctx).Do()
+	} else {
+		_, err = c.service.Projects.Roles.Delete(name).Context(ctx).Do()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete custom role %q: %w", name, err)
+	}
+	return nil
+}
+
+// parentType returns the type of the parent of custom roles, which is the
+// type of the first segment of the name, and must be an organization or a
+// project.
+func parentType(name string) (resource.Type, error) {
+	switch typ := resource.TypeOf(name); typ {
+	case resource.TypeOrganization, resource.TypeProject:
+		return typ, nil
+	default:
+		return "", fmt.Errorf("custom roles of %q are not supported, must be of an organization or a project", name)
+	}
+}
+
+func toRole(r *iam.Role) *Role {
+	return &Role{
+		Name:        r.Name,
+		Title:       r.Title,
+		Description: r.Description,
+		Permissions: r.IncludedPermissions,
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package customrole
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	iam "google.golang.org/api/iam/v1"
+	"google.golang.org/api/option"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestRoleIDAndExpiry(t *testing.T) {
+	t.Parallel()
+
+	expiry := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	id := RoleID(expiry, "seed")
+	if !strings.HasPrefix(id, "aod_1257894000_") || len(id) != len("aod_1257894000_")+8 {
+		t.Errorf("RoleID got %q, want aod_1257894000_ followed by 8 hex digits", id)
+	}
+	if other := RoleID(expiry, "other seed"); other == id {
+		t.Errorf("RoleID got the same ID %q for different seeds", id)
+	}
+
+	cases := []struct {
+		name    string
+		role    string
+		want    time.Time
+		wantAOD bool
+	}{
+		{
+			name:    "id",
+			role:    id,
+			want:    expiry,
+			wantAOD: true,
+		},
+		{
+			name:    "name",
+			role:    "projects/foo/roles/" + id,
+			want:    expiry,
+			wantAOD: true,
+		},
+		{
+			name: "not_aod",
+			role: "projects/foo/roles/myRole",
+		},
+		{
+			name: "invalid_expiry",
+			role: "projects/foo/roles/aod_bananas_0123abcd",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := Expiry(tc.role)
+			if ok != tc.wantAOD || !got.Equal(tc.want) {
+				t.Errorf("Expiry(%q) got (%v, %t), want (%v, %t)", tc.role, got, ok, tc.want, tc.wantAOD)
+			}
+		})
+	}
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var gotCalls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		gotCalls = append(gotCalls, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost:
+			var req iam.CreateRoleRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			req.Role.Name = strings.TrimPrefix(r.URL.Path, "/v1/") + "/" + req.RoleId
+			json.NewEncoder(w).Encode(req.Role) //nolint:errcheck // Test server.
+		case r.Method == http.MethodGet && r.URL.Query().Get("pageToken") == "":
+			json.NewEncoder(w).Encode(&iam.ListRolesResponse{ //nolint:errcheck // Test server.
+				Roles:         []*iam.Role{{Name: "projects/foo/roles/a"}},
+				NextPageToken: "next",
+			})
+		case r.Method == http.MethodGet:
+			json.NewEncoder(w).Encode(&iam.ListRolesResponse{ //nolint:errcheck // Test server.
+				Roles: []*iam.Role{{Name: "projects/foo/roles/b"}},
+			})
+		case r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/missing"):
+			http.Error(w, `{"error": {"code": 404, "message": "not found"}}`, http.StatusNotFound)
+		case r.Method == http.MethodDelete:
+			json.NewEncoder(w).Encode(&iam.Role{Deleted: true}) //nolint:errcheck // Test server.
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	c, err := NewClient(ctx, option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	created, err := c.CreateRole(ctx, "organizations/123", "aod_1_abc", &Role{
+		Title:       "AOD temporary role",
+		Permissions: []string{"storage.objects.get"},
+	})
+	if err != nil {
+		t.Fatalf("CreateRole got unexpected error: %v", err)
+	}
+	want := &Role{
+		Name:        "organizations/123/roles/aod_1_abc",
+		Title:       "AOD temporary role",
+		Permissions: []string{"storage.objects.get"},
+	}
+	if diff := cmp.Diff(want, created); diff != "" {
+		t.Errorf("CreateRole got role diff (-want, +got):\n%s", diff)
+	}
+
+	roles, err := c.ListRoles(ctx, "projects/foo")
+	if err != nil {
+		t.Fatalf("ListRoles got unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]*Role{{Name: "projects/foo/roles/a"}, {Name: "projects/foo/roles/b"}}, roles); diff != "" {
+		t.Errorf("ListRoles got roles diff (-want, +got):\n%s", diff)
+	}
+
+	if err := c.DeleteRole(ctx, "projects/foo/roles/a"); err != nil {
+		t.Errorf("DeleteRole got unexpected error: %v", err)
+	}
+	err = c.DeleteRole(ctx, "projects/foo/roles/missing")
+	if diff := testutil.DiffErrString(err, `failed to delete custom role "projects/foo/roles/missing"`); diff != "" {
+		t.Errorf("DeleteRole got unexpected error: %s", diff)
+	}
+	_, err = c.ListRoles(ctx, "folders/123")
+	if diff := testutil.DiffErrString(err, `custom roles of "folders/123" are not supported`); diff != "" {
+		t.Errorf("ListRoles got unexpected error: %s", diff)
+	}
+
+	wantCalls := []string{
+		"POST /v1/organizations/123/roles",
+		"GET /v1/projects/foo/roles",
+		"GET /v1/projects/foo/roles",
+		"DELETE /v1/projects/foo/roles/a",
+		"DELETE /v1/projects/foo/roles/missing",
+	}
+	if diff := cmp.Diff(wantCalls, gotCalls); diff != "" {
+		t.Errorf("got calls diff (-want, +got):\n%s", diff)
+	}
+}
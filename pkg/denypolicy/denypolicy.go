@@ -0,0 +1,226 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package denypolicy manages the temporary IAM deny policies AOD creates to
+// block access with the IAM v2 Deny API. Like the temporary custom roles, the
+// expiry of a policy is encoded in its ID, so that expired policies can be
+// found and deleted without any other state.
+package denypolicy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	iam "cloud.google.com/go/iam/apiv2"
+	"cloud.google.com/go/iam/apiv2/iampb"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// IDPrefix is the prefix of the IDs of the deny policies created by AOD.
+const IDPrefix = "aod-"
+
+// Policy is an IAM deny policy attached to an organization, folder or
+// project.
+type Policy struct {
+	// Name of the policy, e.g.
+	// "policies/cloudresourcemanager.googleapis.com%2Fprojects%2F123/denypolicies/aod-1257894000-0123abcd".
+	Name string `yaml:"name,omitempty"`
+
+	// DisplayName of the policy.
+	DisplayName string `yaml:"displayName,omitempty"`
+
+	// Rules of the policy.
+	Rules []*Rule `yaml:"rules,omitempty"`
+}
+
+// Rule is a deny rule of a Policy.
+type Rule struct {
+	// DeniedPrincipals are the principals denied the permissions, e.g.
+	// "principal://goog/subject/alice@example.com".
+	DeniedPrincipals []string `yaml:"deniedPrincipals,omitempty"`
+
+	// ExceptionPrincipals are the principals not denied the permissions.
+	ExceptionPrincipals []string `yaml:"exceptionPrincipals,omitempty"`
+
+	// DeniedPermissions are the denied permissions, e.g.
+	// "iam.googleapis.com/roles.list".
+	DeniedPermissions []string `yaml:"deniedPermissions,omitempty"`
+}
+
+// PolicyID returns the ID of a deny policy expiring at expiry, in the format
+// of "aod-<expiry unix seconds>-<hash of seed>".
+func PolicyID(expiry time.Time, seed string) string {
+	return fmt.Sprintf("%s%d-%s", IDPrefix, expiry.Unix(), SeedHash(seed))
+}
+
+// SeedHash returns the hash of the seed in the IDs of deny policies, which
+// identifies the policies of the same rules regardless of their expiry.
+func SeedHash(seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	return hex.EncodeToString(sum[:4])
+}
+
+// Expiry returns the expiry of the deny policy of the name or ID, and whether
+// it is a deny policy created by AOD.
+func Expiry(name string) (time.Time, bool) {
+	rest, ok := strings.CutPrefix(path.Base(name), IDPrefix)
+	if !ok {
+		return time.Time{}, false
+	}
+	secs, _, ok := strings.Cut(rest, "-")
+	if !ok {
+		return time.Time{}, false
+	}
+	n, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(n, 0).UTC(), true
+}
+
+// Principal converts the IAM member, e.g. "user:alice@example.com", to the
+// principal identifier of the IAM deny API, e.g.
+// "principal://goog/subject/alice@example.com".
+func Principal(member string) (string, error) {
+	typ, id, ok := strings.Cut(member, ":")
+	if !ok {
+		return "", fmt.Errorf("member %q is not a valid format", member)
+	}
+	switch typ {
+	case "user":
+		return "principal://goog/subject/" + id, nil
+	case "group":
+		return "principalSet://goog/group/" + id, nil
+	case "serviceAccount":
+		return "principal://iam.googleapis.com/projects/-/serviceAccounts/" + id, nil
+	default:
+		return "", fmt.Errorf("member %q of type %q cannot be denied", member, typ)
+	}
+}
+
+// Parent returns the parent of the deny policies attached to the resource,
+// e.g. "policies/cloudresourcemanager.googleapis.com%2Fprojects%2Ffoo/denypolicies".
+func Parent(resource string) string {
+	return "policies/" + url.PathEscape("cloudresourcemanager.googleapis.com/"+resource) + "/denypolicies"
+}
+
+// Client creates, lists and deletes deny policies with the IAM v2 API.
+type Client struct {
+	client *iam.PoliciesClient
+}
+
+// NewClient creates a new Client.
+func NewClient(ctx context.Context, opts ...option.ClientOption) (*Client, error) {
+	c, err := iam.NewPoliciesClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create policies client: %w", err)
+	}
+	return &Client{client: c}, nil
+}
+
+// Close closes the client.
+func (c *Client) Close() error {
+	if err := c.client.Close(); err != nil {
+		return fmt.Errorf("failed to close policies client: %w", err)
+	}
+	return nil
+}
+
+// CreatePolicy creates the deny policy with the ID attached to the resource,
+// an organization, folder or project, and waits for it to be created.
+func (c *Client) CreatePolicy(ctx context.Context, resource, policyID string, p *Policy) (*Policy, error) {
+	req := &iampb.CreatePolicyRequest{
+		Parent:   Parent(resource),
+		PolicyId: policyID,
+		Policy:   &iampb.Policy{DisplayName: p.DisplayName},
+	}
+	for _, r := range p.Rules {
+		req.Policy.Rules = append(req.Policy.Rules, &iampb.PolicyRule{
+			Kind: &iampb.PolicyRule_DenyRule{DenyRule: &iampb.DenyRule{
+				DeniedPrincipals:    r.DeniedPrincipals,
+				ExceptionPrincipals: r.ExceptionPrincipals,
+				DeniedPermissions:   r.DeniedPermissions,
+			}},
+		})
+	}
+
+	op, err := c.client.CreatePolicy(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create deny policy %q on %q: %w", policyID, resource, err)
+	}
+	created, err := op.Wait(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for deny policy %q on %q to be created: %w", policyID, resource, err)
+	}
+	return toPolicy(created), nil
+}
+
+// ListPolicies lists the deny policies attached to the resource, excluding the
+// deleted policies. The rules of the policies are not listed.
+func (c *Client) ListPolicies(ctx context.Context, resource string) ([]*Policy, error) {
+	var policies []*Policy
+	it := c.client.ListPolicies(ctx, &iampb.ListPoliciesRequest{Parent: Parent(resource)})
+	for {
+		p, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list deny policies of %q: %w", resource, err)
+		}
+		if p.GetDeleteTime() != nil {
+			continue
+		}
+		policies = append(policies, toPolicy(p))
+	}
+	return policies, nil
+}
+
+// DeletePolicy deletes the deny policy of the name, and waits for it to be
+// deleted.
+func (c *Client) DeletePolicy(ctx context.Context, name string) error {
+	op, err := c.client.DeletePolicy(ctx, &iampb.DeletePolicyRequest{Name: name})
+	if err != nil {
+		return fmt.Errorf("failed to delete deny policy %q: %w", name, err)
+	}
+	if _, err := op.Wait(ctx); err != nil {
+		return fmt.Errorf("failed to wait for deny policy %q to be deleted: %w", name, err)
+	}
+	return nil
+}
+
+func toPolicy(p *iampb.Policy) *Policy {
+	policy := &Policy{Name: p.GetName(), DisplayName: p.GetDisplayName()}
+	for _, r := range p.GetRules() {
+		dr := r.GetDenyRule()
+		if dr == nil {
+			continue
+		}
+		policy.Rules = append(policy.Rules, &Rule{
+			DeniedPrincipals:    dr.GetDeniedPrincipals(),
+			ExceptionPrincipals: dr.GetExceptionPrincipals(),
+			DeniedPermissions:   dr.GetDeniedPermissions(),
+		})
+	}
+	return policy
+}
@@ -0,0 +1,242 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package denypolicy
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv2/iampb"
+	"cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestPolicyIDAndExpiry(t *testing.T) {
+	t.Parallel()
+
+	expiry := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	id := PolicyID(expiry, "seed")
+	if want := "aod-1257894000-" + SeedHash("seed"); id != want {
+		t.Errorf("PolicyID got %q, want %q", id, want)
+	}
+	if other := PolicyID(expiry, "other seed"); other == id {
+		t.Errorf("PolicyID got the same ID %q for different seeds", id)
+	}
+
+	cases := []struct {
+		name    string
+		policy  string
+		want    time.Time
+		wantAOD bool
+	}{
+		{
+			name:    "id",
+			policy:  id,
+			want:    expiry,
+			wantAOD: true,
+		},
+		{
+			name:    "name",
+			policy:  Parent("projects/foo") + "/" + id,
+			want:    expiry,
+			wantAOD: true,
+		},
+		{
+			name:   "not_aod",
+			policy: Parent("projects/foo") + "/my-policy",
+		},
+		{
+			name:   "invalid_expiry",
+			policy: Parent("projects/foo") + "/aod-bananas-0123abcd",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := Expiry(tc.policy)
+			if ok != tc.wantAOD || !got.Equal(tc.want) {
+				t.Errorf("Expiry(%q) got (%v, %t), want (%v, %t)", tc.policy, got, ok, tc.want, tc.wantAOD)
+			}
+		})
+	}
+}
+
+func TestPrincipal(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		member  string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "user",
+			member: "user:alice@example.com",
+			want:   "principal://goog/subject/alice@example.com",
+		},
+		{
+			name:   "group",
+			member: "group:oncall@example.com",
+			want:   "principalSet://goog/group/oncall@example.com",
+		},
+		{
+			name:   "service_account",
+			member: "serviceAccount:sa@foo.iam.gserviceaccount.com",
+			want:   "principal://iam.googleapis.com/projects/-/serviceAccounts/sa@foo.iam.gserviceaccount.com",
+		},
+		{
+			name:    "unsupported",
+			member:  "domain:example.com",
+			wantErr: `member "domain:example.com" of type "domain" cannot be denied`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Principal(tc.member)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Principal(%q) got unexpected error: %s", tc.member, diff)
+			}
+			if got != tc.want {
+				t.Errorf("Principal(%q) got %q, want %q", tc.member, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fake := &fakePoliciesServer{}
+	_, conn := testutil.FakeGRPCServer(t, func(s *grpc.Server) {
+		iampb.RegisterPoliciesServer(s, fake)
+	})
+	c, err := NewClient(ctx, option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	rules := []*Rule{{
+		DeniedPrincipals:  []string{"principal://goog/subject/alice@example.com"},
+		DeniedPermissions: []string{"iam.googleapis.com/roles.list"},
+	}}
+	created, err := c.CreatePolicy(ctx, "projects/foo", "aod-1-abc", &Policy{DisplayName: "AOD temporary deny policy", Rules: rules})
+	if err != nil {
+		t.Fatalf("CreatePolicy got unexpected error: %v", err)
+	}
+	want := &Policy{
+		Name:        "policies/cloudresourcemanager.googleapis.com%2Fprojects%2Ffoo/denypolicies/aod-1-abc",
+		DisplayName: "AOD temporary deny policy",
+		Rules:       rules,
+	}
+	if diff := cmp.Diff(want, created); diff != "" {
+		t.Errorf("CreatePolicy got policy diff (-want, +got):\n%s", diff)
+	}
+
+	policies, err := c.ListPolicies(ctx, "projects/foo")
+	if err != nil {
+		t.Fatalf("ListPolicies got unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]*Policy{{Name: want.Name, DisplayName: want.DisplayName}}, policies); diff != "" {
+		t.Errorf("ListPolicies got policies diff (-want, +got):\n%s", diff)
+	}
+
+	if err := c.DeletePolicy(ctx, want.Name); err != nil {
+		t.Errorf("DeletePolicy got unexpected error: %v", err)
+	}
+	err = c.DeletePolicy(ctx, want.Name)
+	if diff := testutil.DiffErrString(err, "not found"); diff != "" {
+		t.Errorf("DeletePolicy got unexpected error: %s", diff)
+	}
+}
+
+// fakePoliciesServer stores the policies in memory, and completes the
+// operations immediately.
+type fakePoliciesServer struct {
+	iampb.UnimplementedPoliciesServer
+
+	mu       sync.Mutex
+	policies []*iampb.Policy
+}
+
+func (s *fakePoliciesServer) CreatePolicy(ctx context.Context, req *iampb.CreatePolicyRequest) (*longrunningpb.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := proto.Clone(req.GetPolicy()).(*iampb.Policy) //nolint:forcetypeassert // Test server.
+	p.Name = req.GetParent() + "/" + req.GetPolicyId()
+	s.policies = append(s.policies, p)
+	return doneOperation(p)
+}
+
+func (s *fakePoliciesServer) ListPolicies(ctx context.Context, req *iampb.ListPoliciesRequest) (*iampb.ListPoliciesResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &iampb.ListPoliciesResponse{
+		// Deleted policies are listed with their delete time.
+		Policies: []*iampb.Policy{{Name: req.GetParent() + "/deleted", DeleteTime: timestamppb.Now()}},
+	}
+	for _, p := range s.policies {
+		if strings.HasPrefix(p.GetName(), req.GetParent()+"/") {
+			// Rules are not listed.
+			resp.Policies = append(resp.Policies, &iampb.Policy{Name: p.GetName(), DisplayName: p.GetDisplayName()})
+		}
+	}
+	return resp, nil
+}
+
+func (s *fakePoliciesServer) DeletePolicy(ctx context.Context, req *iampb.DeletePolicyRequest) (*longrunningpb.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, p := range s.policies {
+		if p.GetName() == req.GetName() {
+			s.policies = append(s.policies[:i], s.policies[i+1:]...)
+			return doneOperation(p)
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "policy %q not found", req.GetName())
+}
+
+func doneOperation(p *iampb.Policy) (*longrunningpb.Operation, error) {
+	resp, err := anypb.New(p)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to marshal policy: %v", err)
+	}
+	return &longrunningpb.Operation{
+		Name:   "operations/" + p.GetName(),
+		Done:   true,
+		Result: &longrunningpb.Operation_Response{Response: resp},
+	}, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package directory checks IAM members against the Google Workspace or Cloud
+// Identity directory.
+package directory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	admin "google.golang.org/api/admin/directory/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+// userPrefix is the prefix of user members.
+const userPrefix = "user:"
+
+// Checker checks user members exist in the directory and are not suspended,
+// which catches typos in members before useless bindings are created.
+type Checker struct {
+	service *admin.Service
+	domains []string
+}
+
+// NewChecker creates a new Checker of the user members in the domains, such
+// as "example.com". Users in other domains, such as external users, are not
+// checked since they are not in the directory. The caller needs to be able to
+// read users in the directory, such as with the "User Management" admin role.
+func NewChecker(ctx context.Context, domains []string, opts ...option.ClientOption) (*Checker, error) {
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("at least one domain is required")
+	}
+	opts = append([]option.ClientOption{option.WithScopes(admin.AdminDirectoryUserReadonlyScope)}, opts...)
+	svc, err := admin.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create directory service: %w", err)
+	}
+	ds := make([]string, 0, len(domains))
+	for _, d := range domains {
+		ds = append(ds, strings.ToLower(d))
+	}
+	return &Checker{
+		service: svc,
+		domains: ds,
+	}, nil
+}
+
+// CheckMembers checks the user members in the domains of the Checker exist in
+// the directory and are not suspended. Other members are ignored. Errors of
+// all the members are returned.
+func (c *Checker) CheckMembers(ctx context.Context, members []string) error {
+	var retErr error
+	checked := make(map[string]struct{}, len(members))
+	for _, m := range members {
+		email, ok := strings.CutPrefix(m, userPrefix)
+		if !ok || !c.inDomains(email) {
+			continue
+		}
+		if _, ok := checked[m]; ok {
+			continue
+		}
+		checked[m] = struct{}{}
+
+		if err := c.checkUser(ctx, email); err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("member %q: %w", m, err))
+		}
+	}
+	return retErr
+}
+
+// inDomains returns whether the email is in one of the domains of the Checker.
+func (c *Checker) inDomains(email string) bool {
+	_, domain, ok := strings.Cut(email, "@")
+	return ok && slices.Contains(c.domains, strings.ToLower(domain))
+}
+
+// checkUser checks the user exists in the directory and is not suspended.
+func (c *Checker) checkUser(ctx context.Context, email string) error {
+	u, err := c.service.Users.Get(email).Fields("primaryEmail", "suspended").Context(ctx).Do()
+	if err != nil {
+		var gErr *googleapi.Error
+		if errors.As(err, &gErr) && gErr.Code == http.StatusNotFound {
+			return fmt.Errorf("user does not exist in the directory")
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if u.Suspended {
+		return fmt.Errorf("user is suspended")
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package directory
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestChecker_CheckMembers(t *testing.T) {
+	t.Parallel()
+
+	users := map[string]string{
+		"alice@example.com": `{"primaryEmail": "alice@example.com"}`,
+		"bob@example.com":   `{"primaryEmail": "bob@example.com", "suspended": true}`,
+	}
+
+	cases := []struct {
+		name         string
+		members      []string
+		status       int
+		wantRequests []string
+		wantErr      string
+	}{
+		{
+			name: "success",
+			members: []string{
+				"user:alice@example.com",
+				"user:alice@example.com",
+				"group:eng@example.com",
+				"user:carol@other.com",
+				"serviceAccount:sa@example.com",
+			},
+			wantRequests: []string{"alice@example.com"},
+		},
+		{
+			name:         "domain_case_insensitive",
+			members:      []string{"user:alice@EXAMPLE.com"},
+			wantRequests: []string{"alice@EXAMPLE.com"},
+		},
+		{
+			name:         "not_found",
+			members:      []string{"user:jdoe@example.com", "user:alice@example.com"},
+			wantRequests: []string{"alice@example.com", "jdoe@example.com"},
+			wantErr:      `member "user:jdoe@example.com": user does not exist in the directory`,
+		},
+		{
+			name:         "suspended",
+			members:      []string{"user:bob@example.com"},
+			wantRequests: []string{"bob@example.com"},
+			wantErr:      `member "user:bob@example.com": user is suspended`,
+		},
+		{
+			name:         "server_error",
+			members:      []string{"user:alice@example.com"},
+			status:       http.StatusForbidden,
+			wantRequests: []string{"alice@example.com"},
+			wantErr:      `member "user:alice@example.com": failed to get user`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			var mu sync.Mutex
+			var gotRequests []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				email := path.Base(r.URL.Path)
+				gotRequests = append(gotRequests, email)
+				if tc.status != 0 {
+					w.WriteHeader(tc.status)
+					fmt.Fprint(w, `{"error": {"code": 403, "message": "Not Authorized to access this resource/api"}}`)
+					return
+				}
+				// Emails in the directory are case-insensitive.
+				u, ok := users[strings.ToLower(email)]
+				if !ok {
+					w.WriteHeader(http.StatusNotFound)
+					fmt.Fprint(w, `{"error": {"code": 404, "message": "Resource Not Found: userKey"}}`)
+					return
+				}
+				fmt.Fprint(w, u)
+			}))
+			t.Cleanup(srv.Close)
+
+			c, err := NewChecker(ctx, []string{"Example.com"},
+				option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotErr := c.CheckMembers(ctx, tc.members)
+			if diff := testutil.DiffErrString(gotErr, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error: %s", tc.name, diff)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			slices.Sort(gotRequests)
+			if diff := cmp.Diff(tc.wantRequests, gotRequests); diff != "" {
+				t.Errorf("Process(%+v) got requests diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestNewChecker_NoDomains(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewChecker(context.Background(), nil, option.WithoutAuthentication())
+	if diff := testutil.DiffErrString(err, "at least one domain is required"); diff != "" {
+		t.Errorf("got unexpected error: %s", diff)
+	}
+}
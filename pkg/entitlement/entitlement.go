@@ -0,0 +1,201 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package entitlement expands the references to named entitlements in IAM
+// requests, such as "bq-oncall", to the policies of their roles on their
+// resources with an entitlement catalog.
+package entitlement
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/resource"
+)
+
+// nameRegex matches the names of entitlements, e.g. "bq-oncall".
+var nameRegex = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// Entitlement is a named bundle of roles on resources, defined by the admins
+// of the AOD deployment.
+type Entitlement struct {
+	// Description of the entitlement.
+	Description string `yaml:"description,omitempty"`
+
+	// Resources the roles are granted on, e.g. "projects/foo".
+	Resources []string `yaml:"resources"`
+
+	// Roles granted on each of the resources.
+	Roles []string `yaml:"roles"`
+
+	// MaxDuration is the max duration the entitlement can be requested for,
+	// e.g. "4h". There is no limit if it is 0.
+	MaxDuration time.Duration `yaml:"maxDuration,omitempty"`
+}
+
+// Catalog maps the names of the entitlements to their definitions.
+type Catalog map[string]*Entitlement
+
+// Read reads and checks the entitlement catalog file at the path.
+func Read(path string) (Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read entitlement catalog file %q: %w", path, err)
+	}
+	c, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid entitlement catalog file %q: %w", path, err)
+	}
+	return c, nil
+}
+
+// Parse parses and checks the entitlement catalog in YAML format, e.g.
+//
+//	bq-oncall:
+//	  resources:
+//	  - projects/foo
+//	  roles:
+//	  - roles/bigquery.dataViewer
+//	  - roles/bigquery.jobUser
+//	  maxDuration: 4h
+//
+// Unknown fields are errors, so that misspelled entitlements are not silently
+// ignored.
+func Parse(data []byte) (Catalog, error) {
+	var c Catalog
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to unmarshal yaml to %T: %w", c, err)
+	}
+	if c == nil {
+		c = Catalog{}
+	}
+
+	var retErr error
+	for _, name := range slices.Sorted(maps.Keys(c)) {
+		if !nameRegex.MatchString(name) {
+			retErr = errors.Join(retErr, fmt.Errorf("invalid entitlement name %q, must match %s", name, nameRegex))
+			continue
+		}
+		if err := c[name].check(); err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("entitlement %q: %w", name, err))
+		}
+	}
+	if retErr != nil {
+		return nil, retErr
+	}
+	return c, nil
+}
+
+// check returns an error if the entitlement has no resources or roles, or its
+// resources are invalid.
+func (e *Entitlement) check() (retErr error) {
+	if e == nil {
+		return fmt.Errorf("entitlement is empty")
+	}
+	if len(e.Resources) == 0 {
+		retErr = errors.Join(retErr, fmt.Errorf("resources are required"))
+	}
+	for _, r := range e.Resources {
+		if _, err := resource.Parse(r); err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("invalid resource %q: %w", r, err))
+		}
+	}
+	if len(e.Roles) == 0 {
+		retErr = errors.Join(retErr, fmt.Errorf("roles are required"))
+	}
+	if e.MaxDuration < 0 {
+		retErr = errors.Join(retErr, fmt.Errorf("max duration must not be negative, got %s", e.MaxDuration))
+	}
+	return retErr
+}
+
+// Expand replaces the entitlement references of the request with a policy of
+// each resource of the entitlements, binding the members to the roles of the
+// entitlement. The request is handled with the duration, or 0 if it is not
+// known, which must not exceed the requested duration of the references, nor
+// the max duration of the entitlements. The errors are v1alpha1.FieldErrors,
+// so that they can be annotated with their positions in the request file. The
+// references are kept if the catalog is nil, and fail validation.
+func (c Catalog) Expand(req *v1alpha1.IAMRequest, duration time.Duration) error {
+	if c == nil || len(req.Entitlements) == 0 {
+		return nil
+	}
+
+	var retErr error
+	var policies []*v1alpha1.ResourcePolicy
+	for i, ref := range req.Entitlements {
+		path := fmt.Sprintf("entitlements[%d]", i)
+		e, ok := c[ref.Entitlement]
+		if !ok {
+			retErr = errors.Join(retErr, &v1alpha1.FieldError{
+				Path: path + ".entitlement",
+				Err:  fmt.Errorf("unknown entitlement %q, must be one of %q", ref.Entitlement, slices.Sorted(maps.Keys(c))),
+			})
+			continue
+		}
+		if len(ref.Members) == 0 {
+			retErr = errors.Join(retErr, &v1alpha1.FieldError{Path: path + ".members", Err: fmt.Errorf("members are required")})
+		}
+		if err := checkDuration(ref, e, duration); err != nil {
+			retErr = errors.Join(retErr, &v1alpha1.FieldError{Path: path + ".duration", Err: err})
+		}
+		for _, r := range e.Resources {
+			p := &v1alpha1.ResourcePolicy{Resource: r}
+			for _, role := range e.Roles {
+				p.Bindings = append(p.Bindings, &v1alpha1.Binding{
+					Members: slices.Clone(ref.Members),
+					Role:    role,
+				})
+			}
+			policies = append(policies, p)
+		}
+	}
+	if retErr != nil {
+		return retErr
+	}
+
+	req.ResourcePolicies = append(req.ResourcePolicies, policies...)
+	req.Entitlements = nil
+	return nil
+}
+
+// checkDuration returns an error if the duration the request is handled with
+// exceeds the requested duration of the reference, or either exceeds the max
+// duration of the entitlement.
+func checkDuration(ref *v1alpha1.EntitlementRef, e *Entitlement, duration time.Duration) error {
+	if ref.Duration < 0 {
+		return fmt.Errorf("duration must not be negative, got %s", ref.Duration)
+	}
+	if ref.Duration > 0 && duration > ref.Duration {
+		return fmt.Errorf("request is handled with duration %s, longer than the requested duration %s of entitlement %q",
+			duration, ref.Duration, ref.Entitlement)
+	}
+	d := max(duration, ref.Duration)
+	if e.MaxDuration > 0 && d > e.MaxDuration {
+		return fmt.Errorf("duration %s exceeds the max duration %s of entitlement %q", d, e.MaxDuration, ref.Entitlement)
+	}
+	return nil
+}
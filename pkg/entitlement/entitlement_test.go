@@ -0,0 +1,281 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entitlement
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		data       string
+		expCatalog Catalog
+		expErr     string
+	}{
+		{
+			name: "success",
+			data: `
+bq-oncall:
+  description: BigQuery on-call access.
+  resources:
+  - projects/foo
+  - projects/bar
+  roles:
+  - roles/bigquery.dataViewer
+  - roles/bigquery.jobUser
+  maxDuration: 4h
+`,
+			expCatalog: Catalog{
+				"bq-oncall": {
+					Description: "BigQuery on-call access.",
+					Resources:   []string{"projects/foo", "projects/bar"},
+					Roles:       []string{"roles/bigquery.dataViewer", "roles/bigquery.jobUser"},
+					MaxDuration: 4 * time.Hour,
+				},
+			},
+		},
+		{
+			name:       "empty",
+			data:       "",
+			expCatalog: Catalog{},
+		},
+		{
+			name:   "invalid_name",
+			data:   "BQ:\n  resources: [projects/foo]\n  roles: [roles/viewer]\n",
+			expErr: `invalid entitlement name "BQ"`,
+		},
+		{
+			name:   "unknown_field",
+			data:   "bq-oncall:\n  resources: [projects/foo]\n  role: roles/viewer\n",
+			expErr: "field role not found",
+		},
+		{
+			name:   "invalid_entitlement",
+			data:   "bq-oncall:\n  resources: [bananas]\n  roles: [roles/viewer]\n",
+			expErr: `entitlement "bq-oncall": invalid resource "bananas"`,
+		},
+		{
+			name:   "negative_max_duration",
+			data:   "bq-oncall:\n  resources: [projects/foo]\n  roles: [roles/viewer]\n  maxDuration: -1h\n",
+			expErr: `entitlement "bq-oncall": max duration must not be negative, got -1h0m0s`,
+		},
+		{
+			name:   "missing_roles",
+			data:   "bq-oncall:\n  resources: [projects/foo]\n",
+			expErr: `entitlement "bq-oncall": roles are required`,
+		},
+		{
+			name:   "empty_entitlement",
+			data:   "bq-oncall:\n",
+			expErr: `entitlement "bq-oncall": entitlement is empty`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Parse([]byte(tc.data))
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expCatalog, got); diff != "" {
+				t.Errorf("Process(%+v) got catalog diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "entitlements.yaml")
+	if err := os.WriteFile(path, []byte("bq-oncall:\n  resources: [projects/foo]\n  roles: [roles/viewer]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read got unexpected error: %v", err)
+	}
+	if _, ok := c["bq-oncall"]; !ok {
+		t.Errorf("Read got catalog %v, want bq-oncall", c)
+	}
+
+	_, err = Read(filepath.Join(dir, "missing.yaml"))
+	if diff := testutil.DiffErrString(err, "failed to read entitlement catalog file"); diff != "" {
+		t.Errorf("Read got error diff (-want, +got):\n%s", diff)
+	}
+}
+
+func TestCatalog_Expand(t *testing.T) {
+	t.Parallel()
+
+	catalog := Catalog{
+		"bq-oncall": {
+			Resources:   []string{"projects/foo", "projects/bar"},
+			Roles:       []string{"roles/bigquery.dataViewer", "roles/bigquery.jobUser"},
+			MaxDuration: 4 * time.Hour,
+		},
+		"viewer": {
+			Resources: []string{"folders/123"},
+			Roles:     []string{"roles/viewer"},
+		},
+	}
+
+	cases := []struct {
+		name     string
+		catalog  Catalog
+		req      *v1alpha1.IAMRequest
+		duration time.Duration
+		expReq   *v1alpha1.IAMRequest
+		expErr   string
+	}{
+		{
+			name:    "success",
+			catalog: catalog,
+			req: &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+					Resource: "projects/baz",
+					Bindings: []*v1alpha1.Binding{{Members: []string{"user:bob@example.com"}, Role: "roles/viewer"}},
+				}},
+				Entitlements: []*v1alpha1.EntitlementRef{
+					{Entitlement: "bq-oncall", Members: []string{"user:alice@example.com"}, Duration: 2 * time.Hour},
+					{Entitlement: "viewer", Members: []string{"user:bob@example.com"}},
+				},
+			},
+			duration: 2 * time.Hour,
+			expReq: &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{
+					{
+						Resource: "projects/baz",
+						Bindings: []*v1alpha1.Binding{{Members: []string{"user:bob@example.com"}, Role: "roles/viewer"}},
+					},
+					{
+						Resource: "projects/foo",
+						Bindings: []*v1alpha1.Binding{
+							{Members: []string{"user:alice@example.com"}, Role: "roles/bigquery.dataViewer"},
+							{Members: []string{"user:alice@example.com"}, Role: "roles/bigquery.jobUser"},
+						},
+					},
+					{
+						Resource: "projects/bar",
+						Bindings: []*v1alpha1.Binding{
+							{Members: []string{"user:alice@example.com"}, Role: "roles/bigquery.dataViewer"},
+							{Members: []string{"user:alice@example.com"}, Role: "roles/bigquery.jobUser"},
+						},
+					},
+					{
+						Resource: "folders/123",
+						Bindings: []*v1alpha1.Binding{{Members: []string{"user:bob@example.com"}, Role: "roles/viewer"}},
+					},
+				},
+			},
+		},
+		{
+			name:    "no_catalog",
+			catalog: nil,
+			req: &v1alpha1.IAMRequest{
+				Entitlements: []*v1alpha1.EntitlementRef{{Entitlement: "viewer", Members: []string{"user:bob@example.com"}}},
+			},
+			expReq: &v1alpha1.IAMRequest{
+				Entitlements: []*v1alpha1.EntitlementRef{{Entitlement: "viewer", Members: []string{"user:bob@example.com"}}},
+			},
+		},
+		{
+			name:    "unknown_entitlement",
+			catalog: catalog,
+			req: &v1alpha1.IAMRequest{
+				Entitlements: []*v1alpha1.EntitlementRef{{Entitlement: "admin", Members: []string{"user:bob@example.com"}}},
+			},
+			expReq: &v1alpha1.IAMRequest{
+				Entitlements: []*v1alpha1.EntitlementRef{{Entitlement: "admin", Members: []string{"user:bob@example.com"}}},
+			},
+			expErr: `entitlements[0].entitlement: unknown entitlement "admin", must be one of ["bq-oncall" "viewer"]`,
+		},
+		{
+			name:    "missing_members",
+			catalog: catalog,
+			req: &v1alpha1.IAMRequest{
+				Entitlements: []*v1alpha1.EntitlementRef{{Entitlement: "viewer"}},
+			},
+			expReq: &v1alpha1.IAMRequest{
+				Entitlements: []*v1alpha1.EntitlementRef{{Entitlement: "viewer"}},
+			},
+			expErr: "entitlements[0].members: members are required",
+		},
+		{
+			name:    "exceeds_max_duration",
+			catalog: catalog,
+			req: &v1alpha1.IAMRequest{
+				Entitlements: []*v1alpha1.EntitlementRef{{Entitlement: "bq-oncall", Members: []string{"user:alice@example.com"}, Duration: 8 * time.Hour}},
+			},
+			expReq: &v1alpha1.IAMRequest{
+				Entitlements: []*v1alpha1.EntitlementRef{{Entitlement: "bq-oncall", Members: []string{"user:alice@example.com"}, Duration: 8 * time.Hour}},
+			},
+			expErr: `entitlements[0].duration: duration 8h0m0s exceeds the max duration 4h0m0s of entitlement "bq-oncall"`,
+		},
+		{
+			name:    "handled_longer_than_requested",
+			catalog: catalog,
+			req: &v1alpha1.IAMRequest{
+				Entitlements: []*v1alpha1.EntitlementRef{{Entitlement: "bq-oncall", Members: []string{"user:alice@example.com"}, Duration: time.Hour}},
+			},
+			duration: 2 * time.Hour,
+			expReq: &v1alpha1.IAMRequest{
+				Entitlements: []*v1alpha1.EntitlementRef{{Entitlement: "bq-oncall", Members: []string{"user:alice@example.com"}, Duration: time.Hour}},
+			},
+			expErr: `entitlements[0].duration: request is handled with duration 2h0m0s, longer than the requested duration 1h0m0s of entitlement "bq-oncall"`,
+		},
+		{
+			name:    "handled_longer_than_max",
+			catalog: catalog,
+			req: &v1alpha1.IAMRequest{
+				Entitlements: []*v1alpha1.EntitlementRef{{Entitlement: "bq-oncall", Members: []string{"user:alice@example.com"}}},
+			},
+			duration: 5 * time.Hour,
+			expReq: &v1alpha1.IAMRequest{
+				Entitlements: []*v1alpha1.EntitlementRef{{Entitlement: "bq-oncall", Members: []string{"user:alice@example.com"}}},
+			},
+			expErr: `entitlements[0].duration: duration 5h0m0s exceeds the max duration 4h0m0s of entitlement "bq-oncall"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.catalog.Expand(tc.req, tc.duration)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expReq, tc.req); diff != "" {
+				t.Errorf("Process(%+v) got request diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
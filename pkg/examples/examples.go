@@ -0,0 +1,108 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package examples bundles curated example request files, to be printed or
+// written as a starting point for new requests.
+package examples
+
+import (
+	"embed"
+	"fmt"
+)
+
+//go:embed files/*.yaml
+var files embed.FS
+
+// Kinds of the example requests.
+const (
+	KindIAM      = "iam"
+	KindTool     = "tool"
+	KindCombined = "combined"
+)
+
+// Example is a bundled example request file.
+type Example struct {
+	// Name of the example, which is also the name of its file without the
+	// ".yaml" extension.
+	Name string
+
+	// Description of the example.
+	Description string
+
+	// Kind of the request of the example, one of KindIAM, KindTool and
+	// KindCombined.
+	Kind string
+}
+
+// all are the bundled examples, in the order they are listed.
+var all = []*Example{
+	{
+		Name:        "iam",
+		Description: "IAM request granting a role to members on a project",
+		Kind:        KindIAM,
+	},
+	{
+		Name:        "tool",
+		Description: "Tool request running gcloud commands",
+		Kind:        KindTool,
+	},
+	{
+		Name:        "combined",
+		Description: "Combined request granting IAM bindings and running the tool commands needing them",
+		Kind:        KindCombined,
+	},
+	{
+		Name:        "multi-resource",
+		Description: "IAM request granting roles on an organization, a folder and projects",
+		Kind:        KindIAM,
+	},
+}
+
+// All returns all the bundled examples.
+func All() []*Example {
+	return all
+}
+
+// Names returns the names of all the bundled examples.
+func Names() []string {
+	names := make([]string, 0, len(all))
+	for _, e := range all {
+		names = append(names, e.Name)
+	}
+	return names
+}
+
+// Lookup returns the example of the given name.
+func Lookup(name string) (*Example, bool) {
+	for _, e := range all {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// FileName returns the name of the file of the example.
+func (e *Example) FileName() string {
+	return e.Name + ".yaml"
+}
+
+// Content returns the content of the file of the example.
+func (e *Example) Content() ([]byte, error) {
+	b, err := files.ReadFile("files/" + e.FileName())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read example %q: %w", e.Name, err)
+	}
+	return b, nil
+}
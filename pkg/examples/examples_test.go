@@ -0,0 +1,104 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package examples
+
+import (
+	"bytes"
+	"io/fs"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"gopkg.in/yaml.v3"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+)
+
+func TestExamples(t *testing.T) {
+	t.Parallel()
+
+	for _, e := range All() {
+		t.Run(e.Name, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := e.Content()
+			if err != nil {
+				t.Fatal(err)
+			}
+			dec := yaml.NewDecoder(bytes.NewReader(b))
+			dec.KnownFields(true)
+
+			switch e.Kind {
+			case KindIAM:
+				var req v1alpha1.IAMRequest
+				if err := dec.Decode(&req); err != nil {
+					t.Fatalf("failed to decode example %q: %v", e.Name, err)
+				}
+				if err := v1alpha1.ValidateIAMRequest(&req, nil); err != nil {
+					t.Errorf("example %q is not valid: %v", e.Name, err)
+				}
+			case KindTool:
+				var req v1alpha1.ToolRequest
+				if err := dec.Decode(&req); err != nil {
+					t.Fatalf("failed to decode example %q: %v", e.Name, err)
+				}
+				if err := v1alpha1.ValidateToolRequest(&req, nil); err != nil {
+					t.Errorf("example %q is not valid: %v", e.Name, err)
+				}
+			case KindCombined:
+				var req v1alpha1.CombinedRequest
+				if err := dec.Decode(&req); err != nil {
+					t.Fatalf("failed to decode example %q: %v", e.Name, err)
+				}
+				if err := v1alpha1.ValidateCombinedRequest(&req, nil); err != nil {
+					t.Errorf("example %q is not valid: %v", e.Name, err)
+				}
+			default:
+				t.Errorf("example %q has unknown kind %q", e.Name, e.Kind)
+			}
+		})
+	}
+}
+
+func TestExamples_AllFilesListed(t *testing.T) {
+	t.Parallel()
+
+	entries, err := fs.ReadDir(files, "files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, 0, len(entries))
+	for _, e := range entries {
+		got = append(got, e.Name())
+	}
+	want := make([]string, 0, len(all))
+	for _, e := range All() {
+		want = append(want, e.FileName())
+	}
+	if diff := cmp.Diff(want, got, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+		t.Errorf("got example files diff (-want, +got):\n%s", diff)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	t.Parallel()
+
+	if e, ok := Lookup("combined"); !ok || e.Kind != KindCombined {
+		t.Errorf("Lookup(%q) got (%+v, %t), want the combined example", "combined", e, ok)
+	}
+	if _, ok := Lookup("bananas"); ok {
+		t.Errorf("Lookup(%q) got ok, want not found", "bananas")
+	}
+}
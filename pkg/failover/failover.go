@@ -0,0 +1,280 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package failover fails IAM calls over between the endpoints of an API, so
+// that a regional API disruption doesn't block urgent cleanups. Each endpoint
+// has a circuit breaker, which skips the endpoint for a cooldown after
+// consecutive failures.
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/abcxyz/access-on-demand/pkg/handler"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	// DefaultThreshold is the default number of consecutive failures opening
+	// the circuit of an endpoint.
+	DefaultThreshold = 3
+
+	// DefaultCooldown is the default duration the circuit of an endpoint stays
+	// open, before the endpoint is tried again.
+	DefaultCooldown = time.Minute
+)
+
+// Endpoint is the IAM client of an API endpoint.
+type Endpoint struct {
+	// Name of the endpoint, e.g. "cloudresourcemanager.googleapis.com:443".
+	Name string
+
+	// Client calling the endpoint.
+	Client handler.IAMClient
+}
+
+// endpoint is an Endpoint with the state of its circuit breaker.
+type endpoint struct {
+	*Endpoint
+
+	// failures is the number of consecutive failures of the endpoint.
+	failures int
+
+	// openUntil is the time until which the circuit is open, the endpoint is
+	// skipped while other endpoints are available.
+	openUntil time.Time
+}
+
+// Client is an IAMClient calling the first available of its endpoints, the
+// following endpoints are called in order if the endpoint is unavailable.
+type Client struct {
+	endpoints []*endpoint
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu sync.Mutex
+	// failovers is the number of calls served by each fallback endpoint.
+	failovers map[string]int
+}
+
+// Option is the option to set up a Client.
+type Option func(c *Client) (*Client, error)
+
+// WithThreshold sets the number of consecutive failures opening the circuit of
+// an endpoint.
+func WithThreshold(n int) Option {
+	return func(c *Client) (*Client, error) {
+		if n <= 0 {
+			return nil, fmt.Errorf("threshold must be positive, got %d", n)
+		}
+		c.threshold = n
+		return c, nil
+	}
+}
+
+// WithCooldown sets the duration the circuit of an endpoint stays open.
+func WithCooldown(d time.Duration) Option {
+	return func(c *Client) (*Client, error) {
+		if d <= 0 {
+			return nil, fmt.Errorf("cooldown must be positive, got %s", d)
+		}
+		c.cooldown = d
+		return c, nil
+	}
+}
+
+// WithNowFunc overrides the function returning the current time, for tests.
+func WithNowFunc(now func() time.Time) Option {
+	return func(c *Client) (*Client, error) {
+		c.now = now
+		return c, nil
+	}
+}
+
+// New creates a Client of the endpoints, the first endpoint is the primary one
+// and the others are fallbacks in order.
+func New(endpoints []*Endpoint, opts ...Option) (*Client, error) {
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("at least one endpoint is required")
+	}
+	c := &Client{
+		threshold: DefaultThreshold,
+		cooldown:  DefaultCooldown,
+		now:       time.Now,
+		failovers: make(map[string]int),
+	}
+	for _, e := range endpoints {
+		c.endpoints = append(c.endpoints, &endpoint{Endpoint: e})
+	}
+	for _, opt := range opts {
+		var err error
+		if c, err = opt(c); err != nil {
+			return nil, fmt.Errorf("failed to apply client options: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// GetIamPolicy gets the IAM policy from the first available endpoint.
+func (c *Client) GetIamPolicy(ctx context.Context, req *iampb.GetIamPolicyRequest, opts ...gax.CallOption) (*iampb.Policy, error) {
+	return c.do(ctx, func(ic handler.IAMClient) (*iampb.Policy, error) {
+		return ic.GetIamPolicy(ctx, req, opts...) //nolint:wrapcheck // Want passthrough
+	})
+}
+
+// SetIamPolicy sets the IAM policy with the first available endpoint. A set
+// failing over is safe, as the etag of the policy rejects it if an unavailable
+// endpoint applied it after all.
+func (c *Client) SetIamPolicy(ctx context.Context, req *iampb.SetIamPolicyRequest, opts ...gax.CallOption) (*iampb.Policy, error) {
+	return c.do(ctx, func(ic handler.IAMClient) (*iampb.Policy, error) {
+		return ic.SetIamPolicy(ctx, req, opts...) //nolint:wrapcheck // Want passthrough
+	})
+}
+
+// Degraded returns whether a call was served by a fallback endpoint, or the
+// circuit of an endpoint is open.
+func (c *Client) Degraded() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.failovers) > 0 || len(c.openCircuits()) > 0
+}
+
+// Failovers returns the number of calls served by each fallback endpoint.
+func (c *Client) Failovers() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return maps.Clone(c.failovers)
+}
+
+// OpenCircuits returns the names of the endpoints whose circuits are open.
+func (c *Client) OpenCircuits() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.openCircuits()
+}
+
+func (c *Client) openCircuits() []string {
+	now := c.now()
+	var result []string
+	for _, e := range c.endpoints {
+		if e.openUntil.After(now) {
+			result = append(result, e.Name)
+		}
+	}
+	return result
+}
+
+// do calls the endpoints in order until one of them is available. Endpoints
+// with open circuits are only called after all the others failed, so a call
+// is never refused without trying every endpoint.
+func (c *Client) do(ctx context.Context, call func(handler.IAMClient) (*iampb.Policy, error)) (*iampb.Policy, error) {
+	logger := logging.FromContext(ctx)
+
+	var errs []error
+	for _, e := range c.candidates() {
+		p, err := call(e.Client)
+		if err == nil || !Failover(err) {
+			// Any response other than an unavailable endpoint means the
+			// endpoint is healthy.
+			c.succeed(e, e != c.endpoints[0])
+			return p, err
+		}
+		errs = append(errs, fmt.Errorf("endpoint %q: %w", e.Name, err))
+		if ctx.Err() != nil {
+			break
+		}
+		if c.fail(e) {
+			logger.WarnContext(ctx, "circuit of endpoint opened, failing over",
+				"endpoint", e.Name,
+				"cooldown", c.cooldown,
+				"error", err)
+		} else {
+			logger.WarnContext(ctx, "endpoint unavailable, failing over",
+				"endpoint", e.Name,
+				"error", err)
+		}
+	}
+	return nil, fmt.Errorf("all endpoints are unavailable: %w", errors.Join(errs...))
+}
+
+// candidates returns the endpoints to call in order, the endpoints with closed
+// circuits are followed by the ones with open circuits.
+func (c *Client) candidates() []*endpoint {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	closed := make([]*endpoint, 0, len(c.endpoints))
+	var open []*endpoint
+	for _, e := range c.endpoints {
+		if e.openUntil.After(now) {
+			open = append(open, e)
+		} else {
+			closed = append(closed, e)
+		}
+	}
+	return slices.Concat(closed, open)
+}
+
+// succeed closes the circuit of the endpoint, and counts the call if it was
+// served by a fallback endpoint.
+func (c *Client) succeed(e *endpoint, fallback bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e.failures = 0
+	e.openUntil = time.Time{}
+	if fallback {
+		c.failovers[e.Name]++
+	}
+}
+
+// fail counts a failure of the endpoint, and returns whether it opened the
+// circuit of the endpoint.
+func (c *Client) fail(e *endpoint) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e.failures++
+	if e.failures < c.threshold {
+		return false
+	}
+	// Every failure after the threshold, such as a failed trial call after the
+	// cooldown, keeps the circuit open for another cooldown.
+	e.openUntil = c.now().Add(c.cooldown)
+	return e.failures == c.threshold
+}
+
+// Failover returns whether the error means the endpoint is unavailable, and
+// the call should be failed over to the next endpoint.
+func Failover(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package failover
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+// fakeIAMClient returns the errors of its calls in order, and the policy for
+// nil errors and once the errors are used up.
+type fakeIAMClient struct {
+	policy *iampb.Policy
+	errs   []error
+	calls  int
+}
+
+func (c *fakeIAMClient) GetIamPolicy(context.Context, *iampb.GetIamPolicyRequest, ...gax.CallOption) (*iampb.Policy, error) {
+	return c.call()
+}
+
+func (c *fakeIAMClient) SetIamPolicy(context.Context, *iampb.SetIamPolicyRequest, ...gax.CallOption) (*iampb.Policy, error) {
+	return c.call()
+}
+
+func (c *fakeIAMClient) call() (*iampb.Policy, error) {
+	c.calls++
+	if c.calls <= len(c.errs) && c.errs[c.calls-1] != nil {
+		return nil, c.errs[c.calls-1]
+	}
+	return c.policy, nil
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	primaryPolicy := &iampb.Policy{Etag: []byte("primary")}
+	fallbackPolicy := &iampb.Policy{Etag: []byte("fallback")}
+	unavailable := status.Error(codes.Unavailable, "regional outage")
+	timeout := status.Error(codes.DeadlineExceeded, "timed out")
+
+	cases := []struct {
+		name             string
+		primaryErrs      []error
+		fallbackErrs     []error
+		calls            int
+		wantPolicy       *iampb.Policy
+		wantErr          string
+		wantPrimaryCalls int
+		wantFailovers    map[string]int
+		wantOpen         []string
+	}{
+		{
+			name:             "primary_available",
+			calls:            2,
+			wantPolicy:       primaryPolicy,
+			wantPrimaryCalls: 2,
+			wantFailovers:    map[string]int{},
+		},
+		{
+			name:             "fail_over_unavailable",
+			primaryErrs:      []error{unavailable},
+			calls:            1,
+			wantPolicy:       fallbackPolicy,
+			wantPrimaryCalls: 1,
+			wantFailovers:    map[string]int{"fallback": 1},
+		},
+		{
+			name:             "fail_over_deadline_exceeded",
+			primaryErrs:      []error{timeout},
+			calls:            1,
+			wantPolicy:       fallbackPolicy,
+			wantPrimaryCalls: 1,
+			wantFailovers:    map[string]int{"fallback": 1},
+		},
+		{
+			name:             "no_fail_over_other_errors",
+			primaryErrs:      []error{status.Error(codes.PermissionDenied, "denied")},
+			calls:            1,
+			wantErr:          "denied",
+			wantPrimaryCalls: 1,
+			wantFailovers:    map[string]int{},
+		},
+		{
+			name:             "primary_recovered",
+			primaryErrs:      []error{unavailable},
+			calls:            2,
+			wantPolicy:       primaryPolicy,
+			wantPrimaryCalls: 2,
+			wantFailovers:    map[string]int{"fallback": 1},
+		},
+		{
+			name:             "circuit_open",
+			primaryErrs:      []error{unavailable, unavailable},
+			calls:            4,
+			wantPolicy:       fallbackPolicy,
+			wantPrimaryCalls: 2,
+			wantFailovers:    map[string]int{"fallback": 4},
+			wantOpen:         []string{"primary"},
+		},
+		{
+			name:             "all_unavailable",
+			primaryErrs:      []error{unavailable},
+			fallbackErrs:     []error{timeout},
+			calls:            1,
+			wantErr:          `all endpoints are unavailable: endpoint "primary": rpc error: code = Unavailable desc = regional outage`,
+			wantPrimaryCalls: 1,
+			wantFailovers:    map[string]int{},
+		},
+		{
+			name:             "open_circuit_tried_last",
+			primaryErrs:      []error{unavailable, unavailable},
+			fallbackErrs:     []error{nil, nil, unavailable},
+			calls:            3,
+			wantPolicy:       primaryPolicy,
+			wantPrimaryCalls: 3,
+			wantFailovers:    map[string]int{"fallback": 2},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			primary := &fakeIAMClient{policy: primaryPolicy, errs: tc.primaryErrs}
+			fallback := &fakeIAMClient{policy: fallbackPolicy, errs: tc.fallbackErrs}
+			c, err := New([]*Endpoint{
+				{Name: "primary", Client: primary},
+				{Name: "fallback", Client: fallback},
+			}, WithThreshold(2), WithNowFunc(func() time.Time {
+				return time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got *iampb.Policy
+			for i := 0; i < tc.calls; i++ {
+				if i%2 == 0 {
+					got, err = c.GetIamPolicy(context.Background(), &iampb.GetIamPolicyRequest{})
+				} else {
+					got, err = c.SetIamPolicy(context.Background(), &iampb.SetIamPolicyRequest{})
+				}
+			}
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Client call got unexpected error substring: %v", diff)
+			}
+			if diff := cmp.Diff(tc.wantPolicy, got, protocmp.Transform()); diff != "" {
+				t.Errorf("Client call got policy diff (-want, +got): %v", diff)
+			}
+			if primary.calls != tc.wantPrimaryCalls {
+				t.Errorf("Client called primary endpoint %d times, want %d", primary.calls, tc.wantPrimaryCalls)
+			}
+			if diff := cmp.Diff(tc.wantFailovers, c.Failovers()); diff != "" {
+				t.Errorf("Failovers() got diff (-want, +got): %v", diff)
+			}
+			if diff := cmp.Diff(tc.wantOpen, c.OpenCircuits()); diff != "" {
+				t.Errorf("OpenCircuits() got diff (-want, +got): %v", diff)
+			}
+			if got, want := c.Degraded(), len(tc.wantFailovers) > 0 || len(tc.wantOpen) > 0; got != want {
+				t.Errorf("Degraded() got %t, want %t", got, want)
+			}
+		})
+	}
+}
+
+func TestClient_CircuitCooldown(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	unavailable := status.Error(codes.Unavailable, "regional outage")
+	primary := &fakeIAMClient{policy: &iampb.Policy{}, errs: []error{unavailable}}
+	fallback := &fakeIAMClient{policy: &iampb.Policy{}}
+	c, err := New([]*Endpoint{
+		{Name: "primary", Client: primary},
+		{Name: "fallback", Client: fallback},
+	}, WithThreshold(1), WithCooldown(time.Minute), WithNowFunc(func() time.Time { return now }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetIamPolicy(ctx, &iampb.GetIamPolicyRequest{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := primary.calls, 1; got != want {
+		t.Errorf("primary endpoint got %d calls with open circuit, want %d", got, want)
+	}
+
+	// The primary endpoint is tried again after the cooldown.
+	now = now.Add(time.Minute)
+	if _, err := c.GetIamPolicy(ctx, &iampb.GetIamPolicyRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := primary.calls, 2; got != want {
+		t.Errorf("primary endpoint got %d calls after cooldown, want %d", got, want)
+	}
+	if got := c.OpenCircuits(); len(got) != 0 {
+		t.Errorf("OpenCircuits() got %q after recovery, want none", got)
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		endpoints []*Endpoint
+		opts      []Option
+		wantErr   string
+	}{
+		{
+			name:      "success",
+			endpoints: []*Endpoint{{Name: "primary", Client: &fakeIAMClient{}}},
+			opts:      []Option{WithThreshold(1), WithCooldown(time.Second)},
+		},
+		{
+			name:    "no_endpoints",
+			wantErr: "at least one endpoint is required",
+		},
+		{
+			name:      "invalid_threshold",
+			endpoints: []*Endpoint{{Name: "primary", Client: &fakeIAMClient{}}},
+			opts:      []Option{WithThreshold(0)},
+			wantErr:   "threshold must be positive, got 0",
+		},
+		{
+			name:      "invalid_cooldown",
+			endpoints: []*Endpoint{{Name: "primary", Client: &fakeIAMClient{}}},
+			opts:      []Option{WithCooldown(-time.Second)},
+			wantErr:   "cooldown must be positive, got -1s",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := New(tc.endpoints, tc.opts...)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("New(%+v) got unexpected error substring: %v", tc.endpoints, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package genericiam gets and sets the IAM policies of resources named by
+// their full resource names, such as
+// "//pubsub.googleapis.com/projects/foo/topics/bar", through the standard
+// IAMPolicy API of their services, so that resources of services without a
+// dedicated client are handled like projects.
+package genericiam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/api/option"
+	gtransport "google.golang.org/api/transport/grpc"
+	"google.golang.org/grpc"
+
+	"github.com/abcxyz/access-on-demand/pkg/iamdelta"
+	"github.com/abcxyz/access-on-demand/pkg/resource"
+)
+
+// scope is the OAuth scope of the IAMPolicy API of all services.
+const scope = "https://www.googleapis.com/auth/cloud-platform"
+
+// Client gets and sets the IAM policies of resources named by their full
+// resource names. The IAMPolicy API of a service is called at the endpoint
+// configured for the service, or at "<service>:443" by default.
+type Client struct {
+	endpoints map[string]string
+	opts      []option.ClientOption
+
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+// NewClient creates a new Client with the endpoints by service, such as
+// "pubsub.googleapis.com", and the options of all connections. Connections
+// are dialed on the first call to each service.
+func NewClient(endpoints map[string]string, opts ...option.ClientOption) *Client {
+	return &Client{
+		endpoints: endpoints,
+		opts:      opts,
+		conns:     make(map[string]*grpc.ClientConn),
+	}
+}
+
+// GetIamPolicy gets the IAM policy of the resource.
+func (c *Client) GetIamPolicy(ctx context.Context, req *iampb.GetIamPolicyRequest, _ ...gax.CallOption) (*iampb.Policy, error) {
+	ic, relative, err := c.client(ctx, req.GetResource())
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := ic.GetIamPolicy(ctx, &iampb.GetIamPolicyRequest{
+		Resource: relative,
+		Options:  req.GetOptions(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IAM policy of resource %q: %w", req.GetResource(), err)
+	}
+	return p, nil
+}
+
+// SetIamPolicy sets the IAM policy of the resource.
+func (c *Client) SetIamPolicy(ctx context.Context, req *iampb.SetIamPolicyRequest, _ ...gax.CallOption) (*iampb.Policy, error) {
+	ic, relative, err := c.client(ctx, req.GetResource())
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := ic.SetIamPolicy(ctx, &iampb.SetIamPolicyRequest{
+		Resource:   relative,
+		Policy:     req.GetPolicy(),
+		UpdateMask: req.GetUpdateMask(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set IAM policy of resource %q: %w", req.GetResource(), err)
+	}
+	return p, nil
+}
+
+// PatchIamPolicy applies the changes to the bindings to the latest IAM policy
+// of the resource.
+func (c *Client) PatchIamPolicy(ctx context.Context, resource string, d *iamdelta.Delta) (*iampb.Policy, error) {
+	return iamdelta.Patch(ctx, c, resource, d) //nolint:wrapcheck // Want passthrough
+}
+
+// Close closes the connections to all services.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var merr error
+	for service, conn := range c.conns {
+		if err := conn.Close(); err != nil {
+			merr = errors.Join(merr, fmt.Errorf("failed to close connection to %s: %w", service, err))
+		}
+	}
+	clear(c.conns)
+	return merr
+}
+
+// client returns the IAMPolicy client of the service of the full resource
+// name, and the relative resource name in the service.
+func (c *Client) client(ctx context.Context, name string) (iampb.IAMPolicyClient, string, error) {
+	rn, err := resource.Parse(name)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse resource: %w", err)
+	}
+	if rn.Type != resource.TypeFullName {
+		return nil, "", fmt.Errorf("resource %q is not a full resource name", name)
+	}
+	service, relative := rn.FullName()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	conn, ok := c.conns[service]
+	if !ok {
+		endpoint := c.endpoints[service]
+		if endpoint == "" {
+			endpoint = service + ":443"
+		}
+		opts := append([]option.ClientOption{option.WithScopes(scope)}, c.opts...)
+		opts = append(opts, option.WithEndpoint(endpoint))
+		if conn, err = gtransport.Dial(ctx, opts...); err != nil {
+			return nil, "", fmt.Errorf("failed to connect to %s at %q: %w", service, endpoint, err)
+		}
+		c.conns[service] = conn
+	}
+	return iampb.NewIAMPolicyClient(conn), relative, nil
+}
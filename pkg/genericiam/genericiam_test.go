@@ -0,0 +1,161 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genericiam
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	"google.golang.org/genproto/googleapis/type/expr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+// fakeIAMPolicy is a fake IAMPolicy API of the IAM policy of a resource.
+type fakeIAMPolicy struct {
+	iampb.UnimplementedIAMPolicyServer
+
+	mu     sync.Mutex
+	policy *iampb.Policy
+	calls  []string
+}
+
+func (f *fakeIAMPolicy) GetIamPolicy(_ context.Context, req *iampb.GetIamPolicyRequest) (*iampb.Policy, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, "GetIamPolicy "+req.GetResource())
+	return proto.Clone(f.policy).(*iampb.Policy), nil //nolint:forcetypeassert // Test server.
+}
+
+func (f *fakeIAMPolicy) SetIamPolicy(_ context.Context, req *iampb.SetIamPolicyRequest) (*iampb.Policy, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, "SetIamPolicy "+req.GetResource())
+	if !bytes.Equal(req.GetPolicy().GetEtag(), f.policy.GetEtag()) {
+		return nil, status.Error(codes.Aborted, "etag mismatch")
+	}
+	f.policy = req.GetPolicy()
+	f.policy.Etag = append(f.policy.GetEtag(), '+')
+	return f.policy, nil
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeIAMPolicy{
+		policy: &iampb.Policy{
+			Version: 1,
+			Etag:    []byte("etag"),
+			Bindings: []*iampb.Binding{
+				{Role: "roles/pubsub.admin", Members: []string{"user:admin@example.com"}},
+			},
+		},
+	}
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	srv := grpc.NewServer()
+	iampb.RegisterIAMPolicyServer(srv, fake)
+	go srv.Serve(lis) //nolint:errcheck // Test server.
+	t.Cleanup(srv.Stop)
+
+	ctx := context.Background()
+	c := NewClient(map[string]string{"pubsub.googleapis.com": lis.Addr().String()},
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())))
+	t.Cleanup(func() {
+		if err := c.Close(); err != nil {
+			t.Errorf("failed to close client: %v", err)
+		}
+	})
+
+	resource := "//pubsub.googleapis.com/projects/foo/topics/bar"
+	got, err := c.GetIamPolicy(ctx, &iampb.GetIamPolicyRequest{
+		Resource: resource,
+		Options:  &iampb.GetPolicyOptions{RequestedPolicyVersion: 3},
+	})
+	if err != nil {
+		t.Fatalf("GetIamPolicy got unexpected error: %v", err)
+	}
+	want := &iampb.Policy{
+		Version: 1,
+		Etag:    []byte("etag"),
+		Bindings: []*iampb.Binding{
+			{Role: "roles/pubsub.admin", Members: []string{"user:admin@example.com"}},
+		},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("GetIamPolicy got policy diff (-want, +got):\n%s", diff)
+	}
+
+	cond := &expr.Expr{Title: "expiry", Expression: `request.time < timestamp("2009-11-10T23:00:00Z")`}
+	got.Version = 3
+	got.Bindings = append(got.Bindings, &iampb.Binding{
+		Role:      "roles/pubsub.subscriber",
+		Members:   []string{"user:alice@example.com"},
+		Condition: cond,
+	})
+	got, err = c.SetIamPolicy(ctx, &iampb.SetIamPolicyRequest{Resource: resource, Policy: got})
+	if err != nil {
+		t.Fatalf("SetIamPolicy got unexpected error: %v", err)
+	}
+	want.Version = 3
+	want.Etag = []byte("etag+")
+	want.Bindings = append(want.Bindings, &iampb.Binding{
+		Role:      "roles/pubsub.subscriber",
+		Members:   []string{"user:alice@example.com"},
+		Condition: cond,
+	})
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("SetIamPolicy got policy diff (-want, +got):\n%s", diff)
+	}
+
+	// The etag of the policy is stale.
+	got.Etag = []byte("etag")
+	_, err = c.SetIamPolicy(ctx, &iampb.SetIamPolicyRequest{Resource: resource, Policy: got})
+	if diff := testutil.DiffErrString(err, `failed to set IAM policy of resource "//pubsub.googleapis.com/projects/foo/topics/bar"`); diff != "" {
+		t.Errorf("SetIamPolicy got unexpected error: %s", diff)
+	}
+	if got, want := status.Code(err), codes.Aborted; got != want {
+		t.Errorf("SetIamPolicy got code %s, want %s", got, want)
+	}
+
+	_, err = c.GetIamPolicy(ctx, &iampb.GetIamPolicyRequest{Resource: "projects/foo"})
+	if diff := testutil.DiffErrString(err, `resource "projects/foo" is not a full resource name`); diff != "" {
+		t.Errorf("GetIamPolicy got unexpected error: %s", diff)
+	}
+
+	wantCalls := []string{
+		"GetIamPolicy projects/foo/topics/bar",
+		"SetIamPolicy projects/foo/topics/bar",
+		"SetIamPolicy projects/foo/topics/bar",
+	}
+	if diff := cmp.Diff(wantCalls, fake.calls); diff != "" {
+		t.Errorf("got calls diff (-want, +got):\n%s", diff)
+	}
+}
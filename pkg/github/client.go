@@ -0,0 +1,130 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// DefaultAPIURL is the URL of the GitHub REST API.
+const DefaultAPIURL = "https://api.github.com"
+
+// repoRegex matches a GitHub repository in the format of "owner/repo".
+var repoRegex = regexp.MustCompile(`^[^/\s]+/[^/\s]+$`)
+
+// ValidateRepo checks the repo is in the format of "owner/repo".
+func ValidateRepo(repo string) error {
+	if !repoRegex.MatchString(repo) {
+		return fmt.Errorf("repo %q isn't in the format of %q", repo, "owner/repo")
+	}
+	return nil
+}
+
+// apiClient sends requests to the GitHub REST API for a repository.
+type apiClient struct {
+	client *http.Client
+	apiURL string
+	repo   string
+	token  string
+}
+
+// newAPIClient creates a new apiClient for the repo, in the format of
+// "owner/repo", with the token.
+func newAPIClient(client *http.Client, apiURL, repo, token string) (*apiClient, error) {
+	if err := ValidateRepo(repo); err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return nil, fmt.Errorf("github token is required")
+	}
+	return &apiClient{
+		client: client,
+		apiURL: strings.TrimSuffix(apiURL, "/"),
+		repo:   repo,
+		token:  token,
+	}, nil
+}
+
+// escapePath escapes the segments of the slash separated path.
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// statusError is a non-2xx response of the GitHub REST API.
+type statusError struct {
+	code int
+	body string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected response status %d: %s", e.code, e.body)
+}
+
+// do sends the request with the JSON body to the GitHub REST API, and decodes
+// the JSON response to out if it is not nil.
+func (o *apiClient) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, o.apiURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+o.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Limit the response body to 4MiB.
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &statusError{code: resp.StatusCode, body: strings.TrimSpace(string(b))}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
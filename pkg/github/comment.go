@@ -0,0 +1,51 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Commenter comments on pull requests in a GitHub repository.
+type Commenter struct {
+	apiClient
+}
+
+// NewCommenter creates a new Commenter commenting on pull requests in the
+// repo, in the format of "owner/repo", with the token which needs permission
+// to write pull requests. The apiURL is the URL of the GitHub REST API, such
+// as DefaultAPIURL.
+func NewCommenter(client *http.Client, apiURL, repo, token string) (*Commenter, error) {
+	c, err := newAPIClient(client, apiURL, repo, token)
+	if err != nil {
+		return nil, err
+	}
+	return &Commenter{apiClient: *c}, nil
+}
+
+// Comment adds a comment with the markdown body to the pull request with the
+// number, and returns the URL of the comment.
+func (c *Commenter) Comment(ctx context.Context, number int, body string) (string, error) {
+	var comment struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/issues/%d/comments", c.repo, number),
+		map[string]string{"body": body}, &comment); err != nil {
+		return "", fmt.Errorf("failed to comment on pull request %d: %w", number, err)
+	}
+	return comment.HTMLURL, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestCommenter_Comment(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		status  int
+		wantURL string
+		wantErr string
+	}{
+		{
+			name:    "success",
+			status:  http.StatusCreated,
+			wantURL: "https://github.com/foo/bar/pull/7#issuecomment-1",
+		},
+		{
+			name:    "failure",
+			status:  http.StatusForbidden,
+			wantErr: "failed to comment on pull request 7: unexpected response status 403",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var gotBody map[string]string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got, want := r.Header.Get("Authorization"), "Bearer test-token"; got != want {
+					t.Errorf("authorization header got %q, want %q", got, want)
+				}
+				if r.Method != http.MethodPost || r.URL.Path != "/repos/foo/bar/issues/7/comments" {
+					t.Errorf("got request %s %s, want POST /repos/foo/bar/issues/7/comments", r.Method, r.URL.Path)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&gotBody); err != nil {
+					t.Errorf("failed to decode request body: %v", err)
+				}
+				w.WriteHeader(tc.status)
+				fmt.Fprint(w, `{"html_url": "https://github.com/foo/bar/pull/7#issuecomment-1"}`)
+			}))
+			t.Cleanup(srv.Close)
+
+			c, err := NewCommenter(srv.Client(), srv.URL, "foo/bar", "test-token")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := c.Comment(context.Background(), 7, "**handled**")
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if got != tc.wantURL {
+				t.Errorf("Process(%+v) got URL %q, want %q", tc.name, got, tc.wantURL)
+			}
+			if diff := cmp.Diff(map[string]string{"body": "**handled**"}, gotBody); diff != "" {
+				t.Errorf("Process(%+v) got body diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
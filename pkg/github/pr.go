@@ -0,0 +1,180 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package github opens pull requests of request files, and comments on pull
+// requests with the results of handling them, in GitHub repositories.
+package github
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// PullRequest is a pull request adding a file to a repository.
+type PullRequest struct {
+	// Branch is the branch to create and commit the file to.
+	Branch string
+
+	// Base is the branch to merge the pull request into, the default branch of
+	// the repository if empty.
+	Base string
+
+	// Path is the path of the file in the repository.
+	Path string
+
+	// Content is the content of the file.
+	Content []byte
+
+	// CommitMessage is the message of the commit of the file.
+	CommitMessage string
+
+	// Title is the title of the pull request.
+	Title string
+
+	// Body is the body of the pull request.
+	Body string
+
+	// Labels are added to the pull request.
+	Labels []string
+
+	// Reviewers are requested to review the pull request, reviewers in the
+	// format of "org/team" are requested as teams.
+	Reviewers []string
+}
+
+// Result is an opened pull request.
+type Result struct {
+	Number int
+	URL    string
+}
+
+// PROpener opens pull requests in a GitHub repository.
+type PROpener struct {
+	apiClient
+}
+
+// NewPROpener creates a new PROpener opening pull requests in the repo, in the
+// format of "owner/repo", with the token which needs permission to write
+// contents and pull requests. The apiURL is the URL of the GitHub REST API,
+// such as DefaultAPIURL.
+func NewPROpener(client *http.Client, apiURL, repo, token string) (*PROpener, error) {
+	c, err := newAPIClient(client, apiURL, repo, token)
+	if err != nil {
+		return nil, err
+	}
+	return &PROpener{apiClient: *c}, nil
+}
+
+// Open creates the branch from the base branch, commits the file to the branch
+// and opens the pull request with the labels and reviewers.
+func (o *PROpener) Open(ctx context.Context, pr *PullRequest) (*Result, error) {
+	base := pr.Base
+	if base == "" {
+		var repo struct {
+			DefaultBranch string `json:"default_branch"`
+		}
+		if err := o.do(ctx, http.MethodGet, "/repos/"+o.repo, nil, &repo); err != nil {
+			return nil, fmt.Errorf("failed to get repo %s: %w", o.repo, err)
+		}
+		base = repo.DefaultBranch
+	}
+
+	var ref struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	if err := o.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/git/ref/heads/%s", o.repo, escapePath(base)), nil, &ref); err != nil {
+		return nil, fmt.Errorf("failed to get base branch %q: %w", base, err)
+	}
+
+	if err := o.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/git/refs", o.repo), map[string]string{
+		"ref": "refs/heads/" + pr.Branch,
+		"sha": ref.Object.SHA,
+	}, nil); err != nil {
+		return nil, fmt.Errorf("failed to create branch %q: %w", pr.Branch, err)
+	}
+
+	if err := o.putFile(ctx, pr); err != nil {
+		return nil, fmt.Errorf("failed to commit file %q: %w", pr.Path, err)
+	}
+
+	var created struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := o.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/pulls", o.repo), map[string]string{
+		"title": pr.Title,
+		"body":  pr.Body,
+		"head":  pr.Branch,
+		"base":  base,
+	}, &created); err != nil {
+		return nil, fmt.Errorf("failed to create pull request: %w", err)
+	}
+	result := &Result{Number: created.Number, URL: created.HTMLURL}
+
+	if len(pr.Labels) > 0 {
+		if err := o.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/issues/%d/labels", o.repo, created.Number),
+			map[string][]string{"labels": pr.Labels}, nil); err != nil {
+			return result, fmt.Errorf("failed to add labels to pull request %s: %w", result.URL, err)
+		}
+	}
+
+	if len(pr.Reviewers) > 0 {
+		users, teams := []string{}, []string{}
+		for _, r := range pr.Reviewers {
+			if _, team, ok := strings.Cut(r, "/"); ok {
+				teams = append(teams, team)
+			} else {
+				users = append(users, r)
+			}
+		}
+		if err := o.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/pulls/%d/requested_reviewers", o.repo, created.Number),
+			map[string][]string{"reviewers": users, "team_reviewers": teams}, nil); err != nil {
+			return result, fmt.Errorf("failed to request reviewers of pull request %s: %w", result.URL, err)
+		}
+	}
+
+	return result, nil
+}
+
+// putFile creates or updates the file in the branch of the pull request.
+func (o *PROpener) putFile(ctx context.Context, pr *PullRequest) error {
+	p := fmt.Sprintf("/repos/%s/contents/%s", o.repo, escapePath(pr.Path))
+
+	// The SHA of the existing file is required to update it.
+	var existing struct {
+		SHA string `json:"sha"`
+	}
+	err := o.do(ctx, http.MethodGet, p+"?ref="+url.QueryEscape(pr.Branch), nil, &existing)
+	var se *statusError
+	if err != nil && !(errors.As(err, &se) && se.code == http.StatusNotFound) {
+		return fmt.Errorf("failed to get existing file: %w", err)
+	}
+
+	in := map[string]string{
+		"message": pr.CommitMessage,
+		"content": base64.StdEncoding.EncodeToString(pr.Content),
+		"branch":  pr.Branch,
+	}
+	if existing.SHA != "" {
+		in["sha"] = existing.SHA
+	}
+	return o.do(ctx, http.MethodPut, p, in, nil)
+}
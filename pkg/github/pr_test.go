@@ -0,0 +1,258 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+// apiCall is a request received by the fake GitHub server.
+type apiCall struct {
+	Method string
+	Path   string
+	Body   map[string]any
+}
+
+func TestPROpener_Open(t *testing.T) {
+	t.Parallel()
+
+	pr := &PullRequest{
+		Branch:        "aod-request",
+		Path:          "requests/iam.yaml",
+		Content:       []byte("policies: []\n"),
+		CommitMessage: "Add request",
+		Title:         "Request access",
+		Body:          "body",
+	}
+
+	cases := []struct {
+		name       string
+		pr         func(*PullRequest)
+		existing   bool
+		failPath   string
+		wantResult *Result
+		wantCalls  []*apiCall
+		wantErr    string
+	}{
+		{
+			name:       "default_base",
+			wantResult: &Result{Number: 7, URL: "https://github.com/foo/bar/pull/7"},
+			wantCalls: []*apiCall{
+				{Method: http.MethodGet, Path: "/repos/foo/bar"},
+				{Method: http.MethodGet, Path: "/repos/foo/bar/git/ref/heads/main"},
+				{Method: http.MethodPost, Path: "/repos/foo/bar/git/refs", Body: map[string]any{
+					"ref": "refs/heads/aod-request", "sha": "base-sha",
+				}},
+				{Method: http.MethodGet, Path: "/repos/foo/bar/contents/requests/iam.yaml"},
+				{Method: http.MethodPut, Path: "/repos/foo/bar/contents/requests/iam.yaml", Body: map[string]any{
+					"message": "Add request", "content": "cG9saWNpZXM6IFtdCg==", "branch": "aod-request",
+				}},
+				{Method: http.MethodPost, Path: "/repos/foo/bar/pulls", Body: map[string]any{
+					"title": "Request access", "body": "body", "head": "aod-request", "base": "main",
+				}},
+			},
+		},
+		{
+			name: "base_labels_reviewers",
+			pr: func(pr *PullRequest) {
+				pr.Base = "release"
+				pr.Labels = []string{"aod"}
+				pr.Reviewers = []string{"alice", "foo/admins"}
+			},
+			existing:   true,
+			wantResult: &Result{Number: 7, URL: "https://github.com/foo/bar/pull/7"},
+			wantCalls: []*apiCall{
+				{Method: http.MethodGet, Path: "/repos/foo/bar/git/ref/heads/release"},
+				{Method: http.MethodPost, Path: "/repos/foo/bar/git/refs", Body: map[string]any{
+					"ref": "refs/heads/aod-request", "sha": "base-sha",
+				}},
+				{Method: http.MethodGet, Path: "/repos/foo/bar/contents/requests/iam.yaml"},
+				{Method: http.MethodPut, Path: "/repos/foo/bar/contents/requests/iam.yaml", Body: map[string]any{
+					"message": "Add request", "content": "cG9saWNpZXM6IFtdCg==", "branch": "aod-request", "sha": "file-sha",
+				}},
+				{Method: http.MethodPost, Path: "/repos/foo/bar/pulls", Body: map[string]any{
+					"title": "Request access", "body": "body", "head": "aod-request", "base": "release",
+				}},
+				{Method: http.MethodPost, Path: "/repos/foo/bar/issues/7/labels", Body: map[string]any{
+					"labels": []any{"aod"},
+				}},
+				{Method: http.MethodPost, Path: "/repos/foo/bar/pulls/7/requested_reviewers", Body: map[string]any{
+					"reviewers": []any{"alice"}, "team_reviewers": []any{"admins"},
+				}},
+			},
+		},
+		{
+			name:     "create_branch_failure",
+			pr:       func(pr *PullRequest) { pr.Base = "main" },
+			failPath: "/repos/foo/bar/git/refs",
+			wantCalls: []*apiCall{
+				{Method: http.MethodGet, Path: "/repos/foo/bar/git/ref/heads/main"},
+				{Method: http.MethodPost, Path: "/repos/foo/bar/git/refs", Body: map[string]any{
+					"ref": "refs/heads/aod-request", "sha": "base-sha",
+				}},
+			},
+			wantErr: `failed to create branch "aod-request": unexpected response status 422`,
+		},
+		{
+			name: "labels_failure",
+			pr: func(pr *PullRequest) {
+				pr.Base = "main"
+				pr.Labels = []string{"aod"}
+			},
+			failPath:   "/repos/foo/bar/issues/7/labels",
+			wantResult: &Result{Number: 7, URL: "https://github.com/foo/bar/pull/7"},
+			wantCalls: []*apiCall{
+				{Method: http.MethodGet, Path: "/repos/foo/bar/git/ref/heads/main"},
+				{Method: http.MethodPost, Path: "/repos/foo/bar/git/refs", Body: map[string]any{
+					"ref": "refs/heads/aod-request", "sha": "base-sha",
+				}},
+				{Method: http.MethodGet, Path: "/repos/foo/bar/contents/requests/iam.yaml"},
+				{Method: http.MethodPut, Path: "/repos/foo/bar/contents/requests/iam.yaml", Body: map[string]any{
+					"message": "Add request", "content": "cG9saWNpZXM6IFtdCg==", "branch": "aod-request",
+				}},
+				{Method: http.MethodPost, Path: "/repos/foo/bar/pulls", Body: map[string]any{
+					"title": "Request access", "body": "body", "head": "aod-request", "base": "main",
+				}},
+				{Method: http.MethodPost, Path: "/repos/foo/bar/issues/7/labels", Body: map[string]any{
+					"labels": []any{"aod"},
+				}},
+			},
+			wantErr: "failed to add labels to pull request https://github.com/foo/bar/pull/7",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			var gotCalls []*apiCall
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				if got, want := r.Header.Get("Authorization"), "Bearer test-token"; got != want {
+					t.Errorf("authorization header got %q, want %q", got, want)
+				}
+				call := &apiCall{Method: r.Method, Path: r.URL.Path}
+				b, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Errorf("failed to read request body: %v", err)
+				}
+				if len(b) > 0 {
+					if err := json.Unmarshal(b, &call.Body); err != nil {
+						t.Errorf("failed to unmarshal request body: %v", err)
+					}
+				}
+				gotCalls = append(gotCalls, call)
+
+				if r.URL.Path == tc.failPath {
+					w.WriteHeader(http.StatusUnprocessableEntity)
+					fmt.Fprint(w, `{"message": "injected error"}`)
+					return
+				}
+				switch {
+				case r.URL.Path == "/repos/foo/bar":
+					fmt.Fprint(w, `{"default_branch": "main"}`)
+				case r.Method == http.MethodGet && r.URL.Path == "/repos/foo/bar/contents/requests/iam.yaml":
+					if got, want := r.URL.Query().Get("ref"), "aod-request"; got != want {
+						t.Errorf("contents ref got %q, want %q", got, want)
+					}
+					if !tc.existing {
+						w.WriteHeader(http.StatusNotFound)
+						fmt.Fprint(w, `{"message": "Not Found"}`)
+						return
+					}
+					fmt.Fprint(w, `{"sha": "file-sha"}`)
+				case r.URL.Path == "/repos/foo/bar/pulls":
+					w.WriteHeader(http.StatusCreated)
+					fmt.Fprint(w, `{"number": 7, "html_url": "https://github.com/foo/bar/pull/7"}`)
+				default:
+					fmt.Fprint(w, `{"object": {"sha": "base-sha"}}`)
+				}
+			}))
+			t.Cleanup(srv.Close)
+
+			o, err := NewPROpener(srv.Client(), srv.URL+"/", "foo/bar", "test-token")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			in := *pr
+			if tc.pr != nil {
+				tc.pr(&in)
+			}
+			got, err := o.Open(context.Background(), &in)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantResult, got); diff != "" {
+				t.Errorf("Process(%+v) got result diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantCalls, gotCalls); diff != "" {
+				t.Errorf("Process(%+v) got calls diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestNewPROpener(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		repo    string
+		token   string
+		wantErr string
+	}{
+		{
+			name:  "valid",
+			repo:  "foo/bar",
+			token: "test-token",
+		},
+		{
+			name:    "invalid_repo",
+			repo:    "foo",
+			token:   "test-token",
+			wantErr: `repo "foo" isn't in the format of "owner/repo"`,
+		},
+		{
+			name:    "missing_token",
+			repo:    "foo/bar",
+			wantErr: "github token is required",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewPROpener(http.DefaultClient, DefaultAPIURL, tc.repo, tc.token)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
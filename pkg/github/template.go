@@ -0,0 +1,71 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Default templates of the pull request title and body.
+const (
+	DefaultTitleTemplate = `Request access to {{ join .Resources ", " }}`
+
+	DefaultBodyTemplate = `Request access for {{ join .Members ", " }}.
+
+- Resources: {{ join .Resources ", " }}
+- Roles: {{ join .Roles ", " }}
+{{- if .Duration }}
+- Duration: {{ .Duration }}
+{{- end }}
+- Request file: ` + "`{{ .Path }}`" + `
+`
+)
+
+// TemplateData is the data of the pull request title and body templates.
+type TemplateData struct {
+	// Path is the path of the request file in the repository.
+	Path string
+
+	// Resources are the distinct resources in the request.
+	Resources []string
+
+	// Members are the distinct members in the request.
+	Members []string
+
+	// Roles are the distinct roles and role bundles in the request.
+	Roles []string
+
+	// Duration is the duration to handle the request with, if any.
+	Duration string
+}
+
+// Render renders the text/template text with the data. The "join" function of
+// strings.Join is available in the template.
+func Render(text string, data *TemplateData) (string, error) {
+	t, err := template.New("").
+		Option("missingkey=error").
+		Funcs(template.FuncMap{"join": strings.Join}).
+		Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+	return b.String(), nil
+}
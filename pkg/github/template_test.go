@@ -0,0 +1,100 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	data := &TemplateData{
+		Path:      "requests/iam.yaml",
+		Resources: []string{"projects/foo", "folders/123"},
+		Members:   []string{"user:alice@example.com"},
+		Roles:     []string{"roles/viewer"},
+		Duration:  "2h0m0s",
+	}
+
+	cases := []struct {
+		name    string
+		text    string
+		data    *TemplateData
+		want    string
+		wantErr string
+	}{
+		{
+			name: "default_title",
+			text: DefaultTitleTemplate,
+			data: data,
+			want: "Request access to projects/foo, folders/123",
+		},
+		{
+			name: "default_body",
+			text: DefaultBodyTemplate,
+			data: data,
+			want: "Request access for user:alice@example.com.\n\n" +
+				"- Resources: projects/foo, folders/123\n" +
+				"- Roles: roles/viewer\n" +
+				"- Duration: 2h0m0s\n" +
+				"- Request file: `requests/iam.yaml`\n",
+		},
+		{
+			name: "default_body_without_duration",
+			text: DefaultBodyTemplate,
+			data: &TemplateData{
+				Path:      "iam.yaml",
+				Resources: []string{"projects/foo"},
+				Members:   []string{"user:alice@example.com"},
+				Roles:     []string{"roles/viewer"},
+			},
+			want: "Request access for user:alice@example.com.\n\n" +
+				"- Resources: projects/foo\n" +
+				"- Roles: roles/viewer\n" +
+				"- Request file: `iam.yaml`\n",
+		},
+		{
+			name:    "invalid_template",
+			text:    "{{ .Path",
+			data:    data,
+			wantErr: "failed to parse template",
+		},
+		{
+			name:    "unknown_field",
+			text:    "{{ .Bananas }}",
+			data:    data,
+			wantErr: "failed to execute template",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Render(tc.text, tc.data)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Process(%+v) got output diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package group manages the temporary memberships of Google Groups with the
+// Cloud Identity API. The memberships expire natively, Cloud Identity removes
+// the members once their expiry passes without any cleanup by AOD.
+package group
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	cloudidentity "google.golang.org/api/cloudidentity/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+// memberRole is the role of the memberships of group members, which is the
+// only role whose expiry can be set.
+const memberRole = "MEMBER"
+
+// Membership is the membership of a member in a group.
+type Membership struct {
+	// Member of the group, e.g. "user:alice@example.com".
+	Member string `yaml:"member"`
+
+	// Expiry of the membership, nil if it is permanent.
+	Expiry *time.Time `yaml:"expiry,omitempty"`
+}
+
+// Client gets, adds, updates and removes the memberships of groups, which are
+// named by their emails, with the Cloud Identity API.
+type Client struct {
+	service *cloudidentity.Service
+}
+
+// NewClient creates a new Client. The caller needs to be able to manage the
+// members of the groups, such as an owner or a manager of the groups, or with
+// the "Groups Admin" admin role.
+func NewClient(ctx context.Context, opts ...option.ClientOption) (*Client, error) {
+	opts = append([]option.ClientOption{option.WithScopes(cloudidentity.CloudIdentityGroupsScope)}, opts...)
+	svc, err := cloudidentity.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cloud identity service: %w", err)
+	}
+	return &Client{service: svc}, nil
+}
+
+// GetMembership returns the membership of the member in the group, or nil if
+// the member is not a direct member of the group.
+func (c *Client) GetMembership(ctx context.Context, group, member string) (*Membership, error) {
+	name, err := c.membershipName(ctx, group, member)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, nil
+	}
+
+	m, err := c.service.Groups.Memberships.Get(name).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get membership of %q in group %q: %w", member, group, err)
+	}
+	result := &Membership{Member: member}
+	for _, r := range m.Roles {
+		if r.Name != memberRole || r.ExpiryDetail == nil || r.ExpiryDetail.ExpireTime == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, r.ExpiryDetail.ExpireTime)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse expiry of %q in group %q: %w", member, group, err)
+		}
+		result.Expiry = &t
+	}
+	return result, nil
+}
+
+// AddMembership adds the member to the group until the expiry.
+func (c *Client) AddMembership(ctx context.Context, group, member string, expiry time.Time) error {
+	email, err := memberEmail(member)
+	if err != nil {
+		return err
+	}
+	parent, err := c.groupName(ctx, group)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.service.Groups.Memberships.Create(parent, &cloudidentity.Membership{
+		PreferredMemberKey: &cloudidentity.EntityKey{Id: email},
+		Roles:              []*cloudidentity.MembershipRole{memberRoleUntil(expiry)},
+	}).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to add %q to group %q: %w", member, group, err)
+	}
+	return nil
+}
+
+// UpdateExpiry updates the expiry of the membership of the member in the
+// group.
+func (c *Client) UpdateExpiry(ctx context.Context, group, member string, expiry time.Time) error {
+	name, err := c.membershipName(ctx, group, member)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return fmt.Errorf("%q is not a member of group %q", member, group)
+	}
+
+	if _, err := c.service.Groups.Memberships.ModifyMembershipRoles(name, &cloudidentity.ModifyMembershipRolesRequest{
+		UpdateRolesParams: []*cloudidentity.UpdateMembershipRolesParams{{
+			FieldMask:      "expiryDetail.expireTime",
+			MembershipRole: memberRoleUntil(expiry),
+		}},
+	}).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to update expiry of %q in group %q: %w", member, group, err)
+	}
+	return nil
+}
+
+// RemoveMembership removes the member from the group.
+func (c *Client) RemoveMembership(ctx context.Context, group, member string) error {
+	name, err := c.membershipName(ctx, group, member)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return fmt.Errorf("%q is not a member of group %q", member, group)
+	}
+
+	if _, err := c.service.Groups.Memberships.Delete(name).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to remove %q from group %q: %w", member, group, err)
+	}
+	return nil
+}
+
+// groupName returns the resource name of the group of the email, in the format
+// of "groups/<id>".
+func (c *Client) groupName(ctx context.Context, group string) (string, error) {
+	resp, err := c.service.Groups.Lookup().GroupKeyId(group).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("failed to look up group %q: %w", group, err)
+	}
+	return resp.Name, nil
+}
+
+// membershipName returns the resource name of the membership of the member in
+// the group, in the format of "groups/<id>/memberships/<id>", or "" if the
+// member is not a direct member of the group.
+func (c *Client) membershipName(ctx context.Context, group, member string) (string, error) {
+	email, err := memberEmail(member)
+	if err != nil {
+		return "", err
+	}
+	parent, err := c.groupName(ctx, group)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.service.Groups.Memberships.Lookup(parent).MemberKeyId(email).Context(ctx).Do()
+	if err != nil {
+		var gerr *googleapi.Error
+		if errors.As(err, &gerr) && gerr.Code == http.StatusNotFound {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to look up membership of %q in group %q: %w", member, group, err)
+	}
+	return resp.Name, nil
+}
+
+// memberEmail returns the email of the user or service account member.
+func memberEmail(member string) (string, error) {
+	for _, prefix := range []string{"user:", "serviceAccount:"} {
+		if email, ok := strings.CutPrefix(member, prefix); ok {
+			return email, nil
+		}
+	}
+	return "", fmt.Errorf("member %q is not a user or a service account", member)
+}
+
+// memberRoleUntil returns the member role expiring at the expiry.
+func memberRoleUntil(expiry time.Time) *cloudidentity.MembershipRole {
+	return &cloudidentity.MembershipRole{
+		Name: memberRole,
+		ExpiryDetail: &cloudidentity.ExpiryDetail{
+			ExpireTime: expiry.UTC().Format(time.RFC3339),
+		},
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package group
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	cloudidentity "google.golang.org/api/cloudidentity/v1"
+	"google.golang.org/api/option"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+// fakeCloudIdentity is a fake Cloud Identity API of the memberships of the
+// group "admins@example.com", by the emails of the members.
+type fakeCloudIdentity struct {
+	mu sync.Mutex
+	// expiries are the expire times of the members, "" if permanent.
+	expiries map[string]string
+	calls    []string
+}
+
+func (f *fakeCloudIdentity) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, r.Method+" "+r.URL.Path)
+
+	notFound := func() {
+		http.Error(w, `{"error": {"code": 404, "message": "not found"}}`, http.StatusNotFound)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	switch path := strings.TrimPrefix(r.URL.Path, "/v1/"); {
+	case path == "groups:lookup":
+		if r.URL.Query().Get("groupKey.id") != "admins@example.com" {
+			notFound()
+			return
+		}
+		json.NewEncoder(w).Encode(&cloudidentity.LookupGroupNameResponse{Name: "groups/g1"}) //nolint:errcheck // Test server.
+	case path == "groups/g1/memberships:lookup":
+		email := r.URL.Query().Get("memberKey.id")
+		if _, ok := f.expiries[email]; !ok {
+			notFound()
+			return
+		}
+		json.NewEncoder(w).Encode(&cloudidentity.LookupMembershipNameResponse{Name: "groups/g1/memberships/" + email}) //nolint:errcheck // Test server.
+	case path == "groups/g1/memberships" && r.Method == http.MethodPost:
+		var m cloudidentity.Membership
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.expiries[m.PreferredMemberKey.Id] = m.Roles[0].ExpiryDetail.ExpireTime
+		json.NewEncoder(w).Encode(&cloudidentity.Operation{Done: true}) //nolint:errcheck // Test server.
+	case strings.HasSuffix(path, ":modifyMembershipRoles"):
+		var req cloudidentity.ModifyMembershipRolesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		email := strings.TrimSuffix(strings.TrimPrefix(path, "groups/g1/memberships/"), ":modifyMembershipRoles")
+		f.expiries[email] = req.UpdateRolesParams[0].MembershipRole.ExpiryDetail.ExpireTime
+		json.NewEncoder(w).Encode(&cloudidentity.ModifyMembershipRolesResponse{}) //nolint:errcheck // Test server.
+	case strings.HasPrefix(path, "groups/g1/memberships/"):
+		email := strings.TrimPrefix(path, "groups/g1/memberships/")
+		expiry, ok := f.expiries[email]
+		if !ok {
+			notFound()
+			return
+		}
+		if r.Method == http.MethodDelete {
+			delete(f.expiries, email)
+			json.NewEncoder(w).Encode(&cloudidentity.Operation{Done: true}) //nolint:errcheck // Test server.
+			return
+		}
+		role := &cloudidentity.MembershipRole{Name: "MEMBER"}
+		if expiry != "" {
+			role.ExpiryDetail = &cloudidentity.ExpiryDetail{ExpireTime: expiry}
+		}
+		json.NewEncoder(w).Encode(&cloudidentity.Membership{ //nolint:errcheck // Test server.
+			Name:  "groups/g1/memberships/" + email,
+			Roles: []*cloudidentity.MembershipRole{role},
+		})
+	default:
+		notFound()
+	}
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeCloudIdentity{
+		expiries: map[string]string{"bob@example.com": ""},
+	}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	c, err := NewClient(ctx, option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	expiry := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	if err := c.AddMembership(ctx, "admins@example.com", "user:alice@example.com", expiry); err != nil {
+		t.Fatalf("AddMembership got unexpected error: %v", err)
+	}
+	got, err := c.GetMembership(ctx, "admins@example.com", "user:alice@example.com")
+	if err != nil {
+		t.Fatalf("GetMembership got unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(&Membership{Member: "user:alice@example.com", Expiry: &expiry}, got); diff != "" {
+		t.Errorf("GetMembership got diff (-want, +got):\n%s", diff)
+	}
+
+	extended := expiry.Add(time.Hour)
+	if err := c.UpdateExpiry(ctx, "admins@example.com", "user:alice@example.com", extended); err != nil {
+		t.Fatalf("UpdateExpiry got unexpected error: %v", err)
+	}
+	if got, want := fake.expiries["alice@example.com"], "2009-11-11T00:00:00Z"; got != want {
+		t.Errorf("UpdateExpiry got expiry %q, want %q", got, want)
+	}
+
+	// The permanent membership has no expiry.
+	got, err = c.GetMembership(ctx, "admins@example.com", "user:bob@example.com")
+	if err != nil {
+		t.Fatalf("GetMembership got unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(&Membership{Member: "user:bob@example.com"}, got); diff != "" {
+		t.Errorf("GetMembership got diff (-want, +got):\n%s", diff)
+	}
+
+	if err := c.RemoveMembership(ctx, "admins@example.com", "user:alice@example.com"); err != nil {
+		t.Fatalf("RemoveMembership got unexpected error: %v", err)
+	}
+	got, err = c.GetMembership(ctx, "admins@example.com", "user:alice@example.com")
+	if err != nil {
+		t.Fatalf("GetMembership got unexpected error: %v", err)
+	}
+	if got != nil {
+		t.Errorf("GetMembership got %+v of removed member, want nil", got)
+	}
+
+	err = c.RemoveMembership(ctx, "admins@example.com", "user:alice@example.com")
+	if diff := testutil.DiffErrString(err, `"user:alice@example.com" is not a member of group "admins@example.com"`); diff != "" {
+		t.Errorf("RemoveMembership got unexpected error: %s", diff)
+	}
+	err = c.AddMembership(ctx, "unknown@example.com", "user:alice@example.com", expiry)
+	if diff := testutil.DiffErrString(err, `failed to look up group "unknown@example.com"`); diff != "" {
+		t.Errorf("AddMembership got unexpected error: %s", diff)
+	}
+	err = c.AddMembership(ctx, "admins@example.com", "group:ops@example.com", expiry)
+	if diff := testutil.DiffErrString(err, `member "group:ops@example.com" is not a user or a service account`); diff != "" {
+		t.Errorf("AddMembership got unexpected error: %s", diff)
+	}
+
+	wantCalls := []string{
+		"GET /v1/groups:lookup",
+		"POST /v1/groups/g1/memberships",
+		"GET /v1/groups:lookup",
+		"GET /v1/groups/g1/memberships:lookup",
+		"GET /v1/groups/g1/memberships/alice@example.com",
+		"GET /v1/groups:lookup",
+		"GET /v1/groups/g1/memberships:lookup",
+		"POST /v1/groups/g1/memberships/alice@example.com:modifyMembershipRoles",
+		"GET /v1/groups:lookup",
+		"GET /v1/groups/g1/memberships:lookup",
+		"GET /v1/groups/g1/memberships/bob@example.com",
+		"GET /v1/groups:lookup",
+		"GET /v1/groups/g1/memberships:lookup",
+		"DELETE /v1/groups/g1/memberships/alice@example.com",
+		"GET /v1/groups:lookup",
+		"GET /v1/groups/g1/memberships:lookup",
+		"GET /v1/groups:lookup",
+		"GET /v1/groups/g1/memberships:lookup",
+		"GET /v1/groups:lookup",
+	}
+	if diff := cmp.Diff(wantCalls, fake.calls); diff != "" {
+		t.Errorf("got calls diff (-want, +got):\n%s", diff)
+	}
+}
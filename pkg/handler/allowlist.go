@@ -0,0 +1,85 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ResourceNotAllowedError is the error of handling a resource that is not in
+// the allowed resources of the handler.
+type ResourceNotAllowedError struct {
+	// Resource that is not allowed.
+	Resource string
+}
+
+func (e *ResourceNotAllowedError) Error() string {
+	return fmt.Sprintf("resource %s is not allowed to be modified by this AOD deployment", e.Resource)
+}
+
+// WithAllowedResourcePrefixes limits the resources whose IAM policies the
+// handler modifies to the ones with any of the prefixes, e.g. "projects/dev-"
+// or "folders/123". It is enforced right before the IAM policies are modified,
+// regardless of how the requests were validated. It can be provided multiple
+// times, together with WithAllowedResourcePatterns, and resources matching any
+// of them are allowed. All resources are allowed if neither is provided.
+func WithAllowedResourcePrefixes(prefixes ...string) Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		for _, prefix := range prefixes {
+			if prefix == "" {
+				return nil, fmt.Errorf("allowed resource prefix must not be empty")
+			}
+		}
+		p.allowedPrefixes = append(p.allowedPrefixes, prefixes...)
+		return p, nil
+	}
+}
+
+// WithAllowedResourcePatterns limits the resources whose IAM policies the
+// handler modifies to the ones fully matching any of the regular expressions,
+// e.g. `projects/team-a-.*`. See WithAllowedResourcePrefixes.
+func WithAllowedResourcePatterns(patterns ...string) Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		for _, pattern := range patterns {
+			re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+			if err != nil {
+				return nil, fmt.Errorf("invalid allowed resource pattern %q: %w", pattern, err)
+			}
+			p.allowedPatterns = append(p.allowedPatterns, re)
+		}
+		return p, nil
+	}
+}
+
+// checkAllowed returns a ResourceNotAllowedError if the resource is not in the
+// allowed resources of the handler.
+func (h *IAMHandler) checkAllowed(resource string) error {
+	if len(h.allowedPrefixes) == 0 && len(h.allowedPatterns) == 0 {
+		return nil
+	}
+	for _, prefix := range h.allowedPrefixes {
+		if strings.HasPrefix(resource, prefix) {
+			return nil
+		}
+	}
+	for _, re := range h.allowedPatterns {
+		if re.MatchString(resource) {
+			return nil
+		}
+	}
+	return &ResourceNotAllowedError{Resource: resource}
+}
@@ -0,0 +1,139 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/sethvargo/go-retry"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestAllowedResources(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		opts          []Option
+		resource      string
+		wantSetCalls  int
+		wantErrSubstr string
+	}{
+		{
+			name:         "no_allow_list",
+			resource:     "projects/foo",
+			wantSetCalls: 1,
+		},
+		{
+			name:         "prefix_allowed",
+			opts:         []Option{WithAllowedResourcePrefixes("projects/dev-", "folders/123")},
+			resource:     "projects/dev-foo",
+			wantSetCalls: 1,
+		},
+		{
+			name:         "pattern_allowed",
+			opts:         []Option{WithAllowedResourcePrefixes("folders/123"), WithAllowedResourcePatterns(`projects/team-[a-z]+`)},
+			resource:     "projects/team-foo",
+			wantSetCalls: 1,
+		},
+		{
+			name:          "pattern_partial_match",
+			opts:          []Option{WithAllowedResourcePatterns(`projects/team-[a-z]+`)},
+			resource:      "projects/team-foo-1",
+			wantErrSubstr: "resource projects/team-foo-1 is not allowed to be modified by this AOD deployment",
+		},
+		{
+			name:          "not_allowed",
+			opts:          []Option{WithAllowedResourcePrefixes("projects/dev-")},
+			resource:      "projects/prod-foo",
+			wantErrSubstr: "resource projects/prod-foo is not allowed to be modified by this AOD deployment",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			server := &fakeServer{policy: &iampb.Policy{}}
+			o, f, p := setupFakeClients(t, ctx, &fakeServer{policy: &iampb.Policy{}}, &fakeServer{policy: &iampb.Policy{}}, server)
+
+			opts := append([]Option{WithRetry(retry.WithMaxRetries(0, retry.NewConstant(time.Millisecond)))}, tc.opts...)
+			h, err := NewIAMHandler(ctx, o, f, p, opts...)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			_, gotErr := h.Do(ctx, &v1alpha1.IAMRequestWrapper{
+				IAMRequest: &v1alpha1.IAMRequest{
+					ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+						Resource: tc.resource,
+						Bindings: []*v1alpha1.Binding{{Members: []string{"user:alice@example.com"}, Role: "roles/browser"}},
+					}},
+				},
+				Duration:  time.Hour,
+				StartTime: time.Now(),
+			})
+			if diff := testutil.DiffErrString(gotErr, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+			var notAllowedErr *ResourceNotAllowedError
+			if got, want := errors.As(gotErr, &notAllowedErr), tc.wantErrSubstr != ""; got != want {
+				t.Errorf("Process(%+v) got ResourceNotAllowedError %t, want %t", tc.name, got, want)
+			}
+			if got, want := server.setCalls, tc.wantSetCalls; got != want {
+				t.Errorf("Process(%+v) got %d SetIamPolicy calls, want %d", tc.name, got, want)
+			}
+		})
+	}
+}
+
+func TestAllowedResourceOptions(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		opt           Option
+		wantErrSubstr string
+	}{
+		{
+			name:          "empty_prefix",
+			opt:           WithAllowedResourcePrefixes(""),
+			wantErrSubstr: "allowed resource prefix must not be empty",
+		},
+		{
+			name:          "invalid_pattern",
+			opt:           WithAllowedResourcePatterns(`projects/(`),
+			wantErrSubstr: `invalid allowed resource pattern "projects/("`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewIAMHandler(context.Background(), nil, nil, nil, tc.opt)
+			if diff := testutil.DiffErrString(err, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// writeAuditEvent writes an audit event of the resource policy handling to the
+// audit sinks, with the expiry and provenance of the request wrapper if it is
+// not nil. Successful events are also published to the event publishers.
+// Failures are logged and ignored.
+func (h *IAMHandler) writeAuditEvent(ctx context.Context, typ string, p *v1alpha1.ResourcePolicy, w *v1alpha1.IAMRequestWrapper, handleErr error) {
+	if len(h.auditSinks) == 0 && len(h.eventPublishers) == 0 {
+		return
+	}
+
+	h.emitAuditEvent(ctx, h.newAuditEvent(ctx, typ, p, w, handleErr))
+}
+
+// emitAuditEvent writes the audit event to the audit sinks, and publishes it to
+// the event publishers if it is successful. Failures are logged and ignored.
+func (h *IAMHandler) emitAuditEvent(ctx context.Context, e *audit.Event) {
+	if len(h.auditSinks) == 0 && len(h.eventPublishers) == 0 {
+		return
+	}
+
+	h.writeAuditSinks(ctx, e)
+
+	if e.Outcome != audit.OutcomeSuccess {
+		return
+	}
+	logger := logging.FromContext(ctx)
+	for _, pub := range h.eventPublishers {
+		if err := pub.Publish(ctx, e); err != nil {
+			logger.WarnContext(ctx, "failed to publish event", "error", err)
+		}
+	}
+}
+
+// newAuditEvent returns an audit event of the resource policy handling, with
+// the expiry and provenance of the request wrapper if it is not nil.
+func (h *IAMHandler) newAuditEvent(ctx context.Context, typ string, p *v1alpha1.ResourcePolicy, w *v1alpha1.IAMRequestWrapper, handleErr error) *audit.Event {
+	e := &audit.Event{
+		Type:           typ,
+		Time:           h.now().UTC(),
+		Resource:       p.Resource,
+		Bindings:       toAuditBindings(p.Bindings),
+		ConditionTitle: h.conditionTitle,
+		Outcome:        audit.OutcomeSuccess,
+		Caller:         h.caller,
+		CorrelationID:  h.requestMetadata(ctx).CorrelationID,
+	}
+	if w != nil {
+		expiry := w.StartTime.Add(w.Duration)
+		e.Expiry = &expiry
+		e.Requester = w.Requester
+		e.Approvers = w.Approvers
+		e.Source = w.Source
+		e.RequestHash = w.RequestHash
+	}
+	if handleErr != nil {
+		e.Outcome = audit.OutcomeFailure
+		e.Error = handleErr.Error()
+	}
+	return e
+}
+
+// writeAuditSinks writes the audit event to the audit sinks. Failures are
+// logged and ignored.
+func (h *IAMHandler) writeAuditSinks(ctx context.Context, e *audit.Event) {
+	logger := logging.FromContext(ctx)
+	for _, s := range h.auditSinks {
+		if err := s.Write(ctx, e); err != nil {
+			logger.WarnContext(ctx, "failed to write audit event", "error", err)
+		}
+	}
+}
+
+func toAuditBindings(bs []*v1alpha1.Binding) []*audit.Binding {
+	result := make([]*audit.Binding, 0, len(bs))
+	for _, b := range bs {
+		for _, r := range b.Roles() {
+			result = append(result, &audit.Binding{Role: r, Members: b.Members})
+		}
+	}
+	return result
+}
@@ -0,0 +1,275 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sethvargo/go-retry"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+)
+
+func TestAuditEvents(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	expiry := now.Add(2 * time.Hour)
+	policies := []*v1alpha1.ResourcePolicy{
+		{
+			Resource: "folders/bar",
+			Bindings: []*v1alpha1.Binding{
+				{
+					Members: []string{"user:test-folder-user@example.com"},
+					Role:    "roles/cloudkms.cryptoOperator",
+				},
+			},
+		},
+		{
+			Resource: "projects/baz",
+			Bindings: []*v1alpha1.Binding{
+				{
+					Members: []string{"user:test-project-user@example.com"},
+					Role:    "roles/bigquery.dataViewer",
+				},
+			},
+		},
+	}
+	folderBindings := []*audit.Binding{
+		{Role: "roles/cloudkms.cryptoOperator", Members: []string{"user:test-folder-user@example.com"}},
+	}
+	projectBindings := []*audit.Binding{
+		{Role: "roles/bigquery.dataViewer", Members: []string{"user:test-project-user@example.com"}},
+	}
+	setErr := status.Error(codes.Internal, "Internal Server Error")
+
+	cases := []struct {
+		name       string
+		cleanup    bool
+		requester  string
+		approvers  []string
+		source     string
+		caller     string
+		wantEvents []*audit.Event
+	}{
+		{
+			name: "grant",
+			wantEvents: []*audit.Event{
+				{
+					Type:           audit.EventTypeGrant,
+					Time:           now,
+					Resource:       "folders/bar",
+					Bindings:       folderBindings,
+					Expiry:         &expiry,
+					ConditionTitle: DefaultConditionTitle,
+					Outcome:        audit.OutcomeSuccess,
+				},
+				{
+					Type:           audit.EventTypeGrant,
+					Time:           now,
+					Resource:       "projects/baz",
+					Bindings:       projectBindings,
+					Expiry:         &expiry,
+					ConditionTitle: DefaultConditionTitle,
+					Outcome:        audit.OutcomeFailure,
+					Error: fmt.Sprintf("failed to handle IAM request: failed to set IAM policy: %s, retrying",
+						setErr),
+				},
+			},
+		},
+		{
+			name:      "grant_with_provenance",
+			requester: "user:requester@example.com",
+			approvers: []string{"user:approver@example.com"},
+			source:    "https://github.com/foo/bar/pull/1",
+			wantEvents: []*audit.Event{
+				{
+					Type:           audit.EventTypeGrant,
+					Time:           now,
+					Resource:       "folders/bar",
+					Bindings:       folderBindings,
+					Expiry:         &expiry,
+					ConditionTitle: DefaultConditionTitle,
+					Requester:      "user:requester@example.com",
+					Approvers:      []string{"user:approver@example.com"},
+					Source:         "https://github.com/foo/bar/pull/1",
+					Outcome:        audit.OutcomeSuccess,
+				},
+				{
+					Type:           audit.EventTypeGrant,
+					Time:           now,
+					Resource:       "projects/baz",
+					Bindings:       projectBindings,
+					Expiry:         &expiry,
+					ConditionTitle: DefaultConditionTitle,
+					Requester:      "user:requester@example.com",
+					Approvers:      []string{"user:approver@example.com"},
+					Source:         "https://github.com/foo/bar/pull/1",
+					Outcome:        audit.OutcomeFailure,
+					Error: fmt.Sprintf("failed to handle IAM request: failed to set IAM policy: %s, retrying",
+						setErr),
+				},
+			},
+		},
+		{
+			name:    "cleanup_with_caller",
+			caller:  "serviceAccount:aod@test-project.iam.gserviceaccount.com",
+			cleanup: true,
+			wantEvents: []*audit.Event{
+				{
+					Type:           audit.EventTypeCleanup,
+					Time:           now,
+					Resource:       "folders/bar",
+					Bindings:       folderBindings,
+					ConditionTitle: DefaultConditionTitle,
+					Outcome:        audit.OutcomeSuccess,
+					Caller:         "serviceAccount:aod@test-project.iam.gserviceaccount.com",
+				},
+				{
+					Type:           audit.EventTypeCleanup,
+					Time:           now,
+					Resource:       "projects/baz",
+					Bindings:       projectBindings,
+					ConditionTitle: DefaultConditionTitle,
+					Outcome:        audit.OutcomeFailure,
+					Error: fmt.Sprintf("failed to handle IAM request: failed to set IAM policy: %s, retrying",
+						setErr),
+					Caller: "serviceAccount:aod@test-project.iam.gserviceaccount.com",
+				},
+			},
+		},
+		{
+			name:    "cleanup",
+			cleanup: true,
+			wantEvents: []*audit.Event{
+				{
+					Type:           audit.EventTypeCleanup,
+					Time:           now,
+					Resource:       "folders/bar",
+					Bindings:       folderBindings,
+					ConditionTitle: DefaultConditionTitle,
+					Outcome:        audit.OutcomeSuccess,
+				},
+				{
+					Type:           audit.EventTypeCleanup,
+					Time:           now,
+					Resource:       "projects/baz",
+					Bindings:       projectBindings,
+					ConditionTitle: DefaultConditionTitle,
+					Outcome:        audit.OutcomeFailure,
+					Error: fmt.Sprintf("failed to handle IAM request: failed to set IAM policy: %s, retrying",
+						setErr),
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}, setIAMPolicyErr: setErr},
+			)
+
+			sink := &fakeAuditSink{}
+			publisher := &fakeEventPublisher{}
+			h, err := NewIAMHandler(
+				ctx,
+				fakeOrganizationsClient,
+				fakeFoldersClient,
+				fakeProjectsClient,
+				WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+				WithNowFunc(func() time.Time { return now }),
+				WithAuditSink(sink),
+				WithEventPublisher(publisher),
+				WithCaller(tc.caller),
+			)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			if tc.cleanup {
+				_, err = h.Cleanup(ctx, &v1alpha1.IAMRequest{ResourcePolicies: policies})
+			} else {
+				_, err = h.Do(ctx, &v1alpha1.IAMRequestWrapper{
+					IAMRequest: &v1alpha1.IAMRequest{ResourcePolicies: policies},
+					Duration:   2 * time.Hour,
+					StartTime:  now,
+					Requester:  tc.requester,
+					Approvers:  tc.approvers,
+					Source:     tc.source,
+				})
+			}
+			if err == nil {
+				t.Errorf("Process(%+v) got nil error, want error", tc.name)
+			}
+
+			if diff := cmp.Diff(tc.wantEvents, sink.events); diff != "" {
+				t.Errorf("Process(%+v) got audit events diff (-want, +got): %v", tc.name, diff)
+			}
+
+			// Only successful events are published.
+			var wantPublished []*audit.Event
+			for _, e := range tc.wantEvents {
+				if e.Outcome == audit.OutcomeSuccess {
+					wantPublished = append(wantPublished, e)
+				}
+			}
+			if diff := cmp.Diff(wantPublished, publisher.events); diff != "" {
+				t.Errorf("Process(%+v) got published events diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+type fakeAuditSink struct {
+	mu     sync.Mutex
+	events []*audit.Event
+}
+
+func (s *fakeAuditSink) Write(_ context.Context, e *audit.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, e)
+	return nil
+}
+
+type fakeEventPublisher struct {
+	mu     sync.Mutex
+	events []*audit.Event
+}
+
+func (p *fakeEventPublisher) Publish(_ context.Context, e *audit.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, e)
+	return nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrAPICallBudgetExceeded is the error of an IAM API call over the budget of
+// the handler, see WithAPICallBudget.
+var ErrAPICallBudgetExceeded = errors.New("IAM API call budget exceeded")
+
+// WithAPICallBudget provides the max number of IAM API calls, including the
+// retries, the handler may make over its lifetime, such as a CLI run. The calls
+// over the budget fail with ErrAPICallBudgetExceeded without being made or
+// retried, protecting shared quotas from pathological requests. Default is no
+// budget.
+func WithAPICallBudget(n int64) Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		if n < 1 {
+			return nil, fmt.Errorf("API call budget must be at least 1, got %d", n)
+		}
+		p.apiCallBudget = n
+		return p, nil
+	}
+}
+
+// APICalls returns the number of IAM API calls the handler made, including the
+// retries.
+func (h *IAMHandler) APICalls() int64 {
+	n := h.apiCalls.Load()
+	if h.apiCallBudget > 0 {
+		// The calls over the budget are counted but not made.
+		return min(n, h.apiCallBudget)
+	}
+	return n
+}
+
+// spendAPICall counts an IAM API call against the budget, and returns an error
+// wrapping ErrAPICallBudgetExceeded if the call is over the budget.
+func (h *IAMHandler) spendAPICall() error {
+	n := h.apiCalls.Add(1)
+	if h.apiCallBudget > 0 && n > h.apiCallBudget {
+		return fmt.Errorf("%w: the budget is %d calls", ErrAPICallBudgetExceeded, h.apiCallBudget)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/sethvargo/go-retry"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+)
+
+func TestAPICallBudget(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	request := func(resources ...string) *v1alpha1.IAMRequestWrapper {
+		r := &v1alpha1.IAMRequestWrapper{
+			IAMRequest: &v1alpha1.IAMRequest{},
+			Duration:   time.Hour,
+			StartTime:  now,
+		}
+		for _, res := range resources {
+			r.ResourcePolicies = append(r.ResourcePolicies, &v1alpha1.ResourcePolicy{
+				Resource: res,
+				Bindings: []*v1alpha1.Binding{{Members: []string{"user:test-user@example.com"}, Role: "roles/viewer"}},
+			})
+		}
+		return r
+	}
+
+	cases := []struct {
+		name         string
+		budget       int64
+		req          *v1alpha1.IAMRequestWrapper
+		setErr       error
+		wantCalls    int64
+		wantSetCalls int
+		wantExceeded bool
+	}{
+		{
+			name:         "within_budget",
+			budget:       4,
+			req:          request("projects/foo", "projects/bar"),
+			wantCalls:    4,
+			wantSetCalls: 2,
+		},
+		{
+			name:         "exceeded",
+			budget:       3,
+			req:          request("projects/foo", "projects/bar"),
+			wantCalls:    3,
+			wantSetCalls: 1,
+			wantExceeded: true,
+		},
+		{
+			name:         "retries_exceeded",
+			budget:       3,
+			req:          request("projects/foo"),
+			setErr:       status.Error(codes.Unavailable, "injected error"),
+			wantCalls:    3,
+			wantSetCalls: 1,
+			wantExceeded: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			projectsServer := &fakeServer{policy: &iampb.Policy{}, setIAMPolicyErr: tc.setErr}
+			organizationsClient, foldersClient, projectsClient := setupFakeClients(t, ctx,
+				&fakeServer{policy: &iampb.Policy{}}, &fakeServer{policy: &iampb.Policy{}}, projectsServer)
+
+			h, err := NewIAMHandler(ctx, organizationsClient, foldersClient, projectsClient,
+				WithRetry(retry.WithMaxRetries(5, retry.NewConstant(time.Millisecond))),
+				WithNowFunc(func() time.Time { return now }),
+				WithAPICallBudget(tc.budget),
+			)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			_, err = h.Do(ctx, tc.req)
+			if got := errors.Is(err, ErrAPICallBudgetExceeded); got != tc.wantExceeded {
+				t.Errorf("Process(%+v) got budget exceeded %t, want %t, error: %v", tc.name, got, tc.wantExceeded, err)
+			}
+			if got := h.APICalls(); got != tc.wantCalls {
+				t.Errorf("Process(%+v) got %d API calls, want %d", tc.name, got, tc.wantCalls)
+			}
+			if got := projectsServer.setCalls; got != tc.wantSetCalls {
+				t.Errorf("Process(%+v) got %d SetIamPolicy calls, want %d", tc.name, got, tc.wantSetCalls)
+			}
+		})
+	}
+}
+
+func TestWithAPICallBudget_Invalid(t *testing.T) {
+	t.Parallel()
+
+	if _, err := WithAPICallBudget(0)(&IAMHandler{}); err == nil {
+		t.Errorf("WithAPICallBudget(0) got no error, want error")
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/cel-go/cel"
+	celast "github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+	"google.golang.org/genproto/googleapis/type/expr"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// maxConditionTitleLength is the maximum length of IAM condition titles.
+	maxConditionTitleLength = 100
+
+	// maxConditionExpressionLength is the maximum length of IAM condition
+	// expressions.
+	maxConditionExpressionLength = 12800
+
+	// maxConditionLogicalOperators is the maximum number of logical operators,
+	// "&&", "||" and "!", in IAM condition expressions.
+	maxConditionLogicalOperators = 12
+)
+
+// InvalidConditionError is the error of an IAM condition that would be
+// rejected by the IAM API. It is returned before the policy is set, instead of
+// the IAM API rejecting the update.
+type InvalidConditionError struct {
+	// Resource of the IAM policy.
+	Resource string
+
+	// Role of the binding with the condition.
+	Role string
+
+	// Condition that is invalid.
+	Condition *expr.Expr
+
+	// Err is the reason the condition is invalid.
+	Err error
+}
+
+func (e *InvalidConditionError) Error() string {
+	return fmt.Sprintf("condition %q of role %s on %s is invalid: %v",
+		e.Condition.GetExpression(), e.Role, e.Resource, e.Err)
+}
+
+func (e *InvalidConditionError) Unwrap() error {
+	return e.Err
+}
+
+// conditionEnv is the CEL environment of the attributes of IAM conditions.
+var conditionEnv = sync.OnceValues(func() (*cel.Env, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("request.time", cel.TimestampType),
+		cel.Variable("resource.name", cel.StringType),
+		cel.Variable("resource.type", cel.StringType),
+		cel.Variable("resource.service", cel.StringType),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+	return env, nil
+})
+
+// checkConditions returns an InvalidConditionError if any condition in the IAM
+// policy of the resource which is not in the prior policy would be rejected by
+// the IAM API. Conditions already in the prior policy were accepted before, so
+// they are not checked again.
+func checkConditions(resource string, prior, p *iampb.Policy) error {
+	var retErr error
+	for _, b := range p.GetBindings() {
+		cond := b.GetCondition()
+		if cond == nil || hasCondition(prior, cond) {
+			continue
+		}
+		if err := lintCondition(cond); err != nil {
+			retErr = errors.Join(retErr, &InvalidConditionError{
+				Resource:  resource,
+				Role:      b.GetRole(),
+				Condition: cond,
+				Err:       err,
+			})
+		}
+	}
+	return retErr
+}
+
+// hasCondition returns whether any binding of the IAM policy has the
+// condition.
+func hasCondition(p *iampb.Policy, cond *expr.Expr) bool {
+	for _, b := range p.GetBindings() {
+		if proto.Equal(b.GetCondition(), cond) {
+			return true
+		}
+	}
+	return false
+}
+
+// lintCondition checks the condition against the limits of IAM conditions, and
+// that its expression compiles to a boolean CEL expression.
+func lintCondition(cond *expr.Expr) error {
+	if n := len(cond.GetTitle()); n > maxConditionTitleLength {
+		return fmt.Errorf("title has %d characters, over the limit of %d", n, maxConditionTitleLength)
+	}
+	if n := len(cond.GetDescription()); n > maxConditionDescriptionLength {
+		return fmt.Errorf("description has %d characters, over the limit of %d", n, maxConditionDescriptionLength)
+	}
+	if n := len(cond.GetExpression()); n > maxConditionExpressionLength {
+		return fmt.Errorf("expression has %d characters, over the limit of %d", n, maxConditionExpressionLength)
+	}
+
+	env, err := conditionEnv()
+	if err != nil {
+		return err
+	}
+	ast, iss := env.Compile(cond.GetExpression())
+	if iss.Err() != nil {
+		return fmt.Errorf("failed to compile expression: %w", iss.Err())
+	}
+	if !ast.OutputType().IsExactType(cel.BoolType) {
+		return fmt.Errorf("expression evaluates to %s, not bool", ast.OutputType())
+	}
+
+	var ops int
+	celast.PreOrderVisit(ast.NativeRep().Expr(), celast.NewExprVisitor(func(e celast.Expr) {
+		if e.Kind() != celast.CallKind {
+			return
+		}
+		switch e.AsCall().FunctionName() {
+		case operators.LogicalAnd, operators.LogicalOr, operators.LogicalNot:
+			ops++
+		}
+	}))
+	if ops > maxConditionLogicalOperators {
+		return fmt.Errorf("expression has %d logical operators, over the limit of %d", ops, maxConditionLogicalOperators)
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sethvargo/go-retry"
+	"google.golang.org/genproto/googleapis/type/expr"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestLintCondition(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		cond    *expr.Expr
+		wantErr string
+	}{
+		{
+			name: "expiry",
+			cond: &expr.Expr{
+				Title:      DefaultConditionTitle,
+				Expression: "request.time < timestamp('2009-11-10T23:00:00Z')",
+			},
+		},
+		{
+			name: "window",
+			cond: &expr.Expr{
+				Title:      DefaultConditionTitle,
+				Expression: fmt.Sprintf(windowExpression, "2009-11-10T23:00:00Z", "2009-11-11T23:00:00Z"),
+			},
+		},
+		{
+			name: "recurring",
+			cond: &expr.Expr{
+				Title: DefaultConditionTitle,
+				Expression: "request.time < timestamp('2009-11-10T23:00:00Z') && " +
+					"request.time.getDayOfWeek('Europe/Berlin') >= 1 && request.time.getDayOfWeek('Europe/Berlin') <= 5 && " +
+					"request.time.getHours('Europe/Berlin') >= 9 && request.time.getHours('Europe/Berlin') < 17",
+			},
+		},
+		{
+			name: "resource_attributes",
+			cond: &expr.Expr{
+				Title:      DefaultConditionTitle,
+				Expression: "resource.name.startsWith('projects/_/buckets/foo') && resource.type == 'storage.googleapis.com/Bucket'",
+			},
+		},
+		{
+			name: "title_too_long",
+			cond: &expr.Expr{
+				Title:      strings.Repeat("a", 101),
+				Expression: "request.time < timestamp('2009-11-10T23:00:00Z')",
+			},
+			wantErr: "title has 101 characters, over the limit of 100",
+		},
+		{
+			name: "description_too_long",
+			cond: &expr.Expr{
+				Title:       DefaultConditionTitle,
+				Description: strings.Repeat("a", 257),
+				Expression:  "request.time < timestamp('2009-11-10T23:00:00Z')",
+			},
+			wantErr: "description has 257 characters, over the limit of 256",
+		},
+		{
+			name: "expression_too_long",
+			cond: &expr.Expr{
+				Title:      DefaultConditionTitle,
+				Expression: "resource.name == '" + strings.Repeat("a", 12783) + "'",
+			},
+			wantErr: "expression has 12802 characters, over the limit of 12800",
+		},
+		{
+			name: "syntax_error",
+			cond: &expr.Expr{
+				Title:      DefaultConditionTitle,
+				Expression: "request.time < timestamp('2009-11-10T23:00:00Z'",
+			},
+			wantErr: "failed to compile expression",
+		},
+		{
+			name: "unknown_attribute",
+			cond: &expr.Expr{
+				Title:      DefaultConditionTitle,
+				Expression: "request.host == 'example.com'",
+			},
+			wantErr: "failed to compile expression",
+		},
+		{
+			name: "not_bool",
+			cond: &expr.Expr{
+				Title:      DefaultConditionTitle,
+				Expression: "request.time",
+			},
+			wantErr: "expression evaluates to google.protobuf.Timestamp, not bool",
+		},
+		{
+			name: "too_many_logical_operators",
+			cond: &expr.Expr{
+				Title:      DefaultConditionTitle,
+				Expression: strings.Repeat("request.time.getHours('UTC') != 1 && ", 13) + "true",
+			},
+			wantErr: "expression has 13 logical operators, over the limit of 12",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := lintCondition(tc.cond)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestCheckConditions(t *testing.T) {
+	t.Parallel()
+
+	invalid := &expr.Expr{Title: DefaultConditionTitle, Expression: "request.time <"}
+	prior := &iampb.Policy{Bindings: []*iampb.Binding{
+		{Role: "roles/viewer", Members: []string{"user:alice@example.com"}, Condition: invalid},
+	}}
+	p := &iampb.Policy{Bindings: []*iampb.Binding{
+		{Role: "roles/viewer", Members: []string{"user:alice@example.com"}, Condition: invalid},
+		{Role: "roles/editor", Members: []string{"user:bob@example.com"}},
+	}}
+	if err := checkConditions("projects/foo", prior, p); err != nil {
+		t.Errorf("checkConditions() got unexpected error for conditions in the prior policy: %v", err)
+	}
+
+	p.Bindings = append(p.Bindings, &iampb.Binding{Role: "roles/owner", Members: []string{"user:bob@example.com"}, Condition: &expr.Expr{
+		Title:      DefaultConditionTitle,
+		Expression: "request.time",
+	}})
+	err := checkConditions("projects/foo", prior, p)
+	if diff := testutil.DiffErrString(err, `condition "request.time" of role roles/owner on projects/foo is invalid`); diff != "" {
+		t.Errorf("checkConditions() got unexpected error substring: %v", diff)
+	}
+}
+
+func TestDo_InvalidCondition(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+
+	policy := &iampb.Policy{Bindings: []*iampb.Binding{{Role: "roles/viewer", Members: []string{"user:bob@example.com"}}}}
+	projectServer := &fakeServer{policy: policy}
+	fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+		t,
+		ctx,
+		&fakeServer{policy: &iampb.Policy{}},
+		&fakeServer{policy: &iampb.Policy{}},
+		projectServer,
+	)
+
+	h, err := NewIAMHandler(
+		ctx,
+		fakeOrganizationsClient,
+		fakeFoldersClient,
+		fakeProjectsClient,
+		WithRetry(retry.WithMaxRetries(3, retry.NewConstant(time.Millisecond))),
+		WithNowFunc(func() time.Time { return now }),
+		WithCustomConditionTitle(strings.Repeat("a", 101)),
+	)
+	if err != nil {
+		t.Fatalf("failed to create IAMHandler: %v", err)
+	}
+
+	_, gotErr := h.Do(ctx, &v1alpha1.IAMRequestWrapper{
+		IAMRequest: &v1alpha1.IAMRequest{
+			ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+				Resource: "projects/baz",
+				Bindings: []*v1alpha1.Binding{{Members: []string{"user:alice@example.com"}, Role: "roles/editor"}},
+			}},
+		},
+		StartTime: now,
+		Duration:  time.Hour,
+	})
+	var condErr *InvalidConditionError
+	if !errors.As(gotErr, &condErr) {
+		t.Fatalf("Do() got error %v, want InvalidConditionError", gotErr)
+	}
+	if got, want := condErr.Role, "roles/editor"; got != want {
+		t.Errorf("Do() got invalid condition of role %q, want %q", got, want)
+	}
+	if diff := cmp.Diff(policy, projectServer.policy, protocmp.Transform()); diff != "" {
+		t.Errorf("Do() got project policy diff (-want, +got): %v", diff)
+	}
+	if got, want := h.APICalls(), int64(1); got != want {
+		t.Errorf("Do() got %d API calls, want %d without retries", got, want)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/sethvargo/go-retry"
+)
+
+// errStaleRead is the error of reading an IAM policy older than the policy
+// known to be current.
+var errStaleRead = errors.New("read stale IAM policy")
+
+// WithConsistentReads makes the handler guard against stale reads of IAM
+// policies. Reads of IAM policies are eventually consistent with the updates,
+// so a read shortly after an update may return the policy before it, and
+// setting a policy based on it fails with a conflict again. With the option,
+// a read returning the etag of a policy a set was already rejected for is
+// retried without setting the policy, and after a policy is set it is read
+// back until the update is visible, so that later requests of the resource
+// read the updated policy. Updates not yet visible when the retries are
+// exhausted are reported as warnings, since the policies were set.
+func WithConsistentReads() Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		p.consistentReads = true
+		return p, nil
+	}
+}
+
+// rejectedEtags are the etags of the IAM policies of a resource which sets
+// were rejected for due to concurrent modifications, which are known to be
+// stale.
+type rejectedEtags [][]byte
+
+// add adds the etag of a rejected set.
+func (r *rejectedEtags) add(etag []byte) {
+	*r = append(*r, etag)
+}
+
+// check returns errStaleRead if the etag was rejected.
+func (r rejectedEtags) check(etag []byte) error {
+	if slices.ContainsFunc(r, func(e []byte) bool { return bytes.Equal(e, etag) }) {
+		return fmt.Errorf("%w with etag %q rejected by a previous set", errStaleRead, etag)
+	}
+	return nil
+}
+
+// waitForReadAfterWrite reads the IAM policy of the resource until the update
+// from the policy with the before etag to the policy with the after etag is
+// visible, retrying with the retry backoff of the handler. A policy with
+// another etag than the before etag is the result of the update or a later
+// one.
+func (h *IAMHandler) waitForReadAfterWrite(ctx context.Context, iamC IAMClient, resource string, before, after []byte) error {
+	if bytes.Equal(before, after) {
+		return nil
+	}
+	if err := retry.Do(ctx, h.retry, func(ctx context.Context) error {
+		p, err := h.getPolicy(ctx, iamC, resource)
+		if err != nil {
+			if errors.Is(err, ErrAPICallBudgetExceeded) {
+				return err
+			}
+			return retry.RetryableError(err)
+		}
+		if bytes.Equal(p.GetEtag(), before) {
+			return retry.RetryableError(fmt.Errorf("%w with etag %q", errStaleRead, before))
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("updated IAM policy of %s is not yet visible in reads: %w", resource, err)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/gax-go/v2"
+	"github.com/sethvargo/go-retry"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestDoConsistentReads(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	request := &v1alpha1.IAMRequestWrapper{
+		IAMRequest: &v1alpha1.IAMRequest{
+			ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+				Resource: "projects/baz",
+				Bindings: []*v1alpha1.Binding{{
+					Members: []string{"user:test-project-user@example.com"},
+					Role:    "roles/bigquery.dataViewer",
+				}},
+			}},
+		},
+		Duration:  2 * time.Hour,
+		StartTime: now,
+	}
+	concurrent := &iampb.Policy{Bindings: []*iampb.Binding{{
+		Members: []string{"user:concurrent-user@example.com"},
+		Role:    "roles/viewer",
+	}}}
+
+	cases := []struct {
+		name            string
+		consistentReads bool
+		staleReads      int
+		concurrent      []*iampb.Policy
+		maxRetries      uint64
+		wantSetCalls    int
+		wantGetCalls    int
+		wantWarnings    []string
+		wantErr         string
+	}{
+		{
+			name:         "stale_read_after_conflict_set_again",
+			staleReads:   1,
+			concurrent:   []*iampb.Policy{concurrent},
+			maxRetries:   2,
+			wantSetCalls: 3,
+			wantGetCalls: 3,
+		},
+		{
+			name:            "stale_read_after_conflict_read_again",
+			consistentReads: true,
+			staleReads:      1,
+			concurrent:      []*iampb.Policy{concurrent},
+			maxRetries:      3,
+			wantSetCalls:    2,
+			wantGetCalls:    5,
+		},
+		{
+			name:            "stale_reads_exhaust_retries",
+			consistentReads: true,
+			staleReads:      3,
+			concurrent:      []*iampb.Policy{concurrent},
+			maxRetries:      2,
+			wantSetCalls:    1,
+			wantGetCalls:    3,
+			wantErr:         `read stale IAM policy with etag "v1" rejected by a previous set`,
+		},
+		{
+			name:            "update_not_visible",
+			consistentReads: true,
+			staleReads:      3,
+			maxRetries:      1,
+			wantSetCalls:    1,
+			wantGetCalls:    3,
+			wantWarnings:    []string{`updated IAM policy of projects/baz is not yet visible in reads: read stale IAM policy with etag "v1"`},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			client := &fakeEventualIAMClient{
+				history:    []*iampb.Policy{{Etag: []byte("v1")}},
+				staleReads: tc.staleReads,
+				concurrent: tc.concurrent,
+			}
+			opts := []Option{WithRetry(retry.WithMaxRetries(tc.maxRetries, retry.NewConstant(time.Millisecond)))}
+			if tc.consistentReads {
+				opts = append(opts, WithConsistentReads())
+			}
+			h, err := NewIAMHandler(ctx, client, client, client, opts...)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			resps, err := h.Do(ctx, request)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error: %s", tc.name, diff)
+			}
+			if got, want := client.sets, tc.wantSetCalls; got != want {
+				t.Errorf("Process(%+v) got %d SetIamPolicy calls, want %d", tc.name, got, want)
+			}
+			if got, want := client.gets, tc.wantGetCalls; got != want {
+				t.Errorf("Process(%+v) got %d GetIamPolicy calls, want %d", tc.name, got, want)
+			}
+			var gotWarnings []string
+			for _, r := range resps {
+				gotWarnings = append(gotWarnings, r.Warnings...)
+			}
+			if diff := cmp.Diff(tc.wantWarnings, gotWarnings); diff != "" {
+				t.Errorf("Process(%+v) got warnings diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+// fakeEventualIAMClient is an IAMClient with eventually consistent reads, the
+// reads after each write return the policy before the write.
+type fakeEventualIAMClient struct {
+	mu sync.Mutex
+
+	// history are the policies written, the last one is the current policy.
+	history []*iampb.Policy
+
+	// staleReads is the number of reads after each write returning the policy
+	// before the write.
+	staleReads int
+	stale      int
+
+	// concurrent are policies written by other writers, one before each
+	// SetIamPolicy call.
+	concurrent []*iampb.Policy
+
+	gets, sets int
+}
+
+func (c *fakeEventualIAMClient) GetIamPolicy(context.Context, *iampb.GetIamPolicyRequest, ...gax.CallOption) (*iampb.Policy, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gets++
+	p := c.history[len(c.history)-1]
+	if c.stale > 0 && len(c.history) > 1 {
+		c.stale--
+		p = c.history[len(c.history)-2]
+	}
+	return proto.Clone(p).(*iampb.Policy), nil //nolint:forcetypeassert // Policy is cloned.
+}
+
+func (c *fakeEventualIAMClient) SetIamPolicy(_ context.Context, r *iampb.SetIamPolicyRequest, _ ...gax.CallOption) (*iampb.Policy, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sets++
+	if len(c.concurrent) > 0 {
+		c.write(proto.Clone(c.concurrent[0]).(*iampb.Policy)) //nolint:forcetypeassert // Policy is cloned.
+		c.concurrent = c.concurrent[1:]
+	}
+	if !bytes.Equal(r.GetPolicy().GetEtag(), c.history[len(c.history)-1].GetEtag()) {
+		return nil, status.Error(codes.Aborted, "There were concurrent policy changes")
+	}
+	p := proto.Clone(r.GetPolicy()).(*iampb.Policy) //nolint:forcetypeassert // Policy is cloned.
+	c.write(p)
+	return proto.Clone(p).(*iampb.Policy), nil //nolint:forcetypeassert // Policy is cloned.
+}
+
+// write writes the policy with a new etag.
+func (c *fakeEventualIAMClient) write(p *iampb.Policy) {
+	p.Etag = []byte(fmt.Sprintf("v%d", len(c.history)+1))
+	c.history = append(c.history, p)
+	c.stale = c.staleReads
+}
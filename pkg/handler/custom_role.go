@@ -0,0 +1,179 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+	"github.com/abcxyz/access-on-demand/pkg/customrole"
+)
+
+// customRoleTitle is the title of the custom roles created by AOD.
+const customRoleTitle = "AOD temporary role"
+
+// CustomRoleManager is the interface to create, list and delete the custom
+// roles of organizations and projects.
+type CustomRoleManager interface {
+	CreateRole(ctx context.Context, parent, roleID string, r *customrole.Role) (*customrole.Role, error)
+	ListRoles(ctx context.Context, parent string) ([]*customrole.Role, error)
+	DeleteRole(ctx context.Context, name string) error
+}
+
+// CustomRoleResponse is the response of a handled CustomRoleRequest.
+type CustomRoleResponse struct {
+	// Role is the custom role created for the request.
+	Role *customrole.Role `yaml:"role"`
+
+	// Responses are the responses of binding the role.
+	Responses []*v1alpha1.IAMResponse `yaml:"responses,omitempty"`
+}
+
+// WithCustomRoleManager provides the manager of custom roles, which is
+// required to handle custom role requests.
+func WithCustomRoleManager(m CustomRoleManager) Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		p.customRoleManager = m
+		return p, nil
+	}
+}
+
+// DoCustomRole creates a custom role of the permissions in the resource of the
+// request, and binds it to the members until the expiry of the wrapper. The
+// role is deleted if it fails to be bound. The expiry is encoded in the ID of
+// the role, so that CleanupCustomRoles deletes it once it expires.
+func (h *IAMHandler) DoCustomRole(ctx context.Context, r *v1alpha1.CustomRoleRequest, w *v1alpha1.IAMRequestWrapper) (*CustomRoleResponse, error) {
+	if h.customRoleManager == nil {
+		return nil, fmt.Errorf("custom role manager is required to handle custom role requests")
+	}
+	if err := h.checkMaintenance(); err != nil {
+		return nil, err
+	}
+	if err := h.checkAllowed(r.Resource); err != nil {
+		return nil, fmt.Errorf("failed to handle custom role request: %w", err)
+	}
+	if err := h.checkProtected([]*v1alpha1.ResourcePolicy{{Resource: r.Resource}}); err != nil {
+		return nil, err
+	}
+
+	expiry := w.StartTime.Add(w.Duration)
+	seed := strings.Join(slices.Concat([]string{r.Resource, w.StartTime.String()}, r.Permissions, r.Members), "\n")
+	desc := fmt.Sprintf("Created by AOD for %s, expires at %s", strings.Join(r.Members, ", "), expiry.UTC().Format(time.RFC3339))
+	if requester := h.requestMetadata(withRequester(ctx, w.Requester)).Requester; requester != "" {
+		desc += fmt.Sprintf(", requested by %s", requester)
+	}
+
+	if err := h.spendAPICall(); err != nil {
+		return nil, fmt.Errorf("failed to create custom role: %w", err)
+	}
+	role, err := h.customRoleManager.CreateRole(ctx, r.Resource, customrole.RoleID(expiry, seed), &customrole.Role{
+		Title:       customRoleTitle,
+		Description: desc,
+		Permissions: r.Permissions,
+	})
+	h.writeCustomRoleEvent(ctx, audit.EventTypeCreateRole, r.Resource, role, r.Permissions, w, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create custom role: %w", err)
+	}
+
+	// Bind the role with the provenance of the request.
+	bindReq := *w
+	bindReq.IAMRequest = &v1alpha1.IAMRequest{
+		ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+			Resource: r.Resource,
+			Bindings: []*v1alpha1.Binding{{Members: r.Members, Role: role.Name}},
+		}},
+	}
+	resps, err := h.Do(ctx, &bindReq)
+	if err != nil {
+		// Delete the role not to leave it behind.
+		err = fmt.Errorf("failed to bind custom role %q: %w", role.Name, err)
+		if delErr := h.deleteCustomRole(ctx, r.Resource, role); delErr != nil {
+			err = errors.Join(err, delErr)
+		}
+		return nil, err
+	}
+	return &CustomRoleResponse{Role: role, Responses: resps}, nil
+}
+
+// CleanupCustomRoles deletes the expired custom roles created by AOD in the
+// resources, organizations or projects, and returns the names of the deleted
+// roles. The expired bindings of the roles are removed by Cleanup.
+func (h *IAMHandler) CleanupCustomRoles(ctx context.Context, resources []string) ([]string, error) {
+	if h.customRoleManager == nil {
+		return nil, fmt.Errorf("custom role manager is required to clean up custom roles")
+	}
+
+	now := h.now()
+	var deleted []string
+	var retErr error
+	for _, res := range resources {
+		if err := h.spendAPICall(); err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("failed to list custom roles of %s: %w", res, err))
+			continue
+		}
+		roles, err := h.customRoleManager.ListRoles(ctx, res)
+		if err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("failed to list custom roles of %s: %w", res, err))
+			continue
+		}
+		for _, role := range roles {
+			if exp, ok := customrole.Expiry(role.Name); !ok || exp.After(now) {
+				continue
+			}
+			if err := h.deleteCustomRole(ctx, res, role); err != nil {
+				retErr = errors.Join(retErr, err)
+				continue
+			}
+			deleted = append(deleted, role.Name)
+		}
+	}
+	return deleted, retErr
+}
+
+// deleteCustomRole deletes the custom role in the resource and writes the
+// audit event.
+func (h *IAMHandler) deleteCustomRole(ctx context.Context, resource string, role *customrole.Role) error {
+	err := h.spendAPICall()
+	if err == nil {
+		err = h.customRoleManager.DeleteRole(ctx, role.Name)
+	}
+	h.writeCustomRoleEvent(ctx, audit.EventTypeDeleteRole, resource, role, nil, nil, err)
+	if err != nil {
+		return fmt.Errorf("failed to delete custom role %q: %w", role.Name, err)
+	}
+	return nil
+}
+
+// writeCustomRoleEvent writes the audit event of creating or deleting the
+// custom role in the resource.
+func (h *IAMHandler) writeCustomRoleEvent(ctx context.Context, typ, resource string, role *customrole.Role, permissions []string, w *v1alpha1.IAMRequestWrapper, err error) {
+	if len(h.auditSinks) == 0 {
+		return
+	}
+	p := &v1alpha1.ResourcePolicy{Resource: resource}
+	if role != nil {
+		p.Bindings = []*v1alpha1.Binding{{Role: role.Name}}
+	}
+	e := h.newAuditEvent(ctx, typ, p, w, err)
+	e.Permissions = permissions
+	h.writeAuditSinks(ctx, e)
+}
@@ -0,0 +1,250 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sethvargo/go-retry"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+	"github.com/abcxyz/access-on-demand/pkg/customrole"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestDoCustomRole(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	req := &v1alpha1.CustomRoleRequest{
+		Resource:    "projects/baz",
+		Permissions: []string{"storage.objects.get", "storage.objects.list"},
+		Members:     []string{"user:alice@example.com"},
+	}
+
+	cases := []struct {
+		name          string
+		manager       *fakeCustomRoleManager
+		projectServer *fakeServer
+		wantBound     bool
+		wantDeleted   bool
+		wantEvents    []string
+		wantErrSubstr string
+	}{
+		{
+			name:          "success",
+			manager:       &fakeCustomRoleManager{},
+			projectServer: &fakeServer{policy: &iampb.Policy{}},
+			wantBound:     true,
+			wantEvents:    []string{audit.EventTypeCreateRole, audit.EventTypeGrant},
+		},
+		{
+			name:          "create_failure",
+			manager:       &fakeCustomRoleManager{createErr: fmt.Errorf("injected error")},
+			projectServer: &fakeServer{policy: &iampb.Policy{}},
+			wantEvents:    []string{audit.EventTypeCreateRole},
+			wantErrSubstr: "failed to create custom role: injected error",
+		},
+		{
+			name:    "bind_failure_deletes_role",
+			manager: &fakeCustomRoleManager{},
+			projectServer: &fakeServer{
+				policy:          &iampb.Policy{},
+				setIAMPolicyErr: status.Error(codes.PermissionDenied, "injected error"),
+			},
+			wantDeleted:   true,
+			wantEvents:    []string{audit.EventTypeCreateRole, audit.EventTypeGrant, audit.EventTypeDeleteRole},
+			wantErrSubstr: "failed to bind custom role",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+				tc.projectServer,
+			)
+
+			sink := &fakeAuditSink{}
+			h, err := NewIAMHandler(
+				ctx,
+				fakeOrganizationsClient,
+				fakeFoldersClient,
+				fakeProjectsClient,
+				WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+				WithNowFunc(func() time.Time { return now }),
+				WithAuditSink(sink),
+				WithCustomRoleManager(tc.manager),
+			)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			resp, gotErr := h.DoCustomRole(ctx, req, &v1alpha1.IAMRequestWrapper{
+				StartTime: now,
+				Duration:  time.Hour,
+			})
+			if diff := testutil.DiffErrString(gotErr, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+
+			gotEvents := make([]string, 0, len(sink.events))
+			for _, e := range sink.events {
+				gotEvents = append(gotEvents, e.Type)
+			}
+			if diff := cmp.Diff(tc.wantEvents, gotEvents); diff != "" {
+				t.Errorf("Process(%+v) got audit event types diff (-want, +got): %v", tc.name, diff)
+			}
+			if got := len(tc.manager.deleted) > 0; got != tc.wantDeleted {
+				t.Errorf("Process(%+v) got role deleted %t, want %t", tc.name, got, tc.wantDeleted)
+			}
+			if !tc.wantBound {
+				return
+			}
+
+			if !strings.HasPrefix(resp.Role.Name, "projects/baz/roles/"+customrole.IDPrefix) {
+				t.Errorf("Process(%+v) got role name %q, want an AOD custom role of projects/baz", tc.name, resp.Role.Name)
+			}
+			if got, _ := customrole.Expiry(resp.Role.Name); !got.Equal(now.Add(time.Hour)) {
+				t.Errorf("Process(%+v) got role expiry %s, want %s", tc.name, got, now.Add(time.Hour))
+			}
+			if diff := cmp.Diff(req.Permissions, resp.Role.Permissions); diff != "" {
+				t.Errorf("Process(%+v) got role permissions diff (-want, +got): %v", tc.name, diff)
+			}
+			if !slices.ContainsFunc(tc.projectServer.policy.GetBindings(), func(b *iampb.Binding) bool {
+				return b.GetRole() == resp.Role.Name && slices.Equal(b.GetMembers(), req.Members)
+			}) {
+				t.Errorf("Process(%+v) got no binding of role %q in policy %v", tc.name, resp.Role.Name, tc.projectServer.policy)
+			}
+		})
+	}
+}
+
+func TestCleanupCustomRoles(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	expiredRole := "projects/baz/roles/" + customrole.RoleID(now.Add(-time.Hour), "expired")
+	activeRole := "projects/baz/roles/" + customrole.RoleID(now.Add(time.Hour), "active")
+	otherRole := "projects/baz/roles/custom_viewer"
+
+	cases := []struct {
+		name          string
+		manager       *fakeCustomRoleManager
+		wantDeleted   []string
+		wantErrSubstr string
+	}{
+		{
+			name: "delete_expired_roles",
+			manager: &fakeCustomRoleManager{
+				roles: []*customrole.Role{{Name: expiredRole}, {Name: activeRole}, {Name: otherRole}},
+			},
+			wantDeleted: []string{expiredRole},
+		},
+		{
+			name:          "list_failure",
+			manager:       &fakeCustomRoleManager{listErr: fmt.Errorf("injected error")},
+			wantErrSubstr: "failed to list custom roles of projects/baz: injected error",
+		},
+		{
+			name: "delete_failure",
+			manager: &fakeCustomRoleManager{
+				roles:     []*customrole.Role{{Name: expiredRole}},
+				deleteErr: fmt.Errorf("injected error"),
+			},
+			wantErrSubstr: fmt.Sprintf("failed to delete custom role %q: injected error", expiredRole),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+			)
+
+			h, err := NewIAMHandler(
+				ctx,
+				fakeOrganizationsClient,
+				fakeFoldersClient,
+				fakeProjectsClient,
+				WithNowFunc(func() time.Time { return now }),
+				WithCustomRoleManager(tc.manager),
+			)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			got, gotErr := h.CleanupCustomRoles(ctx, []string{"projects/baz"})
+			if diff := testutil.DiffErrString(gotErr, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantDeleted, got); diff != "" {
+				t.Errorf("Process(%+v) got deleted roles diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+type fakeCustomRoleManager struct {
+	roles     []*customrole.Role
+	createErr error
+	listErr   error
+	deleteErr error
+	deleted   []string
+}
+
+func (m *fakeCustomRoleManager) CreateRole(ctx context.Context, parent, roleID string, r *customrole.Role) (*customrole.Role, error) {
+	if m.createErr != nil {
+		return nil, m.createErr
+	}
+	role := *r
+	role.Name = parent + "/roles/" + roleID
+	return &role, nil
+}
+
+func (m *fakeCustomRoleManager) ListRoles(ctx context.Context, parent string) ([]*customrole.Role, error) {
+	return m.roles, m.listErr
+}
+
+func (m *fakeCustomRoleManager) DeleteRole(ctx context.Context, name string) error {
+	if m.deleteErr != nil {
+		return m.deleteErr
+	}
+	m.deleted = append(m.deleted, name)
+	return nil
+}
@@ -0,0 +1,208 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+	"github.com/abcxyz/access-on-demand/pkg/denypolicy"
+)
+
+// denyPolicyDisplayName is the display name of the deny policies created by
+// AOD.
+const denyPolicyDisplayName = "AOD temporary deny policy"
+
+// DenyPolicyManager is the interface to create, list and delete the IAM deny
+// policies of organizations, folders and projects.
+type DenyPolicyManager interface {
+	CreatePolicy(ctx context.Context, resource, policyID string, p *denypolicy.Policy) (*denypolicy.Policy, error)
+	ListPolicies(ctx context.Context, resource string) ([]*denypolicy.Policy, error)
+	DeletePolicy(ctx context.Context, name string) error
+}
+
+// WithDenyPolicyManager provides the manager of deny policies, which is
+// required to handle requests with deny policies.
+func WithDenyPolicyManager(m DenyPolicyManager) Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		p.denyPolicyManager = m
+		return p, nil
+	}
+}
+
+// doDenyPolicies creates the deny policies of the request, which expire with
+// the request. The expiry is encoded in the IDs of the policies, so that
+// Cleanup deletes them once they expire.
+func (h *IAMHandler) doDenyPolicies(ctx context.Context, w *v1alpha1.IAMRequestWrapper) error {
+	expiry := w.StartTime.Add(w.Duration)
+
+	var retErr error
+	for _, p := range w.DenyPolicies {
+		if err := h.createDenyPolicy(ctx, p, w, expiry); err != nil {
+			retErr = errors.Join(retErr, err)
+		}
+	}
+	return retErr
+}
+
+// createDenyPolicy creates the deny policy expiring at expiry, and writes the
+// audit event.
+func (h *IAMHandler) createDenyPolicy(ctx context.Context, p *v1alpha1.DenyPolicy, w *v1alpha1.IAMRequestWrapper, expiry time.Time) error {
+	if err := h.checkAllowed(p.Resource); err != nil {
+		return fmt.Errorf("failed to create deny policy on %s: %w", p.Resource, err)
+	}
+	policy, err := toDenyPolicy(p)
+	if err != nil {
+		return fmt.Errorf("failed to create deny policy on %s: %w", p.Resource, err)
+	}
+
+	id := denypolicy.PolicyID(expiry, denyPolicySeed(p))
+	err = h.spendAPICall()
+	if err == nil {
+		_, err = h.denyPolicyManager.CreatePolicy(ctx, p.Resource, id, policy)
+	}
+	h.writeDenyPolicyEvent(ctx, audit.EventTypeCreateDenyPolicy, p, denypolicy.Parent(p.Resource)+"/"+id, w, err)
+	if err != nil {
+		return fmt.Errorf("failed to create deny policy on %s: %w", p.Resource, err)
+	}
+	return nil
+}
+
+// cleanupDenyPolicies deletes the AOD deny policies of the rules of the deny
+// policies, and any expired AOD deny policies of their resources.
+func (h *IAMHandler) cleanupDenyPolicies(ctx context.Context, ps []*v1alpha1.DenyPolicy) error {
+	// The seed hashes of the requested policies by resource.
+	requested := make(map[string][]string)
+	var resources []string
+	for _, p := range ps {
+		if _, ok := requested[p.Resource]; !ok {
+			resources = append(resources, p.Resource)
+		}
+		requested[p.Resource] = append(requested[p.Resource], denypolicy.SeedHash(denyPolicySeed(p)))
+	}
+
+	now := h.now()
+	var retErr error
+	for _, res := range resources {
+		if err := h.spendAPICall(); err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("failed to list deny policies of %s: %w", res, err))
+			continue
+		}
+		policies, err := h.denyPolicyManager.ListPolicies(ctx, res)
+		if err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("failed to list deny policies of %s: %w", res, err))
+			continue
+		}
+		for _, policy := range policies {
+			exp, ok := denypolicy.Expiry(policy.Name)
+			if !ok {
+				continue
+			}
+			if exp.After(now) && !slices.ContainsFunc(requested[res], func(hash string) bool {
+				return strings.HasSuffix(path.Base(policy.Name), "-"+hash)
+			}) {
+				continue
+			}
+
+			err := h.spendAPICall()
+			if err == nil {
+				err = h.denyPolicyManager.DeletePolicy(ctx, policy.Name)
+			}
+			h.writeDenyPolicyEvent(ctx, audit.EventTypeDeleteDenyPolicy, &v1alpha1.DenyPolicy{Resource: res}, policy.Name, nil, err)
+			if err != nil {
+				retErr = errors.Join(retErr, fmt.Errorf("failed to delete deny policy %q: %w", policy.Name, err))
+			}
+		}
+	}
+	return retErr
+}
+
+// writeDenyPolicyEvent writes the audit event of creating or deleting the deny
+// policy of the name in the resource. The denied members are recorded as the
+// members of a binding of the policy name.
+func (h *IAMHandler) writeDenyPolicyEvent(ctx context.Context, typ string, p *v1alpha1.DenyPolicy, name string, w *v1alpha1.IAMRequestWrapper, err error) {
+	if len(h.auditSinks) == 0 {
+		return
+	}
+	var members, permissions []string
+	for _, r := range p.Rules {
+		members = append(members, r.DeniedMembers...)
+		permissions = append(permissions, r.DeniedPermissions...)
+	}
+	rp := &v1alpha1.ResourcePolicy{
+		Resource: p.Resource,
+		Bindings: []*v1alpha1.Binding{{Members: members, Role: name}},
+	}
+	e := h.newAuditEvent(ctx, typ, rp, w, err)
+	e.Permissions = permissions
+	h.writeAuditSinks(ctx, e)
+}
+
+// toDenyPolicy converts the deny policy of a request to a deny policy of the
+// IAM deny API.
+func toDenyPolicy(p *v1alpha1.DenyPolicy) (*denypolicy.Policy, error) {
+	policy := &denypolicy.Policy{DisplayName: denyPolicyDisplayName}
+	for _, r := range p.Rules {
+		denied, err := toPrincipals(r.DeniedMembers)
+		if err != nil {
+			return nil, err
+		}
+		exceptions, err := toPrincipals(r.ExceptionMembers)
+		if err != nil {
+			return nil, err
+		}
+		policy.Rules = append(policy.Rules, &denypolicy.Rule{
+			DeniedPrincipals:    denied,
+			ExceptionPrincipals: exceptions,
+			DeniedPermissions:   r.DeniedPermissions,
+		})
+	}
+	return policy, nil
+}
+
+// toPrincipals converts the IAM members to the principal identifiers of the
+// IAM deny API.
+func toPrincipals(members []string) ([]string, error) {
+	var principals []string
+	for _, m := range members {
+		p, err := denypolicy.Principal(m)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert member: %w", err)
+		}
+		principals = append(principals, p)
+	}
+	return principals, nil
+}
+
+// denyPolicySeed returns the seed of the ID of the deny policy, which is the
+// same for the same resource and rules, so that Cleanup finds the policies of
+// a request.
+func denyPolicySeed(p *v1alpha1.DenyPolicy) string {
+	parts := []string{p.Resource}
+	for _, r := range p.Rules {
+		parts = append(parts,
+			strings.Join(r.DeniedMembers, ","),
+			strings.Join(r.ExceptionMembers, ","),
+			strings.Join(r.DeniedPermissions, ","))
+	}
+	return strings.Join(parts, "\n")
+}
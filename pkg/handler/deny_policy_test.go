@@ -0,0 +1,263 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+	"github.com/abcxyz/access-on-demand/pkg/denypolicy"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestDoDenyPolicies(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	denyPolicy := &v1alpha1.DenyPolicy{
+		Resource: "projects/baz",
+		Rules: []*v1alpha1.DenyRule{{
+			DeniedMembers:     []string{"group:contractors@example.com"},
+			ExceptionMembers:  []string{"user:alice@example.com"},
+			DeniedPermissions: []string{"storage.googleapis.com/buckets.delete"},
+		}},
+	}
+
+	cases := []struct {
+		name          string
+		manager       *fakeDenyPolicyManager
+		denyPolicies  []*v1alpha1.DenyPolicy
+		wantCreated   []*denypolicy.Policy
+		wantEvents    []string
+		wantErrSubstr string
+	}{
+		{
+			name:         "success",
+			manager:      &fakeDenyPolicyManager{},
+			denyPolicies: []*v1alpha1.DenyPolicy{denyPolicy},
+			wantCreated: []*denypolicy.Policy{{
+				Name:        denypolicy.Parent("projects/baz") + "/" + denypolicy.PolicyID(now.Add(time.Hour), denyPolicySeed(denyPolicy)),
+				DisplayName: denyPolicyDisplayName,
+				Rules: []*denypolicy.Rule{{
+					DeniedPrincipals:    []string{"principalSet://goog/group/contractors@example.com"},
+					ExceptionPrincipals: []string{"principal://goog/subject/alice@example.com"},
+					DeniedPermissions:   []string{"storage.googleapis.com/buckets.delete"},
+				}},
+			}},
+			wantEvents: []string{audit.EventTypeCreateDenyPolicy},
+		},
+		{
+			name:          "create_failure",
+			manager:       &fakeDenyPolicyManager{createErr: fmt.Errorf("injected error")},
+			denyPolicies:  []*v1alpha1.DenyPolicy{denyPolicy},
+			wantEvents:    []string{audit.EventTypeCreateDenyPolicy},
+			wantErrSubstr: "failed to create deny policy on projects/baz: injected error",
+		},
+		{
+			name:    "unsupported_member",
+			manager: &fakeDenyPolicyManager{},
+			denyPolicies: []*v1alpha1.DenyPolicy{{
+				Resource: "projects/baz",
+				Rules: []*v1alpha1.DenyRule{{
+					DeniedMembers:     []string{"domain:example.com"},
+					DeniedPermissions: []string{"storage.googleapis.com/buckets.delete"},
+				}},
+			}},
+			wantEvents:    []string{},
+			wantErrSubstr: "cannot be denied",
+		},
+		{
+			name:          "missing_manager",
+			denyPolicies:  []*v1alpha1.DenyPolicy{denyPolicy},
+			wantEvents:    []string{},
+			wantErrSubstr: "deny policy manager is required to handle deny policies",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+			)
+
+			sink := &fakeAuditSink{}
+			opts := []Option{
+				WithNowFunc(func() time.Time { return now }),
+				WithAuditSink(sink),
+			}
+			if tc.manager != nil {
+				opts = append(opts, WithDenyPolicyManager(tc.manager))
+			}
+			h, err := NewIAMHandler(ctx, fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient, opts...)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			_, gotErr := h.Do(ctx, &v1alpha1.IAMRequestWrapper{
+				IAMRequest: &v1alpha1.IAMRequest{DenyPolicies: tc.denyPolicies},
+				StartTime:  now,
+				Duration:   time.Hour,
+			})
+			if diff := testutil.DiffErrString(gotErr, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+
+			gotEvents := make([]string, 0, len(sink.events))
+			for _, e := range sink.events {
+				gotEvents = append(gotEvents, e.Type)
+			}
+			if diff := cmp.Diff(tc.wantEvents, gotEvents); diff != "" {
+				t.Errorf("Process(%+v) got audit event types diff (-want, +got): %v", tc.name, diff)
+			}
+			if tc.manager == nil {
+				return
+			}
+			if diff := cmp.Diff(tc.wantCreated, tc.manager.created); diff != "" {
+				t.Errorf("Process(%+v) got created deny policies diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestCleanupDenyPolicies(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	denyPolicy := &v1alpha1.DenyPolicy{
+		Resource: "projects/baz",
+		Rules: []*v1alpha1.DenyRule{{
+			DeniedMembers:     []string{"group:contractors@example.com"},
+			DeniedPermissions: []string{"storage.googleapis.com/buckets.delete"},
+		}},
+	}
+	parent := denypolicy.Parent("projects/baz")
+	requestedPolicy := parent + "/" + denypolicy.PolicyID(now.Add(time.Hour), denyPolicySeed(denyPolicy))
+	expiredPolicy := parent + "/" + denypolicy.PolicyID(now.Add(-time.Hour), "expired")
+	activePolicy := parent + "/" + denypolicy.PolicyID(now.Add(time.Hour), "active")
+	otherPolicy := parent + "/deny-all-deletes"
+
+	cases := []struct {
+		name          string
+		manager       *fakeDenyPolicyManager
+		wantDeleted   []string
+		wantErrSubstr string
+	}{
+		{
+			name: "delete_requested_and_expired_policies",
+			manager: &fakeDenyPolicyManager{
+				policies: []*denypolicy.Policy{
+					{Name: requestedPolicy},
+					{Name: expiredPolicy},
+					{Name: activePolicy},
+					{Name: otherPolicy},
+				},
+			},
+			wantDeleted: []string{requestedPolicy, expiredPolicy},
+		},
+		{
+			name:          "list_failure",
+			manager:       &fakeDenyPolicyManager{listErr: fmt.Errorf("injected error")},
+			wantErrSubstr: "failed to list deny policies of projects/baz: injected error",
+		},
+		{
+			name: "delete_failure",
+			manager: &fakeDenyPolicyManager{
+				policies:  []*denypolicy.Policy{{Name: expiredPolicy}},
+				deleteErr: fmt.Errorf("injected error"),
+			},
+			wantErrSubstr: fmt.Sprintf("failed to delete deny policy %q: injected error", expiredPolicy),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+			)
+
+			h, err := NewIAMHandler(
+				ctx,
+				fakeOrganizationsClient,
+				fakeFoldersClient,
+				fakeProjectsClient,
+				WithNowFunc(func() time.Time { return now }),
+				WithDenyPolicyManager(tc.manager),
+			)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			_, gotErr := h.Cleanup(ctx, &v1alpha1.IAMRequest{DenyPolicies: []*v1alpha1.DenyPolicy{denyPolicy}})
+			if diff := testutil.DiffErrString(gotErr, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantDeleted, tc.manager.deleted); diff != "" {
+				t.Errorf("Process(%+v) got deleted deny policies diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+type fakeDenyPolicyManager struct {
+	policies  []*denypolicy.Policy
+	createErr error
+	listErr   error
+	deleteErr error
+	created   []*denypolicy.Policy
+	deleted   []string
+}
+
+func (m *fakeDenyPolicyManager) CreatePolicy(ctx context.Context, resource, policyID string, p *denypolicy.Policy) (*denypolicy.Policy, error) {
+	if m.createErr != nil {
+		return nil, m.createErr
+	}
+	policy := *p
+	policy.Name = denypolicy.Parent(resource) + "/" + policyID
+	m.created = append(m.created, &policy)
+	return &policy, nil
+}
+
+func (m *fakeDenyPolicyManager) ListPolicies(ctx context.Context, resource string) ([]*denypolicy.Policy, error) {
+	return m.policies, m.listErr
+}
+
+func (m *fakeDenyPolicyManager) DeletePolicy(ctx context.Context, name string) error {
+	if m.deleteErr != nil {
+		return m.deleteErr
+	}
+	m.deleted = append(m.deleted, name)
+	return nil
+}
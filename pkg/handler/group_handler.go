@@ -0,0 +1,199 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+	"github.com/abcxyz/access-on-demand/pkg/group"
+)
+
+// groupMemberRole is the role of group members in audit events.
+const groupMemberRole = "MEMBER"
+
+// GroupManager is the interface to get, add, update and remove the memberships
+// of Google Groups.
+type GroupManager interface {
+	GetMembership(ctx context.Context, group, member string) (*group.Membership, error)
+	AddMembership(ctx context.Context, group, member string, expiry time.Time) error
+	UpdateExpiry(ctx context.Context, group, member string, expiry time.Time) error
+	RemoveMembership(ctx context.Context, group, member string) error
+}
+
+// GroupResponse is the response of a handled or cleaned up GroupRequest.
+type GroupResponse struct {
+	// Group of the request.
+	Group string `yaml:"group"`
+
+	// Members added to the group or whose memberships were extended, or the
+	// members removed from the group by a cleanup.
+	Members []string `yaml:"members,omitempty"`
+
+	// Expiry of the memberships of the members, not set for cleanups.
+	Expiry *time.Time `yaml:"expiry,omitempty"`
+
+	// Warnings of the members whose memberships were left unchanged.
+	Warnings []string `yaml:"warnings,omitempty"`
+}
+
+// GroupHandler handles GroupRequests by adding the members to Google Groups
+// with an expiry, which Cloud Identity removes once it passes. It shares the
+// maintenance mode, API call budget, audit sinks and correlation ID of the
+// IAMHandler it is created with.
+type GroupHandler struct {
+	iam     *IAMHandler
+	manager GroupManager
+}
+
+// NewGroupHandler creates a new GroupHandler with the IAMHandler and the
+// manager of group memberships.
+func NewGroupHandler(h *IAMHandler, m GroupManager) (*GroupHandler, error) {
+	if h == nil {
+		return nil, fmt.Errorf("IAM handler is required")
+	}
+	if m == nil {
+		return nil, fmt.Errorf("group manager is required")
+	}
+	return &GroupHandler{iam: h, manager: m}, nil
+}
+
+// Do adds the members of the request to the group until the expiry of the
+// wrapper. The memberships of existing temporary members are extended to the
+// expiry, but never shortened, and permanent members are left unchanged, with
+// warnings in the response. Errors of all the members are returned.
+func (h *GroupHandler) Do(ctx context.Context, r *v1alpha1.GroupRequest, w *v1alpha1.IAMRequestWrapper) (*GroupResponse, error) {
+	if err := h.iam.checkMaintenance(); err != nil {
+		return nil, err
+	}
+
+	expiry := w.StartTime.Add(w.Duration)
+	resp := &GroupResponse{Group: r.Group, Expiry: &expiry}
+	var retErr error
+	for _, m := range r.Members {
+		changed, warning, err := h.addMember(ctx, r.Group, m, expiry)
+		if err != nil {
+			retErr = errors.Join(retErr, err)
+		}
+		if changed {
+			h.writeGroupEvent(ctx, audit.EventTypeAddGroupMember, r.Group, m, w, err)
+			if err == nil {
+				resp.Members = append(resp.Members, m)
+			}
+		}
+		if warning != "" {
+			resp.Warnings = append(resp.Warnings, warning)
+		}
+	}
+	return resp, retErr
+}
+
+// addMember adds the member to the group until the expiry, or extends its
+// membership to the expiry, and returns whether the membership was changed, or
+// a warning why it was not.
+func (h *GroupHandler) addMember(ctx context.Context, g, member string, expiry time.Time) (bool, string, error) {
+	if err := h.iam.spendAPICall(); err != nil {
+		return false, "", fmt.Errorf("failed to get membership of %s: %w", member, err)
+	}
+	cur, err := h.manager.GetMembership(ctx, g, member)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to get membership of %s: %w", member, err)
+	}
+
+	switch {
+	case cur == nil:
+		err = h.iam.spendAPICall()
+		if err == nil {
+			err = h.manager.AddMembership(ctx, g, member, expiry)
+		}
+		if err != nil {
+			return true, "", fmt.Errorf("failed to add %s to group %s: %w", member, g, err)
+		}
+	case cur.Expiry == nil:
+		return false, fmt.Sprintf("%s is a permanent member of group %s, its membership is not changed", member, g), nil
+	case !cur.Expiry.Before(expiry):
+		return false, fmt.Sprintf("membership of %s in group %s already expires at %s, it is not shortened",
+			member, g, cur.Expiry.UTC().Format(time.RFC3339)), nil
+	default:
+		err = h.iam.spendAPICall()
+		if err == nil {
+			err = h.manager.UpdateExpiry(ctx, g, member, expiry)
+		}
+		if err != nil {
+			return true, "", fmt.Errorf("failed to extend membership of %s in group %s: %w", member, g, err)
+		}
+	}
+	return true, "", nil
+}
+
+// Cleanup removes the temporary members of the request from the group before
+// their memberships expire, such as when the access is no longer needed.
+// Permanent members and non-members are left unchanged, with warnings in the
+// response. Errors of all the members are returned.
+func (h *GroupHandler) Cleanup(ctx context.Context, r *v1alpha1.GroupRequest) (*GroupResponse, error) {
+	resp := &GroupResponse{Group: r.Group}
+	var retErr error
+	for _, m := range r.Members {
+		if err := h.iam.spendAPICall(); err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("failed to get membership of %s: %w", m, err))
+			continue
+		}
+		cur, err := h.manager.GetMembership(ctx, r.Group, m)
+		if err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("failed to get membership of %s: %w", m, err))
+			continue
+		}
+		switch {
+		case cur == nil:
+			resp.Warnings = append(resp.Warnings, fmt.Sprintf("%s is not a member of group %s", m, r.Group))
+			continue
+		case cur.Expiry == nil:
+			resp.Warnings = append(resp.Warnings,
+				fmt.Sprintf("%s is a permanent member of group %s, it is not removed", m, r.Group))
+			continue
+		}
+
+		err = h.iam.spendAPICall()
+		if err == nil {
+			err = h.manager.RemoveMembership(ctx, r.Group, m)
+		}
+		h.writeGroupEvent(ctx, audit.EventTypeRemoveGroupMember, r.Group, m, nil, err)
+		if err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("failed to remove %s from group %s: %w", m, r.Group, err))
+			continue
+		}
+		resp.Members = append(resp.Members, m)
+	}
+	return resp, retErr
+}
+
+// writeGroupEvent writes the audit event of adding or removing the member of
+// the group, with the group as the resource of the event.
+func (h *GroupHandler) writeGroupEvent(ctx context.Context, typ, g, member string, w *v1alpha1.IAMRequestWrapper, err error) {
+	if len(h.iam.auditSinks) == 0 {
+		return
+	}
+	e := h.iam.newAuditEvent(ctx, typ, &v1alpha1.ResourcePolicy{
+		Resource: "groups/" + g,
+		Bindings: []*v1alpha1.Binding{{Role: groupMemberRole, Members: []string{member}}},
+	}, w, err)
+	// Group memberships have no IAM conditions.
+	e.ConditionTitle = ""
+	h.iam.writeAuditSinks(ctx, e)
+}
@@ -0,0 +1,266 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+	"github.com/abcxyz/access-on-demand/pkg/group"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+// fakeGroupManager is a fake manager of the memberships of a group, by the
+// members.
+type fakeGroupManager struct {
+	memberships map[string]*group.Membership
+	addErr      error
+	removeErr   error
+}
+
+func (m *fakeGroupManager) GetMembership(_ context.Context, _, member string) (*group.Membership, error) {
+	return m.memberships[member], nil
+}
+
+func (m *fakeGroupManager) AddMembership(_ context.Context, _, member string, expiry time.Time) error {
+	if m.addErr != nil {
+		return m.addErr
+	}
+	m.memberships[member] = &group.Membership{Member: member, Expiry: &expiry}
+	return nil
+}
+
+func (m *fakeGroupManager) UpdateExpiry(_ context.Context, _, member string, expiry time.Time) error {
+	m.memberships[member].Expiry = &expiry
+	return nil
+}
+
+func (m *fakeGroupManager) RemoveMembership(_ context.Context, _, member string) error {
+	if m.removeErr != nil {
+		return m.removeErr
+	}
+	delete(m.memberships, member)
+	return nil
+}
+
+func TestGroupHandler(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	expiry := now.Add(time.Hour)
+	earlier := now.Add(time.Minute)
+	later := now.Add(2 * time.Hour)
+
+	cases := []struct {
+		name          string
+		memberships   map[string]*group.Membership
+		addErr        error
+		removeErr     error
+		maintenance   string
+		cleanup       bool
+		wantResp      *GroupResponse
+		wantExpiries  map[string]*time.Time
+		wantEvents    []string
+		wantErrSubstr string
+	}{
+		{
+			name:        "add_members",
+			memberships: map[string]*group.Membership{},
+			wantResp: &GroupResponse{
+				Group:   "admins@example.com",
+				Members: []string{"user:alice@example.com", "user:bob@example.com"},
+				Expiry:  &expiry,
+			},
+			wantExpiries: map[string]*time.Time{"user:alice@example.com": &expiry, "user:bob@example.com": &expiry},
+			wantEvents:   []string{audit.EventTypeAddGroupMember, audit.EventTypeAddGroupMember},
+		},
+		{
+			name: "existing_members",
+			memberships: map[string]*group.Membership{
+				"user:alice@example.com": {Member: "user:alice@example.com", Expiry: &earlier},
+				"user:bob@example.com":   {Member: "user:bob@example.com"},
+			},
+			wantResp: &GroupResponse{
+				Group:    "admins@example.com",
+				Members:  []string{"user:alice@example.com"},
+				Expiry:   &expiry,
+				Warnings: []string{"user:bob@example.com is a permanent member of group admins@example.com, its membership is not changed"},
+			},
+			wantExpiries: map[string]*time.Time{"user:alice@example.com": &expiry, "user:bob@example.com": nil},
+			wantEvents:   []string{audit.EventTypeAddGroupMember},
+		},
+		{
+			name: "not_shortened",
+			memberships: map[string]*group.Membership{
+				"user:alice@example.com": {Member: "user:alice@example.com", Expiry: &later},
+			},
+			wantResp: &GroupResponse{
+				Group:    "admins@example.com",
+				Members:  []string{"user:bob@example.com"},
+				Expiry:   &expiry,
+				Warnings: []string{"membership of user:alice@example.com in group admins@example.com already expires at 2009-11-11T01:00:00Z, it is not shortened"},
+			},
+			wantExpiries: map[string]*time.Time{"user:alice@example.com": &later, "user:bob@example.com": &expiry},
+			wantEvents:   []string{audit.EventTypeAddGroupMember},
+		},
+		{
+			name:        "add_failure",
+			memberships: map[string]*group.Membership{},
+			addErr:      fmt.Errorf("injected error"),
+			wantResp: &GroupResponse{
+				Group:  "admins@example.com",
+				Expiry: &expiry,
+			},
+			wantExpiries:  map[string]*time.Time{},
+			wantEvents:    []string{audit.EventTypeAddGroupMember, audit.EventTypeAddGroupMember},
+			wantErrSubstr: "failed to add user:alice@example.com to group admins@example.com: injected error",
+		},
+		{
+			name:          "maintenance",
+			memberships:   map[string]*group.Membership{},
+			maintenance:   "IAM freeze",
+			wantExpiries:  map[string]*time.Time{},
+			wantErrSubstr: "AOD is in maintenance mode",
+		},
+		{
+			name: "cleanup",
+			memberships: map[string]*group.Membership{
+				"user:alice@example.com": {Member: "user:alice@example.com", Expiry: &expiry},
+				"user:bob@example.com":   {Member: "user:bob@example.com"},
+			},
+			maintenance: "cleanups keep working",
+			cleanup:     true,
+			wantResp: &GroupResponse{
+				Group:   "admins@example.com",
+				Members: []string{"user:alice@example.com"},
+				Warnings: []string{
+					"user:bob@example.com is a permanent member of group admins@example.com, it is not removed",
+				},
+			},
+			wantExpiries: map[string]*time.Time{"user:bob@example.com": nil},
+			wantEvents:   []string{audit.EventTypeRemoveGroupMember},
+		},
+		{
+			name:        "cleanup_not_member",
+			memberships: map[string]*group.Membership{},
+			cleanup:     true,
+			wantResp: &GroupResponse{
+				Group: "admins@example.com",
+				Warnings: []string{
+					"user:alice@example.com is not a member of group admins@example.com",
+					"user:bob@example.com is not a member of group admins@example.com",
+				},
+			},
+			wantExpiries: map[string]*time.Time{},
+		},
+		{
+			name: "cleanup_failure",
+			memberships: map[string]*group.Membership{
+				"user:alice@example.com": {Member: "user:alice@example.com", Expiry: &expiry},
+			},
+			removeErr:     fmt.Errorf("injected error"),
+			cleanup:       true,
+			wantResp:      &GroupResponse{Group: "admins@example.com", Warnings: []string{"user:bob@example.com is not a member of group admins@example.com"}},
+			wantExpiries:  map[string]*time.Time{"user:alice@example.com": &expiry},
+			wantEvents:    []string{audit.EventTypeRemoveGroupMember},
+			wantErrSubstr: "failed to remove user:alice@example.com from group admins@example.com: injected error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+			)
+
+			sink := &fakeAuditSink{}
+			opts := []Option{WithNowFunc(func() time.Time { return now }), WithAuditSink(sink)}
+			if tc.maintenance != "" {
+				opts = append(opts, WithMaintenance(tc.maintenance))
+			}
+			h, err := NewIAMHandler(ctx, fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient, opts...)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+			m := &fakeGroupManager{memberships: tc.memberships, addErr: tc.addErr, removeErr: tc.removeErr}
+			gh, err := NewGroupHandler(h, m)
+			if err != nil {
+				t.Fatalf("failed to create GroupHandler: %v", err)
+			}
+
+			req := &v1alpha1.GroupRequest{
+				Group:   "admins@example.com",
+				Members: []string{"user:alice@example.com", "user:bob@example.com"},
+			}
+			var resp *GroupResponse
+			var gotErr error
+			if tc.cleanup {
+				resp, gotErr = gh.Cleanup(ctx, req)
+			} else {
+				resp, gotErr = gh.Do(ctx, req, &v1alpha1.IAMRequestWrapper{StartTime: now, Duration: time.Hour})
+			}
+			if diff := testutil.DiffErrString(gotErr, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantResp, resp); diff != "" {
+				t.Errorf("Process(%+v) got response diff (-want, +got): %v", tc.name, diff)
+			}
+
+			gotExpiries := make(map[string]*time.Time, len(m.memberships))
+			for member, ms := range m.memberships {
+				gotExpiries[member] = ms.Expiry
+			}
+			if diff := cmp.Diff(tc.wantExpiries, gotExpiries); diff != "" {
+				t.Errorf("Process(%+v) got memberships diff (-want, +got): %v", tc.name, diff)
+			}
+
+			var gotEvents []string
+			for _, e := range sink.events {
+				gotEvents = append(gotEvents, e.Type)
+				if e.Resource != "groups/admins@example.com" {
+					t.Errorf("Process(%+v) got event resource %q, want %q", tc.name, e.Resource, "groups/admins@example.com")
+				}
+			}
+			if diff := cmp.Diff(tc.wantEvents, gotEvents); diff != "" {
+				t.Errorf("Process(%+v) got audit events diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestNewGroupHandler(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewGroupHandler(&IAMHandler{}, nil); err == nil {
+		t.Errorf("NewGroupHandler got no error without a group manager")
+	}
+	if _, err := NewGroupHandler(nil, &fakeGroupManager{}); err == nil {
+		t.Errorf("NewGroupHandler got no error without an IAM handler")
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+)
+
+// Diff returns the IAM binding changes that Do would make to the current IAM
+// policies of the resources in the request, without updating any IAM policy.
+func (h *IAMHandler) Diff(ctx context.Context, r *v1alpha1.IAMRequestWrapper) (diffs []*v1alpha1.IAMPolicyDiff, retErr error) {
+	expiry := r.StartTime.Add(r.Duration)
+	desc := conditionDescription(h.requestMetadata(withRequester(ctx, r.Requester)).Requester, r.Approvers)
+	recurring, err := compileRecurring(r.Recurring)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range r.ResourcePolicies {
+		d, err := h.diffPolicy(ctx, p, r.StartTime, expiry, desc, recurring)
+		if err != nil {
+			retErr = errors.Join(
+				retErr,
+				fmt.Errorf("failed to diff policy for resource %s: %w", p.Resource, err),
+			)
+			continue
+		}
+		diffs = append(diffs, d)
+	}
+	return
+}
+
+func (h *IAMHandler) diffPolicy(ctx context.Context, p *v1alpha1.ResourcePolicy, start, expiry time.Time, description string, recurring *recurringCondition) (*v1alpha1.IAMPolicyDiff, error) {
+	cp, err := h.currentPolicy(ctx, p.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	np, ok := proto.Clone(cp).(*iampb.Policy)
+	if !ok {
+		return nil, fmt.Errorf("failed to clone IAM policy")
+	}
+	// addBindings always returns nil error.
+	_ = h.addBindings(ctx, np, p.Bindings, start, expiry, description, recurring)
+
+	added, removed := diffBindings(cp.GetBindings(), np.GetBindings())
+	return &v1alpha1.IAMPolicyDiff{
+		Resource: p.Resource,
+		Added:    added,
+		Removed:  removed,
+	}, nil
+}
+
+// diffBindings returns the member level binding changes from the before
+// bindings to the after bindings, sorted by role, member and condition.
+func diffBindings(before, after []*iampb.Binding) (added, removed []*v1alpha1.BindingChange) {
+	beforeSet := toBindingChangeSet(before)
+	afterSet := toBindingChangeSet(after)
+	for c := range afterSet {
+		if _, ok := beforeSet[c]; !ok {
+			added = append(added, &c)
+		}
+	}
+	for c := range beforeSet {
+		if _, ok := afterSet[c]; !ok {
+			removed = append(removed, &c)
+		}
+	}
+	slices.SortFunc(added, compareBindingChange)
+	slices.SortFunc(removed, compareBindingChange)
+	return
+}
+
+func toBindingChangeSet(bs []*iampb.Binding) map[v1alpha1.BindingChange]struct{} {
+	result := make(map[v1alpha1.BindingChange]struct{})
+	for _, b := range bs {
+		for _, m := range b.GetMembers() {
+			result[v1alpha1.BindingChange{
+				Role:                b.GetRole(),
+				Member:              m,
+				ConditionTitle:      b.GetCondition().GetTitle(),
+				ConditionExpression: b.GetCondition().GetExpression(),
+			}] = struct{}{}
+		}
+	}
+	return result
+}
+
+func compareBindingChange(a, b *v1alpha1.BindingChange) int {
+	return cmp.Or(
+		cmp.Compare(a.Role, b.Role),
+		cmp.Compare(a.Member, b.Member),
+		cmp.Compare(a.ConditionTitle, b.ConditionTitle),
+		cmp.Compare(a.ConditionExpression, b.ConditionExpression),
+	)
+}
+
+// DiffLive returns the statuses of the requested bindings in the live IAM
+// policies of the resources in the request, without updating any IAM policy.
+// A requested binding is present if there is an active AOD binding of the role
+// and member, with the expected expiry unless the expected expiry is zero.
+func (h *IAMHandler) DiffLive(ctx context.Context, r *v1alpha1.IAMRequest, expiry time.Time) (statuses []*v1alpha1.BindingStatus, retErr error) {
+	for _, p := range r.ResourcePolicies {
+		ss, err := h.diffLivePolicy(ctx, p, expiry)
+		if err != nil {
+			retErr = errors.Join(
+				retErr,
+				fmt.Errorf("failed to diff live policy for resource %s: %w", p.Resource, err),
+			)
+			continue
+		}
+		statuses = append(statuses, ss...)
+	}
+	return
+}
+
+func (h *IAMHandler) diffLivePolicy(ctx context.Context, p *v1alpha1.ResourcePolicy, expiry time.Time) ([]*v1alpha1.BindingStatus, error) {
+	cp, err := h.currentPolicy(ctx, p.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect the expiries of the active AOD bindings by role and member.
+	active := make(map[string]map[string][]time.Time)
+	for _, g := range h.activeGrants(p.Resource, cp) {
+		if active[g.Role] == nil {
+			active[g.Role] = make(map[string][]time.Time)
+		}
+		active[g.Role][g.Member] = append(active[g.Role][g.Member], g.Expiry)
+	}
+
+	var result []*v1alpha1.BindingStatus
+	for _, b := range p.Bindings {
+		for _, role := range b.Roles() {
+			for _, m := range b.Members {
+				s := &v1alpha1.BindingStatus{
+					Resource: p.Resource,
+					Role:     role,
+					Member:   m,
+					Status:   v1alpha1.BindingStatusMissing,
+				}
+				if exps := active[role][m]; len(exps) > 0 {
+					latest := slices.MaxFunc(exps, time.Time.Compare)
+					s.Expiry = &latest
+					s.Status = v1alpha1.BindingStatusDifferentExpiry
+					if expiry.IsZero() || slices.ContainsFunc(exps, expiry.Equal) {
+						s.Status = v1alpha1.BindingStatusPresent
+					}
+				}
+				result = append(result, s)
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,349 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sethvargo/go-retry"
+	"google.golang.org/genproto/googleapis/type/expr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC()
+	expiredExpr := fmt.Sprintf("request.time < timestamp('%s')", now.Add(-1*time.Hour).Format(time.RFC3339))
+	newExpr := fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339))
+
+	cases := []struct {
+		name           string
+		projectsServer *fakeServer
+		request        *v1alpha1.IAMRequestWrapper
+		wantDiffs      []*v1alpha1.IAMPolicyDiff
+		wantErrSubstr  string
+		wantPolicy     *iampb.Policy
+	}{
+		{
+			name: "added_and_removed",
+			projectsServer: &fakeServer{
+				policy: &iampb.Policy{
+					Bindings: []*iampb.Binding{
+						// Non-AOD binding to be kept.
+						{
+							Members: []string{"user:test-userA@example.com"},
+							Role:    "roles/viewer",
+						},
+						// Expired binding to be removed.
+						{
+							Members: []string{"user:test-userB@example.com"},
+							Role:    "roles/cloudkms.cryptoOperator",
+							Condition: &expr.Expr{
+								Title:      DefaultConditionTitle,
+								Expression: expiredExpr,
+							},
+						},
+					},
+				},
+			},
+			request: &v1alpha1.IAMRequestWrapper{
+				IAMRequest: &v1alpha1.IAMRequest{
+					ResourcePolicies: []*v1alpha1.ResourcePolicy{
+						{
+							Resource: "projects/baz",
+							Bindings: []*v1alpha1.Binding{
+								{
+									Members: []string{"user:test-userA@example.com"},
+									Role:    "roles/bigquery.dataViewer",
+								},
+							},
+						},
+					},
+				},
+				Duration:  2 * time.Hour,
+				StartTime: now,
+			},
+			wantDiffs: []*v1alpha1.IAMPolicyDiff{
+				{
+					Resource: "projects/baz",
+					Added: []*v1alpha1.BindingChange{
+						{
+							Role:                "roles/bigquery.dataViewer",
+							Member:              "user:test-userA@example.com",
+							ConditionTitle:      DefaultConditionTitle,
+							ConditionExpression: newExpr,
+						},
+					},
+					Removed: []*v1alpha1.BindingChange{
+						{
+							Role:                "roles/cloudkms.cryptoOperator",
+							Member:              "user:test-userB@example.com",
+							ConditionTitle:      DefaultConditionTitle,
+							ConditionExpression: expiredExpr,
+						},
+					},
+				},
+			},
+			// Policy is not updated.
+			wantPolicy: &iampb.Policy{
+				Bindings: []*iampb.Binding{
+					{
+						Members: []string{"user:test-userA@example.com"},
+						Role:    "roles/viewer",
+					},
+					{
+						Members: []string{"user:test-userB@example.com"},
+						Role:    "roles/cloudkms.cryptoOperator",
+						Condition: &expr.Expr{
+							Title:      DefaultConditionTitle,
+							Expression: expiredExpr,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "get_policy_failure",
+			projectsServer: &fakeServer{
+				policy:          &iampb.Policy{},
+				getIAMPolicyErr: status.Error(codes.Internal, "Internal Server Error"),
+			},
+			request: &v1alpha1.IAMRequestWrapper{
+				IAMRequest: &v1alpha1.IAMRequest{
+					ResourcePolicies: []*v1alpha1.ResourcePolicy{
+						{
+							Resource: "projects/baz",
+							Bindings: []*v1alpha1.Binding{
+								{
+									Members: []string{"user:test-userA@example.com"},
+									Role:    "roles/bigquery.dataViewer",
+								},
+							},
+						},
+					},
+				},
+				Duration:  2 * time.Hour,
+				StartTime: now,
+			},
+			wantErrSubstr: "failed to diff policy for resource projects/baz",
+			wantPolicy:    &iampb.Policy{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{},
+				&fakeServer{},
+				tc.projectsServer,
+			)
+
+			h, err := NewIAMHandler(
+				ctx,
+				fakeOrganizationsClient,
+				fakeFoldersClient,
+				fakeProjectsClient,
+				WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+			)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			gotDiffs, gotErr := h.Diff(ctx, tc.request)
+			if diff := testutil.DiffErrString(gotErr, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantDiffs, gotDiffs); diff != "" {
+				t.Errorf("Process(%+v) got diff (-want, +got): %v", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantPolicy, tc.projectsServer.policy, protocmp.Transform()); diff != "" {
+				t.Errorf("Process(%+v) got project policy diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestDiffLive(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	expiry := now.Add(2 * time.Hour)
+	otherExpiry := now.Add(time.Hour)
+	aodBinding := func(exp time.Time, role string, members ...string) *iampb.Binding {
+		return &iampb.Binding{
+			Members: members,
+			Role:    role,
+			Condition: &expr.Expr{
+				Title:      DefaultConditionTitle,
+				Expression: fmt.Sprintf("request.time < timestamp('%s')", exp.Format(time.RFC3339)),
+			},
+		}
+	}
+
+	request := &v1alpha1.IAMRequest{
+		ResourcePolicies: []*v1alpha1.ResourcePolicy{
+			{
+				Resource: "projects/baz",
+				Bindings: []*v1alpha1.Binding{
+					{
+						Members: []string{"user:alice@example.com", "user:bob@example.com"},
+						Role:    "roles/viewer",
+					},
+					{
+						Members: []string{"user:carol@example.com"},
+						Role:    "roles/editor",
+					},
+				},
+			},
+		},
+	}
+	projectsServer := &fakeServer{
+		policy: &iampb.Policy{
+			Bindings: []*iampb.Binding{
+				aodBinding(expiry, "roles/viewer", "user:alice@example.com"),
+				aodBinding(otherExpiry, "roles/viewer", "user:bob@example.com"),
+				// Expired binding is not active.
+				aodBinding(now.Add(-time.Hour), "roles/editor", "user:carol@example.com"),
+				// Non-AOD binding is not an AOD grant.
+				{
+					Members: []string{"user:carol@example.com"},
+					Role:    "roles/editor",
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		name           string
+		projectsServer *fakeServer
+		expiry         time.Time
+		wantStatuses   []*v1alpha1.BindingStatus
+		wantErrSubstr  string
+	}{
+		{
+			name:           "with_expiry",
+			projectsServer: projectsServer,
+			expiry:         expiry,
+			wantStatuses: []*v1alpha1.BindingStatus{
+				{
+					Resource: "projects/baz",
+					Role:     "roles/viewer",
+					Member:   "user:alice@example.com",
+					Status:   v1alpha1.BindingStatusPresent,
+					Expiry:   &expiry,
+				},
+				{
+					Resource: "projects/baz",
+					Role:     "roles/viewer",
+					Member:   "user:bob@example.com",
+					Status:   v1alpha1.BindingStatusDifferentExpiry,
+					Expiry:   &otherExpiry,
+				},
+				{
+					Resource: "projects/baz",
+					Role:     "roles/editor",
+					Member:   "user:carol@example.com",
+					Status:   v1alpha1.BindingStatusMissing,
+				},
+			},
+		},
+		{
+			name:           "without_expiry",
+			projectsServer: projectsServer,
+			wantStatuses: []*v1alpha1.BindingStatus{
+				{
+					Resource: "projects/baz",
+					Role:     "roles/viewer",
+					Member:   "user:alice@example.com",
+					Status:   v1alpha1.BindingStatusPresent,
+					Expiry:   &expiry,
+				},
+				{
+					Resource: "projects/baz",
+					Role:     "roles/viewer",
+					Member:   "user:bob@example.com",
+					Status:   v1alpha1.BindingStatusPresent,
+					Expiry:   &otherExpiry,
+				},
+				{
+					Resource: "projects/baz",
+					Role:     "roles/editor",
+					Member:   "user:carol@example.com",
+					Status:   v1alpha1.BindingStatusMissing,
+				},
+			},
+		},
+		{
+			name: "get_policy_failure",
+			projectsServer: &fakeServer{
+				getIAMPolicyErr: status.Error(codes.PermissionDenied, "injected error"),
+			},
+			wantErrSubstr: "failed to diff live policy for resource projects/baz",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{},
+				&fakeServer{},
+				tc.projectsServer,
+			)
+
+			h, err := NewIAMHandler(
+				ctx,
+				fakeOrganizationsClient,
+				fakeFoldersClient,
+				fakeProjectsClient,
+				WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+				WithNowFunc(func() time.Time { return now }),
+			)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			gotStatuses, gotErr := h.DiffLive(ctx, request, tc.expiry)
+			if diff := testutil.DiffErrString(gotErr, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantStatuses, gotStatuses); diff != "" {
+				t.Errorf("Process(%+v) got statuses diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
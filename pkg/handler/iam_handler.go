@@ -19,27 +19,52 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"net/http"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"cloud.google.com/go/iam/apiv1/iampb"
 	"github.com/googleapis/gax-go/v2"
 	"github.com/sethvargo/go-retry"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/genproto/googleapis/type/expr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+	"github.com/abcxyz/access-on-demand/pkg/iamdelta"
+	"github.com/abcxyz/access-on-demand/pkg/progress"
+	"github.com/abcxyz/access-on-demand/pkg/resource"
+	"github.com/abcxyz/access-on-demand/pkg/ticket"
 	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/workerpool"
 )
 
+// DefaultConditionTitle of IAM bindings added by AOD.
+const DefaultConditionTitle = "abcxyz-aod-expiry"
+
+// maxConditionDescriptionLength is the maximum length of IAM condition
+// descriptions.
+const maxConditionDescriptionLength = 256
+
 var (
-	// defaultConditionTitle of IAM bindings added by AOD.
-	defaultConditionTitle = "abcxyz-aod-expiry"
 	// expirationExpression of IAM binding condition added by AOD.
 	expirationExpression = "request.time < timestamp('%s')"
 	// expirationRegex matching expirationExpression.
 	expirationRegex = regexp.MustCompile(`request.time < timestamp\('([^']+)'\)`)
+	// windowExpression of IAM binding condition added by AOD for bindings
+	// with start offsets, which contains expirationExpression.
+	windowExpression = "request.time >= timestamp('%s') && request.time < timestamp('%s')"
 )
 
 // IAMHandler updates IAM policies of GCP organizations, folders, and projects
@@ -53,6 +78,86 @@ type IAMHandler struct {
 	retry retry.Backoff
 	// Title for IAM bindings expiration condition, default is "abcxyz-aod-expiry".
 	conditionTitle string
+	// Optional function returning the current time, default is time.Now.
+	now func() time.Time
+	// Optional sinks to write audit events to.
+	auditSinks []audit.Sink
+	// Optional max number of resources to handle concurrently, default is 1.
+	concurrency int64
+	// Optional reporter to report progress events to.
+	progressReporter progress.Reporter
+	// Optional publishers to publish events of added or removed bindings to.
+	eventPublishers []EventPublisher
+	// Optional flag to skip the best effort cleanup of expired AOD bindings when
+	// adding bindings, default is false.
+	skipImplicitCleanup bool
+	// Optional identity making the IAM policy changes, recorded in audit events.
+	caller string
+	// Optional creator of tickets for resources AOD is denied permission to.
+	ticketCreator TicketCreator
+	// Optional OpenTelemetry providers, default is the global providers.
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	telemetry      *telemetry
+	// Optional IAM clients of the resources in specific organizations, keyed by
+	// organization name, e.g. "organizations/123".
+	orgClients map[string]*orgClients
+	// Optional resolver of the organizations of folders and projects, required
+	// if orgClients is set.
+	orgResolver OrganizationResolver
+	// Cache of the organizations of folders and projects.
+	orgCache sync.Map
+	// Optional checker of the lifecycle states of resources, to stop retrying
+	// on resources that are not active.
+	stateChecker StateChecker
+	// Optional call options of the GetIamPolicy and SetIamPolicy calls, default
+	// is the defaults of the IAM clients.
+	getPolicyOpts []gax.CallOption
+	setPolicyOpts []gax.CallOption
+	// Optional correlation ID attached to the IAM calls and audit events when
+	// the context does not have one.
+	correlationID string
+	// Optional prefixes and patterns of the resources whose IAM policies can be
+	// modified, default is all resources.
+	allowedPrefixes []string
+	allowedPatterns []*regexp.Regexp
+	// Optional max number of IAM API calls over the lifetime of the handler,
+	// default is no budget.
+	apiCallBudget int64
+	// Number of IAM API calls, including the ones over the budget.
+	apiCalls atomic.Int64
+	// Optional querier of the activity of members during grants, required to
+	// verify usage.
+	usageQuerier UsageQuerier
+	// Optional flag to roll back the updated resources when Do fails for any
+	// resource, default is false.
+	rollbackOnFailure bool
+	// Optional flag to check all the resources are accessible before modifying
+	// any IAM policy in Do and Renew, default is false.
+	preflight bool
+	// Optional interval of polling the IAM policies in WaitForPropagation,
+	// default is 5s.
+	propagationPollInterval time.Duration
+	// Optional manager of custom roles, required to handle custom role
+	// requests.
+	customRoleManager CustomRoleManager
+	// Optional manager of deny policies, required to handle requests with deny
+	// policies.
+	denyPolicyManager DenyPolicyManager
+	// Optional IAMClients of the resources outside of the resource hierarchy,
+	// such as BigQuery datasets and buckets, by resource type. A client is
+	// required to handle the resources of its type.
+	typeClients map[resource.Type]IAMClient
+	// Optional resources hosting the AOD deployment, which cannot be granted
+	// roles on unless allowProtected is set.
+	protectedResources []string
+	allowProtected     bool
+	// Optional flag to guard against stale reads of IAM policies, default is
+	// false.
+	consistentReads bool
+	// Optional reason of the maintenance mode, where no access is granted,
+	// default is not in maintenance mode.
+	maintenanceReason string
 }
 
 // IAMClient is the interface to get and set IAM policies for GCP organizations,
@@ -62,6 +167,18 @@ type IAMClient interface {
 	SetIamPolicy(context.Context, *iampb.SetIamPolicyRequest, ...gax.CallOption) (*iampb.Policy, error)
 }
 
+// EventPublisher is the interface to publish the events of IAM bindings added
+// or removed by AOD, such as to a Pub/Sub topic.
+type EventPublisher interface {
+	Publish(context.Context, *audit.Event) error
+}
+
+// TicketCreator is the interface to open tickets, such as GitHub issues, and
+// return their URLs.
+type TicketCreator interface {
+	Create(context.Context, *ticket.Ticket) (string, error)
+}
+
 // updatePolicy updates the given IAM policy.
 type updatePolicy func(context.Context, *iampb.Policy, []*v1alpha1.Binding, time.Time) error
 
@@ -85,6 +202,179 @@ func WithCustomConditionTitle(title string) Option {
 	}
 }
 
+// WithNowFunc provides a function returning the current time, which is used to
+// check if AOD bindings are expired. It allows pinning the current time for
+// deterministic outputs.
+func WithNowFunc(now func() time.Time) Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		p.now = now
+		return p, nil
+	}
+}
+
+// WithAuditSink provides a sink to write audit events to, it can be provided
+// multiple times to write to multiple sinks. Failures of writing audit events
+// are logged and do not fail the request.
+func WithAuditSink(s audit.Sink) Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		p.auditSinks = append(p.auditSinks, s)
+		return p, nil
+	}
+}
+
+// WithCaller provides the identity making the IAM policy changes, such as
+// "serviceAccount:aod@my-project.iam.gserviceaccount.com", which is recorded
+// in audit events.
+func WithCaller(caller string) Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		p.caller = caller
+		return p, nil
+	}
+}
+
+// WithTicketCreator provides a creator to open a ticket describing the missing
+// permissions when AOD is denied permission to get or set the IAM policy of a
+// resource. Failures of opening tickets are logged and do not change the
+// result of the request.
+func WithTicketCreator(c TicketCreator) Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		p.ticketCreator = c
+		return p, nil
+	}
+}
+
+// WithTelemetry provides the OpenTelemetry providers to create spans and
+// metrics with, instead of the global providers. Either of them can be nil to
+// use the global provider.
+func WithTelemetry(tp trace.TracerProvider, mp metric.MeterProvider) Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		p.tracerProvider = tp
+		p.meterProvider = mp
+		return p, nil
+	}
+}
+
+// WithConcurrency provides the max number of resources to handle concurrently.
+// The responses and errors are reported in the order of the resources in the
+// request regardless of the order in which they are handled.
+func WithConcurrency(n int) Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		if n < 1 {
+			return nil, fmt.Errorf("concurrency must be at least 1, got %d", n)
+		}
+		p.concurrency = int64(n)
+		return p, nil
+	}
+}
+
+// WithProgressReporter provides a reporter to report the progress of handling
+// each resource to, including the retries. Failures of reporting progress
+// events are logged and do not fail the request.
+func WithProgressReporter(r progress.Reporter) Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		p.progressReporter = r
+		return p, nil
+	}
+}
+
+// WithEventPublisher provides a publisher to publish an event to whenever IAM
+// bindings are added or removed, it can be provided multiple times to publish
+// to multiple publishers. The events have the same schema as the audit events,
+// failed updates are not published. Failures of publishing events are logged
+// and do not fail the request.
+func WithEventPublisher(pub EventPublisher) Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		p.eventPublishers = append(p.eventPublishers, pub)
+		return p, nil
+	}
+}
+
+// WithSkipImplicitCleanup makes Do purely additive, expired AOD bindings are
+// not removed when adding bindings. Existing AOD bindings of the requested
+// members and roles are still replaced.
+func WithSkipImplicitCleanup() Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		p.skipImplicitCleanup = true
+		return p, nil
+	}
+}
+
+// WithOrganizationClients provides the clients to get and set the IAM policies
+// of the organization and its descendant folders and projects with, instead of
+// the default clients, e.g. to use separate credentials or endpoints for each
+// customer organization. It can be provided multiple times for multiple
+// organizations. The organizations of folders and projects are found with the
+// resolver provided by WithOrganizationResolver.
+func WithOrganizationClients(org string, organizationsClient, foldersClient, projectsClient IAMClient) Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		if !resource.HasType(org, resource.TypeOrganization) {
+			return nil, fmt.Errorf("invalid organization %q, must be organizations/<id>", org)
+		}
+		if p.orgClients == nil {
+			p.orgClients = make(map[string]*orgClients)
+		}
+		if _, ok := p.orgClients[org]; ok {
+			return nil, fmt.Errorf("duplicate clients for organization %q", org)
+		}
+		p.orgClients[org] = &orgClients{
+			organizations: organizationsClient,
+			folders:       foldersClient,
+			projects:      projectsClient,
+		}
+		return p, nil
+	}
+}
+
+// WithOrganizationResolver provides the resolver to find the organizations of
+// folders and projects with, which is required by WithOrganizationClients.
+func WithOrganizationResolver(r OrganizationResolver) Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		p.orgResolver = r
+		return p, nil
+	}
+}
+
+// WithStateChecker provides the checker to get the lifecycle states of
+// resources with when failing to get or set their IAM policies. Resources that
+// are not active, such as projects pending deletion, are not retried, and are
+// skipped by Sweep.
+func WithStateChecker(c StateChecker) Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		p.stateChecker = c
+		return p, nil
+	}
+}
+
+// WithGetPolicyCallOptions provides call options, such as timeouts and retry
+// settings, to every GetIamPolicy call. It can be provided multiple times to
+// add more call options.
+func WithGetPolicyCallOptions(opts ...gax.CallOption) Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		p.getPolicyOpts = append(p.getPolicyOpts, opts...)
+		return p, nil
+	}
+}
+
+// WithSetPolicyCallOptions provides call options, such as timeouts and retry
+// settings, to every SetIamPolicy call. It can be provided multiple times to
+// add more call options.
+func WithSetPolicyCallOptions(opts ...gax.CallOption) Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		p.setPolicyOpts = append(p.setPolicyOpts, opts...)
+		return p, nil
+	}
+}
+
+// WithCorrelationID provides the default correlation ID of the AOD run, which
+// is attached to the IAM calls and the audit events unless the context has
+// its own, see WithRequestMetadata.
+func WithCorrelationID(id string) Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		p.correlationID = id
+		return p, nil
+	}
+}
+
 // NewIAMHandler creates a new IAMHandler with provided clients and options.
 func NewIAMHandler(ctx context.Context, organizationsClient, foldersClient, projectsClient IAMClient, opts ...Option) (*IAMHandler, error) {
 	h := &IAMHandler{}
@@ -95,158 +385,711 @@ func NewIAMHandler(ctx context.Context, organizationsClient, foldersClient, proj
 			return nil, fmt.Errorf("failed to apply client options: %w", err)
 		}
 	}
-	h.organizationsClient = organizationsClient
-	h.foldersClient = foldersClient
-	h.projectsClient = projectsClient
+	h.telemetry = newTelemetry(h.tracerProvider, h.meterProvider)
+	h.organizationsClient = &tracedIAMClient{IAMClient: organizationsClient, tracer: h.telemetry.tracer}
+	h.foldersClient = &tracedIAMClient{IAMClient: foldersClient, tracer: h.telemetry.tracer}
+	h.projectsClient = &tracedIAMClient{IAMClient: projectsClient, tracer: h.telemetry.tracer}
+	for typ, c := range h.typeClients {
+		traced := &tracedIAMClient{IAMClient: c, tracer: h.telemetry.tracer}
+		if p, ok := c.(PolicyPatcher); ok {
+			h.typeClients[typ] = &tracedPolicyPatcher{tracedIAMClient: traced, patcher: p}
+			continue
+		}
+		h.typeClients[typ] = traced
+	}
+	for _, c := range h.orgClients {
+		c.organizations = &tracedIAMClient{IAMClient: c.organizations, tracer: h.telemetry.tracer}
+		c.folders = &tracedIAMClient{IAMClient: c.folders, tracer: h.telemetry.tracer}
+		c.projects = &tracedIAMClient{IAMClient: c.projects, tracer: h.telemetry.tracer}
+	}
+	if len(h.orgClients) > 0 && h.orgResolver == nil {
+		return nil, fmt.Errorf("organization resolver is required with organization clients")
+	}
 
 	if h.retry == nil {
 		h.retry = retry.WithMaxRetries(5, retry.NewFibonacci(500*time.Millisecond))
 	}
 
 	if h.conditionTitle == "" {
-		h.conditionTitle = defaultConditionTitle
+		h.conditionTitle = DefaultConditionTitle
+	}
+
+	if h.now == nil {
+		h.now = time.Now
+	}
+
+	if h.concurrency == 0 {
+		h.concurrency = 1
+	}
+
+	if h.propagationPollInterval == 0 {
+		h.propagationPollInterval = defaultPropagationPollInterval
 	}
 
 	return h, nil
 }
 
 // Cleanup removes expired IAM bindings added by AOD from the IAM policies of the resources in the request.
-func (h *IAMHandler) Cleanup(ctx context.Context, r *v1alpha1.IAMRequest) (nps []*v1alpha1.IAMResponse, retErr error) {
-	for _, p := range r.ResourcePolicies {
-		// time.Now() is a dummy parameter used to match the function signature.
-		np, err := h.handlePolicy(ctx, p, time.Now(), h.cleanupBindings)
+// The deny policies in the request and the expired AOD deny policies of their
+// resources are deleted too.
+func (h *IAMHandler) Cleanup(ctx context.Context, r *v1alpha1.IAMRequest) ([]*v1alpha1.IAMResponse, error) {
+	if len(r.DenyPolicies) > 0 && h.denyPolicyManager == nil {
+		return nil, fmt.Errorf("deny policy manager is required to clean up deny policies")
+	}
+	resps, err := h.handlePolicies(ctx, "cleanup", r.ResourcePolicies, func(ctx context.Context, p *v1alpha1.ResourcePolicy) (*v1alpha1.IAMResponse, error) {
+		// h.now() is a dummy parameter used to match the function signature.
+		np, err := h.handlePolicy(ctx, p, h.now(), h.cleanupBindings)
+		h.writeAuditEvent(ctx, audit.EventTypeCleanup, p, nil, err)
 		if err != nil {
-			retErr = errors.Join(
-				retErr,
-				fmt.Errorf("failed to handle policy cleanup for resource %s: %w", p.Resource, err),
-			)
-		}
-		if np != nil {
-			nps = append(nps, np)
+			return np, fmt.Errorf("failed to handle policy cleanup for resource %s: %w", p.Resource, err)
 		}
+		return np, nil
+	})
+	if len(r.DenyPolicies) > 0 {
+		err = errors.Join(err, h.cleanupDenyPolicies(ctx, r.DenyPolicies))
 	}
-	return
+	return resps, err
 }
 
 // Do removes expired or conflicting IAM bindings added by AOD and adds requested IAM bindings to current IAM policy.
-func (h *IAMHandler) Do(ctx context.Context, r *v1alpha1.IAMRequestWrapper) (nps []*v1alpha1.IAMResponse, retErr error) {
+func (h *IAMHandler) Do(ctx context.Context, r *v1alpha1.IAMRequestWrapper) ([]*v1alpha1.IAMResponse, error) {
+	ctx = withRequester(ctx, r.Requester)
 	expiry := r.StartTime.Add(r.Duration)
-	for _, p := range r.ResourcePolicies {
-		np, err := h.handlePolicy(ctx, p, expiry, h.addBindings)
+	desc := conditionDescription(h.requestMetadata(ctx).Requester, r.Approvers)
+
+	if err := h.checkMaintenance(); err != nil {
+		return nil, err
+	}
+
+	if err := checkStartOffsets(r); err != nil {
+		return nil, err
+	}
+
+	recurring, err := compileRecurring(r.Recurring)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.checkProtected(r.ResourcePolicies); err != nil {
+		return nil, err
+	}
+
+	if len(r.DenyPolicies) > 0 && h.denyPolicyManager == nil {
+		return nil, fmt.Errorf("deny policy manager is required to handle deny policies")
+	}
+
+	if h.preflight {
+		if err := h.preflightCheck(ctx, r.ResourcePolicies); err != nil {
+			return nil, err
+		}
+	}
+
+	// The updated resources to roll back if the grant fails.
+	var mu sync.Mutex
+	var updates []*updatedPolicy
+	resps, err := h.handlePolicies(ctx, "grant", r.ResourcePolicies, func(ctx context.Context, p *v1alpha1.ResourcePolicy) (*v1alpha1.IAMResponse, error) {
+		np, prior, err := h.handlePolicyWithPrior(ctx, p, expiry, withCondition(h.addBindings, r.StartTime, desc, recurring))
+		if np != nil && h.rollbackOnFailure {
+			mu.Lock()
+			updates = append(updates, &updatedPolicy{policy: p, prior: prior, current: np.Policy})
+			mu.Unlock()
+		}
+		h.writeAuditEvent(ctx, audit.EventTypeGrant, p, r, err)
 		if err != nil {
-			retErr = errors.Join(
-				retErr,
-				fmt.Errorf("failed to handle policy update for resource %s: %w", p.Resource, err),
-			)
+			return np, fmt.Errorf("failed to handle policy update for resource %s: %w", p.Resource, err)
 		}
-		if np != nil {
-			nps = append(nps, np)
+		h.recordBindingsAdded(ctx, p)
+		return np, nil
+	})
+	h.warnProtected(resps)
+	if err != nil && len(updates) > 0 {
+		return h.rollback(ctx, r, resps, updates, err)
+	}
+	if len(r.DenyPolicies) > 0 {
+		err = errors.Join(err, h.doDenyPolicies(ctx, r))
+	}
+	return resps, err
+}
+
+// Renew extends the expiry of the active IAM bindings added by AOD for the
+// members and roles in the request to the request expiry, instead of adding
+// duplicate bindings. Members without active AOD bindings are not granted and
+// are reported as errors.
+func (h *IAMHandler) Renew(ctx context.Context, r *v1alpha1.IAMRequestWrapper) ([]*v1alpha1.IAMResponse, error) {
+	ctx = withRequester(ctx, r.Requester)
+	expiry := r.StartTime.Add(r.Duration)
+	desc := conditionDescription(h.requestMetadata(ctx).Requester, r.Approvers)
+	if err := h.checkMaintenance(); err != nil {
+		return nil, err
+	}
+	recurring, err := compileRecurring(r.Recurring)
+	if err != nil {
+		return nil, err
+	}
+	if h.preflight {
+		if err := h.preflightCheck(ctx, r.ResourcePolicies); err != nil {
+			return nil, err
 		}
 	}
-	return
+	return h.handlePolicies(ctx, "renew", r.ResourcePolicies, func(ctx context.Context, p *v1alpha1.ResourcePolicy) (*v1alpha1.IAMResponse, error) {
+		np, err := h.handlePolicy(ctx, p, expiry, withCondition(h.renewBindings, r.StartTime, desc, recurring))
+		h.writeAuditEvent(ctx, audit.EventTypeRenew, p, r, err)
+		if err != nil {
+			return np, fmt.Errorf("failed to handle policy renewal for resource %s: %w", p.Resource, err)
+		}
+		return np, nil
+	})
 }
 
-func (h *IAMHandler) handlePolicy(ctx context.Context, p *v1alpha1.ResourcePolicy, expiry time.Time, updateFunc updatePolicy) (*v1alpha1.IAMResponse, error) {
-	var iamC IAMClient
-	switch strings.Split(p.Resource, "/")[0] {
-	case "organizations":
-		iamC = h.organizationsClient
-	case "folders":
-		iamC = h.foldersClient
-	case "projects":
-		iamC = h.projectsClient
+// RevokeMember removes the member from all IAM bindings added by AOD in the IAM
+// policies of the resources, regardless of the roles and expiry.
+func (h *IAMHandler) RevokeMember(ctx context.Context, member string, resources []string) ([]*v1alpha1.IAMResponse, error) {
+	ps := make([]*v1alpha1.ResourcePolicy, 0, len(resources))
+	for _, r := range resources {
+		ps = append(ps, &v1alpha1.ResourcePolicy{
+			Resource: r,
+			Bindings: []*v1alpha1.Binding{{Members: []string{member}}},
+		})
+	}
+	return h.handlePolicies(ctx, "revoke", ps, func(ctx context.Context, p *v1alpha1.ResourcePolicy) (*v1alpha1.IAMResponse, error) {
+		// h.now() is a dummy parameter used to match the function signature.
+		np, err := h.handlePolicy(ctx, p, h.now(), h.revokeMemberBindings)
+		h.writeAuditEvent(ctx, audit.EventTypeRevoke, p, nil, err)
+		if err != nil {
+			return np, fmt.Errorf("failed to handle member revocation for resource %s: %w", p.Resource, err)
+		}
+		return np, nil
+	})
+}
+
+// handlePolicies handles the resource policies of the operation with up to
+// h.concurrency workers, and collects the responses and errors in the order of
+// the resource policies. The operation and each resource are handled in spans.
+func (h *IAMHandler) handlePolicies(ctx context.Context, op string, ps []*v1alpha1.ResourcePolicy, handleFunc func(context.Context, *v1alpha1.ResourcePolicy) (*v1alpha1.IAMResponse, error)) (nps []*v1alpha1.IAMResponse, retErr error) {
+	ctx, span := h.telemetry.tracer.Start(ctx, "IAMHandler."+op,
+		trace.WithAttributes(attrOperation.String(op)))
+	defer func() { endSpan(span, retErr) }()
+
+	// Handle the policies in levels of their dependencies, the policies in a
+	// level are handled concurrently after the policies they depend on. The
+	// policies are cleaned up in the reverse order.
+	levels, err := v1alpha1.PolicyLevels(ps)
+	if err != nil {
+		return nil, fmt.Errorf("failed to order resource policies: %w", err)
+	}
+	if op == "cleanup" {
+		slices.Reverse(levels)
+	}
+
+	resps := make([]*v1alpha1.IAMResponse, len(ps))
+	errs := make([]error, len(ps))
+	failed := make(map[string]struct{})
+	for _, level := range levels {
+		pool := workerpool.New[*v1alpha1.IAMResponse](&workerpool.Config{
+			Concurrency: h.concurrency,
+		})
+		// The indices of the policies submitted to the pool.
+		submitted := make([]int, 0, len(level))
+		for _, i := range level {
+			p := ps[i]
+			if op != "cleanup" {
+				if d := failedDependency(p, failed); d != "" {
+					errs[i] = fmt.Errorf("skipped resource %s since its dependency %s failed", p.Resource, d)
+					failed[p.Resource] = struct{}{}
+					continue
+				}
+			}
+			submitted = append(submitted, i)
+			if err := pool.Do(ctx, func() (*v1alpha1.IAMResponse, error) {
+				return h.handleResource(ctx, op, p, handleFunc)
+			}); err != nil {
+				// The error is also reported in the results.
+				break
+			}
+		}
+
+		results, err := pool.Done(ctx)
+		if results == nil {
+			return nil, fmt.Errorf("failed to wait for resource policies to be handled: %w", err)
+		}
+		for k, r := range results {
+			i := submitted[k]
+			resps[i], errs[i] = r.Value, r.Error
+			if r.Error != nil {
+				failed[ps[i].Resource] = struct{}{}
+			}
+		}
+	}
+
+	for i := range ps {
+		retErr = errors.Join(retErr, errs[i])
+		if resps[i] != nil {
+			nps = append(nps, resps[i])
+		}
+	}
+	return nps, retErr
+}
+
+// failedDependency returns the first dependency of the policy which failed, or
+// an empty string if none failed.
+func failedDependency(p *v1alpha1.ResourcePolicy, failed map[string]struct{}) string {
+	for _, d := range p.DependsOn {
+		if _, ok := failed[d]; ok && d != p.Resource {
+			return d
+		}
+	}
+	return ""
+}
+
+// handleResource handles the resource policy in a span, and records the
+// duration and failure metrics.
+func (h *IAMHandler) handleResource(ctx context.Context, op string, p *v1alpha1.ResourcePolicy, handleFunc func(context.Context, *v1alpha1.ResourcePolicy) (*v1alpha1.IAMResponse, error)) (_ *v1alpha1.IAMResponse, retErr error) {
+	ctx, span := h.telemetry.tracer.Start(ctx, "IAMHandler.handleResource",
+		trace.WithAttributes(attrOperation.String(op), attrResource.String(p.Resource)))
+	defer func() { endSpan(span, retErr) }()
+
+	start := time.Now()
+	resp, err := handleFunc(ctx, p)
+
+	outcome := outcomeSuccess
+	if err != nil {
+		outcome = outcomeFailure
+	}
+	attrs := metric.WithAttributes(attrOperation.String(op), attrResourceType.String(resourceType(p.Resource)))
+	h.telemetry.resourceDuration.Record(ctx, time.Since(start).Seconds(), attrs,
+		metric.WithAttributes(attrOutcome.String(outcome)))
+	if err != nil {
+		h.telemetry.failures.Add(ctx, 1, attrs)
+	}
+	return resp, err
+}
+
+// iamClient returns the IAMClient for the given resource, which is the client
+// of the resource's organization if there is one. Resources outside of the
+// resource hierarchy, such as BigQuery datasets and buckets, are handled by
+// the client of their type regardless of their organizations.
+func (h *IAMHandler) iamClient(ctx context.Context, name string) (IAMClient, error) {
+	clients := &orgClients{
+		organizations: h.organizationsClient,
+		folders:       h.foldersClient,
+		projects:      h.projectsClient,
+	}
+
+	rn, err := resource.Parse(name)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Want passthrough
+	}
+
+	if !resource.IsType(string(rn.Type)) {
+		c, ok := h.typeClients[rn.Type]
+		if !ok {
+			return nil, fmt.Errorf("IAM client of %s is required to handle resource %q", rn.Type, name)
+		}
+		return c, nil
+	}
+
+	if len(h.orgClients) > 0 {
+		org, err := h.organization(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if c, ok := h.orgClients[org]; ok {
+			clients = c
+		}
+	}
+
+	switch rn.Type {
+	case resource.TypeOrganization:
+		return clients.organizations, nil
+	case resource.TypeFolder:
+		return clients.folders, nil
 	default:
-		return nil, fmt.Errorf("resource isn't one of [organizations, folders, projects]")
+		return clients.projects, nil
+	}
+}
+
+// getPolicy gets the current IAM policy of the resource.
+func (h *IAMHandler) getPolicy(ctx context.Context, iamC IAMClient, resource string) (*iampb.Policy, error) {
+	getIAMPolicyRequest := &iampb.GetIamPolicyRequest{
+		Resource: resource,
+		// Set required policy version to 3 to support conditional IAM bindings
+		// in the requested policy.
+		// Note that if the requested policy does not contain conditional IAM
+		// bindings it will return the policy as is, which is version 1.
+		// See details here: https://cloud.google.com/iam/docs/policies#specifying-version-get
+		Options: &iampb.GetPolicyOptions{
+			RequestedPolicyVersion: 3,
+		},
+	}
+	if err := h.spendAPICall(); err != nil {
+		return nil, err
+	}
+	cp, err := iamC.GetIamPolicy(h.outgoingContext(ctx), getIAMPolicyRequest, h.getPolicyOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IAM policy: %w", err)
+	}
+	return cp, nil
+}
+
+// setPolicy sets the updated IAM policy of the resource. If the IAMClient is a
+// PolicyPatcher, only the changes from the prior policy are applied to the
+// latest policy instead.
+func (h *IAMHandler) setPolicy(ctx context.Context, iamC IAMClient, resource string, prior, updated *iampb.Policy) (*iampb.Policy, error) {
+	if pc, ok := iamC.(PolicyPatcher); ok {
+		d := iamdelta.Diff(prior, updated)
+		if d.Empty() {
+			return updated, nil
+		}
+		// Patching gets and sets the latest policy.
+		for range 2 {
+			if err := h.spendAPICall(); err != nil {
+				return nil, err
+			}
+		}
+		return pc.PatchIamPolicy(h.outgoingContext(ctx), resource, d) //nolint:wrapcheck // Want passthrough
 	}
 
-	var np *iampb.Policy
-	var updateErr error
-	if err := retry.Do(ctx, h.retry, func(ctx context.Context) error {
+	if err := h.spendAPICall(); err != nil {
+		return nil, err
+	}
+	return iamC.SetIamPolicy(h.outgoingContext(ctx), &iampb.SetIamPolicyRequest{ //nolint:wrapcheck // Want passthrough
+		Resource: resource,
+		Policy:   updated,
+	}, h.setPolicyOpts...)
+}
+
+func (h *IAMHandler) handlePolicy(ctx context.Context, p *v1alpha1.ResourcePolicy, expiry time.Time, updateFunc updatePolicy) (*v1alpha1.IAMResponse, error) {
+	resp, _, err := h.handlePolicyWithPrior(ctx, p, expiry, updateFunc)
+	return resp, err
+}
+
+// handlePolicyWithPrior updates the IAM policy of the resource policy with the
+// update function, and also returns the policy before the update if the
+// policy was set.
+func (h *IAMHandler) handlePolicyWithPrior(ctx context.Context, p *v1alpha1.ResourcePolicy, expiry time.Time, updateFunc updatePolicy) (*v1alpha1.IAMResponse, *iampb.Policy, error) {
+	// Final guard of the resources this deployment may modify, regardless of
+	// how the request was validated.
+	if err := h.checkAllowed(p.Resource); err != nil {
+		h.reportProgress(ctx, progress.EventTypeFailed, p.Resource, 0, err)
+		return nil, nil, fmt.Errorf("failed to handle IAM request: %w", err)
+	}
+
+	iamC, err := h.iamClient(ctx, p.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	h.reportProgress(ctx, progress.EventTypeStarted, p.Resource, 0, nil)
+
+	var np, prior *iampb.Policy
+	var warnings []string
+	var updateErr, lastErr error
+	var cleaned int
+	var rejected rejectedEtags
+	attempt := 0
+	inactive := h.inactiveOnce(p.Resource)
+	if err := retry.Do(ctx, h.retry, func(ctx context.Context) (retErr error) {
+		attempt++
+		if attempt > 1 {
+			h.reportProgress(ctx, progress.EventTypeRetry, p.Resource, attempt, lastErr)
+			h.telemetry.retries.Add(ctx, 1, metric.WithAttributes(attrResourceType.String(resourceType(p.Resource))))
+		}
+		defer func() {
+			// Keep the error without the "retryable" prefix to report the retry.
+			lastErr = retErr
+			if u := errors.Unwrap(retErr); u != nil {
+				lastErr = u
+			}
+		}()
+
 		// Get current IAM policy.
-		getIAMPolicyRequest := &iampb.GetIamPolicyRequest{
-			Resource: p.Resource,
-			// Set required policy version to 3 to support conditional IAM bindings
-			// in the requested policy.
-			// Note that if the requested policy does not contain conditional IAM
-			// bindings it will return the policy as is, which is version 1.
-			// See details here: https://cloud.google.com/iam/docs/policies#specifying-version-get
-			Options: &iampb.GetPolicyOptions{
-				RequestedPolicyVersion: 3,
-			},
-		}
-		cp, err := iamC.GetIamPolicy(ctx, getIAMPolicyRequest)
-		// Retry when get IAM policy fail.
+		cp, err := h.getPolicy(ctx, iamC, p.Resource)
+		// Retry when get IAM policy fail, unless the resource is not active or
+		// the API call budget is exceeded.
 		if err != nil {
-			return retry.RetryableError(fmt.Errorf("failed to get IAM policy: %w", err))
+			if errors.Is(err, ErrAPICallBudgetExceeded) {
+				return err
+			}
+			if ierr := inactive(ctx); ierr != nil {
+				return ierr
+			}
+			return retry.RetryableError(err)
+		}
+
+		// Keep the etag of the current policy for optimistic concurrency control,
+		// and the current policy to roll back to.
+		etag := cp.GetEtag()
+		if h.consistentReads {
+			// Read again instead of setting a policy known to be rejected.
+			if err := rejected.check(etag); err != nil {
+				return retry.RetryableError(err)
+			}
+		}
+		var ok bool
+		if prior, ok = proto.Clone(cp).(*iampb.Policy); !ok {
+			return fmt.Errorf("failed to clone IAM policy")
 		}
 
 		// Keep handling the request and report the errors at the end.
-		if err := updateFunc(ctx, cp, p.Bindings, expiry); err != nil {
+		updateErr, warnings = nil, nil
+		expiredBefore := h.countExpiredBindings(cp)
+		var warnErr *warningsError
+		if err := updateFunc(ctx, cp, p.Bindings, expiry); errors.As(err, &warnErr) {
+			warnings = warnErr.warnings()
+		} else if err != nil {
 			updateErr = fmt.Errorf("errors when updating IAM policy: %w", err)
 		}
+		cleaned = expiredBefore - h.countExpiredBindings(cp)
 
-		// Set the new policy.
-		setIAMPolicyRequest := &iampb.SetIamPolicyRequest{
-			Resource: p.Resource,
-			Policy:   cp,
+		// Fail without retrying if the updated policy would be rejected for its
+		// size.
+		if err := checkPolicySize(p.Resource, cp); err != nil {
+			return err
 		}
-		np, err = iamC.SetIamPolicy(ctx, setIAMPolicyRequest)
-		// Retry when set IAM policy fail.
-		// TODO(#8): Look for specific errors to retry.
+
+		// Fail without retrying if the IAM API would reject the new
+		// conditions.
+		if err := checkConditions(p.Resource, prior, cp); err != nil {
+			return err
+		}
+
+		// Set the new policy with the etag of the current policy, so that it fails
+		// instead of overwriting the policy if the policy was modified since it
+		// was read.
+		cp.Etag = etag
+		np, err = h.setPolicy(ctx, iamC, p.Resource, prior, cp)
 		if err != nil {
+			// Retry with the latest policy when the policy was modified
+			// concurrently.
+			if isConflict(err) {
+				rejected.add(etag)
+				return retry.RetryableError(fmt.Errorf("failed to set IAM policy due to concurrent policy modification: %w, retrying", err))
+			}
+			if ierr := inactive(ctx); ierr != nil {
+				return ierr
+			}
+			// Do not retry on errors that will not succeed with retries.
+			if isNonRetryable(err) {
+				return fmt.Errorf("failed to set IAM policy: %w", err)
+			}
 			return retry.RetryableError(fmt.Errorf("failed to set IAM policy: %w, retrying", err))
 		}
 		return nil
 	}); err != nil {
-		return nil, errors.Join(updateErr, fmt.Errorf("failed to handle IAM request: %w", err))
+		var inactiveErr *InactiveResourceError
+		if errors.As(err, &inactiveErr) {
+			h.reportProgress(ctx, progress.EventTypeSkipped, p.Resource, 0, inactiveErr)
+			return nil, nil, fmt.Errorf("failed to handle IAM request: %w", err)
+		}
+		err = errors.Join(updateErr, fmt.Errorf("failed to handle IAM request: %w", err))
+		h.reportProgress(ctx, progress.EventTypeFailed, p.Resource, 0, err)
+		h.openPermissionDeniedTicket(ctx, p.Resource, err)
+		return nil, nil, err
 	}
 
-	return &v1alpha1.IAMResponse{Resource: p.Resource, Policy: np}, updateErr
+	if h.consistentReads {
+		if err := h.waitForReadAfterWrite(ctx, iamC, p.Resource, prior.GetEtag(), np.GetEtag()); err != nil {
+			warnings = append(warnings, err.Error())
+		}
+	}
+
+	if cleaned > 0 {
+		h.telemetry.bindingsCleaned.Add(ctx, int64(cleaned),
+			metric.WithAttributes(attrResourceType.String(resourceType(p.Resource))))
+	}
+
+	if updateErr != nil {
+		h.reportProgress(ctx, progress.EventTypeFailed, p.Resource, 0, updateErr)
+	} else {
+		h.reportProgress(ctx, progress.EventTypeCompleted, p.Resource, 0, nil)
+	}
+	return &v1alpha1.IAMResponse{Resource: p.Resource, Policy: np, Warnings: warnings}, prior, updateErr
 }
 
-// addBindings adds new bindings with expiration condition and does best
-// effort cleanup which removes any expired AOD bindings. It always return nil
-// error, any errors encounterred during removal will be ignored and policy
-// update for the request will continue. Removal errors should be handled
-// separately such as in a global IAM cleanup.
-func (h *IAMHandler) addBindings(ctx context.Context, p *iampb.Policy, bs []*v1alpha1.Binding, expiry time.Time) error {
+// addBindings adds new bindings with expiration condition, with the given
+// condition description, starting at the start offsets of the bindings from
+// the given start time if set, and does best effort cleanup which removes any
+// expired AOD bindings, unless implicit cleanup is skipped. Any errors
+// encounterred during removal do not stop the policy update for the request,
+// they are returned as a warningsError to be reported as warnings. Removal
+// errors should be handled separately such as in a global IAM cleanup.
+func (h *IAMHandler) addBindings(ctx context.Context, p *iampb.Policy, bs []*v1alpha1.Binding, start, expiry time.Time, description string, recurring *recurringCondition) (retErr error) {
 	logger := logging.FromContext(ctx)
 
-	// Cleanup policy, returned error is logged.
-	if err := h.cleanupBindings(ctx, p, bs, expiry); err != nil {
+	// Cleanup policy, returned error is logged and reported as warnings.
+	if err := h.removeBindings(p, bs, !h.skipImplicitCleanup); err != nil {
 		logger.WarnContext(ctx, "failed to check expiry", "error", err)
+		retErr = &warningsError{err: err}
 	}
 
-	// Convert new bindings to a role to unique bindings map.
-	bsMap := toBindingsMap(bs)
+	// Convert new bindings to a role to start offset to unique bindings map.
+	bsMap := toWindowedBindingsMap(bs)
 
-	// Add new bindings with expiration condition.
-	t := expiry.Format(time.RFC3339)
-	for r, ms := range bsMap {
-		newBinding := &iampb.Binding{
-			Condition: &expr.Expr{
-				Title:      h.conditionTitle,
-				Expression: fmt.Sprintf(expirationExpression, t),
-			},
-			Role: r,
-		}
-		for m := range ms {
-			newBinding.Members = append(newBinding.GetMembers(), m)
+	// Add new bindings with expiration condition, sorted by role and start
+	// offset for deterministic policies.
+	t := recurring.expiry(expiry).Format(time.RFC3339)
+	for _, r := range slices.Sorted(maps.Keys(bsMap)) {
+		for _, offset := range slices.Sorted(maps.Keys(bsMap[r])) {
+			exp := fmt.Sprintf(expirationExpression, t)
+			if offset > 0 {
+				exp = fmt.Sprintf(windowExpression, start.Add(offset).Format(time.RFC3339), t)
+			}
+			newBinding := &iampb.Binding{
+				Condition: &expr.Expr{
+					Title:       h.conditionTitle,
+					Description: description,
+					Expression:  recurring.withExpression(exp),
+				},
+				Role: r,
+			}
+			for m := range bsMap[r][offset] {
+				newBinding.Members = append(newBinding.GetMembers(), m)
+			}
+			sort.Strings(newBinding.GetMembers())
+			p.Bindings = append(p.GetBindings(), newBinding)
 		}
-		sort.Strings(newBinding.GetMembers())
-		p.Bindings = append(p.GetBindings(), newBinding)
 	}
 
 	// Set policy version to 3 to support conditional IAM bindings.
 	// See details here: https://cloud.google.com/iam/docs/policies#specifying-version-set
 	p.Version = 3
 
+	return retErr
+}
+
+// renewBindings replaces the active AOD bindings of the members and roles in
+// bs with bindings expiring at the given expiry. Members and roles in bs
+// without active AOD bindings are reported as errors.
+func (h *IAMHandler) renewBindings(ctx context.Context, p *iampb.Policy, bs []*v1alpha1.Binding, start, expiry time.Time, description string, recurring *recurringCondition) (retErr error) {
+	// Find the members and roles in bs with active AOD bindings.
+	active := make(map[string]map[string]struct{})
+	for _, b := range p.GetBindings() {
+		if b.GetCondition() == nil || b.GetCondition().GetTitle() != h.conditionTitle {
+			continue
+		}
+		// Expired or invalid bindings are not renewed.
+		if expired, err := expired(b.GetCondition().GetExpression(), h.now()); err != nil || expired {
+			continue
+		}
+		if active[b.GetRole()] == nil {
+			active[b.GetRole()] = make(map[string]struct{})
+		}
+		for _, m := range b.GetMembers() {
+			active[b.GetRole()][m] = struct{}{}
+		}
+	}
+
+	// Iterate in the order of the request for deterministic errors.
+	var renew []*v1alpha1.Binding
+	for _, b := range bs {
+		for _, r := range b.Roles() {
+			nb := &v1alpha1.Binding{Role: r}
+			for _, m := range b.Members {
+				if _, ok := active[r][m]; !ok {
+					retErr = errors.Join(retErr, fmt.Errorf("active AOD binding not found for member %q with role %q", m, r))
+					continue
+				}
+				nb.Members = append(nb.Members, m)
+			}
+			if len(nb.Members) > 0 {
+				renew = append(renew, nb)
+			}
+		}
+	}
+
+	// Replace the active bindings with the renewed bindings.
+	if len(renew) > 0 {
+		retErr = errors.Join(retErr, h.addBindings(ctx, p, renew, start, expiry, description, recurring))
+	}
+	return retErr
+}
+
+// withCondition returns the updatePolicy calling f with the start time of the
+// request, which the start offsets of the bindings are relative to, the
+// condition description and the recurring windows of the request.
+func withCondition(f func(context.Context, *iampb.Policy, []*v1alpha1.Binding, time.Time, time.Time, string, *recurringCondition) error, start time.Time, description string, recurring *recurringCondition) updatePolicy {
+	return func(ctx context.Context, p *iampb.Policy, bs []*v1alpha1.Binding, expiry time.Time) error {
+		return f(ctx, p, bs, start, expiry, description, recurring)
+	}
+}
+
+// checkStartOffsets checks the start offsets of the bindings in the request
+// are less than the duration of the request, so that the bindings start before
+// they expire.
+func checkStartOffsets(r *v1alpha1.IAMRequestWrapper) (retErr error) {
+	for _, p := range r.ResourcePolicies {
+		for _, b := range p.Bindings {
+			if b.StartOffset > 0 && b.StartOffset >= r.Duration {
+				retErr = errors.Join(retErr, fmt.Errorf("start offset %s of binding on %s must be less than duration %s",
+					b.StartOffset, p.Resource, r.Duration))
+			}
+		}
+	}
+	return retErr
+}
+
+// conditionDescription returns the description of the condition of the added
+// bindings, recording the requester and approvers so that every binding in the
+// policy is traceable back to the request. It is truncated to the maximum
+// length of condition descriptions.
+func conditionDescription(requester string, approvers []string) string {
+	var parts []string
+	if requester != "" {
+		parts = append(parts, "requested by "+requester)
+	}
+	if len(approvers) > 0 {
+		parts = append(parts, "approved by "+strings.Join(approvers, ", "))
+	}
+	d := strings.Join(parts, "; ")
+	if len(d) > maxConditionDescriptionLength {
+		d = d[:maxConditionDescriptionLength-3] + "..."
+	}
+	return d
+}
+
+// revokeMemberBindings removes the members in bs from all AOD bindings of the
+// policy, the roles in bs are ignored.
+func (h *IAMHandler) revokeMemberBindings(_ context.Context, p *iampb.Policy, bs []*v1alpha1.Binding, _ time.Time) error {
+	revoke := make(map[string]struct{})
+	for _, b := range bs {
+		for _, m := range b.Members {
+			revoke[m] = struct{}{}
+		}
+	}
+
+	var keep []*iampb.Binding
+	for _, b := range p.GetBindings() {
+		// Keep non-AOD bindings.
+		if b.GetCondition() == nil || b.GetCondition().GetTitle() != h.conditionTitle {
+			keep = append(keep, b)
+			continue
+		}
+
+		var nm []string
+		for _, m := range b.GetMembers() {
+			if _, ok := revoke[m]; !ok {
+				nm = append(nm, m)
+			}
+		}
+		if len(nm) > 0 {
+			b.Members = nm
+			keep = append(keep, b)
+		}
+	}
+	p.Bindings = keep
+
 	return nil
 }
 
 // cleanupBindings does best effort cleanup which removes bs bindings and any
 // expired AOD bindings from the policy.
-func (h *IAMHandler) cleanupBindings(ctx context.Context, p *iampb.Policy, bs []*v1alpha1.Binding, _ time.Time) (retErr error) {
+func (h *IAMHandler) cleanupBindings(ctx context.Context, p *iampb.Policy, bs []*v1alpha1.Binding, _ time.Time) error {
+	return h.removeBindings(p, bs, true)
+}
+
+// removeBindings removes bs bindings from the AOD bindings of the policy, and
+// any expired AOD bindings if removeExpired is true.
+func (h *IAMHandler) removeBindings(p *iampb.Policy, bs []*v1alpha1.Binding, removeExpired bool) (retErr error) {
 	// Convert new bindings to a role to unique bindings map.
 	bsMap := toBindingsMap(bs)
 	var keep []*iampb.Binding
@@ -257,13 +1100,15 @@ func (h *IAMHandler) cleanupBindings(ctx context.Context, p *iampb.Policy, bs []
 			continue
 		}
 
-		expired, err := expired(b.GetCondition().GetExpression())
-		if err != nil {
-			retErr = errors.Join(retErr, fmt.Errorf("failed to check expiry: %w", err))
-		}
-		// Remove expired bindings.
-		if expired {
-			continue
+		if removeExpired {
+			expired, err := expired(b.GetCondition().GetExpression(), h.now())
+			if err != nil {
+				retErr = errors.Join(retErr, fmt.Errorf("failed to check expiry: %w", err))
+			}
+			// Remove expired bindings.
+			if expired {
+				continue
+			}
 		}
 
 		// Keep roles that are not in the request.
@@ -289,27 +1134,120 @@ func (h *IAMHandler) cleanupBindings(ctx context.Context, p *iampb.Policy, bs []
 	return retErr
 }
 
+// warningsError is returned by an updatePolicy when the errors did not stop
+// the IAM policy update, the errors are reported as warnings in the response
+// instead of failing the request.
+type warningsError struct {
+	err error
+}
+
+func (e *warningsError) Error() string {
+	return e.err.Error()
+}
+
+func (e *warningsError) Unwrap() error {
+	return e.err
+}
+
+// warnings returns the messages of the errors, one per joined error.
+func (e *warningsError) warnings() []string {
+	var errs []error
+	if joined, ok := e.err.(interface{ Unwrap() []error }); ok { //nolint:errorlint // Split joined errors.
+		errs = joined.Unwrap()
+	} else {
+		errs = []error{e.err}
+	}
+	result := make([]string, 0, len(errs))
+	for _, err := range errs {
+		result = append(result, err.Error())
+	}
+	return result
+}
+
 func toBindingsMap(bs []*v1alpha1.Binding) map[string]map[string]struct{} {
 	result := make(map[string]map[string]struct{})
 	for _, b := range bs {
-		if result[b.Role] == nil {
-			result[b.Role] = make(map[string]struct{})
+		for _, r := range b.Roles() {
+			if result[r] == nil {
+				result[r] = make(map[string]struct{})
+			}
+			for _, m := range b.Members {
+				result[r][m] = struct{}{}
+			}
 		}
-		for _, m := range b.Members {
-			result[b.Role][m] = struct{}{}
+	}
+	return result
+}
+
+// toWindowedBindingsMap converts the bindings to a role to start offset to
+// unique members map.
+func toWindowedBindingsMap(bs []*v1alpha1.Binding) map[string]map[time.Duration]map[string]struct{} {
+	result := make(map[string]map[time.Duration]map[string]struct{})
+	for _, b := range bs {
+		for _, r := range b.Roles() {
+			if result[r] == nil {
+				result[r] = make(map[time.Duration]map[string]struct{})
+			}
+			if result[r][b.StartOffset] == nil {
+				result[r][b.StartOffset] = make(map[string]struct{})
+			}
+			for _, m := range b.Members {
+				result[r][b.StartOffset][m] = struct{}{}
+			}
 		}
 	}
 	return result
 }
 
-func expired(exp string) (bool, error) {
+func expired(exp string, now time.Time) (bool, error) {
+	t, err := parseExpiry(exp)
+	if err != nil {
+		return false, err
+	}
+	return t.Before(now), nil
+}
+
+// parseExpiry parses the expiry from the expression of an AOD binding
+// condition.
+func parseExpiry(exp string) (time.Time, error) {
 	matches := expirationRegex.FindStringSubmatch(exp)
 	if len(matches) < 2 {
-		return false, fmt.Errorf("expression %q does not match format %q", exp, "request.time < timestamp('YYYY-MM-DDTHH:MM:SSZ')")
+		return time.Time{}, fmt.Errorf("expression %q does not match format %q", exp, "request.time < timestamp('YYYY-MM-DDTHH:MM:SSZ')")
 	}
 	t, err := time.Parse(time.RFC3339, matches[1])
 	if err != nil {
-		return false, fmt.Errorf("failed to parse expiration %q: %w", exp, err)
+		return time.Time{}, fmt.Errorf("failed to parse expiration %q: %w", exp, err)
+	}
+	return t, nil
+}
+
+// isConflict checks if the error is caused by a concurrent modification of the
+// IAM policy, which is reported when the etag of the policy to set does not
+// match the etag of the current policy.
+func isConflict(err error) bool {
+	if c := status.Code(err); c == codes.Aborted {
+		return true
+	}
+	var gErr *googleapi.Error
+	if errors.As(err, &gErr) {
+		return gErr.Code == http.StatusConflict || gErr.Code == http.StatusPreconditionFailed
+	}
+	return false
+}
+
+// isNonRetryable checks if the error will not succeed with retries, such as
+// invalid requests or missing permissions.
+func isNonRetryable(err error) bool {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.NotFound, codes.PermissionDenied, codes.Unauthenticated:
+		return true
+	}
+	var gErr *googleapi.Error
+	if errors.As(err, &gErr) {
+		switch gErr.Code {
+		case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+			return true
+		}
 	}
-	return t.Before(time.Now()), nil
+	return false
 }
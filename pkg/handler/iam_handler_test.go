@@ -16,8 +16,12 @@
 package handler
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -25,10 +29,13 @@ import (
 	resourcemanager "cloud.google.com/go/resourcemanager/apiv3"
 	"cloud.google.com/go/resourcemanager/apiv3/resourcemanagerpb"
 	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/gax-go/v2"
 	"github.com/sethvargo/go-retry"
 	"google.golang.org/api/option"
 	"google.golang.org/genproto/googleapis/type/expr"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/testing/protocmp"
 
 	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
@@ -117,7 +124,7 @@ func TestDo(t *testing.T) {
 								},
 								Role: "roles/accessapproval.approver",
 								Condition: &expr.Expr{
-									Title:      defaultConditionTitle,
+									Title:      DefaultConditionTitle,
 									Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339)),
 								},
 							},
@@ -135,7 +142,7 @@ func TestDo(t *testing.T) {
 								},
 								Role: "roles/cloudkms.cryptoOperator",
 								Condition: &expr.Expr{
-									Title:      defaultConditionTitle,
+									Title:      DefaultConditionTitle,
 									Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339)),
 								},
 							},
@@ -153,7 +160,7 @@ func TestDo(t *testing.T) {
 								},
 								Role: "roles/bigquery.dataViewer",
 								Condition: &expr.Expr{
-									Title:      defaultConditionTitle,
+									Title:      DefaultConditionTitle,
 									Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339)),
 								},
 							},
@@ -171,7 +178,7 @@ func TestDo(t *testing.T) {
 						},
 						Role: "roles/accessapproval.approver",
 						Condition: &expr.Expr{
-							Title:      defaultConditionTitle,
+							Title:      DefaultConditionTitle,
 							Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339)),
 						},
 					},
@@ -186,7 +193,7 @@ func TestDo(t *testing.T) {
 						},
 						Role: "roles/cloudkms.cryptoOperator",
 						Condition: &expr.Expr{
-							Title:      defaultConditionTitle,
+							Title:      DefaultConditionTitle,
 							Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339)),
 						},
 					},
@@ -201,7 +208,7 @@ func TestDo(t *testing.T) {
 						},
 						Role: "roles/bigquery.dataViewer",
 						Condition: &expr.Expr{
-							Title:      defaultConditionTitle,
+							Title:      DefaultConditionTitle,
 							Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339)),
 						},
 					},
@@ -290,7 +297,7 @@ func TestDo(t *testing.T) {
 							},
 							Role: "roles/accessapproval.approver",
 							Condition: &expr.Expr{
-								Title:      defaultConditionTitle,
+								Title:      DefaultConditionTitle,
 								Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(1*time.Hour).Format(time.RFC3339)),
 							},
 						},
@@ -302,7 +309,7 @@ func TestDo(t *testing.T) {
 							},
 							Role: "roles/accessapproval.approver",
 							Condition: &expr.Expr{
-								Title:      defaultConditionTitle,
+								Title:      DefaultConditionTitle,
 								Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(1*time.Hour).Format(time.RFC3339)),
 							},
 						},
@@ -353,7 +360,7 @@ func TestDo(t *testing.T) {
 								},
 								Role: "roles/accessapproval.approver",
 								Condition: &expr.Expr{
-									Title:      defaultConditionTitle,
+									Title:      DefaultConditionTitle,
 									Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(1*time.Hour).Format(time.RFC3339)),
 								},
 							},
@@ -364,7 +371,7 @@ func TestDo(t *testing.T) {
 								},
 								Role: "roles/accessapproval.approver",
 								Condition: &expr.Expr{
-									Title:      defaultConditionTitle,
+									Title:      DefaultConditionTitle,
 									Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339)),
 								},
 							},
@@ -381,7 +388,7 @@ func TestDo(t *testing.T) {
 						},
 						Role: "roles/accessapproval.approver",
 						Condition: &expr.Expr{
-							Title:      defaultConditionTitle,
+							Title:      DefaultConditionTitle,
 							Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(1*time.Hour).Format(time.RFC3339)),
 						},
 					},
@@ -392,7 +399,7 @@ func TestDo(t *testing.T) {
 						},
 						Role: "roles/accessapproval.approver",
 						Condition: &expr.Expr{
-							Title:      defaultConditionTitle,
+							Title:      DefaultConditionTitle,
 							Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339)),
 						},
 					},
@@ -414,7 +421,7 @@ func TestDo(t *testing.T) {
 							},
 							Role: "roles/accessapproval.approver",
 							Condition: &expr.Expr{
-								Title:      defaultConditionTitle,
+								Title:      DefaultConditionTitle,
 								Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(-1*time.Hour).Format(time.RFC3339)),
 							},
 						},
@@ -457,7 +464,7 @@ func TestDo(t *testing.T) {
 								},
 								Role: "roles/bigquery.dataViewer",
 								Condition: &expr.Expr{
-									Title:      defaultConditionTitle,
+									Title:      DefaultConditionTitle,
 									Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339)),
 								},
 							},
@@ -474,7 +481,7 @@ func TestDo(t *testing.T) {
 						},
 						Role: "roles/bigquery.dataViewer",
 						Condition: &expr.Expr{
-							Title:      defaultConditionTitle,
+							Title:      DefaultConditionTitle,
 							Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339)),
 						},
 					},
@@ -495,7 +502,7 @@ func TestDo(t *testing.T) {
 							},
 							Role: "roles/accessapproval.approver",
 							Condition: &expr.Expr{
-								Title:      defaultConditionTitle,
+								Title:      DefaultConditionTitle,
 								Expression: fmt.Sprintf("request.time <= timestamp('%s')", now.Add(-1*time.Hour).Format(time.RFC3339)),
 							},
 						},
@@ -538,7 +545,7 @@ func TestDo(t *testing.T) {
 								},
 								Role: "roles/accessapproval.approver",
 								Condition: &expr.Expr{
-									Title:      defaultConditionTitle,
+									Title:      DefaultConditionTitle,
 									Expression: fmt.Sprintf("request.time <= timestamp('%s')", now.Add(-1*time.Hour).Format(time.RFC3339)),
 								},
 							},
@@ -548,13 +555,18 @@ func TestDo(t *testing.T) {
 								},
 								Role: "roles/bigquery.dataViewer",
 								Condition: &expr.Expr{
-									Title:      defaultConditionTitle,
+									Title:      DefaultConditionTitle,
 									Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339)),
 								},
 							},
 						},
 						Version: 3,
 					},
+					Warnings: []string{
+						fmt.Sprintf("failed to check expiry: expression %q does not match format %q",
+							fmt.Sprintf("request.time <= timestamp('%s')", now.Add(-1*time.Hour).Format(time.RFC3339)),
+							"request.time < timestamp('YYYY-MM-DDTHH:MM:SSZ')"),
+					},
 				},
 			},
 			wantOrganizationsPolicy: &iampb.Policy{
@@ -565,7 +577,7 @@ func TestDo(t *testing.T) {
 						},
 						Role: "roles/accessapproval.approver",
 						Condition: &expr.Expr{
-							Title:      defaultConditionTitle,
+							Title:      DefaultConditionTitle,
 							Expression: fmt.Sprintf("request.time <= timestamp('%s')", now.Add(-1*time.Hour).Format(time.RFC3339)),
 						},
 					},
@@ -575,7 +587,7 @@ func TestDo(t *testing.T) {
 						},
 						Role: "roles/bigquery.dataViewer",
 						Condition: &expr.Expr{
-							Title:      defaultConditionTitle,
+							Title:      DefaultConditionTitle,
 							Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339)),
 						},
 					},
@@ -596,7 +608,7 @@ func TestDo(t *testing.T) {
 							},
 							Role: "roles/accessapproval.approver",
 							Condition: &expr.Expr{
-								Title:      defaultConditionTitle,
+								Title:      DefaultConditionTitle,
 								Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(-1*time.Hour).Format(time.RFC850)),
 							},
 						},
@@ -639,7 +651,7 @@ func TestDo(t *testing.T) {
 								},
 								Role: "roles/accessapproval.approver",
 								Condition: &expr.Expr{
-									Title:      defaultConditionTitle,
+									Title:      DefaultConditionTitle,
 									Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(-1*time.Hour).Format(time.RFC850)),
 								},
 							},
@@ -649,13 +661,18 @@ func TestDo(t *testing.T) {
 								},
 								Role: "roles/bigquery.dataViewer",
 								Condition: &expr.Expr{
-									Title:      defaultConditionTitle,
+									Title:      DefaultConditionTitle,
 									Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339)),
 								},
 							},
 						},
 						Version: 3,
 					},
+					Warnings: []string{
+						fmt.Sprintf("failed to check expiry: failed to parse expiration %q: parsing time %q as %q: cannot parse %q as %q",
+							fmt.Sprintf("request.time < timestamp('%s')", now.Add(-1*time.Hour).Format(time.RFC850)),
+							now.Add(-1*time.Hour).Format(time.RFC850), time.RFC3339, now.Add(-1*time.Hour).Format(time.RFC850), "2006"),
+					},
 				},
 			},
 			wantOrganizationsPolicy: &iampb.Policy{
@@ -666,7 +683,7 @@ func TestDo(t *testing.T) {
 						},
 						Role: "roles/accessapproval.approver",
 						Condition: &expr.Expr{
-							Title:      defaultConditionTitle,
+							Title:      DefaultConditionTitle,
 							Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(-1*time.Hour).Format(time.RFC850)),
 						},
 					},
@@ -676,7 +693,7 @@ func TestDo(t *testing.T) {
 						},
 						Role: "roles/bigquery.dataViewer",
 						Condition: &expr.Expr{
-							Title:      defaultConditionTitle,
+							Title:      DefaultConditionTitle,
 							Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339)),
 						},
 					},
@@ -743,7 +760,7 @@ func TestDo(t *testing.T) {
 								},
 								Role: "roles/cloudkms.cryptoOperator",
 								Condition: &expr.Expr{
-									Title:      defaultConditionTitle,
+									Title:      DefaultConditionTitle,
 									Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339)),
 								},
 							},
@@ -767,7 +784,7 @@ func TestDo(t *testing.T) {
 						},
 						Role: "roles/cloudkms.cryptoOperator",
 						Condition: &expr.Expr{
-							Title:      defaultConditionTitle,
+							Title:      DefaultConditionTitle,
 							Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339)),
 						},
 					},
@@ -794,7 +811,7 @@ func TestDo(t *testing.T) {
 							},
 							Role: "roles/accesscontextmanager.policyAdmin",
 							Condition: &expr.Expr{
-								Title:      defaultConditionTitle,
+								Title:      DefaultConditionTitle,
 								Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(1*time.Hour).Format(time.RFC3339)),
 							},
 						},
@@ -831,7 +848,7 @@ func TestDo(t *testing.T) {
 								},
 								Role: "roles/accesscontextmanager.policyAdmin",
 								Condition: &expr.Expr{
-									Title:      defaultConditionTitle,
+									Title:      DefaultConditionTitle,
 									Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(1*time.Hour).Format(time.RFC3339)),
 								},
 							},
@@ -841,7 +858,7 @@ func TestDo(t *testing.T) {
 								},
 								Role: "roles/accessapproval.approver",
 								Condition: &expr.Expr{
-									Title:      defaultConditionTitle,
+									Title:      DefaultConditionTitle,
 									Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339)),
 								},
 							},
@@ -860,7 +877,7 @@ func TestDo(t *testing.T) {
 						},
 						Role: "roles/accesscontextmanager.policyAdmin",
 						Condition: &expr.Expr{
-							Title:      defaultConditionTitle,
+							Title:      DefaultConditionTitle,
 							Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(1*time.Hour).Format(time.RFC3339)),
 						},
 					},
@@ -870,7 +887,7 @@ func TestDo(t *testing.T) {
 						},
 						Role: "roles/accessapproval.approver",
 						Condition: &expr.Expr{
-							Title:      defaultConditionTitle,
+							Title:      DefaultConditionTitle,
 							Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339)),
 						},
 					},
@@ -932,7 +949,7 @@ func TestDo(t *testing.T) {
 								},
 								Role: "roles/bigquery.dataViewer",
 								Condition: &expr.Expr{
-									Title:      defaultConditionTitle,
+									Title:      DefaultConditionTitle,
 									Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339)),
 								},
 							},
@@ -952,7 +969,7 @@ func TestDo(t *testing.T) {
 						},
 						Role: "roles/bigquery.dataViewer",
 						Condition: &expr.Expr{
-							Title:      defaultConditionTitle,
+							Title:      DefaultConditionTitle,
 							Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339)),
 						},
 					},
@@ -1013,7 +1030,7 @@ func TestDo(t *testing.T) {
 								},
 								Role: "roles/cloudkms.cryptoOperator",
 								Condition: &expr.Expr{
-									Title:      defaultConditionTitle,
+									Title:      DefaultConditionTitle,
 									Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(1*time.Hour).Format(time.RFC3339)),
 								},
 							},
@@ -1032,7 +1049,7 @@ func TestDo(t *testing.T) {
 						},
 						Role: "roles/cloudkms.cryptoOperator",
 						Condition: &expr.Expr{
-							Title:      defaultConditionTitle,
+							Title:      DefaultConditionTitle,
 							Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(1*time.Hour).Format(time.RFC3339)),
 						},
 					},
@@ -1110,6 +1127,7 @@ func TestCleanup(t *testing.T) {
 		foldersServer           *fakeServer
 		projectsServer          *fakeServer
 		request                 *v1alpha1.IAMRequest
+		now                     time.Time
 		wantPolicies            []*v1alpha1.IAMResponse
 		wantErrSubstr           string
 		wantOrganizationsPolicy *iampb.Policy
@@ -1135,7 +1153,7 @@ func TestCleanup(t *testing.T) {
 							},
 							Role: "roles/accessapproval.viewer",
 							Condition: &expr.Expr{
-								Title:      defaultConditionTitle,
+								Title:      DefaultConditionTitle,
 								Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(1*time.Hour).Format(time.RFC3339)),
 							},
 						},
@@ -1146,7 +1164,7 @@ func TestCleanup(t *testing.T) {
 							},
 							Role: "roles/accessapproval.approver",
 							Condition: &expr.Expr{
-								Title:      defaultConditionTitle,
+								Title:      DefaultConditionTitle,
 								Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(1*time.Hour).Format(time.RFC3339)),
 							},
 						},
@@ -1157,7 +1175,7 @@ func TestCleanup(t *testing.T) {
 							},
 							Role: "roles/accessapproval.approver",
 							Condition: &expr.Expr{
-								Title:      defaultConditionTitle,
+								Title:      DefaultConditionTitle,
 								Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(-1*time.Hour).Format(time.RFC3339)),
 							},
 						},
@@ -1168,7 +1186,7 @@ func TestCleanup(t *testing.T) {
 							},
 							Role: "roles/accessapproval.approver",
 							Condition: &expr.Expr{
-								Title:      defaultConditionTitle,
+								Title:      DefaultConditionTitle,
 								Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(-1*time.Hour).Format(time.RFC3339)),
 							},
 						},
@@ -1216,7 +1234,7 @@ func TestCleanup(t *testing.T) {
 								},
 								Role: "roles/accessapproval.viewer",
 								Condition: &expr.Expr{
-									Title:      defaultConditionTitle,
+									Title:      DefaultConditionTitle,
 									Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(1*time.Hour).Format(time.RFC3339)),
 								},
 							},
@@ -1240,7 +1258,7 @@ func TestCleanup(t *testing.T) {
 						},
 						Role: "roles/accessapproval.viewer",
 						Condition: &expr.Expr{
-							Title:      defaultConditionTitle,
+							Title:      DefaultConditionTitle,
 							Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(1*time.Hour).Format(time.RFC3339)),
 						},
 					},
@@ -1261,7 +1279,7 @@ func TestCleanup(t *testing.T) {
 							},
 							Role: "roles/accessapproval.approver",
 							Condition: &expr.Expr{
-								Title:      defaultConditionTitle,
+								Title:      DefaultConditionTitle,
 								Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(-1*time.Hour).Format(time.RFC3339)),
 							},
 						},
@@ -1315,7 +1333,7 @@ func TestCleanup(t *testing.T) {
 							},
 							Role: "roles/accessapproval.approver",
 							Condition: &expr.Expr{
-								Title:      defaultConditionTitle,
+								Title:      DefaultConditionTitle,
 								Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(1*time.Hour).Format(time.RFC3339)),
 							},
 						},
@@ -1368,7 +1386,7 @@ func TestCleanup(t *testing.T) {
 							},
 							Role: "roles/accessapproval.approver",
 							Condition: &expr.Expr{
-								Title:      defaultConditionTitle,
+								Title:      DefaultConditionTitle,
 								Expression: fmt.Sprintf("request.time <= timestamp('%s')", now.Add(-1*time.Hour).Format(time.RFC3339)),
 							},
 						},
@@ -1407,7 +1425,7 @@ func TestCleanup(t *testing.T) {
 								},
 								Role: "roles/accessapproval.approver",
 								Condition: &expr.Expr{
-									Title:      defaultConditionTitle,
+									Title:      DefaultConditionTitle,
 									Expression: fmt.Sprintf("request.time <= timestamp('%s')", now.Add(-1*time.Hour).Format(time.RFC3339)),
 								},
 							},
@@ -1423,7 +1441,7 @@ func TestCleanup(t *testing.T) {
 						},
 						Role: "roles/accessapproval.approver",
 						Condition: &expr.Expr{
-							Title:      defaultConditionTitle,
+							Title:      DefaultConditionTitle,
 							Expression: fmt.Sprintf("request.time <= timestamp('%s')", now.Add(-1*time.Hour).Format(time.RFC3339)),
 						},
 					},
@@ -1444,7 +1462,7 @@ func TestCleanup(t *testing.T) {
 							},
 							Role: "roles/accessapproval.approver",
 							Condition: &expr.Expr{
-								Title:      defaultConditionTitle,
+								Title:      DefaultConditionTitle,
 								Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(-1*time.Hour).Format(time.RFC850)),
 							},
 						},
@@ -1483,7 +1501,7 @@ func TestCleanup(t *testing.T) {
 								},
 								Role: "roles/accessapproval.approver",
 								Condition: &expr.Expr{
-									Title:      defaultConditionTitle,
+									Title:      DefaultConditionTitle,
 									Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(-1*time.Hour).Format(time.RFC850)),
 								},
 							},
@@ -1499,7 +1517,7 @@ func TestCleanup(t *testing.T) {
 						},
 						Role: "roles/accessapproval.approver",
 						Condition: &expr.Expr{
-							Title:      defaultConditionTitle,
+							Title:      DefaultConditionTitle,
 							Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(-1*time.Hour).Format(time.RFC850)),
 						},
 					},
@@ -1584,7 +1602,7 @@ func TestCleanup(t *testing.T) {
 							},
 							Role: "roles/accessapproval.approver",
 							Condition: &expr.Expr{
-								Title:      defaultConditionTitle,
+								Title:      DefaultConditionTitle,
 								Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(1*time.Hour).Format(time.RFC3339)),
 							},
 						},
@@ -1623,7 +1641,7 @@ func TestCleanup(t *testing.T) {
 								},
 								Role: "roles/accessapproval.approver",
 								Condition: &expr.Expr{
-									Title:      defaultConditionTitle,
+									Title:      DefaultConditionTitle,
 									Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(1*time.Hour).Format(time.RFC3339)),
 								},
 							},
@@ -1639,7 +1657,7 @@ func TestCleanup(t *testing.T) {
 						},
 						Role: "roles/accessapproval.approver",
 						Condition: &expr.Expr{
-							Title:      defaultConditionTitle,
+							Title:      DefaultConditionTitle,
 							Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(1*time.Hour).Format(time.RFC3339)),
 						},
 					},
@@ -1751,6 +1769,57 @@ func TestCleanup(t *testing.T) {
 			},
 			wantProjectsPolicy: &iampb.Policy{},
 		},
+		{
+			name: "clean_up_with_pinned_now",
+			organizationsServer: &fakeServer{
+				policy: &iampb.Policy{
+					Bindings: []*iampb.Binding{
+						// Bindings expired at the pinned time to be removed.
+						{
+							Members: []string{
+								"user:test-org-userB@example.com",
+							},
+							Role: "roles/accessapproval.approver",
+							Condition: &expr.Expr{
+								Title:      DefaultConditionTitle,
+								Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(1*time.Hour).Format(time.RFC3339)),
+							},
+						},
+					},
+				},
+			},
+			foldersServer: &fakeServer{
+				policy: &iampb.Policy{},
+			},
+			projectsServer: &fakeServer{
+				policy: &iampb.Policy{},
+			},
+			request: &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{
+					{
+						Resource: "organizations/foo",
+						Bindings: []*v1alpha1.Binding{
+							{
+								Members: []string{
+									"user:test-org-userA@example.com",
+								},
+								Role: "roles/bigquery.dataViewer",
+							},
+						},
+					},
+				},
+			},
+			now: now.Add(2 * time.Hour),
+			wantPolicies: []*v1alpha1.IAMResponse{
+				{
+					Resource: "organizations/foo",
+					Policy:   &iampb.Policy{},
+				},
+			},
+			wantOrganizationsPolicy: &iampb.Policy{},
+			wantFoldersPolicy:       &iampb.Policy{},
+			wantProjectsPolicy:      &iampb.Policy{},
+		},
 	}
 
 	for _, tc := range cases {
@@ -1770,6 +1839,9 @@ func TestCleanup(t *testing.T) {
 			opts := []Option{
 				WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
 			}
+			if !tc.now.IsZero() {
+				opts = append(opts, WithNowFunc(func() time.Time { return tc.now }))
+			}
 
 			h, err := NewIAMHandler(
 				ctx,
@@ -1807,50 +1879,1294 @@ func TestCleanup(t *testing.T) {
 	}
 }
 
-func setupFakeClients(t *testing.T, ctx context.Context, s1, s2, s3 *fakeServer) (c1, c2, c3 IAMClient) {
-	t.Helper()
+func TestDoConcurrentModification(t *testing.T) {
+	t.Parallel()
 
-	ss := []*fakeServer{s1, s2, s3}
-	cs := make([]IAMClient, 3)
-	for i, e := range ss {
-		// Setup fake servers.
-		addr, conn := testutil.FakeGRPCServer(t, func(s *grpc.Server) {
-			// Use ProjectsServer since it has the same APIs we need.
-			resourcemanagerpb.RegisterProjectsServer(s, e)
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	request := &v1alpha1.IAMRequestWrapper{
+		IAMRequest: &v1alpha1.IAMRequest{
+			ResourcePolicies: []*v1alpha1.ResourcePolicy{
+				{
+					Resource: "projects/baz",
+					Bindings: []*v1alpha1.Binding{
+						{
+							Members: []string{"user:test-project-user@example.com"},
+							Role:    "roles/bigquery.dataViewer",
+						},
+					},
+				},
+			},
+		},
+		Duration:  2 * time.Hour,
+		StartTime: now,
+	}
+	concurrentBinding := &iampb.Binding{
+		Members: []string{"user:concurrent-user@example.com"},
+		Role:    "roles/viewer",
+	}
+	requestedBinding := &iampb.Binding{
+		Members: []string{"user:test-project-user@example.com"},
+		Role:    "roles/bigquery.dataViewer",
+		Condition: &expr.Expr{
+			Title:      DefaultConditionTitle,
+			Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339)),
+		},
+	}
+
+	cases := []struct {
+		name          string
+		server        *fakeServer
+		maxRetries    uint64
+		wantErrSubstr string
+		wantSetCalls  int
+		wantPolicy    *iampb.Policy
+	}{
+		{
+			name: "no_conflict",
+			server: &fakeServer{
+				policy: &iampb.Policy{Etag: []byte("v1")},
+			},
+			maxRetries:   1,
+			wantSetCalls: 1,
+			wantPolicy: &iampb.Policy{
+				Bindings: []*iampb.Binding{requestedBinding},
+				Etag:     []byte("v1"),
+				Version:  3,
+			},
+		},
+		{
+			name: "conflict_retried_with_latest_policy",
+			server: &fakeServer{
+				policy:           &iampb.Policy{Etag: []byte("v1")},
+				concurrentWrites: []*iampb.Policy{{Bindings: []*iampb.Binding{concurrentBinding}, Etag: []byte("v2")}},
+			},
+			maxRetries:   1,
+			wantSetCalls: 2,
+			wantPolicy: &iampb.Policy{
+				Bindings: []*iampb.Binding{concurrentBinding, requestedBinding},
+				Etag:     []byte("v2"),
+				Version:  3,
+			},
+		},
+		{
+			name: "conflict_retries_exhausted",
+			server: &fakeServer{
+				policy: &iampb.Policy{Etag: []byte("v1")},
+				concurrentWrites: []*iampb.Policy{
+					{Bindings: []*iampb.Binding{concurrentBinding}, Etag: []byte("v2")},
+					{Bindings: []*iampb.Binding{concurrentBinding}, Etag: []byte("v3")},
+				},
+			},
+			maxRetries:    1,
+			wantErrSubstr: "failed to set IAM policy due to concurrent policy modification",
+			wantSetCalls:  2,
+			wantPolicy: &iampb.Policy{
+				Bindings: []*iampb.Binding{concurrentBinding},
+				Etag:     []byte("v3"),
+			},
+		},
+		{
+			name: "permission_denied_not_retried",
+			server: &fakeServer{
+				policy:          &iampb.Policy{Etag: []byte("v1")},
+				setIAMPolicyErr: status.Error(codes.PermissionDenied, "Permission Denied"),
+			},
+			maxRetries:    3,
+			wantErrSubstr: "failed to set IAM policy: rpc error: code = PermissionDenied",
+			wantSetCalls:  1,
+			wantPolicy:    &iampb.Policy{Etag: []byte("v1")},
+		},
+		{
+			name: "internal_error_retried",
+			server: &fakeServer{
+				policy:          &iampb.Policy{Etag: []byte("v1")},
+				setIAMPolicyErr: status.Error(codes.Internal, "Internal Server Error"),
+			},
+			maxRetries:    2,
+			wantErrSubstr: "failed to set IAM policy: rpc error: code = Internal desc = Internal Server Error, retrying",
+			wantSetCalls:  3,
+			wantPolicy:    &iampb.Policy{Etag: []byte("v1")},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+				tc.server,
+			)
+
+			h, err := NewIAMHandler(
+				ctx,
+				fakeOrganizationsClient,
+				fakeFoldersClient,
+				fakeProjectsClient,
+				WithRetry(retry.WithMaxRetries(tc.maxRetries, retry.NewConstant(time.Millisecond))),
+			)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			_, gotErr := h.Do(ctx, request)
+			if diff := testutil.DiffErrString(gotErr, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+			if got, want := tc.server.setCalls, tc.wantSetCalls; got != want {
+				t.Errorf("Process(%+v) got %d SetIamPolicy calls, want %d", tc.name, got, want)
+			}
+			if diff := cmp.Diff(tc.wantPolicy, tc.server.policy, protocmp.Transform()); diff != "" {
+				t.Errorf("Process(%+v) got project policy diff (-want, +got): %v", tc.name, diff)
+			}
 		})
-		t.Cleanup(func() {
-			conn.Close()
+	}
+}
+
+func TestDoConcurrency(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	resources := []string{"projects/a", "projects/b", "projects/c"}
+	var policies []*v1alpha1.ResourcePolicy
+	for _, r := range resources {
+		policies = append(policies, &v1alpha1.ResourcePolicy{
+			Resource: r,
+			Bindings: []*v1alpha1.Binding{
+				{
+					Members: []string{"user:test-project-user@example.com"},
+					Role:    "roles/bigquery.dataViewer",
+				},
+			},
 		})
-		// Use ProjectsClient since it has the same APIs we need.
-		fakeClient, err := resourcemanager.NewProjectsClient(ctx, option.WithGRPCConn(conn))
-		if err != nil {
-			t.Fatalf("creating client for fake at %q: %v", addr, err)
+	}
+	wantPolicy := func(r string) *v1alpha1.IAMResponse {
+		return &v1alpha1.IAMResponse{
+			Resource: r,
+			Policy: &iampb.Policy{
+				Bindings: []*iampb.Binding{
+					{
+						Members: []string{"user:test-project-user@example.com"},
+						Role:    "roles/bigquery.dataViewer",
+						Condition: &expr.Expr{
+							Title:      DefaultConditionTitle,
+							Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339)),
+						},
+					},
+				},
+				Version: 3,
+			},
 		}
-		cs[i] = fakeClient
 	}
-	return cs[0], cs[1], cs[2]
-}
 
-type fakeServer struct {
-	// Use ProjectsServer since it has the same APIs we need.
-	resourcemanagerpb.UnimplementedProjectsServer
+	cases := []struct {
+		name          string
+		concurrency   int
+		client        *fakeConcurrentIAMClient
+		wantPolicies  []*v1alpha1.IAMResponse
+		wantErrSubstr string
+	}{
+		{
+			name:        "all_resources_in_parallel",
+			concurrency: 3,
+			// Every GetIamPolicy call blocks until all resources are being handled.
+			client: newFakeConcurrentIAMClient(3, nil),
+			wantPolicies: []*v1alpha1.IAMResponse{
+				wantPolicy("projects/a"),
+				wantPolicy("projects/b"),
+				wantPolicy("projects/c"),
+			},
+		},
+		{
+			name:        "errors_reported_in_order",
+			concurrency: 2,
+			client: newFakeConcurrentIAMClient(0, map[string]error{
+				"projects/a": fmt.Errorf("error for projects/a"),
+				"projects/c": fmt.Errorf("error for projects/c"),
+			}),
+			wantPolicies: []*v1alpha1.IAMResponse{
+				wantPolicy("projects/b"),
+			},
+			wantErrSubstr: "failed to handle policy update for resource projects/a: " +
+				"failed to handle IAM request: failed to get IAM policy: error for projects/a\n" +
+				"failed to handle policy update for resource projects/c",
+		},
+	}
 
-	policy          *iampb.Policy
-	getIAMPolicyErr error
-	setIAMPolicyErr error
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			h, err := NewIAMHandler(
+				ctx,
+				tc.client,
+				tc.client,
+				tc.client,
+				WithRetry(retry.WithMaxRetries(0, retry.NewConstant(time.Millisecond))),
+				WithConcurrency(tc.concurrency),
+			)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			gotPolicies, gotErr := h.Do(ctx, &v1alpha1.IAMRequestWrapper{
+				IAMRequest: &v1alpha1.IAMRequest{ResourcePolicies: policies},
+				Duration:   2 * time.Hour,
+				StartTime:  now,
+			})
+			if diff := testutil.DiffErrString(gotErr, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantPolicies, gotPolicies, protocmp.Transform()); diff != "" {
+				t.Errorf("Process(%+v) got diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
 }
 
-func (s *fakeServer) GetIamPolicy(context.Context, *iampb.GetIamPolicyRequest) (*iampb.Policy, error) {
-	if s.getIAMPolicyErr != nil {
-		return nil, s.getIAMPolicyErr
+func TestWithConcurrency(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewIAMHandler(context.Background(), nil, nil, nil, WithConcurrency(0))
+	if diff := testutil.DiffErrString(err, "concurrency must be at least 1, got 0"); diff != "" {
+		t.Errorf("got unexpected error substring: %v", diff)
 	}
-	return s.policy, s.getIAMPolicyErr
 }
 
-func (s *fakeServer) SetIamPolicy(c context.Context, r *iampb.SetIamPolicyRequest) (*iampb.Policy, error) {
-	if s.setIAMPolicyErr != nil {
-		return nil, s.setIAMPolicyErr
+func TestRenew(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	aodBinding := func(expiry time.Time, role string, members ...string) *iampb.Binding {
+		return &iampb.Binding{
+			Members: members,
+			Role:    role,
+			Condition: &expr.Expr{
+				Title:      DefaultConditionTitle,
+				Expression: fmt.Sprintf("request.time < timestamp('%s')", expiry.Format(time.RFC3339)),
+			},
+		}
 	}
-	s.policy = r.GetPolicy()
-	return s.policy, s.setIAMPolicyErr
+	request := func(members ...string) *v1alpha1.IAMRequestWrapper {
+		return &v1alpha1.IAMRequestWrapper{
+			IAMRequest: &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{
+					{
+						Resource: "projects/baz",
+						Bindings: []*v1alpha1.Binding{
+							{
+								Members: members,
+								Role:    "roles/bigquery.dataViewer",
+							},
+						},
+					},
+				},
+			},
+			Duration:  2 * time.Hour,
+			StartTime: now,
+		}
+	}
+
+	cases := []struct {
+		name          string
+		server        *fakeServer
+		request       *v1alpha1.IAMRequestWrapper
+		wantErrSubstr string
+		wantPolicy    *iampb.Policy
+	}{
+		{
+			name: "renew_active_bindings",
+			server: &fakeServer{
+				policy: &iampb.Policy{
+					Bindings: []*iampb.Binding{
+						{
+							Members: []string{"user:test-project-user@example.com"},
+							Role:    "roles/bigquery.dataViewer",
+						},
+						aodBinding(now.Add(10*time.Minute), "roles/bigquery.dataViewer", "user:a@example.com", "user:b@example.com"),
+						aodBinding(now.Add(20*time.Minute), "roles/viewer", "user:a@example.com"),
+					},
+					Version: 3,
+				},
+			},
+			request: request("user:a@example.com"),
+			wantPolicy: &iampb.Policy{
+				Bindings: []*iampb.Binding{
+					{
+						Members: []string{"user:test-project-user@example.com"},
+						Role:    "roles/bigquery.dataViewer",
+					},
+					aodBinding(now.Add(10*time.Minute), "roles/bigquery.dataViewer", "user:b@example.com"),
+					aodBinding(now.Add(20*time.Minute), "roles/viewer", "user:a@example.com"),
+					aodBinding(now.Add(2*time.Hour), "roles/bigquery.dataViewer", "user:a@example.com"),
+				},
+				Version: 3,
+			},
+		},
+		{
+			name: "skip_members_without_active_bindings",
+			server: &fakeServer{
+				policy: &iampb.Policy{
+					Bindings: []*iampb.Binding{
+						aodBinding(now.Add(10*time.Minute), "roles/bigquery.dataViewer", "user:a@example.com"),
+						aodBinding(now.Add(-10*time.Minute), "roles/bigquery.dataViewer", "user:b@example.com"),
+						aodBinding(now.Add(10*time.Minute), "roles/viewer", "user:c@example.com"),
+					},
+					Version: 3,
+				},
+			},
+			request: request("user:a@example.com", "user:b@example.com", "user:c@example.com"),
+			wantErrSubstr: `active AOD binding not found for member "user:b@example.com" with role "roles/bigquery.dataViewer"` + "\n" +
+				`active AOD binding not found for member "user:c@example.com" with role "roles/bigquery.dataViewer"`,
+			wantPolicy: &iampb.Policy{
+				Bindings: []*iampb.Binding{
+					aodBinding(now.Add(10*time.Minute), "roles/viewer", "user:c@example.com"),
+					aodBinding(now.Add(2*time.Hour), "roles/bigquery.dataViewer", "user:a@example.com"),
+				},
+				Version: 3,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+				tc.server,
+			)
+
+			h, err := NewIAMHandler(
+				ctx,
+				fakeOrganizationsClient,
+				fakeFoldersClient,
+				fakeProjectsClient,
+				WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+				WithNowFunc(func() time.Time { return now }),
+			)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			_, gotErr := h.Renew(ctx, tc.request)
+			if diff := testutil.DiffErrString(gotErr, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantPolicy, tc.server.policy, protocmp.Transform()); diff != "" {
+				t.Errorf("Process(%+v) got project policy diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestRevokeMember(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	aodBinding := func(expiry time.Time, role string, members ...string) *iampb.Binding {
+		return &iampb.Binding{
+			Members: members,
+			Role:    role,
+			Condition: &expr.Expr{
+				Title:      DefaultConditionTitle,
+				Expression: fmt.Sprintf("request.time < timestamp('%s')", expiry.Format(time.RFC3339)),
+			},
+		}
+	}
+	nonAODBinding := &iampb.Binding{
+		Members: []string{"user:alice@example.com"},
+		Role:    "roles/owner",
+	}
+
+	foldersServer := &fakeServer{
+		policy: &iampb.Policy{
+			Bindings: []*iampb.Binding{
+				nonAODBinding,
+				aodBinding(now.Add(time.Hour), "roles/viewer", "user:alice@example.com", "user:bob@example.com"),
+				aodBinding(now.Add(-time.Hour), "roles/editor", "user:alice@example.com"),
+			},
+			Version: 3,
+		},
+	}
+	projectsServer := &fakeServer{
+		policy: &iampb.Policy{
+			Bindings: []*iampb.Binding{
+				aodBinding(now.Add(time.Hour), "roles/bigquery.dataViewer", "user:bob@example.com"),
+			},
+			Version: 3,
+		},
+	}
+
+	ctx := context.Background()
+	fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+		t,
+		ctx,
+		&fakeServer{policy: &iampb.Policy{}},
+		foldersServer,
+		projectsServer,
+	)
+
+	h, err := NewIAMHandler(
+		ctx,
+		fakeOrganizationsClient,
+		fakeFoldersClient,
+		fakeProjectsClient,
+		WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+		WithNowFunc(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatalf("failed to create IAMHandler: %v", err)
+	}
+
+	if _, err := h.RevokeMember(ctx, "user:alice@example.com", []string{"folders/bar", "projects/baz"}); err != nil {
+		t.Fatalf("RevokeMember got unexpected error: %v", err)
+	}
+
+	wantFoldersPolicy := &iampb.Policy{
+		Bindings: []*iampb.Binding{
+			nonAODBinding,
+			aodBinding(now.Add(time.Hour), "roles/viewer", "user:bob@example.com"),
+		},
+		Version: 3,
+	}
+	if diff := cmp.Diff(wantFoldersPolicy, foldersServer.policy, protocmp.Transform()); diff != "" {
+		t.Errorf("RevokeMember got folder policy diff (-want, +got): %v", diff)
+	}
+	wantProjectsPolicy := &iampb.Policy{
+		Bindings: []*iampb.Binding{
+			aodBinding(now.Add(time.Hour), "roles/bigquery.dataViewer", "user:bob@example.com"),
+		},
+		Version: 3,
+	}
+	if diff := cmp.Diff(wantProjectsPolicy, projectsServer.policy, protocmp.Transform()); diff != "" {
+		t.Errorf("RevokeMember got project policy diff (-want, +got): %v", diff)
+	}
+}
+
+func TestDoRoleBundle(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	condition := &expr.Expr{
+		Title:      DefaultConditionTitle,
+		Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339)),
+	}
+
+	ctx := context.Background()
+	projectsServer := &fakeServer{policy: &iampb.Policy{}}
+	fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+		t,
+		ctx,
+		&fakeServer{policy: &iampb.Policy{}},
+		&fakeServer{policy: &iampb.Policy{}},
+		projectsServer,
+	)
+
+	h, err := NewIAMHandler(
+		ctx,
+		fakeOrganizationsClient,
+		fakeFoldersClient,
+		fakeProjectsClient,
+		WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+	)
+	if err != nil {
+		t.Fatalf("failed to create IAMHandler: %v", err)
+	}
+
+	if _, err := h.Do(ctx, &v1alpha1.IAMRequestWrapper{
+		IAMRequest: &v1alpha1.IAMRequest{
+			ResourcePolicies: []*v1alpha1.ResourcePolicy{
+				{
+					Resource: "projects/baz",
+					Bindings: []*v1alpha1.Binding{
+						{
+							Members:    []string{"user:test-project-user@example.com"},
+							RoleBundle: "bq-read",
+						},
+					},
+				},
+			},
+		},
+		Duration:  2 * time.Hour,
+		StartTime: now,
+	}); err != nil {
+		t.Fatalf("Do got unexpected error: %v", err)
+	}
+
+	want := &iampb.Policy{
+		Bindings: []*iampb.Binding{
+			{
+				Members:   []string{"user:test-project-user@example.com"},
+				Role:      "roles/bigquery.dataViewer",
+				Condition: condition,
+			},
+			{
+				Members:   []string{"user:test-project-user@example.com"},
+				Role:      "roles/bigquery.jobUser",
+				Condition: condition,
+			},
+			{
+				Members:   []string{"user:test-project-user@example.com"},
+				Role:      "roles/bigquery.metadataViewer",
+				Condition: condition,
+			},
+		},
+		Version: 3,
+	}
+	if diff := cmp.Diff(want, projectsServer.policy, protocmp.Transform()); diff != "" {
+		t.Errorf("Do got project policy diff (-want, +got): %v", diff)
+	}
+}
+
+func TestCleanupCustomRoleBindings(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	condition := &expr.Expr{
+		Title:      DefaultConditionTitle,
+		Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339)),
+	}
+	binding := func(role string) *iampb.Binding {
+		return &iampb.Binding{
+			Members:   []string{"user:test-project-user@example.com"},
+			Role:      role,
+			Condition: condition,
+		}
+	}
+
+	ctx := context.Background()
+	// The custom roles have the same ID as the predefined role, and must not be
+	// removed with it.
+	projectsServer := &fakeServer{policy: &iampb.Policy{
+		Bindings: []*iampb.Binding{
+			binding("roles/viewer"),
+			binding("projects/baz/roles/viewer"),
+			binding("organizations/123/roles/viewer"),
+		},
+	}}
+	fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+		t,
+		ctx,
+		&fakeServer{policy: &iampb.Policy{}},
+		&fakeServer{policy: &iampb.Policy{}},
+		projectsServer,
+	)
+
+	h, err := NewIAMHandler(
+		ctx,
+		fakeOrganizationsClient,
+		fakeFoldersClient,
+		fakeProjectsClient,
+		WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+		WithNowFunc(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatalf("failed to create IAMHandler: %v", err)
+	}
+
+	if _, err := h.Cleanup(ctx, &v1alpha1.IAMRequest{
+		ResourcePolicies: []*v1alpha1.ResourcePolicy{
+			{
+				Resource: "projects/baz",
+				Bindings: []*v1alpha1.Binding{
+					{
+						Members: []string{"user:test-project-user@example.com"},
+						Role:    "projects/baz/roles/viewer",
+					},
+				},
+			},
+		},
+	}); err != nil {
+		t.Fatalf("Cleanup got unexpected error: %v", err)
+	}
+
+	want := &iampb.Policy{
+		Bindings: []*iampb.Binding{
+			binding("roles/viewer"),
+			binding("organizations/123/roles/viewer"),
+		},
+	}
+	if diff := cmp.Diff(want, projectsServer.policy, protocmp.Transform()); diff != "" {
+		t.Errorf("Cleanup got project policy diff (-want, +got): %v", diff)
+	}
+}
+
+func TestDoStartOffset(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	expiry := now.Add(2 * time.Hour).Format(time.RFC3339)
+
+	cases := []struct {
+		name     string
+		bindings []*v1alpha1.Binding
+		want     []*iampb.Binding
+		wantErr  string
+	}{
+		{
+			name: "staggered",
+			bindings: []*v1alpha1.Binding{
+				{Members: []string{"user:dba@example.com"}, Role: "roles/cloudsql.admin", StartOffset: 30 * time.Minute},
+				{Members: []string{"user:sre@example.com"}, Role: "roles/cloudsql.admin"},
+				{Members: []string{"user:sre@example.com"}, Role: "roles/compute.admin"},
+			},
+			want: []*iampb.Binding{
+				{
+					Members: []string{"user:sre@example.com"},
+					Role:    "roles/cloudsql.admin",
+					Condition: &expr.Expr{
+						Title:      DefaultConditionTitle,
+						Expression: fmt.Sprintf("request.time < timestamp('%s')", expiry),
+					},
+				},
+				{
+					Members: []string{"user:dba@example.com"},
+					Role:    "roles/cloudsql.admin",
+					Condition: &expr.Expr{
+						Title:      DefaultConditionTitle,
+						Expression: fmt.Sprintf("request.time >= timestamp('%s') && request.time < timestamp('%s')", now.Add(30*time.Minute).Format(time.RFC3339), expiry),
+					},
+				},
+				{
+					Members: []string{"user:sre@example.com"},
+					Role:    "roles/compute.admin",
+					Condition: &expr.Expr{
+						Title:      DefaultConditionTitle,
+						Expression: fmt.Sprintf("request.time < timestamp('%s')", expiry),
+					},
+				},
+			},
+		},
+		{
+			name: "offset_not_less_than_duration",
+			bindings: []*v1alpha1.Binding{
+				{Members: []string{"user:dba@example.com"}, Role: "roles/cloudsql.admin", StartOffset: 2 * time.Hour},
+			},
+			wantErr: "start offset 2h0m0s of binding on projects/baz must be less than duration 2h0m0s",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			projectsServer := &fakeServer{policy: &iampb.Policy{}}
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+				projectsServer,
+			)
+
+			h, err := NewIAMHandler(
+				ctx,
+				fakeOrganizationsClient,
+				fakeFoldersClient,
+				fakeProjectsClient,
+				WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+			)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			_, err = h.Do(ctx, &v1alpha1.IAMRequestWrapper{
+				IAMRequest: &v1alpha1.IAMRequest{
+					ResourcePolicies: []*v1alpha1.ResourcePolicy{
+						{Resource: "projects/baz", Bindings: tc.bindings},
+					},
+				},
+				Duration:  2 * time.Hour,
+				StartTime: now,
+			})
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error: %s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.want, projectsServer.policy.GetBindings(), protocmp.Transform()); diff != "" {
+				t.Errorf("Process(%+v) got bindings diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestDoConditionDescription(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	expression := fmt.Sprintf("request.time < timestamp('%s')", now.Add(2*time.Hour).Format(time.RFC3339))
+
+	cases := []struct {
+		name      string
+		ctx       func(context.Context) context.Context
+		requester string
+		approvers []string
+		wantDesc  string
+	}{
+		{
+			name:      "requester_and_approvers",
+			requester: "user:alice@example.com",
+			approvers: []string{"user:bob@example.com", "github:carol"},
+			wantDesc:  "requested by user:alice@example.com; approved by user:bob@example.com, github:carol",
+		},
+		{
+			name:      "requester_only",
+			requester: "user:alice@example.com",
+			wantDesc:  "requested by user:alice@example.com",
+		},
+		{
+			name: "requester_from_metadata",
+			ctx: func(ctx context.Context) context.Context {
+				return WithRequestMetadata(ctx, &RequestMetadata{Requester: "user:dave@example.com"})
+			},
+			requester: "user:alice@example.com",
+			wantDesc:  "requested by user:dave@example.com",
+		},
+		{
+			name: "no_provenance",
+		},
+		{
+			name:      "truncated",
+			requester: "user:alice@example.com",
+			approvers: []string{strings.Repeat("a", 300)},
+			wantDesc:  ("requested by user:alice@example.com; approved by " + strings.Repeat("a", 300))[:253] + "...",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			projectsServer := &fakeServer{policy: &iampb.Policy{}}
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+				projectsServer,
+			)
+
+			h, err := NewIAMHandler(
+				ctx,
+				fakeOrganizationsClient,
+				fakeFoldersClient,
+				fakeProjectsClient,
+				WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+			)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			if tc.ctx != nil {
+				ctx = tc.ctx(ctx)
+			}
+			if _, err := h.Do(ctx, &v1alpha1.IAMRequestWrapper{
+				IAMRequest: &v1alpha1.IAMRequest{
+					ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+						Resource: "projects/baz",
+						Bindings: []*v1alpha1.Binding{{
+							Members: []string{"user:test-project-user@example.com"},
+							Role:    "roles/viewer",
+						}},
+					}},
+				},
+				Duration:  2 * time.Hour,
+				StartTime: now,
+				Requester: tc.requester,
+				Approvers: tc.approvers,
+			}); err != nil {
+				t.Fatalf("Process(%+v) got unexpected error: %v", tc.name, err)
+			}
+
+			want := &iampb.Policy{
+				Bindings: []*iampb.Binding{{
+					Members: []string{"user:test-project-user@example.com"},
+					Role:    "roles/viewer",
+					Condition: &expr.Expr{
+						Title:       DefaultConditionTitle,
+						Description: tc.wantDesc,
+						Expression:  expression,
+					},
+				}},
+				Version: 3,
+			}
+			if diff := cmp.Diff(want, projectsServer.policy, protocmp.Transform()); diff != "" {
+				t.Errorf("Process(%+v) got project policy diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestDependsOn(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	req := &v1alpha1.IAMRequest{
+		ResourcePolicies: []*v1alpha1.ResourcePolicy{
+			{
+				Resource:  "projects/baz",
+				Bindings:  []*v1alpha1.Binding{{Members: []string{"user:test-user@example.com"}, Role: "roles/run.developer"}},
+				DependsOn: []string{"folders/bar"},
+			},
+			{
+				Resource: "folders/bar",
+				Bindings: []*v1alpha1.Binding{{Members: []string{"user:test-user@example.com"}, Role: "roles/browser"}},
+			},
+		},
+	}
+
+	cases := []struct {
+		name          string
+		cleanup       bool
+		foldersServer *fakeServer
+		wantSets      []string
+		wantErrSubstr string
+	}{
+		{
+			name:          "grant_in_order",
+			foldersServer: &fakeServer{policy: &iampb.Policy{}},
+			wantSets:      []string{"folders/bar", "projects/baz"},
+		},
+		{
+			name:          "cleanup_in_reverse_order",
+			cleanup:       true,
+			foldersServer: &fakeServer{policy: &iampb.Policy{}},
+			wantSets:      []string{"projects/baz", "folders/bar"},
+		},
+		{
+			name: "dependency_failure",
+			foldersServer: &fakeServer{
+				policy:          &iampb.Policy{},
+				setIAMPolicyErr: status.Error(codes.PermissionDenied, "injected error"),
+			},
+			wantSets:      []string{"folders/bar"},
+			wantErrSubstr: "skipped resource projects/baz since its dependency folders/bar failed",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				tc.foldersServer,
+				&fakeServer{policy: &iampb.Policy{}},
+			)
+			rec := &orderRecorder{}
+			h, err := NewIAMHandler(
+				ctx,
+				fakeOrganizationsClient,
+				&orderingIAMClient{IAMClient: fakeFoldersClient, recorder: rec},
+				&orderingIAMClient{IAMClient: fakeProjectsClient, recorder: rec},
+				WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+				WithNowFunc(func() time.Time { return now }),
+				WithConcurrency(10),
+			)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			if tc.cleanup {
+				_, err = h.Cleanup(ctx, req)
+			} else {
+				_, err = h.Do(ctx, &v1alpha1.IAMRequestWrapper{IAMRequest: req, Duration: time.Hour, StartTime: now})
+			}
+			if diff := testutil.DiffErrString(err, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantSets, rec.resources); diff != "" {
+				t.Errorf("Process(%+v) got SetIamPolicy order diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+// orderRecorder records the order of the resources of SetIamPolicy calls.
+type orderRecorder struct {
+	mu        sync.Mutex
+	resources []string
+}
+
+// orderingIAMClient records the resources of SetIamPolicy calls.
+type orderingIAMClient struct {
+	IAMClient
+
+	recorder *orderRecorder
+}
+
+func (c *orderingIAMClient) SetIamPolicy(ctx context.Context, req *iampb.SetIamPolicyRequest, opts ...gax.CallOption) (*iampb.Policy, error) {
+	c.recorder.mu.Lock()
+	c.recorder.resources = append(c.recorder.resources, req.GetResource())
+	c.recorder.mu.Unlock()
+	return c.IAMClient.SetIamPolicy(ctx, req, opts...) //nolint:wrapcheck // Want passthrough
+}
+
+func TestDoSkipImplicitCleanup(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	condition := func(exp time.Time) *expr.Expr {
+		return &expr.Expr{
+			Title:      DefaultConditionTitle,
+			Expression: fmt.Sprintf("request.time < timestamp('%s')", exp.Format(time.RFC3339)),
+		}
+	}
+	expiredBinding := &iampb.Binding{
+		Members:   []string{"user:test-userB@example.com"},
+		Role:      "roles/viewer",
+		Condition: condition(now.Add(-time.Hour)),
+	}
+	invalidBinding := &iampb.Binding{
+		Members: []string{"user:test-userC@example.com"},
+		Role:    "roles/viewer",
+		Condition: &expr.Expr{
+			Title:      DefaultConditionTitle,
+			Expression: "bananas",
+		},
+	}
+
+	ctx := context.Background()
+	projectsServer := &fakeServer{
+		policy: &iampb.Policy{
+			Bindings: []*iampb.Binding{
+				expiredBinding,
+				invalidBinding,
+				// Existing binding of the requested member and role is replaced.
+				{
+					Members:   []string{"user:test-userA@example.com"},
+					Role:      "roles/bigquery.dataViewer",
+					Condition: condition(now.Add(time.Hour)),
+				},
+			},
+		},
+	}
+	fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+		t,
+		ctx,
+		&fakeServer{policy: &iampb.Policy{}},
+		&fakeServer{policy: &iampb.Policy{}},
+		projectsServer,
+	)
+
+	h, err := NewIAMHandler(
+		ctx,
+		fakeOrganizationsClient,
+		fakeFoldersClient,
+		fakeProjectsClient,
+		WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+		WithNowFunc(func() time.Time { return now }),
+		WithSkipImplicitCleanup(),
+	)
+	if err != nil {
+		t.Fatalf("failed to create IAMHandler: %v", err)
+	}
+
+	if _, err := h.Do(ctx, &v1alpha1.IAMRequestWrapper{
+		IAMRequest: &v1alpha1.IAMRequest{
+			ResourcePolicies: []*v1alpha1.ResourcePolicy{
+				{
+					Resource: "projects/baz",
+					Bindings: []*v1alpha1.Binding{
+						{
+							Members: []string{"user:test-userA@example.com"},
+							Role:    "roles/bigquery.dataViewer",
+						},
+					},
+				},
+			},
+		},
+		Duration:  2 * time.Hour,
+		StartTime: now,
+	}); err != nil {
+		t.Fatalf("Do got unexpected error: %v", err)
+	}
+
+	want := &iampb.Policy{
+		Bindings: []*iampb.Binding{
+			expiredBinding,
+			invalidBinding,
+			{
+				Members:   []string{"user:test-userA@example.com"},
+				Role:      "roles/bigquery.dataViewer",
+				Condition: condition(now.Add(2 * time.Hour)),
+			},
+		},
+		Version: 3,
+	}
+	if diff := cmp.Diff(want, projectsServer.policy, protocmp.Transform()); diff != "" {
+		t.Errorf("Do got project policy diff (-want, +got): %v", diff)
+	}
+}
+
+func TestDoCallOptions(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	req := &v1alpha1.IAMRequestWrapper{
+		IAMRequest: &v1alpha1.IAMRequest{
+			ResourcePolicies: []*v1alpha1.ResourcePolicy{
+				{
+					Resource: "projects/baz",
+					Bindings: []*v1alpha1.Binding{
+						{
+							Members: []string{"user:test-userA@example.com"},
+							Role:    "roles/bigquery.dataViewer",
+						},
+					},
+				},
+			},
+		},
+		Duration:  2 * time.Hour,
+		StartTime: now,
+	}
+
+	cases := []struct {
+		name           string
+		projectsServer *fakeServer
+		opts           []Option
+		wantGetPaths   []string
+		wantSetPaths   []string
+		wantErrSubstr  string
+	}{
+		{
+			name:           "default_options",
+			projectsServer: &fakeServer{policy: &iampb.Policy{}},
+			wantGetPaths:   []string{""},
+			wantSetPaths:   []string{""},
+		},
+		{
+			name:           "call_options",
+			projectsServer: &fakeServer{policy: &iampb.Policy{}},
+			opts: []Option{
+				WithGetPolicyCallOptions(gax.WithPath("get")),
+				WithSetPolicyCallOptions(gax.WithPath("set")),
+			},
+			wantGetPaths: []string{"get"},
+			wantSetPaths: []string{"set"},
+		},
+		{
+			name: "disable_client_retry",
+			projectsServer: &fakeServer{
+				policy: &iampb.Policy{},
+				// The client retries unavailable errors by default.
+				getIAMPolicyErr: status.Error(codes.Unavailable, "injected error"),
+			},
+			opts: []Option{
+				WithGetPolicyCallOptions(gax.WithRetry(func() gax.Retryer { return nil })),
+			},
+			wantGetPaths:  []string{""},
+			wantErrSubstr: "failed to get IAM policy",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+				tc.projectsServer,
+			)
+			projectsClient := &recordingIAMClient{IAMClient: fakeProjectsClient}
+
+			opts := append([]Option{
+				WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+				WithNowFunc(func() time.Time { return now }),
+			}, tc.opts...)
+			h, err := NewIAMHandler(ctx, fakeOrganizationsClient, fakeFoldersClient, projectsClient, opts...)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			_, gotErr := h.Do(ctx, req)
+			if diff := testutil.DiffErrString(gotErr, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantGetPaths, projectsClient.getPaths); diff != "" {
+				t.Errorf("Process(%+v) got GetIamPolicy call option paths diff (-want, +got): %v", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantSetPaths, projectsClient.setPaths); diff != "" {
+				t.Errorf("Process(%+v) got SetIamPolicy call option paths diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+// recordingIAMClient records the paths in the call options of the calls.
+type recordingIAMClient struct {
+	IAMClient
+
+	getPaths []string
+	setPaths []string
+}
+
+func (c *recordingIAMClient) GetIamPolicy(ctx context.Context, req *iampb.GetIamPolicyRequest, opts ...gax.CallOption) (*iampb.Policy, error) {
+	c.getPaths = append(c.getPaths, callPath(opts))
+	return c.IAMClient.GetIamPolicy(ctx, req, opts...) //nolint:wrapcheck // Want passthrough
+}
+
+func (c *recordingIAMClient) SetIamPolicy(ctx context.Context, req *iampb.SetIamPolicyRequest, opts ...gax.CallOption) (*iampb.Policy, error) {
+	c.setPaths = append(c.setPaths, callPath(opts))
+	return c.IAMClient.SetIamPolicy(ctx, req, opts...) //nolint:wrapcheck // Want passthrough
+}
+
+// callPath returns the path set by the call options.
+func callPath(opts []gax.CallOption) string {
+	var s gax.CallSettings
+	for _, o := range opts {
+		o.Resolve(&s)
+	}
+	return s.Path
+}
+
+func setupFakeClients(t *testing.T, ctx context.Context, s1, s2, s3 *fakeServer) (c1, c2, c3 IAMClient) {
+	t.Helper()
+
+	ss := []*fakeServer{s1, s2, s3}
+	cs := make([]IAMClient, 3)
+	for i, e := range ss {
+		// Setup fake servers.
+		addr, conn := testutil.FakeGRPCServer(t, func(s *grpc.Server) {
+			// Use ProjectsServer since it has the same APIs we need.
+			resourcemanagerpb.RegisterProjectsServer(s, e)
+		})
+		t.Cleanup(func() {
+			conn.Close()
+		})
+		// Use ProjectsClient since it has the same APIs we need.
+		fakeClient, err := resourcemanager.NewProjectsClient(ctx, option.WithGRPCConn(conn))
+		if err != nil {
+			t.Fatalf("creating client for fake at %q: %v", addr, err)
+		}
+		cs[i] = fakeClient
+	}
+	return cs[0], cs[1], cs[2]
+}
+
+type fakeServer struct {
+	// Use ProjectsServer since it has the same APIs we need.
+	resourcemanagerpb.UnimplementedProjectsServer
+
+	policy          *iampb.Policy
+	getIAMPolicyErr error
+	setIAMPolicyErr error
+
+	// concurrentWrites are policies written by other writers, one before each
+	// SetIamPolicy call, to simulate concurrent policy modifications.
+	concurrentWrites []*iampb.Policy
+	setCalls         int
+
+	// deniedPermissions are the permissions TestIamPermissions reports the
+	// caller does not have.
+	deniedPermissions []string
+}
+
+func (s *fakeServer) GetIamPolicy(context.Context, *iampb.GetIamPolicyRequest) (*iampb.Policy, error) {
+	if s.getIAMPolicyErr != nil {
+		return nil, s.getIAMPolicyErr
+	}
+	return s.policy, s.getIAMPolicyErr
+}
+
+func (s *fakeServer) TestIamPermissions(_ context.Context, r *iampb.TestIamPermissionsRequest) (*iampb.TestIamPermissionsResponse, error) {
+	resp := &iampb.TestIamPermissionsResponse{}
+	for _, p := range r.GetPermissions() {
+		if !slices.Contains(s.deniedPermissions, p) {
+			resp.Permissions = append(resp.Permissions, p)
+		}
+	}
+	return resp, nil
+}
+
+func (s *fakeServer) SetIamPolicy(c context.Context, r *iampb.SetIamPolicyRequest) (*iampb.Policy, error) {
+	s.setCalls++
+	if s.setIAMPolicyErr != nil {
+		return nil, s.setIAMPolicyErr
+	}
+	if len(s.concurrentWrites) > 0 {
+		s.policy, s.concurrentWrites = s.concurrentWrites[0], s.concurrentWrites[1:]
+	}
+	if !bytes.Equal(r.GetPolicy().GetEtag(), s.policy.GetEtag()) {
+		return nil, status.Error(codes.Aborted, "There were concurrent policy changes")
+	}
+	s.policy = r.GetPolicy()
+	return s.policy, s.setIAMPolicyErr
+}
+
+// fakeConcurrentIAMClient is an in-memory IAMClient safe for concurrent use.
+type fakeConcurrentIAMClient struct {
+	mu       sync.Mutex
+	policies map[string]*iampb.Policy
+	errs     map[string]error
+
+	// barrier blocks GetIamPolicy calls until the given number of calls are
+	// in flight, it is disabled if nil.
+	barrier *sync.WaitGroup
+}
+
+func newFakeConcurrentIAMClient(barrier int, errs map[string]error) *fakeConcurrentIAMClient {
+	c := &fakeConcurrentIAMClient{
+		policies: make(map[string]*iampb.Policy),
+		errs:     errs,
+	}
+	if barrier > 0 {
+		c.barrier = &sync.WaitGroup{}
+		c.barrier.Add(barrier)
+	}
+	return c
+}
+
+func (c *fakeConcurrentIAMClient) GetIamPolicy(ctx context.Context, r *iampb.GetIamPolicyRequest, _ ...gax.CallOption) (*iampb.Policy, error) {
+	if c.barrier != nil {
+		c.barrier.Done()
+		done := make(chan struct{})
+		go func() {
+			c.barrier.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			return nil, fmt.Errorf("timed out waiting for concurrent calls")
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.errs[r.GetResource()]; err != nil {
+		return nil, err
+	}
+	if p, ok := c.policies[r.GetResource()]; ok {
+		return p, nil
+	}
+	return &iampb.Policy{}, nil
+}
+
+func (c *fakeConcurrentIAMClient) SetIamPolicy(ctx context.Context, r *iampb.SetIamPolicyRequest, _ ...gax.CallOption) (*iampb.Policy, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.policies[r.GetResource()] = r.GetPolicy()
+	return r.GetPolicy(), nil
 }
@@ -0,0 +1,68 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+)
+
+// List returns the active IAM bindings added by AOD in the IAM policies of the
+// resources, one grant per role and member, without updating any IAM policy.
+func (h *IAMHandler) List(ctx context.Context, resources []string) (grants []*v1alpha1.ActiveGrant, retErr error) {
+	for _, r := range resources {
+		cp, err := h.currentPolicy(ctx, r)
+		if err != nil {
+			retErr = errors.Join(
+				retErr,
+				fmt.Errorf("failed to list active grants for resource %s: %w", r, err),
+			)
+			continue
+		}
+		grants = append(grants, h.activeGrants(r, cp)...)
+	}
+	return
+}
+
+// activeGrants returns the active AOD bindings in the policy of the resource,
+// in the order of the bindings and members in the policy. AOD bindings with
+// malformed expiry are ignored.
+func (h *IAMHandler) activeGrants(resource string, p *iampb.Policy) []*v1alpha1.ActiveGrant {
+	now := h.now()
+	var result []*v1alpha1.ActiveGrant
+	for _, b := range p.GetBindings() {
+		if b.GetCondition().GetTitle() != h.conditionTitle {
+			continue
+		}
+		exp, err := parseExpiry(b.GetCondition().GetExpression())
+		if err != nil || exp.Before(now) {
+			continue
+		}
+		for _, m := range b.GetMembers() {
+			result = append(result, &v1alpha1.ActiveGrant{
+				Resource: resource,
+				Role:     b.GetRole(),
+				Member:   m,
+				Expiry:   exp,
+			})
+		}
+	}
+	return result
+}
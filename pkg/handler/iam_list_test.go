@@ -0,0 +1,149 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sethvargo/go-retry"
+	"google.golang.org/genproto/googleapis/type/expr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestList(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	expiry := now.Add(2 * time.Hour)
+	aodBinding := func(exp time.Time, role string, members ...string) *iampb.Binding {
+		return &iampb.Binding{
+			Members: members,
+			Role:    role,
+			Condition: &expr.Expr{
+				Title:      DefaultConditionTitle,
+				Expression: fmt.Sprintf("request.time < timestamp('%s')", exp.Format(time.RFC3339)),
+			},
+		}
+	}
+
+	cases := []struct {
+		name          string
+		foldersServer *fakeServer
+		projectServer *fakeServer
+		resources     []string
+		wantGrants    []*v1alpha1.ActiveGrant
+		wantErrSubstr string
+	}{
+		{
+			name: "success",
+			foldersServer: &fakeServer{
+				policy: &iampb.Policy{
+					Bindings: []*iampb.Binding{
+						aodBinding(expiry, "roles/viewer", "user:alice@example.com", "user:bob@example.com"),
+						// Expired binding is not active.
+						aodBinding(now.Add(-time.Hour), "roles/editor", "user:alice@example.com"),
+						// Non-AOD binding is not an AOD grant.
+						{
+							Members: []string{"user:carol@example.com"},
+							Role:    "roles/owner",
+						},
+					},
+				},
+			},
+			projectServer: &fakeServer{
+				policy: &iampb.Policy{
+					Bindings: []*iampb.Binding{
+						aodBinding(expiry, "roles/bigquery.dataViewer", "user:carol@example.com"),
+					},
+				},
+			},
+			resources: []string{"folders/bar", "projects/baz"},
+			wantGrants: []*v1alpha1.ActiveGrant{
+				{Resource: "folders/bar", Role: "roles/viewer", Member: "user:alice@example.com", Expiry: expiry},
+				{Resource: "folders/bar", Role: "roles/viewer", Member: "user:bob@example.com", Expiry: expiry},
+				{Resource: "projects/baz", Role: "roles/bigquery.dataViewer", Member: "user:carol@example.com", Expiry: expiry},
+			},
+		},
+		{
+			name:          "no_grants",
+			foldersServer: &fakeServer{policy: &iampb.Policy{}},
+			projectServer: &fakeServer{policy: &iampb.Policy{}},
+			resources:     []string{"folders/bar", "projects/baz"},
+		},
+		{
+			name: "partial_failure",
+			foldersServer: &fakeServer{
+				getIAMPolicyErr: status.Error(codes.PermissionDenied, "injected error"),
+			},
+			projectServer: &fakeServer{
+				policy: &iampb.Policy{
+					Bindings: []*iampb.Binding{
+						aodBinding(expiry, "roles/bigquery.dataViewer", "user:carol@example.com"),
+					},
+				},
+			},
+			resources: []string{"folders/bar", "projects/baz"},
+			wantGrants: []*v1alpha1.ActiveGrant{
+				{Resource: "projects/baz", Role: "roles/bigquery.dataViewer", Member: "user:carol@example.com", Expiry: expiry},
+			},
+			wantErrSubstr: "failed to list active grants for resource folders/bar",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{},
+				tc.foldersServer,
+				tc.projectServer,
+			)
+
+			h, err := NewIAMHandler(
+				ctx,
+				fakeOrganizationsClient,
+				fakeFoldersClient,
+				fakeProjectsClient,
+				WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+				WithNowFunc(func() time.Time { return now }),
+			)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			gotGrants, gotErr := h.List(ctx, tc.resources)
+			if diff := testutil.DiffErrString(gotErr, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantGrants, gotGrants); diff != "" {
+				t.Errorf("Process(%+v) got grants diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"golang.org/x/time/rate"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+	"github.com/abcxyz/access-on-demand/pkg/progress"
+)
+
+// RetitleConditions replaces the condition title from with the title to in the
+// IAM bindings of the IAM policies of the resources, keeping their roles,
+// members and expiry. It is used to rebrand or split the condition titles of
+// AOD deployments. Each resource waits for the limiter, if it is not nil,
+// before its IAM policy is read. The IAM policies of resources without bindings
+// with the title from are not updated, and those resources are not included in
+// the responses. Resources that are not active are skipped and included in the
+// responses with the reason.
+func (h *IAMHandler) RetitleConditions(ctx context.Context, from, to string, resources []string, limiter *rate.Limiter) ([]*v1alpha1.IAMResponse, error) {
+	ps := make([]*v1alpha1.ResourcePolicy, 0, len(resources))
+	for _, r := range resources {
+		ps = append(ps, &v1alpha1.ResourcePolicy{Resource: r})
+	}
+	return h.handlePolicies(ctx, "retitle", ps, func(ctx context.Context, p *v1alpha1.ResourcePolicy) (*v1alpha1.IAMResponse, error) {
+		if limiter != nil {
+			if err := limiter.Wait(ctx); err != nil {
+				return nil, fmt.Errorf("failed to wait for rate limit of resource %s: %w", p.Resource, err)
+			}
+		}
+
+		cp, err := h.currentPolicy(ctx, p.Resource)
+		var inactiveErr *InactiveResourceError
+		if errors.As(err, &inactiveErr) {
+			h.reportProgress(ctx, progress.EventTypeSkipped, p.Resource, 0, inactiveErr)
+			return skippedResponse(inactiveErr), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to handle condition retitle for resource %s: %w", p.Resource, err)
+		}
+		if !slices.ContainsFunc(cp.GetBindings(), func(b *iampb.Binding) bool {
+			return b.GetCondition().GetTitle() == from
+		}) {
+			h.reportProgress(ctx, progress.EventTypeSkipped, p.Resource, 0, nil)
+			return nil, nil
+		}
+
+		// The retitled bindings of the last attempt are audited.
+		var retitled []*v1alpha1.Binding
+		retitle := func(_ context.Context, p *iampb.Policy, _ []*v1alpha1.Binding, _ time.Time) error {
+			retitled = retitleBindings(p, from, to)
+			return nil
+		}
+		// h.now() is a dummy parameter used to match the function signature.
+		np, err := h.handlePolicy(ctx, p, h.now(), retitle)
+		e := h.newAuditEvent(ctx, audit.EventTypeRetitle, &v1alpha1.ResourcePolicy{Resource: p.Resource, Bindings: retitled}, nil, err)
+		e.ConditionTitle = to
+		h.emitAuditEvent(ctx, e)
+		if errors.As(err, &inactiveErr) {
+			return skippedResponse(inactiveErr), nil
+		}
+		if err != nil {
+			return np, fmt.Errorf("failed to handle condition retitle for resource %s: %w", p.Resource, err)
+		}
+		return np, nil
+	})
+}
+
+// retitleBindings replaces the condition title from with the title to in the
+// bindings of the policy, and returns the retitled bindings. A retitled binding
+// with the same role and condition as another binding is merged into it, and
+// the members of the merged binding are deduplicated and sorted.
+func retitleBindings(p *iampb.Policy, from, to string) []*v1alpha1.Binding {
+	var retitled []*v1alpha1.Binding
+	for _, b := range p.GetBindings() {
+		if b.GetCondition() == nil || b.GetCondition().GetTitle() != from {
+			continue
+		}
+		b.Condition.Title = to
+		retitled = append(retitled, &v1alpha1.Binding{Members: slices.Clone(b.GetMembers()), Role: b.GetRole()})
+	}
+
+	bindings := make([]*iampb.Binding, 0, len(p.GetBindings()))
+	for _, b := range p.GetBindings() {
+		i := slices.IndexFunc(bindings, func(m *iampb.Binding) bool {
+			return m.GetCondition().GetTitle() == to && m.GetRole() == b.GetRole() && proto.Equal(m.GetCondition(), b.GetCondition())
+		})
+		if i < 0 {
+			bindings = append(bindings, b)
+			continue
+		}
+		bindings[i].Members = append(bindings[i].Members, b.GetMembers()...)
+		slices.Sort(bindings[i].Members)
+		bindings[i].Members = slices.Compact(bindings[i].Members)
+	}
+	p.Bindings = bindings
+	return retitled
+}
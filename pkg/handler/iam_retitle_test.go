@@ -0,0 +1,236 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sethvargo/go-retry"
+	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/type/expr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestRetitleConditions(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	binding := func(title string, expiry time.Time, role string, members ...string) *iampb.Binding {
+		return &iampb.Binding{
+			Members: members,
+			Role:    role,
+			Condition: &expr.Expr{
+				Title:      title,
+				Expression: fmt.Sprintf("request.time < timestamp('%s')", expiry.Format(time.RFC3339)),
+			},
+		}
+	}
+	nonAODBinding := func() *iampb.Binding {
+		return &iampb.Binding{
+			Members: []string{"user:alice@example.com"},
+			Role:    "roles/owner",
+		}
+	}
+
+	cases := []struct {
+		name          string
+		foldersServer *fakeServer
+		wantResp      []*v1alpha1.IAMResponse
+		wantPolicy    *iampb.Policy
+		wantEvents    []*audit.Event
+		wantErrSubstr string
+	}{
+		{
+			name: "retitle_bindings",
+			foldersServer: &fakeServer{
+				policy: &iampb.Policy{
+					Bindings: []*iampb.Binding{
+						nonAODBinding(),
+						binding("old-title", now.Add(time.Hour), "roles/viewer", "user:alice@example.com"),
+						binding("other-title", now.Add(time.Hour), "roles/viewer", "user:bob@example.com"),
+						// The retitled binding is merged into the binding with the same
+						// role and condition.
+						binding("new-title", now.Add(2*time.Hour), "roles/editor", "user:bob@example.com"),
+						binding("old-title", now.Add(2*time.Hour), "roles/editor", "user:alice@example.com", "user:bob@example.com"),
+					},
+					Version: 3,
+				},
+			},
+			wantResp: []*v1alpha1.IAMResponse{{
+				Resource: "folders/bar",
+				Policy: &iampb.Policy{
+					Bindings: []*iampb.Binding{
+						nonAODBinding(),
+						binding("new-title", now.Add(time.Hour), "roles/viewer", "user:alice@example.com"),
+						binding("other-title", now.Add(time.Hour), "roles/viewer", "user:bob@example.com"),
+						binding("new-title", now.Add(2*time.Hour), "roles/editor", "user:alice@example.com", "user:bob@example.com"),
+					},
+					Version: 3,
+				},
+			}},
+			wantPolicy: &iampb.Policy{
+				Bindings: []*iampb.Binding{
+					nonAODBinding(),
+					binding("new-title", now.Add(time.Hour), "roles/viewer", "user:alice@example.com"),
+					binding("other-title", now.Add(time.Hour), "roles/viewer", "user:bob@example.com"),
+					binding("new-title", now.Add(2*time.Hour), "roles/editor", "user:alice@example.com", "user:bob@example.com"),
+				},
+				Version: 3,
+			},
+			wantEvents: []*audit.Event{
+				{
+					Type:     audit.EventTypeRetitle,
+					Time:     now,
+					Resource: "folders/bar",
+					Bindings: []*audit.Binding{
+						{Role: "roles/viewer", Members: []string{"user:alice@example.com"}},
+						{Role: "roles/editor", Members: []string{"user:alice@example.com", "user:bob@example.com"}},
+					},
+					ConditionTitle: "new-title",
+					Outcome:        audit.OutcomeSuccess,
+				},
+			},
+		},
+		{
+			name: "no_bindings_with_title",
+			foldersServer: &fakeServer{
+				policy: &iampb.Policy{
+					Bindings: []*iampb.Binding{
+						nonAODBinding(),
+						binding("other-title", now.Add(time.Hour), "roles/viewer", "user:bob@example.com"),
+					},
+				},
+			},
+			wantPolicy: &iampb.Policy{
+				Bindings: []*iampb.Binding{
+					nonAODBinding(),
+					binding("other-title", now.Add(time.Hour), "roles/viewer", "user:bob@example.com"),
+				},
+			},
+		},
+		{
+			name: "set_policy_failure",
+			foldersServer: &fakeServer{
+				policy: &iampb.Policy{
+					Bindings: []*iampb.Binding{
+						binding("old-title", now.Add(time.Hour), "roles/viewer", "user:alice@example.com"),
+					},
+				},
+				setIAMPolicyErr: status.Error(codes.PermissionDenied, "injected error"),
+			},
+			wantPolicy: &iampb.Policy{
+				Bindings: []*iampb.Binding{
+					binding("old-title", now.Add(time.Hour), "roles/viewer", "user:alice@example.com"),
+				},
+			},
+			wantEvents: []*audit.Event{
+				{
+					Type:     audit.EventTypeRetitle,
+					Time:     now,
+					Resource: "folders/bar",
+					Bindings: []*audit.Binding{
+						{Role: "roles/viewer", Members: []string{"user:alice@example.com"}},
+					},
+					ConditionTitle: "new-title",
+					Outcome:        audit.OutcomeFailure,
+					Error: fmt.Sprintf("failed to handle IAM request: failed to set IAM policy: %s",
+						status.Error(codes.PermissionDenied, "injected error")),
+				},
+			},
+			wantErrSubstr: "failed to handle condition retitle for resource folders/bar",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				tc.foldersServer,
+				&fakeServer{policy: &iampb.Policy{}},
+			)
+
+			sink := &fakeAuditSink{}
+			h, err := NewIAMHandler(
+				ctx,
+				fakeOrganizationsClient,
+				fakeFoldersClient,
+				fakeProjectsClient,
+				WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+				WithNowFunc(func() time.Time { return now }),
+				WithAuditSink(sink),
+			)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			limiter := rate.NewLimiter(rate.Inf, 1)
+			gotResp, gotErr := h.RetitleConditions(ctx, "old-title", "new-title", []string{"folders/bar"}, limiter)
+			if diff := testutil.DiffErrString(gotErr, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+			if gotErr == nil {
+				if diff := cmp.Diff(tc.wantResp, gotResp, protocmp.Transform()); diff != "" {
+					t.Errorf("Process(%+v) got response diff (-want, +got): %v", tc.name, diff)
+				}
+			}
+			if diff := cmp.Diff(tc.wantPolicy, tc.foldersServer.policy, protocmp.Transform()); diff != "" {
+				t.Errorf("Process(%+v) got folder policy diff (-want, +got): %v", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantEvents, sink.events); diff != "" {
+				t.Errorf("Process(%+v) got audit events diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestRetitleConditions_RateLimit(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+		t,
+		ctx,
+		&fakeServer{policy: &iampb.Policy{}},
+		&fakeServer{policy: &iampb.Policy{}},
+		&fakeServer{policy: &iampb.Policy{}},
+	)
+	h, err := NewIAMHandler(ctx, fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient)
+	if err != nil {
+		t.Fatalf("failed to create IAMHandler: %v", err)
+	}
+
+	// The limiter has no burst, so it never allows a resource.
+	limiter := rate.NewLimiter(0, 0)
+	_, gotErr := h.RetitleConditions(ctx, "old-title", "new-title", []string{"folders/bar"}, limiter)
+	if diff := testutil.DiffErrString(gotErr, "failed to wait for rate limit of resource folders/bar"); diff != "" {
+		t.Errorf("RetitleConditions got unexpected error substring: %v", diff)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+)
+
+// RewriteMember replaces the member from with the member to in all IAM
+// bindings added by AOD in the IAM policies of the resources, keeping their
+// roles and expiry. It is used to migrate grants when member emails change.
+func (h *IAMHandler) RewriteMember(ctx context.Context, from, to string, resources []string) ([]*v1alpha1.IAMResponse, error) {
+	ps := make([]*v1alpha1.ResourcePolicy, 0, len(resources))
+	for _, r := range resources {
+		ps = append(ps, &v1alpha1.ResourcePolicy{
+			Resource: r,
+			Bindings: []*v1alpha1.Binding{{Members: []string{from, to}}},
+		})
+	}
+	rewrite := func(_ context.Context, p *iampb.Policy, _ []*v1alpha1.Binding, _ time.Time) error {
+		h.rewriteMemberBindings(p, from, to)
+		return nil
+	}
+	return h.handlePolicies(ctx, "rewrite", ps, func(ctx context.Context, p *v1alpha1.ResourcePolicy) (*v1alpha1.IAMResponse, error) {
+		// h.now() is a dummy parameter used to match the function signature.
+		np, err := h.handlePolicy(ctx, p, h.now(), rewrite)
+		h.writeAuditEvent(ctx, audit.EventTypeRewrite, p, nil, err)
+		if err != nil {
+			return np, fmt.Errorf("failed to handle member rewrite for resource %s: %w", p.Resource, err)
+		}
+		return np, nil
+	})
+}
+
+// rewriteMemberBindings replaces the member from with the member to in the AOD
+// bindings of the policy, the members of the updated bindings are deduplicated
+// and sorted.
+func (h *IAMHandler) rewriteMemberBindings(p *iampb.Policy, from, to string) {
+	for _, b := range p.GetBindings() {
+		if b.GetCondition() == nil || b.GetCondition().GetTitle() != h.conditionTitle {
+			continue
+		}
+		i := slices.Index(b.GetMembers(), from)
+		if i < 0 {
+			continue
+		}
+		b.Members[i] = to
+		slices.Sort(b.Members)
+		b.Members = slices.Compact(b.Members)
+	}
+}
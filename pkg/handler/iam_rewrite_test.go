@@ -0,0 +1,159 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sethvargo/go-retry"
+	"google.golang.org/genproto/googleapis/type/expr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestRewriteMember(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	aodBinding := func(expiry time.Time, role string, members ...string) *iampb.Binding {
+		return &iampb.Binding{
+			Members: members,
+			Role:    role,
+			Condition: &expr.Expr{
+				Title:      DefaultConditionTitle,
+				Expression: fmt.Sprintf("request.time < timestamp('%s')", expiry.Format(time.RFC3339)),
+			},
+		}
+	}
+	nonAODBinding := func() *iampb.Binding {
+		return &iampb.Binding{
+			Members: []string{"user:old@example.com"},
+			Role:    "roles/owner",
+		}
+	}
+
+	cases := []struct {
+		name          string
+		foldersServer *fakeServer
+		wantPolicy    *iampb.Policy
+		wantEvents    []*audit.Event
+		wantErrSubstr string
+	}{
+		{
+			name: "rewrite_aod_bindings",
+			foldersServer: &fakeServer{
+				policy: &iampb.Policy{
+					Bindings: []*iampb.Binding{
+						nonAODBinding(),
+						aodBinding(now.Add(time.Hour), "roles/viewer", "user:bob@example.com", "user:old@example.com"),
+						// The new member is deduplicated.
+						aodBinding(now.Add(2*time.Hour), "roles/editor", "user:new@example.com", "user:old@example.com"),
+						aodBinding(now.Add(time.Hour), "roles/browser", "user:bob@example.com"),
+					},
+					Version: 3,
+				},
+			},
+			wantPolicy: &iampb.Policy{
+				Bindings: []*iampb.Binding{
+					nonAODBinding(),
+					aodBinding(now.Add(time.Hour), "roles/viewer", "user:bob@example.com", "user:new@example.com"),
+					aodBinding(now.Add(2*time.Hour), "roles/editor", "user:new@example.com"),
+					aodBinding(now.Add(time.Hour), "roles/browser", "user:bob@example.com"),
+				},
+				Version: 3,
+			},
+			wantEvents: []*audit.Event{
+				{
+					Type:           audit.EventTypeRewrite,
+					Time:           now,
+					Resource:       "folders/bar",
+					Bindings:       []*audit.Binding{{Members: []string{"user:old@example.com", "user:new@example.com"}}},
+					ConditionTitle: DefaultConditionTitle,
+					Outcome:        audit.OutcomeSuccess,
+				},
+			},
+		},
+		{
+			name: "set_policy_failure",
+			foldersServer: &fakeServer{
+				policy:          &iampb.Policy{},
+				setIAMPolicyErr: status.Error(codes.PermissionDenied, "injected error"),
+			},
+			wantPolicy: &iampb.Policy{},
+			wantEvents: []*audit.Event{
+				{
+					Type:           audit.EventTypeRewrite,
+					Time:           now,
+					Resource:       "folders/bar",
+					Bindings:       []*audit.Binding{{Members: []string{"user:old@example.com", "user:new@example.com"}}},
+					ConditionTitle: DefaultConditionTitle,
+					Outcome:        audit.OutcomeFailure,
+					Error: fmt.Sprintf("failed to handle IAM request: failed to set IAM policy: %s",
+						status.Error(codes.PermissionDenied, "injected error")),
+				},
+			},
+			wantErrSubstr: "failed to handle member rewrite for resource folders/bar",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				tc.foldersServer,
+				&fakeServer{policy: &iampb.Policy{}},
+			)
+
+			sink := &fakeAuditSink{}
+			h, err := NewIAMHandler(
+				ctx,
+				fakeOrganizationsClient,
+				fakeFoldersClient,
+				fakeProjectsClient,
+				WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+				WithNowFunc(func() time.Time { return now }),
+				WithAuditSink(sink),
+			)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			_, gotErr := h.RewriteMember(ctx, "user:old@example.com", "user:new@example.com", []string{"folders/bar"})
+			if diff := testutil.DiffErrString(gotErr, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantPolicy, tc.foldersServer.policy, protocmp.Transform()); diff != "" {
+				t.Errorf("Process(%+v) got folder policy diff (-want, +got): %v", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantEvents, sink.events); diff != "" {
+				t.Errorf("Process(%+v) got audit events diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/sethvargo/go-retry"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+	"github.com/abcxyz/access-on-demand/pkg/progress"
+)
+
+// Sweep removes expired IAM bindings added by AOD from the IAM policies of the
+// resources. The IAM policies of resources without expired AOD bindings are not
+// updated, and those resources are not included in the responses. Resources
+// that are not active, such as projects pending deletion, are skipped and
+// included in the responses with the reason.
+func (h *IAMHandler) Sweep(ctx context.Context, resources []string) ([]*v1alpha1.IAMResponse, error) {
+	ps := make([]*v1alpha1.ResourcePolicy, 0, len(resources))
+	for _, r := range resources {
+		ps = append(ps, &v1alpha1.ResourcePolicy{Resource: r})
+	}
+	return h.handlePolicies(ctx, "sweep", ps, func(ctx context.Context, p *v1alpha1.ResourcePolicy) (*v1alpha1.IAMResponse, error) {
+		cp, err := h.currentPolicy(ctx, p.Resource)
+		var inactiveErr *InactiveResourceError
+		if errors.As(err, &inactiveErr) {
+			h.reportProgress(ctx, progress.EventTypeSkipped, p.Resource, 0, inactiveErr)
+			return skippedResponse(inactiveErr), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to handle policy sweep for resource %s: %w", p.Resource, err)
+		}
+		if !h.hasExpiredBindings(cp) {
+			h.reportProgress(ctx, progress.EventTypeSkipped, p.Resource, 0, nil)
+			return nil, nil
+		}
+
+		// h.now() is a dummy parameter used to match the function signature.
+		np, err := h.handlePolicy(ctx, p, h.now(), h.cleanupBindings)
+		h.writeAuditEvent(ctx, audit.EventTypeCleanup, p, nil, err)
+		if errors.As(err, &inactiveErr) {
+			return skippedResponse(inactiveErr), nil
+		}
+		if err != nil {
+			return np, fmt.Errorf("failed to handle policy sweep for resource %s: %w", p.Resource, err)
+		}
+		return np, nil
+	})
+}
+
+// currentPolicy gets the current IAM policy of the resource with retries.
+func (h *IAMHandler) currentPolicy(ctx context.Context, resource string) (*iampb.Policy, error) {
+	iamC, err := h.iamClient(ctx, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	var cp *iampb.Policy
+	inactive := h.inactiveOnce(resource)
+	if err := retry.Do(ctx, h.retry, func(ctx context.Context) error {
+		cp, err = h.getPolicy(ctx, iamC, resource)
+		if err != nil {
+			if errors.Is(err, ErrAPICallBudgetExceeded) {
+				return err
+			}
+			if ierr := inactive(ctx); ierr != nil {
+				return ierr
+			}
+			return retry.RetryableError(err)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to handle IAM request: %w", err)
+	}
+	return cp, nil
+}
+
+// hasExpiredBindings checks if the policy has any expired AOD bindings. AOD
+// bindings with malformed expiry are not considered expired.
+func (h *IAMHandler) hasExpiredBindings(p *iampb.Policy) bool {
+	return h.countExpiredBindings(p) > 0
+}
+
+// countExpiredBindings returns the number of expired AOD bindings in the IAM
+// policy.
+func (h *IAMHandler) countExpiredBindings(p *iampb.Policy) int {
+	var n int
+	for _, b := range p.GetBindings() {
+		if b.GetCondition().GetTitle() != h.conditionTitle {
+			continue
+		}
+		if expired, err := expired(b.GetCondition().GetExpression(), h.now()); err == nil && expired {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,211 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sethvargo/go-retry"
+	"google.golang.org/genproto/googleapis/type/expr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestSweep(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	aodBinding := func(expiry time.Time, role string, members ...string) *iampb.Binding {
+		return &iampb.Binding{
+			Members: members,
+			Role:    role,
+			Condition: &expr.Expr{
+				Title:      DefaultConditionTitle,
+				Expression: fmt.Sprintf("request.time < timestamp('%s')", expiry.Format(time.RFC3339)),
+			},
+		}
+	}
+	nonAODBinding := &iampb.Binding{
+		Members: []string{"user:alice@example.com"},
+		Role:    "roles/owner",
+	}
+
+	cases := []struct {
+		name                string
+		resources           []string
+		organizationsServer *fakeServer
+		foldersServer       *fakeServer
+		projectsServer      *fakeServer
+		expResps            []*v1alpha1.IAMResponse
+		expSetCalls         []int
+		expErr              string
+	}{
+		{
+			name:      "success",
+			resources: []string{"organizations/foo", "folders/bar", "projects/baz"},
+			organizationsServer: &fakeServer{
+				policy: &iampb.Policy{
+					Bindings: []*iampb.Binding{
+						nonAODBinding,
+						aodBinding(now.Add(time.Hour), "roles/viewer", "user:alice@example.com"),
+					},
+					Version: 3,
+				},
+			},
+			foldersServer: &fakeServer{
+				policy: &iampb.Policy{
+					Bindings: []*iampb.Binding{
+						nonAODBinding,
+						aodBinding(now.Add(time.Hour), "roles/viewer", "user:alice@example.com"),
+						aodBinding(now.Add(-time.Hour), "roles/editor", "user:alice@example.com"),
+					},
+					Version: 3,
+				},
+			},
+			projectsServer: &fakeServer{
+				policy: &iampb.Policy{
+					Bindings: []*iampb.Binding{
+						aodBinding(now.Add(-time.Hour), "roles/bigquery.dataViewer", "user:bob@example.com"),
+					},
+					Version: 3,
+				},
+			},
+			expResps: []*v1alpha1.IAMResponse{
+				{
+					Resource: "folders/bar",
+					Policy: &iampb.Policy{
+						Bindings: []*iampb.Binding{
+							nonAODBinding,
+							aodBinding(now.Add(time.Hour), "roles/viewer", "user:alice@example.com"),
+						},
+						Version: 3,
+					},
+				},
+				{
+					Resource: "projects/baz",
+					Policy:   &iampb.Policy{Version: 3},
+				},
+			},
+			expSetCalls: []int{0, 1, 1},
+		},
+		{
+			name:                "nothing_to_sweep",
+			resources:           []string{"organizations/foo", "folders/bar", "projects/baz"},
+			organizationsServer: &fakeServer{policy: &iampb.Policy{}},
+			foldersServer: &fakeServer{
+				policy: &iampb.Policy{
+					Bindings: []*iampb.Binding{nonAODBinding},
+				},
+			},
+			projectsServer: &fakeServer{
+				policy: &iampb.Policy{
+					Bindings: []*iampb.Binding{
+						aodBinding(now.Add(time.Hour), "roles/viewer", "user:alice@example.com"),
+						{
+							Members: []string{"user:bob@example.com"},
+							Role:    "roles/editor",
+							Condition: &expr.Expr{
+								Title:      DefaultConditionTitle,
+								Expression: "malformed",
+							},
+						},
+					},
+					Version: 3,
+				},
+			},
+			expSetCalls: []int{0, 0, 0},
+		},
+		{
+			name:                "get_policy_failure",
+			resources:           []string{"organizations/foo", "projects/baz"},
+			organizationsServer: &fakeServer{policy: &iampb.Policy{}},
+			foldersServer:       &fakeServer{policy: &iampb.Policy{}},
+			projectsServer: &fakeServer{
+				getIAMPolicyErr: status.Error(codes.PermissionDenied, "injected error"),
+			},
+			expSetCalls: []int{0, 0, 0},
+			expErr:      "failed to handle policy sweep for resource projects/baz",
+		},
+		{
+			name:                "set_policy_failure",
+			resources:           []string{"projects/baz"},
+			organizationsServer: &fakeServer{policy: &iampb.Policy{}},
+			foldersServer:       &fakeServer{policy: &iampb.Policy{}},
+			projectsServer: &fakeServer{
+				policy: &iampb.Policy{
+					Bindings: []*iampb.Binding{
+						aodBinding(now.Add(-time.Hour), "roles/bigquery.dataViewer", "user:bob@example.com"),
+					},
+					Version: 3,
+				},
+				setIAMPolicyErr: status.Error(codes.PermissionDenied, "injected error"),
+			},
+			expSetCalls: []int{0, 0, 1},
+			expErr:      "failed to handle policy sweep for resource projects/baz",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t,
+				ctx,
+				tc.organizationsServer,
+				tc.foldersServer,
+				tc.projectsServer,
+			)
+
+			h, err := NewIAMHandler(
+				ctx,
+				fakeOrganizationsClient,
+				fakeFoldersClient,
+				fakeProjectsClient,
+				WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+				WithNowFunc(func() time.Time { return now }),
+			)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			gotResps, err := h.Sweep(ctx, tc.resources)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Sweep(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expResps, gotResps, protocmp.Transform()); diff != "" {
+				t.Errorf("Sweep(%+v) got response diff (-want, +got): %v", tc.name, diff)
+			}
+			gotSetCalls := []int{
+				tc.organizationsServer.setCalls,
+				tc.foldersServer.setCalls,
+				tc.projectsServer.setCalls,
+			}
+			if diff := cmp.Diff(tc.expSetCalls, gotSetCalls); diff != "" {
+				t.Errorf("Sweep(%+v) got SetIamPolicy calls diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
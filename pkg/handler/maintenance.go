@@ -0,0 +1,54 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"fmt"
+)
+
+// MaintenanceError is the error of a grant while the AOD deployment is in
+// maintenance mode, such as during a security incident or an IAM freeze.
+type MaintenanceError struct {
+	// Reason is the reason of the maintenance mode.
+	Reason string
+}
+
+func (e *MaintenanceError) Error() string {
+	return fmt.Sprintf("AOD is in maintenance mode and not granting access: %s; "+
+		"expired grants are still cleaned up", e.Reason)
+}
+
+// WithMaintenance puts the handler in maintenance mode for the reason, where
+// Do, Renew and DoCustomRole fail with a MaintenanceError before modifying any
+// IAM policy, while Cleanup, Sweep and the revocations keep working, so that
+// no new access is granted and the existing grants still expire.
+func WithMaintenance(reason string) Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		if reason == "" {
+			return nil, fmt.Errorf("maintenance reason is required")
+		}
+		p.maintenanceReason = reason
+		return p, nil
+	}
+}
+
+// checkMaintenance returns a MaintenanceError if the handler is in maintenance
+// mode.
+func (h *IAMHandler) checkMaintenance() error {
+	if h.maintenanceReason != "" {
+		return &MaintenanceError{Reason: h.maintenanceReason}
+	}
+	return nil
+}
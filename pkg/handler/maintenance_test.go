@@ -0,0 +1,99 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/sethvargo/go-retry"
+	"google.golang.org/genproto/googleapis/type/expr"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestMaintenance(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	server := &fakeServer{policy: &iampb.Policy{
+		Bindings: []*iampb.Binding{{
+			Role:    "roles/browser",
+			Members: []string{"user:bob@example.com"},
+			Condition: &expr.Expr{
+				Title:      DefaultConditionTitle,
+				Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(-time.Hour).Format(time.RFC3339)),
+			},
+		}},
+	}}
+	o, f, p := setupFakeClients(t, ctx, &fakeServer{policy: &iampb.Policy{}}, &fakeServer{policy: &iampb.Policy{}}, server)
+
+	h, err := NewIAMHandler(ctx, o, f, p,
+		WithRetry(retry.WithMaxRetries(0, retry.NewConstant(time.Millisecond))),
+		WithNowFunc(func() time.Time { return now }),
+		WithMaintenance("incident INC-123"))
+	if err != nil {
+		t.Fatalf("failed to create IAMHandler: %v", err)
+	}
+
+	req := &v1alpha1.IAMRequest{
+		ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+			Resource: "projects/foo",
+			Bindings: []*v1alpha1.Binding{{Members: []string{"user:alice@example.com"}, Role: "roles/browser"}},
+		}},
+	}
+	w := &v1alpha1.IAMRequestWrapper{IAMRequest: req, Duration: time.Hour, StartTime: now}
+	wantErr := "AOD is in maintenance mode and not granting access: incident INC-123"
+
+	_, err = h.Do(ctx, w)
+	if diff := testutil.DiffErrString(err, wantErr); diff != "" {
+		t.Errorf("Do got unexpected error substring: %v", diff)
+	}
+	var maintenanceErr *MaintenanceError
+	if !errors.As(err, &maintenanceErr) {
+		t.Errorf("Do got error %v, want MaintenanceError", err)
+	}
+
+	_, err = h.Renew(ctx, w)
+	if diff := testutil.DiffErrString(err, wantErr); diff != "" {
+		t.Errorf("Renew got unexpected error substring: %v", diff)
+	}
+	if got := server.setCalls; got != 0 {
+		t.Errorf("got %d SetIamPolicy calls before cleanup, want none", got)
+	}
+
+	// The expired grant is still cleaned up.
+	if _, err := h.Cleanup(ctx, req); err != nil {
+		t.Fatalf("Cleanup got unexpected error: %v", err)
+	}
+	if got := len(server.policy.GetBindings()); got != 0 {
+		t.Errorf("Cleanup got %d bindings, want none", got)
+	}
+}
+
+func TestMaintenanceOptions(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewIAMHandler(context.Background(), nil, nil, nil, WithMaintenance(""))
+	if diff := testutil.DiffErrString(err, "maintenance reason is required"); diff != "" {
+		t.Errorf("NewIAMHandler got unexpected error substring: %v", diff)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// Keys of the gRPC metadata attached to the IAM calls, so that the requests in
+// the GCP audit logs can be correlated with AOD runs.
+const (
+	// MetadataKeyCorrelationID is the key of the correlation ID of the AOD run.
+	MetadataKeyCorrelationID = "x-aod-correlation-id"
+
+	// MetadataKeyRequester is the key of the requester of the IAM request.
+	MetadataKeyRequester = "x-aod-requester"
+)
+
+// RequestMetadata is the metadata of an AOD run, attached to the IAM calls and
+// the audit events.
+type RequestMetadata struct {
+	// CorrelationID identifies the AOD run, such as the ID of the workflow run
+	// or of the server request.
+	CorrelationID string
+
+	// Requester of the IAM request, e.g. "user:alice@example.com".
+	Requester string
+}
+
+type requestMetadataKey struct{}
+
+// WithRequestMetadata returns a context with the request metadata, which
+// overrides the default correlation ID of the handler.
+func WithRequestMetadata(ctx context.Context, md *RequestMetadata) context.Context {
+	return context.WithValue(ctx, requestMetadataKey{}, md)
+}
+
+// RequestMetadataFromContext returns the request metadata in the context, or
+// empty metadata if there is none.
+func RequestMetadataFromContext(ctx context.Context) *RequestMetadata {
+	if md, ok := ctx.Value(requestMetadataKey{}).(*RequestMetadata); ok && md != nil {
+		return md
+	}
+	return &RequestMetadata{}
+}
+
+// NewCorrelationID returns a random correlation ID.
+func NewCorrelationID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate correlation ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// withRequester returns a context with the requester added to the request
+// metadata, unless the requester is empty or already set.
+func withRequester(ctx context.Context, requester string) context.Context {
+	md := RequestMetadataFromContext(ctx)
+	if requester == "" || md.Requester != "" {
+		return ctx
+	}
+	return WithRequestMetadata(ctx, &RequestMetadata{
+		CorrelationID: md.CorrelationID,
+		Requester:     requester,
+	})
+}
+
+// requestMetadata returns the request metadata in the context, with the
+// default correlation ID of the handler if the context does not have one.
+func (h *IAMHandler) requestMetadata(ctx context.Context) *RequestMetadata {
+	md := *RequestMetadataFromContext(ctx)
+	if md.CorrelationID == "" {
+		md.CorrelationID = h.correlationID
+	}
+	return &md
+}
+
+// outgoingContext returns a context with the request metadata attached as the
+// gRPC metadata of the IAM calls.
+func (h *IAMHandler) outgoingContext(ctx context.Context) context.Context {
+	md := h.requestMetadata(ctx)
+	var kv []string
+	if md.CorrelationID != "" {
+		kv = append(kv, MetadataKeyCorrelationID, md.CorrelationID)
+	}
+	if md.Requester != "" {
+		kv = append(kv, MetadataKeyRequester, md.Requester)
+	}
+	if len(kv) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
@@ -0,0 +1,207 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	resourcemanager "cloud.google.com/go/resourcemanager/apiv3"
+	"cloud.google.com/go/resourcemanager/apiv3/resourcemanagerpb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sethvargo/go-retry"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestRequestMetadata(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name              string
+		opts              []Option
+		ctxMetadata       *RequestMetadata
+		requester         string
+		wantCorrelationID []string
+		wantRequester     []string
+	}{
+		{
+			name: "no_metadata",
+		},
+		{
+			name:              "default_correlation_id",
+			opts:              []Option{WithCorrelationID("run-1")},
+			wantCorrelationID: []string{"run-1"},
+		},
+		{
+			name:              "context_correlation_id",
+			opts:              []Option{WithCorrelationID("run-1")},
+			ctxMetadata:       &RequestMetadata{CorrelationID: "request-1"},
+			wantCorrelationID: []string{"request-1"},
+		},
+		{
+			name:              "requester",
+			opts:              []Option{WithCorrelationID("run-1")},
+			requester:         "user:alice@example.com",
+			wantCorrelationID: []string{"run-1"},
+			wantRequester:     []string{"user:alice@example.com"},
+		},
+		{
+			name:              "context_requester",
+			ctxMetadata:       &RequestMetadata{CorrelationID: "request-1", Requester: "user:bob@example.com"},
+			requester:         "user:alice@example.com",
+			wantCorrelationID: []string{"request-1"},
+			wantRequester:     []string{"user:bob@example.com"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			if tc.ctxMetadata != nil {
+				ctx = WithRequestMetadata(ctx, tc.ctxMetadata)
+			}
+
+			s := &metadataServer{fakeServer: &fakeServer{policy: &iampb.Policy{}}}
+			_, conn := testutil.FakeGRPCServer(t, func(gs *grpc.Server) {
+				resourcemanagerpb.RegisterProjectsServer(gs, s)
+			})
+			projectsClient, err := resourcemanager.NewProjectsClient(ctx, option.WithGRPCConn(conn))
+			if err != nil {
+				t.Fatal(err)
+			}
+			fakeOrganizationsClient, fakeFoldersClient, _ := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+			)
+
+			sink := &fakeAuditSink{}
+			opts := append([]Option{
+				WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+				WithNowFunc(func() time.Time { return now }),
+				WithAuditSink(sink),
+			}, tc.opts...)
+			h, err := NewIAMHandler(ctx, fakeOrganizationsClient, fakeFoldersClient, projectsClient, opts...)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			if _, err := h.Do(ctx, &v1alpha1.IAMRequestWrapper{
+				IAMRequest: &v1alpha1.IAMRequest{
+					ResourcePolicies: []*v1alpha1.ResourcePolicy{
+						{
+							Resource: "projects/baz",
+							Bindings: []*v1alpha1.Binding{
+								{
+									Members: []string{"user:test-userA@example.com"},
+									Role:    "roles/bigquery.dataViewer",
+								},
+							},
+						},
+					},
+				},
+				Duration:  time.Hour,
+				StartTime: now,
+				Requester: tc.requester,
+			}); err != nil {
+				t.Fatalf("Process(%+v) got unexpected error: %v", tc.name, err)
+			}
+
+			// Both GetIamPolicy and SetIamPolicy have the metadata.
+			if got, want := len(s.got), 2; got != want {
+				t.Fatalf("Process(%+v) got %d calls, want %d", tc.name, got, want)
+			}
+			for _, md := range s.got {
+				if diff := cmp.Diff(tc.wantCorrelationID, md.Get(MetadataKeyCorrelationID)); diff != "" {
+					t.Errorf("Process(%+v) got correlation ID metadata diff (-want, +got):\n%s", tc.name, diff)
+				}
+				if diff := cmp.Diff(tc.wantRequester, md.Get(MetadataKeyRequester)); diff != "" {
+					t.Errorf("Process(%+v) got requester metadata diff (-want, +got):\n%s", tc.name, diff)
+				}
+			}
+
+			var wantEventCorrelationID string
+			if len(tc.wantCorrelationID) > 0 {
+				wantEventCorrelationID = tc.wantCorrelationID[0]
+			}
+			for _, e := range sink.events {
+				if got, want := e.CorrelationID, wantEventCorrelationID; got != want {
+					t.Errorf("Process(%+v) got audit event correlation ID %q, want %q", tc.name, got, want)
+				}
+			}
+			if got, want := len(sink.events), 1; got != want {
+				t.Errorf("Process(%+v) got %d audit events, want %d", tc.name, got, want)
+			}
+		})
+	}
+}
+
+func TestNewCorrelationID(t *testing.T) {
+	t.Parallel()
+
+	id1, err := NewCorrelationID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id2, err := NewCorrelationID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(id1), 32; got != want {
+		t.Errorf("NewCorrelationID() got length %d, want %d", got, want)
+	}
+	if id1 == id2 {
+		t.Errorf("NewCorrelationID() got duplicate IDs %q", id1)
+	}
+}
+
+// metadataServer records the incoming metadata of the IAM calls.
+type metadataServer struct {
+	*fakeServer
+
+	mu  sync.Mutex
+	got []metadata.MD
+}
+
+func (s *metadataServer) GetIamPolicy(ctx context.Context, req *iampb.GetIamPolicyRequest) (*iampb.Policy, error) {
+	s.record(ctx)
+	return s.fakeServer.GetIamPolicy(ctx, req)
+}
+
+func (s *metadataServer) SetIamPolicy(ctx context.Context, req *iampb.SetIamPolicyRequest) (*iampb.Policy, error) {
+	s.record(ctx)
+	return s.fakeServer.SetIamPolicy(ctx, req)
+}
+
+func (s *metadataServer) record(ctx context.Context) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.got = append(s.got, md)
+}
@@ -0,0 +1,53 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/abcxyz/access-on-demand/pkg/resource"
+)
+
+// OrganizationResolver is the interface to find the organizations of GCP
+// folders and projects.
+type OrganizationResolver interface {
+	Organization(ctx context.Context, resource string) (string, error)
+}
+
+// orgClients are the clients to get and set the IAM policies of the resources
+// in an organization.
+type orgClients struct {
+	organizations IAMClient
+	folders       IAMClient
+	projects      IAMClient
+}
+
+// organization returns the organization of the resource, organizations of
+// folders and projects are resolved once and cached.
+func (h *IAMHandler) organization(ctx context.Context, name string) (string, error) {
+	if resource.HasType(name, resource.TypeOrganization) {
+		return name, nil
+	}
+	if org, ok := h.orgCache.Load(name); ok {
+		return org.(string), nil //nolint:forcetypeassert // Only strings are stored.
+	}
+	org, err := h.orgResolver.Organization(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to find organization of %s: %w", name, err)
+	}
+	h.orgCache.Store(name, org)
+	return org, nil
+}
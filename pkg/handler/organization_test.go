@@ -0,0 +1,204 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sethvargo/go-retry"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestOrganizationClients(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name           string
+		resource       string
+		orgs           map[string]string
+		resolverErr    error
+		wantDefaultSet int
+		wantOrgSet     int
+		wantResolved   []string
+		wantErrSubstr  string
+	}{
+		{
+			name:       "organization_with_clients",
+			resource:   "organizations/1",
+			wantOrgSet: 1,
+		},
+		{
+			name:           "organization_without_clients",
+			resource:       "organizations/2",
+			wantDefaultSet: 1,
+		},
+		{
+			name:         "project_in_organization_with_clients",
+			resource:     "projects/foo",
+			orgs:         map[string]string{"projects/foo": "organizations/1"},
+			wantOrgSet:   1,
+			wantResolved: []string{"projects/foo"},
+		},
+		{
+			name:           "folder_in_organization_without_clients",
+			resource:       "folders/bar",
+			orgs:           map[string]string{"folders/bar": "organizations/2"},
+			wantDefaultSet: 1,
+			wantResolved:   []string{"folders/bar"},
+		},
+		{
+			name:          "resolver_failure",
+			resource:      "projects/foo",
+			resolverErr:   fmt.Errorf("injected error"),
+			wantResolved:  []string{"projects/foo"},
+			wantErrSubstr: "failed to find organization of projects/foo: injected error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			defaultServers := []*fakeServer{{policy: &iampb.Policy{}}, {policy: &iampb.Policy{}}, {policy: &iampb.Policy{}}}
+			orgServers := []*fakeServer{{policy: &iampb.Policy{}}, {policy: &iampb.Policy{}}, {policy: &iampb.Policy{}}}
+			o, f, p := setupFakeClients(t, ctx, defaultServers[0], defaultServers[1], defaultServers[2])
+			orgO, orgF, orgP := setupFakeClients(t, ctx, orgServers[0], orgServers[1], orgServers[2])
+
+			resolver := &fakeOrganizationResolver{orgs: tc.orgs, injectErr: tc.resolverErr}
+			h, err := NewIAMHandler(ctx, o, f, p,
+				WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+				WithOrganizationClients("organizations/1", orgO, orgF, orgP),
+				WithOrganizationResolver(resolver),
+			)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			req := &v1alpha1.IAMRequestWrapper{
+				IAMRequest: &v1alpha1.IAMRequest{
+					ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+						Resource: tc.resource,
+						Bindings: []*v1alpha1.Binding{{Members: []string{"user:alice@example.com"}, Role: "roles/viewer"}},
+					}},
+				},
+				Duration:  time.Hour,
+				StartTime: time.Now(),
+			}
+			// Handle the request twice to check the resolved organization is
+			// cached.
+			for range 2 {
+				_, gotErr := h.Do(ctx, req)
+				if diff := testutil.DiffErrString(gotErr, tc.wantErrSubstr); diff != "" {
+					t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+				}
+			}
+
+			var gotDefaultSet, gotOrgSet int
+			for _, s := range defaultServers {
+				gotDefaultSet += s.setCalls
+			}
+			for _, s := range orgServers {
+				gotOrgSet += s.setCalls
+			}
+			if got, want := gotDefaultSet, 2*tc.wantDefaultSet; got != want {
+				t.Errorf("Process(%+v) got %d default client SetIamPolicy calls, want %d", tc.name, got, want)
+			}
+			if got, want := gotOrgSet, 2*tc.wantOrgSet; got != want {
+				t.Errorf("Process(%+v) got %d organization client SetIamPolicy calls, want %d", tc.name, got, want)
+			}
+			wantResolved := tc.wantResolved
+			if tc.resolverErr != nil {
+				// Failures are not cached.
+				wantResolved = append(wantResolved, tc.wantResolved...)
+			}
+			if diff := cmp.Diff(wantResolved, resolver.gotResources); diff != "" {
+				t.Errorf("Process(%+v) got resolved resources diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestWithOrganizationClients(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		opts          []Option
+		wantErrSubstr string
+	}{
+		{
+			name: "success",
+			opts: []Option{
+				WithOrganizationClients("organizations/1", nil, nil, nil),
+				WithOrganizationClients("organizations/2", nil, nil, nil),
+				WithOrganizationResolver(&fakeOrganizationResolver{}),
+			},
+		},
+		{
+			name: "invalid_organization",
+			opts: []Option{
+				WithOrganizationClients("1", nil, nil, nil),
+				WithOrganizationResolver(&fakeOrganizationResolver{}),
+			},
+			wantErrSubstr: `invalid organization "1", must be organizations/<id>`,
+		},
+		{
+			name: "duplicate_organization",
+			opts: []Option{
+				WithOrganizationClients("organizations/1", nil, nil, nil),
+				WithOrganizationClients("organizations/1", nil, nil, nil),
+				WithOrganizationResolver(&fakeOrganizationResolver{}),
+			},
+			wantErrSubstr: `duplicate clients for organization "organizations/1"`,
+		},
+		{
+			name: "missing_resolver",
+			opts: []Option{
+				WithOrganizationClients("organizations/1", nil, nil, nil),
+			},
+			wantErrSubstr: "organization resolver is required with organization clients",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewIAMHandler(context.Background(), nil, nil, nil, tc.opts...)
+			if diff := testutil.DiffErrString(err, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+type fakeOrganizationResolver struct {
+	orgs         map[string]string
+	injectErr    error
+	gotResources []string
+}
+
+func (r *fakeOrganizationResolver) Organization(ctx context.Context, resource string) (string, error) {
+	r.gotResources = append(r.gotResources, resource)
+	return r.orgs[resource], r.injectErr
+}
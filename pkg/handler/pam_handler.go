@@ -0,0 +1,224 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+	"github.com/abcxyz/access-on-demand/pkg/pam"
+	"github.com/abcxyz/access-on-demand/pkg/resource"
+)
+
+// PAMManager is the interface to list the entitlements of Privileged Access
+// Manager (PAM) and to request grants of them.
+type PAMManager interface {
+	ListEntitlements(ctx context.Context, resource string) ([]*pam.Entitlement, error)
+	CreateGrant(ctx context.Context, entitlement string, duration time.Duration, justification string) (*pam.Grant, error)
+}
+
+// PAMHandler handles IAM requests by requesting PAM grants of the existing
+// entitlements granting the requested roles, instead of editing the IAM
+// policies. PAM grants the roles to the caller, and revokes them once the
+// duration passes. It shares the maintenance mode, API call budget, allowed
+// and protected resources, audit sinks and correlation ID of the IAMHandler it
+// is created with.
+type PAMHandler struct {
+	iam     *IAMHandler
+	manager PAMManager
+}
+
+// NewPAMHandler creates a new PAMHandler with the IAMHandler and the manager
+// of PAM grants.
+func NewPAMHandler(h *IAMHandler, m PAMManager) (*PAMHandler, error) {
+	if h == nil {
+		return nil, fmt.Errorf("IAM handler is required")
+	}
+	if m == nil {
+		return nil, fmt.Errorf("PAM manager is required")
+	}
+	return &PAMHandler{iam: h, manager: m}, nil
+}
+
+// Do requests a PAM grant for the duration of the request of each entitlement
+// granting the roles of the bindings on the resources. A binding is granted
+// by the first entitlement of its resource with all its roles, and all its
+// members as eligible principals. Errors of all the resources are returned.
+func (h *PAMHandler) Do(ctx context.Context, r *v1alpha1.IAMRequestWrapper) ([]*v1alpha1.IAMResponse, error) {
+	ctx = withRequester(ctx, r.Requester)
+
+	if err := h.iam.checkMaintenance(); err != nil {
+		return nil, err
+	}
+	if err := checkPAMRequest(r, h.iam.now()); err != nil {
+		return nil, err
+	}
+	if err := h.iam.checkProtected(r.ResourcePolicies); err != nil {
+		return nil, err
+	}
+
+	justification := pamJustification(h.iam.requestMetadata(ctx).Requester, r)
+	resps := make([]*v1alpha1.IAMResponse, 0, len(r.ResourcePolicies))
+	var retErr error
+	for _, p := range r.ResourcePolicies {
+		resp, err := h.doPolicy(ctx, p, r.Duration, justification)
+		h.writePAMEvent(ctx, p, r, err)
+		if err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("failed to request PAM grants for resource %s: %w", p.Resource, err))
+		}
+		if resp != nil {
+			resps = append(resps, resp)
+		}
+	}
+	return resps, retErr
+}
+
+// doPolicy requests the PAM grants of the bindings of the resource policy.
+func (h *PAMHandler) doPolicy(ctx context.Context, p *v1alpha1.ResourcePolicy, duration time.Duration, justification string) (*v1alpha1.IAMResponse, error) {
+	if err := h.iam.checkAllowed(p.Resource); err != nil {
+		return nil, err
+	}
+	if err := h.iam.spendAPICall(); err != nil {
+		return nil, err
+	}
+	ents, err := h.manager.ListEntitlements(ctx, p.Resource)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Already wrapped by the manager.
+	}
+
+	// The entitlements to request grants of, in the order of the bindings.
+	var names []string
+	var retErr error
+	for _, b := range p.Bindings {
+		e, err := matchEntitlement(ents, b, duration)
+		if err != nil {
+			retErr = errors.Join(retErr, err)
+			continue
+		}
+		if !slices.Contains(names, e.Name) {
+			names = append(names, e.Name)
+		}
+	}
+	if retErr != nil {
+		return nil, retErr
+	}
+
+	resp := &v1alpha1.IAMResponse{Resource: p.Resource}
+	for _, name := range names {
+		err := h.iam.spendAPICall()
+		var g *pam.Grant
+		if err == nil {
+			g, err = h.manager.CreateGrant(ctx, name, duration, justification)
+		}
+		if err != nil {
+			retErr = errors.Join(retErr, err)
+			continue
+		}
+		resp.Grants = append(resp.Grants, g.Name)
+	}
+	return resp, retErr
+}
+
+// WaitForPropagation is not supported by PAMHandler, PAM activates the grants
+// asynchronously, and may wait for approvals of the entitlements.
+func (h *PAMHandler) WaitForPropagation(_ context.Context, _ *v1alpha1.IAMRequest, _ time.Time, _ time.Duration) error {
+	return fmt.Errorf("waiting for propagation is not supported for PAM grants")
+}
+
+// writePAMEvent writes the GRANT audit event of the PAM grants of the
+// resource policy. The bindings are managed by PAM, so the event has no
+// condition title.
+func (h *PAMHandler) writePAMEvent(ctx context.Context, p *v1alpha1.ResourcePolicy, w *v1alpha1.IAMRequestWrapper, err error) {
+	if len(h.iam.auditSinks) == 0 {
+		return
+	}
+	e := h.iam.newAuditEvent(ctx, audit.EventTypeGrant, p, w, err)
+	e.ConditionTitle = ""
+	h.iam.writeAuditSinks(ctx, e)
+}
+
+// checkPAMRequest returns an error if the request has anything PAM grants
+// cannot express: resources other than organizations, folders and projects,
+// deny policies, recurring windows, or delayed starts.
+func checkPAMRequest(r *v1alpha1.IAMRequestWrapper, now time.Time) (retErr error) {
+	if len(r.DenyPolicies) > 0 {
+		retErr = errors.Join(retErr, fmt.Errorf("deny policies are not supported for PAM grants"))
+	}
+	if r.Recurring != nil {
+		retErr = errors.Join(retErr, fmt.Errorf("recurring windows are not supported for PAM grants"))
+	}
+	// PAM grants start once they are created.
+	if r.StartTime.After(now.Add(time.Minute)) {
+		retErr = errors.Join(retErr, fmt.Errorf("start time %s is in the future, PAM grants start once they are requested",
+			r.StartTime.UTC().Format(time.RFC3339)))
+	}
+	for _, p := range r.ResourcePolicies {
+		if !resource.HasType(p.Resource, resource.TypeOrganization, resource.TypeFolder, resource.TypeProject) {
+			retErr = errors.Join(retErr, fmt.Errorf("resource %s is not an organization, folder or project, which PAM grants are limited to", p.Resource))
+		}
+		for _, b := range p.Bindings {
+			if b.StartOffset > 0 {
+				retErr = errors.Join(retErr, fmt.Errorf("start offset of binding on %s is not supported for PAM grants", p.Resource))
+			}
+		}
+	}
+	return retErr
+}
+
+// matchEntitlement returns the first entitlement with all the roles of the
+// binding, and all its members as eligible principals.
+func matchEntitlement(ents []*pam.Entitlement, b *v1alpha1.Binding, duration time.Duration) (*pam.Entitlement, error) {
+	roles := b.Roles()
+	for _, e := range ents {
+		if !containsAll(e.Roles, roles) || !containsAll(e.Principals, b.Members) {
+			continue
+		}
+		if e.MaxRequestDuration > 0 && duration > e.MaxRequestDuration {
+			return nil, fmt.Errorf("duration %s exceeds the max request duration %s of entitlement %s",
+				duration, e.MaxRequestDuration, e.Name)
+		}
+		return e, nil
+	}
+	return nil, fmt.Errorf("no PAM entitlement grants roles %q to eligible members %q", roles, b.Members)
+}
+
+// containsAll returns whether s contains all the values.
+func containsAll(s, values []string) bool {
+	for _, v := range values {
+		if !slices.Contains(s, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// pamJustification returns the justification of the PAM grants of the
+// request, recording the requester, approvers and source of the request.
+func pamJustification(requester string, r *v1alpha1.IAMRequestWrapper) string {
+	parts := []string{"requested with AOD"}
+	if d := conditionDescription(requester, r.Approvers); d != "" {
+		parts = append(parts, d)
+	}
+	if r.Source != "" {
+		parts = append(parts, "source "+r.Source)
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,277 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+	"github.com/abcxyz/access-on-demand/pkg/pam"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+// fakePAMManager is a fake manager of the PAM entitlements by resources.
+type fakePAMManager struct {
+	entitlements map[string][]*pam.Entitlement
+	grantErr     error
+
+	gotGrants        []string
+	gotJustification string
+}
+
+func (m *fakePAMManager) ListEntitlements(_ context.Context, resource string) ([]*pam.Entitlement, error) {
+	ents, ok := m.entitlements[resource]
+	if !ok {
+		return nil, fmt.Errorf("permission denied")
+	}
+	return ents, nil
+}
+
+func (m *fakePAMManager) CreateGrant(_ context.Context, entitlement string, _ time.Duration, justification string) (*pam.Grant, error) {
+	if m.grantErr != nil {
+		return nil, m.grantErr
+	}
+	m.gotGrants = append(m.gotGrants, entitlement)
+	m.gotJustification = justification
+	return &pam.Grant{Name: fmt.Sprintf("%s/grants/%d", entitlement, len(m.gotGrants))}, nil
+}
+
+func TestPAMHandler(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	entitlements := map[string][]*pam.Entitlement{
+		"projects/foo": {
+			{
+				Name:               "projects/foo/locations/global/entitlements/admin",
+				Principals:         []string{"user:alice@example.com"},
+				Roles:              []string{"roles/owner"},
+				MaxRequestDuration: time.Hour,
+			},
+			{
+				Name:               "projects/foo/locations/global/entitlements/viewer",
+				Principals:         []string{"user:alice@example.com", "user:bob@example.com"},
+				Roles:              []string{"roles/viewer", "roles/logging.viewer"},
+				MaxRequestDuration: 4 * time.Hour,
+			},
+		},
+		"folders/bar": {
+			{
+				Name:       "folders/bar/locations/global/entitlements/viewer",
+				Principals: []string{"user:alice@example.com"},
+				Roles:      []string{"roles/viewer"},
+			},
+		},
+	}
+
+	cases := []struct {
+		name              string
+		request           *v1alpha1.IAMRequestWrapper
+		grantErr          error
+		maintenance       string
+		wantResps         []*v1alpha1.IAMResponse
+		wantGrants        []string
+		wantJustification string
+		wantEvents        int
+		wantErrSubstr     string
+	}{
+		{
+			name: "success",
+			request: &v1alpha1.IAMRequestWrapper{
+				IAMRequest: &v1alpha1.IAMRequest{ResourcePolicies: []*v1alpha1.ResourcePolicy{
+					{
+						Resource: "projects/foo",
+						Bindings: []*v1alpha1.Binding{
+							{Role: "roles/viewer", Members: []string{"user:alice@example.com", "user:bob@example.com"}},
+							{Role: "roles/logging.viewer", Members: []string{"user:alice@example.com"}},
+						},
+					},
+					{
+						Resource: "folders/bar",
+						Bindings: []*v1alpha1.Binding{{Role: "roles/viewer", Members: []string{"user:alice@example.com"}}},
+					},
+				}},
+				StartTime: now,
+				Duration:  2 * time.Hour,
+				Requester: "alice@example.com",
+				Approvers: []string{"bob@example.com"},
+				Source:    "https://github.com/foo/bar/pull/1",
+			},
+			wantResps: []*v1alpha1.IAMResponse{
+				{Resource: "projects/foo", Grants: []string{"projects/foo/locations/global/entitlements/viewer/grants/1"}},
+				{Resource: "folders/bar", Grants: []string{"folders/bar/locations/global/entitlements/viewer/grants/2"}},
+			},
+			wantGrants: []string{
+				"projects/foo/locations/global/entitlements/viewer",
+				"folders/bar/locations/global/entitlements/viewer",
+			},
+			wantJustification: "requested with AOD; requested by alice@example.com; approved by bob@example.com; source https://github.com/foo/bar/pull/1",
+			wantEvents:        2,
+		},
+		{
+			name: "no_matching_entitlement",
+			request: &v1alpha1.IAMRequestWrapper{
+				IAMRequest: &v1alpha1.IAMRequest{ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+					Resource: "projects/foo",
+					Bindings: []*v1alpha1.Binding{{Role: "roles/owner", Members: []string{"user:bob@example.com"}}},
+				}}},
+				StartTime: now,
+				Duration:  time.Hour,
+			},
+			wantEvents:    1,
+			wantErrSubstr: `no PAM entitlement grants roles ["roles/owner"] to eligible members ["user:bob@example.com"]`,
+		},
+		{
+			name: "exceeds_max_duration",
+			request: &v1alpha1.IAMRequestWrapper{
+				IAMRequest: &v1alpha1.IAMRequest{ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+					Resource: "projects/foo",
+					Bindings: []*v1alpha1.Binding{{Role: "roles/owner", Members: []string{"user:alice@example.com"}}},
+				}}},
+				StartTime: now,
+				Duration:  2 * time.Hour,
+			},
+			wantEvents:    1,
+			wantErrSubstr: "duration 2h0m0s exceeds the max request duration 1h0m0s of entitlement projects/foo/locations/global/entitlements/admin",
+		},
+		{
+			name: "grant_failure",
+			request: &v1alpha1.IAMRequestWrapper{
+				IAMRequest: &v1alpha1.IAMRequest{ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+					Resource: "folders/bar",
+					Bindings: []*v1alpha1.Binding{{Role: "roles/viewer", Members: []string{"user:alice@example.com"}}},
+				}}},
+				StartTime: now,
+				Duration:  time.Hour,
+			},
+			grantErr:      fmt.Errorf("injected error"),
+			wantResps:     []*v1alpha1.IAMResponse{{Resource: "folders/bar"}},
+			wantEvents:    1,
+			wantErrSubstr: "failed to request PAM grants for resource folders/bar: injected error",
+		},
+		{
+			name: "list_failure",
+			request: &v1alpha1.IAMRequestWrapper{
+				IAMRequest: &v1alpha1.IAMRequest{ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+					Resource: "projects/qux",
+					Bindings: []*v1alpha1.Binding{{Role: "roles/viewer", Members: []string{"user:alice@example.com"}}},
+				}}},
+				StartTime: now,
+				Duration:  time.Hour,
+			},
+			wantEvents:    1,
+			wantErrSubstr: "failed to request PAM grants for resource projects/qux: permission denied",
+		},
+		{
+			name: "unsupported_request",
+			request: &v1alpha1.IAMRequestWrapper{
+				IAMRequest: &v1alpha1.IAMRequest{
+					ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+						Resource: "buckets/my-bucket",
+						Bindings: []*v1alpha1.Binding{{Role: "roles/viewer", Members: []string{"user:alice@example.com"}, StartOffset: time.Minute}},
+					}},
+					DenyPolicies: []*v1alpha1.DenyPolicy{{Resource: "projects/foo"}},
+				},
+				StartTime: now.Add(time.Hour),
+				Duration:  time.Hour,
+			},
+			wantErrSubstr: "deny policies are not supported for PAM grants\n" +
+				"start time 2009-11-11T00:00:00Z is in the future, PAM grants start once they are requested\n" +
+				"resource buckets/my-bucket is not an organization, folder or project, which PAM grants are limited to\n" +
+				"start offset of binding on buckets/my-bucket is not supported for PAM grants",
+		},
+		{
+			name: "maintenance",
+			request: &v1alpha1.IAMRequestWrapper{
+				IAMRequest: &v1alpha1.IAMRequest{},
+				StartTime:  now,
+				Duration:   time.Hour,
+			},
+			maintenance:   "IAM freeze",
+			wantErrSubstr: "AOD is in maintenance mode",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+			)
+
+			sink := &fakeAuditSink{}
+			opts := []Option{WithNowFunc(func() time.Time { return now }), WithAuditSink(sink)}
+			if tc.maintenance != "" {
+				opts = append(opts, WithMaintenance(tc.maintenance))
+			}
+			h, err := NewIAMHandler(ctx, fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient, opts...)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+			m := &fakePAMManager{entitlements: entitlements, grantErr: tc.grantErr}
+			ph, err := NewPAMHandler(h, m)
+			if err != nil {
+				t.Fatalf("failed to create PAMHandler: %v", err)
+			}
+
+			resps, gotErr := ph.Do(ctx, tc.request)
+			if diff := testutil.DiffErrString(gotErr, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantResps, resps, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Process(%+v) got responses diff (-want, +got): %v", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantGrants, m.gotGrants); diff != "" {
+				t.Errorf("Process(%+v) got grants diff (-want, +got): %v", tc.name, diff)
+			}
+			if got, want := m.gotJustification, tc.wantJustification; got != want {
+				t.Errorf("Process(%+v) got justification %q, want %q", tc.name, got, want)
+			}
+			if got, want := len(sink.events), tc.wantEvents; got != want {
+				t.Errorf("Process(%+v) got %d audit events, want %d", tc.name, got, want)
+			}
+			for _, e := range sink.events {
+				if e.Type != audit.EventTypeGrant || e.ConditionTitle != "" {
+					t.Errorf("Process(%+v) got audit event %+v, want a GRANT event without condition title", tc.name, e)
+				}
+			}
+		})
+	}
+}
+
+func TestNewPAMHandler(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewPAMHandler(&IAMHandler{}, nil); err == nil {
+		t.Errorf("NewPAMHandler got no error without a PAM manager")
+	}
+	if _, err := NewPAMHandler(nil, &fakePAMManager{}); err == nil {
+		t.Errorf("NewPAMHandler got no error without an IAM handler")
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"fmt"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// MaxPolicyMembers is the max number of members in the bindings of an IAM
+	// policy, a member is counted once for every binding it is in.
+	MaxPolicyMembers = 1500
+
+	// MaxPolicyBytes is the max size of an IAM policy in bytes.
+	MaxPolicyBytes = 64 * 1024
+)
+
+// PolicyTooLargeError is the error of an IAM policy that would be over the
+// limits of IAM policies once updated. It is returned before the policy is set,
+// instead of the IAM API rejecting the update.
+type PolicyTooLargeError struct {
+	// Resource of the IAM policy.
+	Resource string
+
+	// Members is the number of members in the bindings of the updated policy.
+	Members int
+
+	// Bytes is the size of the updated policy.
+	Bytes int
+}
+
+func (e *PolicyTooLargeError) Error() string {
+	var over string
+	if e.Members > MaxPolicyMembers {
+		over = fmt.Sprintf("%d members in its bindings, over the limit of %d", e.Members, MaxPolicyMembers)
+	} else {
+		over = fmt.Sprintf("%d bytes, over the limit of %d", e.Bytes, MaxPolicyBytes)
+	}
+	return fmt.Sprintf("IAM policy of %s would have %s; "+
+		`remove expired AOD bindings with "aod iam sweep" or remove unused bindings from the policy`, e.Resource, over)
+}
+
+// checkPolicySize returns a PolicyTooLargeError if the IAM policy of the
+// resource is over the limits of IAM policies.
+func checkPolicySize(resource string, p *iampb.Policy) error {
+	var members int
+	for _, b := range p.GetBindings() {
+		members += len(b.GetMembers())
+	}
+	bytes := proto.Size(p)
+	if members > MaxPolicyMembers || bytes > MaxPolicyBytes {
+		return &PolicyTooLargeError{Resource: resource, Members: members, Bytes: bytes}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sethvargo/go-retry"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestCheckPolicySize(t *testing.T) {
+	t.Parallel()
+
+	members := func(n int) []string {
+		result := make([]string, 0, n)
+		for i := range n {
+			result = append(result, fmt.Sprintf("user:user-%d@example.com", i))
+		}
+		return result
+	}
+
+	cases := []struct {
+		name    string
+		policy  *iampb.Policy
+		wantErr string
+	}{
+		{
+			name:   "empty",
+			policy: &iampb.Policy{},
+		},
+		{
+			name: "at_members_limit",
+			policy: &iampb.Policy{Bindings: []*iampb.Binding{
+				{Role: "roles/viewer", Members: members(MaxPolicyMembers - 1)},
+				{Role: "roles/editor", Members: members(1)},
+			}},
+		},
+		{
+			name: "over_members_limit",
+			policy: &iampb.Policy{Bindings: []*iampb.Binding{
+				{Role: "roles/viewer", Members: members(MaxPolicyMembers)},
+				{Role: "roles/editor", Members: members(1)},
+			}},
+			wantErr: `IAM policy of projects/foo would have 1501 members in its bindings, over the limit of 1500; remove expired AOD bindings with "aod iam sweep"`,
+		},
+		{
+			name: "over_bytes_limit",
+			policy: &iampb.Policy{Bindings: []*iampb.Binding{
+				{Role: "roles/viewer", Members: []string{"user:" + strings.Repeat("a", MaxPolicyBytes) + "@example.com"}},
+			}},
+			wantErr: "over the limit of 65536",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkPolicySize("projects/foo", tc.policy)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestDo_PolicyTooLarge(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+
+	members := make([]string, 0, MaxPolicyMembers)
+	for i := range MaxPolicyMembers {
+		members = append(members, fmt.Sprintf("user:user-%d@example.com", i))
+	}
+	policy := &iampb.Policy{Bindings: []*iampb.Binding{{Role: "roles/viewer", Members: members}}}
+	projectServer := &fakeServer{policy: policy}
+	fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+		t,
+		ctx,
+		&fakeServer{policy: &iampb.Policy{}},
+		&fakeServer{policy: &iampb.Policy{}},
+		projectServer,
+	)
+
+	h, err := NewIAMHandler(
+		ctx,
+		fakeOrganizationsClient,
+		fakeFoldersClient,
+		fakeProjectsClient,
+		WithRetry(retry.WithMaxRetries(3, retry.NewConstant(time.Millisecond))),
+		WithNowFunc(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatalf("failed to create IAMHandler: %v", err)
+	}
+
+	_, gotErr := h.Do(ctx, &v1alpha1.IAMRequestWrapper{
+		IAMRequest: &v1alpha1.IAMRequest{
+			ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+				Resource: "projects/baz",
+				Bindings: []*v1alpha1.Binding{{Members: []string{"user:alice@example.com"}, Role: "roles/editor"}},
+			}},
+		},
+		StartTime: now,
+		Duration:  time.Hour,
+	})
+	var sizeErr *PolicyTooLargeError
+	if !errors.As(gotErr, &sizeErr) {
+		t.Fatalf("Do() got error %v, want PolicyTooLargeError", gotErr)
+	}
+	if got, want := sizeErr.Members, MaxPolicyMembers+1; got != want {
+		t.Errorf("Do() got %d members, want %d", got, want)
+	}
+	if diff := cmp.Diff(policy, projectServer.policy, protocmp.Transform()); diff != "" {
+		t.Errorf("Do() got project policy diff (-want, +got): %v", diff)
+	}
+	if got, want := h.APICalls(), int64(1); got != want {
+		t.Errorf("Do() got %d API calls, want %d without retries", got, want)
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/googleapis/gax-go/v2"
+	"github.com/sethvargo/go-retry"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/resource"
+	"github.com/abcxyz/pkg/workerpool"
+)
+
+// PreflightError is the error of a request whose resources failed the
+// pre-flight checks, before any IAM policy was modified.
+type PreflightError struct {
+	// Err is the error of the failed resources.
+	Err error
+
+	// Resources are the resources which failed the checks.
+	Resources []string
+}
+
+// Error implements error.
+func (e *PreflightError) Error() string {
+	return fmt.Sprintf("pre-flight checks failed for resources %q, no IAM policies were modified: %v", e.Resources, e.Err)
+}
+
+// Unwrap returns the error of the failed resources.
+func (e *PreflightError) Unwrap() error {
+	return e.Err
+}
+
+// ResourceReadiness is the result of the pre-flight checks of a resource.
+type ResourceReadiness struct {
+	// Resource is the organization, folder or project checked.
+	Resource string `yaml:"resource"`
+
+	// Ready is whether the resource passed the checks.
+	Ready bool `yaml:"ready"`
+
+	// Error is the error of the failed checks.
+	Error string `yaml:"error,omitempty"`
+}
+
+// permissionTester is implemented by the IAMClients which can test the
+// permissions of the caller on resources, such as the Resource Manager clients.
+type permissionTester interface {
+	TestIamPermissions(context.Context, *iampb.TestIamPermissionsRequest, ...gax.CallOption) (*iampb.TestIamPermissionsResponse, error)
+}
+
+// WithPreflight makes Do and Renew check all the resources of the request
+// before modifying any IAM policy, and fail with a PreflightError if any
+// resource is inaccessible. The IAM policy of each resource is read, and the
+// permission to set it is tested if the IAMClient supports testing
+// permissions. This avoids applying a request partially when some resources
+// are denied.
+func WithPreflight() Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		p.preflight = true
+		return p, nil
+	}
+}
+
+// asPermissionTester returns the IAMClient, or the IAMClient it traces, as a
+// permissionTester if it implements one.
+func asPermissionTester(c IAMClient) (permissionTester, bool) {
+	if tc, ok := c.(*tracedIAMClient); ok {
+		c = tc.IAMClient
+	}
+	t, ok := c.(permissionTester)
+	return t, ok
+}
+
+// setPolicyPermission returns the permission to set the IAM policy of the
+// resource, such as "resourcemanager.projects.setIamPolicy".
+func setPolicyPermission(r string) string {
+	return fmt.Sprintf("resourcemanager.%s.setIamPolicy", resource.TypeOf(r))
+}
+
+// preflightCheck checks the resources of the policies concurrently, and
+// returns a PreflightError of the resources which failed the checks.
+func (h *IAMHandler) preflightCheck(ctx context.Context, ps []*v1alpha1.ResourcePolicy) error {
+	resources := make([]string, 0, len(ps))
+	for _, p := range ps {
+		resources = append(resources, p.Resource)
+	}
+	slices.Sort(resources)
+	resources = slices.Compact(resources)
+
+	errs, err := h.checkResources(ctx, resources)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	var retErr error
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, resources[i])
+			retErr = errors.Join(retErr, err)
+		}
+	}
+	if len(failed) > 0 {
+		return &PreflightError{Err: retErr, Resources: failed}
+	}
+	return nil
+}
+
+// CheckResources runs the pre-flight checks of WithPreflight on the resources
+// concurrently, without modifying any IAM policy, and returns the readiness of
+// each resource in the order of the resources. The resources must exist, be
+// allowed, and the caller must be able to read their IAM policies and, if
+// supported by the IAMClients, to set them.
+func (h *IAMHandler) CheckResources(ctx context.Context, resources []string) ([]*ResourceReadiness, error) {
+	errs, err := h.checkResources(ctx, resources)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*ResourceReadiness, 0, len(resources))
+	for i, r := range resources {
+		rr := &ResourceReadiness{Resource: r, Ready: errs[i] == nil}
+		if errs[i] != nil {
+			rr.Error = errs[i].Error()
+		}
+		result = append(result, rr)
+	}
+	return result, nil
+}
+
+// checkResources checks the resources concurrently, and returns the errors of
+// the checks in the order of the resources.
+func (h *IAMHandler) checkResources(ctx context.Context, resources []string) ([]error, error) {
+	pool := workerpool.New[struct{}](&workerpool.Config{
+		Concurrency: h.concurrency,
+	})
+	for _, r := range resources {
+		if err := pool.Do(ctx, func() (struct{}, error) {
+			return struct{}{}, h.checkResource(ctx, r)
+		}); err != nil {
+			// The error is also reported in the results.
+			break
+		}
+	}
+	results, err := pool.Done(ctx)
+	if results == nil {
+		return nil, fmt.Errorf("failed to wait for pre-flight checks: %w", err)
+	}
+
+	errs := make([]error, len(resources))
+	for i := range resources {
+		if i >= len(results) {
+			// The resource was not checked since the checks were stopped.
+			errs[i] = fmt.Errorf("resource %s is not checked: %w", resources[i], err)
+			continue
+		}
+		errs[i] = results[i].Error
+	}
+	return errs, nil
+}
+
+// checkResource checks the resource is allowed, its IAM policy can be read and,
+// if supported by its IAMClient, the caller has the permission to set it.
+func (h *IAMHandler) checkResource(ctx context.Context, r string) error {
+	if err := h.checkAllowed(r); err != nil {
+		return err
+	}
+
+	iamC, err := h.iamClient(ctx, r)
+	if err != nil {
+		return fmt.Errorf("resource %s is not accessible: %w", r, err)
+	}
+
+	if err := retry.Do(ctx, h.retry, func(ctx context.Context) error {
+		if _, err := h.getPolicy(ctx, iamC, r); err != nil {
+			if errors.Is(err, ErrAPICallBudgetExceeded) || isNonRetryable(err) {
+				return err
+			}
+			return retry.RetryableError(err)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("resource %s is not accessible: %w", r, err)
+	}
+
+	tester, ok := asPermissionTester(iamC)
+	if !ok {
+		return nil
+	}
+	perm := setPolicyPermission(r)
+	if err := h.spendAPICall(); err != nil {
+		return fmt.Errorf("resource %s is not accessible: %w", r, err)
+	}
+	resp, err := tester.TestIamPermissions(h.outgoingContext(ctx), &iampb.TestIamPermissionsRequest{
+		Resource:    r,
+		Permissions: []string{perm},
+	})
+	if err != nil {
+		return fmt.Errorf("resource %s is not accessible: failed to test IAM permissions: %w", r, err)
+	}
+	if !slices.Contains(resp.GetPermissions(), perm) {
+		return fmt.Errorf("resource %s is not accessible: missing permission %q", r, perm)
+	}
+	return nil
+}
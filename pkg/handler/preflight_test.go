@@ -0,0 +1,170 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sethvargo/go-retry"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestDoPreflight(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	policy := func(resource string) *v1alpha1.ResourcePolicy {
+		return &v1alpha1.ResourcePolicy{
+			Resource: resource,
+			Bindings: []*v1alpha1.Binding{{
+				Members: []string{"user:alice@example.com"},
+				Role:    "roles/viewer",
+			}},
+		}
+	}
+
+	cases := []struct {
+		name          string
+		policies      []*v1alpha1.ResourcePolicy
+		orgServer     *fakeServer
+		foldersServer *fakeServer
+		projectServer *fakeServer
+		opts          []Option
+		wantSetCalls  int
+		wantFailed    []string
+		wantErrSubstr string
+	}{
+		{
+			name:          "success",
+			policies:      []*v1alpha1.ResourcePolicy{policy("organizations/foo"), policy("folders/bar"), policy("projects/baz")},
+			orgServer:     &fakeServer{policy: &iampb.Policy{}},
+			foldersServer: &fakeServer{policy: &iampb.Policy{}},
+			projectServer: &fakeServer{policy: &iampb.Policy{}},
+			wantSetCalls:  3,
+		},
+		{
+			name:          "get_policy_denied",
+			policies:      []*v1alpha1.ResourcePolicy{policy("organizations/foo"), policy("folders/bar"), policy("projects/baz")},
+			orgServer:     &fakeServer{policy: &iampb.Policy{}},
+			foldersServer: &fakeServer{policy: &iampb.Policy{}},
+			projectServer: &fakeServer{
+				policy:          &iampb.Policy{},
+				getIAMPolicyErr: status.Error(codes.PermissionDenied, "injected error"),
+			},
+			wantFailed:    []string{"projects/baz"},
+			wantErrSubstr: "resource projects/baz is not accessible: failed to get IAM policy",
+		},
+		{
+			name:          "set_policy_permission_missing",
+			policies:      []*v1alpha1.ResourcePolicy{policy("organizations/foo"), policy("folders/bar")},
+			orgServer:     &fakeServer{policy: &iampb.Policy{}},
+			foldersServer: &fakeServer{policy: &iampb.Policy{}, deniedPermissions: []string{"resourcemanager.folders.setIamPolicy"}},
+			projectServer: &fakeServer{policy: &iampb.Policy{}},
+			wantFailed:    []string{"folders/bar"},
+			wantErrSubstr: `resource folders/bar is not accessible: missing permission "resourcemanager.folders.setIamPolicy"`,
+		},
+		{
+			name:          "resource_not_allowed",
+			policies:      []*v1alpha1.ResourcePolicy{policy("organizations/foo"), policy("projects/baz")},
+			orgServer:     &fakeServer{policy: &iampb.Policy{}},
+			foldersServer: &fakeServer{policy: &iampb.Policy{}},
+			projectServer: &fakeServer{policy: &iampb.Policy{}},
+			opts:          []Option{WithAllowedResourcePrefixes("projects/")},
+			wantFailed:    []string{"organizations/foo"},
+			wantErrSubstr: "organizations/foo",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t, ctx, tc.orgServer, tc.foldersServer, tc.projectServer)
+
+			opts := append([]Option{
+				WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+				WithNowFunc(func() time.Time { return now }),
+				WithPreflight(),
+				WithConcurrency(2),
+			}, tc.opts...)
+			h, err := NewIAMHandler(ctx, fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient, opts...)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			_, gotErr := h.Do(ctx, &v1alpha1.IAMRequestWrapper{
+				IAMRequest: &v1alpha1.IAMRequest{ResourcePolicies: tc.policies},
+				Duration:   time.Hour,
+				StartTime:  now,
+			})
+			if diff := testutil.DiffErrString(gotErr, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+			var gotFailed []string
+			if pErr := (*PreflightError)(nil); errors.As(gotErr, &pErr) {
+				gotFailed = pErr.Resources
+			}
+			if diff := cmp.Diff(tc.wantFailed, gotFailed); diff != "" {
+				t.Errorf("Process(%+v) got failed resources diff (-want, +got): %v", tc.name, diff)
+			}
+			gotSetCalls := tc.orgServer.setCalls + tc.foldersServer.setCalls + tc.projectServer.setCalls
+			if gotSetCalls != tc.wantSetCalls {
+				t.Errorf("Process(%+v) got %d SetIamPolicy calls, want %d", tc.name, gotSetCalls, tc.wantSetCalls)
+			}
+		})
+	}
+}
+
+func TestCheckResources(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	projectServer := &fakeServer{policy: &iampb.Policy{}, deniedPermissions: []string{"resourcemanager.projects.setIamPolicy"}}
+	foldersServer := &fakeServer{policy: &iampb.Policy{}}
+	fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+		t, ctx, &fakeServer{policy: &iampb.Policy{}}, foldersServer, projectServer)
+
+	h, err := NewIAMHandler(ctx, fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient,
+		WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))))
+	if err != nil {
+		t.Fatalf("failed to create IAMHandler: %v", err)
+	}
+
+	got, err := h.CheckResources(ctx, []string{"projects/baz", "folders/bar"})
+	if err != nil {
+		t.Fatalf("CheckResources got unexpected error: %v", err)
+	}
+	want := []*ResourceReadiness{
+		{Resource: "projects/baz", Error: `resource projects/baz is not accessible: missing permission "resourcemanager.projects.setIamPolicy"`},
+		{Resource: "folders/bar", Ready: true},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("CheckResources got readiness diff (-want, +got): %v", diff)
+	}
+	if got := projectServer.setCalls + foldersServer.setCalls; got != 0 {
+		t.Errorf("CheckResources got %d SetIamPolicy calls, want 0", got)
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+
+	"github.com/abcxyz/access-on-demand/pkg/progress"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// reportProgress reports a progress event of handling the resource to the
+// progress reporter, with the attempt and error if they are set. Failures are
+// logged and ignored.
+func (h *IAMHandler) reportProgress(ctx context.Context, typ, resource string, attempt int, err error) {
+	if h.progressReporter == nil {
+		return
+	}
+
+	e := &progress.Event{
+		Type:     typ,
+		Time:     h.now().UTC(),
+		Resource: resource,
+		Attempt:  attempt,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	if err := h.progressReporter.Report(ctx, e); err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "failed to report progress event", "error", err)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sethvargo/go-retry"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/progress"
+)
+
+func TestProgressEvents(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	request := &v1alpha1.IAMRequestWrapper{
+		IAMRequest: &v1alpha1.IAMRequest{
+			ResourcePolicies: []*v1alpha1.ResourcePolicy{
+				{
+					Resource: "projects/baz",
+					Bindings: []*v1alpha1.Binding{
+						{
+							Members: []string{"user:test-project-user@example.com"},
+							Role:    "roles/bigquery.dataViewer",
+						},
+					},
+				},
+			},
+		},
+		Duration:  2 * time.Hour,
+		StartTime: now,
+	}
+
+	cases := []struct {
+		name      string
+		server    *fakeServer
+		expEvents []*progress.Event
+	}{
+		{
+			name: "success",
+			server: &fakeServer{
+				policy: &iampb.Policy{Etag: []byte("v1")},
+			},
+			expEvents: []*progress.Event{
+				{Type: progress.EventTypeStarted, Time: now, Resource: "projects/baz"},
+				{Type: progress.EventTypeCompleted, Time: now, Resource: "projects/baz"},
+			},
+		},
+		{
+			name: "success_after_retry",
+			server: &fakeServer{
+				policy:           &iampb.Policy{Etag: []byte("v1")},
+				concurrentWrites: []*iampb.Policy{{Etag: []byte("v2")}},
+			},
+			expEvents: []*progress.Event{
+				{Type: progress.EventTypeStarted, Time: now, Resource: "projects/baz"},
+				{
+					Type:     progress.EventTypeRetry,
+					Time:     now,
+					Resource: "projects/baz",
+					Attempt:  2,
+					Error:    "failed to set IAM policy due to concurrent policy modification: rpc error: code = Aborted desc = There were concurrent policy changes, retrying",
+				},
+				{Type: progress.EventTypeCompleted, Time: now, Resource: "projects/baz"},
+			},
+		},
+		{
+			name: "failure",
+			server: &fakeServer{
+				policy:          &iampb.Policy{Etag: []byte("v1")},
+				setIAMPolicyErr: status.Error(codes.PermissionDenied, "injected error"),
+			},
+			expEvents: []*progress.Event{
+				{Type: progress.EventTypeStarted, Time: now, Resource: "projects/baz"},
+				{
+					Type:     progress.EventTypeFailed,
+					Time:     now,
+					Resource: "projects/baz",
+					Error:    "failed to handle IAM request: failed to set IAM policy: rpc error: code = PermissionDenied desc = injected error",
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{},
+				&fakeServer{},
+				tc.server,
+			)
+
+			reporter := &fakeProgressReporter{}
+			h, err := NewIAMHandler(
+				ctx,
+				fakeOrganizationsClient,
+				fakeFoldersClient,
+				fakeProjectsClient,
+				WithRetry(retry.WithMaxRetries(1, retry.NewConstant(time.Millisecond))),
+				WithNowFunc(func() time.Time { return now }),
+				WithProgressReporter(reporter),
+			)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			// Errors are checked in the handler tests.
+			_, _ = h.Do(ctx, request)
+
+			if diff := cmp.Diff(tc.expEvents, reporter.events); diff != "" {
+				t.Errorf("Process(%+v) got progress events diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+type fakeProgressReporter struct {
+	mu     sync.Mutex
+	events []*progress.Event
+}
+
+func (r *fakeProgressReporter) Report(_ context.Context, e *progress.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+	return nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sethvargo/go-retry"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+)
+
+// defaultPropagationPollInterval is the default interval of polling the IAM
+// policies for the granted bindings.
+const defaultPropagationPollInterval = 5 * time.Second
+
+// WithPropagationPollInterval provides the interval of polling the IAM
+// policies in WaitForPropagation, default is 5s.
+func WithPropagationPollInterval(d time.Duration) Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		if d <= 0 {
+			return nil, fmt.Errorf("propagation poll interval must be positive, got %s", d)
+		}
+		p.propagationPollInterval = d
+		return p, nil
+	}
+}
+
+// WaitForPropagation polls the IAM policies of the resources in the request
+// until the requested bindings with the expiry are visible in all of them, or
+// the timeout passes. It returns an error listing the bindings not visible at
+// the timeout.
+func (h *IAMHandler) WaitForPropagation(ctx context.Context, r *v1alpha1.IAMRequest, expiry time.Time, timeout time.Duration) error {
+	pending := r.ResourcePolicies
+	var missing []*v1alpha1.BindingStatus
+	var pollErr error
+	if err := retry.Do(ctx, retry.WithMaxDuration(timeout, retry.NewConstant(h.propagationPollInterval)), func(ctx context.Context) error {
+		var next []*v1alpha1.ResourcePolicy
+		missing, pollErr = nil, nil
+		for _, p := range pending {
+			ss, err := h.diffLivePolicy(ctx, p, expiry)
+			if err != nil {
+				if errors.Is(err, ErrAPICallBudgetExceeded) {
+					return err
+				}
+				pollErr = errors.Join(pollErr, fmt.Errorf("failed to get IAM policy of resource %s: %w", p.Resource, err))
+				next = append(next, p)
+				continue
+			}
+			visible := true
+			for _, s := range ss {
+				if s.Status != v1alpha1.BindingStatusPresent {
+					missing = append(missing, s)
+					visible = false
+				}
+			}
+			if !visible {
+				next = append(next, p)
+			}
+		}
+		pending = next
+		if len(pending) > 0 {
+			return retry.RetryableError(fmt.Errorf("bindings not visible in %d resources", len(pending)))
+		}
+		return nil
+	}); err != nil {
+		if errors.Is(err, ErrAPICallBudgetExceeded) {
+			return fmt.Errorf("failed to wait for propagation: %w", err)
+		}
+		var missingErr error
+		if len(missing) > 0 {
+			missingErr = fmt.Errorf("missing %s", formatBindingStatuses(missing))
+		}
+		return fmt.Errorf("bindings not visible in the IAM policies after %s: %w", timeout, errors.Join(missingErr, pollErr))
+	}
+	return nil
+}
+
+// formatBindingStatuses formats the binding statuses as a list of
+// "<role> of <member> on <resource>".
+func formatBindingStatuses(ss []*v1alpha1.BindingStatus) string {
+	parts := make([]string, 0, len(ss))
+	for _, s := range ss {
+		parts = append(parts, fmt.Sprintf("%s of %s on %s", s.Role, s.Member, s.Resource))
+	}
+	return strings.Join(parts, ", ")
+}
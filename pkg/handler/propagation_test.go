@@ -0,0 +1,127 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/googleapis/gax-go/v2"
+	"github.com/sethvargo/go-retry"
+	"google.golang.org/genproto/googleapis/type/expr"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestWaitForPropagation(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	expiry := now.Add(time.Hour)
+	granted := &iampb.Policy{
+		Bindings: []*iampb.Binding{{
+			Members: []string{"user:alice@example.com"},
+			Role:    "roles/viewer",
+			Condition: &expr.Expr{
+				Title:      DefaultConditionTitle,
+				Expression: fmt.Sprintf(expirationExpression, expiry.Format(time.RFC3339)),
+			},
+		}},
+		Version: 3,
+	}
+	req := &v1alpha1.IAMRequest{
+		ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+			Resource: "projects/foo",
+			Bindings: []*v1alpha1.Binding{{
+				Members: []string{"user:alice@example.com"},
+				Role:    "roles/viewer",
+			}},
+		}},
+	}
+
+	cases := []struct {
+		name          string
+		policies      []*iampb.Policy
+		wantGets      int
+		wantErrSubstr string
+	}{
+		{
+			name:     "visible",
+			policies: []*iampb.Policy{granted},
+			wantGets: 1,
+		},
+		{
+			name:     "visible_after_polls",
+			policies: []*iampb.Policy{{}, {}, granted},
+			wantGets: 3,
+		},
+		{
+			name:          "timeout",
+			policies:      []*iampb.Policy{{}},
+			wantErrSubstr: "missing roles/viewer of user:alice@example.com on projects/foo",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			c := &fakeStaleIAMClient{policies: tc.policies}
+			h, err := NewIAMHandler(ctx, c, c, c,
+				WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+				WithNowFunc(func() time.Time { return now }),
+				WithPropagationPollInterval(time.Millisecond),
+			)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			gotErr := h.WaitForPropagation(ctx, req, expiry, 100*time.Millisecond)
+			if diff := testutil.DiffErrString(gotErr, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+			if tc.wantGets > 0 && c.gets != tc.wantGets {
+				t.Errorf("Process(%+v) got %d GetIamPolicy calls, want %d", tc.name, c.gets, tc.wantGets)
+			}
+		})
+	}
+}
+
+// fakeStaleIAMClient is an IAMClient returning the policies in order, one for
+// each GetIamPolicy call, and then the last policy, to simulate propagation
+// delays.
+type fakeStaleIAMClient struct {
+	mu       sync.Mutex
+	policies []*iampb.Policy
+	gets     int
+}
+
+func (c *fakeStaleIAMClient) GetIamPolicy(context.Context, *iampb.GetIamPolicyRequest, ...gax.CallOption) (*iampb.Policy, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p := c.policies[min(c.gets, len(c.policies)-1)]
+	c.gets++
+	return p, nil
+}
+
+func (c *fakeStaleIAMClient) SetIamPolicy(_ context.Context, r *iampb.SetIamPolicyRequest, _ ...gax.CallOption) (*iampb.Policy, error) {
+	return r.GetPolicy(), nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/resource"
+)
+
+// ProtectedResourceError is the error of a grant on the resources hosting the
+// AOD deployment itself, which is not allowed unless acknowledged with
+// WithAllowProtectedResources.
+type ProtectedResourceError struct {
+	// Resources are the protected resources of the grant.
+	Resources []string
+}
+
+func (e *ProtectedResourceError) Error() string {
+	return fmt.Sprintf("granting roles on %s hosting this AOD deployment must be explicitly allowed",
+		strings.Join(e.Resources, ", "))
+}
+
+// WithProtectedResources provides the resources hosting the AOD deployment
+// itself, such as the project of its service account and the folders and
+// organization of the project. Granting roles on them would give control over
+// AOD and its enforcement, so Do fails with a ProtectedResourceError before
+// modifying any IAM policy, unless WithAllowProtectedResources is provided.
+// It can be provided multiple times.
+func WithProtectedResources(resources ...string) Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		for _, r := range resources {
+			if _, err := resource.Parse(r); err != nil {
+				return nil, fmt.Errorf("invalid protected resource: %w", err)
+			}
+		}
+		p.protectedResources = append(p.protectedResources, resources...)
+		return p, nil
+	}
+}
+
+// WithAllowProtectedResources allows grants on the protected resources, such
+// as for requests with an extra approval. The grants are reported as warnings
+// of the responses.
+func WithAllowProtectedResources() Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		p.allowProtected = true
+		return p, nil
+	}
+}
+
+// checkProtected returns a ProtectedResourceError of the protected resources
+// of the policies, unless grants on them are allowed.
+func (h *IAMHandler) checkProtected(ps []*v1alpha1.ResourcePolicy) error {
+	if h.allowProtected {
+		return nil
+	}
+	var protected []string
+	for _, p := range ps {
+		if slices.Contains(h.protectedResources, p.Resource) && !slices.Contains(protected, p.Resource) {
+			protected = append(protected, p.Resource)
+		}
+	}
+	if len(protected) > 0 {
+		return &ProtectedResourceError{Resources: protected}
+	}
+	return nil
+}
+
+// warnProtected adds warnings to the responses of the protected resources,
+// whose grants were allowed.
+func (h *IAMHandler) warnProtected(resps []*v1alpha1.IAMResponse) {
+	for _, r := range resps {
+		if r != nil && slices.Contains(h.protectedResources, r.Resource) {
+			r.Warnings = append(r.Warnings,
+				fmt.Sprintf("granted roles on %s hosting this AOD deployment", r.Resource))
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sethvargo/go-retry"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestProtectedResources(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		opts          []Option
+		wantSetCalls  int
+		wantWarnings  []string
+		wantErrSubstr string
+	}{
+		{
+			name:         "no_protected_resources",
+			wantSetCalls: 2,
+		},
+		{
+			name:          "protected",
+			opts:          []Option{WithProtectedResources("projects/aod-infra")},
+			wantErrSubstr: "granting roles on projects/aod-infra hosting this AOD deployment must be explicitly allowed",
+		},
+		{
+			name:         "protected_allowed",
+			opts:         []Option{WithProtectedResources("projects/aod-infra"), WithAllowProtectedResources()},
+			wantSetCalls: 2,
+			wantWarnings: []string{"granted roles on projects/aod-infra hosting this AOD deployment"},
+		},
+		{
+			name:         "other_resources",
+			opts:         []Option{WithProtectedResources("projects/other")},
+			wantSetCalls: 2,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			server := &fakeServer{policy: &iampb.Policy{}}
+			o, f, p := setupFakeClients(t, ctx, &fakeServer{policy: &iampb.Policy{}}, &fakeServer{policy: &iampb.Policy{}}, server)
+
+			opts := append([]Option{WithRetry(retry.WithMaxRetries(0, retry.NewConstant(time.Millisecond)))}, tc.opts...)
+			h, err := NewIAMHandler(ctx, o, f, p, opts...)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			bindings := []*v1alpha1.Binding{{Members: []string{"user:alice@example.com"}, Role: "roles/browser"}}
+			resps, gotErr := h.Do(ctx, &v1alpha1.IAMRequestWrapper{
+				IAMRequest: &v1alpha1.IAMRequest{
+					ResourcePolicies: []*v1alpha1.ResourcePolicy{
+						{Resource: "projects/foo", Bindings: bindings},
+						{Resource: "projects/aod-infra", Bindings: bindings},
+					},
+				},
+				Duration:  time.Hour,
+				StartTime: time.Now(),
+			})
+			if diff := testutil.DiffErrString(gotErr, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+			var protectedErr *ProtectedResourceError
+			if got, want := errors.As(gotErr, &protectedErr), tc.wantErrSubstr != ""; got != want {
+				t.Errorf("Process(%+v) got ProtectedResourceError %t, want %t", tc.name, got, want)
+			}
+			// No IAM policy is modified if any resource is protected.
+			if got, want := server.setCalls, tc.wantSetCalls; got != want {
+				t.Errorf("Process(%+v) got %d SetIamPolicy calls, want %d", tc.name, got, want)
+			}
+			var gotWarnings []string
+			for _, r := range resps {
+				gotWarnings = append(gotWarnings, r.Warnings...)
+			}
+			if diff := cmp.Diff(tc.wantWarnings, gotWarnings); diff != "" {
+				t.Errorf("Process(%+v) got warnings diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestProtectedResourceOptions(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewIAMHandler(context.Background(), nil, nil, nil, WithProtectedResources("projects"))
+	if diff := testutil.DiffErrString(err, "invalid protected resource"); diff != "" {
+		t.Errorf("NewIAMHandler got unexpected error substring: %v", diff)
+	}
+}
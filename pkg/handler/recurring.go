@@ -0,0 +1,131 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+)
+
+// recurringCondition is the recurring windows of a request compiled to a CEL
+// expression, which is appended to the expiry expression of the bindings.
+type recurringCondition struct {
+	// expression restricting the access to the recurring windows.
+	expression string
+
+	// until is when the access ends regardless of the request expiry, or the
+	// zero time if it is not bounded.
+	until time.Time
+}
+
+// compileRecurring compiles the recurring windows of a request, it returns nil
+// if the request has no recurring windows.
+func compileRecurring(r *v1alpha1.Recurring) (*recurringCondition, error) {
+	if r == nil {
+		return nil, nil //nolint:nilnil // No recurring windows.
+	}
+	days, err := r.Weekdays()
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurring days: %w", err)
+	}
+	start, end, err := r.HourRange()
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurring hours: %w", err)
+	}
+	loc, err := r.Location()
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurring time zone: %w", err)
+	}
+	until, err := r.UntilTime()
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurring until: %w", err)
+	}
+
+	var parts []string
+	if exp := daysExpression(days, loc.String()); exp != "" {
+		parts = append(parts, exp)
+	}
+	parts = append(parts, hoursExpressions(start, end, loc.String())...)
+	return &recurringCondition{expression: strings.Join(parts, " && "), until: until}, nil
+}
+
+// expiry returns the expiry of the bindings, which is the earlier of the
+// request expiry and the end of the recurring access.
+func (c *recurringCondition) expiry(expiry time.Time) time.Time {
+	if c == nil || c.until.IsZero() || c.until.After(expiry) {
+		return expiry
+	}
+	return c.until
+}
+
+// withExpression appends the recurring expression to the expiry expression of
+// the bindings.
+func (c *recurringCondition) withExpression(exp string) string {
+	if c == nil || c.expression == "" {
+		return exp
+	}
+	return exp + " && " + c.expression
+}
+
+// daysExpression returns the expression matching the days of the week, with a
+// range comparison of each run of consecutive days. It is empty if the days
+// are all the days of the week.
+func daysExpression(days []time.Weekday, tz string) string {
+	if len(days) == 0 || len(days) == 7 {
+		return ""
+	}
+	day := fmt.Sprintf("request.time.getDayOfWeek('%s')", tz)
+
+	var runs []string
+	for i := 0; i < len(days); {
+		j := i
+		for j+1 < len(days) && days[j+1] == days[j]+1 {
+			j++
+		}
+		if i == j {
+			runs = append(runs, fmt.Sprintf("%s == %d", day, days[i]))
+		} else {
+			runs = append(runs, fmt.Sprintf("(%s >= %d && %s <= %d)", day, days[i], day, days[j]))
+		}
+		i = j + 1
+	}
+	if len(runs) == 1 {
+		return strings.TrimSuffix(strings.TrimPrefix(runs[0], "("), ")")
+	}
+	return "(" + strings.Join(runs, " || ") + ")"
+}
+
+// hoursExpressions returns the expressions matching the times of the day from
+// start to the exclusive end, which are none for all day.
+func hoursExpressions(start, end time.Duration, tz string) []string {
+	hours := fmt.Sprintf("request.time.getHours('%s')", tz)
+	minutes := fmt.Sprintf("request.time.getMinutes('%s')", tz)
+
+	var exps []string
+	if h, m := int(start.Hours()), int(start.Minutes())%60; start > 0 && m == 0 {
+		exps = append(exps, fmt.Sprintf("%s >= %d", hours, h))
+	} else if start > 0 {
+		exps = append(exps, fmt.Sprintf("(%s > %d || (%s == %d && %s >= %d))", hours, h, hours, h, minutes, m))
+	}
+	if h, m := int(end.Hours()), int(end.Minutes())%60; end < 24*time.Hour && m == 0 {
+		exps = append(exps, fmt.Sprintf("%s < %d", hours, h))
+	} else if end < 24*time.Hour {
+		exps = append(exps, fmt.Sprintf("(%s < %d || (%s == %d && %s < %d))", hours, h, hours, h, minutes, m))
+	}
+	return exps
+}
@@ -0,0 +1,179 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sethvargo/go-retry"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestCompileRecurring(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		recurring     *v1alpha1.Recurring
+		wantExp       string
+		wantUntil     time.Time
+		wantErrSubstr string
+	}{
+		{
+			name: "no_recurring",
+		},
+		{
+			name:      "business_hours",
+			recurring: &v1alpha1.Recurring{Days: []string{"Mon-Fri"}, Hours: "09:00-17:00"},
+			wantExp: "request.time.getDayOfWeek('UTC') >= 1 && request.time.getDayOfWeek('UTC') <= 5 && " +
+				"request.time.getHours('UTC') >= 9 && request.time.getHours('UTC') < 17",
+		},
+		{
+			name:      "separate_days",
+			recurring: &v1alpha1.Recurring{Days: []string{"Sat", "Mon", "tuesday"}, TimeZone: "America/New_York"},
+			wantExp: "((request.time.getDayOfWeek('America/New_York') >= 1 && request.time.getDayOfWeek('America/New_York') <= 2) || " +
+				"request.time.getDayOfWeek('America/New_York') == 6)",
+		},
+		{
+			name:      "wrapping_days",
+			recurring: &v1alpha1.Recurring{Days: []string{"Fri-Mon"}},
+			wantExp: "((request.time.getDayOfWeek('UTC') >= 0 && request.time.getDayOfWeek('UTC') <= 1) || " +
+				"(request.time.getDayOfWeek('UTC') >= 5 && request.time.getDayOfWeek('UTC') <= 6))",
+		},
+		{
+			name:      "minutes",
+			recurring: &v1alpha1.Recurring{Hours: "08:30-17:45"},
+			wantExp: "(request.time.getHours('UTC') > 8 || (request.time.getHours('UTC') == 8 && request.time.getMinutes('UTC') >= 30)) && " +
+				"(request.time.getHours('UTC') < 17 || (request.time.getHours('UTC') == 17 && request.time.getMinutes('UTC') < 45))",
+		},
+		{
+			name:      "all_week_until_midnight",
+			recurring: &v1alpha1.Recurring{Days: []string{"Sun-Sat"}, Hours: "18:00-24:00", Until: "2009-11-20"},
+			wantExp:   "request.time.getHours('UTC') >= 18",
+			wantUntil: time.Date(2009, 11, 21, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "invalid_day",
+			recurring:     &v1alpha1.Recurring{Days: []string{"Someday"}},
+			wantErrSubstr: `invalid recurring days: day "Someday" is not a day of the week`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := compileRecurring(tc.recurring)
+			if diff := testutil.DiffErrString(err, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+			if got == nil {
+				if tc.wantExp != "" {
+					t.Errorf("Process(%+v) got no recurring condition, want expression %q", tc.name, tc.wantExp)
+				}
+				return
+			}
+			if diff := cmp.Diff(tc.wantExp, got.expression); diff != "" {
+				t.Errorf("Process(%+v) got expression diff (-want, +got): %v", tc.name, diff)
+			}
+			if !got.until.Equal(tc.wantUntil) {
+				t.Errorf("Process(%+v) got until %s, want %s", tc.name, got.until, tc.wantUntil)
+			}
+		})
+	}
+}
+
+func TestDoRecurring(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name      string
+		recurring *v1alpha1.Recurring
+		wantExp   string
+	}{
+		{
+			name:      "request_expiry",
+			recurring: &v1alpha1.Recurring{Days: []string{"Mon-Fri"}, Until: "2009-12-31"},
+			wantExp: "request.time < timestamp('2009-11-17T23:00:00Z') && " +
+				"request.time.getDayOfWeek('UTC') >= 1 && request.time.getDayOfWeek('UTC') <= 5",
+		},
+		{
+			name:      "until_before_request_expiry",
+			recurring: &v1alpha1.Recurring{Hours: "09:00-17:00", Until: "2009-11-12"},
+			wantExp: "request.time < timestamp('2009-11-13T00:00:00Z') && " +
+				"request.time.getHours('UTC') >= 9 && request.time.getHours('UTC') < 17",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			projectServer := &fakeServer{policy: &iampb.Policy{}}
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+				projectServer,
+			)
+			h, err := NewIAMHandler(
+				ctx,
+				fakeOrganizationsClient,
+				fakeFoldersClient,
+				fakeProjectsClient,
+				WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+				WithNowFunc(func() time.Time { return now }),
+			)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			if _, err := h.Do(ctx, &v1alpha1.IAMRequestWrapper{
+				IAMRequest: &v1alpha1.IAMRequest{
+					ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+						Resource: "projects/baz",
+						Bindings: []*v1alpha1.Binding{{
+							Members: []string{"user:alice@example.com"},
+							Role:    "roles/viewer",
+						}},
+					}},
+					Recurring: tc.recurring,
+				},
+				StartTime: now,
+				Duration:  7 * 24 * time.Hour,
+			}); err != nil {
+				t.Fatalf("Process(%+v) got unexpected error: %v", tc.name, err)
+			}
+
+			bindings := projectServer.policy.GetBindings()
+			if len(bindings) != 1 {
+				t.Fatalf("Process(%+v) got bindings %v, want one binding", tc.name, bindings)
+			}
+			if diff := cmp.Diff(tc.wantExp, bindings[0].GetCondition().GetExpression()); diff != "" {
+				t.Errorf("Process(%+v) got condition expression diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/sethvargo/go-retry"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+)
+
+// RollbackError is the error of a grant which failed for some resources, after
+// which the resources already updated were rolled back to their prior IAM
+// policies.
+type RollbackError struct {
+	// Err is the error of the grant.
+	Err error
+
+	// RolledBack are the resources restored to their prior IAM policies.
+	RolledBack []string
+
+	// Failed are the resources which failed to be rolled back, and still have
+	// the granted bindings.
+	Failed []string
+
+	// RollbackErr is the error of rolling back the failed resources.
+	RollbackErr error
+}
+
+// Error implements error.
+func (e *RollbackError) Error() string {
+	msg := fmt.Sprintf("%v; rolled back resources %q", e.Err, e.RolledBack)
+	if len(e.Failed) > 0 {
+		msg += fmt.Sprintf("; failed to roll back resources %q: %v", e.Failed, e.RollbackErr)
+	}
+	return msg
+}
+
+// Unwrap returns the errors of the grant and the rollback.
+func (e *RollbackError) Unwrap() []error {
+	return []error{e.Err, e.RollbackErr}
+}
+
+// WithRollbackOnFailure makes Do all-or-nothing, when it fails for any
+// resource, the resources already updated are restored to their IAM policies
+// before the update. A resource modified by others since it was updated is not
+// restored, to not overwrite the modifications.
+func WithRollbackOnFailure() Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		p.rollbackOnFailure = true
+		return p, nil
+	}
+}
+
+// updatedPolicy is a resource policy whose IAM policy was updated, with the
+// IAM policies before and after the update.
+type updatedPolicy struct {
+	policy  *v1alpha1.ResourcePolicy
+	prior   *iampb.Policy
+	current *iampb.Policy
+}
+
+// rollback restores the updated resources of the failed grant to their prior
+// IAM policies, in the reverse order of their dependencies, and returns the
+// responses of the resources which are not rolled back with a RollbackError.
+// The updates are in the order they were made.
+func (h *IAMHandler) rollback(ctx context.Context, r *v1alpha1.IAMRequestWrapper, resps []*v1alpha1.IAMResponse, updates []*updatedPolicy, grantErr error) ([]*v1alpha1.IAMResponse, error) {
+	// Restore each resource to the policy before its first update, with the
+	// etag of its last update.
+	var resources []string
+	first := make(map[string]*updatedPolicy)
+	last := make(map[string]*updatedPolicy)
+	for _, u := range updates {
+		if _, ok := first[u.policy.Resource]; !ok {
+			first[u.policy.Resource] = u
+			resources = append(resources, u.policy.Resource)
+		}
+		last[u.policy.Resource] = u
+	}
+
+	// Roll back the dependents before their dependencies.
+	levels, err := v1alpha1.PolicyLevels(r.ResourcePolicies)
+	if err != nil {
+		return resps, errors.Join(grantErr, fmt.Errorf("failed to order resource policies: %w", err))
+	}
+	order := make(map[string]int, len(r.ResourcePolicies))
+	for l, level := range levels {
+		for _, i := range level {
+			order[r.ResourcePolicies[i].Resource] = max(order[r.ResourcePolicies[i].Resource], l)
+		}
+	}
+	slices.SortStableFunc(resources, func(a, b string) int {
+		return order[b] - order[a]
+	})
+
+	rbErr := &RollbackError{Err: grantErr}
+	for _, res := range resources {
+		err := h.restorePolicy(ctx, res, first[res].prior, last[res].current.GetEtag())
+		for _, u := range updates {
+			if u.policy.Resource == res {
+				h.writeAuditEvent(ctx, audit.EventTypeRollback, u.policy, r, err)
+			}
+		}
+		if err != nil {
+			rbErr.Failed = append(rbErr.Failed, res)
+			rbErr.RollbackErr = errors.Join(rbErr.RollbackErr,
+				fmt.Errorf("failed to roll back resource %s: %w", res, err))
+			continue
+		}
+		rbErr.RolledBack = append(rbErr.RolledBack, res)
+	}
+
+	kept := slices.DeleteFunc(resps, func(resp *v1alpha1.IAMResponse) bool {
+		return slices.Contains(rbErr.RolledBack, resp.Resource)
+	})
+	return kept, rbErr
+}
+
+// restorePolicy sets the IAM policy of the resource to the prior policy, with
+// the etag so that it fails instead of overwriting modifications since the
+// policy with the etag.
+func (h *IAMHandler) restorePolicy(ctx context.Context, resource string, prior *iampb.Policy, etag []byte) error {
+	iamC, err := h.iamClient(ctx, resource)
+	if err != nil {
+		return err
+	}
+
+	p, ok := proto.Clone(prior).(*iampb.Policy)
+	if !ok {
+		return fmt.Errorf("failed to clone IAM policy")
+	}
+	p.Etag = etag
+
+	if err := retry.Do(ctx, h.retry, func(ctx context.Context) error {
+		if err := h.spendAPICall(); err != nil {
+			return err
+		}
+		_, err := iamC.SetIamPolicy(h.outgoingContext(ctx), &iampb.SetIamPolicyRequest{
+			Resource: resource,
+			Policy:   p,
+		}, h.setPolicyOpts...)
+		if err == nil {
+			return nil
+		}
+		// Do not retry when the policy was modified since the update, the
+		// modifications must not be overwritten.
+		if isConflict(err) {
+			return fmt.Errorf("failed to set IAM policy due to policy modification since the update: %w", err)
+		}
+		if isNonRetryable(err) {
+			return fmt.Errorf("failed to set IAM policy: %w", err)
+		}
+		return retry.RetryableError(fmt.Errorf("failed to set IAM policy: %w, retrying", err))
+	}); err != nil {
+		return err //nolint:wrapcheck // Want passthrough
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/gax-go/v2"
+	"github.com/sethvargo/go-retry"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestDoRollbackOnFailure(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	existing := func() *iampb.Policy {
+		return &iampb.Policy{
+			Bindings: []*iampb.Binding{{Members: []string{"user:bob@example.com"}, Role: "roles/owner"}},
+			Version:  3,
+		}
+	}
+	policy := func(resource string, dependsOn ...string) *v1alpha1.ResourcePolicy {
+		return &v1alpha1.ResourcePolicy{
+			Resource: resource,
+			Bindings: []*v1alpha1.Binding{{
+				Members: []string{"user:alice@example.com"},
+				Role:    "roles/viewer",
+			}},
+			DependsOn: dependsOn,
+		}
+	}
+	denied := status.Error(codes.PermissionDenied, "injected error")
+
+	cases := []struct {
+		name          string
+		policies      []*v1alpha1.ResourcePolicy
+		setErrs       map[string][]error
+		noRollback    bool
+		wantSets      []string
+		wantRestored  []string
+		wantResps     []string
+		wantEvents    []string
+		wantRollback  *RollbackError
+		wantErrSubstr string
+	}{
+		{
+			name:         "rollback",
+			policies:     []*v1alpha1.ResourcePolicy{policy("projects/a"), policy("projects/b")},
+			setErrs:      map[string][]error{"projects/b": {denied}},
+			wantSets:     []string{"projects/a", "projects/b", "projects/a"},
+			wantRestored: []string{"projects/a", "projects/b"},
+			wantEvents: []string{
+				"GRANT projects/a SUCCESS",
+				"GRANT projects/b FAILURE",
+				"ROLLBACK projects/a SUCCESS",
+			},
+			wantRollback:  &RollbackError{RolledBack: []string{"projects/a"}},
+			wantErrSubstr: `rolled back resources ["projects/a"]`,
+		},
+		{
+			name: "rollback_dependents_first",
+			policies: []*v1alpha1.ResourcePolicy{
+				policy("folders/f"),
+				policy("projects/p", "folders/f"),
+				policy("projects/x"),
+			},
+			setErrs:      map[string][]error{"projects/x": {denied}},
+			wantSets:     []string{"folders/f", "projects/x", "projects/p", "projects/p", "folders/f"},
+			wantRestored: []string{"folders/f", "projects/p", "projects/x"},
+			wantEvents: []string{
+				"GRANT folders/f SUCCESS",
+				"GRANT projects/x FAILURE",
+				"GRANT projects/p SUCCESS",
+				"ROLLBACK projects/p SUCCESS",
+				"ROLLBACK folders/f SUCCESS",
+			},
+			wantRollback:  &RollbackError{RolledBack: []string{"projects/p", "folders/f"}},
+			wantErrSubstr: `rolled back resources ["projects/p" "folders/f"]`,
+		},
+		{
+			name:         "rollback_failure",
+			policies:     []*v1alpha1.ResourcePolicy{policy("projects/a"), policy("projects/b")},
+			setErrs:      map[string][]error{"projects/a": {nil, denied}, "projects/b": {denied}},
+			wantSets:     []string{"projects/a", "projects/b", "projects/a"},
+			wantRestored: []string{"projects/b"},
+			wantResps:    []string{"projects/a"},
+			wantEvents: []string{
+				"GRANT projects/a SUCCESS",
+				"GRANT projects/b FAILURE",
+				"ROLLBACK projects/a FAILURE",
+			},
+			wantRollback:  &RollbackError{Failed: []string{"projects/a"}},
+			wantErrSubstr: `failed to roll back resources ["projects/a"]`,
+		},
+		{
+			name:          "no_rollback",
+			policies:      []*v1alpha1.ResourcePolicy{policy("projects/a"), policy("projects/b")},
+			setErrs:       map[string][]error{"projects/b": {denied}},
+			noRollback:    true,
+			wantSets:      []string{"projects/a", "projects/b"},
+			wantRestored:  []string{"projects/b"},
+			wantResps:     []string{"projects/a"},
+			wantEvents:    []string{"GRANT projects/a SUCCESS", "GRANT projects/b FAILURE"},
+			wantErrSubstr: "failed to handle policy update for resource projects/b",
+		},
+		{
+			name:         "success",
+			policies:     []*v1alpha1.ResourcePolicy{policy("projects/a"), policy("projects/b")},
+			wantSets:     []string{"projects/a", "projects/b"},
+			wantResps:    []string{"projects/a", "projects/b"},
+			wantEvents:   []string{"GRANT projects/a SUCCESS", "GRANT projects/b SUCCESS"},
+			wantRestored: nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			c := &fakeEtagIAMClient{
+				policies: make(map[string]*iampb.Policy),
+				setErrs:  tc.setErrs,
+			}
+			for _, p := range tc.policies {
+				c.policies[p.Resource] = existing()
+			}
+
+			sink := &fakeAuditSink{}
+			opts := []Option{
+				WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+				WithNowFunc(func() time.Time { return now }),
+				WithAuditSink(sink),
+			}
+			if !tc.noRollback {
+				opts = append(opts, WithRollbackOnFailure())
+			}
+			h, err := NewIAMHandler(ctx, c, c, c, opts...)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			resps, gotErr := h.Do(ctx, &v1alpha1.IAMRequestWrapper{
+				IAMRequest: &v1alpha1.IAMRequest{ResourcePolicies: tc.policies},
+				Duration:   time.Hour,
+				StartTime:  now,
+			})
+			if diff := testutil.DiffErrString(gotErr, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+
+			var rbErr *RollbackError
+			if errors.As(gotErr, &rbErr) != (tc.wantRollback != nil) {
+				t.Errorf("Process(%+v) got error %v, want rollback error %t", tc.name, gotErr, tc.wantRollback != nil)
+			}
+			if rbErr != nil && tc.wantRollback != nil {
+				if diff := cmp.Diff(tc.wantRollback.RolledBack, rbErr.RolledBack); diff != "" {
+					t.Errorf("Process(%+v) got rolled back diff (-want, +got): %v", tc.name, diff)
+				}
+				if diff := cmp.Diff(tc.wantRollback.Failed, rbErr.Failed); diff != "" {
+					t.Errorf("Process(%+v) got failed rollback diff (-want, +got): %v", tc.name, diff)
+				}
+			}
+
+			var gotResps []string
+			for _, r := range resps {
+				gotResps = append(gotResps, r.Resource)
+			}
+			if diff := cmp.Diff(tc.wantResps, gotResps); diff != "" {
+				t.Errorf("Process(%+v) got responses diff (-want, +got): %v", tc.name, diff)
+			}
+
+			if diff := cmp.Diff(tc.wantSets, c.sets); diff != "" {
+				t.Errorf("Process(%+v) got SetIamPolicy calls diff (-want, +got): %v", tc.name, diff)
+			}
+
+			// The restored resources have the existing bindings only.
+			var gotRestored []string
+			for _, p := range tc.policies {
+				got := proto.Clone(c.policies[p.Resource]).(*iampb.Policy) //nolint:forcetypeassert // Cloned from the same type.
+				got.Etag = nil
+				if cmp.Diff(existing(), got, protocmp.Transform()) == "" {
+					gotRestored = append(gotRestored, p.Resource)
+				}
+			}
+			if diff := cmp.Diff(tc.wantRestored, gotRestored); diff != "" {
+				t.Errorf("Process(%+v) got restored resources diff (-want, +got): %v", tc.name, diff)
+			}
+
+			var gotEvents []string
+			for _, e := range sink.events {
+				gotEvents = append(gotEvents, fmt.Sprintf("%s %s %s", e.Type, e.Resource, e.Outcome))
+			}
+			if diff := cmp.Diff(tc.wantEvents, gotEvents); diff != "" {
+				t.Errorf("Process(%+v) got audit events diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestRestorePolicy_Modified(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	c := &fakeEtagIAMClient{
+		policies: map[string]*iampb.Policy{
+			"projects/a": {Etag: []byte("modified")},
+		},
+	}
+	h, err := NewIAMHandler(ctx, c, c, c,
+		WithRetry(retry.WithMaxRetries(3, retry.NewConstant(time.Millisecond))))
+	if err != nil {
+		t.Fatalf("failed to create IAMHandler: %v", err)
+	}
+
+	// The policy is not restored, and the conflict is not retried.
+	err = h.restorePolicy(ctx, "projects/a", &iampb.Policy{}, []byte("updated"))
+	if diff := testutil.DiffErrString(err, "failed to set IAM policy due to policy modification since the update"); diff != "" {
+		t.Error(diff)
+	}
+	if got, want := len(c.sets), 1; got != want {
+		t.Errorf("got %d SetIamPolicy calls, want %d", got, want)
+	}
+}
+
+// fakeEtagIAMClient is an in-memory IAMClient which changes the etag of the
+// policies when they are set, and fails SetIamPolicy calls with stale etags.
+type fakeEtagIAMClient struct {
+	mu       sync.Mutex
+	policies map[string]*iampb.Policy
+	version  int
+
+	// setErrs are the errors of the SetIamPolicy calls of each resource, in
+	// order, nil errors do not fail the calls.
+	setErrs map[string][]error
+	sets    []string
+}
+
+func (c *fakeEtagIAMClient) GetIamPolicy(ctx context.Context, r *iampb.GetIamPolicyRequest, _ ...gax.CallOption) (*iampb.Policy, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.policies[r.GetResource()]; ok {
+		return proto.Clone(p).(*iampb.Policy), nil //nolint:forcetypeassert // Cloned from the same type.
+	}
+	return &iampb.Policy{}, nil
+}
+
+func (c *fakeEtagIAMClient) SetIamPolicy(ctx context.Context, r *iampb.SetIamPolicyRequest, _ ...gax.CallOption) (*iampb.Policy, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sets = append(c.sets, r.GetResource())
+	if errs := c.setErrs[r.GetResource()]; len(errs) > 0 {
+		c.setErrs[r.GetResource()] = errs[1:]
+		if errs[0] != nil {
+			return nil, errs[0]
+		}
+	}
+	if string(r.GetPolicy().GetEtag()) != string(c.policies[r.GetResource()].GetEtag()) {
+		return nil, status.Error(codes.Aborted, "There were concurrent policy changes")
+	}
+	c.version++
+	p := proto.Clone(r.GetPolicy()).(*iampb.Policy) //nolint:forcetypeassert // Cloned from the same type.
+	p.Etag = []byte(strconv.Itoa(c.version))
+	c.policies[r.GetResource()] = p
+	return proto.Clone(p).(*iampb.Policy), nil //nolint:forcetypeassert // Cloned from the same type.
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// StateActive is the lifecycle state of active resources.
+const StateActive = "ACTIVE"
+
+// StateChecker is the interface to get the lifecycle states of GCP resources,
+// such as "ACTIVE" or "DELETE_REQUESTED".
+type StateChecker interface {
+	State(ctx context.Context, resource string) (string, error)
+}
+
+// InactiveResourceError is the error of handling a resource that is not
+// active, such as a project pending deletion.
+type InactiveResourceError struct {
+	// Resource that is not active.
+	Resource string
+
+	// State of the resource, e.g. "DELETE_REQUESTED".
+	State string
+}
+
+func (e *InactiveResourceError) Error() string {
+	return fmt.Sprintf("resource %s is not active (state: %s)", e.Resource, e.State)
+}
+
+// inactiveOnce returns a func that checks the state of the resource on its
+// first call, and returns an InactiveResourceError if the resource is not
+// active. The state is only checked once so that retries of other failures do
+// not check it again.
+func (h *IAMHandler) inactiveOnce(resource string) func(context.Context) error {
+	var checked bool
+	return func(ctx context.Context) error {
+		if checked || h.stateChecker == nil {
+			return nil
+		}
+		checked = true
+
+		state, err := h.stateChecker.State(ctx, resource)
+		if err != nil {
+			// The state is only checked to explain other failures, so the
+			// failure to check it is logged and the other failures are retried.
+			logging.FromContext(ctx).WarnContext(ctx, "failed to check resource state",
+				"resource", resource,
+				"error", err)
+			return nil
+		}
+		if state != StateActive {
+			return &InactiveResourceError{Resource: resource, State: state}
+		}
+		return nil
+	}
+}
+
+// skippedResponse returns the response of the resource skipped as it is not
+// active.
+func skippedResponse(e *InactiveResourceError) *v1alpha1.IAMResponse {
+	return &v1alpha1.IAMResponse{Resource: e.Resource, Skipped: e.Error()}
+}
@@ -0,0 +1,171 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/sethvargo/go-retry"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/progress"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestInactiveResources(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		sweep         bool
+		server        *fakeServer
+		state         string
+		stateErr      error
+		wantCalls     int
+		wantResp      []*v1alpha1.IAMResponse
+		wantEvents    []string
+		wantErrSubstr string
+	}{
+		{
+			name:          "get_policy_inactive",
+			server:        &fakeServer{getIAMPolicyErr: status.Error(codes.PermissionDenied, "injected error")},
+			state:         "DELETE_REQUESTED",
+			wantCalls:     1,
+			wantEvents:    []string{progress.EventTypeStarted, progress.EventTypeSkipped},
+			wantErrSubstr: "resource projects/foo is not active (state: DELETE_REQUESTED)",
+		},
+		{
+			name:          "set_policy_inactive",
+			server:        &fakeServer{policy: &iampb.Policy{}, setIAMPolicyErr: status.Error(codes.FailedPrecondition, "injected error")},
+			state:         "DELETE_REQUESTED",
+			wantCalls:     1,
+			wantEvents:    []string{progress.EventTypeStarted, progress.EventTypeSkipped},
+			wantErrSubstr: "resource projects/foo is not active (state: DELETE_REQUESTED)",
+		},
+		{
+			name:          "get_policy_active",
+			server:        &fakeServer{getIAMPolicyErr: status.Error(codes.Internal, "injected error")},
+			state:         StateActive,
+			wantCalls:     1,
+			wantEvents:    []string{progress.EventTypeStarted, progress.EventTypeRetry, progress.EventTypeRetry, progress.EventTypeFailed},
+			wantErrSubstr: "failed to get IAM policy",
+		},
+		{
+			name:          "state_failure",
+			server:        &fakeServer{getIAMPolicyErr: status.Error(codes.Internal, "injected error")},
+			stateErr:      fmt.Errorf("injected error"),
+			wantCalls:     1,
+			wantEvents:    []string{progress.EventTypeStarted, progress.EventTypeRetry, progress.EventTypeRetry, progress.EventTypeFailed},
+			wantErrSubstr: "failed to get IAM policy",
+		},
+		{
+			name:       "success_not_checked",
+			server:     &fakeServer{policy: &iampb.Policy{}},
+			wantEvents: []string{progress.EventTypeStarted, progress.EventTypeCompleted},
+		},
+		{
+			name:      "sweep_inactive",
+			sweep:     true,
+			server:    &fakeServer{getIAMPolicyErr: status.Error(codes.PermissionDenied, "injected error")},
+			state:     "DELETE_REQUESTED",
+			wantCalls: 1,
+			wantResp: []*v1alpha1.IAMResponse{{
+				Resource: "projects/foo",
+				Skipped:  "resource projects/foo is not active (state: DELETE_REQUESTED)",
+			}},
+			wantEvents: []string{progress.EventTypeSkipped},
+		},
+		{
+			name:          "sweep_active",
+			sweep:         true,
+			server:        &fakeServer{getIAMPolicyErr: status.Error(codes.Internal, "injected error")},
+			state:         StateActive,
+			wantCalls:     1,
+			wantErrSubstr: "failed to handle policy sweep for resource projects/foo",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			o, f, p := setupFakeClients(t, ctx, &fakeServer{policy: &iampb.Policy{}}, &fakeServer{policy: &iampb.Policy{}}, tc.server)
+
+			checker := &fakeStateChecker{state: tc.state, injectErr: tc.stateErr}
+			reporter := &fakeProgressReporter{}
+			h, err := NewIAMHandler(ctx, o, f, p,
+				WithRetry(retry.WithMaxRetries(2, retry.NewConstant(time.Millisecond))),
+				WithStateChecker(checker),
+				WithProgressReporter(reporter),
+			)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			var gotResp []*v1alpha1.IAMResponse
+			var gotErr error
+			if tc.sweep {
+				gotResp, gotErr = h.Sweep(ctx, []string{"projects/foo"})
+			} else {
+				_, gotErr = h.Do(ctx, &v1alpha1.IAMRequestWrapper{
+					IAMRequest: &v1alpha1.IAMRequest{
+						ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+							Resource: "projects/foo",
+							Bindings: []*v1alpha1.Binding{{Members: []string{"user:alice@example.com"}, Role: "roles/viewer"}},
+						}},
+					},
+					Duration:  time.Hour,
+					StartTime: time.Now(),
+				})
+			}
+			if diff := testutil.DiffErrString(gotErr, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+			if got, want := checker.calls, tc.wantCalls; got != want {
+				t.Errorf("Process(%+v) got %d state checks, want %d", tc.name, got, want)
+			}
+			if diff := cmp.Diff(tc.wantResp, gotResp, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Process(%+v) got responses diff (-want, +got): %v", tc.name, diff)
+			}
+			gotEvents := make([]string, 0, len(reporter.events))
+			for _, e := range reporter.events {
+				gotEvents = append(gotEvents, e.Type)
+			}
+			if diff := cmp.Diff(tc.wantEvents, gotEvents, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Process(%+v) got progress events diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+type fakeStateChecker struct {
+	state     string
+	injectErr error
+	calls     int
+}
+
+func (c *fakeStateChecker) State(ctx context.Context, resource string) (string, error) {
+	c.calls++
+	return c.state, c.injectErr
+}
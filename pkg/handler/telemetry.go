@@ -0,0 +1,175 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/googleapis/gax-go/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/iamdelta"
+	"github.com/abcxyz/access-on-demand/pkg/resource"
+)
+
+// instrumentationName is the name of the OpenTelemetry tracer and meter of the
+// handlers.
+const instrumentationName = "github.com/abcxyz/access-on-demand/pkg/handler"
+
+// Attribute keys of the spans and metrics.
+const (
+	attrOperation    = attribute.Key("aod.operation")
+	attrResource     = attribute.Key("aod.resource")
+	attrResourceType = attribute.Key("aod.resource_type")
+	attrOutcome      = attribute.Key("aod.outcome")
+	attrTool         = attribute.Key("aod.tool")
+)
+
+// Values of attrOutcome.
+const (
+	outcomeSuccess = "success"
+	outcomeFailure = "failure"
+)
+
+// telemetry is the OpenTelemetry tracer and metric instruments of the handlers.
+type telemetry struct {
+	tracer trace.Tracer
+
+	bindingsAdded    metric.Int64Counter
+	bindingsCleaned  metric.Int64Counter
+	retries          metric.Int64Counter
+	failures         metric.Int64Counter
+	resourceDuration metric.Float64Histogram
+	toolCommands     metric.Int64Counter
+}
+
+// newTelemetry creates the telemetry with the providers, the global providers
+// are used if they are nil. Failures of creating metric instruments are
+// reported to the global OpenTelemetry error handler, and the failed
+// instruments are no-op.
+func newTelemetry(tp trace.TracerProvider, mp metric.MeterProvider) *telemetry {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	if mp == nil {
+		mp = otel.GetMeterProvider()
+	}
+	meter := mp.Meter(instrumentationName)
+
+	t := &telemetry{tracer: tp.Tracer(instrumentationName)}
+	var err error
+	if t.bindingsAdded, err = meter.Int64Counter("aod.iam.bindings.added",
+		metric.WithUnit("{binding}"),
+		metric.WithDescription("The number of member and role pairs granted by AOD.")); err != nil {
+		otel.Handle(err)
+	}
+	if t.bindingsCleaned, err = meter.Int64Counter("aod.iam.bindings.cleaned",
+		metric.WithUnit("{binding}"),
+		metric.WithDescription("The number of expired AOD IAM bindings removed.")); err != nil {
+		otel.Handle(err)
+	}
+	if t.retries, err = meter.Int64Counter("aod.iam.retries",
+		metric.WithUnit("{retry}"),
+		metric.WithDescription("The number of retries of updating IAM policies.")); err != nil {
+		otel.Handle(err)
+	}
+	if t.failures, err = meter.Int64Counter("aod.iam.failures",
+		metric.WithUnit("{resource}"),
+		metric.WithDescription("The number of resources failed to be handled.")); err != nil {
+		otel.Handle(err)
+	}
+	if t.resourceDuration, err = meter.Float64Histogram("aod.iam.resource.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("The duration of handling the IAM policy of a resource.")); err != nil {
+		otel.Handle(err)
+	}
+	if t.toolCommands, err = meter.Int64Counter("aod.tool.commands",
+		metric.WithUnit("{command}"),
+		metric.WithDescription("The number of tool commands run.")); err != nil {
+		otel.Handle(err)
+	}
+	return t
+}
+
+// recordBindingsAdded records the member and role pairs of the resource policy
+// as granted.
+func (h *IAMHandler) recordBindingsAdded(ctx context.Context, p *v1alpha1.ResourcePolicy) {
+	var n int
+	for _, b := range p.Bindings {
+		n += len(b.Roles()) * len(b.Members)
+	}
+	h.telemetry.bindingsAdded.Add(ctx, int64(n),
+		metric.WithAttributes(attrResourceType.String(resourceType(p.Resource))))
+}
+
+// endSpan ends the span, with error status if err is not nil.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// resourceType returns the type of the resource, such as "projects".
+func resourceType(name string) string {
+	return string(resource.TypeOf(name))
+}
+
+// tracedIAMClient is an IAMClient with a span for each API call.
+type tracedIAMClient struct {
+	IAMClient
+	tracer trace.Tracer
+}
+
+// GetIamPolicy gets the IAM policy in a span.
+func (c *tracedIAMClient) GetIamPolicy(ctx context.Context, req *iampb.GetIamPolicyRequest, opts ...gax.CallOption) (_ *iampb.Policy, retErr error) {
+	ctx, span := c.tracer.Start(ctx, "GetIamPolicy",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrResource.String(req.GetResource())))
+	defer func() { endSpan(span, retErr) }()
+	return c.IAMClient.GetIamPolicy(ctx, req, opts...) //nolint:wrapcheck // Want passthrough
+}
+
+// SetIamPolicy sets the IAM policy in a span.
+func (c *tracedIAMClient) SetIamPolicy(ctx context.Context, req *iampb.SetIamPolicyRequest, opts ...gax.CallOption) (_ *iampb.Policy, retErr error) {
+	ctx, span := c.tracer.Start(ctx, "SetIamPolicy",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrResource.String(req.GetResource())))
+	defer func() { endSpan(span, retErr) }()
+	return c.IAMClient.SetIamPolicy(ctx, req, opts...) //nolint:wrapcheck // Want passthrough
+}
+
+// tracedPolicyPatcher is a tracedIAMClient of a PolicyPatcher, with a span for
+// each patch too.
+type tracedPolicyPatcher struct {
+	*tracedIAMClient
+	patcher PolicyPatcher
+}
+
+// PatchIamPolicy patches the IAM policy in a span.
+func (c *tracedPolicyPatcher) PatchIamPolicy(ctx context.Context, resource string, d *iamdelta.Delta) (_ *iampb.Policy, retErr error) {
+	ctx, span := c.tracer.Start(ctx, "PatchIamPolicy",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrResource.String(resource)))
+	defer func() { endSpan(span, retErr) }()
+	return c.patcher.PatchIamPolicy(ctx, resource, d) //nolint:wrapcheck // Want passthrough
+}
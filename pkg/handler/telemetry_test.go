@@ -0,0 +1,198 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sethvargo/go-retry"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/genproto/googleapis/type/expr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+)
+
+func TestIAMHandlerTelemetry(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+
+	expiredBinding := &iampb.Binding{
+		Members: []string{"user:expired@example.com"},
+		Role:    "roles/viewer",
+		Condition: &expr.Expr{
+			Title:      DefaultConditionTitle,
+			Expression: fmt.Sprintf("request.time < timestamp('%s')", now.Add(-time.Hour).Format(time.RFC3339)),
+		},
+	}
+	fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+		t,
+		ctx,
+		&fakeServer{policy: &iampb.Policy{}},
+		&fakeServer{
+			policy: &iampb.Policy{Bindings: []*iampb.Binding{expiredBinding}},
+			// A concurrent write causes a retry.
+			concurrentWrites: []*iampb.Policy{{Bindings: []*iampb.Binding{expiredBinding}, Etag: []byte("etag")}},
+		},
+		&fakeServer{policy: &iampb.Policy{}, setIAMPolicyErr: status.Error(codes.PermissionDenied, "injected error")},
+	)
+
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	h, err := NewIAMHandler(
+		ctx,
+		fakeOrganizationsClient,
+		fakeFoldersClient,
+		fakeProjectsClient,
+		WithRetry(retry.WithMaxRetries(1, retry.NewConstant(time.Millisecond))),
+		WithNowFunc(func() time.Time { return now }),
+		WithTelemetry(tp, mp),
+	)
+	if err != nil {
+		t.Fatalf("failed to create IAMHandler: %v", err)
+	}
+
+	if _, err := h.Do(ctx, &v1alpha1.IAMRequestWrapper{
+		IAMRequest: &v1alpha1.IAMRequest{
+			ResourcePolicies: []*v1alpha1.ResourcePolicy{
+				{
+					Resource: "folders/bar",
+					Bindings: []*v1alpha1.Binding{
+						{
+							Members: []string{"user:alice@example.com", "user:bob@example.com"},
+							Role:    "roles/cloudkms.cryptoOperator",
+						},
+					},
+				},
+				{
+					Resource: "projects/baz",
+					Bindings: []*v1alpha1.Binding{
+						{
+							Members: []string{"user:alice@example.com"},
+							Role:    "roles/bigquery.dataViewer",
+						},
+					},
+				},
+			},
+		},
+		Duration:  time.Hour,
+		StartTime: now,
+	}); err == nil {
+		t.Fatal("got nil error, want error")
+	}
+
+	gotSpans := make(map[string]int)
+	for _, s := range spans.Ended() {
+		gotSpans[s.Name()]++
+	}
+	wantSpans := map[string]int{
+		"IAMHandler.grant":          1,
+		"IAMHandler.handleResource": 2,
+		// One attempt for each resource and a retry for folders/bar.
+		"GetIamPolicy": 3,
+		"SetIamPolicy": 3,
+	}
+	if diff := cmp.Diff(wantSpans, gotSpans); diff != "" {
+		t.Errorf("got spans diff (-want, +got):\n%s", diff)
+	}
+
+	wantMetrics := map[string]int64{
+		"aod.iam.bindings.added":    2,
+		"aod.iam.bindings.cleaned":  1,
+		"aod.iam.retries":           1,
+		"aod.iam.failures":          1,
+		"aod.iam.resource.duration": 2,
+	}
+	if diff := cmp.Diff(wantMetrics, collectMetrics(t, reader)); diff != "" {
+		t.Errorf("got metrics diff (-want, +got):\n%s", diff)
+	}
+}
+
+func TestToolHandlerTelemetry(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	h := NewToolHandler(ctx, WithToolTelemetry(tp, mp))
+	if err := h.Do(ctx, &v1alpha1.ToolRequest{
+		Tool: "echo",
+		Do:   []string{"foo", "bar"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	gotSpans := make(map[string]int)
+	for _, s := range spans.Ended() {
+		gotSpans[s.Name()]++
+	}
+	wantSpans := map[string]int{
+		"ToolHandler.Do":         1,
+		"ToolHandler.runCommand": 2,
+	}
+	if diff := cmp.Diff(wantSpans, gotSpans); diff != "" {
+		t.Errorf("got spans diff (-want, +got):\n%s", diff)
+	}
+
+	wantMetrics := map[string]int64{"aod.tool.commands": 2}
+	if diff := cmp.Diff(wantMetrics, collectMetrics(t, reader)); diff != "" {
+		t.Errorf("got metrics diff (-want, +got):\n%s", diff)
+	}
+}
+
+// collectMetrics returns the sum of the counters and the count of the
+// histograms by name.
+func collectMetrics(tb testing.TB, reader sdkmetric.Reader) map[string]int64 {
+	tb.Helper()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		tb.Fatal(err)
+	}
+	got := make(map[string]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch d := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, p := range d.DataPoints {
+					got[m.Name] += p.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, p := range d.DataPoints {
+					got[m.Name] += int64(p.Count)
+				}
+			}
+		}
+	}
+	return got
+}
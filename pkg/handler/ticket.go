@@ -0,0 +1,59 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/abcxyz/access-on-demand/pkg/ticket"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// openPermissionDeniedTicket opens a ticket describing the missing permissions
+// with the ticket creator if the error is permission denied. Failures are
+// logged and ignored.
+func (h *IAMHandler) openPermissionDeniedTicket(ctx context.Context, resource string, err error) {
+	if h.ticketCreator == nil || !isPermissionDenied(err) {
+		return
+	}
+
+	logger := logging.FromContext(ctx)
+	url, err := h.ticketCreator.Create(ctx, ticket.NewPermissionDenied(resource, h.caller, err))
+	if err != nil {
+		logger.WarnContext(ctx, "failed to open permission denied ticket",
+			"resource", resource,
+			"error", err)
+		return
+	}
+	logger.WarnContext(ctx, "opened permission denied ticket",
+		"resource", resource,
+		"url", url)
+}
+
+// isPermissionDenied returns whether the error is a permission denied error of
+// gRPC or REST clients.
+func isPermissionDenied(err error) bool {
+	if status.Code(err) == codes.PermissionDenied {
+		return true
+	}
+	var gErr *googleapi.Error
+	return errors.As(err, &gErr) && gErr.Code == http.StatusForbidden
+}
@@ -0,0 +1,137 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sethvargo/go-retry"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/ticket"
+)
+
+func TestPermissionDeniedTicket(t *testing.T) {
+	t.Parallel()
+
+	caller := "serviceAccount:aod@test-project.iam.gserviceaccount.com"
+	deniedErr := status.Error(codes.PermissionDenied, "injected error")
+
+	cases := []struct {
+		name        string
+		setErr      error
+		creatorErr  error
+		wantTickets []*ticket.Ticket
+	}{
+		{
+			name:   "permission_denied",
+			setErr: deniedErr,
+			wantTickets: []*ticket.Ticket{
+				ticket.NewPermissionDenied("projects/baz", caller,
+					fmt.Errorf("failed to handle IAM request: failed to set IAM policy: %w", deniedErr)),
+			},
+		},
+		{
+			name:       "creator_failure",
+			setErr:     deniedErr,
+			creatorErr: fmt.Errorf("injected error"),
+			wantTickets: []*ticket.Ticket{
+				ticket.NewPermissionDenied("projects/baz", caller,
+					fmt.Errorf("failed to handle IAM request: failed to set IAM policy: %w", deniedErr)),
+			},
+		},
+		{
+			name:   "other_error",
+			setErr: status.Error(codes.InvalidArgument, "injected error"),
+		},
+		{
+			name: "success",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}, setIAMPolicyErr: tc.setErr},
+			)
+
+			creator := &fakeTicketCreator{injectErr: tc.creatorErr}
+			h, err := NewIAMHandler(
+				ctx,
+				fakeOrganizationsClient,
+				fakeFoldersClient,
+				fakeProjectsClient,
+				WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+				WithCaller(caller),
+				WithTicketCreator(creator),
+			)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			_, gotErr := h.Cleanup(ctx, &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{
+					{
+						Resource: "projects/baz",
+						Bindings: []*v1alpha1.Binding{
+							{
+								Members: []string{"user:test-project-user@example.com"},
+								Role:    "roles/bigquery.dataViewer",
+							},
+						},
+					},
+				},
+			})
+			if (gotErr != nil) != (tc.setErr != nil) {
+				t.Errorf("Process(%+v) got error %v, want error %t", tc.name, gotErr, tc.setErr != nil)
+			}
+			if diff := cmp.Diff(tc.wantTickets, creator.tickets); diff != "" {
+				t.Errorf("Process(%+v) got tickets diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+type fakeTicketCreator struct {
+	injectErr error
+
+	mu      sync.Mutex
+	tickets []*ticket.Ticket
+}
+
+func (c *fakeTicketCreator) Create(_ context.Context, t *ticket.Ticket) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tickets = append(c.tickets, t)
+	if c.injectErr != nil {
+		return "", c.injectErr
+	}
+	return "https://github.com/foo/bar/issues/1", nil
+}
@@ -24,6 +24,8 @@ import (
 	"strings"
 
 	"github.com/mattn/go-shellwords"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
 )
@@ -32,6 +34,10 @@ import (
 type ToolHandler struct {
 	// By default, stdout discards the command outputs, stderr is os.Stderr.
 	stdout, stderr io.Writer
+	// Optional OpenTelemetry providers, default is the global providers.
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	telemetry      *telemetry
 }
 
 // ToolHandlerOption is the option to set up an ToolHandler.
@@ -63,6 +69,17 @@ func WithDefaultStdout() ToolHandlerOption {
 	}
 }
 
+// WithToolTelemetry provides the OpenTelemetry providers to create spans and
+// metrics with, instead of the global providers. Either of them can be nil to
+// use the global provider.
+func WithToolTelemetry(tp trace.TracerProvider, mp metric.MeterProvider) ToolHandlerOption {
+	return func(h *ToolHandler) *ToolHandler {
+		h.tracerProvider = tp
+		h.meterProvider = mp
+		return h
+	}
+}
+
 // NewToolHandler creates a new ToolHandler with provided options.
 func NewToolHandler(ctx context.Context, opts ...ToolHandlerOption) *ToolHandler {
 	// Set default stderr.
@@ -70,26 +87,32 @@ func NewToolHandler(ctx context.Context, opts ...ToolHandlerOption) *ToolHandler
 	for _, opt := range opts {
 		h = opt(h)
 	}
+	h.telemetry = newTelemetry(h.tracerProvider, h.meterProvider)
 	return h
 }
 
 // Do runs the do commands.
-func (h *ToolHandler) Do(ctx context.Context, r *v1alpha1.ToolRequest) error {
+func (h *ToolHandler) Do(ctx context.Context, r *v1alpha1.ToolRequest) (retErr error) {
 	tool := r.Tool
+	ctx, span := h.telemetry.tracer.Start(ctx, "ToolHandler.Do",
+		trace.WithAttributes(attrTool.String(tool)))
+	defer func() { endSpan(span, retErr) }()
+
 	for i, c := range r.Do {
 		args, err := shellwords.Parse(c)
 		if err != nil {
 			return fmt.Errorf("failed to parse cmd %q: %w", c, err)
 		}
 		toolCmd := fmt.Sprintf("%s %s", tool, strings.Join(args, " "))
-		cmd := exec.Command(tool, args...)
+		// The command is killed if the context is done, e.g. on timeout.
+		cmd := exec.CommandContext(ctx, tool, args...)
 		// If stdout is set, it writes the command output to stdout.
 		if h.stdout != nil {
 			cmd.Stdout = h.stdout
 			fmt.Fprint(cmd.Stdout, toolCmd, "\n")
 		}
 		cmd.Stderr = h.stderr
-		if err := cmd.Run(); err != nil {
+		if err := h.runCommand(ctx, tool, cmd); err != nil {
 			return fmt.Errorf("failed to run command %q, error %w", toolCmd, err)
 		}
 		// Empty line in between commands.
@@ -99,3 +122,21 @@ func (h *ToolHandler) Do(ctx context.Context, r *v1alpha1.ToolRequest) error {
 	}
 	return nil
 }
+
+// runCommand runs the command of the tool in a span, and records the command
+// metric. The command arguments are not recorded as they may contain sensitive
+// information.
+func (h *ToolHandler) runCommand(ctx context.Context, tool string, cmd *exec.Cmd) (retErr error) {
+	ctx, span := h.telemetry.tracer.Start(ctx, "ToolHandler.runCommand",
+		trace.WithAttributes(attrTool.String(tool)))
+	defer func() { endSpan(span, retErr) }()
+
+	err := cmd.Run()
+	outcome := outcomeSuccess
+	if err != nil {
+		outcome = outcomeFailure
+	}
+	h.telemetry.toolCommands.Add(ctx, 1,
+		metric.WithAttributes(attrTool.String(tool), attrOutcome.String(outcome)))
+	return err //nolint:wrapcheck // Want passthrough
+}
@@ -0,0 +1,87 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+
+	"github.com/abcxyz/access-on-demand/pkg/iamdelta"
+	"github.com/abcxyz/access-on-demand/pkg/resource"
+)
+
+// PolicyPatcher is implemented by the IAMClients of resources whose IAM
+// policies can be updated with only the changes to their bindings, applied to
+// the latest policies, instead of setting the whole policies read before the
+// changes. It shortens the window for concurrent modifications to conflict, and
+// keeps the rest of the policies as is.
+type PolicyPatcher interface {
+	PatchIamPolicy(ctx context.Context, resource string, d *iamdelta.Delta) (*iampb.Policy, error)
+}
+
+// WithBigQueryClient provides the IAMClient of BigQuery datasets and tables,
+// which is required to handle resources such as
+// "bigquery/datasets/<project>/<dataset>".
+func WithBigQueryClient(c IAMClient) Option {
+	return withTypeClient(c, resource.TypeBigQueryDataset, resource.TypeBigQueryTable)
+}
+
+// WithStorageClient provides the IAMClient of Cloud Storage buckets, which is
+// required to handle resources such as "buckets/<bucket>".
+func WithStorageClient(c IAMClient) Option {
+	return withTypeClient(c, resource.TypeBucket)
+}
+
+// WithSecretManagerClient provides the IAMClient of Secret Manager secrets,
+// which is required to handle resources such as "secrets/<project>/<secret>".
+func WithSecretManagerClient(c IAMClient) Option {
+	return withTypeClient(c, resource.TypeSecret)
+}
+
+// WithKMSClient provides the IAMClient of Cloud KMS key rings and keys, which
+// is required to handle resources such as
+// "kms/keyrings/<project>/<location>/<key ring>/keys/<key>".
+func WithKMSClient(c IAMClient) Option {
+	return withTypeClient(c, resource.TypeKMSKeyRing, resource.TypeKMSKey)
+}
+
+// WithServiceAccountClient provides the IAMClient of service accounts, which is
+// required to handle resources such as
+// "serviceAccounts/<name>@<project>.iam.gserviceaccount.com".
+func WithServiceAccountClient(c IAMClient) Option {
+	return withTypeClient(c, resource.TypeServiceAccount)
+}
+
+// WithIAMPolicyClient provides the IAMClient of resources named by their full
+// resource names, such as "//pubsub.googleapis.com/projects/<project>/topics/<topic>",
+// whose services implement the standard IAMPolicy API. It is required to
+// handle such resources.
+func WithIAMPolicyClient(c IAMClient) Option {
+	return withTypeClient(c, resource.TypeFullName)
+}
+
+// withTypeClient provides the IAMClient of the resources of the types.
+func withTypeClient(c IAMClient, types ...resource.Type) Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		if p.typeClients == nil {
+			p.typeClients = make(map[resource.Type]IAMClient)
+		}
+		for _, typ := range types {
+			p.typeClients[typ] = c
+		}
+		return p, nil
+	}
+}
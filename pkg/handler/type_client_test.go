@@ -0,0 +1,358 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/gax-go/v2"
+	"github.com/sethvargo/go-retry"
+	"google.golang.org/genproto/googleapis/type/expr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/iamdelta"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestDoTypeClients(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name          string
+		resource      string
+		withClients   bool
+		wantBound     bool
+		wantErrSubstr string
+	}{
+		{
+			name:        "dataset",
+			resource:    "bigquery/datasets/foo/bar",
+			withClients: true,
+			wantBound:   true,
+		},
+		{
+			name:        "table",
+			resource:    "bigquery/datasets/foo/bar/tables/baz",
+			withClients: true,
+			wantBound:   true,
+		},
+		{
+			name:        "bucket",
+			resource:    "buckets/foo",
+			withClients: true,
+			wantBound:   true,
+		},
+		{
+			name:        "secret",
+			resource:    "secrets/foo/bar",
+			withClients: true,
+			wantBound:   true,
+		},
+		{
+			name:        "kms_key_ring",
+			resource:    "kms/keyrings/foo/global/bar",
+			withClients: true,
+			wantBound:   true,
+		},
+		{
+			name:        "kms_key",
+			resource:    "kms/keyrings/foo/global/bar/keys/baz",
+			withClients: true,
+			wantBound:   true,
+		},
+		{
+			name:        "service_account",
+			resource:    "serviceAccounts/sa@foo.iam.gserviceaccount.com",
+			withClients: true,
+			wantBound:   true,
+		},
+		{
+			name:        "full_name",
+			resource:    "//pubsub.googleapis.com/projects/foo/topics/bar",
+			withClients: true,
+			wantBound:   true,
+		},
+		{
+			name:          "missing_bigquery_client",
+			resource:      "bigquery/datasets/foo/bar",
+			wantErrSubstr: `IAM client of bigquery/datasets is required to handle resource "bigquery/datasets/foo/bar"`,
+		},
+		{
+			name:          "missing_storage_client",
+			resource:      "buckets/foo",
+			wantErrSubstr: `IAM client of buckets is required to handle resource "buckets/foo"`,
+		},
+		{
+			name:          "missing_secret_manager_client",
+			resource:      "secrets/foo/bar",
+			wantErrSubstr: `IAM client of secrets is required to handle resource "secrets/foo/bar"`,
+		},
+		{
+			name:          "missing_kms_client",
+			resource:      "kms/keyrings/foo/global/bar/keys/baz",
+			wantErrSubstr: `IAM client of kms/keys is required to handle resource "kms/keyrings/foo/global/bar/keys/baz"`,
+		},
+		{
+			name:          "missing_service_account_client",
+			resource:      "serviceAccounts/sa@foo.iam.gserviceaccount.com",
+			wantErrSubstr: `IAM client of serviceAccounts is required to handle resource "serviceAccounts/sa@foo.iam.gserviceaccount.com"`,
+		},
+		{
+			name:          "missing_iam_policy_client",
+			resource:      "//pubsub.googleapis.com/projects/foo/topics/bar",
+			wantErrSubstr: `IAM client of full-names is required to handle resource "//pubsub.googleapis.com/projects/foo/topics/bar"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			projectServer := &fakeServer{policy: &iampb.Policy{}}
+			bigQueryServer := &fakeServer{policy: &iampb.Policy{}}
+			storageServer := &fakeServer{policy: &iampb.Policy{}}
+			secretServer := &fakeServer{policy: &iampb.Policy{}}
+			kmsServer := &fakeServer{policy: &iampb.Policy{}}
+			serviceAccountServer := &fakeServer{policy: &iampb.Policy{}}
+			fullNameServer := &fakeServer{policy: &iampb.Policy{}}
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+				projectServer,
+			)
+			_, _, fakeBigQueryClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+				bigQueryServer,
+			)
+			_, _, fakeStorageClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+				storageServer,
+			)
+			_, _, fakeSecretManagerClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+				secretServer,
+			)
+			_, _, fakeKMSClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+				kmsServer,
+			)
+			_, _, fakeServiceAccountClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+				serviceAccountServer,
+			)
+			_, _, fakeIAMPolicyClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+				fullNameServer,
+			)
+
+			opts := []Option{
+				WithRetry(retry.WithMaxRetries(0, retry.NewFibonacci(500*time.Millisecond))),
+				WithNowFunc(func() time.Time { return now }),
+			}
+			if tc.withClients {
+				opts = append(opts,
+					WithBigQueryClient(fakeBigQueryClient),
+					WithStorageClient(fakeStorageClient),
+					WithSecretManagerClient(fakeSecretManagerClient),
+					WithKMSClient(fakeKMSClient),
+					WithServiceAccountClient(fakeServiceAccountClient),
+					WithIAMPolicyClient(fakeIAMPolicyClient))
+			}
+			h, err := NewIAMHandler(ctx, fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient, opts...)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			_, gotErr := h.Do(ctx, &v1alpha1.IAMRequestWrapper{
+				IAMRequest: &v1alpha1.IAMRequest{
+					ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+						Resource: tc.resource,
+						Bindings: []*v1alpha1.Binding{{
+							Members: []string{"user:alice@example.com"},
+							Role:    "roles/viewer",
+						}},
+					}},
+				},
+				StartTime: now,
+				Duration:  time.Hour,
+			})
+			if diff := testutil.DiffErrString(gotErr, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+
+			typeServer := bigQueryServer
+			switch {
+			case strings.HasPrefix(tc.resource, "buckets/"):
+				typeServer = storageServer
+			case strings.HasPrefix(tc.resource, "secrets/"):
+				typeServer = secretServer
+			case strings.HasPrefix(tc.resource, "kms/"):
+				typeServer = kmsServer
+			case strings.HasPrefix(tc.resource, "serviceAccounts/"):
+				typeServer = serviceAccountServer
+			case strings.HasPrefix(tc.resource, "//"):
+				typeServer = fullNameServer
+			}
+			bound := slices.ContainsFunc(typeServer.policy.GetBindings(), func(b *iampb.Binding) bool {
+				return b.GetRole() == "roles/viewer" && b.GetCondition().GetTitle() == DefaultConditionTitle
+			})
+			if bound != tc.wantBound {
+				t.Errorf("Process(%+v) got binding %t, want %t", tc.name, bound, tc.wantBound)
+			}
+			if got := len(projectServer.policy.GetBindings()); got != 0 {
+				t.Errorf("Process(%+v) got %d project bindings, want none", tc.name, got)
+			}
+		})
+	}
+}
+
+// fakePatcher is a fake IAMClient and PolicyPatcher of a policy which is
+// modified concurrently after it is first read.
+type fakePatcher struct {
+	mu       sync.Mutex
+	policy   *iampb.Policy
+	modified bool
+	sets     int
+}
+
+func (f *fakePatcher) GetIamPolicy(_ context.Context, _ *iampb.GetIamPolicyRequest, _ ...gax.CallOption) (*iampb.Policy, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p := proto.Clone(f.policy).(*iampb.Policy) //nolint:forcetypeassert // Test fake.
+	if !f.modified {
+		f.modified = true
+		f.policy.Bindings = append(f.policy.Bindings, &iampb.Binding{Role: "roles/owner", Members: []string{"user:admin@example.com"}})
+		f.policy.Etag = []byte("etag2")
+	}
+	return p, nil
+}
+
+func (f *fakePatcher) SetIamPolicy(_ context.Context, req *iampb.SetIamPolicyRequest, _ ...gax.CallOption) (*iampb.Policy, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !bytes.Equal(req.GetPolicy().GetEtag(), f.policy.GetEtag()) {
+		return nil, status.Error(codes.Aborted, "etag mismatch")
+	}
+	f.sets++
+	f.policy = req.GetPolicy()
+	f.policy.Etag = append(f.policy.GetEtag(), '+')
+	return f.policy, nil
+}
+
+func (f *fakePatcher) PatchIamPolicy(ctx context.Context, resource string, d *iamdelta.Delta) (*iampb.Policy, error) {
+	return iamdelta.Patch(ctx, f, resource, d) //nolint:wrapcheck // Want passthrough
+}
+
+func TestDoPolicyPatcher(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+
+	fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+		t,
+		ctx,
+		&fakeServer{policy: &iampb.Policy{}},
+		&fakeServer{policy: &iampb.Policy{}},
+		&fakeServer{policy: &iampb.Policy{}},
+	)
+	patcher := &fakePatcher{policy: &iampb.Policy{
+		Etag:     []byte("etag1"),
+		Bindings: []*iampb.Binding{{Role: "roles/viewer", Members: []string{"user:bob@example.com"}}},
+	}}
+
+	h, err := NewIAMHandler(ctx, fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient,
+		WithRetry(retry.WithMaxRetries(3, retry.NewConstant(time.Millisecond))),
+		WithNowFunc(func() time.Time { return now }),
+		WithStorageClient(patcher))
+	if err != nil {
+		t.Fatalf("failed to create IAMHandler: %v", err)
+	}
+
+	if _, err := h.Do(ctx, &v1alpha1.IAMRequestWrapper{
+		IAMRequest: &v1alpha1.IAMRequest{
+			ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+				Resource: "buckets/foo",
+				Bindings: []*v1alpha1.Binding{{Members: []string{"user:alice@example.com"}, Role: "roles/storage.objectViewer"}},
+			}},
+		},
+		StartTime: now,
+		Duration:  time.Hour,
+	}); err != nil {
+		t.Fatalf("Do() got unexpected error: %v", err)
+	}
+
+	// The concurrent modification is kept without retrying the whole update.
+	want := &iampb.Policy{
+		Version: 3,
+		Etag:    []byte("etag2+"),
+		Bindings: []*iampb.Binding{
+			{Role: "roles/viewer", Members: []string{"user:bob@example.com"}},
+			{Role: "roles/owner", Members: []string{"user:admin@example.com"}},
+			{
+				Role:    "roles/storage.objectViewer",
+				Members: []string{"user:alice@example.com"},
+				Condition: &expr.Expr{
+					Title:      DefaultConditionTitle,
+					Expression: "request.time < timestamp('2009-11-11T00:00:00Z')",
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, patcher.policy, protocmp.Transform()); diff != "" {
+		t.Errorf("Do() got policy diff (-want, +got): %v", diff)
+	}
+	if got, want := patcher.sets, 1; got != want {
+		t.Errorf("Do() got %d sets, want %d", got, want)
+	}
+	if got, want := h.APICalls(), int64(3); got != want {
+		t.Errorf("Do() got %d API calls, want %d", got, want)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+)
+
+// UsageQuerier is the interface to query the activity of a member on a
+// resource between start and end, such as in Cloud Audit Logs.
+type UsageQuerier interface {
+	QueryUsage(ctx context.Context, resource, member string, start, end time.Time) (*audit.Usage, error)
+}
+
+// ResourceUsage is the activity of the members of a resource policy during a
+// grant.
+type ResourceUsage struct {
+	// Resource is the name of the GCP resource of the policy, such as
+	// "projects/foo".
+	Resource string `yaml:"resource"`
+
+	// Usages are the activities of the members of the resource policy.
+	Usages []*audit.Usage `yaml:"usages"`
+}
+
+// WithUsageQuerier provides the querier of the activity of members during
+// grants, which is required to verify usage.
+func WithUsageQuerier(q UsageQuerier) Option {
+	return func(p *IAMHandler) (*IAMHandler, error) {
+		p.usageQuerier = q
+		return p, nil
+	}
+}
+
+// VerifyUsage queries the activity of the members of the request during its
+// grant, from the start time to the expiry, and writes a "USAGE" audit event
+// of each member on each resource. The grant must have expired. Only "user"
+// and "serviceAccount" members are queried, since the activity is recorded
+// for individual principals.
+func (h *IAMHandler) VerifyUsage(ctx context.Context, r *v1alpha1.IAMRequestWrapper) ([]*ResourceUsage, error) {
+	if h.usageQuerier == nil {
+		return nil, fmt.Errorf("usage querier is required to verify usage")
+	}
+	expiry := r.StartTime.Add(r.Duration)
+	if now := h.now(); now.Before(expiry) {
+		return nil, fmt.Errorf("grant expires at %s, usage can only be verified after expiry",
+			expiry.UTC().Format(time.RFC3339))
+	}
+
+	var result []*ResourceUsage
+	var merr error
+	for _, p := range r.ResourcePolicies {
+		ru := &ResourceUsage{Resource: p.Resource}
+		for _, m := range usageMembers(p.Bindings) {
+			u, err := h.usageQuerier.QueryUsage(ctx, p.Resource, m, r.StartTime, expiry)
+			if err != nil {
+				err = fmt.Errorf("failed to query usage of %q on resource %s: %w", m, p.Resource, err)
+				merr = errors.Join(merr, err)
+			}
+
+			e := h.newAuditEvent(ctx, audit.EventTypeUsage, memberPolicy(p, m), r, err)
+			e.Usage = u
+			h.writeAuditSinks(ctx, e)
+
+			if u != nil {
+				ru.Usages = append(ru.Usages, u)
+			}
+		}
+		result = append(result, ru)
+	}
+	return result, merr
+}
+
+// usageMembers returns the sorted "user" and "serviceAccount" members of the
+// bindings.
+func usageMembers(bs []*v1alpha1.Binding) []string {
+	var result []string
+	for _, b := range bs {
+		for _, m := range b.Members {
+			if strings.HasPrefix(m, "user:") || strings.HasPrefix(m, "serviceAccount:") {
+				result = append(result, m)
+			}
+		}
+	}
+	slices.Sort(result)
+	return slices.Compact(result)
+}
+
+// memberPolicy returns the resource policy with the bindings of the member
+// only.
+func memberPolicy(p *v1alpha1.ResourcePolicy, member string) *v1alpha1.ResourcePolicy {
+	np := &v1alpha1.ResourcePolicy{Resource: p.Resource}
+	for _, b := range p.Bindings {
+		if !slices.Contains(b.Members, member) {
+			continue
+		}
+		nb := *b
+		nb.Members = []string{member}
+		np.Bindings = append(np.Bindings, &nb)
+	}
+	return np
+}
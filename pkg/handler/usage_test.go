@@ -0,0 +1,228 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestVerifyUsage(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2009, 11, 10, 21, 0, 0, 0, time.UTC)
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	expiry := start.Add(time.Hour)
+	usage := func(member string) *audit.Usage {
+		return &audit.Usage{
+			Member: member,
+			Start:  start,
+			End:    expiry,
+			Calls:  1,
+			Methods: []*audit.MethodUsage{
+				{Service: "storage.googleapis.com", Method: "storage.objects.get", Calls: 1},
+			},
+			Permissions: []string{"storage.objects.get"},
+		}
+	}
+	req := &v1alpha1.IAMRequestWrapper{
+		IAMRequest: &v1alpha1.IAMRequest{
+			ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+				Resource: "projects/baz",
+				Bindings: []*v1alpha1.Binding{
+					{
+						Members: []string{"user:alice@example.com", "group:eng@example.com"},
+						Role:    "roles/storage.objectViewer",
+					},
+					{
+						Members: []string{"user:alice@example.com", "user:bob@example.com"},
+						Role:    "roles/bigquery.dataViewer",
+					},
+				},
+			}},
+		},
+		StartTime: start,
+		Duration:  time.Hour,
+		Requester: "user:alice@example.com",
+	}
+
+	cases := []struct {
+		name       string
+		req        *v1alpha1.IAMRequestWrapper
+		querier    *fakeUsageQuerier
+		want       []*ResourceUsage
+		wantEvents []*audit.Event
+		wantErr    string
+	}{
+		{
+			name:    "success",
+			req:     req,
+			querier: &fakeUsageQuerier{},
+			want: []*ResourceUsage{{
+				Resource: "projects/baz",
+				Usages:   []*audit.Usage{usage("user:alice@example.com"), usage("user:bob@example.com")},
+			}},
+			wantEvents: []*audit.Event{
+				{
+					Type:     audit.EventTypeUsage,
+					Time:     now,
+					Resource: "projects/baz",
+					Bindings: []*audit.Binding{
+						{Role: "roles/storage.objectViewer", Members: []string{"user:alice@example.com"}},
+						{Role: "roles/bigquery.dataViewer", Members: []string{"user:alice@example.com"}},
+					},
+					Expiry:         &expiry,
+					ConditionTitle: DefaultConditionTitle,
+					Requester:      "user:alice@example.com",
+					Outcome:        audit.OutcomeSuccess,
+					Usage:          usage("user:alice@example.com"),
+				},
+				{
+					Type:     audit.EventTypeUsage,
+					Time:     now,
+					Resource: "projects/baz",
+					Bindings: []*audit.Binding{
+						{Role: "roles/bigquery.dataViewer", Members: []string{"user:bob@example.com"}},
+					},
+					Expiry:         &expiry,
+					ConditionTitle: DefaultConditionTitle,
+					Requester:      "user:alice@example.com",
+					Outcome:        audit.OutcomeSuccess,
+					Usage:          usage("user:bob@example.com"),
+				},
+			},
+		},
+		{
+			name: "query_failure",
+			req:  req,
+			querier: &fakeUsageQuerier{injectErrs: map[string]error{
+				"user:bob@example.com": fmt.Errorf("injected error"),
+			}},
+			want: []*ResourceUsage{{
+				Resource: "projects/baz",
+				Usages:   []*audit.Usage{usage("user:alice@example.com")},
+			}},
+			wantEvents: []*audit.Event{
+				{
+					Type:     audit.EventTypeUsage,
+					Time:     now,
+					Resource: "projects/baz",
+					Bindings: []*audit.Binding{
+						{Role: "roles/storage.objectViewer", Members: []string{"user:alice@example.com"}},
+						{Role: "roles/bigquery.dataViewer", Members: []string{"user:alice@example.com"}},
+					},
+					Expiry:         &expiry,
+					ConditionTitle: DefaultConditionTitle,
+					Requester:      "user:alice@example.com",
+					Outcome:        audit.OutcomeSuccess,
+					Usage:          usage("user:alice@example.com"),
+				},
+				{
+					Type:     audit.EventTypeUsage,
+					Time:     now,
+					Resource: "projects/baz",
+					Bindings: []*audit.Binding{
+						{Role: "roles/bigquery.dataViewer", Members: []string{"user:bob@example.com"}},
+					},
+					Expiry:         &expiry,
+					ConditionTitle: DefaultConditionTitle,
+					Requester:      "user:alice@example.com",
+					Outcome:        audit.OutcomeFailure,
+					Error:          `failed to query usage of "user:bob@example.com" on resource projects/baz: injected error`,
+				},
+			},
+			wantErr: `failed to query usage of "user:bob@example.com" on resource projects/baz: injected error`,
+		},
+		{
+			name: "not_expired",
+			req: &v1alpha1.IAMRequestWrapper{
+				IAMRequest: req.IAMRequest,
+				StartTime:  now,
+				Duration:   time.Hour,
+			},
+			querier: &fakeUsageQuerier{},
+			wantErr: "grant expires at 2009-11-11T00:00:00Z, usage can only be verified after expiry",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			fakeOrganizationsClient, fakeFoldersClient, fakeProjectsClient := setupFakeClients(
+				t,
+				ctx,
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+				&fakeServer{policy: &iampb.Policy{}},
+			)
+
+			sink := &fakeAuditSink{}
+			h, err := NewIAMHandler(
+				ctx,
+				fakeOrganizationsClient,
+				fakeFoldersClient,
+				fakeProjectsClient,
+				WithNowFunc(func() time.Time { return now }),
+				WithAuditSink(sink),
+				WithUsageQuerier(tc.querier),
+			)
+			if err != nil {
+				t.Fatalf("failed to create IAMHandler: %v", err)
+			}
+
+			got, gotErr := h.VerifyUsage(ctx, tc.req)
+			if diff := testutil.DiffErrString(gotErr, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Process(%+v) got usages diff (-want, +got): %v", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantEvents, sink.events); diff != "" {
+				t.Errorf("Process(%+v) got audit events diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+type fakeUsageQuerier struct {
+	injectErrs map[string]error
+}
+
+func (q *fakeUsageQuerier) QueryUsage(ctx context.Context, resource, member string, start, end time.Time) (*audit.Usage, error) {
+	if err := q.injectErrs[member]; err != nil {
+		return nil, err
+	}
+	return &audit.Usage{
+		Member: member,
+		Start:  start,
+		End:    end,
+		Calls:  1,
+		Methods: []*audit.MethodUsage{
+			{Service: "storage.googleapis.com", Method: "storage.objects.get", Calls: 1},
+		},
+		Permissions: []string{"storage.objects.get"},
+	}, nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hierarchy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Resolver finds the organizations of folders and projects with multiple
+// walkers, such as one per organization with its own credentials, so that
+// resources are found in organizations not all visible to one identity.
+type Resolver struct {
+	walkers []*Walker
+}
+
+// NewResolver creates a new Resolver trying the walkers in order.
+func NewResolver(walkers ...*Walker) *Resolver {
+	return &Resolver{walkers: walkers}
+}
+
+// Organization returns the organization the given folder or project is in,
+// with the first walker that can find it.
+func (r *Resolver) Organization(ctx context.Context, resource string) (string, error) {
+	var merr error
+	for _, w := range r.walkers {
+		org, err := w.Organization(ctx, resource)
+		if err == nil {
+			return org, nil
+		}
+		merr = errors.Join(merr, err)
+	}
+	if merr == nil {
+		return "", fmt.Errorf("no walkers to find the organization of %q", resource)
+	}
+	return "", merr
+}
+
+// State returns the lifecycle state of the given resource, with the first
+// walker that can find it.
+func (r *Resolver) State(ctx context.Context, resource string) (string, error) {
+	var merr error
+	for _, w := range r.walkers {
+		state, err := w.State(ctx, resource)
+		if err == nil {
+			return state, nil
+		}
+		merr = errors.Join(merr, err)
+	}
+	if merr == nil {
+		return "", fmt.Errorf("no walkers to find the state of %q", resource)
+	}
+	return "", merr
+}
@@ -0,0 +1,103 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hierarchy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestResolverOrganization(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		walkers  func(t *testing.T) []*Walker
+		resource string
+		expOrg   string
+		expErr   string
+	}{
+		{
+			name: "second_walker",
+			walkers: func(t *testing.T) []*Walker {
+				t.Helper()
+				return []*Walker{
+					newTestWalker(t, nil, map[string][]string{"organizations/1": {"projects/1000"}}),
+					newTestWalker(t, map[string][]string{"organizations/2": {"folders/20"}}, map[string][]string{"folders/20": {"projects/2000"}}),
+				}
+			},
+			resource: "projects/2000",
+			expOrg:   "organizations/2",
+		},
+		{
+			name: "not_found",
+			walkers: func(t *testing.T) []*Walker {
+				t.Helper()
+				return []*Walker{
+					newTestWalker(t, nil, map[string][]string{"organizations/1": {"projects/1000"}}),
+				}
+			},
+			resource: "projects/2000",
+			expErr:   `failed to get project "projects/2000"`,
+		},
+		{
+			name: "no_walkers",
+			walkers: func(t *testing.T) []*Walker {
+				t.Helper()
+				return nil
+			},
+			resource: "projects/2000",
+			expErr:   `no walkers to find the organization of "projects/2000"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := NewResolver(tc.walkers(t)...)
+			got, err := r.Organization(context.Background(), tc.resource)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Organization(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if got != tc.expOrg {
+				t.Errorf("Organization(%+v) got %q, want %q", tc.name, got, tc.expOrg)
+			}
+		})
+	}
+}
+
+func TestResolverState(t *testing.T) {
+	t.Parallel()
+
+	r := NewResolver(
+		newTestWalker(t, nil, map[string][]string{"organizations/1": {"projects/1000"}}),
+		newTestWalker(t, nil, map[string][]string{"organizations/2": {"projects/2000"}}, "projects/2000"),
+	)
+
+	got, err := r.State(context.Background(), "projects/2000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "DELETE_REQUESTED"; got != want {
+		t.Errorf("State() got %q, want %q", got, want)
+	}
+
+	if _, err := r.State(context.Background(), "projects/3000"); err == nil {
+		t.Errorf("State() got no error for unknown project")
+	}
+}
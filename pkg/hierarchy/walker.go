@@ -0,0 +1,203 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package hierarchy enumerates the GCP resource hierarchy.
+package hierarchy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	resourcemanager "cloud.google.com/go/resourcemanager/apiv3"
+	"cloud.google.com/go/resourcemanager/apiv3/resourcemanagerpb"
+	"google.golang.org/api/iterator"
+
+	"github.com/abcxyz/access-on-demand/pkg/resource"
+)
+
+// Walker enumerates the descendant folders and projects of GCP organizations
+// and folders with Cloud Resource Manager.
+type Walker struct {
+	foldersClient  *resourcemanager.FoldersClient
+	projectsClient *resourcemanager.ProjectsClient
+}
+
+// NewWalker creates a new Walker with the provided clients.
+func NewWalker(foldersClient *resourcemanager.FoldersClient, projectsClient *resourcemanager.ProjectsClient) *Walker {
+	return &Walker{
+		foldersClient:  foldersClient,
+		projectsClient: projectsClient,
+	}
+}
+
+// Descendants returns the given organization or folder and all its active
+// descendant folders and projects, in breadth-first order so that a folder
+// always comes before its children.
+func (w *Walker) Descendants(ctx context.Context, root string) ([]string, error) {
+	if !resource.HasType(root, resource.TypeOrganization, resource.TypeFolder) {
+		return nil, fmt.Errorf("resource %q isn't one of [organizations, folders]", root)
+	}
+
+	var result []string
+	queue := []string{root}
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+		result = append(result, parent)
+
+		folders, err := w.listFolders(ctx, parent)
+		if err != nil {
+			return nil, err
+		}
+		queue = append(queue, folders...)
+
+		projects, err := w.listProjects(ctx, parent)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, projects...)
+	}
+	return result, nil
+}
+
+// listFolders returns the names of the active folders directly under the parent.
+func (w *Walker) listFolders(ctx context.Context, parent string) ([]string, error) {
+	var names []string
+	it := w.foldersClient.ListFolders(ctx, &resourcemanagerpb.ListFoldersRequest{Parent: parent})
+	for {
+		f, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			return names, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list folders under %q: %w", parent, err)
+		}
+		names = append(names, f.GetName())
+	}
+}
+
+// listProjects returns the names of the active projects directly under the
+// parent.
+func (w *Walker) listProjects(ctx context.Context, parent string) ([]string, error) {
+	var names []string
+	it := w.projectsClient.ListProjects(ctx, &resourcemanagerpb.ListProjectsRequest{Parent: parent})
+	for {
+		p, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			return names, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list projects under %q: %w", parent, err)
+		}
+		names = append(names, p.GetName())
+	}
+}
+
+// maxDepth is the max number of ancestors of a folder or project, folders can
+// be nested up to 10 levels under an organization.
+const maxDepth = 12
+
+// Organization returns the organization the given folder or project is in, by
+// walking up its ancestors. The organization itself is returned as is.
+func (w *Walker) Organization(ctx context.Context, name string) (string, error) {
+	ancestor := name
+	for range maxDepth {
+		switch resource.TypeOf(ancestor) {
+		case resource.TypeOrganization:
+			return ancestor, nil
+		case resource.TypeFolder:
+			f, err := w.foldersClient.GetFolder(ctx, &resourcemanagerpb.GetFolderRequest{Name: ancestor})
+			if err != nil {
+				return "", fmt.Errorf("failed to get folder %q: %w", ancestor, err)
+			}
+			ancestor = f.GetParent()
+		case resource.TypeProject:
+			p, err := w.projectsClient.GetProject(ctx, &resourcemanagerpb.GetProjectRequest{Name: ancestor})
+			if err != nil {
+				return "", fmt.Errorf("failed to get project %q: %w", ancestor, err)
+			}
+			ancestor = p.GetParent()
+		default:
+			return "", fmt.Errorf("resource %q isn't in an organization", name)
+		}
+	}
+	return "", fmt.Errorf("resource %q has more than %d ancestors", name, maxDepth)
+}
+
+// State returns the lifecycle state of the given folder or project, such as
+// "ACTIVE" or "DELETE_REQUESTED". Organizations are always "ACTIVE" as they are
+// not expected to be deleted, and secrets, BigQuery resources, Cloud KMS
+// resources and resources with full resource names under projects have the
+// state of their projects, and so do service accounts created in projects.
+// The states of buckets and of service accounts managed by Google are unknown
+// as their names don't include their projects.
+func (w *Walker) State(ctx context.Context, name string) (string, error) {
+	rn, err := resource.Parse(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse resource: %w", err)
+	}
+	switch rn.Type {
+	case resource.TypeOrganization:
+		return resourcemanagerpb.Organization_ACTIVE.String(), nil
+	case resource.TypeFolder:
+		f, err := w.foldersClient.GetFolder(ctx, &resourcemanagerpb.GetFolderRequest{Name: name})
+		if err != nil {
+			return "", fmt.Errorf("failed to get folder %q: %w", name, err)
+		}
+		return f.GetState().String(), nil
+	case resource.TypeBigQueryDataset, resource.TypeBigQueryTable:
+		project, _, _ := rn.BigQuery()
+		return w.State(ctx, resource.New(resource.TypeProject, project).String())
+	case resource.TypeSecret:
+		project, _ := rn.Secret()
+		return w.State(ctx, resource.New(resource.TypeProject, project).String())
+	case resource.TypeKMSKeyRing, resource.TypeKMSKey:
+		project, _, _, _ := rn.KMS()
+		return w.State(ctx, resource.New(resource.TypeProject, project).String())
+	case resource.TypeServiceAccount:
+		project := rn.ServiceAccountProject()
+		if project == "" {
+			return "", fmt.Errorf("state of service account %q is unknown", name)
+		}
+		return w.State(ctx, resource.New(resource.TypeProject, project).String())
+	case resource.TypeBucket:
+		return "", fmt.Errorf("state of bucket %q is unknown", name)
+	case resource.TypeFullName:
+		_, relative := rn.FullName()
+		project, ok := projectOf(relative)
+		if !ok {
+			return "", fmt.Errorf("state of resource %q is unknown", name)
+		}
+		return w.State(ctx, resource.New(resource.TypeProject, project).String())
+	default:
+		p, err := w.projectsClient.GetProject(ctx, &resourcemanagerpb.GetProjectRequest{Name: name})
+		if err != nil {
+			return "", fmt.Errorf("failed to get project %q: %w", name, err)
+		}
+		return p.GetState().String(), nil
+	}
+}
+
+// projectOf returns the project of the relative resource name if it is under
+// a project, such as "foo" of "projects/foo/topics/bar".
+func projectOf(relative string) (string, bool) {
+	rest, ok := strings.CutPrefix(relative, "projects/")
+	if !ok {
+		return "", false
+	}
+	project, _, _ := strings.Cut(rest, "/")
+	return project, project != ""
+}
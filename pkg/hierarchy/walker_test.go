@@ -0,0 +1,412 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hierarchy
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	resourcemanager "cloud.google.com/go/resourcemanager/apiv3"
+	"cloud.google.com/go/resourcemanager/apiv3/resourcemanagerpb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestDescendants(t *testing.T) {
+	t.Parallel()
+
+	folders := map[string][]string{
+		"organizations/1": {"folders/10", "folders/11"},
+		"folders/10":      {"folders/100"},
+	}
+	projects := map[string][]string{
+		"organizations/1": {"projects/1000"},
+		"folders/10":      {"projects/1001", "projects/1002"},
+		"folders/100":     {"projects/1003"},
+	}
+
+	cases := []struct {
+		name            string
+		root            string
+		listFoldersErr  error
+		listProjectsErr error
+		expResources    []string
+		expErr          string
+	}{
+		{
+			name: "organization",
+			root: "organizations/1",
+			expResources: []string{
+				"organizations/1",
+				"projects/1000",
+				"folders/10",
+				"projects/1001",
+				"projects/1002",
+				"folders/11",
+				"folders/100",
+				"projects/1003",
+			},
+		},
+		{
+			name: "folder",
+			root: "folders/10",
+			expResources: []string{
+				"folders/10",
+				"projects/1001",
+				"projects/1002",
+				"folders/100",
+				"projects/1003",
+			},
+		},
+		{
+			name:         "empty_folder",
+			root:         "folders/11",
+			expResources: []string{"folders/11"},
+		},
+		{
+			name:   "project_root",
+			root:   "projects/1000",
+			expErr: `resource "projects/1000" isn't one of [organizations, folders]`,
+		},
+		{
+			name:           "list_folders_failure",
+			root:           "organizations/1",
+			listFoldersErr: status.Error(codes.PermissionDenied, "injected error"),
+			expErr:         `failed to list folders under "organizations/1"`,
+		},
+		{
+			name:            "list_projects_failure",
+			root:            "organizations/1",
+			listProjectsErr: status.Error(codes.PermissionDenied, "injected error"),
+			expErr:          `failed to list projects under "organizations/1"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			addr, conn := testutil.FakeGRPCServer(t, func(s *grpc.Server) {
+				resourcemanagerpb.RegisterFoldersServer(s, &fakeFoldersServer{
+					children: folders,
+					err:      tc.listFoldersErr,
+				})
+				resourcemanagerpb.RegisterProjectsServer(s, &fakeProjectsServer{
+					children: projects,
+					err:      tc.listProjectsErr,
+				})
+			})
+			t.Cleanup(func() {
+				conn.Close()
+			})
+			foldersClient, err := resourcemanager.NewFoldersClient(ctx, option.WithGRPCConn(conn))
+			if err != nil {
+				t.Fatalf("creating folders client for fake at %q: %v", addr, err)
+			}
+			projectsClient, err := resourcemanager.NewProjectsClient(ctx, option.WithGRPCConn(conn))
+			if err != nil {
+				t.Fatalf("creating projects client for fake at %q: %v", addr, err)
+			}
+
+			w := NewWalker(foldersClient, projectsClient)
+			got, err := w.Descendants(ctx, tc.root)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Descendants(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expResources, got); diff != "" {
+				t.Errorf("Descendants(%+v) got resources diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestOrganization(t *testing.T) {
+	t.Parallel()
+
+	folders := map[string][]string{
+		"organizations/1": {"folders/10"},
+		"folders/10":      {"folders/100"},
+	}
+	projects := map[string][]string{
+		"organizations/1": {"projects/1000"},
+		"folders/100":     {"projects/1003"},
+	}
+
+	cases := []struct {
+		name     string
+		resource string
+		expOrg   string
+		expErr   string
+	}{
+		{
+			name:     "organization",
+			resource: "organizations/1",
+			expOrg:   "organizations/1",
+		},
+		{
+			name:     "nested_project",
+			resource: "projects/1003",
+			expOrg:   "organizations/1",
+		},
+		{
+			name:     "project",
+			resource: "projects/1000",
+			expOrg:   "organizations/1",
+		},
+		{
+			name:     "folder",
+			resource: "folders/100",
+			expOrg:   "organizations/1",
+		},
+		{
+			name:     "unknown_project",
+			resource: "projects/9999",
+			expErr:   `failed to get project "projects/9999"`,
+		},
+		{
+			name:     "invalid_resource",
+			resource: "buckets/foo",
+			expErr:   `resource "buckets/foo" isn't in an organization`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			w := newTestWalker(t, folders, projects)
+
+			got, err := w.Organization(ctx, tc.resource)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Organization(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if got != tc.expOrg {
+				t.Errorf("Organization(%+v) got %q, want %q", tc.name, got, tc.expOrg)
+			}
+		})
+	}
+}
+
+func TestState(t *testing.T) {
+	t.Parallel()
+
+	folders := map[string][]string{
+		"organizations/1": {"folders/10", "folders/11"},
+	}
+	projects := map[string][]string{
+		"folders/10": {"projects/1001", "projects/1002"},
+	}
+
+	cases := []struct {
+		name     string
+		resource string
+		expState string
+		expErr   string
+	}{
+		{
+			name:     "organization",
+			resource: "organizations/1",
+			expState: "ACTIVE",
+		},
+		{
+			name:     "active_folder",
+			resource: "folders/10",
+			expState: "ACTIVE",
+		},
+		{
+			name:     "delete_requested_folder",
+			resource: "folders/11",
+			expState: "DELETE_REQUESTED",
+		},
+		{
+			name:     "active_project",
+			resource: "projects/1001",
+			expState: "ACTIVE",
+		},
+		{
+			name:     "delete_requested_project",
+			resource: "projects/1002",
+			expState: "DELETE_REQUESTED",
+		},
+		{
+			name:     "bigquery_table_of_delete_requested_project",
+			resource: "bigquery/datasets/1002/bar/tables/baz",
+			expState: "DELETE_REQUESTED",
+		},
+		{
+			name:     "secret_of_delete_requested_project",
+			resource: "secrets/1002/bar",
+			expState: "DELETE_REQUESTED",
+		},
+		{
+			name:     "kms_key_of_delete_requested_project",
+			resource: "kms/keyrings/1002/global/bar/keys/baz",
+			expState: "DELETE_REQUESTED",
+		},
+		{
+			name:     "service_account_of_delete_requested_project",
+			resource: "serviceAccounts/sa@1002.iam.gserviceaccount.com",
+			expState: "DELETE_REQUESTED",
+		},
+		{
+			name:     "google_managed_service_account",
+			resource: "serviceAccounts/1002-compute@developer.gserviceaccount.com",
+			expErr:   `state of service account "serviceAccounts/1002-compute@developer.gserviceaccount.com" is unknown`,
+		},
+		{
+			name:     "unknown_folder",
+			resource: "folders/99",
+			expErr:   `failed to get folder "folders/99"`,
+		},
+		{
+			name:     "full_name_of_delete_requested_project",
+			resource: "//pubsub.googleapis.com/projects/1002/topics/bar",
+			expState: "DELETE_REQUESTED",
+		},
+		{
+			name:     "full_name_outside_project",
+			resource: "//storage.googleapis.com/buckets/foo",
+			expErr:   `state of resource "//storage.googleapis.com/buckets/foo" is unknown`,
+		},
+		{
+			name:     "bucket",
+			resource: "buckets/foo",
+			expErr:   `state of bucket "buckets/foo" is unknown`,
+		},
+		{
+			name:     "invalid_resource",
+			resource: "topics/foo",
+			expErr:   `resource "topics/foo" isn't one of [organizations, folders, projects, buckets, secrets, serviceAccounts, bigquery/datasets, kms/keyrings, //<service>]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			w := newTestWalker(t, folders, projects, "folders/11", "projects/1002")
+
+			got, err := w.State(context.Background(), tc.resource)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("State(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if got != tc.expState {
+				t.Errorf("State(%+v) got %q, want %q", tc.name, got, tc.expState)
+			}
+		})
+	}
+}
+
+// newTestWalker creates a Walker with fake servers of the given folders and
+// projects, the given resources are in the DELETE_REQUESTED state.
+func newTestWalker(tb testing.TB, folders, projects map[string][]string, deleteRequested ...string) *Walker {
+	tb.Helper()
+
+	ctx := context.Background()
+	addr, conn := testutil.FakeGRPCServer(tb, func(s *grpc.Server) {
+		resourcemanagerpb.RegisterFoldersServer(s, &fakeFoldersServer{children: folders, deleteRequested: deleteRequested})
+		resourcemanagerpb.RegisterProjectsServer(s, &fakeProjectsServer{children: projects, deleteRequested: deleteRequested})
+	})
+	tb.Cleanup(func() {
+		conn.Close()
+	})
+	foldersClient, err := resourcemanager.NewFoldersClient(ctx, option.WithGRPCConn(conn))
+	if err != nil {
+		tb.Fatalf("creating folders client for fake at %q: %v", addr, err)
+	}
+	projectsClient, err := resourcemanager.NewProjectsClient(ctx, option.WithGRPCConn(conn))
+	if err != nil {
+		tb.Fatalf("creating projects client for fake at %q: %v", addr, err)
+	}
+	return NewWalker(foldersClient, projectsClient)
+}
+
+type fakeFoldersServer struct {
+	resourcemanagerpb.UnimplementedFoldersServer
+
+	children        map[string][]string
+	deleteRequested []string
+	err             error
+}
+
+func (s *fakeFoldersServer) ListFolders(_ context.Context, r *resourcemanagerpb.ListFoldersRequest) (*resourcemanagerpb.ListFoldersResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	resp := &resourcemanagerpb.ListFoldersResponse{}
+	for _, name := range s.children[r.GetParent()] {
+		resp.Folders = append(resp.Folders, &resourcemanagerpb.Folder{Name: name, Parent: r.GetParent()})
+	}
+	return resp, nil
+}
+
+func (s *fakeFoldersServer) GetFolder(_ context.Context, r *resourcemanagerpb.GetFolderRequest) (*resourcemanagerpb.Folder, error) {
+	if parent, ok := parentOf(s.children, r.GetName()); ok {
+		state := resourcemanagerpb.Folder_ACTIVE
+		if slices.Contains(s.deleteRequested, r.GetName()) {
+			state = resourcemanagerpb.Folder_DELETE_REQUESTED
+		}
+		return &resourcemanagerpb.Folder{Name: r.GetName(), Parent: parent, State: state}, nil
+	}
+	return nil, status.Errorf(codes.NotFound, "folder %q not found", r.GetName())
+}
+
+type fakeProjectsServer struct {
+	resourcemanagerpb.UnimplementedProjectsServer
+
+	children        map[string][]string
+	deleteRequested []string
+	err             error
+}
+
+func (s *fakeProjectsServer) ListProjects(_ context.Context, r *resourcemanagerpb.ListProjectsRequest) (*resourcemanagerpb.ListProjectsResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	resp := &resourcemanagerpb.ListProjectsResponse{}
+	for _, name := range s.children[r.GetParent()] {
+		resp.Projects = append(resp.Projects, &resourcemanagerpb.Project{Name: name, Parent: r.GetParent()})
+	}
+	return resp, nil
+}
+
+func (s *fakeProjectsServer) GetProject(_ context.Context, r *resourcemanagerpb.GetProjectRequest) (*resourcemanagerpb.Project, error) {
+	if parent, ok := parentOf(s.children, r.GetName()); ok {
+		state := resourcemanagerpb.Project_ACTIVE
+		if slices.Contains(s.deleteRequested, r.GetName()) {
+			state = resourcemanagerpb.Project_DELETE_REQUESTED
+		}
+		return &resourcemanagerpb.Project{Name: r.GetName(), Parent: parent, State: state}, nil
+	}
+	return nil, status.Errorf(codes.NotFound, "project %q not found", r.GetName())
+}
+
+// parentOf returns the parent of the name in the children of the parents.
+func parentOf(children map[string][]string, name string) (string, bool) {
+	for parent, names := range children {
+		if slices.Contains(names, name) {
+			return parent, true
+		}
+	}
+	return "", false
+}
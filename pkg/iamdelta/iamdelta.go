@@ -0,0 +1,161 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package iamdelta computes the changes to the bindings of IAM policies, and
+// applies them to the latest IAM policies, so that only the changes are
+// written instead of a whole policy read earlier.
+package iamdelta
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/genproto/googleapis/type/expr"
+	"google.golang.org/protobuf/proto"
+)
+
+// Delta is the members added to and removed from the bindings of an IAM
+// policy. Bindings are identified by their role and condition.
+type Delta struct {
+	// Add are the bindings of the added members.
+	Add []*iampb.Binding
+
+	// Remove are the bindings of the removed members.
+	Remove []*iampb.Binding
+}
+
+// Client gets and sets IAM policies.
+type Client interface {
+	GetIamPolicy(context.Context, *iampb.GetIamPolicyRequest, ...gax.CallOption) (*iampb.Policy, error)
+	SetIamPolicy(context.Context, *iampb.SetIamPolicyRequest, ...gax.CallOption) (*iampb.Policy, error)
+}
+
+// Diff returns the delta from the before policy to the after policy.
+func Diff(before, after *iampb.Policy) *Delta {
+	return &Delta{
+		Add:    subtract(after, members(before)),
+		Remove: subtract(before, members(after)),
+	}
+}
+
+// Empty returns whether the delta has no changes.
+func (d *Delta) Empty() bool {
+	return len(d.Add) == 0 && len(d.Remove) == 0
+}
+
+// Apply applies the delta to the policy in place. Bindings left without
+// members are removed, and the policy version is set to 3 if any added
+// binding has a condition.
+func (d *Delta) Apply(p *iampb.Policy) {
+	remove := make(map[string]map[string]struct{})
+	for _, b := range d.Remove {
+		k := key(b.GetRole(), b.GetCondition())
+		if remove[k] == nil {
+			remove[k] = make(map[string]struct{})
+		}
+		for _, m := range b.GetMembers() {
+			remove[k][m] = struct{}{}
+		}
+	}
+	p.Bindings = slices.DeleteFunc(p.Bindings, func(b *iampb.Binding) bool {
+		rm := remove[key(b.GetRole(), b.GetCondition())]
+		b.Members = slices.DeleteFunc(b.Members, func(m string) bool {
+			_, ok := rm[m]
+			return ok
+		})
+		return len(b.Members) == 0
+	})
+
+	for _, add := range d.Add {
+		k := key(add.GetRole(), add.GetCondition())
+		i := slices.IndexFunc(p.Bindings, func(b *iampb.Binding) bool {
+			return key(b.GetRole(), b.GetCondition()) == k
+		})
+		if i < 0 {
+			b, _ := proto.Clone(add).(*iampb.Binding)
+			p.Bindings = append(p.Bindings, b)
+		} else {
+			for _, m := range add.GetMembers() {
+				if !slices.Contains(p.Bindings[i].Members, m) {
+					p.Bindings[i].Members = append(p.Bindings[i].Members, m)
+				}
+			}
+		}
+		if add.GetCondition() != nil {
+			p.Version = 3
+		}
+	}
+}
+
+// Patch applies the delta to the latest IAM policy of the resource, read just
+// before it is set with the etag of the latest policy, so that changes to the
+// rest of the policy since it was read for the delta are kept.
+func Patch(ctx context.Context, c Client, resource string, d *Delta) (*iampb.Policy, error) {
+	p, err := c.GetIamPolicy(ctx, &iampb.GetIamPolicyRequest{
+		Resource: resource,
+		Options:  &iampb.GetPolicyOptions{RequestedPolicyVersion: 3},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IAM policy to patch: %w", err)
+	}
+	d.Apply(p)
+	np, err := c.SetIamPolicy(ctx, &iampb.SetIamPolicyRequest{Resource: resource, Policy: p})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set patched IAM policy: %w", err)
+	}
+	return np, nil
+}
+
+// members returns the members of the bindings of the policy by binding key.
+func members(p *iampb.Policy) map[string]map[string]struct{} {
+	result := make(map[string]map[string]struct{})
+	for _, b := range p.GetBindings() {
+		k := key(b.GetRole(), b.GetCondition())
+		if result[k] == nil {
+			result[k] = make(map[string]struct{})
+		}
+		for _, m := range b.GetMembers() {
+			result[k][m] = struct{}{}
+		}
+	}
+	return result
+}
+
+// subtract returns the bindings of the policy with only the members not in the
+// bindings of the same key in other, in the order of the policy.
+func subtract(p *iampb.Policy, other map[string]map[string]struct{}) []*iampb.Binding {
+	var result []*iampb.Binding
+	for _, b := range p.GetBindings() {
+		k := key(b.GetRole(), b.GetCondition())
+		var ms []string
+		for _, m := range b.GetMembers() {
+			if _, ok := other[k][m]; !ok {
+				ms = append(ms, m)
+			}
+		}
+		if len(ms) > 0 {
+			result = append(result, &iampb.Binding{Role: b.GetRole(), Condition: b.GetCondition(), Members: ms})
+		}
+	}
+	return result
+}
+
+// key returns the key identifying the bindings of the role and condition.
+func key(role string, cond *expr.Expr) string {
+	return strings.Join([]string{role, cond.GetTitle(), cond.GetDescription(), cond.GetExpression()}, "\x00")
+}
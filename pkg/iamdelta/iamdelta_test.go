@@ -0,0 +1,172 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iamdelta
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/genproto/googleapis/type/expr"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+var expiry = &expr.Expr{Title: "expiry", Expression: "request.time < timestamp('2009-11-10T23:00:00Z')"}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		before *iampb.Policy
+		after  *iampb.Policy
+		want   *Delta
+	}{
+		{
+			name: "no_changes",
+			before: &iampb.Policy{Bindings: []*iampb.Binding{
+				{Role: "roles/viewer", Members: []string{"user:alice@example.com"}},
+			}},
+			after: &iampb.Policy{Bindings: []*iampb.Binding{
+				{Role: "roles/viewer", Members: []string{"user:alice@example.com"}},
+			}},
+			want: &Delta{},
+		},
+		{
+			name: "added_and_removed_members",
+			before: &iampb.Policy{Bindings: []*iampb.Binding{
+				{Role: "roles/viewer", Members: []string{"user:alice@example.com", "user:bob@example.com"}},
+				{Role: "roles/viewer", Members: []string{"user:carol@example.com"}, Condition: expiry},
+			}},
+			after: &iampb.Policy{Bindings: []*iampb.Binding{
+				{Role: "roles/viewer", Members: []string{"user:alice@example.com"}},
+				{Role: "roles/editor", Members: []string{"user:bob@example.com"}, Condition: expiry},
+			}},
+			want: &Delta{
+				Add: []*iampb.Binding{
+					{Role: "roles/editor", Members: []string{"user:bob@example.com"}, Condition: expiry},
+				},
+				Remove: []*iampb.Binding{
+					{Role: "roles/viewer", Members: []string{"user:bob@example.com"}},
+					{Role: "roles/viewer", Members: []string{"user:carol@example.com"}, Condition: expiry},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := Diff(tc.before, tc.after)
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("Process(%+v) got delta diff (-want, +got): %v", tc.name, diff)
+			}
+			if got, want := got.Empty(), len(tc.want.Add)+len(tc.want.Remove) == 0; got != want {
+				t.Errorf("Process(%+v) got empty %t, want %t", tc.name, got, want)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	p := &iampb.Policy{
+		Version: 1,
+		Bindings: []*iampb.Binding{
+			{Role: "roles/owner", Members: []string{"user:admin@example.com"}},
+			{Role: "roles/viewer", Members: []string{"user:alice@example.com", "user:bob@example.com"}},
+			{Role: "roles/editor", Members: []string{"user:carol@example.com"}, Condition: expiry},
+		},
+	}
+	d := &Delta{
+		Add: []*iampb.Binding{
+			{Role: "roles/viewer", Members: []string{"user:alice@example.com", "user:dave@example.com"}},
+			{Role: "roles/editor", Members: []string{"user:erin@example.com"}, Condition: expiry},
+		},
+		Remove: []*iampb.Binding{
+			{Role: "roles/viewer", Members: []string{"user:bob@example.com"}},
+			{Role: "roles/editor", Members: []string{"user:carol@example.com"}, Condition: expiry},
+			{Role: "roles/browser", Members: []string{"user:frank@example.com"}},
+		},
+	}
+	d.Apply(p)
+
+	want := &iampb.Policy{
+		Version: 3,
+		Bindings: []*iampb.Binding{
+			{Role: "roles/owner", Members: []string{"user:admin@example.com"}},
+			{Role: "roles/viewer", Members: []string{"user:alice@example.com", "user:dave@example.com"}},
+			{Role: "roles/editor", Members: []string{"user:erin@example.com"}, Condition: expiry},
+		},
+	}
+	if diff := cmp.Diff(want, p, protocmp.Transform()); diff != "" {
+		t.Errorf("Apply() got policy diff (-want, +got): %v", diff)
+	}
+}
+
+// fakeClient is a fake Client whose policy is modified concurrently after it
+// is read by the test.
+type fakeClient struct {
+	policy *iampb.Policy
+}
+
+func (c *fakeClient) GetIamPolicy(_ context.Context, _ *iampb.GetIamPolicyRequest, _ ...gax.CallOption) (*iampb.Policy, error) {
+	return proto.Clone(c.policy).(*iampb.Policy), nil //nolint:forcetypeassert // Test fake.
+}
+
+func (c *fakeClient) SetIamPolicy(_ context.Context, req *iampb.SetIamPolicyRequest, _ ...gax.CallOption) (*iampb.Policy, error) {
+	c.policy = req.GetPolicy()
+	return c.policy, nil
+}
+
+func TestPatch(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	c := &fakeClient{policy: &iampb.Policy{Bindings: []*iampb.Binding{
+		{Role: "roles/viewer", Members: []string{"user:alice@example.com"}},
+	}}}
+
+	before, err := c.GetIamPolicy(ctx, &iampb.GetIamPolicyRequest{Resource: "buckets/foo"})
+	if err != nil {
+		t.Fatalf("GetIamPolicy got unexpected error: %v", err)
+	}
+	after := proto.Clone(before).(*iampb.Policy) //nolint:forcetypeassert // Test policy.
+	after.Bindings = append(after.Bindings, &iampb.Binding{Role: "roles/editor", Members: []string{"user:bob@example.com"}, Condition: expiry})
+
+	// The policy is modified after it was read for the delta.
+	c.policy.Bindings = append(c.policy.Bindings, &iampb.Binding{Role: "roles/owner", Members: []string{"user:admin@example.com"}})
+
+	got, err := Patch(ctx, c, "buckets/foo", Diff(before, after))
+	if err != nil {
+		t.Fatalf("Patch got unexpected error: %v", err)
+	}
+	want := &iampb.Policy{
+		Version: 3,
+		Bindings: []*iampb.Binding{
+			{Role: "roles/viewer", Members: []string{"user:alice@example.com"}},
+			{Role: "roles/owner", Members: []string{"user:admin@example.com"}},
+			{Role: "roles/editor", Members: []string{"user:bob@example.com"}, Condition: expiry},
+		},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("Patch() got policy diff (-want, +got): %v", diff)
+	}
+}
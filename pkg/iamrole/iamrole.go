@@ -0,0 +1,104 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package iamrole checks the roles of IAM requests with the IAM Roles API.
+package iamrole
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/api/googleapi"
+	iam "google.golang.org/api/iam/v1"
+	"google.golang.org/api/option"
+)
+
+// Launch stages of roles which are not to be granted.
+const (
+	stageDeprecated = "DEPRECATED"
+	stageDisabled   = "DISABLED"
+)
+
+// Checker checks roles exist and are not deprecated, which catches typos in
+// roles such as "roles/bigquery.dataVeiwer" before the requests are handled.
+type Checker struct {
+	service *iam.Service
+}
+
+// NewChecker creates a new Checker. The caller needs the "iam.roles.get"
+// permission on the organizations and projects of the custom roles checked.
+func NewChecker(ctx context.Context, opts ...option.ClientOption) (*Checker, error) {
+	svc, err := iam.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create IAM service: %w", err)
+	}
+	return &Checker{service: svc}, nil
+}
+
+// CheckRoles checks the roles exist, and are not deleted, disabled or
+// deprecated. Both predefined roles, such as "roles/viewer", and custom roles,
+// such as "projects/foo/roles/bar" or "organizations/123/roles/bar", are
+// checked. Errors of all the roles are returned.
+func (c *Checker) CheckRoles(ctx context.Context, roles []string) error {
+	var retErr error
+	checked := make(map[string]struct{}, len(roles))
+	for _, r := range roles {
+		if _, ok := checked[r]; ok {
+			continue
+		}
+		checked[r] = struct{}{}
+
+		if err := c.checkRole(ctx, r); err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("role %q: %w", r, err))
+		}
+	}
+	return retErr
+}
+
+// checkRole checks the role exists and can be granted.
+func (c *Checker) checkRole(ctx context.Context, name string) error {
+	role, err := c.getRole(ctx, name)
+	if err != nil {
+		var gErr *googleapi.Error
+		if errors.As(err, &gErr) && gErr.Code == http.StatusNotFound {
+			return fmt.Errorf("role does not exist")
+		}
+		return fmt.Errorf("failed to get role: %w", err)
+	}
+	switch {
+	case role.Deleted:
+		return fmt.Errorf("role is deleted")
+	case role.Stage == stageDisabled:
+		return fmt.Errorf("role is disabled")
+	case role.Stage == stageDeprecated:
+		return fmt.Errorf("role is deprecated")
+	}
+	return nil
+}
+
+// getRole gets the predefined or custom role of the name.
+func (c *Checker) getRole(ctx context.Context, name string) (*iam.Role, error) {
+	fields := googleapi.Field("name,stage,deleted")
+	switch {
+	case strings.HasPrefix(name, "projects/"):
+		return c.service.Projects.Roles.Get(name).Fields(fields).Context(ctx).Do()
+	case strings.HasPrefix(name, "organizations/"):
+		return c.service.Organizations.Roles.Get(name).Fields(fields).Context(ctx).Do()
+	default:
+		return c.service.Roles.Get(name).Fields(fields).Context(ctx).Do()
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iamrole
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestChecker_CheckRoles(t *testing.T) {
+	t.Parallel()
+
+	roles := map[string]string{
+		"roles/viewer":                     `{"name": "roles/viewer", "stage": "GA"}`,
+		"roles/old.viewer":                 `{"name": "roles/old.viewer", "stage": "DEPRECATED"}`,
+		"projects/foo/roles/custom":        `{"name": "projects/foo/roles/custom", "stage": "GA"}`,
+		"projects/foo/roles/deleted":       `{"name": "projects/foo/roles/deleted", "deleted": true}`,
+		"organizations/123/roles/disabled": `{"name": "organizations/123/roles/disabled", "stage": "DISABLED"}`,
+	}
+
+	cases := []struct {
+		name         string
+		roles        []string
+		status       int
+		wantRequests []string
+		wantErr      string
+	}{
+		{
+			name:         "success",
+			roles:        []string{"roles/viewer", "roles/viewer", "projects/foo/roles/custom"},
+			wantRequests: []string{"projects/foo/roles/custom", "roles/viewer"},
+		},
+		{
+			name:         "not_found",
+			roles:        []string{"roles/bigquery.dataVeiwer", "roles/viewer"},
+			wantRequests: []string{"roles/bigquery.dataVeiwer", "roles/viewer"},
+			wantErr:      `role "roles/bigquery.dataVeiwer": role does not exist`,
+		},
+		{
+			name:         "deprecated",
+			roles:        []string{"roles/old.viewer"},
+			wantRequests: []string{"roles/old.viewer"},
+			wantErr:      `role "roles/old.viewer": role is deprecated`,
+		},
+		{
+			name:         "deleted",
+			roles:        []string{"projects/foo/roles/deleted"},
+			wantRequests: []string{"projects/foo/roles/deleted"},
+			wantErr:      `role "projects/foo/roles/deleted": role is deleted`,
+		},
+		{
+			name:         "disabled",
+			roles:        []string{"organizations/123/roles/disabled"},
+			wantRequests: []string{"organizations/123/roles/disabled"},
+			wantErr:      `role "organizations/123/roles/disabled": role is disabled`,
+		},
+		{
+			name:         "server_error",
+			roles:        []string{"roles/viewer"},
+			status:       http.StatusForbidden,
+			wantRequests: []string{"roles/viewer"},
+			wantErr:      `role "roles/viewer": failed to get role`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			var mu sync.Mutex
+			var gotRequests []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				name := strings.TrimPrefix(r.URL.Path, "/v1/")
+				gotRequests = append(gotRequests, name)
+				if tc.status != 0 {
+					w.WriteHeader(tc.status)
+					fmt.Fprint(w, `{"error": {"code": 403, "message": "Permission 'iam.roles.get' denied"}}`)
+					return
+				}
+				role, ok := roles[name]
+				if !ok {
+					w.WriteHeader(http.StatusNotFound)
+					fmt.Fprintf(w, `{"error": {"code": 404, "message": "The role named %s was not found."}}`, name)
+					return
+				}
+				fmt.Fprint(w, role)
+			}))
+			t.Cleanup(srv.Close)
+
+			c, err := NewChecker(ctx, option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = c.CheckRoles(ctx, tc.roles)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			slices.Sort(gotRequests)
+			if diff := cmp.Diff(tc.wantRequests, gotRequests); diff != "" {
+				t.Errorf("Process(%+v) got requests diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
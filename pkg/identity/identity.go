@@ -0,0 +1,166 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package identity resolves member aliases, such as "github:octocat", in IAM
+// requests to IAM members with an identity map.
+package identity
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+)
+
+// kindRegex matches the kind of aliases, e.g. "github".
+var kindRegex = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// memberTypes are the IAM member types, which cannot be used as alias kinds.
+var memberTypes = map[string]struct{}{
+	"user":           {},
+	"group":          {},
+	"serviceAccount": {},
+	"domain":         {},
+	"principal":      {},
+	"principalSet":   {},
+	"deleted":        {},
+}
+
+// Map maps the aliases of each kind to IAM members, e.g. the GitHub username
+// "octocat" of kind "github" to "user:octocat@example.com". Alias names are
+// case-insensitive, like GitHub usernames.
+type Map map[string]map[string]string
+
+// Read reads and checks the identity map file at the path.
+func Read(path string) (Map, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity map file %q: %w", path, err)
+	}
+	m, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity map file %q: %w", path, err)
+	}
+	return m, nil
+}
+
+// Parse parses and checks the identity map in YAML format, e.g.
+//
+//	github:
+//	  octocat: user:octocat@example.com
+func Parse(data []byte) (Map, error) {
+	var raw Map
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(&raw); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to unmarshal yaml to %T: %w", raw, err)
+	}
+
+	m := make(Map, len(raw))
+	var retErr error
+	for _, kind := range slices.Sorted(maps.Keys(raw)) {
+		if !kindRegex.MatchString(kind) {
+			retErr = errors.Join(retErr, fmt.Errorf("invalid kind %q, must match %s", kind, kindRegex))
+			continue
+		}
+		if _, ok := memberTypes[kind]; ok {
+			retErr = errors.Join(retErr, fmt.Errorf("kind %q is an IAM member type", kind))
+			continue
+		}
+		m[kind] = make(map[string]string, len(raw[kind]))
+		for _, name := range slices.Sorted(maps.Keys(raw[kind])) {
+			member := raw[kind][name]
+			typ, email, ok := strings.Cut(member, ":")
+			if !ok || email == "" {
+				retErr = errors.Join(retErr, fmt.Errorf("%s:%s: member %q is not in the form of type:email", kind, name, member))
+				continue
+			}
+			if _, ok := raw[typ]; ok {
+				retErr = errors.Join(retErr, fmt.Errorf("%s:%s: member %q is an alias, aliases cannot be chained", kind, name, member))
+				continue
+			}
+			key := strings.ToLower(name)
+			if _, ok := m[kind][key]; ok {
+				retErr = errors.Join(retErr, fmt.Errorf("%s:%s: duplicate alias, alias names are case-insensitive", kind, name))
+				continue
+			}
+			m[kind][key] = member
+		}
+	}
+	if retErr != nil {
+		return nil, retErr
+	}
+	return m, nil
+}
+
+// Lookup returns the IAM member of the member alias, and whether the member is
+// an alias at all, which is when its type is a kind in the map. An error is
+// returned if the member is an alias that is not in the map.
+func (m Map) Lookup(member string) (string, bool, error) {
+	kind, name, _ := strings.Cut(member, ":")
+	aliases, ok := m[kind]
+	if !ok {
+		return "", false, nil
+	}
+	resolved, ok := aliases[strings.ToLower(name)]
+	if !ok {
+		return "", true, fmt.Errorf("unknown %s identity %q", kind, name)
+	}
+	return resolved, true, nil
+}
+
+// Resolve replaces the member aliases in the bindings and deny rules of the
+// request with their IAM members. The members which are not aliases are kept
+// as is. The errors of unknown aliases are v1alpha1.FieldErrors, so that they
+// can be annotated with their positions in the request file.
+func (m Map) Resolve(req *v1alpha1.IAMRequest) error {
+	if len(m) == 0 {
+		return nil
+	}
+
+	var retErr error
+	resolve := func(path string, members []string) {
+		for i, member := range members {
+			resolved, ok, err := m.Lookup(member)
+			if err != nil {
+				retErr = errors.Join(retErr, &v1alpha1.FieldError{Path: fmt.Sprintf("%s[%d]", path, i), Err: err})
+				continue
+			}
+			if ok {
+				members[i] = resolved
+			}
+		}
+	}
+	for i, p := range req.ResourcePolicies {
+		for j, b := range p.Bindings {
+			resolve(fmt.Sprintf("policies[%d].bindings[%d].members", i, j), b.Members)
+		}
+	}
+	for i, p := range req.DenyPolicies {
+		for j, r := range p.Rules {
+			path := fmt.Sprintf("denyPolicies[%d].rules[%d]", i, j)
+			resolve(path+".deniedMembers", r.DeniedMembers)
+			resolve(path+".exceptionMembers", r.ExceptionMembers)
+		}
+	}
+	return retErr
+}
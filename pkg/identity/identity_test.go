@@ -0,0 +1,222 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		data   string
+		expMap Map
+		expErr string
+	}{
+		{
+			name: "success",
+			data: `
+github:
+  octocat: user:octocat@example.com
+  Hubot: serviceAccount:hubot@my-project.iam.gserviceaccount.com
+`,
+			expMap: Map{
+				"github": {
+					"octocat": "user:octocat@example.com",
+					"hubot":   "serviceAccount:hubot@my-project.iam.gserviceaccount.com",
+				},
+			},
+		},
+		{
+			name:   "empty",
+			data:   "",
+			expMap: Map{},
+		},
+		{
+			name:   "invalid_kind",
+			data:   "GitHub:\n  octocat: user:octocat@example.com\n",
+			expErr: `invalid kind "GitHub"`,
+		},
+		{
+			name:   "member_type_kind",
+			data:   "user:\n  octocat: user:octocat@example.com\n",
+			expErr: `kind "user" is an IAM member type`,
+		},
+		{
+			name:   "invalid_member",
+			data:   "github:\n  octocat: octocat@example.com\n",
+			expErr: `github:octocat: member "octocat@example.com" is not in the form of type:email`,
+		},
+		{
+			name:   "chained_alias",
+			data:   "github:\n  octocat: gitlab:octocat\ngitlab:\n  octocat: user:octocat@example.com\n",
+			expErr: `github:octocat: member "gitlab:octocat" is an alias, aliases cannot be chained`,
+		},
+		{
+			name:   "duplicate_alias",
+			data:   "github:\n  Octocat: user:octocat@example.com\n  octocat: user:cat@example.com\n",
+			expErr: "github:octocat: duplicate alias",
+		},
+		{
+			name:   "invalid_yaml",
+			data:   "github: [octocat]",
+			expErr: "failed to unmarshal yaml",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Parse([]byte(tc.data))
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Parse got error diff (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.expMap, got); diff != "" {
+				t.Errorf("Parse got map diff (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "identities.yaml")
+	if err := os.WriteFile(path, []byte("github:\n  octocat: user:octocat@example.com\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read got unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(Map{"github": {"octocat": "user:octocat@example.com"}}, got); diff != "" {
+		t.Errorf("Read got map diff (-want, +got):\n%s", diff)
+	}
+
+	if _, err := Read(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Errorf("Read got no error for missing file, want error")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	t.Parallel()
+
+	m := Map{"github": {"octocat": "user:octocat@example.com"}}
+
+	cases := []struct {
+		name   string
+		m      Map
+		req    *v1alpha1.IAMRequest
+		expReq *v1alpha1.IAMRequest
+		expErr string
+	}{
+		{
+			name: "success",
+			m:    m,
+			req: &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+					Resource: "projects/foo",
+					Bindings: []*v1alpha1.Binding{{
+						Members: []string{"github:OctoCat", "user:alice@example.com"},
+						Role:    "roles/viewer",
+					}},
+				}},
+				DenyPolicies: []*v1alpha1.DenyPolicy{{
+					Resource: "projects/foo",
+					Rules: []*v1alpha1.DenyRule{{
+						DeniedMembers:     []string{"group:contractors@example.com"},
+						ExceptionMembers:  []string{"github:octocat"},
+						DeniedPermissions: []string{"storage.googleapis.com/buckets.delete"},
+					}},
+				}},
+			},
+			expReq: &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+					Resource: "projects/foo",
+					Bindings: []*v1alpha1.Binding{{
+						Members: []string{"user:octocat@example.com", "user:alice@example.com"},
+						Role:    "roles/viewer",
+					}},
+				}},
+				DenyPolicies: []*v1alpha1.DenyPolicy{{
+					Resource: "projects/foo",
+					Rules: []*v1alpha1.DenyRule{{
+						DeniedMembers:     []string{"group:contractors@example.com"},
+						ExceptionMembers:  []string{"user:octocat@example.com"},
+						DeniedPermissions: []string{"storage.googleapis.com/buckets.delete"},
+					}},
+				}},
+			},
+		},
+		{
+			name: "no_map",
+			req: &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+					Resource: "projects/foo",
+					Bindings: []*v1alpha1.Binding{{Members: []string{"github:octocat"}, Role: "roles/viewer"}},
+				}},
+			},
+			expReq: &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+					Resource: "projects/foo",
+					Bindings: []*v1alpha1.Binding{{Members: []string{"github:octocat"}, Role: "roles/viewer"}},
+				}},
+			},
+		},
+		{
+			name: "unknown_alias",
+			m:    m,
+			req: &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+					Resource: "projects/foo",
+					Bindings: []*v1alpha1.Binding{{Members: []string{"github:octocat", "github:hubot"}, Role: "roles/viewer"}},
+				}},
+			},
+			expReq: &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+					Resource: "projects/foo",
+					Bindings: []*v1alpha1.Binding{{Members: []string{"user:octocat@example.com", "github:hubot"}, Role: "roles/viewer"}},
+				}},
+			},
+			expErr: `policies[0].bindings[0].members[1]: unknown github identity "hubot"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.m.Resolve(tc.req)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Resolve got error diff (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.expReq, tc.req); diff != "" {
+				t.Errorf("Resolve got request diff (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package inventory discovers AOD IAM bindings with Cloud Asset Inventory.
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	cloudasset "google.golang.org/api/cloudasset/v1"
+	"google.golang.org/api/option"
+)
+
+// resourceManagerPrefix is the prefix of the full resource names of
+// organizations, folders and projects in Cloud Asset Inventory.
+const resourceManagerPrefix = "//cloudresourcemanager.googleapis.com/"
+
+// assetTypes are the asset types of the resources AOD grants IAM bindings on.
+var assetTypes = []string{
+	"cloudresourcemanager.googleapis.com/Organization",
+	"cloudresourcemanager.googleapis.com/Folder",
+	"cloudresourcemanager.googleapis.com/Project",
+}
+
+// Binding is an IAM binding added by AOD, found in Cloud Asset Inventory.
+type Binding struct {
+	// Resource is the organization, folder or project of the IAM policy, in the
+	// format of "organizations/123", "folders/123" or "projects/123".
+	Resource string `yaml:"resource" json:"resource"`
+
+	// Role is the role of the binding.
+	Role string `yaml:"role" json:"role"`
+
+	// Members are the members of the binding.
+	Members []string `yaml:"members" json:"members"`
+
+	// Expression is the expiry condition expression of the binding.
+	Expression string `yaml:"expression" json:"expression"`
+}
+
+// Searcher searches AOD IAM bindings with Cloud Asset Inventory
+// SearchAllIamPolicies, which finds the bindings across all the descendants of
+// an organization or folder with a single paginated query, instead of getting
+// the IAM policy of every resource.
+type Searcher struct {
+	service *cloudasset.Service
+}
+
+// NewSearcher creates a new Searcher.
+func NewSearcher(ctx context.Context, opts ...option.ClientOption) (*Searcher, error) {
+	svc, err := cloudasset.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cloud asset service: %w", err)
+	}
+	return &Searcher{service: svc}, nil
+}
+
+// Search returns the IAM bindings with the AOD condition title in the IAM
+// policies of the organizations, folders and projects within the scope. The
+// scope is an organization, folder or project, such as "organizations/123".
+//
+// Cloud Asset Inventory is eventually consistent, so bindings added or removed
+// in the last few minutes may be missing or stale.
+func (s *Searcher) Search(ctx context.Context, scope, conditionTitle string) ([]*Binding, error) {
+	var result []*Binding
+	if err := s.service.V1.SearchAllIamPolicies(scope).
+		AssetTypes(assetTypes...).
+		Context(ctx).
+		Pages(ctx, func(resp *cloudasset.SearchAllIamPoliciesResponse) error {
+			for _, r := range resp.Results {
+				result = append(result, aodBindings(r, conditionTitle)...)
+			}
+			return nil
+		}); err != nil {
+		return nil, fmt.Errorf("failed to search IAM policies in %q: %w", scope, err)
+	}
+	return result, nil
+}
+
+// Resources returns the unique resources of the bindings, in the order of their
+// first occurrences.
+func Resources(bs []*Binding) []string {
+	seen := make(map[string]struct{}, len(bs))
+	var result []string
+	for _, b := range bs {
+		if _, ok := seen[b.Resource]; ok {
+			continue
+		}
+		seen[b.Resource] = struct{}{}
+		result = append(result, b.Resource)
+	}
+	return result
+}
+
+// aodBindings returns the bindings with the condition title in the search
+// result.
+func aodBindings(r *cloudasset.IamPolicySearchResult, conditionTitle string) []*Binding {
+	if r.Policy == nil || !strings.HasPrefix(r.Resource, resourceManagerPrefix) {
+		return nil
+	}
+	resource := strings.TrimPrefix(r.Resource, resourceManagerPrefix)
+
+	var result []*Binding
+	for _, b := range r.Policy.Bindings {
+		if b.Condition == nil || b.Condition.Title != conditionTitle {
+			continue
+		}
+		result = append(result, &Binding{
+			Resource:   resource,
+			Role:       b.Role,
+			Members:    b.Members,
+			Expression: b.Condition.Expression,
+		})
+	}
+	return result
+}
@@ -0,0 +1,186 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inventory
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestSearcher_Search(t *testing.T) {
+	t.Parallel()
+
+	firstPage := `{
+  "results": [
+    {
+      "resource": "//cloudresourcemanager.googleapis.com/organizations/1",
+      "policy": {
+        "bindings": [
+          {"role": "roles/owner", "members": ["user:admin@example.com"]}
+        ]
+      }
+    },
+    {
+      "resource": "//cloudresourcemanager.googleapis.com/folders/2",
+      "policy": {
+        "bindings": [
+          {
+            "role": "roles/viewer",
+            "members": ["user:alice@example.com", "user:bob@example.com"],
+            "condition": {"title": "abcxyz-aod-expiry", "expression": "request.time < timestamp('2009-11-10T23:00:00Z')"}
+          },
+          {
+            "role": "roles/editor",
+            "members": ["user:alice@example.com"],
+            "condition": {"title": "other-condition", "expression": "true"}
+          }
+        ]
+      }
+    }
+  ],
+  "nextPageToken": "page-2"
+}`
+	secondPage := `{
+  "results": [
+    {
+      "resource": "//cloudresourcemanager.googleapis.com/projects/3",
+      "policy": {
+        "bindings": [
+          {
+            "role": "roles/bigquery.dataViewer",
+            "members": ["user:bob@example.com"],
+            "condition": {"title": "abcxyz-aod-expiry", "expression": "request.time < timestamp('2009-11-11T01:00:00Z')"}
+          }
+        ]
+      }
+    },
+    {
+      "resource": "//storage.googleapis.com/buckets/foo",
+      "policy": {
+        "bindings": [
+          {
+            "role": "roles/storage.objectViewer",
+            "members": ["user:bob@example.com"],
+            "condition": {"title": "abcxyz-aod-expiry", "expression": "request.time < timestamp('2009-11-11T01:00:00Z')"}
+          }
+        ]
+      }
+    }
+  ]
+}`
+
+	cases := []struct {
+		name         string
+		status       int
+		expBindings  []*Binding
+		expResources []string
+		expErr       string
+	}{
+		{
+			name:   "success",
+			status: http.StatusOK,
+			expBindings: []*Binding{
+				{
+					Resource:   "folders/2",
+					Role:       "roles/viewer",
+					Members:    []string{"user:alice@example.com", "user:bob@example.com"},
+					Expression: "request.time < timestamp('2009-11-10T23:00:00Z')",
+				},
+				{
+					Resource:   "projects/3",
+					Role:       "roles/bigquery.dataViewer",
+					Members:    []string{"user:bob@example.com"},
+					Expression: "request.time < timestamp('2009-11-11T01:00:00Z')",
+				},
+			},
+			expResources: []string{"folders/2", "projects/3"},
+		},
+		{
+			name:   "search_failure",
+			status: http.StatusForbidden,
+			expErr: `failed to search IAM policies in "organizations/1"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			var mu sync.Mutex
+			var gotPaths, gotAssetTypes []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				gotPaths = append(gotPaths, r.URL.Path)
+				gotAssetTypes = r.URL.Query()["assetTypes"]
+				w.WriteHeader(tc.status)
+				if tc.status != http.StatusOK {
+					w.Write([]byte(`{}`)) //nolint:errcheck // Best effort.
+					return
+				}
+				if r.URL.Query().Get("pageToken") == "page-2" {
+					w.Write([]byte(secondPage)) //nolint:errcheck // Best effort.
+					return
+				}
+				w.Write([]byte(firstPage)) //nolint:errcheck // Best effort.
+			}))
+			t.Cleanup(srv.Close)
+
+			s, err := NewSearcher(ctx, option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := s.Search(ctx, "organizations/1", "abcxyz-aod-expiry")
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error: %s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expBindings, got); diff != "" {
+				t.Errorf("Process(%+v) got bindings diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expResources, Resources(got)); diff != "" {
+				t.Errorf("Process(%+v) got resources diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if tc.expErr != "" {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, p := range gotPaths {
+				if !strings.HasSuffix(p, "/v1/organizations/1:searchAllIamPolicies") {
+					t.Errorf("request path got %q, want suffix %q", p, "/v1/organizations/1:searchAllIamPolicies")
+				}
+			}
+			if got, want := len(gotPaths), 2; got != want {
+				t.Errorf("got %d requests, want %d", got, want)
+			}
+			if diff := cmp.Diff(assetTypes, gotAssetTypes); diff != "" {
+				t.Errorf("Process(%+v) got asset types diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kmsiam gets and sets the IAM policies of Cloud KMS key rings and keys
+// in the format of the IAM API, so that they are handled like the IAM policies
+// of projects.
+package kmsiam
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/googleapis/gax-go/v2"
+	cloudkms "google.golang.org/api/cloudkms/v1"
+	"google.golang.org/api/option"
+	"google.golang.org/genproto/googleapis/type/expr"
+
+	"github.com/abcxyz/access-on-demand/pkg/iamdelta"
+	"github.com/abcxyz/access-on-demand/pkg/resource"
+)
+
+// Client gets and sets the IAM policies of Cloud KMS key rings and keys, named
+// "kms/keyrings/<project>/<location>/<key ring>" and
+// "kms/keyrings/<project>/<location>/<key ring>/keys/<key>".
+type Client struct {
+	service *cloudkms.Service
+}
+
+// NewClient creates a new Client.
+func NewClient(ctx context.Context, opts ...option.ClientOption) (*Client, error) {
+	svc, err := cloudkms.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cloud kms service: %w", err)
+	}
+	return &Client{service: svc}, nil
+}
+
+// GetIamPolicy gets the IAM policy of the key ring or key.
+func (c *Client) GetIamPolicy(ctx context.Context, req *iampb.GetIamPolicyRequest, _ ...gax.CallOption) (*iampb.Policy, error) {
+	name, isKey, err := parse(req.GetResource())
+	if err != nil {
+		return nil, err
+	}
+	v := int64(req.GetOptions().GetRequestedPolicyVersion())
+
+	if isKey {
+		call := c.service.Projects.Locations.KeyRings.CryptoKeys.GetIamPolicy(name).Context(ctx)
+		if v > 0 {
+			call = call.OptionsRequestedPolicyVersion(v)
+		}
+		p, err := call.Do()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get IAM policy of key %q: %w", req.GetResource(), err)
+		}
+		return fromPolicy(p)
+	}
+
+	call := c.service.Projects.Locations.KeyRings.GetIamPolicy(name).Context(ctx)
+	if v > 0 {
+		call = call.OptionsRequestedPolicyVersion(v)
+	}
+	p, err := call.Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IAM policy of key ring %q: %w", req.GetResource(), err)
+	}
+	return fromPolicy(p)
+}
+
+// SetIamPolicy sets the bindings of the IAM policy of the key ring or key, the
+// audit configs of the policy are kept.
+func (c *Client) SetIamPolicy(ctx context.Context, req *iampb.SetIamPolicyRequest, _ ...gax.CallOption) (*iampb.Policy, error) {
+	name, isKey, err := parse(req.GetResource())
+	if err != nil {
+		return nil, err
+	}
+	setReq := &cloudkms.SetIamPolicyRequest{Policy: toPolicy(req.GetPolicy())}
+
+	if isKey {
+		p, err := c.service.Projects.Locations.KeyRings.CryptoKeys.SetIamPolicy(name, setReq).Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("failed to set IAM policy of key %q: %w", req.GetResource(), err)
+		}
+		return fromPolicy(p)
+	}
+
+	p, err := c.service.Projects.Locations.KeyRings.SetIamPolicy(name, setReq).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to set IAM policy of key ring %q: %w", req.GetResource(), err)
+	}
+	return fromPolicy(p)
+}
+
+// PatchIamPolicy applies the changes to the bindings to the latest IAM policy
+// of the key ring or key.
+func (c *Client) PatchIamPolicy(ctx context.Context, resource string, d *iamdelta.Delta) (*iampb.Policy, error) {
+	return iamdelta.Patch(ctx, c, resource, d) //nolint:wrapcheck // Want passthrough
+}
+
+// parse returns the name of the key ring or key in the Cloud KMS API, in the
+// format of "projects/<project>/locations/<location>/keyRings/<key ring>" with
+// "/cryptoKeys/<key>" for keys, and whether it is a key.
+func parse(name string) (string, bool, error) {
+	rn, err := resource.Parse(name)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to parse resource: %w", err)
+	}
+	if rn.Type != resource.TypeKMSKeyRing && rn.Type != resource.TypeKMSKey {
+		return "", false, fmt.Errorf("resource %q is not a Cloud KMS key ring or key", name)
+	}
+	project, location, keyRing, key := rn.KMS()
+	n := fmt.Sprintf("projects/%s/locations/%s/keyRings/%s", project, location, keyRing)
+	if key == "" {
+		return n, false, nil
+	}
+	return n + "/cryptoKeys/" + key, true, nil
+}
+
+// fromPolicy converts the IAM policy of a key ring or key to an IAM policy of
+// the IAM API.
+func fromPolicy(p *cloudkms.Policy) (*iampb.Policy, error) {
+	etag, err := base64.StdEncoding.DecodeString(p.Etag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode etag %q: %w", p.Etag, err)
+	}
+	policy := &iampb.Policy{Version: int32(p.Version), Etag: etag}
+	for _, b := range p.Bindings {
+		policy.Bindings = append(policy.Bindings, &iampb.Binding{
+			Role:      b.Role,
+			Members:   b.Members,
+			Condition: fromExpr(b.Condition),
+		})
+	}
+	return policy, nil
+}
+
+// toPolicy converts the IAM policy of the IAM API to an IAM policy of a key
+// ring or key.
+func toPolicy(p *iampb.Policy) *cloudkms.Policy {
+	policy := &cloudkms.Policy{
+		Version: int64(p.GetVersion()),
+		Etag:    base64.StdEncoding.EncodeToString(p.GetEtag()),
+	}
+	for _, b := range p.GetBindings() {
+		policy.Bindings = append(policy.Bindings, &cloudkms.Binding{
+			Role:      b.GetRole(),
+			Members:   b.GetMembers(),
+			Condition: toExpr(b.GetCondition()),
+		})
+	}
+	return policy
+}
+
+// fromExpr converts the condition of the Cloud KMS API to a condition of the
+// IAM API.
+func fromExpr(e *cloudkms.Expr) *expr.Expr {
+	if e == nil {
+		return nil
+	}
+	return &expr.Expr{
+		Title:       e.Title,
+		Description: e.Description,
+		Expression:  e.Expression,
+		Location:    e.Location,
+	}
+}
+
+// toExpr converts the condition of the IAM API to a condition of the Cloud KMS
+// API.
+func toExpr(e *expr.Expr) *cloudkms.Expr {
+	if e == nil {
+		return nil
+	}
+	return &cloudkms.Expr{
+		Title:       e.GetTitle(),
+		Description: e.GetDescription(),
+		Expression:  e.GetExpression(),
+		Location:    e.GetLocation(),
+	}
+}
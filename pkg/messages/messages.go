@@ -0,0 +1,272 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package messages is the catalog of the user-facing messages of AOD, such as
+// output headers and pull request comments. The messages are Go templates which
+// can be overridden with a file, so organizations can customize the messaging,
+// e.g. to include links to internal support pages, without forking AOD.
+package messages
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ID identifies a message in the catalog.
+type ID string
+
+// IDs of the messages in the catalog.
+const (
+	// HeaderHandled is the output header of a handled IAM request.
+	HeaderHandled ID = "header_handled"
+
+	// HeaderRenewed is the output header of a renewed IAM request.
+	HeaderRenewed ID = "header_renewed"
+
+	// HeaderCleanedUp is the output header of a cleaned up request.
+	HeaderCleanedUp ID = "header_cleaned_up"
+
+	// HeaderUpdatedPolicies is the output header of the IAM policies updated by
+	// a command.
+	HeaderUpdatedPolicies ID = "header_updated_policies"
+
+	// HeaderCleanedUpPolicies is the output header of the IAM policies cleaned
+	// up by a command.
+	HeaderCleanedUpPolicies ID = "header_cleaned_up_policies"
+
+	// HeaderSwept is the output header of a sweep of expired AOD bindings.
+	HeaderSwept ID = "header_swept"
+
+	// HeaderReconciled is the output header of a reconciliation of the grant
+	// registry and the IAM policies.
+	HeaderReconciled ID = "header_reconciled"
+
+	// HeaderRevokedUser is the output header of a member revoked from AOD
+	// bindings.
+	HeaderRevokedUser ID = "header_revoked_user"
+
+	// HeaderRewritten is the output header of a rewritten member.
+	HeaderRewritten ID = "header_rewritten"
+
+	// HeaderRetitled is the output header of retitled AOD bindings.
+	HeaderRetitled ID = "header_retitled"
+
+	// HeaderOpenedPR is the output header of an opened pull request.
+	HeaderOpenedPR ID = "header_opened_pr"
+
+	// HeaderToolOutput is the output header of the output of tool commands.
+	HeaderToolOutput ID = "header_tool_output"
+
+	// HeaderToolCompleted is the output header of completed tool commands.
+	HeaderToolCompleted ID = "header_tool_completed"
+
+	// HeaderToolPermissions is the output header of the permissions of a tool
+	// request.
+	HeaderToolPermissions ID = "header_tool_permissions"
+
+	// HeaderDetached is the output header of the operations of detached
+	// requests.
+	HeaderDetached ID = "header_detached"
+
+	// HeaderOperation is the output header of a detached operation.
+	HeaderOperation ID = "header_operation"
+
+	// HeaderUsage is the output header of the usage of an expired grant.
+	HeaderUsage ID = "header_usage"
+
+	// HeaderPluginHandled is the output header of a handled plugin request.
+	HeaderPluginHandled ID = "header_plugin_handled"
+
+	// HeaderPluginCleanedUp is the output header of a cleaned up plugin
+	// request.
+	HeaderPluginCleanedUp ID = "header_plugin_cleaned_up"
+
+	// HeaderRoleHandled is the output header of a handled custom role request.
+	HeaderRoleHandled ID = "header_role_handled"
+
+	// HeaderRolesCleanedUp is the output header of the expired custom roles
+	// deleted by a cleanup.
+	HeaderRolesCleanedUp ID = "header_roles_cleaned_up"
+
+	// HeaderPAMGrants is the output header of the PAM grants requested by an
+	// IAM request handled with PAM.
+	HeaderPAMGrants ID = "header_pam_grants"
+
+	// HeaderGroupHandled is the output header of a handled group request.
+	HeaderGroupHandled ID = "header_group_handled"
+
+	// HeaderGroupCleanedUp is the output header of a cleaned up group request.
+	HeaderGroupCleanedUp ID = "header_group_cleaned_up"
+
+	// HeaderPolicyTested is the output header of the results of testing a
+	// policy file against requests.
+	HeaderPolicyTested ID = "header_policy_tested"
+
+	// HeaderResourceReadiness is the output header of the readiness of the
+	// resources of requests to be handled.
+	HeaderResourceReadiness ID = "header_resource_readiness"
+
+	// PRCommentSuccess is the headline of the pull request comment of a
+	// request handled successfully. The data has the fields Action and Done,
+	// such as "handle" and "handled".
+	PRCommentSuccess ID = "pr_comment_success"
+
+	// PRCommentFailure is the headline of the pull request comment of a
+	// request failed to be handled. The data has the fields Action and Done.
+	PRCommentFailure ID = "pr_comment_failure"
+
+	// ErrorHelp is appended to the errors of the CLI, and is empty by default.
+	// The data has the field Error.
+	ErrorHelp ID = "error_help"
+)
+
+// defaults are the default templates of the messages.
+var defaults = map[ID]string{
+	HeaderHandled:           "Successfully Handled IAM Request",
+	HeaderRenewed:           "Successfully Renewed IAM Request",
+	HeaderCleanedUp:         "Successfully Removed Requested Bindings",
+	HeaderUpdatedPolicies:   "Updated IAM Policies",
+	HeaderCleanedUpPolicies: "Cleaned Up IAM Policies",
+	HeaderSwept:             "Successfully Swept Expired AOD Bindings",
+	HeaderReconciled:        "Successfully Reconciled AOD Bindings",
+	HeaderRevokedUser:       "Successfully Revoked Member From AOD Bindings",
+	HeaderRewritten:         "Successfully Rewrote Member",
+	HeaderRetitled:          "Successfully Retitled AOD Bindings",
+	HeaderOpenedPR:          "Successfully Opened Pull Request",
+	HeaderToolOutput:        "Tool Commands Output",
+	HeaderToolCompleted:     "Successfully Completed Commands",
+	HeaderToolPermissions:   "Tool Request Permissions",
+	HeaderDetached:          "Successfully Detached IAM Requests",
+	HeaderOperation:         "Operation",
+	HeaderUsage:             "Usage of Expired Grant",
+	HeaderPluginHandled:     "Successfully Handled Plugin Request",
+	HeaderPluginCleanedUp:   "Successfully Cleaned Up Plugin Request",
+	HeaderRoleHandled:       "Successfully Handled Custom Role Request",
+	HeaderRolesCleanedUp:    "Successfully Deleted Expired Custom Roles",
+	HeaderPAMGrants:         "Requested PAM Grants",
+	HeaderGroupHandled:      "Successfully Handled Group Request",
+	HeaderGroupCleanedUp:    "Successfully Cleaned Up Group Request",
+	HeaderPolicyTested:      "Policy Test Results",
+	HeaderResourceReadiness: "Resource Readiness",
+	PRCommentSuccess:        "**AOD successfully {{ .Done }} the IAM request.**",
+	PRCommentFailure:        "**AOD failed to {{ .Action }} the IAM request.**",
+	ErrorHelp:               "",
+}
+
+// file is the file of message overrides.
+type file struct {
+	// Messages maps the message IDs to the templates overriding them.
+	Messages map[ID]string `yaml:"messages"`
+}
+
+// Catalog is a catalog of messages.
+type Catalog struct {
+	templates map[ID]*template.Template
+}
+
+// defaultCatalog parses the default messages once.
+var defaultCatalog = sync.OnceValue(func() *Catalog {
+	c, err := New(nil)
+	if err != nil {
+		panic(fmt.Sprintf("failed to parse default messages: %v", err))
+	}
+	return c
+})
+
+// Default returns the catalog of the default messages.
+func Default() *Catalog {
+	return defaultCatalog()
+}
+
+// New returns the catalog of the default messages with the overrides, which
+// map message IDs to templates.
+func New(overrides map[ID]string) (*Catalog, error) {
+	texts := maps.Clone(defaults)
+	for id, text := range overrides {
+		if _, ok := defaults[id]; !ok {
+			return nil, fmt.Errorf("unknown message %q, must be one of %v", id, IDs())
+		}
+		texts[id] = text
+	}
+
+	c := &Catalog{templates: make(map[ID]*template.Template, len(texts))}
+	for id, text := range texts {
+		tmpl, err := template.New(string(id)).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse message %q: %w", id, err)
+		}
+		c.templates[id] = tmpl
+	}
+	return c, nil
+}
+
+// Load returns the catalog of the default messages with the overrides in the
+// YAML file at the path, in the format of:
+//
+//	messages:
+//	  header_handled: "Successfully Handled IAM Request, see https://example.com/aod-help"
+func Load(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read messages file %q: %w", path, err)
+	}
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse messages file %q: %w", path, err)
+	}
+	c, err := New(f.Messages)
+	if err != nil {
+		return nil, fmt.Errorf("invalid messages file %q: %w", path, err)
+	}
+	return c, nil
+}
+
+// Render returns the message rendered with the data. If an overridden message
+// fails to render, e.g. because it uses a field the data doesn't have, the
+// default message is rendered instead so that the output is never lost.
+func (c *Catalog) Render(id ID, data any) string {
+	if s, err := execute(c.templates[id], data); err == nil {
+		return s
+	}
+	s, err := execute(Default().templates[id], data)
+	if err != nil {
+		return string(id)
+	}
+	return s
+}
+
+// IDs returns the sorted IDs of the messages.
+func IDs() []ID {
+	return slices.Sorted(maps.Keys(defaults))
+}
+
+// execute executes the template with the data.
+func execute(tmpl *template.Template, data any) (string, error) {
+	if tmpl == nil {
+		return "", fmt.Errorf("missing template")
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to execute template %q: %w", tmpl.Name(), err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
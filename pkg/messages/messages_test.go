@@ -0,0 +1,121 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package messages
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		file    string
+		id      ID
+		data    any
+		want    string
+		wantErr string
+	}{
+		{
+			name: "default",
+			file: "messages: {}",
+			id:   HeaderHandled,
+			want: "Successfully Handled IAM Request",
+		},
+		{
+			name: "override",
+			file: `
+messages:
+  header_handled: "Successfully Handled IAM Request, see https://example.com/aod-help"
+`,
+			id:   HeaderHandled,
+			want: "Successfully Handled IAM Request, see https://example.com/aod-help",
+		},
+		{
+			name: "override_with_data",
+			file: `
+messages:
+  pr_comment_success: |
+    **{{ .Done }}!**
+`,
+			id:   PRCommentSuccess,
+			data: map[string]string{"Action": "handle", "Done": "handled"},
+			want: "**handled!**",
+		},
+		{
+			name: "fallback_to_default",
+			file: `
+messages:
+  pr_comment_success: "{{ .Missing }}"
+`,
+			id:   PRCommentSuccess,
+			data: map[string]string{"Action": "handle", "Done": "handled"},
+			want: "**AOD successfully handled the IAM request.**",
+		},
+		{
+			name:    "unknown_message",
+			file:    "messages: {foo: bar}",
+			wantErr: `unknown message "foo"`,
+		},
+		{
+			name:    "invalid_template",
+			file:    `messages: {header_handled: "{{ .Foo"}`,
+			wantErr: `failed to parse message "header_handled"`,
+		},
+		{
+			name:    "invalid_yaml",
+			file:    "messages: [",
+			wantErr: "failed to parse messages file",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "messages.yaml")
+			if err := os.WriteFile(path, []byte(tc.file), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			c, err := Load(path)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected err: %s", tc.name, diff)
+			}
+			if err != nil {
+				return
+			}
+			if got := c.Render(tc.id, tc.data); got != tc.want {
+				t.Errorf("Process(%+v) got message %q, want %q", tc.name, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]string{"Action": "handle", "Done": "handled", "Error": "injected error"}
+	for _, id := range IDs() {
+		if got := Default().Render(id, data); got == string(id) {
+			t.Errorf("Render(%q) failed to render the default message", id)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pam requests grants against the existing entitlements of Privileged
+// Access Manager (PAM) with its REST API. PAM grants the roles of an
+// entitlement to the caller requesting the grant, and revokes them once the
+// requested duration passes.
+package pam
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+const (
+	// DefaultEndpoint is the endpoint of the PAM API.
+	DefaultEndpoint = "https://privilegedaccessmanager.googleapis.com/"
+
+	// location of the entitlements, PAM entitlements are global.
+	location = "global"
+
+	cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+// Entitlement is a PAM entitlement of the roles on its resource, which eligible
+// principals can request grants of.
+type Entitlement struct {
+	// Name of the entitlement, e.g.
+	// "projects/foo/locations/global/entitlements/bar".
+	Name string `yaml:"name"`
+
+	// Principals eligible to request grants of the entitlement, e.g.
+	// "user:alice@example.com".
+	Principals []string `yaml:"principals,omitempty"`
+
+	// Roles granted by the entitlement.
+	Roles []string `yaml:"roles,omitempty"`
+
+	// MaxRequestDuration is the maximum duration of the grants.
+	MaxRequestDuration time.Duration `yaml:"maxRequestDuration,omitempty"`
+}
+
+// Grant is a PAM grant of an entitlement.
+type Grant struct {
+	// Name of the grant, e.g.
+	// "projects/foo/locations/global/entitlements/bar/grants/123".
+	Name string `yaml:"name"`
+
+	// State of the grant, e.g. "APPROVAL_AWAITED" or "ACTIVATING".
+	State string `yaml:"state,omitempty"`
+}
+
+// Client lists PAM entitlements and creates grants of them.
+type Client struct {
+	httpClient *http.Client
+	endpoint   string
+}
+
+// NewClient creates a new Client.
+func NewClient(ctx context.Context, opts ...option.ClientOption) (*Client, error) {
+	opts = append([]option.ClientOption{option.WithScopes(cloudPlatformScope)}, opts...)
+	hc, endpoint, err := htransport.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create PAM http client: %w", err)
+	}
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	return &Client{httpClient: hc, endpoint: strings.TrimSuffix(endpoint, "/") + "/"}, nil
+}
+
+// entitlement is the PAM API representation of an entitlement.
+type entitlement struct {
+	Name          string `json:"name"`
+	State         string `json:"state"`
+	EligibleUsers []struct {
+		Principals []string `json:"principals"`
+	} `json:"eligibleUsers"`
+	MaxRequestDuration string `json:"maxRequestDuration"`
+	PrivilegedAccess   struct {
+		GcpIamAccess struct {
+			RoleBindings []struct {
+				Role string `json:"role"`
+			} `json:"roleBindings"`
+		} `json:"gcpIamAccess"`
+	} `json:"privilegedAccess"`
+}
+
+// ListEntitlements lists the available entitlements of the organization,
+// folder or project resource, e.g. "projects/foo".
+func (c *Client) ListEntitlements(ctx context.Context, resource string) ([]*Entitlement, error) {
+	parent := fmt.Sprintf("%s/locations/%s", resource, location)
+
+	var result []*Entitlement
+	var pageToken string
+	for {
+		path := "v1/" + parent + "/entitlements"
+		if pageToken != "" {
+			path += "?" + url.Values{"pageToken": {pageToken}}.Encode()
+		}
+		var resp struct {
+			Entitlements  []*entitlement `json:"entitlements"`
+			NextPageToken string         `json:"nextPageToken"`
+		}
+		if err := c.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+			return nil, fmt.Errorf("failed to list entitlements of %q: %w", resource, err)
+		}
+		for _, e := range resp.Entitlements {
+			// Entitlements being created, updated or deleted cannot be requested.
+			if e.State != "" && e.State != "AVAILABLE" {
+				continue
+			}
+			ent, err := toEntitlement(e)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, ent)
+		}
+		if resp.NextPageToken == "" {
+			return result, nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+// CreateGrant requests a grant of the entitlement for the caller, with the
+// duration and justification.
+func (c *Client) CreateGrant(ctx context.Context, entitlement string, duration time.Duration, justification string) (*Grant, error) {
+	req := map[string]any{
+		"requestedDuration": formatDuration(duration),
+		"justification": map[string]string{
+			"unstructuredJustification": justification,
+		},
+	}
+	var g Grant
+	if err := c.do(ctx, http.MethodPost, "v1/"+entitlement+"/grants", req, &g); err != nil {
+		return nil, fmt.Errorf("failed to create grant of entitlement %q: %w", entitlement, err)
+	}
+	return &g, nil
+}
+
+// do sends the request with the JSON body to the path of the API, and decodes
+// the JSON response to resp.
+func (c *Client) do(ctx context.Context, method, path string, body, resp any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, r)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(io.LimitReader(res.Body, 4<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return &APIError{StatusCode: res.StatusCode, Body: strings.TrimSpace(string(b))}
+	}
+	if err := json.Unmarshal(b, resp); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// APIError is a non-OK response of the PAM API.
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("PAM API returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// toEntitlement converts the API entitlement.
+func toEntitlement(e *entitlement) (*Entitlement, error) {
+	result := &Entitlement{Name: e.Name}
+	for _, u := range e.EligibleUsers {
+		result.Principals = append(result.Principals, u.Principals...)
+	}
+	for _, b := range e.PrivilegedAccess.GcpIamAccess.RoleBindings {
+		result.Roles = append(result.Roles, b.Role)
+	}
+	if e.MaxRequestDuration != "" {
+		d, err := parseDuration(e.MaxRequestDuration)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse max request duration of entitlement %q: %w", e.Name, err)
+		}
+		result.MaxRequestDuration = d
+	}
+	return result, nil
+}
+
+// formatDuration formats the duration in the JSON format of
+// google.protobuf.Duration, e.g. "7200s".
+func formatDuration(d time.Duration) string {
+	return strconv.FormatInt(int64(d/time.Second), 10) + "s"
+}
+
+// parseDuration parses the duration in the JSON format of
+// google.protobuf.Duration, e.g. "7200s" or "1.5s".
+func parseDuration(s string) (time.Duration, error) {
+	secs, ok := strings.CutSuffix(s, "s")
+	if !ok {
+		return 0, fmt.Errorf("duration %q must end with \"s\"", s)
+	}
+	f, err := strconv.ParseFloat(secs, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+	}
+	return time.Duration(f * float64(time.Second)), nil
+}
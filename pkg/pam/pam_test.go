@@ -0,0 +1,152 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pam
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+// fakePAM is a fake PAM API with two pages of entitlements of the project
+// "foo".
+type fakePAM struct {
+	mu    sync.Mutex
+	calls []string
+	body  string
+}
+
+func (f *fakePAM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, r.Method+" "+r.URL.RequestURI())
+
+	write := func(s string) {
+		io.WriteString(w, s) //nolint:errcheck // Test server.
+	}
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/v1/projects/foo/locations/global/entitlements":
+		if r.URL.Query().Get("pageToken") == "" {
+			write(`{
+				"entitlements": [{
+					"name": "projects/foo/locations/global/entitlements/viewer",
+					"state": "AVAILABLE",
+					"eligibleUsers": [{"principals": ["user:alice@example.com"]}, {"principals": ["group:ops@example.com"]}],
+					"maxRequestDuration": "7200s",
+					"privilegedAccess": {"gcpIamAccess": {"roleBindings": [{"role": "roles/viewer"}, {"role": "roles/logging.viewer"}]}}
+				}, {
+					"name": "projects/foo/locations/global/entitlements/deleting",
+					"state": "DELETING"
+				}],
+				"nextPageToken": "next"
+			}`)
+			return
+		}
+		write(`{"entitlements": [{
+			"name": "projects/foo/locations/global/entitlements/admin",
+			"state": "AVAILABLE",
+			"eligibleUsers": [{"principals": ["user:bob@example.com"]}],
+			"maxRequestDuration": "1800.5s",
+			"privilegedAccess": {"gcpIamAccess": {"roleBindings": [{"role": "roles/owner"}]}}
+		}]}`)
+	case "/v1/projects/foo/locations/global/entitlements/viewer/grants":
+		b, _ := io.ReadAll(r.Body)
+		f.body = string(b)
+		write(`{"name": "projects/foo/locations/global/entitlements/viewer/grants/123", "state": "ACTIVATING"}`)
+	default:
+		http.Error(w, `{"error": {"code": 403, "message": "permission denied"}}`, http.StatusForbidden)
+	}
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakePAM{}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	c, err := NewClient(ctx, option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	got, err := c.ListEntitlements(ctx, "projects/foo")
+	if err != nil {
+		t.Fatalf("ListEntitlements got unexpected error: %v", err)
+	}
+	want := []*Entitlement{
+		{
+			Name:               "projects/foo/locations/global/entitlements/viewer",
+			Principals:         []string{"user:alice@example.com", "group:ops@example.com"},
+			Roles:              []string{"roles/viewer", "roles/logging.viewer"},
+			MaxRequestDuration: 2 * time.Hour,
+		},
+		{
+			Name:               "projects/foo/locations/global/entitlements/admin",
+			Principals:         []string{"user:bob@example.com"},
+			Roles:              []string{"roles/owner"},
+			MaxRequestDuration: 30*time.Minute + 500*time.Millisecond,
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ListEntitlements got diff (-want, +got):\n%s", diff)
+	}
+
+	grant, err := c.CreateGrant(ctx, "projects/foo/locations/global/entitlements/viewer", 2*time.Hour, "on-call")
+	if err != nil {
+		t.Fatalf("CreateGrant got unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(&Grant{Name: "projects/foo/locations/global/entitlements/viewer/grants/123", State: "ACTIVATING"}, grant); diff != "" {
+		t.Errorf("CreateGrant got diff (-want, +got):\n%s", diff)
+	}
+	var gotBody map[string]any
+	if err := json.Unmarshal([]byte(fake.body), &gotBody); err != nil {
+		t.Fatal(err)
+	}
+	wantBody := map[string]any{
+		"requestedDuration": "7200s",
+		"justification":     map[string]any{"unstructuredJustification": "on-call"},
+	}
+	if diff := cmp.Diff(wantBody, gotBody); diff != "" {
+		t.Errorf("CreateGrant got request body diff (-want, +got):\n%s", diff)
+	}
+
+	_, err = c.ListEntitlements(ctx, "projects/bar")
+	if diff := testutil.DiffErrString(err, `failed to list entitlements of "projects/bar": PAM API returned status 403`); diff != "" {
+		t.Errorf("ListEntitlements got unexpected error: %s", diff)
+	}
+
+	wantCalls := []string{
+		"GET /v1/projects/foo/locations/global/entitlements",
+		"GET /v1/projects/foo/locations/global/entitlements?pageToken=next",
+		"POST /v1/projects/foo/locations/global/entitlements/viewer/grants",
+		"GET /v1/projects/bar/locations/global/entitlements",
+	}
+	if diff := cmp.Diff(wantCalls, fake.calls); diff != "" {
+		t.Errorf("got calls diff (-want, +got):\n%s", diff)
+	}
+}
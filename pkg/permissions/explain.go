@@ -0,0 +1,151 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package permissions
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+)
+
+// Statuses of permissions and commands.
+const (
+	// StatusGranted means the permission is granted by the roles of the IAM
+	// request, or all permissions of the command are granted.
+	StatusGranted = "GRANTED"
+
+	// StatusMissing means the permission is not granted by the roles of the IAM
+	// request, which are all in the dataset.
+	StatusMissing = "MISSING"
+
+	// StatusUnknown means the command is not in the dataset, or the permission
+	// is not granted by the roles in the dataset but might be granted by the
+	// other roles of the IAM request.
+	StatusUnknown = "UNKNOWN"
+)
+
+// Explanation explains whether an IAM request grants the permissions needed
+// by the commands of a tool request.
+type Explanation struct {
+	// Commands are the explanations of the commands of the tool request.
+	Commands []*CommandExplanation `yaml:"commands"`
+
+	// UnknownRoles are the roles of the IAM request not in the dataset.
+	UnknownRoles []string `yaml:"unknownRoles,omitempty"`
+}
+
+// CommandExplanation explains the permissions needed by a command.
+type CommandExplanation struct {
+	// Command of the tool request.
+	Command string `yaml:"command"`
+
+	// Matched is the command in the dataset matching the command.
+	Matched string `yaml:"matched,omitempty"`
+
+	// Status is the worst status of the permissions of the command, or
+	// "UNKNOWN" if the command is not in the dataset.
+	Status string `yaml:"status"`
+
+	// Permissions needed by the command.
+	Permissions []*PermissionExplanation `yaml:"permissions,omitempty"`
+}
+
+// PermissionExplanation explains whether a permission is granted.
+type PermissionExplanation struct {
+	// Permission needed by the command.
+	Permission string `yaml:"permission"`
+
+	// Status of the permission.
+	Status string `yaml:"status"`
+
+	// GrantedBy are the roles of the IAM request granting the permission.
+	GrantedBy []string `yaml:"grantedBy,omitempty"`
+}
+
+// Explain explains whether the roles of the IAM request grant the permissions
+// needed by the commands of the tool request. The roles are not matched with
+// the resources the commands run on.
+func Explain(tr *v1alpha1.ToolRequest, ir *v1alpha1.IAMRequest) (*Explanation, error) {
+	var roles []string
+	for _, p := range ir.ResourcePolicies {
+		for _, b := range p.Bindings {
+			roles = append(roles, b.Roles()...)
+		}
+	}
+	slices.Sort(roles)
+	roles = slices.Compact(roles)
+
+	// Permissions to the roles granting them.
+	granted := make(map[string][]string)
+	e := &Explanation{}
+	for _, r := range roles {
+		perms, ok := RolePermissions(r)
+		if !ok {
+			e.UnknownRoles = append(e.UnknownRoles, r)
+			continue
+		}
+		for _, p := range perms {
+			granted[p] = append(granted[p], r)
+		}
+	}
+
+	for _, c := range tr.Do {
+		matched, perms, ok, err := CommandPermissions(tr.Tool, c)
+		if err != nil {
+			return nil, fmt.Errorf("failed to explain command %q: %w", c, err)
+		}
+		ce := &CommandExplanation{Command: c, Matched: matched, Status: StatusUnknown}
+		if ok {
+			ce.Status = StatusGranted
+			for _, p := range perms {
+				pe := &PermissionExplanation{Permission: p, GrantedBy: granted[p]}
+				switch {
+				case len(pe.GrantedBy) > 0:
+					pe.Status = StatusGranted
+				case len(e.UnknownRoles) > 0:
+					pe.Status = StatusUnknown
+				default:
+					pe.Status = StatusMissing
+				}
+				ce.Status = worse(ce.Status, pe.Status)
+				ce.Permissions = append(ce.Permissions, pe)
+			}
+		}
+		e.Commands = append(e.Commands, ce)
+	}
+	return e, nil
+}
+
+// Missing returns the commands with missing permissions.
+func (e *Explanation) Missing() []*CommandExplanation {
+	var missing []*CommandExplanation
+	for _, c := range e.Commands {
+		if c.Status == StatusMissing {
+			missing = append(missing, c)
+		}
+	}
+	return missing
+}
+
+// worse returns the worse of the statuses, missing is worse than unknown, which
+// is worse than granted.
+func worse(a, b string) string {
+	rank := map[string]int{StatusGranted: 0, StatusUnknown: 1, StatusMissing: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,178 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package permissions
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestExplain(t *testing.T) {
+	t.Parallel()
+
+	iamRequest := func(roles ...string) *v1alpha1.IAMRequest {
+		bs := make([]*v1alpha1.Binding, 0, len(roles))
+		for _, r := range roles {
+			bs = append(bs, &v1alpha1.Binding{Members: []string{"user:foo@example.com"}, Role: r})
+		}
+		return &v1alpha1.IAMRequest{
+			ResourcePolicies: []*v1alpha1.ResourcePolicy{{Resource: "projects/foo", Bindings: bs}},
+		}
+	}
+
+	cases := []struct {
+		name        string
+		toolRequest *v1alpha1.ToolRequest
+		iamRequest  *v1alpha1.IAMRequest
+		want        *Explanation
+		wantMissing int
+		wantErr     string
+	}{
+		{
+			name: "granted",
+			toolRequest: &v1alpha1.ToolRequest{
+				Tool: "gcloud",
+				Do:   []string{"storage cp gs://foo/a gs://foo/b"},
+			},
+			iamRequest: iamRequest("roles/storage.objectViewer", "roles/storage.objectCreator"),
+			want: &Explanation{
+				Commands: []*CommandExplanation{{
+					Command: "storage cp gs://foo/a gs://foo/b",
+					Matched: "storage cp",
+					Status:  StatusGranted,
+					Permissions: []*PermissionExplanation{
+						{Permission: "storage.objects.get", Status: StatusGranted, GrantedBy: []string{"roles/storage.objectViewer"}},
+						{Permission: "storage.objects.create", Status: StatusGranted, GrantedBy: []string{"roles/storage.objectCreator"}},
+					},
+				}},
+			},
+		},
+		{
+			name: "missing",
+			toolRequest: &v1alpha1.ToolRequest{
+				Tool: "gcloud",
+				Do:   []string{"storage rm gs://foo/a", "storage ls gs://foo"},
+			},
+			iamRequest: iamRequest("roles/storage.objectViewer"),
+			want: &Explanation{
+				Commands: []*CommandExplanation{
+					{
+						Command: "storage rm gs://foo/a",
+						Matched: "storage rm",
+						Status:  StatusMissing,
+						Permissions: []*PermissionExplanation{
+							{Permission: "storage.objects.delete", Status: StatusMissing},
+						},
+					},
+					{
+						Command: "storage ls gs://foo",
+						Matched: "storage ls",
+						Status:  StatusGranted,
+						Permissions: []*PermissionExplanation{
+							{Permission: "storage.objects.list", Status: StatusGranted, GrantedBy: []string{"roles/storage.objectViewer"}},
+						},
+					},
+				},
+			},
+			wantMissing: 1,
+		},
+		{
+			name: "role_bundle",
+			toolRequest: &v1alpha1.ToolRequest{
+				Tool: "gcloud",
+				Do:   []string{"storage buckets list"},
+			},
+			iamRequest: &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+					Resource: "projects/foo",
+					Bindings: []*v1alpha1.Binding{{Members: []string{"user:foo@example.com"}, RoleBundle: "gcs-read"}},
+				}},
+			},
+			want: &Explanation{
+				Commands: []*CommandExplanation{{
+					Command: "storage buckets list",
+					Matched: "storage buckets list",
+					Status:  StatusGranted,
+					Permissions: []*PermissionExplanation{
+						{Permission: "storage.buckets.list", Status: StatusGranted, GrantedBy: []string{"roles/storage.bucketViewer"}},
+					},
+				}},
+			},
+		},
+		{
+			name: "unknown_role",
+			toolRequest: &v1alpha1.ToolRequest{
+				Tool: "gcloud",
+				Do:   []string{"storage rm gs://foo/a"},
+			},
+			iamRequest: iamRequest("roles/bananas"),
+			want: &Explanation{
+				Commands: []*CommandExplanation{{
+					Command: "storage rm gs://foo/a",
+					Matched: "storage rm",
+					Status:  StatusUnknown,
+					Permissions: []*PermissionExplanation{
+						{Permission: "storage.objects.delete", Status: StatusUnknown},
+					},
+				}},
+				UnknownRoles: []string{"roles/bananas"},
+			},
+		},
+		{
+			name: "unknown_command",
+			toolRequest: &v1alpha1.ToolRequest{
+				Tool: "gcloud",
+				Do:   []string{"bananas list"},
+			},
+			iamRequest: iamRequest("roles/viewer"),
+			want: &Explanation{
+				Commands:     []*CommandExplanation{{Command: "bananas list", Status: StatusUnknown}},
+				UnknownRoles: []string{"roles/viewer"},
+			},
+		},
+		{
+			name: "invalid_command",
+			toolRequest: &v1alpha1.ToolRequest{
+				Tool: "gcloud",
+				Do:   []string{`storage ls "gs://foo`},
+			},
+			iamRequest: iamRequest("roles/storage.objectViewer"),
+			wantErr:    `failed to explain command "storage ls \"gs://foo"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Explain(tc.toolRequest, tc.iamRequest)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Process(%+v) got explanation diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if got != nil {
+				if gotMissing := len(got.Missing()); gotMissing != tc.wantMissing {
+					t.Errorf("Process(%+v) got %d missing commands, want %d", tc.name, gotMissing, tc.wantMissing)
+				}
+			}
+		})
+	}
+}
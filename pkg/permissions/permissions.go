@@ -0,0 +1,95 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package permissions maps tool commands to the IAM permissions they need,
+// and explains whether IAM requests grant them.
+package permissions
+
+import (
+	_ "embed"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mattn/go-shellwords"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed gcloud_permissions.yaml
+var gcloudPermissionsYAML []byte
+
+// releaseTracks are the gcloud release tracks which are not part of commands.
+var releaseTracks = []string{"alpha", "beta", "preview"}
+
+// dataset is the bundled dataset of the permissions of commands and roles.
+type dataset struct {
+	// Commands maps the commands to the permissions they need.
+	Commands map[string][]string `yaml:"commands"`
+
+	// Roles maps the predefined roles to the permissions they grant.
+	Roles map[string][]string `yaml:"roles"`
+}
+
+// gcloudDataset parses the embedded gcloud dataset once.
+var gcloudDataset = sync.OnceValue(func() *dataset {
+	var d dataset
+	if err := yaml.Unmarshal(gcloudPermissionsYAML, &d); err != nil {
+		panic(fmt.Sprintf("failed to parse embedded gcloud permissions: %v", err))
+	}
+	return &d
+})
+
+// CommandPermissions returns the command in the dataset matching the tool
+// command, and the permissions it needs. The command is matched by its longest
+// prefix of command groups and command, ignoring release tracks and flags. It
+// returns false if the tool is not supported or the command is not in the
+// dataset.
+func CommandPermissions(tool, command string) (string, []string, bool, error) {
+	if tool != "gcloud" {
+		return "", nil, false, nil
+	}
+	args, err := shellwords.Parse(command)
+	if err != nil {
+		return "", nil, false, fmt.Errorf("failed to parse command %q: %w", command, err)
+	}
+
+	var words []string
+	for _, a := range args {
+		if strings.HasPrefix(a, "-") {
+			continue
+		}
+		if len(words) == 0 && slices.Contains(releaseTracks, a) {
+			continue
+		}
+		words = append(words, a)
+	}
+
+	d := gcloudDataset()
+	for i := len(words); i > 0; i-- {
+		c := strings.Join(words[:i], " ")
+		if perms, ok := d.Commands[c]; ok {
+			return c, perms, true, nil
+		}
+	}
+	return "", nil, false, nil
+}
+
+// RolePermissions returns the permissions the role grants that are needed by
+// the commands in the dataset. It returns false if the role is not in the
+// dataset.
+func RolePermissions(role string) ([]string, bool) {
+	perms, ok := gcloudDataset().Roles[role]
+	return perms, ok
+}
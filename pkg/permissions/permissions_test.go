@@ -0,0 +1,134 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package permissions
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestCommandPermissions(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name         string
+		tool         string
+		command      string
+		wantMatched  string
+		wantPerms    []string
+		wantOK       bool
+		wantErrSubst string
+	}{
+		{
+			name:        "exact_command",
+			tool:        "gcloud",
+			command:     "compute instances list --project=foo",
+			wantMatched: "compute instances list",
+			wantPerms:   []string{"compute.instances.list"},
+			wantOK:      true,
+		},
+		{
+			name:        "positional_args",
+			tool:        "gcloud",
+			command:     "storage cp gs://foo/a gs://foo/b",
+			wantMatched: "storage cp",
+			wantPerms:   []string{"storage.objects.get", "storage.objects.create"},
+			wantOK:      true,
+		},
+		{
+			name:        "release_track",
+			tool:        "gcloud",
+			command:     "beta compute instances describe foo --zone us-west1-a",
+			wantMatched: "compute instances describe",
+			wantPerms:   []string{"compute.instances.get"},
+			wantOK:      true,
+		},
+		{
+			name:    "unknown_command",
+			tool:    "gcloud",
+			command: "bananas list",
+		},
+		{
+			name:    "unsupported_tool",
+			tool:    "kubectl",
+			command: "get pods",
+		},
+		{
+			name:         "invalid_command",
+			tool:         "gcloud",
+			command:      `compute instances list --filter="name`,
+			wantErrSubst: "failed to parse command",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			gotMatched, gotPerms, gotOK, err := CommandPermissions(tc.tool, tc.command)
+			if diff := testutil.DiffErrString(err, tc.wantErrSubst); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if gotMatched != tc.wantMatched {
+				t.Errorf("Process(%+v) got matched %q, want %q", tc.name, gotMatched, tc.wantMatched)
+			}
+			if diff := cmp.Diff(tc.wantPerms, gotPerms); diff != "" {
+				t.Errorf("Process(%+v) got permissions diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if gotOK != tc.wantOK {
+				t.Errorf("Process(%+v) got ok %t, want %t", tc.name, gotOK, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestRolePermissions(t *testing.T) {
+	t.Parallel()
+
+	perms, ok := RolePermissions("roles/storage.objectViewer")
+	if !ok {
+		t.Fatalf("RolePermissions(roles/storage.objectViewer) got not found")
+	}
+	if diff := cmp.Diff([]string{"storage.objects.get", "storage.objects.list"}, perms); diff != "" {
+		t.Errorf("RolePermissions(roles/storage.objectViewer) got diff (-want, +got):\n%s", diff)
+	}
+
+	if _, ok := RolePermissions("roles/bananas"); ok {
+		t.Errorf("RolePermissions(roles/bananas) got found, want not found")
+	}
+}
+
+func TestDataset(t *testing.T) {
+	t.Parallel()
+
+	// All permissions needed by the commands should be granted by a role in the
+	// dataset, so that they are not reported missing by mistake.
+	granted := make(map[string]bool)
+	for _, perms := range gcloudDataset().Roles {
+		for _, p := range perms {
+			granted[p] = true
+		}
+	}
+	for c, perms := range gcloudDataset().Commands {
+		for _, p := range perms {
+			if !granted[p] {
+				t.Errorf("permission %q of command %q is not granted by any role", p, c)
+			}
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package plugin runs AOD plugins, external executables handling the requests
+// of custom kinds, such as access to internal databases or VPNs, with the
+// same CLI and pull request workflow as IAM requests.
+//
+// The plugin of a kind is the executable "aod-plugin-<kind>", found in the
+// plugin directories or in PATH. It is run with the operation as its only
+// argument, one of "validate", "handle" and "cleanup", and the [Request] in
+// JSON on stdin. It writes the [Response] in JSON on stdout, and exits with a
+// non-zero code or sets the error of the response on failure. Its stderr is
+// included in the error on failure.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ProtocolVersion is the version of the plugin protocol, sent in requests.
+const ProtocolVersion = "v1"
+
+// ExecutablePrefix is the prefix of the plugin executable names.
+const ExecutablePrefix = "aod-plugin-"
+
+// Operations of plugin requests.
+const (
+	// OperationValidate validates the spec of the request without side
+	// effects.
+	OperationValidate = "validate"
+
+	// OperationHandle grants the access in the request until the expiry.
+	OperationHandle = "handle"
+
+	// OperationCleanup removes the access in the request.
+	OperationCleanup = "cleanup"
+)
+
+// maxStderrSize is the max size of the stderr of plugins included in errors.
+const maxStderrSize = 4096
+
+// Request is the request sent to a plugin.
+type Request struct {
+	// ProtocolVersion is the version of the plugin protocol, "v1".
+	ProtocolVersion string `json:"protocolVersion"`
+
+	// Operation is one of "validate", "handle" and "cleanup".
+	Operation string `json:"operation"`
+
+	// Kind of the request, the name of the plugin.
+	Kind string `json:"kind"`
+
+	// Spec of the request as is in the request file.
+	Spec map[string]any `json:"spec"`
+
+	// StartTime and Expiry of the access, only set for "handle".
+	StartTime *time.Time `json:"startTime,omitempty"`
+	Expiry    *time.Time `json:"expiry,omitempty"`
+
+	// Requester of the request, if provided.
+	Requester string `json:"requester,omitempty"`
+}
+
+// Response is the response of a plugin.
+type Response struct {
+	// Message is a human readable summary of the result.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+
+	// Warnings are non-fatal problems of the request.
+	Warnings []string `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+
+	// Output is the structured result of the operation.
+	Output map[string]any `json:"output,omitempty" yaml:"output,omitempty"`
+
+	// Error is the error of the operation, it fails the operation even if the
+	// plugin exits with code zero.
+	Error string `json:"error,omitempty" yaml:"-"`
+}
+
+// Runner finds and runs plugins.
+type Runner struct {
+	dirs     []string
+	stderr   io.Writer
+	lookPath func(string) (string, error)
+}
+
+// Option is the option to set up a Runner.
+type Option func(r *Runner) *Runner
+
+// WithDirs provides the directories to find plugins in before PATH.
+func WithDirs(dirs ...string) Option {
+	return func(r *Runner) *Runner {
+		r.dirs = append(r.dirs, dirs...)
+		return r
+	}
+}
+
+// WithStderr sets the writer of the stderr of successful plugin runs, default
+// is os.Stderr.
+func WithStderr(w io.Writer) Option {
+	return func(r *Runner) *Runner {
+		r.stderr = w
+		return r
+	}
+}
+
+// NewRunner creates a new Runner with the options.
+func NewRunner(opts ...Option) *Runner {
+	r := &Runner{
+		stderr:   os.Stderr,
+		lookPath: exec.LookPath,
+	}
+	for _, opt := range opts {
+		r = opt(r)
+	}
+	return r
+}
+
+// Find returns the path of the plugin executable of the kind, from the plugin
+// directories or PATH.
+func (r *Runner) Find(kind string) (string, error) {
+	name := ExecutablePrefix + kind
+	for _, d := range r.dirs {
+		p := filepath.Join(d, name)
+		if fi, err := os.Stat(p); err == nil && !fi.IsDir() && fi.Mode()&0o111 != 0 {
+			return p, nil
+		}
+	}
+	p, err := r.lookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("plugin %q of kind %q not found: %w", name, kind, err)
+	}
+	return p, nil
+}
+
+// Run runs the operation of the request with the plugin of its kind.
+func (r *Runner) Run(ctx context.Context, req *Request) (*Response, error) {
+	path, err := r.Find(req.Kind)
+	if err != nil {
+		return nil, err
+	}
+
+	req.ProtocolVersion = ProtocolVersion
+	in, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal plugin request: %w", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, req.Operation)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+
+	var resp Response
+	if out := bytes.TrimSpace(stdout.Bytes()); len(out) > 0 {
+		if err := json.Unmarshal(out, &resp); err != nil && runErr == nil {
+			return nil, fmt.Errorf("failed to unmarshal response of plugin %q: %w", path, err)
+		}
+	}
+	if runErr == nil && resp.Error != "" {
+		runErr = errors.New(resp.Error)
+	} else if runErr != nil && resp.Error != "" {
+		runErr = fmt.Errorf("%s: %w", resp.Error, runErr)
+	}
+	if runErr != nil {
+		return nil, fmt.Errorf("plugin %q failed to %s: %w%s", path, req.Operation, runErr, stderrSuffix(stderr.Bytes()))
+	}
+
+	if _, err := r.stderr.Write(stderr.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to write plugin stderr: %w", err)
+	}
+	return &resp, nil
+}
+
+// stderrSuffix returns the stderr of a failed plugin to append to the error,
+// truncated to the last maxStderrSize bytes.
+func stderrSuffix(stderr []byte) string {
+	s := strings.TrimSpace(string(stderr))
+	if s == "" {
+		return ""
+	}
+	if len(s) > maxStderrSize {
+		s = "..." + s[len(s)-maxStderrSize:]
+	}
+	return "\n" + s
+}
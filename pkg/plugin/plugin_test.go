@@ -0,0 +1,176 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestRunner_Run(t *testing.T) {
+	t.Parallel()
+
+	expiry := time.Date(2009, 11, 11, 1, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name       string
+		script     string
+		req        *Request
+		want       *Response
+		wantStdin  string
+		wantStderr string
+		wantErr    string
+	}{
+		{
+			name: "success",
+			script: `cat > "$0.stdin"
+echo "granting $1" >&2
+echo '{"message": "granted", "warnings": ["slow"], "output": {"user": "alice"}}'`,
+			req: &Request{
+				Operation: OperationHandle,
+				Kind:      "test",
+				Spec:      map[string]any{"network": "corp"},
+				Expiry:    &expiry,
+				Requester: "user:alice@example.com",
+			},
+			want: &Response{
+				Message:  "granted",
+				Warnings: []string{"slow"},
+				Output:   map[string]any{"user": "alice"},
+			},
+			wantStdin: `{"protocolVersion":"v1","operation":"handle","kind":"test","spec":{"network":"corp"},` +
+				`"expiry":"2009-11-11T01:00:00Z","requester":"user:alice@example.com"}`,
+			wantStderr: "granting handle\n",
+		},
+		{
+			name:   "empty_response",
+			script: `exit 0`,
+			req:    &Request{Operation: OperationValidate, Kind: "test"},
+			want:   &Response{},
+		},
+		{
+			name: "exit_failure",
+			script: `echo "network not found" >&2
+exit 3`,
+			req:     &Request{Operation: OperationValidate, Kind: "test"},
+			wantErr: "failed to validate: exit status 3\nnetwork not found",
+		},
+		{
+			name:    "response_error",
+			script:  `echo '{"error": "network not found"}'`,
+			req:     &Request{Operation: OperationCleanup, Kind: "test"},
+			wantErr: "failed to cleanup: network not found",
+		},
+		{
+			name:    "invalid_response",
+			script:  `echo 'bananas'`,
+			req:     &Request{Operation: OperationHandle, Kind: "test"},
+			wantErr: "failed to unmarshal response of plugin",
+		},
+		{
+			name:    "not_found",
+			req:     &Request{Operation: OperationHandle, Kind: "missing"},
+			wantErr: `plugin "aod-plugin-missing" of kind "missing" not found`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			path := filepath.Join(dir, ExecutablePrefix+"test")
+			if tc.script != "" {
+				if err := os.WriteFile(path, []byte("#!/bin/sh\n"+tc.script+"\n"), 0o700); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var stderr bytes.Buffer
+			r := NewRunner(WithDirs(dir), WithStderr(&stderr))
+			// Do not find plugins in PATH.
+			r.lookPath = func(file string) (string, error) {
+				return "", os.ErrNotExist
+			}
+
+			got, err := r.Run(context.Background(), tc.req)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error: %s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Process(%+v) got response diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantStderr, stderr.String()); diff != "" {
+				t.Errorf("Process(%+v) got stderr diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if tc.wantStdin != "" {
+				gotStdin, err := os.ReadFile(path + ".stdin")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(tc.wantStdin, string(gotStdin)); diff != "" {
+					t.Errorf("Process(%+v) got stdin diff (-want, +got):\n%s", tc.name, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestRunner_Find(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ExecutablePrefix+"vpn"), []byte("#!/bin/sh\n"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	// Files which are not executable are not plugins.
+	if err := os.WriteFile(filepath.Join(dir, ExecutablePrefix+"db"), []byte("#!/bin/sh\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRunner(WithDirs(dir))
+	r.lookPath = func(file string) (string, error) {
+		if file == ExecutablePrefix+"db" {
+			return "/usr/bin/" + file, nil
+		}
+		return "", os.ErrNotExist
+	}
+
+	cases := []struct {
+		kind    string
+		want    string
+		wantErr string
+	}{
+		{kind: "vpn", want: filepath.Join(dir, ExecutablePrefix+"vpn")},
+		{kind: "db", want: "/usr/bin/" + ExecutablePrefix + "db"},
+		{kind: "missing", wantErr: `plugin "aod-plugin-missing" of kind "missing" not found`},
+	}
+	for _, tc := range cases {
+		got, err := r.Find(tc.kind)
+		if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+			t.Errorf("Find(%q) got unexpected error: %s", tc.kind, diff)
+		}
+		if got != tc.want {
+			t.Errorf("Find(%q) got %q, want %q", tc.kind, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package policy reads the AOD policy file, the policies of the AOD deployment
+// on the requests beyond their format, such as the roles that may or may not
+// be requested.
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"gopkg.in/yaml.v3"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/requestutil"
+)
+
+// Policy is the AOD policy file, e.g. "aod-policy.yaml".
+type Policy struct {
+	// AllowedResources are the patterns of the resources of IAM requests, e.g.
+	// "projects/dev-*". See v1alpha1.ValidateOptions.AllowedResources.
+	AllowedResources []string `yaml:"allowedResources,omitempty"`
+
+	// Roles are the policies of the roles of IAM requests. A role must be
+	// allowed by all the policies applying to the resource.
+	Roles []*v1alpha1.RolePolicy `yaml:"roles,omitempty"`
+
+	// Rules are the custom rules of IAM requests. A request must satisfy all
+	// of them.
+	Rules []*Rule `yaml:"rules,omitempty"`
+
+	// programs are the compiled Rules, set by Parse.
+	programs []cel.Program
+}
+
+// Read reads and checks the policy file at the path.
+func Read(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file %q: %w", path, err)
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy file %q: %w", path, err)
+	}
+	return p, nil
+}
+
+// Parse parses and checks the policy in YAML format. Unknown fields are
+// errors, so that misspelled policies are not silently ignored.
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to unmarshal yaml to %T: %w", &p, requestutil.ExplainUnknownFields(err, &p))
+	}
+	if err := p.ValidateOptions(0).Check(); err != nil {
+		return nil, err //nolint:wrapcheck // Want passthrough
+	}
+	programs, err := compileRules(p.Rules)
+	if err != nil {
+		return nil, err
+	}
+	p.programs = programs
+	return &p, nil
+}
+
+// ValidateOptions returns the options to validate requests with the policy,
+// which are handled with the duration, or 0 if it is not known.
+func (p *Policy) ValidateOptions(duration time.Duration) *v1alpha1.ValidateOptions {
+	opts := &v1alpha1.ValidateOptions{
+		AllowedResources: p.AllowedResources,
+		RolePolicies:     p.Roles,
+	}
+	if len(p.programs) > 0 {
+		opts.CustomCheck = func(r *v1alpha1.IAMRequest) error {
+			return p.CheckRules(r, duration)
+		}
+	}
+	return opts
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestRead(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		data    string
+		want    *Policy
+		wantErr string
+	}{
+		{
+			name: "success",
+			data: `
+allowedResources:
+  - folders/123
+  - projects/dev-*
+roles:
+  - deny:
+      - roles/owner
+      - roles/iam.securityAdmin
+  - resources:
+      - projects/prod-*
+    allow:
+      - roles/viewer
+      - roles/storage.*
+`,
+			want: &Policy{
+				AllowedResources: []string{"folders/123", "projects/dev-*"},
+				Roles: []*v1alpha1.RolePolicy{
+					{Deny: []string{"roles/owner", "roles/iam.securityAdmin"}},
+					{Resources: []string{"projects/prod-*"}, Allow: []string{"roles/viewer", "roles/storage.*"}},
+				},
+			},
+		},
+		{
+			name: "empty",
+			data: "",
+			want: &Policy{},
+		},
+		{
+			name:    "unknown_field",
+			data:    "roles:\n  - denied: [roles/owner]\n",
+			wantErr: `line 2: unknown field "denied" in RolePolicy, must be one of ["resources" "allow" "deny"]`,
+		},
+		{
+			name:    "invalid_pattern",
+			data:    "roles:\n  - deny: ['roles/[']\n",
+			wantErr: `role policy 0 pattern "roles/[" is not valid`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "aod-policy.yaml")
+			if err := os.WriteFile(path, []byte(tc.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Read(path)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected err: %s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.want, got, cmpopts.IgnoreUnexported(Policy{})); diff != "" {
+				t.Errorf("Process(%+v) got policy diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestRead_NotFound(t *testing.T) {
+	t.Parallel()
+
+	_, err := Read(filepath.Join(t.TempDir(), "missing.yaml"))
+	if diff := testutil.DiffErrString(err, "failed to read policy file"); diff != "" {
+		t.Errorf("Read() got unexpected err: %s", diff)
+	}
+}
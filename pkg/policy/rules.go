@@ -0,0 +1,196 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/google/cel-go/cel"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+)
+
+// Rule is a custom rule of IAM requests, a CEL expression which must evaluate
+// to true for a request to be allowed. The variables are:
+//
+//   - request: the IAM request, with "policies" in the schema of the request
+//     file, and "duration", the duration the request is handled with, or 0 if
+//     it is not known, e.g. when the request is only validated.
+//   - bindings: the bindings of the request flattened to one per role, with
+//     "resource", "role" and "members". The roles of role bundles are
+//     expanded.
+//
+// For example:
+//
+//	request.duration <= duration('4h') || !bindings.exists(b, b.role.startsWith('roles/iam'))
+type Rule struct {
+	// Name is the name of the rule, shown when a request violates it.
+	Name string `yaml:"name"`
+
+	// Expression is the CEL expression of the rule.
+	Expression string `yaml:"expression"`
+
+	// Message is the optional message shown when a request violates the
+	// rule, e.g. how to request the access instead.
+	Message string `yaml:"message,omitempty"`
+}
+
+// RuleResult is the result of evaluating a rule against a request.
+type RuleResult struct {
+	Name    string `yaml:"name"`
+	Passed  bool   `yaml:"passed"`
+	Message string `yaml:"message,omitempty"`
+}
+
+// newRuleEnv returns the CEL environment of the rules.
+func newRuleEnv() (*cel.Env, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("request", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("bindings", cel.ListType(cel.MapType(cel.StringType, cel.DynType))),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+	return env, nil
+}
+
+// compileRules compiles the rules to programs, in the same order.
+func compileRules(rules []*Rule) ([]cel.Program, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	env, err := newRuleEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	var retErr error
+	names := make(map[string]struct{}, len(rules))
+	programs := make([]cel.Program, 0, len(rules))
+	for i, r := range rules {
+		if r.Name == "" {
+			retErr = errors.Join(retErr, fmt.Errorf("rule %d name is required", i))
+		} else if _, ok := names[r.Name]; ok {
+			retErr = errors.Join(retErr, fmt.Errorf("rule name %q is duplicated", r.Name))
+		}
+		names[r.Name] = struct{}{}
+
+		ast, iss := env.Compile(r.Expression)
+		if iss.Err() != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("rule %q expression is not valid: %w", r.Name, iss.Err()))
+			continue
+		}
+		if !reflect.DeepEqual(ast.OutputType(), cel.BoolType) {
+			retErr = errors.Join(retErr, fmt.Errorf("rule %q expression must evaluate to bool, got %s", r.Name, ast.OutputType()))
+			continue
+		}
+		prg, err := env.Program(ast)
+		if err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("failed to create program of rule %q: %w", r.Name, err))
+			continue
+		}
+		programs = append(programs, prg)
+	}
+	if retErr != nil {
+		return nil, retErr
+	}
+	return programs, nil
+}
+
+// EvalRules evaluates the rules of the policy against the IAM request handled
+// with the duration, which is 0 if it is not known. An error is returned if a
+// rule cannot be evaluated, not if the request violates it.
+func (p *Policy) EvalRules(r *v1alpha1.IAMRequest, duration time.Duration) ([]*RuleResult, error) {
+	if len(p.programs) == 0 {
+		return nil, nil
+	}
+	vars := ruleVars(r, duration)
+	results := make([]*RuleResult, 0, len(p.programs))
+	for i, prg := range p.programs {
+		rule := p.Rules[i]
+		out, _, err := prg.Eval(vars)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate rule %q: %w", rule.Name, err)
+		}
+		passed, ok := out.Value().(bool)
+		if !ok {
+			return nil, fmt.Errorf("rule %q evaluated to %T, want bool", rule.Name, out.Value())
+		}
+		res := &RuleResult{Name: rule.Name, Passed: passed}
+		if !passed {
+			res.Message = rule.Message
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// CheckRules returns an error if the IAM request handled with the duration,
+// which is 0 if it is not known, violates any of the rules of the policy.
+func (p *Policy) CheckRules(r *v1alpha1.IAMRequest, duration time.Duration) (retErr error) {
+	results, err := p.EvalRules(r, duration)
+	if err != nil {
+		return err
+	}
+	for _, res := range results {
+		if res.Passed {
+			continue
+		}
+		if res.Message != "" {
+			retErr = errors.Join(retErr, fmt.Errorf("request violates rule %q: %s", res.Name, res.Message))
+		} else {
+			retErr = errors.Join(retErr, fmt.Errorf("request violates rule %q", res.Name))
+		}
+	}
+	return retErr
+}
+
+// ruleVars returns the variables of the rules for the IAM request.
+func ruleVars(r *v1alpha1.IAMRequest, duration time.Duration) map[string]any {
+	policies := make([]any, 0, len(r.ResourcePolicies))
+	bindings := make([]any, 0)
+	for _, p := range r.ResourcePolicies {
+		pb := make([]any, 0, len(p.Bindings))
+		for _, b := range p.Bindings {
+			pb = append(pb, map[string]any{
+				"members":    b.Members,
+				"role":       b.Role,
+				"roleBundle": b.RoleBundle,
+			})
+			for _, role := range b.Roles() {
+				bindings = append(bindings, map[string]any{
+					"resource": p.Resource,
+					"role":     role,
+					"members":  b.Members,
+				})
+			}
+		}
+		policies = append(policies, map[string]any{
+			"resource":  p.Resource,
+			"bindings":  pb,
+			"dependsOn": p.DependsOn,
+		})
+	}
+	return map[string]any{
+		"request": map[string]any{
+			"policies": policies,
+			"duration": duration,
+		},
+		"bindings": bindings,
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestCheckRules(t *testing.T) {
+	t.Parallel()
+
+	rules := `
+rules:
+  - name: short-iam-grants
+    expression: request.duration <= duration('4h') || !bindings.exists(b, b.role.startsWith('roles/iam'))
+    message: IAM roles can be requested for at most 4h.
+  - name: no-prod-bundles
+    expression: "!bindings.exists(b, b.resource == 'projects/prod' && b.role == 'roles/logging.viewer')"
+`
+	iamRequest := func(b *v1alpha1.Binding) *v1alpha1.IAMRequest {
+		return &v1alpha1.IAMRequest{
+			ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+				Resource: "projects/prod",
+				Bindings: []*v1alpha1.Binding{b},
+			}},
+		}
+	}
+
+	cases := []struct {
+		name        string
+		policy      string
+		request     *v1alpha1.IAMRequest
+		duration    time.Duration
+		wantResults []*RuleResult
+		wantErr     string
+	}{
+		{
+			name:     "passed",
+			policy:   rules,
+			request:  iamRequest(&v1alpha1.Binding{Members: []string{"user:alice@example.com"}, Role: "roles/iam.roleViewer"}),
+			duration: 2 * time.Hour,
+			wantResults: []*RuleResult{
+				{Name: "short-iam-grants", Passed: true},
+				{Name: "no-prod-bundles", Passed: true},
+			},
+		},
+		{
+			name:     "violated_with_message",
+			policy:   rules,
+			request:  iamRequest(&v1alpha1.Binding{Members: []string{"user:alice@example.com"}, Role: "roles/iam.roleViewer"}),
+			duration: 8 * time.Hour,
+			wantResults: []*RuleResult{
+				{Name: "short-iam-grants", Passed: false, Message: "IAM roles can be requested for at most 4h."},
+				{Name: "no-prod-bundles", Passed: true},
+			},
+			wantErr: `request violates rule "short-iam-grants": IAM roles can be requested for at most 4h.`,
+		},
+		{
+			name:    "violated_by_role_bundle",
+			policy:  rules,
+			request: iamRequest(&v1alpha1.Binding{Members: []string{"user:alice@example.com"}, RoleBundle: "project-viewer-plus-logs"}),
+			wantResults: []*RuleResult{
+				{Name: "short-iam-grants", Passed: true},
+				{Name: "no-prod-bundles", Passed: false},
+			},
+			wantErr: `request violates rule "no-prod-bundles"`,
+		},
+		{
+			name:    "no_rules",
+			policy:  "",
+			request: iamRequest(&v1alpha1.Binding{Members: []string{"user:alice@example.com"}, Role: "roles/owner"}),
+		},
+		{
+			name: "evaluation_failure",
+			policy: `
+rules:
+  - name: missing-key
+    expression: request.requester == 'alice'
+`,
+			request: iamRequest(&v1alpha1.Binding{Members: []string{"user:alice@example.com"}, Role: "roles/viewer"}),
+			wantErr: `failed to evaluate rule "missing-key"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p, err := Parse([]byte(tc.policy))
+			if err != nil {
+				t.Fatalf("failed to parse policy: %v", err)
+			}
+
+			gotResults, _ := p.EvalRules(tc.request, tc.duration)
+			if diff := cmp.Diff(tc.wantResults, gotResults); diff != "" {
+				t.Errorf("Process(%+v) got results diff (-want, +got): %v", tc.name, diff)
+			}
+			err = p.CheckRules(tc.request, tc.duration)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected err: %s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestParse_Rules(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name:    "invalid_expression",
+			data:    "rules:\n  - name: foo\n    expression: 'bindings.exists(b,'\n",
+			wantErr: `rule "foo" expression is not valid`,
+		},
+		{
+			name:    "not_bool",
+			data:    "rules:\n  - name: foo\n    expression: size(bindings)\n",
+			wantErr: `rule "foo" expression must evaluate to bool, got int`,
+		},
+		{
+			name:    "missing_name",
+			data:    "rules:\n  - expression: 'true'\n",
+			wantErr: "rule 0 name is required",
+		},
+		{
+			name:    "duplicated_name",
+			data:    "rules:\n  - name: foo\n    expression: 'true'\n  - name: foo\n    expression: 'false'\n",
+			wantErr: `rule name "foo" is duplicated`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse([]byte(tc.data))
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected err: %s", tc.name, diff)
+			}
+		})
+	}
+}
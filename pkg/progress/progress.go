@@ -0,0 +1,102 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package progress contains the progress events of handling resources and the
+// reporters to report them to.
+package progress
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Types of progress events.
+const (
+	// EventTypeStarted is the type of events when a resource starts to be
+	// handled.
+	EventTypeStarted = "STARTED"
+
+	// EventTypeRetry is the type of events when handling a resource is retried
+	// after a failed attempt.
+	EventTypeRetry = "RETRY"
+
+	// EventTypeCompleted is the type of events when a resource is handled
+	// successfully.
+	EventTypeCompleted = "COMPLETED"
+
+	// EventTypeFailed is the type of events when a resource fails to be handled.
+	EventTypeFailed = "FAILED"
+
+	// EventTypeSkipped is the type of events when a resource is skipped as there
+	// is nothing to change, or as it is not active such as pending deletion.
+	EventTypeSkipped = "SKIPPED"
+)
+
+// Event is a progress event of handling a resource.
+type Event struct {
+	// Type of the event, one of "STARTED", "RETRY", "COMPLETED", "FAILED" and
+	// "SKIPPED".
+	Type string `json:"type"`
+
+	// Time when the event happened.
+	Time time.Time `json:"time"`
+
+	// Resource is the GCP resource being handled.
+	Resource string `json:"resource"`
+
+	// Attempt is the number of the attempt about to start, only set for "RETRY"
+	// events, the first retry is attempt 2.
+	Attempt int `json:"attempt,omitempty"`
+
+	// Error message of the failed attempt for "RETRY" events, of the failure
+	// for "FAILED" events, or why the resource is not active for "SKIPPED"
+	// events.
+	Error string `json:"error,omitempty"`
+}
+
+// Reporter reports progress events.
+type Reporter interface {
+	Report(ctx context.Context, e *Event) error
+}
+
+// JSONReporter reports progress events as JSON lines to a writer. It is safe
+// for concurrent use.
+type JSONReporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONReporter creates a new JSONReporter writing to w.
+func NewJSONReporter(w io.Writer) *JSONReporter {
+	return &JSONReporter{w: w}
+}
+
+// Report writes the event as a single JSON line.
+func (r *JSONReporter) Report(_ context.Context, e *Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal progress event: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := fmt.Fprintf(r.w, "%s\n", b); err != nil {
+		return fmt.Errorf("failed to write progress event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package progress
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestJSONReporter_Report(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	r := NewJSONReporter(&buf)
+	for _, e := range []*Event{
+		{Type: EventTypeStarted, Time: now, Resource: "projects/baz"},
+		{Type: EventTypeRetry, Time: now, Resource: "projects/baz", Attempt: 2, Error: "injected error"},
+		{Type: EventTypeCompleted, Time: now, Resource: "projects/baz"},
+	} {
+		if err := r.Report(ctx, e); err != nil {
+			t.Fatalf("Report got unexpected error: %v", err)
+		}
+	}
+
+	want := `{"type":"STARTED","time":"2009-11-10T23:00:00Z","resource":"projects/baz"}
+{"type":"RETRY","time":"2009-11-10T23:00:00Z","resource":"projects/baz","attempt":2,"error":"injected error"}
+{"type":"COMPLETED","time":"2009-11-10T23:00:00Z","resource":"projects/baz"}
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("Report got output diff (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package receipt signs and verifies grant receipts, the records of the IAM
+// requests handled by AOD. The cleanup of a request presents its receipt to
+// remove exactly the bindings that were granted, even if the request file
+// changed after it was handled.
+package receipt
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+)
+
+// Receipt is the signed record of a handled IAM request.
+type Receipt struct {
+	// GrantID is the SHA256 hash of the request file in hex, which identifies
+	// the grant.
+	GrantID string `yaml:"grantId"`
+
+	// Request is the validated IAM request that was handled.
+	Request *v1alpha1.IAMRequest `yaml:"request"`
+
+	// StartTime is when the granted bindings start.
+	StartTime time.Time `yaml:"startTime"`
+
+	// Expiry is when the granted bindings expire.
+	Expiry time.Time `yaml:"expiry"`
+
+	// Signature is the HMAC-SHA256 of the other fields in base64.
+	Signature string `yaml:"signature,omitempty"`
+}
+
+// Resources returns the resources of the receipt.
+func (r *Receipt) Resources() []string {
+	if r.Request == nil {
+		return nil
+	}
+	resources := make([]string, 0, len(r.Request.ResourcePolicies)+len(r.Request.DenyPolicies))
+	for _, p := range r.Request.ResourcePolicies {
+		resources = append(resources, p.Resource)
+	}
+	for _, p := range r.Request.DenyPolicies {
+		resources = append(resources, p.Resource)
+	}
+	return resources
+}
+
+// Sign sets the signature of the receipt with the key.
+func Sign(r *Receipt, key []byte) error {
+	if len(key) == 0 {
+		return fmt.Errorf("receipt key is required")
+	}
+	mac, err := sign(r, key)
+	if err != nil {
+		return err
+	}
+	r.Signature = base64.StdEncoding.EncodeToString(mac)
+	return nil
+}
+
+// Verify returns an error if the receipt is not signed with the key, or any of
+// its fields changed after it was signed.
+func Verify(r *Receipt, key []byte) error {
+	if len(key) == 0 {
+		return fmt.Errorf("receipt key is required")
+	}
+	if r.Signature == "" {
+		return fmt.Errorf("receipt of grant %q is not signed", r.GrantID)
+	}
+	got, err := base64.StdEncoding.DecodeString(r.Signature)
+	if err != nil {
+		return fmt.Errorf("failed to decode signature of receipt of grant %q: %w", r.GrantID, err)
+	}
+	want, err := sign(r, key)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(got, want) {
+		return fmt.Errorf("invalid signature of receipt of grant %q", r.GrantID)
+	}
+	if r.Request == nil {
+		return fmt.Errorf("receipt of grant %q has no request", r.GrantID)
+	}
+	return nil
+}
+
+// Write writes the receipts to w as YAML documents.
+func Write(w io.Writer, receipts []*Receipt) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	for _, r := range receipts {
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("failed to encode receipt of grant %q: %w", r.GrantID, err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to close yaml encoder: %w", err)
+	}
+	return nil
+}
+
+// Read reads the receipts of the YAML documents in r. The receipts are not
+// verified.
+func Read(r io.Reader) ([]*Receipt, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	var receipts []*Receipt
+	for {
+		var rc Receipt
+		if err := dec.Decode(&rc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to decode receipt %d: %w", len(receipts)+1, err)
+		}
+		receipts = append(receipts, &rc)
+	}
+	if len(receipts) == 0 {
+		return nil, fmt.Errorf("no receipts found")
+	}
+	return receipts, nil
+}
+
+// sign returns the HMAC-SHA256 of the receipt without its signature.
+func sign(r *Receipt, key []byte) ([]byte, error) {
+	b, err := payload(r)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(b)
+	return mac.Sum(nil), nil
+}
+
+// payload returns the canonical YAML encoding of the receipt without its
+// signature. The receipt is encoded after a round trip through YAML, so that
+// the payload is the same whether the receipt was just created or read from
+// its file.
+func payload(r *Receipt) ([]byte, error) {
+	unsigned := *r
+	unsigned.Signature = ""
+	unsigned.StartTime = unsigned.StartTime.UTC()
+	unsigned.Expiry = unsigned.Expiry.UTC()
+
+	b, err := yaml.Marshal(&unsigned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode receipt of grant %q: %w", r.GrantID, err)
+	}
+	var decoded Receipt
+	if err := yaml.NewDecoder(bytes.NewReader(b)).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode receipt of grant %q: %w", r.GrantID, err)
+	}
+	b, err = yaml.Marshal(&decoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode receipt of grant %q: %w", r.GrantID, err)
+	}
+	return b, nil
+}
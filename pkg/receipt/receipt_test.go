@@ -0,0 +1,212 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package receipt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func testReceipt() *Receipt {
+	start := time.Date(2009, 11, 10, 23, 0, 0, 0, time.FixedZone("PST", -8*60*60))
+	return &Receipt{
+		GrantID: "abc123",
+		Request: &v1alpha1.IAMRequest{
+			ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+				Resource: "projects/foo",
+				Bindings: []*v1alpha1.Binding{{
+					Members:     []string{"user:alice@example.com"},
+					Role:        "roles/viewer",
+					StartOffset: 30 * time.Minute,
+				}},
+				DependsOn: []string{},
+			}},
+			DenyPolicies: []*v1alpha1.DenyPolicy{{Resource: "folders/bar"}},
+		},
+		StartTime: start,
+		Expiry:    start.Add(2 * time.Hour),
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("s3cr3t")
+
+	cases := []struct {
+		name          string
+		key           []byte
+		modify        func(r *Receipt)
+		wantErrSubstr string
+	}{
+		{
+			name: "valid",
+			key:  key,
+		},
+		{
+			name: "changed_binding",
+			key:  key,
+			modify: func(r *Receipt) {
+				r.Request.ResourcePolicies[0].Bindings[0].Members = append(r.Request.ResourcePolicies[0].Bindings[0].Members, "user:bob@example.com")
+			},
+			wantErrSubstr: `invalid signature of receipt of grant "abc123"`,
+		},
+		{
+			name: "changed_expiry",
+			key:  key,
+			modify: func(r *Receipt) {
+				r.Expiry = r.Expiry.Add(time.Hour)
+			},
+			wantErrSubstr: `invalid signature of receipt of grant "abc123"`,
+		},
+		{
+			name:          "wrong_key",
+			key:           []byte("other"),
+			wantErrSubstr: `invalid signature of receipt of grant "abc123"`,
+		},
+		{
+			name:          "missing_key",
+			wantErrSubstr: "receipt key is required",
+		},
+		{
+			name: "unsigned",
+			key:  key,
+			modify: func(r *Receipt) {
+				r.Signature = ""
+			},
+			wantErrSubstr: `receipt of grant "abc123" is not signed`,
+		},
+		{
+			name: "malformed_signature",
+			key:  key,
+			modify: func(r *Receipt) {
+				r.Signature = "!!"
+			},
+			wantErrSubstr: `failed to decode signature of receipt of grant "abc123"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := testReceipt()
+			if err := Sign(r, key); err != nil {
+				t.Fatalf("Sign got unexpected error: %v", err)
+			}
+
+			// Verify the receipt read from its file.
+			var buf bytes.Buffer
+			if err := Write(&buf, []*Receipt{r}); err != nil {
+				t.Fatalf("Write got unexpected error: %v", err)
+			}
+			got, err := Read(&buf)
+			if err != nil {
+				t.Fatalf("Read got unexpected error: %v", err)
+			}
+			if tc.modify != nil {
+				tc.modify(got[0])
+			}
+
+			err = Verify(got[0], tc.key)
+			if diff := testutil.DiffErrString(err, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		in            string
+		want          []*Receipt
+		wantErrSubstr string
+	}{
+		{
+			name: "multiple",
+			in: `grantId: a
+request:
+  policies:
+    - resource: projects/foo
+startTime: 2009-11-10T23:00:00Z
+expiry: 2009-11-11T01:00:00Z
+signature: c2ln
+---
+grantId: b
+request: {}
+startTime: 2009-11-10T23:00:00Z
+expiry: 2009-11-11T01:00:00Z
+`,
+			want: []*Receipt{
+				{
+					GrantID:   "a",
+					Request:   &v1alpha1.IAMRequest{ResourcePolicies: []*v1alpha1.ResourcePolicy{{Resource: "projects/foo"}}},
+					StartTime: time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC),
+					Expiry:    time.Date(2009, 11, 11, 1, 0, 0, 0, time.UTC),
+					Signature: "c2ln",
+				},
+				{
+					GrantID:   "b",
+					Request:   &v1alpha1.IAMRequest{},
+					StartTime: time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC),
+					Expiry:    time.Date(2009, 11, 11, 1, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name:          "empty",
+			in:            "",
+			wantErrSubstr: "no receipts found",
+		},
+		{
+			name:          "unknown_field",
+			in:            "grantId: a\nbananas: true\n",
+			wantErrSubstr: "failed to decode receipt 1",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Read(strings.NewReader(tc.in))
+			if diff := testutil.DiffErrString(err, tc.wantErrSubstr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error substring: %v", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Process(%+v) got receipts diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestReceipt_Resources(t *testing.T) {
+	t.Parallel()
+
+	want := []string{"projects/foo", "folders/bar"}
+	if diff := cmp.Diff(want, testReceipt().Resources()); diff != "" {
+		t.Errorf("Resources got diff (-want, +got): %v", diff)
+	}
+}
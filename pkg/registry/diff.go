@@ -0,0 +1,87 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"time"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+)
+
+// GrantDiff is the difference between the active grants at two points in
+// time, for access reviews.
+type GrantDiff struct {
+	// From is the earlier point in time.
+	From time.Time `json:"from" yaml:"from"`
+
+	// To is the later point in time.
+	To time.Time `json:"to" yaml:"to"`
+
+	// Gained are the grants active at To but not at From, with their expiry
+	// at To.
+	Gained []*v1alpha1.ActiveGrant `json:"gained,omitempty" yaml:"gained,omitempty"`
+
+	// Lost are the grants active at From but not at To, with their expiry at
+	// From.
+	Lost []*v1alpha1.ActiveGrant `json:"lost,omitempty" yaml:"lost,omitempty"`
+
+	// Extended are the grants active at both, renewed to a later expiry, with
+	// their expiry at To.
+	Extended []*v1alpha1.ActiveGrant `json:"extended,omitempty" yaml:"extended,omitempty"`
+}
+
+// GrantsAt returns the grants which are active at t according to the records
+// made until t, see [ActiveGrants].
+func GrantsAt(records []*Record, t time.Time) []*v1alpha1.ActiveGrant {
+	until := make([]*Record, 0, len(records))
+	for _, r := range records {
+		if !r.Time.After(t) {
+			until = append(until, r)
+		}
+	}
+	return ActiveGrants(until, t)
+}
+
+// DiffGrants returns the difference between the active grants at from and at
+// to according to the records.
+func DiffGrants(records []*Record, from, to time.Time) *GrantDiff {
+	before := make(map[grantKey]*v1alpha1.ActiveGrant)
+	for _, g := range GrantsAt(records, from) {
+		before[grantKey{resource: g.Resource, role: g.Role, member: g.Member}] = g
+	}
+
+	d := &GrantDiff{From: from, To: to}
+	for _, g := range GrantsAt(records, to) {
+		k := grantKey{resource: g.Resource, role: g.Role, member: g.Member}
+		prev, ok := before[k]
+		delete(before, k)
+		switch {
+		case !ok:
+			d.Gained = append(d.Gained, g)
+		case g.Expiry.After(prev.Expiry):
+			d.Extended = append(d.Extended, g)
+		}
+	}
+	for _, g := range before {
+		d.Lost = append(d.Lost, g)
+	}
+	sortGrants(d.Lost)
+	return d
+}
+
+// Empty returns whether no grant was gained, lost or extended.
+func (d *GrantDiff) Empty() bool {
+	return len(d.Gained) == 0 && len(d.Lost) == 0 && len(d.Extended) == 0
+}
@@ -0,0 +1,76 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+)
+
+func TestDiffGrants(t *testing.T) {
+	t.Parallel()
+
+	from := time.Date(2009, 11, 10, 0, 0, 0, 0, time.UTC)
+	to := from.Add(7 * 24 * time.Hour)
+	ptr := func(t time.Time) *time.Time { return &t }
+
+	records := []*Record{
+		// Active at both.
+		{Type: audit.EventTypeGrant, Time: from.Add(-time.Hour), Resource: "projects/foo", Role: "roles/owner", Members: []string{"user:admin@example.com"}, Expiry: ptr(to.Add(time.Hour))},
+		// Renewed in between.
+		{Type: audit.EventTypeGrant, Time: from.Add(-time.Hour), Resource: "projects/foo", Role: "roles/viewer", Members: []string{"user:alice@example.com"}, Expiry: ptr(from.Add(time.Hour))},
+		{Type: audit.EventTypeRenew, Time: from.Add(30 * time.Minute), Resource: "projects/foo", Role: "roles/viewer", Members: []string{"user:alice@example.com"}, Expiry: ptr(to.Add(2 * time.Hour))},
+		// Revoked in between.
+		{Type: audit.EventTypeGrant, Time: from.Add(-time.Hour), Resource: "projects/foo", Role: "roles/editor", Members: []string{"user:bob@example.com"}, Expiry: ptr(to.Add(time.Hour))},
+		{Type: audit.EventTypeRevoke, Time: from.Add(time.Hour), Resource: "projects/foo", Role: "roles/editor", Members: []string{"user:bob@example.com"}},
+		// Expired in between.
+		{Type: audit.EventTypeGrant, Time: from.Add(-time.Hour), Resource: "projects/bar", Role: "roles/viewer", Members: []string{"user:carol@example.com"}, Expiry: ptr(from.Add(time.Hour))},
+		// Granted in between.
+		{Type: audit.EventTypeGrant, Time: from.Add(24 * time.Hour), Resource: "projects/bar", Role: "roles/editor", Members: []string{"user:dave@example.com"}, Expiry: ptr(to.Add(time.Hour))},
+		// Granted after to.
+		{Type: audit.EventTypeGrant, Time: to.Add(time.Minute), Resource: "projects/bar", Role: "roles/owner", Members: []string{"user:erin@example.com"}, Expiry: ptr(to.Add(time.Hour))},
+	}
+
+	got := DiffGrants(records, from, to)
+	want := &GrantDiff{
+		From: from,
+		To:   to,
+		Gained: []*v1alpha1.ActiveGrant{
+			{Resource: "projects/bar", Role: "roles/editor", Member: "user:dave@example.com", Expiry: to.Add(time.Hour)},
+		},
+		Lost: []*v1alpha1.ActiveGrant{
+			{Resource: "projects/bar", Role: "roles/viewer", Member: "user:carol@example.com", Expiry: from.Add(time.Hour)},
+			{Resource: "projects/foo", Role: "roles/editor", Member: "user:bob@example.com", Expiry: to.Add(time.Hour)},
+		},
+		Extended: []*v1alpha1.ActiveGrant{
+			{Resource: "projects/foo", Role: "roles/viewer", Member: "user:alice@example.com", Expiry: to.Add(2 * time.Hour)},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("DiffGrants got diff (-want, +got):\n%s", diff)
+	}
+	if got.Empty() {
+		t.Errorf("DiffGrants got empty diff, want changes")
+	}
+
+	if got := DiffGrants(records, to, to); !got.Empty() {
+		t.Errorf("DiffGrants got %+v at the same time, want empty", got)
+	}
+}
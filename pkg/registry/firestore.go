@@ -0,0 +1,95 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/option"
+)
+
+// DefaultCollection is the default Firestore collection of the records.
+const DefaultCollection = "aod-grants"
+
+// FirestoreStore stores the records as documents in a Firestore collection.
+type FirestoreStore struct {
+	client     *firestore.Client
+	collection string
+}
+
+// NewFirestoreStore creates a new FirestoreStore storing to the "aod-grants"
+// collection of the Firestore database in the project.
+func NewFirestoreStore(ctx context.Context, projectID, databaseID string, opts ...option.ClientOption) (*FirestoreStore, error) {
+	client, err := firestore.NewClientWithDatabase(ctx, projectID, databaseID, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create firestore client: %w", err)
+	}
+	return &FirestoreStore{
+		client:     client,
+		collection: DefaultCollection,
+	}, nil
+}
+
+// Close closes the Firestore client.
+func (s *FirestoreStore) Close() error {
+	if err := s.client.Close(); err != nil {
+		return fmt.Errorf("failed to close firestore client: %w", err)
+	}
+	return nil
+}
+
+// Add adds the record as a new document.
+func (s *FirestoreStore) Add(ctx context.Context, r *Record) error {
+	if _, err := s.client.Collection(s.collection).NewDoc().Create(ctx, r); err != nil {
+		return fmt.Errorf("failed to create document: %w", err)
+	}
+	return nil
+}
+
+// Query returns the records matching the query, most recent first. Querying
+// by both resource and member requires a composite index on resource, members
+// and time, see docs/registry.md.
+func (s *FirestoreStore) Query(ctx context.Context, q *Query) ([]*Record, error) {
+	fq := s.client.Collection(s.collection).Query
+	if q.Resource != "" {
+		fq = fq.Where("resource", "==", q.Resource)
+	}
+	if q.Member != "" {
+		fq = fq.Where("members", "array-contains", q.Member)
+	}
+	if q.RequestHash != "" {
+		fq = fq.Where("requestHash", "==", q.RequestHash)
+	}
+	fq = fq.OrderBy("time", firestore.Desc)
+	if q.Limit > 0 {
+		fq = fq.Limit(q.Limit)
+	}
+
+	docs, err := fq.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query documents: %w", err)
+	}
+	result := make([]*Record, 0, len(docs))
+	for _, d := range docs {
+		var r Record
+		if err := d.DataTo(&r); err != nil {
+			return nil, fmt.Errorf("failed to decode document %q: %w", d.Ref.ID, err)
+		}
+		result = append(result, &r)
+	}
+	return result, nil
+}
@@ -0,0 +1,161 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestFirestoreStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	expiry := now.Add(2 * time.Hour)
+	record := &Record{
+		Type:        "GRANT",
+		Time:        now,
+		Resource:    "projects/baz",
+		Role:        "roles/viewer",
+		Members:     []string{"user:alice@example.com"},
+		Expiry:      &expiry,
+		Requester:   "user:alice@example.com",
+		RequestHash: "abc123",
+	}
+	recordFields := map[string]*firestorepb.Value{
+		"type":        {ValueType: &firestorepb.Value_StringValue{StringValue: "GRANT"}},
+		"time":        {ValueType: &firestorepb.Value_TimestampValue{TimestampValue: timestamppb.New(now)}},
+		"resource":    {ValueType: &firestorepb.Value_StringValue{StringValue: "projects/baz"}},
+		"role":        {ValueType: &firestorepb.Value_StringValue{StringValue: "roles/viewer"}},
+		"members":     stringArray("user:alice@example.com"),
+		"expiry":      {ValueType: &firestorepb.Value_TimestampValue{TimestampValue: timestamppb.New(expiry)}},
+		"requester":   {ValueType: &firestorepb.Value_StringValue{StringValue: "user:alice@example.com"}},
+		"requestHash": {ValueType: &firestorepb.Value_StringValue{StringValue: "abc123"}},
+	}
+
+	server := &fakeFirestoreServer{
+		docs: []*firestorepb.Document{
+			{
+				Name:       "projects/test-project/databases/(default)/documents/aod-grants/doc1",
+				Fields:     recordFields,
+				CreateTime: timestamppb.New(now),
+				UpdateTime: timestamppb.New(now),
+			},
+		},
+	}
+	_, conn := testutil.FakeGRPCServer(t, func(s *grpc.Server) {
+		firestorepb.RegisterFirestoreServer(s, server)
+	})
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	store, err := NewFirestoreStore(ctx, "test-project", "(default)", option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	if err := store.Add(ctx, record); err != nil {
+		t.Fatalf("Add got unexpected error: %v", err)
+	}
+	server.mu.Lock()
+	if got, want := len(server.commits), 1; got != want {
+		t.Fatalf("got %d commits, want %d", got, want)
+	}
+	gotFields := server.commits[0].GetWrites()[0].GetUpdate().GetFields()
+	server.mu.Unlock()
+	if diff := cmp.Diff(recordFields, gotFields, protocmp.Transform()); diff != "" {
+		t.Errorf("Add got document fields diff (-want, +got):\n%s", diff)
+	}
+
+	got, err := store.Query(ctx, &Query{Resource: "projects/baz", Member: "user:alice@example.com", RequestHash: "abc123", Limit: 10})
+	if err != nil {
+		t.Fatalf("Query got unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]*Record{record}, got); diff != "" {
+		t.Errorf("Query got records diff (-want, +got):\n%s", diff)
+	}
+
+	server.mu.Lock()
+	gotQuery := server.queries[0]
+	server.mu.Unlock()
+	if got, want := gotQuery.GetFrom()[0].GetCollectionId(), DefaultCollection; got != want {
+		t.Errorf("Query got collection %q, want %q", got, want)
+	}
+	if got, want := gotQuery.GetLimit().GetValue(), int32(10); got != want {
+		t.Errorf("Query got limit %d, want %d", got, want)
+	}
+	var gotFilters []string
+	for _, f := range gotQuery.GetWhere().GetCompositeFilter().GetFilters() {
+		ff := f.GetFieldFilter()
+		gotFilters = append(gotFilters, ff.GetField().GetFieldPath()+" "+ff.GetOp().String())
+	}
+	if diff := cmp.Diff([]string{"resource EQUAL", "members ARRAY_CONTAINS", "requestHash EQUAL"}, gotFilters); diff != "" {
+		t.Errorf("Query got filters diff (-want, +got):\n%s", diff)
+	}
+}
+
+func stringArray(ss ...string) *firestorepb.Value {
+	vs := make([]*firestorepb.Value, 0, len(ss))
+	for _, s := range ss {
+		vs = append(vs, &firestorepb.Value{ValueType: &firestorepb.Value_StringValue{StringValue: s}})
+	}
+	return &firestorepb.Value{ValueType: &firestorepb.Value_ArrayValue{ArrayValue: &firestorepb.ArrayValue{Values: vs}}}
+}
+
+type fakeFirestoreServer struct {
+	firestorepb.UnimplementedFirestoreServer
+
+	mu      sync.Mutex
+	docs    []*firestorepb.Document
+	commits []*firestorepb.CommitRequest
+	queries []*firestorepb.StructuredQuery
+}
+
+func (s *fakeFirestoreServer) Commit(_ context.Context, r *firestorepb.CommitRequest) (*firestorepb.CommitResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commits = append(s.commits, r)
+	resp := &firestorepb.CommitResponse{CommitTime: timestamppb.Now()}
+	for range r.GetWrites() {
+		resp.WriteResults = append(resp.WriteResults, &firestorepb.WriteResult{UpdateTime: timestamppb.Now()})
+	}
+	return resp, nil
+}
+
+func (s *fakeFirestoreServer) RunQuery(r *firestorepb.RunQueryRequest, stream firestorepb.Firestore_RunQueryServer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries = append(s.queries, r.GetStructuredQuery())
+	for _, d := range s.docs {
+		if err := stream.Send(&firestorepb.RunQueryResponse{Document: d, ReadTime: timestamppb.Now()}); err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"sort"
+	"time"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+)
+
+// grantKey identifies the grant of a role to a member on a resource.
+type grantKey struct {
+	resource, role, member string
+}
+
+// ActiveGrants returns the grants which are active at now according to the
+// records, one per resource, role and member, sorted by resource, role and
+// member. The records are replayed in time order: "GRANT" and "RENEW" records
+// set the expiry of their members, and "CLEANUP" and "REVOKE" records remove
+// them.
+func ActiveGrants(records []*Record, now time.Time) []*v1alpha1.ActiveGrant {
+	sorted := make([]*Record, len(records))
+	copy(sorted, records)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	expiries := make(map[grantKey]time.Time)
+	for _, r := range sorted {
+		for _, m := range r.Members {
+			k := grantKey{resource: r.Resource, role: r.Role, member: m}
+			switch r.Type {
+			case audit.EventTypeGrant, audit.EventTypeRenew:
+				if r.Expiry != nil {
+					expiries[k] = *r.Expiry
+				}
+			case audit.EventTypeCleanup, audit.EventTypeRevoke:
+				delete(expiries, k)
+			}
+		}
+	}
+
+	result := make([]*v1alpha1.ActiveGrant, 0, len(expiries))
+	for k, exp := range expiries {
+		if !exp.After(now) {
+			continue
+		}
+		result = append(result, &v1alpha1.ActiveGrant{
+			Resource: k.resource,
+			Role:     k.role,
+			Member:   k.member,
+			Expiry:   exp,
+		})
+	}
+	sortGrants(result)
+	return result
+}
+
+// Drift is the difference between the grants recorded in the registry and the
+// grants in the live IAM policies.
+type Drift struct {
+	// Missing are the recorded active grants which are not in the live IAM
+	// policies, or expire earlier there, with the recorded expiry.
+	Missing []*v1alpha1.ActiveGrant `json:"missing,omitempty" yaml:"missing,omitempty"`
+
+	// Untracked are the active grants in the live IAM policies which are not
+	// recorded, e.g. granted without the registry.
+	Untracked []*v1alpha1.ActiveGrant `json:"untracked,omitempty" yaml:"untracked,omitempty"`
+}
+
+// Compare returns the drift of the live active grants from the recorded active
+// grants.
+func Compare(recorded, live []*v1alpha1.ActiveGrant) *Drift {
+	liveExpiries := make(map[grantKey]time.Time, len(live))
+	for _, g := range live {
+		k := grantKey{resource: g.Resource, role: g.Role, member: g.Member}
+		// Keep the latest expiry if a member has multiple AOD bindings of the
+		// role.
+		if exp, ok := liveExpiries[k]; !ok || g.Expiry.After(exp) {
+			liveExpiries[k] = g.Expiry
+		}
+	}
+	recordedKeys := make(map[grantKey]struct{}, len(recorded))
+
+	d := &Drift{}
+	for _, g := range recorded {
+		k := grantKey{resource: g.Resource, role: g.Role, member: g.Member}
+		recordedKeys[k] = struct{}{}
+		if exp, ok := liveExpiries[k]; !ok || exp.Before(g.Expiry) {
+			d.Missing = append(d.Missing, g)
+		}
+	}
+	for _, g := range live {
+		if _, ok := recordedKeys[grantKey{resource: g.Resource, role: g.Role, member: g.Member}]; !ok {
+			d.Untracked = append(d.Untracked, g)
+		}
+	}
+	sortGrants(d.Missing)
+	sortGrants(d.Untracked)
+	return d
+}
+
+// sortGrants sorts the grants by resource, role and member.
+func sortGrants(grants []*v1alpha1.ActiveGrant) {
+	sort.SliceStable(grants, func(i, j int) bool {
+		a, b := grants[i], grants[j]
+		if a.Resource != b.Resource {
+			return a.Resource < b.Resource
+		}
+		if a.Role != b.Role {
+			return a.Role < b.Role
+		}
+		return a.Member < b.Member
+	})
+}
@@ -0,0 +1,142 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+)
+
+func TestActiveGrants(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	ptr := func(t time.Time) *time.Time { return &t }
+
+	cases := []struct {
+		name    string
+		records []*Record
+		want    []*v1alpha1.ActiveGrant
+	}{
+		{
+			name: "grant",
+			records: []*Record{
+				{Type: audit.EventTypeGrant, Time: now.Add(-time.Hour), Resource: "projects/foo", Role: "roles/viewer", Members: []string{"user:bob@example.com", "user:alice@example.com"}, Expiry: ptr(now.Add(time.Hour))},
+			},
+			want: []*v1alpha1.ActiveGrant{
+				{Resource: "projects/foo", Role: "roles/viewer", Member: "user:alice@example.com", Expiry: now.Add(time.Hour)},
+				{Resource: "projects/foo", Role: "roles/viewer", Member: "user:bob@example.com", Expiry: now.Add(time.Hour)},
+			},
+		},
+		{
+			name: "renewed_out_of_order",
+			records: []*Record{
+				{Type: audit.EventTypeRenew, Time: now.Add(-time.Hour), Resource: "projects/foo", Role: "roles/viewer", Members: []string{"user:alice@example.com"}, Expiry: ptr(now.Add(3 * time.Hour))},
+				{Type: audit.EventTypeGrant, Time: now.Add(-2 * time.Hour), Resource: "projects/foo", Role: "roles/viewer", Members: []string{"user:alice@example.com"}, Expiry: ptr(now.Add(time.Hour))},
+			},
+			want: []*v1alpha1.ActiveGrant{
+				{Resource: "projects/foo", Role: "roles/viewer", Member: "user:alice@example.com", Expiry: now.Add(3 * time.Hour)},
+			},
+		},
+		{
+			name: "cleaned_up_and_revoked",
+			records: []*Record{
+				{Type: audit.EventTypeGrant, Time: now.Add(-2 * time.Hour), Resource: "projects/foo", Role: "roles/viewer", Members: []string{"user:alice@example.com", "user:bob@example.com"}, Expiry: ptr(now.Add(time.Hour))},
+				{Type: audit.EventTypeCleanup, Time: now.Add(-time.Hour), Resource: "projects/foo", Role: "roles/viewer", Members: []string{"user:alice@example.com"}},
+				{Type: audit.EventTypeRevoke, Time: now.Add(-time.Hour), Resource: "projects/foo", Role: "roles/viewer", Members: []string{"user:bob@example.com"}},
+			},
+			want: []*v1alpha1.ActiveGrant{},
+		},
+		{
+			name: "expired",
+			records: []*Record{
+				{Type: audit.EventTypeGrant, Time: now.Add(-2 * time.Hour), Resource: "projects/foo", Role: "roles/viewer", Members: []string{"user:alice@example.com"}, Expiry: ptr(now)},
+			},
+			want: []*v1alpha1.ActiveGrant{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := ActiveGrants(tc.records, now)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Process(%+v) got grants diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	grant := func(member string, expiry time.Time) *v1alpha1.ActiveGrant {
+		return &v1alpha1.ActiveGrant{Resource: "projects/foo", Role: "roles/viewer", Member: member, Expiry: expiry}
+	}
+
+	cases := []struct {
+		name     string
+		recorded []*v1alpha1.ActiveGrant
+		live     []*v1alpha1.ActiveGrant
+		want     *Drift
+	}{
+		{
+			name:     "in_sync",
+			recorded: []*v1alpha1.ActiveGrant{grant("user:alice@example.com", now)},
+			live:     []*v1alpha1.ActiveGrant{grant("user:alice@example.com", now)},
+			want:     &Drift{},
+		},
+		{
+			name:     "missing",
+			recorded: []*v1alpha1.ActiveGrant{grant("user:alice@example.com", now)},
+			want:     &Drift{Missing: []*v1alpha1.ActiveGrant{grant("user:alice@example.com", now)}},
+		},
+		{
+			name:     "expires_earlier",
+			recorded: []*v1alpha1.ActiveGrant{grant("user:alice@example.com", now.Add(time.Hour))},
+			live:     []*v1alpha1.ActiveGrant{grant("user:alice@example.com", now)},
+			want:     &Drift{Missing: []*v1alpha1.ActiveGrant{grant("user:alice@example.com", now.Add(time.Hour))}},
+		},
+		{
+			name:     "expires_later",
+			recorded: []*v1alpha1.ActiveGrant{grant("user:alice@example.com", now)},
+			live:     []*v1alpha1.ActiveGrant{grant("user:alice@example.com", now.Add(time.Hour))},
+			want:     &Drift{},
+		},
+		{
+			name: "untracked",
+			live: []*v1alpha1.ActiveGrant{grant("user:bob@example.com", now)},
+			want: &Drift{Untracked: []*v1alpha1.ActiveGrant{grant("user:bob@example.com", now)}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := Compare(tc.recorded, tc.live)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Process(%+v) got drift diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package registry records the grants and cleanups made by AOD, for
+// auditability beyond what lives in the IAM policies.
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+)
+
+// Record is a grant or cleanup of the IAM binding of a role made by AOD.
+type Record struct {
+	// Type of the record, one of "GRANT", "CLEANUP", "RENEW" and "REVOKE".
+	Type string `firestore:"type" json:"type" yaml:"type"`
+
+	// Time when the grant or cleanup was made.
+	Time time.Time `firestore:"time" json:"time" yaml:"time"`
+
+	// Resource is the GCP resource of the IAM policy.
+	Resource string `firestore:"resource" json:"resource" yaml:"resource"`
+
+	// Role of the binding.
+	Role string `firestore:"role" json:"role" yaml:"role"`
+
+	// Members of the binding.
+	Members []string `firestore:"members" json:"members" yaml:"members"`
+
+	// Expiry is the expiration time of the binding, only set for "GRANT" and
+	// "RENEW" records.
+	Expiry *time.Time `firestore:"expiry,omitempty" json:"expiry,omitempty" yaml:"expiry,omitempty"`
+
+	// Requester of the request, if provided.
+	Requester string `firestore:"requester,omitempty" json:"requester,omitempty" yaml:"requester,omitempty"`
+
+	// Approvers of the request, if provided.
+	Approvers []string `firestore:"approvers,omitempty" json:"approvers,omitempty" yaml:"approvers,omitempty"`
+
+	// Source of the request, if provided.
+	Source string `firestore:"source,omitempty" json:"source,omitempty" yaml:"source,omitempty"`
+
+	// RequestHash is the SHA256 hash of the request file, if provided.
+	RequestHash string `firestore:"requestHash,omitempty" json:"requestHash,omitempty" yaml:"requestHash,omitempty"`
+}
+
+// Query filters the records, empty fields match all records.
+type Query struct {
+	// Resource of the records.
+	Resource string
+
+	// Member in the members of the records.
+	Member string
+
+	// RequestHash of the records, which identifies the grants made for the
+	// same request.
+	RequestHash string
+
+	// Limit is the max number of the most recent records to return, no limit if
+	// it is zero.
+	Limit int
+}
+
+// Store stores and queries the records.
+type Store interface {
+	Add(ctx context.Context, r *Record) error
+	Query(ctx context.Context, q *Query) ([]*Record, error)
+}
+
+// recordedTypes are the types of audit events recorded to the registry.
+var recordedTypes = map[string]struct{}{
+	audit.EventTypeGrant:   {},
+	audit.EventTypeCleanup: {},
+	audit.EventTypeRenew:   {},
+	audit.EventTypeRevoke:  {},
+}
+
+// Sink is an audit sink that records the successful grants and cleanups in the
+// audit events to a store, one record per role.
+type Sink struct {
+	store Store
+}
+
+// NewSink creates a new Sink recording to the store.
+func NewSink(store Store) *Sink {
+	return &Sink{store: store}
+}
+
+// Write records the grants or cleanups of the event to the store. Failed
+// events and events of other types are ignored.
+func (s *Sink) Write(ctx context.Context, e *audit.Event) (retErr error) {
+	if _, ok := recordedTypes[e.Type]; !ok || e.Outcome != audit.OutcomeSuccess {
+		return nil
+	}
+
+	for _, b := range e.Bindings {
+		if err := s.store.Add(ctx, &Record{
+			Type:        e.Type,
+			Time:        e.Time,
+			Resource:    e.Resource,
+			Role:        b.Role,
+			Members:     b.Members,
+			Expiry:      e.Expiry,
+			Requester:   e.Requester,
+			Approvers:   e.Approvers,
+			Source:      e.Source,
+			RequestHash: e.RequestHash,
+		}); err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("failed to add record of role %q: %w", b.Role, err))
+		}
+	}
+	return retErr
+}
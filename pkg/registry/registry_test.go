@@ -0,0 +1,175 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestSink_Write(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	expiry := now.Add(2 * time.Hour)
+
+	cases := []struct {
+		name       string
+		event      *audit.Event
+		addErr     error
+		expRecords []*Record
+		expErr     string
+	}{
+		{
+			name: "grant",
+			event: &audit.Event{
+				Type:     audit.EventTypeGrant,
+				Time:     now,
+				Resource: "projects/baz",
+				Bindings: []*audit.Binding{
+					{Role: "roles/viewer", Members: []string{"user:alice@example.com"}},
+					{Role: "roles/editor", Members: []string{"user:bob@example.com"}},
+				},
+				Expiry:      &expiry,
+				Requester:   "user:alice@example.com",
+				Approvers:   []string{"user:carol@example.com"},
+				Source:      "https://github.com/foo/bar/pull/1",
+				RequestHash: "abc123",
+				Outcome:     audit.OutcomeSuccess,
+			},
+			expRecords: []*Record{
+				{
+					Type:        audit.EventTypeGrant,
+					Time:        now,
+					Resource:    "projects/baz",
+					Role:        "roles/viewer",
+					Members:     []string{"user:alice@example.com"},
+					Expiry:      &expiry,
+					Requester:   "user:alice@example.com",
+					Approvers:   []string{"user:carol@example.com"},
+					Source:      "https://github.com/foo/bar/pull/1",
+					RequestHash: "abc123",
+				},
+				{
+					Type:        audit.EventTypeGrant,
+					Time:        now,
+					Resource:    "projects/baz",
+					Role:        "roles/editor",
+					Members:     []string{"user:bob@example.com"},
+					Expiry:      &expiry,
+					Requester:   "user:alice@example.com",
+					Approvers:   []string{"user:carol@example.com"},
+					Source:      "https://github.com/foo/bar/pull/1",
+					RequestHash: "abc123",
+				},
+			},
+		},
+		{
+			name: "cleanup",
+			event: &audit.Event{
+				Type:     audit.EventTypeCleanup,
+				Time:     now,
+				Resource: "projects/baz",
+				Bindings: []*audit.Binding{
+					{Role: "roles/viewer", Members: []string{"user:alice@example.com"}},
+				},
+				Outcome: audit.OutcomeSuccess,
+			},
+			expRecords: []*Record{
+				{
+					Type:     audit.EventTypeCleanup,
+					Time:     now,
+					Resource: "projects/baz",
+					Role:     "roles/viewer",
+					Members:  []string{"user:alice@example.com"},
+				},
+			},
+		},
+		{
+			name: "failed_event_ignored",
+			event: &audit.Event{
+				Type:     audit.EventTypeGrant,
+				Time:     now,
+				Resource: "projects/baz",
+				Bindings: []*audit.Binding{
+					{Role: "roles/viewer", Members: []string{"user:alice@example.com"}},
+				},
+				Outcome: audit.OutcomeFailure,
+				Error:   "injected error",
+			},
+		},
+		{
+			name: "validation_denied_ignored",
+			event: &audit.Event{
+				Type:    audit.EventTypeValidationDenied,
+				Time:    now,
+				Outcome: audit.OutcomeSuccess,
+			},
+		},
+		{
+			name: "add_failure",
+			event: &audit.Event{
+				Type:     audit.EventTypeGrant,
+				Time:     now,
+				Resource: "projects/baz",
+				Bindings: []*audit.Binding{
+					{Role: "roles/viewer", Members: []string{"user:alice@example.com"}},
+				},
+				Outcome: audit.OutcomeSuccess,
+			},
+			addErr: fmt.Errorf("injected error"),
+			expErr: `failed to add record of role "roles/viewer": injected error`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			store := &fakeStore{addErr: tc.addErr}
+			err := NewSink(store).Write(context.Background(), tc.event)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.expRecords, store.records); diff != "" {
+				t.Errorf("Process(%+v) got records diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+type fakeStore struct {
+	addErr  error
+	records []*Record
+}
+
+func (s *fakeStore) Add(_ context.Context, r *Record) error {
+	if s.addErr != nil {
+		return s.addErr
+	}
+	s.records = append(s.records, r)
+	return nil
+}
+
+func (s *fakeStore) Query(_ context.Context, _ *Query) ([]*Record, error) {
+	return s.records, nil
+}
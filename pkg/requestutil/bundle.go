@@ -0,0 +1,457 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requestutil
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/entitlement"
+	"github.com/abcxyz/access-on-demand/pkg/identity"
+)
+
+const (
+	// maxRequestFileSize is the max size of a request file, including a request
+	// file in a tarball bundle.
+	maxRequestFileSize = 64 * 1_000
+
+	// maxBundleSize is the max size of the decompressed content of a bundle.
+	maxBundleSize = 100 * maxRequestFileSize
+
+	// checksumsFileName is the name of the optional checksums file in a tarball
+	// bundle, in the format of the output of "sha256sum".
+	checksumsFileName = "SHA256SUMS"
+)
+
+// Document is a request read from a request bundle.
+type Document[T any] struct {
+	// Name identifies the request in the bundle, e.g. "bundle.tgz:a.yaml#1".
+	Name string
+
+	// Request is the request decoded from the document.
+	Request *T
+
+	// Locator locates the fields of the request in the document.
+	Locator *Locator
+}
+
+// ReadBundleFromPath reads the requests in the bundle at the given path. A
+// bundle is one of:
+//
+//   - A YAML file, which may contain multiple YAML documents.
+//   - A gzip compressed YAML file, with ".gz" extension.
+//   - A tarball of YAML files, with ".tar", ".tar.gz" or ".tgz" extension. If
+//     the tarball contains a "SHA256SUMS" file, the checksums of all YAML files
+//     are verified against it.
+//
+// Each YAML document is decoded to a separate request.
+func ReadBundleFromPath[T any](p string) ([]*Document[T], error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file at %q, %w", p, err)
+	}
+	defer f.Close()
+
+	var r io.Reader = bufio.NewReader(f)
+	if isGzip(p) {
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress file at %q: %w", p, err)
+		}
+		defer gr.Close()
+		r = gr
+	}
+	r = &limitedReader{r: r, n: maxBundleSize}
+
+	if isTarball(p) {
+		return readTarball[T](p, r)
+	}
+
+	data, err := readAllLimited(r, maxRequestFileSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file content at %q: %w", p, err)
+	}
+	return decodeDocuments[T](p, data)
+}
+
+// ReadBundle reads the requests in the YAML data, which may contain multiple
+// YAML documents, the name identifies the data in the document names and
+// errors.
+func ReadBundle[T any](name string, data []byte) ([]*Document[T], error) {
+	if len(data) > maxRequestFileSize {
+		return nil, fmt.Errorf("content of %q exceeds size limit of %d bytes", name, maxRequestFileSize)
+	}
+	return decodeDocuments[T](name, data)
+}
+
+// ValidateIAMBundle validates the IAM requests in the bundle with the options,
+// which may be nil, and merges them into one IAMRequest. The limits of the
+// policies and bindings and the custom check of the options apply to the
+// merged request, so that a request cannot evade them by being split into
+// multiple documents.
+func ValidateIAMBundle(docs []*Document[v1alpha1.IAMRequest], opts *v1alpha1.ValidateOptions) (*v1alpha1.IAMRequest, error) {
+	req := &v1alpha1.IAMRequest{}
+	if len(docs) == 0 {
+		return req, v1alpha1.ValidateIAMRequest(req, opts) //nolint:wrapcheck // Want passthrough
+	}
+
+	docOpts := opts
+	if len(docs) > 1 && opts != nil {
+		o := *opts
+		o.MaxPolicies, o.MaxBindings, o.CustomCheck = 0, 0, nil
+		docOpts = &o
+	}
+
+	// The document and the index in the document of each merged policy.
+	type origin struct {
+		doc   *Document[v1alpha1.IAMRequest]
+		index int
+	}
+	var origins []origin
+
+	var retErr error
+	for _, d := range docs {
+		if err := v1alpha1.ValidateIAMRequest(d.Request, docOpts); err != nil {
+			err = d.Locator.Annotate(err)
+			// Identify the invalid request if there are multiple requests.
+			if len(docs) > 1 {
+				err = fmt.Errorf("%s: %w", d.Name, err)
+			}
+			retErr = errors.Join(retErr, err)
+		}
+		req.ResourcePolicies = append(req.ResourcePolicies, d.Request.ResourcePolicies...)
+		req.DenyPolicies = append(req.DenyPolicies, d.Request.DenyPolicies...)
+		for i := range d.Request.ResourcePolicies {
+			origins = append(origins, origin{doc: d, index: i})
+		}
+	}
+
+	// The recurring windows apply to all the merged policies, so they must be
+	// the same in all requests.
+	req.Recurring = docs[0].Request.Recurring
+	for _, d := range docs[1:] {
+		if !equalRecurring(d.Request.Recurring, req.Recurring) {
+			retErr = errors.Join(retErr, fmt.Errorf("%s: recurring conflicts with %s: recurring, all requests in a bundle must have the same recurring windows",
+				d.Name, docs[0].Name))
+		}
+	}
+
+	// Check conflicts between the policies of different requests, the conflicts
+	// in the same request are already reported by its validation.
+	for _, c := range v1alpha1.PolicyConflicts(req.ResourcePolicies) {
+		first, second := origins[c.First], origins[c.Second]
+		if first.doc == second.doc {
+			continue
+		}
+		retErr = errors.Join(retErr, fmt.Errorf("%s: policies[%d]: resource %q conflicts with %s: policies[%d]: %s",
+			second.doc.Name, second.index, c.Resource, first.doc.Name, first.index, c.Reason))
+	}
+
+	if retErr == nil && len(docs) > 1 {
+		retErr = checkMergedIAMRequest(req, opts)
+	}
+	return req, retErr
+}
+
+// checkMergedIAMRequest checks the merged IAM request of a bundle against the
+// limits of the policies and bindings and the custom check of the options,
+// which may be nil. The bindings of the policies of the same resource in
+// different requests count towards the same limit.
+func checkMergedIAMRequest(req *v1alpha1.IAMRequest, opts *v1alpha1.ValidateOptions) (retErr error) {
+	if opts == nil {
+		return nil
+	}
+	if opts.MaxPolicies > 0 && len(req.ResourcePolicies) > opts.MaxPolicies {
+		retErr = errors.Join(retErr, fmt.Errorf("bundle can have at most %d policies, got %d",
+			opts.MaxPolicies, len(req.ResourcePolicies)))
+	}
+	if opts.MaxBindings > 0 {
+		var resources []string
+		bindings := make(map[string]int)
+		for _, p := range req.ResourcePolicies {
+			if _, ok := bindings[p.Resource]; !ok {
+				resources = append(resources, p.Resource)
+			}
+			bindings[p.Resource] += len(p.Bindings)
+		}
+		for _, r := range resources {
+			if n := bindings[r]; n > opts.MaxBindings {
+				retErr = errors.Join(retErr, fmt.Errorf("bundle can have at most %d bindings on resource %q, got %d",
+					opts.MaxBindings, r, n))
+			}
+		}
+	}
+	if retErr == nil && opts.CustomCheck != nil {
+		if err := opts.CustomCheck(req); err != nil {
+			retErr = fmt.Errorf("bundle: %w", err)
+		}
+	}
+	return retErr
+}
+
+// ResolveIAMBundle resolves the member aliases in the IAM requests in the
+// bundle with the identity map, which may be nil. It must be called before the
+// requests are validated.
+func ResolveIAMBundle(docs []*Document[v1alpha1.IAMRequest], m identity.Map) error {
+	var retErr error
+	for _, d := range docs {
+		if err := m.Resolve(d.Request); err != nil {
+			err = d.Locator.Annotate(err)
+			// Identify the invalid request if there are multiple requests.
+			if len(docs) > 1 {
+				err = fmt.Errorf("%s: %w", d.Name, err)
+			}
+			retErr = errors.Join(retErr, err)
+		}
+	}
+	return retErr
+}
+
+// ExpandIAMBundle expands the entitlement references in the IAM requests in
+// the bundle with the entitlement catalog, which may be nil, for the duration
+// the requests are handled with, or 0 if it is not known. It must be called
+// before the member aliases are resolved and the requests are validated.
+func ExpandIAMBundle(docs []*Document[v1alpha1.IAMRequest], c entitlement.Catalog, duration time.Duration) error {
+	var retErr error
+	for _, d := range docs {
+		if err := c.Expand(d.Request, duration); err != nil {
+			err = d.Locator.Annotate(err)
+			// Identify the invalid request if there are multiple requests.
+			if len(docs) > 1 {
+				err = fmt.Errorf("%s: %w", d.Name, err)
+			}
+			retErr = errors.Join(retErr, err)
+		}
+	}
+	return retErr
+}
+
+// equalRecurring returns whether the recurring windows are the same.
+func equalRecurring(a, b *v1alpha1.Recurring) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return slices.Equal(a.Days, b.Days) && a.Hours == b.Hours && a.TimeZone == b.TimeZone && a.Until == b.Until
+}
+
+// readTarball reads the YAML files in the tarball and verifies them against
+// the checksums file if it exists.
+func readTarball[T any](p string, r io.Reader) ([]*Document[T], error) {
+	tr := tar.NewReader(r)
+	files := make(map[string][]byte)
+	var names []string
+	var checksums []byte
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tarball at %q: %w", p, err)
+		}
+
+		name := path.Clean(h.Name)
+		switch h.Typeflag {
+		case tar.TypeDir:
+			continue
+		case tar.TypeReg:
+			// Ok.
+		default:
+			return nil, fmt.Errorf("tarball entry %q is not a regular file", h.Name)
+		}
+
+		data, err := readAllLimited(tr, maxRequestFileSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tarball entry %q: %w", h.Name, err)
+		}
+
+		if name == checksumsFileName {
+			checksums = data
+			continue
+		}
+		if ext := path.Ext(name); ext != ".yaml" && ext != ".yml" {
+			return nil, fmt.Errorf("tarball entry %q is not a YAML file", h.Name)
+		}
+		if _, ok := files[name]; ok {
+			return nil, fmt.Errorf("duplicate tarball entry %q", h.Name)
+		}
+		files[name] = data
+		names = append(names, name)
+	}
+
+	// Drain the remaining content, so that the checksum of the gzip compressed
+	// tarball is verified.
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return nil, fmt.Errorf("failed to read tarball at %q: %w", p, err)
+	}
+
+	if checksums != nil {
+		if err := verifyChecksums(checksums, files); err != nil {
+			return nil, fmt.Errorf("failed to verify tarball at %q: %w", p, err)
+		}
+	}
+
+	slices.Sort(names)
+	var docs []*Document[T]
+	for _, name := range names {
+		d, err := decodeDocuments[T](p+":"+name, files[name])
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, d...)
+	}
+	return docs, nil
+}
+
+// verifyChecksums verifies the files against the checksums in the format of
+// the output of "sha256sum", every file must have a matching checksum and
+// every checksum must have a matching file.
+func verifyChecksums(checksums []byte, files map[string][]byte) (retErr error) {
+	want := make(map[string]string)
+	for i, line := range strings.Split(string(checksums), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		sum, name, ok := strings.Cut(line, "  ")
+		if !ok {
+			// Binary mode of sha256sum uses " *" as the separator.
+			sum, name, ok = strings.Cut(line, " *")
+		}
+		if !ok {
+			return fmt.Errorf("invalid checksum line %d: %q", i+1, line)
+		}
+		want[path.Clean(strings.TrimSpace(name))] = strings.ToLower(sum)
+	}
+
+	for name, data := range files {
+		w, ok := want[name]
+		if !ok {
+			retErr = errors.Join(retErr, fmt.Errorf("checksum of %q not found", name))
+			continue
+		}
+		got := sha256.Sum256(data)
+		if g := hex.EncodeToString(got[:]); g != w {
+			retErr = errors.Join(retErr, fmt.Errorf("checksum of %q mismatched (got %q, want %q)", name, g, w))
+		}
+	}
+	for name := range want {
+		if _, ok := files[name]; !ok {
+			retErr = errors.Join(retErr, fmt.Errorf("file %q in checksums not found", name))
+		}
+	}
+	return retErr
+}
+
+// decodeDocuments decodes each YAML document in the data to a request. The
+// unknown fields of all documents are reported at once.
+func decodeDocuments[T any](name string, data []byte) ([]*Document[T], error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	nodeDec := yaml.NewDecoder(bytes.NewReader(data))
+
+	var docs []*Document[T]
+	var fieldErr error
+	for i := 0; ; i++ {
+		req := new(T)
+		if err := dec.Decode(req); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			err = fmt.Errorf("failed to unmarshal yaml document %d in %q to %T: %w", i, name, req, ExplainUnknownFields(err, req))
+			// The rest of the documents can still be decoded after type errors
+			// such as unknown fields, but not after syntax errors.
+			var te *yaml.TypeError
+			if !errors.As(err, &te) {
+				return nil, errors.Join(fieldErr, err)
+			}
+			fieldErr = errors.Join(fieldErr, err)
+		}
+
+		// The data is already decoded without syntax errors, so decoding it to
+		// a node is not expected to fail.
+		var n yaml.Node
+		if err := nodeDec.Decode(&n); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal yaml document %d in %q to %T: %w", i, name, &n, err)
+		}
+		if fieldErr != nil {
+			continue
+		}
+
+		docs = append(docs, &Document[T]{
+			Name:    fmt.Sprintf("%s#%d", name, i),
+			Request: req,
+			Locator: &Locator{root: &n},
+		})
+	}
+	if fieldErr != nil {
+		return nil, fieldErr
+	}
+	return docs, nil
+}
+
+// readAllLimited reads all data from the reader, and fails if it exceeds the
+// given size.
+func readAllLimited(r io.Reader, n int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, n+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read: %w", err)
+	}
+	if int64(len(data)) > n {
+		return nil, fmt.Errorf("content exceeds size limit of %d bytes", n)
+	}
+	return data, nil
+}
+
+// limitedReader is like io.LimitedReader, but fails instead of returning EOF
+// when the limit is exceeded, to guard against decompression bombs.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		return 0, fmt.Errorf("bundle exceeds size limit of %d bytes", maxBundleSize)
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err //nolint:wrapcheck // Want passthrough
+}
+
+func isGzip(p string) bool {
+	return strings.HasSuffix(p, ".gz") || strings.HasSuffix(p, ".tgz")
+}
+
+func isTarball(p string) bool {
+	return strings.HasSuffix(p, ".tar") || strings.HasSuffix(p, ".tar.gz") || strings.HasSuffix(p, ".tgz")
+}
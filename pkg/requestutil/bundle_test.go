@@ -0,0 +1,691 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requestutil
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/entitlement"
+	"github.com/abcxyz/access-on-demand/pkg/identity"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+const (
+	bundleTestRequestA = `policies:
+- resource: organizations/foo
+  bindings:
+  - members:
+    - user:test-org-user@example.com
+    role: roles/cloudkms.cryptoOperator
+`
+	bundleTestRequestB = `policies:
+- resource: projects/baz
+  bindings:
+  - members:
+    - user:test-project-user@example.com
+    role: roles/bigquery.dataViewer
+`
+)
+
+var (
+	bundleTestPolicyA = &v1alpha1.ResourcePolicy{
+		Resource: "organizations/foo",
+		Bindings: []*v1alpha1.Binding{
+			{
+				Members: []string{"user:test-org-user@example.com"},
+				Role:    "roles/cloudkms.cryptoOperator",
+			},
+		},
+	}
+	bundleTestPolicyB = &v1alpha1.ResourcePolicy{
+		Resource: "projects/baz",
+		Bindings: []*v1alpha1.Binding{
+			{
+				Members: []string{"user:test-project-user@example.com"},
+				Role:    "roles/bigquery.dataViewer",
+			},
+		},
+	}
+)
+
+func TestReadBundleFromPath(t *testing.T) {
+	t.Parallel()
+
+	multiDoc := bundleTestRequestA + "---\n" + bundleTestRequestB
+	checksums := fmt.Sprintf("%s  a.yaml\n%s  dir/b.yaml\n", sha256Hex(bundleTestRequestA), sha256Hex(bundleTestRequestB))
+
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"single.yaml":    []byte(bundleTestRequestA),
+		"multi.yaml":     []byte(multiDoc),
+		"empty.yaml":     {},
+		"multi.yaml.gz":  gzipBytes(t, []byte(multiDoc)),
+		"corrupted.gz":   corruptGzip(t, gzipBytes(t, []byte(multiDoc))),
+		"too_large.yaml": bytes.Repeat([]byte("#"), maxRequestFileSize+1),
+		"bundle.tgz": gzipBytes(t, tarBytes(t, []*tarEntry{
+			{name: "dir/", dir: true},
+			{name: "dir/b.yaml", content: bundleTestRequestB},
+			{name: "a.yaml", content: bundleTestRequestA},
+			{name: checksumsFileName, content: checksums},
+		})),
+		"no_checksums.tar": tarBytes(t, []*tarEntry{
+			{name: "a.yaml", content: bundleTestRequestA},
+		}),
+		"mismatched_checksum.tar": tarBytes(t, []*tarEntry{
+			{name: "a.yaml", content: bundleTestRequestA},
+			{name: "dir/b.yaml", content: bundleTestRequestA},
+			{name: checksumsFileName, content: checksums},
+		}),
+		"missing_file.tar": tarBytes(t, []*tarEntry{
+			{name: "a.yaml", content: bundleTestRequestA},
+			{name: checksumsFileName, content: checksums},
+		}),
+		"symlink.tar": tarBytes(t, []*tarEntry{
+			{name: "a.yaml", link: "/etc/passwd"},
+		}),
+		"not_yaml.tar": tarBytes(t, []*tarEntry{
+			{name: "a.json", content: "{}"},
+		}),
+		"duplicate.tar": tarBytes(t, []*tarEntry{
+			{name: "a.yaml", content: bundleTestRequestA},
+			{name: "./a.yaml", content: bundleTestRequestB},
+		}),
+		"invalid_doc.tar": tarBytes(t, []*tarEntry{
+			{name: "a.yaml", content: bundleTestRequestA + "---\nfoo: bar\n"},
+		}),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name      string
+		path      string
+		wantNames []string
+		wantReqs  []*v1alpha1.IAMRequest
+		wantErr   string
+	}{
+		{
+			name:      "single_document",
+			path:      "single.yaml",
+			wantNames: []string{"single.yaml#0"},
+			wantReqs:  []*v1alpha1.IAMRequest{{ResourcePolicies: []*v1alpha1.ResourcePolicy{bundleTestPolicyA}}},
+		},
+		{
+			name:      "multiple_documents",
+			path:      "multi.yaml",
+			wantNames: []string{"multi.yaml#0", "multi.yaml#1"},
+			wantReqs: []*v1alpha1.IAMRequest{
+				{ResourcePolicies: []*v1alpha1.ResourcePolicy{bundleTestPolicyA}},
+				{ResourcePolicies: []*v1alpha1.ResourcePolicy{bundleTestPolicyB}},
+			},
+		},
+		{
+			name: "empty",
+			path: "empty.yaml",
+		},
+		{
+			name:      "gzip",
+			path:      "multi.yaml.gz",
+			wantNames: []string{"multi.yaml.gz#0", "multi.yaml.gz#1"},
+			wantReqs: []*v1alpha1.IAMRequest{
+				{ResourcePolicies: []*v1alpha1.ResourcePolicy{bundleTestPolicyA}},
+				{ResourcePolicies: []*v1alpha1.ResourcePolicy{bundleTestPolicyB}},
+			},
+		},
+		{
+			name:    "corrupted_gzip",
+			path:    "corrupted.gz",
+			wantErr: "gzip: invalid checksum",
+		},
+		{
+			name:    "too_large",
+			path:    "too_large.yaml",
+			wantErr: "content exceeds size limit of 64000 bytes",
+		},
+		{
+			name:      "tarball_with_checksums",
+			path:      "bundle.tgz",
+			wantNames: []string{"bundle.tgz:a.yaml#0", "bundle.tgz:dir/b.yaml#0"},
+			wantReqs: []*v1alpha1.IAMRequest{
+				{ResourcePolicies: []*v1alpha1.ResourcePolicy{bundleTestPolicyA}},
+				{ResourcePolicies: []*v1alpha1.ResourcePolicy{bundleTestPolicyB}},
+			},
+		},
+		{
+			name:      "tarball_without_checksums",
+			path:      "no_checksums.tar",
+			wantNames: []string{"no_checksums.tar:a.yaml#0"},
+			wantReqs:  []*v1alpha1.IAMRequest{{ResourcePolicies: []*v1alpha1.ResourcePolicy{bundleTestPolicyA}}},
+		},
+		{
+			name:    "mismatched_checksum",
+			path:    "mismatched_checksum.tar",
+			wantErr: `checksum of "dir/b.yaml" mismatched`,
+		},
+		{
+			name:    "missing_file",
+			path:    "missing_file.tar",
+			wantErr: `file "dir/b.yaml" in checksums not found`,
+		},
+		{
+			name:    "symlink",
+			path:    "symlink.tar",
+			wantErr: `tarball entry "a.yaml" is not a regular file`,
+		},
+		{
+			name:    "not_yaml",
+			path:    "not_yaml.tar",
+			wantErr: `tarball entry "a.json" is not a YAML file`,
+		},
+		{
+			name:    "duplicate",
+			path:    "duplicate.tar",
+			wantErr: `duplicate tarball entry "./a.yaml"`,
+		},
+		{
+			name:    "invalid_document",
+			path:    "invalid_doc.tar",
+			wantErr: "failed to unmarshal yaml document 1 in",
+		},
+		{
+			name:    "not_found",
+			path:    "not_found.yaml",
+			wantErr: "failed to read file at",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			docs, err := ReadBundleFromPath[v1alpha1.IAMRequest](filepath.Join(dir, tc.path))
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+
+			var gotNames []string
+			var gotReqs []*v1alpha1.IAMRequest
+			for _, d := range docs {
+				gotNames = append(gotNames, strings.TrimPrefix(d.Name, dir+string(filepath.Separator)))
+				gotReqs = append(gotReqs, d.Request)
+			}
+			if diff := cmp.Diff(tc.wantNames, gotNames); diff != "" {
+				t.Errorf("Process(%+v) got names diff (-want, +got): %v", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantReqs, gotReqs); diff != "" {
+				t.Errorf("Process(%+v) got requests diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestReadBundle(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		data      string
+		wantNames []string
+		wantReqs  []*v1alpha1.IAMRequest
+		wantErr   string
+	}{
+		{
+			name:      "multiple_documents",
+			data:      bundleTestRequestA + "---\n" + bundleTestRequestB,
+			wantNames: []string{"body#0", "body#1"},
+			wantReqs: []*v1alpha1.IAMRequest{
+				{ResourcePolicies: []*v1alpha1.ResourcePolicy{bundleTestPolicyA}},
+				{ResourcePolicies: []*v1alpha1.ResourcePolicy{bundleTestPolicyB}},
+			},
+		},
+		{
+			name:      "json",
+			data:      `{"policies": [{"resource": "projects/baz", "bindings": [{"members": ["user:test-project-user@example.com"], "role": "roles/bigquery.dataViewer"}]}]}`,
+			wantNames: []string{"body#0"},
+			wantReqs: []*v1alpha1.IAMRequest{
+				{ResourcePolicies: []*v1alpha1.ResourcePolicy{bundleTestPolicyB}},
+			},
+		},
+		{
+			name:    "unknown_field",
+			data:    "foo: bar\n",
+			wantErr: `failed to unmarshal yaml document 0 in "body"`,
+		},
+		{
+			name: "unknown_fields_in_documents",
+			data: "foo: bar\n---\npolices: []\n",
+			wantErr: `failed to unmarshal yaml document 1 in "body" to *v1alpha1.IAMRequest: yaml: unmarshal errors:
+  line 3: unknown field "polices" in IAMRequest, did you mean "policies"?`,
+		},
+		{
+			name:    "too_large",
+			data:    strings.Repeat("#", maxRequestFileSize+1),
+			wantErr: `content of "body" exceeds size limit`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			docs, err := ReadBundle[v1alpha1.IAMRequest]("body", []byte(tc.data))
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+
+			var gotNames []string
+			var gotReqs []*v1alpha1.IAMRequest
+			for _, d := range docs {
+				gotNames = append(gotNames, d.Name)
+				gotReqs = append(gotReqs, d.Request)
+			}
+			if diff := cmp.Diff(tc.wantNames, gotNames); diff != "" {
+				t.Errorf("Process(%+v) got names diff (-want, +got): %v", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantReqs, gotReqs); diff != "" {
+				t.Errorf("Process(%+v) got requests diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestValidateIAMBundle(t *testing.T) {
+	t.Parallel()
+
+	// maxPoliciesCheck rejects requests with more than one policy, as a custom
+	// check that passes on each document of a split bundle.
+	maxPoliciesCheck := func(r *v1alpha1.IAMRequest) error {
+		if len(r.ResourcePolicies) > 1 {
+			return fmt.Errorf("rejected %d policies", len(r.ResourcePolicies))
+		}
+		return nil
+	}
+	splitBindings := bundleTestRequestA + "---\n" + strings.ReplaceAll(bundleTestRequestA, "cryptoOperator", "viewer")
+
+	cases := []struct {
+		name    string
+		data    string
+		opts    *v1alpha1.ValidateOptions
+		wantReq *v1alpha1.IAMRequest
+		wantErr string
+	}{
+		{
+			name: "merged",
+			data: bundleTestRequestA + "---\n" + bundleTestRequestB,
+			wantReq: &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{bundleTestPolicyA, bundleTestPolicyB},
+			},
+		},
+		{
+			name: "invalid_document",
+			data: bundleTestRequestA + "---\n" + strings.ReplaceAll(bundleTestRequestB, "user:", "group:"),
+			wantReq: &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{
+					bundleTestPolicyA,
+					{
+						Resource: "projects/baz",
+						Bindings: []*v1alpha1.Binding{
+							{
+								Members: []string{"group:test-project-user@example.com"},
+								Role:    "roles/bigquery.dataViewer",
+							},
+						},
+					},
+				},
+			},
+			wantErr: `body#1: policies[0].bindings[0].members[0] at line 12, column 7: member "group:test-project-user@example.com" is not of "user" type`,
+		},
+		{
+			name: "conflicting_documents",
+			data: bundleTestRequestA + `- resource: projects/baz
+  bindings:
+  - members:
+    - user:test-project-user@example.com
+    role: roles/viewer
+  dependsOn:
+  - organizations/foo
+---
+` + bundleTestRequestB,
+			wantReq: &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{
+					bundleTestPolicyA,
+					{
+						Resource: "projects/baz",
+						Bindings: []*v1alpha1.Binding{
+							{
+								Members: []string{"user:test-project-user@example.com"},
+								Role:    "roles/viewer",
+							},
+						},
+						DependsOn: []string{"organizations/foo"},
+					},
+					bundleTestPolicyB,
+				},
+			},
+			wantErr: `body#1: policies[0]: resource "projects/baz" conflicts with body#0: policies[1]: dependsOn [] differs from ["organizations/foo"]`,
+		},
+		{
+			name: "same_recurring",
+			data: "recurring:\n  days: [Mon-Fri]\n" + bundleTestRequestA + "---\nrecurring:\n  days: [Mon-Fri]\n" + bundleTestRequestB,
+			wantReq: &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{bundleTestPolicyA, bundleTestPolicyB},
+				Recurring:        &v1alpha1.Recurring{Days: []string{"Mon-Fri"}},
+			},
+		},
+		{
+			name: "conflicting_recurring",
+			data: "recurring:\n  days: [Mon-Fri]\n" + bundleTestRequestA + "---\n" + bundleTestRequestB,
+			wantReq: &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{bundleTestPolicyA, bundleTestPolicyB},
+				Recurring:        &v1alpha1.Recurring{Days: []string{"Mon-Fri"}},
+			},
+			wantErr: "body#1: recurring conflicts with body#0: recurring, all requests in a bundle must have the same recurring windows",
+		},
+		{
+			name: "split_max_policies",
+			data: bundleTestRequestA + "---\n" + bundleTestRequestB,
+			opts: &v1alpha1.ValidateOptions{MaxPolicies: 1},
+			wantReq: &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{bundleTestPolicyA, bundleTestPolicyB},
+			},
+			wantErr: "bundle can have at most 1 policies, got 2",
+		},
+		{
+			name: "split_max_bindings",
+			data: splitBindings,
+			opts: &v1alpha1.ValidateOptions{MaxBindings: 1},
+			wantReq: &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{
+					bundleTestPolicyA,
+					{
+						Resource: "organizations/foo",
+						Bindings: []*v1alpha1.Binding{
+							{
+								Members: []string{"user:test-org-user@example.com"},
+								Role:    "roles/cloudkms.viewer",
+							},
+						},
+					},
+				},
+			},
+			wantErr: `bundle can have at most 1 bindings on resource "organizations/foo", got 2`,
+		},
+		{
+			name: "split_custom_check",
+			data: bundleTestRequestA + "---\n" + bundleTestRequestB,
+			opts: &v1alpha1.ValidateOptions{CustomCheck: maxPoliciesCheck},
+			wantReq: &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{bundleTestPolicyA, bundleTestPolicyB},
+			},
+			wantErr: "bundle: rejected 2 policies",
+		},
+		{
+			name:    "single_document_custom_check",
+			data:    bundleTestRequestA,
+			opts:    &v1alpha1.ValidateOptions{CustomCheck: maxPoliciesCheck},
+			wantReq: &v1alpha1.IAMRequest{ResourcePolicies: []*v1alpha1.ResourcePolicy{bundleTestPolicyA}},
+		},
+		{
+			name:    "empty",
+			wantReq: &v1alpha1.IAMRequest{},
+			wantErr: "policies not found",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			docs, err := ReadBundle[v1alpha1.IAMRequest]("body", []byte(tc.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotReq, err := ValidateIAMBundle(docs, tc.opts)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantReq, gotReq); diff != "" {
+				t.Errorf("Process(%+v) got request diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestResolveIAMBundle(t *testing.T) {
+	t.Parallel()
+
+	m := identity.Map{"github": {"octocat": "user:test-project-user@example.com"}}
+	aliased := strings.ReplaceAll(bundleTestRequestB, "user:test-project-user@example.com", "github:octocat")
+
+	cases := []struct {
+		name     string
+		data     string
+		m        identity.Map
+		wantReqs []*v1alpha1.IAMRequest
+		wantErr  string
+	}{
+		{
+			name: "resolved",
+			data: bundleTestRequestA + "---\n" + aliased,
+			m:    m,
+			wantReqs: []*v1alpha1.IAMRequest{
+				{ResourcePolicies: []*v1alpha1.ResourcePolicy{bundleTestPolicyA}},
+				{ResourcePolicies: []*v1alpha1.ResourcePolicy{bundleTestPolicyB}},
+			},
+		},
+		{
+			name: "unknown_alias",
+			data: bundleTestRequestA + "---\n" + strings.ReplaceAll(aliased, "octocat", "hubot"),
+			m:    m,
+			wantReqs: []*v1alpha1.IAMRequest{
+				{ResourcePolicies: []*v1alpha1.ResourcePolicy{bundleTestPolicyA}},
+				{ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+					Resource: "projects/baz",
+					Bindings: []*v1alpha1.Binding{{Members: []string{"github:hubot"}, Role: "roles/bigquery.dataViewer"}},
+				}}},
+			},
+			wantErr: `body#1: policies[0].bindings[0].members[0] at line 12, column 7: unknown github identity "hubot"`,
+		},
+		{
+			name: "no_map",
+			data: aliased,
+			wantReqs: []*v1alpha1.IAMRequest{
+				{ResourcePolicies: []*v1alpha1.ResourcePolicy{{
+					Resource: "projects/baz",
+					Bindings: []*v1alpha1.Binding{{Members: []string{"github:octocat"}, Role: "roles/bigquery.dataViewer"}},
+				}}},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			docs, err := ReadBundle[v1alpha1.IAMRequest]("body", []byte(tc.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = ResolveIAMBundle(docs, tc.m)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+
+			var gotReqs []*v1alpha1.IAMRequest
+			for _, d := range docs {
+				gotReqs = append(gotReqs, d.Request)
+			}
+			if diff := cmp.Diff(tc.wantReqs, gotReqs); diff != "" {
+				t.Errorf("Process(%+v) got requests diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestExpandIAMBundle(t *testing.T) {
+	t.Parallel()
+
+	c := entitlement.Catalog{"bq-oncall": {
+		Resources:   []string{"projects/baz"},
+		Roles:       []string{"roles/bigquery.dataViewer"},
+		MaxDuration: 4 * time.Hour,
+	}}
+	ref := `entitlements:
+- entitlement: bq-oncall
+  members:
+  - user:test-project-user@example.com
+`
+
+	cases := []struct {
+		name     string
+		data     string
+		c        entitlement.Catalog
+		duration time.Duration
+		wantReqs []*v1alpha1.IAMRequest
+		wantErr  string
+	}{
+		{
+			name:     "expanded",
+			data:     bundleTestRequestA + "---\n" + ref,
+			c:        c,
+			duration: 2 * time.Hour,
+			wantReqs: []*v1alpha1.IAMRequest{
+				{ResourcePolicies: []*v1alpha1.ResourcePolicy{bundleTestPolicyA}},
+				{ResourcePolicies: []*v1alpha1.ResourcePolicy{bundleTestPolicyB}},
+			},
+		},
+		{
+			name:     "exceeds_max_duration",
+			data:     bundleTestRequestA + "---\n" + ref,
+			c:        c,
+			duration: 8 * time.Hour,
+			wantReqs: []*v1alpha1.IAMRequest{
+				{ResourcePolicies: []*v1alpha1.ResourcePolicy{bundleTestPolicyA}},
+				{Entitlements: []*v1alpha1.EntitlementRef{{Entitlement: "bq-oncall", Members: []string{"user:test-project-user@example.com"}}}},
+			},
+			wantErr: `body#1: entitlements[0].duration: duration 8h0m0s exceeds the max duration 4h0m0s of entitlement "bq-oncall"`,
+		},
+		{
+			name: "unknown_entitlement",
+			data: strings.ReplaceAll(ref, "bq-oncall", "admin"),
+			c:    c,
+			wantReqs: []*v1alpha1.IAMRequest{
+				{Entitlements: []*v1alpha1.EntitlementRef{{Entitlement: "admin", Members: []string{"user:test-project-user@example.com"}}}},
+			},
+			wantErr: `entitlements[0].entitlement at line 2, column 16: unknown entitlement "admin"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			docs, err := ReadBundle[v1alpha1.IAMRequest]("body", []byte(tc.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = ExpandIAMBundle(docs, tc.c, tc.duration)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+
+			var gotReqs []*v1alpha1.IAMRequest
+			for _, d := range docs {
+				gotReqs = append(gotReqs, d.Request)
+			}
+			if diff := cmp.Diff(tc.wantReqs, gotReqs); diff != "" {
+				t.Errorf("Process(%+v) got requests diff (-want, +got): %v", tc.name, diff)
+			}
+		})
+	}
+}
+
+type tarEntry struct {
+	name, content, link string
+	dir                 bool
+}
+
+func tarBytes(tb testing.TB, entries []*tarEntry) []byte {
+	tb.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		h := &tar.Header{Name: e.name, Mode: 0o600, Size: int64(len(e.content))}
+		switch {
+		case e.dir:
+			h.Typeflag = tar.TypeDir
+		case e.link != "":
+			h.Typeflag = tar.TypeSymlink
+			h.Linkname = e.link
+		default:
+			h.Typeflag = tar.TypeReg
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			tb.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.content)); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		tb.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func gzipBytes(tb testing.TB, data []byte) []byte {
+	tb.Helper()
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(data); err != nil {
+		tb.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		tb.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// corruptGzip corrupts the CRC-32 checksum in the gzip trailer.
+func corruptGzip(tb testing.TB, data []byte) []byte {
+	tb.Helper()
+
+	data = bytes.Clone(data)
+	data[len(data)-8] ^= 0xff
+	return data
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
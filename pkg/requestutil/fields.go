@@ -0,0 +1,162 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requestutil
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// unknownFieldRegex matches the errors of unknown fields reported by the YAML
+// decoder with known fields, e.g. "line 3: field polices not found in type
+// v1alpha1.IAMRequest".
+var unknownFieldRegex = regexp.MustCompile(`^line (\d+): field (.+) not found in type (\S+)$`)
+
+// ExplainUnknownFields rewrites the errors of unknown fields in the error of
+// decoding YAML to v with known fields, so that they name the known fields of
+// the type, and the closest one if it is likely a typo, e.g. `line 3: unknown
+// field "polices" in IAMRequest, did you mean "policies"?`. All unknown fields
+// of a YAML document are reported by the decoder at once. Other errors are
+// returned as is.
+func ExplainUnknownFields(err error, v any) error {
+	var te *yaml.TypeError
+	if !errors.As(err, &te) {
+		return err
+	}
+
+	fields := make(map[string][]string)
+	collectFields(reflect.TypeOf(v), fields)
+
+	msgs := make([]string, 0, len(te.Errors))
+	for _, msg := range te.Errors {
+		m := unknownFieldRegex.FindStringSubmatch(msg)
+		if m == nil {
+			msgs = append(msgs, msg)
+			continue
+		}
+		line, field, typ := m[1], m[2], m[3]
+		known, ok := fields[typ]
+		if !ok {
+			msgs = append(msgs, msg)
+			continue
+		}
+
+		msg = fmt.Sprintf("line %s: unknown field %q in %s", line, field, typ[strings.LastIndex(typ, ".")+1:])
+		if s := closestField(field, known); s != "" {
+			msg += fmt.Sprintf(", did you mean %q?", s)
+		} else if len(known) > 0 {
+			msg += fmt.Sprintf(", must be one of %q", known)
+		}
+		msgs = append(msgs, msg)
+	}
+	return &yaml.TypeError{Errors: msgs}
+}
+
+// collectFields collects the YAML field names of the struct types reachable
+// from t, by the names of the types in the errors of the YAML decoder.
+func collectFields(t reflect.Type, fields map[string][]string) {
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		t = t.Elem()
+	}
+	if t == nil {
+		return
+	}
+	if t.Kind() == reflect.Map {
+		collectFields(t.Elem(), fields)
+		return
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	if _, ok := fields[t.String()]; ok {
+		return
+	}
+
+	// Add the entry first so that recursive types are only visited once.
+	fields[t.String()] = nil
+	fields[t.String()] = structFields(t, fields)
+}
+
+// structFields returns the YAML field names of the struct type t in order,
+// including the ones of inlined structs, and collects the fields of the types
+// of its fields.
+func structFields(t reflect.Type, fields map[string][]string) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if strings.Contains(opts, "inline") {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				names = append(names, structFields(ft, fields)...)
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		names = append(names, name)
+		collectFields(f.Type, fields)
+	}
+	return names
+}
+
+// closestField returns the known field closest to the field, or empty if none
+// is close enough to be a likely typo of it.
+func closestField(field string, known []string) string {
+	best, bestDist := "", max(len(field)/3, 1)+1
+	for _, k := range known {
+		if d := editDistance(strings.ToLower(field), strings.ToLower(k)); d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
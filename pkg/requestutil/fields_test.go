@@ -0,0 +1,101 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requestutil
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestExplainUnknownFields(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name: "typo",
+			data: "polices: []\n",
+			wantErr: "yaml: unmarshal errors:\n" +
+				`  line 1: unknown field "polices" in IAMRequest, did you mean "policies"?`,
+		},
+		{
+			name: "case",
+			data: "policies:\n- resource: projects/foo\n  DependsOn: []\n",
+			wantErr: "yaml: unmarshal errors:\n" +
+				`  line 3: unknown field "DependsOn" in ResourcePolicy, did you mean "dependsOn"?`,
+		},
+		{
+			name: "no_suggestion",
+			data: "foo: bar\n",
+			wantErr: "yaml: unmarshal errors:\n" +
+				`  line 1: unknown field "foo" in IAMRequest, must be one of ["policies" "denyPolicies" "recurring" "entitlements"]`,
+		},
+		{
+			name: "all_unknown_fields",
+			data: `policies:
+- resource: projects/foo
+  bindings:
+  - member: [user:alice@example.com]
+    rol: roles/viewer
+recuring: {}
+`,
+			wantErr: "yaml: unmarshal errors:\n" +
+				`  line 4: unknown field "member" in Binding, did you mean "members"?` + "\n" +
+				`  line 5: unknown field "rol" in Binding, did you mean "role"?` + "\n" +
+				`  line 6: unknown field "recuring" in IAMRequest, did you mean "recurring"?`,
+		},
+		{
+			name: "other_type_error",
+			data: "policies: foo\n",
+			wantErr: "yaml: unmarshal errors:\n" +
+				"  line 1: cannot unmarshal !!str `foo` into []*v1alpha1.ResourcePolicy",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var req v1alpha1.IAMRequest
+			dec := yaml.NewDecoder(bytes.NewReader([]byte(tc.data)))
+			dec.KnownFields(true)
+			err := ExplainUnknownFields(dec.Decode(&req), &req)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestExplainUnknownFields_OtherErrors(t *testing.T) {
+	t.Parallel()
+
+	if err := ExplainUnknownFields(nil, &v1alpha1.IAMRequest{}); err != nil {
+		t.Errorf("ExplainUnknownFields(nil) got %v, want nil", err)
+	}
+	want := fmt.Errorf("yaml: line 1: did not find expected key")
+	if got := ExplainUnknownFields(want, &v1alpha1.IAMRequest{}); got != want { //nolint:errorlint // Want the same error.
+		t.Errorf("ExplainUnknownFields got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requestutil
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+)
+
+// pathSegmentRegex matches a segment of a field path, e.g. "bindings[0]".
+var pathSegmentRegex = regexp.MustCompile(`^([^\[\]]+)((?:\[\d+\])*)$`)
+
+// Locator locates fields of a request in the YAML file it is read from.
+type Locator struct {
+	root *yaml.Node
+}
+
+// Position returns the line and column of the field at the given path, e.g.
+// "policies[2].bindings[0].members[1]". It returns false if the field is not
+// found.
+func (l *Locator) Position(path string) (line, column int, ok bool) {
+	if l == nil || l.root == nil || len(l.root.Content) == 0 {
+		return 0, 0, false
+	}
+
+	n := l.root.Content[0]
+	for _, seg := range strings.Split(path, ".") {
+		m := pathSegmentRegex.FindStringSubmatch(seg)
+		if m == nil {
+			return 0, 0, false
+		}
+		if n = mappingValue(n, m[1]); n == nil {
+			return 0, 0, false
+		}
+		for _, idx := range strings.Split(strings.Trim(m[2], "[]"), "][") {
+			if idx == "" {
+				continue
+			}
+			i, err := strconv.Atoi(idx)
+			if err != nil || n.Kind != yaml.SequenceNode || i >= len(n.Content) {
+				return 0, 0, false
+			}
+			n = n.Content[i]
+		}
+	}
+	return n.Line, n.Column, true
+}
+
+// Section returns the locator of the section at the key of the request, e.g.
+// the IAM request under "iam" of a combined request. The returned locator
+// locates no fields if the section is not found.
+func (l *Locator) Section(key string) *Locator {
+	if l == nil || l.root == nil || len(l.root.Content) == 0 {
+		return nil
+	}
+	n := mappingValue(l.root.Content[0], key)
+	if n == nil {
+		return nil
+	}
+	return &Locator{root: &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{n}}}
+}
+
+// Annotate sets the positions of the v1alpha1.FieldErrors in the given error,
+// including the ones joined by errors.Join, and returns the error. It must be
+// called before the error is wrapped with fmt.Errorf, which formats the error
+// message eagerly.
+func (l *Locator) Annotate(err error) error {
+	walkFieldErrors(err, func(fe *v1alpha1.FieldError) {
+		if fe.Line > 0 {
+			return
+		}
+		if line, column, ok := l.Position(fe.Path); ok {
+			fe.Line, fe.Column = line, column
+		}
+	})
+	return err
+}
+
+// mappingValue returns the value node of the given key in the mapping node, or
+// nil if the key is not found.
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// walkFieldErrors calls fn on every v1alpha1.FieldError in the error tree.
+func walkFieldErrors(err error, fn func(*v1alpha1.FieldError)) {
+	switch e := err.(type) { //nolint:errorlint // Walking the error tree.
+	case nil:
+		return
+	case *v1alpha1.FieldError:
+		fn(e)
+		walkFieldErrors(e.Err, fn)
+	case interface{ Unwrap() []error }:
+		for _, ee := range e.Unwrap() {
+			walkFieldErrors(ee, fn)
+		}
+	default:
+		walkFieldErrors(errors.Unwrap(err), fn)
+	}
+}
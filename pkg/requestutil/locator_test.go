@@ -0,0 +1,220 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requestutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+const locatorTestRequest = `policies:
+- resource: organizations/foo
+  bindings:
+  - members:
+    - user:test-org-userA@example.com
+    - user:example.com
+    role: roles/cloudkms.cryptoOperator
+- resource: foo/bar
+  bindings:
+    - members:
+      - user:test-folder-user@example.com
+      role: roles/cloudkms.cryptoOperator
+`
+
+func TestLocatorPosition(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "request.yaml")
+	if err := os.WriteFile(path, []byte(locatorTestRequest), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var req v1alpha1.IAMRequest
+	l, err := ReadRequestWithLocator(path, &req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name              string
+		path              string
+		wantLine, wantCol int
+		wantOK            bool
+	}{
+		{
+			name:     "policy",
+			path:     "policies[1]",
+			wantLine: 8,
+			wantCol:  3,
+			wantOK:   true,
+		},
+		{
+			name:     "resource",
+			path:     "policies[1].resource",
+			wantLine: 8,
+			wantCol:  13,
+			wantOK:   true,
+		},
+		{
+			name:     "member",
+			path:     "policies[0].bindings[0].members[1]",
+			wantLine: 6,
+			wantCol:  7,
+			wantOK:   true,
+		},
+		{
+			name: "index_out_of_range",
+			path: "policies[2].resource",
+		},
+		{
+			name: "unknown_field",
+			path: "policies[0].foo",
+		},
+		{
+			name: "index_on_mapping",
+			path: "policies[0].bindings[0][1]",
+		},
+		{
+			name: "invalid_path",
+			path: "policies[a]",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			gotLine, gotCol, gotOK := l.Position(tc.path)
+			if gotLine != tc.wantLine || gotCol != tc.wantCol || gotOK != tc.wantOK {
+				t.Errorf("Position(%q) got (%d, %d, %t), want (%d, %d, %t)",
+					tc.path, gotLine, gotCol, gotOK, tc.wantLine, tc.wantCol, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestLocatorSection(t *testing.T) {
+	t.Parallel()
+
+	// The IAM request is indented under "iam" as in a combined request.
+	data := "iam:\n  " + strings.ReplaceAll(strings.TrimSuffix(locatorTestRequest, "\n"), "\n", "\n  ") + "\n"
+	var req map[string]any
+	l, err := DecodeRequestWithLocator([]byte(data), &req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name              string
+		key               string
+		path              string
+		wantLine, wantCol int
+		wantOK            bool
+	}{
+		{
+			name:     "resource",
+			key:      "iam",
+			path:     "policies[1].resource",
+			wantLine: 9,
+			wantCol:  15,
+			wantOK:   true,
+		},
+		{
+			name: "unknown_field",
+			key:  "iam",
+			path: "tool",
+		},
+		{
+			name: "unknown_section",
+			key:  "foo",
+			path: "policies[1].resource",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			gotLine, gotCol, gotOK := l.Section(tc.key).Position(tc.path)
+			if gotLine != tc.wantLine || gotCol != tc.wantCol || gotOK != tc.wantOK {
+				t.Errorf("Section(%q).Position(%q) got (%d, %d, %t), want (%d, %d, %t)",
+					tc.key, tc.path, gotLine, gotCol, gotOK, tc.wantLine, tc.wantCol, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestLocatorAnnotate(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "request.yaml")
+	if err := os.WriteFile(path, []byte(locatorTestRequest), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var req v1alpha1.IAMRequest
+	l, err := ReadRequestWithLocator(path, &req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		err     error
+		wantErr string
+	}{
+		{
+			name: "nil",
+		},
+		{
+			name:    "not_field_error",
+			err:     fmt.Errorf("policies not found"),
+			wantErr: "policies not found",
+		},
+		{
+			name: "validation_errors",
+			err:  v1alpha1.ValidateIAMRequest(&req, nil),
+			wantErr: `policies[0].bindings[0].members[1] at line 6, column 7: member "user:example.com" does not appear to be a valid email address (got "example.com")` + "\n" +
+				`policies[1].resource at line 8, column 13: resource "foo/bar" isn't one of [organizations, folders, projects, buckets, secrets, serviceAccounts, bigquery/datasets, kms/keyrings, //<service>]`,
+		},
+		{
+			name:    "nested_joined",
+			err:     errors.Join(errors.New("foo"), errors.Join(&v1alpha1.FieldError{Path: "policies[1]", Err: errors.New("bar")})),
+			wantErr: "foo\npolicies[1] at line 8, column 3: bar",
+		},
+		{
+			name:    "unknown_path",
+			err:     &v1alpha1.FieldError{Path: "policies[5]", Err: errors.New("foo")},
+			wantErr: "policies[5]: foo",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := testutil.DiffErrString(l.Annotate(tc.err), tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
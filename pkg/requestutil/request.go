@@ -17,6 +17,8 @@ package requestutil
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
@@ -27,24 +29,68 @@ import (
 // ReadRequestFromPath reads a YAML file at the given path and unmarshal it to
 // the given req.
 func ReadRequestFromPath(path string, req any) error {
+	_, err := ReadRequestWithLocator(path, req)
+	return err
+}
+
+// ReadRequestWithLocator reads a YAML file at the given path and unmarshal it
+// to the given req, it also returns a Locator to locate the fields of the req
+// in the file.
+func ReadRequestWithLocator(path string, req any) (*Locator, error) {
 	f, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("failed to read file at %q, %w", path, err)
+		return nil, fmt.Errorf("failed to read file at %q, %w", path, err)
 	}
 	defer f.Close()
 
-	data, err := io.ReadAll(io.LimitReader(f, 64*1_000))
+	data, err := io.ReadAll(io.LimitReader(f, maxRequestFileSize))
 	if err != nil {
-		return fmt.Errorf("failed to read file content at %q, %w", path, err)
+		return nil, fmt.Errorf("failed to read file content at %q, %w", path, err)
 	}
+	return DecodeRequestWithLocator(data, req)
+}
 
+// DecodeRequestWithLocator unmarshals the YAML data to the given req, it also
+// returns a Locator to locate the fields of the req in the data.
+func DecodeRequestWithLocator(data []byte, req any) (*Locator, error) {
+	l := &Locator{}
 	if len(data) > 0 {
 		dec := yaml.NewDecoder(bytes.NewReader(data))
 		dec.KnownFields(true)
 		if err := dec.Decode(req); err != nil {
-			return fmt.Errorf("failed to unmarshal yaml to %T: %w", req, err)
+			return nil, fmt.Errorf("failed to unmarshal yaml to %T: %w", req, ExplainUnknownFields(err, req))
+		}
+
+		// The data is already successfully decoded, so decoding it to a node
+		// is not expected to fail.
+		var n yaml.Node
+		if err := yaml.Unmarshal(data, &n); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal yaml to %T: %w", &n, err)
 		}
+		l.root = &n
 	}
 
-	return nil
+	return l, nil
+}
+
+// HashFile returns the SHA256 hash of the file at the given path in hex.
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file at %q, %w", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash file content at %q, %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Hash returns the SHA256 hash of the data in hex, the same as [HashFile] of a
+// file with the data.
+func Hash(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
 }
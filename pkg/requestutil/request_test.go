@@ -175,3 +175,45 @@ foo: bar
 		})
 	}
 }
+
+func TestHashFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "request.yaml")
+	if err := os.WriteFile(path, []byte("hello\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		path    string
+		expHash string
+		expErr  string
+	}{
+		{
+			name:    "success",
+			path:    path,
+			expHash: "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03",
+		},
+		{
+			name:   "missing_file",
+			path:   filepath.Join(dir, "missing.yaml"),
+			expErr: "failed to read file",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := HashFile(tc.path)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if got != tc.expHash {
+				t.Errorf("Process(%+v) got hash %q, want %q", tc.name, got, tc.expHash)
+			}
+		})
+	}
+}
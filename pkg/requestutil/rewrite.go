@@ -0,0 +1,108 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requestutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RewriteMembers replaces the member from with the member to in the "members"
+// lists of the YAML documents in data, and returns the rewritten data with the
+// number of replacements. Only the replaced members are changed in the data,
+// the rest of the data including comments and formatting is kept as is.
+func RewriteMembers(data []byte, from, to string) ([]byte, int, error) {
+	var members []*yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var n yaml.Node
+		if err := dec.Decode(&n); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, 0, fmt.Errorf("failed to unmarshal yaml to %T: %w", &n, err)
+		}
+		members = append(members, findMembers(&n, from)...)
+	}
+
+	slices.SortFunc(members, func(a, b *yaml.Node) int {
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		return a.Column - b.Column
+	})
+
+	// Line offsets in data, indexed by line number starting at 1.
+	lineOffsets := []int{0, 0}
+	for i, b := range data {
+		if b == '\n' {
+			lineOffsets = append(lineOffsets, i+1)
+		}
+	}
+
+	// Replace from the end so that the offsets of the other members are kept.
+	result := slices.Clone(data)
+	for i := len(members) - 1; i >= 0; i-- {
+		n := members[i]
+		old, nw := quote(from, n.Style), quote(to, n.Style)
+		start := lineOffsets[n.Line] + n.Column - 1
+		end := start + len(old)
+		if end > len(result) || string(result[start:end]) != old {
+			return nil, 0, fmt.Errorf("failed to locate member %q at line %d, column %d", from, n.Line, n.Column)
+		}
+		result = slices.Concat(result[:start], []byte(nw), result[end:])
+	}
+	return result, len(members), nil
+}
+
+// findMembers returns the scalar nodes of the member in the "members" lists in
+// the node.
+func findMembers(n *yaml.Node, member string) []*yaml.Node {
+	var result []*yaml.Node
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if k.Value != "members" || v.Kind != yaml.SequenceNode {
+				continue
+			}
+			for _, m := range v.Content {
+				if m.Kind == yaml.ScalarNode && m.Value == member {
+					result = append(result, m)
+				}
+			}
+		}
+	}
+	for _, c := range n.Content {
+		result = append(result, findMembers(c, member)...)
+	}
+	return result
+}
+
+// quote returns the member as written in YAML in the style.
+func quote(member string, style yaml.Style) string {
+	switch {
+	case style&yaml.DoubleQuotedStyle != 0:
+		return `"` + member + `"`
+	case style&yaml.SingleQuotedStyle != 0:
+		return `'` + member + `'`
+	default:
+		return member
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requestutil
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestRewriteMembers(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		data      string
+		wantData  string
+		wantCount int
+		wantErr   string
+	}{
+		{
+			name: "rewrite_members",
+			data: `# Access for the incident.
+policies:
+- resource: organizations/foo
+  bindings:
+  - members:
+    - user:old@example.com  # The on-call.
+    - user:bob@example.com
+    role: roles/cloudkms.cryptoOperator
+  - members: ["user:old@example.com", 'user:bob@example.com']
+    role: roles/viewer
+- resource: projects/baz
+  bindings:
+  - members:
+    - 'user:old@example.com'
+    role: roles/bigquery.dataViewer
+    # Not a member.
+    description: user:old@example.com
+`,
+			wantData: `# Access for the incident.
+policies:
+- resource: organizations/foo
+  bindings:
+  - members:
+    - user:new@example.com  # The on-call.
+    - user:bob@example.com
+    role: roles/cloudkms.cryptoOperator
+  - members: ["user:new@example.com", 'user:bob@example.com']
+    role: roles/viewer
+- resource: projects/baz
+  bindings:
+  - members:
+    - 'user:new@example.com'
+    role: roles/bigquery.dataViewer
+    # Not a member.
+    description: user:old@example.com
+`,
+			wantCount: 3,
+		},
+		{
+			name: "multiple_documents",
+			data: `policies:
+- resource: organizations/foo
+  bindings:
+  - members:
+    - user:old@example.com
+    role: roles/viewer
+---
+policies:
+- resource: projects/baz
+  bindings:
+  - members:
+    - user:old@example.com
+    role: roles/viewer
+`,
+			wantData: `policies:
+- resource: organizations/foo
+  bindings:
+  - members:
+    - user:new@example.com
+    role: roles/viewer
+---
+policies:
+- resource: projects/baz
+  bindings:
+  - members:
+    - user:new@example.com
+    role: roles/viewer
+`,
+			wantCount: 2,
+		},
+		{
+			name: "no_match",
+			data: `policies:
+- resource: organizations/foo
+  bindings:
+  - members:
+    - user:bob@example.com
+    role: roles/viewer
+`,
+			wantData: `policies:
+- resource: organizations/foo
+  bindings:
+  - members:
+    - user:bob@example.com
+    role: roles/viewer
+`,
+		},
+		{
+			name:    "invalid_yaml",
+			data:    `policies: [`,
+			wantErr: "failed to unmarshal yaml",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			gotData, gotCount, err := RewriteMembers([]byte(tc.data), "user:old@example.com", "user:new@example.com")
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected err: %s", tc.name, diff)
+			}
+			if tc.wantErr != "" {
+				return
+			}
+			if diff := cmp.Diff(tc.wantData, string(gotData)); diff != "" {
+				t.Errorf("Process(%+v) got data diff (-want, +got): %v", tc.name, diff)
+			}
+			if gotCount != tc.wantCount {
+				t.Errorf("Process(%+v) got count %d, want %d", tc.name, gotCount, tc.wantCount)
+			}
+		})
+	}
+}
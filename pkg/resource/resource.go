@@ -0,0 +1,322 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package resource parses the names of the GCP resources AOD manages IAM
+// policies of, such as "projects/foo", "buckets/foo", "secrets/foo/bar",
+// "serviceAccounts/sa@foo.iam.gserviceaccount.com",
+// "bigquery/datasets/foo/bar", "kms/keyrings/foo/global/bar" or the full
+// resource names of other resources, such as
+// "//pubsub.googleapis.com/projects/foo/topics/bar".
+package resource
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Type is the type of a resource, which is the first segment of its name, or
+// the first two segments of the names of BigQuery and Cloud KMS resources.
+// Resources named by their full resource names are all of [TypeFullName].
+type Type string
+
+// Types of the resources.
+const (
+	TypeOrganization    Type = "organizations"
+	TypeFolder          Type = "folders"
+	TypeProject         Type = "projects"
+	TypeBucket          Type = "buckets"
+	TypeSecret          Type = "secrets"
+	TypeServiceAccount  Type = "serviceAccounts"
+	TypeBigQueryDataset Type = "bigquery/datasets"
+	TypeBigQueryTable   Type = "bigquery/tables"
+	TypeKMSKeyRing      Type = "kms/keyrings"
+	TypeKMSKey          Type = "kms/keys"
+	TypeFullName        Type = "full-names"
+)
+
+// Types are the types of the resource hierarchy, in hierarchy order.
+var Types = []Type{TypeOrganization, TypeFolder, TypeProject}
+
+// leafTypes are the types of the resources outside of the resource hierarchy
+// which are named in the format of "<type>/<id>", such as Cloud Storage
+// buckets.
+var leafTypes = []Type{TypeBucket, TypeSecret, TypeServiceAccount}
+
+// secretFormat is the format of the names of Secret Manager secrets.
+const secretFormat = `"secrets/<project>/<secret>"`
+
+// serviceAccountFormat is the format of the names of service accounts.
+const serviceAccountFormat = `"serviceAccounts/<email>"`
+
+// serviceAccountDomain is the domain of the emails of service accounts.
+const serviceAccountDomain = ".gserviceaccount.com"
+
+// bigQueryFormats are the formats of the names of BigQuery resources.
+const bigQueryFormats = `"bigquery/datasets/<project>/<dataset>" or "bigquery/datasets/<project>/<dataset>/tables/<table>"`
+
+// kmsFormats are the formats of the names of Cloud KMS resources.
+const kmsFormats = `"kms/keyrings/<project>/<location>/<key ring>" or "kms/keyrings/<project>/<location>/<key ring>/keys/<key>"`
+
+// fullNameFormat is the format of full resource names.
+const fullNameFormat = `"//<service>/<relative name>"`
+
+// fullNamePrefix is the prefix of full resource names.
+const fullNamePrefix = "//"
+
+// Name is the parsed name of a resource.
+type Name struct {
+	// Type of the resource.
+	Type Type
+
+	// ID of the resource, such as the organization number, the project ID or
+	// the bucket name.
+	// The ID of a service account is its email.
+	// The ID of a secret is "<project>/<secret>", the ID of a BigQuery dataset
+	// is "<project>/<dataset>", and the ID of a BigQuery table is
+	// "<project>/<dataset>/tables/<table>".
+	// The ID of a Cloud KMS key ring is "<project>/<location>/<key ring>", and
+	// the ID of a key is "<project>/<location>/<key ring>/keys/<key>".
+	// The ID of a full resource name is the name without the leading "//",
+	// such as "pubsub.googleapis.com/projects/foo/topics/bar".
+	ID string
+}
+
+// New returns the name of the resource of the type with the ID.
+func New(typ Type, id string) *Name {
+	return &Name{Type: typ, ID: id}
+}
+
+// String returns the resource name in the format of "<type>/<id>", BigQuery
+// tables and Cloud KMS keys are named under their datasets and key rings, and
+// full resource names are returned as is.
+func (n *Name) String() string {
+	switch n.Type {
+	case TypeBigQueryTable:
+		return string(TypeBigQueryDataset) + "/" + n.ID
+	case TypeKMSKey:
+		return string(TypeKMSKeyRing) + "/" + n.ID
+	case TypeFullName:
+		return fullNamePrefix + n.ID
+	}
+	return string(n.Type) + "/" + n.ID
+}
+
+// Parse parses the resource name in the format of "<type>/<id>", such as
+// "organizations/123", "folders/456", "projects/foo", "buckets/foo",
+// "secrets/foo/bar" or "serviceAccounts/sa@foo.iam.gserviceaccount.com", or the
+// name of a BigQuery dataset or table, such as
+// "bigquery/datasets/foo/bar", or the name of a Cloud KMS key ring or key, such
+// as "kms/keyrings/foo/global/bar", or a full resource name, such as
+// "//pubsub.googleapis.com/projects/foo/topics/bar".
+func Parse(s string) (*Name, error) {
+	if strings.HasPrefix(s, fullNamePrefix) {
+		return parseFullName(s)
+	}
+	if strings.HasPrefix(s, "bigquery/") {
+		return parseBigQuery(s)
+	}
+	if strings.HasPrefix(s, "kms/") {
+		return parseKMS(s)
+	}
+
+	typ, id, _ := strings.Cut(s, "/")
+	if !IsType(typ) && !slices.Contains(leafTypes, Type(typ)) {
+		return nil, fmt.Errorf("resource %q isn't one of %s", s, typesString())
+	}
+	if Type(typ) == TypeSecret {
+		return parseSecret(s, id)
+	}
+	if Type(typ) == TypeServiceAccount {
+		return parseServiceAccount(s, id)
+	}
+	if id == "" {
+		return nil, fmt.Errorf("resource %q is missing the ID, must be in the format of \"%s/<id>\"", s, typ)
+	}
+	if strings.Contains(id, "/") {
+		return nil, fmt.Errorf("resource %q isn't in the format of \"%s/<id>\"", s, typ)
+	}
+	return &Name{Type: Type(typ), ID: id}, nil
+}
+
+// parseSecret parses the name of a Secret Manager secret with the ID.
+func parseSecret(s, id string) (*Name, error) {
+	project, secret, ok := strings.Cut(id, "/")
+	if !ok || project == "" || secret == "" || strings.Contains(secret, "/") {
+		return nil, fmt.Errorf("resource %q isn't in the format of %s", s, secretFormat)
+	}
+	return &Name{Type: TypeSecret, ID: id}, nil
+}
+
+// Secret returns the project and secret of the name of a Secret Manager
+// secret.
+func (n *Name) Secret() (project, secret string) {
+	project, secret, _ = strings.Cut(n.ID, "/")
+	return project, secret
+}
+
+// parseServiceAccount parses the name of a service account with the ID, which
+// must be the email of the service account.
+func parseServiceAccount(s, id string) (*Name, error) {
+	name, domain, ok := strings.Cut(id, "@")
+	if !ok || name == "" || strings.Contains(id, "/") || !strings.HasSuffix(domain, serviceAccountDomain) {
+		return nil, fmt.Errorf("resource %q isn't in the format of %s", s, serviceAccountFormat)
+	}
+	return &Name{Type: TypeServiceAccount, ID: id}, nil
+}
+
+// ServiceAccountProject returns the project of the name of a service account
+// created in a project, such as "foo" of
+// "serviceAccounts/sa@foo.iam.gserviceaccount.com". It is empty for service
+// accounts managed by Google, such as the default compute service account,
+// whose emails don't include their projects.
+func (n *Name) ServiceAccountProject() string {
+	_, domain, _ := strings.Cut(n.ID, "@")
+	project, ok := strings.CutSuffix(domain, ".iam"+serviceAccountDomain)
+	if !ok {
+		return ""
+	}
+	return project
+}
+
+// parseBigQuery parses the name of a BigQuery dataset or table.
+func parseBigQuery(s string) (*Name, error) {
+	id, ok := strings.CutPrefix(s, string(TypeBigQueryDataset)+"/")
+	if !ok {
+		return nil, fmt.Errorf("resource %q isn't one of %s", s, typesString())
+	}
+	parts := strings.Split(id, "/")
+	if slices.Contains(parts, "") {
+		return nil, fmt.Errorf("resource %q isn't in the format of %s", s, bigQueryFormats)
+	}
+	switch {
+	case len(parts) == 2:
+		return &Name{Type: TypeBigQueryDataset, ID: id}, nil
+	case len(parts) == 4 && parts[2] == "tables":
+		return &Name{Type: TypeBigQueryTable, ID: id}, nil
+	default:
+		return nil, fmt.Errorf("resource %q isn't in the format of %s", s, bigQueryFormats)
+	}
+}
+
+// BigQuery returns the project, dataset and table of the name of a BigQuery
+// dataset or table, the table is empty for datasets.
+func (n *Name) BigQuery() (project, dataset, table string) {
+	parts := strings.Split(n.ID, "/")
+	if len(parts) < 2 {
+		return "", "", ""
+	}
+	project, dataset = parts[0], parts[1]
+	if len(parts) == 4 {
+		table = parts[3]
+	}
+	return project, dataset, table
+}
+
+// parseKMS parses the name of a Cloud KMS key ring or key.
+func parseKMS(s string) (*Name, error) {
+	id, ok := strings.CutPrefix(s, string(TypeKMSKeyRing)+"/")
+	if !ok {
+		return nil, fmt.Errorf("resource %q isn't one of %s", s, typesString())
+	}
+	parts := strings.Split(id, "/")
+	if slices.Contains(parts, "") {
+		return nil, fmt.Errorf("resource %q isn't in the format of %s", s, kmsFormats)
+	}
+	switch {
+	case len(parts) == 3:
+		return &Name{Type: TypeKMSKeyRing, ID: id}, nil
+	case len(parts) == 5 && parts[3] == "keys":
+		return &Name{Type: TypeKMSKey, ID: id}, nil
+	default:
+		return nil, fmt.Errorf("resource %q isn't in the format of %s", s, kmsFormats)
+	}
+}
+
+// KMS returns the project, location, key ring and key of the name of a Cloud
+// KMS key ring or key, the key is empty for key rings.
+func (n *Name) KMS() (project, location, keyRing, key string) {
+	parts := strings.Split(n.ID, "/")
+	if len(parts) < 3 {
+		return "", "", "", ""
+	}
+	project, location, keyRing = parts[0], parts[1], parts[2]
+	if len(parts) == 5 {
+		key = parts[4]
+	}
+	return project, location, keyRing, key
+}
+
+// parseFullName parses a full resource name.
+func parseFullName(s string) (*Name, error) {
+	id := strings.TrimPrefix(s, fullNamePrefix)
+	service, relative, _ := strings.Cut(id, "/")
+	if !strings.Contains(service, ".") || relative == "" || slices.Contains(strings.Split(relative, "/"), "") {
+		return nil, fmt.Errorf("resource %q isn't in the format of %s", s, fullNameFormat)
+	}
+	return &Name{Type: TypeFullName, ID: id}, nil
+}
+
+// FullName returns the service and the relative resource name of a full
+// resource name, such as "pubsub.googleapis.com" and
+// "projects/foo/topics/bar".
+func (n *Name) FullName() (service, relative string) {
+	service, relative, _ = strings.Cut(n.ID, "/")
+	return service, relative
+}
+
+// IsType returns whether the string is one of the types of the resource
+// hierarchy.
+func IsType(s string) bool {
+	return slices.Contains(Types, Type(s))
+}
+
+// TypeOf returns the type of the resource name without validating it, such as
+// "projects" for "projects/foo", for labeling resources that may be invalid.
+func TypeOf(s string) Type {
+	if strings.HasPrefix(s, fullNamePrefix) {
+		return TypeFullName
+	}
+	if id, ok := strings.CutPrefix(s, string(TypeBigQueryDataset)+"/"); ok {
+		if strings.Contains(id, "/tables/") {
+			return TypeBigQueryTable
+		}
+		return TypeBigQueryDataset
+	}
+	if id, ok := strings.CutPrefix(s, string(TypeKMSKeyRing)+"/"); ok {
+		if strings.Contains(id, "/keys/") {
+			return TypeKMSKey
+		}
+		return TypeKMSKeyRing
+	}
+	typ, _, _ := strings.Cut(s, "/")
+	return Type(typ)
+}
+
+// HasType returns whether the resource name is of any of the types.
+func HasType(s string, types ...Type) bool {
+	return slices.Contains(types, TypeOf(s))
+}
+
+// typesString returns the types in the format of "[organizations, folders,
+// projects, buckets, secrets, serviceAccounts, bigquery/datasets,
+// kms/keyrings, //<service>]".
+func typesString() string {
+	ss := make([]string, 0, len(Types)+len(leafTypes)+3)
+	for _, t := range slices.Concat(Types, leafTypes) {
+		ss = append(ss, string(t))
+	}
+	ss = append(ss, string(TypeBigQueryDataset), string(TypeKMSKeyRing), fullNamePrefix+"<service>")
+	return "[" + strings.Join(ss, ", ") + "]"
+}
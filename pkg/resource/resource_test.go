@@ -0,0 +1,317 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		s       string
+		want    *Name
+		wantErr string
+	}{
+		{
+			name: "organization",
+			s:    "organizations/123",
+			want: &Name{Type: TypeOrganization, ID: "123"},
+		},
+		{
+			name: "folder",
+			s:    "folders/456",
+			want: &Name{Type: TypeFolder, ID: "456"},
+		},
+		{
+			name: "bucket",
+			s:    "buckets/foo",
+			want: &Name{Type: TypeBucket, ID: "foo"},
+		},
+		{
+			name: "project",
+			s:    "projects/foo",
+			want: &Name{Type: TypeProject, ID: "foo"},
+		},
+		{
+			name: "secret",
+			s:    "secrets/foo/bar",
+			want: &Name{Type: TypeSecret, ID: "foo/bar"},
+		},
+		{
+			name:    "secret_missing_secret",
+			s:       "secrets/foo",
+			wantErr: `resource "secrets/foo" isn't in the format of "secrets/<project>/<secret>"`,
+		},
+		{
+			name:    "secret_extra_segments",
+			s:       "secrets/foo/bar/versions/1",
+			wantErr: `resource "secrets/foo/bar/versions/1" isn't in the format of "secrets/<project>/<secret>"`,
+		},
+		{
+			name: "service_account",
+			s:    "serviceAccounts/sa@foo.iam.gserviceaccount.com",
+			want: &Name{Type: TypeServiceAccount, ID: "sa@foo.iam.gserviceaccount.com"},
+		},
+		{
+			name: "google_managed_service_account",
+			s:    "serviceAccounts/123-compute@developer.gserviceaccount.com",
+			want: &Name{Type: TypeServiceAccount, ID: "123-compute@developer.gserviceaccount.com"},
+		},
+		{
+			name:    "service_account_not_email",
+			s:       "serviceAccounts/sa",
+			wantErr: `resource "serviceAccounts/sa" isn't in the format of "serviceAccounts/<email>"`,
+		},
+		{
+			name:    "service_account_user_email",
+			s:       "serviceAccounts/alice@example.com",
+			wantErr: `resource "serviceAccounts/alice@example.com" isn't in the format of "serviceAccounts/<email>"`,
+		},
+		{
+			name:    "service_account_extra_segments",
+			s:       "serviceAccounts/sa@foo.iam.gserviceaccount.com/keys/1",
+			wantErr: `resource "serviceAccounts/sa@foo.iam.gserviceaccount.com/keys/1" isn't in the format of "serviceAccounts/<email>"`,
+		},
+		{
+			name: "bigquery_dataset",
+			s:    "bigquery/datasets/foo/bar",
+			want: &Name{Type: TypeBigQueryDataset, ID: "foo/bar"},
+		},
+		{
+			name: "bigquery_table",
+			s:    "bigquery/datasets/foo/bar/tables/baz",
+			want: &Name{Type: TypeBigQueryTable, ID: "foo/bar/tables/baz"},
+		},
+		{
+			name:    "bigquery_missing_dataset",
+			s:       "bigquery/datasets/foo",
+			wantErr: `resource "bigquery/datasets/foo" isn't in the format of "bigquery/datasets/<project>/<dataset>" or "bigquery/datasets/<project>/<dataset>/tables/<table>"`,
+		},
+		{
+			name:    "bigquery_invalid_table",
+			s:       "bigquery/datasets/foo/bar/views/baz",
+			wantErr: `resource "bigquery/datasets/foo/bar/views/baz" isn't in the format of "bigquery/datasets/<project>/<dataset>" or "bigquery/datasets/<project>/<dataset>/tables/<table>"`,
+		},
+		{
+			name:    "bigquery_unsupported_type",
+			s:       "bigquery/models/foo/bar",
+			wantErr: `resource "bigquery/models/foo/bar" isn't one of [organizations, folders, projects, buckets, secrets, serviceAccounts, bigquery/datasets, kms/keyrings, //<service>]`,
+		},
+		{
+			name: "kms_key_ring",
+			s:    "kms/keyrings/foo/global/bar",
+			want: &Name{Type: TypeKMSKeyRing, ID: "foo/global/bar"},
+		},
+		{
+			name: "kms_key",
+			s:    "kms/keyrings/foo/us-east1/bar/keys/baz",
+			want: &Name{Type: TypeKMSKey, ID: "foo/us-east1/bar/keys/baz"},
+		},
+		{
+			name:    "kms_missing_location",
+			s:       "kms/keyrings/foo/bar",
+			wantErr: `resource "kms/keyrings/foo/bar" isn't in the format of "kms/keyrings/<project>/<location>/<key ring>" or "kms/keyrings/<project>/<location>/<key ring>/keys/<key>"`,
+		},
+		{
+			name:    "kms_invalid_key",
+			s:       "kms/keyrings/foo/global/bar/cryptoKeys/baz",
+			wantErr: `resource "kms/keyrings/foo/global/bar/cryptoKeys/baz" isn't in the format of "kms/keyrings/<project>/<location>/<key ring>" or "kms/keyrings/<project>/<location>/<key ring>/keys/<key>"`,
+		},
+		{
+			name:    "kms_unsupported_type",
+			s:       "kms/keys/foo/global/bar/keys/baz",
+			wantErr: `resource "kms/keys/foo/global/bar/keys/baz" isn't one of [organizations, folders, projects, buckets, secrets, serviceAccounts, bigquery/datasets, kms/keyrings, //<service>]`,
+		},
+		{
+			name: "full_name",
+			s:    "//pubsub.googleapis.com/projects/foo/topics/bar",
+			want: &Name{Type: TypeFullName, ID: "pubsub.googleapis.com/projects/foo/topics/bar"},
+		},
+		{
+			name:    "full_name_missing_relative_name",
+			s:       "//pubsub.googleapis.com",
+			wantErr: `resource "//pubsub.googleapis.com" isn't in the format of "//<service>/<relative name>"`,
+		},
+		{
+			name:    "full_name_invalid_service",
+			s:       "//projects/foo/topics/bar",
+			wantErr: `resource "//projects/foo/topics/bar" isn't in the format of "//<service>/<relative name>"`,
+		},
+		{
+			name:    "full_name_empty_segment",
+			s:       "//pubsub.googleapis.com/projects//topics/bar",
+			wantErr: `resource "//pubsub.googleapis.com/projects//topics/bar" isn't in the format of "//<service>/<relative name>"`,
+		},
+		{
+			name:    "unsupported_type",
+			s:       "topics/foo",
+			wantErr: `resource "topics/foo" isn't one of [organizations, folders, projects, buckets, secrets, serviceAccounts, bigquery/datasets, kms/keyrings, //<service>]`,
+		},
+		{
+			name:    "bucket_extra_segments",
+			s:       "buckets/foo/objects/bar",
+			wantErr: `resource "buckets/foo/objects/bar" isn't in the format of "buckets/<id>"`,
+		},
+		{
+			name:    "empty",
+			s:       "",
+			wantErr: `resource "" isn't one of [organizations, folders, projects, buckets, secrets, serviceAccounts, bigquery/datasets, kms/keyrings, //<service>]`,
+		},
+		{
+			name:    "missing_id",
+			s:       "projects/",
+			wantErr: `resource "projects/" is missing the ID, must be in the format of "projects/<id>"`,
+		},
+		{
+			name:    "missing_slash",
+			s:       "folders",
+			wantErr: `resource "folders" is missing the ID, must be in the format of "folders/<id>"`,
+		},
+		{
+			name:    "extra_segments",
+			s:       "projects/foo/buckets/bar",
+			wantErr: `resource "projects/foo/buckets/bar" isn't in the format of "projects/<id>"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Parse(tc.s)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected err: %s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Process(%+v) got name diff (-want, +got): %v", tc.name, diff)
+			}
+			if got != nil && got.String() != tc.s {
+				t.Errorf("Process(%+v) got string %q, want %q", tc.name, got.String(), tc.s)
+			}
+		})
+	}
+}
+
+func TestHasType(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name  string
+		s     string
+		types []Type
+		want  bool
+	}{
+		{
+			name:  "match",
+			s:     "organizations/123",
+			types: []Type{TypeOrganization},
+			want:  true,
+		},
+		{
+			name:  "match_any",
+			s:     "folders/456",
+			types: []Type{TypeOrganization, TypeFolder},
+			want:  true,
+		},
+		{
+			name:  "no_match",
+			s:     "projects/foo",
+			types: []Type{TypeOrganization, TypeFolder},
+		},
+		{
+			name:  "prefix_only",
+			s:     "projectsfoo",
+			types: []Type{TypeProject},
+		},
+		{
+			name:  "bigquery_dataset",
+			s:     "bigquery/datasets/foo/bar",
+			types: []Type{TypeBigQueryDataset},
+			want:  true,
+		},
+		{
+			name:  "bigquery_table",
+			s:     "bigquery/datasets/foo/bar/tables/baz",
+			types: []Type{TypeBigQueryDataset},
+		},
+		{
+			name:  "kms_key",
+			s:     "kms/keyrings/foo/global/bar/keys/baz",
+			types: []Type{TypeKMSKey},
+			want:  true,
+		},
+		{
+			name:  "full_name",
+			s:     "//pubsub.googleapis.com/projects/foo/topics/bar",
+			types: []Type{TypeFullName},
+			want:  true,
+		},
+		{
+			name: "no_types",
+			s:    "projects/foo",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := HasType(tc.s, tc.types...); got != tc.want {
+				t.Errorf("Process(%+v) got %t, want %t", tc.name, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestServiceAccountProject(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{
+			name: "user_managed",
+			s:    "serviceAccounts/sa@foo.iam.gserviceaccount.com",
+			want: "foo",
+		},
+		{
+			name: "google_managed",
+			s:    "serviceAccounts/123-compute@developer.gserviceaccount.com",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			n, err := Parse(tc.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := n.ServiceAccountProject(); got != tc.want {
+				t.Errorf("Process(%+v) got project %q, want %q", tc.name, got, tc.want)
+			}
+		})
+	}
+}
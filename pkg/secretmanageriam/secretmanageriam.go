@@ -0,0 +1,166 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package secretmanageriam gets and sets the IAM policies of Secret Manager
+// secrets in the format of the IAM API, so that they are handled like the IAM
+// policies of projects.
+package secretmanageriam
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/api/option"
+	secretmanager "google.golang.org/api/secretmanager/v1"
+	"google.golang.org/genproto/googleapis/type/expr"
+
+	"github.com/abcxyz/access-on-demand/pkg/iamdelta"
+	"github.com/abcxyz/access-on-demand/pkg/resource"
+)
+
+// Client gets and sets the IAM policies of Secret Manager secrets, named
+// "secrets/<project>/<secret>".
+type Client struct {
+	service *secretmanager.Service
+}
+
+// NewClient creates a new Client.
+func NewClient(ctx context.Context, opts ...option.ClientOption) (*Client, error) {
+	svc, err := secretmanager.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create secret manager service: %w", err)
+	}
+	return &Client{service: svc}, nil
+}
+
+// GetIamPolicy gets the IAM policy of the secret.
+func (c *Client) GetIamPolicy(ctx context.Context, req *iampb.GetIamPolicyRequest, _ ...gax.CallOption) (*iampb.Policy, error) {
+	name, err := parse(req.GetResource())
+	if err != nil {
+		return nil, err
+	}
+
+	call := c.service.Projects.Secrets.GetIamPolicy(name).Context(ctx)
+	if v := req.GetOptions().GetRequestedPolicyVersion(); v > 0 {
+		call = call.OptionsRequestedPolicyVersion(int64(v))
+	}
+	p, err := call.Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IAM policy of secret %q: %w", req.GetResource(), err)
+	}
+	return fromPolicy(p)
+}
+
+// SetIamPolicy sets the bindings of the IAM policy of the secret, the audit
+// configs of the policy are kept.
+func (c *Client) SetIamPolicy(ctx context.Context, req *iampb.SetIamPolicyRequest, _ ...gax.CallOption) (*iampb.Policy, error) {
+	name, err := parse(req.GetResource())
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := c.service.Projects.Secrets.SetIamPolicy(name, &secretmanager.SetIamPolicyRequest{
+		Policy: toPolicy(req.GetPolicy()),
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to set IAM policy of secret %q: %w", req.GetResource(), err)
+	}
+	return fromPolicy(p)
+}
+
+// PatchIamPolicy applies the changes to the bindings to the latest IAM policy
+// of the secret.
+func (c *Client) PatchIamPolicy(ctx context.Context, resource string, d *iamdelta.Delta) (*iampb.Policy, error) {
+	return iamdelta.Patch(ctx, c, resource, d) //nolint:wrapcheck // Want passthrough
+}
+
+// parse returns the name of the secret in the Secret Manager API, in the
+// format of "projects/<project>/secrets/<secret>".
+func parse(name string) (string, error) {
+	rn, err := resource.Parse(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse resource: %w", err)
+	}
+	if rn.Type != resource.TypeSecret {
+		return "", fmt.Errorf("resource %q is not a secret", name)
+	}
+	project, secret := rn.Secret()
+	return fmt.Sprintf("projects/%s/secrets/%s", project, secret), nil
+}
+
+// fromPolicy converts the IAM policy of a secret to an IAM policy of the IAM
+// API.
+func fromPolicy(p *secretmanager.Policy) (*iampb.Policy, error) {
+	etag, err := base64.StdEncoding.DecodeString(p.Etag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode etag %q: %w", p.Etag, err)
+	}
+	policy := &iampb.Policy{Version: int32(p.Version), Etag: etag}
+	for _, b := range p.Bindings {
+		policy.Bindings = append(policy.Bindings, &iampb.Binding{
+			Role:      b.Role,
+			Members:   b.Members,
+			Condition: fromExpr(b.Condition),
+		})
+	}
+	return policy, nil
+}
+
+// toPolicy converts the IAM policy of the IAM API to an IAM policy of a
+// secret.
+func toPolicy(p *iampb.Policy) *secretmanager.Policy {
+	policy := &secretmanager.Policy{
+		Version: int64(p.GetVersion()),
+		Etag:    base64.StdEncoding.EncodeToString(p.GetEtag()),
+	}
+	for _, b := range p.GetBindings() {
+		policy.Bindings = append(policy.Bindings, &secretmanager.Binding{
+			Role:      b.GetRole(),
+			Members:   b.GetMembers(),
+			Condition: toExpr(b.GetCondition()),
+		})
+	}
+	return policy
+}
+
+// fromExpr converts the condition of the Secret Manager API to a condition of
+// the IAM API.
+func fromExpr(e *secretmanager.Expr) *expr.Expr {
+	if e == nil {
+		return nil
+	}
+	return &expr.Expr{
+		Title:       e.Title,
+		Description: e.Description,
+		Expression:  e.Expression,
+		Location:    e.Location,
+	}
+}
+
+// toExpr converts the condition of the IAM API to a condition of the Secret
+// Manager API.
+func toExpr(e *expr.Expr) *secretmanager.Expr {
+	if e == nil {
+		return nil
+	}
+	return &secretmanager.Expr{
+		Title:       e.GetTitle(),
+		Description: e.GetDescription(),
+		Expression:  e.GetExpression(),
+		Location:    e.GetLocation(),
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secretmanageriam
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	secretmanager "google.golang.org/api/secretmanager/v1"
+	"google.golang.org/genproto/googleapis/type/expr"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+// fakeSecretManager is a fake Secret Manager API of the IAM policy of a
+// secret.
+type fakeSecretManager struct {
+	mu     sync.Mutex
+	policy *secretmanager.Policy
+	calls  []string
+}
+
+func (f *fakeSecretManager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, r.Method+" "+r.URL.Path)
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case strings.HasSuffix(r.URL.Path, ":getIamPolicy"):
+		if r.URL.Query().Get("options.requestedPolicyVersion") != "3" {
+			http.Error(w, `{"error": {"code": 400, "message": "missing policy version"}}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(f.policy) //nolint:errcheck // Test server.
+	case strings.HasSuffix(r.URL.Path, ":setIamPolicy"):
+		var req secretmanager.SetIamPolicyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Policy.Etag != f.policy.Etag {
+			http.Error(w, `{"error": {"code": 409, "message": "etag mismatch"}}`, http.StatusConflict)
+			return
+		}
+		f.policy = req.Policy
+		f.policy.Etag = base64.StdEncoding.EncodeToString([]byte("etag2"))
+		json.NewEncoder(w).Encode(f.policy) //nolint:errcheck // Test server.
+	}
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeSecretManager{
+		policy: &secretmanager.Policy{
+			Version: 1,
+			Etag:    base64.StdEncoding.EncodeToString([]byte("etag1")),
+			Bindings: []*secretmanager.Binding{
+				{Role: "roles/secretmanager.admin", Members: []string{"group:admins@example.com"}},
+			},
+		},
+	}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	c, err := NewClient(ctx, option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	got, err := c.GetIamPolicy(ctx, &iampb.GetIamPolicyRequest{
+		Resource: "secrets/foo/bar",
+		Options:  &iampb.GetPolicyOptions{RequestedPolicyVersion: 3},
+	})
+	if err != nil {
+		t.Fatalf("GetIamPolicy got unexpected error: %v", err)
+	}
+	want := &iampb.Policy{
+		Version: 1,
+		Etag:    []byte("etag1"),
+		Bindings: []*iampb.Binding{
+			{Role: "roles/secretmanager.admin", Members: []string{"group:admins@example.com"}},
+		},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("GetIamPolicy got policy diff (-want, +got):\n%s", diff)
+	}
+
+	cond := &expr.Expr{Title: "expiry", Expression: `request.time < timestamp("2009-11-10T23:00:00Z")`}
+	got.Version = 3
+	got.Bindings = append(got.Bindings, &iampb.Binding{
+		Role:      "roles/secretmanager.secretAccessor",
+		Members:   []string{"user:oncall@example.com"},
+		Condition: cond,
+	})
+	got, err = c.SetIamPolicy(ctx, &iampb.SetIamPolicyRequest{Resource: "secrets/foo/bar", Policy: got})
+	if err != nil {
+		t.Fatalf("SetIamPolicy got unexpected error: %v", err)
+	}
+	want.Version = 3
+	want.Etag = []byte("etag2")
+	want.Bindings = append(want.Bindings, &iampb.Binding{
+		Role:      "roles/secretmanager.secretAccessor",
+		Members:   []string{"user:oncall@example.com"},
+		Condition: cond,
+	})
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("SetIamPolicy got policy diff (-want, +got):\n%s", diff)
+	}
+
+	// The etag of the policy is stale.
+	got.Etag = []byte("etag1")
+	_, err = c.SetIamPolicy(ctx, &iampb.SetIamPolicyRequest{Resource: "secrets/foo/bar", Policy: got})
+	if diff := testutil.DiffErrString(err, `failed to set IAM policy of secret "secrets/foo/bar"`); diff != "" {
+		t.Errorf("SetIamPolicy got unexpected error: %s", diff)
+	}
+
+	_, err = c.GetIamPolicy(ctx, &iampb.GetIamPolicyRequest{Resource: "projects/foo"})
+	if diff := testutil.DiffErrString(err, `resource "projects/foo" is not a secret`); diff != "" {
+		t.Errorf("GetIamPolicy got unexpected error: %s", diff)
+	}
+
+	wantCalls := []string{
+		"GET /v1/projects/foo/secrets/bar:getIamPolicy",
+		"POST /v1/projects/foo/secrets/bar:setIamPolicy",
+		"POST /v1/projects/foo/secrets/bar:setIamPolicy",
+	}
+	if diff := cmp.Diff(wantCalls, fake.calls); diff != "" {
+		t.Errorf("got calls diff (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+)
+
+// maxResponseSize is the max size of a response body read by the Client.
+const maxResponseSize = 1 << 20
+
+// Client is an HTTP client of the server's endpoints of detached operations.
+type Client struct {
+	client *http.Client
+	url    string
+}
+
+// NewClient creates a new Client of the server at the URL, such as
+// "https://aod.example.com". The HTTP client is expected to authenticate to
+// the server, e.g. with an ID token.
+func NewClient(client *http.Client, serverURL string) (*Client, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid server URL %q, must be an absolute URL", serverURL)
+	}
+	return &Client{client: client, url: strings.TrimSuffix(serverURL, "/")}, nil
+}
+
+// DetachHandle enqueues the IAM request to be handled, and returns the
+// detached operation.
+func (c *Client) DetachHandle(ctx context.Context, req *v1alpha1.IAMRequestWrapper) (*Operation, error) {
+	q := url.Values{}
+	q.Set("detach", "true")
+	q.Set("duration", req.Duration.String())
+	if !req.StartTime.IsZero() {
+		q.Set("start-time", req.StartTime.Format(time.RFC3339))
+	}
+	if req.Requester != "" {
+		q.Set("requester", req.Requester)
+	}
+	for _, a := range req.Approvers {
+		q.Add("approver", a)
+	}
+	if req.Source != "" {
+		q.Set("source", req.Source)
+	}
+	return c.detach(ctx, "/v1/iam:handle", q, req.IAMRequest)
+}
+
+// DetachCleanup enqueues the IAM request to be cleaned up, and returns the
+// detached operation.
+func (c *Client) DetachCleanup(ctx context.Context, req *v1alpha1.IAMRequest) (*Operation, error) {
+	q := url.Values{}
+	q.Set("detach", "true")
+	return c.detach(ctx, "/v1/iam:cleanup", q, req)
+}
+
+// GetOperation returns the operation with the ID.
+func (c *Client) GetOperation(ctx context.Context, id string) (*Operation, error) {
+	return c.do(ctx, http.MethodGet, "/v1/operations/"+url.PathEscape(id), nil, nil)
+}
+
+// detach posts the IAM request to the endpoint as a detached operation.
+func (c *Client) detach(ctx context.Context, path string, q url.Values, req *v1alpha1.IAMRequest) (*Operation, error) {
+	body, err := toJSON(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %T: %w", req, err)
+	}
+	return c.do(ctx, http.MethodPost, path, q, body)
+}
+
+// do calls the endpoint and returns the operation in the response.
+func (c *Client) do(ctx context.Context, method, path string, q url.Values, body []byte) (*Operation, error) {
+	u := c.url + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	var out struct {
+		Operation *Operation `json:"operation"`
+		Error     string     `json:"error"`
+	}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, fmt.Errorf("failed to decode response with status %d: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("%s %s failed with status %d: %s", method, path, resp.StatusCode, out.Error)
+	}
+	if out.Operation == nil {
+		return nil, fmt.Errorf("%s %s returned no operation", method, path)
+	}
+	return out.Operation, nil
+}
@@ -0,0 +1,247 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1/aodpb"
+	"github.com/abcxyz/access-on-demand/pkg/handler"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// grpcService is the gRPC service of AOD, which wraps the same IAM handler and
+// validation as the HTTP endpoints.
+type grpcService struct {
+	aodpb.UnimplementedAccessOnDemandServer
+
+	s *Server
+}
+
+// RegisterGRPC registers the AccessOnDemand gRPC service of the server to the
+// gRPC server.
+func (s *Server) RegisterGRPC(gs *grpc.Server) {
+	aodpb.RegisterAccessOnDemandServer(gs, &grpcService{s: s})
+}
+
+// HandleIAM adds the IAM bindings in the request with an expiry condition.
+func (g *grpcService) HandleIAM(ctx context.Context, in *aodpb.HandleIAMRequest) (*aodpb.HandleIAMResponse, error) {
+	logger := logging.FromContext(ctx)
+
+	if in.GetDuration() == nil {
+		return nil, status.Error(codes.InvalidArgument, "duration is required")
+	}
+	if err := in.GetDuration().CheckValid(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid duration: %s", err)
+	}
+	duration := in.GetDuration().AsDuration()
+
+	startTime := g.s.now()
+	if in.GetStartTime() != nil {
+		if err := in.GetStartTime().CheckValid(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid start_time: %s", err)
+		}
+		startTime = in.GetStartTime().AsTime()
+	}
+	if err := g.s.checkExpiry(startTime, duration); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	reqWrapper := &v1alpha1.IAMRequestWrapper{
+		Duration:  duration,
+		StartTime: startTime,
+		Requester: in.GetRequester(),
+		Approvers: in.GetApprovers(),
+		Source:    in.GetSource(),
+	}
+	if err := g.validate(ctx, in.GetRequest(), reqWrapper); err != nil {
+		return nil, err
+	}
+
+	// Hash the request deterministically, the same request has the same hash.
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(in.GetRequest())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to marshal request: %s", err)
+	}
+	sum := sha256.Sum256(b)
+	reqWrapper.RequestHash = hex.EncodeToString(sum[:])
+
+	resp, err := g.s.handler.Do(rpcContext(ctx), reqWrapper)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to handle IAM request",
+			"error", err,
+			"warnings", warnings(resp))
+		return nil, status.Errorf(codes.Internal, "failed to handle IAM request: %s", err)
+	}
+	return &aodpb.HandleIAMResponse{
+		Request:   in.GetRequest(),
+		Expiry:    timestamppb.New(startTime.Add(duration)),
+		Responses: toResponsesProto(resp),
+	}, nil
+}
+
+// CleanupIAM removes the IAM bindings in the request.
+func (g *grpcService) CleanupIAM(ctx context.Context, in *aodpb.CleanupIAMRequest) (*aodpb.CleanupIAMResponse, error) {
+	logger := logging.FromContext(ctx)
+
+	reqWrapper := &v1alpha1.IAMRequestWrapper{}
+	if err := g.validate(ctx, in.GetRequest(), reqWrapper); err != nil {
+		return nil, err
+	}
+
+	resp, err := g.s.handler.Cleanup(rpcContext(ctx), reqWrapper.IAMRequest)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to clean up IAM policy",
+			"error", err,
+			"warnings", warnings(resp))
+		return nil, status.Errorf(codes.Internal, "failed to clean up IAM policy: %s", err)
+	}
+	return &aodpb.CleanupIAMResponse{
+		Request:   in.GetRequest(),
+		Responses: toResponsesProto(resp),
+	}, nil
+}
+
+// Validate validates the IAM request without handling it.
+func (g *grpcService) Validate(ctx context.Context, in *aodpb.ValidateRequest) (*aodpb.ValidateResponse, error) {
+	if err := g.validate(ctx, in.GetRequest(), &v1alpha1.IAMRequestWrapper{}); err != nil {
+		return nil, err
+	}
+	return &aodpb.ValidateResponse{Request: in.GetRequest()}, nil
+}
+
+// ListGrants lists the active AOD IAM bindings on the resources.
+func (g *grpcService) ListGrants(ctx context.Context, in *aodpb.ListGrantsRequest) (*aodpb.ListGrantsResponse, error) {
+	logger := logging.FromContext(ctx)
+
+	if len(in.GetResources()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "at least one resource is required")
+	}
+
+	grants, err := g.s.handler.List(ctx, in.GetResources())
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to list grants", "error", err)
+		return nil, status.Errorf(codes.Internal, "failed to list grants: %s", err)
+	}
+
+	resp := &aodpb.ListGrantsResponse{Grants: make([]*aodpb.Grant, 0, len(grants))}
+	for _, gr := range grants {
+		resp.Grants = append(resp.Grants, &aodpb.Grant{
+			Resource: gr.Resource,
+			Role:     gr.Role,
+			Member:   gr.Member,
+			Expiry:   timestamppb.New(gr.Expiry),
+		})
+	}
+	return resp, nil
+}
+
+// validate converts the IAM request, resolves its member aliases and validates
+// it the same as a request file, and sets it in the wrapper the ValidateFuncs
+// are run on.
+func (g *grpcService) validate(ctx context.Context, in *aodpb.IAMRequest, w *v1alpha1.IAMRequestWrapper) error {
+	req := fromRequestProto(in)
+	if err := g.s.identities.Resolve(req); err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed to resolve members of %T: %s", req, err)
+	}
+	if err := v1alpha1.ValidateIAMRequest(req, nil); err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed to validate %T: %s", req, err)
+	}
+	w.IAMRequest = req
+	if err := g.s.validate(ctx, w); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+// rpcContext returns the context of the call with its correlation ID, which is
+// the x-aod-correlation-id metadata or a random ID if the metadata is not set.
+// The correlation ID is also set in the response header.
+func rpcContext(ctx context.Context) context.Context {
+	var id string
+	if vs := metadata.ValueFromIncomingContext(ctx, handler.MetadataKeyCorrelationID); len(vs) > 0 {
+		id = vs[0]
+	}
+	if id == "" {
+		// Fall back to the correlation ID of the handler if it fails.
+		id, _ = handler.NewCorrelationID()
+	}
+	if err := grpc.SetHeader(ctx, metadata.Pairs(handler.MetadataKeyCorrelationID, id)); err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "failed to set correlation ID header", "error", err)
+	}
+	return handler.WithRequestMetadata(ctx, &handler.RequestMetadata{CorrelationID: id})
+}
+
+// fromRequestProto converts the IAM request proto to the API type. The members
+// are copied, so that resolving their aliases does not change the proto.
+func fromRequestProto(in *aodpb.IAMRequest) *v1alpha1.IAMRequest {
+	req := &v1alpha1.IAMRequest{}
+	for _, p := range in.GetPolicies() {
+		rp := &v1alpha1.ResourcePolicy{Resource: p.GetResource(), DependsOn: p.GetDependsOn()}
+		for _, b := range p.GetBindings() {
+			rp.Bindings = append(rp.Bindings, &v1alpha1.Binding{
+				Members:     slices.Clone(b.GetMembers()),
+				Role:        b.GetRole(),
+				RoleBundle:  b.GetRoleBundle(),
+				StartOffset: b.GetStartOffset().AsDuration(),
+			})
+		}
+		req.ResourcePolicies = append(req.ResourcePolicies, rp)
+	}
+	for _, p := range in.GetDenyPolicies() {
+		dp := &v1alpha1.DenyPolicy{Resource: p.GetResource()}
+		for _, r := range p.GetRules() {
+			dp.Rules = append(dp.Rules, &v1alpha1.DenyRule{
+				DeniedMembers:     slices.Clone(r.GetDeniedMembers()),
+				ExceptionMembers:  slices.Clone(r.GetExceptionMembers()),
+				DeniedPermissions: r.GetDeniedPermissions(),
+			})
+		}
+		req.DenyPolicies = append(req.DenyPolicies, dp)
+	}
+	if r := in.GetRecurring(); r != nil {
+		req.Recurring = &v1alpha1.Recurring{
+			Days:     r.GetDays(),
+			Hours:    r.GetHours(),
+			TimeZone: r.GetTimeZone(),
+			Until:    r.GetUntil(),
+		}
+	}
+	return req
+}
+
+// toResponsesProto converts the IAM responses to protos, without the policies.
+func toResponsesProto(resp []*v1alpha1.IAMResponse) []*aodpb.IAMResponse {
+	out := make([]*aodpb.IAMResponse, 0, len(resp))
+	for _, r := range resp {
+		out = append(out, &aodpb.IAMResponse{
+			Resource: r.Resource,
+			Warnings: r.Warnings,
+			Skipped:  r.Skipped,
+		})
+	}
+	return out
+}
@@ -0,0 +1,528 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1/aodpb"
+	"github.com/abcxyz/access-on-demand/pkg/handler"
+	"github.com/abcxyz/access-on-demand/pkg/identity"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+// testRequestProtoHash is the SHA256 hash of testRequestProto in hex.
+const testRequestProtoHash = "ec2a811da2bdfb71bfece930544fd7688050a84d4e14ff3f392954a213d29bcc"
+
+var testRequestProto = &aodpb.IAMRequest{
+	Policies: []*aodpb.ResourcePolicy{
+		{
+			Resource: "organizations/foo",
+			Bindings: []*aodpb.Binding{
+				{
+					Members: []string{"user:test-org-user@example.com"},
+					Role:    "roles/cloudkms.cryptoOperator",
+				},
+			},
+		},
+	},
+}
+
+// fullRequestProtoHash is the SHA256 hash of fullRequestProto in hex.
+const fullRequestProtoHash = "3d77ee47efb1cdf58a2e301122a34683abb5fd6aab53e0f62e23077e8a6fa4cf"
+
+// fullRequestProto is an IAM request with all the fields of the proto set.
+var fullRequestProto = &aodpb.IAMRequest{
+	Policies: []*aodpb.ResourcePolicy{
+		{
+			Resource: "organizations/foo",
+			Bindings: []*aodpb.Binding{
+				{
+					Members:     []string{"user:test-org-user@example.com"},
+					Role:        "roles/cloudkms.cryptoOperator",
+					StartOffset: durationpb.New(30 * time.Minute),
+				},
+			},
+		},
+	},
+	DenyPolicies: []*aodpb.DenyPolicy{
+		{
+			Resource: "projects/bar",
+			Rules: []*aodpb.DenyRule{
+				{
+					DeniedMembers:     []string{"user:oncall@example.com"},
+					ExceptionMembers:  []string{"user:test-org-user@example.com"},
+					DeniedPermissions: []string{"cloudresourcemanager.googleapis.com/projects.delete"},
+				},
+			},
+		},
+	},
+	Recurring: &aodpb.Recurring{
+		Days:     []string{"Mon-Fri"},
+		Hours:    "09:00-17:00",
+		TimeZone: "America/Los_Angeles",
+		Until:    "2009-12-31",
+	},
+}
+
+// fullRequest is fullRequestProto converted to the API type.
+var fullRequest = &v1alpha1.IAMRequest{
+	ResourcePolicies: []*v1alpha1.ResourcePolicy{
+		{
+			Resource: "organizations/foo",
+			Bindings: []*v1alpha1.Binding{
+				{
+					Members:     []string{"user:test-org-user@example.com"},
+					Role:        "roles/cloudkms.cryptoOperator",
+					StartOffset: 30 * time.Minute,
+				},
+			},
+		},
+	},
+	DenyPolicies: []*v1alpha1.DenyPolicy{
+		{
+			Resource: "projects/bar",
+			Rules: []*v1alpha1.DenyRule{
+				{
+					DeniedMembers:     []string{"user:oncall@example.com"},
+					ExceptionMembers:  []string{"user:test-org-user@example.com"},
+					DeniedPermissions: []string{"cloudresourcemanager.googleapis.com/projects.delete"},
+				},
+			},
+		},
+	},
+	Recurring: &v1alpha1.Recurring{
+		Days:     []string{"Mon-Fri"},
+		Hours:    "09:00-17:00",
+		TimeZone: "America/Los_Angeles",
+		Until:    "2009-12-31",
+	},
+}
+
+// aliasedRequestProto is testRequestProto with the member alias of the test
+// user.
+var aliasedRequestProto = &aodpb.IAMRequest{
+	Policies: []*aodpb.ResourcePolicy{
+		{
+			Resource: "organizations/foo",
+			Bindings: []*aodpb.Binding{
+				{
+					Members: []string{"github:octocat"},
+					Role:    "roles/cloudkms.cryptoOperator",
+				},
+			},
+		},
+	},
+}
+
+func TestGRPCService(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name      string
+		call      func(context.Context, aodpb.AccessOnDemandClient) (any, error)
+		handler   *fakeIAMHandler
+		validate  ValidateFunc
+		ids       identity.Map
+		wantResp  any
+		wantCode  codes.Code
+		wantErr   string
+		wantDo    *v1alpha1.IAMRequestWrapper
+		wantClean *v1alpha1.IAMRequest
+		wantList  []string
+
+		wantCorrelationID string
+	}{
+		{
+			name: "handle_success",
+			call: func(ctx context.Context, c aodpb.AccessOnDemandClient) (any, error) {
+				return c.HandleIAM(ctx, &aodpb.HandleIAMRequest{
+					Request:   testRequestProto,
+					Duration:  durationpb.New(2 * time.Hour),
+					Requester: "user:alice@example.com",
+					Approvers: []string{"user:bob@example.com"},
+					Source:    "https://example.com/1",
+				})
+			},
+			handler: &fakeIAMHandler{
+				resp: []*v1alpha1.IAMResponse{{Resource: "organizations/foo", Warnings: []string{"injected warning"}}},
+			},
+			wantResp: &aodpb.HandleIAMResponse{
+				Request: testRequestProto,
+				Expiry:  timestamppb.New(now.Add(2 * time.Hour)),
+				Responses: []*aodpb.IAMResponse{
+					{Resource: "organizations/foo", Warnings: []string{"injected warning"}},
+				},
+			},
+			wantDo: &v1alpha1.IAMRequestWrapper{
+				IAMRequest:  &v1alpha1.IAMRequest{ResourcePolicies: []*v1alpha1.ResourcePolicy{testPolicy}},
+				Duration:    2 * time.Hour,
+				StartTime:   now,
+				Requester:   "user:alice@example.com",
+				Approvers:   []string{"user:bob@example.com"},
+				Source:      "https://example.com/1",
+				RequestHash: testRequestProtoHash,
+			},
+		},
+		{
+			name: "handle_with_start_time",
+			call: func(ctx context.Context, c aodpb.AccessOnDemandClient) (any, error) {
+				return c.HandleIAM(ctx, &aodpb.HandleIAMRequest{
+					Request:   testRequestProto,
+					Duration:  durationpb.New(time.Hour),
+					StartTime: timestamppb.New(now.Add(-30 * time.Minute)),
+				})
+			},
+			handler: &fakeIAMHandler{},
+			wantResp: &aodpb.HandleIAMResponse{
+				Request:   testRequestProto,
+				Expiry:    timestamppb.New(now.Add(30 * time.Minute)),
+				Responses: []*aodpb.IAMResponse{},
+			},
+			wantDo: &v1alpha1.IAMRequestWrapper{
+				IAMRequest:  &v1alpha1.IAMRequest{ResourcePolicies: []*v1alpha1.ResourcePolicy{testPolicy}},
+				Duration:    time.Hour,
+				StartTime:   now.Add(-30 * time.Minute),
+				RequestHash: testRequestProtoHash,
+			},
+		},
+		{
+			name: "handle_missing_duration",
+			call: func(ctx context.Context, c aodpb.AccessOnDemandClient) (any, error) {
+				return c.HandleIAM(ctx, &aodpb.HandleIAMRequest{Request: testRequestProto})
+			},
+			handler:  &fakeIAMHandler{},
+			wantCode: codes.InvalidArgument,
+			wantErr:  "duration is required",
+		},
+		{
+			name: "handle_negative_duration",
+			call: func(ctx context.Context, c aodpb.AccessOnDemandClient) (any, error) {
+				return c.HandleIAM(ctx, &aodpb.HandleIAMRequest{
+					Request:  testRequestProto,
+					Duration: durationpb.New(-time.Hour),
+				})
+			},
+			handler:  &fakeIAMHandler{},
+			wantCode: codes.InvalidArgument,
+			wantErr:  "a positive duration is required",
+		},
+		{
+			name: "handle_expired",
+			call: func(ctx context.Context, c aodpb.AccessOnDemandClient) (any, error) {
+				return c.HandleIAM(ctx, &aodpb.HandleIAMRequest{
+					Request:   testRequestProto,
+					Duration:  durationpb.New(time.Hour),
+					StartTime: timestamppb.New(now.Add(-2 * time.Hour)),
+				})
+			},
+			handler:  &fakeIAMHandler{},
+			wantCode: codes.InvalidArgument,
+			wantErr:  "already passed",
+		},
+		{
+			name: "handle_invalid_request",
+			call: func(ctx context.Context, c aodpb.AccessOnDemandClient) (any, error) {
+				return c.HandleIAM(ctx, &aodpb.HandleIAMRequest{
+					Request:  &aodpb.IAMRequest{},
+					Duration: durationpb.New(time.Hour),
+				})
+			},
+			handler:  &fakeIAMHandler{},
+			wantCode: codes.InvalidArgument,
+			wantErr:  "failed to validate *v1alpha1.IAMRequest",
+		},
+		{
+			name: "handle_validate_func_failure",
+			call: func(ctx context.Context, c aodpb.AccessOnDemandClient) (any, error) {
+				return c.HandleIAM(ctx, &aodpb.HandleIAMRequest{
+					Request:  testRequestProto,
+					Duration: durationpb.New(time.Hour),
+				})
+			},
+			handler: &fakeIAMHandler{},
+			validate: func(ctx context.Context, w *v1alpha1.IAMRequestWrapper) error {
+				return fmt.Errorf("injected validation error")
+			},
+			wantCode: codes.InvalidArgument,
+			wantErr:  "injected validation error",
+		},
+		{
+			name: "handle_validate_func_duration",
+			call: func(ctx context.Context, c aodpb.AccessOnDemandClient) (any, error) {
+				return c.HandleIAM(ctx, &aodpb.HandleIAMRequest{
+					Request:  testRequestProto,
+					Duration: durationpb.New(time.Hour),
+				})
+			},
+			handler:  &fakeIAMHandler{},
+			validate: maxDurationValidateFunc(30 * time.Minute),
+			wantCode: codes.InvalidArgument,
+			wantErr:  "duration 1h0m0s exceeds 30m0s",
+		},
+		{
+			name: "handle_failure",
+			call: func(ctx context.Context, c aodpb.AccessOnDemandClient) (any, error) {
+				return c.HandleIAM(ctx, &aodpb.HandleIAMRequest{
+					Request:  testRequestProto,
+					Duration: durationpb.New(time.Hour),
+				})
+			},
+			handler:  &fakeIAMHandler{injectErr: fmt.Errorf("injected error")},
+			wantCode: codes.Internal,
+			wantErr:  "failed to handle IAM request: injected error",
+			wantDo: &v1alpha1.IAMRequestWrapper{
+				IAMRequest:  &v1alpha1.IAMRequest{ResourcePolicies: []*v1alpha1.ResourcePolicy{testPolicy}},
+				Duration:    time.Hour,
+				StartTime:   now,
+				RequestHash: testRequestProtoHash,
+			},
+		},
+		{
+			name: "cleanup_success",
+			call: func(ctx context.Context, c aodpb.AccessOnDemandClient) (any, error) {
+				return c.CleanupIAM(ctx, &aodpb.CleanupIAMRequest{Request: testRequestProto})
+			},
+			handler: &fakeIAMHandler{
+				resp: []*v1alpha1.IAMResponse{{Resource: "organizations/foo"}},
+			},
+			wantResp: &aodpb.CleanupIAMResponse{
+				Request:   testRequestProto,
+				Responses: []*aodpb.IAMResponse{{Resource: "organizations/foo"}},
+			},
+			wantClean: &v1alpha1.IAMRequest{ResourcePolicies: []*v1alpha1.ResourcePolicy{testPolicy}},
+		},
+		{
+			name: "cleanup_with_correlation_id",
+			call: func(ctx context.Context, c aodpb.AccessOnDemandClient) (any, error) {
+				ctx = metadata.AppendToOutgoingContext(ctx, handler.MetadataKeyCorrelationID, "request-1")
+				var header metadata.MD
+				resp, err := c.CleanupIAM(ctx, &aodpb.CleanupIAMRequest{Request: testRequestProto}, grpc.Header(&header))
+				if got, want := header.Get(handler.MetadataKeyCorrelationID), []string{"request-1"}; !cmp.Equal(got, want) {
+					return nil, fmt.Errorf("got correlation ID header %q, want %q", got, want)
+				}
+				return resp, err
+			},
+			handler: &fakeIAMHandler{},
+			wantResp: &aodpb.CleanupIAMResponse{
+				Request:   testRequestProto,
+				Responses: []*aodpb.IAMResponse{},
+			},
+			wantClean:         &v1alpha1.IAMRequest{ResourcePolicies: []*v1alpha1.ResourcePolicy{testPolicy}},
+			wantCorrelationID: "request-1",
+		},
+		{
+			name: "cleanup_failure",
+			call: func(ctx context.Context, c aodpb.AccessOnDemandClient) (any, error) {
+				return c.CleanupIAM(ctx, &aodpb.CleanupIAMRequest{Request: testRequestProto})
+			},
+			handler:   &fakeIAMHandler{injectErr: fmt.Errorf("injected error")},
+			wantCode:  codes.Internal,
+			wantErr:   "failed to clean up IAM policy: injected error",
+			wantClean: &v1alpha1.IAMRequest{ResourcePolicies: []*v1alpha1.ResourcePolicy{testPolicy}},
+		},
+		{
+			name: "handle_full_request",
+			call: func(ctx context.Context, c aodpb.AccessOnDemandClient) (any, error) {
+				return c.HandleIAM(ctx, &aodpb.HandleIAMRequest{
+					Request:  fullRequestProto,
+					Duration: durationpb.New(2 * time.Hour),
+				})
+			},
+			handler: &fakeIAMHandler{},
+			wantResp: &aodpb.HandleIAMResponse{
+				Request:   fullRequestProto,
+				Expiry:    timestamppb.New(now.Add(2 * time.Hour)),
+				Responses: []*aodpb.IAMResponse{},
+			},
+			wantDo: &v1alpha1.IAMRequestWrapper{
+				IAMRequest:  fullRequest,
+				Duration:    2 * time.Hour,
+				StartTime:   now,
+				RequestHash: fullRequestProtoHash,
+			},
+		},
+		{
+			name: "cleanup_full_request",
+			call: func(ctx context.Context, c aodpb.AccessOnDemandClient) (any, error) {
+				return c.CleanupIAM(ctx, &aodpb.CleanupIAMRequest{Request: fullRequestProto})
+			},
+			handler: &fakeIAMHandler{},
+			wantResp: &aodpb.CleanupIAMResponse{
+				Request:   fullRequestProto,
+				Responses: []*aodpb.IAMResponse{},
+			},
+			wantClean: fullRequest,
+		},
+		{
+			name: "cleanup_identity_alias",
+			call: func(ctx context.Context, c aodpb.AccessOnDemandClient) (any, error) {
+				return c.CleanupIAM(ctx, &aodpb.CleanupIAMRequest{Request: aliasedRequestProto})
+			},
+			handler: &fakeIAMHandler{},
+			ids:     identity.Map{"github": {"octocat": "user:test-org-user@example.com"}},
+			wantResp: &aodpb.CleanupIAMResponse{
+				Request:   aliasedRequestProto,
+				Responses: []*aodpb.IAMResponse{},
+			},
+			wantClean: &v1alpha1.IAMRequest{ResourcePolicies: []*v1alpha1.ResourcePolicy{testPolicy}},
+		},
+		{
+			name: "handle_unknown_identity_alias",
+			call: func(ctx context.Context, c aodpb.AccessOnDemandClient) (any, error) {
+				return c.HandleIAM(ctx, &aodpb.HandleIAMRequest{
+					Request:  aliasedRequestProto,
+					Duration: durationpb.New(time.Hour),
+				})
+			},
+			handler:  &fakeIAMHandler{},
+			ids:      identity.Map{"github": {"hubot": "user:test-org-user@example.com"}},
+			wantCode: codes.InvalidArgument,
+			wantErr:  `failed to resolve members of *v1alpha1.IAMRequest: policies[0].bindings[0].members[0]: unknown github identity "octocat"`,
+		},
+		{
+			name: "validate_identity_alias",
+			call: func(ctx context.Context, c aodpb.AccessOnDemandClient) (any, error) {
+				return c.Validate(ctx, &aodpb.ValidateRequest{Request: aliasedRequestProto})
+			},
+			handler:  &fakeIAMHandler{},
+			ids:      identity.Map{"github": {"octocat": "user:test-org-user@example.com"}},
+			wantResp: &aodpb.ValidateResponse{Request: aliasedRequestProto},
+		},
+		{
+			name: "validate_success",
+			call: func(ctx context.Context, c aodpb.AccessOnDemandClient) (any, error) {
+				return c.Validate(ctx, &aodpb.ValidateRequest{Request: testRequestProto})
+			},
+			handler:  &fakeIAMHandler{},
+			wantResp: &aodpb.ValidateResponse{Request: testRequestProto},
+		},
+		{
+			name: "validate_invalid_request",
+			call: func(ctx context.Context, c aodpb.AccessOnDemandClient) (any, error) {
+				return c.Validate(ctx, &aodpb.ValidateRequest{})
+			},
+			handler:  &fakeIAMHandler{},
+			wantCode: codes.InvalidArgument,
+			wantErr:  "failed to validate *v1alpha1.IAMRequest",
+		},
+		{
+			name: "list_success",
+			call: func(ctx context.Context, c aodpb.AccessOnDemandClient) (any, error) {
+				return c.ListGrants(ctx, &aodpb.ListGrantsRequest{Resources: []string{"organizations/foo"}})
+			},
+			handler: &fakeIAMHandler{
+				grants: []*v1alpha1.ActiveGrant{{
+					Resource: "organizations/foo",
+					Role:     "roles/cloudkms.cryptoOperator",
+					Member:   "user:test-org-user@example.com",
+					Expiry:   now.Add(time.Hour),
+				}},
+			},
+			wantResp: &aodpb.ListGrantsResponse{
+				Grants: []*aodpb.Grant{{
+					Resource: "organizations/foo",
+					Role:     "roles/cloudkms.cryptoOperator",
+					Member:   "user:test-org-user@example.com",
+					Expiry:   timestamppb.New(now.Add(time.Hour)),
+				}},
+			},
+			wantList: []string{"organizations/foo"},
+		},
+		{
+			name: "list_missing_resources",
+			call: func(ctx context.Context, c aodpb.AccessOnDemandClient) (any, error) {
+				return c.ListGrants(ctx, &aodpb.ListGrantsRequest{})
+			},
+			handler:  &fakeIAMHandler{},
+			wantCode: codes.InvalidArgument,
+			wantErr:  "at least one resource is required",
+		},
+		{
+			name: "list_failure",
+			call: func(ctx context.Context, c aodpb.AccessOnDemandClient) (any, error) {
+				return c.ListGrants(ctx, &aodpb.ListGrantsRequest{Resources: []string{"organizations/foo"}})
+			},
+			handler:  &fakeIAMHandler{injectErr: fmt.Errorf("injected error")},
+			wantCode: codes.Internal,
+			wantErr:  "failed to list grants: injected error",
+			wantList: []string{"organizations/foo"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			opts := []Option{WithNowFunc(func() time.Time { return now })}
+			if tc.validate != nil {
+				opts = append(opts, WithValidateFunc(tc.validate))
+			}
+			if tc.ids != nil {
+				opts = append(opts, WithIdentityMap(tc.ids))
+			}
+			s, err := New(tc.handler, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, conn := testutil.FakeGRPCServer(t, func(gs *grpc.Server) {
+				s.RegisterGRPC(gs)
+			})
+			client := aodpb.NewAccessOnDemandClient(conn)
+
+			resp, err := tc.call(ctx, client)
+			if got, want := status.Code(err), tc.wantCode; got != want {
+				t.Errorf("Process(%+v) got code %s, want %s", tc.name, got, want)
+			}
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got error diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if err == nil {
+				if diff := cmp.Diff(tc.wantResp, resp, protocmp.Transform()); diff != "" {
+					t.Errorf("Process(%+v) got response diff (-want, +got):\n%s", tc.name, diff)
+				}
+			}
+			if diff := cmp.Diff(tc.wantDo, tc.handler.gotDo); diff != "" {
+				t.Errorf("Process(%+v) got handled request diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantClean, tc.handler.gotCleanup); diff != "" {
+				t.Errorf("Process(%+v) got cleaned up request diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if tc.wantCorrelationID != "" && tc.handler.gotCorrelationID != tc.wantCorrelationID {
+				t.Errorf("Process(%+v) got correlation ID %q, want %q", tc.name, tc.handler.gotCorrelationID, tc.wantCorrelationID)
+			}
+			if diff := cmp.Diff(tc.wantList, tc.handler.gotList); diff != "" {
+				t.Errorf("Process(%+v) got listed resources diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
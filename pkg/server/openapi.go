@@ -0,0 +1,42 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	_ "embed"
+	"net/http"
+
+	"github.com/abcxyz/pkg/logging"
+)
+
+// OpenAPI is the OpenAPI document of the server's endpoints, which the
+// TypeScript client in clients/typescript is generated from.
+//
+//go:embed openapi.yaml
+var OpenAPI []byte
+
+// handleOpenAPI serves the OpenAPI document.
+func (s *Server) handleOpenAPI(ctx context.Context) http.Handler {
+	logger := logging.FromContext(ctx)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(OpenAPI); err != nil {
+			logger.ErrorContext(ctx, "failed to write response", "error", err)
+		}
+	})
+}
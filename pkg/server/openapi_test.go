@@ -0,0 +1,77 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v3"
+
+	"github.com/abcxyz/pkg/logging"
+)
+
+func TestHandleOpenAPI(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+	s, err := New(&fakeIAMHandler{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.Routes(ctx).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/openapi.yaml", nil))
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Fatalf("got code %d, want %d", got, want)
+	}
+	if got, want := w.Header().Get("Content-Type"), "application/yaml"; got != want {
+		t.Errorf("got content type %q, want %q", got, want)
+	}
+
+	var doc struct {
+		OpenAPI string                    `yaml:"openapi"`
+		Paths   map[string]map[string]any `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("failed to parse OpenAPI document: %v", err)
+	}
+	if doc.OpenAPI == "" {
+		t.Errorf("got no OpenAPI version")
+	}
+
+	// Every endpoint of the server must be documented.
+	got := make(map[string][]string)
+	for path, ops := range doc.Paths {
+		got[path] = slices.Sorted(maps.Keys(ops))
+	}
+	want := map[string][]string{
+		"/v1/iam:handle":      {"post"},
+		"/v1/iam:cleanup":     {"post"},
+		"/v1/iam:validate":    {"post"},
+		"/v1/operations/{id}": {"get"},
+		"/v1/openapi.yaml":    {"get"},
+		"/v1/webhooks/revoke": {"post"},
+		"/healthz":            {"get"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("got documented endpoints diff (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// Types of the operations.
+const (
+	OperationTypeHandle  = "handle"
+	OperationTypeCleanup = "cleanup"
+)
+
+// Operation is a detached IAM request handled in the background.
+type Operation struct {
+	// ID identifies the operation.
+	ID string `yaml:"id" json:"id"`
+
+	// Type is the type of the operation, "handle" or "cleanup".
+	Type string `yaml:"type" json:"type"`
+
+	// Done is whether the operation is done, successfully or not.
+	Done bool `yaml:"done" json:"done"`
+
+	// Error is the error of the operation if it failed.
+	Error string `yaml:"error,omitempty" json:"error,omitempty"`
+
+	// Warnings are the warnings of the operation, see [warnings].
+	Warnings []string `yaml:"warnings,omitempty" json:"warnings,omitempty"`
+
+	// CreateTime is when the operation was created.
+	CreateTime time.Time `yaml:"createTime" json:"createTime"`
+
+	// UpdateTime is when the operation was last updated.
+	UpdateTime time.Time `yaml:"updateTime" json:"updateTime"`
+}
+
+// OperationStore stores the operations.
+type OperationStore interface {
+	// Put creates or updates the operation.
+	Put(ctx context.Context, op *Operation) error
+
+	// Get returns the operation with the ID, or nil if it is not found.
+	Get(ctx context.Context, id string) (*Operation, error)
+}
+
+// MemoryOperationStore is an OperationStore in memory. Operations are lost
+// when the server restarts, and are only visible to the server instance which
+// created them.
+type MemoryOperationStore struct {
+	mu  sync.Mutex
+	ops map[string]*Operation
+}
+
+// NewMemoryOperationStore creates a new MemoryOperationStore.
+func NewMemoryOperationStore() *MemoryOperationStore {
+	return &MemoryOperationStore{ops: make(map[string]*Operation)}
+}
+
+// Put creates or updates a copy of the operation.
+func (s *MemoryOperationStore) Put(ctx context.Context, op *Operation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp := *op
+	s.ops[op.ID] = &cp
+	return nil
+}
+
+// Get returns a copy of the operation with the ID, or nil if it is not found.
+func (s *MemoryOperationStore) Get(ctx context.Context, id string) (*Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	op, ok := s.ops[id]
+	if !ok {
+		return nil, nil
+	}
+	cp := *op
+	return &cp, nil
+}
+
+// newOperationID returns a random operation ID.
+func newOperationID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate operation ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// startOperation creates an operation of the type and runs fn in the
+// background, the operation is updated with the result of fn when it is done.
+// fn runs with a context which is not canceled when the HTTP request is done.
+func (s *Server) startOperation(ctx context.Context, typ string, fn func(context.Context) ([]*v1alpha1.IAMResponse, error)) (*Operation, error) {
+	logger := logging.FromContext(ctx)
+
+	id, err := newOperationID()
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	op := &Operation{
+		ID:         id,
+		Type:       typ,
+		CreateTime: now,
+		UpdateTime: now,
+	}
+	if err := s.operations.Put(ctx, op); err != nil {
+		return nil, fmt.Errorf("failed to create operation: %w", err)
+	}
+
+	result := *op
+	s.inflight.Add(1)
+	go func() {
+		defer s.inflight.Done()
+
+		bgCtx := context.WithoutCancel(ctx)
+		resp, err := fn(bgCtx)
+		result.Done = true
+		result.Warnings = warnings(resp)
+		if err != nil {
+			logger.ErrorContext(bgCtx, "operation failed", "operation", result.ID, "error", err)
+			result.Error = err.Error()
+		}
+		result.UpdateTime = s.now()
+		if err := s.operations.Put(bgCtx, &result); err != nil {
+			logger.ErrorContext(bgCtx, "failed to update operation", "operation", result.ID, "error", err)
+		}
+	}()
+	return op, nil
+}
+
+// Wait waits for the detached operations in progress to be done, it should be
+// called before the server exits.
+func (s *Server) Wait() {
+	s.inflight.Wait()
+}
+
+// handleGetOperation returns the operation with the ID in the path.
+func (s *Server) handleGetOperation(ctx context.Context) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		op, err := s.operations.Get(r.Context(), id)
+		if err != nil {
+			writeError(ctx, w, http.StatusInternalServerError, fmt.Errorf("failed to get operation: %w", err))
+			return
+		}
+		if op == nil {
+			writeError(ctx, w, http.StatusNotFound, fmt.Errorf("operation %q not found", id))
+			return
+		}
+		writeJSON(ctx, w, http.StatusOK, map[string]any{"operation": op})
+	})
+}
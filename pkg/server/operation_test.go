@@ -0,0 +1,180 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestDetachedOperations(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	req := &v1alpha1.IAMRequest{ResourcePolicies: []*v1alpha1.ResourcePolicy{testPolicy}}
+
+	cases := []struct {
+		name      string
+		cleanup   bool
+		req       *v1alpha1.IAMRequest
+		handler   *fakeIAMHandler
+		wantOp    *Operation
+		wantErr   string
+		wantDo    *v1alpha1.IAMRequestWrapper
+		wantClean *v1alpha1.IAMRequest
+	}{
+		{
+			name:    "handle",
+			req:     req,
+			handler: &fakeIAMHandler{resp: []*v1alpha1.IAMResponse{{Resource: "organizations/foo", Warnings: []string{"malformed expiry"}}}},
+			wantOp: &Operation{
+				Type:       OperationTypeHandle,
+				Done:       true,
+				Warnings:   []string{"organizations/foo: malformed expiry"},
+				CreateTime: now,
+				UpdateTime: now,
+			},
+			wantDo: &v1alpha1.IAMRequestWrapper{
+				IAMRequest: req,
+				Duration:   2 * time.Hour,
+				StartTime:  now,
+				Requester:  "user:alice@example.com",
+				// The hash of the JSON body.
+				RequestHash: "32f624583b6a6af4743258b06c45e5896a1177114e669e4b6309027b1c65614b",
+			},
+		},
+		{
+			name:    "handle_failure",
+			req:     req,
+			handler: &fakeIAMHandler{injectErr: fmt.Errorf("injected error")},
+			wantOp: &Operation{
+				Type:       OperationTypeHandle,
+				Done:       true,
+				Error:      "injected error",
+				CreateTime: now,
+				UpdateTime: now,
+			},
+		},
+		{
+			name:    "cleanup",
+			cleanup: true,
+			req:     req,
+			handler: &fakeIAMHandler{},
+			wantOp: &Operation{
+				Type:       OperationTypeCleanup,
+				Done:       true,
+				CreateTime: now,
+				UpdateTime: now,
+			},
+			wantClean: req,
+		},
+		{
+			name:    "invalid_request",
+			req:     &v1alpha1.IAMRequest{},
+			handler: &fakeIAMHandler{},
+			wantErr: "failed with status 400: failed to validate *v1alpha1.IAMRequest",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			s, err := New(tc.handler, WithNowFunc(func() time.Time { return now }))
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := httptest.NewServer(s.Routes(ctx))
+			t.Cleanup(srv.Close)
+
+			c, err := NewClient(srv.Client(), srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var op *Operation
+			if tc.cleanup {
+				op, err = c.DetachCleanup(ctx, tc.req)
+			} else {
+				op, err = c.DetachHandle(ctx, &v1alpha1.IAMRequestWrapper{
+					IAMRequest: tc.req,
+					Duration:   2 * time.Hour,
+					StartTime:  now,
+					Requester:  "user:alice@example.com",
+				})
+			}
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatalf("Process(%+v) got unexpected err: %s", tc.name, diff)
+			}
+			if err != nil {
+				return
+			}
+			if op.ID == "" || op.Done {
+				t.Errorf("Process(%+v) got detached operation %+v, want an ID and not done", tc.name, op)
+			}
+
+			s.Wait()
+			got, err := c.GetOperation(ctx, op.ID)
+			if err != nil {
+				t.Fatalf("Process(%+v) failed to get operation: %v", tc.name, err)
+			}
+			tc.wantOp.ID = op.ID
+			if diff := cmp.Diff(tc.wantOp, got); diff != "" {
+				t.Errorf("Process(%+v) got operation diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if tc.wantDo != nil {
+				if diff := cmp.Diff(tc.wantDo, tc.handler.gotDo); diff != "" {
+					t.Errorf("Process(%+v) got request diff (-want, +got):\n%s", tc.name, diff)
+				}
+			}
+			if diff := cmp.Diff(tc.wantClean, tc.handler.gotCleanup); diff != "" {
+				t.Errorf("Process(%+v) got cleanup request diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestGetOperation_NotFound(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	s, err := New(&fakeIAMHandler{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(s.Routes(ctx))
+	t.Cleanup(srv.Close)
+
+	c, err := NewClient(srv.Client(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.GetOperation(ctx, "missing")
+	if diff := testutil.DiffErrString(err, `failed with status 404: operation "missing" not found`); diff != "" {
+		t.Errorf("got unexpected err: %s", diff)
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+	"github.com/abcxyz/access-on-demand/pkg/handler"
+	"github.com/abcxyz/access-on-demand/pkg/registry"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// GrantRegistry queries the grants recorded in the grant registry.
+type GrantRegistry interface {
+	Query(ctx context.Context, q *registry.Query) ([]*registry.Record, error)
+}
+
+// RevokeWebhookRequest is the body of a call to the revoke webhook, which
+// revokes the grants of either a member or a grant ID before they expire.
+type RevokeWebhookRequest struct {
+	// Member to remove from all AOD bindings of the resources, e.g.
+	// "user:alice@example.com". Member aliases such as "github:octocat" are
+	// resolved with the identity map of the server.
+	Member string `json:"member,omitempty"`
+
+	// Resources to remove the member from. Defaults to the resources of the
+	// unexpired grants of the member in the grant registry.
+	Resources []string `json:"resources,omitempty"`
+
+	// GrantID identifies the grants of a handled IAM request, which is the
+	// "grantId" in the response of POST /v1/iam:handle. The unexpired grants
+	// recorded with it in the grant registry are cleaned up.
+	GrantID string `json:"grantId,omitempty"`
+
+	// Reason of the revocation, e.g. "offboarding", which is logged with the
+	// correlation ID of the audit events.
+	Reason string `json:"reason,omitempty"`
+}
+
+// handleRevokeWebhook revokes the grants of the member or the grant ID in the
+// request body. Callers must authenticate with the bearer token of the
+// webhook.
+func (s *Server) handleRevokeWebhook(ctx context.Context) http.Handler {
+	logger := logging.FromContext(ctx)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorizeWebhook(r) {
+			logger.WarnContext(ctx, "rejected unauthenticated revoke webhook call",
+				"remote_addr", r.RemoteAddr)
+			writeError(ctx, w, http.StatusUnauthorized, fmt.Errorf("missing or invalid bearer token"))
+			return
+		}
+
+		var req RevokeWebhookRequest
+		dec := json.NewDecoder(io.LimitReader(r.Body, maxBodySize))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			writeError(ctx, w, http.StatusBadRequest, fmt.Errorf("failed to decode %T: %w", &req, err))
+			return
+		}
+
+		reqCtx := requestContext(w, r)
+		logger.InfoContext(ctx, "revoke webhook called",
+			"member", req.Member,
+			"grant_id", req.GrantID,
+			"reason", req.Reason,
+			"correlation_id", handler.RequestMetadataFromContext(reqCtx).CorrelationID)
+
+		switch {
+		case req.Member != "" && req.GrantID != "":
+			writeError(ctx, w, http.StatusBadRequest, fmt.Errorf("only one of member and grantId can be set"))
+		case req.Member != "":
+			s.revokeMember(ctx, w, reqCtx, &req)
+		case req.GrantID != "":
+			s.revokeGrant(ctx, w, reqCtx, &req)
+		default:
+			writeError(ctx, w, http.StatusBadRequest, fmt.Errorf("one of member and grantId is required"))
+		}
+	})
+}
+
+// authorizeWebhook returns whether the request has the bearer token of the
+// webhook.
+func (s *Server) authorizeWebhook(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.webhookToken)) == 1
+}
+
+// revokeMember removes the member of the webhook request from all AOD bindings
+// of its resources, or of the resources of its unexpired grants in the grant
+// registry.
+func (s *Server) revokeMember(ctx context.Context, w http.ResponseWriter, reqCtx context.Context, req *RevokeWebhookRequest) {
+	logger := logging.FromContext(ctx)
+
+	member := req.Member
+	if resolved, ok, err := s.identities.Lookup(member); err != nil {
+		writeError(ctx, w, http.StatusBadRequest, fmt.Errorf("failed to resolve member: %w", err))
+		return
+	} else if ok {
+		member = resolved
+	}
+
+	resources := slices.Clone(req.Resources)
+	if len(resources) == 0 {
+		if s.grants == nil {
+			writeError(ctx, w, http.StatusBadRequest, fmt.Errorf("resources are required without a grant registry"))
+			return
+		}
+		records, err := s.grants.Query(reqCtx, &registry.Query{Member: member})
+		if err != nil {
+			writeError(ctx, w, http.StatusInternalServerError, fmt.Errorf("failed to query grant registry: %w", err))
+			return
+		}
+		for _, rec := range s.activeGrants(records) {
+			resources = append(resources, rec.Resource)
+		}
+		if len(resources) == 0 {
+			writeError(ctx, w, http.StatusNotFound, fmt.Errorf("no unexpired grants of member %q found", member))
+			return
+		}
+	}
+	slices.Sort(resources)
+	resources = slices.Compact(resources)
+
+	// Validate the member and resources as an IAMRequest.
+	iamReq := &v1alpha1.IAMRequest{}
+	for _, r := range resources {
+		iamReq.ResourcePolicies = append(iamReq.ResourcePolicies, &v1alpha1.ResourcePolicy{
+			Resource: r,
+			Bindings: []*v1alpha1.Binding{{Members: []string{member}}},
+		})
+	}
+	if err := v1alpha1.ValidateIAMRequest(iamReq, nil); err != nil {
+		writeError(ctx, w, http.StatusBadRequest, fmt.Errorf("failed to validate %T: %w", iamReq, err))
+		return
+	}
+
+	resp, err := s.handler.RevokeMember(reqCtx, member, resources)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to revoke member", "error", err)
+		writeJSON(ctx, w, http.StatusInternalServerError, map[string]any{
+			"error":    fmt.Sprintf("failed to revoke member %q: %s", member, err),
+			"warnings": warnings(resp),
+		})
+		return
+	}
+	writeJSON(ctx, w, http.StatusOK, map[string]any{
+		"member":    member,
+		"resources": resources,
+		"warnings":  warnings(resp),
+	})
+}
+
+// revokeGrant cleans up the unexpired grants recorded with the grant ID of the
+// webhook request in the grant registry.
+func (s *Server) revokeGrant(ctx context.Context, w http.ResponseWriter, reqCtx context.Context, req *RevokeWebhookRequest) {
+	logger := logging.FromContext(ctx)
+
+	if s.grants == nil {
+		writeError(ctx, w, http.StatusBadRequest, fmt.Errorf("grantId is not supported without a grant registry"))
+		return
+	}
+	records, err := s.grants.Query(reqCtx, &registry.Query{RequestHash: req.GrantID})
+	if err != nil {
+		writeError(ctx, w, http.StatusInternalServerError, fmt.Errorf("failed to query grant registry: %w", err))
+		return
+	}
+
+	// Group the bindings by resource, the records of the same role and members
+	// are only cleaned up once.
+	policies := make(map[string]*v1alpha1.ResourcePolicy)
+	seen := make(map[string]struct{})
+	for _, rec := range s.activeGrants(records) {
+		key := fmt.Sprintf("%s %s %q", rec.Resource, rec.Role, rec.Members)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		p, ok := policies[rec.Resource]
+		if !ok {
+			p = &v1alpha1.ResourcePolicy{Resource: rec.Resource}
+			policies[rec.Resource] = p
+		}
+		p.Bindings = append(p.Bindings, &v1alpha1.Binding{Members: rec.Members, Role: rec.Role})
+	}
+	if len(policies) == 0 {
+		writeError(ctx, w, http.StatusNotFound, fmt.Errorf("no unexpired grants of grant ID %q found", req.GrantID))
+		return
+	}
+	iamReq := &v1alpha1.IAMRequest{}
+	for _, r := range slices.Sorted(maps.Keys(policies)) {
+		iamReq.ResourcePolicies = append(iamReq.ResourcePolicies, policies[r])
+	}
+
+	resp, err := s.handler.Cleanup(reqCtx, iamReq)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to revoke grant", "error", err)
+		writeJSON(ctx, w, http.StatusInternalServerError, map[string]any{
+			"error":    fmt.Sprintf("failed to revoke grant %q: %s", req.GrantID, err),
+			"warnings": warnings(resp),
+		})
+		return
+	}
+	writeJSON(ctx, w, http.StatusOK, map[string]any{
+		"grantId":  req.GrantID,
+		"request":  iamReq,
+		"warnings": warnings(resp),
+	})
+}
+
+// activeGrants returns the grant and renewal records which have not expired.
+func (s *Server) activeGrants(records []*registry.Record) []*registry.Record {
+	now := s.now()
+	var result []*registry.Record
+	for _, r := range records {
+		if r.Type != audit.EventTypeGrant && r.Type != audit.EventTypeRenew {
+			continue
+		}
+		if r.Expiry == nil || !r.Expiry.After(now) {
+			continue
+		}
+		result = append(result, r)
+	}
+	return result
+}
@@ -0,0 +1,357 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/identity"
+	"github.com/abcxyz/access-on-demand/pkg/registry"
+	"github.com/abcxyz/pkg/logging"
+)
+
+func TestRevokeWebhook(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	expiry := now.Add(time.Hour)
+	expired := now.Add(-time.Hour)
+
+	records := []*registry.Record{
+		{
+			Type:     "GRANT",
+			Resource: "projects/foo",
+			Role:     "roles/viewer",
+			Members:  []string{"user:alice@example.com"},
+			Expiry:   &expiry,
+		},
+		{
+			Type:     "RENEW",
+			Resource: "folders/123",
+			Role:     "roles/editor",
+			Members:  []string{"user:alice@example.com", "user:bob@example.com"},
+			Expiry:   &expiry,
+		},
+		{
+			Type:     "GRANT",
+			Resource: "projects/foo",
+			Role:     "roles/viewer",
+			Members:  []string{"user:alice@example.com"},
+			Expiry:   &expiry,
+		},
+		{
+			Type:     "GRANT",
+			Resource: "projects/expired",
+			Role:     "roles/viewer",
+			Members:  []string{"user:alice@example.com"},
+			Expiry:   &expired,
+		},
+		{
+			Type:     "CLEANUP",
+			Resource: "projects/cleaned",
+			Role:     "roles/viewer",
+			Members:  []string{"user:alice@example.com"},
+		},
+	}
+
+	cases := []struct {
+		name       string
+		body       string
+		token      string
+		handler    *fakeIAMHandler
+		grants     *fakeGrantRegistry
+		ids        identity.Map
+		wantCode   int
+		wantBody   string
+		wantMember string
+		wantRevoke []string
+		wantClean  *v1alpha1.IAMRequest
+		wantQuery  *registry.Query
+	}{
+		{
+			name:       "member_with_resources",
+			body:       `{"member": "user:alice@example.com", "resources": ["projects/foo", "folders/123", "projects/foo"], "reason": "offboarding"}`,
+			token:      "test-token",
+			handler:    &fakeIAMHandler{resp: []*v1alpha1.IAMResponse{{Resource: "projects/foo", Warnings: []string{"malformed expiry"}}}},
+			wantCode:   http.StatusOK,
+			wantBody:   `{"member":"user:alice@example.com","resources":["folders/123","projects/foo"],"warnings":["projects/foo: malformed expiry"]}`,
+			wantMember: "user:alice@example.com",
+			wantRevoke: []string{"folders/123", "projects/foo"},
+		},
+		{
+			name:       "member_from_registry",
+			body:       `{"member": "user:alice@example.com"}`,
+			token:      "test-token",
+			handler:    &fakeIAMHandler{},
+			grants:     &fakeGrantRegistry{records: records},
+			wantCode:   http.StatusOK,
+			wantBody:   `{"member":"user:alice@example.com","resources":["folders/123","projects/foo"],"warnings":[]}`,
+			wantMember: "user:alice@example.com",
+			wantRevoke: []string{"folders/123", "projects/foo"},
+			wantQuery:  &registry.Query{Member: "user:alice@example.com"},
+		},
+		{
+			name:       "member_alias",
+			body:       `{"member": "github:octocat", "resources": ["projects/foo"]}`,
+			token:      "test-token",
+			handler:    &fakeIAMHandler{},
+			ids:        identity.Map{"github": {"octocat": "user:alice@example.com"}},
+			wantCode:   http.StatusOK,
+			wantBody:   `{"member":"user:alice@example.com","resources":["projects/foo"],"warnings":[]}`,
+			wantMember: "user:alice@example.com",
+			wantRevoke: []string{"projects/foo"},
+		},
+		{
+			name:     "member_unknown_alias",
+			body:     `{"member": "github:hubot", "resources": ["projects/foo"]}`,
+			token:    "test-token",
+			handler:  &fakeIAMHandler{},
+			ids:      identity.Map{"github": {"octocat": "user:alice@example.com"}},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"failed to resolve member: unknown github identity \"hubot\""}`,
+		},
+		{
+			name:     "member_invalid_resource",
+			body:     `{"member": "user:alice@example.com", "resources": ["topics/foo"]}`,
+			token:    "test-token",
+			handler:  &fakeIAMHandler{},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"failed to validate *v1alpha1.IAMRequest: policies[0].resource: resource \"topics/foo\" isn't one of [organizations, folders, projects, buckets, secrets, serviceAccounts, bigquery/datasets, kms/keyrings, //\u003cservice\u003e]"}`,
+		},
+		{
+			name:     "member_without_resources_or_registry",
+			body:     `{"member": "user:alice@example.com"}`,
+			token:    "test-token",
+			handler:  &fakeIAMHandler{},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"resources are required without a grant registry"}`,
+		},
+		{
+			name:      "member_without_grants",
+			body:      `{"member": "user:carol@example.com"}`,
+			token:     "test-token",
+			handler:   &fakeIAMHandler{},
+			grants:    &fakeGrantRegistry{},
+			wantCode:  http.StatusNotFound,
+			wantBody:  `{"error":"no unexpired grants of member \"user:carol@example.com\" found"}`,
+			wantQuery: &registry.Query{Member: "user:carol@example.com"},
+		},
+		{
+			name:       "member_failure",
+			body:       `{"member": "user:alice@example.com", "resources": ["projects/foo"]}`,
+			token:      "test-token",
+			handler:    &fakeIAMHandler{injectErr: fmt.Errorf("injected error")},
+			wantCode:   http.StatusInternalServerError,
+			wantBody:   `{"error":"failed to revoke member \"user:alice@example.com\": injected error","warnings":[]}`,
+			wantMember: "user:alice@example.com",
+			wantRevoke: []string{"projects/foo"},
+		},
+		{
+			name:     "grant_id",
+			body:     `{"grantId": "abc123", "reason": "incident closed"}`,
+			token:    "test-token",
+			handler:  &fakeIAMHandler{},
+			grants:   &fakeGrantRegistry{records: records},
+			wantCode: http.StatusOK,
+			wantBody: `{"grantId":"abc123","request":{"policies":[` +
+				`{"bindings":[{"members":["user:alice@example.com","user:bob@example.com"],"role":"roles/editor"}],"resource":"folders/123"},` +
+				`{"bindings":[{"members":["user:alice@example.com"],"role":"roles/viewer"}],"resource":"projects/foo"}]},"warnings":[]}`,
+			wantClean: &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{
+					{
+						Resource: "folders/123",
+						Bindings: []*v1alpha1.Binding{{Members: []string{"user:alice@example.com", "user:bob@example.com"}, Role: "roles/editor"}},
+					},
+					{
+						Resource: "projects/foo",
+						Bindings: []*v1alpha1.Binding{{Members: []string{"user:alice@example.com"}, Role: "roles/viewer"}},
+					},
+				},
+			},
+			wantQuery: &registry.Query{RequestHash: "abc123"},
+		},
+		{
+			name:     "grant_id_without_registry",
+			body:     `{"grantId": "abc123"}`,
+			token:    "test-token",
+			handler:  &fakeIAMHandler{},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"grantId is not supported without a grant registry"}`,
+		},
+		{
+			name:      "grant_id_not_found",
+			body:      `{"grantId": "abc123"}`,
+			token:     "test-token",
+			handler:   &fakeIAMHandler{},
+			grants:    &fakeGrantRegistry{},
+			wantCode:  http.StatusNotFound,
+			wantBody:  `{"error":"no unexpired grants of grant ID \"abc123\" found"}`,
+			wantQuery: &registry.Query{RequestHash: "abc123"},
+		},
+		{
+			name:      "grant_id_registry_failure",
+			body:      `{"grantId": "abc123"}`,
+			token:     "test-token",
+			handler:   &fakeIAMHandler{},
+			grants:    &fakeGrantRegistry{injectErr: fmt.Errorf("injected error")},
+			wantCode:  http.StatusInternalServerError,
+			wantBody:  `{"error":"failed to query grant registry: injected error"}`,
+			wantQuery: &registry.Query{RequestHash: "abc123"},
+		},
+		{
+			name:     "member_and_grant_id",
+			body:     `{"member": "user:alice@example.com", "grantId": "abc123"}`,
+			token:    "test-token",
+			handler:  &fakeIAMHandler{},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"only one of member and grantId can be set"}`,
+		},
+		{
+			name:     "missing_member_and_grant_id",
+			body:     `{"reason": "offboarding"}`,
+			token:    "test-token",
+			handler:  &fakeIAMHandler{},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"one of member and grantId is required"}`,
+		},
+		{
+			name:     "unknown_field",
+			body:     `{"user": "user:alice@example.com"}`,
+			token:    "test-token",
+			handler:  &fakeIAMHandler{},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"failed to decode *server.RevokeWebhookRequest: json: unknown field \"user\""}`,
+		},
+		{
+			name:     "invalid_token",
+			body:     `{"member": "user:alice@example.com", "resources": ["projects/foo"]}`,
+			token:    "wrong-token",
+			handler:  &fakeIAMHandler{},
+			wantCode: http.StatusUnauthorized,
+			wantBody: `{"error":"missing or invalid bearer token"}`,
+		},
+		{
+			name:     "missing_token",
+			body:     `{"member": "user:alice@example.com", "resources": ["projects/foo"]}`,
+			handler:  &fakeIAMHandler{},
+			wantCode: http.StatusUnauthorized,
+			wantBody: `{"error":"missing or invalid bearer token"}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			opts := []Option{
+				WithNowFunc(func() time.Time { return now }),
+				WithRevokeWebhook("test-token"),
+			}
+			if tc.grants != nil {
+				opts = append(opts, WithGrantRegistry(tc.grants))
+			}
+			if tc.ids != nil {
+				opts = append(opts, WithIdentityMap(tc.ids))
+			}
+			s, err := New(tc.handler, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/webhooks/revoke", strings.NewReader(tc.body))
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			w := httptest.NewRecorder()
+			s.Routes(ctx).ServeHTTP(w, req)
+
+			if got, want := w.Code, tc.wantCode; got != want {
+				t.Errorf("Process(%+v) got code %d, want %d", tc.name, got, want)
+			}
+			if diff := cmp.Diff(tc.wantBody, strings.TrimSpace(w.Body.String())); diff != "" {
+				t.Errorf("Process(%+v) got body diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if got, want := tc.handler.gotMember, tc.wantMember; got != want {
+				t.Errorf("Process(%+v) got revoked member %q, want %q", tc.name, got, want)
+			}
+			if diff := cmp.Diff(tc.wantRevoke, tc.handler.gotRevoke); diff != "" {
+				t.Errorf("Process(%+v) got revoked resources diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantClean, tc.handler.gotCleanup); diff != "" {
+				t.Errorf("Process(%+v) got cleaned up request diff (-want, +got):\n%s", tc.name, diff)
+			}
+			var gotQuery *registry.Query
+			if tc.grants != nil {
+				gotQuery = tc.grants.gotQuery
+			}
+			if diff := cmp.Diff(tc.wantQuery, gotQuery); diff != "" {
+				t.Errorf("Process(%+v) got registry query diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestRevokeWebhook_Disabled(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+	s, err := New(&fakeIAMHandler{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/webhooks/revoke", strings.NewReader(`{"member": "user:alice@example.com"}`))
+	w := httptest.NewRecorder()
+	s.Routes(ctx).ServeHTTP(w, req)
+	if got, want := w.Code, http.StatusNotFound; got != want {
+		t.Errorf("got code %d, want %d", got, want)
+	}
+
+	if _, err := New(&fakeIAMHandler{}, WithRevokeWebhook("")); err == nil {
+		t.Errorf("New with empty revoke webhook token got no error")
+	}
+}
+
+type fakeGrantRegistry struct {
+	records   []*registry.Record
+	injectErr error
+	gotQuery  *registry.Query
+}
+
+func (r *fakeGrantRegistry) Query(ctx context.Context, q *registry.Query) ([]*registry.Record, error) {
+	r.gotQuery = q
+	if r.injectErr != nil {
+		return nil, r.injectErr
+	}
+	var result []*registry.Record
+	for _, rec := range r.records {
+		if q.RequestHash != "" || q.Member == "" || slices.Contains(rec.Members, q.Member) {
+			result = append(result, rec)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,440 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package server serves AOD IAM requests over HTTP, so that AOD can run as a
+// service such as on Cloud Run.
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/handler"
+	"github.com/abcxyz/access-on-demand/pkg/identity"
+	"github.com/abcxyz/access-on-demand/pkg/requestutil"
+	"github.com/abcxyz/pkg/healthcheck"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// maxBodySize is the max size of a request body. Bodies that are smaller but
+// still exceed the size limit of a request file are rejected when they are
+// read as a request bundle.
+const maxBodySize = 1 << 20
+
+// headerCorrelationID is the HTTP header of the correlation ID of a request.
+const headerCorrelationID = "X-Correlation-Id"
+
+// IAMHandler handles IAM requests.
+type IAMHandler interface {
+	Do(context.Context, *v1alpha1.IAMRequestWrapper) ([]*v1alpha1.IAMResponse, error)
+	Cleanup(context.Context, *v1alpha1.IAMRequest) ([]*v1alpha1.IAMResponse, error)
+	List(context.Context, []string) ([]*v1alpha1.ActiveGrant, error)
+	RevokeMember(context.Context, string, []string) ([]*v1alpha1.IAMResponse, error)
+}
+
+// ValidateFunc validates an IAM request in addition to its fields, e.g. checks
+// its members exist. The wrapper has the duration, start time and provenance of
+// the request when it is handled, and only the request otherwise.
+type ValidateFunc func(context.Context, *v1alpha1.IAMRequestWrapper) error
+
+// Server is the HTTP server of AOD.
+type Server struct {
+	handler    IAMHandler
+	validates  []ValidateFunc
+	identities identity.Map
+	now        func() time.Time
+	operations OperationStore
+	inflight   sync.WaitGroup
+
+	webhookToken string
+	grants       GrantRegistry
+}
+
+// Option is the option to set up a Server.
+type Option func(s *Server) (*Server, error)
+
+// WithValidateFunc adds a ValidateFunc run on every IAM request after its
+// fields are validated.
+func WithValidateFunc(f ValidateFunc) Option {
+	return func(s *Server) (*Server, error) {
+		s.validates = append(s.validates, f)
+		return s, nil
+	}
+}
+
+// WithIdentityMap sets the identity map to resolve the member aliases in IAM
+// requests with before they are validated, e.g. "github:octocat".
+func WithIdentityMap(m identity.Map) Option {
+	return func(s *Server) (*Server, error) {
+		s.identities = m
+		return s, nil
+	}
+}
+
+// WithNowFunc sets the func to get the current time.
+func WithNowFunc(now func() time.Time) Option {
+	return func(s *Server) (*Server, error) {
+		s.now = now
+		return s, nil
+	}
+}
+
+// WithOperationStore sets the store of the detached operations, default is a
+// MemoryOperationStore.
+func WithOperationStore(store OperationStore) Option {
+	return func(s *Server) (*Server, error) {
+		s.operations = store
+		return s, nil
+	}
+}
+
+// WithRevokeWebhook enables the revoke webhook, which requires callers to
+// authenticate with the token as a bearer token.
+func WithRevokeWebhook(token string) Option {
+	return func(s *Server) (*Server, error) {
+		if token == "" {
+			return nil, fmt.Errorf("revoke webhook token is required")
+		}
+		s.webhookToken = token
+		return s, nil
+	}
+}
+
+// WithGrantRegistry sets the grant registry the revoke webhook looks up the
+// grants of members and grant IDs in.
+func WithGrantRegistry(r GrantRegistry) Option {
+	return func(s *Server) (*Server, error) {
+		s.grants = r
+		return s, nil
+	}
+}
+
+// New creates a new Server with the IAM handler.
+func New(h IAMHandler, opts ...Option) (*Server, error) {
+	s := &Server{
+		handler:    h,
+		now:        time.Now,
+		operations: NewMemoryOperationStore(),
+	}
+	for _, opt := range opts {
+		var err error
+		s, err = opt(s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply server options: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// Routes returns the HTTP handler of the server's endpoints:
+//
+//   - POST /v1/iam:handle adds the IAM bindings in the request body.
+//   - POST /v1/iam:cleanup removes the IAM bindings in the request body.
+//   - POST /v1/iam:validate validates the IAM request in the request body.
+//   - GET /v1/operations/{id} returns the detached operation with the ID.
+//   - GET /v1/openapi.yaml returns the OpenAPI document of the endpoints.
+//   - GET /healthz reports the server is healthy.
+//   - POST /v1/webhooks/revoke revokes the grants of a member or a grant ID
+//     before they expire, if the revoke webhook is enabled, see
+//     [WithRevokeWebhook].
+//
+// The request body is an IAM request, or a bundle of IAM requests, in YAML or
+// JSON format, the same as the request files of the CLI. With the query
+// parameter "detach=true", handle and cleanup return a detached operation
+// immediately and update the IAM policies in the background.
+func (s *Server) Routes(ctx context.Context) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /healthz", healthcheck.HandleHTTPHealthCheck())
+	mux.Handle("POST /v1/iam:handle", s.handleIAM(ctx))
+	mux.Handle("POST /v1/iam:cleanup", s.handleCleanup(ctx))
+	mux.Handle("POST /v1/iam:validate", s.handleValidate(ctx))
+	mux.Handle("GET /v1/operations/{id}", s.handleGetOperation(ctx))
+	mux.Handle("GET /v1/openapi.yaml", s.handleOpenAPI(ctx))
+	if s.webhookToken != "" {
+		mux.Handle("POST /v1/webhooks/revoke", s.handleRevokeWebhook(ctx))
+	}
+	return mux
+}
+
+// handleIAM adds the requested IAM bindings. The query parameters are:
+//
+//   - duration (required): the duration of the IAM bindings, e.g. "2h".
+//   - start-time: the start time in RFC3339 format, default is now.
+//   - requester, approver (repeatable) and source: the provenance of the
+//     request.
+func (s *Server) handleIAM(ctx context.Context) http.Handler {
+	logger := logging.FromContext(ctx)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		duration, err := time.ParseDuration(q.Get("duration"))
+		if err != nil {
+			writeError(ctx, w, http.StatusBadRequest, fmt.Errorf("invalid duration: %w", err))
+			return
+		}
+
+		startTime := s.now()
+		if v := q.Get("start-time"); v != "" {
+			if startTime, err = time.Parse(time.RFC3339, v); err != nil {
+				writeError(ctx, w, http.StatusBadRequest, fmt.Errorf("invalid start-time: %w", err))
+				return
+			}
+		}
+		if err := s.checkExpiry(startTime, duration); err != nil {
+			writeError(ctx, w, http.StatusBadRequest, err)
+			return
+		}
+
+		reqWrapper := &v1alpha1.IAMRequestWrapper{
+			Duration:  duration,
+			StartTime: startTime,
+			Requester: q.Get("requester"),
+			Approvers: q["approver"],
+			Source:    q.Get("source"),
+		}
+		body, err := s.readRequest(r, reqWrapper)
+		if err != nil {
+			writeError(ctx, w, http.StatusBadRequest, err)
+			return
+		}
+		sum := sha256.Sum256(body)
+		reqWrapper.RequestHash = hex.EncodeToString(sum[:])
+
+		if isDetached(r) {
+			s.writeOperation(ctx, w, requestContext(w, r), OperationTypeHandle, func(ctx context.Context) ([]*v1alpha1.IAMResponse, error) {
+				return s.handler.Do(ctx, reqWrapper)
+			})
+			return
+		}
+
+		resp, err := s.handler.Do(requestContext(w, r), reqWrapper)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to handle IAM request", "error", err)
+			// Grants refused in maintenance mode are not server errors.
+			code := http.StatusInternalServerError
+			var mErr *handler.MaintenanceError
+			if errors.As(err, &mErr) {
+				code = http.StatusServiceUnavailable
+			}
+			writeJSON(ctx, w, code, map[string]any{
+				"error":    fmt.Sprintf("failed to handle IAM request: %s", err),
+				"warnings": warnings(resp),
+			})
+			return
+		}
+		writeJSON(ctx, w, http.StatusOK, map[string]any{
+			"request":  reqWrapper,
+			"grantId":  reqWrapper.RequestHash,
+			"warnings": warnings(resp),
+		})
+	})
+}
+
+// handleCleanup removes the requested IAM bindings.
+func (s *Server) handleCleanup(ctx context.Context) http.Handler {
+	logger := logging.FromContext(ctx)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqWrapper := &v1alpha1.IAMRequestWrapper{}
+		if _, err := s.readRequest(r, reqWrapper); err != nil {
+			writeError(ctx, w, http.StatusBadRequest, err)
+			return
+		}
+		req := reqWrapper.IAMRequest
+
+		if isDetached(r) {
+			s.writeOperation(ctx, w, requestContext(w, r), OperationTypeCleanup, func(ctx context.Context) ([]*v1alpha1.IAMResponse, error) {
+				return s.handler.Cleanup(ctx, req)
+			})
+			return
+		}
+
+		resp, err := s.handler.Cleanup(requestContext(w, r), req)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to clean up IAM policy", "error", err)
+			writeJSON(ctx, w, http.StatusInternalServerError, map[string]any{
+				"error":    fmt.Sprintf("failed to clean up IAM policy: %s", err),
+				"warnings": warnings(resp),
+			})
+			return
+		}
+		writeJSON(ctx, w, http.StatusOK, map[string]any{
+			"request":  req,
+			"warnings": warnings(resp),
+		})
+	})
+}
+
+// handleValidate validates the IAM request without handling it.
+func (s *Server) handleValidate(ctx context.Context) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqWrapper := &v1alpha1.IAMRequestWrapper{}
+		if _, err := s.readRequest(r, reqWrapper); err != nil {
+			writeError(ctx, w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(ctx, w, http.StatusOK, map[string]any{
+			"request": reqWrapper.IAMRequest,
+		})
+	})
+}
+
+// isDetached returns whether the request asks to be handled as a detached
+// operation.
+func isDetached(r *http.Request) bool {
+	return r.URL.Query().Get("detach") == "true"
+}
+
+// writeOperation starts a detached operation running fn with the request
+// context, and writes it as a response with status code 202.
+func (s *Server) writeOperation(ctx context.Context, w http.ResponseWriter, reqCtx context.Context, typ string, fn func(context.Context) ([]*v1alpha1.IAMResponse, error)) {
+	// Log the operation with the logger of the server.
+	reqCtx = logging.WithLogger(reqCtx, logging.FromContext(ctx))
+	op, err := s.startOperation(reqCtx, typ, fn)
+	if err != nil {
+		writeError(ctx, w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(ctx, w, http.StatusAccepted, map[string]any{"operation": op})
+}
+
+// readRequest reads and validates the IAM request bundle in the request body,
+// sets the merged IAM request in the wrapper and runs the ValidateFuncs on it,
+// and returns the body.
+func (s *Server) readRequest(r *http.Request, w *v1alpha1.IAMRequestWrapper) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	if len(body) > maxBodySize {
+		return nil, fmt.Errorf("request body exceeds size limit of %d bytes", maxBodySize)
+	}
+
+	docs, err := requestutil.ReadBundle[v1alpha1.IAMRequest]("body", body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %T: %w", &v1alpha1.IAMRequest{}, err)
+	}
+	if err := requestutil.ResolveIAMBundle(docs, s.identities); err != nil {
+		return nil, fmt.Errorf("failed to resolve members of %T: %w", &v1alpha1.IAMRequest{}, err)
+	}
+	req, err := requestutil.ValidateIAMBundle(docs, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate %T: %w", req, err)
+	}
+
+	w.IAMRequest = req
+	if err := s.validate(r.Context(), w); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// validate runs the ValidateFuncs on the IAM request in the wrapper.
+func (s *Server) validate(ctx context.Context, w *v1alpha1.IAMRequestWrapper) error {
+	var merr error
+	for _, validate := range s.validates {
+		merr = errors.Join(merr, validate(ctx, w))
+	}
+	if merr != nil {
+		return fmt.Errorf("failed to validate %T: %w", w.IAMRequest, merr)
+	}
+	return nil
+}
+
+// checkExpiry checks the duration is positive and the expiry has not passed.
+func (s *Server) checkExpiry(startTime time.Time, duration time.Duration) error {
+	if duration <= 0 {
+		return fmt.Errorf("a positive duration is required")
+	}
+	if startTime.Add(duration).Before(s.now()) {
+		return fmt.Errorf("expiry (start time: %q + duration: %q) already passed", startTime, duration)
+	}
+	return nil
+}
+
+// requestContext returns the context of the request with its correlation ID,
+// which is the X-Correlation-Id header or a random ID if the header is not set.
+// The correlation ID is also set in the response header.
+func requestContext(w http.ResponseWriter, r *http.Request) context.Context {
+	id := r.Header.Get(headerCorrelationID)
+	if id == "" {
+		// Fall back to the correlation ID of the handler if it fails.
+		id, _ = handler.NewCorrelationID()
+	}
+	w.Header().Set(headerCorrelationID, id)
+	return handler.WithRequestMetadata(r.Context(), &handler.RequestMetadata{CorrelationID: id})
+}
+
+// writeError writes the error as a JSON response with the status code.
+func writeError(ctx context.Context, w http.ResponseWriter, code int, err error) {
+	writeJSON(ctx, w, code, map[string]any{"error": err.Error()})
+}
+
+// writeJSON writes the value as a JSON response with the status code. The
+// API types only have YAML field names, so the value is converted through YAML
+// to be encoded with the same field names as the CLI output.
+func writeJSON(ctx context.Context, w http.ResponseWriter, code int, v any) {
+	logger := logging.FromContext(ctx)
+
+	b, err := toJSON(v)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to encode response", "error", err)
+		code = http.StatusInternalServerError
+		b = []byte(`{"error":"failed to encode response"}`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if _, err := w.Write(append(b, '\n')); err != nil {
+		logger.ErrorContext(ctx, "failed to write response", "error", err)
+	}
+}
+
+// toJSON encodes the value to JSON with its YAML field names.
+func toJSON(v any) ([]byte, error) {
+	y, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal yaml: %w", err)
+	}
+	var out any
+	if err := yaml.Unmarshal(y, &out); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal yaml: %w", err)
+	}
+	b, err := json.Marshal(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal json: %w", err)
+	}
+	return b, nil
+}
+
+// warnings returns the warnings in the IAM responses.
+func warnings(resp []*v1alpha1.IAMResponse) []string {
+	var ws []string
+	for _, r := range resp {
+		for _, w := range r.Warnings {
+			ws = append(ws, fmt.Sprintf("%s: %s", r.Resource, w))
+		}
+	}
+	return ws
+}
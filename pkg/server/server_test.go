@@ -0,0 +1,397 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/access-on-demand/apis/v1alpha1"
+	"github.com/abcxyz/access-on-demand/pkg/handler"
+	"github.com/abcxyz/access-on-demand/pkg/identity"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const testRequest = `policies:
+- resource: organizations/foo
+  bindings:
+  - members:
+    - user:test-org-user@example.com
+    role: roles/cloudkms.cryptoOperator
+`
+
+var testPolicy = &v1alpha1.ResourcePolicy{
+	Resource: "organizations/foo",
+	Bindings: []*v1alpha1.Binding{
+		{
+			Members: []string{"user:test-org-user@example.com"},
+			Role:    "roles/cloudkms.cryptoOperator",
+		},
+	},
+}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name      string
+		method    string
+		target    string
+		body      string
+		header    http.Header
+		handler   *fakeIAMHandler
+		validate  ValidateFunc
+		ids       identity.Map
+		wantCode  int
+		wantBody  string
+		wantDo    *v1alpha1.IAMRequestWrapper
+		wantClean *v1alpha1.IAMRequest
+	}{
+		{
+			name:     "handle",
+			method:   http.MethodPost,
+			target:   "/v1/iam:handle?duration=2h&requester=user:alice@example.com&approver=user:bob@example.com&approver=user:carol@example.com&source=https://example.com/1",
+			body:     testRequest,
+			handler:  &fakeIAMHandler{resp: []*v1alpha1.IAMResponse{{Resource: "organizations/foo", Warnings: []string{"malformed expiry"}}}},
+			wantCode: http.StatusOK,
+			wantBody: `{"grantId":"046735c8bc2886ab8a6b430b45e05f2b5d3d72e2cd1925452cc39c071ba78279",` +
+				`"request":{"approvers":["user:bob@example.com","user:carol@example.com"],"duration":"2h0m0s",` +
+				`"iamrequest":{"policies":[{"bindings":[{"members":["user:test-org-user@example.com"],"role":"roles/cloudkms.cryptoOperator"}],"resource":"organizations/foo"}]},` +
+				`"requester":"user:alice@example.com","source":"https://example.com/1","starttime":"2009-11-10T23:00:00Z"},` +
+				`"warnings":["organizations/foo: malformed expiry"]}`,
+			wantDo: &v1alpha1.IAMRequestWrapper{
+				IAMRequest:  &v1alpha1.IAMRequest{ResourcePolicies: []*v1alpha1.ResourcePolicy{testPolicy}},
+				Duration:    2 * time.Hour,
+				StartTime:   now,
+				Requester:   "user:alice@example.com",
+				Approvers:   []string{"user:bob@example.com", "user:carol@example.com"},
+				Source:      "https://example.com/1",
+				RequestHash: "046735c8bc2886ab8a6b430b45e05f2b5d3d72e2cd1925452cc39c071ba78279",
+			},
+		},
+		{
+			name:     "handle_json",
+			method:   http.MethodPost,
+			target:   "/v1/iam:handle?duration=1h&start-time=2009-11-10T22:30:00Z",
+			body:     `{"policies": [{"resource": "organizations/foo", "bindings": [{"members": ["user:test-org-user@example.com"], "role": "roles/cloudkms.cryptoOperator"}]}]}`,
+			handler:  &fakeIAMHandler{},
+			wantCode: http.StatusOK,
+			wantBody: `{"grantId":"6288bfc7f5dc7fcb7693796a090d9a6a530992eb1a94e4b1389461806321d94a",` +
+				`"request":{"duration":"1h0m0s",` +
+				`"iamrequest":{"policies":[{"bindings":[{"members":["user:test-org-user@example.com"],"role":"roles/cloudkms.cryptoOperator"}],"resource":"organizations/foo"}]},` +
+				`"starttime":"2009-11-10T22:30:00Z"},"warnings":[]}`,
+			wantDo: &v1alpha1.IAMRequestWrapper{
+				IAMRequest:  &v1alpha1.IAMRequest{ResourcePolicies: []*v1alpha1.ResourcePolicy{testPolicy}},
+				Duration:    time.Hour,
+				StartTime:   now.Add(-30 * time.Minute),
+				RequestHash: "6288bfc7f5dc7fcb7693796a090d9a6a530992eb1a94e4b1389461806321d94a",
+			},
+		},
+		{
+			name:     "handle_missing_duration",
+			method:   http.MethodPost,
+			target:   "/v1/iam:handle",
+			body:     testRequest,
+			handler:  &fakeIAMHandler{},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"invalid duration: time: invalid duration \"\""}`,
+		},
+		{
+			name:     "handle_negative_duration",
+			method:   http.MethodPost,
+			target:   "/v1/iam:handle?duration=-1h",
+			body:     testRequest,
+			handler:  &fakeIAMHandler{},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"a positive duration is required"}`,
+		},
+		{
+			name:     "handle_expired",
+			method:   http.MethodPost,
+			target:   "/v1/iam:handle?duration=1h&start-time=2009-11-10T21:00:00Z",
+			body:     testRequest,
+			handler:  &fakeIAMHandler{},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"expiry (start time: \"2009-11-10 21:00:00 +0000 UTC\" + duration: \"1h0m0s\") already passed"}`,
+		},
+		{
+			name:     "handle_invalid_request",
+			method:   http.MethodPost,
+			target:   "/v1/iam:handle?duration=1h",
+			body:     strings.ReplaceAll(testRequest, "user:", "group:"),
+			handler:  &fakeIAMHandler{},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"failed to validate *v1alpha1.IAMRequest: policies[0].bindings[0].members[0] at line 5, column 7: member \"group:test-org-user@example.com\" is not of \"user\" type (got \"group\")"}`,
+		},
+		{
+			name:     "handle_validate_func_failure",
+			method:   http.MethodPost,
+			target:   "/v1/iam:handle?duration=1h",
+			body:     testRequest,
+			handler:  &fakeIAMHandler{},
+			validate: func(context.Context, *v1alpha1.IAMRequestWrapper) error { return fmt.Errorf("injected error") },
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"failed to validate *v1alpha1.IAMRequest: injected error"}`,
+		},
+		{
+			name:     "handle_validate_func_duration",
+			method:   http.MethodPost,
+			target:   "/v1/iam:handle?duration=1h",
+			body:     testRequest,
+			handler:  &fakeIAMHandler{},
+			validate: maxDurationValidateFunc(30 * time.Minute),
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"failed to validate *v1alpha1.IAMRequest: duration 1h0m0s exceeds 30m0s"}`,
+		},
+		{
+			name:     "handle_failure",
+			method:   http.MethodPost,
+			target:   "/v1/iam:handle?duration=1h",
+			body:     testRequest,
+			handler:  &fakeIAMHandler{injectErr: fmt.Errorf("injected error")},
+			wantCode: http.StatusInternalServerError,
+			wantBody: `{"error":"failed to handle IAM request: injected error","warnings":[]}`,
+			wantDo: &v1alpha1.IAMRequestWrapper{
+				IAMRequest:  &v1alpha1.IAMRequest{ResourcePolicies: []*v1alpha1.ResourcePolicy{testPolicy}},
+				Duration:    time.Hour,
+				StartTime:   now,
+				RequestHash: "046735c8bc2886ab8a6b430b45e05f2b5d3d72e2cd1925452cc39c071ba78279",
+			},
+		},
+		{
+			name:     "handle_maintenance",
+			method:   http.MethodPost,
+			target:   "/v1/iam:handle?duration=1h",
+			body:     testRequest,
+			handler:  &fakeIAMHandler{injectErr: &handler.MaintenanceError{Reason: "IAM freeze"}},
+			wantCode: http.StatusServiceUnavailable,
+			wantBody: `{"error":"failed to handle IAM request: AOD is in maintenance mode and not granting access: IAM freeze; expired grants are still cleaned up","warnings":[]}`,
+			wantDo: &v1alpha1.IAMRequestWrapper{
+				IAMRequest:  &v1alpha1.IAMRequest{ResourcePolicies: []*v1alpha1.ResourcePolicy{testPolicy}},
+				Duration:    time.Hour,
+				StartTime:   now,
+				RequestHash: "046735c8bc2886ab8a6b430b45e05f2b5d3d72e2cd1925452cc39c071ba78279",
+			},
+		},
+		{
+			name:     "cleanup",
+			method:   http.MethodPost,
+			target:   "/v1/iam:cleanup",
+			body:     testRequest,
+			handler:  &fakeIAMHandler{},
+			wantCode: http.StatusOK,
+			wantBody: `{"request":{"policies":[{"bindings":[{"members":["user:test-org-user@example.com"],"role":"roles/cloudkms.cryptoOperator"}],"resource":"organizations/foo"}]},"warnings":[]}`,
+			wantClean: &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{testPolicy},
+			},
+		},
+		{
+			name:     "cleanup_with_correlation_id",
+			method:   http.MethodPost,
+			target:   "/v1/iam:cleanup",
+			body:     testRequest,
+			header:   http.Header{"X-Correlation-Id": []string{"request-1"}},
+			handler:  &fakeIAMHandler{},
+			wantCode: http.StatusOK,
+			wantClean: &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{testPolicy},
+			},
+		},
+		{
+			name:     "cleanup_failure",
+			method:   http.MethodPost,
+			target:   "/v1/iam:cleanup",
+			body:     testRequest,
+			handler:  &fakeIAMHandler{injectErr: fmt.Errorf("injected error")},
+			wantCode: http.StatusInternalServerError,
+			wantBody: `{"error":"failed to clean up IAM policy: injected error","warnings":[]}`,
+			wantClean: &v1alpha1.IAMRequest{
+				ResourcePolicies: []*v1alpha1.ResourcePolicy{testPolicy},
+			},
+		},
+		{
+			name:     "cleanup_unknown_field",
+			method:   http.MethodPost,
+			target:   "/v1/iam:cleanup",
+			body:     "foo: bar\n",
+			handler:  &fakeIAMHandler{},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"failed to read *v1alpha1.IAMRequest: failed to unmarshal yaml document 0 in \"body\" to *v1alpha1.IAMRequest: yaml: unmarshal errors:\n  line 1: unknown field \"foo\" in IAMRequest, must be one of [\"policies\" \"denyPolicies\" \"recurring\" \"entitlements\"]"}`,
+		},
+		{
+			name:     "validate",
+			method:   http.MethodPost,
+			target:   "/v1/iam:validate",
+			body:     testRequest,
+			handler:  &fakeIAMHandler{},
+			wantCode: http.StatusOK,
+			wantBody: `{"request":{"policies":[{"bindings":[{"members":["user:test-org-user@example.com"],"role":"roles/cloudkms.cryptoOperator"}],"resource":"organizations/foo"}]}}`,
+		},
+		{
+			name:     "validate_identity_alias",
+			method:   http.MethodPost,
+			target:   "/v1/iam:validate",
+			body:     strings.ReplaceAll(testRequest, "user:test-org-user@example.com", "github:octocat"),
+			handler:  &fakeIAMHandler{},
+			ids:      identity.Map{"github": {"octocat": "user:test-org-user@example.com"}},
+			wantCode: http.StatusOK,
+			wantBody: `{"request":{"policies":[{"bindings":[{"members":["user:test-org-user@example.com"],"role":"roles/cloudkms.cryptoOperator"}],"resource":"organizations/foo"}]}}`,
+		},
+		{
+			name:     "validate_unknown_identity_alias",
+			method:   http.MethodPost,
+			target:   "/v1/iam:validate",
+			body:     strings.ReplaceAll(testRequest, "user:test-org-user@example.com", "github:hubot"),
+			handler:  &fakeIAMHandler{},
+			ids:      identity.Map{"github": {"octocat": "user:test-org-user@example.com"}},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"failed to resolve members of *v1alpha1.IAMRequest: policies[0].bindings[0].members[0] at line 5, column 7: unknown github identity \"hubot\""}`,
+		},
+		{
+			name:     "validate_too_large",
+			method:   http.MethodPost,
+			target:   "/v1/iam:validate",
+			body:     strings.Repeat("#", maxBodySize+1),
+			handler:  &fakeIAMHandler{},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"request body exceeds size limit of 1048576 bytes"}`,
+		},
+		{
+			name:     "method_not_allowed",
+			method:   http.MethodGet,
+			target:   "/v1/iam:handle",
+			handler:  &fakeIAMHandler{},
+			wantCode: http.StatusMethodNotAllowed,
+		},
+		{
+			name:     "healthz",
+			method:   http.MethodGet,
+			target:   "/healthz",
+			handler:  &fakeIAMHandler{},
+			wantCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			opts := []Option{WithNowFunc(func() time.Time { return now })}
+			if tc.validate != nil {
+				opts = append(opts, WithValidateFunc(tc.validate))
+			}
+			if tc.ids != nil {
+				opts = append(opts, WithIdentityMap(tc.ids))
+			}
+			s, err := New(tc.handler, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			for k, vs := range tc.header {
+				req.Header[k] = vs
+			}
+			w := httptest.NewRecorder()
+			s.Routes(ctx).ServeHTTP(w, req)
+
+			if got, want := w.Code, tc.wantCode; got != want {
+				t.Errorf("Process(%+v) got code %d, want %d", tc.name, got, want)
+			}
+			if tc.wantBody != "" {
+				if diff := cmp.Diff(tc.wantBody, strings.TrimSpace(w.Body.String())); diff != "" {
+					t.Errorf("Process(%+v) got body diff (-want, +got):\n%s", tc.name, diff)
+				}
+			}
+			if diff := cmp.Diff(tc.wantDo, tc.handler.gotDo); diff != "" {
+				t.Errorf("Process(%+v) got handled request diff (-want, +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantClean, tc.handler.gotCleanup); diff != "" {
+				t.Errorf("Process(%+v) got cleaned up request diff (-want, +got):\n%s", tc.name, diff)
+			}
+			// The handler gets the correlation ID in the response header, which is
+			// the one in the request header if set.
+			if got := tc.handler.gotCorrelationID; got != "" {
+				if want := w.Header().Get("X-Correlation-Id"); got != want {
+					t.Errorf("Process(%+v) got correlation ID %q, want %q", tc.name, got, want)
+				}
+				if want := tc.header.Get("X-Correlation-Id"); want != "" && got != want {
+					t.Errorf("Process(%+v) got correlation ID %q, want %q", tc.name, got, want)
+				}
+			} else if tc.wantDo != nil || tc.wantClean != nil {
+				t.Errorf("Process(%+v) got no correlation ID", tc.name)
+			}
+		})
+	}
+}
+
+type fakeIAMHandler struct {
+	resp       []*v1alpha1.IAMResponse
+	injectErr  error
+	gotDo      *v1alpha1.IAMRequestWrapper
+	gotCleanup *v1alpha1.IAMRequest
+	grants     []*v1alpha1.ActiveGrant
+	gotList    []string
+	gotMember  string
+	gotRevoke  []string
+
+	gotCorrelationID string
+}
+
+func (h *fakeIAMHandler) Do(ctx context.Context, req *v1alpha1.IAMRequestWrapper) ([]*v1alpha1.IAMResponse, error) {
+	h.gotDo = req
+	h.gotCorrelationID = handler.RequestMetadataFromContext(ctx).CorrelationID
+	return h.resp, h.injectErr
+}
+
+func (h *fakeIAMHandler) Cleanup(ctx context.Context, req *v1alpha1.IAMRequest) ([]*v1alpha1.IAMResponse, error) {
+	h.gotCleanup = req
+	h.gotCorrelationID = handler.RequestMetadataFromContext(ctx).CorrelationID
+	return h.resp, h.injectErr
+}
+
+func (h *fakeIAMHandler) List(ctx context.Context, resources []string) ([]*v1alpha1.ActiveGrant, error) {
+	h.gotList = resources
+	return h.grants, h.injectErr
+}
+
+func (h *fakeIAMHandler) RevokeMember(ctx context.Context, member string, resources []string) ([]*v1alpha1.IAMResponse, error) {
+	h.gotMember = member
+	h.gotRevoke = resources
+	h.gotCorrelationID = handler.RequestMetadataFromContext(ctx).CorrelationID
+	return h.resp, h.injectErr
+}
+
+// maxDurationValidateFunc returns a ValidateFunc that rejects requests with a
+// duration longer than max.
+func maxDurationValidateFunc(max time.Duration) ValidateFunc {
+	return func(_ context.Context, w *v1alpha1.IAMRequestWrapper) error {
+		if w.Duration > max {
+			return fmt.Errorf("duration %s exceeds %s", w.Duration, max)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceaccountiam
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	iam "google.golang.org/api/iam/v1"
+	"google.golang.org/api/option"
+	"google.golang.org/genproto/googleapis/type/expr"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+// fakeIAM is a fake IAM API of the IAM policies of service accounts.
+type fakeIAM struct {
+	mu     sync.Mutex
+	policy *iam.Policy
+	calls  []string
+}
+
+func (f *fakeIAM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, r.Method+" "+r.URL.Path)
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case strings.HasSuffix(r.URL.Path, ":getIamPolicy"):
+		if r.URL.Query().Get("options.requestedPolicyVersion") != "3" {
+			http.Error(w, `{"error": {"code": 400, "message": "missing policy version"}}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(f.policy) //nolint:errcheck // Test server.
+	case strings.HasSuffix(r.URL.Path, ":setIamPolicy"):
+		var req iam.SetIamPolicyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Policy.Etag != f.policy.Etag {
+			http.Error(w, `{"error": {"code": 409, "message": "etag mismatch"}}`, http.StatusConflict)
+			return
+		}
+		f.policy = req.Policy
+		f.policy.Etag = base64.StdEncoding.EncodeToString([]byte("etag2"))
+		json.NewEncoder(w).Encode(f.policy) //nolint:errcheck // Test server.
+	}
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeIAM{
+		policy: &iam.Policy{
+			Version: 1,
+			Etag:    base64.StdEncoding.EncodeToString([]byte("etag1")),
+			Bindings: []*iam.Binding{
+				{Role: "roles/iam.admin", Members: []string{"group:admins@example.com"}},
+			},
+		},
+	}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	c, err := NewClient(ctx, option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	got, err := c.GetIamPolicy(ctx, &iampb.GetIamPolicyRequest{
+		Resource: "serviceAccounts/sa@foo.iam.gserviceaccount.com",
+		Options:  &iampb.GetPolicyOptions{RequestedPolicyVersion: 3},
+	})
+	if err != nil {
+		t.Fatalf("GetIamPolicy got unexpected error: %v", err)
+	}
+	want := &iampb.Policy{
+		Version: 1,
+		Etag:    []byte("etag1"),
+		Bindings: []*iampb.Binding{
+			{Role: "roles/iam.admin", Members: []string{"group:admins@example.com"}},
+		},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("GetIamPolicy got policy diff (-want, +got):\n%s", diff)
+	}
+
+	cond := &expr.Expr{Title: "expiry", Expression: `request.time < timestamp("2009-11-10T23:00:00Z")`}
+	got.Version = 3
+	got.Bindings = append(got.Bindings, &iampb.Binding{
+		Role:      "roles/iam.cryptoKeyEncrypterDecrypter",
+		Members:   []string{"user:oncall@example.com"},
+		Condition: cond,
+	})
+	got, err = c.SetIamPolicy(ctx, &iampb.SetIamPolicyRequest{Resource: "serviceAccounts/sa@foo.iam.gserviceaccount.com", Policy: got})
+	if err != nil {
+		t.Fatalf("SetIamPolicy got unexpected error: %v", err)
+	}
+	want.Version = 3
+	want.Etag = []byte("etag2")
+	want.Bindings = append(want.Bindings, &iampb.Binding{
+		Role:      "roles/iam.cryptoKeyEncrypterDecrypter",
+		Members:   []string{"user:oncall@example.com"},
+		Condition: cond,
+	})
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("SetIamPolicy got policy diff (-want, +got):\n%s", diff)
+	}
+
+	// The etag of the policy is stale.
+	got.Etag = []byte("etag1")
+	_, err = c.SetIamPolicy(ctx, &iampb.SetIamPolicyRequest{Resource: "serviceAccounts/sa@foo.iam.gserviceaccount.com", Policy: got})
+	if diff := testutil.DiffErrString(err, `failed to set IAM policy of service account "serviceAccounts/sa@foo.iam.gserviceaccount.com"`); diff != "" {
+		t.Errorf("SetIamPolicy got unexpected error: %s", diff)
+	}
+
+	_, err = c.GetIamPolicy(ctx, &iampb.GetIamPolicyRequest{Resource: "projects/foo"})
+	if diff := testutil.DiffErrString(err, `resource "projects/foo" is not a service account`); diff != "" {
+		t.Errorf("GetIamPolicy got unexpected error: %s", diff)
+	}
+
+	wantCalls := []string{
+		"POST /v1/projects/-/serviceAccounts/sa@foo.iam.gserviceaccount.com:getIamPolicy",
+		"POST /v1/projects/-/serviceAccounts/sa@foo.iam.gserviceaccount.com:setIamPolicy",
+		"POST /v1/projects/-/serviceAccounts/sa@foo.iam.gserviceaccount.com:setIamPolicy",
+	}
+	if diff := cmp.Diff(wantCalls, fake.calls); diff != "" {
+		t.Errorf("got calls diff (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package storageiam gets and sets the IAM policies of Cloud Storage buckets
+// in the format of the IAM API, so that they are handled like the IAM policies
+// of projects.
+package storageiam
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/api/option"
+	storage "google.golang.org/api/storage/v1"
+	"google.golang.org/genproto/googleapis/type/expr"
+
+	"github.com/abcxyz/access-on-demand/pkg/iamdelta"
+	"github.com/abcxyz/access-on-demand/pkg/resource"
+)
+
+// Client gets and sets the IAM policies of Cloud Storage buckets, named
+// "buckets/<bucket>". Conditional bindings require uniform bucket-level access
+// to be enabled on the buckets.
+type Client struct {
+	service *storage.Service
+}
+
+// NewClient creates a new Client.
+func NewClient(ctx context.Context, opts ...option.ClientOption) (*Client, error) {
+	svc, err := storage.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage service: %w", err)
+	}
+	return &Client{service: svc}, nil
+}
+
+// GetIamPolicy gets the IAM policy of the bucket.
+func (c *Client) GetIamPolicy(ctx context.Context, req *iampb.GetIamPolicyRequest, _ ...gax.CallOption) (*iampb.Policy, error) {
+	bucket, err := parse(req.GetResource())
+	if err != nil {
+		return nil, err
+	}
+
+	call := c.service.Buckets.GetIamPolicy(bucket).Context(ctx)
+	if v := req.GetOptions().GetRequestedPolicyVersion(); v > 0 {
+		call = call.OptionsRequestedPolicyVersion(int64(v))
+	}
+	p, err := call.Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IAM policy of bucket %q: %w", req.GetResource(), err)
+	}
+	return fromPolicy(p), nil
+}
+
+// SetIamPolicy sets the IAM policy of the bucket.
+func (c *Client) SetIamPolicy(ctx context.Context, req *iampb.SetIamPolicyRequest, _ ...gax.CallOption) (*iampb.Policy, error) {
+	bucket, err := parse(req.GetResource())
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := c.service.Buckets.SetIamPolicy(bucket, toPolicy(req.GetPolicy())).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to set IAM policy of bucket %q: %w", req.GetResource(), err)
+	}
+	return fromPolicy(p), nil
+}
+
+// PatchIamPolicy applies the changes to the bindings to the latest IAM policy
+// of the bucket.
+func (c *Client) PatchIamPolicy(ctx context.Context, resource string, d *iamdelta.Delta) (*iampb.Policy, error) {
+	return iamdelta.Patch(ctx, c, resource, d) //nolint:wrapcheck // Want passthrough
+}
+
+// parse returns the bucket of the resource name.
+func parse(name string) (string, error) {
+	rn, err := resource.Parse(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse resource: %w", err)
+	}
+	if rn.Type != resource.TypeBucket {
+		return "", fmt.Errorf("resource %q is not a bucket", name)
+	}
+	return rn.ID, nil
+}
+
+// fromPolicy converts the IAM policy of a bucket to an IAM policy of the IAM
+// API. The etag of buckets is opaque, so it is kept as is.
+func fromPolicy(p *storage.Policy) *iampb.Policy {
+	policy := &iampb.Policy{Version: int32(p.Version), Etag: []byte(p.Etag)}
+	for _, b := range p.Bindings {
+		policy.Bindings = append(policy.Bindings, &iampb.Binding{
+			Role:      b.Role,
+			Members:   b.Members,
+			Condition: fromExpr(b.Condition),
+		})
+	}
+	return policy
+}
+
+// toPolicy converts the IAM policy of the IAM API to an IAM policy of a
+// bucket.
+func toPolicy(p *iampb.Policy) *storage.Policy {
+	policy := &storage.Policy{
+		Version: int64(p.GetVersion()),
+		Etag:    string(p.GetEtag()),
+	}
+	for _, b := range p.GetBindings() {
+		policy.Bindings = append(policy.Bindings, &storage.PolicyBindings{
+			Role:      b.GetRole(),
+			Members:   b.GetMembers(),
+			Condition: toExpr(b.GetCondition()),
+		})
+	}
+	return policy
+}
+
+// fromExpr converts the condition of the Storage API to a condition of the IAM
+// API.
+func fromExpr(e *storage.Expr) *expr.Expr {
+	if e == nil {
+		return nil
+	}
+	return &expr.Expr{
+		Title:       e.Title,
+		Description: e.Description,
+		Expression:  e.Expression,
+		Location:    e.Location,
+	}
+}
+
+// toExpr converts the condition of the IAM API to a condition of the Storage
+// API.
+func toExpr(e *expr.Expr) *storage.Expr {
+	if e == nil {
+		return nil
+	}
+	return &storage.Expr{
+		Title:       e.GetTitle(),
+		Description: e.GetDescription(),
+		Expression:  e.GetExpression(),
+		Location:    e.GetLocation(),
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageiam
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	storage "google.golang.org/api/storage/v1"
+	"google.golang.org/genproto/googleapis/type/expr"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+// fakeStorage is a fake Storage API of the IAM policy of a bucket.
+type fakeStorage struct {
+	mu     sync.Mutex
+	policy *storage.Policy
+	calls  []string
+}
+
+func (f *fakeStorage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, r.Method+" "+r.URL.Path+"?"+r.URL.Query().Get("optionsRequestedPolicyVersion"))
+
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(f.policy) //nolint:errcheck // Test server.
+	case http.MethodPut:
+		var p storage.Policy
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if p.Etag != f.policy.Etag {
+			http.Error(w, `{"error": {"code": 412, "message": "precondition failed"}}`, http.StatusPreconditionFailed)
+			return
+		}
+		p.Etag += "+"
+		f.policy = &p
+		json.NewEncoder(w).Encode(f.policy) //nolint:errcheck // Test server.
+	}
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeStorage{
+		policy: &storage.Policy{
+			Version: 1,
+			Etag:    "CAE=",
+			Bindings: []*storage.PolicyBindings{
+				{Role: "roles/storage.legacyBucketOwner", Members: []string{"projectOwner:foo"}},
+			},
+		},
+	}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	c, err := NewClient(ctx, option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	got, err := c.GetIamPolicy(ctx, &iampb.GetIamPolicyRequest{
+		Resource: "buckets/foo",
+		Options:  &iampb.GetPolicyOptions{RequestedPolicyVersion: 3},
+	})
+	if err != nil {
+		t.Fatalf("GetIamPolicy got unexpected error: %v", err)
+	}
+	want := &iampb.Policy{
+		Version: 1,
+		Etag:    []byte("CAE="),
+		Bindings: []*iampb.Binding{
+			{Role: "roles/storage.legacyBucketOwner", Members: []string{"projectOwner:foo"}},
+		},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("GetIamPolicy got policy diff (-want, +got):\n%s", diff)
+	}
+
+	cond := &expr.Expr{Title: "expiry", Expression: `request.time < timestamp("2009-11-10T23:00:00Z")`}
+	got.Version = 3
+	got.Bindings = append(got.Bindings, &iampb.Binding{
+		Role:      "roles/storage.objectViewer",
+		Members:   []string{"user:alice@example.com"},
+		Condition: cond,
+	})
+	got, err = c.SetIamPolicy(ctx, &iampb.SetIamPolicyRequest{Resource: "buckets/foo", Policy: got})
+	if err != nil {
+		t.Fatalf("SetIamPolicy got unexpected error: %v", err)
+	}
+	want.Version = 3
+	want.Etag = []byte("CAE=+")
+	want.Bindings = append(want.Bindings, &iampb.Binding{
+		Role:      "roles/storage.objectViewer",
+		Members:   []string{"user:alice@example.com"},
+		Condition: cond,
+	})
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("SetIamPolicy got policy diff (-want, +got):\n%s", diff)
+	}
+
+	// The etag of the policy is stale.
+	got.Etag = []byte("CAE=")
+	_, err = c.SetIamPolicy(ctx, &iampb.SetIamPolicyRequest{Resource: "buckets/foo", Policy: got})
+	if diff := testutil.DiffErrString(err, `failed to set IAM policy of bucket "buckets/foo"`); diff != "" {
+		t.Errorf("SetIamPolicy got unexpected error: %s", diff)
+	}
+
+	_, err = c.GetIamPolicy(ctx, &iampb.GetIamPolicyRequest{Resource: "projects/foo"})
+	if diff := testutil.DiffErrString(err, `resource "projects/foo" is not a bucket`); diff != "" {
+		t.Errorf("GetIamPolicy got unexpected error: %s", diff)
+	}
+
+	wantCalls := []string{
+		"GET /b/foo/iam?3",
+		"PUT /b/foo/iam?",
+		"PUT /b/foo/iam?",
+	}
+	if diff := cmp.Diff(wantCalls, fake.calls); diff != "" {
+		t.Errorf("got calls diff (-want, +got):\n%s", diff)
+	}
+}
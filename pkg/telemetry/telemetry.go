@@ -0,0 +1,143 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package telemetry sets up OpenTelemetry providers exporting the traces and
+// metrics of AOD.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Exporters of the traces and metrics.
+const (
+	// ExporterOTLP exports to an OpenTelemetry collector with OTLP over gRPC.
+	ExporterOTLP = "otlp"
+	// ExporterWriter writes the traces and metrics as JSON to a writer, for
+	// debugging.
+	ExporterWriter = "writer"
+)
+
+// Config is the config of the OpenTelemetry providers.
+type Config struct {
+	// Exporter is one of ExporterOTLP and ExporterWriter.
+	Exporter string
+	// Endpoint is the "host:port" of the OpenTelemetry collector for
+	// ExporterOTLP, the OTEL_EXPORTER_OTLP_* environment variables are used if
+	// it is empty.
+	Endpoint string
+	// Insecure disables TLS to the OpenTelemetry collector for ExporterOTLP.
+	Insecure bool
+	// Writer is where ExporterWriter writes to.
+	Writer io.Writer
+	// ServiceName and ServiceVersion are the service attributes of the
+	// telemetry resource.
+	ServiceName    string
+	ServiceVersion string
+}
+
+// Providers are the OpenTelemetry providers exporting traces and metrics.
+type Providers struct {
+	TracerProvider *sdktrace.TracerProvider
+	MeterProvider  *sdkmetric.MeterProvider
+}
+
+// ValidateExporter checks the exporter is supported.
+func ValidateExporter(exporter string) error {
+	if supported := []string{ExporterOTLP, ExporterWriter}; !slices.Contains(supported, exporter) {
+		return fmt.Errorf("exporter %q is not one of %q", exporter, supported)
+	}
+	return nil
+}
+
+// NewProviders creates the OpenTelemetry providers with the config. The
+// providers must be shut down to flush the telemetry before exiting.
+func NewProviders(ctx context.Context, cfg *Config) (*Providers, error) {
+	if err := ValidateExporter(cfg.Exporter); err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.ServiceVersion(cfg.ServiceVersion),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create telemetry resource: %w", err)
+	}
+
+	var spanExporter sdktrace.SpanExporter
+	var metricExporter sdkmetric.Exporter
+	switch cfg.Exporter {
+	case ExporterOTLP:
+		traceOpts := []otlptracegrpc.Option{}
+		metricOpts := []otlpmetricgrpc.Option{}
+		if cfg.Endpoint != "" {
+			traceOpts = append(traceOpts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+			metricOpts = append(metricOpts, otlpmetricgrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			traceOpts = append(traceOpts, otlptracegrpc.WithInsecure())
+			metricOpts = append(metricOpts, otlpmetricgrpc.WithInsecure())
+		}
+		if spanExporter, err = otlptracegrpc.New(ctx, traceOpts...); err != nil {
+			return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+		}
+		if metricExporter, err = otlpmetricgrpc.New(ctx, metricOpts...); err != nil {
+			return nil, fmt.Errorf("failed to create OTLP metric exporter: %w", err)
+		}
+	case ExporterWriter:
+		if spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(cfg.Writer)); err != nil {
+			return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+		}
+		if metricExporter, err = stdoutmetric.New(stdoutmetric.WithWriter(cfg.Writer)); err != nil {
+			return nil, fmt.Errorf("failed to create metric exporter: %w", err)
+		}
+	}
+
+	return &Providers{
+		TracerProvider: sdktrace.NewTracerProvider(
+			sdktrace.WithResource(res),
+			sdktrace.WithBatcher(spanExporter),
+		),
+		MeterProvider: sdkmetric.NewMeterProvider(
+			sdkmetric.WithResource(res),
+			sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)),
+		),
+	}, nil
+}
+
+// Shutdown flushes the telemetry and shuts down the providers.
+func (p *Providers) Shutdown(ctx context.Context) error {
+	var retErr error
+	if err := p.TracerProvider.Shutdown(ctx); err != nil {
+		retErr = errors.Join(retErr, fmt.Errorf("failed to shut down tracer provider: %w", err))
+	}
+	if err := p.MeterProvider.Shutdown(ctx); err != nil {
+		retErr = errors.Join(retErr, fmt.Errorf("failed to shut down meter provider: %w", err))
+	}
+	return retErr
+}
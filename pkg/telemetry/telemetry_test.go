@@ -0,0 +1,80 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestNewProviders(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		cfg     *Config
+		wantErr string
+	}{
+		{
+			name: "writer",
+			cfg:  &Config{Exporter: ExporterWriter, ServiceName: "aod", ServiceVersion: "v0.0.0"},
+		},
+		{
+			name: "otlp",
+			cfg:  &Config{Exporter: ExporterOTLP, Endpoint: "localhost:4317", Insecure: true, ServiceName: "aod"},
+		},
+		{
+			name:    "unsupported_exporter",
+			cfg:     &Config{Exporter: "zipkin"},
+			wantErr: `exporter "zipkin" is not one of ["otlp" "writer"]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			var buf bytes.Buffer
+			tc.cfg.Writer = &buf
+
+			p, err := NewProviders(ctx, tc.cfg)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatalf("Process(%+v) got unexpected error: %s", tc.name, diff)
+			}
+			if err != nil {
+				return
+			}
+			if tc.cfg.Exporter != ExporterWriter {
+				return
+			}
+
+			_, span := p.TracerProvider.Tracer("test").Start(ctx, "test-span")
+			span.End()
+			if err := p.Shutdown(ctx); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range []string{`"Name":"test-span"`, `"Value":"aod"`} {
+				if got := buf.String(); !strings.Contains(got, want) {
+					t.Errorf("Process(%+v) got output %q, want containing %q", tc.name, got, want)
+				}
+			}
+		})
+	}
+}
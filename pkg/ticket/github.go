@@ -0,0 +1,161 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ticket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// DefaultGitHubAPIURL is the URL of the GitHub REST API.
+const DefaultGitHubAPIURL = "https://api.github.com"
+
+// repoRegex matches a GitHub repository in the format of "owner/repo".
+var repoRegex = regexp.MustCompile(`^[^/\s]+/[^/\s]+$`)
+
+// GitHubCreator opens tickets as issues of a GitHub repository.
+type GitHubCreator struct {
+	client *http.Client
+	apiURL string
+	repo   string
+	token  string
+}
+
+// NewGitHubCreator creates a new GitHubCreator opening issues in the repo, in
+// the format of "owner/repo", with the token which needs permission to read and
+// write issues. The apiURL is the URL of the GitHub REST API, such as
+// DefaultGitHubAPIURL.
+func NewGitHubCreator(client *http.Client, apiURL, repo, token string) (*GitHubCreator, error) {
+	if err := ValidateGitHubRepo(repo); err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return nil, fmt.Errorf("github token is required")
+	}
+	return &GitHubCreator{
+		client: client,
+		apiURL: strings.TrimSuffix(apiURL, "/"),
+		repo:   repo,
+		token:  token,
+	}, nil
+}
+
+// ValidateGitHubRepo checks the repo is in the format of "owner/repo".
+func ValidateGitHubRepo(repo string) error {
+	if !repoRegex.MatchString(repo) {
+		return fmt.Errorf("repo %q isn't in the format of %q", repo, "owner/repo")
+	}
+	return nil
+}
+
+// githubIssue is a GitHub issue in the GitHub REST API.
+type githubIssue struct {
+	Title   string `json:"title"`
+	Body    string `json:"body,omitempty"`
+	HTMLURL string `json:"html_url,omitempty"`
+}
+
+// Create opens an issue for the ticket and returns its URL. If there is an
+// open issue with the same title, its URL is returned instead.
+func (c *GitHubCreator) Create(ctx context.Context, t *Ticket) (string, error) {
+	existing, err := c.findOpenIssue(ctx, t.Title)
+	if err != nil {
+		return "", err
+	}
+	if existing != "" {
+		return existing, nil
+	}
+
+	var issue githubIssue
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/issues", c.repo),
+		&githubIssue{Title: t.Title, Body: t.Body}, &issue); err != nil {
+		return "", fmt.Errorf("failed to create issue: %w", err)
+	}
+	return issue.HTMLURL, nil
+}
+
+// findOpenIssue returns the URL of the open issue with the title in the repo,
+// or an empty string if there is none.
+func (c *GitHubCreator) findOpenIssue(ctx context.Context, title string) (string, error) {
+	q := fmt.Sprintf("repo:%s is:issue is:open in:title %q", c.repo, title)
+	var result struct {
+		Items []*githubIssue `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/search/issues?q="+url.QueryEscape(q), nil, &result); err != nil {
+		return "", fmt.Errorf("failed to search issues: %w", err)
+	}
+	// The search matches words in the title, check for the exact title.
+	for _, i := range result.Items {
+		if i.Title == title {
+			return i.HTMLURL, nil
+		}
+	}
+	return "", nil
+}
+
+// do sends the request with the JSON body to the GitHub REST API, and decodes
+// the JSON response to out.
+func (c *GitHubCreator) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.apiURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return doJSON(c.client, req, out)
+}
+
+// doJSON sends the request and decodes the JSON response to out, non-2xx
+// responses are returned as errors.
+func doJSON(client *http.Client, req *http.Request, out any) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Limit the response body to 4MiB.
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
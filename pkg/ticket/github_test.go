@@ -0,0 +1,155 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ticket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestGitHubCreator_Create(t *testing.T) {
+	t.Parallel()
+
+	ticket := &Ticket{Title: "AOD permission denied on projects/foo", Body: "body"}
+
+	cases := []struct {
+		name         string
+		searchResp   string
+		createStatus int
+		wantURL      string
+		wantCreated  *githubIssue
+		wantErr      string
+	}{
+		{
+			name:         "create",
+			searchResp:   `{"items": [{"title": "AOD permission denied on projects/foo-bar", "html_url": "https://github.com/foo/bar/issues/1"}]}`,
+			createStatus: http.StatusCreated,
+			wantURL:      "https://github.com/foo/bar/issues/2",
+			wantCreated:  &githubIssue{Title: "AOD permission denied on projects/foo", Body: "body"},
+		},
+		{
+			name:       "existing",
+			searchResp: `{"items": [{"title": "AOD permission denied on projects/foo", "html_url": "https://github.com/foo/bar/issues/1"}]}`,
+			wantURL:    "https://github.com/foo/bar/issues/1",
+		},
+		{
+			name:         "create_failure",
+			searchResp:   `{"items": []}`,
+			createStatus: http.StatusForbidden,
+			wantCreated:  &githubIssue{Title: "AOD permission denied on projects/foo", Body: "body"},
+			wantErr:      "failed to create issue: unexpected response status 403",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			var gotCreated *githubIssue
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				if got, want := r.Header.Get("Authorization"), "Bearer test-token"; got != want {
+					t.Errorf("authorization header got %q, want %q", got, want)
+				}
+				switch {
+				case r.Method == http.MethodGet && r.URL.Path == "/search/issues":
+					if got, want := r.URL.Query().Get("q"), `repo:foo/bar is:issue is:open in:title "AOD permission denied on projects/foo"`; got != want {
+						t.Errorf("search query got %q, want %q", got, want)
+					}
+					fmt.Fprint(w, tc.searchResp)
+				case r.Method == http.MethodPost && r.URL.Path == "/repos/foo/bar/issues":
+					gotCreated = &githubIssue{}
+					if err := json.NewDecoder(r.Body).Decode(gotCreated); err != nil {
+						t.Errorf("failed to decode request: %v", err)
+					}
+					w.WriteHeader(tc.createStatus)
+					fmt.Fprint(w, `{"html_url": "https://github.com/foo/bar/issues/2"}`)
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			t.Cleanup(srv.Close)
+
+			c, err := NewGitHubCreator(srv.Client(), srv.URL, "foo/bar", "test-token")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotURL, err := c.Create(context.Background(), ticket)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error: %s", tc.name, diff)
+			}
+			if gotURL != tc.wantURL {
+				t.Errorf("Process(%+v) got url %q, want %q", tc.name, gotURL, tc.wantURL)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if diff := cmp.Diff(tc.wantCreated, gotCreated); diff != "" {
+				t.Errorf("Process(%+v) got created issue diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestNewGitHubCreator(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		repo    string
+		token   string
+		wantErr string
+	}{
+		{
+			name:  "valid",
+			repo:  "foo/bar",
+			token: "test-token",
+		},
+		{
+			name:    "invalid_repo",
+			repo:    "foo",
+			token:   "test-token",
+			wantErr: `repo "foo" isn't in the format of "owner/repo"`,
+		},
+		{
+			name:    "missing_token",
+			repo:    "foo/bar",
+			wantErr: "github token is required",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewGitHubCreator(http.DefaultClient, DefaultGitHubAPIURL, tc.repo, tc.token)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error: %s", tc.name, diff)
+			}
+		})
+	}
+}
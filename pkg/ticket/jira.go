@@ -0,0 +1,117 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ticket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// JiraCreator opens tickets as issues of a JIRA project.
+type JiraCreator struct {
+	client  *http.Client
+	baseURL string
+	project string
+	user    string
+	token   string
+}
+
+// NewJiraCreator creates a new JiraCreator opening "Task" issues in the
+// project, such as "OPS", of the JIRA site at baseURL, such as
+// "https://example.atlassian.net". The user and API token are used for basic
+// authentication.
+func NewJiraCreator(client *http.Client, baseURL, project, user, token string) (*JiraCreator, error) {
+	if baseURL == "" || project == "" {
+		return nil, fmt.Errorf("jira url and project are required")
+	}
+	if user == "" || token == "" {
+		return nil, fmt.Errorf("jira user and token are required")
+	}
+	return &JiraCreator{
+		client:  client,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		project: project,
+		user:    user,
+		token:   token,
+	}, nil
+}
+
+// Create opens an issue for the ticket and returns its URL. If there is an
+// unresolved issue with the same summary, its URL is returned instead.
+func (c *JiraCreator) Create(ctx context.Context, t *Ticket) (string, error) {
+	jql := fmt.Sprintf("project = %q AND summary ~ %q AND statusCategory != Done", c.project, fmt.Sprintf("%q", t.Title))
+	var found struct {
+		Issues []struct {
+			Key    string `json:"key"`
+			Fields struct {
+				Summary string `json:"summary"`
+			} `json:"fields"`
+		} `json:"issues"`
+	}
+	if err := c.do(ctx, "/rest/api/2/search", map[string]any{
+		"jql":    jql,
+		"fields": []string{"summary"},
+	}, &found); err != nil {
+		return "", fmt.Errorf("failed to search issues: %w", err)
+	}
+	// The search matches words in the summary, check for the exact summary.
+	for _, i := range found.Issues {
+		if i.Fields.Summary == t.Title {
+			return c.browseURL(i.Key), nil
+		}
+	}
+
+	var created struct {
+		Key string `json:"key"`
+	}
+	if err := c.do(ctx, "/rest/api/2/issue", map[string]any{
+		"fields": map[string]any{
+			"project":     map[string]string{"key": c.project},
+			"summary":     t.Title,
+			"description": t.Body,
+			"issuetype":   map[string]string{"name": "Task"},
+		},
+	}, &created); err != nil {
+		return "", fmt.Errorf("failed to create issue: %w", err)
+	}
+	return c.browseURL(created.Key), nil
+}
+
+// browseURL returns the URL of the issue with the key.
+func (c *JiraCreator) browseURL(key string) string {
+	return fmt.Sprintf("%s/browse/%s", c.baseURL, key)
+}
+
+// do posts the JSON body to the JIRA REST API, and decodes the JSON response
+// to out.
+func (c *JiraCreator) do(ctx context.Context, path string, in, out any) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.SetBasicAuth(c.user, c.token)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	return doJSON(c.client, req, out)
+}
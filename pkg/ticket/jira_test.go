@@ -0,0 +1,139 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ticket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestJiraCreator_Create(t *testing.T) {
+	t.Parallel()
+
+	ticket := &Ticket{Title: "AOD permission denied on projects/foo", Body: "body"}
+
+	cases := []struct {
+		name         string
+		searchResp   string
+		createStatus int
+		wantPath     string
+		wantCreated  map[string]any
+		wantErr      string
+	}{
+		{
+			name:         "create",
+			searchResp:   `{"issues": []}`,
+			createStatus: http.StatusCreated,
+			wantPath:     "/browse/OPS-2",
+			wantCreated: map[string]any{
+				"fields": map[string]any{
+					"project":     map[string]any{"key": "OPS"},
+					"summary":     "AOD permission denied on projects/foo",
+					"description": "body",
+					"issuetype":   map[string]any{"name": "Task"},
+				},
+			},
+		},
+		{
+			name:       "existing",
+			searchResp: `{"issues": [{"key": "OPS-1", "fields": {"summary": "AOD permission denied on projects/foo"}}]}`,
+			wantPath:   "/browse/OPS-1",
+		},
+		{
+			name:         "create_failure",
+			searchResp:   `{"issues": []}`,
+			createStatus: http.StatusBadRequest,
+			wantCreated: map[string]any{
+				"fields": map[string]any{
+					"project":     map[string]any{"key": "OPS"},
+					"summary":     "AOD permission denied on projects/foo",
+					"description": "body",
+					"issuetype":   map[string]any{"name": "Task"},
+				},
+			},
+			wantErr: "failed to create issue: unexpected response status 400",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			var gotCreated map[string]any
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				if user, token, ok := r.BasicAuth(); !ok || user != "bot@example.com" || token != "test-token" {
+					t.Errorf("basic auth got (%q, %q, %t), want (%q, %q, true)", user, token, ok, "bot@example.com", "test-token")
+				}
+				switch r.URL.Path {
+				case "/rest/api/2/search":
+					var got struct {
+						JQL string `json:"jql"`
+					}
+					if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+						t.Errorf("failed to decode request: %v", err)
+					}
+					if want := `project = "OPS" AND summary ~ "\"AOD permission denied on projects/foo\"" AND statusCategory != Done`; got.JQL != want {
+						t.Errorf("jql got %q, want %q", got.JQL, want)
+					}
+					fmt.Fprint(w, tc.searchResp)
+				case "/rest/api/2/issue":
+					if err := json.NewDecoder(r.Body).Decode(&gotCreated); err != nil {
+						t.Errorf("failed to decode request: %v", err)
+					}
+					w.WriteHeader(tc.createStatus)
+					fmt.Fprint(w, `{"key": "OPS-2"}`)
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			t.Cleanup(srv.Close)
+
+			c, err := NewJiraCreator(srv.Client(), srv.URL+"/", "OPS", "bot@example.com", "test-token")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotURL, err := c.Create(context.Background(), ticket)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error: %s", tc.name, diff)
+			}
+			wantURL := ""
+			if tc.wantPath != "" {
+				wantURL = srv.URL + tc.wantPath
+			}
+			if gotURL != wantURL {
+				t.Errorf("Process(%+v) got url %q, want %q", tc.name, gotURL, wantURL)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if diff := cmp.Diff(tc.wantCreated, gotCreated); diff != "" {
+				t.Errorf("Process(%+v) got created issue diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
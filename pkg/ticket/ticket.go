@@ -0,0 +1,69 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ticket opens tickets, such as GitHub issues and JIRA issues, for AOD
+// failures that need human action.
+package ticket
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ticket is a ticket to open.
+type Ticket struct {
+	// Title is the title of the ticket, open tickets with the same title are
+	// considered duplicates.
+	Title string
+	// Body is the body of the ticket in Markdown.
+	Body string
+}
+
+// iamAdminRoles are the predefined roles to manage the IAM policies of each
+// resource type.
+var iamAdminRoles = map[string]string{
+	"organizations": "roles/resourcemanager.organizationAdmin",
+	"folders":       "roles/resourcemanager.folderIamAdmin",
+	"projects":      "roles/resourcemanager.projectIamAdmin",
+}
+
+// NewPermissionDenied creates a ticket for AOD being denied permission to
+// update the IAM policy of the resource, describing the missing permissions.
+// The caller is the identity running AOD, it is omitted if empty.
+func NewPermissionDenied(resource, caller string, err error) *Ticket {
+	typ, _, _ := strings.Cut(resource, "/")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "AOD was denied permission to update the IAM policy of `%s`.\n\n", resource)
+	if caller != "" {
+		fmt.Fprintf(&b, "To onboard the resource into AOD, grant `%s` ", caller)
+	} else {
+		b.WriteString("To onboard the resource into AOD, grant the identity running AOD ")
+	}
+	if role, ok := iamAdminRoles[typ]; ok {
+		fmt.Fprintf(&b, "the role `%s`, or a role with the permissions:\n\n", role)
+	} else {
+		b.WriteString("a role with the permissions:\n\n")
+	}
+	fmt.Fprintf(&b, "- `resourcemanager.%s.getIamPolicy`\n", typ)
+	fmt.Fprintf(&b, "- `resourcemanager.%s.setIamPolicy`\n", typ)
+	if err != nil {
+		fmt.Fprintf(&b, "\nError:\n\n```\n%s\n```\n", err)
+	}
+
+	return &Ticket{
+		Title: fmt.Sprintf("AOD permission denied on %s", resource),
+		Body:  b.String(),
+	}
+}
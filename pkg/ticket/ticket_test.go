@@ -0,0 +1,73 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ticket
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewPermissionDenied(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		resource string
+		caller   string
+		err      error
+		want     *Ticket
+	}{
+		{
+			name:     "project_with_caller",
+			resource: "projects/foo",
+			caller:   "serviceAccount:aod@my-project.iam.gserviceaccount.com",
+			err:      fmt.Errorf("failed to set IAM policy: permission denied"),
+			want: &Ticket{
+				Title: "AOD permission denied on projects/foo",
+				Body: "AOD was denied permission to update the IAM policy of `projects/foo`.\n\n" +
+					"To onboard the resource into AOD, grant `serviceAccount:aod@my-project.iam.gserviceaccount.com` " +
+					"the role `roles/resourcemanager.projectIamAdmin`, or a role with the permissions:\n\n" +
+					"- `resourcemanager.projects.getIamPolicy`\n" +
+					"- `resourcemanager.projects.setIamPolicy`\n" +
+					"\nError:\n\n```\nfailed to set IAM policy: permission denied\n```\n",
+			},
+		},
+		{
+			name:     "folder_without_caller",
+			resource: "folders/bar",
+			want: &Ticket{
+				Title: "AOD permission denied on folders/bar",
+				Body: "AOD was denied permission to update the IAM policy of `folders/bar`.\n\n" +
+					"To onboard the resource into AOD, grant the identity running AOD " +
+					"the role `roles/resourcemanager.folderIamAdmin`, or a role with the permissions:\n\n" +
+					"- `resourcemanager.folders.getIamPolicy`\n" +
+					"- `resourcemanager.folders.setIamPolicy`\n",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := NewPermissionDenied(tc.resource, tc.caller, tc.err)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Process(%+v) got ticket diff (-want, +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
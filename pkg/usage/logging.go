@@ -0,0 +1,152 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	logging "google.golang.org/api/logging/v2"
+	"google.golang.org/api/option"
+
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+)
+
+const (
+	// DefaultMaxEntries is the default max number of Cloud Audit Logs entries
+	// read per query.
+	DefaultMaxEntries = 1000
+
+	// maxPageSize is the max page size of the Cloud Logging API.
+	maxPageSize = 1000
+)
+
+// LoggingQuerier queries the activity of members in the Cloud Audit Logs of
+// resources with the Cloud Logging API. Only the logs of the resource itself
+// are queried, e.g. the activity in the projects of a folder is not included
+// for the folder.
+type LoggingQuerier struct {
+	service    *logging.Service
+	maxEntries int
+}
+
+// NewLoggingQuerier creates a new LoggingQuerier reading at most maxEntries
+// log entries per query.
+func NewLoggingQuerier(ctx context.Context, maxEntries int, opts ...option.ClientOption) (*LoggingQuerier, error) {
+	if maxEntries < 1 {
+		return nil, fmt.Errorf("max entries must be at least 1, got %d", maxEntries)
+	}
+	svc, err := logging.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create logging service: %w", err)
+	}
+	return &LoggingQuerier{
+		service:    svc,
+		maxEntries: maxEntries,
+	}, nil
+}
+
+// auditLogPayload is the part of the protoPayload of Cloud Audit Logs entries
+// used to summarize the activity.
+type auditLogPayload struct {
+	ServiceName       string `json:"serviceName"`
+	MethodName        string `json:"methodName"`
+	AuthorizationInfo []struct {
+		Permission string `json:"permission"`
+		Granted    bool   `json:"granted"`
+	} `json:"authorizationInfo"`
+}
+
+// QueryUsage returns the usage of the member in the Cloud Audit Logs of the
+// resource between start and end. The member must be of "user" or
+// "serviceAccount" type, since the logs record the individual principals.
+func (q *LoggingQuerier) QueryUsage(ctx context.Context, resource, member string, start, end time.Time) (*audit.Usage, error) {
+	email, err := principalEmail(member)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &logging.ListLogEntriesRequest{
+		ResourceNames: []string{resource},
+		Filter:        Filter(email, start, end),
+		OrderBy:       "timestamp asc",
+		PageSize:      int64(min(q.maxEntries, maxPageSize)),
+	}
+
+	var activities []*Activity
+	truncated := false
+	for {
+		resp, err := q.service.Entries.List(req).Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list log entries of %q: %w", resource, err)
+		}
+		for _, e := range resp.Entries {
+			if len(activities) == q.maxEntries {
+				truncated = true
+				break
+			}
+			a, err := toActivity(e)
+			if err != nil {
+				return nil, err
+			}
+			activities = append(activities, a)
+		}
+		if truncated || resp.NextPageToken == "" {
+			break
+		}
+		req.PageToken = resp.NextPageToken
+	}
+	return Summarize(member, start, end, activities, truncated), nil
+}
+
+// Filter returns the Cloud Logging filter of the Cloud Audit Logs entries of
+// the principal email between start and end.
+func Filter(email string, start, end time.Time) string {
+	return fmt.Sprintf(`logName:"cloudaudit.googleapis.com" AND `+
+		`protoPayload.authenticationInfo.principalEmail="%s" AND `+
+		`timestamp>="%s" AND timestamp<"%s"`,
+		email, start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339))
+}
+
+// principalEmail returns the email of the "user" or "serviceAccount" member.
+func principalEmail(member string) (string, error) {
+	typ, email, ok := strings.Cut(member, ":")
+	if !ok || (typ != "user" && typ != "serviceAccount") {
+		return "", fmt.Errorf("member %q is not of user or serviceAccount type", member)
+	}
+	return email, nil
+}
+
+func toActivity(e *logging.LogEntry) (*Activity, error) {
+	var p auditLogPayload
+	if len(e.ProtoPayload) > 0 {
+		if err := json.Unmarshal(e.ProtoPayload, &p); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal protoPayload of log entry %q: %w", e.InsertId, err)
+		}
+	}
+	a := &Activity{
+		Service: p.ServiceName,
+		Method:  p.MethodName,
+	}
+	for _, ai := range p.AuthorizationInfo {
+		if ai.Granted {
+			a.Permissions = append(a.Permissions, ai.Permission)
+		}
+	}
+	return a, nil
+}
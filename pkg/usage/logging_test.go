@@ -0,0 +1,223 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	logging "google.golang.org/api/logging/v2"
+	"google.golang.org/api/option"
+
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestLoggingQuerier_QueryUsage(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+	entry := func(service, method string, permissions ...string) map[string]any {
+		var infos []any
+		for _, p := range permissions {
+			infos = append(infos, map[string]any{"permission": p, "granted": true})
+		}
+		// Permissions denied to the call are not used.
+		infos = append(infos, map[string]any{"permission": "resourcemanager.projects.delete", "granted": false})
+		return map[string]any{
+			"protoPayload": map[string]any{
+				"@type":             "type.googleapis.com/google.cloud.audit.AuditLog",
+				"serviceName":       service,
+				"methodName":        method,
+				"authorizationInfo": infos,
+			},
+		}
+	}
+	pages := [][]map[string]any{
+		{
+			entry("bigquery.googleapis.com", "jobservice.insert", "bigquery.jobs.create"),
+			entry("storage.googleapis.com", "storage.objects.get", "storage.objects.get"),
+		},
+		{
+			entry("bigquery.googleapis.com", "jobservice.insert", "bigquery.jobs.create", "bigquery.tables.getData"),
+		},
+	}
+
+	cases := []struct {
+		name       string
+		member     string
+		maxEntries int
+		status     int
+		want       *audit.Usage
+		wantCalls  int
+		wantErr    string
+	}{
+		{
+			name:       "success",
+			member:     "user:test-user@example.com",
+			maxEntries: DefaultMaxEntries,
+			status:     http.StatusOK,
+			want: &audit.Usage{
+				Member: "user:test-user@example.com",
+				Start:  start,
+				End:    end,
+				Calls:  3,
+				Methods: []*audit.MethodUsage{
+					{Service: "bigquery.googleapis.com", Method: "jobservice.insert", Calls: 2},
+					{Service: "storage.googleapis.com", Method: "storage.objects.get", Calls: 1},
+				},
+				Permissions: []string{"bigquery.jobs.create", "bigquery.tables.getData", "storage.objects.get"},
+			},
+			wantCalls: 2,
+		},
+		{
+			name:       "truncated",
+			member:     "serviceAccount:test-sa@test-project.iam.gserviceaccount.com",
+			maxEntries: 1,
+			status:     http.StatusOK,
+			want: &audit.Usage{
+				Member: "serviceAccount:test-sa@test-project.iam.gserviceaccount.com",
+				Start:  start,
+				End:    end,
+				Calls:  1,
+				Methods: []*audit.MethodUsage{
+					{Service: "bigquery.googleapis.com", Method: "jobservice.insert", Calls: 1},
+				},
+				Permissions: []string{"bigquery.jobs.create"},
+				Truncated:   true,
+			},
+			wantCalls: 1,
+		},
+		{
+			name:       "unsupported_member",
+			member:     "group:test-group@example.com",
+			maxEntries: DefaultMaxEntries,
+			status:     http.StatusOK,
+			wantErr:    `member "group:test-group@example.com" is not of user or serviceAccount type`,
+		},
+		{
+			name:       "list_failure",
+			member:     "user:test-user@example.com",
+			maxEntries: DefaultMaxEntries,
+			status:     http.StatusForbidden,
+			wantErr:    `failed to list log entries of "projects/test-project"`,
+			wantCalls:  1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			var mu sync.Mutex
+			var gotCalls int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				gotCalls++
+
+				var req logging.ListLogEntriesRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				if diff := cmp.Diff([]string{"projects/test-project"}, req.ResourceNames); diff != "" {
+					t.Errorf("got resource names diff (-want, +got):\n%s", diff)
+				}
+				if want := Filter("test-user@example.com", start, end); tc.member == "user:test-user@example.com" && req.Filter != want {
+					t.Errorf("got filter %q, want %q", req.Filter, want)
+				}
+
+				w.WriteHeader(tc.status)
+				if tc.status != http.StatusOK {
+					w.Write([]byte(`{}`)) //nolint:errcheck // Best effort.
+					return
+				}
+				resp := map[string]any{"entries": pages[0]}
+				if req.PageToken == "" {
+					resp["nextPageToken"] = "page-2"
+				} else {
+					resp["entries"] = pages[1]
+				}
+				json.NewEncoder(w).Encode(resp) //nolint:errcheck // Best effort.
+			}))
+			t.Cleanup(srv.Close)
+
+			q, err := NewLoggingQuerier(ctx, tc.maxEntries,
+				option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := q.QueryUsage(ctx, "projects/test-project", tc.member, start, end)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Process(%+v) got unexpected error: %s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Process(%+v) got usage diff (-want, +got):\n%s", tc.name, diff)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if gotCalls != tc.wantCalls {
+				t.Errorf("Process(%+v) got %d calls, want %d", tc.name, gotCalls, tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestNewLoggingQuerier_InvalidMaxEntries(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewLoggingQuerier(context.Background(), 0, option.WithoutAuthentication())
+	if diff := testutil.DiffErrString(err, "max entries must be at least 1, got 0"); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2009, 11, 10, 23, 0, 0, 0, time.FixedZone("", 3600))
+	got := Filter("test-user@example.com", start, start.Add(time.Hour))
+	want := `logName:"cloudaudit.googleapis.com" AND ` +
+		`protoPayload.authenticationInfo.principalEmail="test-user@example.com" AND ` +
+		`timestamp>="2009-11-10T22:00:00Z" AND timestamp<"2009-11-10T23:00:00Z"`
+	if got != want {
+		t.Errorf("Filter got %q, want %q", got, want)
+	}
+}
+
+func TestSummarize_Empty(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC)
+	got := Summarize("user:test-user@example.com", start, start.Add(time.Hour), nil, false)
+	want := &audit.Usage{
+		Member: "user:test-user@example.com",
+		Start:  start,
+		End:    start.Add(time.Hour),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Summarize got diff (-want, +got):\n%s", diff)
+	}
+}
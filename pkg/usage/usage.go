@@ -0,0 +1,76 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package usage summarizes the activity of members in Cloud Audit Logs during
+// AOD grants, so reviewers can confirm the granted access was used
+// appropriately.
+package usage
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/abcxyz/access-on-demand/pkg/audit"
+)
+
+// Activity is an API call of a member found in Cloud Audit Logs.
+type Activity struct {
+	// Service of the called method, e.g. "bigquery.googleapis.com".
+	Service string
+
+	// Method name, e.g. "google.cloud.bigquery.v2.JobService.InsertJob".
+	Method string
+
+	// Permissions granted to the call.
+	Permissions []string
+}
+
+// Summarize summarizes the activities of the member between start and end.
+// Truncated is whether there are more activities than the given ones.
+func Summarize(member string, start, end time.Time, activities []*Activity, truncated bool) *audit.Usage {
+	u := &audit.Usage{
+		Member:    member,
+		Start:     start.UTC(),
+		End:       end.UTC(),
+		Calls:     len(activities),
+		Truncated: truncated,
+	}
+
+	type key struct{ service, method string }
+	methods := make(map[key]*audit.MethodUsage)
+	for _, a := range activities {
+		k := key{a.Service, a.Method}
+		m, ok := methods[k]
+		if !ok {
+			m = &audit.MethodUsage{Service: a.Service, Method: a.Method}
+			methods[k] = m
+			u.Methods = append(u.Methods, m)
+		}
+		m.Calls++
+		u.Permissions = append(u.Permissions, a.Permissions...)
+	}
+
+	// Sort the methods by the most calls, then by name for stable output.
+	slices.SortFunc(u.Methods, func(a, b *audit.MethodUsage) int {
+		return cmp.Or(
+			cmp.Compare(b.Calls, a.Calls),
+			cmp.Compare(a.Service, b.Service),
+			cmp.Compare(a.Method, b.Method),
+		)
+	})
+	slices.Sort(u.Permissions)
+	u.Permissions = slices.Compact(u.Permissions)
+	return u
+}
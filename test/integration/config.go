@@ -29,6 +29,13 @@ type config struct {
 	QueryRetryLimit        uint64        `env:"INTEG_TEST_QUERY_RETRY_COUNT,default=20"`
 	ConditionTitle         string        `env:"INTEG_TEST_CONDITION_TITLE,required"`
 	IAMUser                string        `env:"INTEG_TEST_IAM_USER,required"`
+
+	// The sandbox project factory config. When SandboxParent is set, an
+	// ephemeral project is created under it for the test run, e.g.
+	// "folders/123", and deleted afterwards.
+	SandboxParent        string            `env:"INTEG_TEST_SANDBOX_PARENT"`
+	SandboxProjectPrefix string            `env:"INTEG_TEST_SANDBOX_PROJECT_PREFIX,default=aod-it"`
+	SandboxLabels        map[string]string `env:"INTEG_TEST_SANDBOX_LABELS"`
 }
 
 func newTestConfig(ctx context.Context) (*config, error) {
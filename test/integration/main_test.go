@@ -59,6 +59,10 @@ var (
 	cfg           *config
 	projectClient *resourcemanager.ProjectsClient
 	iamReqData    string
+
+	// sandboxProjectID is the ID of the ephemeral sandbox project, empty if
+	// the sandbox is not configured.
+	sandboxProjectID string
 )
 
 func TestMain(m *testing.M) {
@@ -90,6 +94,22 @@ func TestMain(m *testing.M) {
 		defer pc.Close()
 		projectClient = pc
 
+		// Set up the ephemeral sandbox project if configured.
+		if cfg.SandboxParent != "" {
+			sb, err := newSandbox(ctx, pc, cfg)
+			if err != nil {
+				log.Printf("failed to set up sandbox: %v", err)
+				return 1
+			}
+			defer func() {
+				if err := sb.Close(ctx); err != nil {
+					log.Printf("failed to tear down sandbox: %v", err)
+				}
+			}()
+			sandboxProjectID = sb.projectID
+			log.Printf("created sandbox project %q", sandboxProjectID)
+		}
+
 		return m.Run()
 	}())
 }
@@ -97,8 +117,23 @@ func TestMain(m *testing.M) {
 func TestIAMHandleAndCleanup(t *testing.T) {
 	t.Parallel()
 
+	for name, projectID := range testProjects() {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			testIAMHandleAndCleanup(t, projectID)
+		})
+	}
+}
+
+// testIAMHandleAndCleanup runs a full handle and cleanup cycle of an IAM
+// request on the project.
+func testIAMHandleAndCleanup(t *testing.T, projectID string) {
+	t.Helper()
+
 	ctx := context.Background()
-	reqFilePath := testWriteReqFile(t, iamReqData, "iam.yaml")
+	reqData := fmt.Sprintf(iamReqDataTmpl, projectID, cfg.IAMUser, cfg.IAMUser)
+	reqFilePath := testWriteReqFile(t, reqData, "iam.yaml")
 
 	now := time.Now().UTC().Round(time.Second)
 	d := 3 * time.Hour
@@ -125,7 +160,7 @@ func TestIAMHandleAndCleanup(t *testing.T) {
 	wantHandleOutput := fmt.Sprintf(`------Successfully Handled IAM Request------
 iamrequest:
   policies:
-    - resource: projects/access-on-demand-i-12af76
+    - resource: projects/%s
       bindings:
         - members:
             - %s
@@ -135,11 +170,11 @@ iamrequest:
           role: roles/ml.viewer
 duration: %s
 starttime: %s
-`, cfg.IAMUser, cfg.IAMUser, d.String(), now.Format(time.RFC3339))
+`, projectID, cfg.IAMUser, cfg.IAMUser, d.String(), now.Format(time.RFC3339))
 
 	wantCleanupOutput := fmt.Sprintf(`------Successfully Removed Requested Bindings------
 policies:
-  - resource: projects/access-on-demand-i-12af76
+  - resource: projects/%s
     bindings:
       - members:
           - %s
@@ -147,7 +182,7 @@ policies:
       - members:
           - %s
         role: roles/ml.viewer
-`, cfg.IAMUser, cfg.IAMUser)
+`, projectID, cfg.IAMUser, cfg.IAMUser)
 
 	handleArgs := []string{
 		"iam", "handle",
@@ -159,12 +194,12 @@ policies:
 
 	// Cleanup/Reset the IAM policy.
 	t.Cleanup(func() {
-		testGetAndResetBindings(ctx, t, cfg, true)
+		testGetAndResetBindings(ctx, t, projectID, true)
 	})
 
 	_, handleStdout, handleStderr := testPipeAndRun(ctx, t, handleArgs)
 
-	gotHandleBindings := testGetAndResetBindings(ctx, t, cfg, false)
+	gotHandleBindings := testGetAndResetBindings(ctx, t, projectID, false)
 
 	if diff := cmp.Diff(wantBindings, gotHandleBindings, protocmp.Transform()); diff != "" {
 		t.Errorf("Handle got project bindings diff (-want, +got): %v", diff)
@@ -180,7 +215,7 @@ policies:
 
 	_, cleanupStdout, cleanupStderr := testPipeAndRun(ctx, t, cleanupArgs)
 
-	gotCleanupBindings := testGetAndResetBindings(ctx, t, cfg, false)
+	gotCleanupBindings := testGetAndResetBindings(ctx, t, projectID, false)
 	if diff := cmp.Diff(wantBindings, gotCleanupBindings, protocmp.Transform()); diff != "" {
 		t.Errorf("Cleanup got project bindings diff (-want, +got): %v", diff)
 	}
@@ -298,14 +333,25 @@ func TestToolValidate(t *testing.T) {
 	}
 }
 
+// testProjects returns the projects to run handle and cleanup cycles on by
+// subtest name: the pre-provisioned project, and the sandbox project if it is
+// configured.
+func testProjects() map[string]string {
+	projects := map[string]string{"pre_provisioned": cfg.ProjectID}
+	if sandboxProjectID != "" {
+		projects["sandbox"] = sandboxProjectID
+	}
+	return projects
+}
+
 // testGetAndResetBindings is a helper function that returns the IAM bindings
-// of matched condition title in the cfg. It also removes them from the project
-// IAM policy if reset is true.
-func testGetAndResetBindings(ctx context.Context, tb testing.TB, cfg *config, reset bool) (result []*iampb.Binding) {
+// of matched condition title in the cfg on the project. It also removes them
+// from the project IAM policy if reset is true.
+func testGetAndResetBindings(ctx context.Context, tb testing.TB, projectID string, reset bool) (result []*iampb.Binding) {
 	tb.Helper()
 
 	getIAMReq := &iampb.GetIamPolicyRequest{
-		Resource: fmt.Sprintf("projects/%s", cfg.ProjectID),
+		Resource: fmt.Sprintf("projects/%s", projectID),
 		Options: &iampb.GetPolicyOptions{
 			RequestedPolicyVersion: 3,
 		},
@@ -333,7 +379,7 @@ func testGetAndResetBindings(ctx context.Context, tb testing.TB, cfg *config, re
 
 		p.Bindings = bs
 		setIAMReq := &iampb.SetIamPolicyRequest{
-			Resource: fmt.Sprintf("projects/%s", cfg.ProjectID),
+			Resource: fmt.Sprintf("projects/%s", projectID),
 			Policy:   p,
 		}
 		if _, err := projectClient.SetIamPolicy(ctx, setIAMReq); err != nil {
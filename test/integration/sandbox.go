@@ -0,0 +1,100 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	resourcemanager "cloud.google.com/go/resourcemanager/apiv3"
+	"cloud.google.com/go/resourcemanager/apiv3/resourcemanagerpb"
+)
+
+const (
+	// maxProjectIDLength is the max length of GCP project IDs.
+	maxProjectIDLength = 30
+
+	// sandboxSuffixBytes is the number of random bytes of the sandbox project
+	// ID suffix, hex encoded.
+	sandboxSuffixBytes = 4
+)
+
+// sandbox is an ephemeral project provisioned for one integration test run, so
+// that the tests can handle and clean up requests without relying on the state
+// of the pre-provisioned project.
+type sandbox struct {
+	client    *resourcemanager.ProjectsClient
+	projectID string
+}
+
+// newSandboxProjectID returns a random project ID with the prefix.
+func newSandboxProjectID(prefix string) (string, error) {
+	if got := len(prefix) + 1 + 2*sandboxSuffixBytes; got > maxProjectIDLength {
+		return "", fmt.Errorf("sandbox project prefix %q is too long, project ID would be %d characters, max is %d", prefix, got, maxProjectIDLength)
+	}
+	b := make([]byte, sandboxSuffixBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate sandbox project ID: %w", err)
+	}
+	return fmt.Sprintf("%s-%s", prefix, hex.EncodeToString(b)), nil
+}
+
+// newSandbox creates a project under the sandbox parent of the config, and
+// waits until it is active.
+func newSandbox(ctx context.Context, client *resourcemanager.ProjectsClient, cfg *config) (*sandbox, error) {
+	projectID, err := newSandboxProjectID(cfg.SandboxProjectPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[string]string{"created-by": "aod-integration-test"}
+	for k, v := range cfg.SandboxLabels {
+		labels[k] = v
+	}
+
+	op, err := client.CreateProject(ctx, &resourcemanagerpb.CreateProjectRequest{
+		Project: &resourcemanagerpb.Project{
+			ProjectId:   projectID,
+			DisplayName: projectID,
+			Parent:      cfg.SandboxParent,
+			Labels:      labels,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sandbox project %q: %w", projectID, err)
+	}
+	if _, err := op.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("failed to wait for sandbox project %q creation: %w", projectID, err)
+	}
+
+	return &sandbox{client: client, projectID: projectID}, nil
+}
+
+// Close deletes the sandbox project. Deleted projects are kept in the
+// DELETE_REQUESTED state until they are purged by GCP.
+func (s *sandbox) Close(ctx context.Context) error {
+	op, err := s.client.DeleteProject(ctx, &resourcemanagerpb.DeleteProjectRequest{
+		Name: fmt.Sprintf("projects/%s", s.projectID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete sandbox project %q: %w", s.projectID, err)
+	}
+	if _, err := op.Wait(ctx); err != nil {
+		return fmt.Errorf("failed to wait for sandbox project %q deletion: %w", s.projectID, err)
+	}
+	return nil
+}